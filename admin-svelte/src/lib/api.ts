@@ -132,6 +132,27 @@ export interface TransferOwnershipRequest {
 	message?: string;
 }
 
+export interface QueryStat {
+	query: string;
+	count: number;
+	avg_top_score: number;
+}
+
+export interface QueryAnalytics {
+	project_id: string;
+	since: string;
+	queries: number;
+	zero_results: number;
+	low_score: number;
+	cache_hits: number;
+	cache_hit_rate: number;
+	latency_p50_ms: number;
+	latency_p95_ms: number;
+	top_queries?: QueryStat[];
+	zero_result_queries?: QueryStat[];
+	low_score_queries?: QueryStat[];
+}
+
 // API client class
 class MetaBaseAPI {
 	private baseURL: string;
@@ -256,6 +277,15 @@ class MetaBaseAPI {
 	async getTenantProjects(tenantId: string) {
 		return this.request<{ projects: Project[]; total: number }>(`/tenants/${tenantId}/projects`);
 	}
+
+	// RAG query analytics
+	async getQueryAnalytics(days = 7) {
+		return this.request<{ data: QueryAnalytics[] }>(`/rag/analytics?days=${days}`);
+	}
+
+	async getProjectQueryAnalytics(projectId: string, days = 7, limit = 10) {
+		return this.request<{ data: QueryAnalytics }>(`/projects/${projectId}/rag/analytics?days=${days}&limit=${limit}`);
+	}
 }
 
 // Create a singleton instance
@@ -0,0 +1,191 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+)
+
+func newTestEngine(t *testing.T, dispatcher Dispatcher) (*Engine, *Manager, *time.Time) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "alerts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	manager := NewManager(db, zap.NewNop())
+	if err := manager.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recorder := NewRecorder(5 * time.Minute)
+	recorder.now = func() time.Time { return now }
+	engine := NewEngine(manager, recorder, dispatcher, DefaultConfig(), zap.NewNop())
+	engine.now = func() time.Time { return now }
+	return engine, manager, &now
+}
+
+type recordingDispatcher struct {
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (d *recordingDispatcher) Dispatch(ctx context.Context, rule *Rule, notification *Notification) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sent = append(d.sent, *notification)
+	return []string{ChannelWebhook}
+}
+
+func (d *recordingDispatcher) statuses() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var statuses []string
+	for _, n := range d.sent {
+		statuses = append(statuses, n.TenantID+":"+n.Status)
+	}
+	return statuses
+}
+
+func TestEngineDedupesAndResolves(t *testing.T) {
+	ctx := context.Background()
+	dispatcher := &recordingDispatcher{}
+	engine, manager, now := newTestEngine(t, dispatcher)
+
+	rule := &Rule{Name: "Job failures", Metric: MetricJobFailures, Operator: OperatorGreaterEqual, Threshold: 2, Severity: SeverityWarning, Enabled: true}
+	if err := manager.SaveRule(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.recorder.RecordJobFailure("acme")
+	engine.recorder.RecordJobFailure("acme")
+	engine.recorder.RecordJobFailure("globex")
+	mustEvaluate(t, engine)
+	mustEvaluate(t, engine) // still firing within the repeat interval
+	if got := dispatcher.statuses(); len(got) != 1 || got[0] != "acme:firing" {
+		t.Fatalf("expected one firing notification for acme, got %v", got)
+	}
+
+	// Past the repeat interval a still-firing alert notifies again
+	*now = now.Add(2 * time.Minute)
+	engine.config.RepeatInterval = time.Minute
+	mustEvaluate(t, engine)
+	if got := dispatcher.statuses(); len(got) != 2 {
+		t.Fatalf("expected a repeat notification, got %v", got)
+	}
+
+	// Failures leave the window and the alert resolves once
+	*now = now.Add(10 * time.Minute)
+	mustEvaluate(t, engine)
+	mustEvaluate(t, engine)
+	if got := dispatcher.statuses(); len(got) != 3 || got[2] != "acme:resolved" {
+		t.Fatalf("expected a resolved notification, got %v", got)
+	}
+
+	notifications, err := manager.ListNotifications(ctx, NotificationFilter{TenantID: "acme", UnreadOnly: true, Limit: 10})
+	if err != nil || len(notifications) != 3 || notifications[0].Status != StatusResolved {
+		t.Fatalf("unexpected notification center %+v, %v", notifications, err)
+	}
+	if marked, err := manager.MarkRead(ctx, []string{notifications[0].ID}, "admin"); err != nil || marked != 1 {
+		t.Fatalf("expected one notification marked read, got %d, %v", marked, err)
+	}
+	if unread, _ := manager.CountUnread(ctx, ""); unread != 2 {
+		t.Fatalf("expected 2 unread notifications, got %d", unread)
+	}
+	if marked, _ := manager.MarkRead(ctx, nil, "admin"); marked != 2 {
+		t.Fatalf("expected remaining notifications marked read, got %d", marked)
+	}
+}
+
+func TestEngineSilences(t *testing.T) {
+	ctx := context.Background()
+	dispatcher := &recordingDispatcher{}
+	engine, manager, now := newTestEngine(t, dispatcher)
+
+	rule := &Rule{Name: "Logins", Metric: MetricFailedLogins, Operator: OperatorGreater, Threshold: 0, Severity: SeverityCritical, Enabled: true}
+	if err := manager.SaveRule(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+	silence := &Silence{RuleID: rule.ID, StartsAt: *now, EndsAt: now.Add(time.Minute)}
+	if err := manager.CreateSilence(ctx, silence); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.recorder.RecordFailedLogin()
+	mustEvaluate(t, engine)
+	if got := dispatcher.statuses(); len(got) != 0 {
+		t.Fatalf("silenced alert should not notify, got %v", got)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	mustEvaluate(t, engine)
+	if got := dispatcher.statuses(); len(got) != 1 || got[0] != ":firing" {
+		t.Fatalf("expected notification after the silence ends, got %v", got)
+	}
+}
+
+func TestApplyThresholds(t *testing.T) {
+	ctx := context.Background()
+	engine, manager, _ := newTestEngine(t, &recordingDispatcher{})
+
+	if err := engine.ApplyThresholds(ctx, map[string]float64{MetricErrorRate: 0.05}); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := manager.GetRule(ctx, "threshold_error_rate")
+	if err != nil || rule.Threshold != 0.05 || !rule.Enabled {
+		t.Fatalf("unexpected threshold rule %+v, %v", rule, err)
+	}
+	if err := engine.ApplyThresholds(ctx, map[string]float64{"latency": 1}); err == nil {
+		t.Fatal("expected unknown metric to be rejected")
+	}
+}
+
+func TestChannelDispatcherUsesTenantTargets(t *testing.T) {
+	var payloads []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads = append(payloads, payload)
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.Notifications = &tenant.NotificationSettings{Webhook: server.URL}
+	dispatcher := NewChannelDispatcher(config, func(ctx context.Context, tenantID string) (*tenant.NotificationSettings, error) {
+		if tenantID == "acme" {
+			return &tenant.NotificationSettings{Slack: server.URL, Discord: server.URL}, nil
+		}
+		return nil, nil
+	}, zap.NewNop())
+
+	rule := &Rule{ID: "r1", Channels: []string{ChannelSlack, ChannelWebhook}}
+	sent := dispatcher.Dispatch(context.Background(), rule, &Notification{TenantID: "acme", Message: "high"})
+	if len(sent) != 1 || sent[0] != ChannelSlack || payloads[0]["text"] != "high" {
+		t.Fatalf("expected only the tenant's slack target, got %v %v", sent, payloads)
+	}
+
+	sent = dispatcher.Dispatch(context.Background(), rule, &Notification{TenantID: "globex", RuleID: "r1"})
+	if len(sent) != 1 || sent[0] != ChannelWebhook || payloads[1]["rule_id"] != "r1" {
+		t.Fatalf("expected the system webhook, got %v %v", sent, payloads)
+	}
+}
+
+func mustEvaluate(t *testing.T, engine *Engine) {
+	t.Helper()
+	if err := engine.Evaluate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,190 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+	"github.com/guileen/metabase/pkg/infra/i18n"
+	"github.com/guileen/metabase/pkg/infra/mailer"
+)
+
+// Dispatcher 将通知发送到外部渠道，返回成功发送的渠道
+type Dispatcher interface {
+	Dispatch(ctx context.Context, rule *Rule, notification *Notification) []string
+}
+
+// TargetLoader 读取租户的通知设置，租户未配置时返回 nil
+type TargetLoader func(ctx context.Context, tenantID string) (*tenant.NotificationSettings, error)
+
+// TargetsFromDB 从租户设置读取通知目标
+func TargetsFromDB(db *sql.DB) TargetLoader {
+	return func(ctx context.Context, tenantID string) (*tenant.NotificationSettings, error) {
+		var raw sql.NullString
+		err := db.QueryRowContext(ctx, `SELECT settings FROM tenants WHERE id = ?`, tenantID).Scan(&raw)
+		if err == sql.ErrNoRows || !raw.Valid || raw.String == "" {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var settings struct {
+			Notifications *tenant.NotificationSettings `json:"notifications"`
+		}
+		if err := json.Unmarshal([]byte(raw.String), &settings); err != nil {
+			return nil, fmt.Errorf("invalid tenant settings: %w", err)
+		}
+		return settings.Notifications, nil
+	}
+}
+
+// LocaleLoader 返回发给租户（tenantID 为空时为系统级）的通知使用的语言
+type LocaleLoader func(ctx context.Context, tenantID string) string
+
+// ChannelDispatcher 通过邮件、Slack、Discord 和 Webhook 发送通知
+type ChannelDispatcher struct {
+	config  *Config
+	targets TargetLoader
+	locales LocaleLoader
+	mailer  *mailer.Mailer
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+// NewChannelDispatcher 创建通知发送器，targets 为空时只使用系统级通知设置
+func NewChannelDispatcher(config *Config, targets TargetLoader, logger *zap.Logger) *ChannelDispatcher {
+	return &ChannelDispatcher{
+		config:  config,
+		targets: targets,
+		mailer:  mailer.New(config.Mail),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		logger:  logger,
+	}
+}
+
+// SetLocales 设置邮件通知的语言，未设置时使用默认语言
+func (d *ChannelDispatcher) SetLocales(locales LocaleLoader) {
+	d.locales = locales
+}
+
+// Dispatch 发送通知。租户告警优先使用租户的通知设置，未配置时使用系统级设置。
+func (d *ChannelDispatcher) Dispatch(ctx context.Context, rule *Rule, notification *Notification) []string {
+	settings := d.config.Notifications
+	if notification.TenantID != "" && d.targets != nil {
+		tenantSettings, err := d.targets(ctx, notification.TenantID)
+		if err != nil {
+			d.logger.Warn("failed to load tenant notification settings",
+				zap.String("tenant_id", notification.TenantID), zap.Error(err))
+		} else if tenantSettings != nil {
+			settings = tenantSettings
+		}
+	}
+	if settings == nil {
+		return nil
+	}
+
+	var sent []string
+	for _, channel := range []string{ChannelEmail, ChannelSlack, ChannelDiscord, ChannelWebhook} {
+		if !wantsChannel(rule.Channels, channel) {
+			continue
+		}
+		var err error
+		switch channel {
+		case ChannelEmail:
+			if !settings.Email || len(settings.EmailTo) == 0 || !d.mailer.Enabled() {
+				continue
+			}
+			err = d.mailer.Send(d.email(ctx, settings.EmailTo, notification))
+		case ChannelSlack:
+			if settings.Slack == "" {
+				continue
+			}
+			err = d.post(ctx, settings.Slack, map[string]string{"text": notification.Message})
+		case ChannelDiscord:
+			if settings.Discord == "" {
+				continue
+			}
+			err = d.post(ctx, settings.Discord, map[string]string{"content": notification.Message})
+		case ChannelWebhook:
+			if settings.Webhook == "" {
+				continue
+			}
+			err = d.post(ctx, settings.Webhook, notification)
+		}
+		if err != nil {
+			d.logger.Warn("failed to send alert notification",
+				zap.String("channel", channel), zap.String("rule_id", rule.ID), zap.Error(err))
+			continue
+		}
+		sent = append(sent, channel)
+	}
+	return sent
+}
+
+// email 按租户语言生成告警邮件
+func (d *ChannelDispatcher) email(ctx context.Context, to []string, notification *Notification) *mailer.Message {
+	locale := i18n.DefaultLocale
+	if d.locales != nil {
+		locale = d.locales(ctx, notification.TenantID)
+	}
+	scope := i18n.Translate(locale, "alert.scope.system", nil)
+	if notification.TenantID != "" {
+		scope = i18n.Translate(locale, "alert.scope.tenant", i18n.Args{"tenant": notification.TenantID})
+	}
+	args := i18n.Args{
+		"severity":  strings.ToUpper(notification.Severity),
+		"rule":      notification.RuleName,
+		"status":    i18n.Translate(locale, "alert.status."+notification.Status, nil),
+		"metric":    notification.Metric,
+		"scope":     scope,
+		"value":     strconv.FormatFloat(notification.Value, 'g', -1, 64),
+		"threshold": strconv.FormatFloat(notification.Threshold, 'g', -1, 64),
+	}
+	return &mailer.Message{
+		To:      to,
+		Subject: i18n.Translate(locale, "alert.email.subject", args),
+		Text:    i18n.Translate(locale, "alert.email.body", args),
+	}
+}
+
+func wantsChannel(channels []string, channel string) bool {
+	if len(channels) == 0 {
+		return true
+	}
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *ChannelDispatcher) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Source 提供记录器以外的指标，例如预算用量
+type Source interface {
+	Samples(ctx context.Context) ([]Sample, error)
+}
+
+// SourceFunc 函数形式的指标来源
+type SourceFunc func(ctx context.Context) ([]Sample, error)
+
+// Samples 实现 Source
+func (f SourceFunc) Samples(ctx context.Context) ([]Sample, error) {
+	return f(ctx)
+}
+
+// Engine 定期评估告警规则，对新触发和恢复的告警写入通知中心并发送通知
+type Engine struct {
+	manager    *Manager
+	recorder   *Recorder
+	dispatcher Dispatcher
+	config     *Config
+	logger     *zap.Logger
+
+	mu      sync.Mutex // 串行化评估
+	sources []Source
+	now     func() time.Time
+}
+
+// NewEngine 创建告警引擎
+func NewEngine(manager *Manager, recorder *Recorder, dispatcher Dispatcher, config *Config, logger *zap.Logger) *Engine {
+	defaults := DefaultConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	if config.RepeatInterval <= 0 {
+		config.RepeatInterval = defaults.RepeatInterval
+	}
+	return &Engine{
+		manager:    manager,
+		recorder:   recorder,
+		dispatcher: dispatcher,
+		config:     config,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// AddSource 添加指标来源
+func (e *Engine) AddSource(source Source) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sources = append(e.sources, source)
+}
+
+// Run 按配置的间隔评估规则，直到 ctx 结束
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Evaluate(ctx); err != nil {
+				e.logger.Warn("alert evaluation failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// ApplyThresholds 将 MetricsConfig.AlertThresholds 同步为告警规则，
+// 规则 ID 为 threshold_<指标>，可在管理接口中调整渠道或停用。
+func (e *Engine) ApplyThresholds(ctx context.Context, thresholds map[string]float64) error {
+	for metric, threshold := range thresholds {
+		id := "threshold_" + metric
+		rule, err := e.manager.GetRule(ctx, id)
+		if err == ErrNotFound {
+			rule = &Rule{
+				ID:       id,
+				Name:     metric + " threshold",
+				Metric:   metric,
+				Operator: OperatorGreaterEqual,
+				Severity: SeverityWarning,
+				Enabled:  true,
+			}
+		} else if err != nil {
+			return err
+		} else if rule.Threshold == threshold {
+			continue
+		}
+		rule.Threshold = threshold
+		if err := e.manager.SaveRule(ctx, rule); err != nil {
+			return fmt.Errorf("threshold %s: %w", metric, err)
+		}
+	}
+	return nil
+}
+
+// Evaluate 评估一次所有启用的规则
+func (e *Engine) Evaluate(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	samples := e.recorder.Samples(e.config.MinRequests)
+	for _, source := range e.sources {
+		extra, err := source.Samples(ctx)
+		if err != nil {
+			e.logger.Warn("failed to collect alert samples", zap.Error(err))
+			continue
+		}
+		samples = append(samples, extra...)
+	}
+
+	rules, err := e.manager.ListRules(ctx)
+	if err != nil {
+		return err
+	}
+	silences, err := e.manager.ListSilences(ctx, now)
+	if err != nil {
+		return err
+	}
+	states, err := e.manager.listStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, sample := range samples {
+			if sample.Metric != rule.Metric || (rule.TenantID != "" && rule.TenantID != sample.TenantID) {
+				continue
+			}
+			seen[sample.TenantID] = true
+			state := states[stateKey(rule.ID, sample.TenantID)]
+			e.evaluateSample(ctx, rule, sample, state, silences, now)
+		}
+
+		// 指标消失（例如窗口内不再有失败）的已触发告警视为恢复
+		for _, state := range states {
+			if state.RuleID == rule.ID && state.Firing && !seen[state.TenantID] {
+				sample := Sample{Metric: rule.Metric, TenantID: state.TenantID}
+				e.evaluateSample(ctx, rule, sample, state, silences, now)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Engine) evaluateSample(ctx context.Context, rule *Rule, sample Sample, state *alertState, silences []Silence, now time.Time) {
+	firing := rule.matches(sample.Value)
+	wasFiring := state != nil && state.Firing
+	if !firing && !wasFiring {
+		return
+	}
+	for i := range silences {
+		if silences[i].covers(rule.ID, sample.TenantID, now) {
+			// 静默期间不通知也不改变状态，结束后按当时的值处理
+			return
+		}
+	}
+
+	if state == nil {
+		state = &alertState{RuleID: rule.ID, TenantID: sample.TenantID}
+	}
+	status := StatusFiring
+	switch {
+	case firing && wasFiring:
+		if now.Sub(state.NotifiedAt) < e.repeatInterval(rule) {
+			return
+		}
+	case !firing:
+		status = StatusResolved
+	}
+
+	notification := &Notification{
+		ID:        "ntf_" + uuid.New().String(),
+		RuleID:    rule.ID,
+		RuleName:  rule.Name,
+		TenantID:  sample.TenantID,
+		Metric:    rule.Metric,
+		Value:     sample.Value,
+		Threshold: rule.Threshold,
+		Severity:  rule.Severity,
+		Status:    status,
+		Message:   alertMessage(rule, sample, status),
+		CreatedAt: now,
+	}
+	notification.Channels = e.dispatcher.Dispatch(ctx, rule, notification)
+	if err := e.manager.SaveNotification(ctx, notification); err != nil {
+		e.logger.Error("failed to save alert notification", zap.String("rule_id", rule.ID), zap.Error(err))
+		return
+	}
+
+	state.Firing = firing
+	state.NotifiedAt = now
+	if err := e.manager.saveState(ctx, state); err != nil {
+		e.logger.Error("failed to save alert state", zap.String("rule_id", rule.ID), zap.Error(err))
+	}
+	e.logger.Info("Alert notification",
+		zap.String("rule_id", rule.ID),
+		zap.String("tenant_id", sample.TenantID),
+		zap.String("status", status),
+		zap.Float64("value", sample.Value))
+}
+
+func (e *Engine) repeatInterval(rule *Rule) time.Duration {
+	if rule.RepeatIntervalSeconds > 0 {
+		return time.Duration(rule.RepeatIntervalSeconds) * time.Second
+	}
+	return e.config.RepeatInterval
+}
+
+func alertMessage(rule *Rule, sample Sample, status string) string {
+	scope := "system"
+	if sample.TenantID != "" {
+		scope = "tenant " + sample.TenantID
+	}
+	if status == StatusResolved {
+		return fmt.Sprintf("[resolved] %s: %s for %s is back to %g (threshold %s %g)",
+			rule.Name, rule.Metric, scope, sample.Value, rule.Operator, rule.Threshold)
+	}
+	return fmt.Sprintf("[%s] %s: %s for %s is %g (threshold %s %g)",
+		rule.Severity, rule.Name, rule.Metric, scope, sample.Value, rule.Operator, rule.Threshold)
+}
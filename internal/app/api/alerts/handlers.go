@@ -0,0 +1,292 @@
+package alerts
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// Handler 告警规则、静默窗口和通知中心的HTTP处理器
+type Handler struct {
+	manager *Manager
+	engine  *Engine
+	logger  *zap.Logger
+}
+
+// NewHandler 创建告警处理器
+func NewHandler(manager *Manager, engine *Engine, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		engine:  engine,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 注册告警规则和静默窗口路由
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/rules", h.handleListRules)
+	r.Post("/rules", h.handleCreateRule)
+	r.Get("/rules/{id}", h.handleGetRule)
+	r.Put("/rules/{id}", h.handleUpdateRule)
+	r.Delete("/rules/{id}", h.handleDeleteRule)
+
+	r.Get("/silences", h.handleListSilences)
+	r.Post("/silences", h.handleCreateSilence)
+	r.Delete("/silences/{id}", h.handleDeleteSilence)
+
+	r.Post("/evaluate", h.handleEvaluate)
+}
+
+// RegisterNotificationRoutes 注册通知中心路由
+func (h *Handler) RegisterNotificationRoutes(r chi.Router) {
+	r.Get("/", h.handleListNotifications)
+	r.Post("/read-all", h.handleMarkAllRead)
+	r.Post("/{id}/read", h.handleMarkRead)
+}
+
+// handleListRules 列出告警规则
+func (h *Handler) handleListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.manager.ListRules(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list alert rules", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list alert rules", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": rules})
+}
+
+// handleCreateRule 创建告警规则
+func (h *Handler) handleCreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule Rule
+	if err := render.DecodeJSON(r.Body, &rule); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+		return
+	}
+	rule.ID = ""
+	rule.CreatedAt = time.Time{}
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		rule.CreatedBy = userID
+	}
+	h.saveRule(w, r, &rule)
+}
+
+// handleGetRule 获取告警规则
+func (h *Handler) handleGetRule(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.manager.GetRule(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Alert rule not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get alert rule", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get alert rule", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": rule})
+}
+
+// handleUpdateRule 更新告警规则
+func (h *Handler) handleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	existing, err := h.manager.GetRule(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Alert rule not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get alert rule", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get alert rule", err, "")
+		return
+	}
+
+	var rule Rule
+	if err := render.DecodeJSON(r.Body, &rule); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+		return
+	}
+	rule.ID = existing.ID
+	rule.CreatedBy = existing.CreatedBy
+	rule.CreatedAt = existing.CreatedAt
+	h.saveRule(w, r, &rule)
+}
+
+func (h *Handler) saveRule(w http.ResponseWriter, r *http.Request, rule *Rule) {
+	if err := rule.Validate(); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid alert rule", err, "invalid_request")
+		return
+	}
+	if err := h.manager.SaveRule(r.Context(), rule); err != nil {
+		h.logger.Error("failed to save alert rule", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to save alert rule", err, "")
+		return
+	}
+
+	h.logger.Info("Alert rule saved",
+		zap.String("id", rule.ID),
+		zap.String("metric", rule.Metric),
+		zap.Float64("threshold", rule.Threshold),
+	)
+	render.JSON(w, r, map[string]interface{}{"data": rule})
+}
+
+// handleDeleteRule 删除告警规则
+func (h *Handler) handleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := h.manager.DeleteRule(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Alert rule not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to delete alert rule", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to delete alert rule", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": map[string]string{"id": id}})
+}
+
+// handleListSilences 列出尚未结束的静默窗口
+func (h *Handler) handleListSilences(w http.ResponseWriter, r *http.Request) {
+	silences, err := h.manager.ListSilences(r.Context(), time.Now())
+	if err != nil {
+		h.logger.Error("failed to list silences", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list silences", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": silences})
+}
+
+// SilenceRequest 创建静默窗口请求，可用 duration_seconds 代替 ends_at
+type SilenceRequest struct {
+	Silence
+	DurationSeconds int `json:"duration_seconds,omitempty"`
+}
+
+// handleCreateSilence 创建静默窗口
+func (h *Handler) handleCreateSilence(w http.ResponseWriter, r *http.Request) {
+	var req SilenceRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+		return
+	}
+	silence := req.Silence
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = time.Now()
+	}
+	if req.DurationSeconds > 0 {
+		silence.EndsAt = silence.StartsAt.Add(time.Duration(req.DurationSeconds) * time.Second)
+	}
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		silence.CreatedBy = userID
+	}
+	if err := silence.Validate(); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid silence", err, "invalid_request")
+		return
+	}
+	if err := h.manager.CreateSilence(r.Context(), &silence); err != nil {
+		h.logger.Error("failed to create silence", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to create silence", err, "")
+		return
+	}
+
+	h.logger.Info("Alert silence created",
+		zap.String("id", silence.ID),
+		zap.String("rule_id", silence.RuleID),
+		zap.String("tenant_id", silence.TenantID),
+		zap.Time("ends_at", silence.EndsAt),
+	)
+	render.JSON(w, r, map[string]interface{}{"data": silence})
+}
+
+// handleDeleteSilence 提前结束静默窗口
+func (h *Handler) handleDeleteSilence(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := h.manager.DeleteSilence(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Silence not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to delete silence", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to delete silence", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": map[string]string{"id": id}})
+}
+
+// handleEvaluate 立即评估一次规则
+func (h *Handler) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	if err := h.engine.Evaluate(r.Context()); err != nil {
+		h.logger.Error("failed to evaluate alert rules", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to evaluate alert rules", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": map[string]bool{"evaluated": true}})
+}
+
+// handleListNotifications 列出通知，支持 tenant_id、unread 和 limit 参数
+func (h *Handler) handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := NotificationFilter{
+		TenantID:   query.Get("tenant_id"),
+		UnreadOnly: query.Get("unread") == "true",
+	}
+	filter.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+
+	notifications, err := h.manager.ListNotifications(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list notifications", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list notifications", err, "")
+		return
+	}
+	unread, err := h.manager.CountUnread(r.Context(), filter.TenantID)
+	if err != nil {
+		h.logger.Error("failed to count notifications", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to count notifications", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data":   notifications,
+		"unread": unread,
+	})
+}
+
+// handleMarkRead 将一条通知标记为已读
+func (h *Handler) handleMarkRead(w http.ResponseWriter, r *http.Request) {
+	h.markRead(w, r, []string{chi.URLParam(r, "id")})
+}
+
+// handleMarkAllRead 将所有未读通知标记为已读
+func (h *Handler) handleMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	h.markRead(w, r, nil)
+}
+
+func (h *Handler) markRead(w http.ResponseWriter, r *http.Request, ids []string) {
+	userID, _ := r.Context().Value("user_id").(string)
+	count, err := h.manager.MarkRead(r.Context(), ids, userID)
+	if err != nil {
+		h.logger.Error("failed to mark notifications read", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to mark notifications read", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": map[string]int{"marked": count}})
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
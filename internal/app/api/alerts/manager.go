@@ -0,0 +1,383 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotFound 规则、静默窗口或通知不存在
+var ErrNotFound = errors.New("not found")
+
+// alertState 规则在某租户下的触发状态，用于去重和恢复通知
+type alertState struct {
+	RuleID     string
+	TenantID   string
+	Firing     bool
+	NotifiedAt time.Time // 最近一次发送触发通知的时间
+}
+
+// Manager 告警规则、静默窗口和通知中心的存储
+type Manager struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewManager 创建告警存储
+func NewManager(db *sql.DB, logger *zap.Logger) *Manager {
+	return &Manager{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Initialize 初始化数据库表
+func (m *Manager) Initialize(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_silences (
+		id TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		ends_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_states (
+		rule_id TEXT NOT NULL,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		firing BOOLEAN NOT NULL DEFAULT 0,
+		notified_at TIMESTAMP,
+		PRIMARY KEY (rule_id, tenant_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS alert_notifications (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		notification TEXT NOT NULL,
+		read_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alert_notifications_created ON alert_notifications(created_at);
+	CREATE INDEX IF NOT EXISTS idx_alert_notifications_tenant ON alert_notifications(tenant_id, created_at);
+	`
+
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		m.logger.Error("failed to initialize alert tables", zap.Error(err))
+		return fmt.Errorf("failed to initialize alert tables: %w", err)
+	}
+	return nil
+}
+
+// SaveRule 创建或更新告警规则
+func (m *Manager) SaveRule(ctx context.Context, rule *Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	if rule.ID == "" {
+		rule.ID = fmt.Sprintf("rule_%d", now.UnixNano())
+	}
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+
+	definition, err := json.Marshal(rule)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert rule: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO alert_rules (id, definition, created_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		rule.ID, string(definition), rule.CreatedAt, rule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save alert rule: %w", err)
+	}
+	return nil
+}
+
+// GetRule 获取告警规则
+func (m *Manager) GetRule(ctx context.Context, id string) (*Rule, error) {
+	var definition string
+	err := m.db.QueryRowContext(ctx, `SELECT definition FROM alert_rules WHERE id = ?`, id).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule: %w", err)
+	}
+	var rule Rule
+	if err := json.Unmarshal([]byte(definition), &rule); err != nil {
+		return nil, fmt.Errorf("failed to decode alert rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// ListRules 列出告警规则
+func (m *Manager) ListRules(ctx context.Context) ([]Rule, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT definition FROM alert_rules ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []Rule{}
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		var rule Rule
+		if err := json.Unmarshal([]byte(definition), &rule); err != nil {
+			return nil, fmt.Errorf("failed to decode alert rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// DeleteRule 删除告警规则及其触发状态
+func (m *Manager) DeleteRule(ctx context.Context, id string) error {
+	result, err := m.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM alert_states WHERE rule_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete alert states: %w", err)
+	}
+	return nil
+}
+
+// CreateSilence 创建静默窗口
+func (m *Manager) CreateSilence(ctx context.Context, silence *Silence) error {
+	now := time.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+	silence.ID = fmt.Sprintf("silence_%d", now.UnixNano())
+	silence.CreatedAt = now
+
+	definition, err := json.Marshal(silence)
+	if err != nil {
+		return fmt.Errorf("failed to encode silence: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `INSERT INTO alert_silences (id, definition, ends_at) VALUES (?, ?, ?)`,
+		silence.ID, string(definition), silence.EndsAt)
+	if err != nil {
+		return fmt.Errorf("failed to create silence: %w", err)
+	}
+	return nil
+}
+
+// ListSilences 列出尚未结束的静默窗口
+func (m *Manager) ListSilences(ctx context.Context, now time.Time) ([]Silence, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT definition FROM alert_silences WHERE ends_at > ? ORDER BY ends_at`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list silences: %w", err)
+	}
+	defer rows.Close()
+
+	silences := []Silence{}
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to scan silence: %w", err)
+		}
+		var silence Silence
+		if err := json.Unmarshal([]byte(definition), &silence); err != nil {
+			return nil, fmt.Errorf("failed to decode silence: %w", err)
+		}
+		silences = append(silences, silence)
+	}
+	return silences, rows.Err()
+}
+
+// DeleteSilence 提前结束静默窗口
+func (m *Manager) DeleteSilence(ctx context.Context, id string) error {
+	result, err := m.db.ExecContext(ctx, `DELETE FROM alert_silences WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete silence: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// listStates 返回所有触发状态，键为 规则ID|租户ID
+func (m *Manager) listStates(ctx context.Context) (map[string]*alertState, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT rule_id, tenant_id, firing, notified_at FROM alert_states`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert states: %w", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]*alertState)
+	for rows.Next() {
+		var state alertState
+		var notifiedAt sql.NullTime
+		if err := rows.Scan(&state.RuleID, &state.TenantID, &state.Firing, &notifiedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert state: %w", err)
+		}
+		state.NotifiedAt = notifiedAt.Time
+		states[stateKey(state.RuleID, state.TenantID)] = &state
+	}
+	return states, rows.Err()
+}
+
+// saveState 保存触发状态
+func (m *Manager) saveState(ctx context.Context, state *alertState) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO alert_states (rule_id, tenant_id, firing, notified_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(rule_id, tenant_id) DO UPDATE SET firing = excluded.firing, notified_at = excluded.notified_at`,
+		state.RuleID, state.TenantID, state.Firing, state.NotifiedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save alert state: %w", err)
+	}
+	return nil
+}
+
+func stateKey(ruleID, tenantID string) string {
+	return ruleID + "|" + tenantID
+}
+
+// SaveNotification 保存通知
+func (m *Manager) SaveNotification(ctx context.Context, notification *Notification) error {
+	data, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO alert_notifications (id, tenant_id, notification, read_at, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET notification = excluded.notification, read_at = excluded.read_at`,
+		notification.ID, notification.TenantID, string(data), notification.ReadAt, notification.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save notification: %w", err)
+	}
+	return nil
+}
+
+// NotificationFilter 通知查询条件
+type NotificationFilter struct {
+	TenantID   string
+	UnreadOnly bool
+	Limit      int
+}
+
+// ListNotifications 按时间倒序列出通知
+func (m *Manager) ListNotifications(ctx context.Context, filter NotificationFilter) ([]Notification, error) {
+	query := `SELECT notification FROM alert_notifications WHERE 1 = 1`
+	var args []interface{}
+	if filter.TenantID != "" {
+		query += ` AND tenant_id = ?`
+		args = append(args, filter.TenantID)
+	}
+	if filter.UnreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		var notification Notification
+		if err := json.Unmarshal([]byte(data), &notification); err != nil {
+			return nil, fmt.Errorf("failed to decode notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// CountUnread 统计未读通知
+func (m *Manager) CountUnread(ctx context.Context, tenantID string) (int, error) {
+	query := `SELECT COUNT(*) FROM alert_notifications WHERE read_at IS NULL`
+	var args []interface{}
+	if tenantID != "" {
+		query += ` AND tenant_id = ?`
+		args = append(args, tenantID)
+	}
+	var count int
+	if err := m.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRead 将通知标记为已读，ids 为空时标记所有未读通知
+func (m *Manager) MarkRead(ctx context.Context, ids []string, userID string) (int, error) {
+	query := `SELECT notification FROM alert_notifications WHERE read_at IS NULL`
+	var args []interface{}
+	if len(ids) > 0 {
+		query += ` AND id IN (?` + repeatPlaceholders(len(ids)-1) + `)`
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unread notifications: %w", err)
+	}
+	var unread []Notification
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		var notification Notification
+		if err := json.Unmarshal([]byte(data), &notification); err == nil {
+			unread = append(unread, notification)
+		}
+	}
+	rows.Close()
+
+	now := time.Now()
+	for i := range unread {
+		unread[i].ReadAt = &now
+		unread[i].ReadBy = userID
+		if err := m.SaveNotification(ctx, &unread[i]); err != nil {
+			return 0, err
+		}
+	}
+	return len(unread), nil
+}
+
+func repeatPlaceholders(n int) string {
+	placeholders := ""
+	for i := 0; i < n; i++ {
+		placeholders += ", ?"
+	}
+	return placeholders
+}
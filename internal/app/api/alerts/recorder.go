@@ -0,0 +1,144 @@
+package alerts
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// bucketSize 计数桶的时间粒度
+const bucketSize = 10 * time.Second
+
+// windowCounter 按时间桶计数，只保留统计窗口内的桶
+type windowCounter struct {
+	buckets map[int64]int
+}
+
+func (c *windowCounter) add(now time.Time) {
+	c.buckets[now.UnixNano()/int64(bucketSize)]++
+}
+
+// sum 返回窗口内的计数并丢弃过期的桶
+func (c *windowCounter) sum(now time.Time, window time.Duration) int {
+	oldest := now.Add(-window).UnixNano() / int64(bucketSize)
+	total := 0
+	for bucket, count := range c.buckets {
+		if bucket <= oldest {
+			delete(c.buckets, bucket)
+			continue
+		}
+		total += count
+	}
+	return total
+}
+
+// Recorder 在内存中统计请求、登录失败和任务失败，供告警引擎读取。
+// 计数只覆盖本实例，多实例部署时规则阈值按单实例流量设置。
+type Recorder struct {
+	mu       sync.Mutex
+	window   time.Duration
+	counters map[Sample]*windowCounter // Value 不用，Metric 与 TenantID 作为键
+	now      func() time.Time
+}
+
+// 内部计数键
+const (
+	counterRequests     = "requests"
+	counterServerErrors = "server_errors"
+)
+
+// NewRecorder 创建统计窗口为 window 的记录器
+func NewRecorder(window time.Duration) *Recorder {
+	if window <= 0 {
+		window = DefaultConfig().Window
+	}
+	return &Recorder{
+		window:   window,
+		counters: make(map[Sample]*windowCounter),
+		now:      time.Now,
+	}
+}
+
+func (r *Recorder) add(metric, tenantID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := Sample{Metric: metric, TenantID: tenantID}
+	counter, ok := r.counters[key]
+	if !ok {
+		counter = &windowCounter{buckets: make(map[int64]int)}
+		r.counters[key] = counter
+	}
+	counter.add(r.now())
+}
+
+// RecordRequest 记录一个 HTTP 响应
+func (r *Recorder) RecordRequest(status int) {
+	r.add(counterRequests, "")
+	if status >= 500 {
+		r.add(counterServerErrors, "")
+	}
+}
+
+// RecordFailedLogin 记录一次登录失败
+func (r *Recorder) RecordFailedLogin() {
+	r.add(MetricFailedLogins, "")
+}
+
+// RecordJobFailure 记录租户的一次后台任务失败，租户未知时为空
+func (r *Recorder) RecordJobFailure(tenantID string) {
+	r.add(MetricJobFailures, tenantID)
+}
+
+// Samples 返回窗口内各指标的当前值。请求数不足 minRequests 时不计算错误率。
+func (r *Recorder) Samples(minRequests int) []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	counts := make(map[Sample]int, len(r.counters))
+	for key, counter := range r.counters {
+		if count := counter.sum(now, r.window); count > 0 {
+			counts[key] = count
+		} else {
+			delete(r.counters, key)
+		}
+	}
+
+	// 失败次数为 0 时也给出样本，已触发的告警才能恢复
+	samples := []Sample{{Metric: MetricFailedLogins, Value: float64(counts[Sample{Metric: MetricFailedLogins}])}}
+	if requests := counts[Sample{Metric: counterRequests}]; requests > 0 && requests >= minRequests {
+		errors := counts[Sample{Metric: counterServerErrors}]
+		samples = append(samples, Sample{Metric: MetricErrorRate, Value: float64(errors) / float64(requests)})
+	}
+	for key, count := range counts {
+		if key.Metric == MetricJobFailures {
+			samples = append(samples, Sample{Metric: MetricJobFailures, TenantID: key.TenantID, Value: float64(count)})
+		}
+	}
+	return samples
+}
+
+// Middleware 记录每个响应的状态码，loginPaths 返回 400、401、403 时计为登录失败
+func (r *Recorder) Middleware(loginPaths ...string) func(http.Handler) http.Handler {
+	logins := make(map[string]bool, len(loginPaths))
+	for _, path := range loginPaths {
+		logins[path] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+			next.ServeHTTP(ww, req)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			r.RecordRequest(status)
+			if logins[req.URL.Path] && (status == http.StatusBadRequest || status == http.StatusUnauthorized || status == http.StatusForbidden) {
+				r.RecordFailedLogin()
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package alerts
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+	"github.com/guileen/metabase/pkg/infra/mailer"
+)
+
+// 内置指标
+const (
+	MetricErrorRate     = "error_rate"     // 窗口内 5xx 响应占比
+	MetricFailedLogins  = "failed_logins"  // 窗口内登录失败次数
+	MetricJobFailures   = "job_failures"   // 窗口内失败的同步、导入任务数，按租户
+	MetricBudgetUsage   = "budget_usage"   // 本计费周期LLM预算已用比例，按租户
+	MetricAuthAnomalies = "auth_anomalies" // 窗口内检测到的认证和 API 密钥异常数，系统级及按租户
+)
+
+// Metrics 可用于告警规则的指标
+var Metrics = []string{MetricErrorRate, MetricFailedLogins, MetricJobFailures, MetricBudgetUsage, MetricAuthAnomalies}
+
+// 比较运算符
+const (
+	OperatorGreater      = ">"
+	OperatorGreaterEqual = ">="
+	OperatorLess         = "<"
+	OperatorLessEqual    = "<="
+)
+
+// 告警级别
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// 通知渠道
+const (
+	ChannelEmail   = "email"
+	ChannelSlack   = "slack"
+	ChannelDiscord = "discord"
+	ChannelWebhook = "webhook"
+)
+
+// 通知状态
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Rule 告警规则：指标与阈值比较成立时触发
+type Rule struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Metric    string   `json:"metric"`
+	Operator  string   `json:"operator"`
+	Threshold float64  `json:"threshold"`
+	Severity  string   `json:"severity"`
+	TenantID  string   `json:"tenant_id,omitempty"` // 为空时对所有租户及系统级指标生效
+	Channels  []string `json:"channels,omitempty"`  // 为空时使用所有已配置渠道
+	Enabled   bool     `json:"enabled"`
+
+	// 持续触发时重复通知的间隔，0 使用默认值
+	RepeatIntervalSeconds int `json:"repeat_interval_seconds,omitempty"`
+
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate 校验规则
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	known := false
+	for _, metric := range Metrics {
+		known = known || metric == r.Metric
+	}
+	if !known {
+		return fmt.Errorf("unknown metric %q", r.Metric)
+	}
+	switch r.Operator {
+	case OperatorGreater, OperatorGreaterEqual, OperatorLess, OperatorLessEqual:
+	default:
+		return fmt.Errorf("invalid operator %q", r.Operator)
+	}
+	switch r.Severity {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("invalid severity %q", r.Severity)
+	}
+	for _, channel := range r.Channels {
+		switch channel {
+		case ChannelEmail, ChannelSlack, ChannelDiscord, ChannelWebhook:
+		default:
+			return fmt.Errorf("invalid channel %q", channel)
+		}
+	}
+	if r.RepeatIntervalSeconds < 0 {
+		return fmt.Errorf("repeat_interval_seconds must not be negative")
+	}
+	return nil
+}
+
+// matches 判断指标值是否触发规则
+func (r *Rule) matches(value float64) bool {
+	switch r.Operator {
+	case OperatorGreater:
+		return value > r.Threshold
+	case OperatorGreaterEqual:
+		return value >= r.Threshold
+	case OperatorLess:
+		return value < r.Threshold
+	case OperatorLessEqual:
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// Silence 静默窗口：窗口内匹配的告警不发送通知
+type Silence struct {
+	ID        string    `json:"id"`
+	RuleID    string    `json:"rule_id,omitempty"`   // 为空时匹配所有规则
+	TenantID  string    `json:"tenant_id,omitempty"` // 为空时匹配所有租户
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate 校验静默窗口
+func (s *Silence) Validate() error {
+	if s.EndsAt.IsZero() || !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// covers 判断静默窗口在 now 是否覆盖规则和租户
+func (s *Silence) covers(ruleID, tenantID string, now time.Time) bool {
+	if now.Before(s.StartsAt) || !now.Before(s.EndsAt) {
+		return false
+	}
+	return (s.RuleID == "" || s.RuleID == ruleID) && (s.TenantID == "" || s.TenantID == tenantID)
+}
+
+// Notification 通知中心中的一条告警通知
+type Notification struct {
+	ID        string     `json:"id"`
+	RuleID    string     `json:"rule_id"`
+	RuleName  string     `json:"rule_name"`
+	TenantID  string     `json:"tenant_id,omitempty"`
+	Metric    string     `json:"metric"`
+	Value     float64    `json:"value"`
+	Threshold float64    `json:"threshold"`
+	Severity  string     `json:"severity"`
+	Status    string     `json:"status"` // firing 或 resolved
+	Message   string     `json:"message"`
+	Channels  []string   `json:"channels,omitempty"` // 成功发送的渠道
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	ReadBy    string     `json:"read_by,omitempty"`
+}
+
+// Sample 一个指标在某租户下的当前值，TenantID 为空表示系统级
+type Sample struct {
+	Metric   string  `json:"metric"`
+	TenantID string  `json:"tenant_id,omitempty"`
+	Value    float64 `json:"value"`
+}
+
+// Config 告警引擎配置
+type Config struct {
+	Interval       time.Duration `json:"interval"`        // 评估间隔
+	Window         time.Duration `json:"window"`          // 错误率、失败次数的统计窗口
+	RepeatInterval time.Duration `json:"repeat_interval"` // 持续触发时重复通知的默认间隔
+	MinRequests    int           `json:"min_requests"`    // 计算错误率所需的最少请求数
+
+	// 系统级告警及未配置通知的租户使用的通知设置
+	Notifications *tenant.NotificationSettings `json:"notifications,omitempty"`
+
+	// 邮件发送
+	Mail *mailer.Config `json:"mail,omitempty"`
+}
+
+// DefaultConfig 默认告警配置
+func DefaultConfig() *Config {
+	return &Config{
+		Interval:       time.Minute,
+		Window:         5 * time.Minute,
+		RepeatInterval: time.Hour,
+		MinRequests:    20,
+	}
+}
+
+// ConfigFromEnv 从环境变量读取系统级通知目标，邮件服务器使用 METABASE_SMTP_*
+func ConfigFromEnv() *Config {
+	cfg := DefaultConfig()
+	targets := &tenant.NotificationSettings{
+		Slack:   os.Getenv("METABASE_ALERT_SLACK_WEBHOOK"),
+		Discord: os.Getenv("METABASE_ALERT_DISCORD_WEBHOOK"),
+		Webhook: os.Getenv("METABASE_ALERT_WEBHOOK_URL"),
+	}
+	if to := os.Getenv("METABASE_ALERT_EMAIL_TO"); to != "" {
+		targets.Email = true
+		targets.EmailTo = splitList(to)
+	}
+	cfg.Notifications = targets
+	cfg.Mail = mailer.ConfigFromEnv()
+	return cfg
+}
+
+// splitList 拆分逗号分隔的列表
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
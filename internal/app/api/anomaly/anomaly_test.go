@@ -0,0 +1,153 @@
+package anomaly
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+func newTestDetector(t *testing.T, config *Config) *Detector {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "anomaly.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	detector := NewDetector(db, config, zap.NewNop())
+	if err := detector.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return detector
+}
+
+func kinds(anomalies []*Anomaly) map[string]int {
+	found := make(map[string]int)
+	for _, a := range anomalies {
+		found[a.Kind]++
+	}
+	return found
+}
+
+func TestImpossibleTravelRequiresStepUp(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.StepUp = true
+	config.GeoRanges = []GeoRange{
+		{CIDR: "10.0.0.0/8", Country: "us", Lat: 40.7, Lon: -74.0},
+		{CIDR: "10.20.0.0/16", Country: "US", Lat: 40.8, Lon: -73.9},
+		{CIDR: "11.0.0.0/8", Country: "DE", Lat: 52.5, Lon: 13.4},
+	}
+	detector := newTestDetector(t, config)
+	start := time.Now().Add(-2 * time.Hour)
+	login := func(ip, agent string, at time.Time) []*Anomaly {
+		found, err := detector.ObserveLogin(ctx, LoginEvent{UserID: "u1", Username: "u1@example.com", TenantID: "t1",
+			IP: ip, UserAgent: agent, Success: true, Time: at})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return found
+	}
+
+	if found := login("10.1.1.1", "firefox", start); len(found) != 0 {
+		t.Fatalf("first login should not be anomalous, got %+v", found)
+	}
+	// A nearby address later the same day is normal
+	if found := login("10.20.1.1", "firefox", start.Add(30*time.Minute)); len(found) != 0 {
+		t.Fatalf("expected no anomalies, got %+v", found)
+	}
+	found := login("11.1.1.1", "chrome", start.Add(time.Hour))
+	if got := kinds(found); got[KindImpossibleTravel] != 1 || got[KindNewCountry] != 1 || got[KindNewDevice] != 1 {
+		t.Fatalf("expected travel, country and device anomalies, got %+v", got)
+	}
+
+	handler := detector.StepUpMiddleware(func(r *http.Request) string { return r.Header.Get("X-User") }, "/auth/")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func(path, user string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := request("/admin/v1/tenants", "u1"); code != http.StatusUnauthorized {
+		t.Fatalf("expected step-up to be required, got %d", code)
+	}
+	if code := request("/auth/login", "u1"); code != http.StatusOK {
+		t.Fatalf("expected auth paths to be exempt, got %d", code)
+	}
+	if code := request("/admin/v1/tenants", "u2"); code != http.StatusOK {
+		t.Fatalf("expected other users to pass, got %d", code)
+	}
+
+	if err := detector.CompleteStepUp(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if code := request("/admin/v1/tenants", "u1"); code != http.StatusOK {
+		t.Fatalf("expected step-up to be cleared, got %d", code)
+	}
+
+	listed, err := detector.List(ctx, Filter{UserID: "u1", Kind: KindImpossibleTravel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || !listed[0].StepUp || listed[0].Details["from_country"] != "US" {
+		t.Fatalf("unexpected anomalies %+v", listed)
+	}
+}
+
+func TestFailedLoginSpike(t *testing.T) {
+	ctx := context.Background()
+	detector := newTestDetector(t, nil)
+
+	var found []*Anomaly
+	for i := 0; i < 12; i++ {
+		anomalies, err := detector.ObserveLogin(ctx, LoginEvent{Username: "admin@example.com", IP: "203.0.113.9"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		found = append(found, anomalies...)
+	}
+	// Address, account and global spike are each reported once per window
+	if len(found) != 3 || kinds(found)[KindFailedLogins] != 3 {
+		t.Fatalf("expected three failed login anomalies, got %+v", found)
+	}
+
+	samples, err := detector.Samples(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Value != 3 || samples[0].TenantID != "" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+}
+
+func TestKeyRateSpike(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.KeyMinRequests = 50
+	detector := newTestDetector(t, config)
+
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Minute)
+	at := start
+	for ; at.Before(start.Add(2 * time.Hour)); at = at.Add(time.Minute) {
+		if a := detector.ObserveKey(ctx, KeyEvent{KeyID: "k1", TenantID: "t1", Time: at}); a != nil {
+			t.Fatalf("steady usage should not be anomalous, got %+v", a)
+		}
+	}
+	var found []*Anomaly
+	for i := 0; i < 200; i++ {
+		if a := detector.ObserveKey(ctx, KeyEvent{KeyID: "k1", TenantID: "t1", Time: at}); a != nil {
+			found = append(found, a)
+		}
+	}
+	if len(found) != 1 || found[0].Kind != KindAPIKeyUsage || found[0].Details["reason"] != "rate_spike" {
+		t.Fatalf("expected one rate spike, got %+v", found)
+	}
+}
@@ -0,0 +1,692 @@
+package anomaly
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/app/api/alerts"
+	"github.com/guileen/metabase/pkg/infra/kv"
+)
+
+// maxListLimit 单次查询异常的上限
+const maxListLimit = 500
+
+// Detector 根据登录记录和 API 密钥调用检测异常。登录记录写入 auth_login_attempts（认证网关的
+// AuthStats 也从这里统计），与已知设备和国家一起由所有实例共享；密钥调用量在各实例内存中统计
+type Detector struct {
+	db     *sql.DB
+	config *Config
+	geo    GeoLocator
+	logger *zap.Logger
+
+	mu    sync.Mutex
+	keys  map[string]*keyUsage
+	cache kv.Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// keyUsage 一个 API 密钥在本实例的调用统计
+type keyUsage struct {
+	firstSeen time.Time
+	buckets   map[int64]int // 每分钟调用数
+	total     int           // 基线时长内的调用总数
+	minute    int64         // 最近一次清理旧分桶的分钟
+	raised    time.Time     // 最近一次报告调用突增的时间
+	countries map[string]bool
+}
+
+// NewDetector 创建异常检测器，config 配置了地址段时检测地理异常
+func NewDetector(db *sql.DB, config *Config, logger *zap.Logger) *Detector {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	d := &Detector{
+		db:     db,
+		config: config,
+		logger: logger,
+		keys:   make(map[string]*keyUsage),
+		cache:  kv.NewMemoryStore(10000),
+		ttl:    30 * time.Second,
+		now:    time.Now,
+	}
+	if len(config.GeoRanges) > 0 {
+		locator, err := NewCIDRLocator(config.GeoRanges)
+		if err != nil {
+			logger.Error("failed to load geo ranges", zap.Error(err))
+		} else {
+			d.geo = locator
+		}
+	}
+	return d
+}
+
+// SetGeoLocator 替换地理位置查询，如接入 GeoIP 数据库
+func (d *Detector) SetGeoLocator(geo GeoLocator) {
+	d.geo = geo
+}
+
+// SetCacheStore 将多因素认证要求的缓存移到 store，多实例共享时解除立即生效
+func (d *Detector) SetCacheStore(store kv.Store) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.cache = store
+}
+
+func (d *Detector) store() kv.Store {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cache
+}
+
+// Initialize 初始化数据库表
+func (d *Detector) Initialize(ctx context.Context) error {
+	_, err := d.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS auth_login_attempts (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL DEFAULT '',
+		username TEXT NOT NULL DEFAULT '',
+		tenant_id TEXT NOT NULL DEFAULT '',
+		ip_address TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		reason TEXT NOT NULL DEFAULT '',
+		country TEXT NOT NULL DEFAULT '',
+		lat REAL NOT NULL DEFAULT 0,
+		lon REAL NOT NULL DEFAULT 0,
+		located BOOLEAN NOT NULL DEFAULT FALSE,
+		device TEXT NOT NULL DEFAULT '',
+		success BOOLEAN NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_auth_login_attempts_user ON auth_login_attempts(user_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_auth_login_attempts_ip ON auth_login_attempts(ip_address, created_at);
+	CREATE INDEX IF NOT EXISTS idx_auth_login_attempts_username ON auth_login_attempts(username, created_at);
+	CREATE INDEX IF NOT EXISTS idx_auth_login_attempts_time ON auth_login_attempts(success, created_at);
+
+	CREATE TABLE IF NOT EXISTS auth_known_values (
+		subject TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		value TEXT NOT NULL,
+		first_seen TIMESTAMP NOT NULL,
+		PRIMARY KEY (subject, kind, value)
+	);
+
+	CREATE TABLE IF NOT EXISTS auth_anomalies (
+		id TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		severity TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id TEXT NOT NULL DEFAULT '',
+		key_id TEXT NOT NULL DEFAULT '',
+		tenant_id TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		country TEXT NOT NULL DEFAULT '',
+		details TEXT NOT NULL DEFAULT '{}',
+		step_up BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_auth_anomalies_subject ON auth_anomalies(kind, subject, created_at);
+	CREATE INDEX IF NOT EXISTS idx_auth_anomalies_time ON auth_anomalies(created_at);
+
+	CREATE TABLE IF NOT EXISTS auth_step_up (
+		user_id TEXT PRIMARY KEY,
+		reason TEXT NOT NULL,
+		anomaly_id TEXT NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	);
+	`)
+	if err != nil {
+		d.logger.Error("failed to initialize anomaly tables", zap.Error(err))
+		return fmt.Errorf("failed to initialize anomaly tables: %w", err)
+	}
+	return nil
+}
+
+// ObserveLogin 记录一次登录尝试并返回检测到的异常。失败的登录检查失败次数突增，
+// 成功的登录检查异地登录、新设备和新国家
+func (d *Detector) ObserveLogin(ctx context.Context, event LoginEvent) ([]*Anomaly, error) {
+	if event.Time.IsZero() {
+		event.Time = d.now()
+	}
+	event.Time = event.Time.UTC()
+	location, located := d.locate(event.IP)
+	device := deviceID(event.UserAgent)
+
+	id := uuid.New().String()
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO auth_login_attempts (id, user_id, username, tenant_id, ip_address, user_agent, reason, country, lat, lon, located, device, success, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, event.UserID, event.Username, event.TenantID, event.IP, event.UserAgent, event.Reason,
+		location.Country, location.Lat, location.Lon, located, device, event.Success, event.Time,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+
+	var found []*Anomaly
+	if !event.Success {
+		found, err = d.checkFailures(ctx, event)
+	} else if event.UserID != "" {
+		found, err = d.checkLogin(ctx, id, event, location, located, device)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range found {
+		if err := d.record(ctx, a); err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// checkFailures 检查同一地址、同一账号和全局的登录失败次数
+func (d *Detector) checkFailures(ctx context.Context, event LoginEvent) ([]*Anomaly, error) {
+	windowStart := event.Time.Add(-d.config.FailedLoginWindow)
+	threshold := d.config.FailedLoginThreshold
+
+	var found []*Anomaly
+	scopes := []struct {
+		column, value, subject string
+	}{
+		{"ip_address", event.IP, "ip:" + event.IP},
+		{"username", event.Username, "account:" + event.Username},
+	}
+	for _, scope := range scopes {
+		if scope.value == "" {
+			continue
+		}
+		var count int
+		err := d.db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM auth_login_attempts WHERE success = FALSE AND `+scope.column+` = ? AND created_at >= ?`,
+			scope.value, windowStart).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count failed logins: %w", err)
+		}
+		if count < threshold {
+			continue
+		}
+		if raised, err := d.recentlyRaised(ctx, KindFailedLogins, scope.subject, windowStart); err != nil || raised {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		found = append(found, &Anomaly{
+			Kind:     KindFailedLogins,
+			Severity: SeverityWarning,
+			Subject:  scope.subject,
+			TenantID: event.TenantID,
+			IP:       event.IP,
+			Details:  map[string]interface{}{"failures": count, "window_seconds": int(d.config.FailedLoginWindow.Seconds())},
+		})
+	}
+
+	// 全局失败数与基线时长内的平均水平比较
+	var current, baseline int
+	baselineStart := event.Time.Add(-d.config.BaselinePeriod)
+	err := d.db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN created_at < ? THEN 1 ELSE 0 END), 0)
+		FROM auth_login_attempts WHERE success = FALSE AND created_at >= ?`,
+		windowStart, windowStart, baselineStart).Scan(&current, &baseline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed logins: %w", err)
+	}
+	windows := float64(d.config.BaselinePeriod-d.config.FailedLoginWindow) / float64(d.config.FailedLoginWindow)
+	average := float64(baseline) / windows
+	if current >= threshold && float64(current) > d.config.FailedLoginFactor*average {
+		raised, err := d.recentlyRaised(ctx, KindFailedLogins, "global", windowStart)
+		if err != nil {
+			return nil, err
+		}
+		if !raised {
+			found = append(found, &Anomaly{
+				Kind:     KindFailedLogins,
+				Severity: SeverityCritical,
+				Subject:  "global",
+				Details: map[string]interface{}{
+					"failures":         current,
+					"baseline_average": average,
+					"window_seconds":   int(d.config.FailedLoginWindow.Seconds()),
+				},
+			})
+		}
+	}
+	return found, nil
+}
+
+// checkLogin 检查成功登录的地点和设备
+func (d *Detector) checkLogin(ctx context.Context, id string, event LoginEvent, location Location, located bool, device string) ([]*Anomaly, error) {
+	subject := "user:" + event.UserID
+	newAnomaly := func(kind, severity string, stepUp bool, details map[string]interface{}) *Anomaly {
+		return &Anomaly{
+			Kind: kind, Severity: severity, Subject: subject, UserID: event.UserID, TenantID: event.TenantID,
+			IP: event.IP, Country: location.Country, StepUp: stepUp, Details: details,
+		}
+	}
+
+	var found []*Anomaly
+	if located {
+		var previous Location
+		var at time.Time
+		err := d.db.QueryRowContext(ctx, `
+			SELECT country, lat, lon, created_at FROM auth_login_attempts
+			WHERE user_id = ? AND success = TRUE AND located = TRUE AND id != ? AND created_at <= ?
+			ORDER BY created_at DESC LIMIT 1`,
+			event.UserID, id, event.Time).Scan(&previous.Country, &previous.Lat, &previous.Lon, &at)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to get previous login: %w", err)
+		}
+		if err == nil {
+			distance := distanceKm(previous, location)
+			hours := event.Time.Sub(at).Hours()
+			if distance >= d.config.MinTravelKm && (hours <= 0 || distance/hours > d.config.MaxTravelSpeedKmh) {
+				found = append(found, newAnomaly(KindImpossibleTravel, SeverityCritical, true, map[string]interface{}{
+					"from_country": previous.Country,
+					"distance_km":  int(distance),
+					"hours":        hours,
+				}))
+			}
+		}
+	}
+
+	if device != "" {
+		isNew, err := d.learn(ctx, subject, "device", device, event.Time)
+		if err != nil {
+			return nil, err
+		}
+		if isNew {
+			found = append(found, newAnomaly(KindNewDevice, SeverityInfo, false, map[string]interface{}{"device": device}))
+		}
+	}
+	if location.Country != "" {
+		isNew, err := d.learn(ctx, subject, "country", location.Country, event.Time)
+		if err != nil {
+			return nil, err
+		}
+		if isNew {
+			found = append(found, newAnomaly(KindNewCountry, SeverityWarning, true, nil))
+		}
+	}
+	return found, nil
+}
+
+// learn 记住 subject 的一个设备或国家。只有已有同类记录时，新的值才算异常，首次登录不报告
+func (d *Detector) learn(ctx context.Context, subject, kind, value string, at time.Time) (bool, error) {
+	var known, seen int
+	err := d.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN value = ? THEN 1 ELSE 0 END), 0)
+		FROM auth_known_values WHERE subject = ? AND kind = ?`,
+		value, subject, kind).Scan(&known, &seen)
+	if err != nil {
+		return false, fmt.Errorf("failed to get known %s: %w", kind, err)
+	}
+	if seen > 0 {
+		return false, nil
+	}
+	_, err = d.db.ExecContext(ctx, `
+		INSERT INTO auth_known_values (subject, kind, value, first_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT (subject, kind, value) DO NOTHING`,
+		subject, kind, value, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to save known %s: %w", kind, err)
+	}
+	return known > 0, nil
+}
+
+// ObserveKey 记录一次 API 密钥调用，调用量突增或来自新的国家时记录异常。
+// 只在异常时访问数据库，可在每个请求中调用
+func (d *Detector) ObserveKey(ctx context.Context, event KeyEvent) *Anomaly {
+	if event.Time.IsZero() {
+		event.Time = d.now()
+	}
+	location, located := d.locate(event.IP)
+
+	d.mu.Lock()
+	usage, ok := d.keys[event.KeyID]
+	if !ok {
+		usage = &keyUsage{firstSeen: event.Time, buckets: make(map[int64]int)}
+		d.keys[event.KeyID] = usage
+	}
+	spike := d.countKey(usage, event.Time)
+	loadCountries := located && usage.countries == nil
+	d.mu.Unlock()
+
+	subject := "key:" + event.KeyID
+	if spike != nil {
+		a := &Anomaly{
+			Kind: KindAPIKeyUsage, Severity: SeverityWarning, Subject: subject, KeyID: event.KeyID,
+			TenantID: event.TenantID, IP: event.IP, Country: location.Country, Details: spike,
+		}
+		d.recordLogged(ctx, a)
+		return a
+	}
+	if !located || location.Country == "" {
+		return nil
+	}
+
+	if loadCountries {
+		countries, err := d.knownValues(ctx, subject, "country")
+		if err != nil {
+			d.logger.Warn("failed to load key countries", zap.String("key_id", event.KeyID), zap.Error(err))
+			return nil
+		}
+		d.mu.Lock()
+		if usage.countries == nil {
+			usage.countries = countries
+		}
+		d.mu.Unlock()
+	}
+	d.mu.Lock()
+	known := usage.countries[location.Country]
+	if !known {
+		usage.countries[location.Country] = true
+	}
+	d.mu.Unlock()
+	if known {
+		return nil
+	}
+
+	isNew, err := d.learn(ctx, subject, "country", location.Country, event.Time)
+	if err != nil {
+		d.logger.Warn("failed to save key country", zap.String("key_id", event.KeyID), zap.Error(err))
+		return nil
+	}
+	if !isNew {
+		return nil
+	}
+	a := &Anomaly{
+		Kind: KindAPIKeyUsage, Severity: SeverityWarning, Subject: subject, KeyID: event.KeyID,
+		TenantID: event.TenantID, IP: event.IP, Country: location.Country,
+		Details: map[string]interface{}{"reason": "new_country"},
+	}
+	d.recordLogged(ctx, a)
+	return a
+}
+
+// countKey 计入一次调用，窗口内调用数超过基线平均的倍数时返回突增详情。调用方持有 mu
+func (d *Detector) countKey(usage *keyUsage, at time.Time) map[string]interface{} {
+	minute := at.Unix() / 60
+	if minute != usage.minute {
+		oldest := at.Add(-d.config.BaselinePeriod).Unix() / 60
+		for bucket, count := range usage.buckets {
+			if bucket < oldest {
+				usage.total -= count
+				delete(usage.buckets, bucket)
+			}
+		}
+		usage.minute = minute
+	}
+	usage.buckets[minute]++
+	usage.total++
+
+	windowStart := at.Add(-d.config.KeyWindow).Unix() / 60
+	current := 0
+	for bucket := windowStart + 1; bucket <= minute; bucket++ {
+		current += usage.buckets[bucket]
+	}
+	if current < d.config.KeyMinRequests || at.Sub(usage.raised) < d.config.KeyWindow {
+		return nil
+	}
+	// 观察时间太短时没有可靠的基线
+	observed := at.Sub(usage.firstSeen)
+	if observed > d.config.BaselinePeriod {
+		observed = d.config.BaselinePeriod
+	}
+	if observed < 12*d.config.KeyWindow {
+		return nil
+	}
+	average := float64(usage.total-current) / (float64(observed-d.config.KeyWindow) / float64(d.config.KeyWindow))
+	if float64(current) <= d.config.KeyRateFactor*average {
+		return nil
+	}
+	usage.raised = at
+	return map[string]interface{}{
+		"reason":           "rate_spike",
+		"requests":         current,
+		"baseline_average": average,
+		"window_seconds":   int(d.config.KeyWindow.Seconds()),
+	}
+}
+
+func (d *Detector) knownValues(ctx context.Context, subject, kind string) (map[string]bool, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT value FROM auth_known_values WHERE subject = ? AND kind = ?`, subject, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make(map[string]bool)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values[value] = true
+	}
+	return values, rows.Err()
+}
+
+func (d *Detector) locate(ip string) (Location, bool) {
+	if d.geo == nil || ip == "" {
+		return Location{}, false
+	}
+	return d.geo.Locate(ip)
+}
+
+// recentlyRaised 判断窗口内是否已报告过同一对象的同类异常
+func (d *Detector) recentlyRaised(ctx context.Context, kind, subject string, since time.Time) (bool, error) {
+	var exists bool
+	err := d.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM auth_anomalies WHERE kind = ? AND subject = ? AND created_at >= ?)`,
+		kind, subject, since).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check recent anomalies: %w", err)
+	}
+	return exists, nil
+}
+
+// record 保存异常，需要时要求用户重新进行多因素认证
+func (d *Detector) record(ctx context.Context, a *Anomaly) error {
+	a.ID = "anm_" + uuid.New().String()
+	a.CreatedAt = d.now().UTC()
+	a.StepUp = a.StepUp && d.config.StepUp && a.UserID != ""
+	details, err := json.Marshal(a.Details)
+	if err != nil {
+		return fmt.Errorf("failed to encode anomaly details: %w", err)
+	}
+	_, err = d.db.ExecContext(ctx, `
+		INSERT INTO auth_anomalies (id, kind, severity, subject, user_id, key_id, tenant_id, ip, country, details, step_up, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, a.Kind, a.Severity, a.Subject, a.UserID, a.KeyID, a.TenantID, a.IP, a.Country, string(details), a.StepUp, a.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save anomaly: %w", err)
+	}
+	d.logger.Warn("authentication anomaly detected",
+		zap.String("kind", a.Kind), zap.String("subject", a.Subject), zap.String("ip", a.IP), zap.String("country", a.Country))
+
+	if a.StepUp {
+		_, err = d.db.ExecContext(ctx, `
+			INSERT INTO auth_step_up (user_id, reason, anomaly_id, expires_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (user_id) DO UPDATE SET reason = excluded.reason, anomaly_id = excluded.anomaly_id, expires_at = excluded.expires_at`,
+			a.UserID, a.Kind, a.ID, a.CreatedAt.Add(d.config.StepUpTTL),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to require step-up authentication: %w", err)
+		}
+		d.invalidateStepUp(ctx, a.UserID)
+	}
+	return nil
+}
+
+// recordLogged 保存异常，失败只记录日志，用于请求路径中的检测
+func (d *Detector) recordLogged(ctx context.Context, a *Anomaly) {
+	if err := d.record(ctx, a); err != nil {
+		d.logger.Error("failed to record anomaly", zap.String("kind", a.Kind), zap.Error(err))
+	}
+}
+
+// List 按时间倒序返回异常
+func (d *Detector) List(ctx context.Context, filter Filter) ([]*Anomaly, error) {
+	if filter.Limit <= 0 || filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	query := `SELECT id, kind, severity, subject, user_id, key_id, tenant_id, ip, country, details, step_up, created_at
+		FROM auth_anomalies WHERE created_at >= ?`
+	args := []interface{}{filter.Since.UTC()}
+	if filter.Kind != "" {
+		query += " AND kind = ?"
+		args = append(args, filter.Kind)
+	}
+	if filter.UserID != "" {
+		query += " AND user_id = ?"
+		args = append(args, filter.UserID)
+	}
+	if filter.TenantID != "" {
+		query += " AND tenant_id = ?"
+		args = append(args, filter.TenantID)
+	}
+	query += " ORDER BY created_at DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomalies: %w", err)
+	}
+	defer rows.Close()
+	anomalies := []*Anomaly{}
+	for rows.Next() {
+		var a Anomaly
+		var details string
+		if err := rows.Scan(&a.ID, &a.Kind, &a.Severity, &a.Subject, &a.UserID, &a.KeyID, &a.TenantID,
+			&a.IP, &a.Country, &details, &a.StepUp, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly: %w", err)
+		}
+		if err := json.Unmarshal([]byte(details), &a.Details); err != nil {
+			return nil, fmt.Errorf("invalid anomaly details: %w", err)
+		}
+		anomalies = append(anomalies, &a)
+	}
+	return anomalies, rows.Err()
+}
+
+// Samples 返回最近一个登录失败窗口内的异常数，系统级总数和各租户分别给出，
+// 供告警规则使用 auth_anomalies 指标
+func (d *Detector) Samples(ctx context.Context) ([]alerts.Sample, error) {
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT tenant_id, COUNT(*) FROM auth_anomalies WHERE created_at >= ? GROUP BY tenant_id`,
+		d.now().Add(-d.config.FailedLoginWindow).UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	// 没有异常时也给出系统级样本，已触发的告警才能恢复
+	total := 0
+	var samples []alerts.Sample
+	for rows.Next() {
+		var tenantID string
+		var count int
+		if err := rows.Scan(&tenantID, &count); err != nil {
+			return nil, err
+		}
+		total += count
+		if tenantID != "" {
+			samples = append(samples, alerts.Sample{Metric: alerts.MetricAuthAnomalies, TenantID: tenantID, Value: float64(count)})
+		}
+	}
+	samples = append(samples, alerts.Sample{Metric: alerts.MetricAuthAnomalies, Value: float64(total)})
+	return samples, rows.Err()
+}
+
+// StepUpRequired 返回用户当前的多因素认证要求，没有或已过期时返回 nil
+func (d *Detector) StepUpRequired(ctx context.Context, userID string) (*StepUp, error) {
+	store := d.store()
+	key := stepUpCacheKey(userID)
+	if data, err := store.Get(ctx, key); err == nil {
+		var cached StepUp
+		if err := json.Unmarshal(data, &cached); err == nil {
+			if cached.UserID == "" || !d.now().Before(cached.ExpiresAt) {
+				return nil, nil
+			}
+			return &cached, nil
+		}
+	}
+
+	var stepUp StepUp
+	err := d.db.QueryRowContext(ctx,
+		`SELECT user_id, reason, anomaly_id, expires_at FROM auth_step_up WHERE user_id = ? AND expires_at > ?`,
+		userID, d.now().UTC()).Scan(&stepUp.UserID, &stepUp.Reason, &stepUp.AnomalyID, &stepUp.ExpiresAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get step-up requirement: %w", err)
+	}
+	// 没有要求时缓存空记录
+	if data, err := json.Marshal(stepUp); err == nil {
+		store.Set(ctx, key, data, d.ttl)
+	}
+	if stepUp.UserID == "" {
+		return nil, nil
+	}
+	return &stepUp, nil
+}
+
+// ListStepUps 返回未过期的多因素认证要求
+func (d *Detector) ListStepUps(ctx context.Context) ([]*StepUp, error) {
+	rows, err := d.db.QueryContext(ctx,
+		`SELECT user_id, reason, anomaly_id, expires_at FROM auth_step_up WHERE expires_at > ? ORDER BY expires_at`,
+		d.now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list step-up requirements: %w", err)
+	}
+	defer rows.Close()
+	stepUps := []*StepUp{}
+	for rows.Next() {
+		var s StepUp
+		if err := rows.Scan(&s.UserID, &s.Reason, &s.AnomalyID, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		stepUps = append(stepUps, &s)
+	}
+	return stepUps, rows.Err()
+}
+
+// CompleteStepUp 解除用户的多因素认证要求，由多因素认证流程或管理员调用
+func (d *Detector) CompleteStepUp(ctx context.Context, userID string) error {
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM auth_step_up WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to clear step-up requirement: %w", err)
+	}
+	d.invalidateStepUp(ctx, userID)
+	return nil
+}
+
+func (d *Detector) invalidateStepUp(ctx context.Context, userID string) {
+	if err := d.store().Delete(ctx, stepUpCacheKey(userID)); err != nil {
+		d.logger.Warn("failed to invalidate step-up requirement", zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+func stepUpCacheKey(userID string) string {
+	return "anomaly:stepup:" + userID
+}
+
+// deviceID 客户端标识的摘要，用于识别设备
+func deviceID(userAgent string) string {
+	if userAgent == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,85 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strings"
+)
+
+// Location IP 地址的地理位置
+type Location struct {
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// GeoLocator 查询 IP 地址的地理位置，未知时返回 false
+type GeoLocator interface {
+	Locate(ip string) (Location, bool)
+}
+
+// GeoRange 一个地址段的地理位置
+type GeoRange struct {
+	CIDR    string  `json:"cidr"`
+	Country string  `json:"country"`
+	Lat     float64 `json:"lat"`
+	Lon     float64 `json:"lon"`
+}
+
+// CIDRLocator 按配置的地址段查询地理位置，多个地址段匹配时使用最长前缀
+type CIDRLocator struct {
+	ranges []cidrRange
+}
+
+type cidrRange struct {
+	network  *net.IPNet
+	prefix   int
+	location Location
+}
+
+// NewCIDRLocator 创建地址段定位器
+func NewCIDRLocator(ranges []GeoRange) (*CIDRLocator, error) {
+	locator := &CIDRLocator{}
+	for _, r := range ranges {
+		_, network, err := net.ParseCIDR(r.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("invalid geo range %q: %w", r.CIDR, err)
+		}
+		prefix, _ := network.Mask.Size()
+		locator.ranges = append(locator.ranges, cidrRange{
+			network:  network,
+			prefix:   prefix,
+			location: Location{Country: strings.ToUpper(r.Country), Lat: r.Lat, Lon: r.Lon},
+		})
+	}
+	return locator, nil
+}
+
+// Locate 实现 GeoLocator
+func (l *CIDRLocator) Locate(ip string) (Location, bool) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return Location{}, false
+	}
+	best := -1
+	for i, r := range l.ranges {
+		if r.network.Contains(addr) && (best < 0 || r.prefix > l.ranges[best].prefix) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Location{}, false
+	}
+	return l.ranges[best].location, true
+}
+
+// distanceKm 两点间的大圆距离
+func distanceKm(a, b Location) float64 {
+	const earthRadiusKm = 6371
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
@@ -0,0 +1,152 @@
+package anomaly
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/i18n"
+)
+
+// Resolver 从请求中解析用户，无法解析时返回空
+type Resolver func(r *http.Request) string
+
+// Handler 异常检测的HTTP处理器
+type Handler struct {
+	detector *Detector
+	logger   *zap.Logger
+}
+
+// NewHandler 创建异常检测处理器
+func NewHandler(detector *Detector, logger *zap.Logger) *Handler {
+	return &Handler{
+		detector: detector,
+		logger:   logger,
+	}
+}
+
+// RegisterRoutes 注册异常检测路由（挂载于 /admin/v1/anomalies，系统管理员权限）
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.handleList)
+	r.Get("/step-up", h.handleListStepUps)
+	r.Delete("/step-up/{userId}", h.handleCompleteStepUp)
+}
+
+// handleList 查询异常，支持 kind、user_id、tenant_id、since（RFC3339）和 limit
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := Filter{
+		Kind:     query.Get("kind"),
+		UserID:   query.Get("user_id"),
+		TenantID: query.Get("tenant_id"),
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			h.error(w, r, http.StatusBadRequest, "Invalid since", err, "invalid_request")
+			return
+		}
+		filter.Since = since
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			h.error(w, r, http.StatusBadRequest, "Invalid limit", err, "invalid_request")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	anomalies, err := h.detector.List(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list anomalies", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list anomalies", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": anomalies})
+}
+
+// handleListStepUps 列出需要重新认证的用户
+func (h *Handler) handleListStepUps(w http.ResponseWriter, r *http.Request) {
+	stepUps, err := h.detector.ListStepUps(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list step-up requirements", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list step-up requirements", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": stepUps})
+}
+
+// handleCompleteStepUp 管理员确认后解除用户的重新认证要求
+func (h *Handler) handleCompleteStepUp(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userId")
+	if err := h.detector.CompleteStepUp(r.Context(), userID); err != nil {
+		h.logger.Error("failed to clear step-up requirement", zap.String("user_id", userID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to clear step-up requirement", err, "")
+		return
+	}
+	h.logger.Info("step-up requirement cleared", zap.String("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StepUpMiddleware 拒绝需要重新认证的用户的请求，exempt 前缀下的路径（如登录接口）不检查。
+// 查询失败时放行，只记录日志
+func (d *Detector) StepUpMiddleware(users Resolver, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := ""
+			if !exempted(r.URL.Path, exempt) {
+				userID = users(r)
+			}
+			if userID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			stepUp, err := d.StepUpRequired(r.Context(), userID)
+			if err != nil {
+				d.logger.Warn("failed to check step-up requirement", zap.String("user_id", userID), zap.Error(err))
+			}
+			if stepUp == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_user_authentication"`)
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, map[string]interface{}{
+				"error":      i18n.T(r.Context(), "Step-up authentication required", nil),
+				"code":       "step_up_required",
+				"reason":     stepUp.Reason,
+				"expires_at": stepUp.ExpiresAt,
+			})
+		})
+	}
+}
+
+func exempted(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// error 输出错误响应
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error": message,
+	}
+	if err != nil {
+		body["details"] = err.Error()
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
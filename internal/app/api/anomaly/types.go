@@ -0,0 +1,148 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 异常类型
+const (
+	KindImpossibleTravel = "impossible_travel" // 两次登录的地理距离在间隔时间内无法到达
+	KindFailedLogins     = "failed_logins"     // 同一地址、用户或全局的登录失败突增
+	KindNewDevice        = "new_device"        // 用户在未见过的设备上登录
+	KindNewCountry       = "new_country"       // 用户在未见过的国家或地区登录
+	KindAPIKeyUsage      = "api_key_usage"     // API 密钥调用量突增或来自新的国家或地区
+)
+
+// 异常级别，与告警级别一致
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// LoginEvent 一次登录尝试
+type LoginEvent struct {
+	UserID    string    // 登录成功时的用户
+	Username  string    // 登录使用的账号，失败时用于按账号统计
+	TenantID  string    // 用户所属租户
+	IP        string    // 客户端地址
+	UserAgent string    // 客户端标识，用于识别设备
+	Success   bool      // 是否登录成功
+	Reason    string    // 失败原因
+	Time      time.Time // 为空时使用当前时间
+}
+
+// KeyEvent 一次 API 密钥调用
+type KeyEvent struct {
+	KeyID    string
+	TenantID string
+	IP       string
+	Time     time.Time
+}
+
+// Anomaly 一条检测到的异常
+type Anomaly struct {
+	ID        string                 `json:"id"`
+	Kind      string                 `json:"kind"`
+	Severity  string                 `json:"severity"`
+	Subject   string                 `json:"subject"` // 异常针对的对象，如 user:<id>、ip:<addr>、key:<id>
+	UserID    string                 `json:"user_id,omitempty"`
+	KeyID     string                 `json:"key_id,omitempty"`
+	TenantID  string                 `json:"tenant_id,omitempty"`
+	IP        string                 `json:"ip,omitempty"`
+	Country   string                 `json:"country,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	StepUp    bool                   `json:"step_up"` // 是否要求用户重新进行多因素认证
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Filter 异常查询条件
+type Filter struct {
+	Kind     string
+	UserID   string
+	TenantID string
+	Since    time.Time
+	Limit    int
+}
+
+// StepUp 要求用户重新认证的记录
+type StepUp struct {
+	UserID    string    `json:"user_id"`
+	Reason    string    `json:"reason"`
+	AnomalyID string    `json:"anomaly_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Config 异常检测配置
+type Config struct {
+	// 登录失败：窗口内同一地址或账号失败次数达到阈值，或全局失败数超过基线的倍数
+	FailedLoginWindow    time.Duration `json:"failed_login_window"`
+	FailedLoginThreshold int           `json:"failed_login_threshold"`
+	FailedLoginFactor    float64       `json:"failed_login_factor"`
+
+	// 异地登录：两次登录间的速度超过此值（公里/小时）且距离超过 MinTravelKm 时视为不可能的旅行
+	MaxTravelSpeedKmh float64 `json:"max_travel_speed_kmh"`
+	MinTravelKm       float64 `json:"min_travel_km"`
+
+	// API 密钥：窗口内调用数至少为 KeyMinRequests 且超过基线平均的 KeyRateFactor 倍时视为突增
+	KeyWindow      time.Duration `json:"key_window"`
+	KeyMinRequests int           `json:"key_min_requests"`
+	KeyRateFactor  float64       `json:"key_rate_factor"`
+
+	// 计算基线的时长
+	BaselinePeriod time.Duration `json:"baseline_period"`
+
+	// 对异地登录和新国家登录要求多因素认证，StepUpTTL 后自动解除
+	StepUp    bool          `json:"step_up"`
+	StepUpTTL time.Duration `json:"step_up_ttl"`
+
+	// IP 地址段到国家和坐标的映射，未配置时不检测地理异常
+	GeoRanges []GeoRange `json:"geo_ranges,omitempty"`
+}
+
+// DefaultConfig 默认异常检测配置
+func DefaultConfig() *Config {
+	return &Config{
+		FailedLoginWindow:    5 * time.Minute,
+		FailedLoginThreshold: 10,
+		FailedLoginFactor:    5,
+		MaxTravelSpeedKmh:    1000,
+		MinTravelKm:          500,
+		KeyWindow:            5 * time.Minute,
+		KeyMinRequests:       100,
+		KeyRateFactor:        10,
+		BaselinePeriod:       24 * time.Hour,
+		StepUpTTL:            24 * time.Hour,
+	}
+}
+
+// ConfigFromEnv 读取 METABASE_ANOMALY_STEP_UP 和 METABASE_GEOIP_RANGES（地址段 JSON 文件）
+func ConfigFromEnv() *Config {
+	cfg := DefaultConfig()
+	cfg.StepUp, _ = strconv.ParseBool(os.Getenv("METABASE_ANOMALY_STEP_UP"))
+	if path := os.Getenv("METABASE_GEOIP_RANGES"); path != "" {
+		ranges, err := loadGeoRanges(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ignoring METABASE_GEOIP_RANGES: %v\n", err)
+		} else {
+			cfg.GeoRanges = ranges
+		}
+	}
+	return cfg
+}
+
+func loadGeoRanges(path string) ([]GeoRange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ranges []GeoRange
+	if err := json.Unmarshal(data, &ranges); err != nil {
+		return nil, fmt.Errorf("invalid geo ranges: %w", err)
+	}
+	return ranges, nil
+}
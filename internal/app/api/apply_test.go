@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/client"
+)
+
+const applyManifest = `
+tenants:
+  - slug: acme
+    name: Acme
+    plan: pro
+    settings:
+      session_timeout_minutes: 60
+    projects:
+      - slug: handbook
+        name: Handbook
+        environment: production
+        members:
+          - user_id: alice
+            role: collaborator
+        datasources:
+          - name: wiki
+            type: filesystem
+            config:
+              root_path: ROOT
+        rag_settings:
+          retrieval:
+            top_k: 8
+`
+
+func TestApplyManifest(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	_, ts := startInstance(t, filepath.Join(dir, "metabase.db"))
+	ctx := context.Background()
+	c := client.New(&client.Config{URL: ts.URL, AccessToken: "test-token"})
+
+	if _, err := client.ParseManifest([]byte("tenants:\n  - slug: acme\n    name: Acme\n    colour: red\n")); err == nil {
+		t.Fatal("expected unknown fields to be rejected")
+	}
+	manifest, err := client.ParseManifest([]byte(strings.ReplaceAll(applyManifest, "ROOT", dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := c.PlanManifest(ctx, manifest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addresses []string
+	for _, change := range plan.Changes {
+		if change.Action != client.ActionCreate {
+			t.Fatalf("expected only creates for an empty server, got %s %s", change.Action, change.Address)
+		}
+		addresses = append(addresses, change.Address)
+	}
+	want := []string{
+		"tenant.acme", "tenant.acme/project.handbook", "tenant.acme/project.handbook/member.alice",
+		"tenant.acme/project.handbook/datasource.wiki", "tenant.acme/project.handbook/rag_settings",
+	}
+	if len(addresses) != len(want) {
+		t.Fatalf("plan = %v, want %v", addresses, want)
+	}
+	for i := range want {
+		if addresses[i] != want[i] {
+			t.Fatalf("plan = %v, want %v", addresses, want)
+		}
+	}
+	if err := c.Apply(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err := c.GetTenantBySlug(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, err := c.ListTenantProjects(ctx, tenant.ID, nil); err != nil || list.Total != 1 || list.Projects[0].Slug != "handbook" {
+		t.Fatalf("expected the applied project in the tenant's projects, got %+v %v", list, err)
+	}
+
+	// Applying is idempotent: the server now matches the manifest
+	if plan, err = c.PlanManifest(ctx, manifest, nil); err != nil || !plan.Empty() {
+		t.Fatalf("expected an empty plan after apply, got %s %v", plan, err)
+	}
+
+	// Drift is reported as field diffs and reconciled
+	manifest.Tenants[0].Settings["session_timeout_minutes"] = 120
+	manifest.Tenants[0].Projects[0].Members[0].Role = "viewer"
+	manifest.Tenants[0].Projects[0].DataSources = nil
+	if plan, err = c.PlanManifest(ctx, manifest, &client.PlanOptions{Prune: true}); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, change := range plan.Changes {
+		got[change.Address] = change.Action
+	}
+	if len(got) != 3 || got["tenant.acme"] != client.ActionUpdate ||
+		got["tenant.acme/project.handbook/member.alice"] != client.ActionUpdate ||
+		got["tenant.acme/project.handbook/datasource.wiki"] != client.ActionDelete {
+		t.Fatalf("unexpected drift plan:\n%s", plan)
+	}
+	if field := plan.Changes[0].Fields; len(field) != 1 || field[0].Field != "settings.session_timeout_minutes" {
+		t.Fatalf("expected a settings diff, got %+v", field)
+	}
+	if err := c.Apply(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = c.PlanManifest(ctx, manifest, &client.PlanOptions{Prune: true}); err != nil || !plan.Empty() {
+		t.Fatalf("expected an empty plan after reconciling drift, got %s %v", plan, err)
+	}
+}
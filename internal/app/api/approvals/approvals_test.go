@@ -0,0 +1,192 @@
+package approvals
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+func newTestManager(t *testing.T, operations ...string) *Manager {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "approvals.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	manager := NewManager(db, &Config{Operations: operations, Window: time.Hour}, zap.NewNop())
+	if err := manager.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	manager.Register(
+		Operation{Name: "tenant.delete", Method: http.MethodDelete, Path: "/admin/v1/tenants/*"},
+		Operation{Name: "rag.force_reindex", Method: http.MethodPost, Path: "/admin/v1/projects/*/rag/datasources/*/sync",
+			When: func(r *http.Request) bool { return r.URL.Query().Get("force") == "true" }},
+		Operation{Name: "project.transfer_ownership", Method: http.MethodPost, Path: "/admin/v1/projects/*/transfer-ownership"},
+	)
+	return manager
+}
+
+func TestApprovalMiddleware(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t, "tenant.delete", "rag.force_reindex")
+	users := func(r *http.Request) string { return r.Header.Get("X-User") }
+	executed := 0
+	failing := false
+	handler := manager.Middleware(users, func(*http.Request) string { return "t1" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		executed++
+		if failing {
+			http.Error(w, "storage unavailable", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(user, method, path, body, approvalID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User", user)
+		if approvalID != "" {
+			req.Header.Set(HeaderApprovalID, approvalID)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Operations that are not configured, or do not match, run directly
+	for _, path := range []string{"/admin/v1/projects/p1/transfer-ownership", "/admin/v1/projects/p1/rag/datasources/ds1/sync"} {
+		if rec := serve("alice", http.MethodPost, path, "", ""); rec.Code != http.StatusNoContent {
+			t.Fatalf("%s: expected direct execution, got %d", path, rec.Code)
+		}
+	}
+
+	// Guarded operations need a verified identity
+	if rec := serve("", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"x"}`, ""); rec.Code != http.StatusUnauthorized || executed != 2 {
+		t.Fatalf("expected an unidentified request to be refused, got %d", rec.Code)
+	}
+
+	rec := serve("alice", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"x"}`, "")
+	if rec.Code != http.StatusAccepted || executed != 2 {
+		t.Fatalf("expected force reindex to wait for approval, got %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data Action `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	id := resp.Data.ID
+	if !strings.HasPrefix(id, "appr_") || len(id) != len("appr_")+36 {
+		t.Fatalf("expected a random approval ID, got %q", id)
+	}
+	if resp.Data.Operation != "rag.force_reindex" || resp.Data.TenantID != "t1" || resp.Data.Status != StatusPending {
+		t.Fatalf("unexpected pending action %+v", resp.Data)
+	}
+
+	if rec := serve("alice", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"x"}`, id); rec.Code != http.StatusConflict {
+		t.Fatalf("expected unapproved action to be refused, got %d", rec.Code)
+	}
+	if _, err := manager.Approve(ctx, id, "alice", ""); err != ErrSelfApproval {
+		t.Fatalf("expected self-approval to fail, got %v", err)
+	}
+	if _, err := manager.Approve(ctx, id, "bob", "looks fine"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The approved request must be resubmitted unchanged by the requester
+	if rec := serve("alice", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"y"}`, id); rec.Code != http.StatusConflict {
+		t.Fatalf("expected changed body to be refused, got %d", rec.Code)
+	}
+	if rec := serve("carol", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"x"}`, id); rec.Code != http.StatusConflict {
+		t.Fatalf("expected another user to be refused, got %d", rec.Code)
+	}
+
+	// A failed operation leaves the approval usable for a retry
+	failing = true
+	if rec := serve("alice", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"x"}`, id); rec.Code != http.StatusInternalServerError || executed != 3 {
+		t.Fatalf("expected the failure to reach the client, got %d", rec.Code)
+	}
+	if action, _ := manager.Get(ctx, id); action.Status != StatusApproved || action.ExecutedAt != nil {
+		t.Fatalf("expected a failed operation to release the approval, got %+v", action)
+	}
+	failing = false
+	if rec := serve("alice", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"x"}`, id); rec.Code != http.StatusNoContent || executed != 4 {
+		t.Fatalf("expected approved action to execute, got %d", rec.Code)
+	}
+	if action, _ := manager.Get(ctx, id); action.Status != StatusExecuted {
+		t.Fatalf("expected executed status, got %s", action.Status)
+	}
+	if rec := serve("alice", http.MethodPost, "/admin/v1/projects/p1/rag/datasources/ds1/sync?force=true", `{"note":"x"}`, id); rec.Code != http.StatusConflict || executed != 4 {
+		t.Fatalf("expected approval to be single use, got %d", rec.Code)
+	}
+
+	// Approvals lapse after the window
+	rec = serve("alice", http.MethodDelete, "/admin/v1/tenants/t1", "", "")
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	manager.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := manager.Approve(ctx, resp.Data.ID, "bob", ""); err != ErrNotPending {
+		t.Fatalf("expected expired action to be refused, got %v", err)
+	}
+	if action, _ := manager.Get(ctx, resp.Data.ID); action.Status != StatusExpired {
+		t.Fatalf("expected expired status, got %s", action.Status)
+	}
+}
+
+func TestApprovalHandlers(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t, "tenant.delete")
+	users := func(r *http.Request) string { return r.Header.Get("X-User") }
+	h := NewHandler(manager, users, func(r *http.Request) bool { return r.Header.Get("X-User") == "admin" }, zap.NewNop())
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	for _, requester := range []string{"alice", "bob"} {
+		if err := manager.Request(ctx, &Action{Operation: "tenant.delete", Method: http.MethodDelete,
+			Path: "/admin/v1/tenants/" + requester, RequestedBy: requester}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	do := func(user, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	list := func(user, query string) []Action {
+		var resp struct {
+			Data []Action `json:"data"`
+		}
+		json.Unmarshal(do(user, http.MethodGet, "/"+query).Body.Bytes(), &resp)
+		return resp.Data
+	}
+
+	if got := list("admin", ""); len(got) != 2 {
+		t.Fatalf("expected admins to see all pending actions, got %d", len(got))
+	}
+	mine := list("alice", "")
+	if len(mine) != 1 || mine[0].RequestedBy != "alice" {
+		t.Fatalf("expected requesters to see their own actions, got %+v", mine)
+	}
+	if rec := do("alice", http.MethodPost, "/"+mine[0].ID+"/approve"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected non-admins to be refused, got %d", rec.Code)
+	}
+	if rec := do("bob", http.MethodPost, "/"+mine[0].ID+"/cancel"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected other users to be unable to cancel, got %d", rec.Code)
+	}
+	if rec := do("alice", http.MethodPost, "/"+mine[0].ID+"/cancel"); rec.Code != http.StatusOK {
+		t.Fatalf("cancel failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("admin", http.MethodPost, "/"+mine[0].ID+"/approve"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected cancelled action to stay cancelled, got %d", rec.Code)
+	}
+	if got := list("admin", "?status=cancelled"); len(got) != 1 {
+		t.Fatalf("expected one cancelled action, got %d", len(got))
+	}
+}
@@ -0,0 +1,271 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// Resolver 从请求中解析用户或租户，无法解析时返回空
+type Resolver func(r *http.Request) string
+
+// Handler 审批单的HTTP处理器
+type Handler struct {
+	manager *Manager
+	users   Resolver
+	isAdmin func(r *http.Request) bool
+	logger  *zap.Logger
+}
+
+// NewHandler 创建审批处理器；isAdmin 判断调用者能否审批
+func NewHandler(manager *Manager, users Resolver, isAdmin func(r *http.Request) bool, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		users:   users,
+		isAdmin: isAdmin,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 注册审批路由（挂载于 /admin/v1/approvals，需登录；审批须管理员权限）
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.handleList)
+	r.Get("/{approvalId}", h.handleGet)
+	r.Post("/{approvalId}/approve", h.handleApprove)
+	r.Post("/{approvalId}/reject", h.handleReject)
+	r.Post("/{approvalId}/cancel", h.handleCancel)
+}
+
+// handleList 列出审批单，默认为待审批的；非管理员只能看到自己提交的
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	filter := Filter{Status: r.URL.Query().Get("status")}
+	if filter.Status == "" {
+		filter.Status = StatusPending
+	} else if filter.Status == "all" {
+		filter.Status = ""
+	}
+	filter.Limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if !h.isAdmin(r) {
+		filter.RequestedBy = h.users(r)
+	}
+
+	actions, err := h.manager.List(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list approvals", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list approvals", err)
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data":       actions,
+		"operations": h.manager.Required(),
+	})
+}
+
+// handleGet 获取审批单
+func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	action, err := h.manager.Get(r.Context(), chi.URLParam(r, "approvalId"))
+	if err == nil && !h.isAdmin(r) && action.RequestedBy != h.users(r) {
+		err = ErrNotFound
+	}
+	if err != nil {
+		h.fail(w, r, "Failed to get approval", err)
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": action})
+}
+
+// decisionRequest 批准或拒绝时的说明
+type decisionRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+// handleApprove 批准操作，须由请求方以外的管理员执行
+func (h *Handler) handleApprove(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.manager.Approve)
+}
+
+// handleReject 拒绝操作
+func (h *Handler) handleReject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.manager.Reject)
+}
+
+func (h *Handler) decide(w http.ResponseWriter, r *http.Request,
+	decide func(ctx context.Context, id, approver, comment string) (*Action, error)) {
+	approver := h.users(r)
+	if approver == "" || !h.isAdmin(r) {
+		h.error(w, r, http.StatusForbidden, "Access denied", errors.New("only admins can decide approvals"))
+		return
+	}
+	var req decisionRequest
+	if r.ContentLength > 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err)
+			return
+		}
+	}
+
+	action, err := decide(r.Context(), chi.URLParam(r, "approvalId"), approver, req.Comment)
+	if err != nil {
+		h.fail(w, r, "Failed to decide approval", err)
+		return
+	}
+	h.logger.Info("approval decided", zap.String("approval_id", action.ID),
+		zap.String("operation", action.Operation), zap.String("status", action.Status))
+	render.JSON(w, r, map[string]interface{}{"data": action})
+}
+
+// handleCancel 撤销尚未执行的操作
+func (h *Handler) handleCancel(w http.ResponseWriter, r *http.Request) {
+	action, err := h.manager.Cancel(r.Context(), chi.URLParam(r, "approvalId"), h.users(r), h.isAdmin(r))
+	if err != nil {
+		h.fail(w, r, "Failed to cancel approval", err)
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": action})
+}
+
+// Middleware 拦截需要审批的请求：未携带审批单时登记待审批操作并返回 202；
+// 携带已批准的审批单且请求一致时放行并将审批单标记为已执行，操作未返回 2xx 时退回已批准状态。
+// 须挂载在认证之后，users 返回经过验证的用户，无法确认身份的请求被拒绝
+func (m *Manager) Middleware(users, tenants Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, ok := m.operation(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			userID := users(r)
+			if userID == "" {
+				writeError(w, r, http.StatusUnauthorized, "Operation requires approval by an identified user", "approval_unauthenticated")
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+			if err != nil || len(body) > maxBodySize {
+				writeError(w, r, http.StatusRequestEntityTooLarge, "Request body too large for approval", "approval_invalid")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			sum := sha256.Sum256(body)
+			request := &Action{
+				Operation:   op.Name,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				Query:       r.URL.RawQuery,
+				Body:        string(body),
+				BodyHash:    hex.EncodeToString(sum[:]),
+				TenantID:    tenants(r),
+				RequestedBy: userID,
+			}
+
+			if id := r.Header.Get(HeaderApprovalID); id != "" {
+				if _, err := m.Execute(r.Context(), id, request); err != nil {
+					if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrNotExecutable) {
+						m.logger.Error("failed to execute approval", zap.String("approval_id", id), zap.Error(err))
+					}
+					writeError(w, r, http.StatusConflict, "Approval does not allow this request", "approval_invalid")
+					return
+				}
+				sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+				next.ServeHTTP(sw, r)
+				if sw.status < 200 || sw.status >= 300 {
+					if err := m.Release(context.WithoutCancel(r.Context()), id); err != nil {
+						m.logger.Error("failed to release approval", zap.String("approval_id", id), zap.Error(err))
+					}
+					m.logger.Warn("approved operation failed", zap.String("approval_id", id),
+						zap.String("operation", op.Name), zap.Int("status", sw.status))
+					return
+				}
+				m.logger.Info("approved operation executed", zap.String("approval_id", id), zap.String("operation", op.Name))
+				return
+			}
+
+			if err := m.Request(r.Context(), request); err != nil {
+				m.logger.Error("failed to request approval", zap.String("operation", op.Name), zap.Error(err))
+				writeError(w, r, http.StatusInternalServerError, "Failed to request approval", "")
+				return
+			}
+			render.Status(r, http.StatusAccepted)
+			render.JSON(w, r, map[string]interface{}{
+				"data":    request,
+				"code":    "approval_required",
+				"message": "Operation requires approval by another admin; resubmit with " + HeaderApprovalID + " once approved",
+			})
+		})
+	}
+}
+
+// statusWriter 记录处理器返回的状态码
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status = status
+		w.wrote = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+// operation 返回请求对应的需审批操作
+func (m *Manager) operation(r *http.Request) (Operation, bool) {
+	for _, op := range m.operations {
+		if op.matches(r) {
+			return op, true
+		}
+	}
+	return Operation{}, false
+}
+
+// fail 按错误类型输出响应
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, message string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrNotPending):
+		status = http.StatusConflict
+	case errors.Is(err, ErrSelfApproval), errors.Is(err, ErrNotAllowed):
+		status = http.StatusForbidden
+	default:
+		h.logger.Error(message, zap.Error(err))
+	}
+	h.error(w, r, status, message, err)
+}
+
+// error 输出错误响应
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error) {
+	render.Status(r, status)
+	render.JSON(w, r, map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	})
+}
+
+// writeError 输出中间件的错误响应
+func writeError(w http.ResponseWriter, r *http.Request, status int, message, code string) {
+	body := map[string]interface{}{"error": message}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
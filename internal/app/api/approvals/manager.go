@@ -0,0 +1,307 @@
+package approvals
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 审批操作的错误
+var (
+	ErrNotFound      = errors.New("approval not found")
+	ErrNotPending    = errors.New("approval is no longer pending")
+	ErrSelfApproval  = errors.New("approvals must be decided by another admin")
+	ErrNotAllowed    = errors.New("only the requester or an admin can cancel an approval")
+	ErrNotExecutable = errors.New("approval does not allow this request")
+)
+
+// Manager 审批单存储，保存在数据库中由所有实例共享
+type Manager struct {
+	db         *sql.DB
+	logger     *zap.Logger
+	window     time.Duration
+	required   []string
+	operations []Operation
+	now        func() time.Time
+}
+
+// NewManager 创建审批单存储
+func NewManager(db *sql.DB, cfg *Config, logger *zap.Logger) *Manager {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultWindow
+	}
+	return &Manager{
+		db:       db,
+		logger:   logger,
+		window:   window,
+		required: cfg.Operations,
+		now:      time.Now,
+	}
+}
+
+// Initialize 初始化数据库表
+func (m *Manager) Initialize(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS approval_actions (
+		id TEXT PRIMARY KEY,
+		operation TEXT NOT NULL,
+		method TEXT NOT NULL,
+		path TEXT NOT NULL,
+		query TEXT NOT NULL DEFAULT '',
+		body TEXT NOT NULL DEFAULT '',
+		body_hash TEXT NOT NULL,
+		tenant_id TEXT NOT NULL DEFAULT '',
+		requested_by TEXT NOT NULL,
+		status TEXT NOT NULL,
+		decided_by TEXT NOT NULL DEFAULT '',
+		comment TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		decided_at TIMESTAMP,
+		executed_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_approval_actions_status ON approval_actions(status, created_at);
+	CREATE INDEX IF NOT EXISTS idx_approval_actions_requester ON approval_actions(requested_by, created_at);
+	`)
+	if err != nil {
+		m.logger.Error("failed to initialize approvals table", zap.Error(err))
+		return fmt.Errorf("failed to initialize approvals table: %w", err)
+	}
+	return nil
+}
+
+// Register 登记可要求审批的操作，只有配置中列出的操作会被拦截
+func (m *Manager) Register(operations ...Operation) {
+	for _, op := range operations {
+		if slices.Contains(m.required, op.Name) {
+			m.operations = append(m.operations, op)
+		}
+	}
+}
+
+// Required 返回需要审批的操作名称
+func (m *Manager) Required() []string {
+	names := make([]string, len(m.operations))
+	for i, op := range m.operations {
+		names[i] = op.Name
+	}
+	return names
+}
+
+// Request 创建待审批的操作
+func (m *Manager) Request(ctx context.Context, action *Action) error {
+	now := m.now()
+	action.ID = "appr_" + uuid.New().String()
+	action.Status = StatusPending
+	action.CreatedAt = now
+	action.ExpiresAt = now.Add(m.window)
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO approval_actions (id, operation, method, path, query, body, body_hash, tenant_id,
+			requested_by, status, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		action.ID, action.Operation, action.Method, action.Path, action.Query, action.Body, action.BodyHash,
+		action.TenantID, action.RequestedBy, action.Status, action.CreatedAt, action.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save approval: %w", err)
+	}
+	return nil
+}
+
+// Get 返回审批单，超过时限的审批单状态为 expired
+func (m *Manager) Get(ctx context.Context, id string) (*Action, error) {
+	actions, err := m.query(ctx, `SELECT `+actionColumns+` FROM approval_actions WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) == 0 {
+		return nil, ErrNotFound
+	}
+	return actions[0], nil
+}
+
+// List 按创建时间倒序返回审批单
+func (m *Manager) List(ctx context.Context, filter Filter) ([]*Action, error) {
+	query := `SELECT ` + actionColumns + ` FROM approval_actions WHERE 1 = 1`
+	var args []interface{}
+	switch filter.Status {
+	case "":
+	case StatusPending, StatusApproved:
+		// 过期的待审批和已批准审批单按 expired 返回
+		query += ` AND status = ? AND expires_at > ?`
+		args = append(args, filter.Status, m.now())
+	case StatusExpired:
+		query += ` AND status IN (?, ?) AND expires_at <= ?`
+		args = append(args, StatusPending, StatusApproved, m.now())
+	default:
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.RequestedBy != "" {
+		query += ` AND requested_by = ?`
+		args = append(args, filter.RequestedBy)
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 500
+	}
+	query += ` ORDER BY created_at DESC LIMIT ?`
+	args = append(args, limit)
+	return m.query(ctx, query, args...)
+}
+
+// Approve 批准待审批的操作，执行时限从批准时起算。请求方不能批准自己的操作
+func (m *Manager) Approve(ctx context.Context, id, approver, comment string) (*Action, error) {
+	return m.decide(ctx, id, approver, comment, StatusApproved)
+}
+
+// Reject 拒绝待审批的操作
+func (m *Manager) Reject(ctx context.Context, id, approver, comment string) (*Action, error) {
+	return m.decide(ctx, id, approver, comment, StatusRejected)
+}
+
+// Cancel 撤销尚未执行的操作，请求方或管理员可以撤销
+func (m *Manager) Cancel(ctx context.Context, id, userID string, admin bool) (*Action, error) {
+	action, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action.RequestedBy != userID && !admin {
+		return nil, ErrNotAllowed
+	}
+	if action.Status != StatusPending && action.Status != StatusApproved {
+		return nil, ErrNotPending
+	}
+	now := m.now()
+	res, err := m.db.ExecContext(ctx, `
+		UPDATE approval_actions SET status = ?, decided_by = ?, decided_at = ?
+		WHERE id = ? AND status = ?`, StatusCancelled, userID, now, id, action.Status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel approval: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotPending
+	}
+	action.Status = StatusCancelled
+	action.DecidedBy = userID
+	action.DecidedAt = &now
+	return action, nil
+}
+
+// Execute 核对请求与已批准的审批单一致后将其标记为已执行，每张审批单只能执行一次。
+// 操作未成功时调用 Release 退回已批准状态，以便在时限内重试
+func (m *Manager) Execute(ctx context.Context, id string, request *Action) (*Action, error) {
+	action, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action.Status != StatusApproved || action.Operation != request.Operation ||
+		action.Method != request.Method || action.Path != request.Path || action.Query != request.Query ||
+		action.BodyHash != request.BodyHash || action.RequestedBy != request.RequestedBy {
+		return nil, ErrNotExecutable
+	}
+	now := m.now()
+	res, err := m.db.ExecContext(ctx, `
+		UPDATE approval_actions SET status = ?, executed_at = ?
+		WHERE id = ? AND status = ? AND expires_at > ?`, StatusExecuted, now, id, StatusApproved, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute approval: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotExecutable
+	}
+	action.Status = StatusExecuted
+	action.ExecutedAt = &now
+	return action, nil
+}
+
+// Release 将 Execute 标记为已执行、但操作未成功的审批单退回已批准状态
+func (m *Manager) Release(ctx context.Context, id string) error {
+	_, err := m.db.ExecContext(ctx, `
+		UPDATE approval_actions SET status = ?, executed_at = NULL
+		WHERE id = ? AND status = ?`, StatusApproved, id, StatusExecuted)
+	if err != nil {
+		return fmt.Errorf("failed to release approval: %w", err)
+	}
+	return nil
+}
+
+// decide 批准或拒绝待审批的操作
+func (m *Manager) decide(ctx context.Context, id, approver, comment, status string) (*Action, error) {
+	action, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+	if action.RequestedBy == approver {
+		return nil, ErrSelfApproval
+	}
+	now := m.now()
+	expires := action.ExpiresAt
+	if status == StatusApproved {
+		expires = now.Add(m.window)
+	}
+	res, err := m.db.ExecContext(ctx, `
+		UPDATE approval_actions SET status = ?, decided_by = ?, comment = ?, decided_at = ?, expires_at = ?
+		WHERE id = ? AND status = ? AND expires_at > ?`,
+		status, approver, comment, now, expires, id, StatusPending, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update approval: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrNotPending
+	}
+	action.Status = status
+	action.DecidedBy = approver
+	action.Comment = comment
+	action.DecidedAt = &now
+	action.ExpiresAt = expires
+	return action, nil
+}
+
+const actionColumns = `id, operation, method, path, query, body, body_hash, tenant_id, requested_by,
+	status, decided_by, comment, created_at, expires_at, decided_at, executed_at`
+
+func (m *Manager) query(ctx context.Context, query string, args ...interface{}) ([]*Action, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query approvals: %w", err)
+	}
+	defer rows.Close()
+
+	now := m.now()
+	actions := []*Action{}
+	for rows.Next() {
+		var action Action
+		var decidedAt, executedAt sql.NullTime
+		err := rows.Scan(&action.ID, &action.Operation, &action.Method, &action.Path, &action.Query,
+			&action.Body, &action.BodyHash, &action.TenantID, &action.RequestedBy, &action.Status,
+			&action.DecidedBy, &action.Comment, &action.CreatedAt, &action.ExpiresAt, &decidedAt, &executedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan approval: %w", err)
+		}
+		if decidedAt.Valid {
+			action.DecidedAt = &decidedAt.Time
+		}
+		if executedAt.Valid {
+			action.ExecutedAt = &executedAt.Time
+		}
+		if (action.Status == StatusPending || action.Status == StatusApproved) && !now.Before(action.ExpiresAt) {
+			action.Status = StatusExpired
+		}
+		actions = append(actions, &action)
+	}
+	return actions, rows.Err()
+}
@@ -0,0 +1,105 @@
+package approvals
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// HeaderApprovalID 重新提交已批准操作时携带的审批单 ID
+const HeaderApprovalID = "X-Approval-ID"
+
+// defaultWindow 未配置时审批和执行的时限
+const defaultWindow = 24 * time.Hour
+
+// maxBodySize 需审批请求体的上限，请求体随审批单保存供审批人查看
+const maxBodySize = 1 << 20
+
+// 审批单状态
+const (
+	StatusPending   = "pending"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCancelled = "cancelled"
+	StatusExecuted  = "executed"
+	StatusExpired   = "expired"
+)
+
+// Operation 需要审批的操作，按请求方法和路径匹配。
+// Path 中的 * 匹配一个路径段；When 不为空时还须满足其条件
+type Operation struct {
+	Name   string
+	Method string
+	Path   string
+	When   func(r *http.Request) bool
+}
+
+// matches 判断请求是否为该操作
+func (o Operation) matches(r *http.Request) bool {
+	if r.Method != o.Method {
+		return false
+	}
+	pattern := strings.Split(strings.Trim(o.Path, "/"), "/")
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pattern) != len(segments) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != segments[i] {
+			return false
+		}
+	}
+	return o.When == nil || o.When(r)
+}
+
+// Action 一次待审批的破坏性操作。请求方提交后操作不执行，另一名管理员在时限内批准后，
+// 请求方携带审批单 ID 重新提交相同请求时才执行，且只能执行一次
+type Action struct {
+	ID          string     `json:"id"`
+	Operation   string     `json:"operation"`
+	Method      string     `json:"method"`
+	Path        string     `json:"path"`
+	Query       string     `json:"query,omitempty"`
+	Body        string     `json:"body,omitempty"`
+	BodyHash    string     `json:"-"`
+	TenantID    string     `json:"tenant_id,omitempty"`
+	RequestedBy string     `json:"requested_by"`
+	Status      string     `json:"status"`
+	DecidedBy   string     `json:"decided_by,omitempty"`
+	Comment     string     `json:"comment,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"` // 待审批时为审批截止时间，批准后为执行截止时间
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+	ExecutedAt  *time.Time `json:"executed_at,omitempty"`
+}
+
+// Filter 审批单查询条件
+type Filter struct {
+	Status      string
+	RequestedBy string
+	Limit       int
+}
+
+// Config 审批配置
+type Config struct {
+	// 需要审批的操作名称，如 tenant.delete；未列出的操作直接执行
+	Operations []string `json:"operations,omitempty"`
+
+	// 审批时限，批准后执行同样受此时限约束
+	Window time.Duration `json:"window,omitempty"`
+}
+
+// ConfigFromEnv 从 METABASE_APPROVAL_OPERATIONS（逗号分隔）和 METABASE_APPROVAL_WINDOW 读取配置
+func ConfigFromEnv() *Config {
+	cfg := &Config{Window: defaultWindow}
+	for _, name := range strings.Split(os.Getenv("METABASE_APPROVAL_OPERATIONS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			cfg.Operations = append(cfg.Operations, name)
+		}
+	}
+	if window, err := time.ParseDuration(os.Getenv("METABASE_APPROVAL_WINDOW")); err == nil && window > 0 {
+		cfg.Window = window
+	}
+	return cfg
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/internal/app/api/approvals"
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+func TestApprovalsUseAuthenticatedIdentity(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.MkdirAll("data", 0o755)
+	server, err := NewServer(&Config{
+		DatabasePath: filepath.Join(dir, "metabase.db"),
+		JWTSecret:    "test-secret",
+		Approvals:    &approvals.Config{Operations: []string{"tenant.delete"}, Window: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	r := chi.NewRouter()
+	server.setupRoutes(r)
+	ts := httptest.NewServer(server.withMiddleware(r))
+	t.Cleanup(func() {
+		ts.Close()
+		server.Stop(context.Background())
+	})
+	if _, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	token := func(userID string) string {
+		signed, err := auth.GenerateToken(userID, time.Now().Add(time.Hour), "test-secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	do := func(method, path, token, approvalID string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, ts.URL+path, bytes.NewReader(nil))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if approvalID != "" {
+			req.Header.Set(approvals.HeaderApprovalID, approvalID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+	requester, approver := token("admin"), token("system_admin")
+
+	// Tokens that do not verify carry no identity and cannot request approvals
+	if status, _ := do(http.MethodDelete, "/admin/v1/tenants/t1", "test-token", ""); status != http.StatusUnauthorized {
+		t.Fatalf("expected an unverified token to be refused, got %d", status)
+	}
+	// Unauthenticated requests are refused by authentication before approvals
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/admin/v1/tenants/t1", nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected authentication to run first, got %v %v", resp, err)
+	}
+
+	status, body := do(http.MethodDelete, "/admin/v1/tenants/t1", requester, "")
+	data, _ := body["data"].(map[string]interface{})
+	if status != http.StatusAccepted || data["requested_by"] != "admin" {
+		t.Fatalf("expected the deletion to wait for approval, got %d %v", status, body)
+	}
+	id, _ := data["id"].(string)
+
+	// The requester cannot approve their own request; another admin can
+	if status, _ := do(http.MethodPost, "/admin/v1/approvals/"+id+"/approve", requester, ""); status != http.StatusForbidden {
+		t.Fatalf("expected self-approval to be refused, got %d", status)
+	}
+	if status, _ := do(http.MethodPost, "/admin/v1/approvals/"+id+"/approve", token("alice"), ""); status != http.StatusForbidden {
+		t.Fatalf("expected a non-admin approval to be refused, got %d", status)
+	}
+	if status, body := do(http.MethodPost, "/admin/v1/approvals/"+id+"/approve", approver, ""); status != http.StatusOK {
+		t.Fatalf("expected approval by another admin, got %d %v", status, body)
+	}
+
+	if status, _ := do(http.MethodDelete, "/admin/v1/tenants/t1", approver, id); status != http.StatusConflict {
+		t.Fatalf("expected the approval to be bound to the requester, got %d", status)
+	}
+	if status, body := do(http.MethodDelete, "/admin/v1/tenants/t1", requester, id); status >= 300 {
+		t.Fatalf("expected the approved deletion to run, got %d %v", status, body)
+	}
+}
@@ -0,0 +1,150 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/internal/app/api/middleware"
+	"go.uber.org/zap"
+)
+
+// Resolver 从请求中解析用户或租户，无法解析时返回空
+type Resolver func(r *http.Request) string
+
+// Handler 审计日志的HTTP处理器
+type Handler struct {
+	manager *Manager
+	logger  *zap.Logger
+}
+
+// NewHandler 创建审计日志处理器
+func NewHandler(manager *Manager, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 注册审计日志路由（挂载于 /admin/v1/audit，系统管理员权限）
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.handleList)
+	r.Get("/verify", h.handleVerify)
+}
+
+// handleList 按序号增量查询审计记录，支持 after_seq、tenant_id、actor 和 limit
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := Filter{
+		TenantID: query.Get("tenant_id"),
+		Actor:    query.Get("actor"),
+	}
+	if v := query.Get("after_seq"); v != "" {
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seq < 0 {
+			h.error(w, r, http.StatusBadRequest, "Invalid after_seq", err, "invalid_request")
+			return
+		}
+		filter.AfterSeq = seq
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			h.error(w, r, http.StatusBadRequest, "Invalid limit", err, "invalid_request")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.manager.List(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list audit entries", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list audit entries", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": entries})
+}
+
+// handleVerify 校验整条审计链
+func (h *Handler) handleVerify(w http.ResponseWriter, r *http.Request) {
+	result, err := h.manager.Verify(r.Context())
+	if err != nil {
+		h.logger.Error("failed to verify audit chain", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to verify audit chain", err, "")
+		return
+	}
+	if !result.Valid {
+		h.logger.Error("audit chain verification failed",
+			zap.Int64("broken_at", result.BrokenAt), zap.String("reason", result.Reason))
+	}
+	render.JSON(w, r, map[string]interface{}{"data": result})
+}
+
+// Middleware 记录管理接口和认证接口的写请求：操作者、路径、租户、来源地址和响应状态。
+// 审计写入失败只记录日志，不影响请求
+func (m *Manager) Middleware(users, tenants Resolver, prefixes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !audited(r, prefixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			entry := &Entry{
+				Actor:      users(r),
+				Action:     r.Method,
+				Resource:   r.URL.Path,
+				TenantID:   tenants(r),
+				Status:     status,
+				RemoteAddr: middleware.ClientIP(r),
+			}
+			if requestID := chimiddleware.GetReqID(r.Context()); requestID != "" {
+				entry.Details = map[string]interface{}{"request_id": requestID}
+			}
+			// 请求可能已被取消，审计记录仍需写入
+			if err := m.Record(context.WithoutCancel(r.Context()), entry); err != nil {
+				m.logger.Error("failed to record audit entry",
+					zap.String("action", entry.Action), zap.String("resource", entry.Resource), zap.Error(err))
+			}
+		})
+	}
+}
+
+// audited 判断请求是否需要审计：路径前缀匹配的非只读请求
+func audited(r *http.Request, prefixes []string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// error 输出错误响应
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error": message,
+	}
+	if err != nil {
+		body["details"] = err.Error()
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
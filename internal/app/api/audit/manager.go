@@ -0,0 +1,282 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/logsink"
+)
+
+// maxAppendAttempts 与其他实例争用序号时的最大重试次数
+const maxAppendAttempts = 5
+
+// Manager 审计日志的存储。记录以哈希链保存在数据库中，所有实例共享同一条链，
+// 并同时转发到配置的日志汇
+type Manager struct {
+	db     *sql.DB
+	logger *zap.Logger
+
+	mu    sync.Mutex
+	sinks []io.WriteCloser
+	now   func() time.Time
+}
+
+// NewManager 创建审计日志存储，无法打开的转发目标记录错误后跳过
+func NewManager(db *sql.DB, config *Config, logger *zap.Logger) *Manager {
+	m := &Manager{db: db, logger: logger, now: time.Now}
+	if config != nil {
+		for i, sinkConfig := range config.Sinks {
+			sink, err := logsink.Open(sinkConfig)
+			if err != nil {
+				logger.Error("failed to open audit sink", zap.Int("sink", i), zap.Error(err))
+				continue
+			}
+			m.sinks = append(m.sinks, sink)
+		}
+	}
+	return m
+}
+
+// Initialize 初始化数据库表
+func (m *Manager) Initialize(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		seq INTEGER PRIMARY KEY,
+		time TEXT NOT NULL,
+		actor TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		resource TEXT NOT NULL DEFAULT '',
+		tenant_id TEXT NOT NULL DEFAULT '',
+		status INTEGER NOT NULL DEFAULT 0,
+		remote_addr TEXT NOT NULL DEFAULT '',
+		details TEXT NOT NULL DEFAULT '',
+		prev_hash TEXT NOT NULL,
+		hash TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_log_tenant ON audit_log(tenant_id, seq);
+	`)
+	if err != nil {
+		m.logger.Error("failed to initialize audit table", zap.Error(err))
+		return fmt.Errorf("failed to initialize audit table: %w", err)
+	}
+	return nil
+}
+
+// Record 将记录追加到审计链末尾并转发到日志汇，填充记录的序号、时间和哈希
+func (m *Manager) Record(ctx context.Context, entry *Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = m.now()
+	}
+	entry.Time = entry.Time.UTC()
+	if len(entry.Details) == 0 {
+		// 空详情读回时为 nil，统一后哈希才能复算
+		entry.Details = nil
+	}
+
+	var err error
+	for attempt := 0; attempt < maxAppendAttempts; attempt++ {
+		if err = m.append(ctx, entry); !errors.Is(err, ErrChainConflict) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	m.ship(entry)
+	return nil
+}
+
+// append 在事务中读取链尾并写入下一条记录，序号冲突时返回 ErrChainConflict
+func (m *Manager) append(ctx context.Context, entry *Entry) error {
+	var details string
+	if len(entry.Details) > 0 {
+		data, err := json.Marshal(entry.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		details = string(data)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin audit transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastSeq int64
+	var lastHash string
+	err = tx.QueryRowContext(ctx, `SELECT seq, hash FROM audit_log ORDER BY seq DESC LIMIT 1`).Scan(&lastSeq, &lastHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	entry.Seq = lastSeq + 1
+	entry.PrevHash = lastHash
+	entry.Hash = entry.computeHash()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_log (seq, time, actor, action, resource, tenant_id, status, remote_addr, details, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Seq, entry.Time.Format(time.RFC3339Nano), entry.Actor, entry.Action, entry.Resource,
+		entry.TenantID, entry.Status, entry.RemoteAddr, details, entry.PrevHash, entry.Hash,
+	)
+	if err != nil {
+		if isConflict(err) {
+			return ErrChainConflict
+		}
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		if isConflict(err) {
+			return ErrChainConflict
+		}
+		return fmt.Errorf("failed to commit audit entry: %w", err)
+	}
+	return nil
+}
+
+// isConflict 判断是否为序号唯一约束冲突或数据库忙
+func isConflict(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique") || strings.Contains(msg, "constraint") ||
+		strings.Contains(msg, "locked") || strings.Contains(msg, "busy")
+}
+
+// ship 以 JSON 行写入各日志汇，失败只记录日志，数据库中的链仍是完整的
+func (m *Manager) ship(entry *Entry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.sinks) == 0 {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		m.logger.Warn("failed to encode audit entry", zap.Int64("seq", entry.Seq), zap.Error(err))
+		return
+	}
+	data = append(data, '\n')
+	for _, sink := range m.sinks {
+		if _, err := sink.Write(data); err != nil {
+			m.logger.Warn("failed to ship audit entry", zap.Int64("seq", entry.Seq), zap.Error(err))
+		}
+	}
+}
+
+// List 按序号升序返回审计记录
+func (m *Manager) List(ctx context.Context, filter Filter) ([]*Entry, error) {
+	if filter.Limit <= 0 || filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	query := `SELECT seq, time, actor, action, resource, tenant_id, status, remote_addr, details, prev_hash, hash
+		FROM audit_log WHERE seq > ?`
+	args := []interface{}{filter.AfterSeq}
+	if filter.TenantID != "" {
+		query += " AND tenant_id = ?"
+		args = append(args, filter.TenantID)
+	}
+	if filter.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	query += " ORDER BY seq LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+	entries := []*Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Verify 从头校验审计链：序号连续、每条记录指向上一条的哈希且自身哈希正确
+func (m *Manager) Verify(ctx context.Context) (*Verification, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT seq, time, actor, action, resource, tenant_id, status, remote_addr, details, prev_hash, hash
+		FROM audit_log ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit chain: %w", err)
+	}
+	defer rows.Close()
+
+	result := &Verification{Valid: true}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Entries++
+		switch {
+		case entry.Seq != result.LastSeq+1:
+			result.fail(entry.Seq, fmt.Sprintf("expected sequence %d", result.LastSeq+1))
+		case entry.PrevHash != result.LastHash:
+			result.fail(entry.Seq, "previous hash does not match")
+		case entry.Hash != entry.computeHash():
+			result.fail(entry.Seq, "entry hash does not match its content")
+		}
+		if !result.Valid {
+			return result, nil
+		}
+		result.LastSeq = entry.Seq
+		result.LastHash = entry.Hash
+	}
+	return result, rows.Err()
+}
+
+func (v *Verification) fail(seq int64, reason string) {
+	v.Valid = false
+	v.BrokenAt = seq
+	v.Reason = reason
+}
+
+// Close 刷新并关闭日志汇
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for _, sink := range m.sinks {
+		errs = append(errs, sink.Close())
+	}
+	m.sinks = nil
+	return errors.Join(errs...)
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEntry(row scanner) (*Entry, error) {
+	var entry Entry
+	var at, details string
+	err := row.Scan(&entry.Seq, &at, &entry.Actor, &entry.Action, &entry.Resource, &entry.TenantID,
+		&entry.Status, &entry.RemoteAddr, &details, &entry.PrevHash, &entry.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+	}
+	if entry.Time, err = time.Parse(time.RFC3339Nano, at); err != nil {
+		return nil, fmt.Errorf("invalid audit entry time %q: %w", at, err)
+	}
+	if details != "" {
+		if err := json.Unmarshal([]byte(details), &entry.Details); err != nil {
+			return nil, fmt.Errorf("invalid audit entry details: %w", err)
+		}
+	}
+	return &entry, nil
+}
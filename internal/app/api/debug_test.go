@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestProfilingRoutes(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.MkdirAll("data", 0o755)
+
+	start := func(enabled bool) *httptest.Server {
+		server, err := NewServer(&Config{DatabasePath: filepath.Join(dir, "metabase.db"), EnableProfiling: enabled})
+		if err != nil {
+			t.Fatalf("failed to create server: %v", err)
+		}
+		r := chi.NewRouter()
+		server.setupRoutes(r)
+		ts := httptest.NewServer(server.withMiddleware(r))
+		t.Cleanup(func() {
+			ts.Close()
+			server.Stop(context.Background())
+		})
+		return ts
+	}
+	get := func(url string, auth bool) int {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if auth {
+			req.Header.Set("Authorization", "Bearer test-token")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := get(start(false).URL+"/debug/runtime", true); status == http.StatusOK {
+		t.Fatal("expected profiling to be off by default")
+	}
+
+	ts := start(true)
+	if status := get(ts.URL+"/debug/pprof/heap", false); status != http.StatusUnauthorized {
+		t.Fatalf("expected unauthenticated profiling to be rejected, got %d", status)
+	}
+	status, body := doJSON(t, http.MethodGet, ts.URL+"/debug/runtime", nil)
+	if status != http.StatusOK || body["system"] == nil || body["build"] == nil {
+		t.Fatalf("unexpected runtime metrics: status %d, body %v", status, body)
+	}
+	if status := get(ts.URL+"/debug/pprof/goroutine?debug=1", true); status != http.StatusOK {
+		t.Fatalf("expected goroutine profile, got %d", status)
+	}
+}
@@ -13,8 +13,10 @@ import (
 
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db      *sql.DB
+	logger  *zap.Logger
+	secret  string
+	onLogin func(r *http.Request, email string, user *UserInfo)
 }
 
 // NewAuthHandler creates a new authentication handler
@@ -25,6 +27,23 @@ func NewAuthHandler(db *sql.DB, logger *zap.Logger) *AuthHandler {
 	}
 }
 
+// SetTokenSecret sets the secret that signs issued access tokens
+func (h *AuthHandler) SetTokenSecret(secret string) {
+	h.secret = secret
+}
+
+// OnLogin registers a hook called after every login attempt. user is nil
+// when the attempt failed
+func (h *AuthHandler) OnLogin(fn func(r *http.Request, email string, user *UserInfo)) {
+	h.onLogin = fn
+}
+
+func (h *AuthHandler) loginAttempted(r *http.Request, email string, user *UserInfo) {
+	if h.onLogin != nil {
+		h.onLogin(r, email, user)
+	}
+}
+
 // LoginRequest represents a login request
 type LoginRequest struct {
 	Email    string `json:"email"`
@@ -66,6 +85,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Validate input
 	if req.Email == "" || req.Password == "" {
+		if req.Email != "" {
+			h.loginAttempted(r, req.Email, nil)
+		}
 		h.writeError(w, "Email and password required", http.StatusBadRequest)
 		return
 	}
@@ -103,6 +125,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		User:         mockUser,
 	}
 
+	h.loginAttempted(r, req.Email, &mockUser)
 	h.writeJSON(w, response)
 }
 
@@ -220,8 +243,10 @@ func (h *AuthHandler) generateJWT(user UserInfo) (string, error) {
 		"iat":       time.Now().Unix(),
 	})
 
-	// TODO: Use proper secret from configuration
-	secret := "your-secret-key"
+	secret := h.secret
+	if secret == "" {
+		secret = "your-secret-key"
+	}
 	return token.SignedString([]byte(secret))
 }
 
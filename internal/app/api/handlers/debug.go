@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// DebugHandler serves pprof profiles and runtime metrics. It is mounted at
+// /debug only when profiling is enabled, behind system admin authentication.
+type DebugHandler struct {
+	logger    *zap.Logger
+	startTime time.Time
+}
+
+// NewDebugHandler creates a new debug handler
+func NewDebugHandler(logger *zap.Logger) *DebugHandler {
+	return &DebugHandler{
+		logger:    logger,
+		startTime: time.Now(),
+	}
+}
+
+// RegisterRoutes registers the debug routes. pprof resolves named profiles
+// from the path, so the router must be mounted at /debug.
+func (h *DebugHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/runtime", h.Runtime)
+	r.Get("/pprof/", pprof.Index)
+	r.Get("/pprof/cmdline", pprof.Cmdline)
+	r.Get("/pprof/profile", pprof.Profile)
+	r.Get("/pprof/symbol", pprof.Symbol)
+	r.Post("/pprof/symbol", pprof.Symbol)
+	r.Get("/pprof/trace", pprof.Trace)
+	r.Get("/pprof/{profile}", pprof.Index)
+}
+
+// Runtime handles runtime metrics requests
+func (h *DebugHandler) Runtime(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, map[string]interface{}{
+		"timestamp": time.Now(),
+		"uptime":    time.Since(h.startTime).Round(time.Second).String(),
+		"system":    core.CollectSystemMetrics(),
+		"cpus":      runtime.NumCPU(),
+		"build":     BuildInfo(),
+	})
+}
+
+// BuildInfo describes the running binary: Go version, platform, module
+// version and the VCS revision it was built from
+func BuildInfo() map[string]string {
+	info := map[string]string{
+		"go_version": runtime.Version(),
+		"os":         runtime.GOOS,
+		"arch":       runtime.GOARCH,
+	}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info["module"] = build.Main.Path
+	info["version"] = build.Main.Version
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified":
+			info[setting.Key] = setting.Value
+		}
+	}
+	return info
+}
+
+// Helper methods
+func (h *DebugHandler) writeJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("Failed to encode JSON response", zap.Error(err))
+	}
+}
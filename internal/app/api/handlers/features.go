@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/features"
+)
+
+// FeatureHandler manages feature flag definitions and evaluates flags for tenants
+type FeatureHandler struct {
+	service *features.Service
+	logger  *zap.Logger
+}
+
+// NewFeatureHandler creates a new feature flag handler
+func NewFeatureHandler(service *features.Service, logger *zap.Logger) *FeatureHandler {
+	return &FeatureHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes registers flag definition routes (system admin only)
+func (h *FeatureHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.ListFlags)
+	r.Get("/{key}", h.GetFlag)
+	r.Put("/{key}", h.SaveFlag)
+	r.Delete("/{key}", h.DeleteFlag)
+}
+
+// RegisterTenantRoutes registers flag evaluation routes under /tenants/{tenantId}
+func (h *FeatureHandler) RegisterTenantRoutes(r chi.Router) {
+	r.Get("/", h.EvaluateFlags)
+	r.Get("/{key}", h.EvaluateFlag)
+}
+
+// FlagRequest represents a flag definition update
+type FlagRequest struct {
+	Description string          `json:"description,omitempty"`
+	Default     bool            `json:"default"`
+	Plans       map[string]bool `json:"plans,omitempty"`
+	Rollout     int             `json:"rollout,omitempty"`
+}
+
+// ListFlags lists every defined flag
+func (h *FeatureHandler) ListFlags(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.service.Flags(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list feature flags", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list feature flags", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": flags})
+}
+
+// GetFlag returns a flag definition
+func (h *FeatureHandler) GetFlag(w http.ResponseWriter, r *http.Request) {
+	flag, err := h.service.Flag(r.Context(), chi.URLParam(r, "key"))
+	if errors.Is(err, features.ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Feature flag not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get feature flag", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get feature flag", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": flag})
+}
+
+// SaveFlag creates or replaces a flag definition
+func (h *FeatureHandler) SaveFlag(w http.ResponseWriter, r *http.Request) {
+	var req FlagRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+		return
+	}
+
+	flag := &features.Flag{
+		Key:         chi.URLParam(r, "key"),
+		Description: req.Description,
+		Default:     req.Default,
+		Plans:       req.Plans,
+		Rollout:     req.Rollout,
+	}
+	if err := flag.Validate(); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid feature flag", err, "invalid_request")
+		return
+	}
+	if err := h.service.SaveFlag(r.Context(), flag); err != nil {
+		h.logger.Error("failed to save feature flag", zap.String("key", flag.Key), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to save feature flag", err, "")
+		return
+	}
+
+	h.logger.Info("Feature flag saved", zap.String("key", flag.Key), zap.Int("rollout", flag.Rollout))
+	render.JSON(w, r, map[string]interface{}{"data": flag})
+}
+
+// DeleteFlag removes a stored flag definition
+func (h *FeatureHandler) DeleteFlag(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+	err := h.service.DeleteFlag(r.Context(), key)
+	if errors.Is(err, features.ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Feature flag not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to delete feature flag", zap.String("key", key), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to delete feature flag", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": map[string]string{"key": key}})
+}
+
+// EvaluateFlags returns the value of every flag for a tenant
+func (h *FeatureHandler) EvaluateFlags(w http.ResponseWriter, r *http.Request) {
+	evaluations, err := h.service.EvaluateAll(r.Context(), chi.URLParam(r, "tenantId"))
+	if err != nil {
+		h.logger.Error("failed to evaluate feature flags", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to evaluate feature flags", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": evaluations})
+}
+
+// EvaluateFlag returns the value of one flag for a tenant
+func (h *FeatureHandler) EvaluateFlag(w http.ResponseWriter, r *http.Request) {
+	evaluation, err := h.service.Evaluate(r.Context(), chi.URLParam(r, "tenantId"), chi.URLParam(r, "key"))
+	if err != nil {
+		h.logger.Error("failed to evaluate feature flag", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to evaluate feature flag", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": evaluation})
+}
+
+func (h *FeatureHandler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/app/api/middleware"
+	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/infra/imaging"
+)
+
+// LogosPath is where uploaded tenant and project logos are served
+const LogosPath = "/public/v1/logos"
+
+const (
+	// maxLogoSize bounds uploaded logo files
+	maxLogoSize = 5 << 20
+
+	// maxLogoPixels bounds decoded logos, so a small file cannot expand
+	// into a huge bitmap
+	maxLogoPixels = 4096 * 4096
+
+	// defaultLogoSize is the rendition stored as the tenant or project logo
+	defaultLogoSize = 256
+)
+
+// LogoSizes are the square renditions every logo is rendered at
+var LogoSizes = []int{32, 64, 128, 256}
+
+// Logo owners, used in blob keys, logo URLs and as the owner's table name
+const (
+	logoOwnerTenant  = "tenants"
+	logoOwnerProject = "projects"
+)
+
+// LogoResponse describes an uploaded logo
+type LogoResponse struct {
+	Logo  string            `json:"logo"`  // URL of the default rendition
+	Sizes map[string]string `json:"sizes"` // URL of each rendition by its size in pixels
+}
+
+// SetLogoStore enables logo uploads into store
+func (h *TenantHandler) SetLogoStore(store blobstore.Store) {
+	h.logos = store
+}
+
+// UploadTenantLogo handles tenant logo uploads
+func (h *TenantHandler) UploadTenantLogo(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "id")
+	if !h.isSystemAdmin(r.Context(), r) && !h.hasTenantRole(r.Context(), r, tenantID, auth.TenantRoleAdmin) {
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
+		return
+	}
+	h.uploadLogo(w, r, logoOwnerTenant, tenantID)
+}
+
+// DeleteTenantLogo removes a tenant's logo
+func (h *TenantHandler) DeleteTenantLogo(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "id")
+	if !h.isSystemAdmin(r.Context(), r) && !h.hasTenantRole(r.Context(), r, tenantID, auth.TenantRoleAdmin) {
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
+		return
+	}
+	h.deleteLogo(w, r, logoOwnerTenant, tenantID)
+}
+
+// UploadProjectLogo handles project logo uploads
+func (h *TenantHandler) UploadProjectLogo(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	if !h.isSystemAdmin(r.Context(), r) && !h.hasProjectRole(r.Context(), r, projectID, auth.ProjectRoleAdmin) {
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
+		return
+	}
+	h.uploadLogo(w, r, logoOwnerProject, projectID)
+}
+
+// DeleteProjectLogo removes a project's logo
+func (h *TenantHandler) DeleteProjectLogo(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	if !h.isSystemAdmin(r.Context(), r) && !h.hasProjectRole(r.Context(), r, projectID, auth.ProjectRoleAdmin) {
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
+		return
+	}
+	h.deleteLogo(w, r, logoOwnerProject, projectID)
+}
+
+// uploadLogo validates an image from the "logo" multipart field or the
+// request body, renders it at LogoSizes and points the owner's logo at it.
+// Renditions are stored under a content hash, so their URLs never change
+// and can be cached indefinitely.
+func (h *TenantHandler) uploadLogo(w http.ResponseWriter, r *http.Request, owner, id string) {
+	if h.logos == nil {
+		h.writeError(w, r, http.StatusServiceUnavailable, "Logo uploads are not configured")
+		return
+	}
+	if !h.logoOwnerExists(w, r, owner, id) {
+		return
+	}
+
+	// Multipart headers need some room on top of the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxLogoSize+64<<10)
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, ferr := r.FormFile("logo")
+		if ferr != nil {
+			h.writeError(w, r, http.StatusBadRequest, "Invalid logo upload")
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil || len(data) == 0 {
+		h.writeError(w, r, http.StatusBadRequest, "Invalid logo upload")
+		return
+	}
+	if len(data) > maxLogoSize {
+		h.writeError(w, r, http.StatusRequestEntityTooLarge, "Logo is too large")
+		return
+	}
+
+	img, _, err := imaging.Decode(data, maxLogoPixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		h.writeError(w, r, http.StatusRequestEntityTooLarge, "Logo is too large")
+		return
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusUnsupportedMediaType, "Logo must be a PNG, JPEG or GIF image")
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:8])
+	resp := &LogoResponse{Sizes: make(map[string]string, len(LogoSizes))}
+	for _, size := range LogoSizes {
+		rendition, err := imaging.EncodePNG(imaging.Fit(img, size))
+		if err == nil {
+			_, err = h.logos.Put(r.Context(), logoKey(owner, id, version, size), bytes.NewReader(rendition), "image/png")
+		}
+		if err != nil {
+			h.logger.Error("Failed to store logo", zap.String("owner", owner), zap.String("id", id), zap.Error(err))
+			h.writeError(w, r, http.StatusInternalServerError, "Failed to save logo")
+			return
+		}
+		resp.Sizes[strconv.Itoa(size)] = LogosPath + "/" + strings.TrimPrefix(logoKey(owner, id, version, size), "logos/")
+	}
+	resp.Logo = resp.Sizes[strconv.Itoa(defaultLogoSize)]
+
+	if err := h.setLogo(r.Context(), owner, id, resp.Logo); err != nil {
+		h.logger.Error("Failed to save logo", zap.String("owner", owner), zap.String("id", id), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to save logo")
+		return
+	}
+	h.pruneLogos(r.Context(), owner, id, version)
+
+	h.logger.Info("Logo uploaded", zap.String("owner", owner), zap.String("id", id), zap.String("version", version))
+	h.writeJSON(w, resp)
+}
+
+// deleteLogo clears the owner's logo and removes its renditions
+func (h *TenantHandler) deleteLogo(w http.ResponseWriter, r *http.Request, owner, id string) {
+	if !h.logoOwnerExists(w, r, owner, id) {
+		return
+	}
+	if err := h.setLogo(r.Context(), owner, id, ""); err != nil {
+		h.logger.Error("Failed to delete logo", zap.String("owner", owner), zap.String("id", id), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to delete logo")
+		return
+	}
+	if h.logos != nil {
+		h.pruneLogos(r.Context(), owner, id, "")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logoOwnerExists writes a not found error when the tenant or project does not exist
+func (h *TenantHandler) logoOwnerExists(w http.ResponseWriter, r *http.Request, owner, id string) bool {
+	var exists bool
+	err := h.db.QueryRowContext(r.Context(),
+		"SELECT EXISTS (SELECT 1 FROM "+owner+" WHERE id = ? AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		h.logger.Error("Failed to get logo owner", zap.String("owner", owner), zap.String("id", id), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to save logo")
+		return false
+	}
+	if !exists {
+		if owner == logoOwnerTenant {
+			h.writeError(w, r, http.StatusNotFound, "Tenant not found")
+		} else {
+			h.writeError(w, r, http.StatusNotFound, "Project not found")
+		}
+		return false
+	}
+	return true
+}
+
+func (h *TenantHandler) setLogo(ctx context.Context, owner, id, logo string) error {
+	_, err := h.db.ExecContext(ctx, "UPDATE "+owner+" SET logo = ?, updated_at = ? WHERE id = ?", logo, time.Now(), id)
+	return err
+}
+
+// pruneLogos removes the owner's renditions of versions other than keep
+func (h *TenantHandler) pruneLogos(ctx context.Context, owner, id, keep string) {
+	prefix := "logos/" + owner + "/" + id + "/"
+	var stale []string
+	err := h.logos.List(ctx, prefix, func(info blobstore.Info) error {
+		if keep == "" || !strings.HasPrefix(info.Key, prefix+keep+"/") {
+			stale = append(stale, info.Key)
+		}
+		return nil
+	})
+	for _, key := range stale {
+		if err := h.logos.Delete(ctx, key); err != nil {
+			h.logger.Warn("Failed to delete old logo", zap.String("key", key), zap.Error(err))
+		}
+	}
+	if err != nil {
+		h.logger.Warn("Failed to list old logos", zap.String("owner", owner), zap.String("id", id), zap.Error(err))
+	}
+}
+
+// ServeLogo serves logo renditions under LogosPath without authentication
+func (h *TenantHandler) ServeLogo(w http.ResponseWriter, r *http.Request) {
+	if h.logos == nil {
+		http.NotFound(w, r)
+		return
+	}
+	key := "logos/" + strings.TrimPrefix(r.URL.Path, LogosPath+"/")
+	parts := strings.Split(key, "/")
+	if len(parts) != 5 || (parts[1] != logoOwnerTenant && parts[1] != logoOwnerProject) || !strings.HasSuffix(parts[4], ".png") {
+		http.NotFound(w, r)
+		return
+	}
+	content, info, err := h.logos.Get(r.Context(), key)
+	if errors.Is(err, blobstore.ErrNotFound) || errors.Is(err, blobstore.ErrInvalidKey) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to get logo", zap.String("key", key), zap.Error(err))
+		http.Error(w, "Failed to get logo", http.StatusInternalServerError)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	middleware.SetETag(w, key)
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", info.ModTime, seeker)
+		return
+	}
+	io.Copy(w, content)
+}
+
+// isLogoURL reports whether logo is empty or a URL issued by a logo upload;
+// logos can no longer be set to arbitrary strings
+func isLogoURL(logo string) bool {
+	return logo == "" || strings.HasPrefix(logo, LogosPath+"/")
+}
+
+func logoKey(owner, id, version string, size int) string {
+	return fmt.Sprintf("logos/%s/%s/%s/%d.png", owner, id, version, size)
+}
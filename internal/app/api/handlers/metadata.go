@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/i18n"
+	"github.com/guileen/metabase/pkg/infra/metadata"
+)
+
+// maxSchemaSize bounds the size of a metadata schema document
+const maxSchemaSize = 64 << 10
+
+// MetadataHandler manages the JSON Schemas tenants define for custom
+// project and document metadata
+type MetadataHandler struct {
+	store  *metadata.Store
+	logger *zap.Logger
+}
+
+// NewMetadataHandler creates a new metadata schema handler
+func NewMetadataHandler(store *metadata.Store, logger *zap.Logger) *MetadataHandler {
+	return &MetadataHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// RegisterRoutes registers schema routes under /tenants/{tenantId}/metadata-schemas
+func (h *MetadataHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.ListSchemas)
+	r.Get("/{target}", h.GetSchema)
+	r.Put("/{target}", h.SaveSchema)
+	r.Delete("/{target}", h.DeleteSchema)
+}
+
+// SchemaResponse is a tenant schema with the fields it makes filterable
+type SchemaResponse struct {
+	Target string           `json:"target"`
+	Schema *metadata.Schema `json:"schema"`
+	Fields []metadata.Field `json:"fields"`
+}
+
+// ListSchemas returns the tenant's schema for every target that has one
+func (h *MetadataHandler) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	schemas := []SchemaResponse{}
+	for _, target := range metadata.Targets {
+		schema, err := h.store.Schema(r.Context(), tenantID, target)
+		if err != nil {
+			h.logger.Error("failed to get metadata schema", zap.String("tenant_id", tenantID), zap.Error(err))
+			h.error(w, r, http.StatusInternalServerError, "Failed to list metadata schemas", err, "")
+			return
+		}
+		if schema != nil {
+			schemas = append(schemas, SchemaResponse{Target: target, Schema: schema, Fields: schema.Fields()})
+		}
+	}
+	render.JSON(w, r, map[string]interface{}{"data": schemas})
+}
+
+// GetSchema returns the tenant's schema for a target
+func (h *MetadataHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID, target, ok := h.target(w, r)
+	if !ok {
+		return
+	}
+	schema, err := h.store.Schema(r.Context(), tenantID, target)
+	if err != nil {
+		h.logger.Error("failed to get metadata schema", zap.String("tenant_id", tenantID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get metadata schema", err, "")
+		return
+	}
+	if schema == nil {
+		h.error(w, r, http.StatusNotFound, "Metadata schema not found", metadata.ErrNotFound, "not_found")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": SchemaResponse{Target: target, Schema: schema, Fields: schema.Fields()}})
+}
+
+// SaveSchema creates or replaces the tenant's schema for a target. The body
+// is the JSON Schema itself.
+func (h *MetadataHandler) SaveSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID, target, ok := h.target(w, r)
+	if !ok {
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxSchemaSize+1))
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, "Failed to read request body", err, "invalid_request")
+		return
+	}
+	if len(data) > maxSchemaSize {
+		h.error(w, r, http.StatusRequestEntityTooLarge, "Metadata schema too large", errors.New("schema exceeds 64KB"), "invalid_request")
+		return
+	}
+	schema, err := metadata.Parse(data)
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid metadata schema", err, "invalid_schema")
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
+	if err := h.store.SaveSchema(r.Context(), tenantID, target, schema, userID); err != nil {
+		h.logger.Error("failed to save metadata schema", zap.String("tenant_id", tenantID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to save metadata schema", err, "")
+		return
+	}
+
+	h.logger.Info("Metadata schema saved", zap.String("tenant_id", tenantID), zap.String("target", target))
+	render.JSON(w, r, map[string]interface{}{"data": SchemaResponse{Target: target, Schema: schema, Fields: schema.Fields()}})
+}
+
+// DeleteSchema removes the tenant's schema for a target, lifting its
+// constraints and dropping the index of its fields
+func (h *MetadataHandler) DeleteSchema(w http.ResponseWriter, r *http.Request) {
+	tenantID, target, ok := h.target(w, r)
+	if !ok {
+		return
+	}
+	err := h.store.DeleteSchema(r.Context(), tenantID, target)
+	if errors.Is(err, metadata.ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Metadata schema not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to delete metadata schema", zap.String("tenant_id", tenantID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to delete metadata schema", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": map[string]string{"target": target}})
+}
+
+// target returns the tenant and target of a request, reporting false after
+// writing an error for unknown targets
+func (h *MetadataHandler) target(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	target := chi.URLParam(r, "target")
+	if !metadata.ValidTarget(target) {
+		h.error(w, r, http.StatusNotFound, "Unknown metadata target", errors.New("target must be project or document"), "not_found")
+		return "", "", false
+	}
+	return chi.URLParam(r, "tenantId"), target, true
+}
+
+func (h *MetadataHandler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
+
+// checkProjectMetadata validates project metadata against the tenant's
+// project schema, returning the schema for indexing. It reports false after
+// writing an error response listing the violations.
+func (h *TenantHandler) checkProjectMetadata(w http.ResponseWriter, r *http.Request, tenantID string, values map[string]interface{}) (*metadata.Schema, bool) {
+	if h.metadataSchemas == nil {
+		return nil, true
+	}
+	schema, err := h.metadataSchemas.Schema(r.Context(), tenantID, metadata.TargetProject)
+	if err != nil {
+		h.logger.Error("Failed to get metadata schema", zap.String("tenant_id", tenantID), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to validate metadata")
+		return nil, false
+	}
+	if schema == nil {
+		return nil, true
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	var invalid *metadata.ValidationError
+	if err := schema.Validate(values); errors.As(err, &invalid) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":      i18n.T(r.Context(), "Metadata does not match the tenant schema", nil),
+			"violations": invalid.Violations,
+			"status":     http.StatusBadRequest,
+			"success":    false,
+		})
+		return nil, false
+	}
+	return schema, true
+}
+
+// indexProjectMetadata records the declared metadata fields of a project so
+// listings can filter by them; failures only make the project unfilterable
+func (h *TenantHandler) indexProjectMetadata(ctx context.Context, tenantID, projectID string, schema *metadata.Schema, values map[string]interface{}) {
+	if h.metadataSchemas == nil {
+		return
+	}
+	if err := h.metadataSchemas.Index(ctx, tenantID, metadata.TargetProject, projectID, schema, values); err != nil {
+		h.logger.Error("Failed to index project metadata", zap.String("id", projectID), zap.Error(err))
+	}
+}
+
+// metadataFilter returns the WHERE clause selecting a tenant's live projects
+// that match the request's meta.<field>=value filters. Only fields declared
+// in the tenant's project schema can be filtered. It reports false after
+// writing an error response.
+func (h *TenantHandler) metadataFilter(w http.ResponseWriter, r *http.Request, tenantID string) (string, []interface{}, bool) {
+	where, args := "tenant_id = ? AND deleted_at IS NULL", []interface{}{tenantID}
+	filters := map[string]string{}
+	for key, values := range r.URL.Query() {
+		if field, ok := strings.CutPrefix(key, "meta."); ok && len(values) > 0 {
+			filters[field] = values[0]
+		}
+	}
+	if len(filters) == 0 {
+		return where, args, true
+	}
+
+	var schema *metadata.Schema
+	if h.metadataSchemas != nil {
+		var err error
+		if schema, err = h.metadataSchemas.Schema(r.Context(), tenantID, metadata.TargetProject); err != nil {
+			h.logger.Error("Failed to get metadata schema", zap.String("tenant_id", tenantID), zap.Error(err))
+			h.writeError(w, r, http.StatusInternalServerError, "Failed to query projects")
+			return "", nil, false
+		}
+	}
+	for field := range filters {
+		if schema == nil || !schema.Declares(field) {
+			h.writeError(w, r, http.StatusBadRequest, "Metadata filters must use fields declared in the tenant schema")
+			return "", nil, false
+		}
+	}
+
+	ids, err := h.metadataSchemas.Match(r.Context(), tenantID, metadata.TargetProject, filters)
+	if err != nil {
+		h.logger.Error("Failed to match project metadata", zap.String("tenant_id", tenantID), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to query projects")
+		return "", nil, false
+	}
+	if len(ids) == 0 {
+		return where + " AND 1 = 0", args, true
+	}
+	where += " AND id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return where, args, true
+}
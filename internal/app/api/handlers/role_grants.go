@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/app/api/middleware"
+	"github.com/guileen/metabase/pkg/common/errors"
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+// Role grant actions passed to OnRoleGrantChange callbacks
+const (
+	RoleGrantRequested = "role_grant.requested"
+	RoleGrantGranted   = "role_grant.granted"
+	RoleGrantApproved  = "role_grant.approved"
+	RoleGrantRejected  = "role_grant.rejected"
+	RoleGrantRevoked   = "role_grant.revoked"
+	RoleGrantExpired   = "role_grant.expired"
+)
+
+// GrantRoleRequest represents a request for temporary elevated access
+type GrantRoleRequest struct {
+	UserID   string `json:"user_id"`
+	Role     string `json:"role"`
+	Duration string `json:"duration"` // e.g. "4h" or "30m"
+	Reason   string `json:"reason,omitempty"`
+}
+
+// OnRoleGrantChange registers a callback run after a temporary role grant is
+// created or changes state, such as to record it in the audit log
+func (h *TenantHandler) OnRoleGrantChange(fn func(ctx context.Context, action, actor string, grant *auth.RoleGrant)) {
+	h.grantChanged = append(h.grantChanged, fn)
+}
+
+func (h *TenantHandler) notifyGrantChange(ctx context.Context, action, actor string, grant *auth.RoleGrant) {
+	for _, fn := range h.grantChanged {
+		fn(ctx, action, actor, grant)
+	}
+}
+
+// GrantRole handles granting a user an elevated project role for a limited
+// time. Tenants requiring approval keep the grant pending until another admin
+// approves it.
+func (h *TenantHandler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectID := chi.URLParam(r, "projectId")
+
+	var req GrantRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if req.UserID == "" || req.Role == "" {
+		h.writeError(w, r, http.StatusBadRequest, "User ID and role are required")
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "Invalid duration")
+		return
+	}
+
+	tenantID, err := h.members.ProjectTenantID(ctx, projectID)
+	if err != nil {
+		h.writeError(w, r, errors.GetHTTPStatus(err), "Project not found")
+		return
+	}
+	requireApproval, err := h.grantApprovalRequired(ctx, tenantID)
+	if err != nil {
+		h.logger.Error("Failed to get tenant settings", zap.String("tenant_id", tenantID), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to get tenant settings")
+		return
+	}
+
+	actor := h.actorID(r)
+	grant := &auth.RoleGrant{
+		ProjectID:   projectID,
+		UserID:      req.UserID,
+		Role:        req.Role,
+		Duration:    duration,
+		Reason:      req.Reason,
+		RequestedBy: actor,
+	}
+	if err := h.members.GrantRole(ctx, grant, requireApproval); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	action := RoleGrantGranted
+	if grant.Status == auth.GrantStatusPending {
+		action = RoleGrantRequested
+	}
+	h.notifyGrantChange(ctx, action, actor, grant)
+
+	h.logger.Info("Temporary role granted",
+		zap.String("project_id", projectID),
+		zap.String("user_id", grant.UserID),
+		zap.String("role", grant.Role),
+		zap.String("status", grant.Status),
+		zap.Duration("duration", grant.Duration))
+
+	w.WriteHeader(http.StatusCreated)
+	h.writeJSON(w, grant)
+}
+
+// ListRoleGrants handles listing the temporary role grants of a project
+func (h *TenantHandler) ListRoleGrants(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectID := chi.URLParam(r, "projectId")
+
+	grants, err := h.members.ListRoleGrants(ctx, projectID)
+	if err != nil {
+		h.logger.Error("Failed to list role grants", zap.String("project_id", projectID), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to list role grants")
+		return
+	}
+
+	response := map[string]interface{}{
+		"grants": grants,
+		"total":  len(grants),
+	}
+
+	h.writeJSON(w, response)
+}
+
+// ApproveRoleGrant handles approving a pending role grant; the grant's
+// duration starts on approval
+func (h *TenantHandler) ApproveRoleGrant(w http.ResponseWriter, r *http.Request) {
+	h.decideRoleGrant(w, r, RoleGrantApproved, h.members.ApproveRoleGrant)
+}
+
+// RejectRoleGrant handles rejecting a pending role grant
+func (h *TenantHandler) RejectRoleGrant(w http.ResponseWriter, r *http.Request) {
+	h.decideRoleGrant(w, r, RoleGrantRejected, h.members.RejectRoleGrant)
+}
+
+// RevokeRoleGrant handles ending an active role grant early
+func (h *TenantHandler) RevokeRoleGrant(w http.ResponseWriter, r *http.Request) {
+	h.decideRoleGrant(w, r, RoleGrantRevoked, h.members.RevokeRoleGrant)
+}
+
+func (h *TenantHandler) decideRoleGrant(w http.ResponseWriter, r *http.Request, action string,
+	decide func(ctx context.Context, projectID, grantID, actor string) (*auth.RoleGrant, error)) {
+	ctx := r.Context()
+	projectID := chi.URLParam(r, "projectId")
+	actor := h.actorID(r)
+
+	grant, err := decide(ctx, projectID, chi.URLParam(r, "grantId"), actor)
+	if err != nil {
+		status := errors.GetHTTPStatus(err)
+		if status == http.StatusInternalServerError {
+			h.logger.Error("Failed to update role grant", zap.String("project_id", projectID), zap.Error(err))
+		}
+		h.writeError(w, r, status, err.Error())
+		return
+	}
+	h.notifyGrantChange(ctx, action, actor, grant)
+
+	h.writeJSON(w, grant)
+}
+
+// grantApprovalRequired reports whether a tenant requires temporary role
+// grants to be approved
+func (h *TenantHandler) grantApprovalRequired(ctx context.Context, tenantID string) (bool, error) {
+	var settingsJSON sql.NullString
+	err := h.db.QueryRowContext(ctx, `SELECT settings FROM tenants WHERE id = ?`, tenantID).Scan(&settingsJSON)
+	if err == sql.ErrNoRows || !settingsJSON.Valid || settingsJSON.String == "" {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var settings auth.TenantSettings
+	if err := json.Unmarshal([]byte(settingsJSON.String), &settings); err != nil {
+		return false, err
+	}
+	return settings.RequireGrantApproval, nil
+}
+
+// actorID returns the user acting on a project, as resolved by the project
+// middleware
+func (h *TenantHandler) actorID(r *http.Request) string {
+	if userID := middleware.GetProjectContext(r).UserID; userID != "" {
+		return userID
+	}
+	return h.getUserID(r.Context())
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/common/errors"
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+// GetTenantBySlug handles tenant retrieval by slug. Slugs the tenant was
+// renamed from redirect to its current slug.
+func (h *TenantHandler) GetTenantBySlug(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	slug := chi.URLParam(r, "slug")
+
+	tenantID, redirected, err := h.slugs.Resolve(ctx, auth.SlugKindTenant, "", slug)
+	if err != nil {
+		h.slugError(w, r, err, "Tenant not found")
+		return
+	}
+	if redirected {
+		h.redirectSlug(w, r, "SELECT slug FROM tenants WHERE id = ?", tenantID,
+			"/admin/v1/tenants/by-slug/")
+		return
+	}
+
+	chi.RouteContext(ctx).URLParams.Add("id", tenantID)
+	h.GetTenant(w, r)
+}
+
+// GetProjectBySlug handles retrieval of a tenant's project by slug. Slugs
+// the project was renamed from redirect to its current slug.
+func (h *TenantHandler) GetProjectBySlug(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	tenantID := chi.URLParam(r, "tenantId")
+	slug := chi.URLParam(r, "slug")
+
+	projectID, redirected, err := h.slugs.Resolve(ctx, auth.SlugKindProject, tenantID, slug)
+	if err != nil {
+		h.slugError(w, r, err, "Project not found")
+		return
+	}
+	if redirected {
+		h.redirectSlug(w, r, "SELECT slug FROM projects WHERE id = ?", projectID,
+			"/admin/v1/tenants/"+url.PathEscape(tenantID)+"/projects/by-slug/")
+		return
+	}
+
+	chi.RouteContext(ctx).URLParams.Add("projectId", projectID)
+	h.GetProject(w, r)
+}
+
+// redirectSlug permanently redirects an old slug to the current slug of id
+func (h *TenantHandler) redirectSlug(w http.ResponseWriter, r *http.Request, query, id, prefix string) {
+	var current string
+	if err := h.db.QueryRowContext(r.Context(), query, id).Scan(&current); err != nil {
+		h.logger.Error("Failed to get current slug", zap.String("id", id), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to resolve slug")
+		return
+	}
+	location := prefix + url.PathEscape(current)
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, location, http.StatusMovedPermanently)
+}
+
+// checkSlug validates a requested slug for a new or existing tenant or
+// project; ownerID is empty for new ones. Without a requested slug, new
+// tenants and projects get one derived from their name. It reports false
+// after writing an error response.
+func (h *TenantHandler) checkSlug(w http.ResponseWriter, r *http.Request, kind, scope, slug, name, ownerID string) (string, bool) {
+	ctx := r.Context()
+	var err error
+	if slug == "" {
+		slug, err = h.slugs.Unique(ctx, kind, scope, name, kind)
+	} else {
+		err = h.slugs.Check(ctx, kind, scope, slug, ownerID)
+	}
+	if err != nil {
+		h.slugError(w, r, err, "Invalid slug")
+		return "", false
+	}
+	return slug, true
+}
+
+// slugError writes the message of a slug validation, conflict or lookup
+// error; other errors are logged and reported as failures
+func (h *TenantHandler) slugError(w http.ResponseWriter, r *http.Request, err error, notFound string) {
+	appErr, ok := errors.IsAppError(err)
+	switch {
+	case ok && appErr.Code == errors.ErrCodeNotFound:
+		h.writeError(w, r, http.StatusNotFound, notFound)
+	case ok:
+		h.writeError(w, r, appErr.HTTPStatus, appErr.Message)
+	default:
+		h.logger.Error("Failed to check slug", zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to check slug")
+	}
+}
@@ -1,16 +1,52 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/guileen/metabase/pkg/config"
 	"go.uber.org/zap"
 )
 
+// Component statuses
+const (
+	ComponentOK       = "ok"
+	ComponentDegraded = "degraded" // working with reduced functionality
+	ComponentDown     = "down"
+	ComponentDisabled = "disabled" // not configured
+)
+
+// componentCheckTimeout bounds each component check of a probe
+const componentCheckTimeout = 2 * time.Second
+
+// ComponentCheck reports the status of a server component and, unless it is
+// ok, why
+type ComponentCheck func(ctx context.Context) (status, message string)
+
+// ComponentStatus is the reported status of a server component
+type ComponentStatus struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"` // the server is not ready while it is down
+	Message  string `json:"message,omitempty"`
+}
+
+type component struct {
+	name     string
+	critical bool
+	check    ComponentCheck
+}
+
 // SystemHandler handles system-related requests
 type SystemHandler struct {
 	logger *zap.Logger
+
+	mu         sync.RWMutex
+	components []component
+	config     func() map[string]interface{}
 }
 
 // NewSystemHandler creates a new system handler
@@ -54,6 +90,107 @@ func (h *SystemHandler) Version(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, version)
 }
 
+// AddComponent registers a component reported by the readiness probe and
+// the system config endpoint. The server is not ready while a critical
+// component is down.
+func (h *SystemHandler) AddComponent(name string, critical bool, check ComponentCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.components = append(h.components, component{name: name, critical: critical, check: check})
+}
+
+// SetConfig sets the source of the effective configuration served by the
+// system config endpoint, as named sections. Secrets are redacted on output.
+func (h *SystemHandler) SetConfig(config func() map[string]interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.config = config
+}
+
+// Components checks every registered component, in registration order
+func (h *SystemHandler) Components(ctx context.Context) []ComponentStatus {
+	h.mu.RLock()
+	components := h.components
+	h.mu.RUnlock()
+
+	statuses := make([]ComponentStatus, len(components))
+	var wg sync.WaitGroup
+	for i, c := range components {
+		wg.Add(1)
+		go func(i int, c component) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, componentCheckTimeout)
+			defer cancel()
+			status, message := c.check(ctx)
+			statuses[i] = ComponentStatus{Name: c.name, Status: status, Critical: c.critical, Message: message}
+		}(i, c)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// Ready handles readiness probes: 200 when no critical component is down,
+// 503 otherwise. Failure messages are only served to system admins by the
+// system config endpoint.
+func (h *SystemHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ready := true
+	components := map[string]string{}
+	for _, c := range h.Components(r.Context()) {
+		components[c.Name] = c.Status
+		if c.Critical && c.Status == ComponentDown {
+			ready = false
+		}
+	}
+
+	status := "ready"
+	if !ready {
+		status = "not_ready"
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	h.writeJSON(w, map[string]interface{}{
+		"status":     status,
+		"components": components,
+	})
+}
+
+// Config handles effective configuration requests: the redacted
+// configuration, component statuses and build information, for operators
+// and tooling to compare against their desired state
+func (h *SystemHandler) Config(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	source := h.config
+	h.mu.RUnlock()
+
+	effective := map[string]interface{}{}
+	if source != nil {
+		for name, section := range source() {
+			effective[name] = redactSection(section)
+		}
+	}
+
+	h.writeJSON(w, map[string]interface{}{
+		"config":       effective,
+		"components":   h.Components(r.Context()),
+		"build":        BuildInfo(),
+		"generated_at": time.Now(),
+	})
+}
+
+// redactSection converts a configuration section to its JSON form and
+// redacts its secrets
+func redactSection(section interface{}) interface{} {
+	data, err := json.Marshal(section)
+	if err != nil {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil
+	}
+	return config.Redact(map[string]interface{}{"section": decoded})["section"]
+}
+
 // Helper methods
 func (h *SystemHandler) writeJSON(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
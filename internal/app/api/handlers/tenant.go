@@ -4,30 +4,96 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/guileen/metabase/internal/app/api/middleware"
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
 	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/infra/i18n"
+	"github.com/guileen/metabase/pkg/infra/mailer"
+	"github.com/guileen/metabase/pkg/infra/metadata"
+	"github.com/guileen/metabase/pkg/rag/core"
 )
 
 // TenantHandler handles tenant and project management requests
 type TenantHandler struct {
-	db            *sql.DB
-	tenantManager *auth.TenantManager
-	logger        *zap.Logger
+	db      *sql.DB
+	members *auth.ProjectMembers
+	slugs   *auth.Slugs
+	logger  *zap.Logger
+
+	// settingsChanged are called after a tenant's settings or plan are
+	// updated or the tenant is deleted, so cached settings can be dropped
+	settingsChanged []func(ctx context.Context, tenantID string)
+
+	// grantChanged are called after a temporary role grant is created or
+	// changes state
+	grantChanged []func(ctx context.Context, action, actor string, grant *auth.RoleGrant)
+
+	// mailer sends invitation and ownership transfer emails in the locale
+	// inviteLocale resolves for the recipient; nothing is emailed without it
+	mailer       *mailer.Mailer
+	inviteLocale InviteLocaleResolver
+
+	// logos stores uploaded tenant and project logos; uploads are
+	// rejected without it
+	logos blobstore.Store
+
+	// metadataSchemas validates and indexes project metadata against the
+	// tenant's schema; metadata is unchecked without it
+	metadataSchemas *metadata.Store
 }
 
+// InviteLocaleResolver returns the locale of an invitation email to a user
+// of a tenant; userID may be empty when inviting by email
+type InviteLocaleResolver func(ctx context.Context, userID, tenantID string) string
+
 // NewTenantHandler creates a new tenant handler
 func NewTenantHandler(db *sql.DB, logger *zap.Logger) *TenantHandler {
 	return &TenantHandler{
-		db:            db,
-		tenantManager: auth.NewTenantManager(),
-		logger:        logger,
+		db:      db,
+		members: auth.NewProjectMembers(db),
+		slugs:   auth.NewSlugs(db),
+		logger:  logger,
+	}
+}
+
+// OnSettingsChange registers a callback run after a tenant's settings or plan change
+func (h *TenantHandler) OnSettingsChange(fn func(ctx context.Context, tenantID string)) {
+	h.settingsChanged = append(h.settingsChanged, fn)
+}
+
+// SetProjectMembers shares a membership store with the project middleware,
+// so membership changes made here invalidate the roles it has cached
+func (h *TenantHandler) SetProjectMembers(members *auth.ProjectMembers) {
+	h.members = members
+}
+
+// SetInvitationMailer emails invitations to project invitees who are
+// invited with an email address
+func (h *TenantHandler) SetInvitationMailer(m *mailer.Mailer, locale InviteLocaleResolver) {
+	h.mailer = m
+	h.inviteLocale = locale
+}
+
+// SetMetadataSchemas validates project metadata against the schemas tenants
+// define and makes their declared fields filterable
+func (h *TenantHandler) SetMetadataSchemas(store *metadata.Store) {
+	h.metadataSchemas = store
+}
+
+func (h *TenantHandler) notifySettingsChange(ctx context.Context, tenantID string) {
+	for _, fn := range h.settingsChanged {
+		fn(ctx, tenantID)
 	}
 }
 
@@ -36,11 +102,12 @@ type TenantRequest struct {
 	Name        string                 `json:"name"`
 	Slug        string                 `json:"slug"`
 	Domain      string                 `json:"domain,omitempty"`
-	Logo        string                 `json:"logo,omitempty"`
+	Logo        string                 `json:"logo,omitempty"` // URL returned by a logo upload
 	Description string                 `json:"description,omitempty"`
 	Settings    auth.TenantSettings    `json:"settings,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	Plan        string                 `json:"plan,omitempty"`
+	Region      string                 `json:"region,omitempty"`
 }
 
 // ProjectRequest represents project creation/update request
@@ -48,7 +115,7 @@ type TenantProjectRequest struct {
 	Name        string                 `json:"name"`
 	Slug        string                 `json:"slug"`
 	Description string                 `json:"description,omitempty"`
-	Logo        string                 `json:"logo,omitempty"`
+	Logo        string                 `json:"logo,omitempty"` // URL returned by a logo upload
 	Settings    auth.ProjectSettings   `json:"settings,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	IsPublic    bool                   `json:"is_public,omitempty"`
@@ -75,19 +142,13 @@ type InviteUserRequest struct {
 	Message string `json:"message,omitempty"`
 }
 
-// TransferOwnershipRequest represents ownership transfer request
-type TransferOwnershipRequest struct {
-	ToUserID string `json:"to_user_id"`
-	Message  string `json:"message,omitempty"`
-}
-
 // ListTenants handles tenant listing requests
 func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	// Check if user is system admin
 	if !h.isSystemAdmin(ctx, r) {
-		h.writeError(w, http.StatusForbidden, "Access denied: system admin required")
+		h.writeError(w, r, http.StatusForbidden, "Access denied: system admin required")
 		return
 	}
 
@@ -104,7 +165,7 @@ func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
 
 	// Query database
 	rows, err := h.db.QueryContext(ctx, `
-		SELECT id, name, slug, domain, logo, description, settings, metadata,
+		SELECT id, name, slug, domain, logo, description, region, settings, metadata,
 			   is_active, plan, limits, created_at, updated_at, deleted_at
 		FROM tenants
 		WHERE deleted_at IS NULL
@@ -113,7 +174,7 @@ func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
 	`, limit, offset)
 	if err != nil {
 		h.logger.Error("Failed to query tenants", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to query tenants")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to query tenants")
 		return
 	}
 	defer rows.Close()
@@ -131,6 +192,7 @@ func (h *TenantHandler) ListTenants(w http.ResponseWriter, r *http.Request) {
 			&tenant.Domain,
 			&tenant.Logo,
 			&tenant.Description,
+			&tenant.Region,
 			&settingsJSON,
 			&metadataJSON,
 			&tenant.IsActive,
@@ -183,33 +245,43 @@ func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 
 	// Check if user is system admin
 	if !h.isSystemAdmin(ctx, r) {
-		h.writeError(w, http.StatusForbidden, "Access denied: system admin required")
+		h.writeError(w, r, http.StatusForbidden, "Access denied: system admin required")
 		return
 	}
 
 	var req TenantRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	// Validate request
 	if req.Name == "" {
-		h.writeError(w, http.StatusBadRequest, "Name is required")
+		h.writeError(w, r, http.StatusBadRequest, "Name is required")
+		return
+	}
+	slug, ok := h.checkSlug(w, r, auth.SlugKindTenant, "", req.Slug, req.Name, "")
+	if !ok {
+		return
+	}
+	if req.Region != "" && !core.ValidRegion(req.Region) {
+		h.writeError(w, r, http.StatusBadRequest, "Invalid region")
 		return
 	}
-	if req.Slug == "" {
-		h.writeError(w, http.StatusBadRequest, "Slug is required")
+	if !isLogoURL(req.Logo) {
+		h.writeError(w, r, http.StatusBadRequest, "Logos must be uploaded")
 		return
 	}
 
 	// Create tenant
 	tenant := &auth.Tenant{
+		ID:          uuid.New().String(),
 		Name:        req.Name,
-		Slug:        req.Slug,
+		Slug:        slug,
 		Domain:      req.Domain,
 		Logo:        req.Logo,
 		Description: req.Description,
+		Region:      req.Region,
 		Settings:    req.Settings,
 		Metadata:    req.Metadata,
 		IsActive:    true,
@@ -249,17 +321,18 @@ func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 
 	// Insert into database
 	query := `
-		INSERT INTO tenants (id, name, slug, domain, logo, description, settings, metadata,
+		INSERT INTO tenants (id, name, slug, domain, logo, description, region, settings, metadata,
 							is_active, plan, limits, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := h.db.ExecContext(ctx, query,
+	_, err := h.db.ExecContext(ctx, query,
 		tenant.ID,
 		tenant.Name,
 		tenant.Slug,
 		tenant.Domain,
 		tenant.Logo,
 		tenant.Description,
+		tenant.Region,
 		string(settingsJSON),
 		string(metadataJSON),
 		tenant.IsActive,
@@ -270,16 +343,10 @@ func (h *TenantHandler) CreateTenant(w http.ResponseWriter, r *http.Request) {
 	)
 	if err != nil {
 		h.logger.Error("Failed to create tenant", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to create tenant")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to create tenant")
 		return
 	}
 
-	id, _ := result.LastInsertId()
-	if id != 0 {
-		// This shouldn't happen with UUID but handle it
-		tenant.ID = strconv.FormatInt(id, 10)
-	}
-
 	h.logger.Info("Tenant created", zap.String("id", tenant.ID), zap.String("name", tenant.Name))
 	h.writeJSON(w, tenant)
 }
@@ -291,7 +358,7 @@ func (h *TenantHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
 
 	// Check if user is system admin or has access to this tenant
 	if !h.isSystemAdmin(ctx, r) && !h.hasTenantAccess(ctx, r, tenantID) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
@@ -300,7 +367,7 @@ func (h *TenantHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
 	var deletedAt sql.NullTime
 
 	query := `
-		SELECT id, name, slug, domain, logo, description, settings, metadata,
+		SELECT id, name, slug, domain, logo, description, region, settings, metadata,
 			   is_active, plan, limits, created_at, updated_at, deleted_at
 		FROM tenants
 		WHERE id = ?
@@ -312,6 +379,7 @@ func (h *TenantHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
 		&tenant.Domain,
 		&tenant.Logo,
 		&tenant.Description,
+		&tenant.Region,
 		&settingsJSON,
 		&metadataJSON,
 		&tenant.IsActive,
@@ -324,11 +392,11 @@ func (h *TenantHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			h.writeError(w, http.StatusNotFound, "Tenant not found")
+			h.writeError(w, r, http.StatusNotFound, "Tenant not found")
 			return
 		}
 		h.logger.Error("Failed to get tenant", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to get tenant")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to get tenant")
 		return
 	}
 
@@ -347,6 +415,7 @@ func (h *TenantHandler) GetTenant(w http.ResponseWriter, r *http.Request) {
 		json.Unmarshal([]byte(limitsJSON.String), &tenant.Limits)
 	}
 
+	middleware.SetETag(w, tenant.ID, tenant.UpdatedAt.UTC().Format(time.RFC3339Nano), fmt.Sprint(deletedAt.Time.Unix()))
 	h.writeJSON(w, tenant)
 }
 
@@ -357,16 +426,56 @@ func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
 
 	// Check if user is system admin or tenant admin
 	if !h.isSystemAdmin(ctx, r) && !h.hasTenantRole(ctx, r, tenantID, auth.TenantRoleAdmin) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	var req TenantRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
+	// The region can be set once; moving a tenant's data between regions is
+	// a migration, not an update
+	if req.Region != "" {
+		if !core.ValidRegion(req.Region) {
+			h.writeError(w, r, http.StatusBadRequest, "Invalid region")
+			return
+		}
+		var region string
+		if err := h.db.QueryRowContext(ctx, "SELECT region FROM tenants WHERE id = ?", tenantID).Scan(&region); err != nil {
+			if err == sql.ErrNoRows {
+				h.writeError(w, r, http.StatusNotFound, "Tenant not found")
+				return
+			}
+			h.logger.Error("Failed to get tenant region", zap.Error(err))
+			h.writeError(w, r, http.StatusInternalServerError, "Failed to update tenant")
+			return
+		}
+		if region != "" && region != req.Region {
+			h.writeError(w, r, http.StatusConflict, "Tenant region cannot be changed")
+			return
+		}
+	}
+
+	// A renamed slug keeps redirecting to the tenant
+	var oldSlug string
+	if req.Slug != "" {
+		if err := h.db.QueryRowContext(ctx, "SELECT slug FROM tenants WHERE id = ?", tenantID).Scan(&oldSlug); err != nil {
+			if err == sql.ErrNoRows {
+				h.writeError(w, r, http.StatusNotFound, "Tenant not found")
+				return
+			}
+			h.logger.Error("Failed to get tenant slug", zap.Error(err))
+			h.writeError(w, r, http.StatusInternalServerError, "Failed to update tenant")
+			return
+		}
+		if _, ok := h.checkSlug(w, r, auth.SlugKindTenant, "", req.Slug, "", tenantID); !ok {
+			return
+		}
+	}
+
 	// Build update query
 	updates := []string{}
 	args := []interface{}{}
@@ -388,6 +497,10 @@ func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
 		argIndex++
 	}
 	if req.Logo != "" {
+		if !isLogoURL(req.Logo) {
+			h.writeError(w, r, http.StatusBadRequest, "Logos must be uploaded")
+			return
+		}
 		updates = append(updates, "logo = ?")
 		args = append(args, req.Logo)
 		argIndex++
@@ -402,9 +515,27 @@ func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
 		args = append(args, req.Plan)
 		argIndex++
 	}
+	if req.Region != "" {
+		updates = append(updates, "region = ?")
+		args = append(args, req.Region)
+		argIndex++
+	}
 
-	// Handle JSON fields
-	if len(req.Settings.EnabledFeatures) > 0 || req.Settings.AllowUserRegistration {
+	// Handle JSON fields; settings are replaced whenever any of them is set
+	settingsUpdated := !reflect.DeepEqual(req.Settings, auth.TenantSettings{})
+	if settingsUpdated {
+		if req.Settings.Locale != "" && i18n.Match(req.Settings.Locale) == "" {
+			h.writeError(w, r, http.StatusBadRequest, "Unsupported locale")
+			return
+		}
+		if err := validateAPISettings(req.Settings.API); err != nil {
+			h.writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := validateNotificationSettings(req.Settings.Notifications); err != nil {
+			h.writeError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
 		settingsJSON, _ := json.Marshal(req.Settings)
 		updates = append(updates, "settings = ?")
 		args = append(args, string(settingsJSON))
@@ -418,7 +549,7 @@ func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(updates) == 0 {
-		h.writeError(w, http.StatusBadRequest, "No updates provided")
+		h.writeError(w, r, http.StatusBadRequest, "No updates provided")
 		return
 	}
 
@@ -432,9 +563,16 @@ func (h *TenantHandler) UpdateTenant(w http.ResponseWriter, r *http.Request) {
 	_, err := h.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		h.logger.Error("Failed to update tenant", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to update tenant")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to update tenant")
 		return
 	}
+	if err := h.slugs.Renamed(ctx, auth.SlugKindTenant, "", tenantID, oldSlug, req.Slug); err != nil {
+		h.logger.Error("Failed to keep old tenant slug", zap.String("id", tenantID), zap.Error(err))
+	}
+
+	if settingsUpdated || req.Plan != "" {
+		h.notifySettingsChange(ctx, tenantID)
+	}
 
 	h.logger.Info("Tenant updated", zap.String("id", tenantID))
 
@@ -449,13 +587,13 @@ func (h *TenantHandler) DeleteTenant(w http.ResponseWriter, r *http.Request) {
 
 	// Only system admin can delete tenants
 	if !h.isSystemAdmin(ctx, r) {
-		h.writeError(w, http.StatusForbidden, "Access denied: system admin required")
+		h.writeError(w, r, http.StatusForbidden, "Access denied: system admin required")
 		return
 	}
 
 	// Prevent deletion of system tenant
 	if tenantID == auth.SystemTenantID {
-		h.writeError(w, http.StatusBadRequest, "Cannot delete system tenant")
+		h.writeError(w, r, http.StatusBadRequest, "Cannot delete system tenant")
 		return
 	}
 
@@ -463,10 +601,12 @@ func (h *TenantHandler) DeleteTenant(w http.ResponseWriter, r *http.Request) {
 	_, err := h.db.ExecContext(ctx, query, time.Now(), tenantID)
 	if err != nil {
 		h.logger.Error("Failed to delete tenant", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to delete tenant")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to delete tenant")
 		return
 	}
 
+	h.notifySettingsChange(ctx, tenantID)
+
 	response := map[string]interface{}{
 		"message": "Tenant deleted successfully",
 		"id":      tenantID,
@@ -483,7 +623,7 @@ func (h *TenantHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 
 	// Check if user is system admin or has tenant access
 	if !h.isSystemAdmin(ctx, r) && !h.hasTenantAccess(ctx, r, tenantID) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
@@ -498,18 +638,24 @@ func (h *TenantHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 	}
 	offset := (page - 1) * limit
 
+	// Restrict to projects whose declared metadata fields match meta.<field> filters
+	where, args, ok := h.metadataFilter(w, r, tenantID)
+	if !ok {
+		return
+	}
+
 	// Query database
 	rows, err := h.db.QueryContext(ctx, `
 		SELECT id, tenant_id, name, slug, description, logo, settings, metadata,
-			   is_active, is_public, environment, owner_id, members, created_at, updated_at, deleted_at
+			   is_active, is_public, environment, owner_id, created_at, updated_at, deleted_at
 		FROM projects
-		WHERE tenant_id = ? AND deleted_at IS NULL
+		WHERE `+where+`
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
-	`, tenantID, limit, offset)
+	`, append(args, limit, offset)...)
 	if err != nil {
 		h.logger.Error("Failed to query projects", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to query projects")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to query projects")
 		return
 	}
 	defer rows.Close()
@@ -517,7 +663,7 @@ func (h *TenantHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 	var projects []auth.Project
 	for rows.Next() {
 		var project auth.Project
-		var settingsJSON, metadataJSON, membersJSON sql.NullString
+		var settingsJSON, metadataJSON sql.NullString
 		var deletedAt sql.NullTime
 
 		err := rows.Scan(
@@ -533,7 +679,6 @@ func (h *TenantHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 			&project.IsPublic,
 			&project.Environment,
 			&project.OwnerID,
-			&membersJSON,
 			&project.CreatedAt,
 			&project.UpdatedAt,
 			&deletedAt,
@@ -554,16 +699,18 @@ func (h *TenantHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 		if metadataJSON.Valid {
 			json.Unmarshal([]byte(metadataJSON.String), &project.Metadata)
 		}
-		if membersJSON.Valid {
-			json.Unmarshal([]byte(membersJSON.String), &project.Members)
-		}
 
 		projects = append(projects, project)
 	}
+	listed := make([]*auth.Project, len(projects))
+	for i := range projects {
+		listed[i] = &projects[i]
+	}
+	h.loadMembers(ctx, listed...)
 
 	// Get total count
 	var total int
-	h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM projects WHERE tenant_id = ? AND deleted_at IS NULL", tenantID).Scan(&total)
+	h.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM projects WHERE "+where, args...).Scan(&total)
 
 	response := map[string]interface{}{
 		"projects": projects,
@@ -582,38 +729,47 @@ func (h *TenantHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 
 	// Check if user is system admin or tenant admin
 	if !h.isSystemAdmin(ctx, r) && !h.hasTenantRole(ctx, r, tenantID, auth.TenantRoleAdmin) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	var req TenantProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	// Validate request
 	if req.Name == "" {
-		h.writeError(w, http.StatusBadRequest, "Name is required")
+		h.writeError(w, r, http.StatusBadRequest, "Name is required")
 		return
 	}
-	if req.Slug == "" {
-		h.writeError(w, http.StatusBadRequest, "Slug is required")
+	slug, ok := h.checkSlug(w, r, auth.SlugKindProject, tenantID, req.Slug, req.Name, "")
+	if !ok {
+		return
+	}
+	if !isLogoURL(req.Logo) {
+		h.writeError(w, r, http.StatusBadRequest, "Logos must be uploaded")
+		return
+	}
+	schema, ok := h.checkProjectMetadata(w, r, tenantID, req.Metadata)
+	if !ok {
 		return
 	}
 
 	// Get user ID from context (from JWT/auth middleware)
 	userID := h.getUserID(ctx)
 	if userID == "" {
-		h.writeError(w, http.StatusUnauthorized, "User not authenticated")
+		h.writeError(w, r, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	// Create project
 	project := &auth.Project{
+		ID:          uuid.New().String(),
 		TenantID:    tenantID,
 		Name:        req.Name,
-		Slug:        req.Slug,
+		Slug:        slug,
 		Description: req.Description,
 		Logo:        req.Logo,
 		Settings:    req.Settings,
@@ -634,15 +790,14 @@ func (h *TenantHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 	// Serialize JSON fields
 	settingsJSON, _ := json.Marshal(project.Settings)
 	metadataJSON, _ := json.Marshal(project.Metadata)
-	membersJSON, _ := json.Marshal(project.Members)
 
 	// Insert into database
 	query := `
 		INSERT INTO projects (id, tenant_id, name, slug, description, logo, settings, metadata,
-							is_active, is_public, environment, owner_id, members, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+							is_active, is_public, environment, owner_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	result, err := h.db.ExecContext(ctx, query,
+	_, err := h.db.ExecContext(ctx, query,
 		project.ID,
 		project.TenantID,
 		project.Name,
@@ -655,24 +810,18 @@ func (h *TenantHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		project.IsPublic,
 		project.Environment,
 		project.OwnerID,
-		string(membersJSON),
 		project.CreatedAt,
 		project.UpdatedAt,
 	)
 	if err != nil {
 		h.logger.Error("Failed to create project", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to create project")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to create project")
 		return
 	}
 
-	id, _ := result.LastInsertId()
-	if id != 0 {
-		// This shouldn't happen with UUID but handle it
-		project.ID = strconv.FormatInt(id, 10)
-	}
-
 	// Add owner as project member
 	h.addUserToProject(ctx, userID, tenantID, project.ID, auth.ProjectRoleOwner)
+	h.indexProjectMetadata(ctx, tenantID, project.ID, schema, project.Metadata)
 
 	h.logger.Info("Project created",
 		zap.String("id", project.ID),
@@ -689,12 +838,12 @@ func (h *TenantHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 
 	// Get project from database
 	var project auth.Project
-	var settingsJSON, metadataJSON, membersJSON sql.NullString
+	var settingsJSON, metadataJSON sql.NullString
 	var deletedAt sql.NullTime
 
 	query := `
 		SELECT id, tenant_id, name, slug, description, logo, settings, metadata,
-			   is_active, is_public, environment, owner_id, members, created_at, updated_at, deleted_at
+			   is_active, is_public, environment, owner_id, created_at, updated_at, deleted_at
 		FROM projects
 		WHERE id = ?
 	`
@@ -711,7 +860,6 @@ func (h *TenantHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 		&project.IsPublic,
 		&project.Environment,
 		&project.OwnerID,
-		&membersJSON,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 		&deletedAt,
@@ -719,11 +867,11 @@ func (h *TenantHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			h.writeError(w, http.StatusNotFound, "Project not found")
+			h.writeError(w, r, http.StatusNotFound, "Project not found")
 			return
 		}
 		h.logger.Error("Failed to get project", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to get project")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to get project")
 		return
 	}
 
@@ -738,16 +886,15 @@ func (h *TenantHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 	if metadataJSON.Valid {
 		json.Unmarshal([]byte(metadataJSON.String), &project.Metadata)
 	}
-	if membersJSON.Valid {
-		json.Unmarshal([]byte(membersJSON.String), &project.Members)
-	}
 
 	// Check access permissions
 	if !h.isSystemAdmin(ctx, r) && !h.hasProjectAccess(ctx, r, projectID) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
+	h.loadMembers(ctx, &project)
+	middleware.SetETag(w, project.ID, project.UpdatedAt.UTC().Format(time.RFC3339Nano), fmt.Sprint(deletedAt.Time.Unix()))
 	h.writeJSON(w, project)
 }
 
@@ -756,23 +903,23 @@ func (h *TenantHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	projectID := chi.URLParam(r, "projectId")
 
-	// Get project tenant ID first
-	var tenantID string
-	err := h.db.QueryRowContext(ctx, "SELECT tenant_id FROM projects WHERE id = ?", projectID).Scan(&tenantID)
+	// Get project tenant ID and current slug first
+	var tenantID, oldSlug string
+	err := h.db.QueryRowContext(ctx, "SELECT tenant_id, slug FROM projects WHERE id = ?", projectID).Scan(&tenantID, &oldSlug)
 	if err != nil {
-		h.writeError(w, http.StatusNotFound, "Project not found")
+		h.writeError(w, r, http.StatusNotFound, "Project not found")
 		return
 	}
 
 	// Check permissions
 	if !h.isSystemAdmin(ctx, r) && !h.hasProjectRole(ctx, r, projectID, auth.ProjectRoleAdmin) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	var req TenantProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
@@ -787,6 +934,10 @@ func (h *TenantHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		argIndex++
 	}
 	if req.Slug != "" {
+		// A renamed slug keeps redirecting to the project
+		if _, ok := h.checkSlug(w, r, auth.SlugKindProject, tenantID, req.Slug, "", projectID); !ok {
+			return
+		}
 		updates = append(updates, "slug = ?")
 		args = append(args, req.Slug)
 		argIndex++
@@ -797,6 +948,10 @@ func (h *TenantHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		argIndex++
 	}
 	if req.Logo != "" {
+		if !isLogoURL(req.Logo) {
+			h.writeError(w, r, http.StatusBadRequest, "Logos must be uploaded")
+			return
+		}
 		updates = append(updates, "logo = ?")
 		args = append(args, req.Logo)
 		argIndex++
@@ -818,7 +973,12 @@ func (h *TenantHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		args = append(args, string(settingsJSON))
 		argIndex++
 	}
+	var schema *metadata.Schema
 	if len(req.Metadata) > 0 {
+		var ok bool
+		if schema, ok = h.checkProjectMetadata(w, r, tenantID, req.Metadata); !ok {
+			return
+		}
 		metadataJSON, _ := json.Marshal(req.Metadata)
 		updates = append(updates, "metadata = ?")
 		args = append(args, string(metadataJSON))
@@ -826,7 +986,7 @@ func (h *TenantHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(updates) == 1 { // Only is_public was updated
-		h.writeError(w, http.StatusBadRequest, "No meaningful updates provided")
+		h.writeError(w, r, http.StatusBadRequest, "No meaningful updates provided")
 		return
 	}
 
@@ -840,9 +1000,17 @@ func (h *TenantHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 	_, err = h.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		h.logger.Error("Failed to update project", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to update project")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to update project")
 		return
 	}
+	if req.Slug != "" {
+		if err := h.slugs.Renamed(ctx, auth.SlugKindProject, tenantID, projectID, oldSlug, req.Slug); err != nil {
+			h.logger.Error("Failed to keep old project slug", zap.String("id", projectID), zap.Error(err))
+		}
+	}
+	if len(req.Metadata) > 0 {
+		h.indexProjectMetadata(ctx, tenantID, projectID, schema, req.Metadata)
+	}
 
 	h.logger.Info("Project updated", zap.String("id", projectID))
 
@@ -859,19 +1027,19 @@ func (h *TenantHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	var tenantID string
 	err := h.db.QueryRowContext(ctx, "SELECT tenant_id FROM projects WHERE id = ?", projectID).Scan(&tenantID)
 	if err != nil {
-		h.writeError(w, http.StatusNotFound, "Project not found")
+		h.writeError(w, r, http.StatusNotFound, "Project not found")
 		return
 	}
 
 	// Check permissions - only system admin or tenant admin can delete projects
 	if !h.isSystemAdmin(ctx, r) && !h.hasTenantRole(ctx, r, tenantID, auth.TenantRoleAdmin) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	// Prevent deletion of system project
 	if projectID == auth.SystemProjectID {
-		h.writeError(w, http.StatusBadRequest, "Cannot delete system project")
+		h.writeError(w, r, http.StatusBadRequest, "Cannot delete system project")
 		return
 	}
 
@@ -879,9 +1047,12 @@ func (h *TenantHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 	_, err = h.db.ExecContext(ctx, query, time.Now(), projectID)
 	if err != nil {
 		h.logger.Error("Failed to delete project", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to delete project")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to delete project")
 		return
 	}
+	if err := h.members.InvalidateProject(ctx, projectID); err != nil {
+		h.logger.Warn("Failed to invalidate project member roles", zap.String("project_id", projectID), zap.Error(err))
+	}
 
 	response := map[string]interface{}{
 		"message": "Project deleted successfully",
@@ -899,18 +1070,18 @@ func (h *TenantHandler) AddUserToTenant(w http.ResponseWriter, r *http.Request)
 
 	// Check if user is system admin or tenant admin
 	if !h.isSystemAdmin(ctx, r) && !h.hasTenantRole(ctx, r, tenantID, auth.TenantRoleAdmin) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	var req UserTenantRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	if req.UserID == "" {
-		h.writeError(w, http.StatusBadRequest, "User ID is required")
+		h.writeError(w, r, http.StatusBadRequest, "User ID is required")
 		return
 	}
 	if req.Role == "" {
@@ -921,7 +1092,7 @@ func (h *TenantHandler) AddUserToTenant(w http.ResponseWriter, r *http.Request)
 	err := h.addUserToTenant(ctx, req.UserID, tenantID, req.Role)
 	if err != nil {
 		h.logger.Error("Failed to add user to tenant", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to add user to tenant")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to add user to tenant")
 		return
 	}
 
@@ -942,18 +1113,18 @@ func (h *TenantHandler) AddUserToProject(w http.ResponseWriter, r *http.Request)
 
 	// Check if user is system admin or project admin
 	if !h.isSystemAdmin(ctx, r) && !h.hasProjectRole(ctx, r, projectID, auth.ProjectRoleAdmin) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	var req ProjectRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
 	if req.UserID == "" {
-		h.writeError(w, http.StatusBadRequest, "User ID is required")
+		h.writeError(w, r, http.StatusBadRequest, "User ID is required")
 		return
 	}
 	if req.Role == "" {
@@ -964,7 +1135,7 @@ func (h *TenantHandler) AddUserToProject(w http.ResponseWriter, r *http.Request)
 	var tenantID string
 	err := h.db.QueryRowContext(ctx, "SELECT tenant_id FROM projects WHERE id = ?", projectID).Scan(&tenantID)
 	if err != nil {
-		h.writeError(w, http.StatusNotFound, "Project not found")
+		h.writeError(w, r, http.StatusNotFound, "Project not found")
 		return
 	}
 
@@ -972,7 +1143,7 @@ func (h *TenantHandler) AddUserToProject(w http.ResponseWriter, r *http.Request)
 	err = h.addUserToProject(ctx, req.UserID, tenantID, projectID, req.Role)
 	if err != nil {
 		h.logger.Error("Failed to add user to project", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to add user to project")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to add user to project")
 		return
 	}
 
@@ -993,14 +1164,14 @@ func (h *TenantHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
 
 	userID := h.getUserID(ctx)
 	if userID == "" {
-		h.writeError(w, http.StatusUnauthorized, "User not authenticated")
+		h.writeError(w, r, http.StatusUnauthorized, "User not authenticated")
 		return
 	}
 
 	// Query user's projects
 	rows, err := h.db.QueryContext(ctx, `
 		SELECT p.id, p.tenant_id, p.name, p.slug, p.description, p.logo, p.settings, p.metadata,
-			   p.is_active, p.is_public, p.environment, p.owner_id, p.members, p.created_at, p.updated_at,
+			   p.is_active, p.is_public, p.environment, p.owner_id, p.created_at, p.updated_at,
 			   up.role as user_role
 		FROM projects p
 		INNER JOIN user_projects up ON p.id = up.project_id
@@ -1009,7 +1180,7 @@ func (h *TenantHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
 	`, userID, tenantID)
 	if err != nil {
 		h.logger.Error("Failed to query user projects", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to query user projects")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to query user projects")
 		return
 	}
 	defer rows.Close()
@@ -1022,7 +1193,7 @@ func (h *TenantHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
 	var projects []Project
 	for rows.Next() {
 		var project Project
-		var settingsJSON, metadataJSON, membersJSON sql.NullString
+		var settingsJSON, metadataJSON sql.NullString
 
 		err := rows.Scan(
 			&project.ID,
@@ -1037,7 +1208,6 @@ func (h *TenantHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
 			&project.IsPublic,
 			&project.Environment,
 			&project.OwnerID,
-			&membersJSON,
 			&project.CreatedAt,
 			&project.UpdatedAt,
 			&project.UserRole,
@@ -1054,12 +1224,14 @@ func (h *TenantHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
 		if metadataJSON.Valid {
 			json.Unmarshal([]byte(metadataJSON.String), &project.Metadata)
 		}
-		if membersJSON.Valid {
-			json.Unmarshal([]byte(membersJSON.String), &project.Members)
-		}
 
 		projects = append(projects, project)
 	}
+	listed := make([]*auth.Project, len(projects))
+	for i := range projects {
+		listed[i] = &projects[i].Project
+	}
+	h.loadMembers(ctx, listed...)
 
 	response := map[string]interface{}{
 		"projects": projects,
@@ -1077,13 +1249,13 @@ func (h *TenantHandler) InviteUserToProject(w http.ResponseWriter, r *http.Reque
 	// Get project context
 	projectCtx := middleware.GetProjectContext(r)
 	if projectCtx == nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to get project context")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to get project context")
 		return
 	}
 
 	var req InviteUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
 		return
 	}
 
@@ -1101,7 +1273,7 @@ func (h *TenantHandler) InviteUserToProject(w http.ResponseWriter, r *http.Reque
 		}
 	}
 	if !validRole {
-		h.writeError(w, http.StatusBadRequest, "Invalid role")
+		h.writeError(w, r, http.StatusBadRequest, "Invalid role")
 		return
 	}
 
@@ -1109,13 +1281,13 @@ func (h *TenantHandler) InviteUserToProject(w http.ResponseWriter, r *http.Reque
 	invitedBy := h.getUserID(ctx)
 
 	// Add user to project (supports cross-tenant collaboration)
-	err := h.tenantManager.AddUserToProject(req.UserID, projectID, req.Role, invitedBy)
+	err := h.members.AddUserToProject(ctx, req.UserID, projectID, req.Role, invitedBy)
 	if err != nil {
 		h.logger.Error("Failed to invite user to project",
 			zap.String("user_id", req.UserID),
 			zap.String("project_id", projectID),
 			zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to invite user to project")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to invite user to project")
 		return
 	}
 
@@ -1125,6 +1297,9 @@ func (h *TenantHandler) InviteUserToProject(w http.ResponseWriter, r *http.Reque
 		"project_id": projectID,
 		"role":       req.Role,
 	}
+	if req.Email != "" {
+		response["email_sent"] = h.sendInvitation(ctx, &req, projectID, projectCtx.TenantID, invitedBy)
+	}
 
 	h.logger.Info("User invited to project",
 		zap.String("invited_user", req.UserID),
@@ -1134,6 +1309,44 @@ func (h *TenantHandler) InviteUserToProject(w http.ResponseWriter, r *http.Reque
 	h.writeJSON(w, response)
 }
 
+// sendInvitation emails an invitation in the invitee's locale and reports
+// whether it was sent
+func (h *TenantHandler) sendInvitation(ctx context.Context, req *InviteUserRequest, projectID, tenantID, invitedBy string) bool {
+	if h.mailer == nil || !h.mailer.Enabled() {
+		return false
+	}
+	var projectName string
+	if err := h.db.QueryRowContext(ctx, "SELECT name FROM projects WHERE id = ?", projectID).Scan(&projectName); err != nil {
+		h.logger.Warn("Failed to get project for invitation", zap.String("project_id", projectID), zap.Error(err))
+		return false
+	}
+
+	locale := i18n.DefaultLocale
+	if h.inviteLocale != nil {
+		locale = h.inviteLocale(ctx, req.UserID, tenantID)
+	}
+	args := i18n.Args{
+		"project": projectName,
+		"inviter": invitedBy,
+		"role":    i18n.Translate(locale, "role."+req.Role, nil),
+	}
+	text := i18n.Translate(locale, "invitation.email.body", args)
+	if req.Message != "" {
+		text += "\n" + req.Message + "\n"
+	}
+	err := h.mailer.Send(&mailer.Message{
+		To:      []string{req.Email},
+		Subject: i18n.Translate(locale, "invitation.email.subject", args),
+		Text:    text,
+	})
+	if err != nil {
+		h.logger.Warn("Failed to send invitation email",
+			zap.String("project_id", projectID), zap.String("email", req.Email), zap.Error(err))
+		return false
+	}
+	return true
+}
+
 // ListProjectMembers handles listing project members
 func (h *TenantHandler) ListProjectMembers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -1141,17 +1354,17 @@ func (h *TenantHandler) ListProjectMembers(w http.ResponseWriter, r *http.Reques
 
 	// Check project access (viewer level is sufficient to see members)
 	if !h.isSystemAdmin(ctx, r) && !h.hasProjectAccess(ctx, r, projectID) {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeError(w, r, http.StatusForbidden, "Access denied")
 		return
 	}
 
 	// Get project members
-	members, err := h.tenantManager.GetProjectMembers(projectID)
+	members, err := h.members.GetProjectMembers(ctx, projectID)
 	if err != nil {
 		h.logger.Error("Failed to get project members",
 			zap.String("project_id", projectID),
 			zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to get project members")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to get project members")
 		return
 	}
 
@@ -1171,16 +1384,16 @@ func (h *TenantHandler) RemoveUserFromProject(w http.ResponseWriter, r *http.Req
 
 	// Check if current user can manage project members
 	if !h.isSystemAdmin(ctx, r) && !h.canManageProject(ctx, r, projectID) {
-		h.writeError(w, http.StatusForbidden, "Access denied: insufficient permissions")
+		h.writeError(w, r, http.StatusForbidden, "Access denied: insufficient permissions")
 		return
 	}
 
 	// Prevent removing project creator
-	members, err := h.tenantManager.GetProjectMembers(projectID)
+	members, err := h.members.GetProjectMembers(ctx, projectID)
 	if err == nil {
 		for _, member := range members {
 			if member.UserID == userID && member.IsCreator {
-				h.writeError(w, http.StatusBadRequest, "Cannot remove project creator from project")
+				h.writeError(w, r, http.StatusBadRequest, "Cannot remove project creator from project")
 				return
 			}
 		}
@@ -1198,9 +1411,10 @@ func (h *TenantHandler) RemoveUserFromProject(w http.ResponseWriter, r *http.Req
 			zap.String("user_id", userID),
 			zap.String("project_id", projectID),
 			zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to remove user from project")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to remove user from project")
 		return
 	}
+	h.members.InvalidateUser(ctx, userID)
 
 	response := map[string]interface{}{
 		"message":    "User removed from project successfully",
@@ -1215,48 +1429,58 @@ func (h *TenantHandler) RemoveUserFromProject(w http.ResponseWriter, r *http.Req
 	h.writeJSON(w, response)
 }
 
-// TransferOwnership handles transferring project ownership
-func (h *TenantHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+// GetRAGPermissions handles showing the RAG permissions of each project role
+func (h *TenantHandler) GetRAGPermissions(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	projectID := chi.URLParam(r, "projectId")
 
-	var req TransferOwnershipRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid JSON")
+	policy, err := h.members.GetRAGPolicy(ctx, projectID)
+	if err != nil {
+		h.logger.Error("Failed to get RAG permissions",
+			zap.String("project_id", projectID),
+			zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to get RAG permissions")
 		return
 	}
 
-	if req.ToUserID == "" {
-		h.writeError(w, http.StatusBadRequest, "Target user ID is required")
-		return
+	response := map[string]interface{}{
+		"overrides":   policy,
+		"effective":   policy.Effective(),
+		"permissions": auth.RAGPermissions,
 	}
 
-	currentUserID := h.getUserID(ctx)
+	h.writeJSON(w, response)
+}
 
-	// Transfer ownership
-	err := h.tenantManager.TransferProjectOwnership(projectID, currentUserID, req.ToUserID)
-	if err != nil {
-		h.logger.Error("Failed to transfer project ownership",
+// UpdateRAGPermissions handles replacing the RAG permissions of viewers and
+// collaborators; roles left out return to their defaults
+func (h *TenantHandler) UpdateRAGPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectID := chi.URLParam(r, "projectId")
+
+	var policy auth.RAGPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.members.SetRAGPolicy(ctx, projectID, policy, h.getUserID(ctx)); err != nil {
+		h.logger.Error("Failed to update RAG permissions",
 			zap.String("project_id", projectID),
-			zap.String("from_user", currentUserID),
-			zap.String("to_user", req.ToUserID),
 			zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to transfer ownership")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to update RAG permissions")
 		return
 	}
 
 	response := map[string]interface{}{
-		"message":    "Project ownership transferred successfully",
-		"project_id": projectID,
-		"from_user":  currentUserID,
-		"to_user":    req.ToUserID,
+		"overrides": policy,
+		"effective": policy.Effective(),
 	}
 
-	h.logger.Info("Project ownership transferred",
-		zap.String("project_id", projectID),
-		zap.String("from_user", currentUserID),
-		zap.String("to_user", req.ToUserID))
-
 	h.writeJSON(w, response)
 }
 
@@ -1290,7 +1514,7 @@ func (h *TenantHandler) ListUserProjects(w http.ResponseWriter, r *http.Request)
 	rows, err := h.db.QueryContext(ctx, query, userID, limit, (page-1)*limit)
 	if err != nil {
 		h.logger.Error("Failed to query user projects", zap.String("user_id", userID), zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "Failed to query projects")
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to query projects")
 		return
 	}
 	defer rows.Close()
@@ -1379,6 +1603,23 @@ func (h *TenantHandler) hasProjectAccess(ctx context.Context, r *http.Request, p
 	return projectCtx != nil && projectCtx.ProjectID == projectID
 }
 
+// loadMembers fills in the members of projects from user_projects with a
+// single query for all of them
+func (h *TenantHandler) loadMembers(ctx context.Context, projects ...*auth.Project) {
+	ids := make([]string, len(projects))
+	for i, project := range projects {
+		ids[i] = project.ID
+	}
+	members, err := h.members.MembersByProject(ctx, ids)
+	if err != nil {
+		h.logger.Error("Failed to load project members", zap.Error(err))
+		return
+	}
+	for _, project := range projects {
+		project.Members = members[project.ID]
+	}
+}
+
 // Helper methods
 
 func (h *TenantHandler) writeJSON(w http.ResponseWriter, data interface{}) {
@@ -1386,11 +1627,43 @@ func (h *TenantHandler) writeJSON(w http.ResponseWriter, data interface{}) {
 	json.NewEncoder(w).Encode(data)
 }
 
-func (h *TenantHandler) writeError(w http.ResponseWriter, status int, message string) {
+// validateAPISettings checks the api section of tenant settings
+func validateAPISettings(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var settings tenant.APISettings
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return fmt.Errorf("invalid api settings: %w", err)
+	}
+	if settings.CORS != nil {
+		if err := settings.CORS.Validate(); err != nil {
+			return err
+		}
+	}
+	if settings.PublicAccess != nil {
+		return settings.PublicAccess.Validate()
+	}
+	return nil
+}
+
+func validateNotificationSettings(raw json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	var settings tenant.NotificationSettings
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return fmt.Errorf("invalid notification settings: %w", err)
+	}
+	return settings.Validate()
+}
+
+// writeError writes an error response with the message in the request's locale
+func (h *TenantHandler) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error":   message,
+		"error":   i18n.T(r.Context(), message, nil),
 		"status":  status,
 		"success": false,
 	})
@@ -1437,6 +1710,7 @@ func (h *TenantHandler) addUserToProject(ctx context.Context, userID, tenantID,
 		VALUES (?, ?, ?, ?, 1, ?)
 	`
 	_, err := h.db.ExecContext(ctx, query, userID, tenantID, projectID, role, time.Now())
+	h.members.InvalidateUser(ctx, userID)
 	return err
 }
 
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/common/errors"
+	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/i18n"
+	"github.com/guileen/metabase/pkg/infra/mailer"
+)
+
+// TransferOwnershipRequest represents ownership transfer request
+type TransferOwnershipRequest struct {
+	ToUserID string `json:"to_user_id"`
+	Message  string `json:"message,omitempty"`
+	// ExpiresIn is how long the new owner has to accept, e.g. "72h";
+	// defaults to auth.DefaultTransferExpiry
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// TransferOwnership handles offering project ownership to another member.
+// Nothing changes until the new owner accepts the transfer.
+func (h *TenantHandler) TransferOwnership(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectID := chi.URLParam(r, "projectId")
+
+	var req TransferOwnershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if req.ToUserID == "" {
+		h.writeError(w, r, http.StatusBadRequest, "Target user ID is required")
+		return
+	}
+
+	var expiry time.Duration
+	if req.ExpiresIn != "" {
+		var err error
+		if expiry, err = time.ParseDuration(req.ExpiresIn); err != nil || expiry <= 0 || expiry > auth.DefaultTransferExpiry {
+			h.writeError(w, r, http.StatusBadRequest, "Invalid expiry")
+			return
+		}
+	}
+
+	currentUserID := h.actorID(r)
+	transfer, err := h.members.RequestOwnershipTransfer(ctx, projectID, currentUserID, req.ToUserID, req.Message, expiry)
+	if err != nil {
+		h.transferError(w, r, projectID, err)
+		return
+	}
+
+	h.mailTransfer(ctx, transfer, transfer.ToUserID, "transfer.email.offered")
+	h.mailTransfer(ctx, transfer, transfer.FromUserID, "transfer.email.requested")
+
+	h.logger.Info("Project ownership transfer requested",
+		zap.String("project_id", projectID),
+		zap.String("transfer_id", transfer.ID),
+		zap.String("from_user", currentUserID),
+		zap.String("to_user", req.ToUserID))
+
+	w.WriteHeader(http.StatusAccepted)
+	h.writeJSON(w, transfer)
+}
+
+// ListOwnershipTransfers handles listing the ownership transfers of a project
+func (h *TenantHandler) ListOwnershipTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectID := chi.URLParam(r, "projectId")
+
+	transfers, err := h.members.ListOwnershipTransfers(ctx, projectID)
+	if err != nil {
+		h.logger.Error("Failed to list ownership transfers", zap.String("project_id", projectID), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Failed to list ownership transfers")
+		return
+	}
+
+	response := map[string]interface{}{
+		"transfers": transfers,
+		"total":     len(transfers),
+	}
+
+	h.writeJSON(w, response)
+}
+
+// AcceptOwnershipTransfer handles the new owner accepting a pending transfer
+func (h *TenantHandler) AcceptOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	h.decideTransfer(w, r, "transfer.email.accepted", h.members.AcceptOwnershipTransfer)
+}
+
+// DeclineOwnershipTransfer handles the new owner declining a pending transfer
+func (h *TenantHandler) DeclineOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	h.decideTransfer(w, r, "transfer.email.declined", h.members.DeclineOwnershipTransfer)
+}
+
+// CancelOwnershipTransfer handles the requesting owner withdrawing a pending
+// transfer
+func (h *TenantHandler) CancelOwnershipTransfer(w http.ResponseWriter, r *http.Request) {
+	h.decideTransfer(w, r, "transfer.email.cancelled", h.members.CancelOwnershipTransfer)
+}
+
+func (h *TenantHandler) decideTransfer(w http.ResponseWriter, r *http.Request, emailKey string,
+	decide func(ctx context.Context, projectID, transferID, userID string) (*auth.OwnershipTransfer, error)) {
+	ctx := r.Context()
+	projectID := chi.URLParam(r, "projectId")
+	actor := h.actorID(r)
+
+	transfer, err := decide(ctx, projectID, chi.URLParam(r, "transferId"), actor)
+	if err != nil {
+		h.transferError(w, r, projectID, err)
+		return
+	}
+	if transfer.Status == auth.TransferStatusAccepted {
+		h.members.InvalidateUser(ctx, transfer.FromUserID, transfer.ToUserID)
+	}
+
+	h.mailTransfer(ctx, transfer, transfer.FromUserID, emailKey)
+	h.mailTransfer(ctx, transfer, transfer.ToUserID, emailKey)
+
+	h.logger.Info("Project ownership transfer decided",
+		zap.String("project_id", projectID),
+		zap.String("transfer_id", transfer.ID),
+		zap.String("status", transfer.Status),
+		zap.String("user_id", actor))
+
+	h.writeJSON(w, transfer)
+}
+
+func (h *TenantHandler) transferError(w http.ResponseWriter, r *http.Request, projectID string, err error) {
+	status := errors.GetHTTPStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error("Failed to update ownership transfer", zap.String("project_id", projectID), zap.Error(err))
+		h.writeError(w, r, status, "Failed to transfer ownership")
+		return
+	}
+	h.writeError(w, r, status, err.Error())
+}
+
+// mailTransfer emails one party of an ownership transfer in their locale.
+// Failures are logged; the transfer itself is already recorded.
+func (h *TenantHandler) mailTransfer(ctx context.Context, transfer *auth.OwnershipTransfer, userID, key string) {
+	if h.mailer == nil || !h.mailer.Enabled() {
+		return
+	}
+	var email, projectName, tenantID string
+	err := h.db.QueryRowContext(ctx, "SELECT email FROM users WHERE id = ?", userID).Scan(&email)
+	if err == sql.ErrNoRows {
+		return
+	}
+	if err == nil {
+		err = h.db.QueryRowContext(ctx, "SELECT name, tenant_id FROM projects WHERE id = ?",
+			transfer.ProjectID).Scan(&projectName, &tenantID)
+	}
+	if err != nil {
+		h.logger.Warn("Failed to prepare ownership transfer email",
+			zap.String("transfer_id", transfer.ID), zap.String("user_id", userID), zap.Error(err))
+		return
+	}
+
+	locale := i18n.DefaultLocale
+	if h.inviteLocale != nil {
+		locale = h.inviteLocale(ctx, userID, tenantID)
+	}
+	args := i18n.Args{
+		"project": projectName,
+		"from":    transfer.FromUserID,
+		"to":      transfer.ToUserID,
+		"expires": transfer.ExpiresAt.UTC().Format(time.RFC1123),
+	}
+	text := i18n.Translate(locale, key, args)
+	if key == "transfer.email.offered" && transfer.Message != "" {
+		text += "\n" + transfer.Message + "\n"
+	}
+	err = h.mailer.Send(&mailer.Message{
+		To:      []string{email},
+		Subject: i18n.Translate(locale, "transfer.email.subject", args),
+		Text:    text,
+	})
+	if err != nil {
+		h.logger.Warn("Failed to send ownership transfer email",
+			zap.String("transfer_id", transfer.ID), zap.String("email", email), zap.Error(err))
+	}
+}
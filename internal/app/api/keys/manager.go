@@ -64,8 +64,22 @@ func (m *Manager) Initialize(ctx context.Context) error {
 	return nil
 }
 
+// execer 可执行写入的数据库连接或事务
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
 // Create 创建新的API密钥
 func (m *Manager) Create(ctx context.Context, req *CreateKeyRequest) (*APIKey, error) {
+	return m.create(ctx, m.db, req)
+}
+
+// CreateTx 在调用方的事务中创建API密钥，事务回滚时密钥一并撤销
+func (m *Manager) CreateTx(ctx context.Context, tx *sql.Tx, req *CreateKeyRequest) (*APIKey, error) {
+	return m.create(ctx, tx, req)
+}
+
+func (m *Manager) create(ctx context.Context, db execer, req *CreateKeyRequest) (*APIKey, error) {
 	// 生成密钥
 	key, prefix, err := GenerateKey()
 	if err != nil {
@@ -113,7 +127,7 @@ func (m *Manager) Create(ctx context.Context, req *CreateKeyRequest) (*APIKey, e
 	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
-	_, err = m.db.ExecContext(ctx, query,
+	_, err = db.ExecContext(ctx, query,
 		apiKey.ID, apiKey.Name, apiKey.Key, apiKey.KeyPrefix, apiKey.Type,
 		apiKey.Status, string(scopesJSON), apiKey.TenantID, apiKey.ProjectID,
 		apiKey.CreatedBy, apiKey.UserID, apiKey.ExpiresAt, string(metadataJSON),
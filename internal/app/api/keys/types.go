@@ -89,6 +89,12 @@ var KeyScopes = map[string]string{
 
 	// 实时权限
 	"realtime": "实时订阅权限",
+
+	// MCP 工具权限，mcp:<工具名> 只允许调用单个工具
+	"mcp":                    "调用全部MCP工具权限",
+	"mcp:rag_search":         "MCP检索项目文档权限",
+	"mcp:rag_fetch_document": "MCP获取项目文档权限",
+	"mcp:cass_findings":      "MCP代码分析权限",
 }
 
 // GetDefaultScopes 根据密钥类型返回默认权限
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+)
+
+func TestProjectLogoUpload(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.MkdirAll("data", 0o755) // request log storage
+	server, err := NewServer(&Config{
+		DatabasePath: filepath.Join(dir, "metabase.db"),
+		Blobs:        &blobstore.Config{Type: blobstore.TypeLocal, Dir: filepath.Join(dir, "blobs")},
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	r := chi.NewRouter()
+	server.setupRoutes(r)
+	ts := httptest.NewServer(server.withMiddleware(r))
+	t.Cleanup(func() {
+		ts.Close()
+		server.Stop(context.Background())
+	})
+	_, err = server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`)
+	if err == nil {
+		_, err = server.db.Exec(`INSERT INTO projects (id, tenant_id, name, slug, description, logo, owner_id) VALUES ('p1', 't1', 'Project', 'p1', '', '', 'u1')`)
+	}
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	upload := func(body []byte) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/admin/v1/projects/p1/logo", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		req.Header.Set("Content-Type", "image/png")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 300, 100)))
+
+	status, first := upload(logo.Bytes())
+	if status != http.StatusOK || !strings.HasPrefix(first["logo"].(string), "/public/v1/logos/projects/p1/") {
+		t.Fatalf("upload failed: %d %v", status, first)
+	}
+	resp, err := http.Get(ts.URL + first["sizes"].(map[string]interface{})["64"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(resp.Body)
+	resp.Body.Close()
+	if err != nil || img.Bounds().Dx() != 64 || img.Bounds().Dy() != 64 {
+		t.Fatalf("unexpected rendition %v %v", img, err)
+	}
+	if _, project := doJSON(t, http.MethodGet, ts.URL+"/admin/v1/projects/p1", nil); !strings.Contains(toJSON(project), first["logo"].(string)) {
+		t.Fatalf("project logo not updated: %v", project)
+	}
+
+	// A new logo replaces the old renditions
+	logo.Reset()
+	png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	if status, _ := upload(logo.Bytes()); status != http.StatusOK {
+		t.Fatalf("second upload failed: %d", status)
+	}
+	if resp, _ := http.Get(ts.URL + first["logo"].(string)); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the old logo to be removed, got %d", resp.StatusCode)
+	}
+
+	if status, _ := upload([]byte("<svg xmlns='http://www.w3.org/2000/svg'/>")); status != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected svg to be rejected, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodPut, ts.URL+"/admin/v1/projects/p1", map[string]interface{}{"logo": "https://example.com/logo.png"}); status != http.StatusBadRequest {
+		t.Fatalf("expected free-form logos to be rejected, got %d", status)
+	}
+}
+
+func toJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,138 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/i18n"
+)
+
+// readOnlyPaths 只读模式下仍允许的写方法路径后缀，查询和登录不修改数据
+var readOnlyPaths = []string{
+	"/query",
+	"/rag/retention/preview",
+	"/auth/login",
+	"/auth/refresh",
+}
+
+// Handler 维护模式开关的HTTP处理器
+type Handler struct {
+	manager *Manager
+	logger  *zap.Logger
+}
+
+// NewHandler 创建维护模式处理器
+func NewHandler(manager *Manager, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 注册维护模式路由（挂载于 /admin/v1/maintenance，系统管理员权限）
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.handleGet)
+	r.Put("/", h.handleSet)
+}
+
+// handleGet 获取维护模式状态
+func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	state, err := h.manager.Get(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get maintenance state", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get maintenance state", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": state})
+}
+
+// handleSet 开启或关闭只读维护模式
+func (h *Handler) handleSet(w http.ResponseWriter, r *http.Request) {
+	var state State
+	if err := render.DecodeJSON(r.Body, &state); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+		return
+	}
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		state.UpdatedBy = userID
+	}
+
+	state, err := h.manager.Set(r.Context(), state)
+	if err != nil {
+		h.logger.Error("failed to set maintenance state", zap.Error(err))
+		h.error(w, r, http.StatusBadRequest, "Failed to set maintenance state", err, "")
+		return
+	}
+	h.logger.Info("maintenance mode changed", zap.Bool("read_only", state.ReadOnly), zap.String("reason", state.Reason))
+	render.JSON(w, r, map[string]interface{}{"data": state})
+}
+
+// Middleware 只读模式下以 503 和 Retry-After 拒绝写请求，读请求和查询照常处理，
+// 错误信息使用请求上下文中的语言。
+// exempt 为始终放行的路径前缀，用于维护开关本身
+func (m *Manager) Middleware(exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if readOnlyAllowed(r, exempt) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			state := m.Current(r.Context())
+			if !state.ReadOnly {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := map[string]interface{}{
+				"error": i18n.T(r.Context(), "Service is in read-only maintenance mode", nil),
+				"code":  "maintenance",
+			}
+			if state.Reason != "" {
+				body["details"] = state.Reason
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(state.retryAfter()))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(body)
+		})
+	}
+}
+
+// readOnlyAllowed 判断请求在只读模式下是否可以执行
+func readOnlyAllowed(r *http.Request, exempt []string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	for _, prefix := range exempt {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	for _, suffix := range readOnlyPaths {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// error 输出错误响应
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
@@ -0,0 +1,82 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "maintenance.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	manager := NewManager(db, zap.NewNop())
+	if err := manager.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestReadOnlyMiddleware(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+	handler := manager.Middleware("/admin/v1/maintenance")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	if rec := serve(http.MethodPost, "/admin/v1/projects/p1/documents"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected writes to pass outside maintenance, got %d", rec.Code)
+	}
+
+	if _, err := manager.Set(ctx, State{ReadOnly: true, Reason: "database migration", RetryAfter: 120}); err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(http.MethodPost, "/admin/v1/projects/p1/documents")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "120" {
+		t.Fatalf("expected 503 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := serve(http.MethodDelete, "/admin/v1/tenants/t1"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected deletes to be rejected, got %d", rec.Code)
+	}
+	for _, allowed := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/v1/projects/p1/documents"},
+		{http.MethodPost, "/admin/v1/projects/p1/rag/query"},
+		{http.MethodPost, "/public/v1/rag/query"},
+		{http.MethodPost, "/auth/login"},
+		{http.MethodPut, "/admin/v1/maintenance"},
+	} {
+		if rec := serve(allowed.method, allowed.path); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected %s %s to pass in read-only mode, got %d", allowed.method, allowed.path, rec.Code)
+		}
+	}
+
+	// Another instance on the same database observes the switch
+	other := &Manager{db: manager.db, logger: zap.NewNop(), refresh: defaultRefreshInterval}
+	if state := other.Current(ctx); !state.ReadOnly || state.Reason != "database migration" {
+		t.Fatalf("expected the shared state, got %+v", state)
+	}
+
+	if _, err := manager.Set(ctx, State{}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(http.MethodPost, "/admin/v1/projects/p1/documents"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected writes to pass after maintenance, got %d", rec.Code)
+	}
+	if _, err := manager.Set(ctx, State{ReadOnly: true, RetryAfter: -1}); err == nil {
+		t.Fatal("expected a negative retry interval to be rejected")
+	}
+}
@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultRetryAfter 未指定时建议客户端的重试间隔
+const defaultRetryAfter = 5 * time.Minute
+
+// defaultRefreshInterval 各实例重新读取维护状态的间隔
+const defaultRefreshInterval = 2 * time.Second
+
+// State 维护模式状态，保存在数据库中由所有实例共享
+type State struct {
+	ReadOnly   bool       `json:"read_only"`
+	Reason     string     `json:"reason,omitempty"`
+	RetryAfter int        `json:"retry_after_seconds,omitempty"` // 建议客户端重试的秒数
+	UpdatedBy  string     `json:"updated_by,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// retryAfter 返回 Retry-After 秒数
+func (s State) retryAfter() int {
+	if s.RetryAfter > 0 {
+		return s.RetryAfter
+	}
+	return int(defaultRetryAfter / time.Second)
+}
+
+// Manager 维护模式状态的存储，带短时缓存以免每个请求都查询数据库
+type Manager struct {
+	db      *sql.DB
+	logger  *zap.Logger
+	refresh time.Duration
+
+	mu        sync.Mutex
+	state     State
+	checkedAt time.Time
+}
+
+// NewManager 创建维护模式存储
+func NewManager(db *sql.DB, logger *zap.Logger) *Manager {
+	return &Manager{
+		db:      db,
+		logger:  logger,
+		refresh: defaultRefreshInterval,
+	}
+}
+
+// Initialize 初始化数据库表
+func (m *Manager) Initialize(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS system_maintenance (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		state TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		m.logger.Error("failed to initialize maintenance table", zap.Error(err))
+		return fmt.Errorf("failed to initialize maintenance table: %w", err)
+	}
+	return nil
+}
+
+// Get 从数据库读取当前状态
+func (m *Manager) Get(ctx context.Context) (State, error) {
+	var raw string
+	err := m.db.QueryRowContext(ctx, `SELECT state FROM system_maintenance WHERE id = 1`).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("failed to get maintenance state: %w", err)
+	}
+	var state State
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return State{}, fmt.Errorf("failed to decode maintenance state: %w", err)
+	}
+	return state, nil
+}
+
+// Set 保存状态，其他实例在刷新间隔内生效
+func (m *Manager) Set(ctx context.Context, state State) (State, error) {
+	if state.RetryAfter < 0 {
+		return State{}, fmt.Errorf("retry_after_seconds must not be negative")
+	}
+	now := time.Now()
+	state.UpdatedAt = &now
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return State{}, fmt.Errorf("failed to encode maintenance state: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO system_maintenance (id, state, updated_at) VALUES (1, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at`,
+		string(raw), now,
+	)
+	if err != nil {
+		return State{}, fmt.Errorf("failed to save maintenance state: %w", err)
+	}
+
+	m.mu.Lock()
+	m.state, m.checkedAt = state, now
+	m.mu.Unlock()
+	return state, nil
+}
+
+// Current 返回缓存的状态，超过刷新间隔时重新读取；读取失败时沿用上次的状态
+func (m *Manager) Current(ctx context.Context) State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if time.Since(m.checkedAt) < m.refresh {
+		return m.state
+	}
+	m.checkedAt = time.Now()
+	state, err := m.Get(ctx)
+	if err != nil {
+		m.logger.Warn("failed to refresh maintenance state", zap.Error(err))
+		return m.state
+	}
+	m.state = state
+	return state
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+func TestProjectRolesCachedAndInvalidated(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	server, ts := startInstance(t, filepath.Join(dir, "metabase.db"))
+
+	_, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`)
+	if err == nil {
+		_, err = server.db.Exec(`INSERT INTO projects (id, tenant_id, name, slug, description, logo, owner_id) VALUES
+			('p1', 't1', 'One', 'p1', '', '', 'u1'), ('p2', 't1', 'Two', 'p2', '', '', 'u1')`)
+	}
+	if err != nil {
+		t.Fatalf("failed to create projects: %v", err)
+	}
+	if err := server.projectMembers.AddUserToProject(ctx, "u2", "p1", auth.ProjectRoleViewer, "u1"); err != nil {
+		t.Fatal(err)
+	}
+
+	viewable := func() []string {
+		t.Helper()
+		projects, err := server.projectMiddleware.ViewableProjects(ctx, "u2", []string{"p1", "p2"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return projects
+	}
+	if got := viewable(); !reflect.DeepEqual(got, []string{"p1"}) {
+		t.Fatalf("expected only p1 to be viewable, got %v", got)
+	}
+
+	// Changes made behind the store's back are not seen until invalidation
+	if _, err := server.db.Exec(`INSERT INTO user_projects (id, user_id, tenant_id, project_id, role, is_active)
+		VALUES ('m2', 'u2', 't1', 'p2', 'viewer', 1)`); err != nil {
+		t.Fatal(err)
+	}
+	if got := viewable(); !reflect.DeepEqual(got, []string{"p1"}) {
+		t.Fatalf("expected cached roles, got %v", got)
+	}
+
+	// Removing a member through the API drops the cached roles
+	if status, body := doJSON(t, http.MethodDelete, ts.URL+"/admin/v1/projects/p1/members/u2", nil); status != http.StatusOK {
+		t.Fatalf("remove member: status %d, body %v", status, body)
+	}
+	if got := viewable(); !reflect.DeepEqual(got, []string{"p2"}) {
+		t.Fatalf("expected p2 after the membership change, got %v", got)
+	}
+
+	// Project members come from user_projects rather than the project row
+	status, project := doJSON(t, http.MethodGet, ts.URL+"/admin/v1/projects/p2", nil)
+	members, _ := project["members"].([]interface{})
+	if status != http.StatusOK || len(members) != 1 || members[0].(map[string]interface{})["user_id"] != "u2" {
+		t.Fatalf("unexpected project members: status %d, body %v", status, project)
+	}
+}
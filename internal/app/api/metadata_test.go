@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectMetadataSchema(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	server, ts := startInstance(t, filepath.Join(dir, "metabase.db"))
+	if _, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	schemaURL := ts.URL + "/admin/v1/tenants/t1/metadata-schemas/project"
+	projectsURL := ts.URL + "/admin/v1/tenants/t1/projects"
+
+	if status, _ := doJSON(t, http.MethodPut, schemaURL, map[string]interface{}{"type": "object", "anyOf": []string{}}); status != http.StatusBadRequest {
+		t.Fatalf("expected unsupported schema to be rejected, got %d", status)
+	}
+	status, body := doJSON(t, http.MethodPut, schemaURL, map[string]interface{}{
+		"type":     "object",
+		"required": []string{"team"},
+		"properties": map[string]interface{}{
+			"team": map[string]interface{}{"type": "string", "enum": []string{"search", "billing"}},
+			"tier": map[string]interface{}{"type": "integer"},
+		},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("save schema: status %d, body %v", status, body)
+	}
+
+	// Metadata is validated on create and update, listing every violation
+	status, body = doJSON(t, http.MethodPost, projectsURL, map[string]interface{}{
+		"name": "Search", "metadata": map[string]interface{}{"tier": "gold"},
+	})
+	violations, _ := body["violations"].([]interface{})
+	if status != http.StatusBadRequest || len(violations) != 2 {
+		t.Fatalf("expected two violations, got %d %v", status, body)
+	}
+	status, body = doJSON(t, http.MethodPost, projectsURL, map[string]interface{}{
+		"name": "Search", "metadata": map[string]interface{}{"team": "search", "tier": 1},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("create: status %d, body %v", status, body)
+	}
+	searchID, _ := body["id"].(string)
+	if status, body := doJSON(t, http.MethodPost, projectsURL, map[string]interface{}{
+		"name": "Billing", "metadata": map[string]interface{}{"team": "billing"},
+	}); status != http.StatusOK {
+		t.Fatalf("create: status %d, body %v", status, body)
+	}
+	if status, _ := doJSON(t, http.MethodPut, ts.URL+"/admin/v1/projects/"+searchID, map[string]interface{}{
+		"metadata": map[string]interface{}{"team": "sales"},
+	}); status != http.StatusBadRequest {
+		t.Fatalf("expected invalid update to be rejected, got %d", status)
+	}
+
+	// Declared fields can filter project listings
+	status, body = doJSON(t, http.MethodGet, projectsURL+"/?meta.team=search", nil)
+	projects, _ := body["projects"].([]interface{})
+	if status != http.StatusOK || len(projects) != 1 || projects[0].(map[string]interface{})["id"] != searchID || body["total"] != 1.0 {
+		t.Fatalf("expected only the search project, got %d %v", status, body)
+	}
+	if status, body := doJSON(t, http.MethodGet, projectsURL+"/", nil); status != http.StatusOK || body["total"] != 2.0 {
+		t.Fatalf("expected unfiltered listing of both projects, got %d %v", status, body)
+	}
+	if status, _ := doJSON(t, http.MethodGet, projectsURL+"/?meta.region=eu", nil); status != http.StatusBadRequest {
+		t.Fatalf("expected undeclared filter to be rejected, got %d", status)
+	}
+
+	if status, _ := doJSON(t, http.MethodDelete, schemaURL, nil); status != http.StatusOK {
+		t.Fatalf("delete schema: status %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodGet, schemaURL, nil); status != http.StatusNotFound {
+		t.Fatalf("expected deleted schema to be missing, got %d", status)
+	}
+}
@@ -27,14 +27,7 @@ func RequestID(next http.Handler) http.Handler {
 // RealIP extracts the real client IP
 func RealIP(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		remoteAddr := r.RemoteAddr
-		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-			remoteAddr = strings.Split(xff, ",")[0]
-		} else if xri := r.Header.Get("X-Real-IP"); xri != "" {
-			remoteAddr = xri
-		}
-
-		ctx := context.WithValue(r.Context(), "remote_addr", remoteAddr)
+		ctx := context.WithValue(r.Context(), "remote_addr", ClientIP(r))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// trustedProxies are the networks of reverse proxies whose forwarding
+// headers are believed
+var trustedProxies struct {
+	mu       sync.RWMutex
+	networks []*net.IPNet
+}
+
+// SetTrustedProxies sets the reverse proxies, as IPs or CIDRs, whose
+// X-Forwarded-For and X-Real-IP headers ClientIP believes. Without trusted
+// proxies the client is always the connection's peer.
+func SetTrustedProxies(proxies []string) error {
+	var networks []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		networks = append(networks, network)
+	}
+
+	trustedProxies.mu.Lock()
+	defer trustedProxies.mu.Unlock()
+	trustedProxies.networks = networks
+	return nil
+}
+
+// trustedProxy reports whether ip is a configured reverse proxy
+func trustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	trustedProxies.mu.RLock()
+	defer trustedProxies.mu.RUnlock()
+	for _, network := range trustedProxies.networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client making a request. Forwarding
+// headers are only believed when the connection comes from a trusted proxy;
+// X-Forwarded-For is then read from the right, skipping trusted proxies, so
+// a client cannot choose its address by sending the header itself.
+func ClientIP(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !trustedProxy(peer) {
+		return peer
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				// A malformed hop was added by an untrusted party
+				break
+			}
+			if !trustedProxy(hop) || i == 0 {
+				return hop
+			}
+		}
+		return peer
+	}
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(real) != nil {
+		return real
+	}
+	return peer
+}
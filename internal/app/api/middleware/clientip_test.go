@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	for _, tc := range []struct {
+		name, remote, forwarded, realIP, want string
+	}{
+		{"direct client", "203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"spoofed header from an untrusted peer", "203.0.113.7:5000", "1.2.3.4", "5.6.7.8", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:443", "198.51.100.9", "", "198.51.100.9"},
+		{"single trusted proxy address", "192.168.1.1:443", "198.51.100.9", "", "198.51.100.9"},
+		{"client prepends a fake hop", "10.1.2.3:443", "1.2.3.4, 198.51.100.9", "", "198.51.100.9"},
+		{"chain of trusted proxies", "10.1.2.3:443", "198.51.100.9, 10.9.9.9", "", "198.51.100.9"},
+		{"only trusted hops", "10.1.2.3:443", "10.4.4.4, 10.9.9.9", "", "10.4.4.4"},
+		{"malformed hop", "10.1.2.3:443", "198.51.100.9, garbage", "", "10.1.2.3"},
+		{"real IP from a trusted proxy", "10.1.2.3:443", "", "198.51.100.9", "198.51.100.9"},
+		{"IPv6 trusted proxy", "[2001:db8::1]:443", "2001:db9::5", "", "2001:db9::5"},
+		{"untrusted IPv4 peer in trusted-looking range", "11.0.0.1:443", "198.51.100.9", "", "11.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remote
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+			if got := ClientIP(req); got != tc.want {
+				t.Fatalf("ClientIP() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+
+	if err := SetTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Fatal("expected an invalid trusted proxy to be rejected")
+	}
+}
@@ -1,11 +1,21 @@
 package middleware
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+	"github.com/guileen/metabase/pkg/infra/kv"
 )
 
 // CORS middleware
@@ -15,10 +25,10 @@ func CORS(next http.Handler) http.Handler {
 
 // CORSConfig struct for configurable CORS middleware
 type CORSConfig struct {
-	AllowedOrigins []string
-	AllowedMethods []string
-	AllowedHeaders []string
-	MaxAge         int
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	MaxAge         int      `json:"max_age,omitempty"`
 }
 
 // CORSHandler returns a CORS middleware handler with the given configuration
@@ -59,3 +69,204 @@ func (c CORSConfig) CORSHandler() func(http.Handler) http.Handler {
 func CORSWithConfig(config CORSConfig) func(http.Handler) http.Handler {
 	return config.CORSHandler()
 }
+
+// DefaultCORSConfig returns the CORS settings applied to requests that do not
+// belong to a tenant, and to tenants without CORS settings. Any origin may
+// call the API, but credentials are never allowed for wildcard origins.
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "apikey"},
+		MaxAge:         600,
+	}
+}
+
+// TenantCORSLoader loads a tenant's CORS settings; nil means the tenant has
+// none and the defaults apply
+type TenantCORSLoader func(ctx context.Context, tenantID string) (*CORSConfig, error)
+
+// TenantResolver returns the tenant a request belongs to, or "" when the
+// request is not tenant scoped
+type TenantResolver func(r *http.Request) string
+
+// TenantCORS applies each tenant's CORS settings. Settings are cached in a
+// kv.Store; call Invalidate when a tenant's settings change.
+type TenantCORS struct {
+	defaults CORSConfig
+	load     TenantCORSLoader
+	resolve  TenantResolver
+	logger   *zap.Logger
+	exempt   []string
+
+	mu    sync.RWMutex
+	cache kv.Store
+	ttl   time.Duration
+}
+
+// NewTenantCORS creates a per-tenant CORS middleware with an in-memory cache
+func NewTenantCORS(defaults CORSConfig, load TenantCORSLoader, resolve TenantResolver, logger *zap.Logger) *TenantCORS {
+	return &TenantCORS{
+		defaults: defaults,
+		load:     load,
+		resolve:  resolve,
+		logger:   logger,
+		cache:    kv.NewMemoryStore(10000),
+		ttl:      5 * time.Minute,
+	}
+}
+
+// SetCacheStore moves the settings cache to store. With a store shared
+// between instances, an invalidation on one instance reaches all of them;
+// otherwise other instances pick up changes when their entries expire.
+func (tc *TenantCORS) SetCacheStore(store kv.Store) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	tc.cache = store
+}
+
+func (tc *TenantCORS) store() kv.Store {
+	tc.mu.RLock()
+	defer tc.mu.RUnlock()
+	return tc.cache
+}
+
+// Exempt passes requests under the path prefixes through untouched, for
+// routes that apply their own CORS policy
+func (tc *TenantCORS) Exempt(prefixes ...string) {
+	tc.exempt = append(tc.exempt, prefixes...)
+}
+
+// Invalidate drops the cached settings of a tenant
+func (tc *TenantCORS) Invalidate(ctx context.Context, tenantID string) {
+	if err := tc.store().Delete(ctx, tenantCORSKey(tenantID)); err != nil {
+		tc.logger.Warn("failed to invalidate tenant CORS settings", zap.String("tenant_id", tenantID), zap.Error(err))
+	}
+}
+
+// Config returns the CORS settings applied to a tenant's requests. Fields the
+// tenant leaves empty fall back to the defaults.
+func (tc *TenantCORS) Config(ctx context.Context, tenantID string) CORSConfig {
+	if tenantID == "" {
+		return tc.defaults
+	}
+
+	var tenantConfig *CORSConfig
+	store := tc.store()
+	key := tenantCORSKey(tenantID)
+	if data, err := store.Get(ctx, key); err == nil {
+		if err := json.Unmarshal(data, &tenantConfig); err != nil {
+			tenantConfig = nil
+		}
+	} else {
+		loaded, err := tc.load(ctx, tenantID)
+		if err != nil {
+			// Not cached, so the next request retries the lookup
+			tc.logger.Error("failed to load tenant CORS settings", zap.String("tenant_id", tenantID), zap.Error(err))
+			return tc.defaults
+		}
+		tenantConfig = loaded
+		if data, err := json.Marshal(loaded); err == nil {
+			store.Set(ctx, key, data, tc.ttl)
+		}
+	}
+	if tenantConfig == nil {
+		return tc.defaults
+	}
+
+	config := *tenantConfig
+	if len(config.AllowedOrigins) == 0 {
+		config.AllowedOrigins = tc.defaults.AllowedOrigins
+	}
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = tc.defaults.AllowedMethods
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = tc.defaults.AllowedHeaders
+	}
+	if config.MaxAge == 0 {
+		config.MaxAge = tc.defaults.MaxAge
+	}
+	return config
+}
+
+// Middleware applies the CORS settings of the request's tenant. Preflight
+// requests from origins the tenant does not allow are rejected.
+func (tc *TenantCORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range tc.exempt {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		config := tc.Config(r.Context(), tc.resolve(r))
+		origin := r.Header.Get("Origin")
+
+		allowed := false
+		for _, allowedOrigin := range config.AllowedOrigins {
+			if allowedOrigin == "*" {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+				allowed = true
+				break
+			}
+			if origin != "" && strings.EqualFold(strings.TrimSuffix(allowedOrigin, "/"), origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				allowed = true
+				break
+			}
+		}
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions {
+			if origin != "" && !allowed {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(config.MaxAge))
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func tenantCORSKey(tenantID string) string {
+	return "cors:tenant:" + tenantID
+}
+
+// TenantCORSFromDB loads CORS settings from the api.cors section of the
+// tenants' settings
+func TenantCORSFromDB(db *sql.DB) TenantCORSLoader {
+	return func(ctx context.Context, tenantID string) (*CORSConfig, error) {
+		var settingsJSON sql.NullString
+		err := db.QueryRowContext(ctx,
+			`SELECT settings FROM tenants WHERE id = ? AND deleted_at IS NULL`, tenantID).Scan(&settingsJSON)
+		if err == sql.ErrNoRows || !settingsJSON.Valid {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var settings struct {
+			API *tenant.APISettings `json:"api"`
+		}
+		if err := json.Unmarshal([]byte(settingsJSON.String), &settings); err != nil {
+			return nil, fmt.Errorf("invalid tenant settings: %w", err)
+		}
+		if settings.API == nil || settings.API.CORS == nil {
+			return nil, nil
+		}
+		cors := settings.API.CORS
+		return &CORSConfig{
+			AllowedOrigins: cors.AllowedOrigins,
+			AllowedMethods: cors.AllowedMethods,
+			AllowedHeaders: cors.AllowedHeaders,
+			MaxAge:         cors.MaxAge,
+		}, nil
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestTenantCORS(t *testing.T) {
+	loads := 0
+	settings := map[string]*CORSConfig{
+		"t1": {AllowedOrigins: []string{"https://app.example.com/"}, AllowedMethods: []string{"GET"}},
+	}
+	load := func(ctx context.Context, tenantID string) (*CORSConfig, error) {
+		loads++
+		return settings[tenantID], nil
+	}
+	resolve := func(r *http.Request) string { return r.URL.Query().Get("tenant") }
+	cors := NewTenantCORS(DefaultCORSConfig(), load, resolve, zap.NewNop())
+	handler := cors.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	do := func(method, tenant, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/?tenant="+tenant, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do(http.MethodOptions, "t1", "https://app.example.com")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Methods") != "GET" {
+		t.Fatalf("unexpected preflight response %d %v", rec.Code, rec.Header())
+	}
+	// Unset fields fall back to the defaults
+	if rec.Header().Get("Access-Control-Allow-Headers") != "Content-Type, Authorization, apikey" {
+		t.Fatalf("expected default headers, got %q", rec.Header().Get("Access-Control-Allow-Headers"))
+	}
+	if rec := do(http.MethodOptions, "t1", "https://evil.example.com"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected preflight from foreign origin to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "t1", "https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("foreign origin must not be allowed, got %v", rec.Header())
+	}
+	if loads != 1 {
+		t.Fatalf("expected settings to be cached, loaded %d times", loads)
+	}
+
+	// Tenants without settings and untenanted requests use the defaults
+	if rec := do(http.MethodGet, "t2", "https://any.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("expected default origin policy, got %v", rec.Header())
+	}
+	if rec := do(http.MethodGet, "", "https://any.example.com"); rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("unexpected default response %d %v", rec.Code, rec.Header())
+	}
+
+	settings["t1"] = &CORSConfig{AllowedOrigins: []string{"https://evil.example.com"}}
+	if rec := do(http.MethodGet, "t1", "https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("cached settings should apply until invalidated")
+	}
+	cors.Invalidate(context.Background(), "t1")
+	if rec := do(http.MethodGet, "t1", "https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "https://evil.example.com" {
+		t.Fatalf("expected updated settings after invalidation, got %v", rec.Header())
+	}
+
+	// Exempt routes handle CORS themselves, preflights included
+	cors.Exempt("/public/")
+	req := httptest.NewRequest(http.MethodOptions, "/public/query", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected exempt route to bypass CORS, got %d %v", rec.Code, rec.Header())
+	}
+}
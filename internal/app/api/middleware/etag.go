@@ -0,0 +1,328 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/infra/events"
+	"github.com/guileen/metabase/pkg/infra/kv"
+	"go.uber.org/zap"
+)
+
+// maxCachedBody is the largest response body kept in the response cache
+const maxCachedBody = 1 << 20
+
+// ScopeResolver returns the cache scopes of a request, e.g. "tenant:<id>"
+// and "project:<id>"; requests without scopes bypass the response cache
+type ScopeResolver func(r *http.Request) []string
+
+// ResponseCache adds ETags to GET responses, answers matching If-None-Match
+// requests with 304, and keeps successful responses for a few seconds.
+// Entries are keyed by the generation of each scope of the request, so a
+// change event on any of them invalidates the entry. Successful writes
+// publish change events for their scopes on the bus.
+//
+// Reads are served by Middleware, which must run after authentication and
+// authorization since hits skip the rest of the chain; writes are observed
+// by InvalidationMiddleware, which can run anywhere.
+type ResponseCache struct {
+	resolve ScopeResolver
+	bus     *events.Bus
+	logger  *zap.Logger
+
+	mu    sync.RWMutex
+	cache kv.Store
+	ttl   time.Duration
+}
+
+// NewResponseCache creates a response cache with an in-memory store,
+// invalidated by resource change events on bus
+func NewResponseCache(resolve ScopeResolver, bus *events.Bus, logger *zap.Logger) *ResponseCache {
+	rc := &ResponseCache{
+		resolve: resolve,
+		bus:     bus,
+		logger:  logger,
+		cache:   kv.NewMemoryStore(10000),
+		ttl:     10 * time.Second,
+	}
+	bus.Subscribe(events.TopicResourceChanged, func(ctx context.Context, event events.Event) {
+		rc.Invalidate(ctx, event.Key)
+	})
+	return rc
+}
+
+// SetCacheStore moves the response cache to store. With a store shared
+// between instances, an invalidation on one instance reaches all of them;
+// otherwise other instances serve stale entries until they expire.
+func (rc *ResponseCache) SetCacheStore(store kv.Store) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.cache = store
+}
+
+func (rc *ResponseCache) store() kv.Store {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.cache
+}
+
+// Invalidate drops the cached responses of a scope by moving it to a new
+// generation
+func (rc *ResponseCache) Invalidate(ctx context.Context, scope string) {
+	if err := rc.store().Set(ctx, scopeGenerationKey(scope), []byte(newGeneration()), 2*rc.ttl); err != nil {
+		rc.logger.Warn("failed to invalidate cached responses", zap.String("scope", scope), zap.Error(err))
+	}
+}
+
+// generation returns the current generation of a scope, starting one when
+// there is none
+func (rc *ResponseCache) generation(ctx context.Context, store kv.Store, scope string) (string, error) {
+	key := scopeGenerationKey(scope)
+	if data, err := store.Get(ctx, key); err == nil {
+		return string(data), nil
+	}
+	generation := newGeneration()
+	if err := store.Set(ctx, key, []byte(generation), 2*rc.ttl); err != nil {
+		return "", err
+	}
+	return generation, nil
+}
+
+// cachedResponse is a response kept in the cache
+type cachedResponse struct {
+	Status      int    `json:"status"`
+	ETag        string `json:"etag"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// Middleware serves GET requests from the cache, adds ETags and handles
+// conditional requests. Other requests are passed through. Hits do not reach
+// next, so it has to be mounted behind the middleware checking that the
+// caller may read the resource.
+func (rc *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scopes := rc.resolve(r)
+		if len(scopes) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		store := rc.store()
+		key, err := rc.responseKey(ctx, store, r, scopes)
+		if err != nil {
+			rc.logger.Warn("failed to resolve response cache key", zap.Error(err))
+		}
+		if key != "" {
+			if data, err := store.Get(ctx, key); err == nil {
+				var cached cachedResponse
+				if err := json.Unmarshal(data, &cached); err == nil {
+					w.Header().Set("X-Cache", "HIT")
+					writeCached(w, r, &cached)
+					return
+				}
+			}
+		}
+
+		rec := &bufferedResponse{w: w, header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.streaming {
+			return
+		}
+		for name, values := range rec.header {
+			w.Header()[name] = values
+		}
+		if rec.status != http.StatusOK {
+			w.WriteHeader(rec.status)
+			w.Write(rec.body.Bytes())
+			return
+		}
+
+		cached := cachedResponse{
+			Status:      rec.status,
+			ETag:        rec.header.Get("ETag"),
+			ContentType: rec.header.Get("Content-Type"),
+			Body:        rec.body.Bytes(),
+		}
+		if cached.ETag == "" {
+			sum := sha256.Sum256(cached.Body)
+			cached.ETag = `W/"` + hex.EncodeToString(sum[:16]) + `"`
+		}
+		if key != "" && rec.header.Get("Cache-Control") != "no-store" {
+			if data, err := json.Marshal(cached); err == nil {
+				store.Set(ctx, key, data, rc.ttl)
+			}
+		}
+		w.Header().Set("X-Cache", "MISS")
+		writeCached(w, r, &cached)
+	})
+}
+
+// InvalidationMiddleware passes write requests through and, when they
+// succeed, publishes change events for the request's scopes
+func (rc *ResponseCache) InvalidationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scopes := rc.resolve(r)
+		if len(scopes) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ww := NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= 300 {
+			return
+		}
+		for _, scope := range scopes {
+			rc.bus.Publish(r.Context(), events.Event{Topic: events.TopicResourceChanged, Key: scope})
+		}
+	})
+}
+
+// varyHeaders are the request headers a cached response depends on: the
+// credentials identifying the caller and the negotiated representation
+var varyHeaders = []string{"Authorization", "X-API-Key", "apikey", "Accept", "Accept-Language"}
+
+// responseKey derives the cache key of a GET request from its URL, the
+// headers in varyHeaders and the generations of its scopes
+func (rc *ResponseCache) responseKey(ctx context.Context, store kv.Store, r *http.Request, scopes []string) (string, error) {
+	hash := sha256.New()
+	parts := []string{r.URL.RequestURI()}
+	for _, name := range varyHeaders {
+		parts = append(parts, r.Header.Get(name))
+	}
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	for _, scope := range scopes {
+		generation, err := rc.generation(ctx, store, scope)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(scope + "=" + generation))
+		hash.Write([]byte{0})
+	}
+	return "etag:resp:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeCached writes a response, or 304 when the client already has it
+func writeCached(w http.ResponseWriter, r *http.Request, cached *cachedResponse) {
+	w.Header().Set("ETag", cached.ETag)
+	if etagMatches(r.Header.Get("If-None-Match"), cached.ETag) {
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if cached.ContentType != "" {
+		w.Header().Set("Content-Type", cached.ContentType)
+	}
+	w.WriteHeader(cached.Status)
+	w.Write(cached.Body)
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using
+// the weak comparison of RFC 9110
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// SetETag sets a strong ETag derived from the identity and version of the
+// resource in a response, such as its ID and updated_at
+func SetETag(w http.ResponseWriter, parts ...string) {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash.Sum(nil)[:16])+`"`)
+}
+
+func scopeGenerationKey(scope string) string {
+	return "etag:gen:" + scope
+}
+
+func newGeneration() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// bufferedResponse captures a response so it can be hashed and cached.
+// Responses larger than maxCachedBody are streamed to w uncached instead.
+type bufferedResponse struct {
+	w         http.ResponseWriter
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	wrote     bool
+	streaming bool
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.wrote {
+		return
+	}
+	b.status = status
+	b.wrote = true
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wrote = true
+	if !b.streaming && b.body.Len()+len(p) > maxCachedBody {
+		b.stream()
+	}
+	if b.streaming {
+		return b.w.Write(p)
+	}
+	return b.body.Write(p)
+}
+
+// stream writes the buffered response to w and passes later writes through
+func (b *bufferedResponse) stream() {
+	b.streaming = true
+	for name, values := range b.header {
+		b.w.Header()[name] = values
+	}
+	b.w.Header().Set("X-Cache", "BYPASS")
+	b.w.WriteHeader(b.status)
+	b.w.Write(b.body.Bytes())
+	b.body.Reset()
+}
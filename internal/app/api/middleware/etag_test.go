@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/infra/events"
+	"go.uber.org/zap"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	version := "v1"
+	resolve := func(r *http.Request) []string {
+		if strings.HasPrefix(r.URL.Path, "/projects/p1") {
+			return []string{"project:p1"}
+		}
+		return nil
+	}
+	cache := NewResponseCache(resolve, events.NewBus(), zap.NewNop())
+	handler := cache.InvalidationMiddleware(cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			version = "v2"
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		calls++
+		if r.URL.Path == "/projects/p1" {
+			SetETag(w, "p1", version)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version":"` + version + `"}`))
+	})))
+	do := func(method, path, ifNoneMatch string, headers ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do(http.MethodGet, "/projects/p1", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || strings.HasPrefix(etag, "W/") || first.Body.String() != `{"version":"v1"}` {
+		t.Fatalf("unexpected first response %d %q %s", first.Code, etag, first.Body)
+	}
+	rec := do(http.MethodGet, "/projects/p1", etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || calls != 1 || rec.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("expected a cached 304, got %d after %d calls", rec.Code, calls)
+	}
+
+	// Other credentials and languages get their own entries
+	for _, header := range [][]string{{"apikey", "k2"}, {"Accept-Language", "zh-CN"}} {
+		if rec := do(http.MethodGet, "/projects/p1", "", header...); rec.Header().Get("X-Cache") != "MISS" {
+			t.Fatalf("expected %s to miss the cache, got %v", header[0], rec.Header())
+		}
+	}
+
+	// Responses without a handler ETag get a weak ETag of the body
+	docs := do(http.MethodGet, "/projects/p1/documents", "")
+	if weak := docs.Header().Get("ETag"); !strings.HasPrefix(weak, "W/") {
+		t.Fatalf("expected a weak ETag, got %q", weak)
+	}
+
+	// A successful write invalidates the scope
+	if rec := do(http.MethodPut, "/projects/p1", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected write status %d", rec.Code)
+	}
+	rec = do(http.MethodGet, "/projects/p1", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag || rec.Body.String() != `{"version":"v2"}` {
+		t.Fatalf("expected the updated project, got %d %q %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+
+	// Unscoped requests bypass the cache
+	do(http.MethodGet, "/other", "")
+	before := calls
+	if rec := do(http.MethodGet, "/other", ""); calls != before+1 || rec.Header().Get("ETag") != "" {
+		t.Fatalf("expected unscoped requests to bypass the cache, got %v", rec.Header())
+	}
+}
+
+func TestResponseCacheStreamsLargeResponses(t *testing.T) {
+	calls := 0
+	body := strings.Repeat("x", maxCachedBody+1)
+	cache := NewResponseCache(func(r *http.Request) []string { return []string{"project:p1"} }, events.NewBus(), zap.NewNop())
+	handler := cache.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(body[:10]))
+		w.Write([]byte(body[10:]))
+	}))
+
+	for i := 1; i <= 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/projects/p1/export", nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != body || rec.Header().Get("X-Cache") != "BYPASS" || rec.Header().Get("Content-Type") != "text/plain" {
+			t.Fatalf("unexpected large response %d %v", rec.Code, rec.Header())
+		}
+		if calls != i {
+			t.Fatalf("expected large responses not to be cached, got %d calls", calls)
+		}
+	}
+}
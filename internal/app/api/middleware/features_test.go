@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/features"
+)
+
+func TestRequireFeature(t *testing.T) {
+	service := features.NewService(nil, func(ctx context.Context, tenantID string) (*features.TenantFlags, error) {
+		return &features.TenantFlags{Plan: tenantID}, nil
+	})
+	service.Register(features.Flag{Key: "uploads", Plans: map[string]bool{"pro": true}})
+
+	resolve := func(r *http.Request) string { return r.URL.Query().Get("tenant") }
+	handler := FeatureFlags(service, resolve, zap.NewNop())(RequireFeature("uploads")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !features.Enabled(r.Context(), "uploads") {
+			t.Error("flag should be readable from the request context")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	for tenant, status := range map[string]int{"pro": http.StatusNoContent, "free": http.StatusNotFound, "": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?tenant="+tenant, nil))
+		if rec.Code != status {
+			t.Fatalf("tenant %q: expected %d, got %d", tenant, status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/i18n"
+	"github.com/guileen/metabase/pkg/infra/kv"
+)
+
+// TenantLocaleLoader loads a tenant's preferred locale; "" means the tenant
+// has none
+type TenantLocaleLoader func(ctx context.Context, tenantID string) (string, error)
+
+// UserLocaleResolver returns the preferred locale of the request's user, or
+// "" when there is none
+type UserLocaleResolver func(r *http.Request) string
+
+// TenantLocales resolves the locale of requests and notifications: the
+// user's locale, then the tenant's, then the server default. Tenant locales
+// are cached in a kv.Store; call Invalidate when a tenant's settings change.
+type TenantLocales struct {
+	defaultLocale string
+	load          TenantLocaleLoader
+	resolve       TenantResolver
+	logger        *zap.Logger
+
+	mu    sync.RWMutex
+	cache kv.Store
+	ttl   time.Duration
+}
+
+// NewTenantLocales creates a locale resolver with an in-memory cache
+func NewTenantLocales(defaultLocale string, load TenantLocaleLoader, resolve TenantResolver, logger *zap.Logger) *TenantLocales {
+	return &TenantLocales{
+		defaultLocale: defaultLocale,
+		load:          load,
+		resolve:       resolve,
+		logger:        logger,
+		cache:         kv.NewMemoryStore(10000),
+		ttl:           5 * time.Minute,
+	}
+}
+
+// SetCacheStore moves the locale cache to store, so invalidations reach all
+// instances sharing it
+func (tl *TenantLocales) SetCacheStore(store kv.Store) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.cache = store
+}
+
+func (tl *TenantLocales) store() kv.Store {
+	tl.mu.RLock()
+	defer tl.mu.RUnlock()
+	return tl.cache
+}
+
+// Invalidate drops the cached locale of a tenant
+func (tl *TenantLocales) Invalidate(ctx context.Context, tenantID string) {
+	if err := tl.store().Delete(ctx, tenantLocaleKey(tenantID)); err != nil {
+		tl.logger.Warn("failed to invalidate tenant locale", zap.String("tenant_id", tenantID), zap.Error(err))
+	}
+}
+
+// TenantLocale returns the locale a tenant prefers, or "" when it has none
+func (tl *TenantLocales) TenantLocale(ctx context.Context, tenantID string) string {
+	if tenantID == "" {
+		return ""
+	}
+	store := tl.store()
+	key := tenantLocaleKey(tenantID)
+	if data, err := store.Get(ctx, key); err == nil {
+		return string(data)
+	}
+	locale, err := tl.load(ctx, tenantID)
+	if err != nil {
+		// Not cached, so the next lookup retries
+		tl.logger.Error("failed to load tenant locale", zap.String("tenant_id", tenantID), zap.Error(err))
+		return ""
+	}
+	store.Set(ctx, key, []byte(locale), tl.ttl)
+	return locale
+}
+
+// Locale resolves the locale of messages sent to a user of a tenant; either
+// may be empty
+func (tl *TenantLocales) Locale(ctx context.Context, userLocale, tenantID string) string {
+	return i18n.Resolve(userLocale, tl.TenantLocale(ctx, tenantID), tl.defaultLocale)
+}
+
+// TenantNotificationLocale resolves the locale of notifications sent to a
+// tenant's contacts rather than to a user
+func (tl *TenantLocales) TenantNotificationLocale(ctx context.Context, tenantID string) string {
+	return tl.Locale(ctx, "", tenantID)
+}
+
+// Middleware stores the request's locale in its context for i18n.T and sets
+// Content-Language
+func (tl *TenantLocales) Middleware(users UserLocaleResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := tl.Locale(r.Context(), users(r), tl.resolve(r))
+			w.Header().Set("Content-Language", locale)
+			next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
+		})
+	}
+}
+
+func tenantLocaleKey(tenantID string) string {
+	return "locale:tenant:" + tenantID
+}
+
+// TenantLocaleFromDB loads the locale field of the tenants' settings
+func TenantLocaleFromDB(db *sql.DB) TenantLocaleLoader {
+	return func(ctx context.Context, tenantID string) (string, error) {
+		var settingsJSON sql.NullString
+		err := db.QueryRowContext(ctx,
+			`SELECT settings FROM tenants WHERE id = ? AND deleted_at IS NULL`, tenantID).Scan(&settingsJSON)
+		if err == sql.ErrNoRows || !settingsJSON.Valid || settingsJSON.String == "" {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		var settings struct {
+			Locale string `json:"locale"`
+		}
+		if err := json.Unmarshal([]byte(settingsJSON.String), &settings); err != nil {
+			return "", fmt.Errorf("invalid tenant settings: %w", err)
+		}
+		return settings.Locale, nil
+	}
+}
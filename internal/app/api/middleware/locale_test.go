@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/i18n"
+)
+
+func TestTenantLocales(t *testing.T) {
+	loads := 0
+	locales := map[string]string{"t1": "zh-CN"}
+	load := func(ctx context.Context, tenantID string) (string, error) {
+		loads++
+		return locales[tenantID], nil
+	}
+	resolve := func(r *http.Request) string { return r.URL.Query().Get("tenant") }
+	tl := NewTenantLocales("en", load, resolve, zap.NewNop())
+	handler := tl.Middleware(func(r *http.Request) string { return r.Header.Get("X-User-Locale") })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(i18n.T(r.Context(), "Tenant not found", nil)))
+		}))
+
+	do := func(tenant, userLocale string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/?tenant="+tenant, nil)
+		if userLocale != "" {
+			req.Header.Set("X-User-Locale", userLocale)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The tenant's locale applies to users without one
+	if rec := do("t1", ""); rec.Body.String() != "租户不存在" || rec.Header().Get("Content-Language") != "zh-CN" {
+		t.Fatalf("expected tenant locale, got %q %v", rec.Body.String(), rec.Header())
+	}
+	// The user's locale takes precedence
+	if rec := do("t1", "en-US"); rec.Body.String() != "Tenant not found" || rec.Header().Get("Content-Language") != "en" {
+		t.Fatalf("expected user locale, got %q %v", rec.Body.String(), rec.Header())
+	}
+	if loads != 1 {
+		t.Fatalf("expected tenant locale to be cached, loaded %d times", loads)
+	}
+
+	locales["t1"] = ""
+	tl.Invalidate(context.Background(), "t1")
+	if rec := do("t1", "fr"); rec.Header().Get("Content-Language") != "en" {
+		t.Fatalf("expected default locale, got %v", rec.Header())
+	}
+	if got := tl.TenantNotificationLocale(context.Background(), "t2"); got != "en" {
+		t.Fatalf("expected default notification locale, got %q", got)
+	}
+}
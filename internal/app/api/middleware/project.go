@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"strings"
 
@@ -28,19 +27,19 @@ type ProjectContext struct {
 
 // ProjectMiddleware handles project authorization and collaboration
 type ProjectMiddleware struct {
-	db            interface{} // *sql.DB placeholder
-	rbacManager   *auth.RBACManager
-	tenantManager *auth.TenantManager
-	logger        *zap.Logger
+	db          interface{} // *sql.DB placeholder
+	rbacManager *auth.RBACManager
+	members     *auth.ProjectMembers
+	logger      *zap.Logger
 }
 
 // NewProjectMiddleware creates a new project middleware
-func NewProjectMiddleware(db interface{}, rbacManager *auth.RBACManager, tenantManager *auth.TenantManager, logger *zap.Logger) *ProjectMiddleware {
+func NewProjectMiddleware(db interface{}, rbacManager *auth.RBACManager, members *auth.ProjectMembers, logger *zap.Logger) *ProjectMiddleware {
 	return &ProjectMiddleware{
-		db:            db,
-		rbacManager:   rbacManager,
-		tenantManager: tenantManager,
-		logger:        logger,
+		db:          db,
+		rbacManager: rbacManager,
+		members:     members,
+		logger:      logger,
 	}
 }
 
@@ -119,7 +118,7 @@ func (pm *ProjectMiddleware) ProjectAccessMiddleware(requiredRole string) func(h
 			}
 
 			// Check user access
-			userProject, err := pm.getUserProjectRole(userID, projectID)
+			userProject, err := pm.getUserProjectRole(r.Context(), userID, projectID)
 			if err != nil {
 				pm.logger.Error("Failed to check project access", zap.String("user_id", userID), zap.String("project_id", projectID), zap.Error(err))
 				http.Error(w, "Failed to verify project access", http.StatusInternalServerError)
@@ -139,7 +138,7 @@ func (pm *ProjectMiddleware) ProjectAccessMiddleware(requiredRole string) func(h
 			}
 
 			// Get project tenant ID
-			project, err := pm.tenantManager.GetProject(projectID)
+			tenantID, err := pm.members.ProjectTenantID(r.Context(), projectID)
 			if err != nil {
 				http.Error(w, "Project not found", http.StatusNotFound)
 				return
@@ -147,7 +146,7 @@ func (pm *ProjectMiddleware) ProjectAccessMiddleware(requiredRole string) func(h
 
 			// Add project context
 			ctx := context.WithValue(r.Context(), "user_id", userID)
-			ctx = context.WithValue(ctx, "tenant_id", project.TenantID)
+			ctx = context.WithValue(ctx, "tenant_id", tenantID)
 			ctx = context.WithValue(ctx, "project_id", projectID)
 			ctx = context.WithValue(ctx, "user_role", userProject.Role)
 			ctx = context.WithValue(ctx, "is_creator", userProject.IsCreator)
@@ -192,7 +191,7 @@ func (pm *ProjectMiddleware) CanManageProjectMiddleware(next http.Handler) http.
 		}
 
 		// Check user access
-		userProject, err := pm.getUserProjectRole(userID, projectID)
+		userProject, err := pm.getUserProjectRole(r.Context(), userID, projectID)
 		if err != nil {
 			pm.logger.Error("Failed to check project access", zap.String("user_id", userID), zap.String("project_id", projectID), zap.Error(err))
 			http.Error(w, "Failed to verify project access", http.StatusInternalServerError)
@@ -235,6 +234,75 @@ func GetProjectContext(r *http.Request) *ProjectContext {
 	}
 }
 
+// ViewableProjects returns the projects among projectIDs the user may view, in
+// request order. Without projectIDs it returns every project the user belongs to.
+func (pm *ProjectMiddleware) ViewableProjects(ctx context.Context, userID string, projectIDs []string) ([]string, error) {
+	if len(projectIDs) == 0 {
+		userProjects, err := pm.members.GetUserProjects(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		var viewable []string
+		for _, up := range userProjects {
+			if up.IsActive && pm.meetsRoleRequirement(up.Role, auth.ProjectRoleViewer) {
+				viewable = append(viewable, up.ProjectID)
+			}
+		}
+		return viewable, nil
+	}
+
+	if isAdmin, _ := pm.checkSystemAdmin(userID); isAdmin {
+		return projectIDs, nil
+	}
+	// All roles are resolved at once; non-members are refused rather than
+	// reported as errors
+	roles, err := pm.members.EffectiveRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var viewable []string
+	for _, projectID := range projectIDs {
+		if userProject, ok := roles[projectID]; ok && pm.meetsRoleRequirement(userProject.Role, auth.ProjectRoleViewer) {
+			viewable = append(viewable, projectID)
+		}
+	}
+	return viewable, nil
+}
+
+// HasRAGPermission reports whether role is granted a RAG permission in a
+// project, applying the project's overrides over the default policy
+func (pm *ProjectMiddleware) HasRAGPermission(ctx context.Context, projectID, role, permission string) (bool, error) {
+	policy, err := pm.members.GetRAGPolicy(ctx, projectID)
+	if err != nil {
+		return false, err
+	}
+	return policy.Allows(role, permission), nil
+}
+
+// IsSystemAdmin reports whether the user making the request is a system admin
+func (pm *ProjectMiddleware) IsSystemAdmin(r *http.Request) bool {
+	userID := pm.extractUserID(r)
+	if userID == "" {
+		return false
+	}
+	isAdmin, _ := pm.checkSystemAdmin(userID)
+	return isAdmin
+}
+
+// IsSystemAdminUser reports whether an authenticated user is a system admin
+func (pm *ProjectMiddleware) IsSystemAdminUser(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	isAdmin, _ := pm.checkSystemAdmin(userID)
+	return isAdmin
+}
+
+// UserID returns the user making the request, or "" when unauthenticated
+func (pm *ProjectMiddleware) UserID(r *http.Request) string {
+	return pm.extractUserID(r)
+}
+
 // Helper methods
 
 func (pm *ProjectMiddleware) extractUserID(r *http.Request) string {
@@ -267,7 +335,7 @@ func (pm *ProjectMiddleware) checkSystemAdmin(userID string) (bool, error) {
 	return userID == "system_admin" || userID == "admin", nil
 }
 
-func (pm *ProjectMiddleware) getUserProjectRole(userID, projectID string) (*auth.UserProject, error) {
+func (pm *ProjectMiddleware) getUserProjectRole(ctx context.Context, userID, projectID string) (*auth.UserProject, error) {
 	// System admin gets highest privileges
 	if isAdmin, _ := pm.checkSystemAdmin(userID); isAdmin {
 		return &auth.UserProject{
@@ -280,19 +348,8 @@ func (pm *ProjectMiddleware) getUserProjectRole(userID, projectID string) (*auth
 		}, nil
 	}
 
-	// Check user project role
-	userProjects, err := pm.tenantManager.GetUserProjects(userID)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, up := range userProjects {
-		if up.ProjectID == projectID && up.IsActive {
-			return (*auth.UserProject)(up), nil
-		}
-	}
-
-	return nil, fmt.Errorf("user %s is not a member of project %s", userID, projectID)
+	// Check user project role from the cached effective roles
+	return pm.members.UserProjectRole(ctx, userID, projectID)
 }
 
 func (pm *ProjectMiddleware) meetsRoleRequirement(userRole, requiredRole string) bool {
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/kv"
+)
+
+// startInstance starts an API server on the shared database, as one of
+// several instances behind a load balancer
+func startInstance(t *testing.T, dbPath string) (*Server, *httptest.Server) {
+	t.Helper()
+	os.MkdirAll("data", 0o755) // request log storage
+	server, err := NewServer(&Config{DatabasePath: dbPath})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	r := chi.NewRouter()
+	server.setupRoutes(r)
+	ts := httptest.NewServer(server.withMiddleware(r))
+	t.Cleanup(func() {
+		ts.Close()
+		server.Stop(context.Background())
+	})
+	return server, ts
+}
+
+func doJSON(t *testing.T, method, url string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	var reader bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reader).Encode(body)
+	}
+	req, _ := http.NewRequest(method, url, &reader)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestInstancesShareState(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	dbPath := filepath.Join(dir, "metabase.db")
+
+	first, a := startInstance(t, dbPath)
+	_, b := startInstance(t, dbPath)
+
+	// Projects exist only in the database; neither instance has seen them
+	_, err := first.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`)
+	if err == nil {
+		_, err = first.db.Exec(`INSERT INTO projects (id, tenant_id, name, slug, owner_id) VALUES ('p1', 't1', 'Project', 'p1', 'u1')`)
+	}
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	base := "/admin/v1/projects/p1/rag/datasources"
+	status, created := doJSON(t, http.MethodPost, a.URL+base, map[string]interface{}{
+		"name":   "docs",
+		"type":   "filesystem",
+		"config": map[string]interface{}{"root_path": dir},
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("create on first instance: status %d, body %v", status, created)
+	}
+	id := created["data"].(map[string]interface{})["id"].(string)
+
+	if status, body := doJSON(t, http.MethodGet, b.URL+base+"/"+id, nil); status != http.StatusOK {
+		t.Fatalf("get on second instance: status %d, body %v", status, body)
+	}
+	if status, body := doJSON(t, http.MethodDelete, b.URL+base+"/"+id, nil); status != http.StatusOK {
+		t.Fatalf("delete on second instance: status %d, body %v", status, body)
+	}
+	if status, _ := doJSON(t, http.MethodGet, a.URL+base+"/"+id, nil); status != http.StatusNotFound {
+		t.Fatalf("deleted source still visible on first instance: status %d", status)
+	}
+}
+
+func TestSessionRevocationAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	dbPath := filepath.Join(dir, "metabase.db")
+
+	first, _ := startInstance(t, dbPath)
+	second, _ := startInstance(t, dbPath)
+
+	ctx := context.Background()
+	newCache := func(s *Server) *auth.SessionCache {
+		store, err := kv.NewSQLStore(ctx, s.db)
+		if err != nil {
+			t.Fatalf("failed to create kv store: %v", err)
+		}
+		return auth.NewSessionCache(store, time.Minute)
+	}
+	cacheA, cacheB := newCache(first), newCache(second)
+
+	session := &auth.Session{
+		ID:        "sess_1",
+		UserID:    "u1",
+		ExpiresAt: time.Now().Add(time.Hour),
+		IsActive:  true,
+	}
+	cacheA.SetSession(ctx, "token", session)
+	if cacheB.GetSessionByToken(ctx, "token") == nil {
+		t.Fatalf("session cached on first instance is not visible on second")
+	}
+
+	cacheB.DeleteSession(ctx, session.ID)
+	if cacheA.GetSessionByToken(ctx, "token") != nil {
+		t.Fatalf("session revoked on second instance is still accepted on first")
+	}
+}
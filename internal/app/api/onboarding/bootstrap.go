@@ -0,0 +1,109 @@
+package onboarding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+// systemAdminRole 内置的系统管理员角色
+const systemAdminRole = "system.admin"
+
+// BootstrapResult 系统初始化结果
+type BootstrapResult struct {
+	Admin           *User  `json:"admin"`
+	Created         bool   `json:"created"`                    // 管理员已存在时为 false
+	InitialPassword string `json:"initial_password,omitempty"` // 仅在自动生成密码时返回
+}
+
+// Bootstrap 为全新安装创建系统管理员，加入系统租户并拥有系统项目。
+// 重复执行是安全的：邮箱已注册时不修改现有账号和密码。
+func (m *Manager) Bootstrap(ctx context.Context, admin AdminInput) (*BootstrapResult, error) {
+	if err := admin.normalize(); err != nil {
+		return nil, err
+	}
+
+	existing, err := m.findUser(ctx, admin.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return &BootstrapResult{Admin: existing}, nil
+	}
+
+	password, passwordHash, err := hashPassword(admin.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 系统租户和项目由数据库迁移创建
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM projects WHERE id = ? AND tenant_id = ?`,
+		auth.SystemProjectID, auth.SystemTenantID).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check system project: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("system tenant and project not found, database migrations have not run")
+	}
+
+	now := time.Now()
+	user := &User{
+		ID:        uuid.New().String(),
+		TenantID:  auth.SystemTenantID,
+		Email:     admin.Email,
+		Name:      admin.Name,
+		IsActive:  true,
+		CreatedAt: now,
+	}
+	if err := insertUser(ctx, tx, user, passwordHash); err != nil {
+		return nil, err
+	}
+	assignment, err := insertAssignment(ctx, tx, user.ID, auth.SystemTenantID, systemAdminRole, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := insertOwner(ctx, tx, user.ID, auth.SystemTenantID, auth.SystemProjectID, now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, wrapConflict("failed to commit bootstrap", err)
+	}
+
+	m.register(nil, []*auth.UserRole{assignment})
+	m.logger.Info("system admin created", zap.String("user_id", user.ID), zap.String("email", user.Email))
+
+	result := &BootstrapResult{Admin: user, Created: true}
+	if admin.Password == "" {
+		result.InitialPassword = password
+	}
+	return result, nil
+}
+
+// findUser 按邮箱查找用户，不存在时返回 nil
+func (m *Manager) findUser(ctx context.Context, email string) (*User, error) {
+	var user User
+	err := m.db.QueryRowContext(ctx,
+		`SELECT id, tenant_id, email, name, is_active, created_at FROM users WHERE email = ?`, email).
+		Scan(&user.ID, &user.TenantID, &user.Email, &user.Name, &user.IsActive, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,67 @@
+package onboarding
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// Handler 租户开通HTTP处理器
+type Handler struct {
+	manager *Manager
+	logger  *zap.Logger
+}
+
+// NewHandler 创建租户开通处理器
+func NewHandler(manager *Manager, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 注册开通路由
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Post("/", h.handleOnboard)
+}
+
+// handleOnboard 创建租户、管理员、默认项目、默认角色和API密钥
+func (h *Handler) handleOnboard(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+		return
+	}
+
+	result, err := h.manager.Onboard(r.Context(), &req)
+	switch {
+	case errors.Is(err, ErrInvalid):
+		h.error(w, r, http.StatusBadRequest, "Invalid onboarding request", err, "invalid_request")
+		return
+	case errors.Is(err, ErrConflict):
+		h.error(w, r, http.StatusConflict, "Tenant or admin already exists", err, "conflict")
+		return
+	case err != nil:
+		h.logger.Error("failed to onboard tenant", zap.String("slug", req.Tenant.Slug), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to onboard tenant", err, "")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{"data": result})
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
@@ -0,0 +1,420 @@
+package onboarding
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/guileen/metabase/internal/app/api/keys"
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+// Manager 租户开通，在一个事务中创建租户、管理员、默认角色、默认项目和API密钥
+type Manager struct {
+	db     *sql.DB
+	keys   *keys.Manager
+	rbac   *auth.RBACManager
+	logger *zap.Logger
+}
+
+// NewManager 创建租户开通管理器
+func NewManager(db *sql.DB, keysManager *keys.Manager, rbac *auth.RBACManager, logger *zap.Logger) *Manager {
+	return &Manager{
+		db:     db,
+		keys:   keysManager,
+		rbac:   rbac,
+		logger: logger,
+	}
+}
+
+// Initialize 初始化用户和租户角色表
+func (m *Manager) Initialize(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS users (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		email TEXT UNIQUE NOT NULL,
+		name TEXT NOT NULL,
+		password_hash TEXT NOT NULL,
+		is_active BOOLEAN DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS tenant_roles (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		description TEXT,
+		permissions TEXT NOT NULL DEFAULT '[]',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(tenant_id, name)
+	);
+
+	CREATE TABLE IF NOT EXISTS tenant_user_roles (
+		user_id TEXT NOT NULL,
+		tenant_id TEXT NOT NULL,
+		role_id TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, role_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id);
+	CREATE INDEX IF NOT EXISTS idx_tenant_roles_tenant_id ON tenant_roles(tenant_id);
+	`
+
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		m.logger.Error("failed to initialize onboarding tables", zap.Error(err))
+		return fmt.Errorf("failed to initialize onboarding tables: %w", err)
+	}
+	return nil
+}
+
+// LoadRoles 将保存的租户角色和角色分配注册到RBAC，服务启动时调用
+func (m *Manager) LoadRoles(ctx context.Context) error {
+	rows, err := m.db.QueryContext(ctx, `SELECT id, tenant_id, name, description, permissions, created_at FROM tenant_roles`)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant roles: %w", err)
+	}
+	var roles []*auth.Role
+	for rows.Next() {
+		var role auth.Role
+		var description sql.NullString
+		var permissions string
+		if err := rows.Scan(&role.ID, &role.TenantID, &role.Name, &description, &permissions, &role.CreatedAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan tenant role: %w", err)
+		}
+		role.Description = description.String
+		role.UpdatedAt = role.CreatedAt
+		json.Unmarshal([]byte(permissions), &role.Permissions)
+		roles = append(roles, &role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = m.db.QueryContext(ctx, `SELECT user_id, tenant_id, role_id, created_at FROM tenant_user_roles`)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant role assignments: %w", err)
+	}
+	defer rows.Close()
+	var assignments []*auth.UserRole
+	for rows.Next() {
+		var assignment auth.UserRole
+		if err := rows.Scan(&assignment.UserID, &assignment.TenantID, &assignment.RoleID, &assignment.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan tenant role assignment: %w", err)
+		}
+		assignments = append(assignments, &assignment)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	m.register(roles, assignments)
+	return nil
+}
+
+// Onboard 创建租户及其首个管理员、默认角色、默认项目和API密钥。
+// 所有记录在同一事务中写入，任何一步失败都不会留下部分数据。
+func (m *Manager) Onboard(ctx context.Context, req *Request) (*Result, error) {
+	if err := req.normalize(); err != nil {
+		return nil, err
+	}
+
+	password, passwordHash, err := hashPassword(req.Admin.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := m.checkAvailable(ctx, tx, req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tenant := newTenant(req.Tenant, now)
+	admin := &User{
+		ID:        uuid.New().String(),
+		TenantID:  tenant.ID,
+		Email:     req.Admin.Email,
+		Name:      req.Admin.Name,
+		IsActive:  true,
+		CreatedAt: now,
+	}
+	project := &auth.Project{
+		ID:          uuid.New().String(),
+		TenantID:    tenant.ID,
+		Name:        req.Project.Name,
+		Slug:        req.Project.Slug,
+		Description: req.Project.Description,
+		IsActive:    true,
+		Environment: "development",
+		OwnerID:     admin.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := insertTenant(ctx, tx, tenant); err != nil {
+		return nil, err
+	}
+	if err := insertUser(ctx, tx, admin, passwordHash); err != nil {
+		return nil, err
+	}
+
+	roles, err := insertRoles(ctx, tx, tenant.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	assignment, err := insertAssignment(ctx, tx, admin.ID, tenant.ID, RoleID(tenant.ID, adminRole), now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertProject(ctx, tx, project); err != nil {
+		return nil, err
+	}
+
+	apiKey, err := m.keys.CreateTx(ctx, tx, &keys.CreateKeyRequest{
+		Name:      tenant.Name + " admin key",
+		Type:      keys.KeyTypeUser,
+		TenantID:  &tenant.ID,
+		ProjectID: &project.ID,
+		UserID:    &admin.ID,
+		Metadata:  map[string]interface{}{"source": "onboarding"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrapConflict("failed to commit onboarding", err)
+	}
+
+	// RBAC只保存在内存中，提交后再注册，事务失败时不会留下角色
+	m.register(roles, []*auth.UserRole{assignment})
+
+	m.logger.Info("tenant onboarded",
+		zap.String("tenant_id", tenant.ID),
+		zap.String("slug", tenant.Slug),
+		zap.String("admin_id", admin.ID),
+		zap.String("project_id", project.ID),
+	)
+
+	result := &Result{
+		Tenant:  tenant,
+		Admin:   admin,
+		Project: project,
+		Roles:   roles,
+		APIKey:  apiKey,
+		Credentials: Credentials{
+			Email:      admin.Email,
+			TenantSlug: tenant.Slug,
+			APIKey:     apiKey.Key,
+		},
+	}
+	if req.Admin.Password == "" {
+		result.Credentials.InitialPassword = password
+	}
+	return result, nil
+}
+
+// checkAvailable 确认租户标识和管理员邮箱未被占用
+func (m *Manager) checkAvailable(ctx context.Context, tx *sql.Tx, req *Request) error {
+	var count int
+	// 改名前的旧标识仍跳转到原租户，同样视为占用
+	err := tx.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM tenants WHERE slug = ?) +
+		(SELECT COUNT(*) FROM slug_redirects WHERE kind = ? AND scope = '' AND slug = ?)`,
+		req.Tenant.Slug, auth.SlugKindTenant, req.Tenant.Slug).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to check tenant slug: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: tenant slug %q is taken", ErrConflict, req.Tenant.Slug)
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE email = ?`, req.Admin.Email).Scan(&count); err != nil {
+		return fmt.Errorf("failed to check admin email: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: email %q is already registered", ErrConflict, req.Admin.Email)
+	}
+	return nil
+}
+
+// register 将角色和角色分配注册到RBAC
+func (m *Manager) register(roles []*auth.Role, assignments []*auth.UserRole) {
+	if m.rbac == nil {
+		return
+	}
+	for _, role := range roles {
+		if err := m.rbac.RegisterRole(role); err != nil {
+			m.logger.Warn("failed to register tenant role", zap.String("role_id", role.ID), zap.Error(err))
+		}
+	}
+	for _, assignment := range assignments {
+		if err := m.rbac.AssignRole(assignment); err != nil {
+			m.logger.Warn("failed to assign tenant role", zap.String("role_id", assignment.RoleID), zap.Error(err))
+		}
+	}
+}
+
+// newTenant 以默认设置和限额创建租户，与租户管理接口一致
+func newTenant(input TenantInput, now time.Time) *auth.Tenant {
+	tenant := &auth.Tenant{
+		ID:        uuid.New().String(),
+		Name:      input.Name,
+		Slug:      input.Slug,
+		Domain:    input.Domain,
+		Region:    input.Region,
+		IsActive:  true,
+		Plan:      input.Plan,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	tenant.Settings.SessionTimeout = 1440 // 24 hours
+	tenant.Limits = auth.TenantLimits{
+		MaxUsers:       10,
+		MaxProjects:    5,
+		MaxStorage:     1024, // 1GB
+		MaxAPIRequests: 10000,
+	}
+	return tenant
+}
+
+func insertTenant(ctx context.Context, tx *sql.Tx, tenant *auth.Tenant) error {
+	settingsJSON, _ := json.Marshal(tenant.Settings)
+	metadataJSON, _ := json.Marshal(tenant.Metadata)
+	limitsJSON, _ := json.Marshal(tenant.Limits)
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO tenants (id, name, slug, domain, region, settings, metadata, is_active, plan, limits, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tenant.ID, tenant.Name, tenant.Slug, tenant.Domain, tenant.Region, string(settingsJSON), string(metadataJSON),
+		tenant.IsActive, tenant.Plan, string(limitsJSON), tenant.CreatedAt, tenant.UpdatedAt)
+	if err != nil {
+		return wrapConflict("failed to create tenant", err)
+	}
+	return nil
+}
+
+// insertRoles 保存租户默认角色
+func insertRoles(ctx context.Context, tx *sql.Tx, tenantID string, now time.Time) ([]*auth.Role, error) {
+	roles := make([]*auth.Role, 0, len(defaultRoles))
+	for _, def := range defaultRoles {
+		role := &auth.Role{
+			ID:          RoleID(tenantID, def.name),
+			Name:        def.name,
+			Description: def.description,
+			Permissions: def.permissions,
+			TenantID:    tenantID,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		permissionsJSON, _ := json.Marshal(role.Permissions)
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO tenant_roles (id, tenant_id, name, description, permissions, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			role.ID, tenantID, role.Name, role.Description, string(permissionsJSON), now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create role %s: %w", role.Name, err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// insertAssignment 保存用户的角色分配
+func insertAssignment(ctx context.Context, tx *sql.Tx, userID, tenantID, roleID string, now time.Time) (*auth.UserRole, error) {
+	assignment := &auth.UserRole{
+		UserID:    userID,
+		RoleID:    roleID,
+		TenantID:  tenantID,
+		CreatedAt: now,
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO tenant_user_roles (user_id, tenant_id, role_id, created_at) VALUES (?, ?, ?, ?)`,
+		assignment.UserID, assignment.TenantID, assignment.RoleID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign role %s: %w", roleID, err)
+	}
+	return assignment, nil
+}
+
+// insertProject 保存默认项目，所有者作为创建者加入
+func insertProject(ctx context.Context, tx *sql.Tx, project *auth.Project) error {
+	settingsJSON, _ := json.Marshal(project.Settings)
+	metadataJSON, _ := json.Marshal(project.Metadata)
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO projects (id, tenant_id, name, slug, description, settings, metadata,
+			is_active, is_public, environment, owner_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		project.ID, project.TenantID, project.Name, project.Slug, project.Description,
+		string(settingsJSON), string(metadataJSON), project.IsActive, project.IsPublic,
+		project.Environment, project.OwnerID, project.CreatedAt, project.UpdatedAt)
+	if err != nil {
+		return wrapConflict("failed to create project", err)
+	}
+	return insertOwner(ctx, tx, project.OwnerID, project.TenantID, project.ID, project.CreatedAt)
+}
+
+func insertUser(ctx context.Context, tx *sql.Tx, user *User, passwordHash []byte) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO users (id, tenant_id, email, name, password_hash, is_active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, user.TenantID, user.Email, user.Name, string(passwordHash), user.IsActive, user.CreatedAt, user.CreatedAt)
+	if err != nil {
+		return wrapConflict("failed to create admin user", err)
+	}
+	return nil
+}
+
+// insertOwner 将用户作为创建者和所有者加入项目
+func insertOwner(ctx context.Context, tx *sql.Tx, userID, tenantID, projectID string, now time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO user_projects (id, user_id, tenant_id, project_id, role, is_active, is_creator,
+			invited_at, joined_at, can_invite, can_manage_members)
+		VALUES (?, ?, ?, ?, ?, 1, 1, ?, ?, 1, 1)`,
+		uuid.New().String(), userID, tenantID, projectID, auth.ProjectRoleOwner, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to add project owner: %w", err)
+	}
+	return nil
+}
+
+// wrapConflict 将并发开通时的唯一约束冲突转换为 ErrConflict
+func wrapConflict(message string, err error) error {
+	if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return fmt.Errorf("%w: %s: %v", ErrConflict, message, err)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+// hashPassword 哈希密码；密码为空时生成随机初始密码，返回明文和哈希
+func hashPassword(password string) (string, []byte, error) {
+	if password == "" {
+		buf := make([]byte, 18)
+		if _, err := rand.Read(buf); err != nil {
+			return "", nil, fmt.Errorf("failed to generate password: %w", err)
+		}
+		password = base64.RawURLEncoding.EncodeToString(buf)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return password, hash, nil
+}
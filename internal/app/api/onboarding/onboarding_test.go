@@ -0,0 +1,222 @@
+package onboarding
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/app/api/keys"
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+func newTestManager(t *testing.T) (*Manager, *sql.DB, *auth.RBACManager) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "onboarding.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := auth.NewMigrationRunner(db).RunMigrations(ctx); err != nil {
+		t.Fatal(err)
+	}
+	keysManager := keys.NewManager(db, zap.NewNop())
+	if err := keysManager.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rbac := auth.NewRBACManager()
+	if err := rbac.InitializeDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(db, keysManager, rbac, zap.NewNop())
+	if err := manager.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return manager, db, rbac
+}
+
+func count(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestOnboard(t *testing.T) {
+	ctx := context.Background()
+	manager, db, rbac := newTestManager(t)
+
+	result, err := manager.Onboard(ctx, &Request{
+		Tenant: TenantInput{Name: "Acme", Slug: "acme", Region: "eu-west-1"},
+		Admin:  AdminInput{Email: "admin@acme.test"},
+	})
+	if err != nil {
+		t.Fatalf("onboard failed: %v", err)
+	}
+
+	if result.Tenant.ID == "" || result.Tenant.Plan != auth.PlanFree {
+		t.Fatalf("unexpected tenant: %+v", result.Tenant)
+	}
+	if result.Project.Slug != defaultProjectSlug || result.Project.OwnerID != result.Admin.ID {
+		t.Fatalf("unexpected project: %+v", result.Project)
+	}
+	if len(result.Roles) != len(defaultRoles) {
+		t.Fatalf("expected %d roles, got %d", len(defaultRoles), len(result.Roles))
+	}
+	if len(result.Credentials.InitialPassword) < minPasswordLength {
+		t.Fatalf("expected a generated initial password, got %q", result.Credentials.InitialPassword)
+	}
+
+	if n := count(t, db, `SELECT COUNT(*) FROM tenants WHERE id = ? AND region = ?`, result.Tenant.ID, "eu-west-1"); n != 1 {
+		t.Fatal("expected the tenant region to be stored")
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM user_projects WHERE user_id = ? AND project_id = ? AND role = ?`,
+		result.Admin.ID, result.Project.ID, auth.ProjectRoleOwner); n != 1 {
+		t.Fatalf("expected admin to own the default project, got %d memberships", n)
+	}
+	key, err := manager.keys.Validate(ctx, result.Credentials.APIKey)
+	if err != nil {
+		t.Fatalf("expected the returned API key to validate: %v", err)
+	}
+	if key.TenantID == nil || *key.TenantID != result.Tenant.ID {
+		t.Fatalf("expected key scoped to tenant %s, got %v", result.Tenant.ID, key.TenantID)
+	}
+
+	ok, err := rbac.HasPermission(result.Admin.ID, result.Tenant.ID, "tenant:*", "manage")
+	if err != nil || !ok {
+		t.Fatalf("expected admin to hold tenant.admin, got %v %v", ok, err)
+	}
+
+	// Roles survive a restart
+	restarted := auth.NewRBACManager()
+	restarted.InitializeDefaults()
+	manager.rbac = restarted
+	if err := manager.LoadRoles(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if roles, _ := restarted.GetUserRoles(result.Admin.ID, result.Tenant.ID); len(roles) != 1 || roles[0].ID != RoleID(result.Tenant.ID, adminRole) {
+		t.Fatalf("expected admin role to be restored, got %v", roles)
+	}
+}
+
+func TestOnboardConflict(t *testing.T) {
+	ctx := context.Background()
+	manager, db, _ := newTestManager(t)
+	seeded := count(t, db, `SELECT COUNT(*) FROM tenants`)
+
+	_, err := manager.Onboard(ctx, &Request{
+		Tenant: TenantInput{Name: "Acme", Slug: "acme"},
+		Admin:  AdminInput{Email: "admin@acme.test", Password: "correct-horse"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := manager.Onboard(ctx, &Request{
+		Tenant: TenantInput{Name: "Acme", Slug: "acme-2"},
+		Admin:  AdminInput{Email: "other@acme.test", Password: "correct-horse"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Credentials.InitialPassword != "" {
+		t.Fatal("expected no initial password when one is given")
+	}
+
+	_, err = manager.Onboard(ctx, &Request{
+		Tenant: TenantInput{Name: "Acme again", Slug: "acme"},
+		Admin:  AdminInput{Email: "new@acme.test"},
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected slug conflict, got %v", err)
+	}
+	_, err = manager.Onboard(ctx, &Request{
+		Tenant: TenantInput{Name: "Globex", Slug: "globex"},
+		Admin:  AdminInput{Email: "admin@acme.test"},
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected email conflict, got %v", err)
+	}
+
+	_, err = manager.Onboard(ctx, &Request{Tenant: TenantInput{Name: "Bad", Slug: "Bad Slug"}, Admin: AdminInput{Email: "a@b.test"}})
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected invalid slug, got %v", err)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM tenants`); n != seeded+2 {
+		t.Fatalf("expected rejected requests to create nothing, got %d tenants", n)
+	}
+}
+
+func TestOnboardRollsBack(t *testing.T) {
+	ctx := context.Background()
+	manager, db, rbac := newTestManager(t)
+	seeded := map[string]int{}
+	tables := []string{"tenants", "users", "tenant_roles", "tenant_user_roles", "projects", "user_projects"}
+	for _, table := range tables {
+		seeded[table] = count(t, db, `SELECT COUNT(*) FROM `+table)
+	}
+
+	// Fail the last step, creating the API key
+	if _, err := db.Exec(`DROP TABLE api_keys`); err != nil {
+		t.Fatal(err)
+	}
+	_, err := manager.Onboard(ctx, &Request{
+		Tenant: TenantInput{Name: "Acme", Slug: "acme"},
+		Admin:  AdminInput{Email: "admin@acme.test"},
+	})
+	if err == nil {
+		t.Fatal("expected onboarding to fail")
+	}
+
+	for _, table := range tables {
+		if n := count(t, db, `SELECT COUNT(*) FROM `+table); n != seeded[table] {
+			t.Fatalf("expected %s to be rolled back, got %d rows, had %d", table, n, seeded[table])
+		}
+	}
+	roles, _ := rbac.ListRoles("")
+	for _, role := range roles {
+		if role.TenantID != "system" {
+			t.Fatalf("expected no tenant roles to be registered, got %s", role.ID)
+		}
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	ctx := context.Background()
+	manager, db, rbac := newTestManager(t)
+
+	result, err := manager.Bootstrap(ctx, AdminInput{Email: "root@localhost.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Created || result.InitialPassword == "" || result.Admin.TenantID != auth.SystemTenantID {
+		t.Fatalf("unexpected bootstrap result: %+v", result)
+	}
+	ok, err := rbac.HasPermission(result.Admin.ID, auth.SystemTenantID, "*", "manage")
+	if err != nil || !ok {
+		t.Fatalf("expected the admin to hold system.admin, got %v %v", ok, err)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM user_projects WHERE user_id = ? AND project_id = ?`,
+		result.Admin.ID, auth.SystemProjectID); n != 1 {
+		t.Fatalf("expected the admin to own the system project, got %d memberships", n)
+	}
+
+	// Running it again keeps the existing account
+	again, err := manager.Bootstrap(ctx, AdminInput{Email: "root@localhost.test", Password: "another-password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Created || again.InitialPassword != "" || again.Admin.ID != result.Admin.ID {
+		t.Fatalf("expected bootstrap to be idempotent, got %+v", again)
+	}
+	if n := count(t, db, `SELECT COUNT(*) FROM users`); n != 1 {
+		t.Fatalf("expected one user, got %d", n)
+	}
+}
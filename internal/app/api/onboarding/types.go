@@ -0,0 +1,164 @@
+package onboarding
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/guileen/metabase/internal/app/api/keys"
+	apperrors "github.com/guileen/metabase/pkg/common/errors"
+	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+var (
+	// ErrConflict 租户标识或管理员邮箱已被占用
+	ErrConflict = errors.New("onboarding conflict")
+	// ErrInvalid 请求参数不合法
+	ErrInvalid = errors.New("invalid onboarding request")
+)
+
+// minPasswordLength 管理员密码最小长度
+const minPasswordLength = 8
+
+// 默认项目名称和标识
+const (
+	defaultProjectName = "Default"
+	defaultProjectSlug = "default"
+)
+
+// TenantInput 新租户信息
+type TenantInput struct {
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	Domain string `json:"domain,omitempty"`
+	Plan   string `json:"plan,omitempty"`   // 默认 free
+	Region string `json:"region,omitempty"` // 数据驻留区域，为空时不限制
+}
+
+// AdminInput 租户首个管理员；密码为空时生成随机初始密码
+type AdminInput struct {
+	Email    string `json:"email"`
+	Name     string `json:"name,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// normalize 填充默认名称并校验邮箱和密码
+func (a *AdminInput) normalize() error {
+	if _, err := mail.ParseAddress(a.Email); err != nil {
+		return fmt.Errorf("%w: admin email is invalid", ErrInvalid)
+	}
+	if a.Password != "" && len(a.Password) < minPasswordLength {
+		return fmt.Errorf("%w: admin password must be at least %d characters", ErrInvalid, minPasswordLength)
+	}
+	if a.Name == "" {
+		a.Name = a.Email
+	}
+	return nil
+}
+
+// ProjectInput 默认项目，为空时创建 Default/default
+type ProjectInput struct {
+	Name        string `json:"name,omitempty"`
+	Slug        string `json:"slug,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Request 租户开通请求
+type Request struct {
+	Tenant  TenantInput  `json:"tenant"`
+	Admin   AdminInput   `json:"admin"`
+	Project ProjectInput `json:"project"`
+}
+
+// normalize 填充默认值并校验请求
+func (r *Request) normalize() error {
+	if r.Tenant.Name == "" {
+		return fmt.Errorf("%w: tenant name is required", ErrInvalid)
+	}
+	// 未指定标识时由名称生成
+	if r.Tenant.Slug == "" {
+		r.Tenant.Slug = auth.NormalizeSlug(r.Tenant.Name)
+	}
+	if err := auth.ValidateSlug(r.Tenant.Slug); err != nil {
+		return fmt.Errorf("%w: tenant %s", ErrInvalid, slugMessage(err))
+	}
+	if r.Tenant.Region != "" && !core.ValidRegion(r.Tenant.Region) {
+		return fmt.Errorf("%w: tenant region is invalid", ErrInvalid)
+	}
+	if r.Tenant.Plan == "" {
+		r.Tenant.Plan = auth.PlanFree
+	}
+	if err := r.Admin.normalize(); err != nil {
+		return err
+	}
+	if r.Project.Name == "" {
+		r.Project.Name = defaultProjectName
+	}
+	if r.Project.Slug == "" {
+		r.Project.Slug = defaultProjectSlug
+	}
+	if err := auth.ValidateSlug(r.Project.Slug); err != nil {
+		return fmt.Errorf("%w: project %s", ErrInvalid, slugMessage(err))
+	}
+	return nil
+}
+
+// slugMessage 返回标识校验错误的说明
+func slugMessage(err error) string {
+	if appErr, ok := apperrors.IsAppError(err); ok {
+		return appErr.Message
+	}
+	return err.Error()
+}
+
+// User 租户用户，不含密码哈希
+type User struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Credentials 首次登录所需信息，密码和密钥只在开通时返回一次
+type Credentials struct {
+	Email           string `json:"email"`
+	TenantSlug      string `json:"tenant_slug"`
+	InitialPassword string `json:"initial_password,omitempty"` // 仅在自动生成密码时返回
+	APIKey          string `json:"api_key"`
+}
+
+// Result 租户开通结果
+type Result struct {
+	Tenant      *auth.Tenant  `json:"tenant"`
+	Admin       *User         `json:"admin"`
+	Project     *auth.Project `json:"project"`
+	Roles       []*auth.Role  `json:"roles"`
+	APIKey      *keys.APIKey  `json:"api_key"`
+	Credentials Credentials   `json:"credentials"`
+}
+
+// defaultRole 每个新租户都会创建的角色
+type defaultRole struct {
+	name        string
+	description string
+	permissions []string
+}
+
+// defaultRoles 新租户的默认角色，管理员获得 admin 角色
+var defaultRoles = []defaultRole{
+	{"admin", "Tenant administrator", []string{"tenant.admin", "project.admin", "user.manage", "data.read", "data.write", "data.delete"}},
+	{"member", "Tenant member", []string{"data.read", "data.write"}},
+	{"viewer", "Read-only tenant member", []string{"data.read"}},
+}
+
+// adminRole 分配给首个管理员的默认角色
+const adminRole = "admin"
+
+// RoleID 返回租户角色在RBAC中的ID
+func RoleID(tenantID, name string) string {
+	return "tenant:" + tenantID + ":" + name
+}
@@ -0,0 +1,232 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/i18n"
+)
+
+// UserResolver 返回请求的当前用户，未认证时返回空
+type UserResolver func(r *http.Request) string
+
+// ProjectAccess 返回 projectIDs 中用户可以查看的项目
+type ProjectAccess func(ctx context.Context, userID string, projectIDs []string) ([]string, error)
+
+// Handler 用户资料的HTTP处理器
+type Handler struct {
+	manager  *Manager
+	users    UserResolver
+	projects ProjectAccess
+	logger   *zap.Logger
+}
+
+// NewHandler 创建用户资料处理器
+func NewHandler(manager *Manager, users UserResolver, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		users:   users,
+		logger:  logger,
+	}
+}
+
+// SetProjectAccess 设置默认项目的访问校验，未设置时不校验
+func (h *Handler) SetProjectAccess(access ProjectAccess) {
+	h.projects = access
+}
+
+// RegisterRoutes 注册当前用户的资料路由（挂载于 /admin/v1/profile）
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.handleGet)
+	r.Put("/", h.handleUpdate)
+	r.Get("/avatar", h.handleGetAvatar)
+	r.Put("/avatar", h.handleSetAvatar)
+	r.Delete("/avatar", h.handleDeleteAvatar)
+}
+
+// user 返回当前用户，未认证时输出 401
+func (h *Handler) user(w http.ResponseWriter, r *http.Request) string {
+	userID := h.users(r)
+	if userID == "" {
+		h.error(w, r, http.StatusUnauthorized, "User not authenticated", errors.New("no user in request"), "unauthorized")
+	}
+	return userID
+}
+
+// handleGet 获取当前用户的资料
+func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	userID := h.user(w, r)
+	if userID == "" {
+		return
+	}
+	profile, err := h.manager.Get(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to get profile", zap.String("user_id", userID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get profile", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": profile})
+}
+
+// handleUpdate 更新显示名称、语言、时区、通知偏好和默认项目
+func (h *Handler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	userID := h.user(w, r)
+	if userID == "" {
+		return
+	}
+	var update Update
+	if err := render.DecodeJSON(r.Body, &update); err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+		return
+	}
+	if update.DefaultProjectID != nil && *update.DefaultProjectID != "" && h.projects != nil {
+		viewable, err := h.projects(r.Context(), userID, []string{*update.DefaultProjectID})
+		if err != nil {
+			h.logger.Error("failed to check project access", zap.String("user_id", userID), zap.Error(err))
+			h.error(w, r, http.StatusInternalServerError, "Failed to check project access", err, "")
+			return
+		}
+		if len(viewable) == 0 {
+			h.error(w, r, http.StatusBadRequest, "Invalid default project",
+				fmt.Errorf("project %s is not accessible", *update.DefaultProjectID), "invalid_request")
+			return
+		}
+	}
+
+	profile, err := h.manager.Update(r.Context(), userID, update)
+	if errors.Is(err, ErrInvalid) {
+		h.error(w, r, http.StatusBadRequest, "Invalid profile", err, "invalid_request")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to update profile", zap.String("user_id", userID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to update profile", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": profile})
+}
+
+// handleGetAvatar 返回当前用户的头像图片
+func (h *Handler) handleGetAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := h.user(w, r)
+	if userID == "" {
+		return
+	}
+	avatar, err := h.manager.GetAvatar(r.Context(), userID)
+	if errors.Is(err, ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Avatar not found", err, "")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get avatar", zap.String("user_id", userID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get avatar", err, "")
+		return
+	}
+	w.Header().Set("Content-Type", avatar.ContentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	http.ServeContent(w, r, "", avatar.UpdatedAt, bytes.NewReader(avatar.Data))
+}
+
+// handleSetAvatar 上传头像，支持 multipart 的 avatar 字段或直接以请求体上传图片
+func (h *Handler) handleSetAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := h.user(w, r)
+	if userID == "" {
+		return
+	}
+	// multipart 表单头部需要额外空间
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarSize+64<<10)
+
+	var data []byte
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, ferr := r.FormFile("avatar")
+		if ferr != nil {
+			h.error(w, r, http.StatusBadRequest, "Invalid avatar upload", ferr, "invalid_request")
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	} else {
+		data, err = io.ReadAll(r.Body)
+	}
+	if err != nil {
+		h.error(w, r, http.StatusBadRequest, "Invalid avatar upload", err, "invalid_request")
+		return
+	}
+
+	avatar, err := h.manager.SetAvatar(r.Context(), userID, data)
+	if errors.Is(err, ErrInvalid) {
+		h.error(w, r, http.StatusBadRequest, "Invalid avatar", err, "invalid_request")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to save avatar", zap.String("user_id", userID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to save avatar", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": map[string]interface{}{
+		"content_type": avatar.ContentType,
+		"size":         len(avatar.Data),
+		"updated_at":   avatar.UpdatedAt,
+	}})
+}
+
+// handleDeleteAvatar 删除当前用户的头像
+func (h *Handler) handleDeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := h.user(w, r)
+	if userID == "" {
+		return
+	}
+	if err := h.manager.DeleteAvatar(r.Context(), userID); err != nil {
+		h.logger.Error("failed to delete avatar", zap.String("user_id", userID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to delete avatar", err, "")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Middleware 将当前用户的资料注入请求上下文，并按用户语言设置 Content-Language，
+// 供处理器生成本地化响应；读取失败时不注入
+func (m *Manager) Middleware(users UserResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := users(r)
+			if userID == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			profile, err := m.Cached(r.Context(), userID)
+			if err != nil {
+				m.logger.Warn("failed to load profile", zap.String("user_id", userID), zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if profile.Locale != "" {
+				w.Header().Set("Content-Language", profile.Locale)
+			}
+			next.ServeHTTP(w, r.WithContext(WithProfile(r.Context(), profile)))
+		})
+	}
+}
+
+// error 输出错误响应，message 按请求的语言翻译
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   i18n.T(r.Context(), message, nil),
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
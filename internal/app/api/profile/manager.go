@@ -0,0 +1,221 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/infra/kv"
+	"go.uber.org/zap"
+)
+
+// Manager 用户资料和头像的存储。资料带短时缓存，供每个请求注入上下文
+type Manager struct {
+	db     *sql.DB
+	logger *zap.Logger
+
+	mu    sync.RWMutex
+	cache kv.Store
+	ttl   time.Duration
+}
+
+// NewManager 创建用户资料存储，缓存默认在内存中
+func NewManager(db *sql.DB, logger *zap.Logger) *Manager {
+	return &Manager{
+		db:     db,
+		logger: logger,
+		cache:  kv.NewMemoryStore(10000),
+		ttl:    time.Minute,
+	}
+}
+
+// SetCacheStore 将资料缓存移到 store，多实例共享 store 时更新立即对所有实例生效
+func (m *Manager) SetCacheStore(store kv.Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = store
+}
+
+func (m *Manager) store() kv.Store {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cache
+}
+
+// Initialize 初始化数据库表
+func (m *Manager) Initialize(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS user_profiles (
+		user_id TEXT PRIMARY KEY,
+		display_name TEXT NOT NULL DEFAULT '',
+		locale TEXT NOT NULL DEFAULT '',
+		timezone TEXT NOT NULL DEFAULT '',
+		notifications TEXT NOT NULL DEFAULT '{}',
+		default_project_id TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS user_avatars (
+		user_id TEXT PRIMARY KEY,
+		content_type TEXT NOT NULL,
+		data BLOB NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	`)
+	if err != nil {
+		m.logger.Error("failed to initialize profile tables", zap.Error(err))
+		return fmt.Errorf("failed to initialize profile tables: %w", err)
+	}
+	return nil
+}
+
+// Get 返回用户资料，未保存过资料的用户返回默认值
+func (m *Manager) Get(ctx context.Context, userID string) (*Profile, error) {
+	profile := &Profile{UserID: userID, Notifications: defaultNotifications}
+	var notifications string
+	var updatedAt time.Time
+	err := m.db.QueryRowContext(ctx, `
+		SELECT display_name, locale, timezone, notifications, default_project_id, updated_at,
+			EXISTS (SELECT 1 FROM user_avatars WHERE user_id = p.user_id)
+		FROM user_profiles p WHERE user_id = ?`, userID,
+	).Scan(&profile.DisplayName, &profile.Locale, &profile.Timezone, &notifications,
+		&profile.DefaultProjectID, &updatedAt, &profile.HasAvatar)
+	if err == sql.ErrNoRows {
+		err = m.db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM user_avatars WHERE user_id = ?)`, userID).Scan(&profile.HasAvatar)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get avatar: %w", err)
+		}
+		return profile, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile: %w", err)
+	}
+	if err := json.Unmarshal([]byte(notifications), &profile.Notifications); err != nil {
+		return nil, fmt.Errorf("failed to decode notification preferences: %w", err)
+	}
+	profile.UpdatedAt = &updatedAt
+	return profile, nil
+}
+
+// Cached 返回缓存的用户资料，缓存缺失时从数据库读取
+func (m *Manager) Cached(ctx context.Context, userID string) (*Profile, error) {
+	store := m.store()
+	key := profileCacheKey(userID)
+	if data, err := store.Get(ctx, key); err == nil {
+		var profile Profile
+		if err := json.Unmarshal(data, &profile); err == nil {
+			return &profile, nil
+		}
+	}
+	profile, err := m.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(profile); err == nil {
+		store.Set(ctx, key, data, m.ttl)
+	}
+	return profile, nil
+}
+
+// Update 校验并保存资料更新，返回更新后的资料
+func (m *Manager) Update(ctx context.Context, userID string, update Update) (*Profile, error) {
+	if err := update.validate(); err != nil {
+		return nil, err
+	}
+	profile, err := m.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	update.apply(profile)
+
+	notifications, err := json.Marshal(profile.Notifications)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification preferences: %w", err)
+	}
+	now := time.Now()
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO user_profiles (user_id, display_name, locale, timezone, notifications, default_project_id, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			display_name = excluded.display_name,
+			locale = excluded.locale,
+			timezone = excluded.timezone,
+			notifications = excluded.notifications,
+			default_project_id = excluded.default_project_id,
+			updated_at = excluded.updated_at`,
+		userID, profile.DisplayName, profile.Locale, profile.Timezone, string(notifications), profile.DefaultProjectID, now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save profile: %w", err)
+	}
+	profile.UpdatedAt = &now
+	m.invalidate(ctx, userID)
+	return profile, nil
+}
+
+// SetAvatar 校验并保存头像，格式由内容判断
+func (m *Manager) SetAvatar(ctx context.Context, userID string, data []byte) (*Avatar, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: avatar is empty", ErrInvalid)
+	}
+	if len(data) > maxAvatarSize {
+		return nil, fmt.Errorf("%w: avatar must be at most %d bytes", ErrInvalid, maxAvatarSize)
+	}
+	contentType := http.DetectContentType(data)
+	if !avatarTypes[contentType] {
+		return nil, fmt.Errorf("%w: avatar must be a PNG, JPEG, GIF or WebP image, got %s", ErrInvalid, contentType)
+	}
+
+	avatar := &Avatar{ContentType: contentType, Data: data, UpdatedAt: time.Now()}
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO user_avatars (user_id, content_type, data, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			content_type = excluded.content_type, data = excluded.data, updated_at = excluded.updated_at`,
+		userID, avatar.ContentType, avatar.Data, avatar.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save avatar: %w", err)
+	}
+	m.invalidate(ctx, userID)
+	return avatar, nil
+}
+
+// GetAvatar 返回用户头像
+func (m *Manager) GetAvatar(ctx context.Context, userID string) (*Avatar, error) {
+	var avatar Avatar
+	err := m.db.QueryRowContext(ctx,
+		`SELECT content_type, data, updated_at FROM user_avatars WHERE user_id = ?`, userID,
+	).Scan(&avatar.ContentType, &avatar.Data, &avatar.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get avatar: %w", err)
+	}
+	return &avatar, nil
+}
+
+// DeleteAvatar 删除用户头像
+func (m *Manager) DeleteAvatar(ctx context.Context, userID string) error {
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM user_avatars WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete avatar: %w", err)
+	}
+	m.invalidate(ctx, userID)
+	return nil
+}
+
+// invalidate 清除用户资料缓存
+func (m *Manager) invalidate(ctx context.Context, userID string) {
+	if err := m.store().Delete(ctx, profileCacheKey(userID)); err != nil {
+		m.logger.Warn("failed to invalidate cached profile", zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+func profileCacheKey(userID string) string {
+	return "profile:" + userID
+}
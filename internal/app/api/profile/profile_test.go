@@ -0,0 +1,156 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+func newTestHandler(t *testing.T) (*Manager, http.Handler) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "profile.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	manager := NewManager(db, zap.NewNop())
+	if err := manager.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	users := func(r *http.Request) string { return r.Header.Get("X-User") }
+	handler := NewHandler(manager, users, zap.NewNop())
+	handler.SetProjectAccess(func(ctx context.Context, userID string, projectIDs []string) ([]string, error) {
+		var viewable []string
+		for _, projectID := range projectIDs {
+			if projectID == "p1" {
+				viewable = append(viewable, projectID)
+			}
+		}
+		return viewable, nil
+	})
+	r := chi.NewRouter()
+	r.Use(manager.Middleware(users))
+	r.Route("/profile", handler.RegisterRoutes)
+	r.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		p := FromContext(r.Context())
+		w.Write([]byte(p.DisplayName + "|" + Locale(r.Context()) + "|" + p.Location().String()))
+	})
+	return manager, r
+}
+
+func serve(handler http.Handler, method, path, user, contentType string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("X-User", user)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProfile(t *testing.T) {
+	_, handler := newTestHandler(t)
+	update := func(user string, body map[string]interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		return serve(handler, http.MethodPut, "/profile", user, "application/json", data)
+	}
+
+	rec := serve(handler, http.MethodGet, "/profile", "u1", "", nil)
+	var got struct{ Data Profile }
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != http.StatusOK || !got.Data.Notifications.Email || got.Data.HasAvatar {
+		t.Fatalf("expected default profile, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := serve(handler, http.MethodGet, "/profile", "", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous requests to be rejected, got %d", rec.Code)
+	}
+
+	// Warm the context cache, then check the update reaches it
+	serve(handler, http.MethodGet, "/whoami", "u1", "", nil)
+	rec = update("u1", map[string]interface{}{
+		"display_name":       "Ada",
+		"locale":             "zh-CN",
+		"timezone":           "Asia/Shanghai",
+		"notifications":      map[string]bool{"email": false, "alerts": true},
+		"default_project_id": "p1",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(handler, http.MethodGet, "/whoami", "u1", "", nil)
+	if rec.Body.String() != "Ada|zh-CN|Asia/Shanghai" || rec.Header().Get("Content-Language") != "zh-CN" {
+		t.Fatalf("expected the profile in context, got %q %v", rec.Body, rec.Header())
+	}
+
+	// Partial updates keep other fields
+	update("u1", map[string]interface{}{"display_name": "Ada L."})
+	rec = serve(handler, http.MethodGet, "/profile", "u1", "", nil)
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if got.Data.DisplayName != "Ada L." || got.Data.Locale != "zh-CN" || got.Data.Notifications.Email || got.Data.DefaultProjectID != "p1" {
+		t.Fatalf("unexpected profile after partial update %+v", got.Data)
+	}
+
+	for _, invalid := range []map[string]interface{}{
+		{"locale": "not a locale"},
+		{"timezone": "Mars/Olympus"},
+		{"default_project_id": "p2"},
+	} {
+		if rec := update("u1", invalid); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected %v to be rejected, got %d", invalid, rec.Code)
+		}
+	}
+}
+
+func TestAvatar(t *testing.T) {
+	_, handler := newTestHandler(t)
+	var img bytes.Buffer
+	png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	if rec := serve(handler, http.MethodGet, "/profile/avatar", "u1", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no avatar, got %d", rec.Code)
+	}
+	if rec := serve(handler, http.MethodPut, "/profile/avatar", "u1", "text/plain", []byte("not an image")); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected non-images to be rejected, got %d", rec.Code)
+	}
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, _ := writer.CreateFormFile("avatar", "me.png")
+	part.Write(img.Bytes())
+	writer.Close()
+	if rec := serve(handler, http.MethodPut, "/profile/avatar", "u1", writer.FormDataContentType(), form.Bytes()); rec.Code != http.StatusOK {
+		t.Fatalf("upload failed: %d %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(handler, http.MethodGet, "/profile/avatar", "u1", "", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || !bytes.Equal(rec.Body.Bytes(), img.Bytes()) {
+		t.Fatalf("unexpected avatar %d %v", rec.Code, rec.Header())
+	}
+	rec = serve(handler, http.MethodGet, "/profile", "u1", "", nil)
+	var got struct{ Data Profile }
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if !got.Data.HasAvatar {
+		t.Fatalf("expected the profile to report the avatar, got %s", rec.Body)
+	}
+
+	if rec := serve(handler, http.MethodDelete, "/profile/avatar", "u1", "", nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete failed: %d", rec.Code)
+	}
+	if rec := serve(handler, http.MethodGet, "/profile/avatar", "u1", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the avatar to be gone, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,144 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	// ErrInvalid 资料参数不合法
+	ErrInvalid = errors.New("invalid profile")
+	// ErrNotFound 用户未上传头像
+	ErrNotFound = errors.New("not found")
+)
+
+// maxAvatarSize 头像文件大小上限
+const maxAvatarSize = 1 << 20
+
+// avatarTypes 允许上传的头像格式
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// localePattern BCP 47 语言标签，如 en、zh-CN、pt-BR
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8}){0,2}$`)
+
+// maxDisplayName 显示名称最大长度
+const maxDisplayName = 100
+
+// NotificationPreferences 用户的通知偏好
+type NotificationPreferences struct {
+	Email          bool `json:"email"`           // 是否接收邮件通知
+	Alerts         bool `json:"alerts"`          // 是否接收告警通知
+	WeeklyReport   bool `json:"weekly_report"`   // 是否接收租户周报
+	ProductUpdates bool `json:"product_updates"` // 是否接收产品更新
+}
+
+// defaultNotifications 未设置时的通知偏好
+var defaultNotifications = NotificationPreferences{
+	Email:        true,
+	Alerts:       true,
+	WeeklyReport: true,
+}
+
+// Profile 用户资料和偏好
+type Profile struct {
+	UserID           string                  `json:"user_id"`
+	DisplayName      string                  `json:"display_name,omitempty"`
+	HasAvatar        bool                    `json:"has_avatar"`
+	Locale           string                  `json:"locale,omitempty"`
+	Timezone         string                  `json:"timezone,omitempty"`
+	Notifications    NotificationPreferences `json:"notifications"`
+	DefaultProjectID string                  `json:"default_project_id,omitempty"`
+	UpdatedAt        *time.Time              `json:"updated_at,omitempty"`
+}
+
+// Location 返回用户时区，未设置或无效时为 UTC
+func (p *Profile) Location() *time.Location {
+	if p == nil || p.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Update 资料更新请求，为 nil 的字段保持不变，空字符串清除该字段
+type Update struct {
+	DisplayName      *string                  `json:"display_name,omitempty"`
+	Locale           *string                  `json:"locale,omitempty"`
+	Timezone         *string                  `json:"timezone,omitempty"`
+	Notifications    *NotificationPreferences `json:"notifications,omitempty"`
+	DefaultProjectID *string                  `json:"default_project_id,omitempty"`
+}
+
+// validate 校验显示名称、语言和时区
+func (u *Update) validate() error {
+	if u.DisplayName != nil && len([]rune(*u.DisplayName)) > maxDisplayName {
+		return fmt.Errorf("%w: display_name must be at most %d characters", ErrInvalid, maxDisplayName)
+	}
+	if u.Locale != nil && *u.Locale != "" && !localePattern.MatchString(*u.Locale) {
+		return fmt.Errorf("%w: locale %q is not a language tag", ErrInvalid, *u.Locale)
+	}
+	if u.Timezone != nil && *u.Timezone != "" {
+		if _, err := time.LoadLocation(*u.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", ErrInvalid, *u.Timezone)
+		}
+	}
+	return nil
+}
+
+// apply 将更新合并到资料
+func (u *Update) apply(p *Profile) {
+	if u.DisplayName != nil {
+		p.DisplayName = *u.DisplayName
+	}
+	if u.Locale != nil {
+		p.Locale = *u.Locale
+	}
+	if u.Timezone != nil {
+		p.Timezone = *u.Timezone
+	}
+	if u.Notifications != nil {
+		p.Notifications = *u.Notifications
+	}
+	if u.DefaultProjectID != nil {
+		p.DefaultProjectID = *u.DefaultProjectID
+	}
+}
+
+// Avatar 头像图片
+type Avatar struct {
+	ContentType string
+	Data        []byte
+	UpdatedAt   time.Time
+}
+
+type profileKey struct{}
+
+// WithProfile 将用户资料写入上下文
+func WithProfile(ctx context.Context, p *Profile) context.Context {
+	return context.WithValue(ctx, profileKey{}, p)
+}
+
+// FromContext 返回上下文中的用户资料，没有时返回 nil
+func FromContext(ctx context.Context) *Profile {
+	p, _ := ctx.Value(profileKey{}).(*Profile)
+	return p
+}
+
+// Locale 返回上下文中用户的语言，未设置时返回空
+func Locale(ctx context.Context) string {
+	if p := FromContext(ctx); p != nil {
+		return p.Locale
+	}
+	return ""
+}
@@ -0,0 +1,332 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// 查询分析默认参数
+const (
+	defaultAnalyticsDays     = 7
+	maxAnalyticsDays         = 90
+	defaultAnalyticsLimit    = 10
+	defaultLowScoreThreshold = 0.3
+
+	// 强制重建索引时比较新旧索引的样本问题数
+	maxSwapSampleQueries = 10
+)
+
+// QueryStat 一个问题（忽略大小写和空白差异）的统计
+type QueryStat struct {
+	Query       string  `json:"query"`
+	Count       int64   `json:"count"`
+	AvgTopScore float64 `json:"avg_top_score"` // 每次查询最高检索得分的平均值
+}
+
+// QueryAnalytics 项目在统计区间内的查询分析
+type QueryAnalytics struct {
+	ProjectID    string    `json:"project_id"`
+	Since        time.Time `json:"since"`
+	Queries      int64     `json:"queries"`
+	ZeroResults  int64     `json:"zero_results"` // 没有检索到任何结果的查询数
+	LowScore     int64     `json:"low_score"`    // 最高得分低于阈值的查询数
+	CacheHits    int64     `json:"cache_hits"`
+	CacheHitRate float64   `json:"cache_hit_rate"`
+	LatencyP50Ms float64   `json:"latency_p50_ms"`
+	LatencyP95Ms float64   `json:"latency_p95_ms"`
+
+	TopQueries        []QueryStat `json:"top_queries,omitempty"`
+	ZeroResultQueries []QueryStat `json:"zero_result_queries,omitempty"` // 内容缺口：没有结果的问题
+	LowScoreQueries   []QueryStat `json:"low_score_queries,omitempty"`   // 内容缺口：结果相关度低的问题
+}
+
+// AnalyticsOptions 查询分析参数
+type AnalyticsOptions struct {
+	Since    time.Time
+	Limit    int     // 各问题列表的条数
+	MinScore float64 // 低于该最高得分的查询视为低分
+}
+
+// normalizeQuery 合并大小写和空白差异，用于按问题聚合
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// StoreQueryRecord 保存一次成功查询的记录，检索结果只保留数量、最高得分和耗时，并累计各分块的命中次数
+func (m *Manager) StoreQueryRecord(ctx context.Context, projectID, tenantID string, record core.QueryRecord) error {
+	if record.ID == "" {
+		record.ID = uuid.New().String()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	var results int
+	var topScore, latency float64
+	var cacheHit bool
+	if result := record.Result; result != nil {
+		results = len(result.RetrievalResults)
+		for _, r := range result.RetrievalResults {
+			topScore = math.Max(topScore, r.Score)
+		}
+		latency = float64(result.TotalTime) / float64(time.Millisecond)
+		cacheHit = result.CacheHit
+	}
+
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO rag_query_records (id, project_id, tenant_id, query, normalized_query, processed_query,
+			user_id, session_id, result_count, top_score, latency_ms, cache_hit, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.ID, projectID, tenantID, record.Query, normalizeQuery(record.Query), record.ProcessedQuery,
+		record.UserID, record.SessionID, results, topScore, latency, cacheHit, record.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store query record: %w", err)
+	}
+	if record.Result != nil {
+		return m.recordChunkHits(ctx, projectID, record.Result.RetrievalResults, record.CreatedAt)
+	}
+	return nil
+}
+
+// QueryAnalytics 计算项目自 opts.Since 以来的查询分析
+func (m *Manager) QueryAnalytics(ctx context.Context, projectID string, opts AnalyticsOptions) (*QueryAnalytics, error) {
+	analytics, err := m.querySummary(ctx, projectID, opts)
+	if err != nil {
+		return nil, err
+	}
+	if analytics.Queries == 0 {
+		return analytics, nil
+	}
+
+	since := opts.Since.UTC()
+	if analytics.TopQueries, err = m.queryStats(ctx, ``, opts.Limit, projectID, since); err != nil {
+		return nil, err
+	}
+	if analytics.ZeroResultQueries, err = m.queryStats(ctx, `AND result_count = 0`, opts.Limit, projectID, since); err != nil {
+		return nil, err
+	}
+	analytics.LowScoreQueries, err = m.queryStats(ctx, `AND result_count > 0 AND top_score < ?`, opts.Limit, projectID, since, opts.MinScore)
+	if err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
+
+// ListQueryAnalytics 列出统计区间内有查询的各项目概要（不含问题列表），按查询数降序
+func (m *Manager) ListQueryAnalytics(ctx context.Context, opts AnalyticsOptions) ([]QueryAnalytics, error) {
+	projects, err := m.queriedProjects(ctx, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]QueryAnalytics, 0, len(projects))
+	for _, projectID := range projects {
+		analytics, err := m.querySummary(ctx, projectID, opts)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *analytics)
+	}
+	return list, nil
+}
+
+// queriedProjects 列出 since 以来有查询的项目，按查询数降序
+func (m *Manager) queriedProjects(ctx context.Context, since time.Time) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT project_id FROM rag_query_records WHERE created_at >= ?
+		GROUP BY project_id ORDER BY COUNT(*) DESC`, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queried projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []string
+	for rows.Next() {
+		var projectID string
+		if err := rows.Scan(&projectID); err != nil {
+			return nil, fmt.Errorf("failed to scan queried projects: %w", err)
+		}
+		projects = append(projects, projectID)
+	}
+	return projects, rows.Err()
+}
+
+// querySummary 计算查询数、内容缺口数、缓存命中率和延迟分位数
+func (m *Manager) querySummary(ctx context.Context, projectID string, opts AnalyticsOptions) (*QueryAnalytics, error) {
+	since := opts.Since.UTC()
+	analytics := &QueryAnalytics{ProjectID: projectID, Since: since}
+
+	var zero, low, hits sql.NullInt64
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			SUM(CASE WHEN result_count = 0 THEN 1 ELSE 0 END),
+			SUM(CASE WHEN result_count > 0 AND top_score < ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN cache_hit THEN 1 ELSE 0 END)
+		FROM rag_query_records WHERE project_id = ? AND created_at >= ?`,
+		opts.MinScore, projectID, since,
+	).Scan(&analytics.Queries, &zero, &low, &hits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize queries: %w", err)
+	}
+	if analytics.Queries == 0 {
+		return analytics, nil
+	}
+	analytics.ZeroResults, analytics.LowScore, analytics.CacheHits = zero.Int64, low.Int64, hits.Int64
+	analytics.CacheHitRate = float64(analytics.CacheHits) / float64(analytics.Queries)
+
+	if analytics.LatencyP50Ms, err = m.latencyPercentile(ctx, projectID, since, analytics.Queries, 0.5); err != nil {
+		return nil, err
+	}
+	if analytics.LatencyP95Ms, err = m.latencyPercentile(ctx, projectID, since, analytics.Queries, 0.95); err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
+
+// latencyPercentile 按最近秩法取延迟分位数，count 为区间内的查询数
+func (m *Manager) latencyPercentile(ctx context.Context, projectID string, since time.Time, count int64, p float64) (float64, error) {
+	offset := int64(math.Ceil(p*float64(count))) - 1
+	if offset < 0 {
+		offset = 0
+	}
+	var latency float64
+	err := m.db.QueryRowContext(ctx, `
+		SELECT latency_ms FROM rag_query_records WHERE project_id = ? AND created_at >= ?
+		ORDER BY latency_ms LIMIT 1 OFFSET ?`,
+		projectID, since, offset,
+	).Scan(&latency)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to compute latency percentile: %w", err)
+	}
+	return latency, nil
+}
+
+// queryStats 按问题聚合，condition 为附加的筛选条件，其参数跟在 projectID、since 之后
+func (m *Manager) queryStats(ctx context.Context, condition string, limit int, args ...interface{}) ([]QueryStat, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT normalized_query, COUNT(*), AVG(top_score) FROM rag_query_records
+		WHERE project_id = ? AND created_at >= ? `+condition+`
+		GROUP BY normalized_query ORDER BY COUNT(*) DESC, normalized_query LIMIT ?`,
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate queries: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []QueryStat
+	for rows.Next() {
+		var stat QueryStat
+		if err := rows.Scan(&stat.Query, &stat.Count, &stat.AvgTopScore); err != nil {
+			return nil, fmt.Errorf("failed to scan query stats: %w", err)
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// swapSampleQueries 强制重建索引时用于比较新旧索引的样本问题：项目近期有检索结果的热门问题
+func (m *Manager) swapSampleQueries(ctx context.Context, projectID string) ([]string, error) {
+	since := time.Now().AddDate(0, 0, -defaultAnalyticsDays).UTC()
+	stats, err := m.queryStats(ctx, `AND result_count > 0`, maxSwapSampleQueries, projectID, since)
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]string, len(stats))
+	for i, stat := range stats {
+		queries[i] = stat.Query
+	}
+	return queries, nil
+}
+
+// recordQuery 保存查询记录，失败只记录日志，不影响查询
+func (h *Handler) recordQuery(ctx context.Context, question string, options core.QueryOptions, result *core.QueryResult) {
+	record := core.QueryRecord{
+		ID:             result.QueryID,
+		Query:          question,
+		ProcessedQuery: result.ProcessedQuery,
+		Result:         result,
+		UserID:         options.UserID,
+		SessionID:      options.SessionID,
+		DataSourceIDs:  options.DataSourceIDs,
+		CreatedAt:      time.Now(),
+	}
+	if result.CacheHit {
+		// 缓存的结果沿用首次查询的ID
+		record.ID = ""
+	}
+	if err := h.manager.StoreQueryRecord(ctx, options.ProjectID, options.TenantID, record); err != nil {
+		h.logger.Warn("failed to store query record", zap.String("project_id", options.ProjectID), zap.Error(err))
+	}
+}
+
+// analyticsOptions 解析 days、limit、min_score 查询参数
+func analyticsOptions(r *http.Request) AnalyticsOptions {
+	query := r.URL.Query()
+	days, _ := strconv.Atoi(query.Get("days"))
+	if days <= 0 || days > maxAnalyticsDays {
+		days = defaultAnalyticsDays
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = defaultAnalyticsLimit
+	}
+	minScore, err := strconv.ParseFloat(query.Get("min_score"), 64)
+	if err != nil || minScore < 0 {
+		minScore = defaultLowScoreThreshold
+	}
+	return AnalyticsOptions{
+		Since:    time.Now().AddDate(0, 0, -days),
+		Limit:    limit,
+		MinScore: minScore,
+	}
+}
+
+// handleQueryAnalytics 获取项目的查询分析
+func (h *Handler) handleQueryAnalytics(w http.ResponseWriter, r *http.Request) {
+	analytics, err := h.manager.QueryAnalytics(r.Context(), chi.URLParam(r, "projectId"), analyticsOptions(r))
+	if err != nil {
+		h.logger.Error("failed to get query analytics", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get query analytics",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": analytics,
+	})
+}
+
+// handleListQueryAnalytics 列出各项目的查询分析概要
+func (h *Handler) handleListQueryAnalytics(w http.ResponseWriter, r *http.Request) {
+	list, err := h.manager.ListQueryAnalytics(r.Context(), analyticsOptions(r))
+	if err != nil {
+		h.logger.Error("failed to list query analytics", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list query analytics",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": list,
+	})
+}
@@ -0,0 +1,67 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestQueryAnalytics(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+
+	store := func(projectID, query string, latency time.Duration, cacheHit bool, scores ...float64) {
+		result := &core.QueryResult{TotalTime: latency, CacheHit: cacheHit}
+		for _, score := range scores {
+			result.RetrievalResults = append(result.RetrievalResults, core.RetrievalResult{Score: score})
+		}
+		if err := h.manager.StoreQueryRecord(ctx, projectID, "t1", core.QueryRecord{Query: query, Result: result}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 20; i++ {
+		store("p1", "How do I deploy?", time.Duration(i)*10*time.Millisecond, i%4 == 0, 0.9, 0.4)
+	}
+	store("p1", "billing  EXPORT", 500*time.Millisecond, false)
+	store("p1", "Billing export", 300*time.Millisecond, false)
+	store("p1", "sso setup", 100*time.Millisecond, false, 0.1)
+	store("p2", "other project", 50*time.Millisecond, true, 0.8)
+
+	req := httptest.NewRequest(http.MethodGet, "/projects/p1/rag/analytics?limit=5", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("analytics failed: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data QueryAnalytics `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	analytics := resp.Data
+
+	if analytics.Queries != 23 || analytics.ZeroResults != 2 || analytics.LowScore != 1 || analytics.CacheHits != 5 {
+		t.Fatalf("unexpected summary %+v", analytics)
+	}
+	if analytics.LatencyP50Ms != 110 || analytics.LatencyP95Ms != 300 {
+		t.Fatalf("unexpected latency percentiles p50=%v p95=%v", analytics.LatencyP50Ms, analytics.LatencyP95Ms)
+	}
+	if len(analytics.TopQueries) != 3 || analytics.TopQueries[0].Query != "how do i deploy?" || analytics.TopQueries[0].AvgTopScore != 0.9 {
+		t.Fatalf("unexpected top queries %+v", analytics.TopQueries)
+	}
+	if len(analytics.ZeroResultQueries) != 1 || analytics.ZeroResultQueries[0] != (QueryStat{Query: "billing export", Count: 2}) {
+		t.Fatalf("unexpected zero-result queries %+v", analytics.ZeroResultQueries)
+	}
+	if len(analytics.LowScoreQueries) != 1 || analytics.LowScoreQueries[0].Query != "sso setup" {
+		t.Fatalf("unexpected low-score queries %+v", analytics.LowScoreQueries)
+	}
+
+	list, err := h.manager.ListQueryAnalytics(ctx, AnalyticsOptions{Since: time.Now().Add(-time.Hour), MinScore: defaultLowScoreThreshold})
+	if err != nil || len(list) != 2 || list[0].ProjectID != "p1" || list[1].CacheHitRate != 1 || list[1].TopQueries != nil {
+		t.Fatalf("unexpected project list %+v, %v", list, err)
+	}
+}
@@ -0,0 +1,140 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"go.uber.org/zap"
+)
+
+const (
+	// blobGCInterval 定期任务回收未被引用对象的间隔
+	blobGCInterval = 24 * time.Hour
+
+	// blobGCGrace 新写入的对象在此时间内不回收，避免回收记录尚未保存的对象
+	blobGCGrace = time.Hour
+)
+
+// blobGCPrefixes 由 rag_download_files 引用的对象键前缀
+var blobGCPrefixes = []string{"originals/", "exports/"}
+
+// BlobLimitsLoader 查找项目所属租户的文件大小和类型限制
+type BlobLimitsLoader func(ctx context.Context, projectID string) (blobstore.Limits, error)
+
+// BlobLimitsFromDB 从租户设置的 storage.max_file_size（字节）和 storage.allowed_types 读取文件限制
+func BlobLimitsFromDB(db *sql.DB) BlobLimitsLoader {
+	return func(ctx context.Context, projectID string) (blobstore.Limits, error) {
+		var raw sql.NullString
+		err := db.QueryRowContext(ctx, `
+			SELECT t.settings FROM projects p JOIN tenants t ON t.id = p.tenant_id
+			WHERE p.id = ? AND p.deleted_at IS NULL`,
+			projectID,
+		).Scan(&raw)
+		if err == sql.ErrNoRows || !raw.Valid || raw.String == "" {
+			return blobstore.Limits{}, nil
+		}
+		if err != nil {
+			return blobstore.Limits{}, err
+		}
+
+		var settings struct {
+			Storage *tenant.StorageSettings `json:"storage"`
+		}
+		if err := json.Unmarshal([]byte(raw.String), &settings); err != nil {
+			return blobstore.Limits{}, fmt.Errorf("invalid tenant settings: %w", err)
+		}
+		if settings.Storage == nil {
+			return blobstore.Limits{}, nil
+		}
+		return blobstore.Limits{
+			MaxSize:      settings.Storage.MaxFileSize,
+			AllowedTypes: settings.Storage.AllowedTypes,
+		}, nil
+	}
+}
+
+// SetBlobLimits 设置租户文件限制来源，未设置时不限制上传文件的大小和类型
+func (h *Handler) SetBlobLimits(loader BlobLimitsLoader) {
+	h.blobLimitsLoader = loader
+}
+
+// blobLimits 获取项目的文件限制
+func (h *Handler) blobLimits(ctx context.Context, projectID string) (blobstore.Limits, error) {
+	if h.blobLimitsLoader == nil {
+		return blobstore.Limits{}, nil
+	}
+	return h.blobLimitsLoader(ctx, projectID)
+}
+
+// downloadFileKeys 所有可下载文件的对象键
+func (m *Manager) downloadFileKeys(ctx context.Context) (map[string]bool, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT object_key FROM rag_download_files`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list download files: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan download file: %w", err)
+		}
+		keys[key] = true
+	}
+	return keys, rows.Err()
+}
+
+// pruneDownloadFiles 删除快照已被删除的导出归档记录，返回删除的记录数
+func (m *Manager) pruneDownloadFiles(ctx context.Context) (int64, error) {
+	result, err := m.db.ExecContext(ctx, `
+		DELETE FROM rag_download_files
+		WHERE kind = ? AND ref_id NOT IN (SELECT id FROM rag_index_snapshots)`,
+		downloadKindExport,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune download files: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// runBlobGC 删除对象存储中不再被引用的原始文档和导出归档
+func (s *SyncScheduler) runBlobGC(ctx context.Context, now time.Time) {
+	if s.handler.objects == nil || s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+	if now.Sub(s.lastBlobGCRun) < blobGCInterval {
+		return
+	}
+	s.lastBlobGCRun = now
+
+	if _, err := s.handler.manager.pruneDownloadFiles(ctx); err != nil {
+		s.logger.Error("failed to prune download files", zap.Error(err))
+		return
+	}
+	referenced, err := s.handler.manager.downloadFileKeys(ctx)
+	if err != nil {
+		s.logger.Error("failed to collect blob garbage", zap.Error(err))
+		return
+	}
+	for _, prefix := range blobGCPrefixes {
+		report, err := blobstore.CollectGarbage(ctx, s.handler.objects, prefix, blobGCGrace, func(key string) bool {
+			return referenced[key]
+		})
+		if err != nil {
+			s.logger.Error("failed to collect blob garbage", zap.String("prefix", prefix), zap.Error(err))
+			continue
+		}
+		if report.Deleted > 0 {
+			s.logger.Info("collected blob garbage",
+				zap.String("prefix", prefix),
+				zap.Int("deleted", report.Deleted),
+				zap.Int64("freed_bytes", report.FreedBytes))
+		}
+	}
+}
@@ -0,0 +1,434 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// 机器人平台
+const (
+	BotPlatformSlack   = "slack"
+	BotPlatformDiscord = "discord"
+)
+
+const (
+	defaultBotCitations = 3
+	maxBotCitations     = 10
+	botAnswerTimeout    = 2 * time.Minute
+)
+
+// errChannelBound 频道已绑定到其他项目
+var errChannelBound = errors.New("channel is bound to another project")
+
+// BotConfig 机器人凭据，一个部署对应一个 Slack 应用和一个 Discord 应用
+type BotConfig struct {
+	SlackBotToken        string `json:"-"`
+	SlackSigningSecret   string `json:"-"`
+	DiscordApplicationID string `json:"discord_application_id,omitempty"`
+	DiscordPublicKey     string `json:"discord_public_key,omitempty"`
+
+	// 平台 API 地址，默认为官方地址
+	SlackAPIURL   string `json:"slack_api_url,omitempty"`
+	DiscordAPIURL string `json:"discord_api_url,omitempty"`
+}
+
+// BotConfigFromEnv 从环境变量读取机器人凭据
+func BotConfigFromEnv() *BotConfig {
+	return &BotConfig{
+		SlackBotToken:        os.Getenv("METABASE_SLACK_BOT_TOKEN"),
+		SlackSigningSecret:   os.Getenv("METABASE_SLACK_SIGNING_SECRET"),
+		DiscordApplicationID: os.Getenv("METABASE_DISCORD_APPLICATION_ID"),
+		DiscordPublicKey:     os.Getenv("METABASE_DISCORD_PUBLIC_KEY"),
+	}
+}
+
+// BotChannel 聊天频道与项目的绑定，频道中的提问使用该项目的RAG索引回答
+type BotChannel struct {
+	Platform  string `json:"platform"`
+	ChannelID string `json:"channel_id"`
+	ProjectID string `json:"project_id"`
+	TenantID  string `json:"tenant_id,omitempty"`
+
+	// 过滤表达式，限定频道可检索的文档，如 tag:handbook
+	Filter string `json:"filter,omitempty"`
+
+	// 引用链接模板，支持 {uri}、{document_id}、{title}；为空时仅 http(s) 文档附带链接
+	CitationURL string `json:"citation_url,omitempty"`
+
+	// 每条回答附带的引用数，默认 3，最多 10
+	MaxCitations int  `json:"max_citations,omitempty"`
+	Enabled      bool `json:"enabled"`
+
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate 校验频道绑定
+func (c *BotChannel) Validate() error {
+	if c.Platform != BotPlatformSlack && c.Platform != BotPlatformDiscord {
+		return fmt.Errorf("unsupported platform %q (slack, discord)", c.Platform)
+	}
+	if strings.TrimSpace(c.ChannelID) == "" {
+		return fmt.Errorf("channel_id is required")
+	}
+	if c.MaxCitations < 0 || c.MaxCitations > maxBotCitations {
+		return fmt.Errorf("max_citations must be between 0 and %d", maxBotCitations)
+	}
+	if c.Filter != "" {
+		if _, err := core.ParseFilterExpression(c.Filter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	return nil
+}
+
+// botChannelRequest 频道绑定请求，未提供的字段保持不变
+type botChannelRequest struct {
+	Filter       *string `json:"filter"`
+	CitationURL  *string `json:"citation_url"`
+	MaxCitations *int    `json:"max_citations"`
+	Enabled      *bool   `json:"enabled"`
+}
+
+func (req *botChannelRequest) apply(c *BotChannel) {
+	if req.Filter != nil {
+		c.Filter = *req.Filter
+	}
+	if req.CitationURL != nil {
+		c.CitationURL = *req.CitationURL
+	}
+	if req.MaxCitations != nil {
+		c.MaxCitations = *req.MaxCitations
+	}
+	if req.Enabled != nil {
+		c.Enabled = *req.Enabled
+	}
+}
+
+// botCitation 回答中的引用
+type botCitation struct {
+	Title   string
+	URL     string
+	Excerpt string
+}
+
+// citations 把来源转换为引用，同一文档只引用一次
+func (c *BotChannel) citations(sources []core.Source) []botCitation {
+	limit := c.MaxCitations
+	if limit == 0 {
+		limit = defaultBotCitations
+	}
+
+	citations := []botCitation{}
+	seen := map[string]bool{}
+	for _, source := range sources {
+		if len(citations) >= limit {
+			break
+		}
+		if seen[source.DocumentID] {
+			continue
+		}
+		seen[source.DocumentID] = true
+
+		citation := botCitation{
+			Title:   source.DocumentTitle,
+			Excerpt: truncateText(source.Excerpt, 280),
+		}
+		if citation.Title == "" {
+			citation.Title = source.DocumentID
+		}
+		if c.CitationURL != "" {
+			citation.URL = strings.NewReplacer(
+				"{uri}", source.DocumentURI,
+				"{document_id}", url.PathEscape(source.DocumentID),
+				"{title}", url.PathEscape(source.DocumentTitle),
+			).Replace(c.CitationURL)
+		} else if source.DeepLink != "" {
+			citation.URL = source.DeepLink
+		} else if strings.HasPrefix(source.DocumentURI, "http://") || strings.HasPrefix(source.DocumentURI, "https://") {
+			citation.URL = source.DocumentURI
+		}
+		citations = append(citations, citation)
+	}
+	return citations
+}
+
+// SaveBotChannel 保存频道绑定，频道已绑定到其他项目时返回错误
+func (m *Manager) SaveBotChannel(ctx context.Context, channel *BotChannel) error {
+	existing, err := m.GetBotChannel(ctx, channel.Platform, channel.ChannelID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ProjectID != channel.ProjectID {
+		return errChannelBound
+	}
+
+	channel.UpdatedAt = time.Now()
+	if existing != nil {
+		channel.CreatedAt = existing.CreatedAt
+		channel.CreatedBy = existing.CreatedBy
+	} else {
+		channel.CreatedAt = channel.UpdatedAt
+	}
+	definition, err := json.Marshal(channel)
+	if err != nil {
+		return fmt.Errorf("failed to encode bot channel: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_bot_channels (platform, channel_id, project_id, definition, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (platform, channel_id) DO UPDATE SET
+			definition = excluded.definition,
+			updated_at = excluded.updated_at`,
+		channel.Platform, channel.ChannelID, channel.ProjectID, string(definition), channel.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save bot channel: %w", err)
+	}
+	return nil
+}
+
+// GetBotChannel 获取频道绑定，不存在时返回 nil
+func (m *Manager) GetBotChannel(ctx context.Context, platform, channelID string) (*BotChannel, error) {
+	var definition string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT definition FROM rag_bot_channels WHERE platform = ? AND channel_id = ?`,
+		platform, channelID,
+	).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bot channel: %w", err)
+	}
+
+	var channel BotChannel
+	if err := json.Unmarshal([]byte(definition), &channel); err != nil {
+		return nil, fmt.Errorf("failed to decode bot channel: %w", err)
+	}
+	return &channel, nil
+}
+
+// ListBotChannels 列出项目绑定的频道
+func (m *Manager) ListBotChannels(ctx context.Context, projectID string) ([]BotChannel, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT definition FROM rag_bot_channels WHERE project_id = ? ORDER BY platform, channel_id`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bot channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := []BotChannel{}
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to list bot channels: %w", err)
+		}
+		var channel BotChannel
+		if err := json.Unmarshal([]byte(definition), &channel); err != nil {
+			return nil, fmt.Errorf("failed to decode bot channel: %w", err)
+		}
+		channels = append(channels, channel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list bot channels: %w", err)
+	}
+	return channels, nil
+}
+
+// DeleteBotChannel 解除项目的频道绑定，返回是否存在该绑定
+func (m *Manager) DeleteBotChannel(ctx context.Context, projectID, platform, channelID string) (bool, error) {
+	result, err := m.db.ExecContext(ctx,
+		`DELETE FROM rag_bot_channels WHERE platform = ? AND channel_id = ? AND project_id = ?`,
+		platform, channelID, projectID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete bot channel: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// handleListBotChannels 列出项目绑定的聊天频道
+func (h *Handler) handleListBotChannels(w http.ResponseWriter, r *http.Request) {
+	channels, err := h.manager.ListBotChannels(r.Context(), chi.URLParam(r, "projectId"))
+	if err != nil {
+		h.logger.Error("failed to list bot channels", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list bot channels",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": channels,
+	})
+}
+
+// handlePutBotChannel 把聊天频道绑定到项目或更新绑定设置
+func (h *Handler) handlePutBotChannel(w http.ResponseWriter, r *http.Request) {
+	var req botChannelRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	platform := chi.URLParam(r, "platform")
+	channelID := chi.URLParam(r, "channelId")
+	channel, err := h.manager.GetBotChannel(r.Context(), platform, channelID)
+	if err != nil {
+		h.logger.Error("failed to get bot channel", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get bot channel",
+			"details": err.Error(),
+		})
+		return
+	}
+	if channel == nil || channel.ProjectID != projectID {
+		channel = &BotChannel{Platform: platform, ChannelID: channelID, ProjectID: projectID, Enabled: true}
+		if userID, ok := r.Context().Value("user_id").(string); ok {
+			channel.CreatedBy = userID
+		}
+	}
+	if tenantID, ok := r.Context().Value("tenant_id").(string); ok {
+		channel.TenantID = tenantID
+	}
+	req.apply(channel)
+
+	if err := channel.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid bot channel",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.manager.SaveBotChannel(r.Context(), channel); err != nil {
+		if errors.Is(err, errChannelBound) {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "Channel is bound to another project",
+				"code":  "channel_bound",
+			})
+			return
+		}
+		h.logger.Error("failed to save bot channel", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save bot channel",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("bot channel bound",
+		zap.String("project_id", projectID),
+		zap.String("platform", platform),
+		zap.String("channel_id", channelID),
+	)
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": channel,
+	})
+}
+
+// handleDeleteBotChannel 解除聊天频道与项目的绑定
+func (h *Handler) handleDeleteBotChannel(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.manager.DeleteBotChannel(r.Context(),
+		chi.URLParam(r, "projectId"), chi.URLParam(r, "platform"), chi.URLParam(r, "channelId"))
+	if err != nil {
+		h.logger.Error("failed to delete bot channel", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete bot channel",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Bot channel not found",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Bot channel deleted",
+	})
+}
+
+// answerInChannel 使用频道绑定项目的RAG索引回答问题
+func (h *Handler) answerInChannel(ctx context.Context, channel *BotChannel, question string) (*core.QueryResult, error) {
+	if h.pipeline == nil {
+		return nil, fmt.Errorf("RAG pipeline not configured")
+	}
+	options := core.QueryOptions{
+		ProjectID: channel.ProjectID,
+		TenantID:  channel.TenantID,
+		Filter:    channel.Filter,
+	}
+	return h.query(ctx, question, options)
+}
+
+// botChannelFor 查找已启用的频道绑定，未绑定或已停用时返回 nil
+func (h *Handler) botChannelFor(ctx context.Context, platform, channelID string) *BotChannel {
+	channel, err := h.manager.GetBotChannel(ctx, platform, channelID)
+	if err != nil {
+		h.logger.Error("failed to get bot channel", zap.String("platform", platform), zap.String("channel_id", channelID), zap.Error(err))
+		return nil
+	}
+	if channel == nil || !channel.Enabled {
+		return nil
+	}
+	return channel
+}
+
+// botAnswerText 回答正文，查询失败时给出简短说明
+func botAnswerText(result *core.QueryResult, err error) string {
+	switch {
+	case errors.Is(err, core.ErrBudgetExceeded):
+		return "Sorry, this project's question budget is used up for now."
+	case err != nil:
+		return "Sorry, I couldn't answer that right now."
+	case strings.TrimSpace(result.GeneratedResponse) == "":
+		return "I couldn't find an answer in this project's documents."
+	default:
+		return result.GeneratedResponse
+	}
+}
+
+// truncateText 截断文本到 limit 个字符
+func truncateText(text string, limit int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= limit {
+		return string(runes)
+	}
+	return string(runes[:limit-1]) + "…"
+}
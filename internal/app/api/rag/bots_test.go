@@ -0,0 +1,190 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/pkg/rag/core"
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+func newBotTestHandler(t *testing.T, bots *BotConfig) (*Handler, *chi.Mux) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "rag.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	manager := NewManager(db, nil, zap.NewNop())
+	if err := manager.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHandler(manager, zap.NewNop())
+	h.SetBotConfig(bots)
+	r := chi.NewRouter()
+	r.Route("/integrations", h.RegisterBotRoutes)
+	r.Route("/projects/{projectId}", func(r chi.Router) {
+		h.RegisterReadRoutes(r)
+		h.RegisterWriteRoutes(r)
+	})
+	return h, r
+}
+
+func signSlack(secret string, req *http.Request, body string, at time.Time) {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestSlackEvents(t *testing.T) {
+	posted := make(chan map[string]interface{}, 1)
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&message)
+		posted <- message
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer slackAPI.Close()
+
+	const secret = "signing-secret"
+	_, router := newBotTestHandler(t, &BotConfig{SlackSigningSecret: secret, SlackBotToken: "xoxb", SlackAPIURL: slackAPI.URL})
+
+	send := func(body string, at time.Time) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/integrations/slack/events", strings.NewReader(body))
+		signSlack(secret, req, body, at)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(`{"type":"url_verification","challenge":"abc"}`, time.Now())
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"challenge":"abc"`) {
+		t.Fatalf("unexpected url verification response %d %s", rec.Code, rec.Body)
+	}
+	if rec := send(`{"type":"url_verification","challenge":"abc"}`, time.Now().Add(-10*time.Minute)); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected stale request to be rejected, got %d", rec.Code)
+	}
+
+	// Mentions in channels without a project binding get a pointer to the setup
+	rec = send(`{"type":"event_callback","event":{"type":"app_mention","channel":"C1","text":"<@U1> hi","ts":"1.2"}}`, time.Now())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected event response %d", rec.Code)
+	}
+	select {
+	case message := <-posted:
+		if message["channel"] != "C1" || message["thread_ts"] != "1.2" || !strings.Contains(message["text"].(string), "not connected") {
+			t.Fatalf("unexpected reply %v", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reply posted")
+	}
+}
+
+func TestDiscordInteractions(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	_, router := newBotTestHandler(t, &BotConfig{DiscordPublicKey: hex.EncodeToString(public)})
+
+	send := func(body string, key ed25519.PrivateKey) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/integrations/discord/interactions", strings.NewReader(body))
+		req.Header.Set("X-Signature-Timestamp", "1700000000")
+		req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte("1700000000"+body))))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(`{"type":1}`, private); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"type":1}` {
+		t.Fatalf("unexpected ping response %d %s", rec.Code, rec.Body)
+	}
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	if rec := send(`{"type":1}`, otherKey); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected forged request to be rejected, got %d", rec.Code)
+	}
+
+	rec := send(`{"type":2,"channel_id":"D1","data":{"name":"ask","options":[{"name":"question","value":"hi"}]}}`, private)
+	if !strings.Contains(rec.Body.String(), "not connected") || !strings.Contains(rec.Body.String(), `"flags":64`) {
+		t.Fatalf("expected ephemeral not-connected reply, got %s", rec.Body)
+	}
+}
+
+func TestBotChannelBindings(t *testing.T) {
+	_, router := newBotTestHandler(t, nil)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/projects/p1/rag/bots/slack/channels/C1", `{"max_citations":2}`); rec.Code != http.StatusOK {
+		t.Fatalf("bind failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPut, "/projects/p2/rag/bots/slack/channels/C1", `{}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected channel bound to another project to conflict, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/projects/p1/rag/bots/teams/channels/C1", `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unsupported platform to be rejected, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/projects/p1/rag/bots/channels", "")
+	var list struct {
+		Data []BotChannel `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Data[0].MaxCitations != 2 || !list.Data[0].Enabled {
+		t.Fatalf("unexpected channels %s", rec.Body)
+	}
+
+	if rec := do(http.MethodDelete, "/projects/p2/rag/bots/slack/channels/C1", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected other project's unbind to fail, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/projects/p1/rag/bots/slack/channels/C1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unbind failed: %d", rec.Code)
+	}
+}
+
+func TestBotCitations(t *testing.T) {
+	channel := &BotChannel{MaxCitations: 2, CitationURL: "https://docs.example.com/{document_id}"}
+	citations := channel.citations([]core.Source{
+		{DocumentID: "a b", DocumentTitle: "A"},
+		{DocumentID: "a b", DocumentTitle: "A"},
+		{DocumentID: "c", DocumentURI: "/tmp/c.md"},
+		{DocumentID: "d"},
+	})
+	if len(citations) != 2 || citations[0].URL != "https://docs.example.com/a%20b" || citations[1].Title != "c" {
+		t.Fatalf("unexpected citations %+v", citations)
+	}
+
+	// Without a template deep links and web documents are linked
+	channel.CitationURL = ""
+	channel.MaxCitations = 3
+	citations = channel.citations([]core.Source{
+		{DocumentID: "x", DocumentURI: "https://example.com/x"},
+		{DocumentID: "y", DocumentURI: "/srv/y"},
+		{DocumentID: "z", DocumentURI: "s3://bucket/z", DeepLink: "https://bucket.s3.amazonaws.com/z?X-Amz-Signature=abc"},
+	})
+	if citations[0].URL != "https://example.com/x" || citations[1].URL != "" || citations[2].URL != "https://bucket.s3.amazonaws.com/z?X-Amz-Signature=abc" {
+		t.Fatalf("unexpected citation links %+v", citations)
+	}
+}
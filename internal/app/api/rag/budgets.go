@@ -0,0 +1,178 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+// cycleKey 计费周期的存储键
+func cycleKey(cycleStart time.Time) string {
+	return cycleStart.UTC().Format("2006-01-02")
+}
+
+// GetBudget 获取租户预算，不存在时返回 nil
+func (m *Manager) GetBudget(ctx context.Context, tenantID string) (*core.TenantBudget, error) {
+	var settings string
+	var updatedBy sql.NullString
+	var updatedAt time.Time
+
+	err := m.db.QueryRowContext(ctx,
+		`SELECT settings, updated_by, updated_at FROM rag_tenant_budgets WHERE tenant_id = ?`,
+		tenantID,
+	).Scan(&settings, &updatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant budget: %w", err)
+	}
+
+	var budget core.TenantBudget
+	if err := json.Unmarshal([]byte(settings), &budget); err != nil {
+		return nil, fmt.Errorf("failed to decode tenant budget: %w", err)
+	}
+	budget.TenantID = tenantID
+	budget.UpdatedBy = updatedBy.String
+	budget.UpdatedAt = updatedAt
+
+	return &budget, nil
+}
+
+// SaveBudget 保存租户预算
+func (m *Manager) SaveBudget(ctx context.Context, budget *core.TenantBudget) error {
+	if err := budget.Validate(); err != nil {
+		return err
+	}
+
+	budget.UpdatedAt = time.Now()
+	settings, err := json.Marshal(budget)
+	if err != nil {
+		return fmt.Errorf("failed to encode tenant budget: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_tenant_budgets (tenant_id, settings, updated_by, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(tenant_id) DO UPDATE SET
+			settings = excluded.settings,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at`,
+		budget.TenantID, string(settings), budget.UpdatedBy, budget.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save tenant budget: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBudget 删除租户预算，已记录的用量保留
+func (m *Manager) DeleteBudget(ctx context.Context, tenantID string) error {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM rag_tenant_budgets WHERE tenant_id = ?`, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant budget: %w", err)
+	}
+	return nil
+}
+
+// ListBudgets 列出所有租户预算
+func (m *Manager) ListBudgets(ctx context.Context) ([]core.TenantBudget, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT tenant_id, settings, updated_by, updated_at FROM rag_tenant_budgets ORDER BY tenant_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []core.TenantBudget
+	for rows.Next() {
+		var tenantID, settings string
+		var updatedBy sql.NullString
+		var updatedAt time.Time
+		if err := rows.Scan(&tenantID, &settings, &updatedBy, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant budget: %w", err)
+		}
+		var budget core.TenantBudget
+		if err := json.Unmarshal([]byte(settings), &budget); err != nil {
+			return nil, fmt.Errorf("failed to decode tenant budget: %w", err)
+		}
+		budget.TenantID = tenantID
+		budget.UpdatedBy = updatedBy.String
+		budget.UpdatedAt = updatedAt
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+// BudgetUsageFraction 返回租户本计费周期已用预算的比例，取 token 和费用中较高者
+func (m *Manager) BudgetUsageFraction(ctx context.Context, budget *core.TenantBudget, now time.Time) (float64, error) {
+	usage, err := m.GetUsage(ctx, budget.TenantID, budget.CycleStart(now))
+	if err != nil {
+		return 0, err
+	}
+	fraction := 0.0
+	if budget.TokenLimit > 0 {
+		fraction = float64(usage.Tokens) / float64(budget.TokenLimit)
+	}
+	if budget.CostLimit > 0 && usage.Cost/budget.CostLimit > fraction {
+		fraction = usage.Cost / budget.CostLimit
+	}
+	return fraction, nil
+}
+
+// GetUsage 获取租户在计费周期内的用量
+func (m *Manager) GetUsage(ctx context.Context, tenantID string, cycleStart time.Time) (*core.BudgetUsage, error) {
+	usage := &core.BudgetUsage{TenantID: tenantID, CycleStart: cycleStart}
+
+	err := m.db.QueryRowContext(ctx,
+		`SELECT tokens, cost FROM rag_tenant_usage WHERE tenant_id = ? AND cycle_start = ?`,
+		tenantID, cycleKey(cycleStart),
+	).Scan(&usage.Tokens, &usage.Cost)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get tenant usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// AddUsage 累加租户在计费周期内的用量并返回累计值
+func (m *Manager) AddUsage(ctx context.Context, tenantID string, cycleStart time.Time, tokens int64, cost float64) (*core.BudgetUsage, error) {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO rag_tenant_usage (tenant_id, cycle_start, tokens, cost, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(tenant_id, cycle_start) DO UPDATE SET
+			tokens = tokens + excluded.tokens,
+			cost = cost + excluded.cost,
+			updated_at = excluded.updated_at`,
+		tenantID, cycleKey(cycleStart), tokens, cost, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record tenant usage: %w", err)
+	}
+
+	return m.GetUsage(ctx, tenantID, cycleStart)
+}
+
+// MarkAlerted 记录计费周期内已发送的告警，重复告警返回 false
+func (m *Manager) MarkAlerted(ctx context.Context, tenantID string, cycleStart time.Time, alert string) (bool, error) {
+	result, err := m.db.ExecContext(ctx, `
+		INSERT INTO rag_tenant_budget_alerts (tenant_id, cycle_start, alert)
+		VALUES (?, ?, ?)
+		ON CONFLICT(tenant_id, cycle_start, alert) DO NOTHING`,
+		tenantID, cycleKey(cycleStart), alert,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record budget alert: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record budget alert: %w", err)
+	}
+	return affected > 0, nil
+}
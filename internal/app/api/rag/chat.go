@@ -0,0 +1,278 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/gorilla/websocket"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// chatPingInterval 服务端发送 WebSocket ping 的周期
+	chatPingInterval = 30 * time.Second
+
+	// chatReadTimeout 超过该时间未收到任何消息或 pong 即断开连接
+	chatReadTimeout = 2 * chatPingInterval
+
+	// chatWriteTimeout 单条消息的写超时
+	chatWriteTimeout = 10 * time.Second
+
+	// chatMaxMessageSize 客户端消息的最大字节数
+	chatMaxMessageSize = 1 << 20
+
+	// chatMaxInFlight 每个连接同时进行的查询数上限
+	chatMaxInFlight = 4
+)
+
+// chatUpgrader 与 API 的 CORS 策略一致，允许任意来源，鉴权由路由中间件完成
+var chatUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// chatSession 一个 WebSocket 聊天连接。查询在各自的 goroutine 中执行，写操作串行化
+type chatSession struct {
+	handler *Handler
+	conn    *websocket.Conn
+	request *http.Request
+	ctx     context.Context
+
+	// transcript 本连接的对话记录，首轮对话完成时写入
+	transcript *ChatTranscript
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	inFlight map[string]*chatQuery
+	wg       sync.WaitGroup
+}
+
+// chatQuery 进行中的查询
+type chatQuery struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// handleChat 以 WebSocket 提供流式聊天：客户端发送 query/cancel/ping 消息，服务端
+// 依次推送 citations、token 和 done（或 cancelled/error）消息。取消查询会中止
+// 对LLM的调用。需要审核或结构化输出的查询不推送 token，只在 done 中返回完整结果。
+// 完成或失败的对话保存在本连接的对话记录中，done 消息带有对话记录 ID
+func (h *Handler) handleChat(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	conn, err := chatUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已向客户端返回错误
+		h.logger.Warn("websocket upgrade failed", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	now := time.Now()
+	transcript := &ChatTranscript{
+		ID:        fmt.Sprintf("chat_%d", now.UnixNano()),
+		ProjectID: chi.URLParam(r, "projectId"),
+		StartedAt: now,
+	}
+	transcript.UserID, _ = r.Context().Value("user_id").(string)
+	transcript.TenantID, _ = r.Context().Value("tenant_id").(string)
+	session := &chatSession{
+		handler:    h,
+		conn:       conn,
+		request:    r,
+		ctx:        ctx,
+		transcript: transcript,
+		inFlight:   make(map[string]*chatQuery),
+	}
+
+	go session.keepalive()
+	session.readLoop()
+
+	// 连接关闭后取消所有查询并等待结束
+	cancel()
+	session.wg.Wait()
+	conn.Close()
+}
+
+// readLoop 读取并分发客户端消息，直到连接关闭或读超时
+func (s *chatSession) readLoop() {
+	s.conn.SetReadLimit(chatMaxMessageSize)
+	s.conn.SetReadDeadline(time.Now().Add(chatReadTimeout))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(chatReadTimeout))
+	})
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				s.handler.logger.Debug("websocket chat closed", zap.Error(err))
+			}
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(chatReadTimeout))
+
+		var msg ChatClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.send(ChatServerMessage{Type: ChatMessageError, Error: "Invalid JSON data: " + err.Error(), Code: "invalid_message"})
+			continue
+		}
+
+		switch msg.Type {
+		case ChatMessagePing:
+			s.send(ChatServerMessage{Type: ChatMessagePong, ID: msg.ID})
+		case ChatMessageCancel:
+			s.cancel(msg.ID)
+		case ChatMessageQuery:
+			s.start(msg)
+		default:
+			s.send(ChatServerMessage{Type: ChatMessageError, ID: msg.ID, Error: "Unknown message type", Code: "invalid_message"})
+		}
+	}
+}
+
+// keepalive 定期发送 ping，直到连接关闭
+func (s *chatSession) keepalive() {
+	ticker := time.NewTicker(chatPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.writeMu.Lock()
+			err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(chatWriteTimeout))
+			s.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// start 校验查询消息并在后台执行
+func (s *chatSession) start(msg ChatClientMessage) {
+	if msg.ID == "" {
+		s.send(ChatServerMessage{Type: ChatMessageError, Error: "Query id is required", Code: "invalid_message"})
+		return
+	}
+	if msg.Query == "" {
+		s.send(ChatServerMessage{Type: ChatMessageError, ID: msg.ID, Error: "Query is required", Code: "invalid_message"})
+		return
+	}
+	options, err := msg.queryOptions(s.request)
+	if err != nil {
+		s.send(ChatServerMessage{Type: ChatMessageError, ID: msg.ID, Error: "Invalid filter: " + err.Error(), Code: "invalid_message"})
+		return
+	}
+
+	s.mu.Lock()
+	if _, exists := s.inFlight[msg.ID]; exists {
+		s.mu.Unlock()
+		s.send(ChatServerMessage{Type: ChatMessageError, ID: msg.ID, Error: "Query id already in progress", Code: "duplicate_id"})
+		return
+	}
+	if len(s.inFlight) >= chatMaxInFlight {
+		s.mu.Unlock()
+		s.send(ChatServerMessage{Type: ChatMessageError, ID: msg.ID, Error: "Too many queries in progress", Code: "too_many_queries"})
+		return
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	query := &chatQuery{cancel: cancel}
+	s.inFlight[msg.ID] = query
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.inFlight, msg.ID)
+			s.mu.Unlock()
+			cancel()
+		}()
+		s.run(ctx, msg.ID, msg.Query, options, query)
+	}()
+}
+
+// run 执行查询，推送流式输出和最终结果
+func (s *chatSession) run(ctx context.Context, id, text string, options core.QueryOptions, query *chatQuery) {
+	options.GenerateOptions.Stream = &chatStream{session: s, id: id}
+	result, err := s.handler.query(ctx, text, options)
+
+	s.mu.Lock()
+	cancelled := query.cancelled
+	s.mu.Unlock()
+
+	switch {
+	case s.ctx.Err() != nil:
+		// 连接已关闭，无需回复
+	case cancelled:
+		s.send(ChatServerMessage{Type: ChatMessageCancelled, ID: id})
+	case errors.Is(err, core.ErrBudgetExceeded):
+		s.send(ChatServerMessage{Type: ChatMessageError, ID: id, Error: err.Error(), Code: "budget_exceeded"})
+	case err != nil:
+		s.handler.logger.Error("rag chat query failed", zap.String("project_id", options.ProjectID), zap.Error(err))
+		s.recordChatTurn(id, text, nil, err)
+		s.send(ChatServerMessage{Type: ChatMessageError, ID: id, Error: err.Error(), Code: "query_failed", TranscriptID: s.transcript.ID})
+	default:
+		s.recordChatTurn(id, text, result, nil)
+		s.send(ChatServerMessage{Type: ChatMessageDone, ID: id, Result: result, TranscriptID: s.transcript.ID})
+	}
+}
+
+// cancel 取消进行中的查询，查询结束时回复 cancelled
+func (s *chatSession) cancel(id string) {
+	s.mu.Lock()
+	query, ok := s.inFlight[id]
+	if ok {
+		query.cancelled = true
+		query.cancel()
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		s.send(ChatServerMessage{Type: ChatMessageError, ID: id, Error: "No query in progress with this id", Code: "unknown_id"})
+	}
+}
+
+// send 写入一条消息
+func (s *chatSession) send(msg ChatServerMessage) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(chatWriteTimeout))
+	return s.conn.WriteJSON(msg)
+}
+
+// chatStream 将管道的流式输出转发给客户端
+type chatStream struct {
+	session *chatSession
+	id      string
+}
+
+// OnCitations 推送生成前的引用来源
+func (c *chatStream) OnCitations(sources []core.Source) {
+	c.session.send(ChatServerMessage{Type: ChatMessageCitations, ID: c.id, Sources: sources})
+}
+
+// OnToken 推送部分生成内容，写失败时中止生成
+func (c *chatStream) OnToken(token string) error {
+	return c.session.send(ChatServerMessage{Type: ChatMessageToken, ID: c.id, Content: token})
+}
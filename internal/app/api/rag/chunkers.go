@@ -0,0 +1,136 @@
+package rag
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+	"github.com/guileen/metabase/pkg/rag/processors"
+)
+
+const (
+	// defaultCompareSampleSize 分块对比默认抽样的文档数
+	defaultCompareSampleSize = 50
+
+	// maxCompareSampleSize 分块对比最多抽样的文档数
+	maxCompareSampleSize = 500
+)
+
+// compareChunkersRequest 分块策略对比请求，A/B 未设置的配置项沿用项目当前的分块配置
+type compareChunkersRequest struct {
+	A               core.ChunkerVariant `json:"a"`
+	B               core.ChunkerVariant `json:"b"`
+	EvalSet         []core.EvalCase     `json:"eval_set"`
+	SampleSize      int                 `json:"sample_size"`
+	TopK            int                 `json:"top_k"`
+	CostPer1KTokens float64             `json:"cost_per_1k_tokens"`
+}
+
+// mergeChunkingConfig 用覆盖项中非零的字段替换当前配置
+func mergeChunkingConfig(current, override core.ChunkingConfig) core.ChunkingConfig {
+	merged := current
+	if override.Strategy != "" {
+		merged.Strategy = override.Strategy
+	}
+	if override.MaxChunkSize > 0 {
+		merged.MaxChunkSize = override.MaxChunkSize
+	}
+	if override.MinChunkSize > 0 {
+		merged.MinChunkSize = override.MinChunkSize
+	}
+	if override.OverlapSize > 0 {
+		merged.OverlapSize = override.OverlapSize
+	}
+	if override.SimilarityThreshold > 0 {
+		merged.SimilarityThreshold = override.SimilarityThreshold
+	}
+	return merged
+}
+
+// handleCompareChunkers 在项目文档样本上对比两种分块配置的检索质量与成本，
+// 两种配置分别索引到临时命名空间，不影响线上索引
+func (h *Handler) handleCompareChunkers(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req compareChunkersRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if len(req.EvalSet) == 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "eval_set is required",
+		})
+		return
+	}
+	if req.SampleSize <= 0 {
+		req.SampleSize = defaultCompareSampleSize
+	}
+	if req.SampleSize > maxCompareSampleSize {
+		req.SampleSize = maxCompareSampleSize
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	config, err := h.pipeline.EffectiveConfig(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to load project config", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to compare chunkers",
+			"details": err.Error(),
+		})
+		return
+	}
+	sourceIDs, err := h.projectDataSourceIDs(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to list data sources", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to compare chunkers",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	for name, variant := range map[string]*core.ChunkerVariant{"a": &req.A, "b": &req.B} {
+		variant.Config = mergeChunkingConfig(config.Processing.Chunking, variant.Config)
+		if variant.Name == "" {
+			variant.Name = name
+		}
+	}
+	comparison, err := h.pipeline.CompareProjectChunkers(r.Context(), sourceIDs, req.SampleSize, core.ChunkerComparisonOptions{
+		A:               req.A,
+		B:               req.B,
+		EvalSet:         req.EvalSet,
+		TopK:            req.TopK,
+		CostPer1KTokens: req.CostPer1KTokens,
+		NewStrategy:     processors.NewChunkingStrategy,
+	})
+	if err != nil {
+		h.logger.Error("failed to compare chunkers", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusUnprocessableEntity)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to compare chunkers",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": comparison,
+	})
+}
@@ -0,0 +1,139 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// ChunkHits 分块被检索命中的统计
+type ChunkHits struct {
+	Hits      int64     `json:"hits"`
+	LastHitAt time.Time `json:"last_hit_at"`
+}
+
+// chunkInspection 分块检查结果中的一个分块，附带检索命中统计
+type chunkInspection struct {
+	core.ChunkInspection
+	Hits      int64      `json:"hits"`
+	LastHitAt *time.Time `json:"last_hit_at,omitempty"`
+}
+
+// documentChunks 文档分块检查结果，分块附带检索命中统计
+type documentChunks struct {
+	core.DocumentInspection
+	Chunks []chunkInspection `json:"chunks"`
+}
+
+// recordChunkHits 累计一次查询中各检索结果分块的命中次数，同一分块在一次查询中只计一次
+func (m *Manager) recordChunkHits(ctx context.Context, projectID string, results []core.RetrievalResult, at time.Time) error {
+	seen := make(map[string]bool, len(results))
+	for _, result := range results {
+		if result.Chunk == nil || result.Chunk.ID == "" || seen[result.Chunk.ID] {
+			continue
+		}
+		seen[result.Chunk.ID] = true
+		documentID := result.Chunk.DocumentID
+		if documentID == "" {
+			documentID = result.DocumentID
+		}
+		_, err := m.db.ExecContext(ctx, `
+			INSERT INTO rag_chunk_hits (project_id, chunk_id, document_id, hits, last_hit_at)
+			VALUES (?, ?, ?, 1, ?)
+			ON CONFLICT(project_id, chunk_id) DO UPDATE SET
+				hits = hits + 1,
+				last_hit_at = excluded.last_hit_at`,
+			projectID, result.Chunk.ID, documentID, at.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record chunk hits: %w", err)
+		}
+	}
+	return nil
+}
+
+// ChunkHits 获取文档各分块的检索命中统计，按分块ID索引
+func (m *Manager) ChunkHits(ctx context.Context, projectID, documentID string) (map[string]ChunkHits, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT chunk_id, hits, last_hit_at FROM rag_chunk_hits WHERE project_id = ? AND document_id = ?`,
+		projectID, documentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunk hits: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make(map[string]ChunkHits)
+	for rows.Next() {
+		var chunkID string
+		var stat ChunkHits
+		if err := rows.Scan(&chunkID, &stat.Hits, &stat.LastHitAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk hits: %w", err)
+		}
+		hits[chunkID] = stat
+	}
+	return hits, rows.Err()
+}
+
+// handleInspectChunks 列出文档的分块边界、token 数、嵌入状态和检索命中次数，用于排查文档检索效果差的原因
+func (h *Handler) handleInspectChunks(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	documentID := chi.URLParam(r, "documentId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	inspection, err := h.pipeline.InspectChunks(r.Context(), documentID)
+	var sources []string
+	if err == nil && inspection != nil {
+		sources, err = h.projectDataSourceIDs(r.Context(), projectID)
+	}
+	var hits map[string]ChunkHits
+	if err == nil && inspection != nil {
+		hits, err = h.manager.ChunkHits(r.Context(), projectID, documentID)
+	}
+	if err != nil {
+		h.logger.Error("failed to inspect chunks", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to inspect chunks",
+			"details": err.Error(),
+		})
+		return
+	}
+	if inspection == nil || !slices.Contains(sources, inspection.DataSourceID) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Document not found",
+		})
+		return
+	}
+
+	data := documentChunks{
+		DocumentInspection: *inspection,
+		Chunks:             make([]chunkInspection, 0, len(inspection.Chunks)),
+	}
+	for _, chunk := range inspection.Chunks {
+		info := chunkInspection{ChunkInspection: chunk}
+		if stat, ok := hits[chunk.ID]; ok {
+			info.Hits = stat.Hits
+			info.LastHitAt = &stat.LastHitAt
+		}
+		data.Chunks = append(data.Chunks, info)
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": data,
+	})
+}
@@ -0,0 +1,48 @@
+package rag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestChunkHits(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+
+	chunk := func(id string) core.RetrievalResult {
+		return core.RetrievalResult{DocumentID: "d1", Chunk: &core.DocumentChunk{ID: id, DocumentID: "d1"}}
+	}
+	first := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i, results := range [][]core.RetrievalResult{
+		{chunk("c1"), chunk("c2"), chunk("c1")},
+		{chunk("c1")},
+		{{DocumentID: "d1"}},
+	} {
+		record := core.QueryRecord{Query: "q", CreatedAt: first.Add(time.Duration(i) * time.Hour), Result: &core.QueryResult{RetrievalResults: results}}
+		if err := h.manager.StoreQueryRecord(ctx, "p1", "t1", record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.manager.StoreQueryRecord(ctx, "p2", "t1", core.QueryRecord{Query: "q", Result: &core.QueryResult{RetrievalResults: []core.RetrievalResult{chunk("c1")}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := h.manager.ChunkHits(ctx, "p1", "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits["c1"].Hits != 2 || hits["c2"].Hits != 1 || !hits["c1"].LastHitAt.Equal(first.Add(time.Hour)) {
+		t.Fatalf("unexpected chunk hits %+v", hits)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/projects/p1/documents/d1/chunks", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without pipeline, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,414 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+	"github.com/guileen/metabase/pkg/rag/datasources"
+)
+
+// 同步任务状态
+const (
+	SyncStatusRunning   = "running"
+	SyncStatusSucceeded = "succeeded"
+	SyncStatusFailed    = "failed"
+)
+
+// credentialKeys 只能通过密钥引用提供的配置项
+var credentialKeys = map[string]bool{
+	"password":         true,
+	"api_key":          true,
+	"bearer_token":     true,
+	"access_key":       true,
+	"secret_key":       true,
+	"session_token":    true,
+	"ssh_key_password": true,
+	"client_secret":    true,
+}
+
+// DataSource 项目数据源定义
+type DataSource struct {
+	ID        string                 `json:"id"`
+	ProjectID string                 `json:"project_id"`
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type"`
+	Config    map[string]interface{} `json:"config"`
+
+	// 凭据通过密钥引用提供：配置项 -> env:NAME 或 file:/path，同步时解析
+	SecretRefs map[string]string `json:"secret_refs,omitempty"`
+
+	// 同步计划：cron 表达式（如 0 */6 * * *）、@daily 等描述符、@every 1h 或间隔 1h；为空时仅手动同步
+	Schedule string `json:"schedule,omitempty"`
+
+	IncludePatterns []string `json:"include_patterns,omitempty"`
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+	Enabled         bool     `json:"enabled"`
+
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DataSourceSyncRun 数据源同步记录
+type DataSourceSyncRun struct {
+	ID          string            `json:"id"`
+	SourceID    string            `json:"source_id"`
+	Trigger     string            `json:"trigger"` // manual、scheduled、reindex 或 retry
+	Status      string            `json:"status"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+	Result      *core.IndexResult `json:"result,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	TriggeredBy string            `json:"triggered_by,omitempty"`
+}
+
+// DataSourceStatus 数据源同步状态
+type DataSourceStatus struct {
+	SourceID      string             `json:"source_id"`
+	Enabled       bool               `json:"enabled"`
+	Attached      bool               `json:"attached"` // 已加载到RAG管道
+	Running       bool               `json:"running"`
+	Schedule      string             `json:"schedule,omitempty"`
+	NextRunAt     *time.Time         `json:"next_run_at,omitempty"` // 下一次计划同步时间，禁用或无计划时为空
+	LastRunAt     *time.Time         `json:"last_run_at,omitempty"`
+	LastRun       *DataSourceSyncRun `json:"last_run,omitempty"`
+	LastSuccessAt *time.Time         `json:"last_success_at,omitempty"`
+}
+
+// ConnectionTestResult 数据源连接测试结果
+type ConnectionTestResult struct {
+	OK      bool          `json:"ok"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// dataSourceRegistry 支持的数据源类型
+var dataSourceRegistry = newDataSourceRegistry()
+
+func newDataSourceRegistry() *datasources.DataSourceRegistry {
+	registry := datasources.NewDataSourceRegistry()
+	filesystem := datasources.NewFileSystemDataSourceFactory()
+	for _, sourceType := range filesystem.GetSupportedTypes() {
+		registry.RegisterFactory(sourceType, filesystem)
+	}
+	return registry
+}
+
+// Validate 校验数据源定义
+func (ds *DataSource) Validate() error {
+	if strings.TrimSpace(ds.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := dataSourceRegistry.GetFactory(ds.Type); err != nil {
+		return err
+	}
+	if ds.Schedule != "" {
+		if _, err := core.ParseSchedule(ds.Schedule); err != nil {
+			return err
+		}
+	}
+	for key := range ds.Config {
+		if credentialKeys[key] {
+			return fmt.Errorf("config.%s must be provided through secret_refs", key)
+		}
+	}
+	for key, ref := range ds.SecretRefs {
+		if !strings.HasPrefix(ref, "env:") && !strings.HasPrefix(ref, "file:") {
+			return fmt.Errorf("secret_refs.%s must reference env:NAME or file:/path", key)
+		}
+	}
+
+	// 类型相关校验不解析密钥，避免在校验时读取凭据
+	return dataSourceRegistry.ValidateConfig(ds.Type, ds.sourceConfig(nil))
+}
+
+// sourceConfig 合并配置、过滤规则与已解析的密钥
+func (ds *DataSource) sourceConfig(secrets map[string]string) map[string]interface{} {
+	config := make(map[string]interface{}, len(ds.Config)+len(secrets)+4)
+	for key, value := range ds.Config {
+		config[key] = value
+	}
+	for key, value := range secrets {
+		config[key] = value
+	}
+	if len(ds.IncludePatterns) > 0 {
+		config["include_patterns"] = ds.IncludePatterns
+	}
+	if len(ds.ExcludePatterns) > 0 {
+		config["exclude_patterns"] = ds.ExcludePatterns
+	}
+	config["id"] = ds.ID
+	config["type"] = ds.Type
+	return config
+}
+
+// Build 解析密钥引用并创建数据源实例
+func (ds *DataSource) Build() (core.DataSource, error) {
+	secrets := make(map[string]string, len(ds.SecretRefs))
+	for key, ref := range ds.SecretRefs {
+		value, err := resolveSecretRef(ref)
+		if err != nil {
+			return nil, fmt.Errorf("secret_refs.%s: %w", key, err)
+		}
+		secrets[key] = value
+	}
+	return dataSourceRegistry.CreateDataSource(ds.Type, ds.sourceConfig(secrets))
+}
+
+// TestConnection 创建数据源并检查其可访问性
+func (ds *DataSource) TestConnection() *ConnectionTestResult {
+	start := time.Now()
+	result := &ConnectionTestResult{}
+
+	source, err := ds.Build()
+	if err == nil {
+		err = source.Validate()
+		source.Close()
+	}
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.OK = true
+	return result
+}
+
+// resolveSecretRef 解析密钥引用：env:NAME 读取环境变量，file:/path 读取文件内容
+func resolveSecretRef(ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		value, ok := os.LookupEnv(strings.TrimPrefix(ref, "env:"))
+		if !ok {
+			return "", fmt.Errorf("environment variable %s not set", strings.TrimPrefix(ref, "env:"))
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(ref, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", fmt.Errorf("unsupported secret reference %q", ref)
+}
+
+// CreateDataSource 保存新的数据源定义
+func (m *Manager) CreateDataSource(ctx context.Context, ds *DataSource) error {
+	now := time.Now()
+	ds.ID = fmt.Sprintf("ds_%d", now.UnixNano())
+	ds.CreatedAt = now
+	ds.UpdatedAt = now
+	return m.saveDataSource(ctx, ds, false)
+}
+
+// UpdateDataSource 更新数据源定义
+func (m *Manager) UpdateDataSource(ctx context.Context, ds *DataSource) error {
+	ds.UpdatedAt = time.Now()
+	return m.saveDataSource(ctx, ds, true)
+}
+
+func (m *Manager) saveDataSource(ctx context.Context, ds *DataSource, update bool) error {
+	definition, err := json.Marshal(ds)
+	if err != nil {
+		return fmt.Errorf("failed to encode data source: %w", err)
+	}
+
+	if update {
+		_, err = m.db.ExecContext(ctx, `
+			UPDATE rag_data_sources SET definition = ?, updated_at = ?
+			WHERE id = ? AND project_id = ?`,
+			string(definition), ds.UpdatedAt, ds.ID, ds.ProjectID,
+		)
+	} else {
+		_, err = m.db.ExecContext(ctx, `
+			INSERT INTO rag_data_sources (id, project_id, definition, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			ds.ID, ds.ProjectID, string(definition), ds.CreatedAt, ds.UpdatedAt,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save data source: %w", err)
+	}
+
+	return nil
+}
+
+// GetDataSource 获取项目的数据源定义，不存在时返回 nil
+func (m *Manager) GetDataSource(ctx context.Context, projectID, sourceID string) (*DataSource, error) {
+	var definition string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT definition FROM rag_data_sources WHERE id = ? AND project_id = ?`,
+		sourceID, projectID,
+	).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data source: %w", err)
+	}
+	return decodeDataSource(definition)
+}
+
+// ListDataSources 列出项目的数据源定义，projectID 为空时列出全部
+func (m *Manager) ListDataSources(ctx context.Context, projectID string) ([]DataSource, error) {
+	query := `SELECT definition FROM rag_data_sources ORDER BY created_at`
+	var args []interface{}
+	if projectID != "" {
+		query = `SELECT definition FROM rag_data_sources WHERE project_id = ? ORDER BY created_at`
+		args = append(args, projectID)
+	}
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data sources: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []DataSource{}
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to list data sources: %w", err)
+		}
+		ds, err := decodeDataSource(definition)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, *ds)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list data sources: %w", err)
+	}
+
+	return sources, nil
+}
+
+// DeleteDataSource 删除数据源定义，同步记录一并删除
+func (m *Manager) DeleteDataSource(ctx context.Context, projectID, sourceID string) error {
+	_, err := m.db.ExecContext(ctx,
+		`DELETE FROM rag_data_sources WHERE id = ? AND project_id = ?`,
+		sourceID, projectID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete data source: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `DELETE FROM rag_data_source_syncs WHERE source_id = ?`, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete data source sync history: %w", err)
+	}
+	return nil
+}
+
+// SaveSyncRun 保存或更新同步记录
+func (m *Manager) SaveSyncRun(ctx context.Context, run *DataSourceSyncRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode sync run: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_data_source_syncs (id, source_id, status, run, started_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			run = excluded.run`,
+		run.ID, run.SourceID, run.Status, string(data), run.StartedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save sync run: %w", err)
+	}
+
+	return nil
+}
+
+// ListSyncRuns 列出数据源的同步历史，最新的在前
+func (m *Manager) ListSyncRuns(ctx context.Context, sourceID string, limit int) ([]DataSourceSyncRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT run FROM rag_data_source_syncs
+		WHERE source_id = ?
+		ORDER BY started_at DESC
+		LIMIT ?`,
+		sourceID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []DataSourceSyncRun{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list sync runs: %w", err)
+		}
+		var run DataSourceSyncRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, fmt.Errorf("failed to decode sync run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// GetSyncStatus 汇总数据源的最近一次同步与最近一次成功时间
+func (m *Manager) GetSyncStatus(ctx context.Context, ds *DataSource) (*DataSourceStatus, error) {
+	status := &DataSourceStatus{SourceID: ds.ID, Enabled: ds.Enabled, Schedule: ds.Schedule}
+
+	runs, err := m.ListSyncRuns(ctx, ds.ID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) > 0 {
+		status.LastRun = &runs[0]
+		status.LastRunAt = &runs[0].StartedAt
+		status.Running = runs[0].Status == SyncStatusRunning
+	}
+	if ds.Enabled {
+		if status.NextRunAt, err = nextScheduledRun(ds, runs); err != nil {
+			return nil, err
+		}
+	}
+
+	var data string
+	err = m.db.QueryRowContext(ctx, `
+		SELECT run FROM rag_data_source_syncs
+		WHERE source_id = ? AND status = ?
+		ORDER BY started_at DESC LIMIT 1`,
+		ds.ID, SyncStatusSucceeded,
+	).Scan(&data)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get sync status: %w", err)
+	}
+	if err == nil {
+		var run DataSourceSyncRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, fmt.Errorf("failed to decode sync run: %w", err)
+		}
+		status.LastSuccessAt = run.FinishedAt
+	}
+
+	return status, nil
+}
+
+func decodeDataSource(definition string) (*DataSource, error) {
+	var ds DataSource
+	if err := json.Unmarshal([]byte(definition), &ds); err != nil {
+		return nil, fmt.Errorf("failed to decode data source: %w", err)
+	}
+	return &ds, nil
+}
@@ -0,0 +1,204 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+func newDataSourceTestManager(t *testing.T) *Manager {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "rag.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	manager := NewManager(db, nil, zap.NewNop())
+	if err := manager.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestDataSourceValidate(t *testing.T) {
+	valid := func() DataSource {
+		return DataSource{
+			Name:   "Docs",
+			Type:   "filesystem",
+			Config: map[string]interface{}{"root_path": "/docs"},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(ds *DataSource)
+		err    string
+	}{
+		{name: "valid", modify: func(ds *DataSource) {}},
+		{name: "valid schedule and secrets", modify: func(ds *DataSource) {
+			ds.Schedule = "1h"
+			ds.SecretRefs = map[string]string{"api_key": "env:DOCS_KEY", "password": "file:/run/secrets/docs"}
+		}},
+		{name: "missing name", modify: func(ds *DataSource) { ds.Name = " " }, err: "name is required"},
+		{name: "unknown type", modify: func(ds *DataSource) { ds.Type = "ftp" }, err: "ftp"},
+		{name: "short schedule", modify: func(ds *DataSource) { ds.Schedule = "30s" }, err: "schedule"},
+		{name: "invalid schedule", modify: func(ds *DataSource) { ds.Schedule = "daily" }, err: "schedule"},
+		{name: "inline credential", modify: func(ds *DataSource) { ds.Config["api_key"] = "secret" }, err: "config.api_key"},
+		{name: "invalid secret ref", modify: func(ds *DataSource) { ds.SecretRefs = map[string]string{"password": "hunter2"} }, err: "secret_refs.password"},
+		{name: "invalid type config", modify: func(ds *DataSource) { delete(ds.Config, "root_path") }, err: "root_path is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := valid()
+			tt.modify(&ds)
+			err := ds.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestResolveSecretRef(t *testing.T) {
+	t.Setenv("RAG_TEST_SECRET", "from-env")
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref  string
+		want string
+		err  bool
+	}{
+		{ref: "env:RAG_TEST_SECRET", want: "from-env"},
+		{ref: "file:" + secretFile, want: "from-file"},
+		{ref: "env:RAG_TEST_MISSING", err: true},
+		{ref: "file:" + secretFile + ".missing", err: true},
+		{ref: "vault:secret", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := resolveSecretRef(tt.ref)
+			if (err != nil) != tt.err || got != tt.want {
+				t.Fatalf("got %q %v, want %q (error %v)", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestDataSourceTestConnection(t *testing.T) {
+	ds := &DataSource{
+		ID:              "ds1",
+		Name:            "Docs",
+		Type:            "filesystem",
+		Config:          map[string]interface{}{"root_path": t.TempDir()},
+		IncludePatterns: []string{"*.md"},
+	}
+	config := ds.sourceConfig(map[string]string{"password": "secret"})
+	if config["id"] != "ds1" || config["password"] != "secret" || config["include_patterns"] == nil {
+		t.Fatalf("unexpected source config %v", config)
+	}
+
+	if result := ds.TestConnection(); !result.OK || result.Error != "" {
+		t.Fatalf("expected the connection to succeed, got %+v", result)
+	}
+
+	ds.Config["root_path"] = filepath.Join(t.TempDir(), "missing")
+	if result := ds.TestConnection(); result.OK || !strings.Contains(result.Error, "root path") {
+		t.Fatalf("expected a missing root path to fail, got %+v", result)
+	}
+
+	ds.SecretRefs = map[string]string{"password": "env:RAG_TEST_MISSING"}
+	if result := ds.TestConnection(); result.OK || !strings.Contains(result.Error, "secret_refs.password") {
+		t.Fatalf("expected an unresolved secret to fail, got %+v", result)
+	}
+}
+
+func TestDataSourceStorage(t *testing.T) {
+	ctx := context.Background()
+	m := newDataSourceTestManager(t)
+
+	ds := &DataSource{ProjectID: "p1", Name: "Docs", Type: "filesystem", Config: map[string]interface{}{"root_path": "/docs"}, Enabled: true}
+	if err := m.CreateDataSource(ctx, ds); err != nil {
+		t.Fatal(err)
+	}
+	other := &DataSource{ProjectID: "p2", Name: "Wiki", Type: "filesystem", Config: map[string]interface{}{"root_path": "/wiki"}}
+	if err := m.CreateDataSource(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.GetDataSource(ctx, "p1", ds.ID)
+	if err != nil || got == nil || got.Name != "Docs" || got.Config["root_path"] != "/docs" {
+		t.Fatalf("unexpected data source %+v %v", got, err)
+	}
+	if got, _ := m.GetDataSource(ctx, "p2", ds.ID); got != nil {
+		t.Fatal("expected data sources to be scoped to their project")
+	}
+
+	ds.Name = "Handbook"
+	if err := m.UpdateDataSource(ctx, ds); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := m.ListDataSources(ctx, "p1")
+	if err != nil || len(sources) != 1 || sources[0].Name != "Handbook" {
+		t.Fatalf("unexpected data sources %+v %v", sources, err)
+	}
+	if all, _ := m.ListDataSources(ctx, ""); len(all) != 2 {
+		t.Fatalf("expected 2 data sources across projects, got %d", len(all))
+	}
+
+	// The latest run is reported alongside the last successful one
+	started := time.Now().Add(-time.Hour)
+	finished := started.Add(time.Minute)
+	runs := []*DataSourceSyncRun{
+		{ID: "run1", SourceID: ds.ID, Status: SyncStatusRunning, StartedAt: started},
+		{ID: "run2", SourceID: ds.ID, Status: SyncStatusRunning, StartedAt: started.Add(30 * time.Minute)},
+	}
+	for _, run := range runs {
+		if err := m.SaveSyncRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs[0].Status = SyncStatusSucceeded
+	runs[0].FinishedAt = &finished
+	runs[0].Result = &core.IndexResult{DocumentsAdded: 3}
+	if err := m.SaveSyncRun(ctx, runs[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := m.ListSyncRuns(ctx, ds.ID, 0)
+	if err != nil || len(history) != 2 || history[0].ID != "run2" || history[1].Result.DocumentsAdded != 3 {
+		t.Fatalf("unexpected sync history %+v %v", history, err)
+	}
+	status, err := m.GetSyncStatus(ctx, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running || status.LastRun.ID != "run2" || status.LastSuccessAt == nil || !status.LastSuccessAt.Equal(finished) {
+		t.Fatalf("unexpected sync status %+v", status)
+	}
+
+	if err := m.DeleteDataSource(ctx, "p1", ds.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.GetDataSource(ctx, "p1", ds.ID); got != nil {
+		t.Fatal("expected the data source to be deleted")
+	}
+	if history, _ := m.ListSyncRuns(ctx, ds.ID, 0); len(history) != 0 {
+		t.Fatalf("expected the sync history to be deleted, got %+v", history)
+	}
+}
@@ -0,0 +1,205 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+const defaultDiscordAPIURL = "https://discord.com/api/v10"
+
+// Discord 交互类型和响应类型
+const (
+	discordInteractionPing    = 1
+	discordInteractionCommand = 2
+
+	discordResponsePong            = 1
+	discordResponseMessage         = 4
+	discordResponseDeferredMessage = 5
+
+	discordFlagEphemeral = 64
+)
+
+// discordContentLimit Discord 消息正文的最大长度
+const discordContentLimit = 2000
+
+// discordInteraction Discord 交互请求，/ask question:<问题> 命令
+type discordInteraction struct {
+	Type      int    `json:"type"`
+	Token     string `json:"token"`
+	ChannelID string `json:"channel_id"`
+	Data      struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+// question 返回命令的 question 参数
+func (i *discordInteraction) question() string {
+	for _, option := range i.Data.Options {
+		if option.Name == "question" {
+			if value, ok := option.Value.(string); ok {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+	return ""
+}
+
+// handleDiscordInteractions 处理 Discord 交互端点：先延迟响应，回答生成后编辑原消息
+func (h *Handler) handleDiscordInteractions(w http.ResponseWriter, r *http.Request) {
+	if h.bots == nil || h.bots.DiscordPublicKey == "" {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Discord integration not configured",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+	if err := verifyDiscordSignature(h.bots.DiscordPublicKey, r.Header, body); err != nil {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid signature",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	switch interaction.Type {
+	case discordInteractionPing:
+		render.JSON(w, r, map[string]interface{}{"type": discordResponsePong})
+	case discordInteractionCommand:
+		channel := h.botChannelFor(r.Context(), BotPlatformDiscord, interaction.ChannelID)
+		question := interaction.question()
+		if channel == nil || question == "" {
+			content := "Ask me a question about this project's documents."
+			if channel == nil {
+				content = "This channel is not connected to a MetaBase project."
+			}
+			render.JSON(w, r, map[string]interface{}{
+				"type": discordResponseMessage,
+				"data": map[string]interface{}{"content": content, "flags": discordFlagEphemeral},
+			})
+			return
+		}
+
+		go h.answerDiscordInteraction(interaction, channel, question)
+		render.JSON(w, r, map[string]interface{}{"type": discordResponseDeferredMessage})
+	default:
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Unsupported interaction type",
+		})
+	}
+}
+
+// answerDiscordInteraction 回答提问，引用作为链接预览（embed）附在回答后
+func (h *Handler) answerDiscordInteraction(interaction discordInteraction, channel *BotChannel, question string) {
+	ctx, cancel := context.WithTimeout(context.Background(), botAnswerTimeout)
+	defer cancel()
+
+	result, err := h.answerInChannel(ctx, channel, question)
+	if err != nil {
+		h.logger.Error("discord bot query failed", zap.String("project_id", channel.ProjectID), zap.Error(err))
+	}
+	message := map[string]interface{}{
+		"content": truncateText(fmt.Sprintf("> %s\n\n%s", question, botAnswerText(result, err)), discordContentLimit),
+	}
+	if err == nil {
+		embeds := []map[string]interface{}{}
+		for _, citation := range channel.citations(result.Sources) {
+			embed := map[string]interface{}{
+				"title":       truncateText(citation.Title, 256),
+				"description": citation.Excerpt,
+			}
+			if citation.URL != "" {
+				embed["url"] = citation.URL
+			}
+			embeds = append(embeds, embed)
+		}
+		message["embeds"] = embeds
+	}
+
+	if err := h.editDiscordResponse(ctx, interaction.Token, message); err != nil {
+		h.logger.Error("failed to post discord message", zap.String("channel_id", interaction.ChannelID), zap.Error(err))
+	}
+}
+
+// editDiscordResponse 编辑交互的延迟响应消息
+func (h *Handler) editDiscordResponse(ctx context.Context, token string, message map[string]interface{}) error {
+	apiURL := h.bots.DiscordAPIURL
+	if apiURL == "" {
+		apiURL = defaultDiscordAPIURL
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/webhooks/%s/%s/messages/@original",
+		apiURL, url.PathEscape(h.bots.DiscordApplicationID), url.PathEscape(token))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := botHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("discord API error (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// verifyDiscordSignature 校验 Discord 请求的 Ed25519 签名，签名内容为时间戳加请求体
+func verifyDiscordSignature(publicKey string, header http.Header, body []byte) error {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key")
+	}
+	signature, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("missing signature")
+	}
+
+	message := append([]byte(header.Get("X-Signature-Timestamp")), body...)
+	if !ed25519.Verify(ed25519.PublicKey(key), message, signature) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
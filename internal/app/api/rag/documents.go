@@ -0,0 +1,210 @@
+package rag
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDocumentPageSize = 50
+	maxDocumentPageSize     = 500
+	maxDocumentExcerpt      = 300
+)
+
+// documentSummary 文档浏览列表中的条目，不返回全文
+type documentSummary struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	URI          string    `json:"uri"`
+	SourceType   string    `json:"source_type"`
+	DataSourceID string    `json:"data_source_id"`
+	FileType     string    `json:"file_type,omitempty"`
+	FilePath     string    `json:"file_path,omitempty"`
+	Author       string    `json:"author,omitempty"`
+	Language     string    `json:"language,omitempty"`
+	Tags         []string  `json:"tags,omitempty"`
+	Categories   []string  `json:"categories,omitempty"`
+	Length       int       `json:"length"`
+	WordCount    int       `json:"word_count"`
+	Version      int       `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	ModifiedAt   time.Time `json:"modified_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	Excerpt      string    `json:"excerpt,omitempty"`
+}
+
+// handleListDocuments 浏览项目已索引的文档，支持全文搜索、排序、偏移或游标分页、过滤条件，
+// 并返回按数据源、文件类型、语言和标签统计的分面计数
+//
+// 查询参数：q、sort_by、sort_order、limit、offset、cursor、filter（过滤表达式）、
+// source、type、tag、category、author（可重复）、lang、facets（逗号分隔）
+func (h *Handler) handleListDocuments(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	options, err := parseBrowseOptions(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid document query",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 限定在项目的数据源内；请求中的数据源作为额外条件，避免越过项目范围
+	sources, err := h.projectDataSourceIDs(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to list project data sources", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list documents",
+			"details": err.Error(),
+		})
+		return
+	}
+	if requested := r.URL.Query()["source"]; len(requested) > 0 {
+		values := make([]interface{}, len(requested))
+		for i, id := range requested {
+			values[i] = id
+		}
+		options.Filter.Expression = core.AndFilterExprs(options.Filter.Expression,
+			&core.FilterExpr{Field: "source", Op: core.FilterOpIn, Value: values})
+	}
+	options.Filter.DataSourceIDs = sources
+
+	page, err := h.pipeline.BrowseDocuments(r.Context(), options)
+	if err != nil {
+		h.logger.Error("failed to browse documents", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list documents",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	terms := strings.Fields(strings.ToLower(options.Query))
+	data := make([]documentSummary, 0, len(page.Documents))
+	for i := range page.Documents {
+		data = append(data, toDocumentSummary(&page.Documents[i], terms))
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data":        data,
+		"total":       page.Total,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"next_cursor": page.NextCursor,
+		"facets":      page.Facets,
+	})
+}
+
+// parseBrowseOptions 解析文档浏览的查询参数
+func parseBrowseOptions(r *http.Request) (core.BrowseOptions, error) {
+	query := r.URL.Query()
+	options := core.BrowseOptions{
+		Query:  strings.TrimSpace(query.Get("q")),
+		Cursor: query.Get("cursor"),
+	}
+	options.SortBy = query.Get("sort_by")
+	options.SortOrder = query.Get("sort_order")
+
+	options.Limit = defaultDocumentPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxDocumentPageSize {
+			return options, fmt.Errorf("limit must be between 1 and %d", maxDocumentPageSize)
+		}
+		options.Limit = n
+	}
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return options, fmt.Errorf("invalid offset %q", value)
+		}
+		options.Offset = n
+	}
+
+	if value := query.Get("filter"); value != "" {
+		expr, err := core.ParseFilterExpression(value)
+		if err != nil {
+			return options, fmt.Errorf("invalid filter: %w", err)
+		}
+		options.Filter.Expression = expr
+	}
+	options.Filter.FileTypes = query["type"]
+	options.Filter.Tags = query["tag"]
+	options.Filter.Categories = query["category"]
+	options.Filter.Authors = query["author"]
+	options.Filter.Language = query.Get("lang")
+
+	if value := query.Get("facets"); value != "" {
+		for _, facet := range strings.Split(value, ",") {
+			if facet = strings.TrimSpace(facet); facet != "" {
+				options.Facets = append(options.Facets, facet)
+			}
+		}
+	}
+
+	return options, options.Validate()
+}
+
+func toDocumentSummary(doc *core.Document, terms []string) documentSummary {
+	return documentSummary{
+		ID:           doc.ID,
+		Title:        doc.Title,
+		URI:          doc.URI,
+		SourceType:   doc.SourceType,
+		DataSourceID: doc.DataSourceID,
+		FileType:     doc.Metadata.FileType,
+		FilePath:     doc.Metadata.FilePath,
+		Author:       doc.Metadata.Author,
+		Language:     doc.Language,
+		Tags:         doc.Tags,
+		Categories:   doc.Categories,
+		Length:       len(doc.Content),
+		WordCount:    doc.Metadata.WordCount,
+		Version:      doc.Version,
+		CreatedAt:    doc.Metadata.CreatedAt,
+		ModifiedAt:   doc.Metadata.ModifiedAt,
+		UpdatedAt:    doc.UpdatedAt,
+		Excerpt:      documentExcerpt(doc.Content, terms, maxDocumentExcerpt),
+	}
+}
+
+// documentExcerpt 截取首个搜索词附近的内容，没有搜索词时截取开头
+func documentExcerpt(content string, terms []string, limit int) string {
+	lower := strings.ToLower(content)
+	for _, term := range terms {
+		i := strings.Index(lower, term)
+		if i < 0 || len(lower) != len(content) {
+			continue
+		}
+		// 从匹配位置前约三分之一窗口处开始，并对齐到字符边界
+		start := max(i-limit/3, 0)
+		for start > 0 && !utf8.RuneStart(content[start]) {
+			start--
+		}
+		if start == 0 {
+			break
+		}
+		return "…" + truncateText(content[start:], limit-1)
+	}
+	return truncateText(content, limit)
+}
@@ -0,0 +1,46 @@
+package rag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseBrowseOptions(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/projects/p1/documents?q=refund&sort_by=title&sort_order=asc&limit=10&tag=billing&tag=faq&lang=en&filter=type:pdf&facets=tag,lang", nil)
+	options, err := parseBrowseOptions(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Query != "refund" || options.Limit != 10 || options.SortBy != "title" || len(options.Filter.Tags) != 2 ||
+		options.Filter.Language != "en" || options.Filter.Expression == nil || strings.Join(options.Facets, ",") != "tag,lang" {
+		t.Fatalf("unexpected options %+v", options)
+	}
+
+	for _, query := range []string{"limit=0", "offset=-1", "sort_by=score", "facets=owner", "filter=tag:(", "cursor=bm90LWpzb24"} {
+		req := httptest.NewRequest(http.MethodGet, "/projects/p1/documents?"+query, nil)
+		if _, err := parseBrowseOptions(req); err == nil {
+			t.Fatalf("expected %s to be rejected", query)
+		}
+	}
+
+	// Without a pipeline there is nothing to browse
+	_, router := newBotTestHandler(t, nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/projects/p1/documents", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without pipeline, got %d", rec.Code)
+	}
+}
+
+func TestDocumentExcerpt(t *testing.T) {
+	content := strings.Repeat("filler ", 100) + "the refund policy" + strings.Repeat(" tail", 100)
+	excerpt := documentExcerpt(content, []string{"refund"}, 120)
+	if !strings.HasPrefix(excerpt, "…") || !strings.Contains(excerpt, "refund policy") {
+		t.Fatalf("expected excerpt around the match, got %q", excerpt)
+	}
+	if excerpt := documentExcerpt(content, nil, 20); !strings.HasPrefix(excerpt, "filler") {
+		t.Fatalf("expected leading excerpt, got %q", excerpt)
+	}
+}
@@ -0,0 +1,441 @@
+package rag
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/app/api/middleware"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/infra/signedurl"
+)
+
+const (
+	// DownloadsPath 签名下载地址的路由前缀
+	DownloadsPath = "/public/v1/downloads"
+
+	defaultDownloadTTL = 15 * time.Minute
+	maxDownloadTTL     = 7 * 24 * time.Hour
+
+	// presignTTL 重定向到对象存储的预签名地址有效期，只需覆盖重定向本身
+	presignTTL = time.Minute
+
+	// 可下载文件的类型
+	downloadKindOriginal = "original" // 上传或导入的原始文档
+	downloadKindExport   = "export"   // 索引快照的导出归档
+)
+
+// errDownloadUsed 单次使用的下载地址已被使用
+var errDownloadUsed = errors.New("download link has already been used")
+
+// DownloadConfig 签名下载配置
+type DownloadConfig struct {
+	// 签名密钥，为空时不保存原始文档也不提供下载；多实例部署需使用相同密钥
+	SigningKey string `json:"-"`
+
+	// 签名地址的外部地址前缀，如 https://api.example.com；为空时返回相对路径
+	BaseURL string `json:"base_url,omitempty"`
+}
+
+// DownloadConfigFromEnv 从环境变量读取签名下载配置
+func DownloadConfigFromEnv() *DownloadConfig {
+	cfg := &DownloadConfig{
+		SigningKey: os.Getenv("METABASE_DOWNLOAD_SIGNING_KEY"),
+		BaseURL:    strings.TrimSuffix(os.Getenv("METABASE_DOWNLOAD_BASE_URL"), "/"),
+	}
+	return cfg
+}
+
+// DownloadFile 可通过签名地址下载的文件
+type DownloadFile struct {
+	Key         string    `json:"key"`
+	ProjectID   string    `json:"project_id"`
+	Kind        string    `json:"kind"`
+	RefID       string    `json:"ref_id"` // 文档 ID 或快照 ID
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DownloadLink 签名下载地址
+type DownloadLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	BoundIP   string    `json:"bound_ip,omitempty"`
+	SingleUse bool      `json:"single_use"`
+}
+
+// downloadLinkRequest 生成下载地址请求
+type downloadLinkRequest struct {
+	TTLSeconds int  `json:"ttl_seconds"` // 默认 15 分钟，最长 7 天
+	BindIP     bool `json:"bind_ip"`     // 只允许请求方当前地址下载
+	SingleUse  bool `json:"single_use"`  // 只能下载一次
+}
+
+// originalKey 原始文档的对象键，文档 ID 可能包含任意字符，取摘要
+func originalKey(projectID, documentID string) string {
+	sum := sha256.Sum256([]byte(documentID))
+	return "originals/" + projectID + "/" + hex.EncodeToString(sum[:16])
+}
+
+// exportKey 快照导出归档的对象键
+func exportKey(projectID, snapshotID string) string {
+	return "exports/" + projectID + "/" + snapshotID + ".json.gz"
+}
+
+// SaveDownloadFile 保存可下载文件的记录，同一对象键覆盖旧记录
+func (m *Manager) SaveDownloadFile(ctx context.Context, file *DownloadFile) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO rag_download_files (object_key, project_id, kind, ref_id, file_name, content_type, size, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (object_key) DO UPDATE SET
+			file_name = excluded.file_name, content_type = excluded.content_type,
+			size = excluded.size, created_at = excluded.created_at`,
+		file.Key, file.ProjectID, file.Kind, file.RefID, file.FileName, file.ContentType, file.Size, file.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save download file: %w", err)
+	}
+	return nil
+}
+
+// GetDownloadFile 获取可下载文件的记录，不存在时返回 nil
+func (m *Manager) GetDownloadFile(ctx context.Context, key string) (*DownloadFile, error) {
+	var file DownloadFile
+	err := m.db.QueryRowContext(ctx, `
+		SELECT object_key, project_id, kind, ref_id, file_name, content_type, size, created_at
+		FROM rag_download_files WHERE object_key = ?`,
+		key,
+	).Scan(&file.Key, &file.ProjectID, &file.Kind, &file.RefID, &file.FileName, &file.ContentType, &file.Size, &file.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get download file: %w", err)
+	}
+	return &file, nil
+}
+
+// DeleteDownloadFile 删除可下载文件的记录
+func (m *Manager) DeleteDownloadFile(ctx context.Context, key string) error {
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM rag_download_files WHERE object_key = ?`, key); err != nil {
+		return fmt.Errorf("failed to delete download file: %w", err)
+	}
+	return nil
+}
+
+// UseDownloadToken 记录单次使用下载地址的使用，已使用过时返回 errDownloadUsed。
+// 记录保留到地址过期，之后顺带清理
+func (m *Manager) UseDownloadToken(ctx context.Context, nonce string, expiresAt time.Time) error {
+	result, err := m.db.ExecContext(ctx, `
+		INSERT INTO rag_download_tokens (nonce, expires_at) VALUES (?, ?)
+		ON CONFLICT (nonce) DO NOTHING`,
+		nonce, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record download token: %w", err)
+	}
+	if inserted, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to record download token: %w", err)
+	} else if inserted == 0 {
+		return errDownloadUsed
+	}
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM rag_download_tokens WHERE expires_at < ?`, time.Now()); err != nil {
+		m.logger.Warn("failed to clean up download tokens", zap.Error(err))
+	}
+	return nil
+}
+
+// SetDownloads 启用原始文档保存和签名下载，原始文档和导出归档保存在 store 中；
+// cfg 未配置签名密钥时不启用
+func (h *Handler) SetDownloads(cfg *DownloadConfig, store blobstore.Store) {
+	if cfg == nil || cfg.SigningKey == "" || store == nil {
+		return
+	}
+	h.downloads = cfg
+	h.objects = store
+	h.signer = signedurl.New([]byte(cfg.SigningKey))
+}
+
+// saveOriginal 保存导入文档的原始内容，超出租户存储限制或保存失败只记录日志
+func (h *Handler) saveOriginal(ctx context.Context, input *ingestInput) {
+	if h.objects == nil || len(input.content) == 0 {
+		return
+	}
+	job := input.job
+	fileName := ingestFileName(job)
+	if fileName == "/" || fileName == "." {
+		fileName = "document"
+	}
+	limits, err := h.blobLimits(ctx, job.ProjectID)
+	if err != nil {
+		h.logger.Warn("failed to load storage limits", zap.String("project_id", job.ProjectID), zap.Error(err))
+	}
+	key := originalKey(job.ProjectID, job.DocumentID)
+	info, err := blobstore.PutLimited(ctx, h.objects, key, fileName, bytes.NewReader(input.content), input.contentType, limits)
+	if errors.Is(err, blobstore.ErrTooLarge) || errors.Is(err, blobstore.ErrTypeNotAllowed) {
+		h.logger.Info("original document not stored", zap.String("document_id", job.DocumentID), zap.Error(err))
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to store original document", zap.String("document_id", job.DocumentID), zap.Error(err))
+		return
+	}
+	err = h.manager.SaveDownloadFile(ctx, &DownloadFile{
+		Key:         key,
+		ProjectID:   job.ProjectID,
+		Kind:        downloadKindOriginal,
+		RefID:       job.DocumentID,
+		FileName:    fileName,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		h.logger.Error("failed to save original document", zap.String("document_id", job.DocumentID), zap.Error(err))
+	}
+}
+
+// handleDocumentDownloadURL 生成原始文档的签名下载地址
+func (h *Handler) handleDocumentDownloadURL(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	h.downloadLink(w, r, originalKey(projectID, chi.URLParam(r, "documentId")), nil)
+}
+
+// handleSnapshotDownloadURL 生成快照导出归档的签名下载地址，首次请求时导出
+func (h *Handler) handleSnapshotDownloadURL(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	snapshotID := chi.URLParam(r, "snapshotId")
+	h.downloadLink(w, r, exportKey(projectID, snapshotID), func(ctx context.Context) (*DownloadFile, error) {
+		return h.exportSnapshot(ctx, projectID, snapshotID)
+	})
+}
+
+// downloadLink 为对象键签名，文件不存在时调用 create 生成，create 为空或返回 nil 时报告不存在
+func (h *Handler) downloadLink(w http.ResponseWriter, r *http.Request, key string, create func(ctx context.Context) (*DownloadFile, error)) {
+	if h.signer == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Signed downloads not configured",
+		})
+		return
+	}
+
+	var req downloadLinkRequest
+	if r.ContentLength > 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if ttl == 0 {
+		ttl = defaultDownloadTTL
+	}
+	if ttl < 0 || ttl > maxDownloadTTL {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": fmt.Sprintf("ttl_seconds must be between 1 and %d", int(maxDownloadTTL.Seconds())),
+		})
+		return
+	}
+
+	file, err := h.manager.GetDownloadFile(r.Context(), key)
+	if err == nil && file == nil && create != nil {
+		file, err = create(r.Context())
+	}
+	if err != nil {
+		h.logger.Error("failed to prepare download", zap.String("key", key), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to prepare download",
+			"details": err.Error(),
+		})
+		return
+	}
+	if file == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "File not found",
+		})
+		return
+	}
+
+	options := signedurl.Options{TTL: ttl, SingleUse: req.SingleUse}
+	if req.BindIP {
+		options.IP = middleware.ClientIP(r)
+	}
+	signed, token := h.signer.Sign(DownloadsPath+"/"+key, options)
+	w.Header().Set("Cache-Control", "no-store")
+	render.JSON(w, r, map[string]interface{}{
+		"data": DownloadLink{
+			URL:       h.downloads.BaseURL + signed,
+			ExpiresAt: token.ExpiresAt,
+			BoundIP:   token.IP,
+			SingleUse: req.SingleUse,
+		},
+	})
+}
+
+// exportSnapshot 将索引快照导出为 gzip 压缩的 JSON 归档并写入对象存储，快照不存在时返回 nil
+func (h *Handler) exportSnapshot(ctx context.Context, projectID, snapshotID string) (*DownloadFile, error) {
+	snapshot, err := h.manager.GetIndexSnapshot(ctx, projectID, snapshotID)
+	if err != nil || snapshot == nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot export: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress snapshot export: %w", err)
+	}
+
+	file := &DownloadFile{
+		Key:         exportKey(projectID, snapshotID),
+		ProjectID:   projectID,
+		Kind:        downloadKindExport,
+		RefID:       snapshotID,
+		FileName:    "snapshot-" + snapshotID + ".json.gz",
+		ContentType: "application/gzip",
+		Size:        int64(buf.Len()),
+		CreatedAt:   time.Now(),
+	}
+	if _, err := h.objects.Put(ctx, file.Key, &buf, file.ContentType); err != nil {
+		return nil, fmt.Errorf("failed to store snapshot export: %w", err)
+	}
+	if err := h.manager.SaveDownloadFile(ctx, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// deleteExport 删除快照的导出归档，失败只记录日志
+func (h *Handler) deleteExport(ctx context.Context, projectID, snapshotID string) {
+	if h.objects == nil {
+		return
+	}
+	key := exportKey(projectID, snapshotID)
+	if err := h.objects.Delete(ctx, key); err != nil {
+		h.logger.Warn("failed to delete snapshot export", zap.String("key", key), zap.Error(err))
+		return
+	}
+	if err := h.manager.DeleteDownloadFile(ctx, key); err != nil {
+		h.logger.Warn("failed to delete snapshot export", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// RegisterDownloadRoutes 注册签名下载路由（挂载于 /public/v1/downloads，通过地址签名认证）
+func (h *Handler) RegisterDownloadRoutes(r chi.Router) {
+	r.Get("/*", h.handleDownload)
+}
+
+// handleDownload 校验签名后下载文件。对象存储支持预签名时重定向过去，否则直接输出文件，支持断点续传
+func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request) {
+	if h.signer == nil {
+		http.NotFound(w, r)
+		return
+	}
+	token, err := h.signer.Verify(r.URL.Path, r.URL.Query(), middleware.ClientIP(r))
+	switch {
+	case errors.Is(err, signedurl.ErrExpired):
+		h.downloadError(w, r, http.StatusGone, "Download link has expired", "expired")
+		return
+	case errors.Is(err, signedurl.ErrIPMismatch):
+		h.downloadError(w, r, http.StatusForbidden, "Download link is bound to another address", "ip_mismatch")
+		return
+	case err != nil:
+		h.downloadError(w, r, http.StatusForbidden, "Invalid download link", "invalid_signature")
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, DownloadsPath+"/")
+	file, err := h.manager.GetDownloadFile(r.Context(), key)
+	if err != nil {
+		h.logger.Error("failed to get download file", zap.String("key", key), zap.Error(err))
+		h.downloadError(w, r, http.StatusInternalServerError, "Failed to download file", "")
+		return
+	}
+	if file == nil {
+		h.downloadError(w, r, http.StatusNotFound, "File not found", "")
+		return
+	}
+
+	if token.Nonce != "" {
+		if err := h.manager.UseDownloadToken(r.Context(), token.Nonce, token.ExpiresAt); err != nil {
+			if errors.Is(err, errDownloadUsed) {
+				h.downloadError(w, r, http.StatusGone, "Download link has already been used", "already_used")
+				return
+			}
+			h.logger.Error("failed to record download token", zap.Error(err))
+			h.downloadError(w, r, http.StatusInternalServerError, "Failed to download file", "")
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	if presigner, ok := h.objects.(blobstore.Presigner); ok {
+		location, err := presigner.PresignGet(r.Context(), file.Key, file.FileName, presignTTL)
+		if err != nil {
+			h.logger.Error("failed to presign download", zap.String("key", key), zap.Error(err))
+			h.downloadError(w, r, http.StatusBadGateway, "Failed to download file", "")
+			return
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+		return
+	}
+
+	content, info, err := h.objects.Get(r.Context(), file.Key)
+	if err != nil {
+		h.logger.Error("failed to open download file", zap.String("key", key), zap.Error(err))
+		h.downloadError(w, r, http.StatusNotFound, "File not found", "")
+		return
+	}
+	defer content.Close()
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName}))
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, file.FileName, file.CreatedAt, seeker)
+		return
+	}
+	// 不支持随机读取的存储不提供断点续传
+	if info.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	io.Copy(w, content)
+}
+
+// downloadError 输出下载错误
+func (h *Handler) downloadError(w http.ResponseWriter, r *http.Request, status int, message, code string) {
+	body := map[string]interface{}{
+		"error": message,
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
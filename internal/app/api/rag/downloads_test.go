@@ -0,0 +1,193 @@
+package rag
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+type presigningStore struct {
+	*blobstore.LocalStore
+}
+
+func (s presigningStore) PresignGet(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	return "https://bucket.example.com/" + key + "?signature=x", nil
+}
+
+func newDownloadTestHandler(t *testing.T, store blobstore.Store) (*Handler, *chi.Mux) {
+	t.Helper()
+	h, router := newBotTestHandler(t, nil)
+	if store == nil {
+		files, err := blobstore.NewLocalStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		store = files
+	}
+	h.SetDownloads(&DownloadConfig{SigningKey: "secret"}, store)
+	router.Route(DownloadsPath, h.RegisterDownloadRoutes)
+	return h, router
+}
+
+func requestDownloadLink(t *testing.T, router http.Handler, path, body, remoteAddr string) (int, DownloadLink) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var resp struct {
+		Data DownloadLink `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec.Code, resp.Data
+}
+
+func download(router http.Handler, link, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, link, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOriginalDocumentDownloads(t *testing.T) {
+	h, router := newDownloadTestHandler(t, nil)
+	indexer := &fakeIndexer{docs: make(chan core.Document, 1)}
+	h.indexer = indexer
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "handbook.md")
+	part.Write([]byte("# Handbook\nWelcome."))
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/projects/p1/documents", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("upload failed: %d %s", rec.Code, rec.Body)
+	}
+	doc := <-indexer.docs
+
+	// The original is stored once indexing completes
+	linkPath := "/projects/p1/documents/" + doc.ID + "/download-url"
+	var code int
+	var link DownloadLink
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if code, link = requestDownloadLink(t, router, linkPath, `{"bind_ip":true,"single_use":true}`, "203.0.113.7:4000"); code != http.StatusNotFound {
+			break
+		}
+	}
+	if code != http.StatusOK || !link.SingleUse || link.BoundIP != "203.0.113.7" || !strings.HasPrefix(link.URL, DownloadsPath+"/originals/p1/") {
+		t.Fatalf("unexpected link %d %+v", code, link)
+	}
+
+	if rec := download(router, link.URL, "198.51.100.1:5000"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected another address to be rejected, got %d", rec.Code)
+	}
+	rec = download(router, link.URL, "203.0.113.7:5000")
+	if rec.Code != http.StatusOK || rec.Body.String() != "# Handbook\nWelcome." ||
+		!strings.Contains(rec.Header().Get("Content-Disposition"), `filename=handbook.md`) {
+		t.Fatalf("unexpected download %d %v %s", rec.Code, rec.Header(), rec.Body)
+	}
+	if rec := download(router, link.URL, "203.0.113.7:5000"); rec.Code != http.StatusGone {
+		t.Fatalf("expected a single-use link to be rejected the second time, got %d", rec.Code)
+	}
+
+	// Reusable links serve ranges repeatedly; tampered links are rejected
+	_, link = requestDownloadLink(t, router, linkPath, "", "203.0.113.7:4000")
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, link.URL, nil)
+		req.Header.Set("Range", "bytes=2-9")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusPartialContent || rec.Body.String() != "Handbook" {
+			t.Fatalf("unexpected range download %d %s", rec.Code, rec.Body)
+		}
+	}
+	other := strings.Replace(link.URL, "/originals/p1/", "/originals/p2/", 1)
+	if rec := download(router, other, "203.0.113.7:5000"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a tampered link to be rejected, got %d", rec.Code)
+	}
+
+	if code, _ := requestDownloadLink(t, router, "/projects/p1/documents/missing/download-url", "", "203.0.113.7:4000"); code != http.StatusNotFound {
+		t.Fatalf("expected documents without an original to be missing, got %d", code)
+	}
+	if code, _ := requestDownloadLink(t, router, linkPath, `{"ttl_seconds":99999999}`, "203.0.113.7:4000"); code != http.StatusBadRequest {
+		t.Fatalf("expected an excessive ttl to be rejected, got %d", code)
+	}
+}
+
+func TestSnapshotExportDownloads(t *testing.T) {
+	files, err := blobstore.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, router := newDownloadTestHandler(t, presigningStore{files})
+	snapshot := &core.IndexSnapshot{
+		ID:        "s1",
+		ProjectID: "p1",
+		Reason:    core.SnapshotManual,
+		CreatedAt: time.Now(),
+		Documents: []core.Document{{ID: "d1", Content: "hello"}},
+	}
+	if err := h.manager.SaveIndexSnapshot(context.Background(), snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	code, link := requestDownloadLink(t, router, "/projects/p1/rag/snapshots/s1/download-url", "", "203.0.113.7:4000")
+	if code != http.StatusOK {
+		t.Fatalf("unexpected link %d %+v", code, link)
+	}
+	// Stores that presign serve the file themselves
+	rec := download(router, link.URL, "203.0.113.7:5000")
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://bucket.example.com/exports/p1/s1.json.gz?signature=x" {
+		t.Fatalf("expected a redirect to the object store, got %d %v", rec.Code, rec.Header())
+	}
+
+	content, _, err := files.Get(context.Background(), exportKey("p1", "s1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+	zr, err := gzip.NewReader(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(zr)
+	var exported core.IndexSnapshot
+	if err := json.Unmarshal(data, &exported); err != nil || len(exported.Documents) != 1 || exported.Documents[0].Content != "hello" {
+		t.Fatalf("unexpected export %s", data)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/projects/p1/rag/snapshots/s1", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if rec := download(router, link.URL, "203.0.113.7:5000"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the export to be removed with its snapshot, got %d", rec.Code)
+	}
+
+	// Exports of deleted snapshots are no longer referenced and their blobs become garbage
+	orphan := &DownloadFile{Key: exportKey("p1", "s2"), ProjectID: "p1", Kind: downloadKindExport, RefID: "s2", CreatedAt: time.Now()}
+	if err := h.manager.SaveDownloadFile(context.Background(), orphan); err != nil {
+		t.Fatal(err)
+	}
+	if pruned, err := h.manager.pruneDownloadFiles(context.Background()); err != nil || pruned != 1 {
+		t.Fatalf("expected the orphaned export to be pruned, got %d %v", pruned, err)
+	}
+	if keys, err := h.manager.downloadFileKeys(context.Background()); err != nil || len(keys) != 0 {
+		t.Fatalf("unexpected referenced keys %v %v", keys, err)
+	}
+}
@@ -0,0 +1,200 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+// maxFederatedProjects 单次联合查询最多覆盖的项目数
+const maxFederatedProjects = 20
+
+// federatedQueryRequest 跨项目联合查询请求
+type federatedQueryRequest struct {
+	Query string `json:"query"`
+
+	// 查询的项目，为空时查询调用者可访问的全部项目
+	ProjectIDs []string `json:"project_ids,omitempty"`
+
+	// 每个项目最多贡献的结果数，默认平分 max_results，剩余名额由其他项目的高分结果补足
+	PerProjectQuota int `json:"per_project_quota,omitempty"`
+
+	Filter     string           `json:"filter,omitempty"`
+	FilterExpr *core.FilterExpr `json:"filter_expr,omitempty"`
+
+	Options core.QueryOptions `json:"options"`
+}
+
+// SetProjectAccess 设置项目访问检查：返回用户可查看的项目，projectIDs 为空时返回用户所属的全部项目
+func (h *Handler) SetProjectAccess(fn func(ctx context.Context, userID string, projectIDs []string) ([]string, error)) {
+	h.projectAccess = fn
+}
+
+// RegisterFederationRoutes 注册跨项目路由（挂载于 /admin/v1/federation，登录用户，按项目检查查看权限）
+func (h *Handler) RegisterFederationRoutes(r chi.Router) {
+	r.Post("/query", h.handleFederatedQuery)
+}
+
+// handleFederatedQuery 在调用者可访问的多个项目中检索，按项目配额合并重排后生成回答，引用标注来源项目
+func (h *Handler) handleFederatedQuery(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req federatedQueryRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.Query == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Query is required",
+		})
+		return
+	}
+	if len(req.ProjectIDs) > maxFederatedProjects {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Too many projects",
+		})
+		return
+	}
+
+	options := req.Options
+	options.ProjectID = ""
+	if err := mergeFilters(&options, req.Filter, req.FilterExpr); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid filter",
+			"details": err.Error(),
+		})
+		return
+	}
+	userID, _ := r.Context().Value("user_id").(string)
+	options.UserID = userID
+
+	projects, err := h.federatedProjects(r.Context(), userID, req.ProjectIDs)
+	var denied *projectAccessError
+	if errors.As(err, &denied) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]interface{}{
+			"error":    "Access denied to projects",
+			"projects": denied.projects,
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to resolve federated projects", zap.String("user_id", userID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to resolve projects",
+			"details": err.Error(),
+		})
+		return
+	}
+	if len(projects) == 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "No accessible projects to query",
+		})
+		return
+	}
+	options.Federation = &core.FederationOptions{
+		Projects:        projects,
+		PerProjectQuota: req.PerProjectQuota,
+	}
+
+	result, err := h.pipeline.Query(r.Context(), req.Query, options)
+	if errors.Is(err, core.ErrBudgetExceeded) {
+		render.Status(r, http.StatusPaymentRequired)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "LLM budget exceeded",
+			"code":    "budget_exceeded",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("federated rag query failed", zap.Int("projects", len(projects)), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Query failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": result,
+	})
+}
+
+// projectAccessError 请求的项目中有调用者无权查看的项目
+type projectAccessError struct {
+	projects []string
+}
+
+func (e *projectAccessError) Error() string {
+	return "access denied to projects"
+}
+
+// federatedProjects 检查访问权限并列出各项目的数据源（含上传文档）
+func (h *Handler) federatedProjects(ctx context.Context, userID string, requested []string) ([]core.FederatedProject, error) {
+	if h.projectAccess == nil {
+		return nil, errors.New("project access check not configured")
+	}
+	allowed, err := h.projectAccess(ctx, userID, requested)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(requested) > 0 {
+		permitted := make(map[string]bool, len(allowed))
+		for _, id := range allowed {
+			permitted[id] = true
+		}
+		var denied []string
+		for _, id := range requested {
+			if !permitted[id] {
+				denied = append(denied, id)
+			}
+		}
+		if len(denied) > 0 {
+			return nil, &projectAccessError{projects: denied}
+		}
+	}
+	if len(allowed) > maxFederatedProjects {
+		allowed = allowed[:maxFederatedProjects]
+	}
+
+	projects := make([]core.FederatedProject, 0, len(allowed))
+	seen := make(map[string]bool, len(allowed))
+	for _, projectID := range allowed {
+		if seen[projectID] {
+			continue
+		}
+		seen[projectID] = true
+
+		sources, err := h.projectDataSourceIDs(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, core.FederatedProject{ProjectID: projectID, DataSourceIDs: sources})
+	}
+	return projects, nil
+}
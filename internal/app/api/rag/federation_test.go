@@ -0,0 +1,67 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFederatedProjects(t *testing.T) {
+	ctx := context.Background()
+	h, _ := newBotTestHandler(t, nil)
+
+	if _, err := h.federatedProjects(ctx, "u1", nil); err == nil {
+		t.Fatalf("expected error without an access check")
+	}
+
+	// u1 may view eng and ops
+	h.SetProjectAccess(func(ctx context.Context, userID string, projectIDs []string) ([]string, error) {
+		viewable := map[string]bool{"eng": true, "ops": true}
+		if len(projectIDs) == 0 {
+			return []string{"eng", "ops"}, nil
+		}
+		var allowed []string
+		for _, id := range projectIDs {
+			if viewable[id] {
+				allowed = append(allowed, id)
+			}
+		}
+		return allowed, nil
+	})
+	wiki := &DataSource{ProjectID: "eng", Name: "Wiki", Type: "filesystem", Config: map[string]interface{}{"path": "/docs/wiki"}}
+	if err := h.manager.CreateDataSource(ctx, wiki); err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := h.federatedProjects(ctx, "u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 || projects[0].ProjectID != "eng" || len(projects[0].DataSourceIDs) != 2 || projects[0].DataSourceIDs[1] != wiki.ID {
+		t.Fatalf("unexpected projects %+v", projects)
+	}
+	if projects[1].DataSourceIDs[0] != uploadDataSourceID("ops") {
+		t.Fatalf("expected uploads to be searched, got %+v", projects[1])
+	}
+
+	_, err = h.federatedProjects(ctx, "u1", []string{"eng", "hr"})
+	var denied *projectAccessError
+	if !errors.As(err, &denied) || len(denied.projects) != 1 || denied.projects[0] != "hr" {
+		t.Fatalf("expected hr to be denied, got %v", err)
+	}
+}
+
+func TestFederatedQueryWithoutPipeline(t *testing.T) {
+	h, r := newBotTestHandler(t, nil)
+	r.Route("/federation", h.RegisterFederationRoutes)
+
+	req := httptest.NewRequest(http.MethodPost, "/federation/query", strings.NewReader(`{"query":"deploy"}`))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without pipeline, got %d", rec.Code)
+	}
+}
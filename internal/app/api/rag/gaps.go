@@ -0,0 +1,491 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// 内容缺口建议类型
+const (
+	GapActionIndexContent     = "index_content"      // 语料中没有相关内容，建议导入
+	GapActionEnableDataSource = "enable_data_source" // 相关数据源已禁用
+	GapActionFixDataSource    = "fix_data_source"    // 相关数据源最近一次同步失败
+	GapActionRetryUpload      = "retry_upload"       // 相关文档导入失败
+	GapActionImproveContent   = "improve_content"    // 已有相关内容但检索得分低
+)
+
+const (
+	// defaultGapMinQuestions 一个主题至少有多少个缺口问题才给出建议
+	defaultGapMinQuestions = 3
+
+	// maxGapRecommendations 每个项目最多给出的建议数
+	maxGapRecommendations = 10
+
+	// maxGapSamples 每条建议列出的示例问题数
+	maxGapSamples = 5
+
+	// gapCorpusJobs 与主题匹配的最近导入任务数
+	gapCorpusJobs = 500
+
+	// contentGapInterval 定期任务重新生成建议的间隔
+	contentGapInterval = 24 * time.Hour
+
+	// contentGapCheckInterval 定期任务检查过期建议的间隔
+	contentGapCheckInterval = time.Hour
+)
+
+// gapStopwords 提取主题时忽略的常见词
+var gapStopwords = map[string]bool{
+	"about": true, "after": true, "all": true, "and": true, "any": true, "are": true, "can": true,
+	"could": true, "does": true, "for": true, "from": true, "get": true, "has": true,
+	"have": true, "how": true, "into": true, "its": true, "not": true, "our": true, "should": true,
+	"that": true, "the": true, "their": true, "there": true, "this": true, "use": true, "using": true,
+	"was": true, "what": true, "when": true, "where": true, "which": true, "who": true, "why": true,
+	"will": true, "with": true, "would": true, "you": true, "your": true, "need": true, "want": true,
+	"如何": true, "怎么": true, "什么": true, "为什": true, "哪里": true, "可以": true,
+	"是否": true, "怎样": true, "我们": true, "如果": true,
+}
+
+// ContentGapReport 项目的内容缺口分析与导入建议
+type ContentGapReport struct {
+	ProjectID       string              `json:"project_id"`
+	Since           time.Time           `json:"since"`
+	GeneratedAt     time.Time           `json:"generated_at"`
+	GapQueries      int64               `json:"gap_queries"` // 无结果或低分的查询数
+	Recommendations []GapRecommendation `json:"recommendations"`
+}
+
+// GapRecommendation 一个主题的缺口和建议
+type GapRecommendation struct {
+	Topic           string      `json:"topic"`
+	Action          string      `json:"action"`
+	Questions       int64       `json:"questions"`    // 该主题下的缺口查询数
+	ZeroResults     int64       `json:"zero_results"` // 其中没有任何结果的查询数
+	SampleQuestions []string    `json:"sample_questions"`
+	Related         []GapSource `json:"related,omitempty"` // 语料中与主题相关的数据源或文档
+	Message         string      `json:"message"`
+}
+
+// GapSource 与缺口主题相关的数据源或导入文档
+type GapSource struct {
+	Type   string `json:"type"` // data_source 或 document
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"` // ok、disabled、sync_failed 或 failed
+}
+
+// GapOptions 内容缺口分析参数
+type GapOptions struct {
+	Since        time.Time
+	MinScore     float64 // 最高得分低于该值的查询视为缺口
+	MinQuestions int64   // 主题的最少缺口查询数
+}
+
+// gapQuery 一个缺口问题及其主题词
+type gapQuery struct {
+	query string
+	count int64
+	zero  int64
+	terms map[string]bool
+}
+
+// gapQueries 统计区间内无结果或低分的问题
+func (m *Manager) gapQueries(ctx context.Context, projectID string, since time.Time, minScore float64) ([]gapQuery, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT normalized_query, COUNT(*), SUM(CASE WHEN result_count = 0 THEN 1 ELSE 0 END)
+		FROM rag_query_records
+		WHERE project_id = ? AND created_at >= ? AND (result_count = 0 OR top_score < ?)
+		GROUP BY normalized_query ORDER BY COUNT(*) DESC, normalized_query`,
+		projectID, since.UTC(), minScore,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list gap queries: %w", err)
+	}
+	defer rows.Close()
+
+	var queries []gapQuery
+	for rows.Next() {
+		var q gapQuery
+		if err := rows.Scan(&q.query, &q.count, &q.zero); err != nil {
+			return nil, fmt.Errorf("failed to scan gap queries: %w", err)
+		}
+		q.terms = topicTerms(q.query)
+		queries = append(queries, q)
+	}
+	return queries, rows.Err()
+}
+
+// topicTerms 提取问题的主题词：去掉常见词和过短的词，英文词去掉复数 s，
+// 中文按相邻两字切分
+func topicTerms(query string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, field := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(field)
+		if unicode.Is(unicode.Han, runes[0]) {
+			for i := 0; i+1 < len(runes); i++ {
+				if term := string(runes[i : i+2]); !gapStopwords[term] {
+					terms[term] = true
+				}
+			}
+			continue
+		}
+		if len(runes) > 4 && strings.HasSuffix(field, "s") && !strings.HasSuffix(field, "ss") {
+			field = strings.TrimSuffix(field, "s")
+		}
+		if len(runes) < 3 || gapStopwords[field] {
+			continue
+		}
+		terms[field] = true
+	}
+	return terms
+}
+
+// gapTopic 一组共享主题词的缺口问题
+type gapTopic struct {
+	term    string
+	queries []gapQuery
+}
+
+// clusterGaps 反复取覆盖缺口查询最多的主题词，将包含该词的问题归入该主题
+func clusterGaps(queries []gapQuery, minQuestions int64) []gapTopic {
+	var topics []gapTopic
+	remaining := queries
+	for len(topics) < maxGapRecommendations {
+		weights := make(map[string]int64)
+		for _, q := range remaining {
+			for term := range q.terms {
+				weights[term] += q.count
+			}
+		}
+		best, weight := "", int64(0)
+		for term, w := range weights {
+			if w > weight || (w == weight && term < best) {
+				best, weight = term, w
+			}
+		}
+		if weight < minQuestions {
+			break
+		}
+
+		topic := gapTopic{term: best}
+		var rest []gapQuery
+		for _, q := range remaining {
+			if q.terms[best] {
+				topic.queries = append(topic.queries, q)
+			} else {
+				rest = append(rest, q)
+			}
+		}
+		topics = append(topics, topic)
+		remaining = rest
+	}
+	return topics
+}
+
+// gapCorpus 项目语料：数据源和导入文档
+type gapCorpus struct {
+	sources []GapSource
+	texts   []string // 与 sources 对应的可匹配文本
+}
+
+// loadGapCorpus 加载项目的数据源（名称、路径和包含规则）和导入文档（标题和来源）
+func (h *Handler) loadGapCorpus(ctx context.Context, projectID string) (*gapCorpus, error) {
+	corpus := &gapCorpus{}
+
+	sources, err := h.manager.ListDataSources(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ds := range sources {
+		status := "ok"
+		if !ds.Enabled {
+			status = "disabled"
+		} else if runs, err := h.manager.ListSyncRuns(ctx, ds.ID, 1); err != nil {
+			return nil, err
+		} else if len(runs) > 0 && runs[0].Status == SyncStatusFailed {
+			status = "sync_failed"
+		}
+
+		text := []string{ds.Name}
+		for _, value := range ds.Config {
+			if s, ok := value.(string); ok {
+				text = append(text, s)
+			}
+		}
+		text = append(text, ds.IncludePatterns...)
+		corpus.sources = append(corpus.sources, GapSource{Type: "data_source", ID: ds.ID, Name: ds.Name, Status: status})
+		corpus.texts = append(corpus.texts, strings.ToLower(strings.Join(text, " ")))
+	}
+
+	jobs, err := h.manager.ListIngestJobs(ctx, projectID, gapCorpusJobs)
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.DuplicateOf != "" {
+			continue
+		}
+		status := "ok"
+		if job.Status == IngestStatusFailed {
+			status = "failed"
+		} else if job.Status != IngestStatusCompleted {
+			continue
+		}
+		name := job.Title
+		if name == "" {
+			name = job.Source
+		}
+		corpus.sources = append(corpus.sources, GapSource{Type: "document", ID: job.DocumentID, Name: name, Status: status})
+		corpus.texts = append(corpus.texts, strings.ToLower(job.Title+" "+job.Source))
+	}
+	return corpus, nil
+}
+
+// related 返回语料中提及主题词的数据源和文档
+func (c *gapCorpus) related(term string) []GapSource {
+	var related []GapSource
+	for i, text := range c.texts {
+		if strings.Contains(text, term) {
+			related = append(related, c.sources[i])
+		}
+	}
+	return related
+}
+
+// recommend 根据相关语料的状态选择建议
+func recommend(topic gapTopic, related []GapSource) GapRecommendation {
+	rec := GapRecommendation{Topic: topic.term, Related: related}
+	for _, q := range topic.queries {
+		rec.Questions += q.count
+		rec.ZeroResults += q.zero
+		if len(rec.SampleQuestions) < maxGapSamples {
+			rec.SampleQuestions = append(rec.SampleQuestions, q.query)
+		}
+	}
+
+	byStatus := make(map[string]string)
+	for _, source := range related {
+		key := source.Type + ":" + source.Status
+		if _, ok := byStatus[key]; !ok {
+			byStatus[key] = source.Name
+		}
+	}
+	prefix := fmt.Sprintf("%d questions about %q had no good sources", rec.Questions, topic.term)
+	switch {
+	case byStatus["data_source:disabled"] != "":
+		rec.Action = GapActionEnableDataSource
+		rec.Message = fmt.Sprintf("%s — consider enabling the data source %q", prefix, byStatus["data_source:disabled"])
+	case byStatus["data_source:sync_failed"] != "":
+		rec.Action = GapActionFixDataSource
+		rec.Message = fmt.Sprintf("%s — the last sync of data source %q failed; fix it and sync again", prefix, byStatus["data_source:sync_failed"])
+	case byStatus["document:failed"] != "":
+		rec.Action = GapActionRetryUpload
+		rec.Message = fmt.Sprintf("%s — importing %q failed; consider uploading it again", prefix, byStatus["document:failed"])
+	case len(related) > 0:
+		name := related[0].Name
+		rec.Action = GapActionImproveContent
+		rec.Message = fmt.Sprintf("%s although %q mentions the topic — consider expanding it or adding an FAQ", prefix, name)
+	default:
+		rec.Action = GapActionIndexContent
+		rec.Message = fmt.Sprintf("%s — consider indexing documentation about %s", prefix, topic.term)
+	}
+	return rec
+}
+
+// DetectContentGaps 将无结果和低分的问题按主题聚合，结合项目语料生成导入建议
+func (h *Handler) DetectContentGaps(ctx context.Context, projectID string, opts GapOptions) (*ContentGapReport, error) {
+	if opts.MinQuestions <= 0 {
+		opts.MinQuestions = defaultGapMinQuestions
+	}
+	queries, err := h.manager.gapQueries(ctx, projectID, opts.Since, opts.MinScore)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ContentGapReport{
+		ProjectID:       projectID,
+		Since:           opts.Since.UTC(),
+		GeneratedAt:     time.Now(),
+		Recommendations: []GapRecommendation{},
+	}
+	for _, q := range queries {
+		report.GapQueries += q.count
+	}
+	topics := clusterGaps(queries, opts.MinQuestions)
+	if len(topics) == 0 {
+		return report, nil
+	}
+
+	corpus, err := h.loadGapCorpus(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, topic := range topics {
+		report.Recommendations = append(report.Recommendations, recommend(topic, corpus.related(topic.term)))
+	}
+	sort.SliceStable(report.Recommendations, func(i, j int) bool {
+		return report.Recommendations[i].Questions > report.Recommendations[j].Questions
+	})
+	return report, nil
+}
+
+// SaveContentGapReport 保存项目最近一次内容缺口分析
+func (m *Manager) SaveContentGapReport(ctx context.Context, report *ContentGapReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode content gap report: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_content_gaps (project_id, report, generated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(project_id) DO UPDATE SET
+			report = excluded.report,
+			generated_at = excluded.generated_at`,
+		report.ProjectID, string(data), report.GeneratedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save content gap report: %w", err)
+	}
+	return nil
+}
+
+// GetContentGapReport 获取项目最近一次内容缺口分析，不存在时返回 nil
+func (m *Manager) GetContentGapReport(ctx context.Context, projectID string) (*ContentGapReport, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx, `SELECT report FROM rag_content_gaps WHERE project_id = ?`, projectID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content gap report: %w", err)
+	}
+	var report ContentGapReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("failed to decode content gap report: %w", err)
+	}
+	return &report, nil
+}
+
+// defaultGapOptions 定期任务使用的参数
+func defaultGapOptions(now time.Time) GapOptions {
+	return GapOptions{
+		Since:        now.AddDate(0, 0, -defaultAnalyticsDays),
+		MinScore:     defaultLowScoreThreshold,
+		MinQuestions: defaultGapMinQuestions,
+	}
+}
+
+// runContentGaps 为近期有查询且建议已过期的项目重新生成内容缺口建议
+func (s *SyncScheduler) runContentGaps(ctx context.Context, now time.Time) {
+	if s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+	if now.Sub(s.lastGapCheck) < contentGapCheckInterval {
+		return
+	}
+	s.lastGapCheck = now
+
+	opts := defaultGapOptions(now)
+	projects, err := s.handler.manager.queriedProjects(ctx, opts.Since)
+	if err != nil {
+		s.logger.Error("failed to list projects for content gap detection", zap.Error(err))
+		return
+	}
+	for _, projectID := range projects {
+		existing, err := s.handler.manager.GetContentGapReport(ctx, projectID)
+		if err != nil {
+			s.logger.Warn("failed to load content gap report", zap.String("project_id", projectID), zap.Error(err))
+			continue
+		}
+		if existing != nil && now.Sub(existing.GeneratedAt) < contentGapInterval {
+			continue
+		}
+		report, err := s.handler.DetectContentGaps(ctx, projectID, opts)
+		if err == nil {
+			err = s.handler.manager.SaveContentGapReport(ctx, report)
+		}
+		if err != nil {
+			s.logger.Error("failed to detect content gaps", zap.String("project_id", projectID), zap.Error(err))
+			continue
+		}
+		if len(report.Recommendations) > 0 {
+			s.logger.Info("Content gaps detected",
+				zap.String("project_id", projectID),
+				zap.Int64("gap_queries", report.GapQueries),
+				zap.Int("recommendations", len(report.Recommendations)),
+			)
+		}
+	}
+}
+
+// gapOptions 解析 days、min_score、min_questions 查询参数
+func gapOptions(r *http.Request) GapOptions {
+	analytics := analyticsOptions(r)
+	minQuestions, _ := strconv.ParseInt(r.URL.Query().Get("min_questions"), 10, 64)
+	return GapOptions{
+		Since:        analytics.Since,
+		MinScore:     analytics.MinScore,
+		MinQuestions: minQuestions,
+	}
+}
+
+// handleGetContentGaps 获取定期任务生成的内容缺口建议，尚未生成时立即生成
+func (h *Handler) handleGetContentGaps(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	report, err := h.manager.GetContentGapReport(r.Context(), projectID)
+	if err == nil && report == nil {
+		report, err = h.DetectContentGaps(r.Context(), projectID, defaultGapOptions(time.Now()))
+		if err == nil {
+			err = h.manager.SaveContentGapReport(r.Context(), report)
+		}
+	}
+	if err != nil {
+		h.logger.Error("failed to get content gaps", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get content gaps",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": report,
+	})
+}
+
+// handleDetectContentGaps 按请求参数重新分析内容缺口；使用默认参数时同时更新保存的建议
+func (h *Handler) handleDetectContentGaps(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	report, err := h.DetectContentGaps(r.Context(), projectID, gapOptions(r))
+	if err == nil && r.URL.RawQuery == "" {
+		err = h.manager.SaveContentGapReport(r.Context(), report)
+	}
+	if err != nil {
+		h.logger.Error("failed to detect content gaps", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to detect content gaps",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": report,
+	})
+}
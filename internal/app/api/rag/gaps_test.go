@@ -0,0 +1,101 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestContentGaps(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+
+	store := func(query string, times int, scores ...float64) {
+		for i := 0; i < times; i++ {
+			result := &core.QueryResult{}
+			for _, score := range scores {
+				result.RetrievalResults = append(result.RetrievalResults, core.RetrievalResult{Score: score})
+			}
+			if err := h.manager.StoreQueryRecord(ctx, "p1", "", core.QueryRecord{Query: query, Result: result}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	store("How do refunds work?", 2)
+	store("Refund policy for annual plans", 2, 0.1)
+	store("Where are invoices stored?", 3)
+	store("Invoice PDF download", 1, 0.2)
+	store("SSO with Okta", 4, 0.15)
+	store("Deploy to kubernetes", 5, 0.9) // well answered
+	store("random question", 1)
+
+	billing := &DataSource{ProjectID: "p1", Name: "Billing runbook", Type: "filesystem", Config: map[string]interface{}{"path": "/docs/invoices"}}
+	if err := h.manager.CreateDataSource(ctx, billing); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.manager.SaveIngestJob(ctx, &IngestJob{ID: "j1", ProjectID: "p1", Source: "okta-sso-setup.pdf", DocumentID: "d1", Status: IngestStatusFailed, CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects/p1/rag/content-gaps", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("content gaps failed: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data ContentGapReport `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	report := resp.Data
+
+	if report.GapQueries != 13 || len(report.Recommendations) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	byTopic := make(map[string]GapRecommendation)
+	for _, r := range report.Recommendations {
+		byTopic[r.Topic] = r
+	}
+	if r := byTopic["refund"]; r.Action != GapActionIndexContent || r.Questions != 4 || r.ZeroResults != 2 || len(r.SampleQuestions) != 2 {
+		t.Fatalf("unexpected refund recommendation %+v", r)
+	}
+	if r := byTopic["invoice"]; r.Action != GapActionEnableDataSource || r.Questions != 4 || len(r.Related) != 1 || r.Related[0].ID != billing.ID {
+		t.Fatalf("unexpected invoice recommendation %+v", r)
+	}
+	if r := byTopic["okta"]; r.Action != GapActionRetryUpload || r.Questions != 4 || len(r.Related) != 1 || r.Related[0].Status != "failed" {
+		t.Fatalf("expected a retry recommendation for the failed upload, got %+v", report.Recommendations)
+	}
+
+	// The stored report is served until regenerated
+	store("Refund timeline", 3)
+	stored, err := h.manager.GetContentGapReport(ctx, "p1")
+	if err != nil || stored == nil || stored.GapQueries != 13 {
+		t.Fatalf("expected stored report, got %+v, %v", stored, err)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/projects/p1/rag/content-gaps", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if stored, _ = h.manager.GetContentGapReport(ctx, "p1"); stored.GapQueries != 16 || stored.Recommendations[0].Topic != "refund" {
+		t.Fatalf("expected regenerated report, got %+v", stored)
+	}
+}
+
+func TestTopicTerms(t *testing.T) {
+	terms := topicTerms("How do I export Invoices to CSV?")
+	for _, want := range []string{"export", "invoice", "csv"} {
+		if !terms[want] {
+			t.Errorf("expected term %q in %v", want, terms)
+		}
+	}
+	if terms["how"] || terms["invoices"] {
+		t.Errorf("unexpected terms %v", terms)
+	}
+	if terms := topicTerms("如何导出发票"); !terms["发票"] || terms["如何"] {
+		t.Errorf("unexpected chinese terms %v", terms)
+	}
+}
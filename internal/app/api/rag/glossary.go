@@ -0,0 +1,236 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// glossaryInterval 定期任务重新挖掘术语表的间隔
+	glossaryInterval = 24 * time.Hour
+
+	// glossaryCheckInterval 定期任务检查过期术语表的间隔
+	glossaryCheckInterval = time.Hour
+)
+
+// GetGlossary 获取项目术语表，不存在时返回 nil，实现 core.GlossaryStore
+func (m *Manager) GetGlossary(ctx context.Context, projectID string) (*core.Glossary, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx, `SELECT glossary FROM rag_glossaries WHERE project_id = ?`, projectID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get glossary: %w", err)
+	}
+	var glossary core.Glossary
+	if err := json.Unmarshal([]byte(data), &glossary); err != nil {
+		return nil, fmt.Errorf("failed to decode glossary: %w", err)
+	}
+	glossary.ProjectID = projectID
+	return &glossary, nil
+}
+
+// SaveGlossary 保存项目术语表，实现 core.GlossaryStore
+func (m *Manager) SaveGlossary(ctx context.Context, glossary *core.Glossary) error {
+	data, err := json.Marshal(glossary)
+	if err != nil {
+		return fmt.Errorf("failed to encode glossary: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_glossaries (project_id, glossary, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(project_id) DO UPDATE SET
+			glossary = excluded.glossary,
+			updated_at = excluded.updated_at`,
+		glossary.ProjectID, string(data), glossary.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save glossary: %w", err)
+	}
+	return nil
+}
+
+// runGlossaries 为近期有查询且术语表已过期的项目重新挖掘缩写和领域术语
+func (s *SyncScheduler) runGlossaries(ctx context.Context, now time.Time) {
+	if s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+	if now.Sub(s.lastGlossaryCheck) < glossaryCheckInterval {
+		return
+	}
+	s.lastGlossaryCheck = now
+
+	projects, err := s.handler.manager.queriedProjects(ctx, now.AddDate(0, 0, -defaultAnalyticsDays))
+	if err != nil {
+		s.logger.Error("failed to list projects for glossary mining", zap.Error(err))
+		return
+	}
+	for _, projectID := range projects {
+		existing, err := s.handler.manager.GetGlossary(ctx, projectID)
+		if err != nil {
+			s.logger.Warn("failed to load glossary", zap.String("project_id", projectID), zap.Error(err))
+			continue
+		}
+		if existing != nil && now.Sub(existing.MinedAt) < glossaryInterval {
+			continue
+		}
+		glossary, err := s.handler.pipeline.MineGlossary(ctx, projectID)
+		if err != nil {
+			s.logger.Error("failed to mine glossary", zap.String("project_id", projectID), zap.Error(err))
+			continue
+		}
+		s.logger.Info("Glossary mined",
+			zap.String("project_id", projectID),
+			zap.Int("entries", len(glossary.Entries)),
+		)
+	}
+}
+
+// handleGetGlossary 获取项目术语表，包括待审核和已拒绝的条目
+func (h *Handler) handleGetGlossary(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	glossary, err := h.manager.GetGlossary(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to get glossary", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get glossary",
+			"details": err.Error(),
+		})
+		return
+	}
+	if glossary == nil {
+		glossary = &core.Glossary{ProjectID: projectID, Entries: []core.GlossaryEntry{}}
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": glossary,
+	})
+}
+
+// handleMineGlossary 立即挖掘项目语料，合并到术语表中，保留人工审核过的条目
+func (h *Handler) handleMineGlossary(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	glossary, err := h.pipeline.MineGlossary(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to mine glossary", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to mine glossary",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": glossary,
+	})
+}
+
+// glossaryTerm 读取路径中的术语，术语可能包含需要转义的字符
+func glossaryTerm(r *http.Request) string {
+	term := chi.URLParam(r, "term")
+	if unescaped, err := url.PathUnescape(term); err == nil {
+		term = unescaped
+	}
+	return term
+}
+
+// handlePutGlossaryTerm 新增或修改术语，修改后的条目不再被挖掘覆盖
+func (h *Handler) handlePutGlossaryTerm(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+
+	var entry core.GlossaryEntry
+	if err := render.DecodeJSON(r.Body, &entry); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	entry.Term = glossaryTerm(r)
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		entry.UpdatedBy = userID
+	}
+
+	glossary, err := h.manager.GetGlossary(r.Context(), projectID)
+	if err == nil && glossary == nil {
+		glossary = &core.Glossary{ProjectID: projectID}
+	}
+	if err == nil {
+		if err := glossary.Upsert(entry, time.Now()); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid glossary entry",
+				"details": err.Error(),
+			})
+			return
+		}
+		err = h.manager.SaveGlossary(r.Context(), glossary)
+	}
+	if err != nil {
+		h.logger.Error("failed to save glossary", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save glossary",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": glossary.Lookup(entry.Term),
+	})
+}
+
+// handleDeleteGlossaryTerm 删除术语；挖掘出的术语标记为已拒绝，避免下次挖掘时重新加入
+func (h *Handler) handleDeleteGlossaryTerm(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	term := glossaryTerm(r)
+	userID, _ := r.Context().Value("user_id").(string)
+
+	glossary, err := h.manager.GetGlossary(r.Context(), projectID)
+	if err == nil && (glossary == nil || !glossary.Remove(term, userID, time.Now())) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Glossary term not found",
+		})
+		return
+	}
+	if err == nil {
+		err = h.manager.SaveGlossary(r.Context(), glossary)
+	}
+	if err != nil {
+		h.logger.Error("failed to delete glossary term", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete glossary term",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Glossary term deleted",
+	})
+}
@@ -0,0 +1,69 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestGlossaryReview(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+
+	mined := &core.Glossary{ProjectID: "p1", MinedAt: time.Now(), Entries: []core.GlossaryEntry{
+		{Term: "SLA", Definition: "Service Level Agreement", Source: core.GlossarySourceMined, Status: core.GlossaryStatusActive},
+		{Term: "TAM", Source: core.GlossarySourceMined, Status: core.GlossaryStatusActive, Occurrences: 4},
+	}}
+	if err := h.manager.SaveGlossary(ctx, mined); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Define a term mining could not expand
+	rec := serve(http.MethodPut, "/projects/p1/rag/glossary/terms/TAM", `{"definition":"Technical Account Manager"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec = serve(http.MethodPut, "/projects/p1/rag/glossary/terms/TAM", `{"status":"pending"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid status to be rejected, got %d", rec.Code)
+	}
+
+	// Reject a mined term, and delete one that does not exist
+	if rec = serve(http.MethodDelete, "/projects/p1/rag/glossary/terms/sla", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec = serve(http.MethodDelete, "/projects/p1/rag/glossary/terms/RPO", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected missing term to return 404, got %d", rec.Code)
+	}
+
+	rec = serve(http.MethodGet, "/projects/p1/rag/glossary", "")
+	var resp struct {
+		Data core.Glossary `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	tam, sla := resp.Data.Lookup("TAM"), resp.Data.Lookup("SLA")
+	if tam == nil || tam.Definition != "Technical Account Manager" || tam.Source != core.GlossarySourceManual || tam.Occurrences != 4 {
+		t.Fatalf("unexpected TAM entry %+v", tam)
+	}
+	if sla == nil || sla.Status != core.GlossaryStatusRejected {
+		t.Fatalf("expected SLA to be rejected, got %+v", sla)
+	}
+
+	// Mining needs the pipeline
+	if rec = serve(http.MethodPost, "/projects/p1/rag/glossary/mine", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected mining without a pipeline to fail, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,1460 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/infra/signedurl"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// Handler 项目RAG配置与查询HTTP处理器
+type Handler struct {
+	manager   *Manager
+	pipeline  *core.Pipeline
+	indexer   documentIndexer
+	scheduler *SyncScheduler
+	bots      *BotConfig
+	widget    *WidgetConfig
+	logger    *zap.Logger
+
+	widgetLimiter *widgetRateLimiter
+	publicLimiter *widgetRateLimiter
+
+	jobFailed      func(ctx context.Context, projectID string)
+	queried        func(ctx context.Context, projectID, question string)
+	projectAccess  func(ctx context.Context, userID string, projectIDs []string) ([]string, error)
+	publicProjects PublicProjectLoader
+	storageQuotas  StorageQuotaLoader
+
+	blobLimitsLoader BlobLimitsLoader
+
+	retentionDefaults RetentionDefaultsLoader
+	chatRetention     ChatRetentionLoader
+
+	permissionCheck PermissionCheck
+	readCache       func(http.Handler) http.Handler
+
+	metadataSchemas MetadataSchemaLoader
+
+	downloads *DownloadConfig
+	objects   blobstore.Store
+	signer    *signedurl.Signer
+
+	workers *jobWorkers
+}
+
+// NewHandler 创建新的项目RAG配置处理器
+func NewHandler(manager *Manager, logger *zap.Logger) *Handler {
+	h := &Handler{
+		manager:       manager,
+		logger:        logger,
+		widgetLimiter: newWidgetRateLimiter(),
+		publicLimiter: newWidgetRateLimiter(),
+		workers:       newJobWorkers(),
+	}
+	h.scheduler = NewSyncScheduler(h, logger)
+	return h
+}
+
+// SetPipeline 设置用于查询的RAG管道，并加载已启用的数据源
+func (h *Handler) SetPipeline(pipeline *core.Pipeline) {
+	h.pipeline = pipeline
+	h.indexer = pipeline
+
+	sources, err := h.manager.ListDataSources(context.Background(), "")
+	if err != nil {
+		h.logger.Error("failed to load data sources", zap.Error(err))
+		return
+	}
+	for i := range sources {
+		h.attachDataSource(&sources[i])
+	}
+}
+
+// SetBotConfig 设置 Slack、Discord 机器人凭据
+func (h *Handler) SetBotConfig(cfg *BotConfig) {
+	h.bots = cfg
+}
+
+// SetWidgetConfig 设置嵌入式组件的 CAPTCHA 校验配置
+func (h *Handler) SetWidgetConfig(cfg *WidgetConfig) {
+	h.widget = cfg
+}
+
+// OnJobFailure 设置数据源同步或文档导入任务失败时的回调
+func (h *Handler) OnJobFailure(fn func(ctx context.Context, projectID string)) {
+	h.jobFailed = fn
+}
+
+// jobFailure 通知任务失败
+func (h *Handler) jobFailure(ctx context.Context, projectID string) {
+	if h.jobFailed != nil {
+		h.jobFailed(ctx, projectID)
+	}
+}
+
+// OnQuery 设置RAG查询成功后的回调，用于用量统计
+func (h *Handler) OnQuery(fn func(ctx context.Context, projectID, question string)) {
+	h.queried = fn
+}
+
+// query 执行RAG查询，保存查询记录、按设置抽样进入审核队列并通知查询回调
+func (h *Handler) query(ctx context.Context, question string, options core.QueryOptions) (*core.QueryResult, error) {
+	result, err := h.pipeline.Query(ctx, question, options)
+	if err != nil {
+		return nil, err
+	}
+	h.recordQuery(ctx, question, options, result)
+	h.sampleForReview(ctx, question, options, result)
+	if h.queried != nil {
+		h.queried(ctx, options.ProjectID, question)
+	}
+	return result, nil
+}
+
+// StartSyncScheduler 启动数据源定时同步
+func (h *Handler) StartSyncScheduler() {
+	h.scheduler.Start()
+}
+
+// StopSyncScheduler 停止数据源定时同步
+func (h *Handler) StopSyncScheduler() {
+	h.scheduler.Stop()
+}
+
+// RegisterReadRoutes 注册只读路由（项目查看权限，并按 RAG 权限校验）
+func (h *Handler) RegisterReadRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(h.require(auth.PermRAGQuery))
+		if h.readCache != nil {
+			r.Use(h.readCache)
+		}
+		r.Get("/rag/settings", h.handleGetSettings)
+		r.Post("/rag/query", h.handleQuery)
+		r.Post("/rag/queries/{queryId}/feedback", h.handleQueryFeedback)
+		r.Get("/rag/chat", h.handleChat)
+		r.Get("/rag/chat/transcripts", h.handleListTranscripts)
+		r.Get("/rag/chat/transcripts/export", h.handleExportTranscripts)
+		r.Get("/rag/chat/transcripts/{transcriptId}", h.handleGetTranscript)
+		r.Delete("/rag/chat/transcripts/{transcriptId}", h.handleDeleteTranscript)
+		r.Get("/rag/tools", h.handleListTools)
+		r.Get("/rag/glossary", h.handleGetGlossary)
+		r.Post("/rag/batch", h.handleStartBatch)
+		r.Get("/rag/batch/{jobId}", h.handleGetBatch)
+		r.Get("/rag/batch/{jobId}/results", h.handleBatchResults)
+		r.Delete("/rag/batch/{jobId}", h.handleCancelBatch)
+		r.Get("/rag/collections", h.handleListCollections)
+		r.Post("/rag/collections", h.handleCreateCollection)
+		r.Get("/rag/collections/{collectionId}", h.handleGetCollection)
+		r.Put("/rag/collections/{collectionId}", h.handleUpdateCollection)
+		r.Delete("/rag/collections/{collectionId}", h.handleDeleteCollection)
+		r.Post("/rag/collections/{collectionId}/run", h.handleRunCollection)
+		r.Post("/rag/collections/{collectionId}/searches", h.handleCreateSavedSearch)
+		r.Put("/rag/collections/{collectionId}/searches/{searchId}", h.handleUpdateSavedSearch)
+		r.Delete("/rag/collections/{collectionId}/searches/{searchId}", h.handleDeleteSavedSearch)
+		r.Post("/rag/collections/{collectionId}/searches/{searchId}/run", h.handleRunSavedSearch)
+		r.Get("/rag/collections/{collectionId}/searches/{searchId}/runs", h.handleListSearchRuns)
+		r.Post("/documents/{documentId}/download-url", h.handleDocumentDownloadURL)
+		r.Get("/rag/documents/deleted", h.handleListDeleted)
+		r.Get("/rag/documents/{documentId}/versions", h.handleListVersions)
+		r.Get("/rag/documents/{documentId}/versions/diff", h.handleDiffVersions)
+		r.Get("/rag/documents/{documentId}/versions/{version}", h.handleGetVersion)
+		r.Get("/documents", h.handleListDocuments)
+		r.Get("/documents/{documentId}/chunks", h.handleInspectChunks)
+		r.Get("/documents/jobs", h.handleListIngestJobs)
+		r.Get("/documents/jobs/{jobId}", h.handleGetIngestJob)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.require(auth.PermRAGViewAnalytics))
+		r.Get("/rag/analytics", h.handleQueryAnalytics)
+		r.Get("/rag/content-gaps", h.handleGetContentGaps)
+		r.Get("/rag/datasources", h.handleListDataSources)
+		r.Get("/rag/datasources/{sourceId}", h.handleGetDataSource)
+		r.Get("/rag/datasources/{sourceId}/status", h.handleDataSourceStatus)
+		r.Get("/rag/datasources/{sourceId}/syncs", h.handleListDataSourceSyncs)
+		r.Get("/rag/bots/channels", h.handleListBotChannels)
+		r.Get("/rag/widget-tokens", h.handleListWidgetTokens)
+		r.Get("/rag/snapshots", h.handleListSnapshots)
+		r.Post("/rag/retention/preview", h.handlePreviewRetention)
+		r.Get("/rag/tiering", h.handleGetTiering)
+		r.Get("/storage", h.handleGetStorageUsage)
+	})
+}
+
+// RegisterBotRoutes 注册聊天平台回调路由（挂载于 /integrations，通过平台签名认证）
+func (h *Handler) RegisterBotRoutes(r chi.Router) {
+	r.Post("/slack/events", h.handleSlackEvents)
+	r.Post("/discord/interactions", h.handleDiscordInteractions)
+}
+
+// RegisterWidgetRoutes 注册嵌入式组件的公开路由（挂载于 /public/v1/rag，通过可公开令牌认证）
+func (h *Handler) RegisterWidgetRoutes(r chi.Router) {
+	r.Options("/query", h.handleWidgetPreflight)
+	r.Post("/query", h.handleWidgetQuery)
+}
+
+// RegisterTenantRoutes 注册租户路由（挂载于 /admin/v1/tenants/{tenantId}/rag，租户管理权限）
+func (h *Handler) RegisterTenantRoutes(r chi.Router) {
+	r.Get("/budget", h.handleGetBudget)
+	r.Put("/budget", h.handleUpdateBudget)
+	r.Delete("/budget", h.handleDeleteBudget)
+	r.Get("/keys", h.handleListTenantKeys)
+	r.Post("/keys/rotate", h.handleRotateTenantKey)
+	r.Get("/keys/events", h.handleListKeyUsage)
+	r.Post("/chat-transcripts/export", h.handleExportTenantTranscripts)
+}
+
+// RegisterAdminRoutes 注册系统管理路由（挂载于 /admin/v1/rag，系统管理员权限）
+func (h *Handler) RegisterAdminRoutes(r chi.Router) {
+	r.Get("/analytics", h.handleListQueryAnalytics)
+	r.Get("/storage", h.handleListStorageConsumers)
+	r.Get("/tiering", h.handleGetTieringStats)
+}
+
+// RegisterJobRoutes 注册后台任务管理路由（挂载于 /admin/v1/jobs，系统管理员权限）
+func (h *Handler) RegisterJobRoutes(r chi.Router) {
+	r.Get("/", h.handleJobsOverview)
+	r.Get("/failures", h.handleListJobFailures)
+	r.Get("/failures/{failureId}", h.handleGetJobFailure)
+	r.Post("/failures/{failureId}/retry", h.handleRetryJob)
+	r.Get("/dead-letter", h.handleListDeadLetters)
+	r.Delete("/dead-letter", h.handlePurgeDeadLetters)
+	r.Delete("/dead-letter/{failureId}", h.handleDiscardDeadLetter)
+}
+
+// RegisterWriteRoutes 注册写路由（按 RAG 权限校验：索引、数据源、分析与提示词配置分别授权）
+func (h *Handler) RegisterWriteRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(h.require(auth.PermRAGIndex))
+		r.Post("/documents", h.handleUploadDocuments)
+		r.Post("/documents/{documentId}/reprocess", h.handleReprocessDocument)
+		r.Put("/documents/{documentId}/pin", h.handlePinDocument)
+		r.Delete("/documents/{documentId}/pin", h.handleUnpinDocument)
+		r.Delete("/rag/documents/{documentId}", h.handleDeleteDocument)
+		r.Post("/rag/documents/{documentId}/restore", h.handleRestoreDocument)
+		r.Post("/rag/documents/purge", h.handlePurgeDocuments)
+		r.Post("/rag/retention/run", h.handleRunRetention)
+		r.Post("/rag/tiering/run", h.handleRunTiering)
+		r.Post("/rag/snapshots", h.handleCreateSnapshot)
+		r.Post("/rag/snapshots/{snapshotId}/restore", h.handleRestoreSnapshot)
+		r.Delete("/rag/snapshots/{snapshotId}", h.handleDeleteSnapshot)
+		r.Post("/rag/snapshots/{snapshotId}/download-url", h.handleSnapshotDownloadURL)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.require(auth.PermRAGManageSources))
+		r.Post("/rag/datasources", h.handleCreateDataSource)
+		r.Post("/rag/datasources/test", h.handleTestDataSourceConfig)
+		r.Put("/rag/datasources/{sourceId}", h.handleUpdateDataSource)
+		r.Delete("/rag/datasources/{sourceId}", h.handleDeleteDataSource)
+		r.Post("/rag/datasources/{sourceId}/test", h.handleTestDataSource)
+		r.Post("/rag/datasources/{sourceId}/sync", h.handleSyncDataSource)
+		r.Get("/rag/quarantine", h.handleListQuarantine)
+		r.Post("/rag/quarantine/{documentId}/requeue", h.handleRequeueDocument)
+		r.Put("/rag/bots/{platform}/channels/{channelId}", h.handlePutBotChannel)
+		r.Delete("/rag/bots/{platform}/channels/{channelId}", h.handleDeleteBotChannel)
+		r.Post("/rag/widget-tokens", h.handleCreateWidgetToken)
+		r.Put("/rag/widget-tokens/{tokenId}", h.handleUpdateWidgetToken)
+		r.Delete("/rag/widget-tokens/{tokenId}", h.handleDeleteWidgetToken)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.require(auth.PermRAGViewAnalytics))
+		r.Get("/rag/reviews", h.handleListReviews)
+		r.Get("/rag/reviews/{reviewId}", h.handleGetReview)
+		r.Get("/rag/eval-set", h.handleGetEvalSet)
+		r.Post("/rag/content-gaps", h.handleDetectContentGaps)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.require(auth.PermRAGManagePrompts))
+		r.Put("/rag/settings", h.handleUpdateSettings)
+		r.Delete("/rag/settings", h.handleDeleteSettings)
+		r.Get("/rag/reviews/settings", h.handleGetReviewSettings)
+		r.Put("/rag/reviews/settings", h.handleUpdateReviewSettings)
+		r.Post("/rag/reviews/{reviewId}/verdict", h.handleReviewVerdict)
+		r.Delete("/rag/eval-set/{caseId}", h.handleDeleteEvalCase)
+		r.Post("/rag/glossary/mine", h.handleMineGlossary)
+		r.Put("/rag/glossary/terms/{term}", h.handlePutGlossaryTerm)
+		r.Delete("/rag/glossary/terms/{term}", h.handleDeleteGlossaryTerm)
+		r.Post("/rag/compare-chunkers", h.handleCompareChunkers)
+	})
+}
+
+// handleGetSettings 获取项目覆盖配置以及合并后的生效配置
+func (h *Handler) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+
+	overrides, err := h.manager.GetProjectConfig(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to get project rag settings", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get RAG settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	effective := overrides.Apply(h.manager.GlobalConfig())
+	if overrides == nil {
+		overrides = &core.ProjectConfig{ProjectID: projectID}
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": map[string]interface{}{
+			"overrides": overrides,
+			"effective": map[string]interface{}{
+				"retrieval": effective.Retrieval,
+				"chunking":  effective.Processing.Chunking,
+			},
+		},
+	})
+}
+
+// handleUpdateSettings 替换项目覆盖配置
+func (h *Handler) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+
+	var req core.ProjectConfig
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.ProjectID = projectID
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		req.UpdatedBy = userID
+	}
+
+	if err := req.Validate(h.manager.GlobalConfig()); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid RAG settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.manager.SaveProjectConfig(r.Context(), &req); err != nil {
+		h.logger.Error("failed to save project rag settings", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save RAG settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("project rag settings updated", zap.String("project_id", projectID))
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": req,
+	})
+}
+
+// handleDeleteSettings 删除项目覆盖配置，恢复全局默认
+func (h *Handler) handleDeleteSettings(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+
+	if err := h.manager.DeleteProjectConfig(r.Context(), projectID); err != nil {
+		h.logger.Error("failed to delete project rag settings", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete RAG settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "RAG settings reset to defaults",
+	})
+}
+
+// handleQuery 执行RAG查询，应用项目配置覆盖和元数据过滤
+func (h *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req QueryRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.Query == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Query is required",
+		})
+		return
+	}
+
+	options, err := req.queryOptions(r)
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid filter",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.query(r.Context(), req.Query, options)
+	if errors.Is(err, core.ErrBudgetExceeded) {
+		render.Status(r, http.StatusPaymentRequired)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "LLM budget exceeded",
+			"code":    "budget_exceeded",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("rag query failed", zap.String("project_id", options.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Query failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": result,
+	})
+}
+
+// queryOptions 由请求生成查询选项：项目、过滤条件、结构化输出以及当前用户和租户
+func (req *QueryRequest) queryOptions(r *http.Request) (core.QueryOptions, error) {
+	options := req.Options
+	options.ProjectID = chi.URLParam(r, "projectId")
+	if err := mergeFilters(&options, req.Filter, req.FilterExpr); err != nil {
+		return options, err
+	}
+	if req.OutputSchema != nil {
+		options.GenerateOptions.OutputSchema = req.OutputSchema
+	}
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		options.UserID = userID
+	}
+	if tenantID, ok := r.Context().Value("tenant_id").(string); ok {
+		options.TenantID = tenantID
+	}
+	return options, nil
+}
+
+// handleListTools 列出项目可用的工具（项目工具及未被覆盖的全局工具）
+func (h *Handler) handleListTools(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	render.JSON(w, r, map[string]interface{}{
+		"data": h.pipeline.ProjectTools(projectID).Definitions(),
+	})
+}
+
+// handleStartBatch 启动批量查询后台任务
+func (h *Handler) handleStartBatch(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req BatchQueryRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	options := req.Options
+	options.ProjectID = chi.URLParam(r, "projectId")
+	if err := mergeFilters(&options, req.Filter, req.FilterExpr); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid filter",
+			"details": err.Error(),
+		})
+		return
+	}
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		options.UserID = userID
+	}
+	if tenantID, ok := r.Context().Value("tenant_id").(string); ok {
+		options.TenantID = tenantID
+	}
+
+	job, err := h.pipeline.StartBatchQuery(r.Context(), core.BatchQueryOptions{
+		Queries:     req.Queries,
+		Options:     options,
+		Concurrency: req.Concurrency,
+	})
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to start batch",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("rag batch started",
+		zap.String("project_id", options.ProjectID),
+		zap.String("job_id", job.ID),
+		zap.Int("queries", job.Total),
+	)
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]interface{}{
+		"data": job,
+	})
+}
+
+// handleGetBatch 查询批量任务进度
+func (h *Handler) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.projectBatch(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": job,
+	})
+}
+
+// handleBatchResults 下载批量任务结果文件（jsonl 或 csv）
+func (h *Handler) handleBatchResults(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.projectBatch(w, r)
+	if !ok {
+		return
+	}
+
+	results, err := h.pipeline.BatchQueryResults(job.ID)
+	if err != nil {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Results not available",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = core.BatchFormatJSONL
+	}
+	contentTypes := map[string]string{
+		core.BatchFormatJSONL: "application/x-ndjson",
+		core.BatchFormatCSV:   "text/csv; charset=utf-8",
+	}
+	contentType, supported := contentTypes[format]
+	if !supported {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Unsupported format, use jsonl or csv",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="rag-batch-%s.%s"`, job.ID, format))
+	if err := core.WriteBatchResults(w, format, results); err != nil {
+		h.logger.Error("failed to write batch results", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// handleCancelBatch 取消批量任务
+func (h *Handler) handleCancelBatch(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.projectBatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.pipeline.CancelBatchQuery(job.ID); err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to cancel batch",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Batch cancelled",
+	})
+}
+
+// projectBatch 获取批量任务并校验其属于当前项目
+func (h *Handler) projectBatch(w http.ResponseWriter, r *http.Request) (*core.BatchQueryJob, bool) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return nil, false
+	}
+
+	job, err := h.pipeline.GetBatchQuery(chi.URLParam(r, "jobId"))
+	if err != nil || job.ProjectID != chi.URLParam(r, "projectId") {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Batch job not found",
+		})
+		return nil, false
+	}
+	return job, true
+}
+
+// mergeFilters 合并表达式与结构化过滤条件并校验
+func mergeFilters(options *core.QueryOptions, filter string, expr *core.FilterExpr) error {
+	if filter != "" {
+		if _, err := core.ParseFilterExpression(filter); err != nil {
+			return err
+		}
+		options.Filter = filter
+	}
+
+	criteria := &options.RetrievalOptions.FilterOptions
+	criteria.Expression = core.AndFilterExprs(criteria.Expression, expr)
+	if criteria.Expression != nil {
+		return criteria.Expression.Validate()
+	}
+	return nil
+}
+
+// budgetResponse 租户预算及当前计费周期用量
+type budgetResponse struct {
+	Budget   *core.TenantBudget `json:"budget"`
+	Usage    *core.BudgetUsage  `json:"usage"`
+	ResetsAt time.Time          `json:"resets_at"`
+}
+
+// handleGetBudget 获取租户预算与本计费周期用量
+func (h *Handler) handleGetBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	budget, err := h.manager.GetBudget(r.Context(), tenantID)
+	if err != nil {
+		h.logger.Error("failed to get tenant budget", zap.String("tenant_id", tenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get budget",
+			"details": err.Error(),
+		})
+		return
+	}
+	if budget == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Budget not set",
+		})
+		return
+	}
+
+	now := time.Now()
+	usage, err := h.manager.GetUsage(r.Context(), tenantID, budget.CycleStart(now))
+	if err != nil {
+		h.logger.Error("failed to get tenant usage", zap.String("tenant_id", tenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get usage",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": budgetResponse{
+			Budget:   budget,
+			Usage:    usage,
+			ResetsAt: budget.CycleEnd(now),
+		},
+	})
+}
+
+// handleUpdateBudget 设置租户预算
+func (h *Handler) handleUpdateBudget(w http.ResponseWriter, r *http.Request) {
+	var budget core.TenantBudget
+	if err := render.DecodeJSON(r.Body, &budget); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	budget.TenantID = chi.URLParam(r, "tenantId")
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		budget.UpdatedBy = userID
+	}
+	if err := budget.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid budget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.manager.SaveBudget(r.Context(), &budget); err != nil {
+		h.logger.Error("failed to save tenant budget", zap.String("tenant_id", budget.TenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save budget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": budget,
+	})
+}
+
+// handleDeleteBudget 删除租户预算，取消限制
+func (h *Handler) handleDeleteBudget(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+
+	if err := h.manager.DeleteBudget(r.Context(), tenantID); err != nil {
+		h.logger.Error("failed to delete tenant budget", zap.String("tenant_id", tenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete budget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Budget removed",
+	})
+}
+
+// handleListVersions 列出文档的历史版本
+func (h *Handler) handleListVersions(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentId")
+
+	versions, err := h.manager.ListDocumentVersions(r.Context(), documentID)
+	if err != nil {
+		h.logger.Error("failed to list document versions", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list versions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": versions,
+	})
+}
+
+// handleGetVersion 获取文档指定版本的内容与分块
+func (h *Handler) handleGetVersion(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentId")
+	number, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || number <= 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Invalid version",
+		})
+		return
+	}
+
+	version, err := h.manager.GetDocumentVersion(r.Context(), documentID, number)
+	if err != nil {
+		h.logger.Error("failed to get document version", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get version",
+			"details": err.Error(),
+		})
+		return
+	}
+	if version == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Version not found",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": version,
+	})
+}
+
+// handleDiffVersions 比较文档两个版本的内容，from 默认为 to 的上一版本，to 默认为最新版本
+func (h *Handler) handleDiffVersions(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentId")
+
+	var from, to int
+	for name, target := range map[string]*int{"from": &from, "to": &to} {
+		value := r.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number <= 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": fmt.Sprintf("Invalid %s version", name),
+			})
+			return
+		}
+		*target = number
+	}
+
+	toVersion, err := h.manager.GetDocumentVersion(r.Context(), documentID, to)
+	if err == nil && toVersion != nil && from == 0 {
+		from = toVersion.Version - 1
+	}
+	var fromVersion *core.DocumentVersion
+	if err == nil && toVersion != nil && from > 0 {
+		fromVersion, err = h.manager.GetDocumentVersion(r.Context(), documentID, from)
+	}
+	if err != nil {
+		h.logger.Error("failed to get document versions", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get versions",
+			"details": err.Error(),
+		})
+		return
+	}
+	if toVersion == nil || fromVersion == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Version not found",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": core.DiffVersions(fromVersion, toVersion),
+	})
+}
+
+// deleteDocumentRequest 文档删除请求
+type deleteDocumentRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// handleDeleteDocument 软删除文档：立即从检索中移除，保留期过后彻底清除
+func (h *Handler) handleDeleteDocument(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req deleteDocumentRequest
+	if r.ContentLength > 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	documentID := chi.URLParam(r, "documentId")
+	userID, _ := r.Context().Value("user_id").(string)
+	tombstone, err := h.pipeline.SoftDeleteDocument(r.Context(), documentID, userID, req.Reason)
+	if err != nil {
+		h.logger.Error("failed to delete document", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": tombstone,
+	})
+}
+
+// handleRestoreDocument 恢复尚未清除的软删除文档
+func (h *Handler) handleRestoreDocument(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	documentID := chi.URLParam(r, "documentId")
+	if err := h.pipeline.RestoreDocument(r.Context(), documentID); err != nil {
+		h.logger.Error("failed to restore document", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to restore document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Document restored",
+	})
+}
+
+// handleListDeleted 列出等待清除的软删除文档
+func (h *Handler) handleListDeleted(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": h.pipeline.ListDeletedDocuments(),
+	})
+}
+
+// handlePurgeDocuments 清除已过保留期的软删除文档，force=true 时忽略保留期
+func (h *Handler) handlePurgeDocuments(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	render.JSON(w, r, map[string]interface{}{
+		"data": h.pipeline.PurgeDeletedDocuments(r.Context(), force),
+	})
+}
+
+// dataSourceRequest 数据源创建与更新请求
+type dataSourceRequest struct {
+	Name            string                 `json:"name"`
+	Type            string                 `json:"type"`
+	Config          map[string]interface{} `json:"config"`
+	SecretRefs      map[string]string      `json:"secret_refs,omitempty"`
+	Schedule        string                 `json:"schedule,omitempty"`
+	IncludePatterns []string               `json:"include_patterns,omitempty"`
+	ExcludePatterns []string               `json:"exclude_patterns,omitempty"`
+	Enabled         *bool                  `json:"enabled,omitempty"`
+}
+
+// apply 将请求内容写入数据源定义，未指定 enabled 时保持原值
+func (req *dataSourceRequest) apply(ds *DataSource) {
+	ds.Name = req.Name
+	ds.Type = req.Type
+	ds.Config = req.Config
+	ds.SecretRefs = req.SecretRefs
+	ds.Schedule = req.Schedule
+	ds.IncludePatterns = req.IncludePatterns
+	ds.ExcludePatterns = req.ExcludePatterns
+	if req.Enabled != nil {
+		ds.Enabled = *req.Enabled
+	}
+}
+
+// handleListDataSources 列出项目的数据源
+func (h *Handler) handleListDataSources(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+
+	sources, err := h.manager.ListDataSources(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to list data sources", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list data sources",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": sources,
+	})
+}
+
+// handleGetDataSource 获取数据源定义
+func (h *Handler) handleGetDataSource(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.projectDataSource(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": ds,
+	})
+}
+
+// handleCreateDataSource 创建数据源，启用时立即加载到RAG管道
+func (h *Handler) handleCreateDataSource(w http.ResponseWriter, r *http.Request) {
+	var req dataSourceRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ds := &DataSource{
+		ProjectID: chi.URLParam(r, "projectId"),
+		Enabled:   true,
+	}
+	req.apply(ds)
+	if userID, ok := r.Context().Value("user_id").(string); ok {
+		ds.CreatedBy = userID
+	}
+
+	if err := ds.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid data source",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.manager.CreateDataSource(r.Context(), ds); err != nil {
+		h.logger.Error("failed to create data source", zap.String("project_id", ds.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to create data source",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.attachDataSource(ds)
+
+	h.logger.Info("data source created",
+		zap.String("project_id", ds.ProjectID),
+		zap.String("source_id", ds.ID),
+		zap.String("type", ds.Type),
+	)
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{
+		"data": ds,
+	})
+}
+
+// handleUpdateDataSource 替换数据源定义并重新加载到RAG管道
+func (h *Handler) handleUpdateDataSource(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.projectDataSource(w, r)
+	if !ok {
+		return
+	}
+
+	var req dataSourceRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.apply(ds)
+
+	if err := ds.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid data source",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.manager.UpdateDataSource(r.Context(), ds); err != nil {
+		h.logger.Error("failed to update data source", zap.String("source_id", ds.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to update data source",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.detachDataSource(ds.ID)
+	h.attachDataSource(ds)
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": ds,
+	})
+}
+
+// handleDeleteDataSource 删除数据源定义并级联软删除其全部文档
+func (h *Handler) handleDeleteDataSource(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.projectDataSource(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.DeleteDataSource(r.Context(), ds.ProjectID, ds.ID); err != nil {
+		h.logger.Error("failed to delete data source", zap.String("source_id", ds.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete data source",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if h.pipeline == nil {
+		render.JSON(w, r, map[string]interface{}{
+			"message": "Data source deleted",
+		})
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
+	result, err := h.pipeline.DeleteDataSource(r.Context(), ds.ID, userID)
+	if err != nil {
+		h.logger.Error("failed to delete data source documents", zap.String("source_id", ds.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete data source documents",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": result,
+	})
+}
+
+// handleTestDataSourceConfig 测试尚未保存的数据源配置能否连接
+func (h *Handler) handleTestDataSourceConfig(w http.ResponseWriter, r *http.Request) {
+	var req dataSourceRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ds := &DataSource{ID: "test", ProjectID: chi.URLParam(r, "projectId")}
+	req.apply(ds)
+	if err := ds.Validate(); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid data source",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": ds.TestConnection(),
+	})
+}
+
+// handleTestDataSource 测试已保存数据源的连接
+func (h *Handler) handleTestDataSource(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.projectDataSource(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": ds.TestConnection(),
+	})
+}
+
+// handleDataSourceStatus 获取数据源同步状态
+func (h *Handler) handleDataSourceStatus(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.projectDataSource(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.manager.GetSyncStatus(r.Context(), ds)
+	if err != nil {
+		h.logger.Error("failed to get data source status", zap.String("source_id", ds.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get data source status",
+			"details": err.Error(),
+		})
+		return
+	}
+	if h.pipeline != nil {
+		for _, source := range h.pipeline.ListDataSources() {
+			if source.GetID() == ds.ID {
+				status.Attached = true
+				break
+			}
+		}
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": status,
+	})
+}
+
+// handleListDataSourceSyncs 列出数据源同步历史
+func (h *Handler) handleListDataSourceSyncs(w http.ResponseWriter, r *http.Request) {
+	ds, ok := h.projectDataSource(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "Invalid limit",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := h.manager.ListSyncRuns(r.Context(), ds.ID, limit)
+	if err != nil {
+		h.logger.Error("failed to list data source syncs", zap.String("source_id", ds.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list sync history",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": runs,
+	})
+}
+
+// handleSyncDataSource 手动触发数据源同步，同步在后台执行；force=true 时强制重建索引
+func (h *Handler) handleSyncDataSource(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	ds, ok := h.projectDataSource(w, r)
+	if !ok {
+		return
+	}
+	if !ds.Enabled {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Data source is disabled",
+		})
+		return
+	}
+
+	// force=true 在影子索引中重建全部文档，校验通过后切换读取，重建前自动为数据源创建快照
+	trigger := "manual"
+	if r.URL.Query().Get("force") == "true" {
+		trigger = "reindex"
+	}
+	userID, _ := r.Context().Value("user_id").(string)
+	run, err := h.startSync(r.Context(), ds, trigger, userID)
+	if errors.Is(err, errSyncInProgress) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Sync already in progress",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to start data source sync", zap.String("source_id", ds.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to start sync",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]interface{}{
+		"data": run,
+	})
+}
+
+// projectDataSource 获取数据源定义并校验其属于当前项目
+func (h *Handler) projectDataSource(w http.ResponseWriter, r *http.Request) (*DataSource, bool) {
+	sourceID := chi.URLParam(r, "sourceId")
+
+	ds, err := h.manager.GetDataSource(r.Context(), chi.URLParam(r, "projectId"), sourceID)
+	if err != nil {
+		h.logger.Error("failed to get data source", zap.String("source_id", sourceID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get data source",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+	if ds == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Data source not found",
+		})
+		return nil, false
+	}
+	return ds, true
+}
+
+// startSync 获取同步锁后启动同步，已有同步在执行时返回 errSyncInProgress
+func (h *Handler) startSync(ctx context.Context, ds *DataSource, trigger, triggeredBy string) (*DataSourceSyncRun, error) {
+	lock, err := h.pipeline.Locker().TryLock(ctx, syncLockKey(ds.ID), h.pipeline.LockTTL())
+	if errors.Is(err, core.ErrLockHeld) {
+		return nil, errSyncInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.runSync(ctx, ds, lock, trigger, triggeredBy)
+}
+
+// runSync 记录同步任务并在后台对数据源执行增量索引；reindex 触发时在影子索引中重建，校验通过后切换。同步期间续期同步锁，结束后释放
+func (h *Handler) runSync(ctx context.Context, ds *DataSource, lock core.Lock, trigger, triggeredBy string) (*DataSourceSyncRun, error) {
+	now := time.Now()
+	run := &DataSourceSyncRun{
+		ID:          fmt.Sprintf("sync_%d", now.UnixNano()),
+		SourceID:    ds.ID,
+		Trigger:     trigger,
+		Status:      SyncStatusRunning,
+		StartedAt:   now,
+		TriggeredBy: triggeredBy,
+	}
+	if err := h.manager.SaveSyncRun(ctx, run); err != nil {
+		lock.Unlock(ctx)
+		return nil, err
+	}
+
+	finished := *run
+	go func() {
+		ctx := context.Background()
+		worker := h.workers.start(JobKindSync, run.ID, ds.ProjectID, ds.ID)
+		defer h.workers.done(worker)
+
+		// 强制重建时新索引需与当前索引对热门问题给出相近的结果才会切换
+		var validation core.SwapValidation
+		if trigger == "reindex" {
+			queries, err := h.manager.swapSampleQueries(ctx, ds.ProjectID)
+			if err != nil {
+				h.logger.Warn("failed to load reindex sample queries", zap.String("project_id", ds.ProjectID), zap.Error(err))
+			}
+			validation.SampleQueries = queries
+		}
+
+		err := core.WithHeldLock(ctx, lock, h.pipeline.LockTTL(), func(ctx context.Context) error {
+			result, err := h.pipeline.Index(ctx, core.IndexOptions{
+				ProjectID:       ds.ProjectID,
+				DataSourceIDs:   []string{ds.ID},
+				ForceReindex:    trigger == "reindex",
+				Incremental:     trigger != "reindex",
+				IncludePatterns: ds.IncludePatterns,
+				ExcludePatterns: ds.ExcludePatterns,
+				Validation:      validation,
+			})
+			finished.Result = result
+			return err
+		})
+
+		end := time.Now()
+		finished.FinishedAt = &end
+		finished.Status = SyncStatusSucceeded
+		if err != nil {
+			finished.Status = SyncStatusFailed
+			finished.Error = err.Error()
+			h.jobFailure(ctx, ds.ProjectID)
+			h.deadLetterSync(ctx, ds, &finished, err)
+		}
+		if err := h.manager.SaveSyncRun(ctx, &finished); err != nil {
+			h.logger.Error("failed to record data source sync", zap.String("source_id", ds.ID), zap.Error(err))
+		}
+	}()
+
+	return run, nil
+}
+
+// attachDataSource 将启用的数据源加载到RAG管道，失败时仅记录日志
+func (h *Handler) attachDataSource(ds *DataSource) {
+	if h.pipeline == nil || !ds.Enabled {
+		return
+	}
+
+	source, err := ds.Build()
+	if err == nil {
+		err = h.pipeline.AddDataSource(source)
+	}
+	if err != nil {
+		h.logger.Warn("failed to attach data source", zap.String("source_id", ds.ID), zap.Error(err))
+	}
+}
+
+// detachDataSource 从RAG管道移除数据源（如已加载）
+func (h *Handler) detachDataSource(sourceID string) {
+	if h.pipeline == nil {
+		return
+	}
+	for _, source := range h.pipeline.ListDataSources() {
+		if source.GetID() == sourceID {
+			if err := h.pipeline.RemoveDataSource(sourceID); err != nil {
+				h.logger.Warn("failed to detach data source", zap.String("source_id", sourceID), zap.Error(err))
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,751 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// 后台任务类型
+const (
+	JobKindIngest = "ingest"
+	JobKindSync   = "sync"
+	JobKindBatch  = "batch"
+)
+
+// 失败任务状态：dead 在死信队列中等待处理，retried 已重新执行，discarded 已丢弃
+const (
+	JobFailureDead      = "dead"
+	JobFailureRetried   = "retried"
+	JobFailureDiscarded = "discarded"
+)
+
+const (
+	defaultJobFailures = 50
+	maxJobFailures     = 500
+
+	// jobPayloadPrefix 失败的上传任务保留原始内容的对象键前缀，重试时读取
+	jobPayloadPrefix = "jobs/failed/"
+)
+
+var (
+	errJobFailureNotFound = errors.New("job failure not found")
+	errJobNotRetryable    = errors.New("job cannot be retried")
+)
+
+// JobFailure 失败的后台任务。任务不会自动重试，失败后进入死信队列，由运维人员重试或丢弃
+type JobFailure struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"` // ingest 或 sync
+	JobID     string `json:"job_id"`
+	ProjectID string `json:"project_id"`
+	SourceID  string `json:"source_id,omitempty"` // 同步任务的数据源
+
+	Error     string `json:"error"`
+	Trace     string `json:"trace,omitempty"` // 错误链、阶段记录或 panic 堆栈
+	Retryable bool   `json:"retryable"`       // 未保留原始内容的上传任务无法重试
+
+	Status     string     `json:"status"`
+	RetriedAs  string     `json:"retried_as,omitempty"` // 重试启动的任务
+	FailedAt   time.Time  `json:"failed_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
+
+// jobPayload 重试失败任务所需的输入
+type jobPayload struct {
+	// 导入任务
+	ContentKey  string                 `json:"content_key,omitempty"`
+	ContentType string                 `json:"content_type,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+
+	// 同步任务
+	Trigger string `json:"trigger,omitempty"`
+}
+
+// JobFailureFilter 失败任务的筛选条件，空字段不筛选
+type JobFailureFilter struct {
+	Kind      string
+	ProjectID string
+	Status    string
+	Limit     int
+}
+
+// JobQueue 一类后台任务的队列状态。任务提交后立即开始执行，in_flight 为已开始尚未结束的任务（所有节点），
+// running 为本节点正在执行的任务
+type JobQueue struct {
+	Kind      string `json:"kind"`
+	InFlight  int    `json:"in_flight"`
+	Running   int    `json:"running"`
+	Pending   int    `json:"pending,omitempty"` // 批量查询尚未回答的问题数
+	Dead      int    `json:"dead"`
+	Failed24h int    `json:"failed_24h"`
+
+	Quarantined int `json:"quarantined,omitempty"` // 多次同步失败后被隔离的文档
+}
+
+// JobWorker 本节点正在执行的后台任务
+type JobWorker struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	JobID     string    `json:"job_id"`
+	ProjectID string    `json:"project_id"`
+	SourceID  string    `json:"source_id,omitempty"`
+	Stage     string    `json:"stage,omitempty"` // 导入阶段或批量查询进度
+	StartedAt time.Time `json:"started_at"`
+}
+
+// JobsOverview 后台任务总览
+type JobsOverview struct {
+	Queues    []JobQueue  `json:"queues"`
+	Workers   []JobWorker `json:"workers"`
+	Scheduler struct {
+		Leader bool `json:"leader"` // 本节点负责定时同步和维护任务
+	} `json:"scheduler"`
+}
+
+// jobWorkers 本节点正在执行的导入和同步任务
+type jobWorkers struct {
+	mu      sync.Mutex
+	next    int
+	running map[string]*JobWorker
+}
+
+func newJobWorkers() *jobWorkers {
+	return &jobWorkers{running: make(map[string]*JobWorker)}
+}
+
+// start 登记开始执行的任务，返回的 ID 用于更新阶段和注销
+func (w *jobWorkers) start(kind, jobID, projectID, sourceID string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.next++
+	id := fmt.Sprintf("worker_%d", w.next)
+	w.running[id] = &JobWorker{
+		ID: id, Kind: kind, JobID: jobID, ProjectID: projectID, SourceID: sourceID, StartedAt: time.Now(),
+	}
+	return id
+}
+
+func (w *jobWorkers) stage(id, stage string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if worker, ok := w.running[id]; ok {
+		worker.Stage = stage
+	}
+}
+
+func (w *jobWorkers) done(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.running, id)
+}
+
+// list 按开始时间返回正在执行的任务
+func (w *jobWorkers) list() []JobWorker {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	workers := make([]JobWorker, 0, len(w.running))
+	for _, worker := range w.running {
+		workers = append(workers, *worker)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].StartedAt.Before(workers[j].StartedAt) })
+	return workers
+}
+
+// SaveJobFailure 保存失败任务及重试所需的输入
+func (m *Manager) SaveJobFailure(ctx context.Context, failure *JobFailure, payload *jobPayload) error {
+	data, err := json.Marshal(failure)
+	if err != nil {
+		return fmt.Errorf("failed to encode job failure: %w", err)
+	}
+	var payloadData sql.NullString
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode job payload: %w", err)
+		}
+		payloadData = sql.NullString{String: string(encoded), Valid: true}
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_job_failures (id, kind, job_id, project_id, status, failure, payload, failed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			failure = excluded.failure`,
+		failure.ID, failure.Kind, failure.JobID, failure.ProjectID, failure.Status, string(data), payloadData, failure.FailedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save job failure: %w", err)
+	}
+	return nil
+}
+
+// GetJobFailure 获取失败任务及其重试输入，不存在时返回 errJobFailureNotFound
+func (m *Manager) GetJobFailure(ctx context.Context, id string) (*JobFailure, *jobPayload, error) {
+	var data string
+	var payloadData sql.NullString
+	err := m.db.QueryRowContext(ctx,
+		`SELECT failure, payload FROM rag_job_failures WHERE id = ?`, id,
+	).Scan(&data, &payloadData)
+	if err == sql.ErrNoRows {
+		return nil, nil, errJobFailureNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get job failure: %w", err)
+	}
+
+	var failure JobFailure
+	if err := json.Unmarshal([]byte(data), &failure); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode job failure: %w", err)
+	}
+	var payload jobPayload
+	if payloadData.Valid {
+		if err := json.Unmarshal([]byte(payloadData.String), &payload); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode job payload: %w", err)
+		}
+	}
+	return &failure, &payload, nil
+}
+
+// ListJobFailures 按失败时间倒序列出失败任务
+func (m *Manager) ListJobFailures(ctx context.Context, filter JobFailureFilter) ([]JobFailure, error) {
+	query := `SELECT failure FROM rag_job_failures WHERE 1 = 1`
+	var args []interface{}
+	if filter.Kind != "" {
+		query += ` AND kind = ?`
+		args = append(args, filter.Kind)
+	}
+	if filter.ProjectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultJobFailures
+	}
+	query += ` ORDER BY failed_at DESC LIMIT ?`
+	args = append(args, filter.Limit)
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := []JobFailure{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list job failures: %w", err)
+		}
+		var failure JobFailure
+		if err := json.Unmarshal([]byte(data), &failure); err != nil {
+			return nil, fmt.Errorf("failed to decode job failure: %w", err)
+		}
+		failures = append(failures, failure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list job failures: %w", err)
+	}
+	return failures, nil
+}
+
+// jobCounts 统计各类任务的执行中、死信和最近失败数量
+func (m *Manager) jobCounts(ctx context.Context, since time.Time) (map[string]*JobQueue, error) {
+	queues := map[string]*JobQueue{
+		JobKindIngest: {Kind: JobKindIngest},
+		JobKindSync:   {Kind: JobKindSync},
+	}
+
+	// 导入任务以 JSON 保存，编码后的状态字段格式固定
+	err := m.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM rag_ingest_jobs WHERE job LIKE ?`, `%"status":"`+IngestStatusProcessing+`"%`,
+	).Scan(&queues[JobKindIngest].InFlight)
+	if err == nil {
+		err = m.db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM rag_data_source_syncs WHERE status = ?`, SyncStatusRunning,
+		).Scan(&queues[JobKindSync].InFlight)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT kind,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END),
+			SUM(CASE WHEN failed_at >= ? THEN 1 ELSE 0 END)
+		FROM rag_job_failures
+		GROUP BY kind`,
+		JobFailureDead, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count job failures: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind string
+		var dead, recent int
+		if err := rows.Scan(&kind, &dead, &recent); err != nil {
+			return nil, fmt.Errorf("failed to count job failures: %w", err)
+		}
+		if queue, ok := queues[kind]; ok {
+			queue.Dead, queue.Failed24h = dead, recent
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count job failures: %w", err)
+	}
+
+	if queues[JobKindSync].Quarantined, err = m.countQuarantined(ctx); err != nil {
+		return nil, err
+	}
+	return queues, nil
+}
+
+// JobsOverview 汇总各类后台任务的队列、本节点的执行情况和调度状态
+func (h *Handler) JobsOverview(ctx context.Context) (*JobsOverview, error) {
+	counts, err := h.manager.jobCounts(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &JobsOverview{Workers: h.workers.list()}
+	for _, worker := range overview.Workers {
+		counts[worker.Kind].Running++
+	}
+
+	batch := JobQueue{Kind: JobKindBatch}
+	if h.pipeline != nil {
+		overview.Scheduler.Leader = h.pipeline.IsLeader()
+		for _, job := range h.pipeline.ListBatchQueries() {
+			if job.Status != core.BatchStatusRunning {
+				continue
+			}
+			batch.InFlight++
+			batch.Running++
+			batch.Pending += job.Total - job.Completed
+			overview.Workers = append(overview.Workers, JobWorker{
+				ID:        "batch_" + job.ID,
+				Kind:      JobKindBatch,
+				JobID:     job.ID,
+				ProjectID: job.ProjectID,
+				Stage:     fmt.Sprintf("%d/%d", job.Completed, job.Total),
+				StartedAt: job.StartedAt,
+			})
+		}
+	}
+	overview.Queues = []JobQueue{*counts[JobKindIngest], *counts[JobKindSync], batch}
+	return overview, nil
+}
+
+// deadLetterIngest 记录失败的导入任务。上传的文件仅在配置了对象存储时保留，用于重试
+func (h *Handler) deadLetterIngest(ctx context.Context, input *ingestInput, err error, trace string) {
+	job := input.job
+	failure := &JobFailure{
+		ID:        fmt.Sprintf("jobfail_%d", time.Now().UnixNano()),
+		Kind:      JobKindIngest,
+		JobID:     job.ID,
+		ProjectID: job.ProjectID,
+		Error:     err.Error(),
+		Trace:     trace,
+		Retryable: job.SourceType == ingestSourceURL,
+		Status:    JobFailureDead,
+		FailedAt:  time.Now(),
+	}
+	if job.Error != nil {
+		failure.Error = fmt.Sprintf("%s: %s", job.Error.Stage, job.Error.Message)
+	}
+	payload := &jobPayload{ContentType: input.contentType, Tags: input.tags, Metadata: input.metadata}
+	if job.SourceType == ingestSourceFile && h.objects != nil && len(input.content) > 0 {
+		key := jobPayloadPrefix + failure.ID
+		if _, err := h.objects.Put(ctx, key, bytes.NewReader(input.content), input.contentType); err != nil {
+			h.logger.Warn("failed to keep failed upload for retry", zap.String("job_id", job.ID), zap.Error(err))
+		} else {
+			payload.ContentKey = key
+			failure.Retryable = true
+		}
+	}
+	if err := h.manager.SaveJobFailure(ctx, failure, payload); err != nil {
+		h.logger.Error("failed to record job failure", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// deadLetterSync 记录失败的同步任务
+func (h *Handler) deadLetterSync(ctx context.Context, ds *DataSource, run *DataSourceSyncRun, err error) {
+	failure := &JobFailure{
+		ID:        fmt.Sprintf("jobfail_%d", time.Now().UnixNano()),
+		Kind:      JobKindSync,
+		JobID:     run.ID,
+		ProjectID: ds.ProjectID,
+		SourceID:  ds.ID,
+		Error:     err.Error(),
+		Trace:     errorTrace(err),
+		Retryable: true,
+		Status:    JobFailureDead,
+		FailedAt:  time.Now(),
+	}
+	if err := h.manager.SaveJobFailure(ctx, failure, &jobPayload{Trigger: run.Trigger}); err != nil {
+		h.logger.Error("failed to record job failure", zap.String("run_id", run.ID), zap.Error(err))
+	}
+}
+
+// ingestTrace 导入任务已完成的阶段和失败原因的错误链
+func ingestTrace(job *IngestJob, err error) string {
+	var b strings.Builder
+	for _, stage := range job.Stages {
+		fmt.Fprintf(&b, "%s completed at %s\n", stage.Stage, stage.CompletedAt.Format(time.RFC3339Nano))
+	}
+	if job.Error != nil {
+		fmt.Fprintf(&b, "%s failed\n", job.Error.Stage)
+	}
+	b.WriteString(errorTrace(err))
+	return b.String()
+}
+
+// errorTrace 逐层列出错误链中每个错误的类型和信息
+func errorTrace(err error) string {
+	var b strings.Builder
+	for depth := 0; err != nil; depth++ {
+		fmt.Fprintf(&b, "%s%T: %s\n", strings.Repeat("  ", depth), err, err.Error())
+		err = errors.Unwrap(err)
+	}
+	return b.String()
+}
+
+// retryJob 重新执行死信队列中的任务，返回重试启动的任务
+func (h *Handler) retryJob(ctx context.Context, id, userID string) (*JobFailure, interface{}, error) {
+	failure, payload, err := h.manager.GetJobFailure(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if failure.Status != JobFailureDead || !failure.Retryable {
+		return nil, nil, errJobNotRetryable
+	}
+
+	var started interface{}
+	switch failure.Kind {
+	case JobKindIngest:
+		job, err := h.retryIngest(ctx, failure, payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		failure.RetriedAs, started = job.ID, job
+	case JobKindSync:
+		ds, err := h.manager.GetDataSource(ctx, failure.ProjectID, failure.SourceID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ds == nil || h.pipeline == nil {
+			return nil, nil, errJobNotRetryable
+		}
+		// 失败的重建仍以重建方式重试，其他同步增量执行
+		trigger := "retry"
+		if payload.Trigger == "reindex" {
+			trigger = "reindex"
+		}
+		run, err := h.startSync(ctx, ds, trigger, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		failure.RetriedAs, started = run.ID, run
+	default:
+		return nil, nil, errJobNotRetryable
+	}
+
+	// 重试已经启动，记录失败不影响结果
+	if err := h.resolveJobFailure(ctx, failure, payload, JobFailureRetried, userID); err != nil {
+		h.logger.Error("failed to resolve job failure", zap.String("failure_id", failure.ID), zap.Error(err))
+	}
+	return failure, started, nil
+}
+
+// retryIngest 以原任务 ID 重新执行导入，保留此前的尝试次数
+func (h *Handler) retryIngest(ctx context.Context, failure *JobFailure, payload *jobPayload) (*IngestJob, error) {
+	if h.indexer == nil {
+		return nil, errJobNotRetryable
+	}
+	job, err := h.manager.GetIngestJob(ctx, failure.ProjectID, failure.JobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.Status != IngestStatusFailed {
+		return nil, errJobNotRetryable
+	}
+
+	input := &ingestInput{job: job, contentType: payload.ContentType, tags: payload.Tags, metadata: payload.Metadata}
+	if job.SourceType == ingestSourceFile {
+		if payload.ContentKey == "" || h.objects == nil {
+			return nil, errJobNotRetryable
+		}
+		reader, _, err := h.objects.Get(ctx, payload.ContentKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read failed upload: %w", err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read failed upload: %w", err)
+		}
+		input.content = content
+	}
+
+	job.Attempts++
+	job.Status = IngestStatusProcessing
+	job.Error = nil
+	job.Stage = ""
+	job.Stages = nil
+	job.complete(core.IngestReceived)
+	if err := h.manager.SaveIngestJob(ctx, job); err != nil {
+		return nil, err
+	}
+	snapshot := *job
+	snapshot.Stages = append([]IngestStageLog(nil), job.Stages...)
+	go h.runIngestJob(input)
+	return &snapshot, nil
+}
+
+// resolveJobFailure 将失败任务移出死信队列并删除保留的上传内容
+func (h *Handler) resolveJobFailure(ctx context.Context, failure *JobFailure, payload *jobPayload, status, userID string) error {
+	now := time.Now()
+	failure.Status = status
+	failure.ResolvedAt = &now
+	failure.ResolvedBy = userID
+	if err := h.manager.SaveJobFailure(ctx, failure, payload); err != nil {
+		return err
+	}
+	if payload != nil && payload.ContentKey != "" && h.objects != nil {
+		if err := h.objects.Delete(ctx, payload.ContentKey); err != nil {
+			h.logger.Warn("failed to delete failed upload", zap.String("key", payload.ContentKey), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// purgeDeadLetters 分批丢弃死信队列中的任务，返回丢弃数量
+func (h *Handler) purgeDeadLetters(ctx context.Context, kind, projectID, userID string) (int, error) {
+	discarded := 0
+	for {
+		failures, err := h.manager.ListJobFailures(ctx, JobFailureFilter{
+			Kind: kind, ProjectID: projectID, Status: JobFailureDead, Limit: maxJobFailures,
+		})
+		if err != nil || len(failures) == 0 {
+			return discarded, err
+		}
+		for i := range failures {
+			_, payload, err := h.manager.GetJobFailure(ctx, failures[i].ID)
+			if err != nil {
+				return discarded, err
+			}
+			if err := h.resolveJobFailure(ctx, &failures[i], payload, JobFailureDiscarded, userID); err != nil {
+				return discarded, err
+			}
+			discarded++
+		}
+	}
+}
+
+// handleJobsOverview 获取后台任务总览
+func (h *Handler) handleJobsOverview(w http.ResponseWriter, r *http.Request) {
+	overview, err := h.JobsOverview(r.Context())
+	if err != nil {
+		h.logger.Error("failed to get jobs overview", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get jobs overview",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": overview,
+	})
+}
+
+// handleListJobFailures 列出失败任务，支持 kind、project_id、status 和 limit 参数
+func (h *Handler) handleListJobFailures(w http.ResponseWriter, r *http.Request) {
+	h.listJobFailures(w, r, r.URL.Query().Get("status"))
+}
+
+// handleListDeadLetters 列出死信队列中的任务
+func (h *Handler) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	h.listJobFailures(w, r, JobFailureDead)
+}
+
+func (h *Handler) listJobFailures(w http.ResponseWriter, r *http.Request, status string) {
+	query := r.URL.Query()
+	filter := JobFailureFilter{Kind: query.Get("kind"), ProjectID: query.Get("project_id"), Status: status}
+	if n, err := strconv.Atoi(query.Get("limit")); err == nil && n > 0 {
+		filter.Limit = min(n, maxJobFailures)
+	}
+
+	failures, err := h.manager.ListJobFailures(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list job failures", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list job failures",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": failures,
+	})
+}
+
+// handleGetJobFailure 获取失败任务，包括错误链或堆栈
+func (h *Handler) handleGetJobFailure(w http.ResponseWriter, r *http.Request) {
+	failure, _, err := h.manager.GetJobFailure(r.Context(), chi.URLParam(r, "failureId"))
+	if errors.Is(err, errJobFailureNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Job failure not found",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get job failure", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get job failure",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": failure,
+	})
+}
+
+// handleRetryJob 重新执行死信队列中的任务
+func (h *Handler) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value("user_id").(string)
+	failure, started, err := h.retryJob(r.Context(), chi.URLParam(r, "failureId"), userID)
+	switch {
+	case errors.Is(err, errJobFailureNotFound):
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Job failure not found",
+		})
+		return
+	case errors.Is(err, errJobNotRetryable):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Job cannot be retried",
+			"details": "the job is not in the dead-letter queue, its input was not retained, or the pipeline is not configured",
+		})
+		return
+	case errors.Is(err, errSyncInProgress):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Sync already in progress",
+		})
+		return
+	case err != nil:
+		h.logger.Error("failed to retry job", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to retry job",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("job retried",
+		zap.String("failure_id", failure.ID),
+		zap.String("kind", failure.Kind),
+		zap.String("job_id", failure.RetriedAs),
+	)
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]interface{}{
+		"data": map[string]interface{}{
+			"failure": failure,
+			"job":     started,
+		},
+	})
+}
+
+// handleDiscardDeadLetter 丢弃死信队列中的任务，任务保持失败状态
+func (h *Handler) handleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	failure, payload, err := h.manager.GetJobFailure(r.Context(), chi.URLParam(r, "failureId"))
+	if err == nil && failure.Status != JobFailureDead {
+		err = errJobFailureNotFound
+	}
+	if errors.Is(err, errJobFailureNotFound) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Job not in the dead-letter queue",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get job failure", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to discard job",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
+	if err := h.resolveJobFailure(r.Context(), failure, payload, JobFailureDiscarded, userID); err != nil {
+		h.logger.Error("failed to discard job", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to discard job",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": failure,
+	})
+}
+
+// handlePurgeDeadLetters 丢弃死信队列中符合 kind、project_id 条件的全部任务
+func (h *Handler) handlePurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	userID, _ := r.Context().Value("user_id").(string)
+	discarded, err := h.purgeDeadLetters(r.Context(), query.Get("kind"), query.Get("project_id"), userID)
+	if err != nil {
+		h.logger.Error("failed to purge dead letters", zap.Int("discarded", discarded), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to purge dead-letter queue",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("dead-letter queue purged", zap.Int("discarded", discarded))
+	render.JSON(w, r, map[string]interface{}{
+		"data": map[string]interface{}{
+			"discarded": discarded,
+		},
+	})
+}
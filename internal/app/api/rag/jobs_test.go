@@ -0,0 +1,142 @@
+package rag
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestJobDeadLetterQueue(t *testing.T) {
+	h, router := newDownloadTestHandler(t, nil)
+	router.Route("/admin/v1/jobs", h.RegisterJobRoutes)
+	indexer := &fakeIndexer{docs: make(chan core.Document, 4), fail: errors.New("embedding service unavailable")}
+	h.indexer = indexer
+
+	do := func(method, path string, body *bytes.Buffer, contentType string, out interface{}) int {
+		if body == nil {
+			body = &bytes.Buffer{}
+		}
+		req := httptest.NewRequest(method, path, body)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if out != nil {
+			json.Unmarshal(rec.Body.Bytes(), &struct {
+				Data interface{} `json:"data"`
+			}{out})
+		}
+		return rec.Code
+	}
+	upload := func(name string) IngestJob {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", name)
+		part.Write([]byte("Rotate the signing keys every quarter."))
+		writer.Close()
+		var jobs []IngestJob
+		if code := do(http.MethodPost, "/projects/p1/documents", body, writer.FormDataContentType(), &jobs); code != http.StatusAccepted || len(jobs) != 1 {
+			t.Fatalf("upload failed: %d", code)
+		}
+		<-indexer.docs
+		return jobs[0]
+	}
+	waitJob := func(id, status string) IngestJob {
+		var job IngestJob
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			do(http.MethodGet, "/projects/p1/documents/jobs/"+id, nil, "", &job)
+			if job.Status == status {
+				return job
+			}
+		}
+		t.Fatalf("job %s did not reach %s: %+v", id, status, job)
+		return job
+	}
+
+	first := upload("keys.md")
+	waitJob(first.ID, IngestStatusFailed)
+
+	var dead []JobFailure
+	do(http.MethodGet, "/admin/v1/jobs/dead-letter", nil, "", &dead)
+	if len(dead) != 1 || dead[0].JobID != first.ID || dead[0].Kind != JobKindIngest || !dead[0].Retryable {
+		t.Fatalf("expected the failed upload in the dead-letter queue, got %+v", dead)
+	}
+	var failure JobFailure
+	do(http.MethodGet, "/admin/v1/jobs/failures/"+dead[0].ID, nil, "", &failure)
+	if failure.Error != "embedded: embedding service unavailable" || failure.Trace == "" {
+		t.Fatalf("expected the failed stage and trace, got %+v", failure)
+	}
+
+	var overview JobsOverview
+	do(http.MethodGet, "/admin/v1/jobs/", nil, "", &overview)
+	if len(overview.Queues) != 3 || overview.Queues[0].Kind != JobKindIngest || overview.Queues[0].Dead != 1 ||
+		overview.Queues[0].Failed24h != 1 || overview.Queues[0].InFlight != 0 || len(overview.Workers) != 0 {
+		t.Fatalf("unexpected overview %+v", overview)
+	}
+
+	// Retrying reruns the same job from the retained upload
+	indexer.fail = nil
+	var retry struct {
+		Failure JobFailure `json:"failure"`
+		Job     IngestJob  `json:"job"`
+	}
+	if code := do(http.MethodPost, "/admin/v1/jobs/failures/"+dead[0].ID+"/retry", nil, "", &retry); code != http.StatusAccepted {
+		t.Fatalf("retry failed: %d", code)
+	}
+	if retry.Failure.Status != JobFailureRetried || retry.Failure.RetriedAs != first.ID || retry.Job.Attempts != 1 {
+		t.Fatalf("unexpected retry %+v", retry)
+	}
+	if doc := <-indexer.docs; doc.Content != "Rotate the signing keys every quarter." {
+		t.Fatalf("expected the retained upload to be reindexed, got %q", doc.Content)
+	}
+	if job := waitJob(first.ID, IngestStatusCompleted); job.Error != nil || job.Attempts != 1 {
+		t.Fatalf("unexpected retried job %+v", job)
+	}
+	if code := do(http.MethodPost, "/admin/v1/jobs/failures/"+dead[0].ID+"/retry", nil, "", nil); code != http.StatusConflict {
+		t.Fatalf("expected a resolved failure not to be retried again, got %d", code)
+	}
+	if _, _, err := h.objects.Get(t.Context(), jobPayloadPrefix+dead[0].ID); err == nil {
+		t.Fatal("expected the retained upload to be deleted after the retry")
+	}
+
+	// Discarding leaves the job failed and empties the queue
+	indexer.fail = errors.New("index unavailable")
+	second := upload("rotation.md")
+	waitJob(second.ID, IngestStatusFailed)
+	third := upload("audit.md")
+	waitJob(third.ID, IngestStatusFailed)
+	do(http.MethodGet, "/admin/v1/jobs/dead-letter", nil, "", &dead)
+	if len(dead) != 2 {
+		t.Fatalf("expected two dead letters, got %+v", dead)
+	}
+	if code := do(http.MethodDelete, "/admin/v1/jobs/dead-letter/"+dead[0].ID, nil, "", &failure); code != http.StatusOK || failure.Status != JobFailureDiscarded {
+		t.Fatalf("discard failed: %d %+v", code, failure)
+	}
+	if code := do(http.MethodDelete, "/admin/v1/jobs/dead-letter/"+dead[0].ID, nil, "", nil); code != http.StatusNotFound {
+		t.Fatalf("expected a discarded job to leave the queue, got %d", code)
+	}
+	var purged struct {
+		Discarded int `json:"discarded"`
+	}
+	if do(http.MethodDelete, "/admin/v1/jobs/dead-letter?kind=ingest", nil, "", &purged); purged.Discarded != 1 {
+		t.Fatalf("expected one purged dead letter, got %d", purged.Discarded)
+	}
+
+	var failures []JobFailure
+	do(http.MethodGet, "/admin/v1/jobs/failures?status=discarded", nil, "", &failures)
+	if len(failures) != 2 {
+		t.Fatalf("expected discarded failures to stay listed, got %+v", failures)
+	}
+	do(http.MethodGet, "/admin/v1/jobs/dead-letter", nil, "", &dead)
+	if len(dead) != 0 {
+		t.Fatalf("expected an empty dead-letter queue, got %+v", dead)
+	}
+}
@@ -0,0 +1,231 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// defaultKeyEventLimit 默认返回的密钥使用事件数
+const defaultKeyEventLimit = 100
+
+// ListTenantKeys 列出租户数据密钥的所有版本，按版本升序
+func (m *Manager) ListTenantKeys(ctx context.Context, tenantID string) ([]core.TenantKey, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT version, kms_key_id, wrapped_key, active, created_at, rewrapped_at
+		FROM rag_tenant_keys WHERE tenant_id = ? ORDER BY version`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []core.TenantKey
+	for rows.Next() {
+		key := core.TenantKey{TenantID: tenantID}
+		var rewrappedAt sql.NullTime
+		if err := rows.Scan(&key.Version, &key.KMSKeyID, &key.WrappedKey, &key.Active, &key.CreatedAt, &rewrappedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant key: %w", err)
+		}
+		if rewrappedAt.Valid {
+			key.RewrappedAt = &rewrappedAt.Time
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// SaveTenantKeys 在一个事务中保存租户数据密钥版本
+func (m *Manager) SaveTenantKeys(ctx context.Context, keys []core.TenantKey) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, key := range keys {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO rag_tenant_keys (tenant_id, version, kms_key_id, wrapped_key, active, created_at, rewrapped_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(tenant_id, version) DO UPDATE SET
+				kms_key_id = excluded.kms_key_id,
+				wrapped_key = excluded.wrapped_key,
+				active = excluded.active,
+				rewrapped_at = excluded.rewrapped_at`,
+			key.TenantID, key.Version, key.KMSKeyID, key.WrappedKey, key.Active, key.CreatedAt, key.RewrappedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save tenant key: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordKeyUsage 记录密钥使用审计事件
+func (m *Manager) RecordKeyUsage(ctx context.Context, event core.KeyUsageEvent) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO rag_tenant_key_events (tenant_id, key_version, kms_key_id, operation, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		event.TenantID, event.KeyVersion, event.KMSKeyID, string(event.Operation), event.At,
+	)
+	if err != nil {
+		m.logger.Warn("failed to record key usage", zap.String("tenant_id", event.TenantID), zap.Error(err))
+		return fmt.Errorf("failed to record key usage: %w", err)
+	}
+	return nil
+}
+
+// ListKeyUsage 列出租户最近的密钥使用事件，最新的在前
+func (m *Manager) ListKeyUsage(ctx context.Context, tenantID string, limit int) ([]core.KeyUsageEvent, error) {
+	if limit <= 0 {
+		limit = defaultKeyEventLimit
+	}
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT key_version, kms_key_id, operation, created_at
+		FROM rag_tenant_key_events WHERE tenant_id = ?
+		ORDER BY created_at DESC, id DESC LIMIT ?`,
+		tenantID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key usage: %w", err)
+	}
+	defer rows.Close()
+
+	events := []core.KeyUsageEvent{}
+	for rows.Next() {
+		event := core.KeyUsageEvent{TenantID: tenantID}
+		var operation string
+		if err := rows.Scan(&event.KeyVersion, &event.KMSKeyID, &operation, &event.At); err != nil {
+			return nil, fmt.Errorf("failed to scan key usage: %w", err)
+		}
+		event.Operation = core.KeyOperation(operation)
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// ProjectTenant 返回项目所属租户，项目不存在时返回空
+func (m *Manager) ProjectTenant(ctx context.Context, projectID string) (string, error) {
+	var tenantID string
+	err := m.db.QueryRowContext(ctx, `SELECT tenant_id FROM projects WHERE id = ?`, projectID).Scan(&tenantID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get project tenant: %w", err)
+	}
+	return tenantID, nil
+}
+
+// keyRing 返回管道的密钥环，未启用加密时返回 nil
+func (h *Handler) keyRing(w http.ResponseWriter, r *http.Request) *core.KeyRing {
+	var ring *core.KeyRing
+	if h.pipeline != nil {
+		ring = h.pipeline.KeyRing()
+	}
+	if ring == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Encryption at rest is not enabled",
+		})
+	}
+	return ring
+}
+
+// handleListTenantKeys 列出租户数据密钥版本，不含密钥材料
+func (h *Handler) handleListTenantKeys(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ring := h.keyRing(w, r)
+	if ring == nil {
+		return
+	}
+
+	keys, err := ring.Keys(r.Context(), tenantID)
+	if err != nil {
+		h.logger.Error("failed to list tenant keys", zap.String("tenant_id", tenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list keys",
+			"details": err.Error(),
+		})
+		return
+	}
+	if keys == nil {
+		keys = []core.TenantKey{}
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": keys,
+	})
+}
+
+// rotateKeyRequest 密钥轮换请求，kms_key_id 为空时沿用当前 KMS 密钥
+type rotateKeyRequest struct {
+	KMSKeyID string `json:"kms_key_id,omitempty"`
+}
+
+// handleRotateTenantKey 轮换租户数据密钥：新数据使用新版本，旧版本重新包装，已有数据无需重新加密
+func (h *Handler) handleRotateTenantKey(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	ring := h.keyRing(w, r)
+	if ring == nil {
+		return
+	}
+
+	var req rotateKeyRequest
+	if r.ContentLength != 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	key, err := ring.Rotate(r.Context(), tenantID, req.KMSKeyID)
+	if err != nil {
+		h.logger.Error("failed to rotate tenant key", zap.String("tenant_id", tenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to rotate key",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("tenant key rotated", zap.String("tenant_id", tenantID), zap.Int("version", key.Version))
+	render.JSON(w, r, map[string]interface{}{
+		"data": key,
+	})
+}
+
+// handleListKeyUsage 列出租户密钥使用审计事件
+func (h *Handler) handleListKeyUsage(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	events, err := h.manager.ListKeyUsage(r.Context(), tenantID, limit)
+	if err != nil {
+		h.logger.Error("failed to list key usage", zap.String("tenant_id", tenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list key usage",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": events,
+	})
+}
@@ -0,0 +1,40 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestTenantKeyStore(t *testing.T) {
+	ctx := context.Background()
+	h, _ := newBotTestHandler(t, nil)
+	kms, err := core.NewLocalKMS("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ring := core.NewKeyRing(kms, h.manager, h.manager, "")
+
+	ciphertext, err := ring.Encrypt(ctx, "t1", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.Rotate(ctx, "t1", "byok-key"); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := h.manager.ListTenantKeys(ctx, "t1")
+	if err != nil || len(keys) != 2 || keys[0].Active || !keys[1].Active || keys[0].KMSKeyID != "byok-key" || keys[0].RewrappedAt == nil {
+		t.Fatalf("unexpected stored keys %+v %v", keys, err)
+	}
+	reloaded := core.NewKeyRing(kms, h.manager, h.manager, "")
+	if plaintext, err := reloaded.Decrypt(ctx, ciphertext); err != nil || string(plaintext) != "hello" {
+		t.Fatalf("expected data written before rotation to decrypt, got %q %v", plaintext, err)
+	}
+
+	events, err := h.manager.ListKeyUsage(ctx, "t1", 0)
+	if err != nil || len(events) != 4 || events[0].Operation != core.KeyUnwrapped || events[len(events)-1].Operation != core.KeyCreated {
+		t.Fatalf("unexpected key usage events %+v %v", events, err)
+	}
+}
@@ -0,0 +1,434 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// Manager 项目级RAG配置管理器，实现 core.ProjectConfigStore、core.BudgetStore、core.DocumentVersionStore、core.TombstoneStore 与 core.GlossaryStore，并保存项目数据源定义
+type Manager struct {
+	db     *sql.DB
+	logger *zap.Logger
+	global *core.Config
+}
+
+// NewManager 创建新的项目RAG配置管理器
+func NewManager(db *sql.DB, global *core.Config, logger *zap.Logger) *Manager {
+	if global == nil {
+		global = core.DefaultConfig()
+	}
+	return &Manager{
+		db:     db,
+		logger: logger,
+		global: global,
+	}
+}
+
+// Initialize 初始化数据库表
+func (m *Manager) Initialize(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS rag_project_settings (
+		project_id TEXT PRIMARY KEY,
+		settings TEXT NOT NULL DEFAULT '{}',
+		updated_by TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_tenant_budgets (
+		tenant_id TEXT PRIMARY KEY,
+		settings TEXT NOT NULL DEFAULT '{}',
+		updated_by TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_tenant_usage (
+		tenant_id TEXT NOT NULL,
+		cycle_start TEXT NOT NULL,
+		tokens INTEGER NOT NULL DEFAULT 0,
+		cost REAL NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (tenant_id, cycle_start)
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_tenant_budget_alerts (
+		tenant_id TEXT NOT NULL,
+		cycle_start TEXT NOT NULL,
+		alert TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (tenant_id, cycle_start, alert)
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_document_versions (
+		document_id TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		content_hash TEXT NOT NULL,
+		snapshot TEXT NOT NULL,
+		created_at_ns INTEGER NOT NULL,
+		PRIMARY KEY (document_id, version)
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_document_tombstones (
+		document_id TEXT PRIMARY KEY,
+		tombstone TEXT NOT NULL,
+		deleted_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_data_sources (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		definition TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_data_source_syncs (
+		id TEXT PRIMARY KEY,
+		source_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		run TEXT NOT NULL,
+		started_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_bot_channels (
+		platform TEXT NOT NULL,
+		channel_id TEXT NOT NULL,
+		project_id TEXT NOT NULL,
+		definition TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (platform, channel_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_widget_tokens (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		definition TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_search_collections (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		created_by TEXT,
+		shared BOOLEAN NOT NULL DEFAULT FALSE,
+		definition TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_search_collections_project ON rag_search_collections(project_id, updated_at);
+
+	CREATE TABLE IF NOT EXISTS rag_saved_searches (
+		id TEXT PRIMARY KEY,
+		collection_id TEXT NOT NULL,
+		project_id TEXT NOT NULL,
+		definition TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_saved_searches_collection ON rag_saved_searches(collection_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rag_saved_search_runs (
+		id TEXT PRIMARY KEY,
+		search_id TEXT NOT NULL,
+		run TEXT NOT NULL,
+		ran_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_saved_search_runs_search ON rag_saved_search_runs(search_id, ran_at);
+
+	CREATE TABLE IF NOT EXISTS rag_chat_transcripts (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		tenant_id TEXT,
+		user_id TEXT,
+		started_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_chat_transcripts_project ON rag_chat_transcripts(project_id, user_id);
+	CREATE INDEX IF NOT EXISTS idx_rag_chat_transcripts_tenant ON rag_chat_transcripts(tenant_id, updated_at);
+
+	CREATE TABLE IF NOT EXISTS rag_chat_turns (
+		id TEXT PRIMARY KEY,
+		transcript_id TEXT NOT NULL,
+		turn TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_chat_turns_transcript ON rag_chat_turns(transcript_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rag_review_settings (
+		project_id TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_review_items (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		query_id TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		status TEXT NOT NULL,
+		priority INTEGER NOT NULL DEFAULT 0,
+		item TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_review_items_queue ON rag_review_items(project_id, status, priority, created_at);
+	CREATE INDEX IF NOT EXISTS idx_rag_review_items_query ON rag_review_items(project_id, query_id);
+
+	CREATE TABLE IF NOT EXISTS rag_query_feedback (
+		query_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		project_id TEXT NOT NULL,
+		feedback TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (query_id, user_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_eval_cases (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		review_id TEXT,
+		entry TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_eval_cases_project ON rag_eval_cases(project_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rag_ingest_jobs (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		job TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_ingest_jobs_project ON rag_ingest_jobs(project_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rag_job_failures (
+		id TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		job_id TEXT NOT NULL,
+		project_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		failure TEXT NOT NULL,
+		payload TEXT,
+		failed_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_job_failures_status ON rag_job_failures(status, failed_at);
+
+	CREATE TABLE IF NOT EXISTS rag_document_failures (
+		document_id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		data_source_id TEXT NOT NULL,
+		quarantined BOOLEAN NOT NULL DEFAULT FALSE,
+		failure TEXT NOT NULL,
+		last_failed_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_document_failures_project ON rag_document_failures(project_id, data_source_id);
+
+	CREATE TABLE IF NOT EXISTS rag_query_records (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		tenant_id TEXT,
+		query TEXT NOT NULL,
+		normalized_query TEXT NOT NULL,
+		processed_query TEXT,
+		user_id TEXT,
+		session_id TEXT,
+		result_count INTEGER NOT NULL DEFAULT 0,
+		top_score REAL NOT NULL DEFAULT 0,
+		latency_ms REAL NOT NULL DEFAULT 0,
+		cache_hit BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_query_records_project ON rag_query_records(project_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rag_chunk_hits (
+		project_id TEXT NOT NULL,
+		chunk_id TEXT NOT NULL,
+		document_id TEXT NOT NULL,
+		hits INTEGER NOT NULL DEFAULT 0,
+		last_hit_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (project_id, chunk_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_chunk_hits_document ON rag_chunk_hits(project_id, document_id);
+
+	CREATE TABLE IF NOT EXISTS rag_content_gaps (
+		project_id TEXT PRIMARY KEY,
+		report TEXT NOT NULL,
+		generated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_glossaries (
+		project_id TEXT PRIMARY KEY,
+		glossary TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_index_snapshots (
+		id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		metadata TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_index_snapshots_project ON rag_index_snapshots(project_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rag_storage_usage (
+		project_id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		usage TEXT NOT NULL,
+		total_bytes INTEGER NOT NULL,
+		measured_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_rag_storage_usage_tenant ON rag_storage_usage(tenant_id);
+
+	CREATE TABLE IF NOT EXISTS rag_cold_documents (
+		document_id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		record TEXT NOT NULL,
+		moved_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_document_pins (
+		document_id TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		pinned_by TEXT NOT NULL,
+		pinned_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_tenant_keys (
+		tenant_id TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		kms_key_id TEXT NOT NULL,
+		wrapped_key BLOB NOT NULL,
+		active INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP NOT NULL,
+		rewrapped_at TIMESTAMP,
+		PRIMARY KEY (tenant_id, version)
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_tenant_key_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tenant_id TEXT NOT NULL,
+		key_version INTEGER NOT NULL,
+		kms_key_id TEXT NOT NULL,
+		operation TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_rag_tenant_key_events_tenant ON rag_tenant_key_events(tenant_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS rag_download_files (
+		object_key TEXT PRIMARY KEY,
+		project_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		ref_id TEXT NOT NULL,
+		file_name TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rag_download_tokens (
+		nonce TEXT PRIMARY KEY,
+		expires_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_rag_download_tokens_expires ON rag_download_tokens(expires_at);
+	`
+
+	_, err := m.db.ExecContext(ctx, query)
+	if err != nil {
+		m.logger.Error("failed to initialize rag tables", zap.Error(err))
+		return fmt.Errorf("failed to initialize rag tables: %w", err)
+	}
+
+	return nil
+}
+
+// GlobalConfig 返回全局默认配置
+func (m *Manager) GlobalConfig() *core.Config {
+	return m.global
+}
+
+// GetProjectConfig 获取项目配置覆盖，不存在时返回 nil
+func (m *Manager) GetProjectConfig(ctx context.Context, projectID string) (*core.ProjectConfig, error) {
+	var settings string
+	var updatedBy sql.NullString
+	var updatedAt time.Time
+
+	err := m.db.QueryRowContext(ctx,
+		`SELECT settings, updated_by, updated_at FROM rag_project_settings WHERE project_id = ?`,
+		projectID,
+	).Scan(&settings, &updatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project rag settings: %w", err)
+	}
+
+	var config core.ProjectConfig
+	if err := json.Unmarshal([]byte(settings), &config); err != nil {
+		return nil, fmt.Errorf("failed to decode project rag settings: %w", err)
+	}
+	config.ProjectID = projectID
+	config.UpdatedBy = updatedBy.String
+	config.UpdatedAt = updatedAt
+
+	return &config, nil
+}
+
+// SaveProjectConfig 保存项目配置覆盖
+func (m *Manager) SaveProjectConfig(ctx context.Context, config *core.ProjectConfig) error {
+	if err := config.Validate(m.global); err != nil {
+		return err
+	}
+
+	config.UpdatedAt = time.Now()
+	settings, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to encode project rag settings: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_project_settings (project_id, settings, updated_by, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(project_id) DO UPDATE SET
+			settings = excluded.settings,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at`,
+		config.ProjectID, string(settings), config.UpdatedBy, config.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save project rag settings: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteProjectConfig 删除项目配置覆盖，恢复全局默认
+func (m *Manager) DeleteProjectConfig(ctx context.Context, projectID string) error {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM rag_project_settings WHERE project_id = ?`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete project rag settings: %w", err)
+	}
+	return nil
+}
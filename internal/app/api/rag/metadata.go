@@ -0,0 +1,85 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/metadata"
+)
+
+// MetadataSchemaLoader 查找项目所属租户为文档元数据定义的结构，未定义时返回 nil
+type MetadataSchemaLoader func(ctx context.Context, projectID string) (*metadata.Schema, error)
+
+// MetadataSchemasFromStore 从结构存储读取项目所属租户的文档元数据结构
+func MetadataSchemasFromStore(db *sql.DB, store *metadata.Store) MetadataSchemaLoader {
+	return func(ctx context.Context, projectID string) (*metadata.Schema, error) {
+		var tenantID string
+		err := db.QueryRowContext(ctx, `SELECT tenant_id FROM projects WHERE id = ? AND deleted_at IS NULL`,
+			projectID).Scan(&tenantID)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get project tenant: %w", err)
+		}
+		return store.Schema(ctx, tenantID, metadata.TargetDocument)
+	}
+}
+
+// SetMetadataSchemas 设置文档元数据结构来源，未设置时上传的元数据不做校验
+func (h *Handler) SetMetadataSchemas(loader MetadataSchemaLoader) {
+	h.metadataSchemas = loader
+}
+
+// decodeMetadata 解析 multipart 表单中以 JSON 字符串提交的元数据
+func decodeMetadata(raw string) (map[string]interface{}, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("metadata must be a JSON object: %w", err)
+	}
+	return values, nil
+}
+
+// checkDocumentMetadata 按租户定义的结构校验上传文档的元数据，不符合时写入错误响应并返回 false
+func (h *Handler) checkDocumentMetadata(w http.ResponseWriter, r *http.Request, projectID string, values map[string]interface{}) bool {
+	if h.metadataSchemas == nil {
+		return true
+	}
+	schema, err := h.metadataSchemas(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to load metadata schema", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to validate metadata",
+			"details": err.Error(),
+		})
+		return false
+	}
+	if schema == nil {
+		return true
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	var invalid *metadata.ValidationError
+	if err := schema.Validate(values); errors.As(err, &invalid) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":      "Metadata does not match the tenant schema",
+			"details":    err.Error(),
+			"violations": invalid.Violations,
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package rag
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// PermissionCheck 判断项目角色是否拥有某项 RAG 权限
+type PermissionCheck func(ctx context.Context, projectID, role, permission string) (bool, error)
+
+// SetPermissionCheck 设置 RAG 权限检查；未设置时仅依赖路由分组的角色校验
+func (h *Handler) SetPermissionCheck(check PermissionCheck) {
+	h.permissionCheck = check
+}
+
+// SetReadCache 设置文档读接口的响应缓存中间件，挂在 RAG 权限校验之后，缓存命中时不再经过处理器
+func (h *Handler) SetReadCache(cache func(http.Handler) http.Handler) {
+	h.readCache = cache
+}
+
+// require 返回校验调用者拥有指定 RAG 权限的中间件，角色取自项目访问中间件写入的 user_role
+func (h *Handler) require(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if h.permissionCheck == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			projectID := chi.URLParam(r, "projectId")
+			role, _ := r.Context().Value("user_role").(string)
+			allowed, err := h.permissionCheck(r.Context(), projectID, role, permission)
+			if err != nil {
+				h.logger.Error("failed to check rag permission", zap.String("project_id", projectID),
+					zap.String("permission", permission), zap.Error(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, map[string]interface{}{
+					"error":   "Failed to verify permissions",
+					"details": err.Error(),
+				})
+				return
+			}
+			if !allowed {
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, map[string]interface{}{
+					"error":   "Access denied: missing RAG permission",
+					"details": permission,
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
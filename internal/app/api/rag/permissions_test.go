@@ -0,0 +1,66 @@
+package rag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+func TestRAGPermissions(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	// Analysts (viewers) may only query; collaborators may also index
+	policy := auth.RAGPolicy{
+		auth.ProjectRoleViewer:       {auth.PermRAGQuery},
+		auth.ProjectRoleCollaborator: {auth.PermRAGQuery, auth.PermRAGIndex},
+	}
+	h.SetPermissionCheck(func(ctx context.Context, projectID, role, permission string) (bool, error) {
+		return policy.Allows(role, permission), nil
+	})
+
+	do := func(role, method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		ctx := context.WithValue(req.Context(), "user_id", "u1")
+		req = req.WithContext(context.WithValue(ctx, "user_role", role))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	cases := []struct {
+		role, method, path string
+		allowed            bool
+	}{
+		{auth.ProjectRoleViewer, http.MethodGet, "/projects/p1/rag/settings", true},
+		{auth.ProjectRoleViewer, http.MethodGet, "/projects/p1/rag/analytics", false},
+		{auth.ProjectRoleViewer, http.MethodDelete, "/projects/p1/rag/snapshots/s1", false},
+		{auth.ProjectRoleCollaborator, http.MethodDelete, "/projects/p1/rag/snapshots/s1", true},
+		{auth.ProjectRoleCollaborator, http.MethodDelete, "/projects/p1/rag/datasources/ds1", false},
+		{auth.ProjectRoleCollaborator, http.MethodPut, "/projects/p1/rag/settings", false},
+		{auth.ProjectRoleOwner, http.MethodGet, "/projects/p1/rag/analytics", true},
+		{auth.ProjectRoleOwner, http.MethodPut, "/projects/p1/rag/settings", true},
+		{"", http.MethodGet, "/projects/p1/rag/settings", false},
+	}
+	for _, c := range cases {
+		code := do(c.role, c.method, c.path)
+		if (code == http.StatusForbidden) == c.allowed {
+			t.Errorf("%s %s %s: got %d, allowed=%v", c.role, c.method, c.path, code, c.allowed)
+		}
+	}
+
+	// Without overrides viewers and collaborators keep read access only
+	defaults := auth.RAGPolicy{}
+	if !defaults.Allows(auth.ProjectRoleViewer, auth.PermRAGViewAnalytics) ||
+		defaults.Allows(auth.ProjectRoleCollaborator, auth.PermRAGIndex) ||
+		!defaults.Allows(auth.RoleSuperAdmin, auth.PermRAGManagePrompts) {
+		t.Fatal("unexpected default policy")
+	}
+	if err := (auth.RAGPolicy{auth.ProjectRoleOwner: nil}).Validate(); err == nil {
+		t.Fatal("expected owner permissions to be fixed")
+	}
+	if err := (auth.RAGPolicy{auth.ProjectRoleViewer: {"rag:everything"}}).Validate(); err == nil {
+		t.Fatal("expected unknown permission to be rejected")
+	}
+}
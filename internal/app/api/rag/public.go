@@ -0,0 +1,395 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/app/api/middleware"
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+const (
+	defaultPublicRateLimit   = 10
+	defaultPublicPageSize    = 20
+	maxPublicPageSize        = 100
+	maxPublicDocumentExcerpt = 500
+)
+
+// PublicProject 可匿名只读访问的项目：项目标记为公开，且所属租户开启了公开访问
+type PublicProject struct {
+	ProjectID string
+	TenantID  string
+
+	// 每个客户端地址每分钟的请求数，默认 10
+	RateLimit int
+}
+
+// PublicProjectLoader 查找公开项目，项目不可匿名访问时返回 nil
+type PublicProjectLoader func(ctx context.Context, projectID string) (*PublicProject, error)
+
+// PublicProjectsFromDB 从项目的 is_public 标记和租户设置的 api.public_access 判断项目是否公开
+func PublicProjectsFromDB(db *sql.DB) PublicProjectLoader {
+	return func(ctx context.Context, projectID string) (*PublicProject, error) {
+		project := &PublicProject{ProjectID: projectID}
+		var raw sql.NullString
+		err := db.QueryRowContext(ctx, `
+			SELECT p.tenant_id, t.settings FROM projects p JOIN tenants t ON t.id = p.tenant_id
+			WHERE p.id = ? AND p.is_public = 1 AND p.is_active = 1 AND p.deleted_at IS NULL
+				AND t.is_active = 1 AND t.deleted_at IS NULL`,
+			projectID,
+		).Scan(&project.TenantID, &raw)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up public project: %w", err)
+		}
+		if !raw.Valid || raw.String == "" {
+			return nil, nil
+		}
+
+		var settings struct {
+			API *tenant.APISettings `json:"api"`
+		}
+		if err := json.Unmarshal([]byte(raw.String), &settings); err != nil {
+			return nil, fmt.Errorf("invalid tenant settings: %w", err)
+		}
+		if settings.API == nil || settings.API.PublicAccess == nil || !settings.API.PublicAccess.Enabled {
+			return nil, nil
+		}
+		project.RateLimit = settings.API.PublicAccess.RequestsPerMinute
+		return project, nil
+	}
+}
+
+// SetPublicProjects 设置公开项目查找，未设置时匿名访问一律拒绝
+func (h *Handler) SetPublicProjects(loader PublicProjectLoader) {
+	h.publicProjects = loader
+}
+
+// RegisterPublicRoutes 注册公开项目的匿名只读路由（挂载于 /public/v1/projects/{projectId}，无需认证，按客户端地址限流）
+func (h *Handler) RegisterPublicRoutes(r chi.Router) {
+	r.Post("/rag/query", h.handlePublicQuery)
+	r.Get("/documents", h.handleListPublicDocuments)
+	r.Get("/documents/{documentId}", h.handleGetPublicDocument)
+}
+
+// publicSource 匿名查询返回的引用，不含作者、路径等元数据
+type publicSource struct {
+	DocumentID    string  `json:"document_id"`
+	DocumentTitle string  `json:"document_title"`
+	URL           string  `json:"url,omitempty"` // 仅网页文档
+	Excerpt       string  `json:"excerpt"`
+	PageNumber    int     `json:"page_number,omitempty"`
+	Relevance     float64 `json:"relevance"`
+}
+
+// publicQueryResponse 匿名查询结果，只包含回答和引用
+type publicQueryResponse struct {
+	QueryID string         `json:"query_id"`
+	Answer  string         `json:"answer"`
+	Sources []publicSource `json:"sources"`
+}
+
+// publicDocument 匿名浏览的文档，不含作者、所有者、文件路径和自定义元数据
+type publicDocument struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url,omitempty"` // 仅网页文档
+	Tags       []string  `json:"tags,omitempty"`
+	Categories []string  `json:"categories,omitempty"`
+	Language   string    `json:"language,omitempty"`
+	WordCount  int       `json:"word_count"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Excerpt    string    `json:"excerpt,omitempty"` // 列表中返回
+	Content    string    `json:"content,omitempty"` // 单个文档中返回
+}
+
+// publicProject 解析请求的公开项目并按客户端地址限流，失败时写入响应并返回 nil
+func (h *Handler) publicProject(w http.ResponseWriter, r *http.Request) *PublicProject {
+	projectID := chi.URLParam(r, "projectId")
+	var project *PublicProject
+	var err error
+	if h.publicProjects != nil {
+		project, err = h.publicProjects(r.Context(), projectID)
+	}
+	if err != nil {
+		h.logger.Error("failed to look up public project", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Failed to look up project",
+		})
+		return nil
+	}
+	// 私有项目与不存在的项目同样返回 404，不暴露项目是否存在
+	if project == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Public project not found",
+			"code":  "not_public",
+		})
+		return nil
+	}
+
+	limit := project.RateLimit
+	if limit == 0 {
+		limit = defaultPublicRateLimit
+	}
+	if ok, retryAfter := h.publicLimiter.allow(project.ProjectID+"|"+middleware.ClientIP(r), limit, time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		render.Status(r, http.StatusTooManyRequests)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Rate limit exceeded",
+			"code":  "rate_limited",
+		})
+		return nil
+	}
+
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return nil
+	}
+	return project
+}
+
+// handlePublicQuery 匿名查询公开项目，检索限定在项目自己的数据源内
+func (h *Handler) handlePublicQuery(w http.ResponseWriter, r *http.Request) {
+	project := h.publicProject(w, r)
+	if project == nil {
+		return
+	}
+
+	var req widgetQueryRequest
+	if err := render.DecodeJSON(http.MaxBytesReader(w, r.Body, 64<<10), &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" || len([]rune(req.Query)) > maxWidgetQueryLength {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": fmt.Sprintf("Query is required and must be at most %d characters", maxWidgetQueryLength),
+		})
+		return
+	}
+
+	sources, err := h.projectDataSourceIDs(r.Context(), project.ProjectID)
+	if err != nil {
+		h.logger.Error("failed to list public project sources", zap.String("project_id", project.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Query failed",
+		})
+		return
+	}
+
+	// 单项目联合查询会丢弃不属于项目数据源的结果，即使检索器忽略了数据源过滤
+	result, err := h.query(r.Context(), req.Query, core.QueryOptions{
+		ProjectID: project.ProjectID,
+		TenantID:  project.TenantID,
+		Federation: &core.FederationOptions{Projects: []core.FederatedProject{
+			{ProjectID: project.ProjectID, DataSourceIDs: sources},
+		}},
+	})
+	if errors.Is(err, core.ErrBudgetExceeded) {
+		render.Status(r, http.StatusPaymentRequired)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "LLM budget exceeded",
+			"code":  "budget_exceeded",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("public query failed", zap.String("project_id", project.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Query failed",
+		})
+		return
+	}
+
+	response := publicQueryResponse{
+		QueryID: result.QueryID,
+		Answer:  result.GeneratedResponse,
+		Sources: make([]publicSource, 0, len(result.Sources)),
+	}
+	for _, source := range result.Sources {
+		response.Sources = append(response.Sources, publicSource{
+			DocumentID:    source.DocumentID,
+			DocumentTitle: source.DocumentTitle,
+			URL:           publicURL(source.DocumentURI),
+			Excerpt:       source.Excerpt,
+			PageNumber:    source.PageNumber,
+			Relevance:     source.Relevance,
+		})
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": response,
+	})
+}
+
+// handleListPublicDocuments 分页浏览公开项目的文档，支持 limit 和 offset 参数
+func (h *Handler) handleListPublicDocuments(w http.ResponseWriter, r *http.Request) {
+	project := h.publicProject(w, r)
+	if project == nil {
+		return
+	}
+
+	limit, offset := defaultPublicPageSize, 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxPublicPageSize {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": fmt.Sprintf("limit must be between 1 and %d", maxPublicPageSize),
+			})
+			return
+		}
+		limit = n
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "Invalid offset",
+			})
+			return
+		}
+		offset = n
+	}
+
+	documents, err := h.publicDocuments(r.Context(), project.ProjectID, limit+1, offset)
+	if err != nil {
+		h.logger.Error("failed to list public documents", zap.String("project_id", project.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Failed to list documents",
+		})
+		return
+	}
+
+	// 多取一个文档判断是否还有下一页
+	hasMore := len(documents) > limit
+	if hasMore {
+		documents = documents[:limit]
+	}
+	page := make([]publicDocument, 0, len(documents))
+	for i := range documents {
+		doc := toPublicDocument(&documents[i])
+		doc.Excerpt = truncateText(documents[i].Content, maxPublicDocumentExcerpt)
+		page = append(page, doc)
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data":     page,
+		"has_more": hasMore,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// handleGetPublicDocument 获取公开项目的单个文档及其内容
+func (h *Handler) handleGetPublicDocument(w http.ResponseWriter, r *http.Request) {
+	project := h.publicProject(w, r)
+	if project == nil {
+		return
+	}
+
+	doc, err := h.pipeline.GetDocument(r.Context(), chi.URLParam(r, "documentId"))
+	if err == nil && doc != nil {
+		var sources []string
+		if sources, err = h.projectDataSourceIDs(r.Context(), project.ProjectID); err == nil && !slices.Contains(sources, doc.DataSourceID) {
+			doc = nil
+		}
+	}
+	if err != nil {
+		h.logger.Error("failed to get public document", zap.String("project_id", project.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Failed to get document",
+		})
+		return
+	}
+	if doc == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Document not found",
+		})
+		return
+	}
+
+	public := toPublicDocument(doc)
+	public.Content = doc.Content
+	render.JSON(w, r, map[string]interface{}{
+		"data": public,
+	})
+}
+
+// publicDocuments 按标题排序分页列出项目数据源中的文档，分页由存储查询完成
+func (h *Handler) publicDocuments(ctx context.Context, projectID string, limit, offset int) ([]core.Document, error) {
+	sources, err := h.projectDataSourceIDs(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	documents, err := h.pipeline.ListDocuments(ctx, core.ListOptions{
+		Limit:  limit,
+		Offset: offset,
+		SortBy: "title",
+		Filter: core.FilterCriteria{DataSourceIDs: sources},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 存储可能忽略过滤条件，这里再按数据源筛选一次
+	scoped := documents[:0]
+	for _, doc := range documents {
+		if slices.Contains(sources, doc.DataSourceID) {
+			scoped = append(scoped, doc)
+		}
+	}
+	return scoped, nil
+}
+
+func toPublicDocument(doc *core.Document) publicDocument {
+	return publicDocument{
+		ID:         doc.ID,
+		Title:      doc.Title,
+		URL:        publicURL(doc.URI),
+		Tags:       doc.Tags,
+		Categories: doc.Categories,
+		Language:   doc.Language,
+		WordCount:  doc.Metadata.WordCount,
+		UpdatedAt:  doc.UpdatedAt,
+	}
+}
+
+// publicURL 只公开网页地址，本地路径和对象存储地址可能包含用户名等信息
+func publicURL(uri string) string {
+	if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+		return uri
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package rag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPublicProjectsFromDB(t *testing.T) {
+	ctx := context.Background()
+	h, _ := newBotTestHandler(t, nil)
+	db := h.manager.db
+	_, err := db.Exec(`
+		CREATE TABLE tenants (id TEXT PRIMARY KEY, settings TEXT, is_active BOOLEAN DEFAULT 1, deleted_at TIMESTAMP);
+		CREATE TABLE projects (id TEXT PRIMARY KEY, tenant_id TEXT, is_public BOOLEAN DEFAULT 0,
+			is_active BOOLEAN DEFAULT 1, deleted_at TIMESTAMP);
+		INSERT INTO tenants (id, settings) VALUES
+			('acme', '{"api":{"public_access":{"enabled":true,"requests_per_minute":5}}}'),
+			('initech', '{"api":{"cors":{"allowed_origins":["*"]}}}');
+		INSERT INTO projects (id, tenant_id, is_public) VALUES
+			('docs', 'acme', 1), ('internal', 'acme', 0), ('handbook', 'initech', 1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := PublicProjectsFromDB(db)
+
+	project, err := load(ctx, "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project == nil || project.TenantID != "acme" || project.RateLimit != 5 {
+		t.Fatalf("unexpected public project %+v", project)
+	}
+
+	// Private projects, and public projects of tenants that have not opted in, stay closed
+	for _, id := range []string{"internal", "handbook", "missing"} {
+		project, err := load(ctx, id)
+		if err != nil || project != nil {
+			t.Fatalf("expected %s to be closed to anonymous access, got %+v %v", id, project, err)
+		}
+	}
+
+	// Database failures are reported instead of treated as a private project
+	if _, err := db.Exec(`DROP TABLE tenants`); err != nil {
+		t.Fatal(err)
+	}
+	if project, err := load(ctx, "docs"); err == nil || project != nil {
+		t.Fatalf("expected the lookup error to be returned, got %+v %v", project, err)
+	}
+}
+
+func TestPublicRoutes(t *testing.T) {
+	h, r := newBotTestHandler(t, nil)
+	r.Route("/public/projects/{projectId}", h.RegisterPublicRoutes)
+
+	get := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/public/projects/docs/documents", "10.0.0.1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without public projects, got %d", rec.Code)
+	}
+
+	h.SetPublicProjects(func(ctx context.Context, projectID string) (*PublicProject, error) {
+		if projectID != "docs" {
+			return nil, nil
+		}
+		return &PublicProject{ProjectID: projectID, TenantID: "acme", RateLimit: 1}, nil
+	})
+	if rec := get("/public/projects/internal/documents", "10.0.0.1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a private project, got %d", rec.Code)
+	}
+
+	// The pipeline is checked after the rate limit, so the first request reaches it
+	if rec := get("/public/projects/docs/documents", "10.0.0.1"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without pipeline, got %d", rec.Code)
+	}
+	rec := get("/public/projects/docs/documents/doc-1", "10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected second request to be rate limited, got %d", rec.Code)
+	}
+	if rec := get("/public/projects/docs/documents", "10.0.0.2"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected other clients to keep their own limit, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/public/projects/docs/rag/query", strings.NewReader(`{"query":"pricing"}`))
+	req.RemoteAddr = "10.0.0.3:1234"
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without pipeline, got %d", rec.Code)
+	}
+}
+
+func TestPublicURL(t *testing.T) {
+	if got := publicURL("https://docs.example.com/pricing"); got != "https://docs.example.com/pricing" {
+		t.Fatalf("expected web URL to be kept, got %q", got)
+	}
+	for _, uri := range []string{"/home/alice/notes.md", "s3://bucket/hr/salaries.csv", "file:///srv/docs/a.txt"} {
+		if got := publicURL(uri); got != "" {
+			t.Fatalf("expected %q to be hidden, got %q", uri, got)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// GetDocumentFailure 获取数据源文档的同步失败记录，无记录时返回 nil
+func (m *Manager) GetDocumentFailure(ctx context.Context, documentID string) (*core.DocumentFailure, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT failure FROM rag_document_failures WHERE document_id = ?`, documentID,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document failure: %w", err)
+	}
+
+	var failure core.DocumentFailure
+	if err := json.Unmarshal([]byte(data), &failure); err != nil {
+		return nil, fmt.Errorf("failed to decode document failure: %w", err)
+	}
+	return &failure, nil
+}
+
+// SaveDocumentFailure 保存数据源文档的同步失败记录
+func (m *Manager) SaveDocumentFailure(ctx context.Context, failure *core.DocumentFailure) error {
+	data, err := json.Marshal(failure)
+	if err != nil {
+		return fmt.Errorf("failed to encode document failure: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_document_failures (document_id, project_id, data_source_id, quarantined, failure, last_failed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (document_id) DO UPDATE SET
+			project_id = excluded.project_id,
+			data_source_id = excluded.data_source_id,
+			quarantined = excluded.quarantined,
+			failure = excluded.failure,
+			last_failed_at = excluded.last_failed_at`,
+		failure.DocumentID, failure.ProjectID, failure.DataSourceID, failure.Quarantined(), string(data), failure.LastFailedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save document failure: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocumentFailure 删除数据源文档的同步失败记录
+func (m *Manager) DeleteDocumentFailure(ctx context.Context, documentID string) error {
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM rag_document_failures WHERE document_id = ?`, documentID); err != nil {
+		return fmt.Errorf("failed to delete document failure: %w", err)
+	}
+	return nil
+}
+
+// ListDocumentFailures 按最近失败时间倒序列出项目中同步失败的文档。sourceID 为空时列出所有数据源，
+// quarantinedOnly 时只列出已隔离的文档
+func (m *Manager) ListDocumentFailures(ctx context.Context, projectID, sourceID string, quarantinedOnly bool) ([]core.DocumentFailure, error) {
+	query := `SELECT failure FROM rag_document_failures WHERE project_id = ?`
+	args := []interface{}{projectID}
+	if sourceID != "" {
+		query += ` AND data_source_id = ?`
+		args = append(args, sourceID)
+	}
+	if quarantinedOnly {
+		query += ` AND quarantined = ?`
+		args = append(args, true)
+	}
+	query += ` ORDER BY last_failed_at DESC`
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document failures: %w", err)
+	}
+	defer rows.Close()
+
+	failures := []core.DocumentFailure{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list document failures: %w", err)
+		}
+		var failure core.DocumentFailure
+		if err := json.Unmarshal([]byte(data), &failure); err != nil {
+			return nil, fmt.Errorf("failed to decode document failure: %w", err)
+		}
+		failures = append(failures, failure)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list document failures: %w", err)
+	}
+	return failures, nil
+}
+
+// countQuarantined 统计所有项目中已隔离的文档数
+func (m *Manager) countQuarantined(ctx context.Context) (int, error) {
+	var count int
+	err := m.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM rag_document_failures WHERE quarantined = ?`, true,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count quarantined documents: %w", err)
+	}
+	return count, nil
+}
+
+// handleListQuarantine 列出项目中已隔离的文档，支持 source_id 参数；all=true 时包括尚未达到重试上限的失败文档
+func (h *Handler) handleListQuarantine(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	query := r.URL.Query()
+	failures, err := h.manager.ListDocumentFailures(r.Context(), projectID, query.Get("source_id"), query.Get("all") != "true")
+	if err != nil {
+		h.logger.Error("failed to list quarantined documents", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list quarantined documents",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": failures,
+	})
+}
+
+// handleRequeueDocument 将文档移出隔离区并重新索引，数据源未加载时由下次同步索引
+func (h *Handler) handleRequeueDocument(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	documentID := chi.URLParam(r, "documentId")
+	failure, err := h.manager.GetDocumentFailure(r.Context(), documentID)
+	if err != nil {
+		h.logger.Error("failed to get document failure", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to requeue document",
+			"details": err.Error(),
+		})
+		return
+	}
+	if failure == nil || failure.ProjectID != projectID {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Document has no recorded failures",
+		})
+		return
+	}
+
+	result, err := h.pipeline.RequeueDocument(r.Context(), documentID)
+	if errors.Is(err, core.ErrNoDocumentFailure) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Document has no recorded failures",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to requeue document",
+			zap.String("project_id", projectID),
+			zap.String("document_id", documentID),
+			zap.Error(err),
+		)
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to requeue document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// 重新索引仍失败时返回新的失败记录
+	retried, err := h.manager.GetDocumentFailure(r.Context(), documentID)
+	if err != nil {
+		h.logger.Warn("failed to get document failure", zap.String("document_id", documentID), zap.Error(err))
+	}
+
+	h.logger.Info("document requeued",
+		zap.String("project_id", projectID),
+		zap.String("document_id", documentID),
+		zap.Bool("indexed", result != nil && retried == nil),
+	)
+	render.JSON(w, r, map[string]interface{}{
+		"data": map[string]interface{}{
+			"document_id": documentID,
+			"result":      result,
+			"failure":     retried,
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestDocumentFailureStore(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	ctx := context.Background()
+	now := time.Now()
+
+	failing := &core.DocumentFailure{DocumentID: "d1", ProjectID: "p1", DataSourceID: "s1", Error: "bad encoding", Attempts: 1, FirstFailedAt: now, LastFailedAt: now}
+	quarantined := &core.DocumentFailure{DocumentID: "d2", ProjectID: "p1", DataSourceID: "s2", Error: "panic: parser crashed", Attempts: 3, FirstFailedAt: now, LastFailedAt: now.Add(time.Second), QuarantinedAt: &now}
+	other := &core.DocumentFailure{DocumentID: "d3", ProjectID: "p2", DataSourceID: "s3", Attempts: 3, LastFailedAt: now, QuarantinedAt: &now}
+	for _, failure := range []*core.DocumentFailure{failing, quarantined, other} {
+		if err := h.manager.SaveDocumentFailure(ctx, failure); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := func(query string) []core.DocumentFailure {
+		req := httptest.NewRequest(http.MethodGet, "/projects/p1/rag/quarantine"+query, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list failed: %d %s", rec.Code, rec.Body)
+		}
+		var resp struct {
+			Data []core.DocumentFailure `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp.Data
+	}
+	if got := list(""); len(got) != 1 || got[0].DocumentID != "d2" || !got[0].Quarantined() {
+		t.Fatalf("expected only the project's quarantined document, got %+v", got)
+	}
+	if got := list("?all=true"); len(got) != 2 || got[0].DocumentID != "d2" || got[1].DocumentID != "d1" {
+		t.Fatalf("expected failing documents too, most recent first, got %+v", got)
+	}
+	if got := list("?all=true&source_id=s1"); len(got) != 1 || got[0].DocumentID != "d1" {
+		t.Fatalf("expected the data source filter to apply, got %+v", got)
+	}
+
+	// A further failure updates the quarantine flag used for listing
+	failing.Attempts, failing.QuarantinedAt = 3, &now
+	if err := h.manager.SaveDocumentFailure(ctx, failing); err != nil {
+		t.Fatal(err)
+	}
+	if got := list(""); len(got) != 2 {
+		t.Fatalf("expected both documents quarantined, got %+v", got)
+	}
+	if count, err := h.manager.countQuarantined(ctx); err != nil || count != 3 {
+		t.Fatalf("countQuarantined = %d, %v", count, err)
+	}
+
+	if err := h.manager.DeleteDocumentFailure(ctx, "d2"); err != nil {
+		t.Fatal(err)
+	}
+	if failure, err := h.manager.GetDocumentFailure(ctx, "d2"); err != nil || failure != nil {
+		t.Fatalf("expected the failure to be deleted, got %+v %v", failure, err)
+	}
+}
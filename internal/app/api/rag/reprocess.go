@@ -0,0 +1,118 @@
+package rag
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+	"github.com/guileen/metabase/pkg/rag/embedding"
+	"github.com/guileen/metabase/pkg/rag/processors"
+)
+
+// reprocessDocumentRequest 单文档重新处理请求，未设置的配置项沿用项目当前配置
+type reprocessDocumentRequest struct {
+	Chunking  *core.ChunkingConfig  `json:"chunking,omitempty"`
+	Embedding *core.EmbeddingConfig `json:"embedding,omitempty"` // 须与当前索引一致，或为正在进行的嵌入迁移的目标模型
+}
+
+// handleReprocessDocument 按可选的分块与嵌入覆盖项重新处理单个文档，无需重建整个索引
+func (h *Handler) handleReprocessDocument(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req reprocessDocumentRequest
+	if r.ContentLength > 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	documentID := chi.URLParam(r, "documentId")
+	options := core.ReprocessOptions{
+		NewStrategy: processors.NewChunkingStrategy,
+	}
+	if req.Chunking != nil {
+		config, err := h.pipeline.EffectiveConfig(r.Context(), projectID)
+		if err != nil {
+			h.logger.Error("failed to load project config", zap.String("project_id", projectID), zap.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Failed to reprocess document",
+				"details": err.Error(),
+			})
+			return
+		}
+		merged := mergeChunkingConfig(config.Processing.Chunking, *req.Chunking)
+		if _, err := processors.NewChunkingStrategy(merged, nil); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid chunking override",
+				"details": err.Error(),
+			})
+			return
+		}
+		options.Chunking = &merged
+	}
+	if req.Embedding != nil {
+		if req.Embedding.Model == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "embedding.model is required",
+			})
+			return
+		}
+		generator, err := embedding.CreateGenerator(req.Embedding.Model, embedding.VectorGeneratorConfig{
+			ModelName: req.Embedding.Model,
+			BatchSize: h.pipeline.Config().Processing.Embedding.BatchSize,
+		})
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid embedding override",
+				"details": err.Error(),
+			})
+			return
+		}
+		defer generator.Close()
+		options.Embedding = req.Embedding
+		options.Generator = generator
+	}
+
+	result, err := h.pipeline.ReprocessDocument(r.Context(), documentID, options)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, core.ErrDocumentNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, core.ErrReprocessBusy), errors.Is(err, core.ErrEmbeddingMismatch):
+			status = http.StatusConflict
+		default:
+			h.logger.Error("failed to reprocess document", zap.String("document_id", documentID), zap.Error(err))
+		}
+		render.Status(r, status)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to reprocess document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": result,
+	})
+}
@@ -0,0 +1,24 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ProjectRegion 返回项目所属租户的数据驻留区域，租户未设置区域或项目不存在时返回空
+func (m *Manager) ProjectRegion(ctx context.Context, projectID string) (string, error) {
+	var region string
+	err := m.db.QueryRowContext(ctx, `
+		SELECT t.region FROM projects p JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.id = ?`,
+		projectID,
+	).Scan(&region)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get project region: %w", err)
+	}
+	return region, nil
+}
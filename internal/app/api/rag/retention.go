@@ -0,0 +1,267 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// retentionInterval 定期任务执行保留策略的间隔
+const retentionInterval = 24 * time.Hour
+
+// tenantRetentionPolicy 租户默认保留策略的名称
+const tenantRetentionPolicy = "tenant default"
+
+// RetentionDefaultsLoader 查找项目所属租户的默认保留策略，租户未启用自动删除时返回 nil
+type RetentionDefaultsLoader func(ctx context.Context, projectID string) (*core.RetentionPolicy, error)
+
+// RetentionDefaultsFromDB 从租户设置的 storage.auto_delete 和 storage.retention_days 生成默认保留策略，
+// 超过保留天数未修改的文档被归档，在删除保留期后清除
+func RetentionDefaultsFromDB(db *sql.DB) RetentionDefaultsLoader {
+	return func(ctx context.Context, projectID string) (*core.RetentionPolicy, error) {
+		var raw sql.NullString
+		err := db.QueryRowContext(ctx, `
+			SELECT t.settings FROM projects p JOIN tenants t ON t.id = p.tenant_id
+			WHERE p.id = ? AND p.deleted_at IS NULL`,
+			projectID,
+		).Scan(&raw)
+		if err == sql.ErrNoRows || !raw.Valid || raw.String == "" {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var settings struct {
+			Storage *tenant.StorageSettings `json:"storage"`
+		}
+		if err := json.Unmarshal([]byte(raw.String), &settings); err != nil {
+			return nil, fmt.Errorf("invalid tenant settings: %w", err)
+		}
+		if settings.Storage == nil || !settings.Storage.AutoDelete || settings.Storage.RetentionDays <= 0 {
+			return nil, nil
+		}
+		return &core.RetentionPolicy{
+			Name:       tenantRetentionPolicy,
+			MaxAgeDays: settings.Storage.RetentionDays,
+			Action:     core.RetentionArchive,
+		}, nil
+	}
+}
+
+// SetRetentionDefaults 设置租户默认保留策略来源，项目自己的策略优先
+func (h *Handler) SetRetentionDefaults(loader RetentionDefaultsLoader) {
+	h.retentionDefaults = loader
+}
+
+// documentLastRetrieved 项目各文档最近一次被检索命中的时间，按文档ID索引
+func (m *Manager) documentLastRetrieved(ctx context.Context, projectID string) (map[string]time.Time, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT document_id, last_hit_at FROM rag_chunk_hits WHERE project_id = ?`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document retrievals: %w", err)
+	}
+	defer rows.Close()
+
+	retrieved := make(map[string]time.Time)
+	for rows.Next() {
+		var documentID string
+		var at time.Time
+		if err := rows.Scan(&documentID, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan document retrievals: %w", err)
+		}
+		if at.After(retrieved[documentID]) {
+			retrieved[documentID] = at
+		}
+	}
+	return retrieved, rows.Err()
+}
+
+// ragProjects 列出配置过RAG设置、数据源或上传过文档的项目
+func (m *Manager) ragProjects(ctx context.Context) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT project_id FROM rag_project_settings
+		UNION SELECT project_id FROM rag_data_sources
+		UNION SELECT project_id FROM rag_ingest_jobs
+		ORDER BY project_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rag projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []string
+	for rows.Next() {
+		var projectID string
+		if err := rows.Scan(&projectID); err != nil {
+			return nil, fmt.Errorf("failed to scan rag projects: %w", err)
+		}
+		projects = append(projects, projectID)
+	}
+	return projects, rows.Err()
+}
+
+// retentionPolicies 项目的生效保留策略：项目策略在前，租户默认策略在后
+func (h *Handler) retentionPolicies(ctx context.Context, projectID string) ([]core.RetentionPolicy, error) {
+	config, err := h.manager.GetProjectConfig(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	var policies []core.RetentionPolicy
+	if config != nil {
+		policies = append(policies, config.Retention...)
+	}
+	if h.retentionDefaults != nil {
+		policy, err := h.retentionDefaults(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			policies = append(policies, *policy)
+		}
+	}
+	return policies, nil
+}
+
+// applyRetention 对项目文档执行保留策略，dryRun 时只列出将被归档或删除的文档
+func (h *Handler) applyRetention(ctx context.Context, projectID string, policies []core.RetentionPolicy, dryRun bool) (*core.RetentionReport, error) {
+	sources, err := h.projectDataSourceIDs(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	retrieved, err := h.manager.documentLastRetrieved(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return h.pipeline.ApplyRetention(ctx, core.RetentionOptions{
+		ProjectID:     projectID,
+		DataSourceIDs: sources,
+		Policies:      policies,
+		LastRetrieved: retrieved,
+		DryRun:        dryRun,
+	})
+}
+
+// runRetention 每天对配置了保留策略的项目执行一次
+func (s *SyncScheduler) runRetention(ctx context.Context, now time.Time) {
+	if s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+	if now.Sub(s.lastRetentionRun) < retentionInterval {
+		return
+	}
+	s.lastRetentionRun = now
+
+	projects, err := s.handler.manager.ragProjects(ctx)
+	if err != nil {
+		s.logger.Error("failed to list projects for retention", zap.Error(err))
+		return
+	}
+	for _, projectID := range projects {
+		policies, err := s.handler.retentionPolicies(ctx, projectID)
+		if err != nil {
+			s.logger.Warn("failed to load retention policies", zap.String("project_id", projectID), zap.Error(err))
+			continue
+		}
+		if len(policies) == 0 {
+			continue
+		}
+		report, err := s.handler.applyRetention(ctx, projectID, policies, false)
+		if err != nil {
+			s.logger.Error("failed to apply retention", zap.String("project_id", projectID), zap.Error(err))
+			continue
+		}
+		if len(report.Candidates) > 0 {
+			s.logger.Info("Retention applied",
+				zap.String("project_id", projectID),
+				zap.Int("archived", report.Archived),
+				zap.Int("deleted", report.Deleted),
+				zap.Int("failed", report.Failed),
+			)
+		}
+	}
+}
+
+// retentionRequest 保留策略预览请求，未指定策略时使用项目的生效策略
+type retentionRequest struct {
+	Policies []core.RetentionPolicy `json:"policies,omitempty"`
+}
+
+// handlePreviewRetention 预览保留策略将归档或删除的文档，不做任何修改，可传入未保存的策略
+func (h *Handler) handlePreviewRetention(w http.ResponseWriter, r *http.Request) {
+	h.handleRetention(w, r, true)
+}
+
+// handleRunRetention 立即对项目执行已保存的保留策略
+func (h *Handler) handleRunRetention(w http.ResponseWriter, r *http.Request) {
+	h.handleRetention(w, r, false)
+}
+
+func (h *Handler) handleRetention(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	projectID := chi.URLParam(r, "projectId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req retentionRequest
+	if dryRun && r.ContentLength > 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+		for i := range req.Policies {
+			if err := req.Policies[i].Validate(); err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, map[string]interface{}{
+					"error":   "Invalid retention policy",
+					"details": fmt.Sprintf("policies[%d]: %v", i, err),
+				})
+				return
+			}
+		}
+	}
+
+	policies := req.Policies
+	var err error
+	if len(policies) == 0 {
+		policies, err = h.retentionPolicies(r.Context(), projectID)
+	}
+	var report *core.RetentionReport
+	if err == nil {
+		report, err = h.applyRetention(r.Context(), projectID, policies, dryRun)
+	}
+	if err != nil {
+		h.logger.Error("failed to apply retention", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to apply retention",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": map[string]interface{}{
+			"policies": policies,
+			"report":   report,
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package rag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestRetentionPolicies(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+
+	if err := h.manager.SaveProjectConfig(ctx, &core.ProjectConfig{
+		ProjectID: "p1",
+		Retention: []core.RetentionPolicy{{Name: "unused", UnretrievedDays: 90, Action: core.RetentionArchive}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.manager.SaveProjectConfig(ctx, &core.ProjectConfig{
+		ProjectID: "p2",
+		Retention: []core.RetentionPolicy{{Action: core.RetentionDelete}},
+	}); err == nil {
+		t.Fatal("expected a policy without age conditions to be rejected")
+	}
+
+	h.SetRetentionDefaults(func(ctx context.Context, projectID string) (*core.RetentionPolicy, error) {
+		return &core.RetentionPolicy{Name: tenantRetentionPolicy, MaxAgeDays: 30, Action: core.RetentionArchive}, nil
+	})
+	policies, err := h.retentionPolicies(ctx, "p1")
+	if err != nil || len(policies) != 2 || policies[0].Name != "unused" || policies[1].Name != tenantRetentionPolicy {
+		t.Fatalf("expected project policies before the tenant default, got %+v %v", policies, err)
+	}
+	if projects, err := h.manager.ragProjects(ctx); err != nil || len(projects) != 1 || projects[0] != "p1" {
+		t.Fatalf("unexpected retention projects %v %v", projects, err)
+	}
+
+	// The latest hit of any chunk is the document's last retrieval
+	first := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	second := first.Add(24 * time.Hour)
+	h.manager.recordChunkHits(ctx, "p1", []core.RetrievalResult{{Chunk: &core.DocumentChunk{ID: "d1_0", DocumentID: "d1"}}}, first)
+	h.manager.recordChunkHits(ctx, "p1", []core.RetrievalResult{{Chunk: &core.DocumentChunk{ID: "d1_1", DocumentID: "d1"}}}, second)
+	retrieved, err := h.manager.documentLastRetrieved(ctx, "p1")
+	if err != nil || len(retrieved) != 1 || !retrieved["d1"].Equal(second) {
+		t.Fatalf("unexpected last retrievals %v %v", retrieved, err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/projects/p1/rag/retention/preview", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a pipeline, got %d", rec.Code)
+	}
+}
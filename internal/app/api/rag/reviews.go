@@ -0,0 +1,739 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// 进入审核队列的原因，按优先级从高到低
+const (
+	ReviewReasonFeedback      = "negative_feedback"
+	ReviewReasonLowConfidence = "low_confidence"
+	ReviewReasonSampled       = "sampled"
+)
+
+// 审核状态
+const (
+	ReviewPending  = "pending"
+	ReviewReviewed = "reviewed"
+)
+
+// 审核结论
+const (
+	VerdictCorrect   = "correct"
+	VerdictPartial   = "partial"
+	VerdictIncorrect = "incorrect"
+)
+
+// defaultReviewDailyLimit 每个项目每天抽样进入队列的答案数上限，负面反馈不受限制
+const defaultReviewDailyLimit = 100
+
+// reviewPriorities 审核队列按优先级从高到低排列
+var reviewPriorities = map[string]int{
+	ReviewReasonFeedback:      3,
+	ReviewReasonLowConfidence: 2,
+	ReviewReasonSampled:       1,
+}
+
+// ReviewSettings 项目的答案抽样设置
+type ReviewSettings struct {
+	Enabled    bool    `json:"enabled"`
+	SampleRate float64 `json:"sample_rate"` // 随机抽样的比例，0 到 1
+
+	// LowConfidence 最高检索得分低于该值的答案总是进入队列，0 时使用默认阈值
+	LowConfidence float64 `json:"low_confidence,omitempty"`
+
+	// DailyLimit 每天抽样进入队列的答案数上限，0 时使用默认值
+	DailyLimit int `json:"daily_limit,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+}
+
+// ReviewItem 审核队列中的一个答案
+type ReviewItem struct {
+	ID        string              `json:"id"`
+	ProjectID string              `json:"project_id"`
+	QueryID   string              `json:"query_id"`
+	Query     string              `json:"query"`
+	Answer    string              `json:"answer,omitempty"`
+	Sources   []core.Source       `json:"sources,omitempty"`
+	TopScore  float64             `json:"top_score"`
+	Reason    string              `json:"reason"`
+	Status    string              `json:"status"`
+	Feedback  *core.QueryFeedback `json:"feedback,omitempty"`
+	Verdict   *ReviewVerdict      `json:"verdict,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+// ReviewVerdict 审核人对答案的结论
+type ReviewVerdict struct {
+	Verdict           string    `json:"verdict"`
+	ExpectedAnswer    string    `json:"expected_answer,omitempty"`
+	RelevantDocuments []string  `json:"relevant_documents,omitempty"`
+	Notes             string    `json:"notes,omitempty"`
+	AddToEvalSet      bool      `json:"add_to_eval_set"`
+	ReviewedBy        string    `json:"reviewed_by,omitempty"`
+	ReviewedAt        time.Time `json:"reviewed_at"`
+}
+
+// EvalDatasetEntry 评估集中的一个问题，可直接作为分块对比的 eval_set 使用
+type EvalDatasetEntry struct {
+	ID string `json:"id"`
+	core.EvalCase
+	ExpectedAnswer string    `json:"expected_answer,omitempty"`
+	ReviewID       string    `json:"review_id,omitempty"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// queryFeedbackRequest 用户对答案的反馈。未被抽样的答案收到负面反馈时按 answer 进入审核队列
+type queryFeedbackRequest struct {
+	core.QueryFeedback
+	Answer string `json:"answer,omitempty"`
+}
+
+// negativeFeedback 评分 1-2，或未评分且标记为无用
+func negativeFeedback(f *core.QueryFeedback) bool {
+	if f.Rating > 0 {
+		return f.Rating <= 2
+	}
+	return !f.Useful
+}
+
+// reviewReason 决定答案是否进入审核队列：低置信度的答案总是进入，其余按比例随机抽样，
+// 抽样数达到每日上限后不再进入。roll 为 [0,1) 的随机数，不进入时返回空
+func reviewReason(settings *ReviewSettings, topScore, roll float64, sampledToday int) string {
+	if settings == nil || !settings.Enabled {
+		return ""
+	}
+	limit := settings.DailyLimit
+	if limit <= 0 {
+		limit = defaultReviewDailyLimit
+	}
+	if sampledToday >= limit {
+		return ""
+	}
+	threshold := settings.LowConfidence
+	if threshold <= 0 {
+		threshold = defaultLowScoreThreshold
+	}
+	if topScore < threshold {
+		return ReviewReasonLowConfidence
+	}
+	if roll < settings.SampleRate {
+		return ReviewReasonSampled
+	}
+	return ""
+}
+
+// resultTopScore 查询结果的最高检索得分
+func resultTopScore(result *core.QueryResult) float64 {
+	top := 0.0
+	for _, retrieved := range result.RetrievalResults {
+		top = math.Max(top, retrieved.Score)
+	}
+	return top
+}
+
+// GetReviewSettings 获取项目的抽样设置，未设置时返回 nil
+func (m *Manager) GetReviewSettings(ctx context.Context, projectID string) (*ReviewSettings, error) {
+	var definition string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT definition FROM rag_review_settings WHERE project_id = ?`, projectID,
+	).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review settings: %w", err)
+	}
+	var settings ReviewSettings
+	if err := json.Unmarshal([]byte(definition), &settings); err != nil {
+		return nil, fmt.Errorf("invalid review settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// SaveReviewSettings 保存项目的抽样设置
+func (m *Manager) SaveReviewSettings(ctx context.Context, projectID string, settings *ReviewSettings) error {
+	definition, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to encode review settings: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_review_settings (project_id, definition, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (project_id) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		projectID, string(definition), settings.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save review settings: %w", err)
+	}
+	return nil
+}
+
+// SaveReviewItem 保存审核队列中的答案
+func (m *Manager) SaveReviewItem(ctx context.Context, item *ReviewItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode review item: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_review_items (id, project_id, query_id, reason, status, priority, item, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			reason = excluded.reason, status = excluded.status, priority = excluded.priority, item = excluded.item`,
+		item.ID, item.ProjectID, item.QueryID, item.Reason, item.Status, reviewPriorities[item.Reason], string(data), item.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save review item: %w", err)
+	}
+	return nil
+}
+
+// GetReviewItem 获取项目审核队列中的答案，不存在时返回 nil
+func (m *Manager) GetReviewItem(ctx context.Context, projectID, itemID string) (*ReviewItem, error) {
+	return m.reviewItem(ctx, `SELECT item FROM rag_review_items WHERE project_id = ? AND id = ?`, projectID, itemID)
+}
+
+// reviewItemForQuery 获取查询对应的审核项，不存在时返回 nil
+func (m *Manager) reviewItemForQuery(ctx context.Context, projectID, queryID string) (*ReviewItem, error) {
+	return m.reviewItem(ctx, `SELECT item FROM rag_review_items WHERE project_id = ? AND query_id = ?`, projectID, queryID)
+}
+
+func (m *Manager) reviewItem(ctx context.Context, query string, args ...interface{}) (*ReviewItem, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx, query, args...).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review item: %w", err)
+	}
+	var item ReviewItem
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		return nil, fmt.Errorf("invalid review item: %w", err)
+	}
+	return &item, nil
+}
+
+// ListReviewItems 列出指定状态的审核项：待审核的按优先级和时间排列，已审核的按时间倒序
+func (m *Manager) ListReviewItems(ctx context.Context, projectID, status string, limit int) ([]ReviewItem, error) {
+	order := `priority DESC, created_at`
+	if status == ReviewReviewed {
+		order = `created_at DESC`
+	}
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT item FROM rag_review_items WHERE project_id = ? AND status = ? ORDER BY `+order+` LIMIT ?`,
+		projectID, status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []ReviewItem{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan review item: %w", err)
+		}
+		var item ReviewItem
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			return nil, fmt.Errorf("invalid review item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// sampledSince 项目自 since 以来抽样进入队列的答案数，不含负面反馈
+func (m *Manager) sampledSince(ctx context.Context, projectID string, since time.Time) (int, error) {
+	var count int
+	err := m.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM rag_review_items
+		WHERE project_id = ? AND reason != ? AND created_at >= ?`,
+		projectID, ReviewReasonFeedback, since,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count sampled answers: %w", err)
+	}
+	return count, nil
+}
+
+// queryRecordText 查询记录中的问题，记录不存在时返回 false
+func (m *Manager) queryRecordText(ctx context.Context, projectID, queryID string) (string, bool, error) {
+	var query string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT query FROM rag_query_records WHERE project_id = ? AND id = ?`, projectID, queryID,
+	).Scan(&query)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get query record: %w", err)
+	}
+	return query, true, nil
+}
+
+// SaveQueryFeedback 保存用户对查询结果的反馈，同一用户重复反馈时覆盖
+func (m *Manager) SaveQueryFeedback(ctx context.Context, projectID, queryID, userID string, feedback *core.QueryFeedback) error {
+	data, err := json.Marshal(feedback)
+	if err != nil {
+		return fmt.Errorf("failed to encode query feedback: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_query_feedback (query_id, user_id, project_id, feedback, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (query_id, user_id) DO UPDATE SET feedback = excluded.feedback, created_at = excluded.created_at`,
+		queryID, userID, projectID, string(data), feedback.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save query feedback: %w", err)
+	}
+	return nil
+}
+
+// SaveEvalEntry 将审核结论加入项目评估集，同一审核项重复审核时覆盖
+func (m *Manager) SaveEvalEntry(ctx context.Context, projectID string, entry *EvalDatasetEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode eval case: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_eval_cases (id, project_id, review_id, entry, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET entry = excluded.entry`,
+		entry.ID, projectID, entry.ReviewID, string(data), entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save eval case: %w", err)
+	}
+	return nil
+}
+
+// ListEvalEntries 按加入时间列出项目评估集
+func (m *Manager) ListEvalEntries(ctx context.Context, projectID string) ([]EvalDatasetEntry, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT entry FROM rag_eval_cases WHERE project_id = ? ORDER BY created_at, id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list eval cases: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []EvalDatasetEntry{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan eval case: %w", err)
+		}
+		var entry EvalDatasetEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("invalid eval case: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteEvalEntry 从评估集删除一个问题，返回是否存在
+func (m *Manager) DeleteEvalEntry(ctx context.Context, projectID, entryID string) (bool, error) {
+	result, err := m.db.ExecContext(ctx,
+		`DELETE FROM rag_eval_cases WHERE project_id = ? AND id = ?`, projectID, entryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete eval case: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+	return deleted > 0, nil
+}
+
+// sampleForReview 按项目设置抽样查询结果进入审核队列，失败只记录日志
+func (h *Handler) sampleForReview(ctx context.Context, question string, options core.QueryOptions, result *core.QueryResult) {
+	if result.QueryID == "" {
+		return
+	}
+	settings, err := h.manager.GetReviewSettings(ctx, options.ProjectID)
+	if err != nil {
+		h.logger.Warn("failed to load review settings", zap.String("project_id", options.ProjectID), zap.Error(err))
+		return
+	}
+	if settings == nil || !settings.Enabled {
+		return
+	}
+
+	now := time.Now()
+	year, month, day := now.Date()
+	sampled, err := h.manager.sampledSince(ctx, options.ProjectID, time.Date(year, month, day, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		h.logger.Warn("failed to sample answer for review", zap.String("project_id", options.ProjectID), zap.Error(err))
+		return
+	}
+	topScore := resultTopScore(result)
+	reason := reviewReason(settings, topScore, rand.Float64(), sampled)
+	if reason == "" {
+		return
+	}
+	// 缓存命中的结果沿用首次查询的ID，已在队列中的不再重复加入
+	if existing, err := h.manager.reviewItemForQuery(ctx, options.ProjectID, result.QueryID); err != nil || existing != nil {
+		return
+	}
+
+	answer := result.GeneratedAnswer
+	if answer == "" {
+		answer = result.GeneratedResponse
+	}
+	item := &ReviewItem{
+		ID:        fmt.Sprintf("rv_%d", now.UnixNano()),
+		ProjectID: options.ProjectID,
+		QueryID:   result.QueryID,
+		Query:     question,
+		Answer:    answer,
+		Sources:   result.Sources,
+		TopScore:  topScore,
+		Reason:    reason,
+		Status:    ReviewPending,
+		CreatedAt: now,
+	}
+	if err := h.manager.SaveReviewItem(ctx, item); err != nil {
+		h.logger.Warn("failed to sample answer for review", zap.String("project_id", options.ProjectID), zap.Error(err))
+	}
+}
+
+// handleQueryFeedback 记录用户对答案的反馈，负面反馈使答案以最高优先级进入审核队列
+func (h *Handler) handleQueryFeedback(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	queryID := chi.URLParam(r, "queryId")
+
+	var req queryFeedbackRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.Rating < 0 || req.Rating > 5 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "rating must be between 1 and 5, or omitted",
+		})
+		return
+	}
+
+	ctx := r.Context()
+	question, found, err := h.manager.queryRecordText(ctx, projectID, queryID)
+	if err == nil && !found {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Query not found",
+		})
+		return
+	}
+	feedback := req.QueryFeedback
+	feedback.CreatedAt = time.Now()
+	if err == nil {
+		err = h.manager.SaveQueryFeedback(ctx, projectID, queryID, searchUser(r), &feedback)
+	}
+	if err == nil && negativeFeedback(&feedback) {
+		err = h.queueNegativeFeedback(ctx, projectID, queryID, question, req.Answer, &feedback)
+	}
+	if err != nil {
+		h.logger.Error("failed to save query feedback", zap.String("query_id", queryID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save feedback",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{
+		"data": feedback,
+	})
+}
+
+// queueNegativeFeedback 将收到负面反馈的答案提升到队列最前，未抽样的答案以反馈中的内容加入
+func (h *Handler) queueNegativeFeedback(ctx context.Context, projectID, queryID, question, answer string, feedback *core.QueryFeedback) error {
+	item, err := h.manager.reviewItemForQuery(ctx, projectID, queryID)
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		now := time.Now()
+		item = &ReviewItem{
+			ID:        fmt.Sprintf("rv_%d", now.UnixNano()),
+			ProjectID: projectID,
+			QueryID:   queryID,
+			Query:     question,
+			Answer:    answer,
+			CreatedAt: now,
+		}
+	}
+	item.Reason = ReviewReasonFeedback
+	item.Status = ReviewPending
+	item.Feedback = feedback
+	return h.manager.SaveReviewItem(ctx, item)
+}
+
+// handleGetReviewSettings 获取项目的答案抽样设置
+func (h *Handler) handleGetReviewSettings(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	settings, err := h.manager.GetReviewSettings(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to get review settings", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get review settings",
+			"details": err.Error(),
+		})
+		return
+	}
+	if settings == nil {
+		settings = &ReviewSettings{}
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": settings,
+	})
+}
+
+// handleUpdateReviewSettings 替换项目的答案抽样设置
+func (h *Handler) handleUpdateReviewSettings(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	var settings ReviewSettings
+	if err := render.DecodeJSON(r.Body, &settings); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if settings.SampleRate < 0 || settings.SampleRate > 1 || settings.LowConfidence < 0 || settings.LowConfidence > 1 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "sample_rate and low_confidence must be between 0 and 1",
+		})
+		return
+	}
+	if settings.DailyLimit < 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "daily_limit must not be negative",
+		})
+		return
+	}
+	settings.UpdatedAt = time.Now()
+	settings.UpdatedBy = searchUser(r)
+
+	if err := h.manager.SaveReviewSettings(r.Context(), projectID, &settings); err != nil {
+		h.logger.Error("failed to save review settings", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save review settings",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": settings,
+	})
+}
+
+// handleListReviews 列出审核队列，status 为 pending（默认）或 reviewed
+func (h *Handler) handleListReviews(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = ReviewPending
+	}
+	if status != ReviewPending && status != ReviewReviewed {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "status must be pending or reviewed",
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	items, err := h.manager.ListReviewItems(r.Context(), projectID, status, limit)
+	if err != nil {
+		h.logger.Error("failed to list review items", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list reviews",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": items,
+	})
+}
+
+// projectReviewItem 获取路径中的审核项；失败时已写入响应
+func (h *Handler) projectReviewItem(w http.ResponseWriter, r *http.Request) *ReviewItem {
+	projectID := chi.URLParam(r, "projectId")
+	itemID := chi.URLParam(r, "reviewId")
+	item, err := h.manager.GetReviewItem(r.Context(), projectID, itemID)
+	if err != nil {
+		h.logger.Error("failed to get review item", zap.String("review_id", itemID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get review",
+			"details": err.Error(),
+		})
+		return nil
+	}
+	if item == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Review not found",
+		})
+		return nil
+	}
+	return item
+}
+
+// handleGetReview 获取一个审核项
+func (h *Handler) handleGetReview(w http.ResponseWriter, r *http.Request) {
+	item := h.projectReviewItem(w, r)
+	if item == nil {
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": item,
+	})
+}
+
+// handleReviewVerdict 记录审核结论，add_to_eval_set 时将问题加入项目评估集：
+// 相关文档默认取答案引用的文档，答案错误时必须由审核人给出
+func (h *Handler) handleReviewVerdict(w http.ResponseWriter, r *http.Request) {
+	item := h.projectReviewItem(w, r)
+	if item == nil {
+		return
+	}
+	var verdict ReviewVerdict
+	if err := render.DecodeJSON(r.Body, &verdict); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	switch verdict.Verdict {
+	case VerdictCorrect, VerdictPartial, VerdictIncorrect:
+	default:
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "verdict must be correct, partial or incorrect",
+		})
+		return
+	}
+	if len(verdict.RelevantDocuments) == 0 && verdict.Verdict == VerdictCorrect {
+		for _, source := range item.Sources {
+			if source.DocumentID != "" && !slices.Contains(verdict.RelevantDocuments, source.DocumentID) {
+				verdict.RelevantDocuments = append(verdict.RelevantDocuments, source.DocumentID)
+			}
+		}
+	}
+	if verdict.AddToEvalSet && len(verdict.RelevantDocuments) == 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "relevant_documents are required to add this answer to the evaluation set",
+		})
+		return
+	}
+	verdict.ReviewedBy = searchUser(r)
+	verdict.ReviewedAt = time.Now()
+	item.Verdict = &verdict
+	item.Status = ReviewReviewed
+
+	ctx := r.Context()
+	err := h.manager.SaveReviewItem(ctx, item)
+	if err == nil && verdict.AddToEvalSet {
+		err = h.manager.SaveEvalEntry(ctx, item.ProjectID, &EvalDatasetEntry{
+			ID: "ev_" + strings.TrimPrefix(item.ID, "rv_"),
+			EvalCase: core.EvalCase{
+				Query:             item.Query,
+				RelevantDocuments: verdict.RelevantDocuments,
+			},
+			ExpectedAnswer: verdict.ExpectedAnswer,
+			ReviewID:       item.ID,
+			CreatedBy:      verdict.ReviewedBy,
+			CreatedAt:      verdict.ReviewedAt,
+		})
+	}
+	if err != nil {
+		h.logger.Error("failed to save review verdict", zap.String("review_id", item.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save verdict",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": item,
+	})
+}
+
+// handleGetEvalSet 获取由审核结论生成的项目评估集
+func (h *Handler) handleGetEvalSet(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	entries, err := h.manager.ListEvalEntries(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to list eval cases", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get evaluation set",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": entries,
+	})
+}
+
+// handleDeleteEvalCase 从项目评估集删除一个问题
+func (h *Handler) handleDeleteEvalCase(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	caseID := chi.URLParam(r, "caseId")
+	deleted, err := h.manager.DeleteEvalEntry(r.Context(), projectID, caseID)
+	if err != nil {
+		h.logger.Error("failed to delete eval case", zap.String("case_id", caseID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete evaluation case",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Evaluation case not found",
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Evaluation case deleted",
+	})
+}
@@ -0,0 +1,122 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestReviewReason(t *testing.T) {
+	settings := &ReviewSettings{Enabled: true, SampleRate: 0.1, DailyLimit: 5}
+	cases := []struct {
+		name     string
+		settings *ReviewSettings
+		score    float64
+		roll     float64
+		sampled  int
+		want     string
+	}{
+		{"disabled", &ReviewSettings{SampleRate: 1}, 0.9, 0, 0, ""},
+		{"low confidence", settings, 0.1, 0.9, 0, ReviewReasonLowConfidence},
+		{"sampled", settings, 0.8, 0.05, 0, ReviewReasonSampled},
+		{"not sampled", settings, 0.8, 0.5, 0, ""},
+		{"daily limit", settings, 0.1, 0.05, 5, ""},
+		{"custom threshold", &ReviewSettings{Enabled: true, LowConfidence: 0.9}, 0.8, 0.5, 0, ReviewReasonLowConfidence},
+	}
+	for _, c := range cases {
+		if got := reviewReason(c.settings, c.score, c.roll, c.sampled); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestReviewQueue(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	ctx := context.Background()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", "alice"))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPut, "/projects/p1/rag/reviews/settings", `{"enabled":true,"sample_rate":1.5}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid sample rate to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/projects/p1/rag/reviews/settings", `{"enabled":true,"sample_rate":1}`); rec.Code != http.StatusOK {
+		t.Fatalf("update settings failed: %d %s", rec.Code, rec.Body)
+	}
+
+	// Every answer is sampled at rate 1; a repeated (cached) answer is queued once
+	result := &core.QueryResult{
+		QueryID:         "q1",
+		GeneratedAnswer: "Run the installer",
+		Sources:         []core.Source{{DocumentID: "install"}},
+		RetrievalResults: []core.RetrievalResult{
+			{DocumentID: "install", Score: 0.8},
+		},
+	}
+	options := core.QueryOptions{ProjectID: "p1"}
+	h.sampleForReview(ctx, "How do I install?", options, result)
+	h.sampleForReview(ctx, "How do I install?", options, result)
+	h.manager.StoreQueryRecord(ctx, "p1", "", core.QueryRecord{ID: "q2", Query: "Where are the logs?"})
+
+	if rec := do(http.MethodPost, "/projects/p1/rag/queries/missing/feedback", `{"rating":1}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown query to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/projects/p1/rag/queries/q2/feedback", `{"rating":2,"comments":"wrong path","answer":"In /tmp"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("feedback failed: %d %s", rec.Code, rec.Body)
+	}
+
+	rec := do(http.MethodGet, "/projects/p1/rag/reviews", "")
+	var queue struct {
+		Data []ReviewItem `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &queue)
+	if len(queue.Data) != 2 || queue.Data[0].QueryID != "q2" || queue.Data[0].Reason != ReviewReasonFeedback ||
+		queue.Data[0].Answer != "In /tmp" || queue.Data[1].Reason != ReviewReasonSampled {
+		t.Fatalf("unexpected queue %s", rec.Body)
+	}
+
+	sampled := queue.Data[1]
+	if rec := do(http.MethodPost, "/projects/p1/rag/reviews/"+queue.Data[0].ID+"/verdict", `{"verdict":"incorrect","add_to_eval_set":true}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected incorrect verdict without documents to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/projects/p1/rag/reviews/"+sampled.ID+"/verdict", `{"verdict":"maybe"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown verdict to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/projects/p1/rag/reviews/"+sampled.ID+"/verdict", `{"verdict":"correct","add_to_eval_set":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("verdict failed: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/projects/p1/rag/eval-set", "")
+	var evalSet struct {
+		Data []EvalDatasetEntry `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &evalSet)
+	if len(evalSet.Data) != 1 || evalSet.Data[0].Query != "How do I install?" ||
+		len(evalSet.Data[0].RelevantDocuments) != 1 || evalSet.Data[0].RelevantDocuments[0] != "install" {
+		t.Fatalf("unexpected eval set %s", rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/projects/p1/rag/reviews?status=reviewed", "")
+	json.Unmarshal(rec.Body.Bytes(), &queue)
+	if len(queue.Data) != 1 || queue.Data[0].Verdict == nil || queue.Data[0].Verdict.ReviewedBy != "alice" {
+		t.Fatalf("unexpected reviewed items %s", rec.Body)
+	}
+
+	if rec := do(http.MethodDelete, "/projects/p1/rag/eval-set/"+evalSet.Data[0].ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete eval case failed: %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/projects/p2/rag/reviews/"+sampled.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected other project's review to be hidden, got %d", rec.Code)
+	}
+}
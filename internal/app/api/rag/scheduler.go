@@ -0,0 +1,180 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// schedulerTick 检查到期数据源的周期
+	schedulerTick = 30 * time.Second
+
+	// schedulerMaxJitter 计划时间之后的最大随机延迟，避免同一时刻集中同步
+	schedulerMaxJitter = time.Minute
+)
+
+// errSyncInProgress 数据源已有同步在执行（可能在其他节点）
+var errSyncInProgress = errors.New("sync already in progress")
+
+// SyncScheduler 按数据源的 schedule 触发同步，每天重新生成内容缺口建议和术语表并执行文档和对话记录的保留策略，
+// 每小时重新统计项目存储用量，并定期将长期未被检索的嵌入移入冷存储、回收未被引用的对象。多节点部署时仅由RAG管道选举出的主节点调度，并通过管道的分布式锁
+// 保证同一数据源同一时刻只有一个节点在同步
+type SyncScheduler struct {
+	handler *Handler
+	logger  *zap.Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+
+	lastGapCheck      time.Time // 仅由调度循环访问
+	lastGlossaryCheck time.Time // 仅由调度循环访问
+	lastStorageCheck  time.Time // 仅由调度循环访问
+	lastRetentionRun  time.Time // 仅由调度循环访问
+	lastTieringRun    time.Time // 仅由调度循环访问
+	lastBlobGCRun     time.Time // 仅由调度循环访问
+
+	lastChatRetentionRun time.Time // 仅由调度循环访问
+}
+
+// NewSyncScheduler 创建数据源同步调度器
+func NewSyncScheduler(handler *Handler, logger *zap.Logger) *SyncScheduler {
+	return &SyncScheduler{
+		handler: handler,
+		logger:  logger,
+	}
+}
+
+// Start 启动调度循环
+func (s *SyncScheduler) Start() {
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.runDue(context.Background(), time.Now())
+				s.runContentGaps(context.Background(), time.Now())
+				s.runGlossaries(context.Background(), time.Now())
+				s.runStorageUsage(context.Background(), time.Now())
+				s.runRetention(context.Background(), time.Now())
+				s.runChatRetention(context.Background(), time.Now())
+				s.runTiering(context.Background(), time.Now())
+				s.runBlobGC(context.Background(), time.Now())
+			}
+		}
+	}()
+}
+
+// Stop 停止调度循环，已开始的同步继续执行至完成
+func (s *SyncScheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.wg.Wait()
+	s.stop = nil
+}
+
+// runDue 为所有到期的数据源启动同步
+func (s *SyncScheduler) runDue(ctx context.Context, now time.Time) {
+	if s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+
+	sources, err := s.handler.manager.ListDataSources(ctx, "")
+	if err != nil {
+		s.logger.Error("failed to list data sources for scheduling", zap.Error(err))
+		return
+	}
+
+	for i := range sources {
+		ds := &sources[i]
+		if !ds.Enabled || ds.Schedule == "" {
+			continue
+		}
+		due, err := s.isDue(ctx, ds, now)
+		if err != nil {
+			s.logger.Warn("failed to schedule data source", zap.String("source_id", ds.ID), zap.Error(err))
+			continue
+		}
+		if !due {
+			continue
+		}
+
+		lock, err := s.handler.pipeline.Locker().TryLock(ctx, syncLockKey(ds.ID), s.handler.pipeline.LockTTL())
+		if err != nil {
+			continue
+		}
+
+		// 手动同步可能在本次检查之后刚完成，持锁后重新确认
+		if due, err = s.isDue(ctx, ds, now); err != nil || !due {
+			lock.Unlock(ctx)
+			continue
+		}
+
+		if _, err := s.handler.runSync(ctx, ds, lock, "scheduled", ""); err != nil {
+			s.logger.Error("failed to start scheduled sync", zap.String("source_id", ds.ID), zap.Error(err))
+		}
+	}
+}
+
+func (s *SyncScheduler) isDue(ctx context.Context, ds *DataSource, now time.Time) (bool, error) {
+	runs, err := s.handler.manager.ListSyncRuns(ctx, ds.ID, 1)
+	if err != nil {
+		return false, err
+	}
+	next, err := nextScheduledRun(ds, runs)
+	if err != nil || next == nil {
+		return false, err
+	}
+	return !now.Before(*next), nil
+}
+
+// nextScheduledRun 计算数据源下一次计划同步时间（含抖动），以最近一次同步的
+// 开始时间为基准，从未同步时以创建时间为基准。未设置 schedule 时返回 nil
+func nextScheduledRun(ds *DataSource, latest []DataSourceSyncRun) (*time.Time, error) {
+	if ds.Schedule == "" {
+		return nil, nil
+	}
+	schedule, err := core.ParseSchedule(ds.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	base := ds.CreatedAt
+	if len(latest) > 0 {
+		base = latest[0].StartedAt
+	}
+	next := schedule.Next(base)
+	if next.IsZero() {
+		return nil, nil
+	}
+
+	// 抖动由数据源与计划时间决定，各节点计算结果一致
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%s:%d", ds.ID, next.Unix())
+	next = next.Add(time.Duration(hash.Sum64() % uint64(schedulerMaxJitter)))
+	return &next, nil
+}
+
+// syncLockKey 数据源同步锁的键
+func syncLockKey(sourceID string) string {
+	return "rag:sync:" + sourceID
+}
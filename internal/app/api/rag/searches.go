@@ -0,0 +1,905 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxCollectionSearches 一个集合最多保存的查询数，整个集合可以一次运行
+	maxCollectionSearches = 50
+
+	// maxSearchRuns 每个保存的查询保留的运行记录数
+	maxSearchRuns = 20
+
+	// searchDriftSimilarity 答案与上次运行的词重合度低于此值时视为漂移
+	searchDriftSimilarity = 0.5
+)
+
+// SearchCollection 项目中命名的查询集合，共享后项目成员都可以查看和运行
+type SearchCollection struct {
+	ID          string        `json:"id"`
+	ProjectID   string        `json:"project_id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Shared      bool          `json:"shared"`
+	CreatedBy   string        `json:"created_by,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Searches    []SavedSearch `json:"searches,omitempty"`
+}
+
+// SavedSearch 保存的查询，包括查询选项和过滤条件
+type SavedSearch struct {
+	ID           string          `json:"id"`
+	CollectionID string          `json:"collection_id"`
+	ProjectID    string          `json:"project_id"`
+	Name         string          `json:"name"`
+	Request      QueryRequest    `json:"request"`
+	CreatedBy    string          `json:"created_by,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	LastRun      *SavedSearchRun `json:"last_run,omitempty"`
+}
+
+// SavedSearchRun 保存的查询的一次运行结果
+type SavedSearchRun struct {
+	ID       string         `json:"id"`
+	SearchID string         `json:"search_id"`
+	QueryID  string         `json:"query_id,omitempty"`
+	Answer   string         `json:"answer,omitempty"`
+	Sources  []RunSource    `json:"sources"`
+	Error    string         `json:"error,omitempty"`
+	RanBy    string         `json:"ran_by,omitempty"`
+	RanAt    time.Time      `json:"ran_at"`
+	Diff     *SearchRunDiff `json:"diff,omitempty"` // 与上一次成功运行的差异，首次运行时为空
+}
+
+// RunSource 运行结果中检索到的一个分块
+type RunSource struct {
+	DocumentID string  `json:"document_id"`
+	ChunkID    string  `json:"chunk_id,omitempty"`
+	Score      float64 `json:"score"`
+}
+
+// SearchRunDiff 两次运行结果的差异，用于发现知识库内容变化导致的答案漂移
+type SearchRunDiff struct {
+	PreviousRunID    string    `json:"previous_run_id"`
+	PreviousRanAt    time.Time `json:"previous_ran_at"`
+	AnswerSimilarity float64   `json:"answer_similarity"` // 两次答案主题词的重合度，0 到 1
+	AnswerChanged    bool      `json:"answer_changed"`
+	AddedDocuments   []string  `json:"added_documents,omitempty"`
+	RemovedDocuments []string  `json:"removed_documents,omitempty"`
+	TopScoreDelta    float64   `json:"top_score_delta"` // 最高检索得分的变化
+	Drifted          bool      `json:"drifted"`         // 答案明显变化或引用的文档不同
+}
+
+// searchCollectionRequest 创建或修改集合的请求
+type searchCollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Shared      bool   `json:"shared"`
+}
+
+// savedSearchRequest 保存查询的请求
+type savedSearchRequest struct {
+	Name    string       `json:"name"`
+	Request QueryRequest `json:"request"`
+}
+
+// SaveSearchCollection 保存查询集合，不含其中的查询
+func (m *Manager) SaveSearchCollection(ctx context.Context, c *SearchCollection) error {
+	stored := *c
+	stored.Searches = nil
+	definition, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode search collection: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_search_collections (id, project_id, created_by, shared, definition, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			shared = excluded.shared, definition = excluded.definition, updated_at = excluded.updated_at`,
+		c.ID, c.ProjectID, c.CreatedBy, c.Shared, string(definition), c.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save search collection: %w", err)
+	}
+	return nil
+}
+
+// GetSearchCollection 获取项目的查询集合，不存在时返回 nil
+func (m *Manager) GetSearchCollection(ctx context.Context, projectID, collectionID string) (*SearchCollection, error) {
+	var definition string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT definition FROM rag_search_collections WHERE id = ? AND project_id = ?`,
+		collectionID, projectID,
+	).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search collection: %w", err)
+	}
+	var c SearchCollection
+	if err := json.Unmarshal([]byte(definition), &c); err != nil {
+		return nil, fmt.Errorf("invalid search collection: %w", err)
+	}
+	return &c, nil
+}
+
+// ListSearchCollections 列出用户在项目中可见的集合：自己创建的和共享的
+func (m *Manager) ListSearchCollections(ctx context.Context, projectID, userID string) ([]SearchCollection, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT definition FROM rag_search_collections
+		WHERE project_id = ? AND (shared = 1 OR created_by = ?)
+		ORDER BY updated_at DESC`,
+		projectID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := []SearchCollection{}
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to scan search collection: %w", err)
+		}
+		var c SearchCollection
+		if err := json.Unmarshal([]byte(definition), &c); err != nil {
+			return nil, fmt.Errorf("invalid search collection: %w", err)
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+// DeleteSearchCollection 删除集合及其中的查询和运行记录
+func (m *Manager) DeleteSearchCollection(ctx context.Context, projectID, collectionID string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete search collection: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`DELETE FROM rag_saved_search_runs WHERE search_id IN (SELECT id FROM rag_saved_searches WHERE collection_id = ?)`,
+		`DELETE FROM rag_saved_searches WHERE collection_id = ?`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement, collectionID); err != nil {
+			return fmt.Errorf("failed to delete search collection: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM rag_search_collections WHERE id = ? AND project_id = ?`, collectionID, projectID,
+	); err != nil {
+		return fmt.Errorf("failed to delete search collection: %w", err)
+	}
+	return tx.Commit()
+}
+
+// SaveSavedSearch 保存集合中的查询
+func (m *Manager) SaveSavedSearch(ctx context.Context, s *SavedSearch) error {
+	stored := *s
+	stored.LastRun = nil
+	definition, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode saved search: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_saved_searches (id, collection_id, project_id, definition, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		s.ID, s.CollectionID, s.ProjectID, string(definition), s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save saved search: %w", err)
+	}
+	return nil
+}
+
+// ListSavedSearches 按创建顺序列出集合中的查询，附带各自最近一次运行
+func (m *Manager) ListSavedSearches(ctx context.Context, collectionID string) ([]SavedSearch, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT definition FROM rag_saved_searches WHERE collection_id = ? ORDER BY created_at, id`,
+		collectionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		var s SavedSearch
+		if err := json.Unmarshal([]byte(definition), &s); err != nil {
+			return nil, fmt.Errorf("invalid saved search: %w", err)
+		}
+		searches = append(searches, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range searches {
+		runs, err := m.ListSearchRuns(ctx, searches[i].ID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(runs) > 0 {
+			searches[i].LastRun = &runs[0]
+		}
+	}
+	return searches, nil
+}
+
+// DeleteSavedSearch 删除集合中的查询及其运行记录，返回是否存在
+func (m *Manager) DeleteSavedSearch(ctx context.Context, collectionID, searchID string) (bool, error) {
+	result, err := m.db.ExecContext(ctx,
+		`DELETE FROM rag_saved_searches WHERE id = ? AND collection_id = ?`, searchID, collectionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return false, nil
+	}
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM rag_saved_search_runs WHERE search_id = ?`, searchID); err != nil {
+		return true, fmt.Errorf("failed to delete saved search runs: %w", err)
+	}
+	return true, nil
+}
+
+// SaveSearchRun 保存一次运行，只保留最近 maxSearchRuns 次
+func (m *Manager) SaveSearchRun(ctx context.Context, run *SavedSearchRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode search run: %w", err)
+	}
+	if _, err := m.db.ExecContext(ctx,
+		`INSERT INTO rag_saved_search_runs (id, search_id, run, ran_at) VALUES (?, ?, ?, ?)`,
+		run.ID, run.SearchID, string(data), run.RanAt,
+	); err != nil {
+		return fmt.Errorf("failed to save search run: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		DELETE FROM rag_saved_search_runs WHERE search_id = ? AND id NOT IN (
+			SELECT id FROM rag_saved_search_runs WHERE search_id = ? ORDER BY ran_at DESC LIMIT ?
+		)`,
+		run.SearchID, run.SearchID, maxSearchRuns,
+	)
+	if err != nil {
+		m.logger.Warn("failed to prune search runs", zap.String("search_id", run.SearchID), zap.Error(err))
+	}
+	return nil
+}
+
+// ListSearchRuns 按时间倒序列出查询的运行记录
+func (m *Manager) ListSearchRuns(ctx context.Context, searchID string, limit int) ([]SavedSearchRun, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT run FROM rag_saved_search_runs WHERE search_id = ? ORDER BY ran_at DESC LIMIT ?`,
+		searchID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []SavedSearchRun{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan search run: %w", err)
+		}
+		var run SavedSearchRun
+		if err := json.Unmarshal([]byte(data), &run); err != nil {
+			return nil, fmt.Errorf("invalid search run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// previousSearchRun 查询最近一次成功的运行，没有时返回 nil
+func (m *Manager) previousSearchRun(ctx context.Context, searchID string) (*SavedSearchRun, error) {
+	runs, err := m.ListSearchRuns(ctx, searchID, maxSearchRuns)
+	if err != nil {
+		return nil, err
+	}
+	for i := range runs {
+		if runs[i].Error == "" {
+			return &runs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// newSearchRun 由查询结果生成运行记录
+func newSearchRun(searchID string, result *core.QueryResult) *SavedSearchRun {
+	run := &SavedSearchRun{
+		SearchID: searchID,
+		QueryID:  result.QueryID,
+		Answer:   result.GeneratedAnswer,
+		Sources:  []RunSource{},
+	}
+	if run.Answer == "" {
+		run.Answer = result.GeneratedResponse
+	}
+	for _, retrieved := range result.RetrievalResults {
+		source := RunSource{DocumentID: retrieved.DocumentID, Score: retrieved.Score}
+		if retrieved.Chunk != nil {
+			source.ChunkID = retrieved.Chunk.ID
+		}
+		run.Sources = append(run.Sources, source)
+	}
+	return run
+}
+
+// diffSearchRuns 比较本次与上一次运行：答案按主题词重合度比较，来源按文档比较
+func diffSearchRuns(previous, current *SavedSearchRun) *SearchRunDiff {
+	diff := &SearchRunDiff{
+		PreviousRunID:    previous.ID,
+		PreviousRanAt:    previous.RanAt,
+		AnswerSimilarity: termSimilarity(topicTerms(previous.Answer), topicTerms(current.Answer)),
+		TopScoreDelta:    math.Round((topScore(current.Sources)-topScore(previous.Sources))*1000) / 1000,
+	}
+	diff.AnswerChanged = strings.TrimSpace(previous.Answer) != strings.TrimSpace(current.Answer)
+
+	before, after := runDocuments(previous.Sources), runDocuments(current.Sources)
+	for id := range after {
+		if !before[id] {
+			diff.AddedDocuments = append(diff.AddedDocuments, id)
+		}
+	}
+	for id := range before {
+		if !after[id] {
+			diff.RemovedDocuments = append(diff.RemovedDocuments, id)
+		}
+	}
+	sort.Strings(diff.AddedDocuments)
+	sort.Strings(diff.RemovedDocuments)
+
+	diff.Drifted = diff.AnswerSimilarity < searchDriftSimilarity ||
+		len(diff.AddedDocuments) > 0 || len(diff.RemovedDocuments) > 0
+	return diff
+}
+
+// termSimilarity 两组词的 Jaccard 系数，两组都为空时视为相同
+func termSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for term := range a {
+		if b[term] {
+			shared++
+		}
+	}
+	similarity := float64(shared) / float64(len(a)+len(b)-shared)
+	return math.Round(similarity*1000) / 1000
+}
+
+func runDocuments(sources []RunSource) map[string]bool {
+	documents := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		documents[source.DocumentID] = true
+	}
+	return documents
+}
+
+func topScore(sources []RunSource) float64 {
+	top := 0.0
+	for _, source := range sources {
+		top = math.Max(top, source.Score)
+	}
+	return top
+}
+
+// runSavedSearch 运行保存的查询并与上一次成功的运行比较；查询失败也会记录
+func (h *Handler) runSavedSearch(r *http.Request, search *SavedSearch) (*SavedSearchRun, error) {
+	ctx := r.Context()
+	options, err := search.Request.queryOptions(r)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := h.manager.previousSearchRun(ctx, search.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var run *SavedSearchRun
+	result, err := h.query(ctx, search.Request.Query, options)
+	if errors.Is(err, core.ErrBudgetExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		run = &SavedSearchRun{SearchID: search.ID, Sources: []RunSource{}, Error: err.Error()}
+	} else {
+		run = newSearchRun(search.ID, result)
+		if previous != nil {
+			run.Diff = diffSearchRuns(previous, run)
+		}
+	}
+	run.RanAt = time.Now()
+	run.ID = fmt.Sprintf("run_%d", run.RanAt.UnixNano())
+	run.RanBy = options.UserID
+	if err := h.manager.SaveSearchRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// searchUser 请求的用户 ID
+func searchUser(r *http.Request) string {
+	userID, _ := r.Context().Value("user_id").(string)
+	return userID
+}
+
+// loadCollection 获取用户可见的集合，write 时要求是集合的创建者；失败时已写入响应
+func (h *Handler) loadCollection(w http.ResponseWriter, r *http.Request, write bool) *SearchCollection {
+	projectID := chi.URLParam(r, "projectId")
+	collectionID := chi.URLParam(r, "collectionId")
+	collection, err := h.manager.GetSearchCollection(r.Context(), projectID, collectionID)
+	if err != nil {
+		h.logger.Error("failed to get search collection", zap.String("collection_id", collectionID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get search collection",
+			"details": err.Error(),
+		})
+		return nil
+	}
+	userID := searchUser(r)
+	if collection == nil || (!collection.Shared && collection.CreatedBy != userID) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Search collection not found",
+		})
+		return nil
+	}
+	if write && collection.CreatedBy != userID {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Only the creator can change a search collection",
+		})
+		return nil
+	}
+	return collection
+}
+
+// loadSavedSearch 获取集合中的查询；失败时已写入响应
+func (h *Handler) loadSavedSearch(w http.ResponseWriter, r *http.Request, collection *SearchCollection) *SavedSearch {
+	searchID := chi.URLParam(r, "searchId")
+	searches, err := h.manager.ListSavedSearches(r.Context(), collection.ID)
+	if err != nil {
+		h.logger.Error("failed to list saved searches", zap.String("collection_id", collection.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get saved search",
+			"details": err.Error(),
+		})
+		return nil
+	}
+	for i := range searches {
+		if searches[i].ID == searchID {
+			return &searches[i]
+		}
+	}
+	render.Status(r, http.StatusNotFound)
+	render.JSON(w, r, map[string]interface{}{
+		"error": "Saved search not found",
+	})
+	return nil
+}
+
+// handleListCollections 列出自己创建的和项目中共享的查询集合
+func (h *Handler) handleListCollections(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	collections, err := h.manager.ListSearchCollections(r.Context(), projectID, searchUser(r))
+	if err != nil {
+		h.logger.Error("failed to list search collections", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list search collections",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": collections,
+	})
+}
+
+// handleCreateCollection 创建查询集合
+func (h *Handler) handleCreateCollection(w http.ResponseWriter, r *http.Request) {
+	var req searchCollectionRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Name is required",
+		})
+		return
+	}
+
+	now := time.Now()
+	collection := &SearchCollection{
+		ID:          fmt.Sprintf("sc_%d", now.UnixNano()),
+		ProjectID:   chi.URLParam(r, "projectId"),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Shared:      req.Shared,
+		CreatedBy:   searchUser(r),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := h.manager.SaveSearchCollection(r.Context(), collection); err != nil {
+		h.logger.Error("failed to save search collection", zap.String("project_id", collection.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save search collection",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{
+		"data": collection,
+	})
+}
+
+// handleGetCollection 获取集合及其中的查询和各自最近一次运行
+func (h *Handler) handleGetCollection(w http.ResponseWriter, r *http.Request) {
+	collection := h.loadCollection(w, r, false)
+	if collection == nil {
+		return
+	}
+	searches, err := h.manager.ListSavedSearches(r.Context(), collection.ID)
+	if err != nil {
+		h.logger.Error("failed to list saved searches", zap.String("collection_id", collection.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get search collection",
+			"details": err.Error(),
+		})
+		return
+	}
+	collection.Searches = searches
+	render.JSON(w, r, map[string]interface{}{
+		"data": collection,
+	})
+}
+
+// handleUpdateCollection 修改集合名称、描述和共享状态
+func (h *Handler) handleUpdateCollection(w http.ResponseWriter, r *http.Request) {
+	collection := h.loadCollection(w, r, true)
+	if collection == nil {
+		return
+	}
+	var req searchCollectionRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	if strings.TrimSpace(req.Name) != "" {
+		collection.Name = strings.TrimSpace(req.Name)
+	}
+	collection.Description = req.Description
+	collection.Shared = req.Shared
+	collection.UpdatedAt = time.Now()
+	if err := h.manager.SaveSearchCollection(r.Context(), collection); err != nil {
+		h.logger.Error("failed to save search collection", zap.String("collection_id", collection.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save search collection",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": collection,
+	})
+}
+
+// handleDeleteCollection 删除集合及其中的查询
+func (h *Handler) handleDeleteCollection(w http.ResponseWriter, r *http.Request) {
+	collection := h.loadCollection(w, r, true)
+	if collection == nil {
+		return
+	}
+	if err := h.manager.DeleteSearchCollection(r.Context(), collection.ProjectID, collection.ID); err != nil {
+		h.logger.Error("failed to delete search collection", zap.String("collection_id", collection.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete search collection",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Search collection deleted",
+	})
+}
+
+// decodeSavedSearch 解析并校验保存查询的请求；失败时已写入响应
+func decodeSavedSearch(w http.ResponseWriter, r *http.Request) *savedSearchRequest {
+	var req savedSearchRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return nil
+	}
+	if strings.TrimSpace(req.Request.Query) == "" {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Query is required",
+		})
+		return nil
+	}
+	if _, err := req.Request.queryOptions(r); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid filter",
+			"details": err.Error(),
+		})
+		return nil
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		req.Name = req.Request.Query
+	}
+	return &req
+}
+
+// handleCreateSavedSearch 将查询保存到集合中
+func (h *Handler) handleCreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	collection := h.loadCollection(w, r, true)
+	if collection == nil {
+		return
+	}
+	req := decodeSavedSearch(w, r)
+	if req == nil {
+		return
+	}
+	searches, err := h.manager.ListSavedSearches(r.Context(), collection.ID)
+	if err == nil && len(searches) >= maxCollectionSearches {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": fmt.Sprintf("A collection holds at most %d searches", maxCollectionSearches),
+		})
+		return
+	}
+
+	now := time.Now()
+	search := &SavedSearch{
+		ID:           fmt.Sprintf("ss_%d", now.UnixNano()),
+		CollectionID: collection.ID,
+		ProjectID:    collection.ProjectID,
+		Name:         strings.TrimSpace(req.Name),
+		Request:      req.Request,
+		CreatedBy:    searchUser(r),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err == nil {
+		err = h.manager.SaveSavedSearch(r.Context(), search)
+	}
+	if err != nil {
+		h.logger.Error("failed to save saved search", zap.String("collection_id", collection.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save search",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{
+		"data": search,
+	})
+}
+
+// handleUpdateSavedSearch 修改保存的查询，之前的运行记录保留用于比较
+func (h *Handler) handleUpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	collection := h.loadCollection(w, r, true)
+	if collection == nil {
+		return
+	}
+	search := h.loadSavedSearch(w, r, collection)
+	if search == nil {
+		return
+	}
+	req := decodeSavedSearch(w, r)
+	if req == nil {
+		return
+	}
+	search.Name = strings.TrimSpace(req.Name)
+	search.Request = req.Request
+	search.UpdatedAt = time.Now()
+	if err := h.manager.SaveSavedSearch(r.Context(), search); err != nil {
+		h.logger.Error("failed to save saved search", zap.String("search_id", search.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to save search",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": search,
+	})
+}
+
+// handleDeleteSavedSearch 从集合中删除查询
+func (h *Handler) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	collection := h.loadCollection(w, r, true)
+	if collection == nil {
+		return
+	}
+	searchID := chi.URLParam(r, "searchId")
+	deleted, err := h.manager.DeleteSavedSearch(r.Context(), collection.ID, searchID)
+	if err != nil {
+		h.logger.Error("failed to delete saved search", zap.String("search_id", searchID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete search",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Saved search not found",
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Search deleted",
+	})
+}
+
+// handleListSearchRuns 列出查询的运行记录
+func (h *Handler) handleListSearchRuns(w http.ResponseWriter, r *http.Request) {
+	collection := h.loadCollection(w, r, false)
+	if collection == nil {
+		return
+	}
+	search := h.loadSavedSearch(w, r, collection)
+	if search == nil {
+		return
+	}
+	runs, err := h.manager.ListSearchRuns(r.Context(), search.ID, maxSearchRuns)
+	if err != nil {
+		h.logger.Error("failed to list search runs", zap.String("search_id", search.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list search runs",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": runs,
+	})
+}
+
+// handleRunSavedSearch 运行一个保存的查询，返回结果及与上次运行的差异
+func (h *Handler) handleRunSavedSearch(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+	collection := h.loadCollection(w, r, false)
+	if collection == nil {
+		return
+	}
+	search := h.loadSavedSearch(w, r, collection)
+	if search == nil {
+		return
+	}
+	run, err := h.runSavedSearch(r, search)
+	if err != nil {
+		h.searchRunError(w, r, search, err)
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": run,
+	})
+}
+
+// handleRunCollection 依次运行集合中的所有查询，单个查询失败不影响其余查询
+func (h *Handler) handleRunCollection(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+	collection := h.loadCollection(w, r, false)
+	if collection == nil {
+		return
+	}
+	searches, err := h.manager.ListSavedSearches(r.Context(), collection.ID)
+	if err != nil {
+		h.logger.Error("failed to list saved searches", zap.String("collection_id", collection.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to run search collection",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	runs := make([]*SavedSearchRun, 0, len(searches))
+	drifted := 0
+	for i := range searches {
+		run, err := h.runSavedSearch(r, &searches[i])
+		if err != nil {
+			h.searchRunError(w, r, &searches[i], err)
+			return
+		}
+		if run.Diff != nil && run.Diff.Drifted {
+			drifted++
+		}
+		runs = append(runs, run)
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data":    runs,
+		"drifted": drifted,
+	})
+}
+
+// searchRunError 输出运行保存的查询时的错误，预算用尽时停止运行
+func (h *Handler) searchRunError(w http.ResponseWriter, r *http.Request, search *SavedSearch, err error) {
+	if errors.Is(err, core.ErrBudgetExceeded) {
+		render.Status(r, http.StatusPaymentRequired)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "LLM budget exceeded",
+			"code":    "budget_exceeded",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.logger.Error("failed to run saved search", zap.String("search_id", search.ID), zap.Error(err))
+	render.Status(r, http.StatusInternalServerError)
+	render.JSON(w, r, map[string]interface{}{
+		"error":   "Failed to run saved search",
+		"details": err.Error(),
+	})
+}
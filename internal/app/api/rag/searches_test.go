@@ -0,0 +1,155 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSavedSearchCollections(t *testing.T) {
+	_, router := newBotTestHandler(t, nil)
+
+	do := func(user, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", user))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("alice", http.MethodPost, "/projects/p1/rag/collections", `{"name":" "}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected missing name to be rejected, got %d", rec.Code)
+	}
+	rec := do("alice", http.MethodPost, "/projects/p1/rag/collections", `{"name":"Release checks"}`)
+	var created struct {
+		Data SearchCollection `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if rec.Code != http.StatusCreated || created.Data.CreatedBy != "alice" {
+		t.Fatalf("create failed: %d %s", rec.Code, rec.Body)
+	}
+	collection := "/projects/p1/rag/collections/" + created.Data.ID
+
+	// Private collections are hidden from other project members
+	if rec := do("bob", http.MethodGet, collection, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected private collection to be hidden, got %d", rec.Code)
+	}
+	if rec := do("bob", http.MethodGet, "/projects/p1/rag/collections", ""); strings.Contains(rec.Body.String(), created.Data.ID) {
+		t.Fatalf("unexpected listing %s", rec.Body)
+	}
+
+	if rec := do("alice", http.MethodPost, collection+"/searches", `{"request":{"query":""}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected empty query to be rejected, got %d", rec.Code)
+	}
+	if rec := do("alice", http.MethodPost, collection+"/searches", `{"request":{"query":"x","filter":"tag:("}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected invalid filter to be rejected, got %d", rec.Code)
+	}
+	rec = do("alice", http.MethodPost, collection+"/searches", `{"request":{"query":"How do I install?","options":{"enable_rerank":true}}}`)
+	var search struct {
+		Data SavedSearch `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &search)
+	if rec.Code != http.StatusCreated || search.Data.Name != "How do I install?" || !search.Data.Request.Options.EnableRerank {
+		t.Fatalf("save search failed: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := do("alice", http.MethodPut, collection, `{"name":"Release checks","shared":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("share failed: %d %s", rec.Code, rec.Body)
+	}
+	rec = do("bob", http.MethodGet, collection, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), search.Data.ID) {
+		t.Fatalf("expected shared collection to be visible: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("bob", http.MethodDelete, collection+"/searches/"+search.Data.ID, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected non-creator edit to be rejected, got %d", rec.Code)
+	}
+
+	// Without a pipeline the searches cannot run
+	if rec := do("bob", http.MethodPost, collection+"/searches/"+search.Data.ID+"/run", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected run without pipeline to be unavailable, got %d", rec.Code)
+	}
+	if rec := do("bob", http.MethodPost, collection+"/run", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected batch run without pipeline to be unavailable, got %d", rec.Code)
+	}
+
+	if rec := do("alice", http.MethodGet, "/projects/p2/rag/collections/"+created.Data.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected other project's collection to be hidden, got %d", rec.Code)
+	}
+	if rec := do("alice", http.MethodDelete, collection, ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("alice", http.MethodGet, collection, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected deleted collection to be gone, got %d", rec.Code)
+	}
+}
+
+func TestSearchRunHistory(t *testing.T) {
+	h, _ := newBotTestHandler(t, nil)
+	ctx := context.Background()
+	start := time.Now()
+
+	for i := 0; i < maxSearchRuns+3; i++ {
+		run := &SavedSearchRun{
+			ID:       "run_" + string(rune('a'+i)),
+			SearchID: "ss_1",
+			Answer:   "answer",
+			RanAt:    start.Add(time.Duration(i) * time.Minute),
+		}
+		if i == maxSearchRuns+2 {
+			run.Error = "pipeline failed"
+		}
+		if err := h.manager.SaveSearchRun(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err := h.manager.ListSearchRuns(ctx, "ss_1", 100)
+	if err != nil || len(runs) != maxSearchRuns || runs[0].Error == "" {
+		t.Fatalf("unexpected runs %d %v", len(runs), err)
+	}
+	previous, err := h.manager.previousSearchRun(ctx, "ss_1")
+	if err != nil || previous == nil || previous.ID != runs[1].ID {
+		t.Fatalf("expected failed run to be skipped, got %+v %v", previous, err)
+	}
+}
+
+func TestDiffSearchRuns(t *testing.T) {
+	previous := &SavedSearchRun{
+		ID:     "run_1",
+		Answer: "Install the CLI with brew install metabase",
+		Sources: []RunSource{
+			{DocumentID: "install", Score: 0.9},
+			{DocumentID: "faq", Score: 0.5},
+		},
+	}
+
+	same := &SavedSearchRun{Answer: previous.Answer, Sources: previous.Sources}
+	diff := diffSearchRuns(previous, same)
+	if diff.Drifted || diff.AnswerChanged || diff.AnswerSimilarity != 1 || diff.TopScoreDelta != 0 {
+		t.Fatalf("unexpected diff for identical run %+v", diff)
+	}
+
+	changed := &SavedSearchRun{
+		Answer: "Download the release archive from the website",
+		Sources: []RunSource{
+			{DocumentID: "install", Score: 0.7},
+			{DocumentID: "releases", Score: 0.6},
+		},
+	}
+	diff = diffSearchRuns(previous, changed)
+	if !diff.Drifted || !diff.AnswerChanged || diff.AnswerSimilarity >= searchDriftSimilarity {
+		t.Fatalf("expected drift %+v", diff)
+	}
+	if len(diff.AddedDocuments) != 1 || diff.AddedDocuments[0] != "releases" ||
+		len(diff.RemovedDocuments) != 1 || diff.RemovedDocuments[0] != "faq" {
+		t.Fatalf("unexpected document changes %+v", diff)
+	}
+	if diff.TopScoreDelta != -0.2 || diff.PreviousRunID != "run_1" {
+		t.Fatalf("unexpected score delta %+v", diff)
+	}
+}
@@ -0,0 +1,207 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSlackAPIURL = "https://slack.com/api"
+
+	// slackMaxSkew Slack 请求时间戳允许的最大偏差，防止重放
+	slackMaxSkew = 5 * time.Minute
+)
+
+// botHTTPClient 调用聊天平台 API 的 HTTP 客户端
+var botHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// slackMention 消息中的 @提及，如 <@U0123ABC>
+var slackMention = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+
+// slackEnvelope Slack Events API 请求
+type slackEnvelope struct {
+	Type      string     `json:"type"`
+	Challenge string     `json:"challenge"`
+	Event     slackEvent `json:"event"`
+}
+
+type slackEvent struct {
+	Type     string `json:"type"`
+	Channel  string `json:"channel"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+}
+
+// handleSlackEvents 处理 Slack Events API：@机器人 的消息在消息线程中回答
+func (h *Handler) handleSlackEvents(w http.ResponseWriter, r *http.Request) {
+	if h.bots == nil || h.bots.SlackSigningSecret == "" {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Slack integration not configured",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Invalid request body",
+		})
+		return
+	}
+	if err := verifySlackSignature(h.bots.SlackSigningSecret, r.Header, body, time.Now()); err != nil {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid signature",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var envelope slackEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	switch envelope.Type {
+	case "url_verification":
+		render.JSON(w, r, map[string]interface{}{
+			"challenge": envelope.Challenge,
+		})
+		return
+	case "event_callback":
+		// Slack 在 3 秒内未收到响应时重试，重试的事件已在处理中
+		event := envelope.Event
+		if event.Type == "app_mention" && event.BotID == "" && r.Header.Get("X-Slack-Retry-Num") == "" {
+			go h.answerSlackMention(event)
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// answerSlackMention 回答 @机器人 的提问并附带引用
+func (h *Handler) answerSlackMention(event slackEvent) {
+	ctx, cancel := context.WithTimeout(context.Background(), botAnswerTimeout)
+	defer cancel()
+
+	thread := event.ThreadTS
+	if thread == "" {
+		thread = event.TS
+	}
+	message := map[string]interface{}{
+		"channel":      event.Channel,
+		"thread_ts":    thread,
+		"unfurl_links": false,
+	}
+
+	question := strings.TrimSpace(slackMention.ReplaceAllString(event.Text, ""))
+	channel := h.botChannelFor(ctx, BotPlatformSlack, event.Channel)
+	switch {
+	case channel == nil:
+		message["text"] = "This channel is not connected to a MetaBase project."
+	case question == "":
+		message["text"] = "Ask me a question about this project's documents."
+	default:
+		result, err := h.answerInChannel(ctx, channel, question)
+		if err != nil {
+			h.logger.Error("slack bot query failed", zap.String("project_id", channel.ProjectID), zap.Error(err))
+		}
+		message["text"] = botAnswerText(result, err)
+		if err == nil {
+			attachments := []map[string]interface{}{}
+			for _, citation := range channel.citations(result.Sources) {
+				attachments = append(attachments, map[string]interface{}{
+					"color":      "#4a90d9",
+					"title":      citation.Title,
+					"title_link": citation.URL,
+					"text":       citation.Excerpt,
+				})
+			}
+			message["attachments"] = attachments
+		}
+	}
+
+	if err := h.postSlackMessage(ctx, message); err != nil {
+		h.logger.Error("failed to post slack message", zap.String("channel_id", event.Channel), zap.Error(err))
+	}
+}
+
+// postSlackMessage 调用 chat.postMessage 发送消息
+func (h *Handler) postSlackMessage(ctx context.Context, message map[string]interface{}) error {
+	apiURL := h.bots.SlackAPIURL
+	if apiURL == "" {
+		apiURL = defaultSlackAPIURL
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+h.bots.SlackBotToken)
+
+	resp, err := botHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid slack response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack API error: %s", result.Error)
+	}
+	return nil
+}
+
+// verifySlackSignature 校验 Slack 请求签名：v0=HMAC-SHA256(signing secret, "v0:时间戳:请求体")
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing request timestamp")
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return fmt.Errorf("request timestamp too old")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
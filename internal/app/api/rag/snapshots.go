@@ -0,0 +1,370 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+// maxAutomaticSnapshots 每个项目保留的自动快照数量（重建索引前与恢复前），手动快照不自动清理
+const maxAutomaticSnapshots = 5
+
+// createSnapshotRequest 创建索引快照请求
+type createSnapshotRequest struct {
+	Name string `json:"name"`
+}
+
+// restoreSnapshotRequest 恢复索引快照请求
+type restoreSnapshotRequest struct {
+	Force bool `json:"force"` // 嵌入模型已变更时仍然恢复
+}
+
+// SaveIndexSnapshot 保存索引快照，并清理超出保留数量的自动快照
+func (m *Manager) SaveIndexSnapshot(ctx context.Context, snapshot *core.IndexSnapshot) error {
+	content, err := json.Marshal(struct {
+		Documents []core.Document      `json:"documents"`
+		Chunks    []core.DocumentChunk `json:"chunks"`
+	}{snapshot.Documents, snapshot.Chunks})
+	if err != nil {
+		return fmt.Errorf("failed to encode index snapshot: %w", err)
+	}
+	summary := *snapshot
+	summary.Documents = nil
+	summary.Chunks = nil
+	metadata, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode index snapshot: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_index_snapshots (id, project_id, reason, metadata, content, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		snapshot.ID, snapshot.ProjectID, snapshot.Reason, string(metadata), string(content), snapshot.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save index snapshot: %w", err)
+	}
+
+	if snapshot.Reason == core.SnapshotManual {
+		return nil
+	}
+	_, err = m.db.ExecContext(ctx, `
+		DELETE FROM rag_index_snapshots
+		WHERE project_id = ? AND reason != ? AND id NOT IN (
+			SELECT id FROM rag_index_snapshots
+			WHERE project_id = ? AND reason != ?
+			ORDER BY created_at DESC LIMIT ?
+		)`,
+		snapshot.ProjectID, core.SnapshotManual, snapshot.ProjectID, core.SnapshotManual, maxAutomaticSnapshots,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune index snapshots: %w", err)
+	}
+	return nil
+}
+
+// ListIndexSnapshots 列出项目的索引快照（不含内容），按创建时间倒序
+func (m *Manager) ListIndexSnapshots(ctx context.Context, projectID string) ([]core.IndexSnapshot, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT metadata FROM rag_index_snapshots
+		WHERE project_id = ?
+		ORDER BY created_at DESC`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list index snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []core.IndexSnapshot{}
+	for rows.Next() {
+		var metadata string
+		if err := rows.Scan(&metadata); err != nil {
+			return nil, fmt.Errorf("failed to list index snapshots: %w", err)
+		}
+		var snapshot core.IndexSnapshot
+		if err := json.Unmarshal([]byte(metadata), &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode index snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list index snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// GetIndexSnapshot 获取包含内容的索引快照，不存在时返回 nil
+func (m *Manager) GetIndexSnapshot(ctx context.Context, projectID, snapshotID string) (*core.IndexSnapshot, error) {
+	var metadata, content string
+	err := m.db.QueryRowContext(ctx, `
+		SELECT metadata, content FROM rag_index_snapshots
+		WHERE project_id = ? AND id = ?`,
+		projectID, snapshotID,
+	).Scan(&metadata, &content)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index snapshot: %w", err)
+	}
+
+	var snapshot core.IndexSnapshot
+	if err := json.Unmarshal([]byte(metadata), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode index snapshot: %w", err)
+	}
+	if err := json.Unmarshal([]byte(content), &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode index snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// DeleteIndexSnapshot 删除索引快照
+func (m *Manager) DeleteIndexSnapshot(ctx context.Context, projectID, snapshotID string) error {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM rag_index_snapshots WHERE project_id = ? AND id = ?`, projectID, snapshotID)
+	if err != nil {
+		return fmt.Errorf("failed to delete index snapshot: %w", err)
+	}
+	return nil
+}
+
+// projectDataSourceIDs 项目全部数据源（含停用的数据源与上传文档）的ID
+func (h *Handler) projectDataSourceIDs(ctx context.Context, projectID string) ([]string, error) {
+	sources, err := h.manager.ListDataSources(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{uploadDataSourceID(projectID)}
+	for _, ds := range sources {
+		ids = append(ids, ds.ID)
+	}
+	return ids, nil
+}
+
+// lockProjectSyncs 获取项目全部数据源的同步锁，任一数据源正在同步时返回 errSyncInProgress
+func (h *Handler) lockProjectSyncs(ctx context.Context, sourceIDs []string) (func(), error) {
+	var locks []core.Lock
+	release := func() {
+		for _, lock := range locks {
+			lock.Unlock(context.Background())
+		}
+	}
+	for _, id := range sourceIDs {
+		lock, err := h.pipeline.Locker().TryLock(ctx, syncLockKey(id), h.pipeline.LockTTL())
+		if err != nil {
+			release()
+			if errors.Is(err, core.ErrLockHeld) {
+				return nil, errSyncInProgress
+			}
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+	return release, nil
+}
+
+// handleListSnapshots 列出项目的索引快照
+func (h *Handler) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+
+	snapshots, err := h.manager.ListIndexSnapshots(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to list index snapshots", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list snapshots",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": snapshots,
+	})
+}
+
+// handleCreateSnapshot 为项目全部数据源的分块、向量与配置指纹创建快照
+func (h *Handler) handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req createSnapshotRequest
+	if r.ContentLength > 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	sourceIDs, err := h.projectDataSourceIDs(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to list data sources", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to create snapshot",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, _ := r.Context().Value("user_id").(string)
+	snapshot, err := h.pipeline.CreateSnapshot(r.Context(), core.SnapshotOptions{
+		ProjectID:     projectID,
+		Name:          req.Name,
+		Reason:        core.SnapshotManual,
+		DataSourceIDs: sourceIDs,
+		CreatedBy:     userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to create index snapshot", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to create snapshot",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	snapshot.Documents = nil
+	snapshot.Chunks = nil
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{
+		"data": snapshot,
+	})
+}
+
+// handleRestoreSnapshot 用快照替换其数据源当前的索引内容，恢复期间暂停这些数据源的同步
+func (h *Handler) handleRestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req restoreSnapshotRequest
+	if r.ContentLength > 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	snapshotID := chi.URLParam(r, "snapshotId")
+	snapshots, err := h.manager.ListIndexSnapshots(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to list index snapshots", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to restore snapshot",
+			"details": err.Error(),
+		})
+		return
+	}
+	var snapshot *core.IndexSnapshot
+	for i := range snapshots {
+		if snapshots[i].ID == snapshotID {
+			snapshot = &snapshots[i]
+		}
+	}
+	if snapshot == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Snapshot not found",
+		})
+		return
+	}
+
+	release, err := h.lockProjectSyncs(r.Context(), snapshot.DataSourceIDs)
+	if errors.Is(err, errSyncInProgress) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Sync in progress",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to lock data source syncs", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to restore snapshot",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer release()
+
+	userID, _ := r.Context().Value("user_id").(string)
+	result, err := h.pipeline.RestoreSnapshot(r.Context(), projectID, snapshotID, core.SnapshotRestoreOptions{
+		Force:      req.Force,
+		RestoredBy: userID,
+	})
+	if errors.Is(err, core.ErrSnapshotIncompatible) {
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Snapshot embedding model differs from the current configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to restore index snapshot", zap.String("snapshot_id", snapshotID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to restore snapshot",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": result,
+	})
+}
+
+// handleDeleteSnapshot 删除索引快照
+func (h *Handler) handleDeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	snapshotID := chi.URLParam(r, "snapshotId")
+
+	if err := h.manager.DeleteIndexSnapshot(r.Context(), projectID, snapshotID); err != nil {
+		h.logger.Error("failed to delete index snapshot", zap.String("snapshot_id", snapshotID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete snapshot",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.deleteExport(r.Context(), projectID, snapshotID)
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Snapshot deleted",
+	})
+}
@@ -0,0 +1,87 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestIndexSnapshots(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+
+	start := time.Now().Add(-time.Hour)
+	manual := &core.IndexSnapshot{
+		ID:            "manual",
+		ProjectID:     "p1",
+		Name:          "before chunker change",
+		Reason:        core.SnapshotManual,
+		DataSourceIDs: []string{"ds1"},
+		DocumentCount: 1,
+		ChunkCount:    1,
+		CreatedAt:     start,
+		Documents:     []core.Document{{ID: "d1", DataSourceID: "ds1", Content: "hello"}},
+		Chunks:        []core.DocumentChunk{{ID: "c1", DocumentID: "d1", Content: "hello", Embedding: []float64{0.1, 0.2}}},
+	}
+	if err := h.manager.SaveIndexSnapshot(ctx, manual); err != nil {
+		t.Fatal(err)
+	}
+	// Only the latest automatic snapshots are kept
+	for i := 1; i <= maxAutomaticSnapshots+2; i++ {
+		err := h.manager.SaveIndexSnapshot(ctx, &core.IndexSnapshot{
+			ID:        fmt.Sprintf("auto%d", i),
+			ProjectID: "p1",
+			Reason:    core.SnapshotPreReindex,
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects/p1/rag/snapshots", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list snapshots failed: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data []core.IndexSnapshot `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if len(resp.Data) != maxAutomaticSnapshots+1 || resp.Data[0].ID != "auto7" || resp.Data[len(resp.Data)-1].ID != "manual" {
+		t.Fatalf("unexpected snapshots %+v", resp.Data)
+	}
+	if listed := resp.Data[len(resp.Data)-1]; listed.Chunks != nil || listed.ChunkCount != 1 {
+		t.Fatalf("expected listed snapshot without content, got %+v", listed)
+	}
+
+	stored, err := h.manager.GetIndexSnapshot(ctx, "p1", "manual")
+	if err != nil || stored == nil || len(stored.Documents) != 1 || len(stored.Chunks) != 1 || len(stored.Chunks[0].Embedding) != 2 {
+		t.Fatalf("expected snapshot content, got %+v, %v", stored, err)
+	}
+	if other, _ := h.manager.GetIndexSnapshot(ctx, "p2", "manual"); other != nil {
+		t.Fatal("expected snapshot to be scoped to its project")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/projects/p1/rag/snapshots/manual", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if stored, _ := h.manager.GetIndexSnapshot(ctx, "p1", "manual"); rec.Code != http.StatusOK || stored != nil {
+		t.Fatalf("expected snapshot deleted: %d %s", rec.Code, rec.Body)
+	}
+
+	// Creating and restoring snapshots needs the pipeline
+	req = httptest.NewRequest(http.MethodPost, "/projects/p1/rag/snapshots/auto7/restore", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected restore without pipeline to fail, got %d", rec.Code)
+	}
+}
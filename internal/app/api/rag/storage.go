@@ -0,0 +1,372 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// storageUsageTTL 缓存的存储用量在此时间内直接返回，定期任务按此间隔重新统计
+	storageUsageTTL = time.Hour
+
+	// storageCheckInterval 定期任务检查过期存储用量的间隔
+	storageCheckInterval = 10 * time.Minute
+
+	// storageTopDocuments 项目存储用量中列出的最大文档数
+	storageTopDocuments = 10
+
+	// defaultStorageConsumers 管理端存储排行默认返回的项目数
+	defaultStorageConsumers = 20
+)
+
+// StorageQuota 项目所属租户的存储配额，LimitBytes 为 0 表示不限制
+type StorageQuota struct {
+	TenantID   string `json:"tenant_id"`
+	LimitBytes int64  `json:"limit_bytes"`
+}
+
+// StorageQuotaLoader 查找项目所属租户的存储配额，项目不存在时返回 nil
+type StorageQuotaLoader func(ctx context.Context, projectID string) (*StorageQuota, error)
+
+// StorageQuotasFromDB 从租户 limits 中的 max_storage_mb 读取存储配额
+func StorageQuotasFromDB(db *sql.DB) StorageQuotaLoader {
+	return func(ctx context.Context, projectID string) (*StorageQuota, error) {
+		quota := &StorageQuota{}
+		var raw sql.NullString
+		err := db.QueryRowContext(ctx, `
+			SELECT p.tenant_id, t.limits FROM projects p JOIN tenants t ON t.id = p.tenant_id
+			WHERE p.id = ? AND p.deleted_at IS NULL`,
+			projectID,
+		).Scan(&quota.TenantID, &raw)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !raw.Valid || raw.String == "" {
+			return quota, nil
+		}
+
+		var limits struct {
+			MaxStorageMB int64 `json:"max_storage_mb"`
+		}
+		if err := json.Unmarshal([]byte(raw.String), &limits); err != nil {
+			return nil, fmt.Errorf("invalid tenant limits: %w", err)
+		}
+		if limits.MaxStorageMB > 0 {
+			quota.LimitBytes = limits.MaxStorageMB << 20
+		}
+		return quota, nil
+	}
+}
+
+// SetStorageQuotas 设置租户存储配额来源，未设置时不限制上传
+func (h *Handler) SetStorageQuotas(loader StorageQuotaLoader) {
+	h.storageQuotas = loader
+}
+
+// ProjectStorage 管理端存储排行中的一个项目
+type ProjectStorage struct {
+	ProjectID    string    `json:"project_id"`
+	TenantID     string    `json:"tenant_id"`
+	TotalBytes   int64     `json:"total_bytes"`
+	DeletedBytes int64     `json:"deleted_bytes"`
+	Documents    int       `json:"documents"`
+	MeasuredAt   time.Time `json:"measured_at"`
+}
+
+// TenantStorage 租户下所有项目的存储用量合计
+type TenantStorage struct {
+	TenantID   string `json:"tenant_id"`
+	Projects   int    `json:"projects"`
+	TotalBytes int64  `json:"total_bytes"`
+}
+
+// SaveStorageUsage 保存项目最近一次统计的存储用量
+func (m *Manager) SaveStorageUsage(ctx context.Context, tenantID string, usage *core.StorageUsage) error {
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return fmt.Errorf("failed to encode storage usage: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_storage_usage (project_id, tenant_id, usage, total_bytes, measured_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(project_id) DO UPDATE SET
+			tenant_id = excluded.tenant_id,
+			usage = excluded.usage,
+			total_bytes = excluded.total_bytes,
+			measured_at = excluded.measured_at`,
+		usage.ProjectID, tenantID, string(data), usage.TotalSize, usage.MeasuredAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save storage usage: %w", err)
+	}
+	return nil
+}
+
+// GetStorageUsage 获取项目最近一次统计的存储用量，从未统计时返回 nil
+func (m *Manager) GetStorageUsage(ctx context.Context, projectID string) (*core.StorageUsage, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx, `SELECT usage FROM rag_storage_usage WHERE project_id = ?`, projectID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	var usage core.StorageUsage
+	if err := json.Unmarshal([]byte(data), &usage); err != nil {
+		return nil, fmt.Errorf("failed to decode storage usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// TenantStorageBytes 租户下所有项目最近一次统计的存储字节数之和
+func (m *Manager) TenantStorageBytes(ctx context.Context, tenantID string) (int64, error) {
+	var total sql.NullInt64
+	err := m.db.QueryRowContext(ctx,
+		`SELECT SUM(total_bytes) FROM rag_storage_usage WHERE tenant_id = ?`, tenantID,
+	).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum tenant storage: %w", err)
+	}
+	return total.Int64, nil
+}
+
+// ListStorageConsumers 按存储字节数从大到小列出项目
+func (m *Manager) ListStorageConsumers(ctx context.Context, limit int) ([]ProjectStorage, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT project_id, tenant_id, usage, total_bytes, measured_at FROM rag_storage_usage
+		ORDER BY total_bytes DESC, project_id LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	projects := make([]ProjectStorage, 0)
+	for rows.Next() {
+		var project ProjectStorage
+		var data string
+		if err := rows.Scan(&project.ProjectID, &project.TenantID, &data, &project.TotalBytes, &project.MeasuredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan storage usage: %w", err)
+		}
+		var usage core.StorageUsage
+		if err := json.Unmarshal([]byte(data), &usage); err == nil {
+			project.DeletedBytes = usage.DeletedBytes
+			project.Documents = usage.DocumentCount
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// ListTenantStorage 按存储字节数从大到小列出租户
+func (m *Manager) ListTenantStorage(ctx context.Context, limit int) ([]TenantStorage, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT tenant_id, COUNT(*), SUM(total_bytes) FROM rag_storage_usage WHERE tenant_id != ''
+		GROUP BY tenant_id ORDER BY SUM(total_bytes) DESC, tenant_id LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant storage: %w", err)
+	}
+	defer rows.Close()
+
+	tenants := make([]TenantStorage, 0)
+	for rows.Next() {
+		var tenant TenantStorage
+		if err := rows.Scan(&tenant.TenantID, &tenant.Projects, &tenant.TotalBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant storage: %w", err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// storageProjects 列出存储用量早于 before 或从未统计、但有数据源或上传文档的项目
+func (m *Manager) storageProjects(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT project_id FROM (
+			SELECT project_id FROM rag_data_sources
+			UNION SELECT project_id FROM rag_ingest_jobs
+			UNION SELECT project_id FROM rag_storage_usage
+		) WHERE project_id NOT IN (SELECT project_id FROM rag_storage_usage WHERE measured_at >= ?)
+		ORDER BY project_id`, before.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projects []string
+	for rows.Next() {
+		var projectID string
+		if err := rows.Scan(&projectID); err != nil {
+			return nil, fmt.Errorf("failed to scan storage projects: %w", err)
+		}
+		projects = append(projects, projectID)
+	}
+	return projects, rows.Err()
+}
+
+// storageQuota 获取项目的存储配额，未设置配额来源时返回 nil
+func (h *Handler) storageQuota(ctx context.Context, projectID string) (*StorageQuota, error) {
+	if h.storageQuotas == nil {
+		return nil, nil
+	}
+	return h.storageQuotas(ctx, projectID)
+}
+
+// measureStorage 重新统计项目的存储用量并保存，用于配额检查和存储排行
+func (h *Handler) measureStorage(ctx context.Context, projectID string) (*core.StorageUsage, error) {
+	sources, err := h.projectDataSourceIDs(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := h.pipeline.MeasureStorage(ctx, core.StorageUsageOptions{
+		ProjectID:     projectID,
+		DataSourceIDs: sources,
+		Top:           storageTopDocuments,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID := ""
+	quota, err := h.storageQuota(ctx, projectID)
+	if err != nil {
+		h.logger.Warn("failed to load storage quota", zap.String("project_id", projectID), zap.Error(err))
+	} else if quota != nil {
+		tenantID = quota.TenantID
+	}
+	if err := h.manager.SaveStorageUsage(ctx, tenantID, usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// checkStorageQuota 检查租户已用存储加上新增字节数是否超出配额，返回超出的配额，未超出时返回 nil
+func (h *Handler) checkStorageQuota(ctx context.Context, projectID string, incoming int64) (*StorageQuota, int64, error) {
+	quota, err := h.storageQuota(ctx, projectID)
+	if err != nil || quota == nil || quota.LimitBytes == 0 {
+		return nil, 0, err
+	}
+	used, err := h.manager.TenantStorageBytes(ctx, quota.TenantID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if used+incoming > quota.LimitBytes {
+		return quota, used, nil
+	}
+	return nil, used, nil
+}
+
+// runStorageUsage 重新统计存储用量已过期的项目，供配额检查和管理端存储排行使用
+func (s *SyncScheduler) runStorageUsage(ctx context.Context, now time.Time) {
+	if s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+	if now.Sub(s.lastStorageCheck) < storageCheckInterval {
+		return
+	}
+	s.lastStorageCheck = now
+
+	projects, err := s.handler.manager.storageProjects(ctx, now.Add(-storageUsageTTL))
+	if err != nil {
+		s.logger.Error("failed to list projects for storage usage", zap.Error(err))
+		return
+	}
+	for _, projectID := range projects {
+		if _, err := s.handler.measureStorage(ctx, projectID); err != nil {
+			s.logger.Error("failed to measure storage usage", zap.String("project_id", projectID), zap.Error(err))
+		}
+	}
+}
+
+// handleGetStorageUsage 获取项目文档、分块、嵌入和索引的存储用量及占用最多的文档，
+// 默认返回一小时内的统计结果，refresh=true 时重新统计
+func (h *Handler) handleGetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	usage, err := h.manager.GetStorageUsage(r.Context(), projectID)
+	refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh"))
+	if err == nil && (refresh || usage == nil || time.Since(usage.MeasuredAt) >= storageUsageTTL) {
+		usage, err = h.measureStorage(r.Context(), projectID)
+	}
+	var quota *StorageQuota
+	var used int64
+	if err == nil {
+		quota, err = h.storageQuota(r.Context(), projectID)
+	}
+	if err == nil && quota != nil {
+		used, err = h.manager.TenantStorageBytes(r.Context(), quota.TenantID)
+	}
+	if err != nil {
+		h.logger.Error("failed to get storage usage", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get storage usage",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	data := map[string]interface{}{
+		"usage": usage,
+	}
+	if quota != nil {
+		data["quota"] = map[string]interface{}{
+			"tenant_id":   quota.TenantID,
+			"limit_bytes": quota.LimitBytes,
+			"used_bytes":  used,
+		}
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": data,
+	})
+}
+
+// handleListStorageConsumers 列出存储占用最多的项目和租户，limit 默认 20
+func (h *Handler) handleListStorageConsumers(w http.ResponseWriter, r *http.Request) {
+	limit := defaultStorageConsumers
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+
+	projects, err := h.manager.ListStorageConsumers(r.Context(), limit)
+	var tenants []TenantStorage
+	if err == nil {
+		tenants, err = h.manager.ListTenantStorage(r.Context(), limit)
+	}
+	if err != nil {
+		h.logger.Error("failed to list storage consumers", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list storage consumers",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": map[string]interface{}{
+			"projects": projects,
+			"tenants":  tenants,
+		},
+	})
+}
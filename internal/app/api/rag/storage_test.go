@@ -0,0 +1,130 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestStorageUsage(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+	m := h.manager
+
+	now := time.Now()
+	save := func(tenantID, projectID string, total int64, at time.Time) {
+		usage := &core.StorageUsage{ProjectID: projectID, MeasuredAt: at}
+		usage.TotalSize = total
+		usage.DocumentCount = 2
+		if err := m.SaveStorageUsage(ctx, tenantID, usage); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save("t1", "p1", 600, now)
+	save("t1", "p2", 300, now.Add(-2*time.Hour))
+	save("t2", "p3", 800, now)
+	save("t1", "p1", 700, now)
+
+	if total, err := m.TenantStorageBytes(ctx, "t1"); err != nil || total != 1000 {
+		t.Fatalf("expected t1 to use 1000 bytes, got %d %v", total, err)
+	}
+	usage, err := m.GetStorageUsage(ctx, "p1")
+	if err != nil || usage == nil || usage.TotalSize != 700 || usage.DocumentCount != 2 {
+		t.Fatalf("unexpected usage %+v %v", usage, err)
+	}
+	if usage, _ := m.GetStorageUsage(ctx, "missing"); usage != nil {
+		t.Fatalf("expected no usage, got %+v", usage)
+	}
+
+	projects, err := m.ListStorageConsumers(ctx, 2)
+	if err != nil || len(projects) != 2 || projects[0].ProjectID != "p3" || projects[1].ProjectID != "p1" {
+		t.Fatalf("unexpected consumers %+v %v", projects, err)
+	}
+	tenants, err := m.ListTenantStorage(ctx, 10)
+	if err != nil || len(tenants) != 2 || tenants[0].TenantID != "t1" || tenants[0].Projects != 2 {
+		t.Fatalf("unexpected tenants %+v %v", tenants, err)
+	}
+
+	// Only projects measured more than an hour ago or never are refreshed
+	stale, err := m.storageProjects(ctx, now.Add(-storageUsageTTL))
+	if err != nil || len(stale) != 1 || stale[0] != "p2" {
+		t.Fatalf("unexpected stale projects %v %v", stale, err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/projects/p1/storage", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a pipeline, got %d", rec.Code)
+	}
+}
+
+func TestUploadStorageQuota(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	h.indexer = &fakeIndexer{docs: make(chan core.Document, 4)}
+	h.SetStorageQuotas(func(ctx context.Context, projectID string) (*StorageQuota, error) {
+		return &StorageQuota{TenantID: "t1", LimitBytes: 1000}, nil
+	})
+	usage := &core.StorageUsage{ProjectID: "p2", MeasuredAt: time.Now()}
+	usage.TotalSize = 990
+	if err := h.manager.SaveStorageUsage(context.Background(), "t1", usage); err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(content string) int {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "notes.md")
+		part.Write([]byte(content))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/projects/p1/documents", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := upload("a longer note"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected the upload to exceed the quota, got %d", code)
+	}
+	if code := upload("# ok"); code != http.StatusAccepted {
+		t.Fatalf("expected the upload to fit the quota, got %d", code)
+	}
+}
+
+func TestUploadBlobLimits(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	h.indexer = &fakeIndexer{docs: make(chan core.Document, 4)}
+	h.SetBlobLimits(func(ctx context.Context, projectID string) (blobstore.Limits, error) {
+		return blobstore.Limits{MaxSize: 16, AllowedTypes: []string{".md", "application/pdf"}}, nil
+	})
+
+	upload := func(name, content string) int {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", name)
+		part.Write([]byte(content))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/projects/p1/documents", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := upload("notes.md", "a note longer than the limit"); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected the file to exceed the size limit, got %d", code)
+	}
+	if code := upload("page.html", "<p>hi</p>"); code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected html to be rejected, got %d", code)
+	}
+	if code := upload("notes.md", "# ok"); code != http.StatusAccepted {
+		t.Fatalf("expected the file to be allowed, got %d", code)
+	}
+}
@@ -0,0 +1,308 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// tieringInterval 定期任务将冷文档嵌入移出索引的间隔
+const tieringInterval = 6 * time.Hour
+
+// SaveColdDocument 保存嵌入已移入冷存储的文档，实现 core.TierStore
+func (m *Manager) SaveColdDocument(ctx context.Context, doc *core.ColdDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode cold document: %w", err)
+	}
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_cold_documents (document_id, project_id, record, moved_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(document_id) DO UPDATE SET
+			project_id = excluded.project_id,
+			record = excluded.record,
+			moved_at = excluded.moved_at`,
+		doc.DocumentID, doc.ProjectID, string(data), doc.MovedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save cold document: %w", err)
+	}
+	return nil
+}
+
+// DeleteColdDocument 删除文档的冷存储记录（回迁或删除后）
+func (m *Manager) DeleteColdDocument(ctx context.Context, documentID string) error {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM rag_cold_documents WHERE document_id = ?`, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete cold document: %w", err)
+	}
+	return nil
+}
+
+// ListColdDocuments 列出全部冷存储记录
+func (m *Manager) ListColdDocuments(ctx context.Context) ([]core.ColdDocument, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT record FROM rag_cold_documents ORDER BY moved_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cold documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := []core.ColdDocument{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list cold documents: %w", err)
+		}
+		var doc core.ColdDocument
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			return nil, fmt.Errorf("failed to decode cold document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// SavePin 保存常驻索引的文档
+func (m *Manager) SavePin(ctx context.Context, pin *core.DocumentPin) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO rag_document_pins (document_id, project_id, pinned_by, pinned_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(document_id) DO UPDATE SET
+			project_id = excluded.project_id,
+			pinned_by = excluded.pinned_by,
+			pinned_at = excluded.pinned_at`,
+		pin.DocumentID, pin.ProjectID, pin.PinnedBy, pin.PinnedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save pin: %w", err)
+	}
+	return nil
+}
+
+// DeletePin 取消文档常驻
+func (m *Manager) DeletePin(ctx context.Context, documentID string) error {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM rag_document_pins WHERE document_id = ?`, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete pin: %w", err)
+	}
+	return nil
+}
+
+// ListPins 列出全部常驻文档
+func (m *Manager) ListPins(ctx context.Context) ([]core.DocumentPin, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT document_id, project_id, pinned_by, pinned_at FROM rag_document_pins ORDER BY pinned_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pins: %w", err)
+	}
+	defer rows.Close()
+
+	pins := []core.DocumentPin{}
+	for rows.Next() {
+		var pin core.DocumentPin
+		if err := rows.Scan(&pin.DocumentID, &pin.ProjectID, &pin.PinnedBy, &pin.PinnedAt); err != nil {
+			return nil, fmt.Errorf("failed to list pins: %w", err)
+		}
+		pins = append(pins, pin)
+	}
+	return pins, rows.Err()
+}
+
+// tierProject 将项目中长期未被检索的文档嵌入移入冷存储，coldAfter 为 0 时使用管道配置
+func (h *Handler) tierProject(ctx context.Context, projectID string, coldAfter time.Duration) (*core.TieringResult, error) {
+	sources, err := h.projectDataSourceIDs(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	retrieved, err := h.manager.documentLastRetrieved(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return h.pipeline.TierColdEmbeddings(ctx, core.TieringOptions{
+		ProjectID:     projectID,
+		DataSourceIDs: sources,
+		ColdAfter:     coldAfter,
+		LastRetrieved: retrieved,
+	})
+}
+
+// runTiering 管道配置了 cold_after 时，定期将各项目长期未被检索的文档嵌入移入冷存储
+func (s *SyncScheduler) runTiering(ctx context.Context, now time.Time) {
+	if s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+	if s.handler.pipeline.Config().Storage.ColdAfter <= 0 {
+		return
+	}
+	if now.Sub(s.lastTieringRun) < tieringInterval {
+		return
+	}
+	s.lastTieringRun = now
+
+	projects, err := s.handler.manager.ragProjects(ctx)
+	if err != nil {
+		s.logger.Error("failed to list projects for tiering", zap.Error(err))
+		return
+	}
+	for _, projectID := range projects {
+		result, err := s.handler.tierProject(ctx, projectID, 0)
+		if err != nil {
+			s.logger.Error("failed to tier embeddings", zap.String("project_id", projectID), zap.Error(err))
+			continue
+		}
+		if result.DocumentsMoved > 0 {
+			s.logger.Info("Embeddings moved to cold storage",
+				zap.String("project_id", projectID),
+				zap.Int("documents", result.DocumentsMoved),
+				zap.Int64("bytes", result.BytesMoved),
+			)
+		}
+	}
+}
+
+// handleGetTiering 获取项目冷热分层统计和常驻文档
+func (h *Handler) handleGetTiering(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": map[string]interface{}{
+			"stats":  h.pipeline.TieringStats(projectID),
+			"pinned": h.pipeline.ListPinnedDocuments(projectID),
+		},
+	})
+}
+
+// handleRunTiering 立即将项目中长期未被检索的文档嵌入移入冷存储，cold_after 覆盖配置的时长（如 720h）
+func (h *Handler) handleRunTiering(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var coldAfter time.Duration
+	if value := r.URL.Query().Get("cold_after"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "cold_after must be a positive duration",
+			})
+			return
+		}
+		coldAfter = d
+	}
+
+	result, err := h.tierProject(r.Context(), projectID, coldAfter)
+	if err != nil {
+		h.logger.Error("failed to tier embeddings", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to tier embeddings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": result,
+	})
+}
+
+// handlePinDocument 将文档设为常驻索引，冷文档立即回迁
+func (h *Handler) handlePinDocument(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	documentID := chi.URLParam(r, "documentId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	doc, err := h.pipeline.GetDocument(r.Context(), documentID)
+	if err == nil && doc == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Document not found",
+		})
+		return
+	}
+	pin := core.DocumentPin{DocumentID: documentID, ProjectID: projectID}
+	pin.PinnedBy, _ = r.Context().Value("user_id").(string)
+	if err == nil {
+		err = h.pipeline.PinDocument(r.Context(), pin)
+	}
+	if err != nil {
+		h.logger.Error("failed to pin document", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to pin document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Document pinned",
+	})
+}
+
+// handleUnpinDocument 取消文档常驻，之后长期未被检索时可移入冷存储
+func (h *Handler) handleUnpinDocument(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentId")
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	if err := h.pipeline.UnpinDocument(r.Context(), documentID); err != nil {
+		h.logger.Error("failed to unpin document", zap.String("document_id", documentID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to unpin document",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Document unpinned",
+	})
+}
+
+// handleGetTieringStats 获取全部项目的冷热分层统计
+func (h *Handler) handleGetTieringStats(w http.ResponseWriter, r *http.Request) {
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": h.pipeline.TieringStats(""),
+	})
+}
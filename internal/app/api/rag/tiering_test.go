@@ -0,0 +1,61 @@
+package rag
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestTierStore(t *testing.T) {
+	ctx := context.Background()
+	h, router := newBotTestHandler(t, nil)
+	m := h.manager
+
+	cold := &core.ColdDocument{DocumentID: "d1", ProjectID: "p1", ChunkIDs: []string{"d1_0"}, Bytes: 64, MovedAt: time.Now()}
+	if err := m.SaveColdDocument(ctx, cold); err != nil {
+		t.Fatal(err)
+	}
+	cold.StoredBytes = 20
+	if err := m.SaveColdDocument(ctx, cold); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := m.ListColdDocuments(ctx)
+	if err != nil || len(docs) != 1 || docs[0].StoredBytes != 20 || len(docs[0].ChunkIDs) != 1 {
+		t.Fatalf("unexpected cold documents %+v %v", docs, err)
+	}
+	if err := m.DeleteColdDocument(ctx, "d1"); err != nil {
+		t.Fatal(err)
+	}
+	if docs, _ := m.ListColdDocuments(ctx); len(docs) != 0 {
+		t.Fatalf("expected no cold documents, got %+v", docs)
+	}
+
+	if err := m.SavePin(ctx, &core.DocumentPin{DocumentID: "d2", ProjectID: "p1", PinnedBy: "u1", PinnedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	pins, err := m.ListPins(ctx)
+	if err != nil || len(pins) != 1 || pins[0].PinnedBy != "u1" {
+		t.Fatalf("unexpected pins %+v %v", pins, err)
+	}
+	if err := m.DeletePin(ctx, "d2"); err != nil {
+		t.Fatal(err)
+	}
+	if pins, _ := m.ListPins(ctx); len(pins) != 0 {
+		t.Fatalf("expected no pins, got %+v", pins)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/projects/p1/rag/tiering", nil),
+		httptest.NewRequest(http.MethodPut, "/projects/p1/documents/d2/pin", nil),
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503 without a pipeline for %s, got %d", req.URL, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+// SaveTombstone 保存文档软删除标记
+func (m *Manager) SaveTombstone(ctx context.Context, tombstone *core.Tombstone) error {
+	data, err := json.Marshal(tombstone)
+	if err != nil {
+		return fmt.Errorf("failed to encode tombstone: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_document_tombstones (document_id, tombstone, deleted_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(document_id) DO UPDATE SET
+			tombstone = excluded.tombstone,
+			deleted_at = excluded.deleted_at`,
+		tombstone.DocumentID, string(data), tombstone.DeletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save tombstone: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTombstone 删除文档软删除标记（恢复或彻底清除后）
+func (m *Manager) DeleteTombstone(ctx context.Context, documentID string) error {
+	_, err := m.db.ExecContext(ctx, `DELETE FROM rag_document_tombstones WHERE document_id = ?`, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tombstone: %w", err)
+	}
+	return nil
+}
+
+// ListTombstones 列出全部软删除标记
+func (m *Manager) ListTombstones(ctx context.Context) ([]core.Tombstone, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT tombstone FROM rag_document_tombstones ORDER BY deleted_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tombstones: %w", err)
+	}
+	defer rows.Close()
+
+	tombstones := []core.Tombstone{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list tombstones: %w", err)
+		}
+		var tombstone core.Tombstone
+		if err := json.Unmarshal([]byte(data), &tombstone); err != nil {
+			return nil, fmt.Errorf("failed to decode tombstone: %w", err)
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tombstones: %w", err)
+	}
+
+	return tombstones, nil
+}
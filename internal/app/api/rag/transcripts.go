@@ -0,0 +1,519 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// ChatTranscript 一次聊天连接的对话记录
+type ChatTranscript struct {
+	ID        string     `json:"id"`
+	ProjectID string     `json:"project_id"`
+	TenantID  string     `json:"tenant_id,omitempty"`
+	UserID    string     `json:"user_id,omitempty"`
+	StartedAt time.Time  `json:"started_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	TurnCount int        `json:"turn_count"`
+	Turns     []ChatTurn `json:"turns,omitempty"`
+
+	// PIIMasked 导出时被遮盖的个人信息数，仅出现在管理员导出中
+	PIIMasked *core.PIIMaskResult `json:"pii_masked,omitempty"`
+}
+
+// ChatTurn 对话中的一问一答
+type ChatTurn struct {
+	ID        string        `json:"id"`
+	QueryID   string        `json:"query_id,omitempty"`
+	Query     string        `json:"query"`
+	Answer    string        `json:"answer,omitempty"`
+	Sources   []core.Source `json:"sources,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// TranscriptFilter 筛选对话记录，空字段不限制
+type TranscriptFilter struct {
+	TenantID  string     `json:"-"`
+	ProjectID string     `json:"project_id,omitempty"`
+	UserID    string     `json:"user_id,omitempty"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+}
+
+// ChatRetentionLoader 查找租户的对话记录保留天数，0 表示永久保留
+type ChatRetentionLoader func(ctx context.Context, tenantID string) (int, error)
+
+// ChatRetentionFromDB 从租户设置的 storage.chat_retention_days 读取对话记录保留天数
+func ChatRetentionFromDB(db *sql.DB) ChatRetentionLoader {
+	return func(ctx context.Context, tenantID string) (int, error) {
+		var raw sql.NullString
+		err := db.QueryRowContext(ctx, `SELECT settings FROM tenants WHERE id = ?`, tenantID).Scan(&raw)
+		if err == sql.ErrNoRows || !raw.Valid || raw.String == "" {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		var settings struct {
+			Storage *tenant.StorageSettings `json:"storage"`
+		}
+		if err := json.Unmarshal([]byte(raw.String), &settings); err != nil {
+			return 0, fmt.Errorf("invalid tenant settings: %w", err)
+		}
+		if settings.Storage == nil || settings.Storage.ChatRetentionDays <= 0 {
+			return 0, nil
+		}
+		return settings.Storage.ChatRetentionDays, nil
+	}
+}
+
+// SetChatRetention 设置对话记录保留天数来源，未设置时对话记录永久保留
+func (h *Handler) SetChatRetention(loader ChatRetentionLoader) {
+	h.chatRetention = loader
+}
+
+// SaveChatTurn 追加一轮对话，对话记录不存在时创建
+func (m *Manager) SaveChatTurn(ctx context.Context, transcript *ChatTranscript, turn *ChatTurn) error {
+	data, err := json.Marshal(turn)
+	if err != nil {
+		return fmt.Errorf("failed to encode chat turn: %w", err)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save chat turn: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rag_chat_transcripts (id, project_id, tenant_id, user_id, started_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET updated_at = excluded.updated_at`,
+		transcript.ID, transcript.ProjectID, transcript.TenantID, transcript.UserID, transcript.StartedAt, turn.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to save chat transcript: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO rag_chat_turns (id, transcript_id, turn, created_at) VALUES (?, ?, ?, ?)`,
+		turn.ID, transcript.ID, string(data), turn.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to save chat turn: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListChatTranscripts 按最近更新倒序列出对话记录，不含对话内容
+func (m *Manager) ListChatTranscripts(ctx context.Context, filter TranscriptFilter) ([]ChatTranscript, error) {
+	query := `
+		SELECT t.id, t.project_id, COALESCE(t.tenant_id, ''), COALESCE(t.user_id, ''), t.started_at, t.updated_at,
+			(SELECT COUNT(*) FROM rag_chat_turns c WHERE c.transcript_id = t.id)
+		FROM rag_chat_transcripts t WHERE 1 = 1`
+	var args []interface{}
+	if filter.TenantID != "" {
+		query += ` AND t.tenant_id = ?`
+		args = append(args, filter.TenantID)
+	}
+	if filter.ProjectID != "" {
+		query += ` AND t.project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.UserID != "" {
+		query += ` AND t.user_id = ?`
+		args = append(args, filter.UserID)
+	}
+	if filter.From != nil {
+		query += ` AND t.updated_at >= ?`
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		query += ` AND t.started_at < ?`
+		args = append(args, *filter.To)
+	}
+	query += ` ORDER BY t.updated_at DESC`
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat transcripts: %w", err)
+	}
+	defer rows.Close()
+
+	transcripts := []ChatTranscript{}
+	for rows.Next() {
+		var t ChatTranscript
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.TenantID, &t.UserID, &t.StartedAt, &t.UpdatedAt, &t.TurnCount); err != nil {
+			return nil, fmt.Errorf("failed to scan chat transcript: %w", err)
+		}
+		transcripts = append(transcripts, t)
+	}
+	return transcripts, rows.Err()
+}
+
+// chatTurns 按时间顺序读取对话内容
+func (m *Manager) chatTurns(ctx context.Context, transcriptID string) ([]ChatTurn, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT turn FROM rag_chat_turns WHERE transcript_id = ? ORDER BY created_at, id`,
+		transcriptID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat turns: %w", err)
+	}
+	defer rows.Close()
+
+	turns := []ChatTurn{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan chat turn: %w", err)
+		}
+		var turn ChatTurn
+		if err := json.Unmarshal([]byte(data), &turn); err != nil {
+			return nil, fmt.Errorf("invalid chat turn: %w", err)
+		}
+		turns = append(turns, turn)
+	}
+	return turns, rows.Err()
+}
+
+// DeleteChatTranscripts 删除对话记录及其内容，返回删除的记录数
+func (m *Manager) DeleteChatTranscripts(ctx context.Context, ids []string) (int, error) {
+	deleted := 0
+	for _, id := range ids {
+		if _, err := m.db.ExecContext(ctx, `DELETE FROM rag_chat_turns WHERE transcript_id = ?`, id); err != nil {
+			return deleted, fmt.Errorf("failed to delete chat turns: %w", err)
+		}
+		result, err := m.db.ExecContext(ctx, `DELETE FROM rag_chat_transcripts WHERE id = ?`, id)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete chat transcript: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		deleted += int(n)
+	}
+	return deleted, nil
+}
+
+// PurgeChatTranscripts 删除租户中超过保留期未更新的对话记录
+func (m *Manager) PurgeChatTranscripts(ctx context.Context, tenantID string, before time.Time) (int, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT id FROM rag_chat_transcripts WHERE tenant_id = ? AND updated_at < ?`,
+		tenantID, before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired chat transcripts: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan chat transcript: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return m.DeleteChatTranscripts(ctx, ids)
+}
+
+// chatTranscriptTenants 有对话记录的租户
+func (m *Manager) chatTranscriptTenants(ctx context.Context) ([]string, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT DISTINCT tenant_id FROM rag_chat_transcripts WHERE tenant_id IS NOT NULL AND tenant_id != '' ORDER BY tenant_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat transcript tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, fmt.Errorf("failed to scan chat transcript tenant: %w", err)
+		}
+		tenants = append(tenants, tenantID)
+	}
+	return tenants, rows.Err()
+}
+
+// recordChatTurn 保存聊天连接中完成或失败的一轮对话，保存失败只记录日志
+func (s *chatSession) recordChatTurn(id, text string, result *core.QueryResult, queryErr error) {
+	turn := &ChatTurn{
+		ID:        fmt.Sprintf("turn_%d", time.Now().UnixNano()),
+		Query:     text,
+		CreatedAt: time.Now(),
+	}
+	if queryErr != nil {
+		turn.Error = queryErr.Error()
+	} else {
+		turn.QueryID = result.QueryID
+		turn.Answer = result.GeneratedAnswer
+		if turn.Answer == "" {
+			turn.Answer = result.GeneratedResponse
+		}
+		turn.Sources = result.Sources
+	}
+	// 连接可能已关闭，记录仍需写入
+	ctx := context.WithoutCancel(s.ctx)
+	if err := s.handler.manager.SaveChatTurn(ctx, s.transcript, turn); err != nil {
+		s.handler.logger.Error("failed to save chat transcript",
+			zap.String("transcript_id", s.transcript.ID), zap.String("query_id", id), zap.Error(err))
+	}
+}
+
+// maskTranscript 遮盖对话内容中的个人信息
+func maskTranscript(t *ChatTranscript) {
+	masked := core.PIIMaskResult{}
+	mask := func(text *string) {
+		var result core.PIIMaskResult
+		*text, result = core.MaskPII(*text)
+		masked.Add(result)
+	}
+	for i := range t.Turns {
+		turn := &t.Turns[i]
+		mask(&turn.Query)
+		mask(&turn.Answer)
+		mask(&turn.Error)
+		for j := range turn.Sources {
+			mask(&turn.Sources[j].Excerpt)
+		}
+	}
+	t.PIIMasked = &masked
+}
+
+// writeTranscripts 以 JSON Lines 逐条输出对话记录及其内容，mask 时遮盖个人信息
+func (h *Handler) writeTranscripts(w http.ResponseWriter, r *http.Request, transcripts []ChatTranscript, filename string, mask bool) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	encoder := json.NewEncoder(w)
+	for i := range transcripts {
+		t := &transcripts[i]
+		turns, err := h.manager.chatTurns(r.Context(), t.ID)
+		if err != nil {
+			// 响应已开始，只能中止输出
+			h.logger.Error("failed to export chat transcript", zap.String("transcript_id", t.ID), zap.Error(err))
+			return
+		}
+		t.Turns = turns
+		if mask {
+			maskTranscript(t)
+		}
+		if err := encoder.Encode(t); err != nil {
+			return
+		}
+	}
+}
+
+// ownTranscript 获取当前用户在项目中的对话记录；失败时已写入响应
+func (h *Handler) ownTranscript(w http.ResponseWriter, r *http.Request) *ChatTranscript {
+	transcriptID := chi.URLParam(r, "transcriptId")
+	userID := searchUser(r)
+	if userID == "" {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Chat transcript not found",
+		})
+		return nil
+	}
+	transcripts, err := h.manager.ListChatTranscripts(r.Context(), TranscriptFilter{
+		ProjectID: chi.URLParam(r, "projectId"),
+		UserID:    userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list chat transcripts", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get chat transcript",
+			"details": err.Error(),
+		})
+		return nil
+	}
+	for i := range transcripts {
+		if transcripts[i].ID == transcriptID {
+			return &transcripts[i]
+		}
+	}
+	render.Status(r, http.StatusNotFound)
+	render.JSON(w, r, map[string]interface{}{
+		"error": "Chat transcript not found",
+	})
+	return nil
+}
+
+// handleListTranscripts 列出当前用户在项目中的对话记录
+func (h *Handler) handleListTranscripts(w http.ResponseWriter, r *http.Request) {
+	userID := searchUser(r)
+	if userID == "" {
+		render.JSON(w, r, map[string]interface{}{
+			"data": []ChatTranscript{},
+		})
+		return
+	}
+	transcripts, err := h.manager.ListChatTranscripts(r.Context(), TranscriptFilter{
+		ProjectID: chi.URLParam(r, "projectId"),
+		UserID:    userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list chat transcripts", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list chat transcripts",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"data": transcripts,
+	})
+}
+
+// handleGetTranscript 获取当前用户的一条对话记录及其内容
+func (h *Handler) handleGetTranscript(w http.ResponseWriter, r *http.Request) {
+	transcript := h.ownTranscript(w, r)
+	if transcript == nil {
+		return
+	}
+	turns, err := h.manager.chatTurns(r.Context(), transcript.ID)
+	if err != nil {
+		h.logger.Error("failed to get chat turns", zap.String("transcript_id", transcript.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get chat transcript",
+			"details": err.Error(),
+		})
+		return
+	}
+	transcript.Turns = turns
+	render.JSON(w, r, map[string]interface{}{
+		"data": transcript,
+	})
+}
+
+// handleDeleteTranscript 删除当前用户的一条对话记录
+func (h *Handler) handleDeleteTranscript(w http.ResponseWriter, r *http.Request) {
+	transcript := h.ownTranscript(w, r)
+	if transcript == nil {
+		return
+	}
+	if _, err := h.manager.DeleteChatTranscripts(r.Context(), []string{transcript.ID}); err != nil {
+		h.logger.Error("failed to delete chat transcript", zap.String("transcript_id", transcript.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete chat transcript",
+			"details": err.Error(),
+		})
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Chat transcript deleted",
+	})
+}
+
+// handleExportTranscripts 以 JSON Lines 导出当前用户在项目中的全部对话记录
+func (h *Handler) handleExportTranscripts(w http.ResponseWriter, r *http.Request) {
+	projectID := chi.URLParam(r, "projectId")
+	userID := searchUser(r)
+	if userID == "" {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Authentication required",
+		})
+		return
+	}
+	transcripts, err := h.manager.ListChatTranscripts(r.Context(), TranscriptFilter{ProjectID: projectID, UserID: userID})
+	if err != nil {
+		h.logger.Error("failed to list chat transcripts", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to export chat transcripts",
+			"details": err.Error(),
+		})
+		return
+	}
+	h.writeTranscripts(w, r, transcripts, fmt.Sprintf("chat-transcripts-%s.jsonl", projectID), false)
+}
+
+// handleExportTenantTranscripts 为电子取证批量导出租户的对话记录，可按项目、用户和时间筛选，
+// 导出内容中的个人信息被遮盖。使用 POST 以便导出操作进入审计日志
+func (h *Handler) handleExportTenantTranscripts(w http.ResponseWriter, r *http.Request) {
+	tenantID := chi.URLParam(r, "tenantId")
+	var filter TranscriptFilter
+	if r.ContentLength != 0 {
+		if err := render.DecodeJSON(r.Body, &filter); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "from must be before to",
+		})
+		return
+	}
+	filter.TenantID = tenantID
+
+	transcripts, err := h.manager.ListChatTranscripts(r.Context(), filter)
+	if err != nil {
+		h.logger.Error("failed to list chat transcripts", zap.String("tenant_id", tenantID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to export chat transcripts",
+			"details": err.Error(),
+		})
+		return
+	}
+	w.Header().Set("X-Transcript-Count", strconv.Itoa(len(transcripts)))
+	h.writeTranscripts(w, r, transcripts, fmt.Sprintf("chat-transcripts-%s.jsonl", tenantID), true)
+}
+
+// runChatRetention 每天删除超过租户保留天数的对话记录
+func (s *SyncScheduler) runChatRetention(ctx context.Context, now time.Time) {
+	if s.handler.chatRetention == nil || s.handler.pipeline == nil || !s.handler.pipeline.IsLeader() {
+		return
+	}
+	if now.Sub(s.lastChatRetentionRun) < retentionInterval {
+		return
+	}
+	s.lastChatRetentionRun = now
+
+	tenants, err := s.handler.manager.chatTranscriptTenants(ctx)
+	if err != nil {
+		s.logger.Error("failed to list tenants for chat retention", zap.Error(err))
+		return
+	}
+	for _, tenantID := range tenants {
+		days, err := s.handler.chatRetention(ctx, tenantID)
+		if err != nil {
+			s.logger.Warn("failed to load chat retention", zap.String("tenant_id", tenantID), zap.Error(err))
+			continue
+		}
+		if days <= 0 {
+			continue
+		}
+		deleted, err := s.handler.manager.PurgeChatTranscripts(ctx, tenantID, now.AddDate(0, 0, -days))
+		if err != nil {
+			s.logger.Error("failed to purge chat transcripts", zap.String("tenant_id", tenantID), zap.Error(err))
+			continue
+		}
+		if deleted > 0 {
+			s.logger.Info("Chat transcripts purged", zap.String("tenant_id", tenantID), zap.Int("deleted", deleted))
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package rag
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+func TestChatTranscripts(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	router.Route("/tenants/{tenantId}/rag", h.RegisterTenantRoutes)
+	ctx := context.Background()
+
+	old := time.Now().AddDate(0, 0, -40)
+	transcripts := []*ChatTranscript{
+		{ID: "chat_1", ProjectID: "p1", TenantID: "t1", UserID: "alice", StartedAt: old},
+		{ID: "chat_2", ProjectID: "p1", TenantID: "t1", UserID: "bob", StartedAt: time.Now()},
+	}
+	turns := []*ChatTurn{
+		{ID: "turn_1", Query: "Email jane@example.com the invoice", Answer: "Done", CreatedAt: old},
+		{ID: "turn_2", Query: "Who owns billing?", Answer: "Call 415-555-0132", CreatedAt: time.Now(),
+			Sources: []core.Source{{DocumentID: "d1", Excerpt: "Billing: ops@example.com"}}},
+	}
+	for i := range transcripts {
+		if err := h.manager.SaveChatTurn(ctx, transcripts[i], turns[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	do := func(user, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req = req.WithContext(context.WithValue(req.Context(), "user_id", user))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("alice", http.MethodGet, "/projects/p1/rag/chat/transcripts", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "chat_1") || strings.Contains(rec.Body.String(), "chat_2") {
+		t.Fatalf("unexpected listing %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("alice", http.MethodGet, "/projects/p1/rag/chat/transcripts/chat_2", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected other user's transcript to be hidden, got %d", rec.Code)
+	}
+
+	// Users export their own transcripts unmasked
+	rec = do("alice", http.MethodGet, "/projects/p1/rag/chat/transcripts/export", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "jane@example.com") || strings.Contains(rec.Body.String(), "chat_2") {
+		t.Fatalf("unexpected user export %d %s", rec.Code, rec.Body)
+	}
+
+	// Admin exports mask PII and can be filtered
+	rec = do("admin", http.MethodPost, "/tenants/t1/rag/chat-transcripts/export", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Transcript-Count") != "2" {
+		t.Fatalf("admin export failed: %d %s", rec.Code, rec.Body)
+	}
+	masked := 0
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var exported ChatTranscript
+		if err := json.Unmarshal(scanner.Bytes(), &exported); err != nil {
+			t.Fatal(err)
+		}
+		if len(exported.Turns) != 1 || exported.PIIMasked == nil {
+			t.Fatalf("unexpected exported transcript %s", scanner.Text())
+		}
+		masked += exported.PIIMasked.Masked
+		if strings.Contains(scanner.Text(), "@example.com") || strings.Contains(scanner.Text(), "555-0132") {
+			t.Fatalf("PII leaked in export %s", scanner.Text())
+		}
+	}
+	if masked != 3 {
+		t.Fatalf("expected 3 masked spans, got %d", masked)
+	}
+	rec = do("admin", http.MethodPost, "/tenants/t1/rag/chat-transcripts/export", `{"user_id":"bob"}`)
+	if rec.Header().Get("X-Transcript-Count") != "1" || !strings.Contains(rec.Body.String(), "chat_2") {
+		t.Fatalf("unexpected filtered export %s", rec.Body)
+	}
+	if rec := do("admin", http.MethodPost, "/tenants/t2/rag/chat-transcripts/export", ""); rec.Header().Get("X-Transcript-Count") != "0" {
+		t.Fatalf("expected other tenant's export to be empty, got %s", rec.Body)
+	}
+
+	// Retention deletes transcripts idle for longer than the tenant allows
+	deleted, err := h.manager.PurgeChatTranscripts(ctx, "t1", time.Now().AddDate(0, 0, -30))
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected one purged transcript, got %d %v", deleted, err)
+	}
+	if turns, _ := h.manager.chatTurns(ctx, "chat_1"); len(turns) != 0 {
+		t.Fatalf("expected purged turns to be deleted, got %d", len(turns))
+	}
+
+	if rec := do("bob", http.MethodDelete, "/projects/p1/rag/chat/transcripts/chat_2", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do("bob", http.MethodGet, "/projects/p1/rag/chat/transcripts/chat_2", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected deleted transcript to be gone, got %d", rec.Code)
+	}
+}
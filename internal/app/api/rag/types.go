@@ -0,0 +1,60 @@
+package rag
+
+import "github.com/guileen/metabase/pkg/rag/core"
+
+// QueryRequest RAG查询请求
+type QueryRequest struct {
+	Query string `json:"query"`
+
+	// 过滤条件：表达式语法或结构化JSON，两者同时提供时取交集
+	Filter     string           `json:"filter,omitempty"`
+	FilterExpr *core.FilterExpr `json:"filter_expr,omitempty"`
+
+	// 结构化输出：要求答案为符合该JSON Schema的JSON
+	OutputSchema map[string]interface{} `json:"output_schema,omitempty"`
+
+	Options core.QueryOptions `json:"options"`
+}
+
+// BatchQueryRequest 批量查询请求
+type BatchQueryRequest struct {
+	Queries     []core.BatchQueryItem `json:"queries"`
+	Filter      string                `json:"filter,omitempty"`
+	FilterExpr  *core.FilterExpr      `json:"filter_expr,omitempty"`
+	Options     core.QueryOptions     `json:"options"`
+	Concurrency int                   `json:"concurrency,omitempty"`
+}
+
+// WebSocket 聊天消息类型
+const (
+	ChatMessageQuery     = "query"     // 客户端：发起查询
+	ChatMessageCancel    = "cancel"    // 客户端：取消进行中的查询
+	ChatMessagePing      = "ping"      // 客户端：保活
+	ChatMessagePong      = "pong"      // 服务端：保活应答
+	ChatMessageCitations = "citations" // 服务端：生成前的引用来源
+	ChatMessageToken     = "token"     // 服务端：部分生成内容
+	ChatMessageDone      = "done"      // 服务端：完整查询结果
+	ChatMessageCancelled = "cancelled" // 服务端：查询已取消
+	ChatMessageError     = "error"     // 服务端：查询或消息出错
+)
+
+// ChatClientMessage 客户端发送的WebSocket消息，query 消息携带与 /rag/query 相同的请求字段
+type ChatClientMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	QueryRequest
+}
+
+// ChatServerMessage 服务端发送的WebSocket消息，ID 对应客户端查询的 ID
+type ChatServerMessage struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id,omitempty"`
+	Content string            `json:"content,omitempty"`
+	Sources []core.Source     `json:"sources,omitempty"`
+	Result  *core.QueryResult `json:"result,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Code    string            `json:"code,omitempty"`
+
+	// TranscriptID 保存本轮对话的对话记录
+	TranscriptID string `json:"transcript_id,omitempty"`
+}
@@ -0,0 +1,715 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// 文档导入任务状态
+const (
+	IngestStatusProcessing = "processing"
+	IngestStatusCompleted  = "completed"
+	IngestStatusFailed     = "failed"
+)
+
+const (
+	maxUploadSize       = 32 << 20
+	maxIngestDocument   = 10 << 20
+	ingestTimeout       = 10 * time.Minute
+	defaultIngestJobs   = 50
+	ingestSourceFile    = "file"
+	ingestSourceURL     = "url"
+	ingestFetchTimeout  = 30 * time.Second
+	uploadDataSourceTag = "uploads"
+)
+
+// documentIndexer 索引单个文档并报告完成的阶段，由 *core.Pipeline 实现
+type documentIndexer interface {
+	IndexDocument(ctx context.Context, doc core.Document, progress func(core.IngestStage)) (*core.IndexResult, error)
+}
+
+// IngestJob 文档导入任务，记录 received → extracted → chunked → embedded → indexed 各阶段进度
+type IngestJob struct {
+	ID         string `json:"id"`
+	ProjectID  string `json:"project_id"`
+	SourceType string `json:"source_type"` // file 或 url
+	Source     string `json:"source"`      // 文件名或 URL
+	DocumentID string `json:"document_id"`
+	Title      string `json:"title,omitempty"`
+
+	Status string           `json:"status"`
+	Stage  core.IngestStage `json:"stage"` // 最近完成的阶段
+	Stages []IngestStageLog `json:"stages"`
+	Error  *IngestJobError  `json:"error,omitempty"`
+
+	ChunksCreated int    `json:"chunks_created,omitempty"`
+	Attempts      int    `json:"attempts,omitempty"`     // 从死信队列重试的次数
+	DuplicateOf   string `json:"duplicate_of,omitempty"` // 内容与本项目已索引文档相同时，跳过处理并关联到该文档
+
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IngestStageLog 阶段完成时间
+type IngestStageLog struct {
+	Stage       core.IngestStage `json:"stage"`
+	CompletedAt time.Time        `json:"completed_at"`
+}
+
+// IngestJobError 导入失败的阶段和原因
+type IngestJobError struct {
+	Stage   core.IngestStage `json:"stage"`
+	Message string           `json:"message"`
+}
+
+// complete 记录阶段完成
+func (j *IngestJob) complete(stage core.IngestStage) {
+	j.Stage = stage
+	j.Stages = append(j.Stages, IngestStageLog{Stage: stage, CompletedAt: time.Now()})
+}
+
+// fail 记录失败，失败阶段为最近完成阶段的下一阶段
+func (j *IngestJob) fail(err error) {
+	stage := nextIngestStage(j.Stage)
+	var ingestErr *core.IngestError
+	if errors.As(err, &ingestErr) {
+		stage = ingestErr.Stage
+		err = ingestErr.Err
+	}
+	j.Status = IngestStatusFailed
+	j.Error = &IngestJobError{Stage: stage, Message: err.Error()}
+}
+
+func nextIngestStage(stage core.IngestStage) core.IngestStage {
+	for i, s := range core.IngestStages {
+		if s == stage && i+1 < len(core.IngestStages) {
+			return core.IngestStages[i+1]
+		}
+	}
+	return stage
+}
+
+// ingestInput 待导入的文件内容或 URL
+type ingestInput struct {
+	job         *IngestJob
+	content     []byte
+	contentType string
+	tags        []string
+	metadata    map[string]interface{}
+}
+
+// uploadDataSourceID 上传文档所属的数据源
+func uploadDataSourceID(projectID string) string {
+	return uploadDataSourceTag + ":" + projectID
+}
+
+// uploadDocumentID 同一项目中同名文件或同一 URL 的再次上传作为新版本
+func uploadDocumentID(projectID, source string) string {
+	sum := sha256.Sum256([]byte(projectID + "\x00" + source))
+	return "upload_" + hex.EncodeToString(sum[:12])
+}
+
+// SaveIngestJob 保存导入任务
+func (m *Manager) SaveIngestJob(ctx context.Context, job *IngestJob) error {
+	job.UpdatedAt = time.Now()
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode ingest job: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_ingest_jobs (id, project_id, job, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			job = excluded.job,
+			updated_at = excluded.updated_at`,
+		job.ID, job.ProjectID, string(data), job.CreatedAt, job.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save ingest job: %w", err)
+	}
+	return nil
+}
+
+// GetIngestJob 获取项目的导入任务，不存在时返回 nil
+func (m *Manager) GetIngestJob(ctx context.Context, projectID, jobID string) (*IngestJob, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT job FROM rag_ingest_jobs WHERE id = ? AND project_id = ?`,
+		jobID, projectID,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ingest job: %w", err)
+	}
+
+	var job IngestJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to decode ingest job: %w", err)
+	}
+	return &job, nil
+}
+
+// ListIngestJobs 列出项目最近的导入任务
+func (m *Manager) ListIngestJobs(ctx context.Context, projectID string, limit int) ([]IngestJob, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT job FROM rag_ingest_jobs WHERE project_id = ? ORDER BY created_at DESC LIMIT ?`,
+		projectID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingest jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []IngestJob{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list ingest jobs: %w", err)
+		}
+		var job IngestJob
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return nil, fmt.Errorf("failed to decode ingest job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list ingest jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// handleUploadDocuments 接收上传的文件（multipart 的 file 字段，可多个）或 URL（JSON），
+// 返回导入任务，文档在后台处理。metadata 为自定义元数据（multipart 中为 JSON 字符串），
+// 须符合租户定义的文档元数据结构，其字段可用 meta.<字段> 过滤
+func (h *Handler) handleUploadDocuments(w http.ResponseWriter, r *http.Request) {
+	if h.indexer == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	projectID := chi.URLParam(r, "projectId")
+	userID, _ := r.Context().Value("user_id").(string)
+	var inputs []*ingestInput
+	newJob := func(sourceType, source, title string) *IngestJob {
+		now := time.Now()
+		return &IngestJob{
+			ID:         fmt.Sprintf("ingest_%d_%d", now.UnixNano(), len(inputs)),
+			ProjectID:  projectID,
+			SourceType: sourceType,
+			Source:     source,
+			DocumentID: uploadDocumentID(projectID, source),
+			Title:      title,
+			Status:     IngestStatusProcessing,
+			CreatedBy:  userID,
+			CreatedAt:  now,
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid upload",
+				"details": err.Error(),
+			})
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		tags := splitTags(r.FormValue("tags"))
+		custom, err := decodeMetadata(r.FormValue("metadata"))
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid metadata",
+				"details": err.Error(),
+			})
+			return
+		}
+		files := r.MultipartForm.File["file"]
+		if len(files) == 0 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "At least one file is required",
+			})
+			return
+		}
+		for _, header := range files {
+			if header.Size > maxIngestDocument {
+				render.Status(r, http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, map[string]interface{}{
+					"error": fmt.Sprintf("File %s exceeds %d MB", header.Filename, maxIngestDocument>>20),
+				})
+				return
+			}
+			file, err := header.Open()
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, map[string]interface{}{
+					"error":   "Invalid upload",
+					"details": err.Error(),
+				})
+				return
+			}
+			content, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, map[string]interface{}{
+					"error":   "Invalid upload",
+					"details": err.Error(),
+				})
+				return
+			}
+
+			title := ""
+			if len(files) == 1 {
+				title = r.FormValue("title")
+			}
+			inputs = append(inputs, &ingestInput{
+				job:         newJob(ingestSourceFile, filepath.Base(header.Filename), title),
+				content:     content,
+				contentType: header.Header.Get("Content-Type"),
+				tags:        tags,
+				metadata:    custom,
+			})
+		}
+	} else {
+		var req struct {
+			URL      string                 `json:"url"`
+			Title    string                 `json:"title"`
+			Tags     []string               `json:"tags"`
+			Metadata map[string]interface{} `json:"metadata"`
+		}
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Invalid JSON data",
+				"details": err.Error(),
+			})
+			return
+		}
+		u, err := url.Parse(req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "A file upload or an http(s) url is required",
+			})
+			return
+		}
+		inputs = append(inputs, &ingestInput{
+			job:      newJob(ingestSourceURL, u.String(), req.Title),
+			tags:     req.Tags,
+			metadata: req.Metadata,
+		})
+	}
+	if !h.checkDocumentMetadata(w, r, projectID, inputs[0].metadata) {
+		return
+	}
+
+	// 上传文件须符合租户的文件大小和类型限制，URL 的内容在保存原始文档时检查
+	limits, err := h.blobLimits(r.Context(), projectID)
+	if err != nil {
+		h.logger.Error("failed to load storage limits", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to check storage limits",
+			"details": err.Error(),
+		})
+		return
+	}
+	for _, input := range inputs {
+		if input.content == nil {
+			continue
+		}
+		contentType := blobstore.DetectType(input.content, input.contentType)
+		err := limits.Check(input.job.Source, contentType, int64(len(input.content)))
+		if errors.Is(err, blobstore.ErrTooLarge) {
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   fmt.Sprintf("File %s is too large", input.job.Source),
+				"details": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			render.Status(r, http.StatusUnsupportedMediaType)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   fmt.Sprintf("File type of %s is not allowed", input.job.Source),
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	// URL 的内容在下载后才知道大小，只按已上传文件计入
+	var incoming int64
+	for _, input := range inputs {
+		incoming += int64(len(input.content))
+	}
+	quota, used, err := h.checkStorageQuota(r.Context(), projectID, incoming)
+	if err != nil {
+		h.logger.Error("failed to check storage quota", zap.String("project_id", projectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to check storage quota",
+			"details": err.Error(),
+		})
+		return
+	}
+	if quota != nil {
+		render.Status(r, http.StatusRequestEntityTooLarge)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Storage quota exceeded",
+			"details": fmt.Sprintf("tenant uses %d of %d bytes", used, quota.LimitBytes),
+		})
+		return
+	}
+
+	jobs := make([]IngestJob, 0, len(inputs))
+	for _, input := range inputs {
+		input.job.complete(core.IngestReceived)
+		if err := h.manager.SaveIngestJob(r.Context(), input.job); err != nil {
+			h.logger.Error("failed to save ingest job", zap.String("project_id", projectID), zap.Error(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "Failed to create ingest job",
+				"details": err.Error(),
+			})
+			return
+		}
+		snapshot := *input.job
+		snapshot.Stages = append([]IngestStageLog(nil), input.job.Stages...)
+		jobs = append(jobs, snapshot)
+	}
+	for _, input := range inputs {
+		go h.runIngestJob(input)
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]interface{}{
+		"data": jobs,
+	})
+}
+
+// runIngestJob 提取文本并索引，每个阶段完成后保存任务状态
+func (h *Handler) runIngestJob(input *ingestInput) {
+	ctx, cancel := context.WithTimeout(context.Background(), ingestTimeout)
+	defer cancel()
+
+	job := input.job
+	worker := h.workers.start(JobKindIngest, job.ID, job.ProjectID, "")
+	defer h.workers.done(worker)
+	save := func() {
+		h.workers.stage(worker, string(job.Stage))
+		if err := h.manager.SaveIngestJob(ctx, job); err != nil {
+			h.logger.Error("failed to save ingest job", zap.String("job_id", job.ID), zap.Error(err))
+		}
+	}
+
+	// 提取器或索引器 panic 时任务记为失败，堆栈保存到死信队列
+	defer func() {
+		if p := recover(); p != nil {
+			err := fmt.Errorf("panic: %v", p)
+			h.logger.Error("document ingestion panicked", zap.String("job_id", job.ID), zap.Any("panic", p))
+			job.fail(err)
+			save()
+			h.jobFailure(ctx, job.ProjectID)
+			h.deadLetterIngest(ctx, input, err, ingestTrace(job, err)+string(debug.Stack()))
+		}
+	}()
+
+	doc, err := h.extractDocument(ctx, input)
+	if err != nil {
+		job.fail(err)
+		save()
+		h.jobFailure(ctx, job.ProjectID)
+		h.deadLetterIngest(ctx, input, err, ingestTrace(job, err))
+		return
+	}
+	job.complete(core.IngestExtracted)
+	save()
+
+	result, err := h.indexer.IndexDocument(ctx, *doc, func(stage core.IngestStage) {
+		job.complete(stage)
+		save()
+	})
+	if result != nil {
+		job.ChunksCreated = result.ChunksCreated
+		for _, duplicate := range result.Duplicates {
+			if duplicate.DocumentID == job.DocumentID {
+				job.DuplicateOf = duplicate.DuplicateOf
+			}
+		}
+	}
+	if err != nil {
+		h.logger.Error("document ingestion failed",
+			zap.String("project_id", job.ProjectID),
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		job.fail(err)
+		h.jobFailure(ctx, job.ProjectID)
+		h.deadLetterIngest(ctx, input, err, ingestTrace(job, err))
+	} else {
+		job.Status = IngestStatusCompleted
+		h.saveOriginal(ctx, input)
+	}
+	save()
+}
+
+// extractDocument 下载 URL 或读取上传内容并提取文本
+func (h *Handler) extractDocument(ctx context.Context, input *ingestInput) (*core.Document, error) {
+	job := input.job
+	content, contentType := input.content, input.contentType
+	if job.SourceType == ingestSourceURL {
+		var err error
+		content, contentType, err = fetchDocument(ctx, job.Source)
+		if err != nil {
+			return nil, err
+		}
+		// 保留下载的内容，索引完成后保存原始文档
+		input.content, input.contentType = content, contentType
+	}
+
+	name := ingestFileName(job)
+	text, title, err := extractText(name, contentType, content)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("no text found in document")
+	}
+
+	if job.Title != "" {
+		title = job.Title
+	}
+	if title == "" {
+		title = name
+	}
+	now := time.Now()
+	extension := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	// 自定义元数据不能覆盖系统字段
+	custom := make(map[string]interface{}, len(input.metadata)+2)
+	for key, value := range input.metadata {
+		custom[key] = value
+	}
+	custom["project_id"] = job.ProjectID
+	custom["ingest_job_id"] = job.ID
+	return &core.Document{
+		ID:           job.DocumentID,
+		Title:        title,
+		Content:      text,
+		URI:          job.Source,
+		SourceType:   "upload",
+		DataSourceID: uploadDataSourceID(job.ProjectID),
+		Tags:         input.tags,
+		Metadata: core.DocumentMetadata{
+			FileName:   name,
+			FileSize:   int64(len(content)),
+			FileType:   contentType,
+			Extension:  extension,
+			CreatedAt:  now,
+			ModifiedAt: now,
+			Owner:      job.CreatedBy,
+			Length:     utf8.RuneCountInString(text),
+			WordCount:  len(strings.Fields(text)),
+			LineCount:  strings.Count(text, "\n") + 1,
+			Custom:     custom,
+		},
+		UpdatedAt: now,
+	}, nil
+}
+
+// ingestFileName 导入任务的文件名，URL 取路径最后一段
+func ingestFileName(job *IngestJob) string {
+	name := job.Source
+	if job.SourceType == ingestSourceURL {
+		if u, err := url.Parse(job.Source); err == nil {
+			name = path.Base(u.Path)
+		}
+	}
+	return name
+}
+
+// fetchDocument 下载 URL 内容，大小不超过 maxIngestDocument
+func fetchDocument(ctx context.Context, source string) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ingestFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := botHTTPClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch url: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("failed to fetch url: HTTP %d", resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxIngestDocument+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch url: %w", err)
+	}
+	if len(content) > maxIngestDocument {
+		return nil, "", fmt.Errorf("document exceeds %d MB", maxIngestDocument>>20)
+	}
+	return content, resp.Header.Get("Content-Type"), nil
+}
+
+// textExtensions 按纯文本导入的文件扩展名
+var textExtensions = map[string]bool{
+	".txt": true, ".md": true, ".markdown": true, ".rst": true, ".csv": true, ".tsv": true,
+	".json": true, ".yaml": true, ".yml": true, ".xml": true, ".log": true, ".ini": true, ".toml": true,
+	".go": true, ".py": true, ".js": true, ".ts": true, ".java": true, ".rb": true, ".rs": true,
+	".c": true, ".h": true, ".cpp": true, ".sh": true, ".sql": true,
+}
+
+var (
+	htmlTitle    = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlHead     = regexp.MustCompile(`(?is)<head[^>]*>.*?</head>`)
+	htmlHidden   = regexp.MustCompile(`(?is)<(script|style|noscript|template)[^>]*>.*?</(script|style|noscript|template)>`)
+	htmlBreaks   = regexp.MustCompile(`(?i)<(br|/p|/div|/h[1-6]|/li|/tr|/pre|/blockquote)[^>]*>`)
+	htmlTags     = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines   = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+	inlineSpaces = regexp.MustCompile(`[ \t]+`)
+)
+
+// extractText 按文件类型提取文本，HTML 去除标签并提取标题
+func extractText(name, contentType string, content []byte) (string, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	extension := strings.ToLower(filepath.Ext(name))
+
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml" || extension == ".html" || extension == ".htm"
+	isText := strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" ||
+		mediaType == "application/xml" || textExtensions[extension]
+	if !isHTML && !isText {
+		kind := mediaType
+		if kind == "" || kind == "application/octet-stream" {
+			kind = extension
+		}
+		return "", "", fmt.Errorf("unsupported document type %q", kind)
+	}
+	if !utf8.Valid(content) {
+		return "", "", fmt.Errorf("document is not valid UTF-8 text")
+	}
+
+	text := string(content)
+	if !isHTML {
+		return text, "", nil
+	}
+
+	title := ""
+	if match := htmlTitle.FindStringSubmatch(text); match != nil {
+		title = strings.TrimSpace(html.UnescapeString(match[1]))
+	}
+	text = htmlHead.ReplaceAllString(text, "")
+	text = htmlHidden.ReplaceAllString(text, "")
+	text = htmlBreaks.ReplaceAllString(text, "\n")
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+	text = inlineSpaces.ReplaceAllString(text, " ")
+	text = strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+	return text, title, nil
+}
+
+// splitTags 解析逗号分隔的标签
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// handleListIngestJobs 列出项目最近的文档导入任务
+func (h *Handler) handleListIngestJobs(w http.ResponseWriter, r *http.Request) {
+	limit := defaultIngestJobs
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > 500 {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "limit must be between 1 and 500",
+			})
+			return
+		}
+		limit = n
+	}
+
+	jobs, err := h.manager.ListIngestJobs(r.Context(), chi.URLParam(r, "projectId"), limit)
+	if err != nil {
+		h.logger.Error("failed to list ingest jobs", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list ingest jobs",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": jobs,
+	})
+}
+
+// handleGetIngestJob 获取文档导入任务的处理进度
+func (h *Handler) handleGetIngestJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.manager.GetIngestJob(r.Context(), chi.URLParam(r, "projectId"), chi.URLParam(r, "jobId"))
+	if err != nil {
+		h.logger.Error("failed to get ingest job", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get ingest job",
+			"details": err.Error(),
+		})
+		return
+	}
+	if job == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Ingest job not found",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": job,
+	})
+}
@@ -0,0 +1,171 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+type fakeIndexer struct {
+	docs    chan core.Document
+	fail    error
+	indexed map[string]string // content -> document ID
+}
+
+func (f *fakeIndexer) IndexDocument(ctx context.Context, doc core.Document, progress func(core.IngestStage)) (*core.IndexResult, error) {
+	f.docs <- doc
+	progress(core.IngestChunked)
+	if f.fail != nil {
+		return &core.IndexResult{}, f.fail
+	}
+	progress(core.IngestEmbedded)
+	progress(core.IngestIndexed)
+	if canonical, ok := f.indexed[doc.Content]; ok {
+		return &core.IndexResult{
+			DocumentsDuplicate: 1,
+			Duplicates:         []core.DuplicateDocument{{DocumentID: doc.ID, DuplicateOf: canonical}},
+		}, nil
+	}
+	if f.indexed == nil {
+		f.indexed = make(map[string]string)
+	}
+	f.indexed[doc.Content] = doc.ID
+	return &core.IndexResult{ChunksCreated: 2}, nil
+}
+
+func TestDocumentUploads(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	indexer := &fakeIndexer{docs: make(chan core.Document, 4)}
+	h.indexer = indexer
+
+	upload := func(name, content string) []IngestJob {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", name)
+		part.Write([]byte(content))
+		writer.WriteField("tags", "guide, setup")
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/projects/p1/documents", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("upload failed: %d %s", rec.Code, rec.Body)
+		}
+		var resp struct {
+			Data []IngestJob `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if len(resp.Data) != 1 || resp.Data[0].Stage != core.IngestReceived {
+			t.Fatalf("unexpected jobs %s", rec.Body)
+		}
+		return resp.Data
+	}
+	waitJob := func(id string) *IngestJob {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			req := httptest.NewRequest(http.MethodGet, "/projects/p1/documents/jobs/"+id, nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			var resp struct {
+				Data IngestJob `json:"data"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if resp.Data.Status != IngestStatusProcessing {
+				return &resp.Data
+			}
+		}
+		t.Fatalf("job %s did not finish", id)
+		return nil
+	}
+
+	jobs := upload("page.html", "<html><head><title>Install &amp; Setup</title><script>x()</script></head><body><p>Run make.</p></body></html>")
+	doc := <-indexer.docs
+	if doc.Title != "Install & Setup" || doc.Content != "Run make." || len(doc.Tags) != 2 || doc.ID != jobs[0].DocumentID {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	job := waitJob(jobs[0].ID)
+	if job.Status != IngestStatusCompleted || len(job.Stages) != 5 || job.ChunksCreated != 2 {
+		t.Fatalf("unexpected completed job %+v", job)
+	}
+	for i, stage := range core.IngestStages {
+		if job.Stages[i].Stage != stage {
+			t.Fatalf("stages out of order: %+v", job.Stages)
+		}
+	}
+
+	// The same content uploaded under another name is linked, not reprocessed
+	duplicates := upload("install.htm", "<p>Run make.</p>")
+	<-indexer.docs
+	if job := waitJob(duplicates[0].ID); job.Status != IngestStatusCompleted || job.DuplicateOf != jobs[0].DocumentID {
+		t.Fatalf("expected duplicate of %s, got %+v", jobs[0].DocumentID, job)
+	}
+
+	// Extraction failures are reported against the extracted stage
+	jobs = upload("scan.pdf", "%PDF-1.7")
+	if job := waitJob(jobs[0].ID); job.Error == nil || job.Error.Stage != core.IngestExtracted {
+		t.Fatalf("expected extraction failure, got %+v", job)
+	}
+
+	indexer.fail = &core.IngestError{Stage: core.IngestEmbedded, Err: errors.New("embedding service unavailable")}
+	jobs = upload("notes.md", "# Notes")
+	<-indexer.docs
+	if job := waitJob(jobs[0].ID); job.Error == nil || job.Error.Stage != core.IngestEmbedded || job.Stage != core.IngestChunked {
+		t.Fatalf("expected embedding failure, got %+v", job)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/projects/p1/documents/jobs", nil))
+	if strings.Count(rec.Body.String(), `"id":"ingest_`) != 4 {
+		t.Fatalf("unexpected job list %s", rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/projects/p2/documents/jobs/%s", jobs[0].ID), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected other project's job to be hidden, got %d", rec.Code)
+	}
+}
+
+func TestDocumentUploadURL(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("Release notes"))
+	}))
+	defer site.Close()
+
+	h, router := newBotTestHandler(t, nil)
+	indexer := &fakeIndexer{docs: make(chan core.Document, 1)}
+	h.indexer = indexer
+
+	req := httptest.NewRequest(http.MethodPost, "/projects/p1/documents", strings.NewReader(`{"url":"ftp://example.com/x"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected non-http url to be rejected, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/projects/p1/documents", strings.NewReader(`{"url":"`+site.URL+`/notes.txt","title":"Notes"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("url ingest failed: %d %s", rec.Code, rec.Body)
+	}
+	select {
+	case doc := <-indexer.docs:
+		if doc.Content != "Release notes" || doc.Title != "Notes" || doc.URI != site.URL+"/notes.txt" {
+			t.Fatalf("unexpected document %+v", doc)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("url was not ingested")
+	}
+}
@@ -0,0 +1,105 @@
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+// SaveDocumentVersion 保存文档版本快照，版本不可修改
+func (m *Manager) SaveDocumentVersion(ctx context.Context, version *core.DocumentVersion) error {
+	snapshot, err := json.Marshal(version)
+	if err != nil {
+		return fmt.Errorf("failed to encode document version: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_document_versions (document_id, version, content_hash, snapshot, created_at_ns)
+		VALUES (?, ?, ?, ?, ?)`,
+		version.DocumentID, version.Version, version.ContentHash, string(snapshot), version.CreatedAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save document version: %w", err)
+	}
+
+	return nil
+}
+
+// ListDocumentVersions 列出文档的全部版本（不含内容与分块），按版本升序
+func (m *Manager) ListDocumentVersions(ctx context.Context, documentID string) ([]core.DocumentVersion, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT snapshot FROM rag_document_versions
+		WHERE document_id = ?
+		ORDER BY version`,
+		documentID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []core.DocumentVersion{}
+	for rows.Next() {
+		var snapshot string
+		if err := rows.Scan(&snapshot); err != nil {
+			return nil, fmt.Errorf("failed to list document versions: %w", err)
+		}
+		version, err := decodeDocumentVersion(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		version.Content = ""
+		version.Chunks = nil
+		versions = append(versions, *version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list document versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetDocumentVersion 获取文档指定版本，version <= 0 时返回最新版本，不存在时返回 nil
+func (m *Manager) GetDocumentVersion(ctx context.Context, documentID string, version int) (*core.DocumentVersion, error) {
+	query := `SELECT snapshot FROM rag_document_versions WHERE document_id = ? AND version = ?`
+	args := []interface{}{documentID, version}
+	if version <= 0 {
+		query = `SELECT snapshot FROM rag_document_versions WHERE document_id = ? ORDER BY version DESC LIMIT 1`
+		args = args[:1]
+	}
+	return m.queryDocumentVersion(ctx, query, args...)
+}
+
+// GetDocumentVersionAt 获取某一时刻生效的文档版本，文档尚不存在时返回 nil
+func (m *Manager) GetDocumentVersionAt(ctx context.Context, documentID string, at time.Time) (*core.DocumentVersion, error) {
+	return m.queryDocumentVersion(ctx, `
+		SELECT snapshot FROM rag_document_versions
+		WHERE document_id = ? AND created_at_ns <= ?
+		ORDER BY version DESC LIMIT 1`,
+		documentID, at.UnixNano(),
+	)
+}
+
+func (m *Manager) queryDocumentVersion(ctx context.Context, query string, args ...interface{}) (*core.DocumentVersion, error) {
+	var snapshot string
+	err := m.db.QueryRowContext(ctx, query, args...).Scan(&snapshot)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document version: %w", err)
+	}
+	return decodeDocumentVersion(snapshot)
+}
+
+func decodeDocumentVersion(snapshot string) (*core.DocumentVersion, error) {
+	var version core.DocumentVersion
+	if err := json.Unmarshal([]byte(snapshot), &version); err != nil {
+		return nil, fmt.Errorf("failed to decode document version: %w", err)
+	}
+	return &version, nil
+}
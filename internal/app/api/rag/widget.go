@@ -0,0 +1,712 @@
+package rag
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/guileen/metabase/internal/app/api/middleware"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// WidgetTokenPrefix 可公开令牌的前缀，便于与服务端 API 密钥区分
+	WidgetTokenPrefix = "pk_"
+
+	defaultWidgetRateLimit = 30
+	maxWidgetRateLimit     = 600
+	widgetRatePeriod       = time.Minute
+	maxWidgetQueryLength   = 2000
+
+	defaultCaptchaVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// WidgetConfig 嵌入式组件的服务端配置。令牌的限流计数保存在各实例内存中、不在实例间共享，
+// 多实例部署时令牌的实际限额为 rate_limit 乘以实例数
+type WidgetConfig struct {
+	// CAPTCHA 服务端密钥和校验地址，兼容 Turnstile、hCaptcha 的 siteverify 接口
+	CaptchaSecret    string `json:"-"`
+	CaptchaVerifyURL string `json:"captcha_verify_url,omitempty"`
+}
+
+// WidgetConfigFromEnv 从环境变量读取嵌入式组件配置
+func WidgetConfigFromEnv() *WidgetConfig {
+	return &WidgetConfig{
+		CaptchaSecret:    os.Getenv("METABASE_WIDGET_CAPTCHA_SECRET"),
+		CaptchaVerifyURL: os.Getenv("METABASE_WIDGET_CAPTCHA_VERIFY_URL"),
+	}
+}
+
+// WidgetToken 可公开的只读令牌，允许浏览器组件查询指定项目的RAG索引
+type WidgetToken struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"`
+
+	// 允许的来源，如 https://docs.example.com 或 https://*.example.com
+	AllowedOrigins []string `json:"allowed_origins"`
+
+	// 每分钟允许的查询数，默认 30。计数保存在各实例内存中，多实例部署时实际限额为此值乘以实例数
+	RateLimit int `json:"rate_limit,omitempty"`
+
+	// 是否要求每次查询附带 CAPTCHA 校验
+	RequireCaptcha bool `json:"require_captcha"`
+
+	// 过滤表达式，限定组件可检索的文档
+	Filter  string `json:"filter,omitempty"`
+	Enabled bool   `json:"enabled"`
+
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate 校验令牌设置
+func (t *WidgetToken) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(t.AllowedOrigins) == 0 {
+		return fmt.Errorf("at least one allowed origin is required")
+	}
+	for i, origin := range t.AllowedOrigins {
+		normalized, err := normalizeOrigin(origin)
+		if err != nil {
+			return err
+		}
+		t.AllowedOrigins[i] = normalized
+	}
+	if t.RateLimit < 0 || t.RateLimit > maxWidgetRateLimit {
+		return fmt.Errorf("rate_limit must be between 0 and %d", maxWidgetRateLimit)
+	}
+	if t.Filter != "" {
+		if _, err := core.ParseFilterExpression(t.Filter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	return nil
+}
+
+// AllowsOrigin 判断请求来源是否在允许列表中，*. 通配任意子域名
+func (t *WidgetToken) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range t.AllowedOrigins {
+		if allowed == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeOrigin 校验并规范化来源，只允许协议和主机（可带端口）
+func normalizeOrigin(origin string) (string, error) {
+	origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.Path != "" || u.RawQuery != "" || u.User != nil {
+		return "", fmt.Errorf("invalid origin %q (expected scheme://host)", origin)
+	}
+	return origin, nil
+}
+
+// widgetTokenRequest 令牌创建和更新请求，未提供的字段保持不变
+type widgetTokenRequest struct {
+	Name           *string   `json:"name"`
+	AllowedOrigins *[]string `json:"allowed_origins"`
+	RateLimit      *int      `json:"rate_limit"`
+	RequireCaptcha *bool     `json:"require_captcha"`
+	Filter         *string   `json:"filter"`
+	Enabled        *bool     `json:"enabled"`
+}
+
+func (req *widgetTokenRequest) apply(t *WidgetToken) {
+	if req.Name != nil {
+		t.Name = *req.Name
+	}
+	if req.AllowedOrigins != nil {
+		t.AllowedOrigins = append([]string{}, *req.AllowedOrigins...)
+	}
+	if req.RateLimit != nil {
+		t.RateLimit = *req.RateLimit
+	}
+	if req.RequireCaptcha != nil {
+		t.RequireCaptcha = *req.RequireCaptcha
+	}
+	if req.Filter != nil {
+		t.Filter = *req.Filter
+	}
+	if req.Enabled != nil {
+		t.Enabled = *req.Enabled
+	}
+}
+
+// generateWidgetToken 生成令牌值，返回令牌和用于展示的前缀
+func generateWidgetToken() (string, string, error) {
+	randomBytes := make([]byte, 24)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", "", err
+	}
+	token := WidgetTokenPrefix + hex.EncodeToString(randomBytes)
+	return token, token[:len(WidgetTokenPrefix)+8] + "...", nil
+}
+
+// hashWidgetToken 令牌的 SHA-256 摘要，令牌本身不落库
+func hashWidgetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateWidgetToken 创建令牌并返回令牌值，令牌值只在创建时返回一次
+func (m *Manager) CreateWidgetToken(ctx context.Context, t *WidgetToken) (string, error) {
+	token, prefix, err := generateWidgetToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate widget token: %w", err)
+	}
+
+	now := time.Now()
+	t.ID = fmt.Sprintf("wt_%d", now.UnixNano())
+	t.Prefix = prefix
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	definition, err := json.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode widget token: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO rag_widget_tokens (id, project_id, token_hash, definition, updated_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		t.ID, t.ProjectID, hashWidgetToken(token), string(definition), t.UpdatedAt,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to save widget token: %w", err)
+	}
+	return token, nil
+}
+
+// UpdateWidgetToken 更新令牌设置
+func (m *Manager) UpdateWidgetToken(ctx context.Context, t *WidgetToken) error {
+	t.UpdatedAt = time.Now()
+	definition, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode widget token: %w", err)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		UPDATE rag_widget_tokens SET definition = ?, updated_at = ?
+		WHERE id = ? AND project_id = ?`,
+		string(definition), t.UpdatedAt, t.ID, t.ProjectID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save widget token: %w", err)
+	}
+	return nil
+}
+
+// GetWidgetToken 获取项目的令牌，不存在时返回 nil
+func (m *Manager) GetWidgetToken(ctx context.Context, projectID, tokenID string) (*WidgetToken, error) {
+	return m.queryWidgetToken(ctx,
+		`SELECT definition FROM rag_widget_tokens WHERE id = ? AND project_id = ?`, tokenID, projectID)
+}
+
+// LookupWidgetToken 按令牌值查找令牌，不存在时返回 nil
+func (m *Manager) LookupWidgetToken(ctx context.Context, token string) (*WidgetToken, error) {
+	if !strings.HasPrefix(token, WidgetTokenPrefix) {
+		return nil, nil
+	}
+	return m.queryWidgetToken(ctx,
+		`SELECT definition FROM rag_widget_tokens WHERE token_hash = ?`, hashWidgetToken(token))
+}
+
+func (m *Manager) queryWidgetToken(ctx context.Context, query string, args ...interface{}) (*WidgetToken, error) {
+	var definition string
+	err := m.db.QueryRowContext(ctx, query, args...).Scan(&definition)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get widget token: %w", err)
+	}
+
+	var token WidgetToken
+	if err := json.Unmarshal([]byte(definition), &token); err != nil {
+		return nil, fmt.Errorf("failed to decode widget token: %w", err)
+	}
+	return &token, nil
+}
+
+// ListWidgetTokens 列出项目的令牌
+func (m *Manager) ListWidgetTokens(ctx context.Context, projectID string) ([]WidgetToken, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT definition FROM rag_widget_tokens WHERE project_id = ? ORDER BY id`,
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list widget tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []WidgetToken{}
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to list widget tokens: %w", err)
+		}
+		var token WidgetToken
+		if err := json.Unmarshal([]byte(definition), &token); err != nil {
+			return nil, fmt.Errorf("failed to decode widget token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list widget tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// DeleteWidgetToken 吊销项目的令牌，返回是否存在该令牌
+func (m *Manager) DeleteWidgetToken(ctx context.Context, projectID, tokenID string) (bool, error) {
+	result, err := m.db.ExecContext(ctx,
+		`DELETE FROM rag_widget_tokens WHERE id = ? AND project_id = ?`,
+		tokenID, projectID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete widget token: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return affected > 0, nil
+}
+
+// widgetRateLimiter 按令牌计数的固定窗口限流器，计数保存在本实例内，不在实例间共享
+type widgetRateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*widgetRateWindow
+}
+
+type widgetRateWindow struct {
+	start time.Time
+	count int
+}
+
+func newWidgetRateLimiter() *widgetRateLimiter {
+	return &widgetRateLimiter{windows: make(map[string]*widgetRateWindow)}
+}
+
+// allow 记录一次请求，超出限额时返回 false 和距窗口重置的时间
+func (l *widgetRateLimiter) allow(tokenID string, limit int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window, ok := l.windows[tokenID]
+	if !ok || now.Sub(window.start) >= widgetRatePeriod {
+		if len(l.windows) > 10000 {
+			for id, w := range l.windows {
+				if now.Sub(w.start) >= widgetRatePeriod {
+					delete(l.windows, id)
+				}
+			}
+		}
+		window = &widgetRateWindow{start: now}
+		l.windows[tokenID] = window
+	}
+	if window.count >= limit {
+		return false, window.start.Add(widgetRatePeriod).Sub(now)
+	}
+	window.count++
+	return true, 0
+}
+
+// verifyCaptcha 调用 siteverify 接口校验 CAPTCHA 响应
+func (h *Handler) verifyCaptcha(ctx context.Context, response, remoteIP string) error {
+	if response == "" {
+		return fmt.Errorf("captcha_token is required")
+	}
+	verifyURL := h.widget.CaptchaVerifyURL
+	if verifyURL == "" {
+		verifyURL = defaultCaptchaVerifyURL
+	}
+
+	form := url.Values{"secret": {h.widget.CaptchaSecret}, "response": {response}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := botHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha verification failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid captcha response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("captcha rejected: %s", strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
+
+// handleListWidgetTokens 列出项目的嵌入式组件令牌
+func (h *Handler) handleListWidgetTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.manager.ListWidgetTokens(r.Context(), chi.URLParam(r, "projectId"))
+	if err != nil {
+		h.logger.Error("failed to list widget tokens", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to list widget tokens",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": tokens,
+	})
+}
+
+// handleCreateWidgetToken 创建嵌入式组件令牌，令牌值只在响应中返回一次
+func (h *Handler) handleCreateWidgetToken(w http.ResponseWriter, r *http.Request) {
+	var req widgetTokenRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	projectCtx := middleware.GetProjectContext(r)
+	token := &WidgetToken{
+		ProjectID: chi.URLParam(r, "projectId"),
+		TenantID:  projectCtx.TenantID,
+		CreatedBy: projectCtx.UserID,
+		Enabled:   true,
+	}
+	req.apply(token)
+	if !h.validWidgetToken(w, r, token) {
+		return
+	}
+
+	value, err := h.manager.CreateWidgetToken(r.Context(), token)
+	if err != nil {
+		h.logger.Error("failed to create widget token", zap.String("project_id", token.ProjectID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to create widget token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("widget token created",
+		zap.String("project_id", token.ProjectID),
+		zap.String("token_id", token.ID),
+	)
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{
+		"data": struct {
+			*WidgetToken
+			Token string `json:"token"`
+		}{token, value},
+	})
+}
+
+// handleUpdateWidgetToken 更新嵌入式组件令牌设置
+func (h *Handler) handleUpdateWidgetToken(w http.ResponseWriter, r *http.Request) {
+	var req widgetTokenRequest
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	token, err := h.manager.GetWidgetToken(r.Context(), chi.URLParam(r, "projectId"), chi.URLParam(r, "tokenId"))
+	if err != nil {
+		h.logger.Error("failed to get widget token", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to get widget token",
+			"details": err.Error(),
+		})
+		return
+	}
+	if token == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Widget token not found",
+		})
+		return
+	}
+	req.apply(token)
+	if !h.validWidgetToken(w, r, token) {
+		return
+	}
+
+	if err := h.manager.UpdateWidgetToken(r.Context(), token); err != nil {
+		h.logger.Error("failed to update widget token", zap.String("token_id", token.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to update widget token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": token,
+	})
+}
+
+// validWidgetToken 校验令牌设置，要求 CAPTCHA 时服务端必须配置密钥
+func (h *Handler) validWidgetToken(w http.ResponseWriter, r *http.Request, token *WidgetToken) bool {
+	err := token.Validate()
+	if err == nil && token.RequireCaptcha && (h.widget == nil || h.widget.CaptchaSecret == "") {
+		err = fmt.Errorf("require_captcha needs a captcha secret configured on the server")
+	}
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid widget token",
+			"details": err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// handleDeleteWidgetToken 吊销嵌入式组件令牌
+func (h *Handler) handleDeleteWidgetToken(w http.ResponseWriter, r *http.Request) {
+	deleted, err := h.manager.DeleteWidgetToken(r.Context(), chi.URLParam(r, "projectId"), chi.URLParam(r, "tokenId"))
+	if err != nil {
+		h.logger.Error("failed to delete widget token", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Failed to delete widget token",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Widget token not found",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"message": "Widget token revoked",
+	})
+}
+
+// widgetQueryRequest 嵌入式组件的查询请求
+type widgetQueryRequest struct {
+	Query        string `json:"query"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+}
+
+// widgetQueryResponse 嵌入式组件的查询结果，只包含回答和引用
+type widgetQueryResponse struct {
+	QueryID string        `json:"query_id"`
+	Answer  string        `json:"answer"`
+	Sources []core.Source `json:"sources"`
+}
+
+// widgetTokenValue 返回请求携带的令牌值，Authorization 头优先于 token 查询参数
+func widgetTokenValue(r *http.Request) string {
+	if bearer := r.Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		return strings.TrimPrefix(bearer, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// handleWidgetPreflight 响应查询接口的 CORS 预检。预检请求不带 Authorization 头，
+// 令牌通过 token 查询参数传入时只放行该令牌允许的来源；否则放行请求来源，
+// 由查询请求本身按令牌校验来源
+func (h *Handler) handleWidgetPreflight(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if value := r.URL.Query().Get("token"); value != "" {
+		token, err := h.manager.LookupWidgetToken(r.Context(), value)
+		if err != nil {
+			h.logger.Error("failed to look up widget token", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if token == nil || !token.Enabled || !token.AllowsOrigin(origin) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+	w.Header().Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWidgetQuery 使用可公开令牌执行只读查询：校验来源、限流并按需校验 CAPTCHA。
+// 只回显令牌允许的来源，服务端的 CORS 设置不作用于此接口
+func (h *Handler) handleWidgetQuery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Origin")
+
+	token, err := h.manager.LookupWidgetToken(r.Context(), widgetTokenValue(r))
+	if err != nil {
+		h.logger.Error("failed to look up widget token", zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Failed to verify widget token",
+		})
+		return
+	}
+	if token == nil || !token.Enabled {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Invalid widget token",
+			"code":  "invalid_token",
+		})
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	if !token.AllowsOrigin(origin) {
+		render.Status(r, http.StatusForbidden)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Origin not allowed for this widget token",
+			"code":  "origin_not_allowed",
+		})
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+
+	limit := token.RateLimit
+	if limit == 0 {
+		limit = defaultWidgetRateLimit
+	}
+	if ok, retryAfter := h.widgetLimiter.allow(token.ID, limit, time.Now()); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		render.Status(r, http.StatusTooManyRequests)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Rate limit exceeded",
+			"code":  "rate_limited",
+		})
+		return
+	}
+
+	if h.pipeline == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "RAG pipeline not configured",
+		})
+		return
+	}
+
+	var req widgetQueryRequest
+	if err := render.DecodeJSON(http.MaxBytesReader(w, r.Body, 64<<10), &req); err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   "Invalid JSON data",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" || len([]rune(req.Query)) > maxWidgetQueryLength {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": fmt.Sprintf("Query is required and must be at most %d characters", maxWidgetQueryLength),
+		})
+		return
+	}
+
+	if token.RequireCaptcha {
+		if h.widget == nil || h.widget.CaptchaSecret == "" {
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, map[string]interface{}{
+				"error": "CAPTCHA verification not configured",
+			})
+			return
+		}
+		if err := h.verifyCaptcha(r.Context(), req.CaptchaToken, middleware.ClientIP(r)); err != nil {
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, map[string]interface{}{
+				"error":   "CAPTCHA verification failed",
+				"code":    "captcha_failed",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	result, err := h.query(r.Context(), req.Query, core.QueryOptions{
+		ProjectID: token.ProjectID,
+		TenantID:  token.TenantID,
+		Filter:    token.Filter,
+	})
+	if errors.Is(err, core.ErrBudgetExceeded) {
+		render.Status(r, http.StatusPaymentRequired)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "LLM budget exceeded",
+			"code":  "budget_exceeded",
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Error("widget query failed", zap.String("project_id", token.ProjectID), zap.String("token_id", token.ID), zap.Error(err))
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Query failed",
+		})
+		return
+	}
+
+	render.JSON(w, r, map[string]interface{}{
+		"data": widgetQueryResponse{
+			QueryID: result.QueryID,
+			Answer:  result.GeneratedResponse,
+			Sources: result.Sources,
+		},
+	})
+}
@@ -0,0 +1,143 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWidgetTokens(t *testing.T) {
+	h, router := newBotTestHandler(t, nil)
+	router.Route("/public/v1/rag", h.RegisterWidgetRoutes)
+
+	do := func(method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/projects/p1/rag/widget-tokens", `{"name":"docs","allowed_origins":["docs.example.com"]}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected origin without scheme to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/projects/p1/rag/widget-tokens", `{"name":"docs","allowed_origins":["https://docs.example.com"],"require_captcha":true}`, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected captcha without server secret to be rejected, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodPost, "/projects/p1/rag/widget-tokens", `{"name":"docs","allowed_origins":["https://docs.example.com/","https://*.example.org"],"rate_limit":1}`, nil)
+	var created struct {
+		Data struct {
+			WidgetToken
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if rec.Code != http.StatusCreated || !strings.HasPrefix(created.Data.Token, WidgetTokenPrefix) {
+		t.Fatalf("create failed: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = do(http.MethodGet, "/projects/p1/rag/widget-tokens", "", nil)
+	if strings.Contains(rec.Body.String(), created.Data.Token) || !strings.Contains(rec.Body.String(), "https://docs.example.com\"") {
+		t.Fatalf("unexpected token listing %s", rec.Body)
+	}
+
+	// Preflights carrying the token only succeed for its allowed origins
+	preflight := func(path, origin string) *httptest.ResponseRecorder {
+		return do(http.MethodOptions, path, "", map[string]string{"Origin": origin, "Access-Control-Request-Method": "POST"})
+	}
+	if rec := preflight("/public/v1/rag/query?token="+created.Data.Token, "https://evil.example.com"); rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("expected foreign origin preflight to be rejected, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := preflight("/public/v1/rag/query?token=pk_unknown", "https://docs.example.com"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected unknown token preflight to be rejected, got %d", rec.Code)
+	}
+	rec = preflight("/public/v1/rag/query?token="+created.Data.Token, "https://docs.example.com")
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://docs.example.com" ||
+		!strings.Contains(rec.Header().Get("Access-Control-Allow-Headers"), "Authorization") {
+		t.Fatalf("unexpected preflight response %d %v", rec.Code, rec.Header())
+	}
+
+	query := `{"query":"how do I install?"}`
+	bearer := "Bearer " + created.Data.Token
+	if rec := do(http.MethodPost, "/public/v1/rag/query", query, map[string]string{"Origin": "https://docs.example.com"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected missing token to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/public/v1/rag/query", query, map[string]string{"Authorization": bearer, "Origin": "https://evil.example.com"}); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected foreign origin to be rejected, got %d", rec.Code)
+	}
+
+	// Allowed origins are echoed back; without a pipeline the query itself is unavailable
+	rec = do(http.MethodPost, "/public/v1/rag/query", query, map[string]string{"Authorization": bearer, "Origin": "https://app.example.org"})
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.org" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	rec = do(http.MethodPost, "/public/v1/rag/query", query, map[string]string{"Authorization": bearer, "Origin": "https://docs.example.com"})
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected rate limit, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodPut, "/projects/p1/rag/widget-tokens/"+created.Data.ID, `{"enabled":false}`, nil); rec.Code != http.StatusOK {
+		t.Fatalf("update failed: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/public/v1/rag/query", query, map[string]string{"Authorization": bearer, "Origin": "https://docs.example.com"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected disabled token to be rejected, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/projects/p2/rag/widget-tokens/"+created.Data.ID, "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected other project's revoke to fail, got %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/projects/p1/rag/widget-tokens/"+created.Data.ID, "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("revoke failed: %d", rec.Code)
+	}
+}
+
+func TestWidgetRateLimiter(t *testing.T) {
+	limiter := newWidgetRateLimiter()
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("wt", 2, now); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	if ok, retry := limiter.allow("wt", 2, now.Add(10*time.Second)); ok || retry != 50*time.Second {
+		t.Fatalf("expected limit with 50s retry, got %v %v", ok, retry)
+	}
+	if ok, _ := limiter.allow("other", 2, now); !ok {
+		t.Fatal("limits should be per token")
+	}
+	if ok, _ := limiter.allow("wt", 2, now.Add(time.Minute)); !ok {
+		t.Fatal("window should reset")
+	}
+}
+
+func TestVerifyCaptcha(t *testing.T) {
+	siteverify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("secret") == "secret" && r.Form.Get("response") == "ok" && r.Form.Get("remoteip") == "10.0.0.1" {
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	}))
+	defer siteverify.Close()
+
+	h, _ := newBotTestHandler(t, nil)
+	h.SetWidgetConfig(&WidgetConfig{CaptchaSecret: "secret", CaptchaVerifyURL: siteverify.URL})
+	if err := h.verifyCaptcha(context.Background(), "ok", "10.0.0.1"); err != nil {
+		t.Fatalf("expected captcha to pass: %v", err)
+	}
+	if err := h.verifyCaptcha(context.Background(), "bad", "10.0.0.1"); err == nil || !strings.Contains(err.Error(), "invalid-input-response") {
+		t.Fatalf("expected captcha to be rejected, got %v", err)
+	}
+	if err := h.verifyCaptcha(context.Background(), "", ""); err == nil {
+		t.Fatal("expected missing captcha token to be rejected")
+	}
+}
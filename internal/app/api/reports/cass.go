@@ -0,0 +1,69 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// cassClient 读取 CASS 分析服务的质量指标和安全报告
+type cassClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newCASSClient(baseURL string) *cassClient {
+	return &cassClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// quality 调用 /api/v1/quality/metrics
+func (c *cassClient) quality(ctx context.Context, tenantID string) (*QualitySummary, error) {
+	var result struct {
+		OverallScore float64            `json:"overall_score"`
+		Metrics      map[string]float64 `json:"metrics"`
+	}
+	if err := c.get(ctx, "/api/v1/quality/metrics", tenantID, &result); err != nil {
+		return nil, err
+	}
+	return &QualitySummary{OverallScore: result.OverallScore, Metrics: result.Metrics}, nil
+}
+
+// security 调用 /api/v1/security/report
+func (c *cassClient) security(ctx context.Context, tenantID string) (*SecuritySummary, error) {
+	var result struct {
+		Summary SecuritySummary `json:"summary"`
+	}
+	if err := c.get(ctx, "/api/v1/security/report", tenantID, &result); err != nil {
+		return nil, err
+	}
+	return &result.Summary, nil
+}
+
+func (c *cassClient) get(ctx context.Context, path, tenantID string, out interface{}) error {
+	endpoint := c.baseURL + path + "?" + url.Values{"tenant_id": {tenantID}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("CASS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("CASS %s failed (%d): %s", path, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid CASS response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package reports
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// Handler 租户报告HTTP处理器
+type Handler struct {
+	manager   *Manager
+	scheduler *Scheduler
+	logger    *zap.Logger
+}
+
+// NewHandler 创建报告处理器
+func NewHandler(manager *Manager, scheduler *Scheduler, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager:   manager,
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// RegisterRoutes 注册 /tenants/{tenantId}/reports 下的路由
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.handleList)
+	r.Post("/", h.handleGenerate)
+	r.Get("/{id}", h.handleGet)
+}
+
+// GenerateRequest 立即生成报告请求
+type GenerateRequest struct {
+	Deliver bool `json:"deliver"` // 是否发送到租户的通知目标
+}
+
+// handleGenerate 立即生成最近一个周期的报告
+func (h *Handler) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var req GenerateRequest
+	if r.ContentLength != 0 {
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			h.error(w, r, http.StatusBadRequest, "Invalid JSON data", err, "invalid_request")
+			return
+		}
+	}
+
+	tenantID := chi.URLParam(r, "tenantId")
+	userID, _ := r.Context().Value("user_id").(string)
+	report, err := h.scheduler.Generate(r.Context(), tenantID, userID, req.Deliver)
+	if errors.Is(err, ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Tenant not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to generate report", zap.String("tenant_id", tenantID), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to generate report", err, "")
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]interface{}{"data": report})
+}
+
+// handleList 列出租户的报告
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	reports, err := h.manager.ListReports(r.Context(), chi.URLParam(r, "tenantId"), limit)
+	if err != nil {
+		h.logger.Error("failed to list reports", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to list reports", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": reports})
+}
+
+// handleGet 获取报告；format=markdown 或 html 时直接返回渲染后的正文
+func (h *Handler) handleGet(w http.ResponseWriter, r *http.Request) {
+	report, err := h.manager.GetReport(r.Context(), chi.URLParam(r, "tenantId"), chi.URLParam(r, "id"))
+	if errors.Is(err, ErrNotFound) {
+		h.error(w, r, http.StatusNotFound, "Report not found", err, "not_found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to get report", zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to get report", err, "")
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case FormatMarkdown:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(report.Markdown))
+	case FormatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(report.HTML))
+	default:
+		render.JSON(w, r, map[string]interface{}{"data": report})
+	}
+}
+
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
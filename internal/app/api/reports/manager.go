@@ -0,0 +1,273 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotFound 报告不存在
+var ErrNotFound = errors.New("report not found")
+
+// errExists 计划报告已由其他实例或之前的运行生成
+var errExists = errors.New("report already exists")
+
+// dayFormat 用量按 UTC 日期存储
+const dayFormat = "2006-01-02"
+
+// Manager 报告与每日用量的存储
+type Manager struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewManager 创建报告存储
+func NewManager(db *sql.DB, logger *zap.Logger) *Manager {
+	return &Manager{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Initialize 初始化数据库表
+func (m *Manager) Initialize(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS report_usage (
+		tenant_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		errors INTEGER NOT NULL DEFAULT 0,
+		queries INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (tenant_id, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS report_questions (
+		tenant_id TEXT NOT NULL,
+		day TEXT NOT NULL,
+		question TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (tenant_id, day, question)
+	);
+
+	CREATE TABLE IF NOT EXISTS reports (
+		id TEXT PRIMARY KEY,
+		tenant_id TEXT NOT NULL,
+		trigger_type TEXT NOT NULL,
+		period_end TIMESTAMP NOT NULL,
+		report TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_reports_tenant ON reports(tenant_id, created_at);
+	`
+
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		m.logger.Error("failed to initialize report tables", zap.Error(err))
+		return fmt.Errorf("failed to initialize report tables: %w", err)
+	}
+	return nil
+}
+
+// addUsage 累加租户某日的请求、错误和查询数
+func (m *Manager) addUsage(ctx context.Context, tenantID string, day time.Time, c *usageCounts) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO report_usage (tenant_id, day, requests, errors, queries) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(tenant_id, day) DO UPDATE SET
+			requests = requests + excluded.requests,
+			errors = errors + excluded.errors,
+			queries = queries + excluded.queries`,
+		tenantID, day.Format(dayFormat), c.requests, c.errors, c.queries,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record usage: %w", err)
+	}
+	return nil
+}
+
+// addQuestion 累加租户某日某个问题的次数
+func (m *Manager) addQuestion(ctx context.Context, tenantID string, day time.Time, question string, count int64) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO report_questions (tenant_id, day, question, count) VALUES (?, ?, ?, ?)
+		ON CONFLICT(tenant_id, day, question) DO UPDATE SET count = count + excluded.count`,
+		tenantID, day.Format(dayFormat), question, count,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record question: %w", err)
+	}
+	return nil
+}
+
+// usageBetween 统计 [from, to) 内各日的用量，按日期计
+func (m *Manager) usageBetween(ctx context.Context, tenantID string, from, to time.Time) (requests, errs, queries int64, err error) {
+	err = m.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(requests), 0), COALESCE(SUM(errors), 0), COALESCE(SUM(queries), 0)
+		FROM report_usage WHERE tenant_id = ? AND day >= ? AND day < ?`,
+		tenantID, from.UTC().Format(dayFormat), to.UTC().Format(dayFormat),
+	).Scan(&requests, &errs, &queries)
+	if err != nil {
+		err = fmt.Errorf("failed to sum usage: %w", err)
+	}
+	return requests, errs, queries, err
+}
+
+// topQuestions 返回 [from, to) 内次数最多的问题
+func (m *Manager) topQuestions(ctx context.Context, tenantID string, from, to time.Time, limit int) ([]QuestionCount, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT question, SUM(count) AS total FROM report_questions
+		WHERE tenant_id = ? AND day >= ? AND day < ?
+		GROUP BY question ORDER BY total DESC, question LIMIT ?`,
+		tenantID, from.UTC().Format(dayFormat), to.UTC().Format(dayFormat), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list top questions: %w", err)
+	}
+	defer rows.Close()
+
+	questions := []QuestionCount{}
+	for rows.Next() {
+		var q QuestionCount
+		if err := rows.Scan(&q.Question, &q.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan question: %w", err)
+		}
+		questions = append(questions, q)
+	}
+	return questions, rows.Err()
+}
+
+// tenantInfo 活跃租户的ID和名称
+type tenantInfo struct {
+	ID   string
+	Name string
+}
+
+// activeTenants 列出未删除的活跃租户
+func (m *Manager) activeTenants(ctx context.Context) ([]tenantInfo, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT id, name FROM tenants WHERE is_active = 1 AND deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []tenantInfo
+	for rows.Next() {
+		var t tenantInfo
+		if err := rows.Scan(&t.ID, &t.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// tenantName 返回租户名称，租户不存在时返回 ErrNotFound
+func (m *Manager) tenantName(ctx context.Context, tenantID string) (string, error) {
+	var name string
+	err := m.db.QueryRowContext(ctx, `SELECT name FROM tenants WHERE id = ? AND deleted_at IS NULL`, tenantID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return name, nil
+}
+
+// createReport 保存新报告，ID 已存在时返回 errExists
+func (m *Manager) createReport(ctx context.Context, report *Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	result, err := m.db.ExecContext(ctx, `
+		INSERT INTO reports (id, tenant_id, trigger_type, period_end, report, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO NOTHING`,
+		report.ID, report.TenantID, report.Trigger, report.PeriodEnd, string(data), report.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save report: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errExists
+	}
+	return nil
+}
+
+// updateReport 更新报告（发送结果）
+func (m *Manager) updateReport(ctx context.Context, report *Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if _, err := m.db.ExecContext(ctx, `UPDATE reports SET report = ? WHERE id = ?`, string(data), report.ID); err != nil {
+		return fmt.Errorf("failed to update report: %w", err)
+	}
+	return nil
+}
+
+// GetReport 获取租户的报告
+func (m *Manager) GetReport(ctx context.Context, tenantID, id string) (*Report, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx, `SELECT report FROM reports WHERE id = ? AND tenant_id = ?`, id, tenantID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+	return &report, nil
+}
+
+// ListReports 按时间倒序列出租户的报告，不含渲染后的正文
+func (m *Manager) ListReports(ctx context.Context, tenantID string, limit int) ([]Report, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT report FROM reports WHERE tenant_id = ? ORDER BY created_at DESC, id DESC LIMIT ?`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan report: %w", err)
+		}
+		var report Report
+		if err := json.Unmarshal([]byte(data), &report); err != nil {
+			return nil, fmt.Errorf("failed to decode report: %w", err)
+		}
+		report.Markdown, report.HTML = "", ""
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// previousReport 返回租户在 before 之前最近一份报告，没有时返回 nil
+func (m *Manager) previousReport(ctx context.Context, tenantID string, before time.Time) (*Report, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx,
+		`SELECT report FROM reports WHERE tenant_id = ? AND created_at < ? ORDER BY created_at DESC LIMIT 1`,
+		tenantID, before,
+	).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous report: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+	return &report, nil
+}
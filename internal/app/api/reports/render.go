@@ -0,0 +1,147 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var templateFuncs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02") },
+	// lastDay 区间结束是次日零点，显示为最后一天
+	"lastDay": func(t time.Time) string { return t.UTC().AddDate(0, 0, -1).Format("2006-01-02") },
+	"cell":    func(s string) string { return strings.ReplaceAll(s, "|", "\\|") },
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.2f%%", v*100)
+	},
+	"trend": func(current, previous int64) string {
+		if previous == 0 {
+			return "—"
+		}
+		return fmt.Sprintf("%+.0f%%", (float64(current)-float64(previous))/float64(previous)*100)
+	},
+	"score": func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"scoreChange": func(v *float64) string {
+		if v == nil {
+			return "—"
+		}
+		return fmt.Sprintf("%+.1f", *v)
+	},
+	"countChange": func(v *int) string {
+		if v == nil {
+			return "—"
+		}
+		return fmt.Sprintf("%+d", *v)
+	},
+	"sortedMetrics": sortedMetrics,
+	"inc":           func(i int) int { return i + 1 },
+}
+
+type metric struct {
+	Name  string
+	Value float64
+}
+
+func sortedMetrics(metrics map[string]float64) []metric {
+	list := make([]metric, 0, len(metrics))
+	for name, value := range metrics {
+		list = append(list, metric{Name: name, Value: value})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+const markdownTemplate = `# {{.TenantName}} 周报
+
+统计区间：{{date .PeriodStart}} 至 {{lastDay .PeriodEnd}}（UTC）
+
+## API 调用
+
+| 指标 | 本期 | 环比 |
+|------|------|------|
+| 请求数 | {{.API.Requests}} | {{trend .API.Requests .API.PreviousRequests}} |
+| 5xx 错误 | {{.API.Errors}} | |
+| 错误率 | {{percent .API.ErrorRate}} | |
+
+## RAG 查询
+
+本期查询 {{.RAG.Queries}} 次，环比 {{trend .RAG.Queries .RAG.PreviousQueries}}。
+{{if .RAG.TopQuestions}}
+### 热门问题
+
+| # | 问题 | 次数 |
+|---|------|------|
+{{range $i, $q := .RAG.TopQuestions}}| {{inc $i}} | {{cell $q.Question}} | {{$q.Count}} |
+{{end}}{{end}}
+{{- if .Quality}}
+## 代码质量（CASS）
+
+总分 {{score .Quality.OverallScore}}，较上期 {{scoreChange .Quality.Change}}。
+{{if .Quality.Metrics}}
+| 指标 | 值 |
+|------|----|
+{{range sortedMetrics .Quality.Metrics}}| {{.Name}} | {{score .Value}} |
+{{end}}{{end}}{{end}}
+{{- if .Security}}
+## 安全（CASS）
+
+未解决漏洞 {{.Security.Total}} 个（严重 {{.Security.Critical}}、高 {{.Security.High}}、中 {{.Security.Medium}}、低 {{.Security.Low}}），较上期 {{countChange .Security.Change}}。
+{{end}}
+{{- if .Warnings}}
+---
+{{range .Warnings}}
+> {{.}}
+{{end}}{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.TenantName}} 周报</title>
+<style>body{font-family:sans-serif;color:#222;max-width:720px}table{border-collapse:collapse}td,th{border:1px solid #ddd;padding:4px 8px;text-align:left}.note{color:#888}</style>
+</head><body>
+<h1>{{.TenantName}} 周报</h1>
+<p>统计区间：{{date .PeriodStart}} 至 {{lastDay .PeriodEnd}}（UTC）</p>
+<h2>API 调用</h2>
+<table>
+<tr><th>指标</th><th>本期</th><th>环比</th></tr>
+<tr><td>请求数</td><td>{{.API.Requests}}</td><td>{{trend .API.Requests .API.PreviousRequests}}</td></tr>
+<tr><td>5xx 错误</td><td>{{.API.Errors}}</td><td></td></tr>
+<tr><td>错误率</td><td>{{percent .API.ErrorRate}}</td><td></td></tr>
+</table>
+<h2>RAG 查询</h2>
+<p>本期查询 {{.RAG.Queries}} 次，环比 {{trend .RAG.Queries .RAG.PreviousQueries}}。</p>
+{{if .RAG.TopQuestions}}<h3>热门问题</h3>
+<table>
+<tr><th>#</th><th>问题</th><th>次数</th></tr>
+{{range $i, $q := .RAG.TopQuestions}}<tr><td>{{inc $i}}</td><td>{{$q.Question}}</td><td>{{$q.Count}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Quality}}<h2>代码质量（CASS）</h2>
+<p>总分 {{score .Quality.OverallScore}}，较上期 {{scoreChange .Quality.Change}}。</p>
+{{if .Quality.Metrics}}<table>
+<tr><th>指标</th><th>值</th></tr>
+{{range sortedMetrics .Quality.Metrics}}<tr><td>{{.Name}}</td><td>{{score .Value}}</td></tr>
+{{end}}</table>{{end}}{{end}}
+{{if .Security}}<h2>安全（CASS）</h2>
+<p>未解决漏洞 {{.Security.Total}} 个（严重 {{.Security.Critical}}、高 {{.Security.High}}、中 {{.Security.Medium}}、低 {{.Security.Low}}），较上期 {{countChange .Security.Change}}。</p>{{end}}
+{{range .Warnings}}<p class="note">{{.}}</p>
+{{end}}</body></html>
+`
+
+var (
+	markdownReport = template.Must(template.New("markdown").Funcs(templateFuncs).Parse(markdownTemplate))
+	htmlReport     = htmltemplate.Must(htmltemplate.New("html").Funcs(templateFuncs).Parse(htmlTemplate))
+)
+
+// renderDigest 将报告内容渲染为 Markdown 和 HTML
+func renderDigest(digest *Digest) (string, string, error) {
+	var md, page bytes.Buffer
+	if err := markdownReport.Execute(&md, digest); err != nil {
+		return "", "", fmt.Errorf("failed to render markdown report: %w", err)
+	}
+	if err := htmlReport.Execute(&page, digest); err != nil {
+		return "", "", fmt.Errorf("failed to render html report: %w", err)
+	}
+	return md.String(), page.String(), nil
+}
@@ -0,0 +1,159 @@
+package reports
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/biz/domain/tenant"
+)
+
+func newTestScheduler(t *testing.T, config *Config, webhook string) (*Scheduler, *time.Time) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "reports.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(`CREATE TABLE tenants (id TEXT PRIMARY KEY, name TEXT, is_active BOOLEAN DEFAULT 1, deleted_at TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO tenants (id, name) VALUES ('acme', 'Acme'), ('globex', 'Globex')`); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(db, zap.NewNop())
+	if err := manager.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wednesday
+	now := time.Date(2026, 3, 4, 9, 30, 0, 0, time.UTC)
+	usage := NewUsageRecorder(manager)
+	usage.now = func() time.Time { return now }
+	targets := func(ctx context.Context, tenantID string) (*tenant.NotificationSettings, error) {
+		if tenantID == "acme" {
+			return &tenant.NotificationSettings{Webhook: webhook}, nil
+		}
+		return nil, nil
+	}
+	scheduler := NewScheduler(manager, usage, targets, config, zap.NewNop())
+	scheduler.now = func() time.Time { return now }
+	return scheduler, &now
+}
+
+func TestGenerateReport(t *testing.T) {
+	ctx := context.Background()
+	var received map[string]interface{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer webhook.Close()
+
+	cassCalls := 0
+	cass := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cassCalls++
+		switch r.URL.Path {
+		case "/api/v1/quality/metrics":
+			json.NewEncoder(w).Encode(map[string]interface{}{"overall_score": 80 + float64(cassCalls), "metrics": map[string]float64{"complexity": 12}})
+		case "/api/v1/security/report":
+			http.Error(w, "scanner offline", http.StatusServiceUnavailable)
+		}
+	}))
+	defer cass.Close()
+
+	config := DefaultConfig()
+	config.CASSURL = cass.URL
+	scheduler, now := newTestScheduler(t, config, webhook.URL)
+
+	// Last week's traffic, then this week's
+	*now = now.AddDate(0, 0, -7)
+	scheduler.usage.RecordRequest("acme", 200)
+	*now = now.AddDate(0, 0, 7)
+	for i := 0; i < 3; i++ {
+		scheduler.usage.RecordRequest("acme", 200)
+		scheduler.usage.RecordQuery("acme", "How do I  reset my password?")
+	}
+	scheduler.usage.RecordRequest("acme", 502)
+	scheduler.usage.RecordQuery("acme", "Where are invoices?")
+	scheduler.usage.RecordRequest("", 200) // no tenant, not counted
+
+	report, err := scheduler.Generate(ctx, "acme", "admin", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := report.Digest
+	if digest.API.Requests != 4 || digest.API.Errors != 1 || digest.API.PreviousRequests != 1 || digest.RAG.Queries != 4 {
+		t.Fatalf("unexpected usage %+v %+v", digest.API, digest.RAG)
+	}
+	if len(digest.RAG.TopQuestions) != 2 || digest.RAG.TopQuestions[0].Question != "how do i reset my password?" || digest.RAG.TopQuestions[0].Count != 3 {
+		t.Fatalf("unexpected top questions %+v", digest.RAG.TopQuestions)
+	}
+	if digest.Quality == nil || digest.Security != nil || len(digest.Warnings) != 1 {
+		t.Fatalf("expected quality data and a security warning, got %+v %+v %v", digest.Quality, digest.Security, digest.Warnings)
+	}
+	if !strings.Contains(report.Markdown, "| 1 | how do i reset my password? | 3 |") || !strings.Contains(report.HTML, "<td>where are invoices?</td>") {
+		t.Fatalf("unexpected rendering:\n%s", report.Markdown)
+	}
+	if len(report.Deliveries) != 1 || report.Deliveries[0].Error != "" || received["id"] != report.ID {
+		t.Fatalf("expected webhook delivery, got %+v %v", report.Deliveries, received)
+	}
+
+	// The next report compares quality with the previous one
+	*now = now.Add(time.Hour)
+	next, err := scheduler.Generate(ctx, "acme", "admin", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change := next.Digest.Quality.Change; change == nil || *change != 2 {
+		t.Fatalf("expected a quality change of 2, got %v", change)
+	}
+
+	stored, err := scheduler.manager.GetReport(ctx, "acme", report.ID)
+	if err != nil || len(stored.Deliveries) != 1 {
+		t.Fatalf("expected stored report with deliveries, got %+v, %v", stored, err)
+	}
+	if _, err := scheduler.manager.GetReport(ctx, "globex", report.ID); err != ErrNotFound {
+		t.Fatalf("reports must be scoped to their tenant, got %v", err)
+	}
+	if _, err := scheduler.Generate(ctx, "missing", "admin", false); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for unknown tenant, got %v", err)
+	}
+}
+
+func TestScheduledReportsRunOncePerWeek(t *testing.T) {
+	ctx := context.Background()
+	scheduler, now := newTestScheduler(t, DefaultConfig(), "")
+
+	// More than a day after Monday 08:00: no catch-up report
+	scheduler.tick(ctx)
+	if reports, _ := scheduler.manager.ListReports(ctx, "acme", 10); len(reports) != 0 {
+		t.Fatalf("expected no late report, got %d", len(reports))
+	}
+
+	*now = time.Date(2026, 3, 9, 8, 1, 0, 0, time.UTC) // Monday
+	scheduler.tick(ctx)
+	scheduler.lastRun = time.Time{} // as if another instance or a restart
+	scheduler.tick(ctx)
+
+	for _, tenantID := range []string{"acme", "globex"} {
+		reports, err := scheduler.manager.ListReports(ctx, tenantID, 10)
+		if err != nil || len(reports) != 1 {
+			t.Fatalf("expected one weekly report for %s, got %d, %v", tenantID, len(reports), err)
+		}
+		report := reports[0]
+		if report.ID != "weekly_"+tenantID+"_20260309" || !report.PeriodStart.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)) {
+			t.Fatalf("unexpected weekly report %+v", report)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/internal/app/api/alerts"
+	"github.com/guileen/metabase/pkg/infra/i18n"
+	"github.com/guileen/metabase/pkg/infra/mailer"
+)
+
+// catchUpWindow 计划时间过后多久内（例如实例重启）仍补发当期报告
+const catchUpWindow = 24 * time.Hour
+
+// Scheduler 每周为各租户生成报告并发送到租户通知设置中的邮箱和 Webhook
+type Scheduler struct {
+	manager *Manager
+	usage   *UsageRecorder
+	targets alerts.TargetLoader
+	locales alerts.LocaleLoader
+	mailer  *mailer.Mailer
+	cass    *cassClient
+	client  *http.Client
+	config  *Config
+	logger  *zap.Logger
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+	now  func() time.Time
+
+	lastRun time.Time // 最近处理过的计划时间，仅由后台循环访问
+}
+
+// NewScheduler 创建报告调度器，targets 为空时报告只保存不发送
+func NewScheduler(manager *Manager, usage *UsageRecorder, targets alerts.TargetLoader, config *Config, logger *zap.Logger) *Scheduler {
+	defaults := DefaultConfig()
+	if config == nil {
+		config = defaults
+	}
+	if config.Days <= 0 {
+		config.Days = defaults.Days
+	}
+	if config.TopQuestions <= 0 {
+		config.TopQuestions = defaults.TopQuestions
+	}
+	s := &Scheduler{
+		manager: manager,
+		usage:   usage,
+		targets: targets,
+		mailer:  mailer.New(config.Mail),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		config:  config,
+		logger:  logger,
+		now:     time.Now,
+	}
+	if config.CASSURL != "" {
+		s.cass = newCASSClient(config.CASSURL)
+	}
+	return s
+}
+
+// SetLocales 设置报告邮件标题的语言，未设置时使用默认语言
+func (s *Scheduler) SetLocales(locales alerts.LocaleLoader) {
+	s.locales = locales
+}
+
+// Start 启动后台循环：每分钟写入用量，到计划时间时生成报告
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.loop(s.stop, s.done)
+}
+
+// Stop 停止后台循环并写入剩余用量
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (s *Scheduler) loop(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			if err := s.usage.Flush(context.Background()); err != nil {
+				s.logger.Warn("failed to flush report usage", zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+			s.tick(context.Background())
+		}
+	}
+}
+
+// tick 写入用量，并在计划时间后生成尚未生成的当期报告
+func (s *Scheduler) tick(ctx context.Context) {
+	if err := s.usage.Flush(ctx); err != nil {
+		s.logger.Warn("failed to flush report usage", zap.Error(err))
+	}
+	if !s.config.Enabled {
+		return
+	}
+
+	now := s.now()
+	run := s.config.lastRun(now)
+	if run.Equal(s.lastRun) || now.Sub(run) > catchUpWindow {
+		return
+	}
+	s.lastRun = run
+
+	tenants, err := s.manager.activeTenants(ctx)
+	if err != nil {
+		s.logger.Error("failed to list tenants for reports", zap.Error(err))
+		return
+	}
+	for _, t := range tenants {
+		_, err := s.generate(ctx, t.ID, startOfDay(run), TriggerScheduled, "", true)
+		if errors.Is(err, errExists) {
+			continue
+		}
+		if err != nil {
+			s.logger.Error("failed to generate weekly report", zap.String("tenant_id", t.ID), zap.Error(err))
+		}
+	}
+}
+
+// Generate 立即为租户生成截至今天（含）的报告，deliver 为 true 时发送
+func (s *Scheduler) Generate(ctx context.Context, tenantID, createdBy string, deliver bool) (*Report, error) {
+	if err := s.usage.Flush(ctx); err != nil {
+		s.logger.Warn("failed to flush report usage", zap.Error(err))
+	}
+	end := startOfDay(s.now()).AddDate(0, 0, 1)
+	return s.generate(ctx, tenantID, end, TriggerManual, createdBy, deliver)
+}
+
+// generate 生成 [end-Days, end) 的报告并保存。计划报告的ID由租户和日期确定，
+// 多实例同时生成时只有一个实例保存并发送
+func (s *Scheduler) generate(ctx context.Context, tenantID string, end time.Time, trigger, createdBy string, deliver bool) (*Report, error) {
+	name, err := s.manager.tenantName(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	start := end.AddDate(0, 0, -s.config.Days)
+	digest, err := s.compile(ctx, tenantID, name, start, end)
+	if err != nil {
+		return nil, err
+	}
+	markdown, html, err := renderDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		ID:          "report_" + uuid.New().String(),
+		TenantID:    tenantID,
+		Trigger:     trigger,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Digest:      digest,
+		Markdown:    markdown,
+		HTML:        html,
+		CreatedBy:   createdBy,
+		CreatedAt:   s.now(),
+	}
+	if trigger == TriggerScheduled {
+		report.ID = fmt.Sprintf("weekly_%s_%s", tenantID, end.Format("20060102"))
+	}
+	if err := s.manager.createReport(ctx, report); err != nil {
+		return nil, err
+	}
+
+	if deliver {
+		report.Deliveries = s.deliver(ctx, report)
+		if err := s.manager.updateReport(ctx, report); err != nil {
+			s.logger.Warn("failed to record report deliveries", zap.String("report_id", report.ID), zap.Error(err))
+		}
+	}
+	s.logger.Info("Report generated",
+		zap.String("report_id", report.ID),
+		zap.String("tenant_id", tenantID),
+		zap.Int("deliveries", len(report.Deliveries)),
+	)
+	return report, nil
+}
+
+// compile 汇总用量、热门问题和 CASS 指标；CASS 不可用时记录警告而不失败
+func (s *Scheduler) compile(ctx context.Context, tenantID, name string, start, end time.Time) (*Digest, error) {
+	digest := &Digest{TenantID: tenantID, TenantName: name, PeriodStart: start, PeriodEnd: end}
+
+	requests, errs, queries, err := s.manager.usageBetween(ctx, tenantID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	prevRequests, _, prevQueries, err := s.manager.usageBetween(ctx, tenantID, start.AddDate(0, 0, -s.config.Days), start)
+	if err != nil {
+		return nil, err
+	}
+	digest.API = UsageSummary{Requests: requests, Errors: errs, PreviousRequests: prevRequests}
+	if requests > 0 {
+		digest.API.ErrorRate = float64(errs) / float64(requests)
+	}
+	digest.RAG = QuerySummary{Queries: queries, PreviousQueries: prevQueries}
+	if digest.RAG.TopQuestions, err = s.manager.topQuestions(ctx, tenantID, start, end, s.config.TopQuestions); err != nil {
+		return nil, err
+	}
+
+	if s.cass == nil {
+		return digest, nil
+	}
+	previous, err := s.manager.previousReport(ctx, tenantID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if digest.Quality, err = s.cass.quality(ctx, tenantID); err != nil {
+		digest.Warnings = append(digest.Warnings, "代码质量数据获取失败: "+err.Error())
+	} else if previous != nil && previous.Digest != nil && previous.Digest.Quality != nil {
+		change := digest.Quality.OverallScore - previous.Digest.Quality.OverallScore
+		digest.Quality.Change = &change
+	}
+	if digest.Security, err = s.cass.security(ctx, tenantID); err != nil {
+		digest.Warnings = append(digest.Warnings, "安全扫描数据获取失败: "+err.Error())
+	} else if previous != nil && previous.Digest != nil && previous.Digest.Security != nil {
+		change := digest.Security.Total - previous.Digest.Security.Total
+		digest.Security.Change = &change
+	}
+	return digest, nil
+}
+
+// deliver 发送到租户通知设置中的邮箱和 Webhook
+func (s *Scheduler) deliver(ctx context.Context, report *Report) []Delivery {
+	if s.targets == nil {
+		return nil
+	}
+	settings, err := s.targets(ctx, report.TenantID)
+	if err != nil {
+		s.logger.Warn("failed to load tenant notification settings", zap.String("tenant_id", report.TenantID), zap.Error(err))
+		return nil
+	}
+	if settings == nil {
+		return nil
+	}
+
+	var deliveries []Delivery
+	if settings.Email && len(settings.EmailTo) > 0 && s.mailer.Enabled() {
+		locale := i18n.DefaultLocale
+		if s.locales != nil {
+			locale = s.locales(ctx, report.TenantID)
+		}
+		err := s.mailer.Send(&mailer.Message{
+			To: settings.EmailTo,
+			Subject: i18n.Translate(locale, "report.email.subject", i18n.Args{
+				"tenant": report.Digest.TenantName,
+				"start":  report.PeriodStart.Format("2006-01-02"),
+				"end":    report.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+			}),
+			Text: report.Markdown,
+			HTML: report.HTML,
+		})
+		for _, to := range settings.EmailTo {
+			deliveries = append(deliveries, delivery("email", to, err))
+		}
+	}
+	if settings.Webhook != "" {
+		deliveries = append(deliveries, delivery("webhook", settings.Webhook, s.post(ctx, settings.Webhook, report)))
+	}
+	return deliveries
+}
+
+func delivery(channel, target string, err error) Delivery {
+	d := Delivery{Channel: channel, Target: target}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	return d
+}
+
+// post 以 JSON 发送报告内容和 Markdown 正文
+func (s *Scheduler) post(ctx context.Context, url string, report *Report) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"type":         "report",
+		"id":           report.ID,
+		"tenant_id":    report.TenantID,
+		"period_start": report.PeriodStart,
+		"period_end":   report.PeriodEnd,
+		"digest":       report.Digest,
+		"markdown":     report.Markdown,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package reports
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/pkg/infra/mailer"
+)
+
+// 报告触发方式
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+)
+
+// 报告格式
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Report 一份租户周报
+type Report struct {
+	ID          string     `json:"id"`
+	TenantID    string     `json:"tenant_id"`
+	Trigger     string     `json:"trigger"`
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	Digest      *Digest    `json:"digest"`
+	Markdown    string     `json:"markdown,omitempty"`
+	HTML        string     `json:"html,omitempty"`
+	Deliveries  []Delivery `json:"deliveries,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Delivery 报告的一次发送结果
+type Delivery struct {
+	Channel string `json:"channel"` // email 或 webhook
+	Target  string `json:"target"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Digest 报告内容
+type Digest struct {
+	TenantID    string    `json:"tenant_id"`
+	TenantName  string    `json:"tenant_name"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	API      UsageSummary     `json:"api"`
+	RAG      QuerySummary     `json:"rag"`
+	Quality  *QualitySummary  `json:"quality,omitempty"`  // 未配置 CASS 时为空
+	Security *SecuritySummary `json:"security,omitempty"` // 未配置 CASS 时为空
+	Warnings []string         `json:"warnings,omitempty"` // 部分数据无法获取时的说明
+}
+
+// UsageSummary API 调用统计
+type UsageSummary struct {
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	ErrorRate        float64 `json:"error_rate"`
+	PreviousRequests int64   `json:"previous_requests"`
+}
+
+// QuerySummary RAG 查询统计
+type QuerySummary struct {
+	Queries         int64           `json:"queries"`
+	PreviousQueries int64           `json:"previous_queries"`
+	TopQuestions    []QuestionCount `json:"top_questions"`
+}
+
+// QuestionCount 问题及其出现次数
+type QuestionCount struct {
+	Question string `json:"question"`
+	Count    int64  `json:"count"`
+}
+
+// QualitySummary CASS 代码质量指标
+type QualitySummary struct {
+	OverallScore float64            `json:"overall_score"`
+	Metrics      map[string]float64 `json:"metrics,omitempty"`
+	Change       *float64           `json:"change,omitempty"` // 相对上期的总分变化
+}
+
+// SecuritySummary CASS 安全扫描结果
+type SecuritySummary struct {
+	Total    int  `json:"total_vulnerabilities"`
+	Critical int  `json:"critical"`
+	High     int  `json:"high"`
+	Medium   int  `json:"medium"`
+	Low      int  `json:"low"`
+	Change   *int `json:"change,omitempty"` // 相对上期的漏洞总数变化
+}
+
+// Config 报告调度配置
+type Config struct {
+	Enabled      bool         `json:"enabled"`
+	Weekday      time.Weekday `json:"weekday"`       // 每周生成报告的日期
+	Hour         int          `json:"hour"`          // 生成时间（UTC 小时）
+	Days         int          `json:"days"`          // 报告覆盖的天数
+	TopQuestions int          `json:"top_questions"` // 列出的热门问题数
+	CASSURL      string       `json:"cass_url,omitempty"`
+
+	Mail *mailer.Config `json:"mail,omitempty"`
+}
+
+// DefaultConfig 默认每周一 08:00 UTC 生成上一周的报告
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:      true,
+		Weekday:      time.Monday,
+		Hour:         8,
+		Days:         7,
+		TopQuestions: 10,
+	}
+}
+
+// ConfigFromEnv 从环境变量读取报告配置，邮件服务器使用 METABASE_SMTP_*
+func ConfigFromEnv() *Config {
+	cfg := DefaultConfig()
+	if os.Getenv("METABASE_REPORTS_DISABLED") == "true" {
+		cfg.Enabled = false
+	}
+	if weekday, ok := parseWeekday(os.Getenv("METABASE_REPORTS_WEEKDAY")); ok {
+		cfg.Weekday = weekday
+	}
+	if hour, err := strconv.Atoi(os.Getenv("METABASE_REPORTS_HOUR")); err == nil && hour >= 0 && hour < 24 {
+		cfg.Hour = hour
+	}
+	cfg.CASSURL = os.Getenv("METABASE_CASS_URL")
+	cfg.Mail = mailer.ConfigFromEnv()
+	return cfg
+}
+
+func parseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// lastRun 返回 now 之前最近一次的计划生成时间
+func (c *Config) lastRun(now time.Time) time.Time {
+	now = now.UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), c.Hour, 0, 0, 0, time.UTC)
+	for end.Weekday() != c.Weekday || end.After(now) {
+		end = end.AddDate(0, 0, -1)
+	}
+	return end
+}
+
+// startOfDay 返回 t 所在 UTC 日期的零点
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
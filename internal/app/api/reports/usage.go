@@ -0,0 +1,158 @@
+package reports
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// maxQuestionLength 记录的问题长度上限（字符）
+const maxQuestionLength = 300
+
+// usageCounts 一个租户一天的计数
+type usageCounts struct {
+	requests int64
+	errors   int64
+	queries  int64
+}
+
+type usageKey struct {
+	tenantID string
+	day      time.Time
+}
+
+type questionKey struct {
+	usageKey
+	question string
+}
+
+// UsageRecorder 在内存中累计各租户的 API 请求和 RAG 查询，定期写入数据库，
+// 避免每个请求都写库
+type UsageRecorder struct {
+	manager *Manager
+
+	mu        sync.Mutex
+	usage     map[usageKey]*usageCounts
+	questions map[questionKey]int64
+	now       func() time.Time
+}
+
+// NewUsageRecorder 创建用量记录器
+func NewUsageRecorder(manager *Manager) *UsageRecorder {
+	return &UsageRecorder{
+		manager:   manager,
+		usage:     make(map[usageKey]*usageCounts),
+		questions: make(map[questionKey]int64),
+		now:       time.Now,
+	}
+}
+
+func (u *UsageRecorder) countsLocked(tenantID string) (*usageCounts, usageKey) {
+	key := usageKey{tenantID: tenantID, day: startOfDay(u.now())}
+	counts, ok := u.usage[key]
+	if !ok {
+		counts = &usageCounts{}
+		u.usage[key] = counts
+	}
+	return counts, key
+}
+
+// RecordRequest 记录租户的一次 API 请求
+func (u *UsageRecorder) RecordRequest(tenantID string, status int) {
+	if tenantID == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	counts, _ := u.countsLocked(tenantID)
+	counts.requests++
+	if status >= 500 {
+		counts.errors++
+	}
+}
+
+// RecordQuery 记录租户的一次 RAG 查询
+func (u *UsageRecorder) RecordQuery(tenantID, question string) {
+	if tenantID == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	counts, key := u.countsLocked(tenantID)
+	counts.queries++
+	if question = normalizeQuestion(question); question != "" {
+		u.questions[questionKey{usageKey: key, question: question}]++
+	}
+}
+
+// normalizeQuestion 合并大小写和空白差异，截断过长的问题
+func normalizeQuestion(question string) string {
+	question = strings.ToLower(strings.Join(strings.Fields(question), " "))
+	if runes := []rune(question); len(runes) > maxQuestionLength {
+		question = string(runes[:maxQuestionLength])
+	}
+	return question
+}
+
+// Middleware 按 resolve 解析出的租户记录请求，无法确定租户的请求不计入
+func (u *UsageRecorder) Middleware(resolve func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			u.RecordRequest(resolve(r), status)
+		})
+	}
+}
+
+// Flush 将累计的用量写入数据库；写入失败的计数保留到下次
+func (u *UsageRecorder) Flush(ctx context.Context) error {
+	u.mu.Lock()
+	usage, questions := u.usage, u.questions
+	u.usage = make(map[usageKey]*usageCounts)
+	u.questions = make(map[questionKey]int64)
+	u.mu.Unlock()
+
+	var firstErr error
+	for key, counts := range usage {
+		if err := u.manager.addUsage(ctx, key.tenantID, key.day, counts); err != nil {
+			u.restoreUsage(key, counts)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	for key, count := range questions {
+		if err := u.manager.addQuestion(ctx, key.tenantID, key.day, key.question, count); err != nil {
+			u.mu.Lock()
+			u.questions[key] += count
+			u.mu.Unlock()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (u *UsageRecorder) restoreUsage(key usageKey, counts *usageCounts) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	existing, ok := u.usage[key]
+	if !ok {
+		u.usage[key] = counts
+		return
+	}
+	existing.requests += counts.requests
+	existing.errors += counts.errors
+	existing.queries += counts.queries
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestResponseCacheAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	server, _ := startInstance(t, filepath.Join(dir, "metabase.db"))
+	if _, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	for _, tc := range []struct {
+		method, path string
+		cached       bool
+	}{
+		{http.MethodGet, "/admin/v1/tenants", true},
+		{http.MethodGet, "/admin/v1/tenants/t1", true},
+		{http.MethodGet, "/admin/v1/tenants/t1/projects", true},
+		{http.MethodGet, "/admin/v1/projects/p1", true},
+		{http.MethodGet, "/admin/v1/projects/p1/documents", true},
+		{http.MethodGet, "/admin/v1/projects/p1/documents/d1/chunks", true},
+
+		// Polled status endpoints and other reads are never cached
+		{http.MethodGet, "/admin/v1/projects/p1/documents/jobs", false},
+		{http.MethodGet, "/admin/v1/projects/p1/documents/jobs/j1", false},
+		{http.MethodGet, "/admin/v1/projects/p1/rag/batch/j1", false},
+		{http.MethodGet, "/admin/v1/projects/p1/rag/datasources/s1/status", false},
+		{http.MethodGet, "/admin/v1/projects/p1/rag/reviews", false},
+		{http.MethodGet, "/admin/v1/tenants/t1/reports/usage", false},
+		{http.MethodGet, "/admin/v1/jobs", false},
+	} {
+		scopes := server.responseScopes(httptest.NewRequest(tc.method, tc.path, nil))
+		if cached := len(scopes) > 0; cached != tc.cached {
+			t.Errorf("%s %s: cached = %v, want %v (scopes %v)", tc.method, tc.path, cached, tc.cached, scopes)
+		}
+	}
+
+	// Writes anywhere under a project still invalidate its cached reads
+	scopes := server.responseScopes(httptest.NewRequest(http.MethodPost, "/admin/v1/projects/p1/rag/batch", nil))
+	for _, scope := range scopes {
+		if scope == "project:p1" {
+			return
+		}
+	}
+	t.Fatalf("expected project writes to invalidate the project, got %v", scopes)
+}
+
+func TestResponseCacheRunsBehindAuthentication(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	server, ts := startInstance(t, filepath.Join(dir, "metabase.db"))
+	if _, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+
+	get := func(authorization string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/admin/v1/tenants", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	for _, want := range []string{"MISS", "HIT"} {
+		if resp := get("Bearer test-token"); resp.StatusCode != http.StatusOK || resp.Header.Get("X-Cache") != want {
+			t.Fatalf("expected a cache %s, got %d %q", want, resp.StatusCode, resp.Header.Get("X-Cache"))
+		}
+	}
+
+	// Cached reads are only served once the caller is authenticated
+	if resp := get(""); resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("X-Cache") != "" {
+		t.Fatalf("expected an unauthenticated request to be rejected before the cache, got %d %q", resp.StatusCode, resp.Header.Get("X-Cache"))
+	}
+}
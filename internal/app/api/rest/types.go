@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/internal/app/api/middleware"
 )
 
 // RequestMethod HTTP请求方法
@@ -356,34 +357,10 @@ func ValidateStruct(v interface{}) error {
 	return nil
 }
 
-// GetClientIP gets the client IP address from request
+// GetClientIP gets the client IP address from request, believing
+// forwarding headers only from trusted proxies
 func GetClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP if multiple
-		if idx := len(xff); idx > 0 {
-			if commaIdx := 0; commaIdx < idx {
-				for i, c := range xff {
-					if c == ',' {
-						commaIdx = i
-						break
-					}
-				}
-				if commaIdx > 0 {
-					return xff[:commaIdx]
-				}
-			}
-			return xff
-		}
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
-	}
-
-	// Fall back to RemoteAddr
-	return r.RemoteAddr
+	return middleware.ClientIP(r)
 }
 
 // ExtractBearerToken extracts bearer token from Authorization header
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/internal/app/api/audit"
+	"github.com/guileen/metabase/internal/app/api/handlers"
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+func TestTemporaryRoleGrants(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	server, ts := startInstance(t, filepath.Join(dir, "metabase.db"))
+
+	_, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`)
+	if err == nil {
+		_, err = server.db.Exec(`INSERT INTO projects (id, tenant_id, name, slug, owner_id) VALUES ('p1', 't1', 'One', 'p1', 'u1')`)
+	}
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	if err := server.projectMembers.AddUserToProject(ctx, "u2", "p1", auth.ProjectRoleViewer, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	role := func() string {
+		t.Helper()
+		member, err := server.projectMembers.UserProjectRole(ctx, "u2", "p1")
+		if err != nil {
+			return ""
+		}
+		return member.Role
+	}
+	grantsURL := ts.URL + "/admin/v1/projects/p1/role-grants"
+
+	// Grants take effect immediately and revert when revoked
+	status, grant := doJSON(t, http.MethodPost, grantsURL, map[string]string{
+		"user_id": "u2", "role": auth.ProjectRoleOwner, "duration": "4h", "reason": "incident 42",
+	})
+	if status != http.StatusCreated || grant["status"] != auth.GrantStatusActive {
+		t.Fatalf("grant: status %d, body %v", status, grant)
+	}
+	if got := role(); got != auth.ProjectRoleOwner {
+		t.Fatalf("expected elevated role, got %q", got)
+	}
+	if status, _ := doJSON(t, http.MethodDelete, grantsURL+"/"+grant["id"].(string), nil); status != http.StatusOK {
+		t.Fatalf("revoke: status %d", status)
+	}
+	if got := role(); got != auth.ProjectRoleViewer {
+		t.Fatalf("expected role to revert after revocation, got %q", got)
+	}
+	if status, _ := doJSON(t, http.MethodPost, grantsURL, map[string]string{
+		"user_id": "u2", "role": auth.ProjectRoleOwner, "duration": "30d",
+	}); status != http.StatusBadRequest {
+		t.Fatalf("expected invalid duration to be rejected, got %d", status)
+	}
+
+	// Expired grants revert and are reported once
+	expiring := &auth.RoleGrant{ProjectID: "p1", UserID: "u3", Role: auth.ProjectRoleCollaborator,
+		Duration: time.Minute, RequestedBy: "u1"}
+	if err := server.projectMembers.GrantRole(ctx, expiring, false); err != nil {
+		t.Fatal(err)
+	}
+	if member, err := server.projectMembers.UserProjectRole(ctx, "u3", "p1"); err != nil || member.Role != auth.ProjectRoleCollaborator {
+		t.Fatalf("expected non-member to gain access, got %v %v", member, err)
+	}
+	expired, err := server.projectMembers.ExpireRoleGrants(ctx, time.Now().Add(2*time.Minute))
+	if err != nil || len(expired) != 1 || expired[0].ID != expiring.ID {
+		t.Fatalf("expected grant to expire, got %v %v", expired, err)
+	}
+	if again, _ := server.projectMembers.ExpireRoleGrants(ctx, time.Now().Add(2*time.Minute)); len(again) != 0 {
+		t.Fatalf("expected expired grant to be swept once, got %d", len(again))
+	}
+
+	// Tenants may require a second admin to approve grants
+	if _, err := server.db.Exec(`UPDATE tenants SET settings = '{"require_grant_approval": true}' WHERE id = 't1'`); err != nil {
+		t.Fatal(err)
+	}
+	status, grant = doJSON(t, http.MethodPost, grantsURL, map[string]string{
+		"user_id": "u2", "role": auth.ProjectRoleCollaborator, "duration": "1h",
+	})
+	if status != http.StatusCreated || grant["status"] != auth.GrantStatusPending {
+		t.Fatalf("grant: status %d, body %v", status, grant)
+	}
+	if got := role(); got != auth.ProjectRoleViewer {
+		t.Fatalf("expected pending grant to have no effect, got %q", got)
+	}
+	if status, _ := doJSON(t, http.MethodPost, grantsURL+"/"+grant["id"].(string)+"/approve", nil); status != http.StatusForbidden {
+		t.Fatalf("expected self-approval to be refused, got %d", status)
+	}
+	if _, err := server.projectMembers.ApproveRoleGrant(ctx, "p1", grant["id"].(string), "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := role(); got != auth.ProjectRoleCollaborator {
+		t.Fatalf("expected approved grant to apply, got %q", got)
+	}
+
+	// Every grant change lands in the audit log
+	entries, err := server.auditManager.List(ctx, audit.Filter{TenantID: "t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]int{}
+	for _, entry := range entries {
+		actions[entry.Action]++
+	}
+	if actions[handlers.RoleGrantGranted] != 1 || actions[handlers.RoleGrantRevoked] != 1 || actions[handlers.RoleGrantRequested] != 1 {
+		t.Fatalf("unexpected audited actions %v", actions)
+	}
+}
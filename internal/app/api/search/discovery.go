@@ -0,0 +1,388 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/guileen/metabase/pkg/infra/metadata"
+)
+
+const (
+	// discoveryCandidates 项目发现时最多比较的候选项目数，按最近更新优先
+	discoveryCandidates = 5000
+	// maxFacetValues 每个分面最多返回的取值数
+	maxFacetValues = 20
+	// tagsField 项目元数据中作为标签的字段
+	tagsField = "tags"
+)
+
+// 分面名称，声明的元数据字段的分面为 meta.<字段>
+const (
+	FacetTenant      = "tenant"
+	FacetEnvironment = "environment"
+	FacetTag         = "tag"
+)
+
+// 各字段命中查询词时的权重
+var discoveryWeights = map[string]float64{
+	"name":        3,
+	"tags":        2.5,
+	"slug":        2,
+	"metadata":    1.5,
+	"description": 1,
+}
+
+// DiscoverQuery 项目发现条件，Text 为空时只按过滤条件浏览
+type DiscoverQuery struct {
+	Text        string            `json:"q,omitempty"`
+	TenantID    string            `json:"tenant_id,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Tags        []string          `json:"tags,omitempty"` // 须包含全部标签
+	Meta        map[string]string `json:"meta,omitempty"` // 声明的元数据字段取值
+	Limit       int               `json:"limit,omitempty"`
+	Offset      int               `json:"offset,omitempty"`
+}
+
+// ProjectHit 项目发现结果
+type ProjectHit struct {
+	ID          string                 `json:"id"`
+	TenantID    string                 `json:"tenant_id"`
+	TenantName  string                 `json:"tenant_name"`
+	Name        string                 `json:"name"`
+	Slug        string                 `json:"slug"`
+	Description string                 `json:"description,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"` // 仅包含声明的字段
+	Matches     []string               `json:"matches,omitempty"`  // 命中查询词的字段
+	Score       float64                `json:"score"`
+
+	// searchable 参与匹配和分面的元数据字段取值
+	searchable map[string][]string
+}
+
+// FacetValue 分面的一个取值及其项目数
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// Discovery 项目发现结果，分面按过滤后的全部结果统计
+type Discovery struct {
+	Results []ProjectHit            `json:"results"`
+	Facets  map[string][]FacetValue `json:"facets"`
+	Total   int                     `json:"total"`
+}
+
+// ViewableProjects 返回 projectIDs 中调用者可以查看的项目
+type ViewableProjects func(ctx context.Context, projectIDs []string) ([]string, error)
+
+// SetMetadataSchemas 设置租户的项目元数据结构来源，声明的字段可用于搜索、过滤和分面；
+// 未设置时搜索全部顶层元数据，不提供元数据分面
+func (m *Manager) SetMetadataSchemas(store *metadata.Store) {
+	m.schemas = store
+}
+
+// Discover 在调用者可以查看的项目中按名称、描述、标签和声明的元数据字段搜索，
+// 按相关度排序并统计分面。viewable 为 nil 时不校验访问权限
+func (m *Manager) Discover(ctx context.Context, query DiscoverQuery, viewable ViewableProjects) (*Discovery, error) {
+	terms := tokenize(query.Text)
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	candidates, err := m.discoveryCandidates(ctx, terms, query.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if viewable != nil && len(candidates) > 0 {
+		ids := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			ids[i] = candidate.ID
+		}
+		allowed, err := viewable(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check project access: %w", err)
+		}
+		visible := make(map[string]bool, len(allowed))
+		for _, id := range allowed {
+			visible[id] = true
+		}
+		var kept []ProjectHit
+		for _, candidate := range candidates {
+			if visible[candidate.ID] {
+				kept = append(kept, candidate)
+			}
+		}
+		candidates = kept
+	}
+
+	schemas := map[string]*metadata.Schema{}
+	var hits []ProjectHit
+	for _, hit := range candidates {
+		schema, ok := schemas[hit.TenantID]
+		if !ok && m.schemas != nil {
+			if schema, err = m.schemas.Schema(ctx, hit.TenantID, metadata.TargetProject); err != nil {
+				return nil, err
+			}
+			schemas[hit.TenantID] = schema
+		}
+		hit.restrictMetadata(schema)
+		if !hit.matchesFilters(query) {
+			continue
+		}
+		if len(terms) > 0 && !hit.score(terms) {
+			continue
+		}
+		hits = append(hits, hit)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	discovery := &Discovery{Results: []ProjectHit{}, Facets: facets(hits), Total: len(hits)}
+	if query.Offset < len(hits) {
+		end := min(max(query.Offset, 0)+limit, len(hits))
+		discovery.Results = hits[max(query.Offset, 0):end]
+	}
+	return discovery, nil
+}
+
+// discoveryCandidates 读取未删除的项目，有查询词时只保留包含每个词前两个字符的项目，
+// 以便模糊匹配仍能命中拼写错误
+func (m *Manager) discoveryCandidates(ctx context.Context, terms []string, tenantID string) ([]ProjectHit, error) {
+	var conditions []string
+	var args []interface{}
+	if tenantID != "" {
+		conditions = append(conditions, "p.tenant_id = ?")
+		args = append(args, tenantID)
+	}
+	for _, term := range terms {
+		runes := []rune(term)
+		conditions = append(conditions, `LOWER(p.name || ' ' || p.slug || ' ' || COALESCE(p.description, '') || ' ' || COALESCE(p.metadata, '')) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(string(runes[:min(len(runes), 2)]))+"%")
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " AND " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, discoveryCandidates)
+	query := `
+		SELECT p.id, p.tenant_id, COALESCE(t.name, ''), p.name, p.slug, COALESCE(p.description, ''),
+			COALESCE(p.environment, ''), COALESCE(p.metadata, '')
+		FROM projects p LEFT JOIN tenants t ON t.id = p.tenant_id
+		WHERE p.deleted_at IS NULL` + where + `
+		ORDER BY p.updated_at DESC LIMIT ?`
+	if m.driver == "postgres" || m.driver == "pgx" {
+		query = numberPlaceholders(query)
+	}
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover projects: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []ProjectHit
+	for rows.Next() {
+		var hit ProjectHit
+		var raw string
+		if err := rows.Scan(&hit.ID, &hit.TenantID, &hit.TenantName, &hit.Name, &hit.Slug,
+			&hit.Description, &hit.Environment, &raw); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		if raw != "" {
+			json.Unmarshal([]byte(raw), &hit.Metadata)
+		}
+		candidates = append(candidates, hit)
+	}
+	return candidates, rows.Err()
+}
+
+// restrictMetadata 取出标签，并只保留租户声明的元数据字段；没有结构时搜索全部顶层字段，
+// 但不返回元数据
+func (h *ProjectHit) restrictMetadata(schema *metadata.Schema) {
+	all := h.Metadata
+	h.Metadata = nil
+	h.Tags = metadata.Values(all[tagsField])
+	h.searchable = map[string][]string{}
+	for name, value := range all {
+		if name == tagsField {
+			continue
+		}
+		if schema == nil {
+			h.searchable[name] = metadata.Values(value)
+			continue
+		}
+		if schema.Declares(name) {
+			h.searchable[name] = metadata.Values(value)
+			if h.Metadata == nil {
+				h.Metadata = map[string]interface{}{}
+			}
+			h.Metadata[name] = value
+		}
+	}
+	if schema == nil {
+		// 未声明的字段不能作为分面
+		h.searchable = map[string][]string{"": flatten(h.searchable)}
+	}
+}
+
+// matchesFilters 判断项目是否满足环境、标签和元数据过滤条件
+func (h *ProjectHit) matchesFilters(query DiscoverQuery) bool {
+	if query.Environment != "" && !strings.EqualFold(h.Environment, query.Environment) {
+		return false
+	}
+	for _, tag := range query.Tags {
+		if !containsFold(h.Tags, tag) {
+			return false
+		}
+	}
+	for field, value := range query.Meta {
+		if field == "" || !containsFold(h.searchable[field], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// score 计算相关度，每个查询词取命中最好的字段；有查询词未命中任何字段时返回 false
+func (h *ProjectHit) score(terms []string) bool {
+	fields := map[string][]string{
+		"name":        tokenize(h.Name),
+		"slug":        tokenize(h.Slug),
+		"description": tokenize(h.Description),
+		"tags":        tokenize(strings.Join(h.Tags, " ")),
+		"metadata":    tokenize(strings.Join(flatten(h.searchable), " ")),
+	}
+	matched := map[string]bool{}
+	total := 0.0
+	for _, term := range terms {
+		best, bestField := 0.0, ""
+		for field, words := range fields {
+			if s := wordMatch(term, words, field != "description") * discoveryWeights[field]; s > best {
+				best, bestField = s, field
+			}
+		}
+		if best == 0 {
+			return false
+		}
+		total += best
+		matched[bestField] = true
+	}
+	// 名称与查询完全相同的项目排在最前面
+	if strings.Join(fields["name"], " ") == strings.Join(terms, " ") {
+		total += discoveryWeights["name"]
+	}
+	for field := range matched {
+		h.Matches = append(h.Matches, field)
+	}
+	sort.Strings(h.Matches)
+	h.Score = total / float64(len(terms))
+	return true
+}
+
+// wordMatch 返回查询词与一组词的最佳匹配度：相同为 1，前缀为 0.8，允许时按编辑距离近似匹配
+func wordMatch(term string, words []string, fuzzy bool) float64 {
+	best := 0.0
+	for _, word := range words {
+		switch {
+		case word == term:
+			return 1
+		case strings.HasPrefix(word, term):
+			best = max(best, 0.8)
+		case fuzzy && len([]rune(term)) >= 3:
+			best = max(best, similarity(term, word)*0.6)
+		}
+	}
+	return best
+}
+
+// facets 统计租户、环境、标签和声明的元数据字段的取值分布
+func facets(hits []ProjectHit) map[string][]FacetValue {
+	counts := map[string]map[string]int{}
+	labels := map[string]string{}
+	add := func(facet, value string) {
+		if value == "" {
+			return
+		}
+		if counts[facet] == nil {
+			counts[facet] = map[string]int{}
+		}
+		counts[facet][value]++
+	}
+	for _, hit := range hits {
+		add(FacetTenant, hit.TenantID)
+		labels[hit.TenantID] = hit.TenantName
+		add(FacetEnvironment, hit.Environment)
+		for _, tag := range unique(hit.Tags) {
+			add(FacetTag, tag)
+		}
+		for field, values := range hit.searchable {
+			if field == "" {
+				continue
+			}
+			for _, value := range unique(values) {
+				add("meta."+field, value)
+			}
+		}
+	}
+
+	result := make(map[string][]FacetValue, len(counts))
+	for facet, values := range counts {
+		list := make([]FacetValue, 0, len(values))
+		for value, count := range values {
+			entry := FacetValue{Value: value, Count: count}
+			if facet == FacetTenant {
+				entry.Label = labels[value]
+			}
+			list = append(list, entry)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Value < list[j].Value
+		})
+		if len(list) > maxFacetValues {
+			list = list[:maxFacetValues]
+		}
+		result[facet] = list
+	}
+	return result
+}
+
+func flatten(fields map[string][]string) []string {
+	var values []string
+	for _, v := range fields {
+		values = append(values, v...)
+	}
+	return values
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+func containsFold(values []string, want string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, want) {
+			return true
+		}
+	}
+	return false
+}
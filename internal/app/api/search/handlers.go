@@ -0,0 +1,149 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// Handler 管理后台搜索的HTTP处理器
+type Handler struct {
+	manager *Manager
+	logger  *zap.Logger
+
+	userID   func(r *http.Request) string
+	projects ProjectAccess
+}
+
+// ProjectAccess 返回 projectIDs 中用户可以查看的项目
+type ProjectAccess func(ctx context.Context, userID string, projectIDs []string) ([]string, error)
+
+// NewHandler 创建搜索处理器
+func NewHandler(manager *Manager, logger *zap.Logger) *Handler {
+	return &Handler{
+		manager: manager,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes 注册搜索路由（挂载于 /admin/v1/search，系统管理员权限）
+func (h *Handler) RegisterRoutes(r chi.Router) {
+	r.Get("/", h.handleSearch)
+}
+
+// SetProjectAccess 设置项目发现的调用者识别和访问校验，未设置时项目发现不可用
+func (h *Handler) SetProjectAccess(userID func(r *http.Request) string, access ProjectAccess) {
+	h.userID = userID
+	h.projects = access
+}
+
+// RegisterDiscoveryRoutes 注册项目发现路由（挂载于 /admin/v1/discover，登录用户可用）
+func (h *Handler) RegisterDiscoveryRoutes(r chi.Router) {
+	r.Get("/projects", h.handleDiscoverProjects)
+}
+
+// handleSearch 按 q 搜索租户和项目，kind 限定类型，limit 限定数量
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := Query{
+		Text: r.URL.Query().Get("q"),
+		Kind: r.URL.Query().Get("kind"),
+	}
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			h.error(w, r, http.StatusBadRequest, "Invalid limit", err, "invalid_request")
+			return
+		}
+		query.Limit = limit
+	}
+	if len(tokenize(query.Text)) == 0 {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Search text is required",
+			"code":  "invalid_request",
+		})
+		return
+	}
+
+	results, err := h.manager.Search(r.Context(), query)
+	if err != nil {
+		h.logger.Error("failed to search", zap.String("q", query.Text), zap.Error(err))
+		h.error(w, r, http.StatusBadRequest, "Failed to search", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": results})
+}
+
+// handleDiscoverProjects 在调用者可以查看的项目中搜索。q 为查询文本，tenant、environment、
+// tag（可多个）和 meta.<字段> 过滤结果，limit 和 offset 分页
+func (h *Handler) handleDiscoverProjects(w http.ResponseWriter, r *http.Request) {
+	if h.userID == nil || h.projects == nil {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "Project discovery not configured",
+		})
+		return
+	}
+	userID := h.userID(r)
+	if userID == "" {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]interface{}{
+			"error": "User not authenticated",
+			"code":  "unauthorized",
+		})
+		return
+	}
+
+	params := r.URL.Query()
+	query := DiscoverQuery{
+		Text:        params.Get("q"),
+		TenantID:    params.Get("tenant"),
+		Environment: params.Get("environment"),
+		Tags:        params["tag"],
+		Meta:        map[string]string{},
+	}
+	for key, values := range params {
+		if field, ok := strings.CutPrefix(key, "meta."); ok && len(values) > 0 {
+			query.Meta[field] = values[0]
+		}
+	}
+	for name, target := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		if raw := params.Get(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 0 {
+				h.error(w, r, http.StatusBadRequest, "Invalid "+name, fmt.Errorf("%s must be a non-negative integer", name), "invalid_request")
+				return
+			}
+			*target = n
+		}
+	}
+
+	discovery, err := h.manager.Discover(r.Context(), query, func(ctx context.Context, projectIDs []string) ([]string, error) {
+		return h.projects(ctx, userID, projectIDs)
+	})
+	if err != nil {
+		h.logger.Error("failed to discover projects", zap.String("q", query.Text), zap.Error(err))
+		h.error(w, r, http.StatusInternalServerError, "Failed to discover projects", err, "")
+		return
+	}
+	render.JSON(w, r, map[string]interface{}{"data": discovery})
+}
+
+// error 输出错误响应
+func (h *Handler) error(w http.ResponseWriter, r *http.Request, status int, message string, err error, code string) {
+	body := map[string]interface{}{
+		"error":   message,
+		"details": err.Error(),
+	}
+	if code != "" {
+		body["code"] = code
+	}
+	render.Status(r, status)
+	render.JSON(w, r, body)
+}
@@ -0,0 +1,445 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+
+	"github.com/guileen/metabase/pkg/infra/metadata"
+)
+
+const (
+	// defaultLimit 默认返回的结果数
+	defaultLimit = 20
+	// maxLimit 单次查询最多返回的结果数
+	maxLimit = 100
+	// fuzzyCandidates 模糊匹配时最多比较的候选记录数
+	fuzzyCandidates = 1000
+)
+
+// 结果类型
+const (
+	KindTenant  = "tenant"
+	KindProject = "project"
+)
+
+// 匹配方式
+const (
+	MatchText  = "text"  // 全文或前缀匹配
+	MatchFuzzy = "fuzzy" // 编辑距离内的近似匹配
+)
+
+// Query 搜索条件
+type Query struct {
+	Text  string `json:"q"`
+	Kind  string `json:"kind,omitempty"` // tenant、project，为空时两者都搜索
+	Limit int    `json:"limit,omitempty"`
+}
+
+// Result 搜索结果
+type Result struct {
+	Kind     string  `json:"kind"`
+	ID       string  `json:"id"`
+	TenantID string  `json:"tenant_id"`
+	Name     string  `json:"name"`
+	Slug     string  `json:"slug"`
+	Domain   string  `json:"domain,omitempty"`
+	Match    string  `json:"match"`
+	Score    float64 `json:"score"`
+}
+
+// Manager 管理后台的租户和项目搜索。SQLite 编译了 FTS5 时使用全文索引，
+// Postgres 使用 tsvector 表达式索引，否则退回 LIKE 匹配；全文结果不足时
+// 再按编辑距离做模糊匹配
+type Manager struct {
+	db      *sql.DB
+	driver  string
+	logger  *zap.Logger
+	fts     bool
+	schemas *metadata.Store
+}
+
+// NewManager 创建搜索管理器，driver 为数据库驱动名
+func NewManager(db *sql.DB, driver string, logger *zap.Logger) *Manager {
+	return &Manager{
+		db:     db,
+		driver: driver,
+		logger: logger,
+	}
+}
+
+// Initialize 创建全文索引，需在租户和项目表迁移之后调用
+func (m *Manager) Initialize(ctx context.Context) error {
+	switch m.driver {
+	case "postgres", "pgx":
+		_, err := m.db.ExecContext(ctx, `
+		CREATE INDEX IF NOT EXISTS idx_tenants_search ON tenants USING GIN (`+postgresTenantVector+`);
+		CREATE INDEX IF NOT EXISTS idx_projects_search ON projects USING GIN (`+postgresProjectVector+`);
+		`)
+		if err != nil {
+			m.logger.Error("failed to initialize search indexes", zap.Error(err))
+			return fmt.Errorf("failed to initialize search indexes: %w", err)
+		}
+		m.fts = true
+		return nil
+	}
+
+	if _, err := m.db.ExecContext(ctx, `
+	CREATE VIRTUAL TABLE IF NOT EXISTS admin_search USING fts5(
+		kind UNINDEXED, id UNINDEXED, tenant_id UNINDEXED,
+		name, slug, domain, metadata,
+		prefix = '2 3'
+	)`); err != nil {
+		// 未编译 FTS5 的 SQLite 使用 LIKE 匹配
+		m.logger.Info("full-text search unavailable, using LIKE matching", zap.Error(err))
+		return nil
+	}
+	if _, err := m.db.ExecContext(ctx, sqliteSearchTriggers); err != nil {
+		m.logger.Error("failed to initialize search triggers", zap.Error(err))
+		return fmt.Errorf("failed to initialize search triggers: %w", err)
+	}
+	if err := m.Rebuild(ctx); err != nil {
+		return err
+	}
+	m.fts = true
+	return nil
+}
+
+// FullText 返回是否使用全文索引，否则使用 LIKE 匹配
+func (m *Manager) FullText() bool {
+	return m.fts
+}
+
+// Rebuild 从租户和项目表重建 SQLite 全文索引
+func (m *Manager) Rebuild(ctx context.Context) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	DELETE FROM admin_search;
+	INSERT INTO admin_search (kind, id, tenant_id, name, slug, domain, metadata)
+		SELECT 'tenant', id, id, name, slug, COALESCE(domain, ''), COALESCE(metadata, '')
+		FROM tenants WHERE deleted_at IS NULL;
+	INSERT INTO admin_search (kind, id, tenant_id, name, slug, domain, metadata)
+		SELECT 'project', id, tenant_id, name, slug, '', COALESCE(metadata, '')
+		FROM projects WHERE deleted_at IS NULL;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild search index: %w", err)
+	}
+	return tx.Commit()
+}
+
+// sqliteSearchTriggers 保持全文索引与租户、项目表同步，软删除的记录移出索引
+const sqliteSearchTriggers = `
+CREATE TRIGGER IF NOT EXISTS admin_search_tenants_insert AFTER INSERT ON tenants
+WHEN new.deleted_at IS NULL BEGIN
+	INSERT INTO admin_search (kind, id, tenant_id, name, slug, domain, metadata)
+	VALUES ('tenant', new.id, new.id, new.name, new.slug, COALESCE(new.domain, ''), COALESCE(new.metadata, ''));
+END;
+CREATE TRIGGER IF NOT EXISTS admin_search_tenants_update AFTER UPDATE ON tenants BEGIN
+	DELETE FROM admin_search WHERE kind = 'tenant' AND id = old.id;
+	INSERT INTO admin_search (kind, id, tenant_id, name, slug, domain, metadata)
+	SELECT 'tenant', new.id, new.id, new.name, new.slug, COALESCE(new.domain, ''), COALESCE(new.metadata, '')
+	WHERE new.deleted_at IS NULL;
+END;
+CREATE TRIGGER IF NOT EXISTS admin_search_tenants_delete AFTER DELETE ON tenants BEGIN
+	DELETE FROM admin_search WHERE kind = 'tenant' AND id = old.id;
+END;
+CREATE TRIGGER IF NOT EXISTS admin_search_projects_insert AFTER INSERT ON projects
+WHEN new.deleted_at IS NULL BEGIN
+	INSERT INTO admin_search (kind, id, tenant_id, name, slug, domain, metadata)
+	VALUES ('project', new.id, new.tenant_id, new.name, new.slug, '', COALESCE(new.metadata, ''));
+END;
+CREATE TRIGGER IF NOT EXISTS admin_search_projects_update AFTER UPDATE ON projects BEGIN
+	DELETE FROM admin_search WHERE kind = 'project' AND id = old.id;
+	INSERT INTO admin_search (kind, id, tenant_id, name, slug, domain, metadata)
+	SELECT 'project', new.id, new.tenant_id, new.name, new.slug, '', COALESCE(new.metadata, '')
+	WHERE new.deleted_at IS NULL;
+END;
+CREATE TRIGGER IF NOT EXISTS admin_search_projects_delete AFTER DELETE ON projects BEGIN
+	DELETE FROM admin_search WHERE kind = 'project' AND id = old.id;
+END;
+`
+
+// postgres 全文索引表达式，查询时须使用相同的表达式才能命中索引
+const (
+	postgresTenantVector  = `to_tsvector('simple', name || ' ' || slug || ' ' || COALESCE(domain, '') || ' ' || COALESCE(metadata::text, ''))`
+	postgresProjectVector = `to_tsvector('simple', name || ' ' || slug || ' ' || COALESCE(metadata::text, ''))`
+)
+
+// Search 按名称、slug、域名和元数据搜索租户和项目，先返回全文匹配，
+// 不足 limit 时补充模糊匹配
+func (m *Manager) Search(ctx context.Context, query Query) ([]Result, error) {
+	terms := tokenize(query.Text)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search text is required")
+	}
+	if query.Kind != "" && query.Kind != KindTenant && query.Kind != KindProject {
+		return nil, fmt.Errorf("unknown kind %q", query.Kind)
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	var results []Result
+	var err error
+	switch {
+	case m.fts && (m.driver == "postgres" || m.driver == "pgx"):
+		results, err = m.searchPostgres(ctx, terms, query.Kind, limit)
+	case m.fts:
+		results, err = m.searchFTS(ctx, terms, query.Kind, limit)
+	default:
+		results, err = m.searchLike(ctx, terms, query.Kind, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) < limit {
+		fuzzy, err := m.searchFuzzy(ctx, terms, query.Kind)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool, len(results))
+		for _, result := range results {
+			seen[result.Kind+":"+result.ID] = true
+		}
+		for _, result := range fuzzy {
+			if len(results) >= limit {
+				break
+			}
+			if !seen[result.Kind+":"+result.ID] {
+				results = append(results, result)
+			}
+		}
+	}
+	if results == nil {
+		results = []Result{}
+	}
+	return results, nil
+}
+
+// searchFTS 使用 SQLite FTS5 前缀查询，按 bm25 排序
+func (m *Manager) searchFTS(ctx context.Context, terms []string, kind string, limit int) ([]Result, error) {
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `"*`
+	}
+	args := []interface{}{strings.Join(match, " AND ")}
+	where := ""
+	if kind != "" {
+		where = " AND kind = ?"
+		args = append(args, kind)
+	}
+	args = append(args, limit)
+
+	return m.scan(ctx, `
+		SELECT kind, id, tenant_id, name, slug, domain, -rank
+		FROM admin_search WHERE admin_search MATCH ?`+where+`
+		ORDER BY rank LIMIT ?`, args...)
+}
+
+// searchPostgres 使用 tsvector 前缀查询，按 ts_rank 排序
+func (m *Manager) searchPostgres(ctx context.Context, terms []string, kind string, limit int) ([]Result, error) {
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = term + ":*"
+	}
+	tsquery := strings.Join(match, " & ")
+
+	var parts []string
+	if kind == "" || kind == KindTenant {
+		parts = append(parts, `SELECT 'tenant', id, id, name, slug, COALESCE(domain, ''),
+			ts_rank(`+postgresTenantVector+`, to_tsquery('simple', $1))
+			FROM tenants WHERE deleted_at IS NULL AND `+postgresTenantVector+` @@ to_tsquery('simple', $1)`)
+	}
+	if kind == "" || kind == KindProject {
+		parts = append(parts, `SELECT 'project', id, tenant_id, name, slug, '',
+			ts_rank(`+postgresProjectVector+`, to_tsquery('simple', $1))
+			FROM projects WHERE deleted_at IS NULL AND `+postgresProjectVector+` @@ to_tsquery('simple', $1)`)
+	}
+	return m.scan(ctx, strings.Join(parts, " UNION ALL ")+` ORDER BY 7 DESC LIMIT $2`, tsquery, limit)
+}
+
+// searchLike 未启用全文索引时按子串匹配，名称以查询开头的排在前面
+func (m *Manager) searchLike(ctx context.Context, terms []string, kind string, limit int) ([]Result, error) {
+	var parts []string
+	var args []interface{}
+	add := func(kindName, tenantColumn, domainColumn, table string) {
+		// 排序分数的参数在前，与 SELECT 中占位符的顺序一致
+		args = append(args, escapeLike(terms[0])+"%")
+		var conditions []string
+		for _, term := range terms {
+			pattern := "%" + escapeLike(term) + "%"
+			conditions = append(conditions, `(LOWER(name) LIKE ? ESCAPE '\' OR LOWER(slug) LIKE ? ESCAPE '\'
+				OR LOWER(`+domainColumn+`) LIKE ? ESCAPE '\' OR LOWER(COALESCE(metadata, '')) LIKE ? ESCAPE '\')`)
+			args = append(args, pattern, pattern, pattern, pattern)
+		}
+		parts = append(parts, `SELECT '`+kindName+`', id, `+tenantColumn+`, name, slug, `+domainColumn+`,
+			CASE WHEN LOWER(name) LIKE ? ESCAPE '\' THEN 1.0 ELSE 0.5 END
+			FROM `+table+` WHERE deleted_at IS NULL AND `+strings.Join(conditions, " AND "))
+	}
+	if kind == "" || kind == KindTenant {
+		add(KindTenant, "id", "COALESCE(domain, '')", "tenants")
+	}
+	if kind == "" || kind == KindProject {
+		add(KindProject, "tenant_id", "''", "projects")
+	}
+	args = append(args, limit)
+	return m.scan(ctx, strings.Join(parts, " UNION ALL ")+` ORDER BY 7 DESC, 4 LIMIT ?`, args...)
+}
+
+// searchFuzzy 在名称、slug 或域名与查询前两个字符相同的候选中按编辑距离匹配
+func (m *Manager) searchFuzzy(ctx context.Context, terms []string, kind string) ([]Result, error) {
+	var parts []string
+	var args []interface{}
+	for _, term := range terms {
+		if len([]rune(term)) < 3 {
+			return nil, nil
+		}
+	}
+	prefix := escapeLike(string([]rune(terms[0])[:2])) + "%"
+	if kind == "" || kind == KindTenant {
+		parts = append(parts, `SELECT 'tenant', id, id, name, slug, COALESCE(domain, ''), 0 FROM tenants
+			WHERE deleted_at IS NULL AND (LOWER(name) LIKE ? ESCAPE '\' OR LOWER(slug) LIKE ? ESCAPE '\' OR LOWER(COALESCE(domain, '')) LIKE ? ESCAPE '\')`)
+		args = append(args, prefix, prefix, prefix)
+	}
+	if kind == "" || kind == KindProject {
+		parts = append(parts, `SELECT 'project', id, tenant_id, name, slug, '', 0 FROM projects
+			WHERE deleted_at IS NULL AND (LOWER(name) LIKE ? ESCAPE '\' OR LOWER(slug) LIKE ? ESCAPE '\')`)
+		args = append(args, prefix, prefix)
+	}
+	args = append(args, fuzzyCandidates)
+	query := strings.Join(parts, " UNION ALL ") + ` LIMIT ?`
+	if m.driver == "postgres" || m.driver == "pgx" {
+		query = numberPlaceholders(query)
+	}
+	candidates, err := m.scan(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Result
+	for _, candidate := range candidates {
+		words := tokenize(candidate.Name + " " + candidate.Slug + " " + candidate.Domain)
+		score := 0.0
+		for _, term := range terms {
+			best := 0.0
+			for _, word := range words {
+				if s := similarity(term, word); s > best {
+					best = s
+				}
+			}
+			if best == 0 {
+				score = 0
+				break
+			}
+			score += best / float64(len(terms))
+		}
+		if score > 0 {
+			candidate.Match = MatchFuzzy
+			candidate.Score = score
+			matches = append(matches, candidate)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+// scan 执行查询，每行依次为 kind、id、tenant_id、name、slug、domain、score
+func (m *Manager) scan(ctx context.Context, query string, args ...interface{}) ([]Result, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []Result
+	for rows.Next() {
+		result := Result{Match: MatchText}
+		if err := rows.Scan(&result.Kind, &result.ID, &result.TenantID, &result.Name, &result.Slug, &result.Domain, &result.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// tokenize 将文本按非字母数字字符拆分为小写词
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// similarity 返回查询词与候选词的相似度，超出允许的编辑距离时为 0。
+// 候选词以查询词开头视为完全匹配，以便模糊匹配也支持前缀
+func similarity(term, word string) float64 {
+	a, b := []rune(term), []rune(word)
+	if len(b) > len(a) && string(b[:len(a)]) == term {
+		b = b[:len(a)]
+	}
+	allowed := 1
+	if len(a) > 5 {
+		allowed = 2
+	}
+	distance := levenshtein(a, b)
+	if distance > allowed {
+		return 0
+	}
+	return 1 - float64(distance)/float64(len(a)+1)
+}
+
+// levenshtein 计算编辑距离
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// numberPlaceholders 将 ? 占位符改写为 Postgres 的 $n
+func numberPlaceholders(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,184 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/metadata"
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+)
+
+func newTestManager(t *testing.T) (*Manager, *sql.DB) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := auth.NewMigrationRunner(db).RunMigrations(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO tenants (id, name, slug, domain, metadata) VALUES
+			('t1', 'Acme Corporation', 'acme', 'acme.example.com', '{"industry": "logistics"}'),
+			('t2', 'Globex', 'globex', 'globex.io', '{}');
+		INSERT INTO projects (id, tenant_id, name, slug, owner_id, metadata) VALUES
+			('p1', 't1', 'Acme Analytics', 'acme-analytics', 'u1', '{}'),
+			('p2', 't2', 'Warehouse', 'warehouse', 'u1', '{}');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewManager(db, "sqlite3", zap.NewNop())
+	if err := manager.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return manager, db
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	manager, db := newTestManager(t)
+	ids := func(query Query) []string {
+		t.Helper()
+		results, err := manager.Search(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []string
+		for _, result := range results {
+			found = append(found, result.Kind+":"+result.ID+":"+result.Match)
+		}
+		return found
+	}
+	contains := func(found []string, want string) bool {
+		for _, f := range found {
+			if f == want {
+				return true
+			}
+		}
+		return false
+	}
+
+	if found := ids(Query{Text: "acm"}); !contains(found, "tenant:t1:text") || !contains(found, "project:p1:text") {
+		t.Fatalf("expected prefix matches for tenant and project, got %v", found)
+	}
+	if found := ids(Query{Text: "acm", Kind: KindTenant}); len(found) != 1 || found[0] != "tenant:t1:text" {
+		t.Fatalf("expected only the tenant, got %v", found)
+	}
+	if found := ids(Query{Text: "globex.io"}); !contains(found, "tenant:t2:text") {
+		t.Fatalf("expected a domain match, got %v", found)
+	}
+	if found := ids(Query{Text: "logistics"}); !contains(found, "tenant:t1:text") {
+		t.Fatalf("expected a metadata match, got %v", found)
+	}
+	if found := ids(Query{Text: "warehuose"}); len(found) != 1 || found[0] != "project:p2:fuzzy" {
+		t.Fatalf("expected a fuzzy match for a typo, got %v", found)
+	}
+
+	// Soft deleted tenants drop out of the results
+	if _, err := db.Exec(`UPDATE tenants SET deleted_at = CURRENT_TIMESTAMP WHERE id = 't2'`); err != nil {
+		t.Fatal(err)
+	}
+	if found := ids(Query{Text: "globex"}); len(found) != 0 {
+		t.Fatalf("expected deleted tenants to be hidden, got %v", found)
+	}
+	if _, err := manager.Search(ctx, Query{Text: "  "}); err == nil {
+		t.Fatal("expected empty search text to be rejected")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	ctx := context.Background()
+	manager, db := newTestManager(t)
+	_, err := db.Exec(`
+		INSERT INTO projects (id, tenant_id, name, slug, owner_id, description, environment, metadata) VALUES
+			('p3', 't1', 'Search Relevance', 'search-relevance', 'u1', 'Ranking experiments', 'production',
+				'{"tags": ["ml", "search"], "team": "discovery", "secret": "hidden"}'),
+			('p4', 't1', 'Billing', 'billing', 'u1', 'Invoices and search over payments', 'development',
+				'{"tags": ["finance"], "team": "payments"}'),
+			('p5', 't2', 'Research Notes', 'research-notes', 'u1', '', 'development', '{"tags": ["search"]}');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := metadata.NewStore(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, err := metadata.Parse([]byte(`{"type": "object", "properties": {"team": {"type": "string"}, "tags": {"type": "array", "items": {"type": "string"}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveSchema(ctx, "t1", metadata.TargetProject, schema, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	manager.SetMetadataSchemas(store)
+	viewable := func(ctx context.Context, projectIDs []string) ([]string, error) {
+		var allowed []string
+		for _, id := range projectIDs {
+			if id != "p5" {
+				allowed = append(allowed, id)
+			}
+		}
+		return allowed, nil
+	}
+	ids := func(discovery *Discovery) []string {
+		var found []string
+		for _, hit := range discovery.Results {
+			found = append(found, hit.ID)
+		}
+		return found
+	}
+
+	// Name matches outrank description matches; inaccessible projects are hidden
+	discovery, err := manager.Discover(ctx, DiscoverQuery{Text: "search"}, viewable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := ids(discovery); len(found) != 2 || found[0] != "p3" || found[1] != "p4" {
+		t.Fatalf("expected p3 before p4, got %v", found)
+	}
+	if hit := discovery.Results[0]; hit.Metadata["secret"] != nil || hit.Metadata["team"] != "discovery" || len(hit.Tags) != 2 {
+		t.Fatalf("expected only declared metadata and tags, got %+v", hit)
+	}
+
+	// Declared metadata fields are searchable, typos still match
+	if discovery, _ := manager.Discover(ctx, DiscoverQuery{Text: "paymnets"}, viewable); len(discovery.Results) != 1 || discovery.Results[0].ID != "p4" {
+		t.Fatalf("expected a fuzzy metadata match, got %v", ids(discovery))
+	}
+	if discovery, _ := manager.Discover(ctx, DiscoverQuery{Text: "hidden"}, viewable); len(discovery.Results) != 0 {
+		t.Fatalf("expected undeclared metadata to be ignored, got %v", ids(discovery))
+	}
+
+	// Filters narrow the results and facets count what is left
+	discovery, err = manager.Discover(ctx, DiscoverQuery{TenantID: "t1", Tags: []string{"finance"}}, viewable)
+	if err != nil || len(discovery.Results) != 1 || discovery.Results[0].ID != "p4" {
+		t.Fatalf("expected the finance project, got %v, %v", discovery, err)
+	}
+	discovery, _ = manager.Discover(ctx, DiscoverQuery{Meta: map[string]string{"team": "discovery"}}, viewable)
+	if found := ids(discovery); len(found) != 1 || found[0] != "p3" {
+		t.Fatalf("expected a metadata filter match, got %v", found)
+	}
+	discovery, _ = manager.Discover(ctx, DiscoverQuery{TenantID: "t1"}, viewable)
+	if discovery.Total != 3 {
+		t.Fatalf("expected three projects in t1, got %d", discovery.Total)
+	}
+	tenants := discovery.Facets[FacetTenant]
+	if len(tenants) != 1 || tenants[0].Value != "t1" || tenants[0].Label != "Acme Corporation" || tenants[0].Count != 3 {
+		t.Fatalf("unexpected tenant facet %+v", tenants)
+	}
+	if teams := discovery.Facets["meta.team"]; len(teams) != 2 || teams[0].Count != 1 {
+		t.Fatalf("unexpected team facet %+v", teams)
+	}
+	if tags := discovery.Facets[FacetTag]; len(tags) != 3 {
+		t.Fatalf("unexpected tag facet %+v", tags)
+	}
+	if discovery, _ := manager.Discover(ctx, DiscoverQuery{TenantID: "t1", Limit: 2, Offset: 2}, viewable); len(discovery.Results) != 1 || discovery.Total != 3 {
+		t.Fatalf("expected the last page to hold one project, got %d of %d", len(discovery.Results), discovery.Total)
+	}
+}
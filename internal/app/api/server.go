@@ -3,19 +3,38 @@ package api
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/internal/app/api/alerts"
+	"github.com/guileen/metabase/internal/app/api/anomaly"
+	"github.com/guileen/metabase/internal/app/api/approvals"
+	"github.com/guileen/metabase/internal/app/api/audit"
 	"github.com/guileen/metabase/internal/app/api/handlers"
 	"github.com/guileen/metabase/internal/app/api/keys"
+	"github.com/guileen/metabase/internal/app/api/maintenance"
 	"github.com/guileen/metabase/internal/app/api/middleware"
+	"github.com/guileen/metabase/internal/app/api/onboarding"
+	"github.com/guileen/metabase/internal/app/api/profile"
+	"github.com/guileen/metabase/internal/app/api/rag"
+	"github.com/guileen/metabase/internal/app/api/reports"
+	"github.com/guileen/metabase/internal/app/api/search"
+	"github.com/guileen/metabase/internal/app/mcp"
 	"github.com/guileen/metabase/internal/app/trojan"
 	"github.com/guileen/metabase/pkg/config"
 	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/blobstore"
+	"github.com/guileen/metabase/pkg/infra/events"
+	"github.com/guileen/metabase/pkg/infra/features"
+	"github.com/guileen/metabase/pkg/infra/logsink"
+	"github.com/guileen/metabase/pkg/infra/mailer"
+	"github.com/guileen/metabase/pkg/infra/metadata"
 	"github.com/guileen/metabase/pkg/log"
+	"github.com/guileen/metabase/pkg/rag/core"
 	_ "github.com/mattn/go-sqlite3"
 	"go.uber.org/zap"
 )
@@ -27,6 +46,48 @@ type Config struct {
 	DevMode      bool                  `json:"dev_mode"`
 	DatabasePath string                `json:"database_path"`
 	LogConfig    *config.LoggingConfig `json:"log_config,omitempty"`
+	MCP          *mcp.Config           `json:"mcp,omitempty"`
+	Bots         *rag.BotConfig        `json:"bots,omitempty"`
+	Widget       *rag.WidgetConfig     `json:"widget,omitempty"`
+	Downloads    *rag.DownloadConfig   `json:"downloads,omitempty"`
+
+	// Where uploaded originals, logos and export archives are stored
+	Blobs *blobstore.Config `json:"blobs,omitempty"`
+
+	// CORS settings for requests without tenant CORS settings
+	CORS *middleware.CORSConfig `json:"cors,omitempty"`
+
+	// Alert evaluation and system-level notification targets
+	Alerts *alerts.Config `json:"alerts,omitempty"`
+
+	// Weekly tenant report schedule
+	Reports *reports.Config `json:"reports,omitempty"`
+
+	// Serve pprof profiles and runtime metrics under /debug to system admins
+	EnableProfiling bool `json:"enable_profiling,omitempty"`
+
+	// Locale of messages and emails when neither the user nor the tenant sets one
+	DefaultLocale string `json:"default_locale,omitempty"`
+
+	// Reverse proxies, as IPs or CIDRs, whose X-Forwarded-For headers are
+	// believed; without them the client is the connection's peer
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+
+	// Where the server's logs are written; empty logs to stderr in development format
+	LogSinks []logsink.Config `json:"log_sinks,omitempty"`
+
+	// Hash-chained audit log of admin and auth writes and where it is shipped
+	Audit *audit.Config `json:"audit,omitempty"`
+
+	// Login and API key anomaly detection and step-up authentication
+	Anomaly *anomaly.Config `json:"anomaly,omitempty"`
+
+	// Destructive operations that need a second admin's approval
+	Approvals *approvals.Config `json:"approvals,omitempty"`
+
+	// Secret that signs and verifies access tokens; a random secret is used
+	// when empty, so tokens do not survive a restart
+	JWTSecret string `json:"-"`
 }
 
 // NewConfig creates a new API server configuration with defaults and environment variables
@@ -38,6 +99,21 @@ func NewConfig() *Config {
 		Host:         appConfig.GetString("server.host"),
 		DevMode:      appConfig.GetBool("server.dev_mode"),
 		DatabasePath: appConfig.GetString("database.sqlite_path"),
+		Bots:         rag.BotConfigFromEnv(),
+		Widget:       rag.WidgetConfigFromEnv(),
+		Downloads:    rag.DownloadConfigFromEnv(),
+		Blobs:        blobstore.ConfigFromEnv(),
+		Alerts:       alerts.ConfigFromEnv(),
+		Reports:      reports.ConfigFromEnv(),
+
+		EnableProfiling: appConfig.GetBool("server.enable_profiling"),
+		DefaultLocale:   appConfig.GetString("server.default_locale"),
+		TrustedProxies:  strings.Split(appConfig.GetString("server.trusted_proxies"), ","),
+		LogSinks:        logsink.ConfigsFromEnv("METABASE_LOG_SINKS"),
+		Audit:           audit.ConfigFromEnv(),
+		Anomaly:         anomaly.ConfigFromEnv(),
+		Approvals:       approvals.ConfigFromEnv(),
+		JWTSecret:       appConfig.GetString("auth.jwt_secret"),
 	}
 
 	// Use API port from config
@@ -57,16 +133,50 @@ type Server struct {
 	db                *sql.DB
 	keysManager       *keys.Manager
 	rbacManager       *auth.RBACManager
-	tenantManager     *auth.TenantManager
 	restHandler       *handlers.RestHandler
 	authHandler       *handlers.AuthHandler
 	systemHandler     *handlers.SystemHandler
 	keyHandler        *keys.Handler
+	ragManager        *rag.Manager
+	ragHandler        *rag.Handler
+	mcpServer         *mcp.Server
 	tenantHandler     *handlers.TenantHandler
 	adminHandler      *handlers.AdminHandler
 	trojanHandler     *handlers.TrojanHandler
 	trojanManager     *trojan.Manager
 	projectMiddleware *middleware.ProjectMiddleware
+	tenantCORS        *middleware.TenantCORS
+	tenantLocales     *middleware.TenantLocales
+	projectMembers    *auth.ProjectMembers
+	features          *features.Service
+	featureHandler    *handlers.FeatureHandler
+	metadataHandler   *handlers.MetadataHandler
+	alertManager      *alerts.Manager
+	alertRecorder     *alerts.Recorder
+	alertEngine       *alerts.Engine
+	alertHandler      *alerts.Handler
+	stopAlerts        context.CancelFunc
+	stopGrantExpiry   context.CancelFunc
+	reportUsage       *reports.UsageRecorder
+	reportScheduler   *reports.Scheduler
+	reportHandler     *reports.Handler
+	onboardingManager *onboarding.Manager
+	onboardingHandler *onboarding.Handler
+	readOnlyMode      *maintenance.Manager
+	readOnlyHandler   *maintenance.Handler
+	events            *events.Bus
+	responseCache     *middleware.ResponseCache
+	searchHandler     *search.Handler
+	profileManager    *profile.Manager
+	profileHandler    *profile.Handler
+	auditManager      *audit.Manager
+	auditHandler      *audit.Handler
+	approvalManager   *approvals.Manager
+	approvalHandler   *approvals.Handler
+	anomalyDetector   *anomaly.Detector
+	anomalyHandler    *anomaly.Handler
+	blobs             blobstore.Store
+	closeLogs         func() error
 }
 
 // NewServer creates a new API server
@@ -76,6 +186,14 @@ func NewServer(cfg *Config) (*Server, error) {
 	}
 
 	logger, _ := zap.NewDevelopment()
+	closeLogs := logger.Sync
+	if len(cfg.LogSinks) > 0 {
+		sinkLogger, closeSinks, err := logsink.New(cfg.LogSinks, zap.AddCaller())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log sinks: %w", err)
+		}
+		logger, closeLogs = sinkLogger, closeSinks
+	}
 
 	// 初始化数据库
 	db, err := sql.Open("sqlite3", cfg.DatabasePath)
@@ -119,7 +237,7 @@ func NewServer(cfg *Config) (*Server, error) {
 		MaxBodySize:         1024 * 1024,
 		GenerateTraceID:     true,
 		LogStatus:           "all",
-		SkipPaths:           []string{"/health", "/ping", "/version"},
+		SkipPaths:           []string{"/health", "/ping", "/version", "/mcp/sse"},
 		DefaultFields:       map[string]interface{}{},
 		MeasureResponseTime: true,
 	}
@@ -132,6 +250,12 @@ func NewServer(cfg *Config) (*Server, error) {
 		// 继续运行，可能是表已存在
 	}
 
+	// 初始化项目RAG配置管理器
+	ragManager := rag.NewManager(db, nil, logger)
+	if err := ragManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize RAG settings manager", zap.Error(err))
+	}
+
 	// 运行数据库迁移，创建租户和项目表
 	migrationRunner := auth.NewMigrationRunner(db)
 	if err := migrationRunner.RunMigrations(context.Background()); err != nil {
@@ -145,10 +269,104 @@ func NewServer(cfg *Config) (*Server, error) {
 		logger.Error("Failed to initialize RBAC manager", zap.Error(err))
 	}
 
-	tenantManager := auth.NewTenantManager()
+	// 初始化租户开通，恢复已开通租户的角色
+	onboardingManager := onboarding.NewManager(db, keysManager, rbacManager, logger)
+	if err := onboardingManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize onboarding manager", zap.Error(err))
+	} else if err := onboardingManager.LoadRoles(context.Background()); err != nil {
+		logger.Error("Failed to load tenant roles", zap.Error(err))
+	}
+
+	// 初始化项目权限中间件，成员关系从数据库读取，多实例间保持一致
+	projectMembers := auth.NewProjectMembers(db)
+	projectMiddleware := middleware.NewProjectMiddleware(db, rbacManager, projectMembers, logger)
+
+	// 初始化功能开关，定义保存在数据库中，多实例间保持一致
+	var featureStore features.Store
+	featureStore, err = features.NewSQLStore(context.Background(), db)
+	if err != nil {
+		logger.Error("Failed to initialize feature flag store", zap.Error(err))
+		featureStore = features.NewMemoryStore()
+	}
+	featureService := features.NewService(featureStore, middleware.TenantFeaturesFromDB(db))
+
+	// 初始化租户定义的元数据结构，未能初始化时元数据不做校验
+	metadataSchemas, err := metadata.NewStore(context.Background(), db)
+	if err != nil {
+		logger.Error("Failed to initialize metadata schema store", zap.Error(err))
+	}
+
+	// 初始化告警规则和通知中心
+	if cfg.Alerts == nil {
+		cfg.Alerts = alerts.DefaultConfig()
+	}
+	alertManager := alerts.NewManager(db, logger)
+	if err := alertManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize alert manager", zap.Error(err))
+	}
+	alertRecorder := alerts.NewRecorder(cfg.Alerts.Window)
+	alertDispatcher := alerts.NewChannelDispatcher(cfg.Alerts, alerts.TargetsFromDB(db), logger)
+	alertEngine := alerts.NewEngine(alertManager, alertRecorder, alertDispatcher, cfg.Alerts, logger)
+
+	// 初始化租户周报
+	reportManager := reports.NewManager(db, logger)
+	if err := reportManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize report manager", zap.Error(err))
+	}
+	reportUsage := reports.NewUsageRecorder(reportManager)
+	reportScheduler := reports.NewScheduler(reportManager, reportUsage, alerts.TargetsFromDB(db), cfg.Reports, logger)
+
+	// 只读维护模式，状态保存在数据库中由所有实例共享
+	maintenanceManager := maintenance.NewManager(db, logger)
+	if err := maintenanceManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize maintenance manager", zap.Error(err))
+	}
+
+	// 审计日志，哈希链保存在数据库中并转发到配置的日志汇
+	auditManager := audit.NewManager(db, cfg.Audit, logger)
+	if err := auditManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize audit log", zap.Error(err))
+	}
+
+	// 客户端地址只信任来自可信代理的转发头
+	if err := middleware.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	// 访问令牌的签名密钥，未配置时使用随机密钥
+	if cfg.JWTSecret == "" {
+		logger.Warn("auth.jwt_secret not configured, access tokens will not survive a restart")
+		cfg.JWTSecret = auth.GenerateRandomToken(32)
+	}
+
+	// 破坏性操作的双人审批，审批单保存在数据库中由所有实例共享
+	approvalManager := approvals.NewManager(db, cfg.Approvals, logger)
+	if err := approvalManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize approvals", zap.Error(err))
+	}
+	approvalManager.Register(approvalOperations...)
+	// 审批须由经过验证的系统管理员作出
+	isApprovalAdmin := func(r *http.Request) bool {
+		return projectMiddleware.IsSystemAdminUser(authenticatedUser(r))
+	}
+
+	// 登录和 API 密钥异常检测
+	anomalyDetector := anomaly.NewDetector(db, cfg.Anomaly, logger)
+	if err := anomalyDetector.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize anomaly detector", zap.Error(err))
+	}
+
+	// 用户资料和偏好，每个请求注入上下文
+	profileManager := profile.NewManager(db, logger)
+	if err := profileManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize profile manager", zap.Error(err))
+	}
 
-	// 初始化项目权限中间件
-	projectMiddleware := middleware.NewProjectMiddleware(db, rbacManager, tenantManager, logger)
+	// 管理后台的租户和项目搜索，全文索引依赖迁移创建的表
+	searchManager := search.NewManager(db, "sqlite3", logger)
+	if err := searchManager.Initialize(context.Background()); err != nil {
+		logger.Error("Failed to initialize search manager", zap.Error(err))
+	}
 
 	// 初始化Trojan管理器
 	trojanManager := trojan.NewManager(db, logger)
@@ -169,21 +387,304 @@ func NewServer(cfg *Config) (*Server, error) {
 		db:                db,
 		keysManager:       keysManager,
 		rbacManager:       rbacManager,
-		tenantManager:     tenantManager,
 		restHandler:       handlers.NewRestHandler(db, logger),
 		authHandler:       handlers.NewAuthHandler(db, logger),
 		systemHandler:     handlers.NewSystemHandler(logger),
 		keyHandler:        keys.NewHandler(keysManager, logger),
+		ragManager:        ragManager,
+		ragHandler:        rag.NewHandler(ragManager, logger),
+		mcpServer:         mcp.NewServer(cfg.MCP, keysManager, ragManager, logger),
 		tenantHandler:     handlers.NewTenantHandler(db, logger),
 		adminHandler:      handlers.NewAdminHandler(db, logger),
 		trojanHandler:     trojanHandler,
 		trojanManager:     trojanManager,
 		projectMiddleware: projectMiddleware,
+		projectMembers:    projectMembers,
+		features:          featureService,
+		featureHandler:    handlers.NewFeatureHandler(featureService, logger),
+		alertManager:      alertManager,
+		alertRecorder:     alertRecorder,
+		alertEngine:       alertEngine,
+		alertHandler:      alerts.NewHandler(alertManager, alertEngine, logger),
+		reportUsage:       reportUsage,
+		reportScheduler:   reportScheduler,
+		reportHandler:     reports.NewHandler(reportManager, reportScheduler, logger),
+		onboardingManager: onboardingManager,
+		onboardingHandler: onboarding.NewHandler(onboardingManager, logger),
+		readOnlyMode:      maintenanceManager,
+		readOnlyHandler:   maintenance.NewHandler(maintenanceManager, logger),
+		events:            events.NewBus(),
+		searchHandler:     search.NewHandler(searchManager, logger),
+		profileManager:    profileManager,
+		auditManager:      auditManager,
+		auditHandler:      audit.NewHandler(auditManager, logger),
+		approvalManager:   approvalManager,
+		approvalHandler:   approvals.NewHandler(approvalManager, authenticatedUser, isApprovalAdmin, logger),
+		anomalyDetector:   anomalyDetector,
+		anomalyHandler:    anomaly.NewHandler(anomalyDetector, logger),
+		closeLogs:         closeLogs,
+		profileHandler:    profile.NewHandler(profileManager, projectMiddleware.UserID, logger),
+	}
+
+	// 租户CORS配置，租户设置更新时清除缓存
+	corsDefaults := middleware.DefaultCORSConfig()
+	if cfg.CORS != nil {
+		corsDefaults = *cfg.CORS
+	}
+	server.tenantCORS = middleware.NewTenantCORS(corsDefaults, middleware.TenantCORSFromDB(db), server.requestTenant, logger)
+	// 嵌入式组件的查询接口按令牌的允许来源自行处理 CORS
+	server.tenantCORS.Exempt("/public/v1/rag/query")
+	server.tenantHandler.OnSettingsChange(server.tenantCORS.Invalidate)
+	server.tenantHandler.OnSettingsChange(server.features.Invalidate)
+
+	// 语言依次取用户资料、租户设置和服务器默认值，用于错误信息、邀请邮件和通知
+	server.tenantLocales = middleware.NewTenantLocales(cfg.DefaultLocale, middleware.TenantLocaleFromDB(db), server.requestTenant, logger)
+	server.tenantHandler.OnSettingsChange(server.tenantLocales.Invalidate)
+	server.tenantHandler.SetInvitationMailer(mailer.New(cfg.Alerts.Mail), server.inviteLocale)
+	alertDispatcher.SetLocales(server.tenantLocales.TenantNotificationLocale)
+	reportScheduler.SetLocales(server.tenantLocales.TenantNotificationLocale)
+
+	// 项目中间件与租户处理器共用成员存储，成员变更时清除缓存的有效角色
+	server.tenantHandler.SetProjectMembers(projectMembers)
+	// 临时角色授权的每次变更都写入审计日志
+	server.tenantHandler.OnRoleGrantChange(server.auditRoleGrant)
+
+	// 租户、项目和文档读接口的 ETag 与短时响应缓存，挂在各路由的鉴权之后；写操作通过事件总线使其失效
+	server.responseCache = middleware.NewResponseCache(server.responseScopes, server.events, logger)
+
+	server.profileHandler.SetProjectAccess(server.projectMiddleware.ViewableProjects)
+	server.authHandler.SetTokenSecret(cfg.JWTSecret)
+
+	server.ragHandler.SetBotConfig(cfg.Bots)
+	server.ragHandler.SetWidgetConfig(cfg.Widget)
+
+	// 原始文档、标识和导出归档保存在本地目录或 S3 兼容的对象存储中
+	if cfg.Blobs != nil {
+		blobs, err := blobstore.Open(cfg.Blobs)
+		if err != nil {
+			logger.Error("Failed to open blob store", zap.String("type", cfg.Blobs.Type), zap.Error(err))
+		} else {
+			server.blobs = blobs
+		}
+	}
+
+	// 原始文档和快照导出通过签名地址下载，未配置签名密钥时不启用
+	if server.blobs != nil {
+		server.ragHandler.SetDownloads(cfg.Downloads, server.blobs)
+	}
+
+	// 租户和项目标识上传后按标准尺寸保存在对象存储中
+	if server.blobs != nil {
+		server.tenantHandler.SetLogoStore(server.blobs)
+	}
+	server.ragHandler.SetBlobLimits(rag.BlobLimitsFromDB(db))
+
+	// 项目和上传文档的自定义元数据须符合租户定义的结构
+	if metadataSchemas != nil {
+		server.metadataHandler = handlers.NewMetadataHandler(metadataSchemas, logger)
+		server.tenantHandler.SetMetadataSchemas(metadataSchemas)
+		server.ragHandler.SetMetadataSchemas(rag.MetadataSchemasFromStore(db, metadataSchemas))
+		searchManager.SetMetadataSchemas(metadataSchemas)
 	}
 
+	// 项目发现只返回调用者可以查看的项目
+	server.searchHandler.SetProjectAccess(server.projectMiddleware.UserID, server.projectMiddleware.ViewableProjects)
+
+	// 就绪探针和系统配置接口报告组件状态与生效配置
+	server.registerComponents(searchManager, metadataSchemas != nil)
+
+	// 任务失败和预算用量作为告警指标
+	server.ragHandler.OnJobFailure(server.recordJobFailure)
+	server.ragHandler.OnQuery(server.recordQuery)
+	server.ragHandler.SetProjectAccess(server.projectMiddleware.ViewableProjects)
+	server.ragHandler.SetPermissionCheck(server.projectMiddleware.HasRAGPermission)
+	server.ragHandler.SetReadCache(server.responseCache.Middleware)
+	server.ragHandler.SetPublicProjects(rag.PublicProjectsFromDB(db))
+	server.ragHandler.SetStorageQuotas(rag.StorageQuotasFromDB(db))
+	server.ragHandler.SetRetentionDefaults(rag.RetentionDefaultsFromDB(db))
+	server.ragHandler.SetChatRetention(rag.ChatRetentionFromDB(db))
+	server.alertEngine.AddSource(alerts.SourceFunc(server.budgetSamples))
+
+	// 登录和 API 密钥调用交给异常检测，异常数作为告警指标
+	server.authHandler.OnLogin(server.observeLogin)
+	server.alertEngine.AddSource(alerts.SourceFunc(server.anomalyDetector.Samples))
+
 	return server, nil
 }
 
+// Features returns the feature flag service, for registering flags defined in code
+func (s *Server) Features() *features.Service {
+	return s.features
+}
+
+// Bootstrap creates the initial system admin of a fresh install; the schema,
+// system tenant and system project are created by NewServer
+func (s *Server) Bootstrap(ctx context.Context, admin onboarding.AdminInput) (*onboarding.BootstrapResult, error) {
+	return s.onboardingManager.Bootstrap(ctx, admin)
+}
+
+// SetRAGPipeline attaches a RAG pipeline to serve project queries.
+// The pipeline reads per-project overrides, tenant budgets, document versions,
+// soft-deleted documents and cold embedding tiers from the server's RAG store,
+// and scheduled data source syncs start running. Unless the pipeline uses
+// Redis locking, its background jobs are coordinated through locks in the
+// server's database, so it must be attached before the pipeline is started.
+func (s *Server) SetRAGPipeline(pipeline *core.Pipeline) {
+	if _, local := pipeline.Locker().(*core.LocalLocker); local {
+		locker, err := core.NewSQLLocker(context.Background(), s.db, "sqlite3")
+		if err != nil {
+			s.logger.Error("failed to create RAG job locker", zap.Error(err))
+		} else {
+			pipeline.SetLocker(locker)
+		}
+	}
+
+	pipeline.SetProjectConfigStore(s.ragManager)
+	pipeline.SetBudgetStore(s.ragManager)
+	pipeline.SetVersionStore(s.ragManager)
+	pipeline.SetSnapshotStore(s.ragManager)
+	pipeline.SetDocumentFailureStore(s.ragManager)
+	pipeline.SetGlossaryStore(s.ragManager)
+	pipeline.SetResidencyStore(s.ragManager)
+	if storage := pipeline.Config().Storage; storage.EnableEncryption {
+		kms, err := core.NewLocalKMS(storage.EncryptionKey)
+		if err != nil {
+			s.logger.Error("failed to enable RAG encryption", zap.Error(err))
+		} else {
+			pipeline.SetKeyRing(core.NewKeyRing(kms, s.ragManager, s.ragManager, core.LocalKMSKeyID), s.ragManager)
+		}
+	}
+	if err := pipeline.SetTombstoneStore(context.Background(), s.ragManager); err != nil {
+		s.logger.Error("failed to load deleted RAG documents", zap.Error(err))
+	}
+	if storage := pipeline.Config().Storage; storage.ColdAfter > 0 && storage.ColdDirectory != "" {
+		cold, err := core.NewFileColdStore(storage.ColdDirectory)
+		if err == nil {
+			err = pipeline.SetColdStore(context.Background(), cold, s.ragManager)
+		}
+		if err != nil {
+			s.logger.Error("failed to enable RAG cold storage", zap.Error(err))
+		}
+	}
+	s.ragHandler.SetPipeline(pipeline)
+	s.mcpServer.SetRetriever(pipeline)
+	s.ragHandler.StartSyncScheduler()
+
+	if metrics := pipeline.Config().Metrics; metrics.EnableAlerts {
+		if err := s.alertEngine.ApplyThresholds(context.Background(), metrics.AlertThresholds); err != nil {
+			s.logger.Error("failed to apply RAG alert thresholds", zap.Error(err))
+		}
+	}
+}
+
+// recordJobFailure counts a failed sync or ingest job against the project's tenant
+func (s *Server) recordJobFailure(ctx context.Context, projectID string) {
+	tenantID, err := s.projectMembers.ProjectTenantID(ctx, projectID)
+	if err != nil {
+		s.logger.Warn("failed to resolve tenant of failed job", zap.String("project_id", projectID), zap.Error(err))
+	}
+	s.alertRecorder.RecordJobFailure(tenantID)
+}
+
+// observeLogin passes a login attempt to anomaly detection; detection runs
+// after the response so a slow database never delays logins
+func (s *Server) observeLogin(r *http.Request, email string, user *handlers.UserInfo) {
+	event := anomaly.LoginEvent{
+		Username:  email,
+		IP:        middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Success:   user != nil,
+		Time:      time.Now(),
+	}
+	if user != nil {
+		event.UserID = user.ID
+		event.TenantID = user.TenantID
+	} else {
+		event.Reason = "invalid_credentials"
+	}
+	go func() {
+		if _, err := s.anomalyDetector.ObserveLogin(context.Background(), event); err != nil {
+			s.logger.Warn("failed to check login for anomalies", zap.String("username", email), zap.Error(err))
+		}
+	}()
+}
+
+// recordQuery counts a RAG query and its question for the project's tenant report
+func (s *Server) recordQuery(ctx context.Context, projectID, question string) {
+	tenantID, err := s.projectMembers.ProjectTenantID(ctx, projectID)
+	if err != nil {
+		return
+	}
+	s.reportUsage.RecordQuery(tenantID, question)
+}
+
+// auditRoleGrant records a temporary role grant change in the audit log
+func (s *Server) auditRoleGrant(ctx context.Context, action, actor string, grant *auth.RoleGrant) {
+	details := map[string]interface{}{
+		"grant_id": grant.ID,
+		"user_id":  grant.UserID,
+		"role":     grant.Role,
+		"status":   grant.Status,
+		"duration": grant.Duration.String(),
+	}
+	if grant.Reason != "" {
+		details["reason"] = grant.Reason
+	}
+	if grant.ExpiresAt != nil {
+		details["expires_at"] = grant.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	entry := &audit.Entry{
+		Actor:    actor,
+		Action:   action,
+		Resource: "projects/" + grant.ProjectID,
+		TenantID: grant.TenantID,
+		Details:  details,
+	}
+	if err := s.auditManager.Record(context.WithoutCancel(ctx), entry); err != nil {
+		s.logger.Error("failed to audit role grant", zap.String("grant_id", grant.ID), zap.Error(err))
+	}
+}
+
+// expireRoleGrants ends temporary role grants once their time is up. Roles
+// revert as soon as a grant expires; the sweep marks grants expired and
+// audits them.
+func (s *Server) expireRoleGrants(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := s.projectMembers.ExpireRoleGrants(ctx, now)
+			if err != nil {
+				s.logger.Error("failed to expire role grants", zap.Error(err))
+			}
+			for _, grant := range expired {
+				s.auditRoleGrant(ctx, handlers.RoleGrantExpired, "system", grant)
+			}
+		}
+	}
+}
+
+// budgetSamples reports the used fraction of every tenant budget
+func (s *Server) budgetSamples(ctx context.Context) ([]alerts.Sample, error) {
+	budgets, err := s.ragManager.ListBudgets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	samples := make([]alerts.Sample, 0, len(budgets))
+	for i := range budgets {
+		fraction, err := s.ragManager.BudgetUsageFraction(ctx, &budgets[i], now)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, alerts.Sample{Metric: alerts.MetricBudgetUsage, TenantID: budgets[i].TenantID, Value: fraction})
+	}
+	return samples, nil
+}
+
 // Start starts the API server
 func (s *Server) Start() error {
 	// 使用 chi 路由器
@@ -200,6 +701,15 @@ func (s *Server) Start() error {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	alertCtx, stopAlerts := context.WithCancel(context.Background())
+	s.stopAlerts = stopAlerts
+	go s.alertEngine.Run(alertCtx)
+	s.reportScheduler.Start()
+
+	grantCtx, stopGrantExpiry := context.WithCancel(context.Background())
+	s.stopGrantExpiry = stopGrantExpiry
+	go s.expireRoleGrants(grantCtx)
+
 	s.logger.Info("Starting API server",
 		zap.String("address", s.httpServer.Addr),
 	)
@@ -218,6 +728,15 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
+	s.ragHandler.StopSyncScheduler()
+	if s.stopAlerts != nil {
+		s.stopAlerts()
+	}
+	if s.stopGrantExpiry != nil {
+		s.stopGrantExpiry()
+	}
+	s.reportScheduler.Stop()
+
 	if s.httpServer != nil {
 		if err := s.httpServer.Shutdown(ctx); err != nil {
 			return err
@@ -236,19 +755,44 @@ func (s *Server) Stop(ctx context.Context) error {
 		}
 	}
 
-	if s.logger != nil {
-		s.logger.Sync()
+	if err := s.auditManager.Close(); err != nil {
+		s.logger.Error("Failed to close audit sinks", zap.Error(err))
+	}
+	if s.closeLogs != nil {
+		s.closeLogs()
 	}
 
 	return nil
 }
 
+// approvalOperations are the destructive operations that can be configured
+// to require a second admin's approval
+var approvalOperations = []approvals.Operation{
+	{Name: "tenant.delete", Method: http.MethodDelete, Path: "/admin/v1/tenants/*"},
+	{Name: "tenant.delete", Method: http.MethodDelete, Path: "/admin/tenants/*"},
+	{Name: "project.transfer_ownership", Method: http.MethodPost, Path: "/admin/v1/projects/*/transfer-ownership"},
+	{Name: "rag.force_reindex", Method: http.MethodPost, Path: "/admin/v1/projects/*/rag/datasources/*/sync",
+		When: func(r *http.Request) bool { return r.URL.Query().Get("force") == "true" }},
+}
+
+// maintenancePath is the route of the read-only maintenance switch
+const maintenancePath = "/admin/v1/maintenance"
+
 // setupRoutes configures API routes
 func (s *Server) setupRoutes(r chi.Router) {
 	// Health and system routes (no auth required)
 	r.Get("/health", s.systemHandler.Health)
 	r.Get("/ping", s.systemHandler.Ping)
 	r.Get("/version", s.systemHandler.Version)
+	r.Get("/readyz", s.systemHandler.Ready)
+
+	// Effective configuration and component status for operators (system admin only)
+	r.Route("/admin/v1/system", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+
+		r.Get("/config", s.systemHandler.Config)
+	})
 
 	// Authentication routes
 	r.Route("/auth", func(r chi.Router) {
@@ -262,12 +806,108 @@ func (s *Server) setupRoutes(r chi.Router) {
 		// Only system admins can manage tenants
 		r.Use(s.authMiddleware)
 		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		r.Use(s.responseCache.Middleware)
 
 		r.Get("/", s.tenantHandler.ListTenants)
 		r.Post("/", s.tenantHandler.CreateTenant)
+		r.Get("/by-slug/{slug}", s.tenantHandler.GetTenantBySlug)
 		r.Get("/{id}", s.tenantHandler.GetTenant)
 		r.Put("/{id}", s.tenantHandler.UpdateTenant)
 		r.Delete("/{id}", s.tenantHandler.DeleteTenant)
+		r.Put("/{id}/logo", s.tenantHandler.UploadTenantLogo)
+		r.Delete("/{id}/logo", s.tenantHandler.DeleteTenantLogo)
+	})
+
+	// Tenant onboarding: tenant, first admin, default project, roles and API key in one step (system admin only)
+	r.Route("/admin/v1/onboard", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.onboardingHandler.RegisterRoutes(r)
+	})
+
+	// Pending approvals of destructive operations; deciding requires a system admin
+	r.Route("/admin/v1/approvals", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		s.approvalHandler.RegisterRoutes(r)
+	})
+
+	// Read-only maintenance switch, exempt from read-only mode (system admin only)
+	r.Route(maintenancePath, func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.readOnlyHandler.RegisterRoutes(r)
+	})
+
+	// Profile and preferences of the current user
+	r.Route("/admin/v1/profile", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		s.profileHandler.RegisterRoutes(r)
+	})
+
+	// Audit log and chain verification (system admin only)
+	r.Route("/admin/v1/audit", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.auditHandler.RegisterRoutes(r)
+	})
+
+	// Authentication and API key anomalies, step-up requirements (system admin only)
+	r.Route("/admin/v1/anomalies", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.anomalyHandler.RegisterRoutes(r)
+	})
+
+	// Tenant and project search for the admin console (system admin only)
+	r.Route("/admin/v1/search", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.searchHandler.RegisterRoutes(r)
+	})
+
+	// Discovery across the projects the caller can view
+	r.Route("/admin/v1/discover", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		s.searchHandler.RegisterDiscoveryRoutes(r)
+	})
+
+	// Feature flag definitions (system admin only)
+	r.Route("/admin/v1/features", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.featureHandler.RegisterRoutes(r)
+	})
+
+	// Alert rules, silences and the notification center (system admin only)
+	r.Route("/admin/v1/alerts", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.alertHandler.RegisterRoutes(r)
+	})
+	r.Route("/admin/v1/notifications", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.alertHandler.RegisterNotificationRoutes(r)
+	})
+
+	// Query analytics across projects (system admin only)
+	r.Route("/admin/v1/rag", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.ragHandler.RegisterAdminRoutes(r)
+	})
+
+	// Background jobs: queues, workers, failures and the dead-letter queue
+	r.Route("/admin/v1/jobs", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.SystemAdminMiddleware)
+		s.ragHandler.RegisterJobRoutes(r)
+	})
+
+	// Queries across the projects the caller can view
+	r.Route("/admin/v1/federation", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		s.ragHandler.RegisterFederationRoutes(r)
 	})
 
 	// Project management routes (project-centric)
@@ -275,6 +915,7 @@ func (s *Server) setupRoutes(r chi.Router) {
 		// List projects for current user
 		r.Group(func(r chi.Router) {
 			r.Use(s.authMiddleware)
+			r.Use(s.responseCache.Middleware)
 			r.Get("/", s.tenantHandler.ListUserProjects) // User's projects across all tenants
 		})
 
@@ -284,7 +925,15 @@ func (s *Server) setupRoutes(r chi.Router) {
 			r.Group(func(r chi.Router) {
 				r.Use(s.authMiddleware)
 				r.Use(s.projectMiddleware.ProjectViewerMiddleware)
-				r.Get("/", s.tenantHandler.GetProject)
+				r.With(s.responseCache.Middleware).Get("/", s.tenantHandler.GetProject)
+				// RAG routes are gated by per-role RAG permissions
+				s.ragHandler.RegisterReadRoutes(r)
+				s.ragHandler.RegisterWriteRoutes(r)
+
+				// The new owner accepts or declines an ownership transfer
+				r.Get("/transfer-ownership", s.tenantHandler.ListOwnershipTransfers)
+				r.Post("/transfer-ownership/{transferId}/accept", s.tenantHandler.AcceptOwnershipTransfer)
+				r.Post("/transfer-ownership/{transferId}/decline", s.tenantHandler.DeclineOwnershipTransfer)
 			})
 
 			// Update project requires owner access
@@ -292,6 +941,21 @@ func (s *Server) setupRoutes(r chi.Router) {
 				r.Use(s.authMiddleware)
 				r.Use(s.projectMiddleware.ProjectOwnerMiddleware)
 				r.Put("/", s.tenantHandler.UpdateProject)
+				r.Put("/logo", s.tenantHandler.UploadProjectLogo)
+				r.Delete("/logo", s.tenantHandler.DeleteProjectLogo)
+				r.Get("/rag-permissions", s.tenantHandler.GetRAGPermissions)
+				r.Put("/rag-permissions", s.tenantHandler.UpdateRAGPermissions)
+
+				// Temporary elevated roles
+				r.Get("/role-grants", s.tenantHandler.ListRoleGrants)
+				r.Post("/role-grants", s.tenantHandler.GrantRole)
+				r.Post("/role-grants/{grantId}/approve", s.tenantHandler.ApproveRoleGrant)
+				r.Post("/role-grants/{grantId}/reject", s.tenantHandler.RejectRoleGrant)
+				r.Delete("/role-grants/{grantId}", s.tenantHandler.RevokeRoleGrant)
+
+				// Ownership transfers wait for the new owner to accept them
+				r.Post("/transfer-ownership", s.tenantHandler.TransferOwnership)
+				r.Delete("/transfer-ownership/{transferId}", s.tenantHandler.CancelOwnershipTransfer)
 			})
 
 			// Delete project requires owner access
@@ -305,6 +969,7 @@ func (s *Server) setupRoutes(r chi.Router) {
 			r.Group(func(r chi.Router) {
 				r.Use(s.authMiddleware)
 				r.Use(s.projectMiddleware.CanManageProjectMiddleware)
+				r.Use(s.responseCache.Middleware)
 
 				// Invite user to project (supports cross-tenant collaboration)
 				r.Post("/invite", s.tenantHandler.InviteUserToProject)
@@ -314,9 +979,6 @@ func (s *Server) setupRoutes(r chi.Router) {
 
 				// Remove user from project
 				r.Delete("/members/{userId}", s.tenantHandler.RemoveUserFromProject)
-
-				// Transfer ownership
-				r.Post("/transfer-ownership", s.tenantHandler.TransferOwnership)
 			})
 		})
 	})
@@ -326,7 +988,40 @@ func (s *Server) setupRoutes(r chi.Router) {
 		r.Use(s.authMiddleware)
 		// User must have access to the tenant to create projects
 		r.Use(s.projectMiddleware.TenantAccessMiddleware)
+		r.Use(s.responseCache.Middleware)
+		r.Get("/", s.tenantHandler.ListProjects)
 		r.Post("/", s.tenantHandler.CreateProject)
+		r.Get("/by-slug/{slug}", s.tenantHandler.GetProjectBySlug)
+	})
+
+	// Tenant metadata schemas for custom project and document metadata
+	if s.metadataHandler != nil {
+		r.Route("/admin/v1/tenants/{tenantId}/metadata-schemas", func(r chi.Router) {
+			r.Use(s.authMiddleware)
+			r.Use(s.projectMiddleware.TenantAccessMiddleware)
+			s.metadataHandler.RegisterRoutes(r)
+		})
+	}
+
+	// Tenant RAG budget routes
+	r.Route("/admin/v1/tenants/{tenantId}/rag", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.TenantAccessMiddleware)
+		s.ragHandler.RegisterTenantRoutes(r)
+	})
+
+	// Tenant usage and quality reports
+	r.Route("/admin/v1/tenants/{tenantId}/reports", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.TenantAccessMiddleware)
+		s.reportHandler.RegisterRoutes(r)
+	})
+
+	// Tenant feature flag evaluation
+	r.Route("/admin/v1/tenants/{tenantId}/features", func(r chi.Router) {
+		r.Use(s.authMiddleware)
+		r.Use(s.projectMiddleware.TenantAccessMiddleware)
+		s.featureHandler.RegisterTenantRoutes(r)
 	})
 
 	// API Key management routes (requires auth)
@@ -335,6 +1030,33 @@ func (s *Server) setupRoutes(r chi.Router) {
 		s.keyHandler.RegisterRoutes(r)
 	})
 
+	// Slack and Discord bot callbacks, authenticated by platform signatures
+	r.Route("/integrations", s.ragHandler.RegisterBotRoutes)
+
+	// Browser widget queries, authenticated with publishable widget tokens
+	r.Route("/public/v1/rag", s.ragHandler.RegisterWidgetRoutes)
+
+	// Original documents and snapshot exports, authenticated by URL signatures
+	r.Route(rag.DownloadsPath, s.ragHandler.RegisterDownloadRoutes)
+
+	// Tenant and project logos, addressed by content hash
+	r.Get(handlers.LogosPath+"/*", s.tenantHandler.ServeLogo)
+
+	// Anonymous read-only access to public projects of tenants that opted in
+	r.Route("/public/v1/projects/{projectId}", s.ragHandler.RegisterPublicRoutes)
+
+	// MCP server over SSE, authenticated with project API keys
+	r.Mount("/mcp", s.mcpServer.Handler())
+
+	// Profiling and runtime metrics (system admin only, off unless enabled)
+	if s.config.EnableProfiling {
+		r.Route("/debug", func(r chi.Router) {
+			r.Use(s.authMiddleware)
+			r.Use(s.projectMiddleware.SystemAdminMiddleware)
+			handlers.NewDebugHandler(s.logger).RegisterRoutes(r)
+		})
+	}
+
 	// Log management routes (requires auth)
 	r.Route("/admin/logs", func(r chi.Router) {
 		r.Use(s.authMiddleware)
@@ -378,27 +1100,173 @@ func (s *Server) setupRoutes(r chi.Router) {
 
 // withMiddleware applies global middleware
 func (s *Server) withMiddleware(handler http.Handler) http.Handler {
-	return s.corsMiddleware(s.logMiddleware.Middleware(s.logMiddleware.ComponentMiddleware("api")(handler)))
+	flags := middleware.FeatureFlags(s.features, s.requestTenant, s.logger)
+	record := s.alertRecorder.Middleware("/auth/login", "/auth/refresh")
+	usage := s.reportUsage.Middleware(s.requestTenant)
+	readOnly := s.readOnlyMode.Middleware(maintenancePath)
+	profiles := s.profileManager.Middleware(s.projectMiddleware.UserID)
+	locales := s.tenantLocales.Middleware(func(r *http.Request) string { return profile.Locale(r.Context()) })
+	auditLog := s.auditManager.Middleware(s.projectMiddleware.UserID, s.requestTenant, "/admin/", "/auth/")
+	stepUp := s.anomalyDetector.StepUpMiddleware(s.projectMiddleware.UserID, "/auth/", "/health")
+	return record(usage(s.tenantCORS.Middleware(s.logMiddleware.Middleware(s.logMiddleware.ComponentMiddleware("api")(auditLog(profiles(locales(stepUp(readOnly(flags(s.responseCache.InvalidationMiddleware(handler))))))))))))
 }
 
-// corsMiddleware handles CORS
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, apikey")
+// inviteLocale resolves the locale of an invitation email from the
+// invitee's profile and the project's tenant
+func (s *Server) inviteLocale(ctx context.Context, userID, tenantID string) string {
+	var userLocale string
+	if userID != "" {
+		if p, err := s.profileManager.Cached(ctx, userID); err == nil {
+			userLocale = p.Locale
+		}
+	}
+	return s.tenantLocales.Locale(ctx, userLocale, tenantID)
+}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+// cachedReads are the tenant, project and document reads served from the
+// response cache, relative to /admin/v1; "*" matches one path segment. Status
+// endpoints that clients poll and other reads are never cached.
+var cachedReads = []string{
+	"tenants",
+	"tenants/*",
+	"tenants/by-slug/*",
+	"tenants/*/projects",
+	"tenants/*/projects/by-slug/*",
+	"projects",
+	"projects/*",
+	"projects/*/members",
+	"projects/*/documents",
+	"projects/*/documents/*/chunks",
+}
+
+// cachedRead reports whether a GET of the path segments after /admin/v1 is
+// in cachedReads
+func cachedRead(parts []string) bool {
+	for _, pattern := range cachedReads {
+		segments := strings.Split(pattern, "/")
+		if len(segments) != len(parts) {
+			continue
 		}
+		matched := true
+		for i, segment := range segments {
+			if segment != "*" && segment != parts[i] || parts[i] == "" {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
 
-		next.ServeHTTP(w, r)
+// responseScopes resolves the response cache scopes of tenant and project
+// routes. Writes also touch the tenant or project listings; reads outside
+// cachedReads have no scopes and bypass the cache.
+func (s *Server) responseScopes(r *http.Request) []string {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "admin" || parts[1] != "v1" {
+		return nil
+	}
+	if r.Method == http.MethodGet && !cachedRead(parts[2:]) {
+		return nil
+	}
+	var scopes []string
+	switch parts[2] {
+	case "tenants":
+		// Slug lookups do not know the tenant ID, so any tenant write invalidates them
+		bySlug := len(parts) > 3 && parts[3] == "by-slug"
+		if r.Method != http.MethodGet || len(parts) == 3 || bySlug {
+			scopes = append(scopes, "tenants")
+		}
+		if len(parts) > 3 && parts[3] != "" && !bySlug {
+			scopes = append(scopes, "tenant:"+parts[3])
+		}
+	case "projects":
+		if r.Method != http.MethodGet || len(parts) == 3 {
+			scopes = append(scopes, "projects")
+		}
+		if len(parts) > 3 && parts[3] != "" {
+			scopes = append(scopes, "project:"+parts[3])
+			if tenantID, err := s.projectMembers.ProjectTenantID(r.Context(), parts[3]); err == nil && tenantID != "" {
+				scopes = append(scopes, "tenant:"+tenantID)
+			}
+		}
+	}
+	return scopes
+}
+
+// registerComponents registers the components reported by /readyz and
+// /admin/v1/system/config, and the configuration the latter serves
+func (s *Server) registerComponents(searchManager *search.Manager, metadataSchemas bool) {
+	s.systemHandler.AddComponent("database", true, func(ctx context.Context) (string, string) {
+		if err := s.db.PingContext(ctx); err != nil {
+			return handlers.ComponentDown, err.Error()
+		}
+		return handlers.ComponentOK, ""
+	})
+	s.systemHandler.AddComponent("search", false, func(ctx context.Context) (string, string) {
+		if !searchManager.FullText() {
+			return handlers.ComponentDegraded, "full-text index unavailable, using LIKE matching"
+		}
+		return handlers.ComponentOK, ""
 	})
+	s.systemHandler.AddComponent("metadata_schemas", false, func(ctx context.Context) (string, string) {
+		if !metadataSchemas {
+			return handlers.ComponentDown, "schema store failed to initialize; metadata is not validated"
+		}
+		return handlers.ComponentOK, ""
+	})
+	s.systemHandler.AddComponent("blobstore", false, func(ctx context.Context) (string, string) {
+		switch {
+		case s.config.Blobs == nil:
+			return handlers.ComponentDisabled, ""
+		case s.blobs == nil:
+			return handlers.ComponentDown, "failed to open " + s.config.Blobs.Type + " blob store"
+		}
+		return handlers.ComponentOK, ""
+	})
+	s.systemHandler.AddComponent("maintenance", false, func(ctx context.Context) (string, string) {
+		if state := s.readOnlyMode.Current(ctx); state.ReadOnly {
+			return handlers.ComponentDegraded, "read-only: " + state.Reason
+		}
+		return handlers.ComponentOK, ""
+	})
+
+	s.systemHandler.SetConfig(func() map[string]interface{} {
+		sections := map[string]interface{}{"server": s.config}
+		if cfg := config.Get(); cfg != nil {
+			sections["app"] = cfg.GetAppConfig()
+		}
+		return sections
+	})
+}
+
+// requestTenant resolves the tenant of tenant and project routes for CORS,
+// feature flags and usage reports; other routes use the defaults
+func (s *Server) requestTenant(r *http.Request) string {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "admin" || parts[1] != "v1" || parts[3] == "" {
+		return ""
+	}
+	switch parts[2] {
+	case "tenants":
+		return parts[3]
+	case "projects":
+		tenantID, err := s.projectMembers.ProjectTenantID(r.Context(), parts[3])
+		if err != nil {
+			return ""
+		}
+		return tenantID
+	}
+	return ""
 }
 
 // authMiddleware handles authentication using JWT
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	// Approvals run after authentication, on the verified identity
+	approve := s.approvalManager.Middleware(authenticatedUser, s.requestTenant)(next)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
@@ -421,7 +1289,12 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		// Signed tokens carry the identity that approvals are recorded under
+		ctx := r.Context()
+		if userID, err := auth.ValidateToken(token, s.config.JWTSecret); err == nil && userID != "" {
+			ctx = context.WithValue(ctx, userIDKey, userID)
+		}
+		approve.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -447,12 +1320,34 @@ func (s *Server) apiKeyMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		event := anomaly.KeyEvent{KeyID: validKey.ID, IP: middleware.ClientIP(r)}
+		if validKey.TenantID != nil {
+			event.TenantID = *validKey.TenantID
+		}
+		s.anomalyDetector.ObserveKey(context.WithoutCancel(r.Context()), event)
+
 		// Add API key to context
 		ctx := context.WithValue(r.Context(), "apiKey", validKey.ToRestAPIKey())
+		if validKey.UserID != nil && *validKey.UserID != "" {
+			ctx = context.WithValue(ctx, userIDKey, *validKey.UserID)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// identityKey is the context key of the authenticated user
+type identityKey struct{}
+
+var userIDKey = identityKey{}
+
+// authenticatedUser returns the user verified by authMiddleware from a signed
+// access token, or the owner of the API key verified by apiKeyMiddleware; ""
+// when the request carries no verified identity
+func authenticatedUser(r *http.Request) string {
+	userID, _ := r.Context().Value(userIDKey).(string)
+	return userID
+}
+
 // responseWriter wrapper to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+func TestSlugValidationAndRedirects(t *testing.T) {
+	for name, want := range map[string]string{
+		"Acme Corp":        "acme-corp",
+		"  Bob's  Café!! ": "bobs-caf",
+		"R&D / Team 42":    "r-d-team-42",
+		"项目":               "",
+		"--already-slug--": "already-slug",
+	} {
+		if got := auth.NormalizeSlug(name); got != want {
+			t.Errorf("NormalizeSlug(%q) = %q, want %q", name, got, want)
+		}
+	}
+	for _, slug := range []string{"a", "Acme", "acme--corp", "-acme", "acme_corp", "admin", "api"} {
+		if auth.ValidateSlug(slug) == nil {
+			t.Errorf("expected %q to be rejected", slug)
+		}
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	server, ts := startInstance(t, filepath.Join(dir, "metabase.db"))
+	if _, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`); err != nil {
+		t.Fatalf("failed to create tenant: %v", err)
+	}
+	projectsURL := ts.URL + "/admin/v1/tenants/t1/projects"
+
+	// Slugs are derived from names and kept unique within the tenant
+	if status, body := doJSON(t, http.MethodPost, projectsURL, map[string]string{"name": "Search Docs"}); status != http.StatusOK || body["slug"] != "search-docs" {
+		t.Fatalf("create: status %d, body %v", status, body)
+	}
+	if status, _ := doJSON(t, http.MethodPost, projectsURL, map[string]string{"name": "Other", "slug": "search-docs"}); status != http.StatusConflict {
+		t.Fatalf("expected duplicate slug to conflict, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodPost, projectsURL, map[string]string{"name": "Other", "slug": "Not A Slug"}); status != http.StatusBadRequest {
+		t.Fatalf("expected invalid slug to be rejected, got %d", status)
+	}
+
+	// Renamed slugs redirect to the current one and stay reserved
+	var projectID string
+	if err := server.db.QueryRow(`SELECT id FROM projects WHERE slug = 'search-docs'`).Scan(&projectID); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := doJSON(t, http.MethodPut, ts.URL+"/admin/v1/projects/"+projectID, map[string]string{"slug": "docs-search"}); status != http.StatusOK || body["slug"] != "docs-search" {
+		t.Fatalf("rename: status %d, body %v", status, body)
+	}
+	if status, body := doJSON(t, http.MethodGet, projectsURL+"/by-slug/search-docs", nil); status != http.StatusOK || body["id"] != projectID || body["slug"] != "docs-search" {
+		t.Fatalf("expected old slug to redirect, got %d %v", status, body)
+	}
+	req, _ := http.NewRequest(http.MethodGet, projectsURL+"/by-slug/search-docs", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != "/admin/v1/tenants/t1/projects/by-slug/docs-search" {
+		t.Fatalf("expected permanent redirect, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if status, _ := doJSON(t, http.MethodPost, projectsURL, map[string]string{"name": "Other", "slug": "search-docs"}); status != http.StatusConflict {
+		t.Fatalf("expected redirecting slug to stay taken, got %d", status)
+	}
+	if status, _ := doJSON(t, http.MethodGet, projectsURL+"/by-slug/missing", nil); status != http.StatusNotFound {
+		t.Fatalf("expected unknown slug to be missing, got %d", status)
+	}
+}
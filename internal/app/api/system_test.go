@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadinessAndSystemConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	dbPath := filepath.Join(dir, "metabase.db")
+	server, ts := startInstance(t, dbPath)
+
+	status, body := doJSON(t, http.MethodGet, ts.URL+"/readyz", nil)
+	components, _ := body["components"].(map[string]interface{})
+	if status != http.StatusOK || body["status"] != "ready" || components["database"] != "ok" || components["blobstore"] != "disabled" {
+		t.Fatalf("expected a ready server, got %d %v", status, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/admin/v1/system/config", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected the config endpoint to require authentication, got %d", resp.StatusCode)
+	}
+	status, body = doJSON(t, http.MethodGet, ts.URL+"/admin/v1/system/config", nil)
+	config, _ := body["config"].(map[string]interface{})
+	serverConfig, _ := config["server"].(map[string]interface{})
+	if status != http.StatusOK || serverConfig["database_path"] != dbPath {
+		t.Fatalf("expected the effective server config, got %d %v", status, body)
+	}
+	statuses, _ := body["components"].([]interface{})
+	if len(statuses) != len(components) {
+		t.Fatalf("expected %d component statuses, got %v", len(components), body["components"])
+	}
+
+	// A critical component that is down fails the readiness probe
+	server.db.Close()
+	status, body = doJSON(t, http.MethodGet, ts.URL+"/readyz", nil)
+	if components, _ := body["components"].(map[string]interface{}); status != http.StatusServiceUnavailable || components["database"] != "down" {
+		t.Fatalf("expected an unready server, got %d %v", status, body)
+	}
+}
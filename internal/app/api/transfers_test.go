@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/common/errors"
+	"github.com/guileen/metabase/pkg/infra/auth"
+)
+
+func TestOwnershipTransferRequiresAcceptance(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	server, ts := startInstance(t, filepath.Join(dir, "metabase.db"))
+
+	_, err := server.db.Exec(`INSERT INTO tenants (id, name, slug) VALUES ('t1', 'Tenant', 't1')`)
+	if err == nil {
+		_, err = server.db.Exec(`INSERT INTO projects (id, tenant_id, name, slug, owner_id) VALUES ('p1', 't1', 'One', 'p1', 'system_admin')`)
+	}
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	members := server.projectMembers
+	if err := members.AddUserToProject(ctx, "system_admin", "p1", auth.ProjectRoleOwner, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := members.AddUserToProject(ctx, "u2", "p1", auth.ProjectRoleViewer, "system_admin"); err != nil {
+		t.Fatal(err)
+	}
+	role := func(userID string) string {
+		t.Helper()
+		member, err := members.UserProjectRole(ctx, userID, "p1")
+		if err != nil {
+			return ""
+		}
+		return member.Role
+	}
+	transfersURL := ts.URL + "/admin/v1/projects/p1/transfer-ownership"
+
+	// Only project members can be offered ownership
+	if status, _ := doJSON(t, http.MethodPost, transfersURL, map[string]string{"to_user_id": "u3"}); status != http.StatusBadRequest {
+		t.Fatalf("expected non-member to be refused, got %d", status)
+	}
+
+	// Requesting a transfer changes nothing until it is accepted
+	status, transfer := doJSON(t, http.MethodPost, transfersURL, map[string]string{"to_user_id": "u2", "message": "over to you"})
+	if status != http.StatusAccepted || transfer["status"] != auth.TransferStatusPending {
+		t.Fatalf("transfer: status %d, body %v", status, transfer)
+	}
+	if got := role("u2"); got != auth.ProjectRoleViewer {
+		t.Fatalf("expected role to be unchanged before acceptance, got %q", got)
+	}
+	if status, _ := doJSON(t, http.MethodPost, transfersURL, map[string]string{"to_user_id": "u2"}); status != http.StatusConflict {
+		t.Fatalf("expected a second pending transfer to be refused, got %d", status)
+	}
+
+	// Only the target can accept; cancelled transfers cannot be accepted
+	id := transfer["id"].(string)
+	if _, err := members.AcceptOwnershipTransfer(ctx, "p1", id, "u3"); errors.GetCode(err) != errors.ErrCodeForbidden {
+		t.Fatalf("expected another user to be refused, got %v", err)
+	}
+	if status, _ := doJSON(t, http.MethodDelete, transfersURL+"/"+id, nil); status != http.StatusOK {
+		t.Fatalf("cancel: status %d", status)
+	}
+	if _, err := members.AcceptOwnershipTransfer(ctx, "p1", id, "u2"); errors.GetCode(err) != errors.ErrCodeConflict {
+		t.Fatalf("expected cancelled transfer to stay cancelled, got %v", err)
+	}
+
+	// Accepting makes the target an owner and demotes the previous owner
+	pending, err := members.RequestOwnershipTransfer(ctx, "p1", "system_admin", "u2", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := members.AcceptOwnershipTransfer(ctx, "p1", pending.ID, "u2")
+	if err != nil || accepted.Status != auth.TransferStatusAccepted {
+		t.Fatalf("accept: %v %v", accepted, err)
+	}
+	if got := role("u2"); got != auth.ProjectRoleOwner {
+		t.Fatalf("expected new owner, got %q", got)
+	}
+	if got := role("system_admin"); got != auth.ProjectRoleCollaborator {
+		t.Fatalf("expected previous owner to become a collaborator, got %q", got)
+	}
+
+	// Pending transfers lapse after their expiry
+	if _, err := server.db.Exec(`UPDATE project_ownership_transfers SET status = 'pending', expires_at = '2000-01-01 00:00:00' WHERE id = ?`, id); err != nil {
+		t.Fatal(err)
+	}
+	transfers, err := members.ListOwnershipTransfers(ctx, "p1")
+	if err != nil || len(transfers) != 2 || transfers[1].Status != auth.TransferStatusExpired {
+		t.Fatalf("expected the reopened transfer to be expired, got %v %v", transfers, err)
+	}
+}
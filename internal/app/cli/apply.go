@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/guileen/metabase/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "按声明式配置同步租户和项目",
+	Long: `读取 YAML 配置文件，将租户、项目、成员、数据源和 RAG 设置同步到 API 服务器。
+
+执行前先输出计划 (+ 创建, ~ 更新, - 删除)，确认后才会应用。
+租户和项目按 slug 匹配，成员按用户 ID 匹配，数据源按名称匹配。
+未填写的字段保持不变；settings、metadata 和 rag_settings 只管理列出的键。
+--prune 会删除已声明项目中未列出的成员和数据源，租户和项目永远不会被删除。
+
+示例:
+  metabase apply -f metabase.yaml --plan
+  metabase apply -f metabase.yaml --auto-approve`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		apiKey, _ := cmd.Flags().GetString("apikey")
+		planOnly, _ := cmd.Flags().GetBool("plan")
+		prune, _ := cmd.Flags().GetBool("prune")
+		autoApprove, _ := cmd.Flags().GetBool("auto-approve")
+		if file == "" {
+			return fmt.Errorf("请使用 -f 指定配置文件")
+		}
+		if token == "" {
+			token = os.Getenv("METABASE_TOKEN")
+		}
+		if apiKey == "" {
+			apiKey = os.Getenv("METABASE_API_KEY")
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("读取配置文件失败: %w", err)
+		}
+		manifest, err := client.ParseManifest(data)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		c := client.New(&client.Config{URL: strings.TrimRight(server, "/"), AccessToken: token, APIKey: apiKey})
+		plan, err := c.PlanManifest(ctx, manifest, &client.PlanOptions{Prune: prune})
+		if err != nil {
+			return fmt.Errorf("生成计划失败: %w", err)
+		}
+		fmt.Print(plan.String())
+		if plan.Empty() || planOnly {
+			return nil
+		}
+
+		if !autoApprove {
+			fmt.Print("\n确认应用以上变更? 输入 yes 继续: ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if strings.TrimSpace(answer) != "yes" {
+				fmt.Println("已取消")
+				return nil
+			}
+		}
+		if err := c.Apply(ctx, plan); err != nil {
+			return fmt.Errorf("应用失败: %w", err)
+		}
+		fmt.Printf("✅ 已应用 %d 项变更\n", len(plan.Changes))
+		return nil
+	},
+}
+
+func init() {
+	applyCmd.Flags().StringP("file", "f", "", "声明式配置文件 (YAML)")
+	applyCmd.Flags().StringP("server", "s", "http://localhost:7610", "API服务器地址")
+	applyCmd.Flags().StringP("token", "t", "", "访问令牌 (默认读取 METABASE_TOKEN)")
+	applyCmd.Flags().String("apikey", "", "API 密钥 (默认读取 METABASE_API_KEY)")
+	applyCmd.Flags().Bool("plan", false, "只输出计划，不应用")
+	applyCmd.Flags().Bool("prune", false, "删除已声明项目中未列出的成员和数据源")
+	applyCmd.Flags().Bool("auto-approve", false, "跳过确认直接应用")
+
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+	"github.com/spf13/cobra"
+)
+
+// benchUploadBatch is the number of documents sent per upload request
+const benchUploadBatch = 50
+
+var benchmarkRAGCmd = &cobra.Command{
+	Use:   "rag",
+	Short: "Load test the RAG indexing and query path",
+	Long: `Generate a synthetic corpus, index it through a running API server and
+query it at a fixed rate with a mix of query kinds.
+
+Reports indexing throughput, query latency percentiles, throughput, dropped
+and failed queries, cache hit rate and server memory. Memory is read from
+/debug/runtime and needs server.enable_profiling and a system admin token.
+
+Query kinds:
+  - keyword: a term found in a single document
+  - phrase: several words of a topic, matched by many documents
+  - repeat: one of a few hot queries, exercising the query cache
+  - miss: terms no document contains
+
+Examples:
+  metabase bench rag --project demo                       # 200 docs, 10 QPS for 30s
+  metabase bench rag --project demo --docs 2000 --qps 50 --duration 2m
+  metabase bench rag --project demo --mix keyword=1,repeat=3
+  metabase bench rag --project demo --skip-index --json report.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server, _ := cmd.Flags().GetString("server")
+		project, _ := cmd.Flags().GetString("project")
+		token, _ := cmd.Flags().GetString("token")
+		mix, _ := cmd.Flags().GetString("mix")
+		jsonPath, _ := cmd.Flags().GetString("json")
+		if project == "" {
+			return fmt.Errorf("--project is required")
+		}
+		if token == "" {
+			token = os.Getenv("METABASE_ADMIN_TOKEN")
+		}
+
+		options := core.DefaultLoadTestOptions()
+		options.Corpus.Documents, _ = cmd.Flags().GetInt("docs")
+		options.Corpus.WordsPerDocument, _ = cmd.Flags().GetInt("words")
+		options.Corpus.Topics, _ = cmd.Flags().GetInt("topics")
+		options.Corpus.Seed, _ = cmd.Flags().GetInt64("seed")
+		options.QPS, _ = cmd.Flags().GetFloat64("qps")
+		options.Duration, _ = cmd.Flags().GetDuration("duration")
+		options.Concurrency, _ = cmd.Flags().GetInt("concurrency")
+		options.SkipIndex, _ = cmd.Flags().GetBool("skip-index")
+		if mix != "" {
+			parsed, err := parseQueryMix(mix)
+			if err != nil {
+				return err
+			}
+			options.Mix = parsed
+		}
+
+		target := &httpLoadTarget{
+			server:  strings.TrimRight(server, "/"),
+			project: project,
+			token:   token,
+			client:  &http.Client{Timeout: time.Minute},
+		}
+		fmt.Printf("🚀 RAG load test: %d docs, %.1f QPS for %s with %d workers\n",
+			options.Corpus.Documents, options.QPS, options.Duration, options.Concurrency)
+
+		report, err := core.RunLoadTest(cmd.Context(), target, options)
+		if err != nil {
+			return err
+		}
+		printLoadTestReport(report)
+
+		if jsonPath != "" {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(jsonPath, data, 0644); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+			fmt.Printf("\n📄 Report saved to %s\n", jsonPath)
+		}
+		return nil
+	},
+}
+
+// parseQueryMix parses weights such as "keyword=3,phrase=4,repeat=2,miss=1"
+func parseQueryMix(value string) (core.QueryMix, error) {
+	var mix core.QueryMix
+	for _, part := range strings.Split(value, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		var w float64
+		if ok {
+			_, err := fmt.Sscanf(weight, "%g", &w)
+			ok = err == nil
+		}
+		if !ok {
+			return mix, fmt.Errorf("invalid mix entry %q, expected kind=weight", part)
+		}
+		switch name {
+		case core.LoadQueryKeyword:
+			mix.Keyword = w
+		case core.LoadQueryPhrase:
+			mix.Phrase = w
+		case core.LoadQueryRepeat:
+			mix.Repeat = w
+		case core.LoadQueryMiss:
+			mix.Miss = w
+		default:
+			return mix, fmt.Errorf("unknown query kind %q", name)
+		}
+	}
+	return mix, nil
+}
+
+// httpLoadTarget load tests a project through the admin API
+type httpLoadTarget struct {
+	server  string
+	project string
+	token   string
+	client  *http.Client
+}
+
+// Index uploads the documents in batches and waits for their ingest jobs
+func (t *httpLoadTarget) Index(ctx context.Context, docs []core.Document) error {
+	var jobIDs []string
+	for start := 0; start < len(docs); start += benchUploadBatch {
+		end := start + benchUploadBatch
+		if end > len(docs) {
+			end = len(docs)
+		}
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		form.WriteField("tags", "bench")
+		for _, doc := range docs[start:end] {
+			part, err := form.CreateFormFile("file", doc.URI)
+			if err != nil {
+				return err
+			}
+			io.WriteString(part, doc.Content)
+		}
+		form.Close()
+
+		var jobs []struct {
+			ID string `json:"id"`
+		}
+		if err := t.do(ctx, http.MethodPost, t.projectPath("/documents"), form.FormDataContentType(), &body, &jobs); err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			jobIDs = append(jobIDs, job.ID)
+		}
+		fmt.Printf("  uploaded %d/%d documents\r", end, len(docs))
+	}
+	fmt.Println()
+
+	failed := 0
+	for _, id := range jobIDs {
+		for {
+			var job struct {
+				Status string `json:"status"`
+			}
+			if err := t.do(ctx, http.MethodGet, t.projectPath("/documents/jobs/"+id), "", nil, &job); err != nil {
+				return err
+			}
+			if job.Status != "processing" {
+				if job.Status == "failed" {
+					failed++
+				}
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("⚠️  %d of %d documents failed to index\n", failed, len(jobIDs))
+	}
+	return nil
+}
+
+// Query runs a RAG query and reads whether it was served from cache
+func (t *httpLoadTarget) Query(ctx context.Context, query string) (bool, error) {
+	body, _ := json.Marshal(map[string]interface{}{"query": query})
+	var result struct {
+		CacheHit bool `json:"cache_hit"`
+	}
+	err := t.do(ctx, http.MethodPost, t.projectPath("/rag/query"), "application/json", bytes.NewReader(body), &result)
+	return result.CacheHit, err
+}
+
+// SystemMetrics reads the server's memory, nil when profiling is disabled
+func (t *httpLoadTarget) SystemMetrics(ctx context.Context) (*core.SystemMetrics, error) {
+	var runtime struct {
+		System core.SystemMetrics `json:"system"`
+	}
+	if err := t.do(ctx, http.MethodGet, "/debug/runtime", "", nil, &runtime); err != nil {
+		return nil, err
+	}
+	return &runtime.System, nil
+}
+
+func (t *httpLoadTarget) projectPath(path string) string {
+	return "/admin/v1/projects/" + t.project + path
+}
+
+// do sends a request and decodes the response's data field into out,
+// or the whole body for responses without one
+func (t *httpLoadTarget) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, t.server+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && len(envelope.Data) > 0 {
+		data = envelope.Data
+	}
+	return json.Unmarshal(data, out)
+}
+
+// printLoadTestReport prints a load test report
+func printLoadTestReport(report *core.LoadTestReport) {
+	ms := func(d time.Duration) string {
+		return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000)
+	}
+
+	fmt.Printf("\n📊 Results (%s)\n", report.Elapsed.Round(time.Millisecond))
+	if report.IndexTime > 0 {
+		fmt.Printf("Indexing:     %d docs in %s (%.1f docs/s)\n",
+			report.Documents, report.IndexTime.Round(time.Millisecond), report.IndexThroughput)
+	}
+	fmt.Printf("Queries:      %d sent, %d completed, %d failed, %d dropped\n",
+		report.Sent, report.Completed, report.Errors, report.Dropped)
+	fmt.Printf("Throughput:   %.2f queries/s\n", report.Throughput)
+	fmt.Printf("Cache hits:   %d (%.1f%%)\n", report.CacheHits, report.CacheHitRate*100)
+
+	fmt.Printf("\n%-10s %8s %10s %10s %10s %10s %10s %10s\n", "Kind", "Count", "Mean", "P50", "P90", "P95", "P99", "Max")
+	row := func(kind string, s core.LatencyStats) {
+		fmt.Printf("%-10s %8d %10s %10s %10s %10s %10s %10s\n",
+			kind, s.Count, ms(s.Mean), ms(s.P50), ms(s.P90), ms(s.P95), ms(s.P99), ms(s.Max))
+	}
+	row("all", report.Latency)
+	for _, kind := range []string{core.LoadQueryKeyword, core.LoadQueryPhrase, core.LoadQueryRepeat, core.LoadQueryMiss} {
+		if stats, ok := report.ByKind[kind]; ok {
+			row(kind, stats)
+		}
+	}
+
+	if report.MemoryBefore != nil && report.MemoryAfter != nil {
+		fmt.Printf("\nServer memory: %.1f MB → %.1f MB heap, %d → %d goroutines\n",
+			float64(report.MemoryBefore.HeapSize)/(1<<20), float64(report.MemoryAfter.HeapSize)/(1<<20),
+			report.MemoryBefore.GoRoutines, report.MemoryAfter.GoRoutines)
+	} else {
+		fmt.Println("\nServer memory: unavailable (enable server.enable_profiling)")
+	}
+	if report.FirstError != "" {
+		fmt.Printf("First error:  %s\n", report.FirstError)
+	}
+}
+
+func init() {
+	benchmarkRAGCmd.Flags().StringP("server", "s", "http://localhost:7610", "API server address")
+	benchmarkRAGCmd.Flags().StringP("project", "p", "", "Project to index and query")
+	benchmarkRAGCmd.Flags().StringP("token", "t", "", "Admin token (default METABASE_ADMIN_TOKEN)")
+	benchmarkRAGCmd.Flags().Int("docs", 200, "Number of synthetic documents")
+	benchmarkRAGCmd.Flags().Int("words", 300, "Words per document")
+	benchmarkRAGCmd.Flags().Int("topics", 10, "Number of topics sharing vocabulary")
+	benchmarkRAGCmd.Flags().Int64("seed", 1, "Corpus and query seed")
+	benchmarkRAGCmd.Flags().Float64("qps", 10, "Queries per second")
+	benchmarkRAGCmd.Flags().Duration("duration", 30*time.Second, "Query phase duration")
+	benchmarkRAGCmd.Flags().Int("concurrency", 8, "Maximum queries in flight")
+	benchmarkRAGCmd.Flags().String("mix", "", "Query mix weights, e.g. keyword=3,phrase=4,repeat=2,miss=1")
+	benchmarkRAGCmd.Flags().Bool("skip-index", false, "Query a corpus indexed by an earlier run")
+	benchmarkRAGCmd.Flags().String("json", "", "Write the report as JSON to this file")
+
+	benchmarkCmd.AddCommand(benchmarkRAGCmd)
+}
@@ -14,8 +14,9 @@ import (
 )
 
 var benchmarkCmd = &cobra.Command{
-	Use:   "benchmark",
-	Short: "Performance benchmarking for embeddings and vocab operations",
+	Use:     "benchmark",
+	Aliases: []string{"bench"},
+	Short:   "Performance benchmarking for embeddings, vocab operations and the RAG path",
 	Long: `Run comprehensive performance benchmarks for different embedding methods.
 
 Examples:
@@ -24,7 +25,8 @@ Examples:
   metabase benchmark embedding --texts 1000       # Benchmark with 1000 texts
   metabase benchmark embedding --batch-sizes 32,64 # Test specific batch sizes
   metabase benchmark vocab                        # Benchmark vocab operations
-  metabase benchmark models                       # New comprehensive model comparison`,
+  metabase benchmark models                       # New comprehensive model comparison
+  metabase bench rag --project demo               # Load test RAG indexing and queries`,
 }
 
 var benchmarkEmbeddingCmd = &cobra.Command{
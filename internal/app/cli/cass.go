@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	analysis "github.com/guileen/metabase/internal/cass"
+	"github.com/spf13/cobra"
+)
+
+var cassCmd = &cobra.Command{
+	Use:   "cass",
+	Short: "CASS 代码分析",
+	Long:  `CASS (Code Analysis & Search System) 代码分析工具。`,
+}
+
+var cassServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "启动保存即分析的本地守护进程",
+	Long: `启动常驻的 CASS 分析守护进程，分析器常驻内存，工作区文件保存后
+在毫秒级内重新分析，结果通过本地 JSON-RPC/HTTP 接口提供给编辑器插件。
+
+接口:
+- POST /rpc          JSON-RPC 2.0: cass/analyze, cass/results, cass/files,
+                     textDocument/didSave, textDocument/didChange
+- GET  /files?path=  文件最新分析结果
+- GET  /events       分析结果的 SSE 事件流
+- GET  /health       守护进程状态
+
+文件范围和架构规则读取 --config 指定的 CASS 配置。
+
+示例:
+  metabase cass serve --watch
+  metabase cass serve --watch --addr unix:/tmp/cass.sock --root ./myproject`,
+	Run: func(cmd *cobra.Command, args []string) {
+		root, _ := cmd.Flags().GetString("root")
+		addr, _ := cmd.Flags().GetString("addr")
+		watch, _ := cmd.Flags().GetBool("watch")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		configPath, _ := cmd.Flags().GetString("config")
+
+		ciConfig, err := analysis.LoadConfig(configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载 CASS 配置失败: %v\n", err)
+			os.Exit(1)
+		}
+
+		daemon, err := analysis.NewDaemon(&analysis.DaemonConfig{
+			Root:         root,
+			Addr:         addr,
+			Watch:        watch,
+			PollInterval: interval,
+			CI:           ciConfig,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建分析守护进程失败: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Fprintf(os.Stderr, "🚀 CASS 守护进程已启动: %s (工作区 %s)\n", addr, root)
+		if err := daemon.Serve(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "CASS 守护进程出错: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	defaults := analysis.DefaultDaemonConfig()
+	cassServeCmd.Flags().String("root", defaults.Root, "工作区根目录")
+	cassServeCmd.Flags().String("addr", defaults.Addr, "监听地址，unix:/path 使用 Unix 套接字")
+	cassServeCmd.Flags().Bool("watch", false, "监视工作区，文件变化后自动重新分析")
+	cassServeCmd.Flags().Duration("interval", defaults.PollInterval, "检查文件变化的间隔")
+	cassServeCmd.Flags().String("config", ".cass.yaml", "CASS 配置文件路径")
+
+	cassCmd.AddCommand(cassServeCmd)
+	AddCommand(cassCmd)
+}
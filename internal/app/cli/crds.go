@@ -0,0 +1,310 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/guileen/metabase/pkg/client"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// crdVersion 导出资源的 API 版本
+const crdVersion = "v1alpha1"
+
+// crdPageSize 列出租户、项目时的分页大小
+const crdPageSize = 100
+
+var exportCRDsCmd = &cobra.Command{
+	Use:   "export-crds",
+	Short: "导出项目和数据源的 Kubernetes CRD 清单",
+	Long: `将 API 服务器上的项目和数据源导出为 Kubernetes 自定义资源清单 (多文档 YAML)，
+供外部 operator 对账。
+
+--definitions 同时输出 Project 和 DataSource 的 CustomResourceDefinition；
+--resources=false 只输出定义，不访问服务器。
+资源名由租户、项目和数据源名称生成，并带有 <group>/tenant 与 <group>/project 标签。
+
+示例:
+  metabase export-crds --definitions --resources=false > crds.yaml
+  metabase export-crds --tenant acme -n metabase -o acme.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		apiKey, _ := cmd.Flags().GetString("apikey")
+		group, _ := cmd.Flags().GetString("group")
+		namespace, _ := cmd.Flags().GetString("namespace")
+		tenants, _ := cmd.Flags().GetStringSlice("tenant")
+		definitions, _ := cmd.Flags().GetBool("definitions")
+		resources, _ := cmd.Flags().GetBool("resources")
+		output, _ := cmd.Flags().GetString("output")
+		if token == "" {
+			token = os.Getenv("METABASE_TOKEN")
+		}
+		if apiKey == "" {
+			apiKey = os.Getenv("METABASE_API_KEY")
+		}
+
+		var docs []interface{}
+		if definitions {
+			docs = append(docs, projectCRD(group), dataSourceCRD(group))
+		}
+		if resources {
+			c := client.New(&client.Config{URL: strings.TrimRight(server, "/"), AccessToken: token, APIKey: apiKey})
+			exported, err := exportResources(context.Background(), c, group, namespace, tenants)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, exported...)
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("创建输出文件失败: %w", err)
+			}
+			defer file.Close()
+			w = file
+		}
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		for _, doc := range docs {
+			if err := encoder.Encode(doc); err != nil {
+				return fmt.Errorf("写入清单失败: %w", err)
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return fmt.Errorf("写入清单失败: %w", err)
+		}
+		if output != "" {
+			fmt.Printf("✅ 已导出 %d 个资源: %s\n", len(docs), output)
+		}
+		return nil
+	},
+}
+
+// crdObject Kubernetes 资源
+type crdObject struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   crdMetadata `yaml:"metadata"`
+	Spec       interface{} `yaml:"spec"`
+}
+
+// crdMetadata 资源元数据
+type crdMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// projectSpec Project 资源的期望状态，租户和项目按 slug 引用
+type projectSpec struct {
+	Tenant      string                 `yaml:"tenant"`
+	Slug        string                 `yaml:"slug"`
+	Name        string                 `yaml:"name"`
+	Description string                 `yaml:"description,omitempty"`
+	Environment string                 `yaml:"environment,omitempty"`
+	IsPublic    bool                   `yaml:"isPublic"`
+	Settings    map[string]interface{} `yaml:"settings,omitempty"`
+	Metadata    map[string]interface{} `yaml:"metadata,omitempty"`
+}
+
+// dataSourceSpec DataSource 资源的期望状态，密钥只以引用形式导出
+type dataSourceSpec struct {
+	Tenant          string                 `yaml:"tenant"`
+	Project         string                 `yaml:"project"`
+	Name            string                 `yaml:"name"`
+	Type            string                 `yaml:"type"`
+	Config          map[string]interface{} `yaml:"config,omitempty"`
+	SecretRefs      map[string]string      `yaml:"secretRefs,omitempty"`
+	Schedule        string                 `yaml:"schedule,omitempty"`
+	IncludePatterns []string               `yaml:"includePatterns,omitempty"`
+	ExcludePatterns []string               `yaml:"excludePatterns,omitempty"`
+	Enabled         bool                   `yaml:"enabled"`
+}
+
+// exportResources 导出租户的项目和数据源，未指定租户时导出全部租户
+func exportResources(ctx context.Context, c *client.Client, group, namespace string, slugs []string) ([]interface{}, error) {
+	var tenants []client.Tenant
+	if len(slugs) > 0 {
+		for _, slug := range slugs {
+			tenant, err := c.GetTenantBySlug(ctx, slug)
+			if err != nil {
+				return nil, fmt.Errorf("获取租户 %s 失败: %w", slug, err)
+			}
+			tenants = append(tenants, *tenant)
+		}
+	} else {
+		for tenant, err := range c.Tenants(ctx, crdPageSize) {
+			if err != nil {
+				return nil, fmt.Errorf("列出租户失败: %w", err)
+			}
+			tenants = append(tenants, tenant)
+		}
+	}
+
+	apiVersion := group + "/" + crdVersion
+	var docs []interface{}
+	for _, tenant := range tenants {
+		for project, err := range c.TenantProjects(ctx, tenant.ID, crdPageSize) {
+			if err != nil {
+				return nil, fmt.Errorf("列出租户 %s 的项目失败: %w", tenant.Slug, err)
+			}
+			labels := map[string]string{group + "/tenant": tenant.Slug, group + "/project": project.Slug}
+			docs = append(docs, crdObject{
+				APIVersion: apiVersion,
+				Kind:       "Project",
+				Metadata:   crdMetadata{Name: resourceName(tenant.Slug, project.Slug), Namespace: namespace, Labels: labels},
+				Spec: projectSpec{
+					Tenant: tenant.Slug, Slug: project.Slug, Name: project.Name, Description: project.Description,
+					Environment: project.Environment, IsPublic: project.IsPublic,
+					Settings: project.Settings, Metadata: project.Metadata,
+				},
+			})
+
+			sources, err := c.ListDataSources(ctx, project.ID)
+			if err != nil {
+				return nil, fmt.Errorf("列出项目 %s/%s 的数据源失败: %w", tenant.Slug, project.Slug, err)
+			}
+			for _, source := range sources {
+				docs = append(docs, crdObject{
+					APIVersion: apiVersion,
+					Kind:       "DataSource",
+					Metadata:   crdMetadata{Name: resourceName(tenant.Slug, project.Slug, source.Name), Namespace: namespace, Labels: labels},
+					Spec: dataSourceSpec{
+						Tenant: tenant.Slug, Project: project.Slug, Name: source.Name, Type: source.Type,
+						Config: source.Config, SecretRefs: source.SecretRefs, Schedule: source.Schedule,
+						IncludePatterns: source.IncludePatterns, ExcludePatterns: source.ExcludePatterns,
+						Enabled: source.Enabled,
+					},
+				})
+			}
+		}
+	}
+	return docs, nil
+}
+
+// invalidNameChars 资源名中不允许的字符
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// resourceName 将各部分转换为合法的 Kubernetes 资源名 (RFC 1123 子域名)
+func resourceName(parts ...string) string {
+	for i, part := range parts {
+		parts[i] = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(part), "-"), "-")
+	}
+	name := strings.Join(parts, ".")
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-.")
+	}
+	return name
+}
+
+// projectCRD Project 资源的 CustomResourceDefinition
+func projectCRD(group string) map[string]interface{} {
+	return crdDefinition(group, "Project", "projects", []string{"tenant", "slug", "name"}, map[string]interface{}{
+		"tenant":      stringSchema("Slug of the project's tenant"),
+		"slug":        stringSchema("Project slug, unique within the tenant"),
+		"name":        stringSchema("Display name"),
+		"description": stringSchema(""),
+		"environment": stringSchema("e.g. production, staging or development"),
+		"isPublic":    map[string]interface{}{"type": "boolean"},
+		"settings":    freeformSchema(),
+		"metadata":    freeformSchema(),
+	})
+}
+
+// dataSourceCRD DataSource 资源的 CustomResourceDefinition
+func dataSourceCRD(group string) map[string]interface{} {
+	stringList := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	return crdDefinition(group, "DataSource", "datasources", []string{"tenant", "project", "name", "type"}, map[string]interface{}{
+		"tenant":          stringSchema("Slug of the tenant"),
+		"project":         stringSchema("Slug of the project"),
+		"name":            stringSchema("Data source name, unique within the project"),
+		"type":            stringSchema("Connector type"),
+		"config":          freeformSchema(),
+		"secretRefs":      map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+		"schedule":        stringSchema("Cron expression of scheduled syncs"),
+		"includePatterns": stringList,
+		"excludePatterns": stringList,
+		"enabled":         map[string]interface{}{"type": "boolean"},
+	})
+}
+
+// crdDefinition 生成带 status 子资源的命名空间级 CRD
+func crdDefinition(group, kind, plural string, required []string, properties map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata":   map[string]interface{}{"name": plural + "." + group},
+		"spec": map[string]interface{}{
+			"group": group,
+			"scope": "Namespaced",
+			"names": map[string]interface{}{
+				"kind":     kind,
+				"listKind": kind + "List",
+				"plural":   plural,
+				"singular": strings.ToLower(kind),
+			},
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":         crdVersion,
+					"served":       true,
+					"storage":      true,
+					"subresources": map[string]interface{}{"status": map[string]interface{}{}},
+					"schema": map[string]interface{}{
+						"openAPIV3Schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"spec": map[string]interface{}{
+									"type":       "object",
+									"required":   required,
+									"properties": properties,
+								},
+								"status": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"id":                 stringSchema("ID assigned by MetaBase"),
+										"observedGeneration": map[string]interface{}{"type": "integer"},
+										"conditions":         map[string]interface{}{"type": "array", "items": freeformSchema()},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func stringSchema(description string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "string"}
+	if description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+func freeformSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+}
+
+func init() {
+	exportCRDsCmd.Flags().StringP("server", "s", "http://localhost:7610", "API服务器地址")
+	exportCRDsCmd.Flags().StringP("token", "t", "", "访问令牌 (默认读取 METABASE_TOKEN)")
+	exportCRDsCmd.Flags().String("apikey", "", "API 密钥 (默认读取 METABASE_API_KEY)")
+	exportCRDsCmd.Flags().String("group", "metabase.io", "自定义资源的 API 组")
+	exportCRDsCmd.Flags().StringP("namespace", "n", "", "资源所在的 Kubernetes 命名空间")
+	exportCRDsCmd.Flags().StringSlice("tenant", nil, "只导出这些租户 (slug)，默认导出全部")
+	exportCRDsCmd.Flags().Bool("definitions", false, "输出 CustomResourceDefinition")
+	exportCRDsCmd.Flags().Bool("resources", true, "从服务器导出项目和数据源")
+	exportCRDsCmd.Flags().StringP("output", "o", "", "输出文件，默认标准输出")
+
+	rootCmd.AddCommand(exportCRDsCmd)
+}
@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/internal/app/api/handlers"
+	"github.com/guileen/metabase/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// maxLogTail 支持包中本地日志文件保留的末尾字节数
+const maxLogTail = 1 << 20
+
+var diagnoseCmd = &cobra.Command{
+	Use:   "diagnose",
+	Short: "生成诊断支持包",
+	Long: `采集运行中 API 服务器的诊断信息并打包为 zip 支持包。
+
+包含内容:
+- 版本和构建信息 (本地命令和服务器)
+- 脱敏后的配置
+- 运行时指标和 pprof 数据 (heap、allocs、goroutine，可选 CPU)
+- 最近的请求日志和本地日志文件末尾
+
+服务器需开启 server.enable_profiling，令牌需属于系统管理员。
+单项采集失败会记录在 errors.txt 中，不影响其余内容。`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		output, _ := cmd.Flags().GetString("output")
+		cpuSeconds, _ := cmd.Flags().GetInt("cpu-seconds")
+		logLimit, _ := cmd.Flags().GetInt("logs")
+		if token == "" {
+			token = os.Getenv("METABASE_ADMIN_TOKEN")
+		}
+		if output == "" {
+			output = fmt.Sprintf("metabase-diagnose-%s.zip", time.Now().Format("20060102-150405"))
+		}
+
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("创建支持包失败: %w", err)
+		}
+		defer file.Close()
+
+		bundle := &supportBundle{
+			zip:    zip.NewWriter(file),
+			server: strings.TrimRight(server, "/"),
+			token:  token,
+			client: &http.Client{Timeout: time.Duration(cpuSeconds+30) * time.Second},
+		}
+		bundle.collect(cpuSeconds, logLimit)
+		if err := bundle.close(); err != nil {
+			return fmt.Errorf("写入支持包失败: %w", err)
+		}
+
+		fmt.Printf("✅ 支持包已生成: %s\n", output)
+		if len(bundle.errors) > 0 {
+			fmt.Printf("⚠️  %d 项采集失败，详见包内 errors.txt\n", len(bundle.errors))
+		}
+		return nil
+	},
+}
+
+// supportBundle 逐项写入支持包，记录失败项而不中断
+type supportBundle struct {
+	zip    *zip.Writer
+	server string
+	token  string
+	client *http.Client
+	errors []string
+}
+
+// collect 采集全部内容
+func (b *supportBundle) collect(cpuSeconds, logLimit int) {
+	b.writeJSON("version.json", map[string]interface{}{
+		"metabase":     rootCmd.Version,
+		"build":        handlers.BuildInfo(),
+		"generated_at": time.Now(),
+	})
+	b.writeConfig()
+
+	b.fetch("server/runtime.json", "/debug/runtime")
+	b.fetch("server/config.json", "/admin/v1/system/config")
+	b.fetch("server/heap.pprof", "/debug/pprof/heap")
+	b.fetch("server/allocs.pprof", "/debug/pprof/allocs")
+	b.fetch("server/goroutines.txt", "/debug/pprof/goroutine?debug=2")
+	if cpuSeconds > 0 {
+		b.fetch("server/cpu.pprof", fmt.Sprintf("/debug/pprof/profile?seconds=%d", cpuSeconds))
+	}
+	if logLimit > 0 {
+		b.fetch("logs/requests.json", fmt.Sprintf("/admin/logs/logs?limit=%d", logLimit))
+	}
+	b.writeLogTail()
+}
+
+// writeConfig 写入脱敏后的配置
+func (b *supportBundle) writeConfig() {
+	cfg := config.Get()
+	if cfg == nil {
+		b.fail("config.json", fmt.Errorf("configuration not loaded"))
+		return
+	}
+	data, err := json.Marshal(cfg.GetAppConfig())
+	if err != nil {
+		b.fail("config.json", err)
+		return
+	}
+	var settings map[string]interface{}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		b.fail("config.json", err)
+		return
+	}
+	b.writeJSON("config.json", config.Redact(settings))
+}
+
+// writeLogTail 写入本地日志文件的末尾部分
+func (b *supportBundle) writeLogTail() {
+	cfg := config.Get()
+	if cfg == nil {
+		return
+	}
+	path := cfg.GetString("logging.file")
+	if path == "" {
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		b.fail("logs/app.log", err)
+		return
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.Size() > maxLogTail {
+		file.Seek(info.Size()-maxLogTail, io.SeekStart)
+	}
+	b.copy("logs/app.log", file)
+}
+
+// fetch 从服务器下载一项内容
+func (b *supportBundle) fetch(name, path string) {
+	req, err := http.NewRequest(http.MethodGet, b.server+path, nil)
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		b.fail(name, fmt.Errorf("GET %s: %s %s", path, resp.Status, strings.TrimSpace(string(body))))
+		return
+	}
+	b.copy(name, resp.Body)
+}
+
+func (b *supportBundle) writeJSON(name string, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	b.copy(name, strings.NewReader(string(data)))
+}
+
+func (b *supportBundle) copy(name string, r io.Reader) {
+	w, err := b.zip.Create(name)
+	if err == nil {
+		_, err = io.Copy(w, r)
+	}
+	if err != nil {
+		b.fail(name, err)
+	}
+}
+
+func (b *supportBundle) fail(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+// close 写入失败记录并完成 zip
+func (b *supportBundle) close() error {
+	if len(b.errors) > 0 {
+		w, err := b.zip.Create("errors.txt")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, strings.Join(b.errors, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return b.zip.Close()
+}
+
+func init() {
+	diagnoseCmd.Flags().StringP("server", "s", "http://localhost:7610", "API服务器地址")
+	diagnoseCmd.Flags().StringP("token", "t", "", "系统管理员令牌 (默认读取 METABASE_ADMIN_TOKEN)")
+	diagnoseCmd.Flags().StringP("output", "o", "", "支持包路径 (默认 metabase-diagnose-<时间>.zip)")
+	diagnoseCmd.Flags().Int("cpu-seconds", 0, "采集CPU profile的秒数，0 表示不采集")
+	diagnoseCmd.Flags().Int("logs", 500, "包含的最近请求日志条数，0 表示不包含")
+
+	rootCmd.AddCommand(diagnoseCmd)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/guileen/metabase/internal/app/api"
+	"github.com/guileen/metabase/internal/app/api/onboarding"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"github.com/spf13/cobra"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "初始化自托管安装",
+	Long: `一条命令完成全新安装的初始化:
+
+- 创建数据库表结构、系统租户和系统项目
+- 创建初始系统管理员账号
+- 生成默认 RAG 配置文件
+- 检查配置的模型服务是否可以连通
+
+管理员密码从 METABASE_ADMIN_PASSWORD 读取，未设置时随机生成并只显示一次。
+重复执行是安全的: 已有的管理员账号和配置文件不会被修改。
+
+Examples:
+  metabase init
+  METABASE_ADMIN_PASSWORD=... metabase init --admin-email ops@example.com
+  metabase init --db /var/lib/metabase/metabase.db --rag-config /etc/metabase/rag.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dbPath, _ := cmd.Flags().GetString("db")
+		ragConfigPath, _ := cmd.Flags().GetString("rag-config")
+		email, _ := cmd.Flags().GetString("admin-email")
+		name, _ := cmd.Flags().GetString("admin-name")
+		force, _ := cmd.Flags().GetBool("force")
+		skipChecks, _ := cmd.Flags().GetBool("skip-checks")
+		checkTimeout, _ := cmd.Flags().GetDuration("check-timeout")
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		// 1. 表结构、系统租户和项目在创建服务器时建立
+		fmt.Println("🗄️  初始化数据库...")
+		for _, dir := range []string{filepath.Dir(dbPath), "data"} {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("创建数据目录失败: %w", err)
+			}
+		}
+		config := api.NewConfig()
+		config.DatabasePath = dbPath
+		server, err := api.NewServer(config)
+		if err != nil {
+			return fmt.Errorf("初始化数据库失败: %w", err)
+		}
+		defer server.Stop(context.Background())
+		fmt.Printf("✅ 数据库: %s\n", dbPath)
+
+		// 2. 初始管理员
+		result, err := server.Bootstrap(ctx, onboarding.AdminInput{
+			Email:    email,
+			Name:     name,
+			Password: os.Getenv("METABASE_ADMIN_PASSWORD"),
+		})
+		if err != nil {
+			return fmt.Errorf("创建管理员失败: %w", err)
+		}
+		if result.Created {
+			fmt.Printf("✅ 管理员: %s\n", result.Admin.Email)
+			if result.InitialPassword != "" {
+				fmt.Printf("🔑 初始密码: %s\n", result.InitialPassword)
+				fmt.Println("   密码只显示这一次，请妥善保存并在首次登录后修改")
+			}
+		} else {
+			fmt.Printf("⏭️  管理员 %s 已存在，保持不变\n", result.Admin.Email)
+		}
+
+		// 3. 默认 RAG 配置
+		if _, err := os.Stat(ragConfigPath); err == nil && !force {
+			fmt.Printf("⏭️  RAG 配置 %s 已存在，保持不变 (--force 覆盖)\n", ragConfigPath)
+		} else {
+			if err := core.SaveConfig(core.DefaultConfig(), ragConfigPath); err != nil {
+				return fmt.Errorf("写入 RAG 配置失败: %w", err)
+			}
+			fmt.Printf("✅ RAG 配置: %s\n", ragConfigPath)
+		}
+
+		// 4. 模型服务连通性
+		if skipChecks {
+			fmt.Println("\n🎉 初始化完成")
+			return nil
+		}
+		ragConfig, err := core.LoadConfig(ragConfigPath)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\n🔌 检查模型服务...")
+		checks, err := core.CheckProviders(ctx, ragConfig, checkTimeout)
+		if err != nil {
+			return fmt.Errorf("检查模型服务失败: %w", err)
+		}
+		failed := 0
+		for _, check := range checks {
+			if check.OK() {
+				fmt.Printf("✅ %-10s %-20s %s\n", check.Kind, check.Name, check.Latency.Round(time.Millisecond))
+				continue
+			}
+			failed++
+			fmt.Printf("❌ %-10s %-20s %s\n", check.Kind, check.Name, check.Error)
+		}
+
+		if failed > 0 {
+			fmt.Printf("\n⚠️  初始化完成，但 %d 个模型服务无法连通，请检查 %s 中的地址和密钥\n", failed, ragConfigPath)
+			return nil
+		}
+		fmt.Println("\n🎉 初始化完成，运行 metabase gateway 启动服务")
+		return nil
+	},
+}
+
+func init() {
+	initCmd.Flags().String("db", "./data/metabase.db", "数据库文件路径")
+	initCmd.Flags().String("rag-config", "./config/rag.json", "RAG 配置文件路径")
+	initCmd.Flags().String("admin-email", envOr("METABASE_ADMIN_EMAIL", "admin@localhost"), "管理员邮箱 (默认读取 METABASE_ADMIN_EMAIL)")
+	initCmd.Flags().String("admin-name", "Administrator", "管理员名称")
+	initCmd.Flags().Bool("force", false, "覆盖已存在的 RAG 配置文件")
+	initCmd.Flags().Bool("skip-checks", false, "跳过模型服务连通性检查")
+	initCmd.Flags().Duration("check-timeout", 15*time.Second, "每个模型服务的检查超时")
+
+	rootCmd.AddCommand(initCmd)
+}
+
+// envOr 返回环境变量的值，未设置时返回默认值
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
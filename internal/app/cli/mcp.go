@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/internal/app/api/keys"
+	"github.com/guileen/metabase/internal/app/api/rag"
+	"github.com/guileen/metabase/internal/app/mcp"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "启动 MCP (Model Context Protocol) 服务器",
+	Long: `启动 MCP 服务器，把项目的 RAG 检索、文档获取和 CASS 代码分析结果
+作为工具提供给 Claude Desktop、Cursor 等智能 IDE 工具。
+
+工具:
+- rag_search          检索项目文档
+- rag_fetch_document  获取文档全文
+- cass_findings       代码分析结果 (需要 --cass-url)
+
+认证使用项目 API 密钥，密钥需要 mcp 权限（或 mcp:<工具名> 只允许单个工具），
+系统密钥需要通过 --project 指定项目。
+
+示例:
+  METABASE_API_KEY=mb_xxx metabase mcp
+  metabase mcp --transport sse --port 7611 --tools rag_search,rag_fetch_document
+
+API 服务器也在 /mcp/sse 提供 SSE 传输。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		transport, _ := cmd.Flags().GetString("transport")
+		host, _ := cmd.Flags().GetString("host")
+		port, _ := cmd.Flags().GetString("port")
+		dbPath, _ := cmd.Flags().GetString("db")
+		apiKey, _ := cmd.Flags().GetString("api-key")
+		projectID, _ := cmd.Flags().GetString("project")
+		tools, _ := cmd.Flags().GetStringSlice("tools")
+		cassURL, _ := cmd.Flags().GetString("cass-url")
+		ragConfig, _ := cmd.Flags().GetString("rag-config")
+		if apiKey == "" {
+			apiKey = os.Getenv("METABASE_API_KEY")
+		}
+
+		// 日志输出到标准错误，标准输出用于 stdio 传输
+		logger, _ := zap.NewDevelopment()
+		defer logger.Sync()
+
+		db, err := sql.Open("sqlite3", dbPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "打开数据库失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+
+		ragManager := rag.NewManager(db, nil, logger)
+		if err := ragManager.Initialize(context.Background()); err != nil {
+			logger.Error("Failed to initialize RAG settings manager", zap.Error(err))
+		}
+		server := mcp.NewServer(&mcp.Config{Tools: tools, CASSURL: cassURL}, keys.NewManager(db, logger), ragManager, logger)
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		if pipeline, err := startMCPPipeline(ctx, ragConfig, ragManager); err != nil {
+			logger.Warn("RAG pipeline unavailable, RAG tools are disabled", zap.Error(err))
+		} else {
+			defer pipeline.Close()
+			server.SetRetriever(pipeline)
+		}
+
+		switch transport {
+		case "stdio":
+			if err := server.ServeStdio(ctx, os.Stdin, os.Stdout, apiKey, projectID); err != nil && ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "MCP 服务器出错: %v\n", err)
+				os.Exit(1)
+			}
+		case "sse":
+			router := chi.NewRouter()
+			router.Mount("/mcp", server.Handler())
+			httpServer := &http.Server{Addr: net.JoinHostPort(host, port), Handler: router}
+			go func() {
+				<-ctx.Done()
+				httpServer.Close()
+			}()
+
+			fmt.Fprintf(os.Stderr, "🚀 MCP 服务器已启动: http://%s/mcp/sse\n", httpServer.Addr)
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(os.Stderr, "MCP 服务器出错: %v\n", err)
+				os.Exit(1)
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "不支持的传输方式: %s (可选 stdio, sse)\n", transport)
+			os.Exit(1)
+		}
+	},
+}
+
+// startMCPPipeline 创建并启动 RAG 管道，项目配置、文档版本、术语表和已删除文档从 RAG 存储读取
+func startMCPPipeline(ctx context.Context, configPath string, store *rag.Manager) (*core.Pipeline, error) {
+	config, err := core.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	pipeline, err := core.NewPipeline(config)
+	if err != nil {
+		return nil, err
+	}
+	pipeline.SetProjectConfigStore(store)
+	pipeline.SetVersionStore(store)
+	pipeline.SetGlossaryStore(store)
+	if err := pipeline.SetTombstoneStore(ctx, store); err != nil {
+		pipeline.Close()
+		return nil, err
+	}
+	if err := pipeline.Start(ctx); err != nil {
+		pipeline.Close()
+		return nil, err
+	}
+	return pipeline, nil
+}
+
+func init() {
+	mcpCmd.Flags().String("transport", "stdio", "传输方式: stdio 或 sse")
+	mcpCmd.Flags().StringP("host", "H", "localhost", "SSE 绑定主机")
+	mcpCmd.Flags().StringP("port", "p", "7611", "SSE 端口")
+	mcpCmd.Flags().String("db", "./data/metabase.db", "数据库文件路径")
+	mcpCmd.Flags().String("api-key", "", "项目 API 密钥 (默认读取 METABASE_API_KEY)")
+	mcpCmd.Flags().String("project", "", "项目ID (默认为密钥所属项目)")
+	mcpCmd.Flags().StringSlice("tools", nil, "允许提供的工具，默认全部: "+strings.Join([]string{mcp.ToolRAGSearch, mcp.ToolFetchDocument, mcp.ToolCASSFindings}, ","))
+	mcpCmd.Flags().String("cass-url", "", "CASS 分析服务地址，如 http://localhost:7620")
+	mcpCmd.Flags().String("rag-config", "", "RAG 配置文件路径")
+}
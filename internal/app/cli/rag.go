@@ -3,10 +3,17 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/guileen/metabase/pkg/rag"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"github.com/guileen/metabase/pkg/rag/embedding"
+	"github.com/guileen/metabase/pkg/rag/processors"
 	"github.com/spf13/cobra"
 )
 
@@ -84,6 +91,9 @@ func init() {
 	ragCmd.AddCommand(ragSearchCmd())
 	ragCmd.AddCommand(ragVocabCmd())
 	ragCmd.AddCommand(ragQuickCmd())
+	ragCmd.AddCommand(ragReembedCmd())
+	ragCmd.AddCommand(ragCompareChunkersCmd())
+	ragCmd.AddCommand(ragFsckCmd())
 
 	// 将 RAG 命令添加到根命令
 	AddCommand(ragCmd)
@@ -226,3 +236,323 @@ func ragQuickCmd() *cobra.Command {
 		},
 	}
 }
+
+// ragReembedCmd 嵌入模型迁移子命令
+func ragReembedCmd() *cobra.Command {
+	var (
+		configPath string
+		model      string
+		provider   string
+		batchSize  int
+		documents  []string
+		dryRun     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reembed",
+		Short: "迁移到新的嵌入模型",
+		Long: `使用新的嵌入模型重新生成所有分块的向量。
+
+迁移在后台进行，期间查询同时读取新旧两个索引；迁移完成后自动切换到新索引。
+
+示例:
+  metabase rag reembed --model text-embedding-3-large
+  metabase rag reembed --model bge-small-zh --provider local --dry-run`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if model == "" {
+				cmd.PrintErrln("请指定目标模型 (--model)")
+				return
+			}
+
+			config, err := core.LoadConfig(configPath)
+			if err != nil {
+				cmd.PrintErrln("加载配置失败:", err.Error())
+				return
+			}
+
+			pipeline, err := core.NewPipeline(config)
+			if err != nil {
+				cmd.PrintErrln("创建 RAG 管道失败:", err.Error())
+				return
+			}
+			defer pipeline.Close()
+
+			if err := pipeline.Start(cmd.Context()); err != nil {
+				cmd.PrintErrln("启动 RAG 管道失败:", err.Error())
+				return
+			}
+
+			generator, err := embedding.CreateGenerator(model, embedding.VectorGeneratorConfig{
+				ModelName: model,
+				BatchSize: batchSize,
+			})
+			if err != nil {
+				cmd.PrintErrln("创建嵌入模型失败:", err.Error())
+				return
+			}
+			defer generator.Close()
+
+			job, err := pipeline.StartReembed(cmd.Context(), core.ReembedOptions{
+				Target: core.EmbeddingConfig{
+					Model:    model,
+					Provider: provider,
+				},
+				Generator:   generator,
+				DocumentIDs: documents,
+				BatchSize:   batchSize,
+				DryRun:      dryRun,
+			})
+			if err != nil {
+				cmd.PrintErrln("启动迁移失败:", err.Error())
+				return
+			}
+
+			fmt.Printf("迁移任务 %s: %s -> %s\n", job.ID, job.FromVersion, job.ToVersion)
+
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-cmd.Context().Done():
+					pipeline.CancelReembed()
+					fmt.Println("迁移已取消")
+					return
+				case <-ticker.C:
+				}
+
+				job = pipeline.GetReembedJob()
+				fmt.Printf("  文档 %d/%d, 已迁移 %d, 跳过 %d, 失败 %d\n",
+					job.DocumentsDone, job.DocumentsTotal, job.ChunksMigrated, job.ChunksSkipped, job.ChunksFailed)
+				if job.Status != core.ReembedStatusRunning {
+					break
+				}
+			}
+
+			fmt.Printf("迁移结束: %s\n", job.Status)
+			for _, e := range job.Errors {
+				fmt.Printf("  错误: %s\n", e)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", "", "RAG 配置文件路径")
+	cmd.Flags().StringVar(&model, "model", "", "目标嵌入模型")
+	cmd.Flags().StringVar(&provider, "provider", "", "目标嵌入模型提供方")
+	cmd.Flags().IntVar(&batchSize, "batch", 32, "每批嵌入的分块数")
+	cmd.Flags().StringSliceVar(&documents, "document", []string{}, "仅迁移指定文档 (可多次使用)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "仅统计需要迁移的分块")
+
+	return cmd
+}
+
+func ragFsckCmd() *cobra.Command {
+	var (
+		configPath string
+		repair     bool
+		asJSON     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "fsck",
+		Short: "检查并修复 RAG 存储的一致性",
+		Long: `检查 RAG 存储中的不一致：没有文档的分块、没有分块的向量、
+引用已删除分块的索引条目，以及维度与索引版本不符的向量。
+
+--repair 删除孤立的分块、向量和索引条目，并重新处理向量维度不符的文档。
+
+示例:
+  metabase rag fsck
+  metabase rag fsck --repair --json > fsck.json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := core.LoadConfig(configPath)
+			if err != nil {
+				cmd.PrintErrln("加载配置失败:", err.Error())
+				return
+			}
+
+			pipeline, err := core.NewPipeline(config)
+			if err != nil {
+				cmd.PrintErrln("创建 RAG 管道失败:", err.Error())
+				return
+			}
+			defer pipeline.Close()
+
+			if err := pipeline.Start(cmd.Context()); err != nil {
+				cmd.PrintErrln("启动 RAG 管道失败:", err.Error())
+				return
+			}
+
+			report, err := pipeline.CheckConsistency(cmd.Context(), core.FsckOptions{Repair: repair})
+			if err != nil {
+				cmd.PrintErrln("一致性检查失败:", err.Error())
+				return
+			}
+
+			if asJSON {
+				data, _ := json.MarshalIndent(report, "", "  ")
+				fmt.Println(string(data))
+				return
+			}
+
+			fmt.Printf("文档 %d, 分块 %d, 向量 %d", report.Documents, report.Chunks, report.Embeddings)
+			if report.IndexChecked {
+				fmt.Printf(", 索引条目 %d", report.IndexEntries)
+			}
+			fmt.Printf(" (%s)\n", report.Duration.Round(time.Millisecond))
+			if !report.IndexChecked {
+				fmt.Println("检索索引不支持列出条目，已跳过索引检查")
+			}
+			if len(report.Issues) == 0 {
+				fmt.Println("未发现问题")
+				return
+			}
+
+			for _, kind := range []string{core.FsckOrphanChunk, core.FsckOrphanEmbedding, core.FsckDanglingIndex, core.FsckDimensionMismatch} {
+				if n := report.Counts[kind]; n > 0 {
+					fmt.Printf("  %-20s %d\n", kind, n)
+				}
+			}
+			for _, issue := range report.Issues {
+				status := ""
+				switch {
+				case issue.Repaired:
+					status = " [已修复]"
+				case issue.Error != "":
+					status = " [修复失败: " + issue.Error + "]"
+				}
+				fmt.Printf("  %s %s %s %s%s\n", issue.Kind, issue.ChunkID, issue.DocumentID, issue.Detail, status)
+			}
+			if repair {
+				fmt.Printf("\n已修复 %d, 失败 %d\n", report.Repaired, report.Failed)
+			} else {
+				fmt.Println("\n使用 --repair 修复以上问题")
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configPath, "config", "", "RAG 配置文件路径")
+	cmd.Flags().BoolVar(&repair, "repair", false, "修复发现的问题")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "以 JSON 输出报告")
+
+	return cmd
+}
+
+func ragCompareChunkersCmd() *cobra.Command {
+	var (
+		dir        string
+		evalPath   string
+		model      string
+		sampleSize int
+		topK       int
+		cost       float64
+		variants   [2]core.ChunkingConfig
+	)
+
+	cmd := &cobra.Command{
+		Use:   "compare-chunkers",
+		Short: "对比两种分块配置的检索效果",
+		Long: `用两种分块配置分别索引目录中的文档样本（临时命名空间，不写入索引），
+运行评测集并并排给出检索质量（命中率、召回率、MRR）与成本（分块数、嵌入 token、上下文 token）。
+
+评测集为 JSON 数组，relevant_documents 为相对 --dir 的文件路径:
+  [{"query": "如何退款", "relevant_documents": ["billing/refund.md"]}]
+
+示例:
+  metabase rag compare-chunkers --dir ./docs --eval eval.json --model bge-small-zh \
+    --a-strategy fixed --a-max 1000 --b-strategy paragraph --b-max 2000`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if dir == "" || evalPath == "" || model == "" {
+				cmd.PrintErrln("请指定文档目录 (--dir)、评测集 (--eval) 和嵌入模型 (--model)")
+				return
+			}
+
+			data, err := os.ReadFile(evalPath)
+			if err != nil {
+				cmd.PrintErrln("读取评测集失败:", err.Error())
+				return
+			}
+			var evalSet []core.EvalCase
+			if err := json.Unmarshal(data, &evalSet); err != nil {
+				cmd.PrintErrln("解析评测集失败:", err.Error())
+				return
+			}
+
+			var documents []core.Document
+			err = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				id, _ := filepath.Rel(dir, path)
+				documents = append(documents, core.Document{ID: filepath.ToSlash(id), Title: entry.Name(), Content: string(content)})
+				return nil
+			})
+			if err != nil {
+				cmd.PrintErrln("读取文档失败:", err.Error())
+				return
+			}
+
+			generator, err := embedding.CreateGenerator(model, embedding.VectorGeneratorConfig{
+				ModelName: model,
+			})
+			if err != nil {
+				cmd.PrintErrln("创建嵌入模型失败:", err.Error())
+				return
+			}
+			defer generator.Close()
+
+			comparison, err := core.CompareChunkers(cmd.Context(), core.ChunkerComparisonOptions{
+				A:               core.ChunkerVariant{Name: "A", Config: variants[0]},
+				B:               core.ChunkerVariant{Name: "B", Config: variants[1]},
+				Documents:       core.SampleDocuments(documents, evalSet, sampleSize),
+				EvalSet:         evalSet,
+				Generator:       generator,
+				TopK:            topK,
+				CostPer1KTokens: cost,
+				NewStrategy:     processors.NewChunkingStrategy,
+			})
+			if err != nil {
+				cmd.PrintErrln("对比失败:", err.Error())
+				return
+			}
+
+			fmt.Printf("文档 %d, 问题 %d, Top %d\n\n", comparison.Documents, comparison.Questions, comparison.TopK)
+			fmt.Printf("%-16s %14s %14s\n", "", "A ("+comparison.A.Strategy+")", "B ("+comparison.B.Strategy+")")
+			fmt.Printf("%-16s %14.1f%% %13.1f%%\n", "命中率", comparison.A.HitRate*100, comparison.B.HitRate*100)
+			fmt.Printf("%-16s %14.1f%% %13.1f%%\n", "召回率", comparison.A.Recall*100, comparison.B.Recall*100)
+			fmt.Printf("%-16s %14.3f %14.3f\n", "MRR", comparison.A.MRR, comparison.B.MRR)
+			fmt.Printf("%-16s %14d %14d\n", "分块数", comparison.A.Chunks, comparison.B.Chunks)
+			fmt.Printf("%-16s %14d %14d\n", "嵌入 token", comparison.A.EmbeddedTokens, comparison.B.EmbeddedTokens)
+			fmt.Printf("%-16s %14.0f %14.0f\n", "上下文 token", comparison.A.ContextTokens, comparison.B.ContextTokens)
+			if cost > 0 {
+				fmt.Printf("%-16s %14.4f %14.4f\n", "嵌入成本", comparison.A.EstimatedCost, comparison.B.EstimatedCost)
+			}
+			if comparison.Winner != "" {
+				fmt.Printf("\n检索效果更好: %s (MRR %+.3f)\n", comparison.Winner, comparison.MRRDelta)
+			} else {
+				fmt.Println("\n两种配置的 MRR 相同")
+			}
+			for _, e := range append(comparison.A.Errors, comparison.B.Errors...) {
+				fmt.Printf("  错误: %s\n", e)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "样本文档目录")
+	cmd.Flags().StringVar(&evalPath, "eval", "", "评测集 JSON 文件")
+	cmd.Flags().StringVar(&model, "model", "", "嵌入模型")
+	cmd.Flags().IntVar(&sampleSize, "sample", 50, "最多索引的文档数 (0 表示全部)")
+	cmd.Flags().IntVar(&topK, "top", 5, "每个问题检索的分块数")
+	cmd.Flags().Float64Var(&cost, "cost", 0, "每千 token 嵌入价格，用于估算成本")
+	for i, side := range []string{"a", "b"} {
+		cmd.Flags().StringVar(&variants[i].Strategy, side+"-strategy", "paragraph", "分块策略 (fixed, paragraph, semantic, code, table_aware)")
+		cmd.Flags().IntVar(&variants[i].MaxChunkSize, side+"-max", 0, "最大分块字符数 (0 使用策略默认值)")
+		cmd.Flags().IntVar(&variants[i].MinChunkSize, side+"-min", 0, "最小分块字符数")
+		cmd.Flags().IntVar(&variants[i].OverlapSize, side+"-overlap", 0, "分块重叠字符数")
+	}
+
+	return cmd
+}
@@ -58,6 +58,7 @@ func init() {
 	rootCmd.AddCommand(apiCmd)
 	rootCmd.AddCommand(adminCmd)
 	rootCmd.AddCommand(wwwCmd)
+	rootCmd.AddCommand(mcpCmd)
 
 	// 保持原有命令
 	rootCmd.AddCommand(versionCmd)
@@ -0,0 +1,97 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cassArtifact CASS 分析请求中的代码制品
+type cassArtifact struct {
+	ID        string `json:"id"`
+	TenantID  string `json:"tenant_id"`
+	ProjectID string `json:"project_id"`
+	Type      int    `json:"type"` // 0 为源代码
+	Language  string `json:"language"`
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Content   []byte `json:"content"`
+	Size      int64  `json:"size"`
+}
+
+// cassFinding CASS 分析发现的问题
+type cassFinding struct {
+	ID         string  `json:"id"`
+	Analyzer   string  `json:"analyzer,omitempty"`
+	Type       string  `json:"type"`
+	Severity   string  `json:"severity"`
+	Line       int     `json:"line,omitempty"`
+	Column     int     `json:"column,omitempty"`
+	EndLine    int     `json:"end_line,omitempty"`
+	Message    string  `json:"message"`
+	Rule       string  `json:"rule,omitempty"`
+	Category   string  `json:"category,omitempty"`
+	Suggestion string  `json:"suggestion,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// cassClient CASS 分析服务的 HTTP 客户端
+type cassClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newCASSClient(baseURL string) *cassClient {
+	return &cassClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// analyze 调用 /api/v1/analyze 分析制品，返回各分析器的发现
+func (c *cassClient) analyze(ctx context.Context, artifact *cassArtifact) ([]cassFinding, error) {
+	artifact.Size = int64(len(artifact.Content))
+	body, err := json.Marshal(map[string]interface{}{"artifact": artifact})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/analyze", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("CASS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("CASS analysis failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var result struct {
+		Data []struct {
+			AnalyzerID string        `json:"analyzer_id"`
+			Findings   []cassFinding `json:"findings"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid CASS response: %w", err)
+	}
+
+	findings := []cassFinding{}
+	for _, analysis := range result.Data {
+		for _, finding := range analysis.Findings {
+			finding.Analyzer = analysis.AnalyzerID
+			findings = append(findings, finding)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,230 @@
+// Package mcp 实现 Model Context Protocol 服务器，把项目的 RAG 检索、文档获取和
+// CASS 代码分析结果以工具的形式提供给 Claude Desktop、Cursor 等智能 IDE 工具
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/guileen/metabase/internal/app/api/keys"
+	"github.com/guileen/metabase/internal/app/api/rag"
+	"github.com/guileen/metabase/pkg/rag/core"
+	"go.uber.org/zap"
+)
+
+// ScopeAll 允许调用全部工具的 API 密钥权限，mcp:<工具名> 只允许调用单个工具
+const ScopeAll = "mcp"
+
+// ErrUnauthorized API 密钥无效或无权访问项目
+var ErrUnauthorized = errors.New("unauthorized")
+
+// KeyValidator 校验 API 密钥，*keys.Manager 实现了该接口
+type KeyValidator interface {
+	Validate(ctx context.Context, key string) (*keys.APIKey, error)
+}
+
+// SourceStore 列出项目的数据源，*rag.Manager 实现了该接口
+type SourceStore interface {
+	ListDataSources(ctx context.Context, projectID string) ([]rag.DataSource, error)
+}
+
+// Retriever 检索和获取文档，*core.Pipeline 实现了该接口
+type Retriever interface {
+	Search(ctx context.Context, query string, options core.QueryOptions) ([]core.RetrievalResult, error)
+	GetDocument(ctx context.Context, documentID string) (*core.Document, error)
+}
+
+// Config MCP 服务器配置
+type Config struct {
+	// Tools 允许提供的工具，为空时提供全部工具
+	Tools []string `json:"tools,omitempty"`
+
+	// CASSURL CASS 分析服务地址，如 http://localhost:7620；为空时不提供 cass_findings
+	CASSURL string `json:"cass_url,omitempty"`
+}
+
+// Server MCP 服务器
+type Server struct {
+	config  *Config
+	keys    KeyValidator
+	sources SourceStore
+	cass    *cassClient
+	logger  *zap.Logger
+
+	mu        sync.RWMutex
+	retriever Retriever
+	tools     map[string]*tool
+	order     []string
+
+	sessionsMu sync.Mutex
+	sessions   map[string]*sseSession
+}
+
+// tool 已注册的工具
+type tool struct {
+	definition Tool
+	call       func(ctx context.Context, sess *Session, args json.RawMessage) (interface{}, error)
+}
+
+// Session 已认证的客户端会话，限定在一个项目内
+type Session struct {
+	Key       *keys.APIKey
+	ProjectID string
+}
+
+// NewServer 创建 MCP 服务器
+func NewServer(cfg *Config, keyValidator KeyValidator, sources SourceStore, logger *zap.Logger) *Server {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	s := &Server{
+		config:   cfg,
+		keys:     keyValidator,
+		sources:  sources,
+		logger:   logger,
+		tools:    make(map[string]*tool),
+		sessions: make(map[string]*sseSession),
+	}
+	if cfg.CASSURL != "" {
+		s.cass = newCASSClient(cfg.CASSURL)
+	}
+	s.registerTools()
+	return s
+}
+
+// SetRetriever 设置 RAG 检索管道，未设置时 RAG 工具返回错误
+func (s *Server) SetRetriever(retriever Retriever) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retriever = retriever
+}
+
+func (s *Server) getRetriever() Retriever {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.retriever
+}
+
+// register 注册工具，不在配置允许列表中的工具被忽略
+func (s *Server) register(t *tool) {
+	if len(s.config.Tools) > 0 && !contains(s.config.Tools, t.definition.Name) {
+		return
+	}
+	s.tools[t.definition.Name] = t
+	s.order = append(s.order, t.definition.Name)
+}
+
+// Authenticate 校验 API 密钥并确定会话项目；项目密钥只能访问其所属项目，
+// projectID 为空时使用密钥所属项目
+func (s *Server) Authenticate(ctx context.Context, apiKey, projectID string) (*Session, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: API key required", ErrUnauthorized)
+	}
+	key, err := s.keys.Validate(ctx, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid API key", ErrUnauthorized)
+	}
+
+	if projectID == "" && key.ProjectID != nil {
+		projectID = *key.ProjectID
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("%w: project required", ErrUnauthorized)
+	}
+	if !key.CanAccessProject(projectID) {
+		return nil, fmt.Errorf("%w: API key cannot access project %s", ErrUnauthorized, projectID)
+	}
+	return &Session{Key: key, ProjectID: projectID}, nil
+}
+
+// allowed 检查会话是否可以调用工具：系统密钥可调用全部工具，其他密钥需要 mcp 或 mcp:<工具名> 权限
+func (sess *Session) allowed(name string) bool {
+	return sess.Key.Type == keys.KeyTypeSystem || sess.Key.HasScope(ScopeAll) || sess.Key.HasScope(ScopeAll+":"+name)
+}
+
+// availableTools 返回会话可以调用的工具定义
+func (s *Server) availableTools(sess *Session) []Tool {
+	tools := []Tool{}
+	for _, name := range s.order {
+		if sess.allowed(name) {
+			tools = append(tools, s.tools[name].definition)
+		}
+	}
+	return tools
+}
+
+// handleMessage 处理一条 JSON-RPC 消息，通知没有响应时返回 nil
+func (s *Server) handleMessage(ctx context.Context, sess *Session, data []byte) *response {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return newErrorResponse(nil, codeParseError, "parse error")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return newErrorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+	if req.ID == nil {
+		// 通知（如 notifications/initialized）无需响应
+		return nil
+	}
+
+	switch req.Method {
+	case "initialize":
+		return newResponse(req.ID, initializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
+			ServerInfo:      serverInfo{Name: "metabase", Version: "1.0.0"},
+		})
+	case "ping":
+		return newResponse(req.ID, map[string]interface{}{})
+	case "tools/list":
+		return newResponse(req.ID, map[string]interface{}{"tools": s.availableTools(sess)})
+	case "tools/call":
+		var params callToolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return newErrorResponse(req.ID, codeInvalidParams, "invalid params")
+		}
+		t, ok := s.tools[params.Name]
+		if !ok || !sess.allowed(params.Name) {
+			return newErrorResponse(req.ID, codeInvalidParams, "unknown tool: "+params.Name)
+		}
+		return newResponse(req.ID, s.callTool(ctx, sess, t, params.Arguments))
+	default:
+		return newErrorResponse(req.ID, codeMethodNotFound, "method not found: "+req.Method)
+	}
+}
+
+// callTool 执行工具，执行失败作为工具结果返回给模型而不是协议错误
+func (s *Server) callTool(ctx context.Context, sess *Session, t *tool, args json.RawMessage) toolResult {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	result, err := t.call(ctx, sess, args)
+	if err != nil {
+		s.logger.Warn("mcp tool call failed",
+			zap.String("tool", t.definition.Name),
+			zap.String("project_id", sess.ProjectID),
+			zap.Error(err))
+		return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: string(data)}}}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/guileen/metabase/internal/app/api/keys"
+	"github.com/guileen/metabase/internal/app/api/rag"
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+type fakeKeys map[string]*keys.APIKey
+
+func (f fakeKeys) Validate(ctx context.Context, key string) (*keys.APIKey, error) {
+	if k, ok := f[key]; ok {
+		return k, nil
+	}
+	return nil, errors.New("invalid API key")
+}
+
+type fakeSources map[string][]rag.DataSource
+
+func (f fakeSources) ListDataSources(ctx context.Context, projectID string) ([]rag.DataSource, error) {
+	return f[projectID], nil
+}
+
+type fakeRetriever struct {
+	docs    map[string]*core.Document
+	options core.QueryOptions
+}
+
+func (f *fakeRetriever) Search(ctx context.Context, query string, options core.QueryOptions) ([]core.RetrievalResult, error) {
+	f.options = options
+	doc := f.docs["d1"]
+	return []core.RetrievalResult{{
+		DocumentID: doc.ID,
+		Document:   doc,
+		Chunk:      &core.DocumentChunk{Content: "func main() {}", StartLine: 3},
+		Score:      0.9,
+	}}, nil
+}
+
+func (f *fakeRetriever) GetDocument(ctx context.Context, documentID string) (*core.Document, error) {
+	return f.docs[documentID], nil
+}
+
+func stringPtr(s string) *string { return &s }
+
+func newTestServer(cfg *Config) (*Server, *fakeRetriever) {
+	validator := fakeKeys{
+		"all":    {ProjectID: stringPtr("p1"), Scopes: []string{"mcp"}},
+		"search": {ProjectID: stringPtr("p1"), Scopes: []string{"mcp:rag_search"}},
+		"none":   {ProjectID: stringPtr("p1"), Scopes: []string{"read"}},
+		"system": {Type: keys.KeyTypeSystem},
+	}
+	sources := fakeSources{
+		"p1": {{ID: "s1", ProjectID: "p1"}},
+		"p2": {{ID: "s2", ProjectID: "p2"}},
+	}
+	retriever := &fakeRetriever{docs: map[string]*core.Document{
+		"d1": {ID: "d1", Title: "main.go", Content: "package main", DataSourceID: "s1"},
+		"d2": {ID: "d2", Title: "secret.go", Content: "package secret", DataSourceID: "s2"},
+	}}
+	server := NewServer(cfg, validator, sources, nil)
+	server.SetRetriever(retriever)
+	return server, retriever
+}
+
+// exchange runs a stdio session and returns the responses by request ID
+func exchange(t *testing.T, server *Server, apiKey string, messages ...string) map[string]response {
+	t.Helper()
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(messages, "\n"))
+	if err := server.ServeStdio(context.Background(), in, &out, apiKey, ""); err != nil {
+		t.Fatalf("ServeStdio failed: %v", err)
+	}
+
+	responses := map[string]response{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var resp response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", scanner.Text(), err)
+		}
+		responses[string(resp.ID)] = resp
+	}
+	return responses
+}
+
+func call(id int, tool string, args string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, id, tool, args)
+}
+
+func toolText(t *testing.T, resp response) (string, bool) {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("unexpected protocol error: %+v", resp.Error)
+	}
+	data, _ := json.Marshal(resp.Result)
+	var result toolResult
+	json.Unmarshal(data, &result)
+	if len(result.Content) != 1 {
+		t.Fatalf("unexpected tool result: %s", data)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestStdioTools(t *testing.T) {
+	server, retriever := newTestServer(nil)
+	responses := exchange(t, server, "all",
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		call(3, ToolRAGSearch, `{"query":"main"}`),
+		call(4, ToolFetchDocument, `{"document_id":"d1"}`),
+		call(5, ToolFetchDocument, `{"document_id":"d2"}`),
+		`{"jsonrpc":"2.0","id":6,"method":"unknown"}`,
+	)
+	if len(responses) != 6 {
+		t.Fatalf("expected 6 responses, got %d", len(responses))
+	}
+
+	var list struct {
+		Tools []Tool `json:"tools"`
+	}
+	data, _ := json.Marshal(responses["2"].Result)
+	json.Unmarshal(data, &list)
+	// cass_findings is not offered without a CASS URL
+	if len(list.Tools) != 2 || list.Tools[0].Name != ToolRAGSearch {
+		t.Fatalf("unexpected tools: %s", data)
+	}
+
+	text, isError := toolText(t, responses["3"])
+	if isError || !strings.Contains(text, "func main() {}") {
+		t.Fatalf("unexpected search result: %s", text)
+	}
+	expr := retriever.options.RetrievalOptions.FilterOptions.Expression
+	if retriever.options.ProjectID != "p1" || expr == nil || expr.Field != "source" || fmt.Sprint(expr.Value) != "[s1]" {
+		t.Fatalf("search not scoped to project sources: %+v", retriever.options)
+	}
+
+	if text, isError := toolText(t, responses["4"]); isError || !strings.Contains(text, "package main") {
+		t.Fatalf("unexpected document: %s", text)
+	}
+	// Documents of other projects are not found
+	if text, isError := toolText(t, responses["5"]); !isError || strings.Contains(text, "package secret") {
+		t.Fatalf("expected other project's document to be hidden, got %s", text)
+	}
+	if responses["6"].Error == nil || responses["6"].Error.Code != codeMethodNotFound {
+		t.Fatalf("expected method not found, got %+v", responses["6"])
+	}
+}
+
+func TestToolAllowlists(t *testing.T) {
+	// Per-key scopes
+	server, _ := newTestServer(nil)
+	responses := exchange(t, server, "search",
+		`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`,
+		call(2, ToolFetchDocument, `{"document_id":"d1"}`),
+	)
+	if data, _ := json.Marshal(responses["1"].Result); strings.Contains(string(data), ToolFetchDocument) {
+		t.Fatalf("tool outside key scopes listed: %s", data)
+	}
+	if responses["2"].Error == nil || responses["2"].Error.Code != codeInvalidParams {
+		t.Fatalf("expected tool outside key scopes to be rejected, got %+v", responses["2"])
+	}
+
+	// Server configuration
+	server, _ = newTestServer(&Config{Tools: []string{ToolRAGSearch}, CASSURL: "http://cass"})
+	responses = exchange(t, server, "all", call(1, ToolFetchDocument, `{"document_id":"d1"}`))
+	if responses["1"].Error == nil {
+		t.Fatalf("expected tool outside configured allowlist to be rejected")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	server, _ := newTestServer(nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		key, project string
+		ok           bool
+	}{
+		{"all", "", true},
+		{"all", "p2", false},
+		{"missing", "", false},
+		{"system", "", false},
+		{"system", "p2", true},
+	}
+	for _, tt := range tests {
+		sess, err := server.Authenticate(ctx, tt.key, tt.project)
+		if (err == nil) != tt.ok {
+			t.Errorf("Authenticate(%q, %q) error = %v", tt.key, tt.project, err)
+		}
+		if err != nil && !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("expected ErrUnauthorized, got %v", err)
+		}
+		if err == nil && tt.project != "" && sess.ProjectID != tt.project {
+			t.Errorf("unexpected session project %s", sess.ProjectID)
+		}
+	}
+
+	// A valid key without MCP scopes sees no tools
+	responses := exchange(t, server, "none", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if data, _ := json.Marshal(responses["1"].Result); string(data) != `{"tools":[]}` {
+		t.Fatalf("expected no tools, got %s", data)
+	}
+}
+
+func TestSSETransport(t *testing.T) {
+	server, _ := newTestServer(nil)
+	router := chi.NewRouter()
+	router.Mount("/mcp", server.Handler())
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/mcp/sse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without API key, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/mcp/sse", nil)
+	req.Header.Set("Authorization", "Bearer all")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	events := bufio.NewScanner(resp.Body)
+	nextData := func() string {
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				return data
+			}
+		}
+		t.Fatalf("event stream ended: %v", events.Err())
+		return ""
+	}
+
+	endpoint := nextData()
+	if !strings.HasPrefix(endpoint, "/mcp/message?sessionId=") {
+		t.Fatalf("unexpected endpoint %q", endpoint)
+	}
+	post, err := http.Post(ts.URL+endpoint, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", post.StatusCode)
+	}
+	if data := nextData(); data != `{"jsonrpc":"2.0","id":7,"result":{}}` {
+		t.Fatalf("unexpected response %s", data)
+	}
+}
+
+func TestSSETransportOutlivesWriteTimeout(t *testing.T) {
+	server, _ := newTestServer(nil)
+	router := chi.NewRouter()
+	router.Mount("/mcp", server.Handler())
+	ts := httptest.NewUnstartedServer(router)
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/mcp/sse", nil)
+	req.Header.Set("Authorization", "Bearer all")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	events := bufio.NewScanner(resp.Body)
+	nextData := func() string {
+		for events.Scan() {
+			if data, ok := strings.CutPrefix(events.Text(), "data: "); ok {
+				return data
+			}
+		}
+		t.Fatalf("event stream ended: %v", events.Err())
+		return ""
+	}
+
+	endpoint := nextData()
+	time.Sleep(300 * time.Millisecond)
+	post, err := http.Post(ts.URL+endpoint, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":8,"method":"ping"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post.Body.Close()
+	if data := nextData(); data != `{"jsonrpc":"2.0","id":8,"result":{}}` {
+		t.Fatalf("unexpected response %s", data)
+	}
+}
@@ -0,0 +1,279 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+// 工具名称
+const (
+	ToolRAGSearch     = "rag_search"
+	ToolFetchDocument = "rag_fetch_document"
+	ToolCASSFindings  = "cass_findings"
+)
+
+const (
+	defaultSearchResults = 5
+	maxSearchResults     = 20
+)
+
+// errNoPipeline RAG 管道未配置
+var errNoPipeline = errors.New("RAG pipeline not configured")
+
+// registerTools 注册内置工具，未配置 CASS 时不提供 cass_findings
+func (s *Server) registerTools() {
+	s.register(&tool{
+		definition: Tool{
+			Name:        ToolRAGSearch,
+			Description: "Search the project's indexed documents and code. Returns the most relevant passages with their document IDs and scores.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"query":       stringProperty("Natural language or keyword query"),
+				"max_results": map[string]interface{}{"type": "integer", "description": "Maximum number of passages (default 5, at most 20)"},
+				"filter":      stringProperty("Optional filter expression, e.g. tag:go AND lang:en"),
+			}, "query"),
+		},
+		call: s.ragSearch,
+	})
+	s.register(&tool{
+		definition: Tool{
+			Name:        ToolFetchDocument,
+			Description: "Fetch the full content of a project document by ID, e.g. one returned by rag_search.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"document_id": stringProperty("Document ID"),
+			}, "document_id"),
+		},
+		call: s.fetchDocument,
+	})
+	if s.cass != nil {
+		s.register(&tool{
+			definition: Tool{
+				Name:        ToolCASSFindings,
+				Description: "Run code analysis (issues, vulnerabilities, duplicates) on a project document or on the given source code and return the findings.",
+				InputSchema: objectSchema(map[string]interface{}{
+					"document_id": stringProperty("Document ID to analyze"),
+					"content":     stringProperty("Source code to analyze when no document_id is given"),
+					"path":        stringProperty("File path of the source code"),
+					"language":    stringProperty("Programming language of the source code"),
+				}),
+			},
+			call: s.cassFindings,
+		})
+	}
+}
+
+// searchResult rag_search 返回的段落
+type searchResult struct {
+	DocumentID   string  `json:"document_id"`
+	Title        string  `json:"title,omitempty"`
+	Path         string  `json:"path,omitempty"`
+	DataSourceID string  `json:"data_source_id,omitempty"`
+	Score        float64 `json:"score"`
+	StartLine    int     `json:"start_line,omitempty"`
+	EndLine      int     `json:"end_line,omitempty"`
+	Content      string  `json:"content"`
+}
+
+func (s *Server) ragSearch(ctx context.Context, sess *Session, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		Query      string `json:"query"`
+		MaxResults int    `json:"max_results"`
+		Filter     string `json:"filter"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if input.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if input.MaxResults <= 0 {
+		input.MaxResults = defaultSearchResults
+	}
+	if input.MaxResults > maxSearchResults {
+		input.MaxResults = maxSearchResults
+	}
+
+	retriever := s.getRetriever()
+	if retriever == nil {
+		return nil, errNoPipeline
+	}
+	sourceIDs, err := s.projectSources(ctx, sess.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	results := []searchResult{}
+	if len(sourceIDs) == 0 {
+		return results, nil
+	}
+
+	options := core.QueryOptions{
+		ProjectID:  sess.ProjectID,
+		Filter:     input.Filter,
+		MaxResults: input.MaxResults,
+	}
+	if sess.Key.TenantID != nil {
+		options.TenantID = *sess.Key.TenantID
+	}
+	// 只检索项目数据源中的文档
+	options.RetrievalOptions.FilterOptions.Expression = &core.FilterExpr{
+		Field: "source",
+		Op:    core.FilterOpIn,
+		Value: stringsToValues(sourceIDs),
+	}
+
+	retrieved, err := retriever.Search(ctx, input.Query, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range retrieved {
+		result := searchResult{DocumentID: r.DocumentID, Score: r.Score}
+		if r.Document != nil {
+			result.Title = r.Document.Title
+			result.Path = r.Document.Metadata.FilePath
+			result.DataSourceID = r.Document.DataSourceID
+		}
+		if r.Chunk != nil {
+			result.Content = r.Chunk.Content
+			result.StartLine = r.Chunk.StartLine
+			result.EndLine = r.Chunk.EndLine
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// documentResult rag_fetch_document 返回的文档
+type documentResult struct {
+	ID           string `json:"id"`
+	Title        string `json:"title,omitempty"`
+	URI          string `json:"uri,omitempty"`
+	Path         string `json:"path,omitempty"`
+	Language     string `json:"language,omitempty"`
+	DataSourceID string `json:"data_source_id"`
+	Content      string `json:"content"`
+}
+
+func (s *Server) fetchDocument(ctx context.Context, sess *Session, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		DocumentID string `json:"document_id"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	doc, err := s.projectDocument(ctx, sess, input.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	return documentResult{
+		ID:           doc.ID,
+		Title:        doc.Title,
+		URI:          doc.URI,
+		Path:         doc.Metadata.FilePath,
+		Language:     doc.Language,
+		DataSourceID: doc.DataSourceID,
+		Content:      doc.Content,
+	}, nil
+}
+
+func (s *Server) cassFindings(ctx context.Context, sess *Session, args json.RawMessage) (interface{}, error) {
+	var input struct {
+		DocumentID string `json:"document_id"`
+		Content    string `json:"content"`
+		Path       string `json:"path"`
+		Language   string `json:"language"`
+	}
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	artifact := &cassArtifact{
+		ID:        input.DocumentID,
+		ProjectID: sess.ProjectID,
+		Path:      input.Path,
+		Language:  input.Language,
+		Content:   []byte(input.Content),
+	}
+	if sess.Key.TenantID != nil {
+		artifact.TenantID = *sess.Key.TenantID
+	}
+	if input.DocumentID != "" {
+		doc, err := s.projectDocument(ctx, sess, input.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		artifact.Content = []byte(doc.Content)
+		artifact.Path = doc.Metadata.FilePath
+		artifact.Language = doc.Language
+	} else if input.Content == "" {
+		return nil, fmt.Errorf("document_id or content is required")
+	}
+	if artifact.Path != "" {
+		artifact.Name = path.Base(artifact.Path)
+	}
+
+	return s.cass.analyze(ctx, artifact)
+}
+
+// projectSources 返回项目数据源 ID
+func (s *Server) projectSources(ctx context.Context, projectID string) ([]string, error) {
+	sources, err := s.sources.ListDataSources(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(sources))
+	for _, ds := range sources {
+		ids = append(ids, ds.ID)
+	}
+	return ids, nil
+}
+
+// projectDocument 获取文档，不属于会话项目数据源的文档视为不存在
+func (s *Server) projectDocument(ctx context.Context, sess *Session, documentID string) (*core.Document, error) {
+	if documentID == "" {
+		return nil, fmt.Errorf("document_id is required")
+	}
+	retriever := s.getRetriever()
+	if retriever == nil {
+		return nil, errNoPipeline
+	}
+
+	doc, err := retriever.GetDocument(ctx, documentID)
+	if err != nil || doc == nil {
+		return nil, fmt.Errorf("document %s not found", documentID)
+	}
+	sourceIDs, err := s.projectSources(ctx, sess.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(sourceIDs, doc.DataSourceID) {
+		return nil, fmt.Errorf("document %s not found", documentID)
+	}
+	return doc, nil
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func stringProperty(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func stringsToValues(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
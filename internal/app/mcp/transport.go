@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxMessageSize 单条 JSON-RPC 消息的最大长度
+const maxMessageSize = 4 * 1024 * 1024
+
+// ServeStdio 通过标准输入输出提供服务，每行一条 JSON-RPC 消息，直到输入结束或 ctx 取消
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer, apiKey, projectID string) error {
+	sess, err := s.Authenticate(ctx, apiKey, projectID)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// 并发处理请求，慢查询不阻塞 ping 等请求
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp := s.handleMessage(ctx, sess, []byte(line)); resp != nil {
+				writeMu.Lock()
+				defer writeMu.Unlock()
+				if err := encoder.Encode(resp); err != nil {
+					s.logger.Error("failed to write mcp response", zap.Error(err))
+				}
+			}
+		}()
+	}
+	return scanner.Err()
+}
+
+// sseSession SSE 传输的客户端连接
+type sseSession struct {
+	session  *Session
+	ctx      context.Context
+	messages chan *response
+}
+
+// Handler 返回 SSE 传输的 HTTP 处理器：GET /sse 建立事件流，
+// 客户端向 endpoint 事件给出的地址 POST 消息，响应通过事件流返回
+func (s *Server) Handler() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/sse", s.handleSSE)
+	r.Post("/message", s.handlePostMessage)
+	return r
+}
+
+// handleSSE 认证客户端并保持事件流
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.Authenticate(r.Context(), apiKeyFromRequest(r), r.URL.Query().Get("project_id"))
+	if err != nil {
+		render.Status(r, http.StatusUnauthorized)
+		render.JSON(w, r, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, map[string]interface{}{"error": "streaming not supported"})
+		return
+	}
+	// 事件流长期保持，不受服务器写超时的限制
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Warn("failed to clear mcp event stream write deadline", zap.Error(err))
+	}
+
+	id := uuid.New().String()
+	conn := &sseSession{session: sess, ctx: r.Context(), messages: make(chan *response, 16)}
+	s.sessionsMu.Lock()
+	s.sessions[id] = conn
+	s.sessionsMu.Unlock()
+	defer func() {
+		s.sessionsMu.Lock()
+		delete(s.sessions, id)
+		s.sessionsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	endpoint := strings.TrimSuffix(r.URL.Path, "/sse") + "/message?sessionId=" + id
+	fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", endpoint)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case resp := <-conn.messages:
+			data, err := json.Marshal(resp)
+			if err != nil {
+				s.logger.Error("failed to encode mcp response", zap.Error(err))
+				continue
+			}
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+// handlePostMessage 接收客户端消息，异步处理后通过事件流返回响应
+func (s *Server) handlePostMessage(w http.ResponseWriter, r *http.Request) {
+	s.sessionsMu.Lock()
+	conn := s.sessions[r.URL.Query().Get("sessionId")]
+	s.sessionsMu.Unlock()
+	if conn == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, map[string]interface{}{"error": "Session not found"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, map[string]interface{}{"error": "Invalid request body"})
+		return
+	}
+
+	go func() {
+		resp := s.handleMessage(conn.ctx, conn.session, data)
+		if resp == nil {
+			return
+		}
+		select {
+		case conn.messages <- resp:
+		case <-conn.ctx.Done():
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// apiKeyFromRequest 从 apikey 头、Bearer 令牌或 apikey 查询参数读取 API 密钥
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("apikey"); key != "" {
+		return key
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.URL.Query().Get("apikey")
+}
@@ -3,12 +3,14 @@ package authgateway
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/guileen/metabase/pkg/common/errors"
 	"github.com/guileen/metabase/pkg/infra/auth"
+	"github.com/guileen/metabase/pkg/infra/kv"
 )
 
 // AuthGatewayManager manages the unified authentication gateway
@@ -171,13 +173,11 @@ type TokenResult struct {
 	TokenType    string `json:"token_type"`
 }
 
-// AuthGatewayCache provides caching for authentication gateway
+// AuthGatewayCache caches user info in a kv.Store, shared between instances
+// when the store is
 type AuthGatewayCache struct {
-	userInfo map[string]*UserInfo
-	sessions map[string]*AuthSession
-	mu       sync.RWMutex
-	ttl      time.Duration
-	maxSize  int
+	store kv.Store
+	ttl   time.Duration
 }
 
 // AuthSession represents an authentication session
@@ -277,7 +277,7 @@ func NewAuthGatewayManager(db *sql.DB, authMgr *auth.Manager, rbac *auth.RBACMan
 		authMgr:   authMgr,
 		rbac:      rbac,
 		config:    config,
-		cache:     NewAuthGatewayCache(config.SessionTimeout/4, 10000),
+		cache:     NewAuthGatewayCache(kv.NewMemoryStore(10000), config.SessionTimeout/4),
 		providers: make(map[string]AuthProvider),
 	}
 
@@ -291,15 +291,27 @@ func NewAuthGatewayManager(db *sql.DB, authMgr *auth.Manager, rbac *auth.RBACMan
 }
 
 // NewAuthGatewayCache creates a new authentication gateway cache
-func NewAuthGatewayCache(ttl time.Duration, maxSize int) *AuthGatewayCache {
+func NewAuthGatewayCache(store kv.Store, ttl time.Duration) *AuthGatewayCache {
 	return &AuthGatewayCache{
-		userInfo: make(map[string]*UserInfo),
-		sessions: make(map[string]*AuthSession),
-		ttl:      ttl,
-		maxSize:  maxSize,
+		store: store,
+		ttl:   ttl,
 	}
 }
 
+// SetCacheStore moves the user info cache to store, which must be shared
+// between instances in multi-instance deployments
+func (agm *AuthGatewayManager) SetCacheStore(store kv.Store) {
+	agm.mu.Lock()
+	defer agm.mu.Unlock()
+	agm.cache = NewAuthGatewayCache(store, agm.config.SessionTimeout/4)
+}
+
+func (agm *AuthGatewayManager) userCache() *AuthGatewayCache {
+	agm.mu.RLock()
+	defer agm.mu.RUnlock()
+	return agm.cache
+}
+
 // Authenticate handles authentication requests
 func (agm *AuthGatewayManager) Authenticate(ctx context.Context, req *AuthRequest) (*AuthResult, error) {
 	// Validate request
@@ -377,7 +389,7 @@ func (agm *AuthGatewayManager) Authenticate(ctx context.Context, req *AuthReques
 		UpdatedAt: time.Now(),
 	}
 
-	agm.cache.SetUserInfo(authResult.UserID, userInfo)
+	agm.userCache().SetUserInfo(ctx, authResult.UserID, userInfo)
 
 	// Log successful authentication
 	agm.logSuccessfulAuth(ctx, req, authResult.UserID)
@@ -435,18 +447,13 @@ func (agm *AuthGatewayManager) RegisterUser(ctx context.Context, req *UserRegist
 	}
 
 	// Cache user info
-	agm.cache.SetUserInfo(userInfo.ID, userInfo)
+	agm.userCache().SetUserInfo(ctx, userInfo.ID, userInfo)
 
 	return userInfo, nil
 }
 
 // ValidateToken validates an access token
 func (agm *AuthGatewayManager) ValidateToken(ctx context.Context, token string) (*UserInfo, error) {
-	// Check cache first
-	if userInfo := agm.cache.GetUserInfoByToken(token); userInfo != nil {
-		return userInfo, nil
-	}
-
 	// Validate with auth manager
 	session, err := agm.authMgr.ValidateSession(ctx, token)
 	if err != nil {
@@ -460,7 +467,7 @@ func (agm *AuthGatewayManager) ValidateToken(ctx context.Context, token string)
 	}
 
 	// Update cache
-	agm.cache.SetUserInfo(userInfo.ID, userInfo)
+	agm.userCache().SetUserInfo(ctx, userInfo.ID, userInfo)
 
 	return userInfo, nil
 }
@@ -488,7 +495,7 @@ func (agm *AuthGatewayManager) Logout(ctx context.Context, token string) error {
 		// Token might be invalid, but still proceed with cache cleanup
 	} else {
 		// Remove from cache
-		agm.cache.DeleteUserInfo(session.UserID)
+		agm.userCache().DeleteUserInfo(ctx, session.UserID)
 	}
 
 	// Invalidate session using auth manager
@@ -502,7 +509,7 @@ func (agm *AuthGatewayManager) Logout(ctx context.Context, token string) error {
 // GetUserInfo retrieves user information
 func (agm *AuthGatewayManager) GetUserInfo(ctx context.Context, userID string) (*UserInfo, error) {
 	// Check cache first
-	if userInfo := agm.cache.GetUserInfo(userID); userInfo != nil {
+	if userInfo := agm.userCache().GetUserInfo(ctx, userID); userInfo != nil {
 		return userInfo, nil
 	}
 
@@ -513,7 +520,7 @@ func (agm *AuthGatewayManager) GetUserInfo(ctx context.Context, userID string) (
 	}
 
 	// Update cache
-	agm.cache.SetUserInfo(userID, userInfo)
+	agm.userCache().SetUserInfo(ctx, userID, userInfo)
 
 	return userInfo, nil
 }
@@ -526,7 +533,7 @@ func (agm *AuthGatewayManager) UpdateUserInfo(ctx context.Context, userInfo *Use
 	}
 
 	// Update cache
-	agm.cache.SetUserInfo(userInfo.ID, userInfo)
+	agm.userCache().SetUserInfo(ctx, userInfo.ID, userInfo)
 
 	return nil
 }
@@ -863,35 +870,33 @@ func (agm *AuthGatewayManager) getProviderFeatures(name string) []string {
 }
 
 // Cache methods
-func (cache *AuthGatewayCache) GetUserInfo(userID string) *UserInfo {
-	cache.mu.RLock()
-	defer cache.mu.RUnlock()
-	return cache.userInfo[userID]
-}
-
-func (cache *AuthGatewayCache) GetUserInfoByToken(token string) *UserInfo {
-	cache.mu.RLock()
-	defer cache.mu.RUnlock()
-	for _, userInfo := range cache.userInfo {
-		// TODO: Implement token-based lookup
-		_ = userInfo // suppress unused variable warning
+func (cache *AuthGatewayCache) GetUserInfo(ctx context.Context, userID string) *UserInfo {
+	data, err := cache.store.Get(ctx, userInfoCacheKey(userID))
+	if err != nil {
+		return nil
 	}
-	return nil
+	var userInfo UserInfo
+	if err := json.Unmarshal(data, &userInfo); err != nil {
+		return nil
+	}
+	return &userInfo
 }
 
-func (cache *AuthGatewayCache) SetUserInfo(userID string, userInfo *UserInfo) {
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
-
-	if len(cache.userInfo) >= cache.maxSize {
-		// TODO: Implement LRU eviction
+func (cache *AuthGatewayCache) SetUserInfo(ctx context.Context, userID string, userInfo *UserInfo) {
+	if cache.ttl <= 0 {
+		return
 	}
+	data, err := json.Marshal(userInfo)
+	if err != nil {
+		return
+	}
+	cache.store.Set(ctx, userInfoCacheKey(userID), data, cache.ttl)
+}
 
-	cache.userInfo[userID] = userInfo
+func (cache *AuthGatewayCache) DeleteUserInfo(ctx context.Context, userID string) {
+	cache.store.Delete(ctx, userInfoCacheKey(userID))
 }
 
-func (cache *AuthGatewayCache) DeleteUserInfo(userID string) {
-	cache.mu.Lock()
-	defer cache.mu.Unlock()
-	delete(cache.userInfo, userID)
+func userInfoCacheKey(userID string) string {
+	return "authgateway:user:" + userID
 }
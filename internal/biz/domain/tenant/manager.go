@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -50,6 +53,7 @@ type TenantSettings struct {
 	Integration   *IntegrationSettings   `json:"integration,omitempty"`
 	Features      map[string]bool        `json:"features,omitempty"`
 	Custom        map[string]interface{} `json:"custom,omitempty"`
+	Locale        string                 `json:"locale,omitempty"`
 }
 
 // ThemeSettings represents theme configuration
@@ -97,6 +101,9 @@ type StorageSettings struct {
 	AllowedTypes  []string `json:"allowed_types,omitempty"`
 	AutoDelete    bool     `json:"auto_delete,omitempty"`
 	RetentionDays int      `json:"retention_days,omitempty"`
+
+	// Chat transcripts are deleted this many days after their last message, kept forever when 0
+	ChatRetentionDays int `json:"chat_retention_days,omitempty"`
 }
 
 // APISettings represents API configuration
@@ -105,6 +112,26 @@ type APISettings struct {
 	CORS      *CORSConfig      `json:"cors,omitempty"`
 	Webhooks  []string         `json:"webhooks,omitempty"`
 	APIKeys   bool             `json:"api_keys_enabled,omitempty"`
+
+	// Anonymous read-only access to projects marked public, off unless enabled
+	PublicAccess *PublicAccessConfig `json:"public_access,omitempty"`
+}
+
+// PublicAccessConfig represents the tenant's opt-in to anonymous access
+type PublicAccessConfig struct {
+	Enabled           bool `json:"enabled"`
+	RequestsPerMinute int  `json:"requests_per_minute,omitempty"` // Per client address, defaults to 10
+}
+
+// MaxPublicRequestsPerMinute caps the anonymous rate a tenant may allow
+const MaxPublicRequestsPerMinute = 120
+
+// Validate checks that the anonymous rate limit is within bounds
+func (c *PublicAccessConfig) Validate() error {
+	if c.RequestsPerMinute < 0 || c.RequestsPerMinute > MaxPublicRequestsPerMinute {
+		return fmt.Errorf("public_access requests_per_minute must be between 0 and %d", MaxPublicRequestsPerMinute)
+	}
+	return nil
 }
 
 // RateLimitConfig represents rate limit configuration
@@ -121,15 +148,61 @@ type CORSConfig struct {
 	MaxAge         int      `json:"max_age,omitempty"`
 }
 
+// Validate checks that origins are "*" or scheme://host and that methods
+// are HTTP method names
+func (c *CORSConfig) Validate() error {
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("invalid CORS origin %q (expected * or scheme://host)", origin)
+		}
+	}
+	for _, method := range c.AllowedMethods {
+		if method == "" || strings.ToUpper(method) != method || strings.ContainsAny(method, " ,") {
+			return fmt.Errorf("invalid CORS method %q", method)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("CORS max_age must not be negative")
+	}
+	return nil
+}
+
 // NotificationSettings represents notification configuration
 type NotificationSettings struct {
 	Email    bool     `json:"email_notifications,omitempty"`
+	EmailTo  []string `json:"email_to,omitempty"`
 	SMS      bool     `json:"sms_notifications,omitempty"`
 	Slack    string   `json:"slack_webhook,omitempty"`
 	Discord  string   `json:"discord_webhook,omitempty"`
+	Webhook  string   `json:"webhook_url,omitempty"`
 	Channels []string `json:"channels,omitempty"`
 }
 
+// Validate checks that email recipients are addresses and webhooks are
+// http(s) URLs
+func (n *NotificationSettings) Validate() error {
+	for _, address := range n.EmailTo {
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid notification email %q", address)
+		}
+	}
+	for _, webhook := range []string{n.Slack, n.Discord, n.Webhook} {
+		if webhook == "" {
+			continue
+		}
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid notification webhook %q", webhook)
+		}
+	}
+	return nil
+}
+
 // IntegrationSettings represents third-party integrations
 type IntegrationSettings struct {
 	Google *GoogleIntegration     `json:"google,omitempty"`
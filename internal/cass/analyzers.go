@@ -940,20 +940,7 @@ func (q *QualityAnalyzer) Analyze(ctx context.Context, artifact *Artifact) (*Ana
 
 		// Create findings for poor metrics
 		if q.isPoorMetric(name, value) {
-			result.Findings = append(result.Findings, Finding{
-				ID:         generateID(),
-				Type:       "quality",
-				Severity:   q.getMetricSeverity(name, value),
-				Message:    q.getMetricMessage(name, value),
-				Rule:       fmt.Sprintf("QUALITY-%s", strings.ToUpper(name)),
-				Category:   "quality",
-				Suggestion: q.getMetricSuggestion(name),
-				Confidence: 0.9,
-				Metadata: map[string]interface{}{
-					"metric_name":  name,
-					"metric_value": value,
-				},
-			})
+			result.Findings = append(result.Findings, q.metricFinding(name, value))
 		}
 	}
 
@@ -965,6 +952,51 @@ func (q *QualityAnalyzer) Analyze(ctx context.Context, artifact *Artifact) (*Ana
 	return result, nil
 }
 
+// ApplyMeasuredCoverage replaces the estimated test coverage of a quality
+// result with coverage measured by a coverage report
+func (q *QualityAnalyzer) ApplyMeasuredCoverage(result *AnalysisResult, coverage float64) {
+	rule := q.metricRule("test_coverage")
+	findings := result.Findings[:0]
+	for _, finding := range result.Findings {
+		if finding.Rule != rule {
+			findings = append(findings, finding)
+		}
+	}
+	result.Findings = findings
+
+	result.Metrics["test_coverage"] = coverage
+	if q.isPoorMetric("test_coverage", coverage) {
+		finding := q.metricFinding("test_coverage", coverage)
+		finding.Confidence = 1.0
+		finding.Metadata["measured"] = true
+		result.Findings = append(result.Findings, finding)
+	}
+	result.Score = q.calculateQualityScore(result.Metrics)
+}
+
+// metricFinding creates the finding for a poor metric
+func (q *QualityAnalyzer) metricFinding(name string, value float64) Finding {
+	return Finding{
+		ID:         generateID(),
+		Type:       "quality",
+		Severity:   q.getMetricSeverity(name, value),
+		Message:    q.getMetricMessage(name, value),
+		Rule:       q.metricRule(name),
+		Category:   "quality",
+		Suggestion: q.getMetricSuggestion(name),
+		Confidence: 0.9,
+		Metadata: map[string]interface{}{
+			"metric_name":  name,
+			"metric_value": value,
+		},
+	}
+}
+
+// metricRule returns the rule ID of a metric
+func (q *QualityAnalyzer) metricRule(name string) string {
+	return fmt.Sprintf("QUALITY-%s", strings.ToUpper(name))
+}
+
 // calculateComplexity calculates cyclomatic complexity
 func (q *QualityAnalyzer) calculateComplexity(artifact *Artifact) float64 {
 	content := string(artifact.Content)
@@ -997,7 +1029,8 @@ func (q *QualityAnalyzer) calculateMaintainability(artifact *Artifact) float64 {
 	return math.Max(0, maintainability)
 }
 
-// estimateTestCoverage estimates test coverage
+// estimateTestCoverage estimates test coverage from test keywords. The CI
+// runner replaces it with measured coverage when coverage reports are given.
 func (q *QualityAnalyzer) estimateTestCoverage(artifact *Artifact) float64 {
 	// Check if it's a test file
 	isTest := strings.Contains(strings.ToLower(artifact.Path), "test") ||
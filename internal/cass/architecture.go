@@ -0,0 +1,258 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LayerRule restricts what the files of a layer may import, e.g.
+//
+//   - id: app-no-rag-internals
+//     from: internal/app/**
+//     forbid: [pkg/biz/rag/internal]
+//   - id: handlers-no-sql
+//     from: "**/handlers/**"
+//     forbid: [database/sql]
+//
+// File patterns match paths from the repository root. Import patterns also
+// match after a path prefix, so Go import paths match without the module path,
+// and relative JavaScript imports are resolved against the importing file.
+// A pattern without wildcards matches the path and everything below it.
+type LayerRule struct {
+	ID          string   `yaml:"id" json:"id"`
+	Description string   `yaml:"description" json:"description"`
+	From        string   `yaml:"from" json:"from"`                       // Files the rule applies to
+	Forbid      []string `yaml:"forbid" json:"forbid"`                   // Imports the files may not use
+	Allow       []string `yaml:"allow,omitempty" json:"allow,omitempty"` // Exceptions to Forbid
+	Severity    string   `yaml:"severity,omitempty" json:"severity,omitempty"`
+}
+
+// Validate checks that the rule names its files and forbidden imports
+func (l *LayerRule) Validate() error {
+	if l.ID == "" {
+		return fmt.Errorf("architecture rule id is required")
+	}
+	if l.From == "" {
+		return fmt.Errorf("architecture rule %s: from is required", l.ID)
+	}
+	if len(l.Forbid) == 0 {
+		return fmt.Errorf("architecture rule %s: forbid is required", l.ID)
+	}
+	switch l.Severity {
+	case "", "low", "medium", "high", "critical":
+	default:
+		return fmt.Errorf("architecture rule %s: invalid severity %s", l.ID, l.Severity)
+	}
+	return nil
+}
+
+// compiledLayerRule is a layer rule with its patterns compiled
+type compiledLayerRule struct {
+	LayerRule
+	from   *regexp.Regexp
+	forbid []*regexp.Regexp
+	allow  []*regexp.Regexp
+}
+
+// ArchitectureAnalyzer validates the imports of each artifact against layer rules
+type ArchitectureAnalyzer struct {
+	*BaseAnalyzer
+	layerMu sync.RWMutex
+	layers  []*compiledLayerRule
+}
+
+// NewArchitectureAnalyzer creates an architecture analyzer enforcing the layer rules
+func NewArchitectureAnalyzer(rules []LayerRule) (*ArchitectureAnalyzer, error) {
+	analyzer := &ArchitectureAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(
+			"architecture-analyzer",
+			"Architecture Conformance Analyzer",
+			"1.0.0",
+			CapabilityAnalyze|CapabilityValidate,
+		),
+	}
+
+	// Set supported languages
+	analyzer.languages = []string{"go", "javascript", "typescript", "python", "java", "c", "cpp"}
+
+	for _, rule := range rules {
+		if err := analyzer.AddLayerRule(rule); err != nil {
+			return nil, err
+		}
+	}
+	return analyzer, nil
+}
+
+// AddLayerRule adds a layer rule
+func (a *ArchitectureAnalyzer) AddLayerRule(rule LayerRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if rule.Severity == "" {
+		rule.Severity = "high"
+	}
+
+	compiled := &compiledLayerRule{LayerRule: rule, from: layerPattern(rule.From, true)}
+	for _, pattern := range rule.Forbid {
+		compiled.forbid = append(compiled.forbid, layerPattern(pattern, false))
+	}
+	for _, pattern := range rule.Allow {
+		compiled.allow = append(compiled.allow, layerPattern(pattern, false))
+	}
+
+	a.layerMu.Lock()
+	a.layers = append(a.layers, compiled)
+	a.layerMu.Unlock()
+
+	a.AddRule(Rule{
+		ID:          rule.ID,
+		Name:        "Layer Rule",
+		Description: rule.Description,
+		Type:        "architecture",
+		Severity:    rule.Severity,
+		Pattern:     rule.From,
+		Enabled:     true,
+		Config: map[string]interface{}{
+			"forbid": rule.Forbid,
+			"allow":  rule.Allow,
+		},
+	})
+	return nil
+}
+
+// Analyze reports the imports of the artifact that its layer rules forbid
+func (a *ArchitectureAnalyzer) Analyze(ctx context.Context, artifact *Artifact) (*AnalysisResult, error) {
+	start := time.Now()
+	result := &AnalysisResult{
+		ArtifactID:  artifact.ID,
+		AnalyzerID:  a.ID(),
+		Type:        "architecture",
+		Findings:    make([]Finding, 0),
+		Metrics:     make(map[string]float64),
+		ProcessedAt: time.Now(),
+	}
+
+	filePath := path.Clean(strings.ReplaceAll(artifact.Path, "\\", "/"))
+	content := string(artifact.Content)
+	imports := extractImports(artifact.Path, artifact.Language, content)
+
+	a.layerMu.RLock()
+	defer a.layerMu.RUnlock()
+	applied := 0
+	for _, rule := range a.layers {
+		if !rule.from.MatchString(filePath) {
+			continue
+		}
+		applied++
+		for _, imported := range imports {
+			if !rule.forbids(importTargets(filePath, imported)) {
+				continue
+			}
+			line := importLine(content, imported)
+			result.Findings = append(result.Findings, Finding{
+				ID:         generateID(),
+				Type:       "issue",
+				Severity:   rule.Severity,
+				Line:       line,
+				Message:    fmt.Sprintf("%s must not import %s", filePath, imported),
+				Rule:       rule.ID,
+				Category:   "architecture",
+				Context:    rule.Description,
+				Suggestion: "Depend on an interface of an allowed layer instead, or move the code to the layer that owns the dependency",
+				Confidence: 1.0,
+				Metadata: map[string]interface{}{
+					"from":   rule.From,
+					"import": imported,
+				},
+			})
+		}
+	}
+
+	result.Metrics["imports"] = float64(len(imports))
+	result.Metrics["rules_applied"] = float64(applied)
+	result.Metrics["violations"] = float64(len(result.Findings))
+	result.Score = 100.0
+	if len(result.Findings) > 0 {
+		result.Score = 0.0
+	}
+	result.Duration = time.Since(start)
+	result.Confidence = 1.0
+
+	return result, nil
+}
+
+// forbids reports whether any of the import's targets is forbidden and not allowed
+func (r *compiledLayerRule) forbids(targets []string) bool {
+	for _, target := range targets {
+		for _, allow := range r.allow {
+			if allow.MatchString(target) {
+				return false
+			}
+		}
+	}
+	for _, target := range targets {
+		for _, forbid := range r.forbid {
+			if forbid.MatchString(target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importTargets returns the import as written and, for relative imports, the
+// path it resolves to
+func importTargets(filePath, imported string) []string {
+	targets := []string{imported}
+	if strings.HasPrefix(imported, "./") || strings.HasPrefix(imported, "../") {
+		targets = append(targets, path.Join(path.Dir(filePath), imported))
+	}
+	return targets
+}
+
+// importLine finds the line of an import, or 0 when it cannot be located
+func importLine(content, imported string) int {
+	for _, quoted := range []string{`"` + imported + `"`, `'` + imported + `'`, "<" + imported + ">", imported} {
+		if index := strings.Index(content, quoted); index >= 0 {
+			return strings.Count(content[:index], "\n") + 1
+		}
+	}
+	return 0
+}
+
+// layerPattern compiles a layer pattern. ** matches across directories, *
+// and ? within one. Anchored patterns match from the start of the path;
+// others from any directory boundary.
+func layerPattern(pattern string, anchored bool) *regexp.Regexp {
+	pattern = strings.TrimSuffix(path.Clean(pattern), "/")
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("(^|/)")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			// Any number of whole directories, including none
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("(/.*)?$")
+	return regexp.MustCompile(b.String())
+}
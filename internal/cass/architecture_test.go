@@ -0,0 +1,192 @@
+package analysis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLayerPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		anchored bool
+		matches  []string
+		rejects  []string
+	}{
+		{
+			pattern:  "internal/app/**",
+			anchored: true,
+			matches:  []string{"internal/app/server.go", "internal/app/api/rag/widget.go"},
+			rejects:  []string{"pkg/internal/app/x.go", "internal/application.go"},
+		},
+		{
+			pattern:  "**/handlers/**",
+			anchored: true,
+			matches:  []string{"handlers/a.go", "internal/api/handlers/a.go"},
+			rejects:  []string{"internal/api/handler/a.go"},
+		},
+		{
+			pattern:  "internal/*/db.go",
+			anchored: true,
+			matches:  []string{"internal/store/db.go"},
+			rejects:  []string{"internal/a/b/db.go"},
+		},
+		{
+			pattern:  "pkg/biz/rag/internal/",
+			anchored: false,
+			matches:  []string{"pkg/biz/rag/internal", "github.com/guileen/metabase/pkg/biz/rag/internal/store"},
+			rejects:  []string{"pkg/biz/rag/internals", "xpkg/biz/rag/internal"},
+		},
+		{
+			pattern:  "database/sql",
+			anchored: false,
+			matches:  []string{"database/sql", "database/sql/driver"},
+			rejects:  []string{"database/sqlx"},
+		},
+		{
+			pattern:  "v?",
+			anchored: false,
+			matches:  []string{"api/v1", "v2"},
+			rejects:  []string{"api/v10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			regex := layerPattern(tt.pattern, tt.anchored)
+			for _, target := range tt.matches {
+				if !regex.MatchString(target) {
+					t.Errorf("expected %s to match %s", tt.pattern, target)
+				}
+			}
+			for _, target := range tt.rejects {
+				if regex.MatchString(target) {
+					t.Errorf("expected %s not to match %s", tt.pattern, target)
+				}
+			}
+		})
+	}
+}
+
+func TestLayerRuleValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		rule LayerRule
+		err  string
+	}{
+		{name: "valid", rule: LayerRule{ID: "r", From: "a/**", Forbid: []string{"b"}}},
+		{name: "missing id", rule: LayerRule{From: "a/**", Forbid: []string{"b"}}, err: "id is required"},
+		{name: "missing from", rule: LayerRule{ID: "r", Forbid: []string{"b"}}, err: "from is required"},
+		{name: "missing forbid", rule: LayerRule{ID: "r", From: "a/**"}, err: "forbid is required"},
+		{name: "invalid severity", rule: LayerRule{ID: "r", From: "a/**", Forbid: []string{"b"}, Severity: "fatal"}, err: "invalid severity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+	if _, err := NewArchitectureAnalyzer([]LayerRule{{ID: "r"}}); err == nil {
+		t.Fatal("expected invalid rules to be rejected")
+	}
+}
+
+func TestArchitectureAnalyzer(t *testing.T) {
+	analyzer, err := NewArchitectureAnalyzer([]LayerRule{
+		{
+			ID:     "app-no-rag-internals",
+			From:   "internal/app/**",
+			Forbid: []string{"pkg/biz/rag/internal"},
+			Allow:  []string{"pkg/biz/rag/internal/api"},
+		},
+		{
+			ID:       "handlers-no-sql",
+			From:     "**/handlers/**",
+			Forbid:   []string{"database/sql"},
+			Severity: "medium",
+		},
+		{
+			ID:     "ui-no-server",
+			From:   "web/src/**",
+			Forbid: []string{"web/server"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		artifact   *Artifact
+		violations map[string]int // rule -> line
+		applied    float64
+	}{
+		{
+			name: "forbidden go import",
+			artifact: &Artifact{Path: "internal/app/server.go", Language: "go", Content: []byte(
+				"package app\n\nimport (\n\t\"fmt\"\n\t\"github.com/guileen/metabase/pkg/biz/rag/internal/store\"\n)\n")},
+			violations: map[string]int{"app-no-rag-internals": 5},
+			applied:    1,
+		},
+		{
+			name: "allowed exception",
+			artifact: &Artifact{Path: "internal/app/server.go", Language: "go", Content: []byte(
+				"package app\n\nimport \"github.com/guileen/metabase/pkg/biz/rag/internal/api\"\n")},
+			violations: map[string]int{},
+			applied:    1,
+		},
+		{
+			name: "two layers apply",
+			artifact: &Artifact{Path: "internal/app/handlers/docs.go", Language: "go", Content: []byte(
+				"package handlers\n\nimport \"database/sql\"\n")},
+			violations: map[string]int{"handlers-no-sql": 3},
+			applied:    2,
+		},
+		{
+			name: "relative javascript import",
+			artifact: &Artifact{Path: "web/src/views/page.ts", Language: "typescript", Content: []byte(
+				"import { db } from '../../server/db'\nimport { x } from './x'\n")},
+			violations: map[string]int{"ui-no-server": 1},
+			applied:    1,
+		},
+		{
+			name: "file outside every layer",
+			artifact: &Artifact{Path: "pkg/biz/rag/service.go", Language: "go", Content: []byte(
+				"package rag\n\nimport \"database/sql\"\n")},
+			violations: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := analyzer.Analyze(context.Background(), tt.artifact)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]int)
+			for _, finding := range result.Findings {
+				got[finding.Rule] = finding.Line
+			}
+			if !reflect.DeepEqual(got, tt.violations) {
+				t.Fatalf("got violations %v, want %v", got, tt.violations)
+			}
+			if result.Metrics["rules_applied"] != tt.applied {
+				t.Fatalf("got %v rules applied, want %v", result.Metrics["rules_applied"], tt.applied)
+			}
+			wantScore := 100.0
+			if len(tt.violations) > 0 {
+				wantScore = 0
+			}
+			if result.Score != wantScore {
+				t.Fatalf("got score %v, want %v", result.Score, wantScore)
+			}
+		})
+	}
+}
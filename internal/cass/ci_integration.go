@@ -1,14 +1,17 @@
 package analysis
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io/fs"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -74,6 +77,26 @@ type CIConfig struct {
 	BaselineFile         string   `yaml:"baseline_file"`
 	CustomRules          []string `yaml:"custom_rules"`
 	EnvironmentVariables []string `yaml:"environment_variables"`
+
+	// Coverage reports (go coverprofile, lcov or cobertura); globs allowed
+	CoverageFiles []string `yaml:"coverage_files"`
+
+	// JUnit reports of the project's own test runs, and where their
+	// pass/fail history is kept for flaky test detection
+	TestResultFiles []string `yaml:"test_result_files"`
+	TestHistoryFile string   `yaml:"test_history_file"`
+
+	// Weights of the change risk score features
+	RiskWeights RiskWeights `yaml:"risk_weights"`
+
+	// Ownership
+	CodeownersFile string            `yaml:"codeowners_file"` // Searched in the usual locations when empty
+	BlameOwnership bool              `yaml:"blame_ownership"` // Fall back to the top git blame author
+	OwnerWebhooks  map[string]string `yaml:"owner_webhooks"`  // Owner to Slack compatible webhook URL
+	NotifySeverity string            `yaml:"notify_severity"` // Minimum severity of new issues to notify
+
+	// Layer constraints; any violation fails its artifact and the gate
+	ArchitectureRules []LayerRule `yaml:"architecture_rules"`
 }
 
 // CIRunner runs the CASS analysis in CI/CD environments
@@ -83,6 +106,7 @@ type CIRunner struct {
 	context   *CIContext
 	storage   storage.Storage
 	baseline  *CIBaseline
+	coverage  *CoverageReport
 	reporters map[string]CIReporter
 	startTime time.Time
 }
@@ -94,6 +118,7 @@ type CIBaseline struct {
 	Timestamp time.Time                  `json:"timestamp"`
 	Metrics   map[string]float64         `json:"metrics"`
 	Issues    map[string][]BaselineIssue `json:"issues"`
+	Coverage  map[string]float64         `json:"coverage,omitempty"` // Per package
 	Artifacts int                        `json:"artifacts"`
 	Version   string                     `json:"version"`
 }
@@ -128,6 +153,10 @@ type CIResults struct {
 	Duplicates  []*CIDuplicateResult  `json:"duplicates"`
 	Trends      *CITrends             `json:"trends"`
 	Baseline    *CIBaseline           `json:"baseline"`
+	Coverage    *CICoverage           `json:"coverage,omitempty"`
+	Tests       *CITestResults        `json:"tests,omitempty"`
+	Risk        *CIRiskScore          `json:"risk,omitempty"`
+	Owners      []*CIOwnerSummary     `json:"owners,omitempty"`
 	Duration    time.Duration         `json:"duration"`
 	GeneratedAt time.Time             `json:"generated_at"`
 }
@@ -145,6 +174,7 @@ type CIArtifactResult struct {
 	Results    []*AnalysisResult      `json:"results"`
 	Score      float64                `json:"score"`
 	Status     string                 `json:"status"` // "passed", "failed", "warning"
+	Owners     []string               `json:"owners,omitempty"`
 	Metadata   map[string]interface{} `json:"metadata"`
 }
 
@@ -192,9 +222,24 @@ type CIIssue struct {
 	New         bool                   `json:"new"`      // Is this a new issue?
 	Baseline    bool                   `json:"baseline"` // Was this in baseline?
 	Hash        string                 `json:"hash"`
+	Owners      []string               `json:"owners,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata"`
 }
 
+// CIOwnerSummary represents the findings and quality attributed to an owner
+type CIOwnerSummary struct {
+	Owner          string  `json:"owner"`
+	Artifacts      int     `json:"artifacts"`
+	Issues         int     `json:"issues"`
+	NewIssues      int     `json:"new_issues"`
+	CriticalIssues int     `json:"critical_issues"`
+	HighIssues     int     `json:"high_issues"`
+	MediumIssues   int     `json:"medium_issues"`
+	LowIssues      int     `json:"low_issues"`
+	QualityScore   float64 `json:"quality_score"`
+	SecurityScore  float64 `json:"security_score"`
+}
+
 // CIDuplicateResult represents a duplicate detection result
 type CIDuplicateResult struct {
 	ArtifactID1  string    `json:"artifact_id1"`
@@ -211,6 +256,48 @@ type CIDuplicateResult struct {
 	Timestamp    time.Time `json:"timestamp"`
 }
 
+// CICoverage represents measured test coverage
+type CICoverage struct {
+	Total     float64              `json:"total"`
+	Threshold float64              `json:"threshold"`
+	Passed    bool                 `json:"passed"`
+	Files     int                  `json:"files"`
+	Packages  []*CIPackageCoverage `json:"packages"`
+}
+
+// CIPackageCoverage represents the coverage of a package and its change
+// against the baseline
+type CIPackageCoverage struct {
+	Package  string   `json:"package"`
+	Coverage float64  `json:"coverage"`
+	Total    int      `json:"total"`
+	Covered  int      `json:"covered"`
+	Baseline *float64 `json:"baseline,omitempty"`
+	Delta    float64  `json:"delta"`
+}
+
+// CITestResults represents the ingested test results of this run
+type CITestResults struct {
+	Total    int               `json:"total"`
+	Passed   int               `json:"passed"`
+	Failed   int               `json:"failed"`
+	Skipped  int               `json:"skipped"`
+	Duration time.Duration     `json:"duration"`
+	Failures []*TestCaseResult `json:"failures"`
+	Flaky    []*CIFlakyTest    `json:"flaky"`
+}
+
+// CIFlakyTest represents a test that fails intermittently
+type CIFlakyTest struct {
+	Suite       string    `json:"suite"`
+	Name        string    `json:"name"`
+	Runs        int       `json:"runs"`
+	Failures    int       `json:"failures"`
+	FlipRate    float64   `json:"flip_rate"`   // Share of consecutive runs that changed outcome
+	SameCommit  bool      `json:"same_commit"` // Passed and failed on the same commit
+	LastFailure time.Time `json:"last_failure"`
+}
+
 // CITrends represents trend analysis
 type CITrends struct {
 	QualityTrend  []float64   `json:"quality_trend"`
@@ -241,6 +328,9 @@ func DefaultCIConfig() *CIConfig {
 		EnableSearchIndex: false,
 		UpdateBaseline:    false,
 		BaselineFile:      ".cass-baseline.json",
+		TestHistoryFile:   ".cass-test-history.json",
+		RiskWeights:       DefaultRiskWeights(),
+		NotifySeverity:    "high",
 	}
 }
 
@@ -293,6 +383,17 @@ func (r *CIRunner) Run(ctx context.Context) (*CIResults, error) {
 		return nil, fmt.Errorf("failed to analyze artifacts: %w", err)
 	}
 
+	// Replace estimated coverage with measured coverage
+	if err := r.loadCoverage(); err != nil {
+		log.Printf("Warning: Could not load coverage: %v", err)
+	}
+	r.applyCoverage(results)
+
+	// Check layer constraints
+	if err := r.checkArchitecture(analysisCtx, artifacts, results); err != nil {
+		return nil, fmt.Errorf("failed to check architecture rules: %w", err)
+	}
+
 	// Find duplicates
 	duplicates, err := r.findDuplicates(analysisCtx, artifacts)
 	if err != nil {
@@ -302,16 +403,37 @@ func (r *CIRunner) Run(ctx context.Context) (*CIResults, error) {
 	// Generate comprehensive results
 	ciResults := r.generateResults(results, duplicates)
 
+	// Enforce the coverage threshold
+	r.checkCoverage(ciResults)
+
+	// Record test results and detect flaky tests
+	if err := r.analyzeTests(ciResults); err != nil {
+		log.Printf("Warning: Test result analysis failed: %v", err)
+	}
+
+	// Score the risk of the change
+	if r.context.PullRequest != "" || len(r.context.ChangedFiles) > 0 {
+		ciResults.Risk = r.scoreRisk(ciResults)
+	}
+
 	// Compare with baseline
 	if r.baseline != nil {
 		r.compareWithBaseline(ciResults)
 	}
 
+	// Attribute findings to owners
+	if err := r.attributeOwners(ciResults); err != nil {
+		log.Printf("Warning: Ownership attribution failed: %v", err)
+	}
+
 	// Generate reports
 	if err := r.generateReports(analysisCtx, ciResults); err != nil {
 		log.Printf("Warning: Report generation failed: %v", err)
 	}
 
+	// Notify owners of new issues
+	r.notifyOwners(analysisCtx, ciResults)
+
 	// Update baseline if requested
 	if r.config.UpdateBaseline {
 		if err := r.updateBaseline(ciResults); err != nil {
@@ -564,6 +686,382 @@ func (r *CIRunner) findDuplicates(ctx context.Context, artifacts []*Artifact) ([
 	return duplicates, nil
 }
 
+// loadCoverage loads and merges the configured coverage reports
+func (r *CIRunner) loadCoverage() error {
+	var files []string
+	for _, pattern := range r.config.CoverageFiles {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid coverage pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			log.Printf("Warning: No coverage files match %s", pattern)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	coverage := NewCoverageReport()
+	for _, file := range files {
+		report, err := ParseCoverageFile(file)
+		if err != nil {
+			return err
+		}
+		coverage.Merge(report)
+	}
+	r.coverage = coverage
+	return nil
+}
+
+// applyCoverage replaces the estimated test coverage of each artifact's quality
+// results with measured coverage and re-evaluates the artifact
+func (r *CIRunner) applyCoverage(results []*CIArtifactResult) {
+	if r.coverage == nil {
+		return
+	}
+	quality := NewQualityAnalyzer()
+	for _, result := range results {
+		file, ok := r.coverage.ForFile(result.Path)
+		if !ok {
+			continue
+		}
+		result.Metadata["coverage"] = file.Percent()
+
+		applied := false
+		for _, analysis := range result.Results {
+			if analysis.Type == "quality" {
+				quality.ApplyMeasuredCoverage(analysis, file.Percent())
+				applied = true
+			}
+		}
+		if !applied {
+			continue
+		}
+		result.Score = r.calculateArtifactScore(result.Results)
+		result.Status = "passed"
+		if r.shouldFailArtifact(result) {
+			result.Status = "failed"
+		} else if r.shouldWarnArtifact(result) {
+			result.Status = "warning"
+		}
+	}
+}
+
+// checkCoverage reports per package coverage against the baseline and fails
+// the run when total coverage is below the threshold
+func (r *CIRunner) checkCoverage(results *CIResults) {
+	if r.coverage == nil {
+		return
+	}
+
+	threshold := r.config.Thresholds.TestCoverage
+	coverage := &CICoverage{
+		Total:     r.coverage.Total(),
+		Threshold: threshold,
+		Files:     len(r.coverage.Files),
+		Packages:  make([]*CIPackageCoverage, 0),
+	}
+	coverage.Passed = coverage.Total >= threshold
+
+	for _, pkg := range r.coverage.Packages() {
+		pkgCoverage := &CIPackageCoverage{
+			Package:  pkg.Path,
+			Coverage: pkg.Percent(),
+			Total:    pkg.Total,
+			Covered:  pkg.Covered,
+		}
+		if r.baseline != nil {
+			if previous, ok := r.baseline.Coverage[pkg.Path]; ok {
+				pkgCoverage.Baseline = &previous
+				pkgCoverage.Delta = pkgCoverage.Coverage - previous
+			}
+		}
+		coverage.Packages = append(coverage.Packages, pkgCoverage)
+	}
+
+	results.Coverage = coverage
+	results.Metrics["test_coverage"] = coverage.Total
+	if trend := results.Trends.CoverageTrend; len(trend) > 0 {
+		trend[len(trend)-1] = coverage.Total
+	}
+	if !coverage.Passed {
+		results.Summary.Status = "failed"
+		results.Summary.Recommendations = append(results.Summary.Recommendations,
+			fmt.Sprintf("Raise test coverage from %.1f%% to at least %.1f%%", coverage.Total, threshold))
+	}
+}
+
+// analyzeTests ingests the configured JUnit reports, appends them to the test
+// history and reports flaky tests
+func (r *CIRunner) analyzeTests(results *CIResults) error {
+	if len(r.config.TestResultFiles) == 0 {
+		return nil
+	}
+
+	tests := &CITestResults{
+		Failures: make([]*TestCaseResult, 0),
+		Flaky:    make([]*CIFlakyTest, 0),
+	}
+	var cases []*TestCaseResult
+	for _, pattern := range r.config.TestResultFiles {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid test result pattern %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			log.Printf("Warning: No test result files match %s", pattern)
+		}
+		for _, file := range matches {
+			parsed, err := ParseJUnitFile(file)
+			if err != nil {
+				return err
+			}
+			cases = append(cases, parsed...)
+		}
+	}
+
+	for _, tc := range cases {
+		tests.Total++
+		tests.Duration += tc.Duration
+		switch tc.Status {
+		case "passed":
+			tests.Passed++
+		case "failed":
+			tests.Failed++
+			tests.Failures = append(tests.Failures, tc)
+		case "skipped":
+			tests.Skipped++
+		}
+	}
+	results.Tests = tests
+
+	history, err := LoadTestHistory(r.config.TestHistoryFile)
+	if err != nil {
+		return fmt.Errorf("failed to load test history: %w", err)
+	}
+	history.Record(cases, r.context)
+	tests.Flaky = history.FlakyTests()
+	if len(tests.Flaky) > 0 {
+		results.Summary.Recommendations = append(results.Summary.Recommendations,
+			fmt.Sprintf("Stabilize %d flaky tests", len(tests.Flaky)))
+	}
+
+	return history.Save(r.config.TestHistoryFile)
+}
+
+// attributeOwners assigns owners to artifacts and issues and summarizes the
+// findings and scores of each owner
+func (r *CIRunner) attributeOwners(results *CIResults) error {
+	ownership, err := LoadOwnership(r.config.CodeownersFile, r.config.BlameOwnership)
+	if err != nil {
+		return err
+	}
+	if len(ownership.Rules()) == 0 && !r.config.BlameOwnership {
+		return nil
+	}
+
+	type scores struct{ quality, security, qualityCount, securityCount float64 }
+	summaries := make(map[string]*CIOwnerSummary)
+	ownerScores := make(map[string]*scores)
+	summaryOf := func(owner string) *CIOwnerSummary {
+		if summaries[owner] == nil {
+			summaries[owner] = &CIOwnerSummary{Owner: owner}
+			ownerScores[owner] = &scores{}
+		}
+		return summaries[owner]
+	}
+
+	artifactOwners := make(map[string][]string, len(results.Artifacts))
+	for _, artifact := range results.Artifacts {
+		artifact.Owners = ownership.OwnersOf(artifact.Path)
+		if len(artifact.Owners) == 0 {
+			artifact.Owners = []string{UnownedOwner}
+		}
+		artifactOwners[artifact.ArtifactID] = artifact.Owners
+
+		for _, owner := range artifact.Owners {
+			summaryOf(owner).Artifacts++
+			s := ownerScores[owner]
+			for _, analysis := range artifact.Results {
+				switch analysis.Type {
+				case "quality":
+					s.quality += analysis.Score
+					s.qualityCount++
+				case "security":
+					s.security += analysis.Score
+					s.securityCount++
+				}
+			}
+		}
+	}
+
+	for _, issues := range results.Issues {
+		for _, issue := range issues {
+			issue.Owners = artifactOwners[issue.ArtifactID]
+			if len(issue.Owners) == 0 {
+				issue.Owners = []string{UnownedOwner}
+			}
+			for _, owner := range issue.Owners {
+				summary := summaryOf(owner)
+				summary.Issues++
+				if issue.New {
+					summary.NewIssues++
+				}
+				switch issue.Severity {
+				case "critical":
+					summary.CriticalIssues++
+				case "high":
+					summary.HighIssues++
+				case "medium":
+					summary.MediumIssues++
+				case "low":
+					summary.LowIssues++
+				}
+			}
+		}
+	}
+
+	results.Owners = make([]*CIOwnerSummary, 0, len(summaries))
+	for owner, summary := range summaries {
+		s := ownerScores[owner]
+		if s.qualityCount > 0 {
+			summary.QualityScore = s.quality / s.qualityCount
+		}
+		if s.securityCount > 0 {
+			summary.SecurityScore = s.security / s.securityCount
+		}
+		results.Owners = append(results.Owners, summary)
+	}
+	sort.Slice(results.Owners, func(i, j int) bool {
+		a, b := results.Owners[i], results.Owners[j]
+		if a.CriticalIssues != b.CriticalIssues {
+			return a.CriticalIssues > b.CriticalIssues
+		}
+		if a.Issues != b.Issues {
+			return a.Issues > b.Issues
+		}
+		return a.Owner < b.Owner
+	})
+	return nil
+}
+
+// IssuesByOwner groups the issues of a run by owner, keeping issues at or
+// above minSeverity; an empty minSeverity keeps all issues
+func IssuesByOwner(results *CIResults, minSeverity string) map[string][]*CIIssue {
+	grouped := make(map[string][]*CIIssue)
+	for _, issues := range results.Issues {
+		for _, issue := range issues {
+			if severityRank[issue.Severity] < severityRank[minSeverity] {
+				continue
+			}
+			owners := issue.Owners
+			if len(owners) == 0 {
+				owners = []string{UnownedOwner}
+			}
+			for _, owner := range owners {
+				grouped[owner] = append(grouped[owner], issue)
+			}
+		}
+	}
+	return grouped
+}
+
+// notifyOwners posts the new issues at or above the notify severity to the
+// webhook of each owning team
+func (r *CIRunner) notifyOwners(ctx context.Context, results *CIResults) {
+	if len(r.config.OwnerWebhooks) == 0 || results.Owners == nil {
+		return
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	for owner, issues := range IssuesByOwner(results, r.config.NotifySeverity) {
+		url := r.config.OwnerWebhooks[owner]
+		if url == "" {
+			continue
+		}
+
+		var lines []string
+		for _, issue := range issues {
+			if issue.New {
+				lines = append(lines, fmt.Sprintf("- [%s] %s:%d %s",
+					strings.ToUpper(issue.Severity), issue.Path, issue.Line, issue.Message))
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		text := fmt.Sprintf("CASS found %d new issues owned by %s in %s@%s (%s)\n%s",
+			len(lines), owner, r.context.Repository, r.context.Branch, r.context.Commit,
+			strings.Join(lines[:min(10, len(lines))], "\n"))
+		if len(lines) > 10 {
+			text += fmt.Sprintf("\n...and %d more", len(lines)-10)
+		}
+		if err := postWebhook(ctx, client, url, map[string]string{"text": text}); err != nil {
+			log.Printf("Warning: Failed to notify %s: %v", owner, err)
+		}
+	}
+}
+
+// postWebhook posts a JSON payload to a webhook
+func postWebhook(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// checkArchitecture validates the imports of every artifact against the
+// configured layer rules. Violations fail the artifact regardless of severity.
+func (r *CIRunner) checkArchitecture(ctx context.Context, artifacts []*Artifact, results []*CIArtifactResult) error {
+	if len(r.config.ArchitectureRules) == 0 {
+		return nil
+	}
+	analyzer, err := NewArchitectureAnalyzer(r.config.ArchitectureRules)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*CIArtifactResult, len(results))
+	for _, result := range results {
+		byID[result.ArtifactID] = result
+	}
+	for _, artifact := range artifacts {
+		result := byID[artifact.ID]
+		if result == nil {
+			continue
+		}
+		analysis, err := analyzer.Analyze(ctx, artifact)
+		if err != nil {
+			log.Printf("Architecture check failed for %s: %v", artifact.Path, err)
+			continue
+		}
+		result.Analyzers = append(append([]string(nil), result.Analyzers...), analyzer.ID())
+		result.Results = append(result.Results, analysis)
+		if len(analysis.Findings) > 0 {
+			result.Status = "failed"
+			result.Metadata["architecture_violations"] = len(analysis.Findings)
+		}
+	}
+	return nil
+}
+
 // generateResults generates comprehensive CI results
 func (r *CIRunner) generateResults(artifactResults []*CIArtifactResult, duplicates []*CIDuplicateResult) *CIResults {
 	results := &CIResults{
@@ -668,6 +1166,11 @@ func (r *CIRunner) generateSummary(artifactResults []*CIArtifactResult) *CISumma
 // Helper functions for language detection, hashing, etc.
 
 func (r *CIRunner) detectLanguage(filePath string) string {
+	return detectLanguage(filePath)
+}
+
+// detectLanguage maps a file extension to its language
+func detectLanguage(filePath string) string {
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
 	case ".go":
@@ -884,6 +1387,15 @@ func (r *CIRunner) printSummary(results *CIResults) {
 	fmt.Printf("  Critical: %d\n", results.Summary.CriticalIssues)
 	fmt.Printf("  High: %d\n", results.Summary.HighIssues)
 	fmt.Printf("  Overall Score: %.1f\n", results.Summary.OverallScore)
+	if results.Risk != nil {
+		fmt.Printf("  Risk: %.1f (%s)\n", results.Risk.Score, results.Risk.Level)
+	}
+	if results.Tests != nil {
+		fmt.Printf("  Tests: %d passed, %d failed, %d flaky\n", results.Tests.Passed, results.Tests.Failed, len(results.Tests.Flaky))
+	}
+	if results.Coverage != nil {
+		fmt.Printf("  Coverage: %.1f%% (threshold %.1f%%)\n", results.Coverage.Total, results.Coverage.Threshold)
+	}
 	fmt.Printf("  Status: %s\n", results.Summary.Status)
 
 	if len(results.Summary.Recommendations) > 0 {
@@ -944,6 +1456,14 @@ func (r *CIRunner) updateBaseline(results *CIResults) error {
 		Version:   "1.0.0",
 	}
 
+	// Record per package coverage for deltas
+	if results.Coverage != nil {
+		baseline.Coverage = make(map[string]float64, len(results.Coverage.Packages))
+		for _, pkg := range results.Coverage.Packages {
+			baseline.Coverage[pkg.Package] = pkg.Coverage
+		}
+	}
+
 	// Convert issues to baseline issues
 	for issueType, issues := range results.Issues {
 		baselineIssues := make([]BaselineIssue, 0, len(issues))
@@ -988,4 +1508,5 @@ func (r *CIRunner) registerReporters() {
 	r.reporters["json"] = NewJSONReporter(r.config.OutputDirectory)
 	r.reporters["markdown"] = NewMarkdownReporter(r.config.OutputDirectory)
 	r.reporters["junit"] = NewJunitReporter(r.config.OutputDirectory)
+	r.reporters["github-annotations"] = NewGitHubAnnotationsReporter(r.config.OutputDirectory)
 }
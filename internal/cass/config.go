@@ -79,6 +79,18 @@ func loadEnvironmentOverrides(config *CIConfig) {
 	if val := os.Getenv("CASS_BASELINE_FILE"); val != "" {
 		config.BaselineFile = val
 	}
+	if val := os.Getenv("CASS_TEST_RESULT_FILES"); val != "" {
+		config.TestResultFiles = strings.Split(val, ",")
+		for i, file := range config.TestResultFiles {
+			config.TestResultFiles[i] = strings.TrimSpace(file)
+		}
+	}
+	if val := os.Getenv("CASS_COVERAGE_FILES"); val != "" {
+		config.CoverageFiles = strings.Split(val, ",")
+		for i, file := range config.CoverageFiles {
+			config.CoverageFiles[i] = strings.TrimSpace(file)
+		}
+	}
 
 	// Analyzers
 	if val := os.Getenv("CASS_ENABLED_ANALYZERS"); val != "" {
@@ -258,17 +270,31 @@ func ValidateConfig(config *CIConfig) error {
 		return fmt.Errorf("coverage threshold must be between 0 and 100")
 	}
 
+	weights := config.RiskWeights
+	if weights.DiffSize < 0 || weights.Complexity < 0 || weights.DefectDensity < 0 || weights.Security < 0 {
+		return fmt.Errorf("risk weights must not be negative")
+	}
+
+	if len(config.TestResultFiles) > 0 && config.TestHistoryFile == "" {
+		return fmt.Errorf("test_history_file is required with test_result_files")
+	}
+
 	// Validate severity
 	validSeverities := map[string]bool{"low": true, "medium": true, "high": true, "critical": true}
 	if !validSeverities[config.FailOnSeverity] {
 		return fmt.Errorf("invalid fail_on_severity: %s (must be low, medium, high, or critical)", config.FailOnSeverity)
 	}
 
+	if len(config.OwnerWebhooks) > 0 && !validSeverities[config.NotifySeverity] {
+		return fmt.Errorf("invalid notify_severity: %s (must be low, medium, high, or critical)", config.NotifySeverity)
+	}
+
 	// Validate analyzers
 	validAnalyzers := map[string]bool{
-		"duplicate-detector": true,
-		"security-scanner":   true,
-		"quality-analyzer":   true,
+		"duplicate-detector":  true,
+		"security-scanner":    true,
+		"quality-analyzer":    true,
+		"dependency-analyzer": true,
 	}
 	for _, analyzer := range config.EnabledAnalyzers {
 		if !validAnalyzers[analyzer] {
@@ -276,6 +302,18 @@ func ValidateConfig(config *CIConfig) error {
 		}
 	}
 
+	// Validate architecture rules
+	ruleIDs := make(map[string]bool)
+	for _, rule := range config.ArchitectureRules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if ruleIDs[rule.ID] {
+			return fmt.Errorf("duplicate architecture rule id: %s", rule.ID)
+		}
+		ruleIDs[rule.ID] = true
+	}
+
 	// Validate report formats
 	validFormats := map[string]bool{
 		"json":               true,
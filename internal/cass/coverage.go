@@ -0,0 +1,299 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CoverageReport holds measured coverage per source file
+type CoverageReport struct {
+	Files map[string]*FileCoverage `json:"files"`
+}
+
+// FileCoverage is the coverage of one source file. Units are statements for
+// Go coverprofiles and lines for lcov and cobertura.
+type FileCoverage struct {
+	Path    string `json:"path"`
+	Total   int    `json:"total"`
+	Covered int    `json:"covered"`
+}
+
+// Percent returns the covered percentage, 100 for files without coverable code
+func (f *FileCoverage) Percent() float64 {
+	return coveragePercent(f.Covered, f.Total)
+}
+
+// NewCoverageReport creates an empty coverage report
+func NewCoverageReport() *CoverageReport {
+	return &CoverageReport{Files: make(map[string]*FileCoverage)}
+}
+
+// ParseCoverageFile reads a coverage file, detecting its format from content
+func ParseCoverageFile(filePath string) (*CoverageReport, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	report := NewCoverageReport()
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("mode:")):
+		err = report.parseGoCoverProfile(bytes.NewReader(data))
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		err = report.parseCobertura(bytes.NewReader(data))
+	case bytes.HasPrefix(trimmed, []byte("TN:")) || bytes.HasPrefix(trimmed, []byte("SF:")):
+		err = report.parseLCOV(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unrecognized coverage format: %s", filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+	return report, nil
+}
+
+// Merge adds the files of other, keeping the better covered entry for files
+// present in both
+func (c *CoverageReport) Merge(other *CoverageReport) {
+	for name, file := range other.Files {
+		if existing, ok := c.Files[name]; ok && existing.Covered >= file.Covered {
+			continue
+		}
+		c.Files[name] = file
+	}
+}
+
+// ForFile finds the coverage of a repository relative path. Reports name files
+// by import path, absolute path or source root relative path, so the entry
+// sharing the longest path suffix wins.
+func (c *CoverageReport) ForFile(filePath string) (*FileCoverage, bool) {
+	filePath = path.Clean(filepath.ToSlash(filePath))
+	var best *FileCoverage
+	bestLen := 0
+	for name, file := range c.Files {
+		var matched int
+		switch {
+		case name == filePath:
+			return file, true
+		case strings.HasSuffix(name, "/"+filePath):
+			matched = len(filePath)
+		case strings.HasSuffix(filePath, "/"+name):
+			matched = len(name)
+		}
+		if matched > bestLen {
+			best, bestLen = file, matched
+		}
+	}
+	return best, best != nil
+}
+
+// Total returns the overall covered percentage
+func (c *CoverageReport) Total() float64 {
+	total, covered := 0, 0
+	for _, file := range c.Files {
+		total += file.Total
+		covered += file.Covered
+	}
+	return coveragePercent(covered, total)
+}
+
+// Packages aggregates coverage by directory, sorted by package
+func (c *CoverageReport) Packages() []*FileCoverage {
+	byDir := make(map[string]*FileCoverage)
+	for name, file := range c.Files {
+		dir := path.Dir(name)
+		pkg, ok := byDir[dir]
+		if !ok {
+			pkg = &FileCoverage{Path: dir}
+			byDir[dir] = pkg
+		}
+		pkg.Total += file.Total
+		pkg.Covered += file.Covered
+	}
+
+	packages := make([]*FileCoverage, 0, len(byDir))
+	for _, pkg := range byDir {
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Path < packages[j].Path })
+	return packages
+}
+
+// file returns the entry for a file, creating it if needed
+func (c *CoverageReport) file(name string) *FileCoverage {
+	name = normalizeCoveragePath(name)
+	file, ok := c.Files[name]
+	if !ok {
+		file = &FileCoverage{Path: name}
+		c.Files[name] = file
+	}
+	return file
+}
+
+// parseGoCoverProfile parses `go test -coverprofile` output:
+// name.go:line.column,line.column numberOfStatements count
+func (c *CoverageReport) parseGoCoverProfile(r io.Reader) error {
+	type block struct {
+		file, position string
+	}
+	statements := make(map[block]int)
+	covered := make(map[block]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		colon := strings.LastIndex(line, ":")
+		fields := strings.Fields(line[colon+1:])
+		if colon < 0 || len(fields) != 3 {
+			return fmt.Errorf("invalid coverprofile line: %s", line)
+		}
+		numStmt, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid statement count: %s", line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("invalid hit count: %s", line)
+		}
+
+		// Profiles merged from several packages repeat blocks
+		b := block{file: line[:colon], position: fields[0]}
+		statements[b] = numStmt
+		covered[b] = covered[b] || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for b, numStmt := range statements {
+		file := c.file(b.file)
+		file.Total += numStmt
+		if covered[b] {
+			file.Covered += numStmt
+		}
+	}
+	return nil
+}
+
+// parseLCOV parses lcov tracefiles, counting DA line records and falling back
+// to the LF/LH summary when a record has none
+func (c *CoverageReport) parseLCOV(r io.Reader) error {
+	var file *FileCoverage
+	var found, hit, lines int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, _ := strings.Cut(line, ":")
+		switch {
+		case key == "SF":
+			file = c.file(value)
+			found, hit, lines = 0, 0, 0
+		case file == nil:
+			continue
+		case key == "DA":
+			parts := strings.Split(value, ",")
+			if len(parts) < 2 {
+				return fmt.Errorf("invalid DA record: %s", line)
+			}
+			lines++
+			file.Total++
+			if count, err := strconv.ParseFloat(parts[1], 64); err == nil && count > 0 {
+				file.Covered++
+			}
+		case key == "LF":
+			found, _ = strconv.Atoi(value)
+		case key == "LH":
+			hit, _ = strconv.Atoi(value)
+		case line == "end_of_record":
+			if lines == 0 {
+				file.Total += found
+				file.Covered += hit
+			}
+			file = nil
+		}
+	}
+	return scanner.Err()
+}
+
+// coberturaReport is the subset of the cobertura XML format that is read
+type coberturaReport struct {
+	Sources  []string `xml:"sources>source"`
+	Packages []struct {
+		Classes []struct {
+			Filename string `xml:"filename,attr"`
+			Lines    []struct {
+				Number int `xml:"number,attr"`
+				Hits   int `xml:"hits,attr"`
+			} `xml:"lines>line"`
+		} `xml:"classes>class"`
+	} `xml:"packages>package"`
+}
+
+// parseCobertura parses cobertura XML. Classes of one file are merged by line
+// number, since a file can declare several classes.
+func (c *CoverageReport) parseCobertura(r io.Reader) error {
+	var report coberturaReport
+	if err := xml.NewDecoder(r).Decode(&report); err != nil {
+		return err
+	}
+
+	hits := make(map[string]map[int]bool)
+	for _, pkg := range report.Packages {
+		for _, class := range pkg.Classes {
+			lines, ok := hits[class.Filename]
+			if !ok {
+				lines = make(map[int]bool)
+				hits[class.Filename] = lines
+			}
+			for _, line := range class.Lines {
+				lines[line.Number] = lines[line.Number] || line.Hits > 0
+			}
+		}
+	}
+
+	for filename, lines := range hits {
+		file := c.file(filename)
+		for _, isHit := range lines {
+			file.Total++
+			if isHit {
+				file.Covered++
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeCoveragePath makes absolute paths inside the working directory
+// relative, so they line up with artifact paths
+func normalizeCoveragePath(name string) string {
+	if filepath.IsAbs(name) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, name); err == nil && !strings.HasPrefix(rel, "..") {
+				name = rel
+			}
+		}
+	}
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// coveragePercent returns covered as a percentage of total
+func coveragePercent(covered, total int) float64 {
+	if total == 0 {
+		return 100.0
+	}
+	return float64(covered) / float64(total) * 100.0
+}
@@ -0,0 +1,180 @@
+package analysis
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeCoverageFile(t *testing.T, content string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "coverage.out")
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestParseCoverageFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string][2]int // path -> covered, total
+		err     string
+	}{
+		{
+			name: "go coverprofile",
+			content: "mode: set\n" +
+				"example.com/app/pkg/a.go:3.10,5.2 2 1\n" +
+				"example.com/app/pkg/a.go:7.10,9.2 3 0\n" +
+				"example.com/app/pkg/b.go:1.1,2.2 4 0\n",
+			want: map[string][2]int{"example.com/app/pkg/a.go": {2, 5}, "example.com/app/pkg/b.go": {0, 4}},
+		},
+		{
+			name: "go coverprofile with repeated blocks",
+			content: "mode: count\n" +
+				"example.com/app/a.go:3.10,5.2 2 0\n" +
+				"example.com/app/a.go:3.10,5.2 2 3\n",
+			want: map[string][2]int{"example.com/app/a.go": {2, 2}},
+		},
+		{
+			name:    "invalid coverprofile line",
+			content: "mode: set\nexample.com/app/a.go:3.10,5.2 2\n",
+			err:     "invalid coverprofile line",
+		},
+		{
+			name:    "invalid statement count",
+			content: "mode: set\nexample.com/app/a.go:3.10,5.2 x 1\n",
+			err:     "invalid statement count",
+		},
+		{
+			name: "lcov line records",
+			content: "TN:\nSF:src/a.js\nDA:1,1\nDA:2,0\nDA:3,5\nLF:10\nLH:9\nend_of_record\n" +
+				"SF:src/b.js\nLF:4\nLH:1\nend_of_record\n",
+			want: map[string][2]int{"src/a.js": {2, 3}, "src/b.js": {1, 4}},
+		},
+		{
+			name:    "invalid lcov record",
+			content: "SF:src/a.js\nDA:1\nend_of_record\n",
+			err:     "invalid DA record",
+		},
+		{
+			name: "cobertura classes merged by line",
+			content: `<?xml version="1.0"?>
+<coverage>
+  <packages>
+    <package name="app">
+      <classes>
+        <class filename="app/models.py">
+          <lines><line number="1" hits="1"/><line number="2" hits="0"/></lines>
+        </class>
+        <class filename="app/models.py">
+          <lines><line number="2" hits="3"/><line number="3" hits="0"/></lines>
+        </class>
+      </classes>
+    </package>
+  </packages>
+</coverage>`,
+			want: map[string][2]int{"app/models.py": {2, 3}},
+		},
+		{
+			name:    "unknown format",
+			content: "{}",
+			err:     "unrecognized coverage format",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := ParseCoverageFile(writeCoverageFile(t, tt.content))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string][2]int, len(report.Files))
+			for name, file := range report.Files {
+				got[name] = [2]int{file.Covered, file.Total}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverageReportForFile(t *testing.T) {
+	report := NewCoverageReport()
+	for _, name := range []string{"example.com/app/pkg/api/server.go", "pkg/store/store.go", "src/app.js", "app.js"} {
+		report.file(name).Total = 1
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "pkg/api/server.go", want: "example.com/app/pkg/api/server.go"},
+		{path: "./pkg/store/store.go", want: "pkg/store/store.go"},
+		{path: "web/src/app.js", want: "src/app.js"},
+		{path: "app.js", want: "app.js"},
+		{path: "pkg/api/routes.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			file, ok := report.ForFile(tt.path)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("expected no coverage for %s, got %s", tt.path, file.Path)
+				}
+				return
+			}
+			if !ok || file.Path != tt.want {
+				t.Fatalf("got %+v, want %s", file, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverageReportAggregates(t *testing.T) {
+	report := NewCoverageReport()
+	if report.Total() != 100 {
+		t.Fatalf("expected an empty report to be fully covered, got %v", report.Total())
+	}
+	report.Files["pkg/a/a.go"] = &FileCoverage{Path: "pkg/a/a.go", Total: 10, Covered: 5}
+	report.Files["pkg/a/b.go"] = &FileCoverage{Path: "pkg/a/b.go", Total: 10, Covered: 10}
+	report.Files["pkg/b/c.go"] = &FileCoverage{Path: "pkg/b/c.go", Total: 20, Covered: 5}
+
+	other := NewCoverageReport()
+	other.Files["pkg/a/a.go"] = &FileCoverage{Path: "pkg/a/a.go", Total: 10, Covered: 8}
+	other.Files["pkg/a/b.go"] = &FileCoverage{Path: "pkg/a/b.go", Total: 10, Covered: 2}
+	other.Files["pkg/c/d.go"] = &FileCoverage{Path: "pkg/c/d.go"}
+	report.Merge(other)
+
+	// The better covered entry of each file is kept
+	if report.Files["pkg/a/a.go"].Covered != 8 || report.Files["pkg/a/b.go"].Covered != 10 {
+		t.Fatalf("unexpected merge %+v %+v", report.Files["pkg/a/a.go"], report.Files["pkg/a/b.go"])
+	}
+	if got := report.Total(); math.Abs(got-57.5) > 1e-9 {
+		t.Fatalf("got total %v", got)
+	}
+
+	var packages []string
+	for _, pkg := range report.Packages() {
+		packages = append(packages, pkg.Path)
+		if pkg.Path == "pkg/a" && pkg.Percent() != 90 {
+			t.Fatalf("got %v%% for pkg/a, want 90%%", pkg.Percent())
+		}
+		if pkg.Path == "pkg/c" && pkg.Percent() != 100 {
+			t.Fatalf("expected a package without statements to be fully covered, got %v", pkg.Percent())
+		}
+	}
+	if want := []string{"pkg/a", "pkg/b", "pkg/c"}; !reflect.DeepEqual(packages, want) {
+		t.Fatalf("got packages %q, want %q", packages, want)
+	}
+}
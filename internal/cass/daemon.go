@@ -0,0 +1,610 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DaemonConfig configures the on-save analysis daemon
+type DaemonConfig struct {
+	Root         string        // Workspace root
+	Addr         string        // host:port, or unix:/path for a unix socket
+	Watch        bool          // Re-analyze files changed on disk
+	PollInterval time.Duration // How often the workspace is checked for changes
+	CI           *CIConfig     // File patterns and architecture rules
+}
+
+// DefaultDaemonConfig returns the default daemon configuration
+func DefaultDaemonConfig() *DaemonConfig {
+	return &DaemonConfig{
+		Root:         ".",
+		Addr:         "127.0.0.1:7621",
+		PollInterval: 250 * time.Millisecond,
+		CI:           DefaultCIConfig(),
+	}
+}
+
+// FileAnalysis is the latest analysis of a workspace file
+type FileAnalysis struct {
+	Path        string            `json:"path"`
+	Language    string            `json:"language"`
+	Version     int               `json:"version"` // Increments on each analysis
+	Deleted     bool              `json:"deleted,omitempty"`
+	Results     []*AnalysisResult `json:"results"`
+	Diagnostics []Diagnostic      `json:"diagnostics"`
+	Duration    time.Duration     `json:"duration"`
+	AnalyzedAt  time.Time         `json:"analyzed_at"`
+}
+
+// Diagnostic is a finding in the shape of an LSP diagnostic, with zero based
+// lines
+type Diagnostic struct {
+	Range    DiagnosticRange `json:"range"`
+	Severity int             `json:"severity"` // 1 error, 2 warning, 3 information, 4 hint
+	Code     string          `json:"code"`
+	Source   string          `json:"source"`
+	Message  string          `json:"message"`
+}
+
+// DiagnosticRange is the span of a diagnostic
+type DiagnosticRange struct {
+	Start DiagnosticPosition `json:"start"`
+	End   DiagnosticPosition `json:"end"`
+}
+
+// DiagnosticPosition is a zero based line and character
+type DiagnosticPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// fileAnalyzer is an analyzer the daemon keeps warm
+type fileAnalyzer interface {
+	ID() string
+	Analyze(ctx context.Context, artifact *Artifact) (*AnalysisResult, error)
+}
+
+// Daemon keeps analyzers warm, re-analyzes files on save and serves the
+// results to editor plugins over JSON-RPC and HTTP
+type Daemon struct {
+	config    *DaemonConfig
+	analyzers []fileAnalyzer
+	include   []*regexp.Regexp
+	exclude   []*regexp.Regexp
+
+	// Analyzers keep state such as the duplicate index, so analysis is serialized
+	analyzeMu sync.Mutex
+
+	mu     sync.RWMutex
+	files  map[string]*FileAnalysis
+	mtimes map[string]time.Time
+
+	subMu       sync.Mutex
+	subscribers map[chan *FileAnalysis]struct{}
+}
+
+// NewDaemon creates an analysis daemon
+func NewDaemon(config *DaemonConfig) (*Daemon, error) {
+	if config.CI == nil {
+		config.CI = DefaultCIConfig()
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultDaemonConfig().PollInterval
+	}
+
+	d := &Daemon{
+		config:      config,
+		analyzers:   []fileAnalyzer{NewSecurityScanner(), NewQualityAnalyzer(), NewDuplicateDetector()},
+		files:       make(map[string]*FileAnalysis),
+		mtimes:      make(map[string]time.Time),
+		subscribers: make(map[chan *FileAnalysis]struct{}),
+	}
+	if len(config.CI.ArchitectureRules) > 0 {
+		architecture, err := NewArchitectureAnalyzer(config.CI.ArchitectureRules)
+		if err != nil {
+			return nil, err
+		}
+		d.analyzers = append(d.analyzers, architecture)
+	}
+	for _, pattern := range config.CI.IncludePatterns {
+		d.include = append(d.include, layerPattern(pattern, true))
+	}
+	for _, pattern := range config.CI.ExcludePatterns {
+		d.exclude = append(d.exclude, layerPattern(pattern, true))
+	}
+
+	return d, nil
+}
+
+// Serve analyzes the workspace, watches it if enabled and serves requests
+// until the context is cancelled
+func (d *Daemon) Serve(ctx context.Context) error {
+	listener, err := d.listen()
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: d.Handler()}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	// The initial scan warms the duplicate index while requests are served
+	go func() {
+		start := time.Now()
+		d.scan(ctx)
+		log.Printf("CASS daemon analyzed %d files in %s", len(d.Files()), time.Since(start).Round(time.Millisecond))
+		if d.config.Watch {
+			d.watch(ctx)
+		}
+	}()
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// listen opens the TCP or unix socket
+func (d *Daemon) listen() (net.Listener, error) {
+	if socket, ok := strings.CutPrefix(d.config.Addr, "unix:"); ok {
+		// A stale socket from a previous run would block listening
+		_ = os.Remove(socket)
+		return net.Listen("unix", socket)
+	}
+	return net.Listen("tcp", d.config.Addr)
+}
+
+// AnalyzeFile analyzes a workspace file. Content overrides what is on disk,
+// so editors can analyze unsaved buffers.
+func (d *Daemon) AnalyzeFile(ctx context.Context, filePath string, content []byte) (*FileAnalysis, error) {
+	relPath, err := d.relative(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		if content, err = os.ReadFile(filepath.Join(d.config.Root, relPath)); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	artifact := &Artifact{
+		ID:        relPath,
+		TenantID:  "default",
+		Type:      ArtifactTypeSource,
+		Language:  detectLanguage(relPath),
+		Path:      relPath,
+		Name:      filepath.Base(relPath),
+		Content:   content,
+		Size:      int64(len(content)),
+		Stage:     StageRaw,
+		Features:  make(map[FeatureType][]byte),
+		Metadata:  make(map[string]interface{}),
+		CreatedAt: start,
+		UpdatedAt: start,
+		Version:   1,
+	}
+
+	analysis := &FileAnalysis{
+		Path:        relPath,
+		Language:    artifact.Language,
+		Results:     make([]*AnalysisResult, 0, len(d.analyzers)),
+		Diagnostics: make([]Diagnostic, 0),
+	}
+	d.analyzeMu.Lock()
+	for _, analyzer := range d.analyzers {
+		result, err := analyzer.Analyze(ctx, artifact)
+		if err != nil {
+			log.Printf("Warning: %s failed on %s: %v", analyzer.ID(), relPath, err)
+			continue
+		}
+		analysis.Results = append(analysis.Results, result)
+		for _, finding := range result.Findings {
+			analysis.Diagnostics = append(analysis.Diagnostics, newDiagnostic(finding))
+		}
+	}
+	d.analyzeMu.Unlock()
+	analysis.Duration = time.Since(start)
+	analysis.AnalyzedAt = time.Now()
+
+	d.mu.Lock()
+	if previous, ok := d.files[relPath]; ok {
+		analysis.Version = previous.Version + 1
+	} else {
+		analysis.Version = 1
+	}
+	d.files[relPath] = analysis
+	d.mu.Unlock()
+
+	d.publish(analysis)
+	return analysis, nil
+}
+
+// Result returns the latest analysis of a file
+func (d *Daemon) Result(filePath string) (*FileAnalysis, bool) {
+	relPath, err := d.relative(filePath)
+	if err != nil {
+		return nil, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	analysis, ok := d.files[relPath]
+	return analysis, ok
+}
+
+// Files returns the paths of all analyzed files
+func (d *Daemon) Files() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	files := make([]string, 0, len(d.files))
+	for path := range d.files {
+		files = append(files, path)
+	}
+	return files
+}
+
+// Subscribe returns a channel of analyses as they complete, and a function to
+// unsubscribe. Slow subscribers miss analyses rather than block analysis.
+func (d *Daemon) Subscribe() (<-chan *FileAnalysis, func()) {
+	ch := make(chan *FileAnalysis, 64)
+	d.subMu.Lock()
+	d.subscribers[ch] = struct{}{}
+	d.subMu.Unlock()
+	return ch, func() {
+		d.subMu.Lock()
+		delete(d.subscribers, ch)
+		d.subMu.Unlock()
+	}
+}
+
+// publish sends an analysis to all subscribers
+func (d *Daemon) publish(analysis *FileAnalysis) {
+	d.subMu.Lock()
+	defer d.subMu.Unlock()
+	for ch := range d.subscribers {
+		select {
+		case ch <- analysis:
+		default:
+		}
+	}
+}
+
+// scan analyzes every workspace file that changed since it was last analyzed
+// and forgets deleted files
+func (d *Daemon) scan(ctx context.Context) {
+	seen := make(map[string]bool)
+	_ = filepath.WalkDir(d.config.Root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || ctx.Err() != nil {
+			return ctx.Err()
+		}
+		relPath, relErr := filepath.Rel(d.config.Root, path)
+		if relErr != nil {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+		if entry.IsDir() {
+			if relPath != "." && (strings.HasPrefix(entry.Name(), ".") || d.excluded(relPath+"/")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.watched(relPath) {
+			return nil
+		}
+		seen[relPath] = true
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		d.mu.RLock()
+		modified := !info.ModTime().Equal(d.mtimes[relPath])
+		d.mu.RUnlock()
+		if !modified {
+			return nil
+		}
+
+		d.mu.Lock()
+		d.mtimes[relPath] = info.ModTime()
+		d.mu.Unlock()
+		if _, err := d.AnalyzeFile(ctx, relPath, nil); err != nil {
+			log.Printf("Warning: Failed to analyze %s: %v", relPath, err)
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return
+	}
+
+	var deleted []*FileAnalysis
+	d.mu.Lock()
+	for path, analysis := range d.files {
+		if !seen[path] {
+			delete(d.files, path)
+			delete(d.mtimes, path)
+			deleted = append(deleted, &FileAnalysis{Path: path, Language: analysis.Language, Version: analysis.Version + 1, Deleted: true})
+		}
+	}
+	d.mu.Unlock()
+	for _, analysis := range deleted {
+		d.publish(analysis)
+	}
+}
+
+// watch rescans the workspace on every poll interval
+func (d *Daemon) watch(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.scan(ctx)
+		}
+	}
+}
+
+// watched reports whether a file matches the include and not the exclude patterns
+func (d *Daemon) watched(relPath string) bool {
+	if d.excluded(relPath) {
+		return false
+	}
+	for _, include := range d.include {
+		if include.MatchString(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether a path matches an exclude pattern
+func (d *Daemon) excluded(relPath string) bool {
+	for _, exclude := range d.exclude {
+		if exclude.MatchString(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// relative turns an absolute, file:// or workspace relative path into a
+// workspace relative path, rejecting paths outside the workspace
+func (d *Daemon) relative(filePath string) (string, error) {
+	if strings.HasPrefix(filePath, "file://") {
+		parsed, err := url.Parse(filePath)
+		if err != nil {
+			return "", err
+		}
+		filePath = parsed.Path
+	}
+	if filepath.IsAbs(filePath) {
+		root, err := filepath.Abs(d.config.Root)
+		if err != nil {
+			return "", err
+		}
+		if filePath, err = filepath.Rel(root, filePath); err != nil {
+			return "", err
+		}
+	}
+	filePath = filepath.Clean(filePath)
+	if filePath == ".." || strings.HasPrefix(filePath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path is outside the workspace: %s", filePath)
+	}
+	return filepath.ToSlash(filePath), nil
+}
+
+// newDiagnostic converts a finding into a diagnostic
+func newDiagnostic(finding Finding) Diagnostic {
+	line := max(finding.Line-1, 0)
+	endLine := max(finding.EndLine-1, line)
+	column := max(finding.Column-1, 0)
+	endColumn := finding.EndColumn - 1
+	if endColumn <= 0 && endLine == line {
+		// Without an end the whole line is marked
+		endLine, endColumn = line+1, 0
+	}
+
+	severity := 3
+	switch finding.Severity {
+	case "critical", "high":
+		severity = 1
+	case "medium":
+		severity = 2
+	case "low":
+		severity = 3
+	}
+
+	return Diagnostic{
+		Range: DiagnosticRange{
+			Start: DiagnosticPosition{Line: line, Character: column},
+			End:   DiagnosticPosition{Line: endLine, Character: max(endColumn, 0)},
+		},
+		Severity: severity,
+		Code:     finding.Rule,
+		Source:   "cass",
+		Message:  finding.Message,
+	}
+}
+
+// rpcRequest is a JSON-RPC 2.0 request
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC 2.0 error
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// Handler returns the daemon's HTTP handler:
+//
+//	POST /rpc               JSON-RPC 2.0: cass/analyze, cass/results, cass/files,
+//	                        textDocument/didSave, textDocument/didChange
+//	GET  /files?path=       latest analysis of a file
+//	GET  /events            server-sent events of analyses as they complete
+//	GET  /health            daemon status
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /rpc", d.handleRPC)
+	mux.HandleFunc("GET /files", d.handleFile)
+	mux.HandleFunc("GET /events", d.handleEvents)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "ok",
+			"root":   d.config.Root,
+			"watch":  d.config.Watch,
+			"files":  len(d.Files()),
+		})
+	})
+	return mux
+}
+
+// handleRPC serves JSON-RPC requests; notifications without an id get no body
+func (d *Daemon) handleRPC(w http.ResponseWriter, r *http.Request) {
+	var req rpcRequest
+	resp := rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp.Error = &rpcError{Code: rpcParseError, Message: err.Error()}
+	} else {
+		if len(req.ID) > 0 {
+			resp.ID = req.ID
+		}
+		resp.Result, resp.Error = d.call(r.Context(), req.Method, req.Params)
+	}
+
+	if len(req.ID) == 0 && resp.Error == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// call dispatches a JSON-RPC method
+func (d *Daemon) call(ctx context.Context, method string, raw json.RawMessage) (interface{}, *rpcError) {
+	var params struct {
+		Path         string  `json:"path"`
+		Content      *string `json:"content"`
+		Text         *string `json:"text"`
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+	}
+	path := params.Path
+	if path == "" {
+		path = params.TextDocument.URI
+	}
+
+	switch method {
+	case "cass/files":
+		return d.Files(), nil
+	case "cass/results":
+		if analysis, ok := d.Result(path); ok {
+			return analysis, nil
+		}
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "file has not been analyzed: " + path}
+	case "cass/analyze", "textDocument/didSave", "textDocument/didChange":
+		if path == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "path or textDocument.uri is required"}
+		}
+		var content []byte
+		switch {
+		case params.Content != nil:
+			content = []byte(*params.Content)
+		case params.Text != nil:
+			content = []byte(*params.Text)
+		case len(params.ContentChanges) > 0:
+			// Full document sync sends the whole buffer as the last change
+			content = []byte(params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+		analysis, err := d.AnalyzeFile(ctx, path, content)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return analysis, nil
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+// handleFile returns the latest analysis of a file
+func (d *Daemon) handleFile(w http.ResponseWriter, r *http.Request) {
+	analysis, ok := d.Result(r.URL.Query().Get("path"))
+	if !ok {
+		http.Error(w, "File has not been analyzed", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(analysis)
+}
+
+// handleEvents streams analyses as server-sent events
+func (d *Daemon) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case analysis := <-events:
+			data, err := json.Marshal(analysis)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: analysis\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestDaemon(t *testing.T) (*Daemon, string) {
+	t.Helper()
+	root := t.TempDir()
+	config := DefaultDaemonConfig()
+	config.Root = root
+	d, err := NewDaemon(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, root
+}
+
+func writeWorkspaceFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewDiagnostic(t *testing.T) {
+	tests := []struct {
+		name    string
+		finding Finding
+		want    Diagnostic
+	}{
+		{
+			name:    "whole line without an end",
+			finding: Finding{Line: 3, Column: 5, Severity: "high", Rule: "SEC-002", Message: "secret"},
+			want: Diagnostic{
+				Range:    DiagnosticRange{Start: DiagnosticPosition{Line: 2, Character: 4}, End: DiagnosticPosition{Line: 3}},
+				Severity: 1, Code: "SEC-002", Source: "cass", Message: "secret",
+			},
+		},
+		{
+			name:    "explicit span",
+			finding: Finding{Line: 1, Column: 2, EndLine: 4, EndColumn: 8, Severity: "medium"},
+			want: Diagnostic{
+				Range:    DiagnosticRange{Start: DiagnosticPosition{Line: 0, Character: 1}, End: DiagnosticPosition{Line: 3, Character: 7}},
+				Severity: 2, Source: "cass",
+			},
+		},
+		{
+			name:    "file level finding",
+			finding: Finding{Severity: "critical"},
+			want: Diagnostic{
+				Range:    DiagnosticRange{End: DiagnosticPosition{Line: 1}},
+				Severity: 1, Source: "cass",
+			},
+		},
+		{
+			name:    "low and unknown severities are information",
+			finding: Finding{Line: 2, Severity: "info"},
+			want: Diagnostic{
+				Range:    DiagnosticRange{Start: DiagnosticPosition{Line: 1}, End: DiagnosticPosition{Line: 2}},
+				Severity: 3, Source: "cass",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newDiagnostic(tt.finding); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonRelativePaths(t *testing.T) {
+	d, root := newTestDaemon(t)
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "pkg/a.go", want: "pkg/a.go"},
+		{path: "./pkg/../b.go", want: "b.go"},
+		{path: filepath.Join(root, "pkg", "a.go"), want: "pkg/a.go"},
+		{path: "file://" + filepath.ToSlash(filepath.Join(root, "c.go")), want: "c.go"},
+		{path: "../outside.go", wantErr: true},
+		{path: filepath.Join(filepath.Dir(root), "outside.go"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := d.relative(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected %s to be rejected, got %q", tt.path, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("got %q %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaemonRPC(t *testing.T) {
+	d, root := newTestDaemon(t)
+	writeWorkspaceFile(t, root, "config.go", "package config\n\nvar password = \"hunter2\"\n")
+	server := httptest.NewServer(d.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		body        string
+		status      int
+		errorCode   int
+		diagnostics []string
+	}{
+		{
+			name:        "analyze from disk",
+			body:        `{"jsonrpc":"2.0","id":1,"method":"cass/analyze","params":{"path":"config.go"}}`,
+			status:      http.StatusOK,
+			diagnostics: []string{"SEC-002"},
+		},
+		{
+			name:   "unsaved buffer from didChange",
+			body:   `{"jsonrpc":"2.0","id":2,"method":"textDocument/didChange","params":{"textDocument":{"uri":"config.go"},"contentChanges":[{"text":"package config\n"}]}}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "notification without id",
+			body:   `{"jsonrpc":"2.0","method":"textDocument/didSave","params":{"textDocument":{"uri":"config.go"}}}`,
+			status: http.StatusNoContent,
+		},
+		{
+			name:      "missing path",
+			body:      `{"jsonrpc":"2.0","id":3,"method":"cass/analyze","params":{}}`,
+			status:    http.StatusOK,
+			errorCode: rpcInvalidParams,
+		},
+		{
+			name:      "file outside the workspace",
+			body:      `{"jsonrpc":"2.0","id":4,"method":"cass/analyze","params":{"path":"../etc/passwd"}}`,
+			status:    http.StatusOK,
+			errorCode: rpcInternalError,
+		},
+		{
+			name:      "results of an unknown file",
+			body:      `{"jsonrpc":"2.0","id":5,"method":"cass/results","params":{"path":"missing.go"}}`,
+			status:    http.StatusOK,
+			errorCode: rpcInvalidParams,
+		},
+		{
+			name:      "unknown method",
+			body:      `{"jsonrpc":"2.0","id":6,"method":"cass/nope"}`,
+			status:    http.StatusOK,
+			errorCode: rpcMethodNotFound,
+		},
+		{
+			name:      "malformed request",
+			body:      `{`,
+			status:    http.StatusOK,
+			errorCode: rpcParseError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL+"/rpc", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if resp.StatusCode == http.StatusNoContent {
+				return
+			}
+
+			var body struct {
+				Result *FileAnalysis `json:"result"`
+				Error  *rpcError     `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.errorCode != 0 {
+				if body.Error == nil || body.Error.Code != tt.errorCode {
+					t.Fatalf("got error %+v, want code %d", body.Error, tt.errorCode)
+				}
+				return
+			}
+			if body.Error != nil {
+				t.Fatalf("unexpected error %+v", body.Error)
+			}
+			var codes []string
+			for _, diagnostic := range body.Result.Diagnostics {
+				if strings.HasPrefix(diagnostic.Code, "SEC-") {
+					codes = append(codes, diagnostic.Code)
+				}
+			}
+			if !reflect.DeepEqual(codes, tt.diagnostics) {
+				t.Fatalf("got security diagnostics %q, want %q", codes, tt.diagnostics)
+			}
+		})
+	}
+
+	// Every analysis of the file bumps its version
+	analysis, ok := d.Result("config.go")
+	if !ok || analysis.Version != 3 {
+		t.Fatalf("expected 3 analyses of config.go, got %+v", analysis)
+	}
+	resp, err := http.Get(server.URL + "/files?path=missing.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown file, got %d", resp.StatusCode)
+	}
+}
+
+func TestDaemonScan(t *testing.T) {
+	d, root := newTestDaemon(t)
+	writeWorkspaceFile(t, root, "main.go", "package main\n")
+	writeWorkspaceFile(t, root, "README.md", "# readme\n")
+	writeWorkspaceFile(t, root, "vendor/lib/lib.go", "package lib\n")
+	writeWorkspaceFile(t, root, ".git/hooks/hook.go", "package hooks\n")
+	events, unsubscribe := d.Subscribe()
+	defer unsubscribe()
+
+	ctx := context.Background()
+	d.scan(ctx)
+	if files := d.Files(); !reflect.DeepEqual(files, []string{"main.go"}) {
+		t.Fatalf("expected only watched files to be analyzed, got %q", files)
+	}
+	if analysis := <-events; analysis.Path != "main.go" || analysis.Version != 1 {
+		t.Fatalf("unexpected event %+v", analysis)
+	}
+
+	// Unchanged files are not analyzed again
+	d.scan(ctx)
+	select {
+	case analysis := <-events:
+		t.Fatalf("unexpected event %+v", analysis)
+	default:
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(root, "main.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	d.scan(ctx)
+	if analysis := <-events; analysis.Path != "main.go" || analysis.Version != 2 {
+		t.Fatalf("expected the modified file to be analyzed again, got %+v", analysis)
+	}
+
+	if err := os.Remove(filepath.Join(root, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+	d.scan(ctx)
+	if analysis := <-events; !analysis.Deleted || analysis.Version != 3 {
+		t.Fatalf("expected a deletion event, got %+v", analysis)
+	}
+	if files := d.Files(); len(files) != 0 {
+		t.Fatalf("expected the deleted file to be forgotten, got %q", files)
+	}
+}
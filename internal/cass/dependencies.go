@@ -0,0 +1,699 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DependencyAnalyzer builds import/include dependency graphs per project and
+// reports cycles, god modules and architecture rule violations
+type DependencyAnalyzer struct {
+	*BaseAnalyzer
+	godFanIn  int
+	godFanOut int
+
+	graphMu sync.RWMutex
+	sources map[string]map[string]*moduleSource // project -> artifact -> imports
+	graphs  map[string]*DependencyGraph
+}
+
+// moduleSource records the imports of one artifact
+type moduleSource struct {
+	artifactID string
+	path       string
+	language   string
+	imports    []string
+}
+
+// DependencyGraph is the module dependency graph of a project
+type DependencyGraph struct {
+	ProjectID string                     `json:"project_id"`
+	Nodes     map[string]*DependencyNode `json:"nodes"`
+	Edges     []DependencyEdge           `json:"edges"`
+	Cycles    [][]string                 `json:"cycles"`
+	BuiltAt   time.Time                  `json:"built_at"`
+}
+
+// DependencyNode is a module: a Go package directory, or a file path
+// without its extension for other languages
+type DependencyNode struct {
+	ID          string   `json:"id"`
+	Language    string   `json:"language"`
+	Artifacts   []string `json:"artifacts"`
+	FanIn       int      `json:"fan_in"`
+	FanOut      int      `json:"fan_out"`
+	Instability float64  `json:"instability"`        // FanOut / (FanIn + FanOut)
+	External    []string `json:"external,omitempty"` // Imports outside the project
+	InCycle     bool     `json:"in_cycle"`
+	GodModule   bool     `json:"god_module"`
+}
+
+// DependencyEdge is an import of one module by another
+type DependencyEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+var (
+	jsImportPattern     = regexp.MustCompile(`(?m)(?:\bimport\s+(?:[\w*{}\s,]+\s+from\s+)?|\bexport\s+[\w*{}\s,]+\s+from\s+|\brequire\(\s*|\bimport\(\s*)['"]([^'"]+)['"]`)
+	pythonImportPattern = regexp.MustCompile(`(?m)^\s*(?:from\s+([.\w]+)\s+import\b|import\s+([\w.]+(?:\s*,\s*[\w.]+)*))`)
+	includePattern      = regexp.MustCompile(`(?m)^\s*#\s*include\s*[<"]([^>"]+)[>"]`)
+	javaImportPattern   = regexp.MustCompile(`(?m)^\s*import\s+(?:static\s+)?([\w.]+?)(?:\.\*)?\s*;`)
+)
+
+// NewDependencyAnalyzer creates a new dependency analyzer
+func NewDependencyAnalyzer() *DependencyAnalyzer {
+	analyzer := &DependencyAnalyzer{
+		BaseAnalyzer: NewBaseAnalyzer(
+			"dependency-analyzer",
+			"Dependency Graph Analyzer",
+			"1.0.0",
+			CapabilityAnalyze|CapabilityIndex|CapabilityValidate,
+		),
+		godFanIn:  10,
+		godFanOut: 10,
+		sources:   make(map[string]map[string]*moduleSource),
+		graphs:    make(map[string]*DependencyGraph),
+	}
+
+	// Set supported languages
+	analyzer.languages = []string{"go", "javascript", "typescript", "python", "java", "c", "cpp"}
+
+	analyzer.AddRule(Rule{
+		ID:          "DEPENDENCY-001",
+		Name:        "Dependency Cycle",
+		Description: "Detects modules that import each other directly or transitively",
+		Type:        "dependency",
+		Severity:    "high",
+		Enabled:     true,
+	})
+
+	analyzer.AddRule(Rule{
+		ID:          "DEPENDENCY-002",
+		Name:        "God Module",
+		Description: "Detects modules with both high fan-in and high fan-out",
+		Type:        "dependency",
+		Severity:    "medium",
+		Enabled:     true,
+	})
+
+	return analyzer
+}
+
+// SetGodModuleThresholds sets the fan-in and fan-out a module must both reach
+// to be reported as a god module
+func (d *DependencyAnalyzer) SetGodModuleThresholds(fanIn, fanOut int) {
+	d.graphMu.Lock()
+	defer d.graphMu.Unlock()
+	d.godFanIn = fanIn
+	d.godFanOut = fanOut
+}
+
+// AddArchitectureRule forbids modules matching the from pattern to import
+// modules matching the to pattern. Patterns are regular expressions on module IDs.
+func (d *DependencyAnalyzer) AddArchitectureRule(id, description, from, to, severity string) error {
+	if _, err := regexp.Compile(from); err != nil {
+		return fmt.Errorf("invalid from pattern: %w", err)
+	}
+	if _, err := regexp.Compile(to); err != nil {
+		return fmt.Errorf("invalid to pattern: %w", err)
+	}
+	d.AddRule(Rule{
+		ID:          id,
+		Name:        "Architecture Rule",
+		Description: description,
+		Type:        "architecture",
+		Severity:    severity,
+		Pattern:     from,
+		Enabled:     true,
+		Config: map[string]interface{}{
+			"from": from,
+			"to":   to,
+		},
+	})
+	return nil
+}
+
+// BuildIndex replaces the sources of every project among the artifacts and
+// rebuilds their graphs
+func (d *DependencyAnalyzer) BuildIndex(ctx context.Context, artifacts []*Artifact) error {
+	projects := make(map[string]map[string]*moduleSource)
+	for _, artifact := range artifacts {
+		if projects[artifact.ProjectID] == nil {
+			projects[artifact.ProjectID] = make(map[string]*moduleSource)
+		}
+		projects[artifact.ProjectID][artifact.ID] = d.extractSource(artifact)
+	}
+
+	d.graphMu.Lock()
+	defer d.graphMu.Unlock()
+	for projectID, sources := range projects {
+		d.sources[projectID] = sources
+		d.graphs[projectID] = d.buildGraph(projectID, sources)
+	}
+	return nil
+}
+
+// Analyze adds the artifact to its project's graph and reports the findings
+// concerning its module
+func (d *DependencyAnalyzer) Analyze(ctx context.Context, artifact *Artifact) (*AnalysisResult, error) {
+	start := time.Now()
+	result := &AnalysisResult{
+		ArtifactID:  artifact.ID,
+		AnalyzerID:  d.ID(),
+		Type:        "dependency",
+		Findings:    make([]Finding, 0),
+		Metrics:     make(map[string]float64),
+		ProcessedAt: time.Now(),
+	}
+
+	source := d.extractSource(artifact)
+	d.graphMu.Lock()
+	if d.sources[artifact.ProjectID] == nil {
+		d.sources[artifact.ProjectID] = make(map[string]*moduleSource)
+	}
+	d.sources[artifact.ProjectID][artifact.ID] = source
+	graph := d.buildGraph(artifact.ProjectID, d.sources[artifact.ProjectID])
+	d.graphs[artifact.ProjectID] = graph
+	d.graphMu.Unlock()
+
+	module := moduleID(source.path, source.language)
+	for _, finding := range d.graphFindings(graph) {
+		if finding.Metadata["module"] == module || finding.Metadata["from"] == module {
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+
+	if node := graph.Nodes[module]; node != nil {
+		result.Metrics["fan_in"] = float64(node.FanIn)
+		result.Metrics["fan_out"] = float64(node.FanOut)
+		result.Metrics["instability"] = node.Instability
+		result.Metrics["external_dependencies"] = float64(len(node.External))
+	}
+	result.Metrics["imports"] = float64(len(source.imports))
+
+	result.Score = d.calculateDependencyScore(result.Findings)
+	result.Duration = time.Since(start)
+	result.Confidence = 0.9
+
+	return result, nil
+}
+
+// Graph returns the dependency graph of a project, or nil before it is indexed
+func (d *DependencyAnalyzer) Graph(projectID string) *DependencyGraph {
+	d.graphMu.RLock()
+	defer d.graphMu.RUnlock()
+	return d.graphs[projectID]
+}
+
+// Findings reports the cycles, god modules and architecture rule violations
+// of a project's graph
+func (d *DependencyAnalyzer) Findings(projectID string) []Finding {
+	graph := d.Graph(projectID)
+	if graph == nil {
+		return nil
+	}
+	return d.graphFindings(graph)
+}
+
+// Export renders a project's graph for visualization, as "dot" or "json"
+func (d *DependencyAnalyzer) Export(projectID, format string) ([]byte, error) {
+	graph := d.Graph(projectID)
+	if graph == nil {
+		return nil, fmt.Errorf("no dependency graph for project %s", projectID)
+	}
+	switch format {
+	case "dot":
+		return []byte(graph.DOT()), nil
+	case "json":
+		return json.MarshalIndent(graph, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ExtractFeatures extracts dependency metrics of the artifact's module
+func (d *DependencyAnalyzer) ExtractFeatures(ctx context.Context, artifact *Artifact) ([]*FeatureVector, error) {
+	vector := make([]float64, 8)
+
+	source := d.extractSource(artifact)
+	vector[0] = float64(len(source.imports))
+	if graph := d.Graph(artifact.ProjectID); graph != nil {
+		if node := graph.Nodes[moduleID(source.path, source.language)]; node != nil {
+			vector[1] = float64(node.FanIn)
+			vector[2] = float64(node.FanOut)
+			vector[3] = node.Instability
+			vector[4] = float64(len(node.External))
+			if node.InCycle {
+				vector[5] = 1.0
+			}
+			if node.GodModule {
+				vector[6] = 1.0
+			}
+		}
+	}
+
+	return []*FeatureVector{{
+		ArtifactID: artifact.ID,
+		Type:       FeatureStructural,
+		Vector:     vector,
+		Metadata: map[string]string{
+			"analyzer": "dependency-analyzer",
+			"feature":  "dependency_metrics",
+		},
+		Confidence:  0.9,
+		GeneratedAt: time.Now(),
+	}}, nil
+}
+
+// extractSource extracts the imports of an artifact
+func (d *DependencyAnalyzer) extractSource(artifact *Artifact) *moduleSource {
+	return &moduleSource{
+		artifactID: artifact.ID,
+		path:       path.Clean(strings.ReplaceAll(artifact.Path, "\\", "/")),
+		language:   artifact.Language,
+		imports:    extractImports(artifact.Path, artifact.Language, string(artifact.Content)),
+	}
+}
+
+// extractImports lists the modules imported or included by source code
+func extractImports(filePath, language, content string) []string {
+	var imports []string
+	switch language {
+	case "go":
+		file, err := parser.ParseFile(token.NewFileSet(), filePath, content, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, spec := range file.Imports {
+			imports = append(imports, strings.Trim(spec.Path.Value, "\"`"))
+		}
+	case "javascript", "typescript":
+		for _, match := range jsImportPattern.FindAllStringSubmatch(content, -1) {
+			imports = append(imports, match[1])
+		}
+	case "python":
+		for _, match := range pythonImportPattern.FindAllStringSubmatch(content, -1) {
+			if match[1] != "" {
+				imports = append(imports, match[1])
+				continue
+			}
+			for _, name := range strings.Split(match[2], ",") {
+				imports = append(imports, strings.TrimSpace(name))
+			}
+		}
+	case "c", "cpp":
+		for _, match := range includePattern.FindAllStringSubmatch(content, -1) {
+			imports = append(imports, match[1])
+		}
+	case "java":
+		for _, match := range javaImportPattern.FindAllStringSubmatch(content, -1) {
+			imports = append(imports, match[1])
+		}
+	}
+	return unique(imports)
+}
+
+// moduleID identifies the module of a file
+func moduleID(filePath, language string) string {
+	if language == "go" {
+		return path.Dir(filePath)
+	}
+	return strings.TrimSuffix(filePath, path.Ext(filePath))
+}
+
+// buildGraph resolves the imports of a project's sources to its modules and
+// computes metrics and cycles. Callers hold graphMu.
+func (d *DependencyAnalyzer) buildGraph(projectID string, sources map[string]*moduleSource) *DependencyGraph {
+	graph := &DependencyGraph{
+		ProjectID: projectID,
+		Nodes:     make(map[string]*DependencyNode),
+		Edges:     make([]DependencyEdge, 0),
+		Cycles:    make([][]string, 0),
+		BuiltAt:   time.Now(),
+	}
+
+	ids := make([]string, 0, len(sources))
+	for id := range sources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		source := sources[id]
+		module := moduleID(source.path, source.language)
+		node := graph.Nodes[module]
+		if node == nil {
+			node = &DependencyNode{ID: module, Language: source.language}
+			graph.Nodes[module] = node
+		}
+		node.Artifacts = append(node.Artifacts, source.artifactID)
+	}
+
+	adjacency := make(map[string]map[string]bool)
+	for _, id := range ids {
+		source := sources[id]
+		from := moduleID(source.path, source.language)
+		for _, imported := range source.imports {
+			to := graph.resolve(source, imported)
+			if to == "" {
+				graph.Nodes[from].External = append(graph.Nodes[from].External, imported)
+				continue
+			}
+			if to == from {
+				continue
+			}
+			if adjacency[from] == nil {
+				adjacency[from] = make(map[string]bool)
+			}
+			adjacency[from][to] = true
+		}
+	}
+
+	for from, targets := range adjacency {
+		for to := range targets {
+			graph.Edges = append(graph.Edges, DependencyEdge{From: from, To: to})
+			graph.Nodes[from].FanOut++
+			graph.Nodes[to].FanIn++
+		}
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	for _, node := range graph.Nodes {
+		node.External = unique(node.External)
+		if total := node.FanIn + node.FanOut; total > 0 {
+			node.Instability = float64(node.FanOut) / float64(total)
+		}
+		node.GodModule = node.FanIn >= d.godFanIn && node.FanOut >= d.godFanOut
+	}
+
+	graph.Cycles = graph.findCycles()
+	for _, cycle := range graph.Cycles {
+		for _, module := range cycle {
+			graph.Nodes[module].InCycle = true
+		}
+	}
+	return graph
+}
+
+// resolve maps an import to a module of the graph, or "" for imports from
+// outside the project
+func (g *DependencyGraph) resolve(source *moduleSource, imported string) string {
+	dir := path.Dir(source.path)
+	var candidates []string
+	suffix := ""
+
+	switch source.language {
+	case "go":
+		suffix = imported
+	case "javascript", "typescript":
+		if !strings.HasPrefix(imported, ".") {
+			return ""
+		}
+		target := path.Join(dir, imported)
+		candidates = []string{strings.TrimSuffix(target, path.Ext(target)), target, path.Join(target, "index")}
+	case "python":
+		if strings.HasPrefix(imported, ".") {
+			trimmed := strings.TrimLeft(imported, ".")
+			base := dir
+			for i := 1; i < len(imported)-len(trimmed); i++ {
+				base = path.Dir(base)
+			}
+			target := path.Join(base, strings.ReplaceAll(trimmed, ".", "/"))
+			candidates = []string{target, path.Join(target, "__init__")}
+			break
+		}
+		suffix = strings.ReplaceAll(imported, ".", "/")
+		candidates = []string{suffix, path.Join(suffix, "__init__")}
+	case "c", "cpp":
+		target := strings.TrimSuffix(imported, path.Ext(imported))
+		candidates = []string{path.Join(dir, target), target}
+		suffix = target
+	case "java":
+		suffix = strings.ReplaceAll(imported, ".", "/")
+	}
+
+	for _, candidate := range candidates {
+		if _, ok := g.Nodes[candidate]; ok {
+			return candidate
+		}
+	}
+	if suffix == "" {
+		return ""
+	}
+
+	// Go import paths carry the module path the file paths lack, while file
+	// paths of other languages may carry a source root the imports lack. The
+	// longest matching module wins.
+	best := ""
+	for id := range g.Nodes {
+		if id == "." || id == "" {
+			continue
+		}
+		matches := suffix == id || strings.HasSuffix(suffix, "/"+id)
+		if source.language != "go" {
+			matches = matches || strings.HasSuffix(id, "/"+suffix)
+		}
+		if matches && len(id) > len(best) {
+			best = id
+		}
+	}
+	return best
+}
+
+// findCycles returns the strongly connected components with more than one
+// module, using Tarjan's algorithm
+func (g *DependencyGraph) findCycles() [][]string {
+	adjacency := make(map[string][]string)
+	for _, edge := range g.Edges {
+		adjacency[edge.From] = append(adjacency[edge.From], edge.To)
+	}
+	modules := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		modules = append(modules, id)
+	}
+	sort.Strings(modules)
+
+	index := 0
+	indices := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	cycles := make([][]string, 0)
+
+	var connect func(string)
+	connect = func(v string) {
+		indices[v] = index
+		lowlink[v] = index
+		index++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range adjacency[v] {
+			if _, visited := indices[w]; !visited {
+				connect(w)
+				lowlink[v] = min(lowlink[v], lowlink[w])
+			} else if onStack[w] {
+				lowlink[v] = min(lowlink[v], indices[w])
+			}
+		}
+
+		if lowlink[v] == indices[v] {
+			var component []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			if len(component) > 1 {
+				sort.Strings(component)
+				cycles = append(cycles, component)
+			}
+		}
+	}
+
+	for _, module := range modules {
+		if _, visited := indices[module]; !visited {
+			connect(module)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// graphFindings reports the cycles, god modules and architecture rule
+// violations of a graph
+func (d *DependencyAnalyzer) graphFindings(graph *DependencyGraph) []Finding {
+	findings := make([]Finding, 0)
+	rules := d.GetRules()
+	enabled := make(map[string]bool)
+	for _, rule := range rules {
+		enabled[rule.ID] = rule.Enabled
+	}
+
+	if enabled["DEPENDENCY-001"] {
+		for _, cycle := range graph.Cycles {
+			for _, module := range cycle {
+				findings = append(findings, Finding{
+					ID:         generateID(),
+					Type:       "issue",
+					Severity:   "high",
+					Message:    fmt.Sprintf("Module %s is part of a dependency cycle of %d modules", module, len(cycle)),
+					Rule:       "DEPENDENCY-001",
+					Category:   "architecture",
+					Context:    strings.Join(cycle, " -> "),
+					Suggestion: "Break the cycle by extracting shared code or inverting a dependency through an interface",
+					Confidence: 1.0,
+					Metadata: map[string]interface{}{
+						"module": module,
+						"cycle":  cycle,
+					},
+				})
+			}
+		}
+	}
+
+	if enabled["DEPENDENCY-002"] {
+		modules := make([]string, 0, len(graph.Nodes))
+		for id, node := range graph.Nodes {
+			if node.GodModule {
+				modules = append(modules, id)
+			}
+		}
+		sort.Strings(modules)
+		for _, module := range modules {
+			node := graph.Nodes[module]
+			findings = append(findings, Finding{
+				ID:         generateID(),
+				Type:       "issue",
+				Severity:   "medium",
+				Message:    fmt.Sprintf("Module %s is a god module (fan-in %d, fan-out %d)", module, node.FanIn, node.FanOut),
+				Rule:       "DEPENDENCY-002",
+				Category:   "architecture",
+				Suggestion: "Split the module by responsibility so dependents only import what they use",
+				Confidence: 0.8,
+				Metadata: map[string]interface{}{
+					"module":  module,
+					"fan_in":  node.FanIn,
+					"fan_out": node.FanOut,
+				},
+			})
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.Type != "architecture" || !rule.Enabled {
+			continue
+		}
+		from, _ := rule.Config["from"].(string)
+		to, _ := rule.Config["to"].(string)
+		fromPattern, err := regexp.Compile(from)
+		if err != nil {
+			continue
+		}
+		toPattern, err := regexp.Compile(to)
+		if err != nil {
+			continue
+		}
+		for _, edge := range graph.Edges {
+			if !fromPattern.MatchString(edge.From) || !toPattern.MatchString(edge.To) {
+				continue
+			}
+			findings = append(findings, Finding{
+				ID:         generateID(),
+				Type:       "issue",
+				Severity:   rule.Severity,
+				Message:    fmt.Sprintf("%s must not import %s: %s", edge.From, edge.To, rule.Description),
+				Rule:       rule.ID,
+				Category:   "architecture",
+				Context:    edge.From + " -> " + edge.To,
+				Suggestion: "Remove the import or move the code to a layer both modules may depend on",
+				Confidence: 1.0,
+				Metadata: map[string]interface{}{
+					"module": edge.From,
+					"from":   edge.From,
+					"to":     edge.To,
+				},
+			})
+		}
+	}
+
+	return findings
+}
+
+// calculateDependencyScore calculates dependency health score
+func (d *DependencyAnalyzer) calculateDependencyScore(findings []Finding) float64 {
+	score := 100.0
+	for _, finding := range findings {
+		switch finding.Severity {
+		case "critical":
+			score -= 25
+		case "high":
+			score -= 15
+		case "medium":
+			score -= 10
+		case "low":
+			score -= 5
+		}
+	}
+	return math.Max(0, score)
+}
+
+// DOT renders the graph in Graphviz DOT format. Modules in cycles are drawn
+// red and god modules filled orange.
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n", g.ProjectID)
+	b.WriteString("  rankdir=LR;\n  node [shape=box, fontname=\"Helvetica\"];\n")
+
+	modules := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		modules = append(modules, id)
+	}
+	sort.Strings(modules)
+	for _, id := range modules {
+		node := g.Nodes[id]
+		attrs := []string{fmt.Sprintf("label=%q", fmt.Sprintf("%s\nin %d / out %d", id, node.FanIn, node.FanOut))}
+		if node.InCycle {
+			attrs = append(attrs, "color=red")
+		}
+		if node.GodModule {
+			attrs = append(attrs, "style=filled", "fillcolor=orange")
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", id, strings.Join(attrs, ", "))
+	}
+
+	inCycle := make(map[string]int)
+	for i, cycle := range g.Cycles {
+		for _, module := range cycle {
+			inCycle[module] = i + 1
+		}
+	}
+	for _, edge := range g.Edges {
+		if c := inCycle[edge.From]; c != 0 && c == inCycle[edge.To] {
+			fmt.Fprintf(&b, "  %q -> %q [color=red];\n", edge.From, edge.To)
+			continue
+		}
+		fmt.Fprintf(&b, "  %q -> %q;\n", edge.From, edge.To)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,295 @@
+package analysis
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractImports(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		language string
+		content  string
+		want     []string
+	}{
+		{
+			name:     "go",
+			path:     "pkg/api/server.go",
+			language: "go",
+			content:  "package api\n\nimport (\n\t\"fmt\"\n\tstore \"example.com/app/pkg/store\"\n)\n",
+			want:     []string{"fmt", "example.com/app/pkg/store"},
+		},
+		{
+			name:     "go syntax error",
+			path:     "broken.go",
+			language: "go",
+			content:  "package",
+		},
+		{
+			name:     "javascript",
+			path:     "src/app.js",
+			language: "javascript",
+			content:  "import React from 'react'\nimport { a, b } from \"./util\"\nexport * from './types'\nconst x = require('./x')\nconst y = await import('./lazy')\n",
+			want:     []string{"react", "./util", "./types", "./x", "./lazy"},
+		},
+		{
+			name:     "python",
+			path:     "app/views.py",
+			language: "python",
+			content:  "import os, sys\nfrom .models import User\nfrom app.util import helper\n",
+			want:     []string{"os", "sys", ".models", "app.util"},
+		},
+		{
+			name:     "c includes",
+			path:     "src/main.c",
+			language: "c",
+			content:  "#include <stdio.h>\n#  include \"util.h\"\n",
+			want:     []string{"stdio.h", "util.h"},
+		},
+		{
+			name:     "java",
+			path:     "src/com/acme/App.java",
+			language: "java",
+			content:  "import com.acme.util.Strings;\nimport static com.acme.Math.max;\nimport java.util.*;\nimport java.util.List;\n",
+			want:     []string{"com.acme.util.Strings", "com.acme.Math.max", "java.util", "java.util.List"},
+		},
+		{
+			name:     "duplicates",
+			path:     "a.py",
+			language: "python",
+			content:  "import os\nimport os\n",
+			want:     []string{"os"},
+		},
+		{
+			name:     "unsupported language",
+			path:     "main.rs",
+			language: "rust",
+			content:  "use std::io;",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractImports(tt.path, tt.language, tt.content)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyGraphResolvesModules(t *testing.T) {
+	tests := []struct {
+		name      string
+		artifacts []*Artifact
+		edges     []DependencyEdge
+		external  map[string][]string
+	}{
+		{
+			name: "go packages by import path suffix",
+			artifacts: []*Artifact{
+				{ID: "1", Path: "cmd/app/main.go", Language: "go", Content: []byte("package main\nimport (\n\"fmt\"\n\"example.com/app/pkg/api\"\n)")},
+				{ID: "2", Path: "pkg/api/server.go", Language: "go", Content: []byte("package api\nimport \"example.com/app/pkg/store\"")},
+				{ID: "3", Path: "pkg/api/routes.go", Language: "go", Content: []byte("package api")},
+				{ID: "4", Path: "pkg/store/store.go", Language: "go", Content: []byte("package store")},
+			},
+			edges:    []DependencyEdge{{From: "cmd/app", To: "pkg/api"}, {From: "pkg/api", To: "pkg/store"}},
+			external: map[string][]string{"cmd/app": {"fmt"}},
+		},
+		{
+			name: "relative javascript imports and index files",
+			artifacts: []*Artifact{
+				{ID: "1", Path: "src/app.ts", Language: "typescript", Content: []byte("import { x } from './lib'\nimport y from './util.ts'\nimport z from 'lodash'")},
+				{ID: "2", Path: "src/lib/index.ts", Language: "typescript"},
+				{ID: "3", Path: "src/util.ts", Language: "typescript"},
+			},
+			edges:    []DependencyEdge{{From: "src/app", To: "src/lib/index"}, {From: "src/app", To: "src/util"}},
+			external: map[string][]string{"src/app": {"lodash"}},
+		},
+		{
+			name: "python absolute and relative imports",
+			artifacts: []*Artifact{
+				{ID: "1", Path: "src/app/views.py", Language: "python", Content: []byte("from .models import User\nfrom ..shared import log\nimport app.util")},
+				{ID: "2", Path: "src/app/models.py", Language: "python"},
+				{ID: "3", Path: "src/shared/__init__.py", Language: "python"},
+				{ID: "4", Path: "src/app/util.py", Language: "python"},
+			},
+			edges: []DependencyEdge{
+				{From: "src/app/views", To: "src/app/models"},
+				{From: "src/app/views", To: "src/app/util"},
+				{From: "src/app/views", To: "src/shared/__init__"},
+			},
+		},
+		{
+			name: "c includes next to the file",
+			artifacts: []*Artifact{
+				{ID: "1", Path: "src/main.c", Language: "c", Content: []byte("#include <stdio.h>\n#include \"util.h\"")},
+				{ID: "2", Path: "src/util.h", Language: "c"},
+			},
+			edges:    []DependencyEdge{{From: "src/main", To: "src/util"}},
+			external: map[string][]string{"src/main": {"stdio.h"}},
+		},
+		{
+			name: "java imports under a source root",
+			artifacts: []*Artifact{
+				{ID: "1", Path: "src/main/java/com/acme/App.java", Language: "java", Content: []byte("import com.acme.util.Strings;")},
+				{ID: "2", Path: "src/main/java/com/acme/util/Strings.java", Language: "java"},
+			},
+			edges: []DependencyEdge{{From: "src/main/java/com/acme/App", To: "src/main/java/com/acme/util/Strings"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDependencyAnalyzer()
+			for _, artifact := range tt.artifacts {
+				artifact.ProjectID = "p1"
+			}
+			if err := d.BuildIndex(context.Background(), tt.artifacts); err != nil {
+				t.Fatal(err)
+			}
+			graph := d.Graph("p1")
+			if !reflect.DeepEqual(graph.Edges, tt.edges) {
+				t.Fatalf("got edges %+v, want %+v", graph.Edges, tt.edges)
+			}
+			for module, want := range tt.external {
+				if got := graph.Nodes[module].External; !reflect.DeepEqual(got, want) {
+					t.Fatalf("got external imports %q of %s, want %q", got, module, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDependencyFindings(t *testing.T) {
+	goFile := func(id, dir string, imports ...string) *Artifact {
+		content := "package " + packageName(dir) + "\n"
+		for _, imported := range imports {
+			content += "import _ \"example.com/app/" + imported + "\"\n"
+		}
+		return &Artifact{ID: id, ProjectID: "p1", Path: dir + "/file" + id + ".go", Language: "go", Content: []byte(content)}
+	}
+
+	tests := []struct {
+		name      string
+		artifacts []*Artifact
+		setup     func(d *DependencyAnalyzer)
+		rules     map[string][]string // rule -> modules
+	}{
+		{
+			name: "acyclic graph",
+			artifacts: []*Artifact{
+				goFile("1", "a", "b"),
+				goFile("2", "b"),
+			},
+			rules: map[string][]string{},
+		},
+		{
+			name: "transitive cycle",
+			artifacts: []*Artifact{
+				goFile("1", "a", "b"),
+				goFile("2", "b", "c"),
+				goFile("3", "c", "a"),
+				goFile("4", "d", "a"),
+			},
+			rules: map[string][]string{"DEPENDENCY-001": {"a", "b", "c"}},
+		},
+		{
+			name: "god module",
+			artifacts: []*Artifact{
+				goFile("1", "a", "hub"),
+				goFile("2", "b", "hub"),
+				goFile("3", "hub", "c", "d"),
+				goFile("4", "c"),
+				goFile("5", "d"),
+			},
+			setup: func(d *DependencyAnalyzer) { d.SetGodModuleThresholds(2, 2) },
+			rules: map[string][]string{"DEPENDENCY-002": {"hub"}},
+		},
+		{
+			name: "architecture rule",
+			artifacts: []*Artifact{
+				goFile("1", "domain", "infra"),
+				goFile("2", "infra", "domain"),
+				goFile("3", "api", "infra"),
+			},
+			setup: func(d *DependencyAnalyzer) {
+				d.AddArchitectureRule("ARCH-001", "the domain stays independent of infrastructure", "^domain$", "^infra$", "high")
+			},
+			rules: map[string][]string{
+				"DEPENDENCY-001": {"domain", "infra"},
+				"ARCH-001":       {"domain"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDependencyAnalyzer()
+			if tt.setup != nil {
+				tt.setup(d)
+			}
+			if err := d.BuildIndex(context.Background(), tt.artifacts); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string][]string{}
+			for _, finding := range d.Findings("p1") {
+				got[finding.Rule] = append(got[finding.Rule], finding.Metadata["module"].(string))
+			}
+			if !reflect.DeepEqual(got, tt.rules) {
+				t.Fatalf("got findings %v, want %v", got, tt.rules)
+			}
+		})
+	}
+}
+
+// packageName returns the package name of a directory
+func packageName(dir string) string {
+	return dir[strings.LastIndex(dir, "/")+1:]
+}
+
+func TestDependencyAnalyzeReportsModuleFindings(t *testing.T) {
+	d := NewDependencyAnalyzer()
+	ctx := context.Background()
+	artifacts := []*Artifact{
+		{ID: "1", ProjectID: "p1", Path: "a/a.go", Language: "go", Content: []byte("package a\nimport \"example.com/app/b\"")},
+		{ID: "2", ProjectID: "p1", Path: "b/b.go", Language: "go", Content: []byte("package b\nimport \"example.com/app/a\"")},
+		{ID: "3", ProjectID: "p1", Path: "c/c.go", Language: "go", Content: []byte("package c\nimport \"example.com/app/a\"")},
+	}
+	var result *AnalysisResult
+	for _, artifact := range artifacts {
+		var err error
+		if result, err = d.Analyze(ctx, artifact); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// c is outside the cycle it depends on
+	if len(result.Findings) != 0 || result.Score != 100 || result.Metrics["fan_out"] != 1 {
+		t.Fatalf("unexpected result for c: %+v", result)
+	}
+	result, err := d.Analyze(ctx, artifacts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Rule != "DEPENDENCY-001" || result.Score != 85 {
+		t.Fatalf("expected a cycle finding for a, got %+v", result.Findings)
+	}
+	if result.Metrics["fan_in"] != 2 || result.Metrics["instability"] != 1.0/3 {
+		t.Fatalf("unexpected metrics %v", result.Metrics)
+	}
+
+	dot, err := d.Export("p1", "dot")
+	if err != nil || !strings.Contains(string(dot), `"a" -> "b" [color=red];`) || !strings.Contains(string(dot), `"c" -> "a";`) {
+		t.Fatalf("unexpected DOT export %s %v", dot, err)
+	}
+	if _, err := d.Export("p1", "svg"); err == nil {
+		t.Fatal("expected unsupported formats to be rejected")
+	}
+	if _, err := d.Export("missing", "json"); err == nil {
+		t.Fatal("expected unknown projects to be rejected")
+	}
+}
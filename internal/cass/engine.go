@@ -221,6 +221,8 @@ type Config struct {
 	VectorDim      int             `json:"vector_dim"`
 	MaxTokens      int             `json:"max_tokens"`
 	EnableRealtime bool            `json:"enable_realtime"`
+	PluginDir      string          `json:"plugin_dir"` // Analyzer plugin executables, loaded at startup
+	PluginLimits   PluginLimits    `json:"plugin_limits"`
 }
 
 // Engine is the core engine that unifies analysis and search
@@ -365,6 +367,13 @@ func NewEngine(config *Config) (*Engine, error) {
 		go engine.worker(i)
 	}
 
+	if config.PluginDir != "" {
+		if _, err := engine.LoadPlugins(config.PluginDir, config.PluginLimits); err != nil {
+			engine.Close()
+			return nil, fmt.Errorf("failed to load plugins: %w", err)
+		}
+	}
+
 	return engine, nil
 }
 
@@ -24,6 +24,8 @@ type Integration struct {
 	wsClients     map[*websocket.Conn]bool
 	wsMutex       sync.RWMutex
 	subscriptions map[string]*nats.Subscription
+	ciMutex       sync.RWMutex
+	ciResults     *CIResults
 }
 
 // APIRequest represents an API request
@@ -148,6 +150,10 @@ func (i *Integration) setupHTTPServer(config *IntegrationConfig) {
 	api.HandleFunc("/quality/metrics", i.getQualityMetrics).Methods("GET")
 	api.HandleFunc("/quality/report", i.getQualityReport).Methods("POST")
 
+	// Ownership endpoints
+	api.HandleFunc("/owners", i.getOwners).Methods("GET")
+	api.HandleFunc("/owners/issues", i.getIssuesByOwner).Methods("GET")
+
 	// Index management
 	api.HandleFunc("/index/build", i.buildIndex).Methods("POST")
 	api.HandleFunc("/index/stats", i.getIndexStats).Methods("GET")
@@ -675,6 +681,66 @@ func (i *Integration) getQualityReport(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(report)
 }
 
+// SetCIResults sets the CI run that ownership queries are answered from
+func (i *Integration) SetCIResults(results *CIResults) {
+	i.ciMutex.Lock()
+	defer i.ciMutex.Unlock()
+	i.ciResults = results
+}
+
+// latestCIResults returns the latest CI run, or writes 404 without one
+func (i *Integration) latestCIResults(w http.ResponseWriter) *CIResults {
+	i.ciMutex.RLock()
+	defer i.ciMutex.RUnlock()
+	if i.ciResults == nil {
+		http.Error(w, "No CI results available", http.StatusNotFound)
+	}
+	return i.ciResults
+}
+
+// getOwners returns the per-owner summary of the latest CI run
+func (i *Integration) getOwners(w http.ResponseWriter, r *http.Request) {
+	results := i.latestCIResults(w)
+	if results == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"owners":    results.Owners,
+		"commit":    results.Context.Commit,
+		"timestamp": results.GeneratedAt,
+	})
+}
+
+// getIssuesByOwner returns the issues of the latest CI run grouped by owner,
+// e.g. ?severity=critical for open critical issues by team; ?owner= narrows
+// the result to one owner
+func (i *Integration) getIssuesByOwner(w http.ResponseWriter, r *http.Request) {
+	results := i.latestCIResults(w)
+	if results == nil {
+		return
+	}
+	severity := r.URL.Query().Get("severity")
+	if _, ok := severityRank[severity]; severity != "" && !ok {
+		http.Error(w, "Invalid severity", http.StatusBadRequest)
+		return
+	}
+
+	grouped := IssuesByOwner(results, severity)
+	if owner := r.URL.Query().Get("owner"); owner != "" {
+		grouped = map[string][]*CIIssue{owner: grouped[owner]}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issues":    grouped,
+		"severity":  severity,
+		"commit":    results.Context.Commit,
+		"timestamp": results.GeneratedAt,
+	})
+}
+
 // getAnalysisResult returns specific analysis result
 func (i *Integration) getAnalysisResult(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
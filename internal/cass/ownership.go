@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// UnownedOwner groups files that no CODEOWNERS rule or blame author covers
+const UnownedOwner = "(unowned)"
+
+// codeownersLocations are the paths searched for a CODEOWNERS file, in the
+// order GitHub and GitLab use them
+var codeownersLocations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// severityRank orders finding severities
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// CodeownersRule maps a CODEOWNERS pattern to its owners
+type CodeownersRule struct {
+	Pattern string   `json:"pattern"`
+	Owners  []string `json:"owners"` // Empty when the pattern removes ownership
+	Line    int      `json:"line"`
+	regex   *regexp.Regexp
+}
+
+// Ownership attributes files to owners from CODEOWNERS, optionally falling
+// back to the author of most lines according to git blame
+type Ownership struct {
+	rules  []*CodeownersRule
+	blame  bool
+	mu     sync.Mutex
+	blamed map[string][]string
+}
+
+// LoadOwnership loads the CODEOWNERS file, searching the usual locations when
+// codeownersPath is empty. A missing file leaves only the blame fallback.
+func LoadOwnership(codeownersPath string, blameFallback bool) (*Ownership, error) {
+	ownership := &Ownership{blame: blameFallback, blamed: make(map[string][]string)}
+
+	candidates := codeownersLocations
+	if codeownersPath != "" {
+		candidates = []string{codeownersPath}
+	}
+	for _, candidate := range candidates {
+		file, err := os.Open(candidate)
+		if os.IsNotExist(err) && codeownersPath == "" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rules, err := ParseCodeowners(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", candidate, err)
+		}
+		ownership.rules = rules
+		break
+	}
+	return ownership, nil
+}
+
+// ParseCodeowners parses CODEOWNERS rules. GitLab section headers are skipped,
+// so their rules apply as if unsectioned.
+func ParseCodeowners(r io.Reader) ([]*CodeownersRule, error) {
+	var rules []*CodeownersRule
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if index := strings.Index(line, " #"); index >= 0 {
+			line = line[:index]
+		}
+
+		fields := strings.Fields(line)
+		regex, err := codeownersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		rules = append(rules, &CodeownersRule{
+			Pattern: fields[0],
+			Owners:  fields[1:],
+			Line:    lineNumber,
+			regex:   regex,
+		})
+	}
+	return rules, scanner.Err()
+}
+
+// Rules returns the parsed CODEOWNERS rules
+func (o *Ownership) Rules() []*CodeownersRule {
+	return o.rules
+}
+
+// OwnersOf returns the owners of a repository relative path. The last matching
+// CODEOWNERS rule wins; without one the top blame author is used if enabled.
+func (o *Ownership) OwnersOf(filePath string) []string {
+	filePath = path.Clean(strings.TrimPrefix(filepath.ToSlash(filePath), "./"))
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		if o.rules[i].regex.MatchString(filePath) {
+			if len(o.rules[i].Owners) == 0 {
+				break
+			}
+			return o.rules[i].Owners
+		}
+	}
+	if !o.blame {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	owners, ok := o.blamed[filePath]
+	if !ok {
+		if author := blameAuthor(filePath); author != "" {
+			owners = []string{author}
+		}
+		o.blamed[filePath] = owners
+	}
+	return owners
+}
+
+// blameAuthor returns the email of the author of most lines of a file
+func blameAuthor(filePath string) string {
+	output, err := exec.Command("git", "blame", "--line-porcelain", "--", filePath).Output()
+	if err != nil {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	top := ""
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		mail, ok := strings.CutPrefix(scanner.Text(), "author-mail ")
+		if !ok {
+			continue
+		}
+		mail = strings.Trim(mail, "<>")
+		counts[mail]++
+		if counts[mail] > counts[top] || (counts[mail] == counts[top] && mail < top) {
+			top = mail
+		}
+	}
+	return top
+}
+
+// codeownersPattern compiles a CODEOWNERS pattern with gitignore semantics:
+// patterns containing a slash other than a trailing one are relative to the
+// repository root, others match at any depth; a match on a directory covers
+// everything below it, except for patterns ending in /* which cover only the
+// directory's direct files
+func codeownersPattern(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	trimmed := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(trimmed, "/")
+	trimmed = strings.TrimPrefix(trimmed, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(.*/)?")
+	}
+	for i := 0; i < len(trimmed); i++ {
+		switch c := trimmed[i]; {
+		case strings.HasPrefix(trimmed[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(trimmed[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	switch {
+	case dirOnly:
+		b.WriteString("/.*$")
+	case strings.HasSuffix(trimmed, "/*"):
+		b.WriteString("$")
+	default:
+		b.WriteString("(/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
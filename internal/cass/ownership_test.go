@@ -0,0 +1,107 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testCodeowners = `# Default owners
+*                   @org/core
+
+[Frontend]
+*.js                @org/web # inline comment
+/docs/              @org/docs
+docs/api/*          @org/api
+**/migrations/**    @org/dba
+build/logs          @org/ops
+/vendor/
+internal/?ib/       @alice bob@example.com
+`
+
+func TestParseCodeowners(t *testing.T) {
+	rules, err := ParseCodeowners(strings.NewReader(testCodeowners))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var patterns []string
+	for _, rule := range rules {
+		patterns = append(patterns, rule.Pattern)
+	}
+	want := []string{"*", "*.js", "/docs/", "docs/api/*", "**/migrations/**", "build/logs", "/vendor/", "internal/?ib/"}
+	if !reflect.DeepEqual(patterns, want) {
+		t.Fatalf("got patterns %q, want %q", patterns, want)
+	}
+	if last := rules[len(rules)-1]; last.Line != 11 || !reflect.DeepEqual(last.Owners, []string{"@alice", "bob@example.com"}) {
+		t.Fatalf("unexpected rule %+v", last)
+	}
+	if rules[1].Owners[0] != "@org/web" || len(rules[1].Owners) != 1 {
+		t.Fatalf("expected the inline comment to be dropped, got %q", rules[1].Owners)
+	}
+}
+
+func TestOwnershipOwnersOf(t *testing.T) {
+	rules, err := ParseCodeowners(strings.NewReader(testCodeowners))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownership := &Ownership{rules: rules}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "main.go", want: []string{"@org/core"}},
+		{path: "web/src/app.js", want: []string{"@org/web"}},
+		{path: "docs/guide/intro.md", want: []string{"@org/docs"}},
+		{path: "docs/api/rest.md", want: []string{"@org/api"}},
+		{path: "docs/api/v1/rest.md", want: []string{"@org/docs"}},
+		{path: "db/migrations/001.sql", want: []string{"@org/dba"}},
+		{path: "migrations/001.sql", want: []string{"@org/dba"}},
+		{path: "build/logs/today.log", want: []string{"@org/ops"}},
+		{path: "src/build/logs", want: []string{"@org/core"}},
+		{path: "./internal/lib/lib.go", want: []string{"@alice", "bob@example.com"}},
+		{path: filepath.FromSlash("internal/fib/fib.go"), want: []string{"@alice", "bob@example.com"}},
+		{path: "vendor/lib/lib.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := ownership.OwnersOf(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOwnership(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// Without a CODEOWNERS file and outside git nobody owns anything
+	ownership, err := LoadOwnership("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owners := ownership.OwnersOf("main.go"); owners != nil {
+		t.Fatalf("expected no owners, got %q", owners)
+	}
+	if _, err := LoadOwnership("missing/CODEOWNERS", false); err == nil {
+		t.Fatal("expected an explicit missing CODEOWNERS file to be an error")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("* @org/core\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ownership, err = LoadOwnership("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owners := ownership.OwnersOf("main.go"); !reflect.DeepEqual(owners, []string{"@org/core"}) {
+		t.Fatalf("expected .github/CODEOWNERS to be found, got %q", owners)
+	}
+}
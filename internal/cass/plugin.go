@@ -0,0 +1,473 @@
+package analysis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Analyzer plugins are separate executables speaking newline delimited JSON
+// over stdin/stdout. The host starts a plugin with CASS_PLUGIN_MAGIC_COOKIE
+// set, so a binary run by accident can refuse to start, and sends a handshake
+// listing the protocol versions it speaks. The plugin answers with the version
+// it chose and its info, then serves requests one at a time:
+//
+//	-> {"id":1,"method":"handshake","params":{"protocol_versions":[1]}}
+//	<- {"id":1,"result":{"protocol_version":1,"id":"eslint","name":"ESLint","version":"1.0.0",
+//	                     "capabilities":["analyze"],"languages":["javascript"]}}
+//	-> {"id":2,"method":"analyze","params":{"artifact":{...}}}
+//	<- {"id":2,"result":{...AnalysisResult...}}
+//	-> {"id":3,"method":"shutdown"}
+//
+// Failed requests answer with {"id":n,"error":{"message":"..."}}.
+
+const (
+	// PluginProtocolVersion is the newest plugin protocol version the host speaks
+	PluginProtocolVersion = 1
+
+	// PluginMagicCookieKey and PluginMagicCookieValue are set in the plugin's
+	// environment by the host
+	PluginMagicCookieKey   = "CASS_PLUGIN_MAGIC_COOKIE"
+	PluginMagicCookieValue = "5b0d6c1e-cass-analyzer-plugin"
+)
+
+// Plugin methods
+const (
+	PluginMethodHandshake       = "handshake"
+	PluginMethodAnalyze         = "analyze"
+	PluginMethodExtractFeatures = "extract_features"
+	PluginMethodCompare         = "compare"
+	PluginMethodShutdown        = "shutdown"
+)
+
+// pluginCapabilities maps protocol capability names to analyzer capabilities
+var pluginCapabilities = map[string]AnalyzerCapability{
+	"analyze":   CapabilityAnalyze,
+	"compare":   CapabilityCompare,
+	"validate":  CapabilityValidate,
+	"recommend": CapabilityRecommend,
+}
+
+// PluginLimits sandboxes plugin processes
+type PluginLimits struct {
+	Timeout       time.Duration // Per request, including the handshake
+	MemoryLimitMB int           // Address space limit, 0 for none
+}
+
+// DefaultPluginLimits returns the default plugin limits
+func DefaultPluginLimits() PluginLimits {
+	return PluginLimits{Timeout: 10 * time.Second, MemoryLimitMB: 1024}
+}
+
+// PluginInfo describes a plugin, as returned by its handshake
+type PluginInfo struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Version         string   `json:"version"`
+	Capabilities    []string `json:"capabilities"`
+	Languages       []string `json:"languages"`
+}
+
+// pluginRequest is a request from the host to a plugin
+type pluginRequest struct {
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// pluginResponse is a plugin's answer to a request
+type pluginResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// PluginAnalyzer runs an analyzer plugin process. The process is restarted on
+// the next request after it crashes or exceeds its timeout.
+type PluginAnalyzer struct {
+	*BaseAnalyzer
+	path   string
+	limits PluginLimits
+	info   PluginInfo
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	nextID  int64
+	stopped bool
+}
+
+// NewPluginAnalyzer starts a plugin and negotiates the protocol with it
+func NewPluginAnalyzer(ctx context.Context, path string, limits PluginLimits) (*PluginAnalyzer, error) {
+	p := &PluginAnalyzer{path: path, limits: limits}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.start(ctx); err != nil {
+		return nil, err
+	}
+
+	var capabilities AnalyzerCapability
+	for _, name := range p.info.Capabilities {
+		capabilities |= pluginCapabilities[name]
+	}
+	p.BaseAnalyzer = NewBaseAnalyzer(p.info.ID, p.info.Name, p.info.Version, capabilities)
+	if len(p.info.Languages) > 0 {
+		p.languages = p.info.Languages
+	}
+	return p, nil
+}
+
+// Info returns the plugin's handshake info
+func (p *PluginAnalyzer) Info() PluginInfo {
+	return p.info
+}
+
+// Analyze sends the artifact to the plugin
+func (p *PluginAnalyzer) Analyze(ctx context.Context, artifact *Artifact) (*AnalysisResult, error) {
+	var result AnalysisResult
+	if err := p.call(ctx, PluginMethodAnalyze, map[string]interface{}{"artifact": artifact}, &result); err != nil {
+		return nil, err
+	}
+	// Results are attributed to the plugin whatever it reports
+	result.ArtifactID = artifact.ID
+	result.AnalyzerID = p.ID()
+	return &result, nil
+}
+
+// ExtractFeatures asks the plugin for feature vectors
+func (p *PluginAnalyzer) ExtractFeatures(ctx context.Context, artifact *Artifact) ([]*FeatureVector, error) {
+	if !slices.Contains(p.info.Capabilities, PluginMethodExtractFeatures) {
+		return nil, nil
+	}
+	var vectors []*FeatureVector
+	err := p.call(ctx, PluginMethodExtractFeatures, map[string]interface{}{"artifact": artifact}, &vectors)
+	return vectors, err
+}
+
+// Compare asks the plugin to compare two artifacts
+func (p *PluginAnalyzer) Compare(ctx context.Context, artifact1, artifact2 *Artifact) (*SimilarityResult, error) {
+	if p.Capabilities()&CapabilityCompare == 0 {
+		return nil, fmt.Errorf("plugin %s does not support compare", p.ID())
+	}
+	var result SimilarityResult
+	err := p.call(ctx, PluginMethodCompare, map[string]interface{}{"artifact1": artifact1, "artifact2": artifact2}, &result)
+	return &result, err
+}
+
+// BuildIndex is not supported by plugins
+func (p *PluginAnalyzer) BuildIndex(ctx context.Context, artifacts []*Artifact) error {
+	return nil
+}
+
+// Search is not supported by plugins
+func (p *PluginAnalyzer) Search(ctx context.Context, query *Query) ([]*SearchResult, error) {
+	return nil, nil
+}
+
+// Cleanup asks the plugin to shut down and stops its process
+func (p *PluginAnalyzer) Cleanup() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	if p.cmd == nil {
+		return nil
+	}
+	_ = json.NewEncoder(p.stdin).Encode(pluginRequest{ID: p.nextID + 1, Method: PluginMethodShutdown})
+	p.stdin.Close()
+
+	done := make(chan struct{})
+	go func(cmd *exec.Cmd) {
+		_ = cmd.Wait()
+		close(done)
+	}(p.cmd)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		_ = p.cmd.Process.Kill()
+		<-done
+	}
+	p.cmd = nil
+	return nil
+}
+
+// call sends a request and decodes its result, restarting the plugin first
+// if it is not running
+func (p *PluginAnalyzer) call(ctx context.Context, method string, params, out interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return fmt.Errorf("plugin %s is stopped", p.path)
+	}
+	if p.cmd == nil {
+		if err := p.start(ctx); err != nil {
+			return err
+		}
+	}
+	return p.roundTrip(ctx, method, params, out)
+}
+
+// start launches the plugin process and performs the handshake
+func (p *PluginAnalyzer) start(ctx context.Context) error {
+	cmd := pluginCommand(p.path, p.limits)
+	cmd.Env = append(os.Environ(),
+		PluginMagicCookieKey+"="+PluginMagicCookieValue,
+		"CASS_PLUGIN_PROTOCOL_VERSIONS="+strconv.Itoa(PluginProtocolVersion),
+	)
+	if p.limits.MemoryLimitMB > 0 {
+		// A soft limit for Go plugins, below the hard address space limit
+		cmd.Env = append(cmd.Env, fmt.Sprintf("GOMEMLIMIT=%dMiB", p.limits.MemoryLimitMB*3/4))
+	}
+	cmd.Stderr = &pluginLogWriter{path: p.path}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", p.path, err)
+	}
+	p.cmd, p.stdin, p.stdout = cmd, stdin, bufio.NewReader(stdout)
+
+	var info PluginInfo
+	params := map[string]interface{}{"protocol_versions": []int{PluginProtocolVersion}}
+	if err := p.roundTrip(ctx, PluginMethodHandshake, params, &info); err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	if info.ProtocolVersion < 1 || info.ProtocolVersion > PluginProtocolVersion {
+		p.kill()
+		return fmt.Errorf("plugin %s speaks unsupported protocol version %d", p.path, info.ProtocolVersion)
+	}
+	if info.ID == "" {
+		p.kill()
+		return fmt.Errorf("plugin %s did not report an id", p.path)
+	}
+	if p.info.ID != "" && info.ID != p.info.ID {
+		p.kill()
+		return fmt.Errorf("plugin %s changed its id from %s to %s", p.path, p.info.ID, info.ID)
+	}
+	p.info = info
+	return nil
+}
+
+// roundTrip writes a request and reads its response within the timeout. The
+// process is killed when it times out or breaks the protocol.
+func (p *PluginAnalyzer) roundTrip(ctx context.Context, method string, params, out interface{}) error {
+	p.nextID++
+	id := p.nextID
+	if err := json.NewEncoder(p.stdin).Encode(pluginRequest{ID: id, Method: method, Params: params}); err != nil {
+		p.kill()
+		return fmt.Errorf("plugin %s: %w", p.path, err)
+	}
+
+	type readResult struct {
+		line []byte
+		err  error
+	}
+	read := make(chan readResult, 1)
+	go func(stdout *bufio.Reader) {
+		line, err := stdout.ReadBytes('\n')
+		read <- readResult{line, err}
+	}(p.stdout)
+
+	timeout := p.limits.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPluginLimits().Timeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var res readResult
+	select {
+	case res = <-read:
+	case <-timer.C:
+		p.kill()
+		return fmt.Errorf("plugin %s timed out after %s", p.path, timeout)
+	case <-ctx.Done():
+		p.kill()
+		return ctx.Err()
+	}
+	if res.err != nil {
+		p.kill()
+		return fmt.Errorf("plugin %s exited: %w", p.path, res.err)
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(res.line, &resp); err != nil || resp.ID != id {
+		p.kill()
+		return fmt.Errorf("plugin %s sent an invalid response", p.path)
+	}
+	if resp.Error != nil {
+		return errors.New(resp.Error.Message)
+	}
+	if out != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, out)
+	}
+	return nil
+}
+
+// kill stops the process; the next call restarts it
+func (p *PluginAnalyzer) kill() {
+	if p.cmd == nil {
+		return
+	}
+	_ = p.cmd.Process.Kill()
+	_ = p.cmd.Wait()
+	p.cmd = nil
+}
+
+// pluginCommand builds the plugin command, limiting its address space with
+// ulimit where a POSIX shell is available
+func pluginCommand(path string, limits PluginLimits) *exec.Cmd {
+	if limits.MemoryLimitMB > 0 && runtime.GOOS != "windows" {
+		script := fmt.Sprintf(`ulimit -v %d && exec "$0"`, limits.MemoryLimitMB*1024)
+		return exec.Command("/bin/sh", "-c", script, path)
+	}
+	return exec.Command(path)
+}
+
+// pluginLogWriter forwards plugin stderr to the log
+type pluginLogWriter struct {
+	path string
+}
+
+func (w *pluginLogWriter) Write(data []byte) (int, error) {
+	log.Printf("plugin %s: %s", filepath.Base(w.path), data)
+	return len(data), nil
+}
+
+// LoadPlugins starts every executable in dir as an analyzer plugin and
+// registers it. Plugins that fail to start are logged and skipped.
+func (e *Engine) LoadPlugins(dir string, limits PluginLimits) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []string
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || (runtime.GOOS != "windows" && info.Mode()&0111 == 0) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		plugin, err := NewPluginAnalyzer(e.ctx, path, limits)
+		if err != nil {
+			log.Printf("Warning: Skipping analyzer plugin %s: %v", path, err)
+			continue
+		}
+		if err := e.RegisterAnalyzer(plugin); err != nil {
+			plugin.Cleanup()
+			log.Printf("Warning: Skipping analyzer plugin %s: %v", path, err)
+			continue
+		}
+		loaded = append(loaded, plugin.ID())
+	}
+	return loaded, nil
+}
+
+// PluginServer is implemented by analyzers served as plugins with ServePlugin.
+// Analyzers that also implement ExtractFeatures or Compare get those methods
+// served when they list the capability.
+type PluginServer interface {
+	Info() PluginInfo
+	Analyze(ctx context.Context, artifact *Artifact) (*AnalysisResult, error)
+}
+
+// ServePlugin serves an analyzer over stdin/stdout, for use in a plugin's main
+func ServePlugin(server PluginServer) error {
+	if os.Getenv(PluginMagicCookieKey) != PluginMagicCookieValue {
+		return fmt.Errorf("this binary is a CASS analyzer plugin and is started by the CASS engine")
+	}
+	return servePlugin(context.Background(), server, os.Stdin, os.Stdout)
+}
+
+// servePlugin answers requests until shutdown or end of input
+func servePlugin(ctx context.Context, server PluginServer, r io.Reader, w io.Writer) error {
+	type artifactParams struct {
+		Artifact  *Artifact `json:"artifact"`
+		Artifact1 *Artifact `json:"artifact1"`
+		Artifact2 *Artifact `json:"artifact2"`
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	encoder := json.NewEncoder(w)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return fmt.Errorf("invalid request: %w", err)
+		}
+
+		var params artifactParams
+		if len(req.Params) > 0 {
+			_ = json.Unmarshal(req.Params, &params)
+		}
+
+		var result interface{}
+		var err error
+		switch req.Method {
+		case PluginMethodHandshake:
+			info := server.Info()
+			info.ProtocolVersion = PluginProtocolVersion
+			result = info
+		case PluginMethodAnalyze:
+			result, err = server.Analyze(ctx, params.Artifact)
+		case PluginMethodExtractFeatures:
+			extractor, ok := server.(interface {
+				ExtractFeatures(ctx context.Context, artifact *Artifact) ([]*FeatureVector, error)
+			})
+			if !ok {
+				err = fmt.Errorf("method not supported: %s", req.Method)
+				break
+			}
+			result, err = extractor.ExtractFeatures(ctx, params.Artifact)
+		case PluginMethodCompare:
+			comparer, ok := server.(interface {
+				Compare(ctx context.Context, artifact1, artifact2 *Artifact) (*SimilarityResult, error)
+			})
+			if !ok {
+				err = fmt.Errorf("method not supported: %s", req.Method)
+				break
+			}
+			result, err = comparer.Compare(ctx, params.Artifact1, params.Artifact2)
+		case PluginMethodShutdown:
+			return nil
+		default:
+			err = fmt.Errorf("method not supported: %s", req.Method)
+		}
+
+		resp := map[string]interface{}{"id": req.ID}
+		if err != nil {
+			resp["error"] = map[string]string{"message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,227 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPluginModeKey makes the test binary act as a plugin when the host
+// starts it, in the mode given by the variable's value
+const testPluginModeKey = "CASS_TEST_PLUGIN_MODE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(testPluginModeKey); mode != "" && os.Getenv(PluginMagicCookieKey) == PluginMagicCookieValue {
+		runTestPlugin(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// echoPlugin reports one finding naming the artifact it analyzed
+type echoPlugin struct {
+	mode string
+}
+
+func (p *echoPlugin) Info() PluginInfo {
+	return PluginInfo{ID: "echo", Name: "Echo", Version: "1.0.0", Capabilities: []string{"analyze", "compare"}, Languages: []string{"go"}}
+}
+
+func (p *echoPlugin) Analyze(ctx context.Context, artifact *Artifact) (*AnalysisResult, error) {
+	switch {
+	case p.mode == "crash" && artifact.ID == "crash":
+		os.Exit(1)
+	case p.mode == "hang" && artifact.ID == "hang":
+		time.Sleep(time.Minute)
+	case artifact.ID == "fail":
+		return nil, fmt.Errorf("cannot analyze %s", artifact.Path)
+	}
+	return &AnalysisResult{
+		ArtifactID: "spoofed",
+		AnalyzerID: "spoofed",
+		Findings:   []Finding{{Message: "analyzed " + artifact.Path, Severity: "low"}},
+		Score:      90,
+	}, nil
+}
+
+func (p *echoPlugin) Compare(ctx context.Context, artifact1, artifact2 *Artifact) (*SimilarityResult, error) {
+	return &SimilarityResult{ArtifactID1: artifact1.ID, ArtifactID2: artifact2.ID, Score: 0.5}, nil
+}
+
+// runTestPlugin serves echoPlugin, or answers the handshake with a broken
+// response in the bad modes
+func runTestPlugin(mode string) {
+	switch mode {
+	case "old-protocol", "no-id":
+		var req pluginRequest
+		json.NewDecoder(os.Stdin).Decode(&req)
+		info := PluginInfo{ProtocolVersion: PluginProtocolVersion, ID: "echo"}
+		if mode == "old-protocol" {
+			info.ProtocolVersion = PluginProtocolVersion + 1
+		} else {
+			info.ID = ""
+		}
+		json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"id": req.ID, "result": info})
+		return
+	}
+	if err := ServePlugin(&echoPlugin{mode: mode}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func startTestPlugin(t *testing.T, mode string, limits PluginLimits) (*PluginAnalyzer, error) {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testPluginModeKey, mode)
+	plugin, err := NewPluginAnalyzer(context.Background(), executable, limits)
+	if err == nil {
+		t.Cleanup(func() { plugin.Cleanup() })
+	}
+	return plugin, err
+}
+
+func TestServePlugin(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		want    string
+	}{
+		{
+			name:    "handshake",
+			request: `{"id":1,"method":"handshake","params":{"protocol_versions":[1]}}`,
+			want:    `{"id":1,"result":{"protocol_version":1,"id":"echo","name":"Echo","version":"1.0.0","capabilities":["analyze","compare"],"languages":["go"]}}`,
+		},
+		{
+			name:    "analyze",
+			request: `{"id":2,"method":"analyze","params":{"artifact":{"id":"a","path":"main.go"}}}`,
+			want:    `"message":"analyzed main.go"`,
+		},
+		{
+			name:    "analyzer error",
+			request: `{"id":3,"method":"analyze","params":{"artifact":{"id":"fail","path":"main.go"}}}`,
+			want:    `{"error":{"message":"cannot analyze main.go"},"id":3}`,
+		},
+		{
+			name:    "compare",
+			request: `{"id":4,"method":"compare","params":{"artifact1":{"id":"a"},"artifact2":{"id":"b"}}}`,
+			want:    `"artifact_id1":"a","artifact_id2":"b","score":0.5`,
+		},
+		{
+			name:    "unsupported method",
+			request: `{"id":5,"method":"extract_features","params":{"artifact":{"id":"a"}}}`,
+			want:    `{"error":{"message":"method not supported: extract_features"},"id":5}`,
+		},
+		{
+			name:    "shutdown",
+			request: `{"id":6,"method":"shutdown"}`,
+			want:    ``,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := servePlugin(context.Background(), &echoPlugin{}, strings.NewReader(tt.request+"\n"), &out); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(out.String()); !strings.Contains(got, tt.want) || (tt.want == "" && got != "") {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if err := servePlugin(context.Background(), &echoPlugin{}, strings.NewReader("not json\n"), &bytes.Buffer{}); err == nil {
+		t.Fatal("expected malformed requests to stop the plugin")
+	}
+	if err := ServePlugin(&echoPlugin{}); err == nil {
+		t.Fatal("expected ServePlugin to refuse to run without the magic cookie")
+	}
+}
+
+func TestPluginAnalyzer(t *testing.T) {
+	plugin, err := startTestPlugin(t, "ok", PluginLimits{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plugin.ID() != "echo" || plugin.Capabilities() != CapabilityAnalyze|CapabilityCompare || plugin.SupportedLanguages()[0] != "go" {
+		t.Fatalf("unexpected plugin info %+v", plugin.Info())
+	}
+
+	ctx := context.Background()
+	result, err := plugin.Analyze(ctx, &Artifact{ID: "a", Path: "main.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Results are attributed to the plugin, not to what it claims
+	if result.ArtifactID != "a" || result.AnalyzerID != "echo" || len(result.Findings) != 1 || result.Findings[0].Message != "analyzed main.go" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if _, err := plugin.Analyze(ctx, &Artifact{ID: "fail", Path: "main.go"}); err == nil || err.Error() != "cannot analyze main.go" {
+		t.Fatalf("expected the plugin's error, got %v", err)
+	}
+	similarity, err := plugin.Compare(ctx, &Artifact{ID: "a"}, &Artifact{ID: "b"})
+	if err != nil || similarity.Score != 0.5 {
+		t.Fatalf("unexpected comparison %+v %v", similarity, err)
+	}
+	if vectors, err := plugin.ExtractFeatures(ctx, &Artifact{ID: "a"}); err != nil || vectors != nil {
+		t.Fatalf("expected no features from a plugin without the capability, got %v %v", vectors, err)
+	}
+
+	plugin.Cleanup()
+	if _, err := plugin.Analyze(ctx, &Artifact{ID: "a"}); err == nil {
+		t.Fatal("expected a stopped plugin to refuse requests")
+	}
+}
+
+func TestPluginAnalyzerRestarts(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		artifact string
+		err      string
+	}{
+		{name: "crash", mode: "crash", artifact: "crash", err: "exited"},
+		{name: "timeout", mode: "hang", artifact: "hang", err: "timed out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin, err := startTestPlugin(t, tt.mode, PluginLimits{Timeout: 500 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			if _, err := plugin.Analyze(ctx, &Artifact{ID: tt.artifact}); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+			// The next request starts a new process
+			if result, err := plugin.Analyze(ctx, &Artifact{ID: "a", Path: "main.go"}); err != nil || len(result.Findings) != 1 {
+				t.Fatalf("expected the plugin to be restarted, got %+v %v", result, err)
+			}
+		})
+	}
+}
+
+func TestPluginAnalyzerRejectsBadHandshakes(t *testing.T) {
+	tests := []struct {
+		mode string
+		err  string
+	}{
+		{mode: "old-protocol", err: "unsupported protocol version"},
+		{mode: "no-id", err: "did not report an id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			if _, err := startTestPlugin(t, tt.mode, PluginLimits{Timeout: 5 * time.Second}); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -54,6 +54,10 @@ func (r *JSONReporter) Generate(ctx context.Context, results *CIResults) error {
 		"high_issues":     results.Summary.HighIssues,
 		"overall_score":   results.Summary.OverallScore,
 		"recommendations": results.Summary.Recommendations,
+		"coverage":        results.Coverage,
+		"tests":           results.Tests,
+		"risk":            results.Risk,
+		"owners":          results.Owners,
 	}
 
 	summaryData, err := json.MarshalIndent(summary, "", "  ")
@@ -176,6 +180,71 @@ func (r *MarkdownReporter) Generate(ctx context.Context, results *CIResults) err
 		}
 	}
 
+	// Coverage
+	if coverage := results.Coverage; coverage != nil {
+		md.WriteString(fmt.Sprintf("## Test Coverage: %.1f%% (threshold %.1f%%)\n\n", coverage.Total, coverage.Threshold))
+		md.WriteString("| Package | Coverage | Delta |\n")
+		md.WriteString("|---------|----------|-------|\n")
+		for _, pkg := range coverage.Packages {
+			delta := "new"
+			if pkg.Baseline != nil {
+				delta = fmt.Sprintf("%+.1f%%", pkg.Delta)
+			}
+			md.WriteString(fmt.Sprintf("| `%s` | %.1f%% | %s |\n", pkg.Package, pkg.Coverage, delta))
+		}
+		md.WriteString("\n")
+	}
+
+	// Ownership
+	if len(results.Owners) > 0 {
+		md.WriteString("## Issues by Owner\n\n")
+		md.WriteString("| Owner | Files | Issues | New | Critical | High | Quality | Security |\n")
+		md.WriteString("|-------|-------|--------|-----|----------|------|---------|----------|\n")
+		for _, owner := range results.Owners {
+			md.WriteString(fmt.Sprintf("| %s | %d | %d | %d | %d | %d | %.1f | %.1f |\n",
+				owner.Owner, owner.Artifacts, owner.Issues, owner.NewIssues, owner.CriticalIssues,
+				owner.HighIssues, owner.QualityScore, owner.SecurityScore))
+		}
+		md.WriteString("\n")
+	}
+
+	// Risk
+	if risk := results.Risk; risk != nil {
+		md.WriteString(fmt.Sprintf("## Change Risk: %.0f/100 (%s)\n\n", risk.Score, strings.Title(risk.Level)))
+		md.WriteString("| Feature | Value | Normalized | Weight | Contribution |\n")
+		md.WriteString("|---------|-------|------------|--------|--------------|\n")
+		for _, feature := range risk.Features {
+			md.WriteString(fmt.Sprintf("| %s | %.2f | %.2f | %.2f | %.1f |\n",
+				feature.Description, feature.Value, feature.Normalized, feature.Weight, feature.Contribution))
+		}
+		md.WriteString("\n")
+	}
+
+	// Tests
+	if tests := results.Tests; tests != nil {
+		md.WriteString("## Test Results\n\n")
+		md.WriteString(fmt.Sprintf("%d tests: %d passed, %d failed, %d skipped in %s\n\n",
+			tests.Total, tests.Passed, tests.Failed, tests.Skipped, tests.Duration.Round(time.Millisecond)))
+		for _, failure := range tests.Failures {
+			md.WriteString(fmt.Sprintf("- **FAILED** `%s` %s %s\n", failure.Suite, failure.Name, failure.Message))
+		}
+		if len(tests.Failures) > 0 {
+			md.WriteString("\n")
+		}
+
+		if len(tests.Flaky) > 0 {
+			md.WriteString(fmt.Sprintf("### Flaky Tests (%d found)\n\n", len(tests.Flaky)))
+			md.WriteString("| Test | Failures | Flip Rate | Same Commit | Last Failure |\n")
+			md.WriteString("|------|----------|-----------|-------------|--------------|\n")
+			for _, flaky := range tests.Flaky {
+				md.WriteString(fmt.Sprintf("| `%s` %s | %d/%d | %.0f%% | %t | %s |\n",
+					flaky.Suite, flaky.Name, flaky.Failures, flaky.Runs, flaky.FlipRate*100,
+					flaky.SameCommit, flaky.LastFailure.Format(time.RFC3339)))
+			}
+			md.WriteString("\n")
+		}
+	}
+
 	// Duplicates
 	if len(results.Duplicates) > 0 {
 		md.WriteString(fmt.Sprintf("## Code Duplicates (%d found)\n\n", len(results.Duplicates)))
@@ -427,6 +496,24 @@ func (r *GitHubAnnotationsReporter) Generate(ctx context.Context, results *CIRes
 
 	annotations.WriteString(fmt.Sprintf("::%s title=CASS Analysis::%s\n", summaryLevel, summaryMessage))
 
+	// Generate risk annotation with the features behind the score
+	if risk := results.Risk; risk != nil {
+		riskLevel := "notice"
+		switch risk.Level {
+		case "high":
+			riskLevel = "error"
+		case "medium":
+			riskLevel = "warning"
+		}
+
+		features := make([]string, 0, len(risk.Features))
+		for _, feature := range risk.Features {
+			features = append(features, fmt.Sprintf("%s=%.1f (+%.1f)", feature.Name, feature.Value, feature.Contribution))
+		}
+		annotations.WriteString(fmt.Sprintf("::%s title=CASS Change Risk::Risk %.0f/100 (%s) across %d files: %s\n",
+			riskLevel, risk.Score, risk.Level, risk.Files, strings.Join(features, ", ")))
+	}
+
 	// Write file
 	if err := os.WriteFile(reportFile, []byte(annotations.String()), 0644); err != nil {
 		return fmt.Errorf("failed to write GitHub annotations: %w", err)
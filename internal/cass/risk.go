@@ -0,0 +1,205 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RiskWeights weights the features of the change risk score. Weights are
+// relative; a zero weight drops the feature.
+type RiskWeights struct {
+	DiffSize      float64 `yaml:"diff_size" json:"diff_size"`
+	Complexity    float64 `yaml:"complexity" json:"complexity"`
+	DefectDensity float64 `yaml:"defect_density" json:"defect_density"`
+	Security      float64 `yaml:"security" json:"security"`
+}
+
+// DefaultRiskWeights returns equal weights for all features
+func DefaultRiskWeights() RiskWeights {
+	return RiskWeights{DiffSize: 0.25, Complexity: 0.25, DefectDensity: 0.25, Security: 0.25}
+}
+
+// Feature scales: the raw value at which a feature reaches half its maximum
+const (
+	riskDiffLinesScale     = 400.0 // Changed lines
+	riskComplexityScale    = 15.0  // Average cyclomatic complexity of touched files
+	riskDefectDensityScale = 3.0   // Baseline issues per touched file
+	riskSecurityScale      = 10.0  // Severity weighted security findings
+)
+
+// CIRiskScore is the risk of a change, with the features it was derived from
+type CIRiskScore struct {
+	Score    float64          `json:"score"` // 0-100
+	Level    string           `json:"level"` // "low", "medium", "high"
+	Files    int              `json:"files"`
+	Features []*CIRiskFeature `json:"features"`
+}
+
+// CIRiskFeature is one input of the risk score
+type CIRiskFeature struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`      // Raw value
+	Normalized   float64 `json:"normalized"` // 0-1
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // Points of the score
+	Description  string  `json:"description"`
+}
+
+// scoreRisk computes the risk of the change under analysis: the diff size,
+// the complexity of touched files, their historical defect density from the
+// baseline and the security findings in them
+func (r *CIRunner) scoreRisk(results *CIResults) *CIRiskScore {
+	touched := r.touchedArtifacts(results)
+	weights := r.config.RiskWeights
+	features := make([]*CIRiskFeature, 0, 4)
+
+	if lines, err := diffLines(r.context.BaseBranch); err != nil {
+		// Without git the score is built from the remaining features
+		log.Printf("Warning: Could not measure diff size: %v", err)
+	} else {
+		features = append(features, riskFeature("diff_size", float64(lines), riskDiffLinesScale, weights.DiffSize,
+			"Lines added and deleted"))
+	}
+
+	complexity, measured := 0.0, 0
+	for _, artifact := range touched {
+		for _, analysis := range artifact.Results {
+			if value, ok := analysis.Metrics["complexity"]; ok && analysis.Type == "quality" {
+				complexity += value
+				measured++
+			}
+		}
+	}
+	if measured > 0 {
+		complexity /= float64(measured)
+	}
+	features = append(features, riskFeature("complexity", complexity, riskComplexityScale, weights.Complexity,
+		"Average cyclomatic complexity of touched files"))
+
+	defects := 0
+	if r.baseline != nil {
+		paths := make(map[string]bool, len(touched))
+		for _, artifact := range touched {
+			paths[filepath.ToSlash(artifact.Path)] = true
+		}
+		for _, issues := range r.baseline.Issues {
+			for _, issue := range issues {
+				if paths[filepath.ToSlash(issue.File)] {
+					defects++
+				}
+			}
+		}
+	}
+	density := 0.0
+	if len(touched) > 0 {
+		density = float64(defects) / float64(len(touched))
+	}
+	features = append(features, riskFeature("defect_density", density, riskDefectDensityScale, weights.DefectDensity,
+		"Baseline issues per touched file"))
+
+	severityWeights := map[string]float64{"critical": 10, "high": 5, "medium": 2, "low": 1}
+	touchedIDs := make(map[string]bool, len(touched))
+	for _, artifact := range touched {
+		touchedIDs[artifact.ArtifactID] = true
+	}
+	security := 0.0
+	for _, issue := range results.Issues["security"] {
+		if touchedIDs[issue.ArtifactID] {
+			security += severityWeights[issue.Severity]
+		}
+	}
+	features = append(features, riskFeature("security", security, riskSecurityScale, weights.Security,
+		"Severity weighted security findings in touched files"))
+
+	totalWeight := 0.0
+	for _, feature := range features {
+		totalWeight += feature.Weight
+	}
+	risk := &CIRiskScore{Files: len(touched), Features: features}
+	for _, feature := range features {
+		if totalWeight > 0 {
+			feature.Contribution = feature.Normalized * feature.Weight / totalWeight * 100
+		}
+		risk.Score += feature.Contribution
+	}
+	risk.Level = riskLevel(risk.Score)
+
+	return risk
+}
+
+// touchedArtifacts returns the results of changed files, or of all analyzed
+// files when the changes are unknown
+func (r *CIRunner) touchedArtifacts(results *CIResults) []*CIArtifactResult {
+	if len(r.context.ChangedFiles) == 0 {
+		return results.Artifacts
+	}
+	changed := make(map[string]bool, len(r.context.ChangedFiles))
+	for _, file := range r.context.ChangedFiles {
+		changed[filepath.Clean(file)] = true
+	}
+	touched := make([]*CIArtifactResult, 0, len(changed))
+	for _, artifact := range results.Artifacts {
+		if changed[filepath.Clean(artifact.Path)] {
+			touched = append(touched, artifact)
+		}
+	}
+	return touched
+}
+
+// riskFeature normalizes a raw value with a saturating curve that reaches 0.5
+// at scale
+func riskFeature(name string, value, scale, weight float64, description string) *CIRiskFeature {
+	return &CIRiskFeature{
+		Name:        name,
+		Value:       value,
+		Normalized:  value / (value + scale),
+		Weight:      math.Max(0, weight),
+		Description: description,
+	}
+}
+
+// riskLevel buckets a risk score
+func riskLevel(score float64) string {
+	switch {
+	case score >= 60:
+		return "high"
+	case score >= 30:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// diffLines counts the lines added and deleted since the merge base with the
+// base branch, or in the last commit without one
+func diffLines(baseBranch string) (int, error) {
+	args := []string{"diff", "--numstat", "HEAD~1", "HEAD"}
+	if baseBranch != "" {
+		args = []string{"diff", "--numstat", "origin/" + baseBranch + "...HEAD"}
+	}
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return 0, fmt.Errorf("git diff failed: %w", err)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		// Binary files report "-" for both counts
+		added, _ := strconv.Atoi(fields[0])
+		deleted, _ := strconv.Atoi(fields[1])
+		lines += added + deleted
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRiskLevel(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  string
+	}{
+		{score: 0, want: "low"},
+		{score: 29.9, want: "low"},
+		{score: 30, want: "medium"},
+		{score: 59.9, want: "medium"},
+		{score: 60, want: "high"},
+		{score: 100, want: "high"},
+	}
+	for _, tt := range tests {
+		if got := riskLevel(tt.score); got != tt.want {
+			t.Errorf("riskLevel(%v) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestScoreRisk(t *testing.T) {
+	// Outside a git repository the diff size is left out of the score
+	t.Chdir(t.TempDir())
+
+	quality := func(complexity float64) []*AnalysisResult {
+		return []*AnalysisResult{{Type: "quality", Metrics: map[string]float64{"complexity": complexity}}}
+	}
+	results := &CIResults{
+		Artifacts: []*CIArtifactResult{
+			{ArtifactID: "a", Path: "pkg/a.go", Results: quality(10)},
+			{ArtifactID: "b", Path: "pkg/b.go", Results: quality(20)},
+			{ArtifactID: "c", Path: "pkg/c.go", Results: quality(100)},
+		},
+		Issues: map[string][]*CIIssue{
+			"security": {
+				{ArtifactID: "a", Severity: "critical"},
+				{ArtifactID: "b", Severity: "medium"},
+				{ArtifactID: "c", Severity: "critical"},
+			},
+		},
+	}
+	baseline := &CIBaseline{Issues: map[string][]BaselineIssue{
+		"quality": {{File: "pkg/a.go"}, {File: "pkg/a.go"}, {File: "pkg/b.go"}, {File: "pkg/c.go"}},
+	}}
+
+	tests := []struct {
+		name     string
+		changed  []string
+		weights  RiskWeights
+		features map[string]float64 // name -> raw value
+		score    float64
+		level    string
+	}{
+		{
+			name:     "changed files only",
+			changed:  []string{"pkg/a.go", "./pkg/b.go"},
+			weights:  DefaultRiskWeights(),
+			features: map[string]float64{"complexity": 15, "defect_density": 1.5, "security": 12},
+			// (15/30 + 1.5/4.5 + 12/22) / 3
+			score: (0.5 + 1.0/3 + 12.0/22) / 3 * 100,
+			level: "medium",
+		},
+		{
+			name:     "all files without known changes",
+			weights:  DefaultRiskWeights(),
+			features: map[string]float64{"complexity": 130.0 / 3, "defect_density": 4.0 / 3, "security": 22},
+			score:    ((130.0/3)/(130.0/3+15) + (4.0/3)/(4.0/3+3) + 22.0/32) / 3 * 100,
+			level:    "medium",
+		},
+		{
+			name:     "weights select features",
+			changed:  []string{"pkg/a.go"},
+			weights:  RiskWeights{Security: 1, Complexity: -1},
+			features: map[string]float64{"complexity": 10, "defect_density": 2, "security": 10},
+			score:    50,
+			level:    "medium",
+		},
+		{
+			name:     "nothing touched",
+			changed:  []string{"docs/README.md"},
+			weights:  DefaultRiskWeights(),
+			features: map[string]float64{"complexity": 0, "defect_density": 0, "security": 0},
+			score:    0,
+			level:    "low",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &CIRunner{
+				config:   &CIConfig{RiskWeights: tt.weights},
+				context:  &CIContext{ChangedFiles: tt.changed},
+				baseline: baseline,
+			}
+			risk := r.scoreRisk(results)
+			if len(risk.Features) != len(tt.features) {
+				t.Fatalf("got %d features, want %d", len(risk.Features), len(tt.features))
+			}
+			for _, feature := range risk.Features {
+				if want, ok := tt.features[feature.Name]; !ok || math.Abs(feature.Value-want) > 1e-9 {
+					t.Fatalf("got %s = %v, want %v", feature.Name, feature.Value, want)
+				}
+			}
+			if math.Abs(risk.Score-tt.score) > 1e-9 || risk.Level != tt.level {
+				t.Fatalf("got score %v (%s), want %v (%s)", risk.Score, risk.Level, tt.score, tt.level)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package analysis
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxTestRuns bounds the stored history of each test
+	maxTestRuns = 50
+	// minFlakyFlips is the number of pass/fail flips after which a test is
+	// flaky; a test that broke and was fixed flips only once
+	minFlakyFlips = 2
+)
+
+// TestCaseResult is the outcome of one test case in a JUnit report
+type TestCaseResult struct {
+	Suite    string        `json:"suite"`
+	Name     string        `json:"name"`
+	Status   string        `json:"status"` // "passed", "failed", "skipped"
+	Duration time.Duration `json:"duration"`
+	Message  string        `json:"message,omitempty"`
+}
+
+// Key identifies the test across runs
+func (t *TestCaseResult) Key() string {
+	return t.Suite + "::" + t.Name
+}
+
+// TestHistory stores the pass/fail history of every test
+type TestHistory struct {
+	Tests     map[string]*TestRecord `json:"tests"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// TestRecord is the history of a single test, oldest run first
+type TestRecord struct {
+	Suite string    `json:"suite"`
+	Name  string    `json:"name"`
+	Runs  []TestRun `json:"runs"`
+}
+
+// TestRun is one recorded execution of a test
+type TestRun struct {
+	Commit    string        `json:"commit"`
+	Branch    string        `json:"branch"`
+	Build     string        `json:"build"`
+	Status    string        `json:"status"`
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// junitFailure is a failure or error element of a test case
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitTestCase is a JUnit testcase element
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+// junitTestSuite is a JUnit testsuite element; suites may nest
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	TestCases []junitTestCase  `xml:"testcase"`
+	Suites    []junitTestSuite `xml:"testsuite"`
+}
+
+// ParseJUnitFile reads the test cases of a JUnit XML report with either a
+// testsuites or a testsuite root
+func ParseJUnitFile(filePath string) ([]*TestCaseResult, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// A testsuites root decodes as a suite without test cases of its own
+	var root junitTestSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+	}
+
+	var results []*TestCaseResult
+	var collect func(suite junitTestSuite)
+	collect = func(suite junitTestSuite) {
+		for _, tc := range suite.TestCases {
+			result := &TestCaseResult{
+				Suite:  tc.ClassName,
+				Name:   tc.Name,
+				Status: "passed",
+			}
+			if result.Suite == "" {
+				result.Suite = suite.Name
+			}
+			if seconds, err := strconv.ParseFloat(tc.Time, 64); err == nil {
+				result.Duration = time.Duration(seconds * float64(time.Second))
+			}
+			switch {
+			case tc.Failure != nil:
+				result.Status = "failed"
+				result.Message = tc.Failure.Message
+			case tc.Error != nil:
+				result.Status = "failed"
+				result.Message = tc.Error.Message
+			case tc.Skipped != nil:
+				result.Status = "skipped"
+			}
+			results = append(results, result)
+		}
+		for _, nested := range suite.Suites {
+			collect(nested)
+		}
+	}
+	collect(root)
+
+	return results, nil
+}
+
+// LoadTestHistory loads the test history, returning an empty history when the
+// file does not exist yet
+func LoadTestHistory(filePath string) (*TestHistory, error) {
+	history := &TestHistory{Tests: make(map[string]*TestRecord)}
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, history); err != nil {
+		return nil, err
+	}
+	if history.Tests == nil {
+		history.Tests = make(map[string]*TestRecord)
+	}
+	return history, nil
+}
+
+// Save writes the test history
+func (h *TestHistory) Save(filePath string) error {
+	h.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0644)
+}
+
+// Record appends a run of each test, keeping the latest maxTestRuns runs.
+// Skipped tests are not recorded.
+func (h *TestHistory) Record(results []*TestCaseResult, ctx *CIContext) {
+	now := time.Now()
+	for _, result := range results {
+		if result.Status == "skipped" {
+			continue
+		}
+		record, ok := h.Tests[result.Key()]
+		if !ok {
+			record = &TestRecord{Suite: result.Suite, Name: result.Name}
+			h.Tests[result.Key()] = record
+		}
+		record.Runs = append(record.Runs, TestRun{
+			Commit:    ctx.Commit,
+			Branch:    ctx.Branch,
+			Build:     ctx.BuildNumber,
+			Status:    result.Status,
+			Duration:  result.Duration,
+			Timestamp: now,
+		})
+		if len(record.Runs) > maxTestRuns {
+			record.Runs = record.Runs[len(record.Runs)-maxTestRuns:]
+		}
+	}
+}
+
+// FlakyTests returns the tests that fail intermittently, most flaky first
+func (h *TestHistory) FlakyTests() []*CIFlakyTest {
+	flaky := make([]*CIFlakyTest, 0)
+	for _, record := range h.Tests {
+		if test := record.flakiness(); test != nil {
+			flaky = append(flaky, test)
+		}
+	}
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].FlipRate != flaky[j].FlipRate {
+			return flaky[i].FlipRate > flaky[j].FlipRate
+		}
+		return flaky[i].Suite+flaky[i].Name < flaky[j].Suite+flaky[j].Name
+	})
+	return flaky
+}
+
+// flakiness reports the test as flaky when it both passed and failed on the
+// same commit, or flipped between passing and failing repeatedly
+func (r *TestRecord) flakiness() *CIFlakyTest {
+	if len(r.Runs) < 2 {
+		return nil
+	}
+
+	failures, flips := 0, 0
+	var lastFailure time.Time
+	outcomes := make(map[string]map[string]bool)
+	sameCommit := false
+	for i, run := range r.Runs {
+		if run.Status == "failed" {
+			failures++
+			lastFailure = run.Timestamp
+		}
+		if i > 0 && run.Status != r.Runs[i-1].Status {
+			flips++
+		}
+		if run.Commit == "" {
+			continue
+		}
+		if outcomes[run.Commit] == nil {
+			outcomes[run.Commit] = make(map[string]bool)
+		}
+		outcomes[run.Commit][run.Status] = true
+		if len(outcomes[run.Commit]) > 1 {
+			sameCommit = true
+		}
+	}
+
+	if failures == 0 || failures == len(r.Runs) || (!sameCommit && flips < minFlakyFlips) {
+		return nil
+	}
+	return &CIFlakyTest{
+		Suite:       r.Suite,
+		Name:        r.Name,
+		Runs:        len(r.Runs),
+		Failures:    failures,
+		FlipRate:    float64(flips) / float64(len(r.Runs)-1),
+		SameCommit:  sameCommit,
+		LastFailure: lastFailure,
+	}
+}
@@ -0,0 +1,152 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseJUnitFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		report string
+		want   []TestCaseResult
+	}{
+		{
+			name: "testsuites root with nested suites",
+			report: `<?xml version="1.0"?>
+<testsuites>
+  <testsuite name="api">
+    <testcase classname="api.Server" name="TestGet" time="0.25"/>
+    <testcase name="TestPost" time="1">
+      <failure message="expected 201">stack</failure>
+    </testcase>
+    <testsuite name="api.nested">
+      <testcase name="TestBoom" time="x"><error message="panic"/></testcase>
+      <testcase name="TestLater"><skipped/></testcase>
+    </testsuite>
+  </testsuite>
+</testsuites>`,
+			want: []TestCaseResult{
+				{Suite: "api.Server", Name: "TestGet", Status: "passed", Duration: 250 * time.Millisecond},
+				{Suite: "api", Name: "TestPost", Status: "failed", Duration: time.Second, Message: "expected 201"},
+				{Suite: "api.nested", Name: "TestBoom", Status: "failed", Message: "panic"},
+				{Suite: "api.nested", Name: "TestLater", Status: "skipped"},
+			},
+		},
+		{
+			name:   "testsuite root",
+			report: `<testsuite name="store"><testcase name="TestPut" time="0.5"/></testsuite>`,
+			want: []TestCaseResult{
+				{Suite: "store", Name: "TestPut", Status: "passed", Duration: 500 * time.Millisecond},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "junit.xml")
+			if err := os.WriteFile(filePath, []byte(tt.report), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			results, err := ParseJUnitFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]TestCaseResult, len(results))
+			for i, result := range results {
+				got[i] = *result
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	filePath := filepath.Join(t.TempDir(), "junit.xml")
+	if err := os.WriteFile(filePath, []byte("<testsuite>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseJUnitFile(filePath); err == nil {
+		t.Fatal("expected malformed reports to be rejected")
+	}
+}
+
+func TestTestRecordFlakiness(t *testing.T) {
+	runs := func(spec ...string) []TestRun {
+		// Each run is "commit:status", with p for passed and f for failed
+		var result []TestRun
+		for _, run := range spec {
+			status := "passed"
+			if run[len(run)-1] == 'f' {
+				status = "failed"
+			}
+			result = append(result, TestRun{Commit: run[:len(run)-2], Status: status})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name       string
+		runs       []TestRun
+		flaky      bool
+		flipRate   float64
+		sameCommit bool
+	}{
+		{name: "single run", runs: runs("a:f")},
+		{name: "always passing", runs: runs("a:p", "b:p", "c:p")},
+		{name: "always failing", runs: runs("a:f", "b:f")},
+		{name: "broken then fixed", runs: runs("a:f", "b:f", "c:p")},
+		{name: "flipping", runs: runs("a:p", "b:f", "c:p", "d:p"), flaky: true, flipRate: 2.0 / 3},
+		{name: "retried on one commit", runs: runs("a:f", "a:p"), flaky: true, flipRate: 1, sameCommit: true},
+		{name: "retried without commits", runs: runs(":f", ":p")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &TestRecord{Suite: "s", Name: "n", Runs: tt.runs}
+			test := record.flakiness()
+			if !tt.flaky {
+				if test != nil {
+					t.Fatalf("expected the test not to be flaky, got %+v", test)
+				}
+				return
+			}
+			if test == nil || test.FlipRate != tt.flipRate || test.SameCommit != tt.sameCommit || test.Runs != len(tt.runs) {
+				t.Fatalf("got %+v, want flip rate %v and same commit %v", test, tt.flipRate, tt.sameCommit)
+			}
+		})
+	}
+}
+
+func TestTestHistory(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "history.json")
+	history, err := LoadTestHistory(filePath)
+	if err != nil || len(history.Tests) != 0 {
+		t.Fatalf("expected an empty history, got %+v %v", history, err)
+	}
+
+	outcomes := []string{"passed", "failed", "passed", "failed"}
+	for i := 0; i < maxTestRuns+len(outcomes); i++ {
+		history.Record([]*TestCaseResult{
+			{Suite: "api", Name: "TestFlaky", Status: outcomes[i%len(outcomes)]},
+			{Suite: "api", Name: "TestStable", Status: "passed"},
+			{Suite: "api", Name: "TestSkipped", Status: "skipped"},
+		}, &CIContext{Commit: "c", Branch: "main"})
+	}
+	if len(history.Tests) != 2 || len(history.Tests["api::TestFlaky"].Runs) != maxTestRuns {
+		t.Fatalf("expected the latest %d runs of 2 tests, got %+v", maxTestRuns, history.Tests)
+	}
+
+	if err := history.Save(filePath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTestHistory(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flaky := loaded.FlakyTests()
+	if len(flaky) != 1 || flaky[0].Name != "TestFlaky" || flaky[0].FlipRate != 1 || !flaky[0].SameCommit {
+		t.Fatalf("unexpected flaky tests %+v", flaky)
+	}
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Alert metrics
+const (
+	AlertMetricErrorRate    = "error_rate"
+	AlertMetricFailedLogins = "failed_logins"
+	AlertMetricJobFailures  = "job_failures"
+	AlertMetricBudgetUsage  = "budget_usage"
+)
+
+// AlertRule represents an alerting rule
+type AlertRule struct {
+	ID                    string    `json:"id,omitempty"`
+	Name                  string    `json:"name"`
+	Metric                string    `json:"metric"`
+	Operator              string    `json:"operator"` // >, >=, < or <=
+	Threshold             float64   `json:"threshold"`
+	Severity              string    `json:"severity"` // info, warning or critical
+	TenantID              string    `json:"tenant_id,omitempty"`
+	Channels              []string  `json:"channels,omitempty"` // email, slack, discord, webhook; empty for all
+	Enabled               bool      `json:"enabled"`
+	RepeatIntervalSeconds int       `json:"repeat_interval_seconds,omitempty"`
+	CreatedBy             string    `json:"created_by,omitempty"`
+	CreatedAt             time.Time `json:"created_at,omitempty"`
+	UpdatedAt             time.Time `json:"updated_at,omitempty"`
+}
+
+// AlertSilence suppresses notifications for a rule or tenant until EndsAt
+type AlertSilence struct {
+	ID              string    `json:"id,omitempty"`
+	RuleID          string    `json:"rule_id,omitempty"`
+	TenantID        string    `json:"tenant_id,omitempty"`
+	StartsAt        time.Time `json:"starts_at,omitempty"`
+	EndsAt          time.Time `json:"ends_at,omitempty"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"` // Alternative to EndsAt when creating
+	Reason          string    `json:"reason,omitempty"`
+	CreatedBy       string    `json:"created_by,omitempty"`
+}
+
+// Notification represents an entry in the admin notification center
+type Notification struct {
+	ID        string     `json:"id"`
+	RuleID    string     `json:"rule_id"`
+	RuleName  string     `json:"rule_name"`
+	TenantID  string     `json:"tenant_id,omitempty"`
+	Metric    string     `json:"metric"`
+	Value     float64    `json:"value"`
+	Threshold float64    `json:"threshold"`
+	Severity  string     `json:"severity"`
+	Status    string     `json:"status"` // firing or resolved
+	Message   string     `json:"message"`
+	Channels  []string   `json:"channels,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	ReadBy    string     `json:"read_by,omitempty"`
+}
+
+// NotificationList is a page of notifications with the unread count
+type NotificationList struct {
+	Data   []Notification `json:"data"`
+	Unread int            `json:"unread"`
+}
+
+// ListAlertRules lists alerting rules
+func (c *Client) ListAlertRules(ctx context.Context) ([]AlertRule, error) {
+	var rules []AlertRule
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/alerts/rules", nil, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// SaveAlertRule creates a rule, or updates it when rule.ID is set
+func (c *Client) SaveAlertRule(ctx context.Context, rule *AlertRule) (*AlertRule, error) {
+	method, path := http.MethodPost, "/admin/v1/alerts/rules"
+	if rule.ID != "" {
+		method, path = http.MethodPut, path+"/"+url.PathEscape(rule.ID)
+	}
+	var saved AlertRule
+	if err := c.getData(ctx, method, path, rule, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// DeleteAlertRule deletes an alerting rule
+func (c *Client) DeleteAlertRule(ctx context.Context, id string) error {
+	return c.getData(ctx, http.MethodDelete, "/admin/v1/alerts/rules/"+url.PathEscape(id), nil, nil)
+}
+
+// ListAlertSilences lists silences that have not ended
+func (c *Client) ListAlertSilences(ctx context.Context) ([]AlertSilence, error) {
+	var silences []AlertSilence
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/alerts/silences", nil, &silences); err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+// CreateAlertSilence creates a silence window
+func (c *Client) CreateAlertSilence(ctx context.Context, silence *AlertSilence) (*AlertSilence, error) {
+	var created AlertSilence
+	if err := c.getData(ctx, http.MethodPost, "/admin/v1/alerts/silences", silence, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// DeleteAlertSilence ends a silence window early
+func (c *Client) DeleteAlertSilence(ctx context.Context, id string) error {
+	return c.getData(ctx, http.MethodDelete, "/admin/v1/alerts/silences/"+url.PathEscape(id), nil, nil)
+}
+
+// ListNotifications lists notifications, newest first. tenantID filters by
+// tenant when not empty.
+func (c *Client) ListNotifications(ctx context.Context, tenantID string, unreadOnly bool, limit int) (*NotificationList, error) {
+	query := url.Values{}
+	if tenantID != "" {
+		query.Set("tenant_id", tenantID)
+	}
+	if unreadOnly {
+		query.Set("unread", "true")
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/admin/v1/notifications"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var list NotificationList
+	if err := c.getJSON(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// MarkNotificationRead marks a notification as read
+func (c *Client) MarkNotificationRead(ctx context.Context, id string) error {
+	return c.getData(ctx, http.MethodPost, "/admin/v1/notifications/"+url.PathEscape(id)+"/read", nil, nil)
+}
+
+// MarkAllNotificationsRead marks every unread notification as read
+func (c *Client) MarkAllNotificationsRead(ctx context.Context) error {
+	return c.getData(ctx, http.MethodPost, "/admin/v1/notifications/read-all", nil, nil)
+}
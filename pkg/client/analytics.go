@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// QueryStat counts how often a question was asked, ignoring case and spacing
+type QueryStat struct {
+	Query       string  `json:"query"`
+	Count       int64   `json:"count"`
+	AvgTopScore float64 `json:"avg_top_score"`
+}
+
+// QueryAnalytics summarizes a project's RAG queries over a period
+type QueryAnalytics struct {
+	ProjectID    string    `json:"project_id"`
+	Since        time.Time `json:"since"`
+	Queries      int64     `json:"queries"`
+	ZeroResults  int64     `json:"zero_results"`
+	LowScore     int64     `json:"low_score"`
+	CacheHits    int64     `json:"cache_hits"`
+	CacheHitRate float64   `json:"cache_hit_rate"`
+	LatencyP50Ms float64   `json:"latency_p50_ms"`
+	LatencyP95Ms float64   `json:"latency_p95_ms"`
+
+	TopQueries        []QueryStat `json:"top_queries,omitempty"`
+	ZeroResultQueries []QueryStat `json:"zero_result_queries,omitempty"`
+	LowScoreQueries   []QueryStat `json:"low_score_queries,omitempty"`
+}
+
+// AnalyticsOptions selects the period and list sizes of query analytics;
+// zero values use the server defaults
+type AnalyticsOptions struct {
+	Days     int
+	Limit    int
+	MinScore float64 // Queries whose best result scores below this count as low score
+}
+
+func (o *AnalyticsOptions) query() string {
+	if o == nil {
+		return ""
+	}
+	values := url.Values{}
+	if o.Days > 0 {
+		values.Set("days", strconv.Itoa(o.Days))
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.MinScore > 0 {
+		values.Set("min_score", strconv.FormatFloat(o.MinScore, 'f', -1, 64))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// GetQueryAnalytics retrieves a project's top queries, content gaps, latency
+// percentiles and cache hit rate
+func (c *Client) GetQueryAnalytics(ctx context.Context, projectID string, opts *AnalyticsOptions) (*QueryAnalytics, error) {
+	var analytics QueryAnalytics
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/rag/analytics")+opts.query(), nil, &analytics); err != nil {
+		return nil, err
+	}
+	return &analytics, nil
+}
+
+// ListQueryAnalytics lists the query summary of every project with queries in
+// the period, busiest first (system admin only)
+func (c *Client) ListQueryAnalytics(ctx context.Context, opts *AnalyticsOptions) ([]QueryAnalytics, error) {
+	var list []QueryAnalytics
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/rag/analytics"+opts.query(), nil, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Content gap recommendation actions
+const (
+	GapActionIndexContent     = "index_content"
+	GapActionEnableDataSource = "enable_data_source"
+	GapActionFixDataSource    = "fix_data_source"
+	GapActionRetryUpload      = "retry_upload"
+	GapActionImproveContent   = "improve_content"
+)
+
+// ContentGapReport groups poorly answered questions by topic with indexing
+// recommendations
+type ContentGapReport struct {
+	ProjectID       string              `json:"project_id"`
+	Since           time.Time           `json:"since"`
+	GeneratedAt     time.Time           `json:"generated_at"`
+	GapQueries      int64               `json:"gap_queries"`
+	Recommendations []GapRecommendation `json:"recommendations"`
+}
+
+// GapRecommendation is a suggestion for one topic the corpus answers poorly
+type GapRecommendation struct {
+	Topic           string      `json:"topic"`
+	Action          string      `json:"action"`
+	Questions       int64       `json:"questions"`
+	ZeroResults     int64       `json:"zero_results"`
+	SampleQuestions []string    `json:"sample_questions"`
+	Related         []GapSource `json:"related,omitempty"`
+	Message         string      `json:"message"`
+}
+
+// GapSource is a data source or uploaded document related to a gap topic
+type GapSource struct {
+	Type   string `json:"type"` // data_source or document
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"` // ok, disabled, sync_failed or failed
+}
+
+// GetContentGaps retrieves the content gap report last generated by the daily job
+func (c *Client) GetContentGaps(ctx context.Context, projectID string) (*ContentGapReport, error) {
+	var report ContentGapReport
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/rag/content-gaps"), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// DetectContentGaps regenerates the content gap report; with nil options the
+// stored report is replaced
+func (c *Client) DetectContentGaps(ctx context.Context, projectID string, opts *AnalyticsOptions) (*ContentGapReport, error) {
+	var report ContentGapReport
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/rag/content-gaps")+opts.query(), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,656 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Plan actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Manifest declares the tenants, projects, members, data sources and RAG
+// settings that PlanManifest reconciles against a server. Tenants are
+// matched by slug, projects by slug within their tenant, members by user ID
+// and data sources by name within their project.
+type Manifest struct {
+	Tenants []ManifestTenant `json:"tenants" yaml:"tenants"`
+}
+
+// ManifestTenant declares a tenant. Empty fields are left unmanaged;
+// settings and metadata only manage the keys they list.
+type ManifestTenant struct {
+	Slug        string                 `json:"slug" yaml:"slug"`
+	Name        string                 `json:"name" yaml:"name"`
+	Domain      string                 `json:"domain,omitempty" yaml:"domain"`
+	Description string                 `json:"description,omitempty" yaml:"description"`
+	Plan        string                 `json:"plan,omitempty" yaml:"plan"`
+	Settings    map[string]interface{} `json:"settings,omitempty" yaml:"settings"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata"`
+	Projects    []ManifestProject      `json:"projects,omitempty" yaml:"projects"`
+}
+
+// ManifestProject declares a project of a tenant. RAGSettings manages the
+// top-level override sections it lists (retrieval, chunking, hooks, retention).
+type ManifestProject struct {
+	Slug        string                 `json:"slug" yaml:"slug"`
+	Name        string                 `json:"name" yaml:"name"`
+	Description string                 `json:"description,omitempty" yaml:"description"`
+	Environment string                 `json:"environment,omitempty" yaml:"environment"`
+	IsPublic    bool                   `json:"is_public,omitempty" yaml:"is_public"`
+	Settings    map[string]interface{} `json:"settings,omitempty" yaml:"settings"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata"`
+	Members     []ManifestMember       `json:"members,omitempty" yaml:"members"`
+	DataSources []DataSourceInput      `json:"datasources,omitempty" yaml:"datasources"`
+	RAGSettings map[string]interface{} `json:"rag_settings,omitempty" yaml:"rag_settings"`
+}
+
+// ManifestMember declares a project member and their role
+type ManifestMember struct {
+	UserID string `json:"user_id" yaml:"user_id"`
+	Role   string `json:"role" yaml:"role"`
+}
+
+// PlanOptions controls what a plan may change
+type PlanOptions struct {
+	// Prune deletes members and data sources of declared projects that the
+	// manifest does not list. Tenants and projects are never deleted.
+	Prune bool
+}
+
+// Change is one create, update or delete in a plan
+type Change struct {
+	Action  string        `json:"action"`
+	Address string        `json:"address"` // e.g. tenant.acme/project.docs/datasource.wiki
+	Fields  []FieldChange `json:"fields,omitempty"`
+
+	apply func(ctx context.Context, ids map[string]string) error
+}
+
+// FieldChange is the old and new value of a changed field
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// Plan lists the changes that reconcile a server with a manifest, in the
+// order they are applied
+type Plan struct {
+	Changes []Change `json:"changes"`
+
+	// ids maps the addresses of existing tenants and projects to their IDs;
+	// Apply adds the ones it creates
+	ids map[string]string
+}
+
+// ParseManifest decodes a YAML or JSON manifest and checks it. Unknown
+// fields are rejected so typos do not silently leave settings unmanaged.
+func ParseManifest(data []byte) (*Manifest, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var manifest Manifest
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Validate checks that every resource has its identifying fields and is
+// declared once
+func (m *Manifest) Validate() error {
+	tenants := map[string]bool{}
+	for _, tenant := range m.Tenants {
+		if tenant.Slug == "" || tenant.Name == "" {
+			return fmt.Errorf("invalid manifest: tenants need a slug and a name")
+		}
+		if tenants[tenant.Slug] {
+			return fmt.Errorf("invalid manifest: tenant %q is declared twice", tenant.Slug)
+		}
+		tenants[tenant.Slug] = true
+
+		projects := map[string]bool{}
+		for _, project := range tenant.Projects {
+			address := tenantAddress(tenant.Slug) + "/" + projectAddress(project.Slug)
+			if project.Slug == "" || project.Name == "" {
+				return fmt.Errorf("invalid manifest: projects of tenant %q need a slug and a name", tenant.Slug)
+			}
+			if projects[project.Slug] {
+				return fmt.Errorf("invalid manifest: %s is declared twice", address)
+			}
+			projects[project.Slug] = true
+
+			members := map[string]bool{}
+			for _, member := range project.Members {
+				switch {
+				case member.UserID == "":
+					return fmt.Errorf("invalid manifest: members of %s need a user_id", address)
+				case member.Role != "owner" && member.Role != "collaborator" && member.Role != "viewer":
+					return fmt.Errorf("invalid manifest: member %q of %s has invalid role %q", member.UserID, address, member.Role)
+				case members[member.UserID]:
+					return fmt.Errorf("invalid manifest: member %q of %s is declared twice", member.UserID, address)
+				}
+				members[member.UserID] = true
+			}
+
+			sources := map[string]bool{}
+			for _, source := range project.DataSources {
+				if source.Name == "" || source.Type == "" {
+					return fmt.Errorf("invalid manifest: data sources of %s need a name and a type", address)
+				}
+				if sources[source.Name] {
+					return fmt.Errorf("invalid manifest: data source %q of %s is declared twice", source.Name, address)
+				}
+				sources[source.Name] = true
+			}
+		}
+	}
+	return nil
+}
+
+// PlanManifest compares a manifest with the server and returns the changes
+// that would reconcile them. Nothing is changed until the plan is applied.
+func (c *Client) PlanManifest(ctx context.Context, manifest *Manifest, opts *PlanOptions) (*Plan, error) {
+	if opts == nil {
+		opts = &PlanOptions{}
+	}
+	plan := &Plan{ids: map[string]string{}}
+	for i := range manifest.Tenants {
+		if err := c.planTenant(ctx, plan, &manifest.Tenants[i], opts); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// Apply makes the changes of a plan in order, stopping at the first failure.
+// Changes made before a failure are kept; planning again picks up the rest.
+func (c *Client) Apply(ctx context.Context, plan *Plan) error {
+	for _, change := range plan.Changes {
+		if err := change.apply(ctx, plan.ids); err != nil {
+			return fmt.Errorf("failed to %s %s: %w", change.Action, change.Address, err)
+		}
+	}
+	return nil
+}
+
+// Empty reports whether the server already matches the manifest
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String renders the plan as a diff: + creates, ~ updates and - deletes
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "No changes. The server matches the manifest.\n"
+	}
+	var b strings.Builder
+	counts := map[string]int{}
+	for _, change := range p.Changes {
+		counts[change.Action]++
+		symbol := map[string]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[change.Action]
+		fmt.Fprintf(&b, "%s %s\n", symbol, change.Address)
+		for _, field := range change.Fields {
+			switch change.Action {
+			case ActionCreate:
+				fmt.Fprintf(&b, "    %s: %s\n", field.Field, formatValue(field.New))
+			default:
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", field.Field, formatValue(field.Old), formatValue(field.New))
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete])
+	return b.String()
+}
+
+func (c *Client) planTenant(ctx context.Context, plan *Plan, desired *ManifestTenant, opts *PlanOptions) error {
+	address := tenantAddress(desired.Slug)
+	current, err := c.GetTenantBySlug(ctx, desired.Slug)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("failed to read %s: %w", address, err)
+	}
+
+	if current == nil {
+		input := &TenantInput{
+			Name: desired.Name, Slug: desired.Slug, Domain: desired.Domain, Description: desired.Description,
+			Plan: desired.Plan, Settings: desired.Settings, Metadata: desired.Metadata,
+		}
+		plan.add(Change{
+			Action:  ActionCreate,
+			Address: address,
+			Fields:  fields("name", desired.Name, "plan", desired.Plan, "domain", desired.Domain),
+			apply: func(ctx context.Context, ids map[string]string) error {
+				tenant, err := c.CreateTenant(ctx, input)
+				if err == nil {
+					ids[address] = tenant.ID
+				}
+				return err
+			},
+		})
+	} else {
+		plan.ids[address] = current.ID
+		var changes []FieldChange
+		changes = diffString(changes, "slug", current.Slug, desired.Slug)
+		changes = diffString(changes, "name", current.Name, desired.Name)
+		changes = diffString(changes, "domain", current.Domain, desired.Domain)
+		changes = diffString(changes, "description", current.Description, desired.Description)
+		changes = diffString(changes, "plan", current.Plan, desired.Plan)
+		settings, changes := diffMap(changes, "settings", current.Settings, desired.Settings)
+		metadata, changes := diffMap(changes, "metadata", current.Metadata, desired.Metadata)
+		if len(changes) > 0 {
+			input := &TenantInput{
+				Name: desired.Name, Slug: desired.Slug, Domain: desired.Domain, Description: desired.Description,
+				Plan: desired.Plan, Settings: settings, Metadata: metadata,
+			}
+			id := current.ID
+			plan.add(Change{
+				Action:  ActionUpdate,
+				Address: address,
+				Fields:  changes,
+				apply: func(ctx context.Context, ids map[string]string) error {
+					_, err := c.UpdateTenant(ctx, id, input)
+					return err
+				},
+			})
+		}
+	}
+
+	for i := range desired.Projects {
+		if err := c.planProject(ctx, plan, address, current, &desired.Projects[i], opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) planProject(ctx context.Context, plan *Plan, tenantAddr string, tenant *Tenant, desired *ManifestProject, opts *PlanOptions) error {
+	address := tenantAddr + "/" + projectAddress(desired.Slug)
+	var current *Project
+	if tenant != nil {
+		var err error
+		current, err = c.GetProjectBySlug(ctx, tenant.ID, desired.Slug)
+		if err != nil && !IsNotFound(err) {
+			return fmt.Errorf("failed to read %s: %w", address, err)
+		}
+	}
+
+	if current == nil {
+		input := &ProjectInput{
+			Name: desired.Name, Slug: desired.Slug, Description: desired.Description, Environment: desired.Environment,
+			IsPublic: desired.IsPublic, Settings: desired.Settings, Metadata: desired.Metadata,
+		}
+		plan.add(Change{
+			Action:  ActionCreate,
+			Address: address,
+			Fields:  fields("name", desired.Name, "environment", desired.Environment),
+			apply: func(ctx context.Context, ids map[string]string) error {
+				project, err := c.CreateProject(ctx, ids[tenantAddr], input)
+				if err == nil {
+					ids[address] = project.ID
+				}
+				return err
+			},
+		})
+		for _, member := range desired.Members {
+			plan.addMember(c, address, member, "")
+		}
+		for i := range desired.DataSources {
+			plan.addDataSource(c, address, &desired.DataSources[i], nil)
+		}
+		if len(desired.RAGSettings) > 0 {
+			plan.addRAGSettings(c, address, nil, desired.RAGSettings, nil)
+		}
+		return nil
+	}
+
+	plan.ids[address] = current.ID
+	var changes []FieldChange
+	changes = diffString(changes, "slug", current.Slug, desired.Slug)
+	changes = diffString(changes, "name", current.Name, desired.Name)
+	changes = diffString(changes, "description", current.Description, desired.Description)
+	changes = diffString(changes, "environment", current.Environment, desired.Environment)
+	if current.IsPublic != desired.IsPublic {
+		changes = append(changes, FieldChange{Field: "is_public", Old: current.IsPublic, New: desired.IsPublic})
+	}
+	settings, changes := diffMap(changes, "settings", current.Settings, desired.Settings)
+	metadata, changes := diffMap(changes, "metadata", current.Metadata, desired.Metadata)
+	if len(changes) > 0 {
+		// Updates always carry the name, as the server ignores updates that
+		// only set is_public
+		input := &ProjectInput{
+			Name: desired.Name, Slug: desired.Slug, Description: desired.Description, Environment: desired.Environment,
+			IsPublic: desired.IsPublic, Settings: settings, Metadata: metadata,
+		}
+		id := current.ID
+		plan.add(Change{
+			Action:  ActionUpdate,
+			Address: address,
+			Fields:  changes,
+			apply: func(ctx context.Context, ids map[string]string) error {
+				_, err := c.UpdateProject(ctx, id, input)
+				return err
+			},
+		})
+	}
+
+	if err := c.planMembers(ctx, plan, address, current, desired.Members, opts); err != nil {
+		return err
+	}
+	if err := c.planDataSources(ctx, plan, address, current.ID, desired.DataSources, opts); err != nil {
+		return err
+	}
+	if len(desired.RAGSettings) > 0 {
+		overrides, err := c.GetRAGSettings(ctx, current.ID)
+		if err != nil {
+			return fmt.Errorf("failed to read RAG settings of %s: %w", address, err)
+		}
+		var changes []FieldChange
+		for _, section := range sortedKeys(desired.RAGSettings) {
+			old, new := normalize(overrides[section]), normalize(desired.RAGSettings[section])
+			if !reflect.DeepEqual(old, new) {
+				changes = append(changes, FieldChange{Field: section, Old: old, New: new})
+			}
+		}
+		if len(changes) > 0 {
+			plan.addRAGSettings(c, address, overrides, desired.RAGSettings, changes)
+		}
+	}
+	return nil
+}
+
+func (c *Client) planMembers(ctx context.Context, plan *Plan, projectAddr string, project *Project, desired []ManifestMember, opts *PlanOptions) error {
+	members, err := c.ListProjectMembers(ctx, project.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read members of %s: %w", projectAddr, err)
+	}
+	current := make(map[string]ProjectMember, len(members))
+	for _, member := range members {
+		current[member.UserID] = member
+	}
+	declared := map[string]bool{}
+	for _, member := range desired {
+		declared[member.UserID] = true
+		existing, ok := current[member.UserID]
+		if !ok || existing.Role != member.Role {
+			plan.addMember(c, projectAddr, member, existing.Role)
+		}
+	}
+	if !opts.Prune {
+		return nil
+	}
+	for _, member := range members {
+		// The owner and creator always stay members
+		if declared[member.UserID] || member.IsCreator || member.UserID == project.OwnerID {
+			continue
+		}
+		userID := member.UserID
+		plan.add(Change{
+			Action:  ActionDelete,
+			Address: projectAddr + "/member." + userID,
+			Fields:  []FieldChange{{Field: "role", Old: member.Role}},
+			apply: func(ctx context.Context, ids map[string]string) error {
+				return c.RemoveProjectMember(ctx, ids[projectAddr], userID)
+			},
+		})
+	}
+	return nil
+}
+
+func (c *Client) planDataSources(ctx context.Context, plan *Plan, projectAddr, projectID string, desired []DataSourceInput, opts *PlanOptions) error {
+	sources, err := c.ListDataSources(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to read data sources of %s: %w", projectAddr, err)
+	}
+	current := make(map[string]*DataSource, len(sources))
+	for i := range sources {
+		current[sources[i].Name] = &sources[i]
+	}
+	for i := range desired {
+		source := &desired[i]
+		existing := current[source.Name]
+		if existing == nil {
+			plan.addDataSource(c, projectAddr, source, nil)
+			continue
+		}
+		var changes []FieldChange
+		changes = diffString(changes, "type", existing.Type, source.Type)
+		changes = diffValue(changes, "config", existing.Config, source.Config)
+		changes = diffValue(changes, "secret_refs", existing.SecretRefs, source.SecretRefs)
+		changes = diffValue(changes, "schedule", existing.Schedule, source.Schedule)
+		changes = diffValue(changes, "include_patterns", existing.IncludePatterns, source.IncludePatterns)
+		changes = diffValue(changes, "exclude_patterns", existing.ExcludePatterns, source.ExcludePatterns)
+		if source.Enabled != nil && *source.Enabled != existing.Enabled {
+			changes = append(changes, FieldChange{Field: "enabled", Old: existing.Enabled, New: *source.Enabled})
+		}
+		if len(changes) > 0 {
+			plan.addDataSource(c, projectAddr, source, &dataSourceUpdate{id: existing.ID, changes: changes})
+		}
+	}
+	if !opts.Prune {
+		return nil
+	}
+	declared := map[string]bool{}
+	for _, source := range desired {
+		declared[source.Name] = true
+	}
+	for _, source := range sources {
+		if declared[source.Name] {
+			continue
+		}
+		id := source.ID
+		plan.add(Change{
+			Action:  ActionDelete,
+			Address: projectAddr + "/datasource." + source.Name,
+			Fields:  []FieldChange{{Field: "type", Old: source.Type}},
+			apply: func(ctx context.Context, ids map[string]string) error {
+				return c.DeleteDataSource(ctx, ids[projectAddr], id)
+			},
+		})
+	}
+	return nil
+}
+
+// dataSourceUpdate identifies an existing data source and how it differs
+type dataSourceUpdate struct {
+	id      string
+	changes []FieldChange
+}
+
+func (p *Plan) add(change Change) {
+	p.Changes = append(p.Changes, change)
+}
+
+// addMember adds a member, or changes their role when oldRole is set
+func (p *Plan) addMember(c *Client, projectAddr string, member ManifestMember, oldRole string) {
+	change := Change{
+		Action:  ActionCreate,
+		Address: projectAddr + "/member." + member.UserID,
+		Fields:  []FieldChange{{Field: "role", Old: oldRole, New: member.Role}},
+		apply: func(ctx context.Context, ids map[string]string) error {
+			return c.InviteToProject(ctx, ids[projectAddr], &InviteRequest{UserID: member.UserID, Role: member.Role})
+		},
+	}
+	if oldRole != "" {
+		change.Action = ActionUpdate
+	}
+	p.add(change)
+}
+
+// addDataSource creates a data source, or updates the existing one
+func (p *Plan) addDataSource(c *Client, projectAddr string, source *DataSourceInput, update *dataSourceUpdate) {
+	change := Change{
+		Action:  ActionCreate,
+		Address: projectAddr + "/datasource." + source.Name,
+		Fields:  fields("type", source.Type, "schedule", source.Schedule),
+		apply: func(ctx context.Context, ids map[string]string) error {
+			_, err := c.CreateDataSource(ctx, ids[projectAddr], source)
+			return err
+		},
+	}
+	if update != nil {
+		change.Action = ActionUpdate
+		change.Fields = update.changes
+		change.apply = func(ctx context.Context, ids map[string]string) error {
+			_, err := c.UpdateDataSource(ctx, ids[projectAddr], update.id, source)
+			return err
+		}
+	}
+	p.add(change)
+}
+
+// addRAGSettings replaces the declared sections of a project's RAG setting
+// overrides, keeping the sections the manifest does not list
+func (p *Plan) addRAGSettings(c *Client, projectAddr string, current, desired map[string]interface{}, changes []FieldChange) {
+	overrides := map[string]interface{}{}
+	for key, value := range current {
+		overrides[key] = value
+	}
+	for key, value := range desired {
+		overrides[key] = normalize(value)
+	}
+	delete(overrides, "project_id")
+	delete(overrides, "updated_at")
+	delete(overrides, "updated_by")
+
+	change := Change{
+		Action:  ActionUpdate,
+		Address: projectAddr + "/rag_settings",
+		Fields:  changes,
+		apply: func(ctx context.Context, ids map[string]string) error {
+			return c.SaveRAGSettings(ctx, ids[projectAddr], overrides)
+		},
+	}
+	if current == nil {
+		change.Action = ActionCreate
+		change.Fields = nil
+		for _, section := range sortedKeys(desired) {
+			change.Fields = append(change.Fields, FieldChange{Field: section, New: normalize(desired[section])})
+		}
+	}
+	p.add(change)
+}
+
+func tenantAddress(slug string) string {
+	return "tenant." + slug
+}
+
+func projectAddress(slug string) string {
+	return "project." + slug
+}
+
+// fields lists the non-empty values of name/value pairs for a create
+func fields(pairs ...string) []FieldChange {
+	var changes []FieldChange
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			changes = append(changes, FieldChange{Field: pairs[i], New: pairs[i+1]})
+		}
+	}
+	return changes
+}
+
+// diffString records a change when a managed (non-empty) field differs
+func diffString(changes []FieldChange, field, current, desired string) []FieldChange {
+	if desired != "" && current != desired {
+		changes = append(changes, FieldChange{Field: field, Old: current, New: desired})
+	}
+	return changes
+}
+
+// diffValue records a change when a field differs, comparing JSON forms
+func diffValue(changes []FieldChange, field string, current, desired interface{}) []FieldChange {
+	old, new := normalize(current), normalize(desired)
+	if isEmpty(old) && isEmpty(new) {
+		return changes
+	}
+	if !reflect.DeepEqual(old, new) {
+		changes = append(changes, FieldChange{Field: field, Old: old, New: new})
+	}
+	return changes
+}
+
+// diffMap records a change for each listed key whose value differs and
+// returns the current map with the listed keys set
+func diffMap(changes []FieldChange, field string, current, desired map[string]interface{}) (map[string]interface{}, []FieldChange) {
+	changed := false
+	for _, key := range sortedKeys(desired) {
+		old, new := normalize(current[key]), normalize(desired[key])
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, FieldChange{Field: field + "." + key, Old: old, New: new})
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, changes
+	}
+	merged := make(map[string]interface{}, len(current)+len(desired))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range desired {
+		merged[key] = normalize(value)
+	}
+	return merged, changes
+}
+
+// normalize converts a value to its JSON form, so YAML integers compare
+// equal to the numbers the server returns
+func normalize(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
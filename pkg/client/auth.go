@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sessionStorageKey is the Storage key of a persisted session
+const sessionStorageKey = "metabase.session"
+
+// User represents the authenticated user
+type User struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	TenantID string `json:"tenant_id"`
+}
+
+// Session represents an authenticated session
+type Session struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+	User         *User     `json:"user,omitempty"`
+}
+
+// RegisterRequest represents a user registration request
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// tokenResponse is the response of the login, register and refresh endpoints
+type tokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         *User  `json:"user"`
+}
+
+// Login signs in with email and password; subsequent requests use the session
+func (c *Client) Login(ctx context.Context, email, password string) (*Session, error) {
+	var response tokenResponse
+	err := c.getJSON(ctx, http.MethodPost, "/auth/login", map[string]string{
+		"email":    email,
+		"password": password,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return c.storeSession(response, nil), nil
+}
+
+// Register creates a user and signs in as it
+func (c *Client) Register(ctx context.Context, req *RegisterRequest) (*Session, error) {
+	var response tokenResponse
+	if err := c.getJSON(ctx, http.MethodPost, "/auth/register", req, &response); err != nil {
+		return nil, err
+	}
+	return c.storeSession(response, nil), nil
+}
+
+// RefreshSession exchanges the session's refresh token for a new access token
+func (c *Client) RefreshSession(ctx context.Context) (*Session, error) {
+	current := c.Session()
+	if current == nil || current.RefreshToken == "" {
+		return nil, fmt.Errorf("no refresh token available")
+	}
+
+	var response tokenResponse
+	err := c.getJSON(ctx, http.MethodPost, "/auth/refresh", map[string]string{
+		"refresh_token": current.RefreshToken,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return c.storeSession(response, current), nil
+}
+
+// Logout forgets the current session
+func (c *Client) Logout() {
+	c.mu.Lock()
+	c.session = nil
+	c.mu.Unlock()
+
+	if storage := c.sessionStorage(); storage != nil {
+		storage.Delete(sessionStorageKey)
+	}
+}
+
+// Session returns a copy of the current session, or nil when not signed in
+func (c *Client) Session() *Session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.session == nil {
+		return nil
+	}
+	session := *c.session
+	return &session
+}
+
+// SetSession replaces the current session, e.g. with one obtained elsewhere
+func (c *Client) SetSession(session *Session) {
+	c.mu.Lock()
+	c.session = session
+	c.mu.Unlock()
+	c.persistSession(session)
+}
+
+// AccessToken returns the session's access token
+func (c *Client) AccessToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.session == nil {
+		return ""
+	}
+	return c.session.AccessToken
+}
+
+// storeSession saves a token response as the current session; previous
+// supplies fields the refresh endpoint does not return
+func (c *Client) storeSession(response tokenResponse, previous *Session) *Session {
+	session := &Session{
+		AccessToken:  response.Token,
+		RefreshToken: response.RefreshToken,
+		User:         response.User,
+	}
+	if response.ExpiresIn > 0 {
+		session.ExpiresAt = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	if previous != nil {
+		if session.RefreshToken == "" {
+			session.RefreshToken = previous.RefreshToken
+		}
+		if session.User == nil {
+			session.User = previous.User
+		}
+	}
+
+	c.SetSession(session)
+	result := *session
+	return &result
+}
+
+// canRefresh reports whether a 401 response on path should trigger a token refresh
+func (c *Client) canRefresh(path string) bool {
+	if c.config.Auth == nil || !c.config.Auth.AutoRefreshToken || strings.HasPrefix(path, "/auth/") {
+		return false
+	}
+	session := c.Session()
+	return session != nil && session.RefreshToken != ""
+}
+
+// sessionStorage returns the storage sessions persist to, if enabled
+func (c *Client) sessionStorage() Storage {
+	if c.config.Auth == nil || !c.config.Auth.PersistSession {
+		return nil
+	}
+	return c.config.Auth.Storage
+}
+
+func (c *Client) persistSession(session *Session) {
+	storage := c.sessionStorage()
+	if storage == nil {
+		return
+	}
+	if session == nil {
+		storage.Delete(sessionStorageKey)
+		return
+	}
+	if data, err := json.Marshal(session); err == nil {
+		storage.Set(sessionStorageKey, string(data))
+	}
+}
+
+// restoreSession loads a persisted session
+func (c *Client) restoreSession() {
+	storage := c.sessionStorage()
+	if storage == nil {
+		return
+	}
+	data, err := storage.Get(sessionStorageKey)
+	if err != nil || data == "" {
+		return
+	}
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err == nil {
+		c.session = &session
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// ChunkingConfig overrides the project's chunking settings; zero fields keep
+// the current value
+type ChunkingConfig struct {
+	Strategy            string  `json:"strategy,omitempty"` // fixed, paragraph, semantic, code or table_aware
+	MaxChunkSize        int     `json:"max_chunk_size,omitempty"`
+	MinChunkSize        int     `json:"min_chunk_size,omitempty"`
+	OverlapSize         int     `json:"overlap_size,omitempty"`
+	SimilarityThreshold float64 `json:"similarity_threshold,omitempty"`
+}
+
+// ChunkerVariant is one side of a chunker comparison
+type ChunkerVariant struct {
+	Name   string         `json:"name"`
+	Config ChunkingConfig `json:"config"`
+}
+
+// EvalCase is a question with the documents that answer it
+type EvalCase struct {
+	Query             string   `json:"query"`
+	RelevantDocuments []string `json:"relevant_documents"`
+}
+
+// CompareChunkersRequest defines a chunker A/B comparison
+type CompareChunkersRequest struct {
+	A               ChunkerVariant `json:"a"`
+	B               ChunkerVariant `json:"b"`
+	EvalSet         []EvalCase     `json:"eval_set"`
+	SampleSize      int            `json:"sample_size,omitempty"` // Documents to index, 50 by default
+	TopK            int            `json:"top_k,omitempty"`
+	CostPer1KTokens float64        `json:"cost_per_1k_tokens,omitempty"`
+}
+
+// EvalCaseResult is how one variant answered one evaluation question
+type EvalCaseResult struct {
+	Query         string   `json:"query"`
+	Documents     []string `json:"documents"`
+	FirstRelevant int      `json:"first_relevant"` // 1-based rank, 0 if no relevant document was retrieved
+	Recall        float64  `json:"recall"`
+	TopScore      float64  `json:"top_score"`
+}
+
+// ChunkerReport holds the retrieval quality and cost of one variant
+type ChunkerReport struct {
+	Name           string           `json:"name"`
+	Strategy       string           `json:"strategy"`
+	Config         ChunkingConfig   `json:"config"`
+	Chunks         int              `json:"chunks"`
+	AvgChunkTokens float64          `json:"avg_chunk_tokens"`
+	EmbeddedTokens int              `json:"embedded_tokens"`
+	EmbeddingCalls int              `json:"embedding_calls"`
+	EstimatedCost  float64          `json:"estimated_cost"`
+	ContextTokens  float64          `json:"context_tokens"`
+	IndexTime      time.Duration    `json:"index_time"`
+	HitRate        float64          `json:"hit_rate"`
+	Recall         float64          `json:"recall"`
+	MRR            float64          `json:"mrr"`
+	AvgTopScore    float64          `json:"avg_top_score"`
+	Cases          []EvalCaseResult `json:"cases"`
+	Errors         []string         `json:"errors,omitempty"`
+}
+
+// ChunkerComparison reports two chunking configurations side by side; deltas
+// are B minus A
+type ChunkerComparison struct {
+	Documents           int           `json:"documents"`
+	Questions           int           `json:"questions"`
+	TopK                int           `json:"top_k"`
+	A                   ChunkerReport `json:"a"`
+	B                   ChunkerReport `json:"b"`
+	HitRateDelta        float64       `json:"hit_rate_delta"`
+	RecallDelta         float64       `json:"recall_delta"`
+	MRRDelta            float64       `json:"mrr_delta"`
+	EmbeddedTokensDelta int           `json:"embedded_tokens_delta"`
+	ContextTokensDelta  float64       `json:"context_tokens_delta"`
+	Winner              string        `json:"winner"` // Variant with the higher MRR, empty on a tie
+}
+
+// CompareChunkers indexes a sample of the project's documents with two
+// chunking configurations in temporary namespaces and runs the evaluation
+// set against both. The serving index is not changed.
+func (c *Client) CompareChunkers(ctx context.Context, projectID string, req *CompareChunkersRequest) (*ChunkerComparison, error) {
+	var comparison ChunkerComparison
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/rag/compare-chunkers"), req, &comparison); err != nil {
+		return nil, err
+	}
+	return &comparison, nil
+}
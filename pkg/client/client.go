@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -23,6 +25,11 @@ type Config struct {
 	HTTPClient  *http.Client      `json:"-"`
 	Database    *DatabaseConfig   `json:"db,omitempty"`
 	Auth        *AuthConfig       `json:"auth,omitempty"`
+
+	// Retries for transient failures (network errors, 429, 502-504).
+	// Requests that may not be safe to repeat (POST) are only retried on 429.
+	MaxRetries   int           `json:"max_retries,omitempty"`
+	RetryBackoff time.Duration `json:"retry_backoff,omitempty"`
 }
 
 // DatabaseConfig represents database configuration
@@ -113,17 +120,41 @@ type QueryResult struct {
 type APIError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
 	Status  int    `json:"status"`
 }
 
 func (e *APIError) Error() string {
-	return fmt.Sprintf("API Error [%s]: %s", e.Code, e.Message)
+	code := e.Code
+	if code == "" {
+		code = strconv.Itoa(e.Status)
+	}
+	if e.Details != "" {
+		return fmt.Sprintf("API Error [%s]: %s: %s", code, e.Message, e.Details)
+	}
+	return fmt.Sprintf("API Error [%s]: %s", code, e.Message)
+}
+
+// IsNotFound reports whether err is an API error with status 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
 }
 
+const (
+	defaultMaxRetries   = 2
+	defaultRetryBackoff = 200 * time.Millisecond
+	maxRetryDelay       = 10 * time.Second
+)
+
 // Client represents the MetaBase client
 type Client struct {
 	config *Config
 	http   *http.Client
+
+	// Session obtained from Login/Register; takes precedence over Config credentials
+	mu      sync.RWMutex
+	session *Session
 }
 
 // New creates a new MetaBase client
@@ -133,11 +164,19 @@ func New(config *Config) *Client {
 			Timeout: 30 * time.Second,
 		}
 	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
 
-	return &Client{
+	c := &Client{
 		config: config,
 		http:   config.HTTPClient,
 	}
+	c.restoreSession()
+	return c
 }
 
 // Create creates a new record
@@ -355,53 +394,164 @@ func (c *Client) Health(ctx context.Context) (map[string]interface{}, error) {
 	return response, nil
 }
 
-// makeRequest makes an HTTP request with authentication
+// makeRequest makes an HTTP request with authentication, retrying transient
+// failures and refreshing an expired session once when AutoRefreshToken is set
 func (c *Client) makeRequest(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
-	var reqBody io.Reader
+	var payload []byte
 	if body != nil {
 		data, err := json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request: %w", err)
 		}
-		reqBody = bytes.NewBuffer(data)
+		payload = data
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, c.config.URL+path, reqBody)
+	resp, err := c.send(ctx, method, path, payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+	result, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	c.setAuthHeader(req)
+	return result, nil
+}
 
-	resp, err := c.http.Do(req)
+// getJSON performs a request and decodes the response body into out
+func (c *Client) getJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	result, err := c.makeRequest(ctx, method, path, body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	if out == nil || len(result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
 
-	if resp.StatusCode >= 400 {
+// getData performs a request against an endpoint that wraps its result in
+// {"data": ...} and decodes the wrapped value into out
+func (c *Client) getData(ctx context.Context, method, path string, body, out interface{}) error {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := c.getJSON(ctx, method, path, body, &envelope); err != nil {
+		return err
+	}
+	if out == nil || len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// send performs the request and returns a successful response, whose body
+// the caller must close
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.config.URL+path, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		c.setAuthHeader(req)
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= c.config.MaxRetries || method == http.MethodPost {
+				return nil, fmt.Errorf("failed to make request: %w", err)
+			}
+			if err := c.wait(ctx, attempt, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode < 400 {
+			return resp, nil
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && !refreshed && c.canRefresh(path) {
+			resp.Body.Close()
+			refreshed = true
+			if _, err := c.RefreshSession(ctx); err == nil {
+				continue
+			}
+			return nil, &APIError{Status: http.StatusUnauthorized, Message: "session expired"}
+		}
+
+		if attempt < c.config.MaxRetries && retryable(method, resp.StatusCode) {
+			resp.Body.Close()
+			if err := c.wait(ctx, attempt, resp); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
 		return nil, c.handleAPIError(resp)
 	}
+}
 
-	result, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+// retryable reports whether a failed request may be repeated
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return method != http.MethodPost
 	}
+	return false
+}
 
-	return result, nil
+// wait sleeps before the next attempt, honouring Retry-After when present
+func (c *Client) wait(ctx context.Context, attempt int, resp *http.Response) error {
+	delay := c.config.RetryBackoff << attempt
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 // setAuthHeader sets the authentication header
 func (c *Client) setAuthHeader(req *http.Request) {
-	if c.config.APIKey != "" {
+	if token := c.AccessToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if c.config.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 	} else if c.config.AccessToken != "" {
 		req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
 	}
+	if c.config.APIKey != "" {
+		req.Header.Set("apikey", c.config.APIKey)
+	}
 
 	// Add custom headers
 	for key, value := range c.config.Headers {
@@ -409,7 +559,8 @@ func (c *Client) setAuthHeader(req *http.Request) {
 	}
 }
 
-// handleAPIError handles API error responses
+// handleAPIError handles API error responses. The API reports errors as
+// {"error", "details", "code"}; plain text bodies become the message.
 func (c *Client) handleAPIError(resp *http.Response) error {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -419,14 +570,30 @@ func (c *Client) handleAPIError(resp *http.Response) error {
 		}
 	}
 
-	var apiErr APIError
-	if err := json.Unmarshal(body, &apiErr); err != nil {
+	var payload struct {
+		Error   string          `json:"error"`
+		Message string          `json:"message"`
+		Details string          `json:"details"`
+		Code    json.RawMessage `json:"code"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
 		return &APIError{
 			Status:  resp.StatusCode,
-			Message: string(body),
+			Message: strings.TrimSpace(string(body)),
 		}
 	}
 
-	apiErr.Status = resp.StatusCode
-	return &apiErr
+	apiErr := &APIError{
+		Status:  resp.StatusCode,
+		Message: payload.Error,
+		Details: payload.Details,
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = payload.Message
+	}
+	// code is a string on RAG endpoints and the HTTP status on auth endpoints
+	if err := json.Unmarshal(payload.Code, &apiErr.Code); err != nil {
+		apiErr.Code = strconv.Itoa(resp.StatusCode)
+	}
+	return apiErr
 }
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(&Config{URL: server.URL, AccessToken: "token", RetryBackoff: time.Millisecond})
+}
+
+func TestRetryAndErrors(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "Project not found", "status": 404})
+	})
+
+	_, err := c.GetProject(context.Background(), "p1")
+	if calls.Load() != 2 {
+		t.Fatalf("expected GET to be retried once, got %d calls", calls.Load())
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Project not found" || !IsNotFound(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// POST is not retried on 503
+	calls.Store(0)
+	c.CreateTenant(context.Background(), &TenantInput{Name: "t"})
+	if calls.Load() != 1 {
+		t.Fatalf("expected POST not to be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestTenantPagination(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var tenants []Tenant
+		for i := 0; i < 2 && (page-1)*2+i < 5; i++ {
+			tenants = append(tenants, Tenant{ID: fmt.Sprintf("t%d", (page-1)*2+i)})
+		}
+		json.NewEncoder(w).Encode(TenantList{Tenants: tenants, Total: 5, Page: page, Limit: 2})
+	})
+
+	var ids []string
+	for tenant, err := range c.Tenants(context.Background(), 2) {
+		if err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		ids = append(ids, tenant.ID)
+	}
+	if len(ids) != 5 || ids[4] != "t4" {
+		t.Fatalf("expected 5 tenants across 3 pages, got %v", ids)
+	}
+}
+
+func TestAutoRefreshToken(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/auth/refresh":
+			json.NewEncoder(w).Encode(map[string]interface{}{"token": "fresh", "expires_in": 3600})
+		case r.Header.Get("Authorization") != "Bearer fresh":
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": []DataSource{{ID: "ds1"}}})
+		}
+	})
+	c.config.Auth = &AuthConfig{AutoRefreshToken: true}
+	c.SetSession(&Session{AccessToken: "stale", RefreshToken: "refresh"})
+
+	sources, err := c.ListDataSources(context.Background(), "p1")
+	if err != nil || len(sources) != 1 {
+		t.Fatalf("expected request to succeed after refresh, got %v, %v", sources, err)
+	}
+	if session := c.Session(); session.AccessToken != "fresh" || session.RefreshToken != "refresh" {
+		t.Fatalf("unexpected session after refresh: %+v", session)
+	}
+}
+
+func TestStreamQuery(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	cancelled := make(chan struct{})
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			id := msg["id"]
+			switch msg["type"] {
+			case "query":
+				conn.WriteJSON(map[string]interface{}{"type": "citations", "id": id, "sources": []Source{{DocumentID: "d1"}}})
+				conn.WriteJSON(map[string]interface{}{"type": "token", "id": id, "content": "Hel"})
+				conn.WriteJSON(map[string]interface{}{"type": "token", "id": id, "content": "lo"})
+				if msg["query"] == "hi" {
+					conn.WriteJSON(map[string]interface{}{"type": "done", "id": id, "result": RAGQueryResult{GeneratedResponse: "Hello"}})
+				}
+			case "cancel":
+				close(cancelled)
+				conn.WriteJSON(map[string]interface{}{"type": "cancelled", "id": id})
+			}
+		}
+	})
+
+	var citations int
+	var answer string
+	result, err := c.StreamQuery(context.Background(), "p1", &RAGQueryRequest{Query: "hi"}, &StreamHandler{
+		OnCitations: func(sources []Source) { citations += len(sources) },
+		OnToken: func(token string) error {
+			answer += token
+			return nil
+		},
+	})
+	if err != nil || result.GeneratedResponse != "Hello" || answer != "Hello" || citations != 1 {
+		t.Fatalf("unexpected stream outcome: %v, %v, %q, %d", result, err, answer, citations)
+	}
+
+	// An error from OnToken cancels the query on the server
+	stop := errors.New("stop")
+	_, err = c.StreamQuery(context.Background(), "p1", &RAGQueryRequest{Query: "long"}, &StreamHandler{
+		OnToken: func(string) error { return stop },
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("server did not receive cancel")
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Document ingestion stages, in order
+const (
+	IngestReceived  = "received"
+	IngestExtracted = "extracted"
+	IngestChunked   = "chunked"
+	IngestEmbedded  = "embedded"
+	IngestIndexed   = "indexed"
+)
+
+// Ingest job statuses
+const (
+	IngestStatusProcessing = "processing"
+	IngestStatusCompleted  = "completed"
+	IngestStatusFailed     = "failed"
+)
+
+// IngestJob represents the processing of an uploaded file or URL
+type IngestJob struct {
+	ID            string           `json:"id"`
+	ProjectID     string           `json:"project_id"`
+	SourceType    string           `json:"source_type"` // file or url
+	Source        string           `json:"source"`      // File name or URL
+	DocumentID    string           `json:"document_id"`
+	Title         string           `json:"title,omitempty"`
+	Status        string           `json:"status"`
+	Stage         string           `json:"stage"` // Last completed stage
+	Stages        []IngestStageLog `json:"stages"`
+	Error         *IngestJobError  `json:"error,omitempty"`
+	ChunksCreated int              `json:"chunks_created,omitempty"`
+	DuplicateOf   string           `json:"duplicate_of,omitempty"` // Indexed document of the same project with the same content
+	Attempts      int              `json:"attempts,omitempty"`     // Retries from the dead-letter queue
+	CreatedBy     string           `json:"created_by,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// IngestStageLog records when an ingestion stage completed
+type IngestStageLog struct {
+	Stage       string    `json:"stage"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// IngestJobError reports the stage at which ingestion failed
+type IngestJobError struct {
+	Stage   string `json:"stage"`
+	Message string `json:"message"`
+}
+
+// Done reports whether the job has completed or failed
+func (j *IngestJob) Done() bool {
+	return j.Status != IngestStatusProcessing
+}
+
+// DocumentInput represents the optional title and tags of an ingested document
+type DocumentInput struct {
+	Title string   `json:"title,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// UploadDocument uploads a file to the project's RAG index. The file is
+// processed in the background; poll the returned job with GetIngestJob.
+func (c *Client) UploadDocument(ctx context.Context, projectID, filename string, file io.Reader, input *DocumentInput) (*IngestJob, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, fmt.Errorf("failed to copy file: %w", err)
+	}
+	if input != nil {
+		if input.Title != "" {
+			writer.WriteField("title", input.Title)
+		}
+		if len(input.Tags) > 0 {
+			writer.WriteField("tags", strings.Join(input.Tags, ","))
+		}
+	}
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.URL+projectPath(projectID, "/documents"), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	c.setAuthHeader(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, c.handleAPIError(resp)
+	}
+
+	var envelope struct {
+		Data []IngestJob `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(envelope.Data) == 0 {
+		return nil, fmt.Errorf("no ingest job returned")
+	}
+	return &envelope.Data[0], nil
+}
+
+// IngestURL fetches a web page or text document into the project's RAG index
+// in the background
+func (c *Client) IngestURL(ctx context.Context, projectID, documentURL string, input *DocumentInput) (*IngestJob, error) {
+	req := struct {
+		URL string `json:"url"`
+		DocumentInput
+	}{URL: documentURL}
+	if input != nil {
+		req.DocumentInput = *input
+	}
+
+	var jobs []IngestJob
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/documents"), req, &jobs); err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no ingest job returned")
+	}
+	return &jobs[0], nil
+}
+
+// GetIngestJob returns the progress of a document ingestion
+func (c *Client) GetIngestJob(ctx context.Context, projectID, jobID string) (*IngestJob, error) {
+	var job IngestJob
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/documents/jobs/"+url.PathEscape(jobID)), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListIngestJobs lists the project's most recent ingestion jobs; limit 0
+// uses the server default
+func (c *Client) ListIngestJobs(ctx context.Context, projectID string, limit int) ([]IngestJob, error) {
+	path := projectPath(projectID, "/documents/jobs")
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var jobs []IngestJob
+	if err := c.getData(ctx, http.MethodGet, path, nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
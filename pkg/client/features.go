@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Feature flag evaluation reasons
+const (
+	FeatureReasonOverride = "override"
+	FeatureReasonPlan     = "plan"
+	FeatureReasonRollout  = "rollout"
+	FeatureReasonDefault  = "default"
+	FeatureReasonUnknown  = "unknown"
+)
+
+// FeatureFlag represents a feature flag definition
+type FeatureFlag struct {
+	Key         string          `json:"key"`
+	Description string          `json:"description,omitempty"`
+	Default     bool            `json:"default"`
+	Plans       map[string]bool `json:"plans,omitempty"`   // Plan name -> enabled
+	Rollout     int             `json:"rollout,omitempty"` // Percentage of tenants enabled, 0-100
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// FeatureFlagInput represents a feature flag definition update
+type FeatureFlagInput struct {
+	Description string          `json:"description,omitempty"`
+	Default     bool            `json:"default"`
+	Plans       map[string]bool `json:"plans,omitempty"`
+	Rollout     int             `json:"rollout,omitempty"`
+}
+
+// FeatureEvaluation represents the value of a flag for a tenant
+type FeatureEvaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+// ListFeatureFlags lists every defined feature flag
+func (c *Client) ListFeatureFlags(ctx context.Context) ([]FeatureFlag, error) {
+	var flags []FeatureFlag
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/features", nil, &flags); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// SaveFeatureFlag creates or replaces a feature flag definition
+func (c *Client) SaveFeatureFlag(ctx context.Context, key string, input *FeatureFlagInput) (*FeatureFlag, error) {
+	var flag FeatureFlag
+	if err := c.getData(ctx, http.MethodPut, "/admin/v1/features/"+url.PathEscape(key), input, &flag); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// DeleteFeatureFlag removes a stored feature flag definition
+func (c *Client) DeleteFeatureFlag(ctx context.Context, key string) error {
+	return c.getData(ctx, http.MethodDelete, "/admin/v1/features/"+url.PathEscape(key), nil, nil)
+}
+
+// EvaluateFeatures returns the value of every feature flag for a tenant
+func (c *Client) EvaluateFeatures(ctx context.Context, tenantID string) ([]FeatureEvaluation, error) {
+	var evaluations []FeatureEvaluation
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/tenants/"+url.PathEscape(tenantID)+"/features", nil, &evaluations); err != nil {
+		return nil, err
+	}
+	return evaluations, nil
+}
+
+// FeatureEnabled reports whether a feature flag is enabled for a tenant
+func (c *Client) FeatureEnabled(ctx context.Context, tenantID, key string) (bool, error) {
+	var evaluation FeatureEvaluation
+	path := "/admin/v1/tenants/" + url.PathEscape(tenantID) + "/features/" + url.PathEscape(key)
+	if err := c.getData(ctx, http.MethodGet, path, nil, &evaluation); err != nil {
+		return false, err
+	}
+	return evaluation.Enabled, nil
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Background job kinds
+const (
+	JobKindIngest = "ingest"
+	JobKindSync   = "sync"
+	JobKindBatch  = "batch"
+)
+
+// Job failure statuses
+const (
+	JobFailureDead      = "dead" // In the dead-letter queue
+	JobFailureRetried   = "retried"
+	JobFailureDiscarded = "discarded"
+)
+
+// JobQueue reports the state of one kind of background job. Jobs start as
+// soon as they are submitted: InFlight counts started jobs on all instances,
+// Running the jobs on the instance that answered.
+type JobQueue struct {
+	Kind      string `json:"kind"`
+	InFlight  int    `json:"in_flight"`
+	Running   int    `json:"running"`
+	Pending   int    `json:"pending,omitempty"` // Unanswered batch questions
+	Dead      int    `json:"dead"`
+	Failed24h int    `json:"failed_24h"`
+
+	Quarantined int `json:"quarantined,omitempty"` // Documents skipped by syncs after repeatedly failing
+}
+
+// JobWorker represents a job running on the instance that answered
+type JobWorker struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	JobID     string    `json:"job_id"`
+	ProjectID string    `json:"project_id"`
+	SourceID  string    `json:"source_id,omitempty"`
+	Stage     string    `json:"stage,omitempty"` // Ingestion stage or batch progress
+	StartedAt time.Time `json:"started_at"`
+}
+
+// JobsOverview summarizes the background job subsystem
+type JobsOverview struct {
+	Queues    []JobQueue  `json:"queues"`
+	Workers   []JobWorker `json:"workers"`
+	Scheduler struct {
+		Leader bool `json:"leader"` // The instance runs scheduled syncs and maintenance
+	} `json:"scheduler"`
+}
+
+// JobFailure represents a failed ingest or sync job. Failed jobs are not
+// retried automatically; they wait in the dead-letter queue until retried or
+// discarded.
+type JobFailure struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	JobID      string     `json:"job_id"`
+	ProjectID  string     `json:"project_id"`
+	SourceID   string     `json:"source_id,omitempty"`
+	Error      string     `json:"error"`
+	Trace      string     `json:"trace,omitempty"` // Error chain, completed stages or panic stack
+	Retryable  bool       `json:"retryable"`
+	Status     string     `json:"status"`
+	RetriedAs  string     `json:"retried_as,omitempty"`
+	FailedAt   time.Time  `json:"failed_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+}
+
+// JobFailureOptions filters job failures; zero values do not filter
+type JobFailureOptions struct {
+	Kind      string
+	ProjectID string
+	Status    string // Ignored when listing the dead-letter queue
+	Limit     int
+}
+
+func (o *JobFailureOptions) query() string {
+	if o == nil {
+		return ""
+	}
+	values := url.Values{}
+	if o.Kind != "" {
+		values.Set("kind", o.Kind)
+	}
+	if o.ProjectID != "" {
+		values.Set("project_id", o.ProjectID)
+	}
+	if o.Status != "" {
+		values.Set("status", o.Status)
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// JobRetry reports a retried job failure and the job it started: IngestJob
+// for ingest failures, SyncRun for sync failures
+type JobRetry struct {
+	Failure   JobFailure
+	IngestJob *IngestJob
+	SyncRun   *SyncRun
+}
+
+// GetJobsOverview retrieves queue depths, running workers and the scheduler
+// state (system admin only)
+func (c *Client) GetJobsOverview(ctx context.Context) (*JobsOverview, error) {
+	var overview JobsOverview
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/jobs", nil, &overview); err != nil {
+		return nil, err
+	}
+	return &overview, nil
+}
+
+// ListJobFailures lists failed jobs, most recent first
+func (c *Client) ListJobFailures(ctx context.Context, opts *JobFailureOptions) ([]JobFailure, error) {
+	var failures []JobFailure
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/jobs/failures"+opts.query(), nil, &failures); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// ListDeadLetters lists the jobs waiting in the dead-letter queue
+func (c *Client) ListDeadLetters(ctx context.Context, opts *JobFailureOptions) ([]JobFailure, error) {
+	var failures []JobFailure
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/jobs/dead-letter"+opts.query(), nil, &failures); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// GetJobFailure retrieves a failed job with its error trace
+func (c *Client) GetJobFailure(ctx context.Context, id string) (*JobFailure, error) {
+	var failure JobFailure
+	if err := c.getData(ctx, http.MethodGet, "/admin/v1/jobs/failures/"+url.PathEscape(id), nil, &failure); err != nil {
+		return nil, err
+	}
+	return &failure, nil
+}
+
+// RetryJob runs a job from the dead-letter queue again
+func (c *Client) RetryJob(ctx context.Context, id string) (*JobRetry, error) {
+	var resp struct {
+		Failure JobFailure      `json:"failure"`
+		Job     json.RawMessage `json:"job"`
+	}
+	if err := c.getData(ctx, http.MethodPost, "/admin/v1/jobs/failures/"+url.PathEscape(id)+"/retry", nil, &resp); err != nil {
+		return nil, err
+	}
+	retry := &JobRetry{Failure: resp.Failure}
+	var err error
+	switch resp.Failure.Kind {
+	case JobKindIngest:
+		retry.IngestJob = &IngestJob{}
+		err = json.Unmarshal(resp.Job, retry.IngestJob)
+	case JobKindSync:
+		retry.SyncRun = &SyncRun{}
+		err = json.Unmarshal(resp.Job, retry.SyncRun)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return retry, nil
+}
+
+// DiscardDeadLetter removes a job from the dead-letter queue without retrying it
+func (c *Client) DiscardDeadLetter(ctx context.Context, id string) error {
+	return c.getData(ctx, http.MethodDelete, "/admin/v1/jobs/dead-letter/"+url.PathEscape(id), nil, nil)
+}
+
+// PurgeDeadLetters discards every job in the dead-letter queue matching the
+// kind and project filters and returns how many were discarded
+func (c *Client) PurgeDeadLetters(ctx context.Context, opts *JobFailureOptions) (int, error) {
+	var resp struct {
+		Discarded int `json:"discarded"`
+	}
+	if err := c.getData(ctx, http.MethodDelete, "/admin/v1/jobs/dead-letter"+opts.query(), nil, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Discarded, nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// Targets of tenant metadata schemas
+const (
+	MetadataTargetProject  = "project"
+	MetadataTargetDocument = "document"
+)
+
+// MetadataSchema represents a tenant's JSON Schema for custom metadata
+type MetadataSchema struct {
+	Target string          `json:"target"`
+	Schema json.RawMessage `json:"schema"`
+	Fields []MetadataField `json:"fields"` // Declared fields usable as meta.<name> filters
+}
+
+// MetadataField represents a declared metadata field that can be filtered on
+type MetadataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ListMetadataSchemas lists a tenant's metadata schemas
+func (c *Client) ListMetadataSchemas(ctx context.Context, tenantID string) ([]MetadataSchema, error) {
+	var schemas []MetadataSchema
+	if err := c.getData(ctx, http.MethodGet, metadataSchemasPath(tenantID, ""), nil, &schemas); err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+// GetMetadataSchema returns a tenant's metadata schema for a target
+func (c *Client) GetMetadataSchema(ctx context.Context, tenantID, target string) (*MetadataSchema, error) {
+	var schema MetadataSchema
+	if err := c.getData(ctx, http.MethodGet, metadataSchemasPath(tenantID, target), nil, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// SaveMetadataSchema creates or replaces a tenant's metadata schema for a target
+func (c *Client) SaveMetadataSchema(ctx context.Context, tenantID, target string, schema json.RawMessage) (*MetadataSchema, error) {
+	var saved MetadataSchema
+	if err := c.getData(ctx, http.MethodPut, metadataSchemasPath(tenantID, target), schema, &saved); err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// DeleteMetadataSchema removes a tenant's metadata schema for a target
+func (c *Client) DeleteMetadataSchema(ctx context.Context, tenantID, target string) error {
+	return c.getData(ctx, http.MethodDelete, metadataSchemasPath(tenantID, target), nil, nil)
+}
+
+func metadataSchemasPath(tenantID, target string) string {
+	path := "/admin/v1/tenants/" + url.PathEscape(tenantID) + "/metadata-schemas"
+	if target != "" {
+		path += "/" + url.PathEscape(target)
+	}
+	return path
+}
@@ -0,0 +1,803 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// FilterExpr represents a structured RAG filter: either a combinator (And,
+// Or, Not) or a condition on a field
+type FilterExpr struct {
+	And []*FilterExpr `json:"and,omitempty"`
+	Or  []*FilterExpr `json:"or,omitempty"`
+	Not *FilterExpr   `json:"not,omitempty"`
+
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// RAGRetrievalOptions represents retrieval options; zero values use the
+// project's settings
+type RAGRetrievalOptions struct {
+	TopK                int     `json:"top_k,omitempty"`
+	SimilarityThreshold float64 `json:"similarity_threshold,omitempty"`
+	EnableHybridSearch  bool    `json:"enable_hybrid_search,omitempty"`
+	IncludeImages       bool    `json:"include_images,omitempty"`
+	EnableRerank        bool    `json:"enable_rerank,omitempty"`
+	RerankTopK          int     `json:"rerank_top_k,omitempty"`
+}
+
+// RAGGenerateOptions represents generation options; zero values use the
+// project's settings
+type RAGGenerateOptions struct {
+	Model           string  `json:"model,omitempty"`
+	Temperature     float64 `json:"temperature,omitempty"`
+	MaxTokens       int     `json:"max_tokens,omitempty"`
+	SystemPrompt    string  `json:"system_prompt,omitempty"`
+	PromptTemplate  string  `json:"prompt_template,omitempty"`
+	EnableCitations bool    `json:"enable_citations,omitempty"`
+	IncludeSummary  bool    `json:"include_summary,omitempty"`
+	Format          string  `json:"format,omitempty"`
+	EnableTools     bool    `json:"enable_tools,omitempty"`
+}
+
+// RAGQueryOptions represents RAG query options
+type RAGQueryOptions struct {
+	Retrieval *RAGRetrievalOptions `json:"retrieval,omitempty"`
+	Generate  *RAGGenerateOptions  `json:"generate,omitempty"`
+
+	EnableCache  bool `json:"enable_cache,omitempty"`
+	EnableRerank bool `json:"enable_rerank,omitempty"`
+
+	DataSourceIDs []string   `json:"data_source_ids,omitempty"`
+	DocumentIDs   []string   `json:"document_ids,omitempty"`
+	FileTypes     []string   `json:"file_types,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	DateRange     *DateRange `json:"date_range,omitempty"`
+	AsOf          *time.Time `json:"as_of,omitempty"` // Answer from the document versions current at this time
+
+	MaxResults int               `json:"max_results,omitempty"`
+	MinScore   float64           `json:"min_score,omitempty"`
+	Highlight  *HighlightOptions `json:"highlight,omitempty"`
+
+	SessionID string                 `json:"session_id,omitempty"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+}
+
+// HighlightOptions controls the matches and excerpts of retrieval results
+type HighlightOptions struct {
+	Disabled          bool    `json:"disabled,omitempty"`
+	ExcerptLength     int     `json:"excerpt_length,omitempty"` // Characters per excerpt, 200 by default
+	MaxExcerpts       int     `json:"max_excerpts,omitempty"`   // Excerpts per result, 2 by default
+	Semantic          bool    `json:"semantic,omitempty"`       // Also mark the sentence closest in meaning
+	SemanticThreshold float64 `json:"semantic_threshold,omitempty"`
+}
+
+// TextMatch is a highlighted span of a chunk. Positions are character
+// (Unicode code point) offsets within the chunk content.
+type TextMatch struct {
+	Text     string  `json:"text"`
+	StartPos int     `json:"start_pos"`
+	EndPos   int     `json:"end_pos"`
+	Score    float64 `json:"score"`
+	Type     string  `json:"type"` // exact, term or semantic
+}
+
+// Excerpt is a passage of a chunk around its best matches
+type Excerpt struct {
+	Text     string `json:"text"`
+	StartPos int    `json:"start_pos"`
+	EndPos   int    `json:"end_pos"`
+}
+
+// RetrievedChunk is the chunk of a retrieval result
+type RetrievedChunk struct {
+	ID         string `json:"id"`
+	DocumentID string `json:"document_id"`
+	Content    string `json:"content"`
+	ChunkIndex int    `json:"chunk_index"`
+}
+
+// RetrievalResult is a chunk retrieved for a query, with its highlights
+type RetrievalResult struct {
+	DocumentID string          `json:"document_id"`
+	Chunk      *RetrievedChunk `json:"chunk"`
+	Score      float64         `json:"score"`
+	Matches    []TextMatch     `json:"matches"`
+	Highlights []string        `json:"highlights"` // Excerpt texts, with ellipses where truncated
+	Excerpts   []Excerpt       `json:"excerpts,omitempty"`
+	ProjectID  string          `json:"project_id,omitempty"` // Set in federated queries
+}
+
+// RAGQueryRequest represents a RAG query
+type RAGQueryRequest struct {
+	Query string `json:"query"`
+
+	// Filter expression (e.g. "tag:go AND created>=2024-01-01") and/or
+	// structured filter; both apply when set
+	Filter     string      `json:"filter,omitempty"`
+	FilterExpr *FilterExpr `json:"filter_expr,omitempty"`
+
+	// Request a JSON answer conforming to this JSON Schema
+	OutputSchema map[string]interface{} `json:"output_schema,omitempty"`
+
+	Options RAGQueryOptions `json:"options"`
+}
+
+// Source represents a source citation
+type Source struct {
+	DocumentID    string  `json:"document_id"`
+	DocumentTitle string  `json:"document_title"`
+	DocumentURI   string  `json:"document_uri"`
+	ChunkID       string  `json:"chunk_id"`
+	Relevance     float64 `json:"relevance"`
+	Excerpt       string  `json:"excerpt"`
+	PageNumber    int     `json:"page_number,omitempty"`
+	ImageURI      string  `json:"image_uri,omitempty"`
+	SourceType    string  `json:"source_type,omitempty"`
+	DeepLink      string  `json:"deep_link,omitempty"`  // Opens the cited passage in its source system
+	ProjectID     string  `json:"project_id,omitempty"` // Set in federated queries
+}
+
+// StructuredOutput represents a validated JSON answer
+type StructuredOutput struct {
+	Data   json.RawMessage `json:"data,omitempty"`
+	Valid  bool            `json:"valid"`
+	Errors []string        `json:"errors,omitempty"`
+}
+
+// RAGQueryResult represents the result of a RAG query
+type RAGQueryResult struct {
+	QueryID string `json:"query_id"`
+	Query   string `json:"query"`
+
+	GeneratedResponse string   `json:"generated_response"`
+	GeneratedAnswer   string   `json:"generated_answer"`
+	GeneratedSummary  string   `json:"generated_summary"`
+	Sources           []Source `json:"sources"`
+
+	RetrievalResults []RetrievalResult `json:"retrieval_results"`
+	TotalRetrieved   int               `json:"total_retrieved"`
+	TotalReturned    int               `json:"total_returned"`
+
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalTokens  int     `json:"total_tokens"`
+	Cost         float64 `json:"cost"`
+
+	TotalTime time.Duration `json:"total_time"`
+	CacheHit  bool          `json:"cache_hit"`
+
+	StructuredOutput *StructuredOutput `json:"structured_output,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+
+	FederatedProjects []FederatedProjectResult `json:"federated_projects,omitempty"`
+}
+
+// FederatedQueryRequest represents a query across several projects
+type FederatedQueryRequest struct {
+	Query string `json:"query"`
+
+	// Projects to search; empty searches every project the caller can view
+	ProjectIDs []string `json:"project_ids,omitempty"`
+
+	// Most results one project contributes, defaults to an even share of max_results
+	PerProjectQuota int `json:"per_project_quota,omitempty"`
+
+	Filter     string          `json:"filter,omitempty"`
+	FilterExpr *FilterExpr     `json:"filter_expr,omitempty"`
+	Options    RAGQueryOptions `json:"options"`
+}
+
+// FederatedProjectResult reports one project's part in a federated query
+type FederatedProjectResult struct {
+	ProjectID string `json:"project_id"`
+	Retrieved int    `json:"retrieved"`
+	Returned  int    `json:"returned"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BatchQueryItem represents one question of a batch
+type BatchQueryItem struct {
+	ID    string `json:"id,omitempty"`
+	Query string `json:"query"`
+}
+
+// BatchQueryRequest represents a batch of questions answered with shared options
+type BatchQueryRequest struct {
+	Queries     []BatchQueryItem `json:"queries"`
+	Filter      string           `json:"filter,omitempty"`
+	FilterExpr  *FilterExpr      `json:"filter_expr,omitempty"`
+	Options     RAGQueryOptions  `json:"options"`
+	Concurrency int              `json:"concurrency,omitempty"`
+}
+
+// Batch job statuses
+const (
+	BatchStatusRunning   = "running"
+	BatchStatusCompleted = "completed"
+	BatchStatusCancelled = "cancelled"
+)
+
+// BatchJob represents the progress of a batch query job
+type BatchJob struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id,omitempty"`
+	Status      string    `json:"status"`
+	Concurrency int       `json:"concurrency"`
+	Total       int       `json:"total"`
+	Completed   int       `json:"completed"`
+	Failed      int       `json:"failed"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+// BatchResult represents the outcome of one question in a batch
+type BatchResult struct {
+	Index    int           `json:"index"`
+	ID       string        `json:"id,omitempty"`
+	Query    string        `json:"query"`
+	Answer   string        `json:"answer"`
+	Sources  []Source      `json:"sources"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// DataSource represents a project data source indexed into RAG
+type DataSource struct {
+	ID              string                 `json:"id"`
+	ProjectID       string                 `json:"project_id"`
+	Name            string                 `json:"name"`
+	Type            string                 `json:"type"`
+	Config          map[string]interface{} `json:"config"`
+	SecretRefs      map[string]string      `json:"secret_refs,omitempty"`
+	Schedule        string                 `json:"schedule,omitempty"`
+	IncludePatterns []string               `json:"include_patterns,omitempty"`
+	ExcludePatterns []string               `json:"exclude_patterns,omitempty"`
+	Enabled         bool                   `json:"enabled"`
+	CreatedBy       string                 `json:"created_by,omitempty"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+// DataSourceInput represents a data source create or update request
+type DataSourceInput struct {
+	Name            string                 `json:"name" yaml:"name"`
+	Type            string                 `json:"type" yaml:"type"`
+	Config          map[string]interface{} `json:"config" yaml:"config"`
+	SecretRefs      map[string]string      `json:"secret_refs,omitempty" yaml:"secret_refs"`
+	Schedule        string                 `json:"schedule,omitempty" yaml:"schedule"` // Cron expression
+	IncludePatterns []string               `json:"include_patterns,omitempty" yaml:"include_patterns"`
+	ExcludePatterns []string               `json:"exclude_patterns,omitempty" yaml:"exclude_patterns"`
+	Enabled         *bool                  `json:"enabled,omitempty" yaml:"enabled"` // Unchanged on update when nil
+}
+
+// BotChannel represents a Slack or Discord channel bound to a project; the
+// bot answers questions asked in the channel from the project's RAG index
+type BotChannel struct {
+	Platform     string    `json:"platform"` // slack or discord
+	ChannelID    string    `json:"channel_id"`
+	ProjectID    string    `json:"project_id"`
+	TenantID     string    `json:"tenant_id,omitempty"`
+	Filter       string    `json:"filter,omitempty"`
+	CitationURL  string    `json:"citation_url,omitempty"` // Template with {uri}, {document_id} and {title}
+	MaxCitations int       `json:"max_citations,omitempty"`
+	Enabled      bool      `json:"enabled"`
+	CreatedBy    string    `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// BotChannelInput represents the settings of a channel binding; nil fields
+// are unchanged on update
+type BotChannelInput struct {
+	Filter       *string `json:"filter,omitempty"`
+	CitationURL  *string `json:"citation_url,omitempty"`
+	MaxCitations *int    `json:"max_citations,omitempty"`
+	Enabled      *bool   `json:"enabled,omitempty"`
+}
+
+// WidgetToken represents a publishable token that lets a browser widget run
+// read-only RAG queries against a project from the allowed origins
+type WidgetToken struct {
+	ID             string    `json:"id"`
+	ProjectID      string    `json:"project_id"`
+	TenantID       string    `json:"tenant_id,omitempty"`
+	Name           string    `json:"name"`
+	Prefix         string    `json:"prefix"`
+	Token          string    `json:"token,omitempty"`      // Only returned on creation
+	AllowedOrigins []string  `json:"allowed_origins"`      // e.g. https://docs.example.com or https://*.example.com
+	RateLimit      int       `json:"rate_limit,omitempty"` // Queries per minute, default 30
+	RequireCaptcha bool      `json:"require_captcha"`      // Every query must carry a CAPTCHA response
+	Filter         string    `json:"filter,omitempty"`     // Limits the documents the widget can search
+	Enabled        bool      `json:"enabled"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// WidgetTokenInput represents the settings of a widget token; nil fields are
+// unchanged on update
+type WidgetTokenInput struct {
+	Name           *string   `json:"name,omitempty"`
+	AllowedOrigins *[]string `json:"allowed_origins,omitempty"`
+	RateLimit      *int      `json:"rate_limit,omitempty"`
+	RequireCaptcha *bool     `json:"require_captcha,omitempty"`
+	Filter         *string   `json:"filter,omitempty"`
+	Enabled        *bool     `json:"enabled,omitempty"`
+}
+
+// IndexResult represents the statistics of an indexing run
+type IndexResult struct {
+	DocumentsProcessed   int           `json:"documents_processed"`
+	DocumentsUpdated     int           `json:"documents_updated"`
+	DocumentsAdded       int           `json:"documents_added"`
+	DocumentsSkipped     int           `json:"documents_skipped"`
+	DocumentsErrored     int           `json:"documents_errored"`
+	DocumentsDuplicate   int           `json:"documents_duplicate"`             // Skipped as duplicates of indexed documents
+	DocumentsQuarantined int           `json:"documents_quarantined,omitempty"` // Skipped after repeatedly failing
+	ChunksCreated        int           `json:"chunks_created"`
+	ChunksUpdated        int           `json:"chunks_updated"`
+	ChunksDeleted        int           `json:"chunks_deleted"`
+	TotalTime            time.Duration `json:"total_time"`
+	Errors               []string      `json:"errors,omitempty"`
+
+	Duplicates []DuplicateDocument `json:"duplicates,omitempty"`
+	Swap       *IndexSwapReport    `json:"swap,omitempty"` // Set by ReindexDataSource
+}
+
+// Index swap outcomes
+const (
+	IndexSwapSwapped  = "swapped"
+	IndexSwapRejected = "rejected"
+	IndexSwapFailed   = "failed"
+)
+
+// IndexSwapReport describes how a rebuilt index was validated before it
+// replaced the serving one
+type IndexSwapReport struct {
+	Status           string     `json:"status"`
+	CurrentDocuments int        `json:"current_documents"`
+	RebuiltDocuments int        `json:"rebuilt_documents"`
+	RebuiltChunks    int        `json:"rebuilt_chunks"`
+	CopiedChunks     int        `json:"copied_chunks"`
+	MeanOverlap      float64    `json:"mean_overlap"`
+	Reason           string     `json:"reason,omitempty"` // Why the rebuilt index was rejected
+	DocumentsRemoved int        `json:"documents_removed"`
+	ChunksCollected  int        `json:"chunks_collected"`
+	SwappedAt        *time.Time `json:"swapped_at,omitempty"`
+
+	Comparisons []struct {
+		Query            string   `json:"query"`
+		CurrentDocuments []string `json:"current_documents"`
+		RebuiltDocuments []string `json:"rebuilt_documents"`
+		Overlap          float64  `json:"overlap"`
+	} `json:"comparisons,omitempty"`
+}
+
+// DuplicateDocument represents a document linked to an indexed document with
+// the same content instead of being processed again
+type DuplicateDocument struct {
+	DocumentID  string `json:"document_id"`
+	URI         string `json:"uri,omitempty"`
+	DuplicateOf string `json:"duplicate_of"`
+}
+
+// SyncRun represents one sync (indexing run) of a data source
+type SyncRun struct {
+	ID          string       `json:"id"`
+	SourceID    string       `json:"source_id"`
+	Trigger     string       `json:"trigger"` // manual, scheduled, reindex or retry
+	Status      string       `json:"status"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+	Result      *IndexResult `json:"result,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	TriggeredBy string       `json:"triggered_by,omitempty"`
+}
+
+// DocumentFailure records the failed sync attempts of a data source
+// document. Once Attempts reaches the limit the document is quarantined:
+// syncs skip it until its content changes or it is requeued.
+type DocumentFailure struct {
+	DocumentID    string     `json:"document_id"`
+	ProjectID     string     `json:"project_id,omitempty"`
+	DataSourceID  string     `json:"data_source_id"`
+	Title         string     `json:"title,omitempty"`
+	URI           string     `json:"uri,omitempty"`
+	ContentHash   string     `json:"content_hash"`
+	Error         string     `json:"error"`
+	Trace         string     `json:"trace,omitempty"` // Panic stack when processing crashed
+	Attempts      int        `json:"attempts"`
+	FirstFailedAt time.Time  `json:"first_failed_at"`
+	LastFailedAt  time.Time  `json:"last_failed_at"`
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+}
+
+// DocumentRequeue reports a requeued document. Result is nil when the data
+// source is not attached and the document is left to its next sync; Failure
+// is set when indexing failed again.
+type DocumentRequeue struct {
+	DocumentID string           `json:"document_id"`
+	Result     *IndexResult     `json:"result,omitempty"`
+	Failure    *DocumentFailure `json:"failure,omitempty"`
+}
+
+// DataSourceStatus represents the sync status of a data source
+type DataSourceStatus struct {
+	SourceID      string     `json:"source_id"`
+	Enabled       bool       `json:"enabled"`
+	Attached      bool       `json:"attached"`
+	Running       bool       `json:"running"`
+	Schedule      string     `json:"schedule,omitempty"`
+	NextRunAt     *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastRun       *SyncRun   `json:"last_run,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+// ConnectionTestResult represents the outcome of a data source connection test
+type ConnectionTestResult struct {
+	OK      bool          `json:"ok"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// Tombstone represents a soft-deleted document awaiting purge
+type Tombstone struct {
+	DocumentID   string    `json:"document_id"`
+	DataSourceID string    `json:"data_source_id,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	DeletedBy    string    `json:"deleted_by,omitempty"`
+	DeletedAt    time.Time `json:"deleted_at"`
+	PurgeAfter   time.Time `json:"purge_after"`
+	ChunkCount   int       `json:"chunk_count"`
+}
+
+// RAGQuery answers a question from the project's indexed documents
+func (c *Client) RAGQuery(ctx context.Context, projectID string, req *RAGQueryRequest) (*RAGQueryResult, error) {
+	var result RAGQueryResult
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/rag/query"), req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FederatedQuery answers a question from several projects' indexes. Sources
+// and retrieval results carry the project they came from.
+func (c *Client) FederatedQuery(ctx context.Context, req *FederatedQueryRequest) (*RAGQueryResult, error) {
+	var result RAGQueryResult
+	if err := c.getData(ctx, http.MethodPost, "/admin/v1/federation/query", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// StartBatch starts a background job answering every question in the batch
+func (c *Client) StartBatch(ctx context.Context, projectID string, req *BatchQueryRequest) (*BatchJob, error) {
+	var job BatchJob
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/rag/batch"), req, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetBatch retrieves the progress of a batch job
+func (c *Client) GetBatch(ctx context.Context, projectID, jobID string) (*BatchJob, error) {
+	var job BatchJob
+	if err := c.getData(ctx, http.MethodGet, batchPath(projectID, jobID, ""), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CancelBatch cancels a running batch job
+func (c *Client) CancelBatch(ctx context.Context, projectID, jobID string) error {
+	return c.getJSON(ctx, http.MethodDelete, batchPath(projectID, jobID, ""), nil, nil)
+}
+
+// WaitBatch polls a batch job every interval until it is no longer running
+func (c *Client) WaitBatch(ctx context.Context, projectID, jobID string, interval time.Duration) (*BatchJob, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := c.GetBatch(ctx, projectID, jobID)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != BatchStatusRunning {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetRAGSettings returns the project's RAG setting overrides: retrieval,
+// chunking, hooks and retention
+func (c *Client) GetRAGSettings(ctx context.Context, projectID string) (map[string]interface{}, error) {
+	var settings struct {
+		Overrides map[string]interface{} `json:"overrides"`
+	}
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/rag/settings"), nil, &settings); err != nil {
+		return nil, err
+	}
+	return settings.Overrides, nil
+}
+
+// SaveRAGSettings replaces the project's RAG setting overrides
+func (c *Client) SaveRAGSettings(ctx context.Context, projectID string, overrides map[string]interface{}) error {
+	return c.getData(ctx, http.MethodPut, projectPath(projectID, "/rag/settings"), overrides, nil)
+}
+
+// ListDataSources lists the project's data sources
+func (c *Client) ListDataSources(ctx context.Context, projectID string) ([]DataSource, error) {
+	var sources []DataSource
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/rag/datasources"), nil, &sources); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// GetDataSource retrieves a data source
+func (c *Client) GetDataSource(ctx context.Context, projectID, sourceID string) (*DataSource, error) {
+	var source DataSource
+	if err := c.getData(ctx, http.MethodGet, dataSourcePath(projectID, sourceID, ""), nil, &source); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// CreateDataSource creates a data source; it is indexed on its schedule or by SyncDataSource
+func (c *Client) CreateDataSource(ctx context.Context, projectID string, input *DataSourceInput) (*DataSource, error) {
+	var source DataSource
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/rag/datasources"), input, &source); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// UpdateDataSource replaces a data source's definition
+func (c *Client) UpdateDataSource(ctx context.Context, projectID, sourceID string, input *DataSourceInput) (*DataSource, error) {
+	var source DataSource
+	if err := c.getData(ctx, http.MethodPut, dataSourcePath(projectID, sourceID, ""), input, &source); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// DeleteDataSource deletes a data source and soft-deletes its documents
+func (c *Client) DeleteDataSource(ctx context.Context, projectID, sourceID string) error {
+	return c.getJSON(ctx, http.MethodDelete, dataSourcePath(projectID, sourceID, ""), nil, nil)
+}
+
+// TestDataSource checks that a saved data source can be reached
+func (c *Client) TestDataSource(ctx context.Context, projectID, sourceID string) (*ConnectionTestResult, error) {
+	var result ConnectionTestResult
+	if err := c.getData(ctx, http.MethodPost, dataSourcePath(projectID, sourceID, "/test"), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SyncDataSource starts indexing a data source in the background
+func (c *Client) SyncDataSource(ctx context.Context, projectID, sourceID string) (*SyncRun, error) {
+	var run SyncRun
+	if err := c.getData(ctx, http.MethodPost, dataSourcePath(projectID, sourceID, "/sync"), nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ReindexDataSource rebuilds the index of every document of a data source in
+// the background. The rebuild is built beside the serving index and replaces
+// it only after validation; the current index is snapshotted first.
+func (c *Client) ReindexDataSource(ctx context.Context, projectID, sourceID string) (*SyncRun, error) {
+	var run SyncRun
+	if err := c.getData(ctx, http.MethodPost, dataSourcePath(projectID, sourceID, "/sync?force=true"), nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// DataSourceStatus retrieves the sync status of a data source
+func (c *Client) DataSourceStatus(ctx context.Context, projectID, sourceID string) (*DataSourceStatus, error) {
+	var status DataSourceStatus
+	if err := c.getData(ctx, http.MethodGet, dataSourcePath(projectID, sourceID, "/status"), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// ListQuarantinedDocuments lists the documents of a project that syncs skip
+// after repeatedly failing, most recent first. sourceID filters by data
+// source when not empty; includeFailing also lists documents that failed but
+// have attempts left.
+func (c *Client) ListQuarantinedDocuments(ctx context.Context, projectID, sourceID string, includeFailing bool) ([]DocumentFailure, error) {
+	query := url.Values{}
+	if sourceID != "" {
+		query.Set("source_id", sourceID)
+	}
+	if includeFailing {
+		query.Set("all", "true")
+	}
+	path := projectPath(projectID, "/rag/quarantine")
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var failures []DocumentFailure
+	if err := c.getData(ctx, http.MethodGet, path, nil, &failures); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// RequeueDocument releases a document from quarantine with a fresh set of
+// attempts and indexes it again
+func (c *Client) RequeueDocument(ctx context.Context, projectID, documentID string) (*DocumentRequeue, error) {
+	var requeue DocumentRequeue
+	path := projectPath(projectID, "/rag/quarantine/"+url.PathEscape(documentID)+"/requeue")
+	if err := c.getData(ctx, http.MethodPost, path, nil, &requeue); err != nil {
+		return nil, err
+	}
+	return &requeue, nil
+}
+
+// ListSyncRuns lists the most recent syncs of a data source, newest first
+func (c *Client) ListSyncRuns(ctx context.Context, projectID, sourceID string, limit int) ([]SyncRun, error) {
+	path := dataSourcePath(projectID, sourceID, "/syncs")
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var runs []SyncRun
+	if err := c.getData(ctx, http.MethodGet, path, nil, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// DeleteDocument soft-deletes a document; it is purged after the retention period
+func (c *Client) DeleteDocument(ctx context.Context, projectID, documentID, reason string) (*Tombstone, error) {
+	var body interface{}
+	if reason != "" {
+		body = map[string]string{"reason": reason}
+	}
+	var tombstone Tombstone
+	path := projectPath(projectID, "/rag/documents/"+url.PathEscape(documentID))
+	if err := c.getData(ctx, http.MethodDelete, path, body, &tombstone); err != nil {
+		return nil, err
+	}
+	return &tombstone, nil
+}
+
+// RestoreDocument restores a soft-deleted document
+func (c *Client) RestoreDocument(ctx context.Context, projectID, documentID string) error {
+	path := projectPath(projectID, "/rag/documents/"+url.PathEscape(documentID)+"/restore")
+	return c.getJSON(ctx, http.MethodPost, path, nil, nil)
+}
+
+// EmbeddingOverride selects the embedding model used to reprocess a document
+type EmbeddingOverride struct {
+	Model    string `json:"model"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// ReprocessOptions overrides how a document is re-chunked and re-embedded;
+// nil fields keep the project's configuration
+type ReprocessOptions struct {
+	Chunking  *ChunkingConfig    `json:"chunking,omitempty"`
+	Embedding *EmbeddingOverride `json:"embedding,omitempty"` // Must match the index or a running re-embedding target
+}
+
+// ReprocessResult reports the chunks replaced by reprocessing a document
+type ReprocessResult struct {
+	DocumentID          string          `json:"document_id"`
+	Strategy            string          `json:"strategy,omitempty"`
+	Chunking            *ChunkingConfig `json:"chunking,omitempty"`
+	IndexVersion        string          `json:"index_version"`
+	ChunksRemoved       int             `json:"chunks_removed"`
+	ChunksCreated       int             `json:"chunks_created"`
+	EmbeddingsGenerated int             `json:"embeddings_generated"`
+	Errors              []string        `json:"errors,omitempty"`
+	TotalTime           time.Duration   `json:"total_time"`
+}
+
+// ReprocessDocument re-chunks and re-embeds a single document without a full
+// reindex. The new chunks replace the old ones only once they are built.
+func (c *Client) ReprocessDocument(ctx context.Context, projectID, documentID string, opts *ReprocessOptions) (*ReprocessResult, error) {
+	var result ReprocessResult
+	path := projectPath(projectID, "/documents/"+url.PathEscape(documentID)+"/reprocess")
+	if err := c.getData(ctx, http.MethodPost, path, opts, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListBotChannels lists the chat channels bound to the project
+func (c *Client) ListBotChannels(ctx context.Context, projectID string) ([]BotChannel, error) {
+	var channels []BotChannel
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/rag/bots/channels"), nil, &channels); err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// SetBotChannel binds a chat channel to the project or updates its settings.
+// A channel can only be bound to one project.
+func (c *Client) SetBotChannel(ctx context.Context, projectID, platform, channelID string, input *BotChannelInput) (*BotChannel, error) {
+	if input == nil {
+		input = &BotChannelInput{}
+	}
+	var channel BotChannel
+	if err := c.getData(ctx, http.MethodPut, botChannelPath(projectID, platform, channelID), input, &channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// DeleteBotChannel unbinds a chat channel from the project
+func (c *Client) DeleteBotChannel(ctx context.Context, projectID, platform, channelID string) error {
+	return c.getJSON(ctx, http.MethodDelete, botChannelPath(projectID, platform, channelID), nil, nil)
+}
+
+// ListWidgetTokens lists the project's widget tokens; token values are not
+// included
+func (c *Client) ListWidgetTokens(ctx context.Context, projectID string) ([]WidgetToken, error) {
+	var tokens []WidgetToken
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/rag/widget-tokens"), nil, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// CreateWidgetToken creates a publishable widget token. The token value is
+// only returned here and cannot be retrieved later.
+func (c *Client) CreateWidgetToken(ctx context.Context, projectID string, input *WidgetTokenInput) (*WidgetToken, error) {
+	var token WidgetToken
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/rag/widget-tokens"), input, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UpdateWidgetToken updates a widget token's settings
+func (c *Client) UpdateWidgetToken(ctx context.Context, projectID, tokenID string, input *WidgetTokenInput) (*WidgetToken, error) {
+	var token WidgetToken
+	if err := c.getData(ctx, http.MethodPut, widgetTokenPath(projectID, tokenID), input, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// DeleteWidgetToken revokes a widget token
+func (c *Client) DeleteWidgetToken(ctx context.Context, projectID, tokenID string) error {
+	return c.getJSON(ctx, http.MethodDelete, widgetTokenPath(projectID, tokenID), nil, nil)
+}
+
+func batchPath(projectID, jobID, suffix string) string {
+	return projectPath(projectID, "/rag/batch/"+url.PathEscape(jobID)+suffix)
+}
+
+func dataSourcePath(projectID, sourceID, suffix string) string {
+	return projectPath(projectID, "/rag/datasources/"+url.PathEscape(sourceID)+suffix)
+}
+
+func botChannelPath(projectID, platform, channelID string) string {
+	return projectPath(projectID, "/rag/bots/"+url.PathEscape(platform)+"/channels/"+url.PathEscape(channelID))
+}
+
+func widgetTokenPath(projectID, tokenID string) string {
+	return projectPath(projectID, "/rag/widget-tokens/"+url.PathEscape(tokenID))
+}
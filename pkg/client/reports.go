@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Report formats accepted by GetReportContent
+const (
+	ReportFormatMarkdown = "markdown"
+	ReportFormatHTML     = "html"
+)
+
+// Report is a generated tenant usage and quality digest
+type Report struct {
+	ID          string           `json:"id"`
+	TenantID    string           `json:"tenant_id"`
+	Trigger     string           `json:"trigger"` // scheduled or manual
+	PeriodStart time.Time        `json:"period_start"`
+	PeriodEnd   time.Time        `json:"period_end"` // Exclusive
+	Digest      *ReportDigest    `json:"digest"`
+	Markdown    string           `json:"markdown,omitempty"`
+	HTML        string           `json:"html,omitempty"`
+	Deliveries  []ReportDelivery `json:"deliveries,omitempty"`
+	CreatedBy   string           `json:"created_by,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// ReportDelivery is the result of sending a report to one target
+type ReportDelivery struct {
+	Channel string `json:"channel"` // email or webhook
+	Target  string `json:"target"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ReportDigest holds the figures a report is rendered from
+type ReportDigest struct {
+	TenantID    string    `json:"tenant_id"`
+	TenantName  string    `json:"tenant_name"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	API         struct {
+		Requests         int64   `json:"requests"`
+		Errors           int64   `json:"errors"`
+		ErrorRate        float64 `json:"error_rate"`
+		PreviousRequests int64   `json:"previous_requests"`
+	} `json:"api"`
+	RAG struct {
+		Queries         int64 `json:"queries"`
+		PreviousQueries int64 `json:"previous_queries"`
+		TopQuestions    []struct {
+			Question string `json:"question"`
+			Count    int64  `json:"count"`
+		} `json:"top_questions"`
+	} `json:"rag"`
+	Quality *struct {
+		OverallScore float64            `json:"overall_score"`
+		Metrics      map[string]float64 `json:"metrics,omitempty"`
+		Change       *float64           `json:"change,omitempty"`
+	} `json:"quality,omitempty"`
+	Security *struct {
+		Total    int  `json:"total_vulnerabilities"`
+		Critical int  `json:"critical"`
+		High     int  `json:"high"`
+		Medium   int  `json:"medium"`
+		Low      int  `json:"low"`
+		Change   *int `json:"change,omitempty"`
+	} `json:"security,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func reportsPath(tenantID string) string {
+	return "/admin/v1/tenants/" + url.PathEscape(tenantID) + "/reports"
+}
+
+// ListReports lists a tenant's most recent reports without their rendered bodies
+func (c *Client) ListReports(ctx context.Context, tenantID string, limit int) ([]Report, error) {
+	path := reportsPath(tenantID)
+	if limit > 0 {
+		path += "?limit=" + strconv.Itoa(limit)
+	}
+	var reports []Report
+	if err := c.getData(ctx, http.MethodGet, path, nil, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// GenerateReport generates a report for the period ending today, sending it to
+// the tenant's notification targets when deliver is set
+func (c *Client) GenerateReport(ctx context.Context, tenantID string, deliver bool) (*Report, error) {
+	var report Report
+	body := map[string]bool{"deliver": deliver}
+	if err := c.getData(ctx, http.MethodPost, reportsPath(tenantID), body, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetReport retrieves a report
+func (c *Client) GetReport(ctx context.Context, tenantID, id string) (*Report, error) {
+	var report Report
+	if err := c.getData(ctx, http.MethodGet, reportsPath(tenantID)+"/"+url.PathEscape(id), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetReportContent retrieves a report's rendered body in the given format
+func (c *Client) GetReportContent(ctx context.Context, tenantID, id, format string) (string, error) {
+	path := reportsPath(tenantID) + "/" + url.PathEscape(id) + "?format=" + url.QueryEscape(format)
+	result, err := c.makeRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Snapshot reasons
+const (
+	SnapshotManual     = "manual"
+	SnapshotPreReindex = "pre_reindex"
+	SnapshotPreRestore = "pre_restore"
+)
+
+// IndexSnapshot is a saved copy of a project's chunks and embeddings
+type IndexSnapshot struct {
+	ID                string    `json:"id"`
+	ProjectID         string    `json:"project_id"`
+	Name              string    `json:"name"`
+	Reason            string    `json:"reason"` // manual, pre_reindex or pre_restore
+	DataSourceIDs     []string  `json:"data_source_ids"`
+	ConfigFingerprint string    `json:"config_fingerprint"`
+	IndexVersion      string    `json:"index_version"`
+	DocumentCount     int       `json:"document_count"`
+	ChunkCount        int       `json:"chunk_count"`
+	EmbeddingCount    int       `json:"embedding_count"`
+	CreatedBy         string    `json:"created_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// SnapshotRestoreResult reports what restoring a snapshot replaced
+type SnapshotRestoreResult struct {
+	SnapshotID        string   `json:"snapshot_id"`
+	BackupSnapshotID  string   `json:"backup_snapshot_id,omitempty"`
+	DocumentsRemoved  int      `json:"documents_removed"`
+	DocumentsRestored int      `json:"documents_restored"`
+	ChunksRestored    int      `json:"chunks_restored"`
+	ConfigChanged     bool     `json:"config_changed"`
+	Errors            []string `json:"errors,omitempty"`
+}
+
+func snapshotPath(projectID, snapshotID, suffix string) string {
+	return projectPath(projectID, "/rag/snapshots/"+url.PathEscape(snapshotID)+suffix)
+}
+
+// ListSnapshots lists a project's index snapshots, newest first
+func (c *Client) ListSnapshots(ctx context.Context, projectID string) ([]IndexSnapshot, error) {
+	var snapshots []IndexSnapshot
+	if err := c.getData(ctx, http.MethodGet, projectPath(projectID, "/rag/snapshots"), nil, &snapshots); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// CreateSnapshot snapshots the chunks and embeddings of all the project's data sources
+func (c *Client) CreateSnapshot(ctx context.Context, projectID, name string) (*IndexSnapshot, error) {
+	var snapshot IndexSnapshot
+	body := map[string]string{"name": name}
+	if err := c.getData(ctx, http.MethodPost, projectPath(projectID, "/rag/snapshots"), body, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// RestoreSnapshot replaces the project's index with a snapshot. The server
+// refuses snapshots of another embedding model unless force is set.
+func (c *Client) RestoreSnapshot(ctx context.Context, projectID, snapshotID string, force bool) (*SnapshotRestoreResult, error) {
+	var result SnapshotRestoreResult
+	body := map[string]bool{"force": force}
+	if err := c.getData(ctx, http.MethodPost, snapshotPath(projectID, snapshotID, "/restore"), body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteSnapshot deletes an index snapshot
+func (c *Client) DeleteSnapshot(ctx context.Context, projectID, snapshotID string) error {
+	return c.getJSON(ctx, http.MethodDelete, snapshotPath(projectID, snapshotID, ""), nil, nil)
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// cancelGracePeriod bounds how long StreamQuery waits for the server to
+// acknowledge a cancellation before closing the connection
+const cancelGracePeriod = 5 * time.Second
+
+// ErrQueryCancelled is returned by StreamQuery when the server cancelled the query
+var ErrQueryCancelled = errors.New("query cancelled")
+
+// StreamHandler receives the partial output of a streamed RAG query
+type StreamHandler struct {
+	// OnCitations is called with the retrieved sources before generation starts
+	OnCitations func(sources []Source)
+
+	// OnToken is called with each piece of the answer as it is generated;
+	// returning an error cancels the query. Not called for queries that
+	// require moderation or structured output.
+	OnToken func(token string) error
+}
+
+// chatMessage is a message of the RAG chat WebSocket protocol
+type chatMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Content string          `json:"content,omitempty"`
+	Sources []Source        `json:"sources,omitempty"`
+	Result  *RAGQueryResult `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Code    string          `json:"code,omitempty"`
+}
+
+// StreamQuery runs a RAG query over the chat WebSocket, delivering citations
+// and tokens to handler as they arrive, and returns the final result.
+// Cancelling ctx cancels the query on the server, which aborts generation.
+func (c *Client) StreamQuery(ctx context.Context, projectID string, req *RAGQueryRequest, handler *StreamHandler) (*RAGQueryResult, error) {
+	conn, err := c.dialChat(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if handler == nil {
+		handler = &StreamHandler{}
+	}
+
+	const queryID = "1"
+	var writeMu sync.Mutex
+	write := func(msg interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(msg)
+	}
+	cancel := func() {
+		write(chatMessage{Type: "cancel", ID: queryID})
+		conn.SetReadDeadline(time.Now().Add(cancelGracePeriod))
+	}
+
+	err = write(struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		*RAGQueryRequest
+	}{"query", queryID, req})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+
+	// Cancel the query on the server when ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-done:
+		}
+	}()
+
+	var handlerErr error
+	for {
+		var msg chatMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to read message: %w", err)
+		}
+		if msg.ID != queryID && msg.Type != "error" {
+			continue
+		}
+
+		switch msg.Type {
+		case "citations":
+			if handler.OnCitations != nil {
+				handler.OnCitations(msg.Sources)
+			}
+		case "token":
+			if handler.OnToken != nil && handlerErr == nil {
+				if handlerErr = handler.OnToken(msg.Content); handlerErr != nil {
+					cancel()
+				}
+			}
+		case "done":
+			return msg.Result, nil
+		case "cancelled":
+			if handlerErr != nil {
+				return nil, handlerErr
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, ErrQueryCancelled
+		case "error":
+			if handlerErr != nil {
+				return nil, handlerErr
+			}
+			return nil, &APIError{Code: msg.Code, Message: msg.Error}
+		}
+	}
+}
+
+// dialChat opens the project's RAG chat WebSocket with the client's credentials
+func (c *Client) dialChat(ctx context.Context, projectID string) (*websocket.Conn, error) {
+	endpoint := c.config.URL + projectPath(projectID, "/rag/chat")
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		endpoint = "wss://" + strings.TrimPrefix(endpoint, "https://")
+	case strings.HasPrefix(endpoint, "http://"):
+		endpoint = "ws://" + strings.TrimPrefix(endpoint, "http://")
+	}
+
+	// Reuse the header logic of regular requests
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	c.setAuthHeader(req)
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, endpoint, req.Header)
+	if err != nil {
+		if resp != nil && resp.StatusCode >= 400 {
+			defer resp.Body.Close()
+			return nil, c.handleAPIError(resp)
+		}
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	return conn, nil
+}
+
+// BatchResults streams the results of a finished batch job one at a time,
+// without holding the whole result file in memory
+func (c *Client) BatchResults(ctx context.Context, projectID, jobID string) iter.Seq2[BatchResult, error] {
+	return func(yield func(BatchResult, error) bool) {
+		resp, err := c.send(ctx, http.MethodGet, batchPath(projectID, jobID, "/results?format=jsonl"), nil)
+		if err != nil {
+			yield(BatchResult{}, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var result BatchResult
+			if err := json.Unmarshal(line, &result); err != nil {
+				yield(BatchResult{}, fmt.Errorf("failed to unmarshal result: %w", err))
+				return
+			}
+			if !yield(result, nil) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(BatchResult{}, fmt.Errorf("failed to read results: %w", err))
+		}
+	}
+}
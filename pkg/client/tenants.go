@@ -0,0 +1,398 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Tenant represents a tenant
+type Tenant struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Slug        string                 `json:"slug"`
+	Domain      string                 `json:"domain,omitempty"`
+	Logo        string                 `json:"logo,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Settings    map[string]interface{} `json:"settings,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	IsActive    bool                   `json:"is_active"`
+	Plan        string                 `json:"plan"`
+	Limits      map[string]interface{} `json:"limits,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// TenantInput represents the fields of a tenant create or update request
+type TenantInput struct {
+	Name        string                 `json:"name,omitempty"`
+	Slug        string                 `json:"slug,omitempty"`
+	Domain      string                 `json:"domain,omitempty"`
+	Logo        string                 `json:"logo,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Settings    map[string]interface{} `json:"settings,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Plan        string                 `json:"plan,omitempty"`
+}
+
+// Project represents a project. The membership fields are set when listing
+// the current user's projects.
+type Project struct {
+	ID          string                 `json:"id"`
+	TenantID    string                 `json:"tenant_id"`
+	Name        string                 `json:"name"`
+	Slug        string                 `json:"slug"`
+	Description string                 `json:"description,omitempty"`
+	Logo        string                 `json:"logo,omitempty"`
+	Settings    map[string]interface{} `json:"settings,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	IsActive    bool                   `json:"is_active"`
+	IsPublic    bool                   `json:"is_public"`
+	Environment string                 `json:"environment"`
+	OwnerID     string                 `json:"owner_id"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+
+	UserRole               string    `json:"user_role,omitempty"`
+	IsCreator              bool      `json:"is_creator,omitempty"`
+	IsExternalCollaborator bool      `json:"is_external_collaborator,omitempty"`
+	CanInvite              bool      `json:"can_invite,omitempty"`
+	CanManageMembers       bool      `json:"can_manage_members,omitempty"`
+	JoinedAt               time.Time `json:"joined_at,omitempty"`
+}
+
+// ProjectInput represents the fields of a project create or update request
+type ProjectInput struct {
+	Name        string                 `json:"name,omitempty"`
+	Slug        string                 `json:"slug,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Logo        string                 `json:"logo,omitempty"`
+	Settings    map[string]interface{} `json:"settings,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	IsPublic    bool                   `json:"is_public,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+}
+
+// ProjectMember represents a user's membership in a project
+type ProjectMember struct {
+	ID                     string     `json:"id"`
+	UserID                 string     `json:"user_id"`
+	TenantID               string     `json:"tenant_id"`
+	ProjectID              string     `json:"project_id"`
+	Role                   string     `json:"effective_role"`
+	IsActive               bool       `json:"is_active"`
+	JoinedAt               time.Time  `json:"joined_at"`
+	LeftAt                 *time.Time `json:"left_at,omitempty"`
+	IsCreator              bool       `json:"is_creator"`
+	InvitedBy              string     `json:"invited_by,omitempty"`
+	IsExternalCollaborator bool       `json:"is_external_collaborator"`
+	CanInvite              bool       `json:"can_invite"`
+	CanManageMembers       bool       `json:"can_manage_members"`
+}
+
+// OwnershipTransfer is a pending or decided offer of project ownership
+type OwnershipTransfer struct {
+	ID         string     `json:"id"`
+	ProjectID  string     `json:"project_id"`
+	FromUserID string     `json:"from_user_id"`
+	ToUserID   string     `json:"to_user_id"`
+	Message    string     `json:"message,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+}
+
+// InviteRequest represents a project invitation
+type InviteRequest struct {
+	UserID  string `json:"user_id,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Role    string `json:"role"`
+	Message string `json:"message,omitempty"`
+}
+
+// ListOptions represents page-based list options
+type ListOptions struct {
+	Page  int // 1-based
+	Limit int // Server default 20, maximum 100
+}
+
+// TenantList represents a page of tenants
+type TenantList struct {
+	Tenants []Tenant `json:"tenants"`
+	Total   int      `json:"total"`
+	Page    int      `json:"page"`
+	Limit   int      `json:"limit"`
+}
+
+// ProjectList represents a page of projects
+type ProjectList struct {
+	Projects []Project `json:"projects"`
+	Total    int       `json:"total"`
+	Page     int       `json:"page"`
+	Limit    int       `json:"limit"`
+}
+
+// ListTenants lists one page of tenants (system admin only)
+func (c *Client) ListTenants(ctx context.Context, opts *ListOptions) (*TenantList, error) {
+	var list TenantList
+	if err := c.getJSON(ctx, http.MethodGet, "/admin/v1/tenants"+opts.query(), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Tenants iterates over all tenants, fetching pages of limit tenants as needed
+func (c *Client) Tenants(ctx context.Context, limit int) iter.Seq2[Tenant, error] {
+	return paginate(limit, func(opts *ListOptions) ([]Tenant, int, error) {
+		list, err := c.ListTenants(ctx, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return list.Tenants, list.Total, nil
+	})
+}
+
+// GetTenant retrieves a tenant by ID
+func (c *Client) GetTenant(ctx context.Context, id string) (*Tenant, error) {
+	var tenant Tenant
+	if err := c.getJSON(ctx, http.MethodGet, "/admin/v1/tenants/"+url.PathEscape(id), nil, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// GetTenantBySlug retrieves a tenant by its current or a previous slug
+func (c *Client) GetTenantBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	var tenant Tenant
+	if err := c.getJSON(ctx, http.MethodGet, "/admin/v1/tenants/by-slug/"+url.PathEscape(slug), nil, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// CreateTenant creates a tenant
+func (c *Client) CreateTenant(ctx context.Context, input *TenantInput) (*Tenant, error) {
+	var tenant Tenant
+	if err := c.getJSON(ctx, http.MethodPost, "/admin/v1/tenants", input, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// UpdateTenant updates the non-empty fields of a tenant
+func (c *Client) UpdateTenant(ctx context.Context, id string, input *TenantInput) (*Tenant, error) {
+	var tenant Tenant
+	if err := c.getJSON(ctx, http.MethodPut, "/admin/v1/tenants/"+url.PathEscape(id), input, &tenant); err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// DeleteTenant soft-deletes a tenant
+func (c *Client) DeleteTenant(ctx context.Context, id string) error {
+	return c.getJSON(ctx, http.MethodDelete, "/admin/v1/tenants/"+url.PathEscape(id), nil, nil)
+}
+
+// ListProjects lists one page of the current user's projects across tenants
+func (c *Client) ListProjects(ctx context.Context, opts *ListOptions) (*ProjectList, error) {
+	var list ProjectList
+	if err := c.getJSON(ctx, http.MethodGet, "/admin/v1/projects"+opts.query(), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Projects iterates over all of the current user's projects
+func (c *Client) Projects(ctx context.Context, limit int) iter.Seq2[Project, error] {
+	return paginate(limit, func(opts *ListOptions) ([]Project, int, error) {
+		list, err := c.ListProjects(ctx, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return list.Projects, list.Total, nil
+	})
+}
+
+// ListTenantProjects lists one page of a tenant's projects
+func (c *Client) ListTenantProjects(ctx context.Context, tenantID string, opts *ListOptions) (*ProjectList, error) {
+	var list ProjectList
+	path := "/admin/v1/tenants/" + url.PathEscape(tenantID) + "/projects" + opts.query()
+	if err := c.getJSON(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// TenantProjects iterates over all of a tenant's projects
+func (c *Client) TenantProjects(ctx context.Context, tenantID string, limit int) iter.Seq2[Project, error] {
+	return paginate(limit, func(opts *ListOptions) ([]Project, int, error) {
+		list, err := c.ListTenantProjects(ctx, tenantID, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		return list.Projects, list.Total, nil
+	})
+}
+
+// GetProject retrieves a project by ID
+func (c *Client) GetProject(ctx context.Context, id string) (*Project, error) {
+	var project Project
+	if err := c.getJSON(ctx, http.MethodGet, projectPath(id, ""), nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// GetProjectBySlug retrieves a tenant's project by its current or a previous slug
+func (c *Client) GetProjectBySlug(ctx context.Context, tenantID, slug string) (*Project, error) {
+	var project Project
+	path := "/admin/v1/tenants/" + url.PathEscape(tenantID) + "/projects/by-slug/" + url.PathEscape(slug)
+	if err := c.getJSON(ctx, http.MethodGet, path, nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// CreateProject creates a project in a tenant
+func (c *Client) CreateProject(ctx context.Context, tenantID string, input *ProjectInput) (*Project, error) {
+	var project Project
+	path := "/admin/v1/tenants/" + url.PathEscape(tenantID) + "/projects"
+	if err := c.getJSON(ctx, http.MethodPost, path, input, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// UpdateProject updates the non-empty fields of a project
+func (c *Client) UpdateProject(ctx context.Context, id string, input *ProjectInput) (*Project, error) {
+	var project Project
+	if err := c.getJSON(ctx, http.MethodPut, projectPath(id, ""), input, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// DeleteProject soft-deletes a project
+func (c *Client) DeleteProject(ctx context.Context, id string) error {
+	return c.getJSON(ctx, http.MethodDelete, projectPath(id, ""), nil, nil)
+}
+
+// ListProjectMembers lists the active members of a project
+func (c *Client) ListProjectMembers(ctx context.Context, projectID string) ([]ProjectMember, error) {
+	var response struct {
+		Members []ProjectMember `json:"members"`
+	}
+	if err := c.getJSON(ctx, http.MethodGet, projectPath(projectID, "/members"), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Members, nil
+}
+
+// InviteToProject adds a user to a project with a role
+func (c *Client) InviteToProject(ctx context.Context, projectID string, req *InviteRequest) error {
+	return c.getJSON(ctx, http.MethodPost, projectPath(projectID, "/invite"), req, nil)
+}
+
+// RemoveProjectMember removes a user from a project
+func (c *Client) RemoveProjectMember(ctx context.Context, projectID, userID string) error {
+	return c.getJSON(ctx, http.MethodDelete, projectPath(projectID, "/members/"+url.PathEscape(userID)), nil, nil)
+}
+
+// TransferProjectOwnership offers ownership of the project to toUserID, who
+// must be a member. Ownership changes once they accept the transfer.
+func (c *Client) TransferProjectOwnership(ctx context.Context, projectID, toUserID string) (*OwnershipTransfer, error) {
+	var transfer OwnershipTransfer
+	err := c.getJSON(ctx, http.MethodPost, projectPath(projectID, "/transfer-ownership"), map[string]string{
+		"to_user_id": toUserID,
+	}, &transfer)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// ListOwnershipTransfers lists the ownership transfers of a project, newest first
+func (c *Client) ListOwnershipTransfers(ctx context.Context, projectID string) ([]OwnershipTransfer, error) {
+	var response struct {
+		Transfers []OwnershipTransfer `json:"transfers"`
+	}
+	if err := c.getJSON(ctx, http.MethodGet, projectPath(projectID, "/transfer-ownership"), nil, &response); err != nil {
+		return nil, err
+	}
+	return response.Transfers, nil
+}
+
+// AcceptOwnershipTransfer accepts a transfer offered to the current user
+func (c *Client) AcceptOwnershipTransfer(ctx context.Context, projectID, transferID string) (*OwnershipTransfer, error) {
+	return c.decideTransfer(ctx, http.MethodPost, projectPath(projectID, "/transfer-ownership/"+url.PathEscape(transferID)+"/accept"))
+}
+
+// DeclineOwnershipTransfer declines a transfer offered to the current user
+func (c *Client) DeclineOwnershipTransfer(ctx context.Context, projectID, transferID string) (*OwnershipTransfer, error) {
+	return c.decideTransfer(ctx, http.MethodPost, projectPath(projectID, "/transfer-ownership/"+url.PathEscape(transferID)+"/decline"))
+}
+
+// CancelOwnershipTransfer withdraws a transfer the current user offered
+func (c *Client) CancelOwnershipTransfer(ctx context.Context, projectID, transferID string) (*OwnershipTransfer, error) {
+	return c.decideTransfer(ctx, http.MethodDelete, projectPath(projectID, "/transfer-ownership/"+url.PathEscape(transferID)))
+}
+
+func (c *Client) decideTransfer(ctx context.Context, method, path string) (*OwnershipTransfer, error) {
+	var transfer OwnershipTransfer
+	if err := c.getJSON(ctx, method, path, nil, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// projectPath returns the path of a project resource
+func projectPath(projectID, suffix string) string {
+	return "/admin/v1/projects/" + url.PathEscape(projectID) + suffix
+}
+
+// query encodes the list options as a query string
+func (o *ListOptions) query() string {
+	if o == nil {
+		return ""
+	}
+	params := url.Values{}
+	if o.Page > 0 {
+		params.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		params.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "?" + params.Encode()
+}
+
+// paginate iterates over page-based results until the total is reached or a
+// page comes back empty. A failed fetch is yielded once and ends iteration.
+func paginate[T any](limit int, fetch func(opts *ListOptions) ([]T, int, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		seen := 0
+		for page := 1; ; page++ {
+			items, total, err := fetch(&ListOptions{Page: page, Limit: limit})
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			seen += len(items)
+			if len(items) == 0 || seen >= total {
+				return
+			}
+		}
+	}
+}
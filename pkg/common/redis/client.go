@@ -0,0 +1,358 @@
+// Package redis provides a minimal RESP client shared by the cache, lock and
+// session backends
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned while the client backs off after a connection failure
+var ErrUnavailable = errors.New("redis unavailable")
+
+// ErrPoolExhausted is returned when no connection frees up within the timeout
+var ErrPoolExhausted = errors.New("redis connection pool exhausted")
+
+// ErrClosed is returned by a closed client
+var ErrClosed = errors.New("redis client closed")
+
+// DefaultPoolSize is the maximum number of connections a client opens
+const DefaultPoolSize = 10
+
+// Error is an error reply from the server
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Client is a minimal RESP client over a pool of connections. Commands run
+// concurrently on up to DefaultPoolSize connections; after a connection
+// failure further commands fail fast until the backoff passes, so callers
+// degrade instead of blocking.
+type Client struct {
+	addr     string
+	username string
+	password string
+	db       int
+	useTLS   bool
+	timeout  time.Duration
+	backoff  time.Duration
+
+	slots chan struct{} // one token per open connection
+	idle  chan *conn
+
+	mu        sync.Mutex
+	downUntil time.Time
+	closed    bool
+}
+
+// conn is one pooled connection
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// NewClient parses a redis:// or rediss:// URL. An explicit password or
+// database overrides the URL.
+func NewClient(rawURL, password string, db int, timeout time.Duration) (*Client, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "redis://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("unsupported redis url scheme: %s", u.Scheme)
+	}
+
+	client := &Client{
+		addr:    u.Host,
+		db:      db,
+		useTLS:  u.Scheme == "rediss",
+		timeout: timeout,
+		backoff: 5 * time.Second,
+		slots:   make(chan struct{}, DefaultPoolSize),
+		idle:    make(chan *conn, DefaultPoolSize),
+	}
+	if u.Port() == "" {
+		client.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		client.username = u.User.Username()
+		client.password, _ = u.User.Password()
+		if client.password == "" {
+			// redis://secret@host uses the user part as the password
+			client.password, client.username = client.username, ""
+		}
+	}
+	if password != "" {
+		client.password = password
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" && db == 0 {
+		if client.db, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("invalid redis database: %s", path)
+		}
+	}
+	return client, nil
+}
+
+// Do sends a command on a pooled connection and returns its reply: string,
+// int64, nil, []interface{} or an Error
+func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.roundTrip(ctx, c.timeout, args)
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection state is unknown after an I/O error
+		c.discard(cn)
+		if ctx.Err() == nil {
+			c.markDown()
+		}
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// get takes an idle connection or dials a new one, waiting up to the timeout
+// for a connection to free up when the pool is full
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	closed, down := c.closed, time.Now().Before(c.downUntil)
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+	if down {
+		return nil, ErrUnavailable
+	}
+
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	var expired <-chan time.Time
+	if c.timeout > 0 {
+		timer := time.NewTimer(c.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	case c.slots <- struct{}{}:
+	case <-expired:
+		return nil, ErrPoolExhausted
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	cn, err := c.dial(ctx)
+	if err != nil {
+		<-c.slots
+		if ctx.Err() == nil {
+			c.markDown()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return cn, nil
+}
+
+// put returns a healthy connection to the pool
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	if !c.closed {
+		// Never blocks: there are at most as many connections as idle slots
+		c.idle <- cn
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	c.discard(cn)
+}
+
+// discard closes a connection and frees its slot
+func (c *Client) discard(cn *conn) {
+	cn.Close()
+	<-c.slots
+}
+
+// markDown starts the backoff and drops the idle connections, which most
+// likely failed along with the one that reported the error
+func (c *Client) markDown() {
+	c.mu.Lock()
+	c.downUntil = time.Now().Add(c.backoff)
+	c.mu.Unlock()
+	c.drain()
+}
+
+// drain closes the idle connections
+func (c *Client) drain() {
+	for {
+		select {
+		case cn := <-c.idle:
+			c.discard(cn)
+		default:
+			return
+		}
+	}
+}
+
+// dial connects to the server, authenticates and selects the database
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	var nc net.Conn
+	var err error
+	if c.useTLS {
+		host, _, _ := net.SplitHostPort(c.addr)
+		nc, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		nc, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, reader: bufio.NewReader(nc)}
+
+	var setup [][]interface{}
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []interface{}{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []interface{}{"AUTH", c.password})
+		}
+	}
+	if c.db != 0 {
+		setup = append(setup, []interface{}{"SELECT", c.db})
+	}
+	for _, args := range setup {
+		if _, err := cn.roundTrip(ctx, c.timeout, args); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// roundTrip writes one command and reads its reply. The command is bounded
+// by the timeout and the context's deadline, and aborted if the context is
+// canceled.
+func (cn *conn) roundTrip(ctx context.Context, timeout time.Duration, args []interface{}) (interface{}, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	cn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		cn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			s = fmt.Sprint(v)
+		}
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(s), s)
+	}
+	if _, err := io.WriteString(cn.Conn, b.String()); err != nil {
+		return nil, err
+	}
+	return cn.readReply()
+}
+
+// readReply parses one RESP reply
+func (cn *conn) readReply() (interface{}, error) {
+	line, err := cn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed redis reply: %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, Error(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = cn.readReply(); err != nil {
+				var replyErr Error
+				if !errors.As(err, &replyErr) {
+					return nil, err
+				}
+				items[i] = replyErr
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown redis reply type %q", kind)
+}
+
+// Close closes the idle connections; connections in use are closed when
+// their command completes
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	c.drain()
+	return nil
+}
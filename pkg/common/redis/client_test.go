@@ -0,0 +1,304 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer answers RESP commands with the raw reply returned by handle
+type fakeServer struct {
+	listener net.Listener
+	handle   func(args []string) string
+
+	mu       sync.Mutex
+	commands [][]string
+	open     int32
+	maxOpen  int32
+	accepted int32
+}
+
+func newFakeServer(t *testing.T, handle func(args []string) string) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, handle: handle}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			nc, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&s.accepted, 1)
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(nc net.Conn) {
+	defer nc.Close()
+	open := atomic.AddInt32(&s.open, 1)
+	defer atomic.AddInt32(&s.open, -1)
+	for {
+		max := atomic.LoadInt32(&s.maxOpen)
+		if open <= max || atomic.CompareAndSwapInt32(&s.maxOpen, max, open) {
+			break
+		}
+	}
+
+	reader := bufio.NewReader(nc)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args)
+		s.mu.Unlock()
+		reply := s.handle(args)
+		if reply == "" {
+			// Drop the connection without replying
+			return
+		}
+		if _, err := nc.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) url() string {
+	return "redis://" + s.listener.Addr().String()
+}
+
+func (s *fakeServer) received() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+// readCommand parses one command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		value, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(value, "\r\n")
+	}
+	return args, nil
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+		err   string
+	}{
+		{"simple string", "+OK\r\n", "OK", ""},
+		{"error", "-ERR wrong type\r\n", nil, "ERR wrong type"},
+		{"integer", ":42\r\n", int64(42), ""},
+		{"bulk string", "$12\r\nhello\r\nworld\r\n", "hello\r\nworld", ""},
+		{"empty bulk string", "$0\r\n\r\n", "", ""},
+		{"nil bulk string", "$-1\r\n", nil, ""},
+		{"array", "*3\r\n$1\r\na\r\n:2\r\n$-1\r\n", []interface{}{"a", int64(2), nil}, ""},
+		{"nested array", "*2\r\n*1\r\n+x\r\n*0\r\n", []interface{}{[]interface{}{"x"}, []interface{}{}}, ""},
+		{"array with error", "*2\r\n+OK\r\n-ERR no\r\n", []interface{}{"OK", Error("ERR no")}, ""},
+		{"nil array", "*-1\r\n", nil, ""},
+		{"missing CRLF", "+OK\n", nil, "malformed redis reply"},
+		{"unknown type", "!3\r\n", nil, "unknown redis reply type"},
+		{"truncated bulk string", "$5\r\nab", nil, "EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cn := &conn{reader: bufio.NewReader(strings.NewReader(tt.input))}
+			got, err := cn.readReply()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientEncodesCommandsAndSetsUpConnections(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "GET" {
+			return "-WRONGTYPE bad\r\n"
+		}
+		return "+OK\r\n"
+	})
+	url := strings.Replace(server.url(), "redis://", "redis://app:secret@", 1) + "/2"
+	client, err := NewClient(url, "", 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	reply, err := client.Do(context.Background(), "SET", "k", []byte("v w"), 5, int64(6), 1.5)
+	if err != nil || reply != "OK" {
+		t.Fatalf("unexpected reply %v %v", reply, err)
+	}
+	want := [][]string{
+		{"AUTH", "app", "secret"},
+		{"SELECT", "2"},
+		{"SET", "k", "v w", "5", "6", "1.5"},
+	}
+	if got := server.received(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got commands %q, want %q", got, want)
+	}
+
+	// Error replies leave the connection usable
+	var replyErr Error
+	if _, err := client.Do(context.Background(), "GET", "k"); !errors.As(err, &replyErr) {
+		t.Fatalf("expected an error reply, got %v", err)
+	}
+	if accepted := atomic.LoadInt32(&server.accepted); accepted != 1 {
+		t.Fatalf("expected the connection to be reused, got %d connections", accepted)
+	}
+}
+
+func TestClientPoolsConnections(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		time.Sleep(50 * time.Millisecond)
+		return ":1\r\n"
+	})
+	client, err := NewClient(server.url(), "", 0, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*DefaultPoolSize)
+	for i := 0; i < 3*DefaultPoolSize; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Do(context.Background(), "INCR", "n"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if max := atomic.LoadInt32(&server.maxOpen); max != DefaultPoolSize {
+		t.Fatalf("expected %d concurrent connections, got %d", DefaultPoolSize, max)
+	}
+	// Three rounds of DefaultPoolSize commands instead of one command at a time
+	if elapsed := time.Since(start); elapsed > time.Duration(2*DefaultPoolSize)*50*time.Millisecond {
+		t.Fatalf("expected commands to run concurrently, took %v", elapsed)
+	}
+}
+
+func TestClientReconnectsAfterBackoff(t *testing.T) {
+	var calls int32
+	server := newFakeServer(t, func(args []string) string {
+		if atomic.AddInt32(&calls, 1) == 2 {
+			return ""
+		}
+		return "+PONG\r\n"
+	})
+	client, err := NewClient(server.url(), "", 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.backoff = 100 * time.Millisecond
+
+	ctx := context.Background()
+	if _, err := client.Do(ctx, "PING"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(ctx, "PING"); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected the dropped connection to fail the command, got %v", err)
+	}
+	if _, err := client.Do(ctx, "PING"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected commands to fail fast during the backoff, got %v", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if reply, err := client.Do(ctx, "PING"); err != nil || reply != "PONG" {
+		t.Fatalf("expected a new connection after the backoff, got %v %v", reply, err)
+	}
+	if accepted := atomic.LoadInt32(&server.accepted); accepted != 2 {
+		t.Fatalf("expected 2 connections, got %d", accepted)
+	}
+}
+
+func TestClientTimeouts(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	server := newFakeServer(t, func(args []string) string {
+		<-hang
+		return "+OK\r\n"
+	})
+
+	client, err := NewClient(server.url(), "", 0, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	start := time.Now()
+	var netErr net.Error
+	if _, err := client.Do(context.Background(), "GET", "k"); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the command timeout to apply, took %v", elapsed)
+	}
+
+	// Without a client timeout the context bounds the command
+	client, err = NewClient(server.url(), "", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := client.Do(ctx, "GET", "k"); err == nil {
+		t.Fatal("expected canceling the context to abort the command")
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Do(ctx, "GET", "k"); errors.Is(err, ErrUnavailable) {
+		t.Fatal("expected a canceled command not to start the backoff")
+	}
+}
@@ -64,6 +64,9 @@ type ServerConfig struct {
 	IdleTimeout     string `yaml:"idle_timeout" json:"idle_timeout"`
 	MaxHeaderBytes  int    `yaml:"max_header_bytes" json:"max_header_bytes"`
 	ShutdownTimeout string `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+	EnableProfiling bool   `yaml:"enable_profiling" json:"enable_profiling"` // Serve /debug/pprof to system admins
+	DefaultLocale   string `yaml:"default_locale" json:"default_locale"`     // Locale of messages when neither user nor tenant sets one
+	TrustedProxies  string `yaml:"trusted_proxies" json:"trusted_proxies"`   // Comma-separated proxy IPs or CIDRs whose forwarding headers are believed
 }
 
 // DatabaseConfig contains database-related configuration
@@ -151,6 +154,7 @@ func DefaultConfig() *AppConfig {
 			IdleTimeout:     "120s",
 			MaxHeaderBytes:  1 << 20, // 1MB
 			ShutdownTimeout: "30s",
+			EnableProfiling: false,
 		},
 		Database: DatabaseConfig{
 			Type:        "sqlite",
@@ -342,6 +346,9 @@ func (c *Config) GetAppConfig() *AppConfig {
 			IdleTimeout:     c.GetString("server.idle_timeout"),
 			MaxHeaderBytes:  c.GetInt("server.max_header_bytes"),
 			ShutdownTimeout: c.GetString("server.shutdown_timeout"),
+			EnableProfiling: c.GetBool("server.enable_profiling"),
+			DefaultLocale:   c.GetString("server.default_locale"),
+			TrustedProxies:  c.GetString("server.trusted_proxies"),
 		},
 		Database: DatabaseConfig{
 			Type:        c.GetString("database.type"),
@@ -500,6 +507,18 @@ func createConfigSchema() *config.ConfigSchema {
 				Type:    "boolean",
 				Default: false,
 			},
+			"server.enable_profiling": {
+				Type:    "boolean",
+				Default: false,
+			},
+			"server.default_locale": {
+				Type:    "string",
+				Default: "en",
+			},
+			"server.trusted_proxies": {
+				Type:    "string",
+				Default: "",
+			},
 			"database.type": {
 				Type:    "string",
 				Default: "sqlite",
@@ -418,3 +418,27 @@ func TestConfigConcurrentAccess(t *testing.T) {
 		}
 	}
 }
+
+func TestRedact(t *testing.T) {
+	settings := Redact(map[string]interface{}{
+		"host":       "localhost",
+		"jwt_secret": "s3cret",
+		"api_key":    "",
+		"database":   map[string]interface{}{"password": "pw", "url": "postgres://app:pw@db:5432/metabase"},
+		"sinks": []interface{}{
+			map[string]interface{}{"headers": map[string]interface{}{"Authorization": "Bearer abc"}},
+		},
+	})
+
+	if settings["host"] != "localhost" || settings["jwt_secret"] != Redacted || settings["api_key"] != "" {
+		t.Errorf("unexpected top-level settings: %v", settings)
+	}
+	database := settings["database"].(map[string]interface{})
+	if database["password"] != Redacted || database["url"] != "postgres://app:xxxxx@db:5432/metabase" {
+		t.Errorf("unexpected database settings: %v", database)
+	}
+	headers := settings["sinks"].([]interface{})[0].(map[string]interface{})["headers"].(map[string]interface{})
+	if headers["Authorization"] != Redacted {
+		t.Errorf("expected header to be redacted, got %v", headers)
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces secret values in redacted settings
+const Redacted = "[REDACTED]"
+
+// secretKeyParts mark settings whose values are secrets
+var secretKeyParts = []string{"secret", "password", "token", "api_key", "apikey", "credential", "private", "authorization"}
+
+// Redact replaces the non-empty values of settings whose names look like
+// secrets, and passwords embedded in URLs, in decoded JSON settings. Nested
+// objects and arrays are redacted in place.
+func Redact(settings map[string]interface{}) map[string]interface{} {
+	for key, value := range settings {
+		if s, ok := value.(string); ok && s != "" && IsSecretKey(key) {
+			settings[key] = Redacted
+			continue
+		}
+		settings[key] = redactValue(value)
+	}
+	return settings
+}
+
+// IsSecretKey reports whether a setting name looks like it holds a secret
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return Redact(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+		return v
+	case string:
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			return u.Redacted()
+		}
+		return v
+	}
+	return value
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/common/errors"
+)
+
+// Role grant statuses
+const (
+	GrantStatusPending  = "pending"
+	GrantStatusActive   = "active"
+	GrantStatusRejected = "rejected"
+	GrantStatusRevoked  = "revoked"
+	GrantStatusExpired  = "expired"
+)
+
+// MaxRoleGrantDuration caps how long a temporary role grant may last
+const MaxRoleGrantDuration = 7 * 24 * time.Hour
+
+// RoleGrant is a time-boxed elevation of a user's role in a project. While
+// active it takes precedence over a lower membership role; afterwards the
+// user reverts to their membership role, or loses access if they had none.
+type RoleGrant struct {
+	ID          string        `json:"id"`
+	ProjectID   string        `json:"project_id"`
+	TenantID    string        `json:"tenant_id"`
+	UserID      string        `json:"user_id"`
+	Role        string        `json:"role"`
+	Reason      string        `json:"reason,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Status      string        `json:"status"`
+	RequestedBy string        `json:"requested_by"`
+	DecidedBy   string        `json:"decided_by,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	StartsAt    *time.Time    `json:"starts_at,omitempty"`
+	ExpiresAt   *time.Time    `json:"expires_at,omitempty"`
+	EndedAt     *time.Time    `json:"ended_at,omitempty"`
+}
+
+const roleGrantColumns = `id, project_id, tenant_id, user_id, role, reason, duration_seconds, status,
+	requested_by, decided_by, created_at, starts_at, expires_at, ended_at`
+
+// grantableRoles are the roles a temporary grant may confer
+var grantableRoles = map[string]bool{
+	ProjectRoleViewer:       true,
+	ProjectRoleCollaborator: true,
+	ProjectRoleOwner:        true,
+}
+
+// GrantRole records a temporary role grant. Without approval the grant is
+// active immediately and its duration starts now; with approval it stays
+// pending until ApproveRoleGrant, and the duration starts on approval.
+func (pm *ProjectMembers) GrantRole(ctx context.Context, grant *RoleGrant, requireApproval bool) error {
+	if !grantableRoles[grant.Role] {
+		return fmt.Errorf("role %q cannot be granted temporarily", grant.Role)
+	}
+	if grant.Duration <= 0 || grant.Duration > MaxRoleGrantDuration {
+		return fmt.Errorf("grant duration must be between 1s and %s", MaxRoleGrantDuration)
+	}
+	tenantID, err := pm.ProjectTenantID(ctx, grant.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	grant.ID = generateUUID()
+	grant.TenantID = tenantID
+	grant.CreatedAt = now
+	grant.Status = GrantStatusPending
+	if !requireApproval {
+		grant.activate(now)
+	}
+	_, err = pm.db.ExecContext(ctx, `
+		INSERT INTO project_role_grants (`+roleGrantColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		grant.ID, grant.ProjectID, grant.TenantID, grant.UserID, grant.Role, grant.Reason,
+		int64(grant.Duration/time.Second), grant.Status, grant.RequestedBy, grant.DecidedBy,
+		grant.CreatedAt, grant.StartsAt, grant.ExpiresAt, grant.EndedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save role grant: %w", err)
+	}
+	if grant.Status == GrantStatusActive {
+		pm.InvalidateUser(ctx, grant.UserID)
+	}
+	return nil
+}
+
+// ApproveRoleGrant activates a pending grant. Grants cannot be approved by
+// the admin who requested them.
+func (pm *ProjectMembers) ApproveRoleGrant(ctx context.Context, projectID, grantID, approvedBy string) (*RoleGrant, error) {
+	grant, err := pm.GetRoleGrant(ctx, projectID, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Status != GrantStatusPending {
+		return nil, errors.Conflict(fmt.Sprintf("role grant is %s", grant.Status))
+	}
+	if grant.RequestedBy == approvedBy {
+		return nil, errors.Forbidden("role grants must be approved by another admin")
+	}
+	grant.activate(time.Now())
+	grant.DecidedBy = approvedBy
+	if err := pm.transitionGrant(ctx, grant, GrantStatusPending); err != nil {
+		return nil, err
+	}
+	pm.InvalidateUser(ctx, grant.UserID)
+	return grant, nil
+}
+
+// RejectRoleGrant declines a pending grant
+func (pm *ProjectMembers) RejectRoleGrant(ctx context.Context, projectID, grantID, rejectedBy string) (*RoleGrant, error) {
+	return pm.endRoleGrant(ctx, projectID, grantID, rejectedBy, GrantStatusPending, GrantStatusRejected)
+}
+
+// RevokeRoleGrant ends an active grant before it expires
+func (pm *ProjectMembers) RevokeRoleGrant(ctx context.Context, projectID, grantID, revokedBy string) (*RoleGrant, error) {
+	grant, err := pm.endRoleGrant(ctx, projectID, grantID, revokedBy, GrantStatusActive, GrantStatusRevoked)
+	if err != nil {
+		return nil, err
+	}
+	pm.InvalidateUser(ctx, grant.UserID)
+	return grant, nil
+}
+
+// ExpireRoleGrants marks active grants whose time is up as expired and
+// returns them. Each grant is claimed with a conditional update, so when
+// several instances sweep at once every grant is returned by only one.
+func (pm *ProjectMembers) ExpireRoleGrants(ctx context.Context, now time.Time) ([]*RoleGrant, error) {
+	due, err := pm.queryGrants(ctx, `SELECT `+roleGrantColumns+` FROM project_role_grants
+		WHERE status = ? AND expires_at <= ?`, GrantStatusActive, now)
+	if err != nil {
+		return nil, err
+	}
+	var expired []*RoleGrant
+	for _, grant := range due {
+		grant.Status = GrantStatusExpired
+		grant.EndedAt = grant.ExpiresAt
+		if err := pm.transitionGrant(ctx, grant, GrantStatusActive); err != nil {
+			if errors.GetCode(err) == errors.ErrCodeConflict {
+				continue
+			}
+			return expired, err
+		}
+		pm.InvalidateUser(ctx, grant.UserID)
+		expired = append(expired, grant)
+	}
+	return expired, nil
+}
+
+// GetRoleGrant returns a grant of a project
+func (pm *ProjectMembers) GetRoleGrant(ctx context.Context, projectID, grantID string) (*RoleGrant, error) {
+	grants, err := pm.queryGrants(ctx, `SELECT `+roleGrantColumns+` FROM project_role_grants
+		WHERE id = ? AND project_id = ?`, grantID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(grants) == 0 {
+		return nil, errors.NotFound("role grant")
+	}
+	return grants[0], nil
+}
+
+// ListRoleGrants returns the grants of a project, newest first
+func (pm *ProjectMembers) ListRoleGrants(ctx context.Context, projectID string) ([]*RoleGrant, error) {
+	return pm.queryGrants(ctx, `SELECT `+roleGrantColumns+` FROM project_role_grants
+		WHERE project_id = ? ORDER BY created_at DESC`, projectID)
+}
+
+// activeGrants returns a user's grants in effect at now
+func (pm *ProjectMembers) activeGrants(ctx context.Context, userID string, now time.Time) ([]*RoleGrant, error) {
+	return pm.queryGrants(ctx, `SELECT `+prefixColumns("g.", roleGrantColumns)+`
+		FROM project_role_grants g
+		INNER JOIN projects p ON p.id = g.project_id
+		WHERE g.user_id = ? AND g.status = ? AND g.expires_at > ? AND p.deleted_at IS NULL`,
+		userID, GrantStatusActive, now)
+}
+
+// applyGrants raises memberships to the role of active grants, adding a
+// membership for grants to users outside the project. It returns the time the
+// first grant ends, which bounds how long the result may be cached.
+func applyGrants(members []*UserTenantProject, grants []*RoleGrant) ([]*UserTenantProject, time.Time) {
+	var firstExpiry time.Time
+	byProject := rolesByProject(members)
+	for _, grant := range grants {
+		if firstExpiry.IsZero() || grant.ExpiresAt.Before(firstExpiry) {
+			firstExpiry = *grant.ExpiresAt
+		}
+		member, ok := byProject[grant.ProjectID]
+		if !ok {
+			member = &UserTenantProject{
+				UserID:    grant.UserID,
+				TenantID:  grant.TenantID,
+				ProjectID: grant.ProjectID,
+				IsActive:  true,
+				JoinedAt:  *grant.StartsAt,
+			}
+			byProject[grant.ProjectID] = member
+			members = append(members, member)
+		} else if roleRank(member.Role) >= roleRank(grant.Role) {
+			continue
+		}
+		member.Role = grant.Role
+		member.CanInvite = grant.Role == ProjectRoleOwner || grant.Role == ProjectRoleCollaborator
+		member.CanManageMembers = grant.Role == ProjectRoleOwner
+	}
+	return members, firstExpiry
+}
+
+// roleRank orders project roles from least to most privileged
+func roleRank(role string) int {
+	switch role {
+	case ProjectRoleViewer:
+		return 1
+	case ProjectRoleCollaborator:
+		return 2
+	case ProjectRoleOwner:
+		return 3
+	case ProjectRoleCreator:
+		return 4
+	}
+	return 0
+}
+
+func (g *RoleGrant) activate(now time.Time) {
+	expires := now.Add(g.Duration)
+	g.Status = GrantStatusActive
+	g.StartsAt = &now
+	g.ExpiresAt = &expires
+}
+
+func (pm *ProjectMembers) endRoleGrant(ctx context.Context, projectID, grantID, decidedBy, from, to string) (*RoleGrant, error) {
+	grant, err := pm.GetRoleGrant(ctx, projectID, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Status != from {
+		return nil, errors.Conflict(fmt.Sprintf("role grant is %s", grant.Status))
+	}
+	now := time.Now()
+	grant.Status = to
+	grant.DecidedBy = decidedBy
+	grant.EndedAt = &now
+	if err := pm.transitionGrant(ctx, grant, from); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// transitionGrant saves a grant's new state if it is still in status from
+func (pm *ProjectMembers) transitionGrant(ctx context.Context, grant *RoleGrant, from string) error {
+	result, err := pm.db.ExecContext(ctx, `
+		UPDATE project_role_grants SET status = ?, decided_by = ?, starts_at = ?, expires_at = ?, ended_at = ?
+		WHERE id = ? AND status = ?`,
+		grant.Status, grant.DecidedBy, grant.StartsAt, grant.ExpiresAt, grant.EndedAt, grant.ID, from)
+	if err != nil {
+		return fmt.Errorf("failed to update role grant: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.Conflict("role grant changed concurrently")
+	}
+	return nil
+}
+
+func (pm *ProjectMembers) queryGrants(ctx context.Context, query string, args ...interface{}) ([]*RoleGrant, error) {
+	rows, err := pm.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query role grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := []*RoleGrant{}
+	for rows.Next() {
+		var grant RoleGrant
+		var reason, decidedBy sql.NullString
+		var seconds int64
+		var startsAt, expiresAt, endedAt sql.NullTime
+		err := rows.Scan(&grant.ID, &grant.ProjectID, &grant.TenantID, &grant.UserID, &grant.Role,
+			&reason, &seconds, &grant.Status, &grant.RequestedBy, &decidedBy, &grant.CreatedAt,
+			&startsAt, &expiresAt, &endedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role grant: %w", err)
+		}
+		grant.Reason = reason.String
+		grant.DecidedBy = decidedBy.String
+		grant.Duration = time.Duration(seconds) * time.Second
+		grant.StartsAt = nullTimePtr(startsAt)
+		grant.ExpiresAt = nullTimePtr(expiresAt)
+		grant.EndedAt = nullTimePtr(endedAt)
+		grants = append(grants, &grant)
+	}
+	return grants, rows.Err()
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -3,14 +3,17 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/guileen/metabase/pkg/common/errors"
+	"github.com/guileen/metabase/pkg/infra/kv"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -69,13 +72,12 @@ type APIKey struct {
 	UpdatedAt   time.Time              `json:"updated_at"`
 }
 
-// SessionCache provides caching for session data
+// SessionCache caches validated sessions and API keys by token. Entries
+// live in a kv.Store, so with a shared backend a session revoked on one
+// instance is no longer accepted by the others.
 type SessionCache struct {
-	sessions map[string]*Session
-	apiKeys  map[string]*APIKey
-	mu       sync.RWMutex
-	ttl      time.Duration
-	maxSize  int
+	store kv.Store
+	ttl   time.Duration
 }
 
 // CacheEntry represents a cached entry
@@ -102,7 +104,7 @@ func NewManager(db *sql.DB, jwt *JWTManager, rbac *RBACManager, config *SessionC
 		jwt:    jwt,
 		rbac:   rbac,
 		config: config,
-		cache:  NewSessionCache(config.SessionTimeout/4, 10000),
+		cache:  NewSessionCache(kv.NewMemoryStore(10000), config.SessionTimeout/4),
 	}
 
 	// Start cleanup routine
@@ -111,16 +113,28 @@ func NewManager(db *sql.DB, jwt *JWTManager, rbac *RBACManager, config *SessionC
 	return manager
 }
 
-// NewSessionCache creates a new session cache
-func NewSessionCache(ttl time.Duration, maxSize int) *SessionCache {
+// NewSessionCache creates a session cache keeping entries in store for at most ttl
+func NewSessionCache(store kv.Store, ttl time.Duration) *SessionCache {
 	return &SessionCache{
-		sessions: make(map[string]*Session),
-		apiKeys:  make(map[string]*APIKey),
-		ttl:      ttl,
-		maxSize:  maxSize,
+		store: store,
+		ttl:   ttl,
 	}
 }
 
+// SetCacheStore moves the session cache to store. Multi-instance deployments
+// must use a shared store (database or Redis) so revocations apply everywhere.
+func (m *Manager) SetCacheStore(store kv.Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cache = NewSessionCache(store, m.config.SessionTimeout/4)
+}
+
+func (m *Manager) sessionCache() *SessionCache {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cache
+}
+
 // CreateSession creates a new user session
 func (m *Manager) CreateSession(ctx context.Context, userID, tenantID, projectID, ipAddress, userAgent string, metadata map[string]interface{}) (*Session, error) {
 	// Check session limit
@@ -162,7 +176,7 @@ func (m *Manager) CreateSession(ctx context.Context, userID, tenantID, projectID
 	}
 
 	// Update cache
-	m.cache.SetSession(session)
+	m.sessionCache().SetSession(ctx, accessToken, session)
 
 	return session, nil
 }
@@ -170,7 +184,7 @@ func (m *Manager) CreateSession(ctx context.Context, userID, tenantID, projectID
 // ValidateSession validates a session token
 func (m *Manager) ValidateSession(ctx context.Context, token string) (*Session, error) {
 	// Check cache first
-	if cached := m.cache.GetSessionByToken(token); cached != nil {
+	if cached := m.sessionCache().GetSessionByToken(ctx, token); cached != nil {
 		return cached, nil
 	}
 
@@ -199,7 +213,7 @@ func (m *Manager) ValidateSession(ctx context.Context, token string) (*Session,
 	}
 
 	// Update cache
-	m.cache.SetSession(session)
+	m.sessionCache().SetSession(ctx, token, session)
 
 	return session, nil
 }
@@ -254,7 +268,7 @@ func (m *Manager) RefreshToken(ctx context.Context, refreshToken string) (string
 	}
 
 	// Update cache
-	m.cache.SetSession(session)
+	m.sessionCache().SetSession(ctx, newAccessToken, session)
 
 	return newAccessToken, nil
 }
@@ -274,7 +288,7 @@ func (m *Manager) InvalidateSession(ctx context.Context, sessionID string) error
 	}
 
 	// Remove from cache
-	m.cache.DeleteSession(sessionID)
+	m.sessionCache().DeleteSession(ctx, sessionID)
 
 	return nil
 }
@@ -295,7 +309,7 @@ func (m *Manager) InvalidateUserSessions(ctx context.Context, userID string) err
 		}
 
 		// Remove from cache
-		m.cache.DeleteSession(session.ID)
+		m.sessionCache().DeleteSession(ctx, session.ID)
 	}
 
 	return nil
@@ -344,7 +358,7 @@ func (m *Manager) ValidateAPIKey(ctx context.Context, apiKey string) (*APIKey, e
 	}
 
 	// Check cache first
-	if cached := m.cache.GetAPIKeyByKey(apiKey); cached != nil {
+	if cached := m.sessionCache().GetAPIKeyByKey(ctx, apiKey); cached != nil {
 		return cached, nil
 	}
 
@@ -377,7 +391,7 @@ func (m *Manager) ValidateAPIKey(ctx context.Context, apiKey string) (*APIKey, e
 	}
 
 	// Update cache
-	m.cache.SetAPIKey(keyRecord)
+	m.sessionCache().SetAPIKey(ctx, apiKey, keyRecord)
 
 	return keyRecord, nil
 }
@@ -397,7 +411,7 @@ func (m *Manager) RevokeAPIKey(ctx context.Context, keyID string) error {
 	}
 
 	// Remove from cache
-	m.cache.DeleteAPIKey(keyID)
+	m.sessionCache().DeleteAPIKey(ctx, keyID)
 
 	return nil
 }
@@ -754,138 +768,109 @@ func (m *Manager) cleanupExpiredSessions() {
 	}
 
 	// Clean cache
-	m.cache.Cleanup()
+	m.sessionCache().Cleanup(ctx)
 }
 
 // Cache methods
 
-func (sc *SessionCache) GetSession(sessionID string) *Session {
-	sc.mu.RLock()
-	defer sc.mu.RUnlock()
-
-	if session, exists := sc.sessions[sessionID]; exists {
-		if time.Now().After(session.ExpiresAt) {
-			delete(sc.sessions, sessionID)
-			return nil
-		}
-		return session
+func (sc *SessionCache) GetSessionByToken(ctx context.Context, token string) *Session {
+	var session Session
+	if !sc.get(ctx, sessionTokenKey(token), &session) {
+		return nil
 	}
-	return nil
-}
-
-func (sc *SessionCache) GetSessionByToken(token string) *Session {
-	tokenHash := hashToken(token)
-	sc.mu.RLock()
-	defer sc.mu.RUnlock()
-
-	for _, session := range sc.sessions {
-		if session.TokenHash == tokenHash && time.Now().Before(session.ExpiresAt) {
-			return session
-		}
+	if !session.IsActive || time.Now().After(session.ExpiresAt) {
+		return nil
 	}
-	return nil
+	return &session
 }
 
-func (sc *SessionCache) GetAPIKeyByKey(apiKey string) *APIKey {
-	keyHash := hashToken(apiKey)
-	sc.mu.RLock()
-	defer sc.mu.RUnlock()
-
-	for _, key := range sc.apiKeys {
-		if key.KeyHash == keyHash && key.IsActive &&
-			(key.ExpiresAt == nil || time.Now().Before(*key.ExpiresAt)) {
-			return key
-		}
+func (sc *SessionCache) GetAPIKeyByKey(ctx context.Context, apiKey string) *APIKey {
+	var key APIKey
+	if !sc.get(ctx, apiKeyTokenKey(apiKey), &key) {
+		return nil
 	}
-	return nil
-}
-
-func (sc *SessionCache) SetSession(session *Session) {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-
-	// Remove oldest entries if cache is full
-	if len(sc.sessions) >= sc.maxSize {
-		sc.evictOldestSession()
+	if !key.IsActive || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil
 	}
-
-	sc.sessions[session.ID] = session
+	return &key
 }
 
-func (sc *SessionCache) SetAPIKey(key *APIKey) {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
+// SetSession caches a session under its access token. The session ID maps to
+// the token entry so the session can be evicted without knowing the token.
+func (sc *SessionCache) SetSession(ctx context.Context, token string, session *Session) {
+	sc.set(ctx, "auth:session:"+session.ID, sessionTokenKey(token), session, time.Until(session.ExpiresAt))
+}
 
-	// Remove oldest entries if cache is full
-	if len(sc.apiKeys) >= sc.maxSize {
-		sc.evictOldestAPIKey()
+// SetAPIKey caches an API key record under the raw key
+func (sc *SessionCache) SetAPIKey(ctx context.Context, apiKey string, key *APIKey) {
+	ttl := sc.ttl
+	if key.ExpiresAt != nil {
+		ttl = time.Until(*key.ExpiresAt)
 	}
-
-	sc.apiKeys[key.ID] = key
+	sc.set(ctx, "auth:apikey:"+key.ID, apiKeyTokenKey(apiKey), key, ttl)
 }
 
-func (sc *SessionCache) DeleteSession(sessionID string) {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-	delete(sc.sessions, sessionID)
+func (sc *SessionCache) DeleteSession(ctx context.Context, sessionID string) {
+	sc.delete(ctx, "auth:session:"+sessionID)
 }
 
-func (sc *SessionCache) DeleteAPIKey(keyID string) {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-	delete(sc.apiKeys, keyID)
+func (sc *SessionCache) DeleteAPIKey(ctx context.Context, keyID string) {
+	sc.delete(ctx, "auth:apikey:"+keyID)
 }
 
-func (sc *SessionCache) Cleanup() {
-	sc.mu.Lock()
-	defer sc.mu.Unlock()
-
-	// Clean expired sessions
-	now := time.Now()
-	for id, session := range sc.sessions {
-		if now.After(session.ExpiresAt) {
-			delete(sc.sessions, id)
-		}
+// Cleanup drops expired entries from stores that do not expire them on their own
+func (sc *SessionCache) Cleanup(ctx context.Context) {
+	if expirer, ok := sc.store.(kv.Expirer); ok {
+		expirer.DeleteExpired(ctx)
 	}
+}
 
-	// Clean expired API keys
-	for id, key := range sc.apiKeys {
-		if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
-			delete(sc.apiKeys, id)
-		}
+// get decodes a cached entry; store errors are treated as misses
+func (sc *SessionCache) get(ctx context.Context, key string, target interface{}) bool {
+	data, err := sc.store.Get(ctx, key)
+	if err != nil {
+		return false
 	}
+	return json.Unmarshal(data, target) == nil
 }
 
-func (sc *SessionCache) evictOldestSession() {
-	var oldestID string
-	var oldestTime time.Time = time.Now()
-
-	for id, session := range sc.sessions {
-		if session.CreatedAt.Before(oldestTime) {
-			oldestTime = session.CreatedAt
-			oldestID = id
-		}
+// set caches value under entryKey for at most the cache ttl, and records
+// entryKey under idKey
+func (sc *SessionCache) set(ctx context.Context, idKey, entryKey string, value interface{}, ttl time.Duration) {
+	if ttl > sc.ttl {
+		ttl = sc.ttl
 	}
-
-	if oldestID != "" {
-		delete(sc.sessions, oldestID)
+	if ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	// Drop the entry of a previous token, e.g. after a refresh
+	sc.delete(ctx, idKey)
+	if sc.store.Set(ctx, idKey, []byte(entryKey), ttl) == nil {
+		sc.store.Set(ctx, entryKey, data, ttl)
 	}
 }
 
-func (sc *SessionCache) evictOldestAPIKey() {
-	var oldestID string
-	var oldestTime time.Time = time.Now()
-
-	for id, key := range sc.apiKeys {
-		if key.CreatedAt.Before(oldestTime) {
-			oldestTime = key.CreatedAt
-			oldestID = id
-		}
+// delete removes the entry recorded under idKey
+func (sc *SessionCache) delete(ctx context.Context, idKey string) {
+	if entryKey, err := sc.store.Get(ctx, idKey); err == nil {
+		sc.store.Delete(ctx, string(entryKey), idKey)
 	}
+}
 
-	if oldestID != "" {
-		delete(sc.apiKeys, oldestID)
-	}
+// sessionTokenKey derives a cache key from an access token. Token hashes are
+// salted, so the key uses a plain digest instead.
+func sessionTokenKey(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return "auth:session-token:" + hex.EncodeToString(digest[:])
+}
+
+func apiKeyTokenKey(apiKey string) string {
+	digest := sha256.Sum256([]byte(apiKey))
+	return "auth:apikey-token:" + hex.EncodeToString(digest[:])
 }
 
 // Helper functions
@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/common/errors"
+	"github.com/guileen/metabase/pkg/infra/kv"
+)
+
+// ProjectMembers reads and updates project membership in the database
+// (projects and user_projects tables). Unlike TenantManager it keeps no state
+// in memory, so every API instance sees the same members and roles. A user's
+// effective roles are cached in a kv.Store for a short time and invalidated
+// on membership changes.
+type ProjectMembers struct {
+	db *sql.DB
+
+	mu    sync.RWMutex
+	cache kv.Store
+	ttl   time.Duration
+}
+
+// NewProjectMembers creates a database-backed project membership store with
+// an in-memory role cache
+func NewProjectMembers(db *sql.DB) *ProjectMembers {
+	return &ProjectMembers{
+		db:    db,
+		cache: kv.NewMemoryStore(10000),
+		ttl:   30 * time.Second,
+	}
+}
+
+// SetCacheStore moves the role cache to store. With a store shared between
+// instances, a membership change on one instance reaches all of them;
+// otherwise other instances pick up changes when their entries expire.
+func (pm *ProjectMembers) SetCacheStore(store kv.Store) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.cache = store
+}
+
+func (pm *ProjectMembers) store() kv.Store {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.cache
+}
+
+const projectMemberColumns = `id, user_id, tenant_id, project_id, role, is_active, is_creator,
+	invited_by, joined_at, left_at, is_external_collaborator, can_invite, can_manage_members`
+
+// ProjectTenantID returns the tenant owning a project
+func (pm *ProjectMembers) ProjectTenantID(ctx context.Context, projectID string) (string, error) {
+	var tenantID string
+	err := pm.db.QueryRowContext(ctx,
+		`SELECT tenant_id FROM projects WHERE id = ? AND deleted_at IS NULL`, projectID).Scan(&tenantID)
+	if err == sql.ErrNoRows {
+		return "", errors.NotFound("project")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get project: %w", err)
+	}
+	return tenantID, nil
+}
+
+// EffectiveRoles returns a user's active memberships of projects that are not
+// deleted, keyed by project ID, with active temporary role grants applied.
+// All memberships are loaded with one query and cached until they expire or
+// change, and never past the end of a grant.
+func (pm *ProjectMembers) EffectiveRoles(ctx context.Context, userID string) (map[string]*UserTenantProject, error) {
+	store := pm.store()
+	key := effectiveRolesKey(userID)
+	var members []*UserTenantProject
+	if data, err := store.Get(ctx, key); err == nil && json.Unmarshal(data, &members) == nil {
+		return rolesByProject(members), nil
+	}
+
+	members, err := pm.query(ctx, `SELECT `+prefixColumns("up.", projectMemberColumns)+`
+		FROM user_projects up
+		INNER JOIN projects p ON p.id = up.project_id
+		WHERE up.user_id = ? AND up.is_active = 1 AND p.deleted_at IS NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	grants, err := pm.activeGrants(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	members, firstExpiry := applyGrants(members, grants)
+
+	// Elevated roles must not outlive their grant in the cache
+	ttl := pm.ttl
+	if !firstExpiry.IsZero() && firstExpiry.Sub(now) < ttl {
+		ttl = firstExpiry.Sub(now)
+	}
+	if data, err := json.Marshal(members); err == nil {
+		store.Set(ctx, key, data, ttl)
+	}
+	return rolesByProject(members), nil
+}
+
+// UserProjectRole returns a user's active membership of a project
+func (pm *ProjectMembers) UserProjectRole(ctx context.Context, userID, projectID string) (*UserTenantProject, error) {
+	roles, err := pm.EffectiveRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	member, ok := roles[projectID]
+	if !ok {
+		return nil, fmt.Errorf("user %s is not a member of project %s", userID, projectID)
+	}
+	return member, nil
+}
+
+// MembersByProject returns the active members of each of projectIDs with a
+// single query, for listing a page of projects
+func (pm *ProjectMembers) MembersByProject(ctx context.Context, projectIDs []string) (map[string][]ProjectMember, error) {
+	result := make(map[string][]ProjectMember, len(projectIDs))
+	if len(projectIDs) == 0 {
+		return result, nil
+	}
+	args := make([]interface{}, len(projectIDs))
+	for i, projectID := range projectIDs {
+		args[i] = projectID
+	}
+	members, err := pm.query(ctx, `SELECT `+projectMemberColumns+` FROM user_projects
+		WHERE project_id IN (?`+strings.Repeat(", ?", len(projectIDs)-1)+`) AND is_active = 1
+		ORDER BY joined_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		result[member.ProjectID] = append(result[member.ProjectID], ProjectMember{
+			UserID:    member.UserID,
+			Role:      member.Role,
+			JoinedAt:  member.JoinedAt,
+			InvitedBy: member.InvitedBy,
+		})
+	}
+	return result, nil
+}
+
+// InvalidateUser drops the cached roles of a user
+func (pm *ProjectMembers) InvalidateUser(ctx context.Context, userIDs ...string) {
+	keys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		keys[i] = effectiveRolesKey(userID)
+	}
+	if len(keys) > 0 {
+		pm.store().Delete(ctx, keys...)
+	}
+}
+
+// InvalidateProject drops the cached roles of every member of a project, such
+// as when the project is deleted
+func (pm *ProjectMembers) InvalidateProject(ctx context.Context, projectID string) error {
+	rows, err := pm.db.QueryContext(ctx, `SELECT user_id FROM user_projects WHERE project_id = ?`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to query project members: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return fmt.Errorf("failed to scan project member: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	pm.InvalidateUser(ctx, userIDs...)
+	return nil
+}
+
+// GetUserProjects returns a user's active project memberships
+func (pm *ProjectMembers) GetUserProjects(ctx context.Context, userID string) ([]*UserTenantProject, error) {
+	return pm.query(ctx, `SELECT `+projectMemberColumns+` FROM user_projects
+		WHERE user_id = ? AND is_active = 1 ORDER BY joined_at`, userID)
+}
+
+// GetProjectMembers returns the active members of a project
+func (pm *ProjectMembers) GetProjectMembers(ctx context.Context, projectID string) ([]*UserTenantProject, error) {
+	return pm.query(ctx, `SELECT `+projectMemberColumns+` FROM user_projects
+		WHERE project_id = ? AND is_active = 1 ORDER BY joined_at`, projectID)
+}
+
+// AddUserToProject adds a user to a project with a role, reactivating a
+// previous membership
+func (pm *ProjectMembers) AddUserToProject(ctx context.Context, userID, projectID, role, invitedBy string) error {
+	tenantID, err := pm.ProjectTenantID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	defer pm.InvalidateUser(ctx, userID)
+	return pm.upsert(ctx, pm.db, &UserTenantProject{
+		UserID:           userID,
+		TenantID:         tenantID,
+		ProjectID:        projectID,
+		Role:             role,
+		InvitedBy:        invitedBy,
+		CanInvite:        role == ProjectRoleOwner || role == ProjectRoleCollaborator,
+		CanManageMembers: role == ProjectRoleOwner,
+	})
+}
+
+// TransferProjectOwnership makes toUserID an owner of the project. The
+// previous creator stays an owner; a previous owner becomes a collaborator.
+func (pm *ProjectMembers) TransferProjectOwnership(ctx context.Context, projectID, fromUserID, toUserID string) error {
+	tx, err := pm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var role, tenantID string
+	err = tx.QueryRowContext(ctx,
+		`SELECT role, tenant_id FROM user_projects WHERE user_id = ? AND project_id = ? AND is_active = 1`,
+		fromUserID, projectID).Scan(&role, &tenantID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user %s is not a member of project %s", fromUserID, projectID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get project member: %w", err)
+	}
+	if role != ProjectRoleCreator && role != ProjectRoleOwner {
+		return fmt.Errorf("user %s does not have permission to transfer ownership", fromUserID)
+	}
+
+	err = pm.upsert(ctx, tx, &UserTenantProject{
+		UserID:           toUserID,
+		TenantID:         tenantID,
+		ProjectID:        projectID,
+		Role:             ProjectRoleOwner,
+		InvitedBy:        fromUserID,
+		CanInvite:        true,
+		CanManageMembers: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	newRole := ProjectRoleCollaborator
+	if role == ProjectRoleCreator {
+		newRole = ProjectRoleOwner // Creator can't be demoted fully
+	}
+	_, err = tx.ExecContext(ctx,
+		`UPDATE user_projects SET role = ?, can_manage_members = ? WHERE user_id = ? AND project_id = ?`,
+		newRole, newRole == ProjectRoleOwner, fromUserID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to update previous owner: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	pm.InvalidateUser(ctx, fromUserID, toUserID)
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (pm *ProjectMembers) upsert(ctx context.Context, db execer, member *UserTenantProject) error {
+	now := time.Now()
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO user_projects (id, user_id, tenant_id, project_id, role, is_active,
+			invited_by, invited_at, joined_at, can_invite, can_manage_members)
+		VALUES (?, ?, ?, ?, ?, 1, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, project_id) DO UPDATE SET
+			role = excluded.role, is_active = 1, left_at = NULL,
+			can_invite = excluded.can_invite, can_manage_members = excluded.can_manage_members`,
+		generateUUID(), member.UserID, member.TenantID, member.ProjectID, member.Role,
+		member.InvitedBy, now, now, member.CanInvite, member.CanManageMembers)
+	if err != nil {
+		return fmt.Errorf("failed to save project member: %w", err)
+	}
+	return nil
+}
+
+func (pm *ProjectMembers) query(ctx context.Context, query string, args ...interface{}) ([]*UserTenantProject, error) {
+	rows, err := pm.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*UserTenantProject{}
+	for rows.Next() {
+		var member UserTenantProject
+		var id, invitedBy sql.NullString
+		var joinedAt, leftAt sql.NullTime
+		err := rows.Scan(&id, &member.UserID, &member.TenantID, &member.ProjectID, &member.Role,
+			&member.IsActive, &member.IsCreator, &invitedBy, &joinedAt, &leftAt,
+			&member.IsExternalCollaborator, &member.CanInvite, &member.CanManageMembers)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project member: %w", err)
+		}
+		member.ID = id.String
+		member.InvitedBy = invitedBy.String
+		member.JoinedAt = joinedAt.Time
+		if leftAt.Valid {
+			member.LeftAt = &leftAt.Time
+		}
+		members = append(members, &member)
+	}
+	return members, rows.Err()
+}
+
+func effectiveRolesKey(userID string) string {
+	return "members:roles:" + userID
+}
+
+func rolesByProject(members []*UserTenantProject) map[string]*UserTenantProject {
+	roles := make(map[string]*UserTenantProject, len(members))
+	for _, member := range members {
+		roles[member.ProjectID] = member
+	}
+	return roles
+}
+
+// prefixColumns qualifies a comma separated column list with a table alias
+func prefixColumns(prefix, columns string) string {
+	fields := strings.Split(columns, ",")
+	for i, field := range fields {
+		fields[i] = prefix + strings.TrimSpace(field)
+	}
+	return strings.Join(fields, ", ")
+}
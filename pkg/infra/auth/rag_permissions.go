@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// RAG permissions granted to project roles
+const (
+	PermRAGQuery         = "rag:query"
+	PermRAGIndex         = "rag:index"
+	PermRAGManageSources = "rag:manage_sources"
+	PermRAGViewAnalytics = "rag:view_analytics"
+	PermRAGManagePrompts = "rag:manage_prompts"
+)
+
+// RAGPermissions lists every RAG permission
+var RAGPermissions = []string{
+	PermRAGQuery,
+	PermRAGIndex,
+	PermRAGManageSources,
+	PermRAGViewAnalytics,
+	PermRAGManagePrompts,
+}
+
+// RAGPolicy maps project roles to the RAG permissions they are granted. Roles
+// missing from a policy keep their default permissions.
+type RAGPolicy map[string][]string
+
+// DefaultRAGPolicy returns the permissions each role has unless a project
+// overrides them. Viewers and collaborators may query and see analytics;
+// indexing and configuration are left to owners.
+func DefaultRAGPolicy() RAGPolicy {
+	return RAGPolicy{
+		ProjectRoleViewer:       {PermRAGQuery, PermRAGViewAnalytics},
+		ProjectRoleCollaborator: {PermRAGQuery, PermRAGViewAnalytics},
+		ProjectRoleOwner:        slices.Clone(RAGPermissions),
+		ProjectRoleCreator:      slices.Clone(RAGPermissions),
+	}
+}
+
+// Allows reports whether role is granted permission. Creators, owners and
+// super admins always hold every permission so a project cannot lock itself
+// out of its own configuration.
+func (p RAGPolicy) Allows(role, permission string) bool {
+	switch role {
+	case RoleSuperAdmin, ProjectRoleCreator, ProjectRoleOwner:
+		return true
+	}
+	granted, ok := p[role]
+	if !ok {
+		granted = DefaultRAGPolicy()[role]
+	}
+	return slices.Contains(granted, permission)
+}
+
+// Effective returns the permissions of every project role after applying p
+// over the defaults
+func (p RAGPolicy) Effective() RAGPolicy {
+	effective := DefaultRAGPolicy()
+	for role, permissions := range p {
+		if _, ok := effective[role]; ok && role != ProjectRoleOwner && role != ProjectRoleCreator {
+			effective[role] = permissions
+		}
+	}
+	return effective
+}
+
+// Validate checks that p only names overridable roles and known permissions
+func (p RAGPolicy) Validate() error {
+	for role, permissions := range p {
+		switch role {
+		case ProjectRoleViewer, ProjectRoleCollaborator:
+		case ProjectRoleOwner, ProjectRoleCreator:
+			return fmt.Errorf("permissions of role %s cannot be changed", role)
+		default:
+			return fmt.Errorf("unknown project role %q", role)
+		}
+		for _, permission := range permissions {
+			if !slices.Contains(RAGPermissions, permission) {
+				return fmt.Errorf("unknown permission %q", permission)
+			}
+		}
+	}
+	return nil
+}
+
+// GetRAGPolicy returns the RAG permission overrides of a project. Policies
+// are cached alongside effective roles since every RAG request checks one.
+func (pm *ProjectMembers) GetRAGPolicy(ctx context.Context, projectID string) (RAGPolicy, error) {
+	store := pm.store()
+	key := ragPolicyKey(projectID)
+	var policy RAGPolicy
+	if data, err := store.Get(ctx, key); err == nil && json.Unmarshal(data, &policy) == nil {
+		return policy, nil
+	}
+
+	rows, err := pm.db.QueryContext(ctx,
+		`SELECT role, permissions FROM project_rag_permissions WHERE project_id = ?`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rag permissions: %w", err)
+	}
+	defer rows.Close()
+
+	policy = RAGPolicy{}
+	for rows.Next() {
+		var role, data string
+		if err := rows.Scan(&role, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan rag permissions: %w", err)
+		}
+		var permissions []string
+		if err := json.Unmarshal([]byte(data), &permissions); err != nil {
+			return nil, fmt.Errorf("invalid rag permissions for role %s: %w", role, err)
+		}
+		policy[role] = permissions
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(policy); err == nil {
+		store.Set(ctx, key, data, pm.ttl)
+	}
+	return policy, nil
+}
+
+// SetRAGPolicy replaces the RAG permission overrides of a project. Roles left
+// out of policy return to their defaults.
+func (pm *ProjectMembers) SetRAGPolicy(ctx context.Context, projectID string, policy RAGPolicy, updatedBy string) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	tx, err := pm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM project_rag_permissions WHERE project_id = ?`, projectID); err != nil {
+		return fmt.Errorf("failed to clear rag permissions: %w", err)
+	}
+	now := time.Now()
+	for role, permissions := range policy {
+		if permissions == nil {
+			permissions = []string{}
+		}
+		data, err := json.Marshal(permissions)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO project_rag_permissions (project_id, role, permissions, updated_by, updated_at)
+			VALUES (?, ?, ?, ?, ?)`, projectID, role, string(data), updatedBy, now)
+		if err != nil {
+			return fmt.Errorf("failed to save rag permissions: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	pm.store().Delete(ctx, ragPolicyKey(projectID))
+	return nil
+}
+
+func ragPolicyKey(projectID string) string {
+	return "members:rag_policy:" + projectID
+}
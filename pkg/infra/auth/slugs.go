@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/pkg/common/errors"
+)
+
+// Slug length limits
+const (
+	MinSlugLength = 2
+	MaxSlugLength = 63
+)
+
+// Kinds of slugged resources
+const (
+	SlugKindTenant  = "tenant"
+	SlugKindProject = "project"
+)
+
+// slugPattern allows lowercase letters and digits in hyphen-separated words
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// reservedSlugs clash with routes, subdomains or well-known pages
+var reservedSlugs = map[string]bool{
+	"admin": true, "api": true, "app": true, "assets": true, "auth": true,
+	"by-slug": true, "dashboard": true, "debug": true, "docs": true, "help": true,
+	"integrations": true, "internal": true, "keys": true, "login": true, "logout": true,
+	"mcp": true, "me": true, "new": true, "public": true, "root": true,
+	"settings": true, "signup": true, "static": true, "status": true, "support": true,
+	"system": true, "www": true,
+}
+
+// ValidateSlug checks a slug's charset, length and reserved words
+func ValidateSlug(slug string) error {
+	if len(slug) < MinSlugLength || len(slug) > MaxSlugLength {
+		return errors.Validation(fmt.Sprintf("slug must be %d-%d characters", MinSlugLength, MaxSlugLength))
+	}
+	if !slugPattern.MatchString(slug) {
+		return errors.Validation("slug must be lowercase letters and digits separated by single hyphens")
+	}
+	if reservedSlugs[slug] {
+		return errors.Validation(fmt.Sprintf("slug %q is reserved", slug))
+	}
+	return nil
+}
+
+// NormalizeSlug derives a slug from a name: letters are lowercased, other
+// characters become hyphens and the result is trimmed to MaxSlugLength.
+// Names without ASCII letters or digits give an empty slug.
+func NormalizeSlug(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		case r == '\'':
+			// Keep contractions together: "Bob's" becomes "bobs"
+		default:
+			hyphen = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > MaxSlugLength {
+		slug = strings.TrimRight(slug[:MaxSlugLength], "-")
+	}
+	return slug
+}
+
+// Slugs keeps tenant slugs unique and project slugs unique per tenant, and
+// remembers renamed slugs so links using them still resolve
+type Slugs struct {
+	db *sql.DB
+}
+
+// NewSlugs creates a slug registry on the tenant database
+func NewSlugs(db *sql.DB) *Slugs {
+	return &Slugs{db: db}
+}
+
+// Check validates a slug and returns a Conflict error when it is used,
+// currently or as a redirect, by anything but ownerID. Projects are scoped
+// by tenant ID; tenants use an empty scope.
+func (s *Slugs) Check(ctx context.Context, kind, scope, slug, ownerID string) error {
+	if err := ValidateSlug(slug); err != nil {
+		return err
+	}
+	id, found, _, err := s.lookup(ctx, kind, scope, slug)
+	if err != nil {
+		return err
+	}
+	if found && id != ownerID {
+		return errors.Conflict(fmt.Sprintf("%s slug %q is already taken", kind, slug))
+	}
+	return nil
+}
+
+// Unique derives a free slug from a name, appending -2, -3 and so on when
+// it is taken. fallback is used when the name gives no usable slug.
+func (s *Slugs) Unique(ctx context.Context, kind, scope, name, fallback string) (string, error) {
+	base := NormalizeSlug(name)
+	if ValidateSlug(base) != nil {
+		base = fallback
+	}
+	slug := base
+	for n := 2; ; n++ {
+		_, found, _, err := s.lookup(ctx, kind, scope, slug)
+		if err != nil {
+			return "", err
+		}
+		if !found && ValidateSlug(slug) == nil {
+			return slug, nil
+		}
+		suffix := fmt.Sprintf("-%d", n)
+		if len(base)+len(suffix) > MaxSlugLength {
+			base = strings.TrimRight(base[:MaxSlugLength-len(suffix)], "-")
+		}
+		slug = base + suffix
+	}
+}
+
+// Renamed records oldSlug as a redirect to ownerID after its slug changed
+// to newSlug. A redirect previously left at newSlug is dropped, so renaming
+// back and forth keeps a single current slug.
+func (s *Slugs) Renamed(ctx context.Context, kind, scope, ownerID, oldSlug, newSlug string) error {
+	if oldSlug == "" || oldSlug == newSlug {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO slug_redirects (kind, scope, slug, target_id, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(kind, scope, slug) DO UPDATE SET target_id = excluded.target_id, created_at = excluded.created_at`,
+		kind, scope, oldSlug, ownerID, time.Now())
+	if err == nil {
+		_, err = s.db.ExecContext(ctx, `DELETE FROM slug_redirects WHERE kind = ? AND scope = ? AND slug = ?`,
+			kind, scope, newSlug)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record slug redirect: %w", err)
+	}
+	return nil
+}
+
+// Resolve returns the ID of the tenant or project a slug belongs to and
+// whether the slug is an old one that now redirects
+func (s *Slugs) Resolve(ctx context.Context, kind, scope, slug string) (string, bool, error) {
+	id, found, redirected, err := s.lookup(ctx, kind, scope, slug)
+	if err == nil && !found {
+		err = errors.NotFound(kind)
+	}
+	return id, redirected, err
+}
+
+// lookup finds the owner of a slug, checking current slugs before redirects
+func (s *Slugs) lookup(ctx context.Context, kind, scope, slug string) (id string, found, redirected bool, err error) {
+	var query string
+	var args []interface{}
+	switch kind {
+	case SlugKindTenant:
+		query, args = `SELECT id FROM tenants WHERE slug = ?`, []interface{}{slug}
+	case SlugKindProject:
+		query, args = `SELECT id FROM projects WHERE tenant_id = ? AND slug = ?`, []interface{}{scope, slug}
+	default:
+		return "", false, false, fmt.Errorf("unknown slug kind %q", kind)
+	}
+
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&id)
+	if err == nil {
+		return id, true, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", false, false, fmt.Errorf("failed to look up slug: %w", err)
+	}
+	err = s.db.QueryRowContext(ctx, `SELECT target_id FROM slug_redirects WHERE kind = ? AND scope = ? AND slug = ?`,
+		kind, scope, slug).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", false, false, nil
+	}
+	if err != nil {
+		return "", false, false, fmt.Errorf("failed to look up slug redirect: %w", err)
+	}
+	return id, true, true, nil
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -17,6 +18,10 @@ type Tenant struct {
 	Logo        string `json:"logo,omitempty" yaml:"logo,omitempty"`
 	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 
+	// Data residency region; documents, embeddings and backups stay in the
+	// region's storage. Empty for tenants without a residency requirement.
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+
 	// Configuration
 	Settings TenantSettings         `json:"settings" yaml:"settings"`
 	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
@@ -46,8 +51,13 @@ type TenantSettings struct {
 	RequireTwoFactor         bool `json:"require_two_factor"`
 	SessionTimeout           int  `json:"session_timeout_minutes"`
 
-	// Features
-	EnabledFeatures []string `json:"enabled_features,omitempty"`
+	// Temporary project role grants wait for a second admin's approval
+	RequireGrantApproval bool `json:"require_grant_approval,omitempty"`
+
+	// Features; Features overrides feature flags and takes precedence over
+	// EnabledFeatures, which only turns flags on
+	EnabledFeatures []string        `json:"enabled_features,omitempty"`
+	Features        map[string]bool `json:"features,omitempty"`
 
 	// UI Customization
 	Theme     ThemeSettings `json:"theme,omitempty"`
@@ -57,6 +67,16 @@ type TenantSettings struct {
 	// Integration
 	WebhookURL string            `json:"webhook_url,omitempty"`
 	Webhooks   map[string]string `json:"webhooks,omitempty"`
+
+	// API settings such as CORS, in the tenant domain's APISettings format
+	API json.RawMessage `json:"api,omitempty"`
+
+	// Alert notification targets, in the tenant domain's NotificationSettings format
+	Notifications json.RawMessage `json:"notifications,omitempty"`
+
+	// Locale of emails, notifications and API messages for users without
+	// their own locale, e.g. "zh-CN"
+	Locale string `json:"locale,omitempty"`
 }
 
 // ThemeSettings defines UI theme customization
@@ -117,8 +137,10 @@ type ProjectSettings struct {
 	RequireAuthForWrite bool     `json:"require_auth_for_write"`
 	AllowedOrigins      []string `json:"allowed_origins,omitempty"`
 
-	// Features
-	EnabledFeatures []string `json:"enabled_features,omitempty"`
+	// Features; Features overrides feature flags and takes precedence over
+	// EnabledFeatures, which only turns flags on
+	EnabledFeatures []string        `json:"enabled_features,omitempty"`
+	Features        map[string]bool `json:"features,omitempty"`
 
 	// Rate limiting
 	RateLimit RateLimitSettings `json:"rate_limit,omitempty"`
@@ -202,6 +202,130 @@ var TenantMigrations = []Migration{
 			DELETE FROM tenants WHERE id = 'system';
 		`,
 	},
+	{
+		ID:          "006_add_tenant_region",
+		Version:     "006",
+		Name:        "Add tenant region",
+		Description: "Adds the data residency region of each tenant",
+		UpSQL: `
+			ALTER TABLE tenants ADD COLUMN region TEXT NOT NULL DEFAULT '';
+		`,
+		DownSQL: `
+			ALTER TABLE tenants DROP COLUMN region;
+		`,
+	},
+	{
+		ID:          "007_add_user_projects_active_indexes",
+		Version:     "007",
+		Name:        "Add active membership indexes",
+		Description: "Indexes active memberships by user and by project for batched permission lookups",
+		UpSQL: `
+			CREATE INDEX IF NOT EXISTS idx_user_projects_user_active ON user_projects(user_id, is_active);
+			CREATE INDEX IF NOT EXISTS idx_user_projects_project_active ON user_projects(project_id, is_active);
+		`,
+		DownSQL: `
+			DROP INDEX IF EXISTS idx_user_projects_user_active;
+			DROP INDEX IF EXISTS idx_user_projects_project_active;
+		`,
+	},
+	{
+		ID:          "008_create_project_rag_permissions_table",
+		Version:     "008",
+		Name:        "Create project RAG permissions table",
+		Description: "Stores per-project overrides of the RAG permissions granted to each role",
+		UpSQL: `
+			CREATE TABLE IF NOT EXISTS project_rag_permissions (
+				project_id TEXT NOT NULL,
+				role TEXT NOT NULL,
+				permissions TEXT NOT NULL DEFAULT '[]',
+				updated_by TEXT,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (project_id, role),
+				FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+			);
+		`,
+		DownSQL: `
+			DROP TABLE IF EXISTS project_rag_permissions;
+		`,
+	},
+	{
+		ID:          "009_create_project_role_grants_table",
+		Version:     "009",
+		Name:        "Create project role grants table",
+		Description: "Creates the table of time-boxed elevated project roles",
+		UpSQL: `
+			CREATE TABLE IF NOT EXISTS project_role_grants (
+				id TEXT PRIMARY KEY,
+				project_id TEXT NOT NULL,
+				tenant_id TEXT NOT NULL,
+				user_id TEXT NOT NULL,
+				role TEXT NOT NULL,
+				reason TEXT,
+				duration_seconds INTEGER NOT NULL,
+				status TEXT NOT NULL,
+				requested_by TEXT NOT NULL,
+				decided_by TEXT,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				starts_at DATETIME,
+				expires_at DATETIME,
+				ended_at DATETIME,
+				FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_project_role_grants_user ON project_role_grants(user_id, status);
+			CREATE INDEX IF NOT EXISTS idx_project_role_grants_project ON project_role_grants(project_id, created_at);
+			CREATE INDEX IF NOT EXISTS idx_project_role_grants_expiry ON project_role_grants(status, expires_at);
+		`,
+		DownSQL: `
+			DROP TABLE IF EXISTS project_role_grants;
+		`,
+	},
+	{
+		ID:          "010_create_project_ownership_transfers_table",
+		Version:     "010",
+		Name:        "Create project ownership transfers table",
+		Description: "Creates the table of ownership transfers awaiting acceptance by the new owner",
+		UpSQL: `
+			CREATE TABLE IF NOT EXISTS project_ownership_transfers (
+				id TEXT PRIMARY KEY,
+				project_id TEXT NOT NULL,
+				from_user_id TEXT NOT NULL,
+				to_user_id TEXT NOT NULL,
+				message TEXT,
+				status TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				expires_at DATETIME NOT NULL,
+				decided_at DATETIME,
+				FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_project_ownership_transfers_project ON project_ownership_transfers(project_id, status);
+		`,
+		DownSQL: `
+			DROP TABLE IF EXISTS project_ownership_transfers;
+		`,
+	},
+	{
+		ID:          "011_create_slug_redirects_table",
+		Version:     "011",
+		Name:        "Create slug redirects table",
+		Description: "Keeps renamed tenant and project slugs resolving to their owners",
+		UpSQL: `
+			CREATE TABLE IF NOT EXISTS slug_redirects (
+				kind TEXT NOT NULL,
+				scope TEXT NOT NULL DEFAULT '',
+				slug TEXT NOT NULL,
+				target_id TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (kind, scope, slug)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_slug_redirects_target ON slug_redirects(kind, target_id);
+		`,
+		DownSQL: `
+			DROP TABLE IF EXISTS slug_redirects;
+		`,
+	},
 }
 
 // Migration represents a database migration
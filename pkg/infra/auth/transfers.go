@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/common/errors"
+)
+
+// Ownership transfer statuses
+const (
+	TransferStatusPending   = "pending"
+	TransferStatusAccepted  = "accepted"
+	TransferStatusDeclined  = "declined"
+	TransferStatusCancelled = "cancelled"
+	TransferStatusExpired   = "expired"
+)
+
+// DefaultTransferExpiry is how long the new owner has to accept a transfer
+const DefaultTransferExpiry = 7 * 24 * time.Hour
+
+// OwnershipTransfer is a request to make another project member an owner.
+// Nothing changes until the target accepts it before it expires.
+type OwnershipTransfer struct {
+	ID         string     `json:"id"`
+	ProjectID  string     `json:"project_id"`
+	FromUserID string     `json:"from_user_id"`
+	ToUserID   string     `json:"to_user_id"`
+	Message    string     `json:"message,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	DecidedAt  *time.Time `json:"decided_at,omitempty"`
+}
+
+const transferColumns = `id, project_id, from_user_id, to_user_id, message, status, created_at, expires_at, decided_at`
+
+// RequestOwnershipTransfer records a pending transfer from an owner or
+// creator of the project to another active member. A project has at most one
+// pending transfer.
+func (pm *ProjectMembers) RequestOwnershipTransfer(ctx context.Context, projectID, fromUserID, toUserID, message string, expiry time.Duration) (*OwnershipTransfer, error) {
+	if fromUserID == toUserID {
+		return nil, errors.InvalidInput("cannot transfer ownership to yourself")
+	}
+	roles, err := pm.memberRoles(ctx, projectID, fromUserID, toUserID)
+	if err != nil {
+		return nil, err
+	}
+	if role := roles[fromUserID]; role != ProjectRoleCreator && role != ProjectRoleOwner {
+		return nil, errors.Forbidden("only project owners can transfer ownership")
+	}
+	if _, ok := roles[toUserID]; !ok {
+		return nil, errors.InvalidInput(fmt.Sprintf("user %s is not a member of project %s", toUserID, projectID))
+	}
+	if pending, err := pm.PendingOwnershipTransfer(ctx, projectID); err != nil {
+		return nil, err
+	} else if pending != nil {
+		return nil, errors.Conflict("project already has a pending ownership transfer")
+	}
+
+	if expiry <= 0 {
+		expiry = DefaultTransferExpiry
+	}
+	now := time.Now()
+	transfer := &OwnershipTransfer{
+		ID:         generateUUID(),
+		ProjectID:  projectID,
+		FromUserID: fromUserID,
+		ToUserID:   toUserID,
+		Message:    message,
+		Status:     TransferStatusPending,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(expiry),
+	}
+	_, err = pm.db.ExecContext(ctx, `
+		INSERT INTO project_ownership_transfers (`+transferColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULL)`,
+		transfer.ID, transfer.ProjectID, transfer.FromUserID, transfer.ToUserID, transfer.Message,
+		transfer.Status, transfer.CreatedAt, transfer.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save ownership transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// AcceptOwnershipTransfer completes a pending transfer on behalf of its
+// target. The previous owner's role changes as in TransferProjectOwnership.
+func (pm *ProjectMembers) AcceptOwnershipTransfer(ctx context.Context, projectID, transferID, userID string) (*OwnershipTransfer, error) {
+	transfer, err := pm.pendingTransferFor(ctx, projectID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != userID {
+		return nil, errors.Forbidden("only the new owner can accept an ownership transfer")
+	}
+	// Claim the transfer first so it is accepted at most once
+	if err := pm.decideTransfer(ctx, transfer, TransferStatusAccepted); err != nil {
+		return nil, err
+	}
+	if err := pm.TransferProjectOwnership(ctx, projectID, transfer.FromUserID, transfer.ToUserID); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// DeclineOwnershipTransfer refuses a pending transfer on behalf of its target
+func (pm *ProjectMembers) DeclineOwnershipTransfer(ctx context.Context, projectID, transferID, userID string) (*OwnershipTransfer, error) {
+	transfer, err := pm.pendingTransferFor(ctx, projectID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.ToUserID != userID {
+		return nil, errors.Forbidden("only the new owner can decline an ownership transfer")
+	}
+	if err := pm.decideTransfer(ctx, transfer, TransferStatusDeclined); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// CancelOwnershipTransfer withdraws a pending transfer on behalf of the owner
+// who requested it
+func (pm *ProjectMembers) CancelOwnershipTransfer(ctx context.Context, projectID, transferID, userID string) (*OwnershipTransfer, error) {
+	transfer, err := pm.pendingTransferFor(ctx, projectID, transferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.FromUserID != userID {
+		return nil, errors.Forbidden("only the requesting owner can cancel an ownership transfer")
+	}
+	if err := pm.decideTransfer(ctx, transfer, TransferStatusCancelled); err != nil {
+		return nil, err
+	}
+	return transfer, nil
+}
+
+// PendingOwnershipTransfer returns the project's unexpired pending transfer,
+// or nil when there is none
+func (pm *ProjectMembers) PendingOwnershipTransfer(ctx context.Context, projectID string) (*OwnershipTransfer, error) {
+	transfers, err := pm.queryTransfers(ctx, `SELECT `+transferColumns+` FROM project_ownership_transfers
+		WHERE project_id = ? AND status = ? AND expires_at > ? ORDER BY created_at DESC LIMIT 1`,
+		projectID, TransferStatusPending, time.Now())
+	if err != nil || len(transfers) == 0 {
+		return nil, err
+	}
+	return transfers[0], nil
+}
+
+// ListOwnershipTransfers returns the transfers of a project, newest first
+func (pm *ProjectMembers) ListOwnershipTransfers(ctx context.Context, projectID string) ([]*OwnershipTransfer, error) {
+	return pm.queryTransfers(ctx, `SELECT `+transferColumns+` FROM project_ownership_transfers
+		WHERE project_id = ? ORDER BY created_at DESC`, projectID)
+}
+
+// pendingTransferFor returns a transfer that can still be decided
+func (pm *ProjectMembers) pendingTransferFor(ctx context.Context, projectID, transferID string) (*OwnershipTransfer, error) {
+	transfers, err := pm.queryTransfers(ctx, `SELECT `+transferColumns+` FROM project_ownership_transfers
+		WHERE id = ? AND project_id = ?`, transferID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(transfers) == 0 {
+		return nil, errors.NotFound("ownership transfer")
+	}
+	if transfer := transfers[0]; transfer.Status != TransferStatusPending {
+		return nil, errors.Conflict(fmt.Sprintf("ownership transfer is %s", transfer.Status))
+	}
+	return transfers[0], nil
+}
+
+// decideTransfer moves a pending, unexpired transfer to status
+func (pm *ProjectMembers) decideTransfer(ctx context.Context, transfer *OwnershipTransfer, status string) error {
+	now := time.Now()
+	result, err := pm.db.ExecContext(ctx, `
+		UPDATE project_ownership_transfers SET status = ?, decided_at = ?
+		WHERE id = ? AND status = ? AND expires_at > ?`,
+		status, now, transfer.ID, TransferStatusPending, now)
+	if err != nil {
+		return fmt.Errorf("failed to update ownership transfer: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errors.Conflict("ownership transfer is no longer pending")
+	}
+	transfer.Status = status
+	transfer.DecidedAt = &now
+	return nil
+}
+
+// memberRoles returns the membership roles of the given users in a project.
+// Temporary role grants do not count; only members can give or take ownership.
+func (pm *ProjectMembers) memberRoles(ctx context.Context, projectID string, userIDs ...string) (map[string]string, error) {
+	members, err := pm.GetProjectMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make(map[string]string, len(userIDs))
+	for _, member := range members {
+		for _, userID := range userIDs {
+			if member.UserID == userID {
+				roles[userID] = member.Role
+			}
+		}
+	}
+	return roles, nil
+}
+
+func (pm *ProjectMembers) queryTransfers(ctx context.Context, query string, args ...interface{}) ([]*OwnershipTransfer, error) {
+	rows, err := pm.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ownership transfers: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	transfers := []*OwnershipTransfer{}
+	for rows.Next() {
+		var transfer OwnershipTransfer
+		var message sql.NullString
+		var decidedAt sql.NullTime
+		err := rows.Scan(&transfer.ID, &transfer.ProjectID, &transfer.FromUserID, &transfer.ToUserID,
+			&message, &transfer.Status, &transfer.CreatedAt, &transfer.ExpiresAt, &decidedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ownership transfer: %w", err)
+		}
+		transfer.Message = message.String
+		transfer.DecidedAt = nullTimePtr(decidedAt)
+		if transfer.Status == TransferStatusPending && !now.Before(transfer.ExpiresAt) {
+			transfer.Status = TransferStatusExpired
+		}
+		transfers = append(transfers, &transfer)
+	}
+	return transfers, rows.Err()
+}
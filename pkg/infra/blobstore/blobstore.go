@@ -0,0 +1,132 @@
+// Package blobstore stores binary objects such as uploaded documents, logos
+// and export archives on local disk or in S3-compatible object storage
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store types
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+)
+
+// Errors returned by stores and PutLimited
+var (
+	ErrNotFound        = errors.New("blob not found")
+	ErrInvalidKey      = errors.New("invalid blob key")
+	ErrTooLarge        = errors.New("blob exceeds the size limit")
+	ErrTypeNotAllowed  = errors.New("blob content type is not allowed")
+	errUnsupportedType = errors.New("unsupported blob store type")
+)
+
+// Info describes a stored blob
+type Info struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type,omitempty"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// Store keeps blobs under slash-separated keys such as originals/p1/abc
+type Store interface {
+	// Put writes a blob, replacing any blob with the same key
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error)
+
+	// Get opens a blob for reading. Local blobs also implement io.Seeker.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Info, error)
+
+	// Stat returns a blob's metadata
+	Stat(ctx context.Context, key string) (*Info, error)
+
+	// Delete removes a blob; deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+
+	// List calls fn for every blob whose key starts with prefix
+	List(ctx context.Context, prefix string, fn func(Info) error) error
+}
+
+// Presigner is implemented by stores that can hand out time-limited URLs,
+// so clients download straight from the store instead of through the API
+type Presigner interface {
+	PresignGet(ctx context.Context, key, filename string, ttl time.Duration) (string, error)
+}
+
+// Config selects and configures a store
+type Config struct {
+	Type string `json:"type"` // local (default) or s3
+
+	// Local directory
+	Dir string `json:"dir,omitempty"`
+
+	// S3-compatible storage; Endpoint defaults to AWS for Region
+	Endpoint        string `json:"endpoint,omitempty"`
+	Region          string `json:"region,omitempty"`
+	Bucket          string `json:"bucket,omitempty"`
+	AccessKeyID     string `json:"-"`
+	SecretAccessKey string `json:"-"`
+	PathStyle       bool   `json:"path_style,omitempty"` // bucket in the path, as MinIO expects
+}
+
+// ConfigFromEnv reads METABASE_BLOB_STORE and the METABASE_BLOB_DIR or
+// METABASE_S3_* settings; AWS_* credentials are used when the S3 ones are unset
+func ConfigFromEnv() *Config {
+	cfg := &Config{
+		Type:            os.Getenv("METABASE_BLOB_STORE"),
+		Dir:             os.Getenv("METABASE_BLOB_DIR"),
+		Endpoint:        os.Getenv("METABASE_S3_ENDPOINT"),
+		Region:          os.Getenv("METABASE_S3_REGION"),
+		Bucket:          os.Getenv("METABASE_S3_BUCKET"),
+		AccessKeyID:     os.Getenv("METABASE_S3_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("METABASE_S3_SECRET_ACCESS_KEY"),
+	}
+	cfg.PathStyle, _ = strconv.ParseBool(os.Getenv("METABASE_S3_PATH_STYLE"))
+	if cfg.AccessKeyID == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Type == "" {
+		cfg.Type = TypeLocal
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "./data/blobs"
+	}
+	return cfg
+}
+
+// Open creates the store described by cfg
+func Open(cfg *Config) (Store, error) {
+	switch cfg.Type {
+	case "", TypeLocal:
+		return NewLocalStore(cfg.Dir)
+	case TypeS3:
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedType, cfg.Type)
+	}
+}
+
+// validKey rejects keys that are empty, absolute or escape their prefix.
+// Segments may not start with a dot, which the local store reserves.
+func validKey(key string) error {
+	if key == "" || strings.Contains(key, "\\") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || strings.HasPrefix(part, ".") {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
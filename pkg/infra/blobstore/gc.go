@@ -0,0 +1,41 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GCReport summarizes a garbage collection run
+type GCReport struct {
+	Scanned    int   `json:"scanned"`
+	Deleted    int   `json:"deleted"`
+	FreedBytes int64 `json:"freed_bytes"`
+}
+
+// CollectGarbage deletes blobs under prefix that referenced does not claim.
+// Blobs younger than grace are kept, so an upload whose reference has not
+// been recorded yet is not lost.
+func CollectGarbage(ctx context.Context, store Store, prefix string, grace time.Duration, referenced func(key string) bool) (*GCReport, error) {
+	report := &GCReport{}
+	cutoff := time.Now().Add(-grace)
+	var garbage []Info
+	err := store.List(ctx, prefix, func(info Info) error {
+		report.Scanned++
+		if info.ModTime.Before(cutoff) && !referenced(info.Key) {
+			garbage = append(garbage, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+	for _, info := range garbage {
+		if err := store.Delete(ctx, info.Key); err != nil {
+			return report, fmt.Errorf("failed to delete blob %s: %w", info.Key, err)
+		}
+		report.Deleted++
+		report.FreedBytes += info.Size
+	}
+	return report, nil
+}
@@ -0,0 +1,94 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Limits restricts what may be stored, typically from a tenant's storage settings
+type Limits struct {
+	// MaxSize in bytes, 0 for no limit
+	MaxSize int64
+
+	// AllowedTypes are MIME types ("application/pdf"), wildcards ("image/*")
+	// or file extensions (".md"); empty allows any type
+	AllowedTypes []string
+}
+
+// Allows reports whether a blob named name with the given content type may be stored
+func (l Limits) Allows(name, contentType string) bool {
+	if len(l.AllowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = contentType
+	}
+	extension := strings.ToLower(path.Ext(name))
+	for _, allowed := range l.AllowedTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		switch {
+		case strings.HasPrefix(allowed, "."):
+			if extension == allowed {
+				return true
+			}
+		case strings.HasSuffix(allowed, "/*"):
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+				return true
+			}
+		case mediaType == allowed:
+			return true
+		}
+	}
+	return false
+}
+
+// Check validates a blob's name, content type and size against the limits
+func (l Limits) Check(name, contentType string, size int64) error {
+	if l.MaxSize > 0 && size > l.MaxSize {
+		return fmt.Errorf("%w of %d bytes", ErrTooLarge, l.MaxSize)
+	}
+	if !l.Allows(name, contentType) {
+		return fmt.Errorf("%w: %s", ErrTypeNotAllowed, contentType)
+	}
+	return nil
+}
+
+// DetectType sniffs the content type from the first bytes of a blob. The
+// declared type is only trusted when sniffing is inconclusive, so a client
+// cannot label HTML as an image.
+func DetectType(head []byte, declared string) string {
+	sniffed := http.DetectContentType(head)
+	if declared == "" {
+		return sniffed
+	}
+	mediaType, _, _ := mime.ParseMediaType(sniffed)
+	if mediaType == "application/octet-stream" || mediaType == "text/plain" {
+		return declared
+	}
+	return sniffed
+}
+
+// PutLimited reads r, detects its content type and stores it when it is
+// within limits; name is only used to match extensions
+func PutLimited(ctx context.Context, store Store, key, name string, r io.Reader, declared string, limits Limits) (*Info, error) {
+	reader := r
+	if limits.MaxSize > 0 {
+		reader = io.LimitReader(r, limits.MaxSize+1)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	contentType := DetectType(content, declared)
+	if err := limits.Check(name, contentType, int64(len(content))); err != nil {
+		return nil, err
+	}
+	return store.Put(ctx, key, bytes.NewReader(content), contentType)
+}
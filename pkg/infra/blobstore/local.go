@@ -0,0 +1,173 @@
+package blobstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// metaDir holds each blob's content type, next to the blobs themselves
+const metaDir = ".meta"
+
+// LocalStore keeps blobs as files under a directory
+type LocalStore struct {
+	dir string
+}
+
+type localMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+// NewLocalStore creates a store in dir, creating it if needed
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+// Put implements Store. The blob is written to a temporary file and renamed,
+// so readers never see a partial blob.
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	target := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+
+	meta, _ := json.Marshal(localMeta{ContentType: contentType})
+	metaPath := s.metaPath(key)
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(metaPath, meta, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	stat, err := os.Stat(target)
+	if err != nil {
+		return nil, err
+	}
+	return &Info{Key: key, Size: size, ContentType: contentType, ModTime: stat.ModTime()}, nil
+}
+
+// Get implements Store; the reader is an *os.File
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *Info, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, nil, notFound(err)
+	}
+	return file, info, nil
+}
+
+// Stat implements Store
+func (s *LocalStore) Stat(ctx context.Context, key string) (*Info, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(s.path(key))
+	if err != nil {
+		return nil, notFound(err)
+	}
+	info := &Info{Key: key, Size: stat.Size(), ModTime: stat.ModTime()}
+	if data, err := os.ReadFile(s.metaPath(key)); err == nil {
+		var meta localMeta
+		if json.Unmarshal(data, &meta) == nil {
+			info.ContentType = meta.ContentType
+		}
+	}
+	return info, nil
+}
+
+// Delete implements Store
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.metaPath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List implements Store, in lexical key order
+func (s *LocalStore) List(ctx context.Context, prefix string, fn func(Info) error) error {
+	return filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if path != s.dir && (name == metaDir || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(name, ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		info, err := s.Stat(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fn(*info)
+	})
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *LocalStore) metaPath(key string) string {
+	return filepath.Join(s.dir, metaDir, filepath.FromSlash(key)+".json")
+}
+
+func notFound(err error) error {
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
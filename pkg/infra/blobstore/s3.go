@@ -0,0 +1,384 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3Service        = "s3"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3MaxPresignTime = 7 * 24 * time.Hour
+)
+
+// S3Store keeps blobs in an S3-compatible bucket (AWS S3, MinIO, R2, GCS
+// interoperability), signing requests with AWS Signature Version 4
+type S3Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewS3Store creates a store for cfg.Bucket
+func NewS3Store(cfg *Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 credentials are required")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+	return &S3Store{
+		endpoint:  u,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		pathStyle: cfg.PathStyle,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		now:       time.Now,
+	}, nil
+}
+
+// Put implements Store. The body is buffered to sign its checksum.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, contentType string) (*Info, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	sum := sha256.Sum256(content)
+	resp, err := s.do(req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to put blob %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return &Info{Key: key, Size: int64(len(content)), ContentType: contentType, ModTime: s.now()}, nil
+}
+
+// Get implements Store
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, *Info, error) {
+	if err := validKey(key); err != nil {
+		return nil, nil, err
+	}
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.do(req, s3UnsignedBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, responseInfo(key, resp), nil
+}
+
+// Stat implements Store
+func (s *S3Store) Stat(ctx context.Context, key string) (*Info, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, s3UnsignedBody)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return responseInfo(key, resp), nil
+}
+
+// Delete implements Store
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, s3UnsignedBody)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", key, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listResult is the part of a ListObjectsV2 response the store uses
+type listResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+// List implements Store; listings do not include content types
+func (s *S3Store) List(ctx context.Context, prefix string, fn func(Info) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := s.do(req, s3UnsignedBody)
+		if err != nil {
+			return fmt.Errorf("failed to list blobs: %w", err)
+		}
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("invalid list response: %w", err)
+		}
+		for _, object := range result.Contents {
+			if err := fn(Info{Key: object.Key, Size: object.Size, ModTime: object.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// PresignGet implements Presigner with a query-signed URL that downloads
+// the blob as an attachment named filename
+func (s *S3Store) PresignGet(ctx context.Context, key, filename string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	if ttl <= 0 || ttl > s3MaxPresignTime {
+		return "", fmt.Errorf("presigned url lifetime must be between 1s and %s", s3MaxPresignTime)
+	}
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(amzDate[:8])
+
+	u := s.objectURL(key)
+	query := url.Values{
+		"X-Amz-Algorithm":     {s3Algorithm},
+		"X-Amz-Credential":    {s.accessKey + "/" + scope},
+		"X-Amz-Date":          {amzDate},
+		"X-Amz-Expires":       {strconv.Itoa(int(ttl.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if filename != "" {
+		query.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedBody,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(amzDate, scope, canonical))
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// objectURL addresses key, or the bucket when key is empty
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	objectPath := "/" + key
+	if s.pathStyle {
+		objectPath = "/" + s.bucket + objectPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = u.Path + objectPath
+	u.RawPath = encodePath(u.Path)
+	return &u
+}
+
+func (s *S3Store) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := s.objectURL(key)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends req, turning error responses into errors
+func (s *S3Store) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	var failure struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(body, &failure) == nil && failure.Code != "" {
+		return nil, fmt.Errorf("s3 returned %d %s: %s", resp.StatusCode, failure.Code, failure.Message)
+	}
+	return nil, fmt.Errorf("s3 returned %d", resp.StatusCode)
+}
+
+// sign adds a Signature Version 4 Authorization header covering the host
+// and every header already set on req
+func (s *S3Store) sign(req *http.Request, payloadHash string) {
+	amzDate := s.now().UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := s.scope(amzDate[:8])
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.accessKey, scope, signedHeaders, s.signature(amzDate, scope, canonical)))
+}
+
+func (s *S3Store) scope(date string) string {
+	return date + "/" + s.region + "/" + s3Service + "/aws4_request"
+}
+
+func (s *S3Store) signature(amzDate, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := s3Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), amzDate[:8])
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s3Service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func responseInfo(key string, resp *http.Response) *Info {
+	info := &Info{Key: key, Size: resp.ContentLength, ContentType: resp.Header.Get("Content-Type")}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modified
+	}
+	return info
+}
+
+// canonicalQuery encodes a query sorted by key as Signature Version 4 requires
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// encodePath escapes each segment of an object path
+func encodePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode escapes everything but unreserved characters, and slashes
+// unless encodeSlash is set
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+// Package events is an in-process publish/subscribe bus for change
+// notifications between components, such as caches that must drop entries
+// when the resources behind them change.
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// TopicResourceChanged is published after a resource is created, updated or
+// deleted; the event key names the resource scope, e.g. "project:<id>"
+const TopicResourceChanged = "resource.changed"
+
+// Event is a notification on a topic
+type Event struct {
+	Topic string
+	Key   string
+}
+
+// Handler receives events of a subscribed topic
+type Handler func(ctx context.Context, event Event)
+
+// Bus delivers events to the handlers subscribed to their topic. Handlers run
+// synchronously in the publishing goroutine, in subscription order.
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[string]map[int]Handler
+	order    map[string][]int
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[string]map[int]Handler),
+		order:    make(map[string][]int),
+	}
+}
+
+// Subscribe registers handler for topic and returns a function removing it
+func (b *Bus) Subscribe(topic string, handler Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	if b.handlers[topic] == nil {
+		b.handlers[topic] = make(map[int]Handler)
+	}
+	b.handlers[topic][id] = handler
+	b.order[topic] = append(b.order[topic], id)
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers[topic], id)
+		ids := b.order[topic]
+		for i, existing := range ids {
+			if existing == id {
+				b.order[topic] = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish delivers event to the handlers of its topic
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.order[event.Topic]))
+	for _, id := range b.order[event.Topic] {
+		handlers = append(handlers, b.handlers[event.Topic][id])
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
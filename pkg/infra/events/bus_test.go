@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewBus()
+	var received []string
+	record := func(name string) Handler {
+		return func(ctx context.Context, event Event) {
+			received = append(received, name+":"+event.Key)
+		}
+	}
+
+	unsubscribe := bus.Subscribe(TopicResourceChanged, record("a"))
+	bus.Subscribe(TopicResourceChanged, record("b"))
+	bus.Subscribe("other", record("c"))
+
+	bus.Publish(ctx, Event{Topic: TopicResourceChanged, Key: "project:p1"})
+	unsubscribe()
+	bus.Publish(ctx, Event{Topic: TopicResourceChanged, Key: "tenant:t1"})
+
+	if got := strings.Join(received, ","); got != "a:project:p1,b:project:p1,b:tenant:t1" {
+		t.Fatalf("unexpected deliveries %s", got)
+	}
+}
@@ -0,0 +1,34 @@
+package features
+
+import "context"
+
+// Set is the evaluated flags of one tenant, by key
+type Set map[string]bool
+
+// NewSet collects evaluations into a set
+func NewSet(evaluations []Evaluation) Set {
+	set := make(Set, len(evaluations))
+	for _, evaluation := range evaluations {
+		set[evaluation.Key] = evaluation.Enabled
+	}
+	return set
+}
+
+type contextKey struct{}
+
+// WithSet returns a context carrying evaluated flags
+func WithSet(ctx context.Context, set Set) context.Context {
+	return context.WithValue(ctx, contextKey{}, set)
+}
+
+// FromContext returns the evaluated flags carried by ctx, or nil
+func FromContext(ctx context.Context) Set {
+	set, _ := ctx.Value(contextKey{}).(Set)
+	return set
+}
+
+// Enabled reports whether a flag is enabled in the flags carried by ctx.
+// Contexts without flags, such as background jobs, have every flag disabled.
+func Enabled(ctx context.Context, key string) bool {
+	return FromContext(ctx)[key]
+}
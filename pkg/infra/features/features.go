@@ -0,0 +1,324 @@
+// Package features evaluates tenant feature flags. A flag is enabled for a
+// tenant by, in order of precedence, the tenant's own override, the default
+// of the tenant's plan, a percentage rollout, and finally the flag default.
+// Handlers and the RAG pipeline read evaluated flags from the request context.
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/infra/kv"
+)
+
+// Evaluation reasons
+const (
+	ReasonOverride = "override" // Set in the tenant's settings
+	ReasonPlan     = "plan"     // Default of the tenant's plan
+	ReasonRollout  = "rollout"  // Tenant falls inside the rollout percentage
+	ReasonDefault  = "default"  // Flag default
+	ReasonUnknown  = "unknown"  // No such flag
+)
+
+// defaultCacheTTL bounds how long other instances serve stale flags and
+// tenant settings
+const defaultCacheTTL = time.Minute
+
+// ErrNotFound is returned when a flag is not defined
+var ErrNotFound = errors.New("features: flag not found")
+
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,99}$`)
+
+// Flag defines a feature flag
+type Flag struct {
+	Key         string          `json:"key"`
+	Description string          `json:"description,omitempty"`
+	Default     bool            `json:"default"`
+	Plans       map[string]bool `json:"plans,omitempty"`   // Plan name -> enabled
+	Rollout     int             `json:"rollout,omitempty"` // Percentage of tenants enabled, 0-100
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Validate checks the flag key and rollout percentage
+func (f *Flag) Validate() error {
+	if !flagKeyPattern.MatchString(f.Key) {
+		return fmt.Errorf("flag key must be lowercase letters, digits, '.', '_' or '-'")
+	}
+	if f.Rollout < 0 || f.Rollout > 100 {
+		return fmt.Errorf("rollout must be between 0 and 100")
+	}
+	return nil
+}
+
+// Evaluation is the value of a flag for a tenant
+type Evaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason"`
+}
+
+// TenantFlags holds what flag evaluation needs to know about a tenant
+type TenantFlags struct {
+	Plan      string          `json:"plan"`
+	Overrides map[string]bool `json:"overrides,omitempty"`
+}
+
+// TenantLoader returns a tenant's plan and flag overrides, or nil if the
+// tenant does not exist
+type TenantLoader func(ctx context.Context, tenantID string) (*TenantFlags, error)
+
+// Service evaluates feature flags for tenants. Flags are defined in code with
+// Register and can be redefined at runtime through the store; stored
+// definitions take precedence.
+type Service struct {
+	store  Store
+	loader TenantLoader
+	cache  kv.Store
+	ttl    time.Duration
+
+	mu         sync.RWMutex
+	registered map[string]Flag
+}
+
+// NewService creates a flag service. loader may be nil, in which case
+// tenants only get flag defaults and rollouts.
+func NewService(store Store, loader TenantLoader) *Service {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Service{
+		store:      store,
+		loader:     loader,
+		cache:      kv.NewMemoryStore(0),
+		ttl:        defaultCacheTTL,
+		registered: make(map[string]Flag),
+	}
+}
+
+// SetCacheStore replaces the cache of flag definitions and tenant settings,
+// for example with a store shared between instances so invalidations reach
+// every instance. Call it before the service is used.
+func (s *Service) SetCacheStore(cache kv.Store, ttl time.Duration) {
+	s.cache = cache
+	if ttl > 0 {
+		s.ttl = ttl
+	}
+}
+
+// Register defines flags in code. Invalid flags panic, as they are
+// programming errors.
+func (s *Service) Register(flags ...Flag) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, flag := range flags {
+		if err := flag.Validate(); err != nil {
+			panic(fmt.Sprintf("features: invalid flag %q: %v", flag.Key, err))
+		}
+		s.registered[flag.Key] = flag
+	}
+}
+
+// Flags returns every defined flag sorted by key
+func (s *Service) Flags(ctx context.Context) ([]Flag, error) {
+	stored, err := s.storedFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]Flag)
+	s.mu.RLock()
+	for key, flag := range s.registered {
+		byKey[key] = flag
+	}
+	s.mu.RUnlock()
+	for _, flag := range stored {
+		byKey[flag.Key] = flag
+	}
+
+	flags := make([]Flag, 0, len(byKey))
+	for _, flag := range byKey {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+// Flag returns a flag definition, or ErrNotFound
+func (s *Service) Flag(ctx context.Context, key string) (*Flag, error) {
+	flags, err := s.Flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, flag := range flags {
+		if flag.Key == key {
+			return &flag, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// SaveFlag creates or replaces a stored flag definition
+func (s *Service) SaveFlag(ctx context.Context, flag *Flag) error {
+	if err := flag.Validate(); err != nil {
+		return err
+	}
+	now := time.Now()
+	if existing, err := s.Flag(ctx, flag.Key); err == nil && !existing.CreatedAt.IsZero() {
+		flag.CreatedAt = existing.CreatedAt
+	} else {
+		flag.CreatedAt = now
+	}
+	flag.UpdatedAt = now
+
+	if err := s.store.SaveFlag(ctx, flag); err != nil {
+		return err
+	}
+	s.cache.Delete(ctx, flagsCacheKey)
+	return nil
+}
+
+// DeleteFlag removes a stored flag definition. A flag registered in code
+// reverts to its code definition.
+func (s *Service) DeleteFlag(ctx context.Context, key string) error {
+	if err := s.store.DeleteFlag(ctx, key); err != nil {
+		return err
+	}
+	s.cache.Delete(ctx, flagsCacheKey)
+	return nil
+}
+
+// Invalidate drops a tenant's cached plan and overrides; call it after the
+// tenant's settings change
+func (s *Service) Invalidate(ctx context.Context, tenantID string) {
+	s.cache.Delete(ctx, tenantCacheKey(tenantID))
+}
+
+// Evaluate returns the value of a flag for a tenant. Unknown flags are
+// disabled. tenantID may be empty for requests outside a tenant.
+func (s *Service) Evaluate(ctx context.Context, tenantID, key string) (Evaluation, error) {
+	flag, err := s.Flag(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return Evaluation{Key: key, Reason: ReasonUnknown}, nil
+	}
+	if err != nil {
+		return Evaluation{}, err
+	}
+	tenant, err := s.tenant(ctx, tenantID)
+	if err != nil {
+		return Evaluation{}, err
+	}
+	return evaluate(flag, tenantID, tenant), nil
+}
+
+// EvaluateAll returns the value of every flag for a tenant
+func (s *Service) EvaluateAll(ctx context.Context, tenantID string) ([]Evaluation, error) {
+	flags, err := s.Flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenant, err := s.tenant(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluations := make([]Evaluation, len(flags))
+	for i := range flags {
+		evaluations[i] = evaluate(&flags[i], tenantID, tenant)
+	}
+	return evaluations, nil
+}
+
+// Enabled reports whether a flag is enabled for a tenant; errors disable it
+func (s *Service) Enabled(ctx context.Context, tenantID, key string) bool {
+	evaluation, err := s.Evaluate(ctx, tenantID, key)
+	return err == nil && evaluation.Enabled
+}
+
+// evaluate applies the precedence rules to one flag
+func evaluate(flag *Flag, tenantID string, tenant *TenantFlags) Evaluation {
+	evaluation := Evaluation{Key: flag.Key}
+	if tenant != nil {
+		if enabled, ok := tenant.Overrides[flag.Key]; ok {
+			evaluation.Enabled, evaluation.Reason = enabled, ReasonOverride
+			return evaluation
+		}
+		if enabled, ok := flag.Plans[tenant.Plan]; ok && tenant.Plan != "" {
+			evaluation.Enabled, evaluation.Reason = enabled, ReasonPlan
+			return evaluation
+		}
+	}
+	if tenantID != "" && flag.Rollout > 0 && rolloutBucket(flag.Key, tenantID) < flag.Rollout {
+		evaluation.Enabled, evaluation.Reason = true, ReasonRollout
+		return evaluation
+	}
+	evaluation.Enabled, evaluation.Reason = flag.Default, ReasonDefault
+	return evaluation
+}
+
+// rolloutBucket places a tenant in one of 100 buckets per flag, so raising
+// the rollout percentage only adds tenants and flags roll out independently
+func rolloutBucket(key, tenantID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(tenantID))
+	return int(h.Sum32() % 100)
+}
+
+const flagsCacheKey = "features:flags"
+
+func tenantCacheKey(tenantID string) string {
+	return "features:tenant:" + tenantID
+}
+
+// storedFlags returns the stored flag definitions through the cache
+func (s *Service) storedFlags(ctx context.Context) ([]Flag, error) {
+	if data, err := s.cache.Get(ctx, flagsCacheKey); err == nil {
+		var flags []Flag
+		if json.Unmarshal(data, &flags) == nil {
+			return flags, nil
+		}
+	}
+
+	flags, err := s.store.ListFlags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+	if data, err := json.Marshal(flags); err == nil {
+		s.cache.Set(ctx, flagsCacheKey, data, s.ttl)
+	}
+	return flags, nil
+}
+
+// tenant returns a tenant's plan and overrides through the cache; nil
+// results are cached too
+func (s *Service) tenant(ctx context.Context, tenantID string) (*TenantFlags, error) {
+	if tenantID == "" || s.loader == nil {
+		return nil, nil
+	}
+
+	key := tenantCacheKey(tenantID)
+	if data, err := s.cache.Get(ctx, key); err == nil {
+		var tenant *TenantFlags
+		if json.Unmarshal(data, &tenant) == nil {
+			return tenant, nil
+		}
+	}
+
+	tenant, err := s.loader(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant feature settings: %w", err)
+	}
+	if data, err := json.Marshal(tenant); err == nil {
+		s.cache.Set(ctx, key, data, s.ttl)
+	}
+	return tenant, nil
+}
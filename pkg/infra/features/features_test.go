@@ -0,0 +1,157 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestEvaluatePrecedence(t *testing.T) {
+	ctx := context.Background()
+	tenants := map[string]*TenantFlags{
+		"acme":    {Plan: "enterprise", Overrides: map[string]bool{"rag.rerank": false}},
+		"globex":  {Plan: "enterprise"},
+		"initech": {Plan: "free"},
+	}
+	loads := 0
+	service := NewService(nil, func(ctx context.Context, tenantID string) (*TenantFlags, error) {
+		loads++
+		return tenants[tenantID], nil
+	})
+	service.Register(Flag{Key: "rag.rerank", Plans: map[string]bool{"enterprise": true}})
+
+	cases := []struct {
+		tenant  string
+		enabled bool
+		reason  string
+	}{
+		{"acme", false, ReasonOverride},
+		{"globex", true, ReasonPlan},
+		{"initech", false, ReasonDefault},
+		{"", false, ReasonDefault},
+	}
+	for _, c := range cases {
+		evaluation, err := service.Evaluate(ctx, c.tenant, "rag.rerank")
+		if err != nil || evaluation.Enabled != c.enabled || evaluation.Reason != c.reason {
+			t.Fatalf("tenant %q: got %+v, %v", c.tenant, evaluation, err)
+		}
+	}
+	if evaluation, _ := service.Evaluate(ctx, "acme", "missing"); evaluation.Enabled || evaluation.Reason != ReasonUnknown {
+		t.Fatalf("unknown flags should be disabled, got %+v", evaluation)
+	}
+
+	// Tenant settings are cached until invalidated
+	tenants["initech"].Overrides = map[string]bool{"rag.rerank": true}
+	if service.Enabled(ctx, "initech", "rag.rerank") {
+		t.Fatal("cached tenant settings should apply until invalidated")
+	}
+	service.Invalidate(ctx, "initech")
+	if !service.Enabled(ctx, "initech", "rag.rerank") {
+		t.Fatal("expected override after invalidation")
+	}
+	if loads != 4 {
+		t.Fatalf("expected one load per tenant plus one after invalidation, got %d", loads)
+	}
+
+	// Stored definitions replace code definitions until deleted
+	if err := service.SaveFlag(ctx, &Flag{Key: "rag.rerank", Default: true}); err != nil {
+		t.Fatal(err)
+	}
+	if evaluation, _ := service.Evaluate(ctx, "globex", "rag.rerank"); evaluation.Reason != ReasonDefault || !evaluation.Enabled {
+		t.Fatalf("expected stored definition, got %+v", evaluation)
+	}
+	if err := service.DeleteFlag(ctx, "rag.rerank"); err != nil {
+		t.Fatal(err)
+	}
+	if evaluation, _ := service.Evaluate(ctx, "globex", "rag.rerank"); evaluation.Reason != ReasonPlan {
+		t.Fatalf("expected code definition after delete, got %+v", evaluation)
+	}
+}
+
+func TestRolloutIsStable(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(nil, nil)
+
+	enabledAt := func(rollout int) map[string]bool {
+		if err := service.SaveFlag(ctx, &Flag{Key: "chat.v2", Rollout: rollout}); err != nil {
+			t.Fatal(err)
+		}
+		enabled := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			tenantID := fmt.Sprintf("tenant-%d", i)
+			if service.Enabled(ctx, tenantID, "chat.v2") {
+				enabled[tenantID] = true
+			}
+		}
+		return enabled
+	}
+
+	quarter := enabledAt(25)
+	if len(quarter) < 180 || len(quarter) > 320 {
+		t.Fatalf("expected about 250 of 1000 tenants at 25%%, got %d", len(quarter))
+	}
+	half := enabledAt(50)
+	for tenantID := range quarter {
+		if !half[tenantID] {
+			t.Fatalf("raising the rollout disabled %s", tenantID)
+		}
+	}
+	if len(enabledAt(100)) != 1000 || len(enabledAt(0)) != 0 {
+		t.Fatal("0% and 100% rollouts should cover no and all tenants")
+	}
+
+	if err := service.SaveFlag(ctx, &Flag{Key: "Bad Key"}); err == nil {
+		t.Fatal("expected invalid key to be rejected")
+	}
+	if err := service.SaveFlag(ctx, &Flag{Key: "x", Rollout: 101}); err == nil {
+		t.Fatal("expected invalid rollout to be rejected")
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "flags.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := NewSQLStore(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(store, nil)
+	if err := service.SaveFlag(ctx, &Flag{Key: "uploads", Plans: map[string]bool{"pro": true}}); err != nil {
+		t.Fatal(err)
+	}
+	created := mustFlag(t, service, "uploads").CreatedAt
+	if err := service.SaveFlag(ctx, &Flag{Key: "uploads", Default: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second service over the same database sees the stored flag
+	other := NewService(store, nil)
+	flag := mustFlag(t, other, "uploads")
+	if !flag.Default || flag.Plans != nil || !flag.CreatedAt.Equal(created) {
+		t.Fatalf("unexpected stored flag %+v", flag)
+	}
+	if err := store.DeleteFlag(ctx, "uploads"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteFlag(ctx, "uploads"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func mustFlag(t *testing.T, service *Service, key string) *Flag {
+	t.Helper()
+	flag, err := service.Flag(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return flag
+}
@@ -0,0 +1,134 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Store keeps runtime flag definitions
+type Store interface {
+	// ListFlags returns every stored flag
+	ListFlags(ctx context.Context) ([]Flag, error)
+
+	// SaveFlag creates or replaces a flag
+	SaveFlag(ctx context.Context, flag *Flag) error
+
+	// DeleteFlag removes a flag, or returns ErrNotFound
+	DeleteFlag(ctx context.Context, key string) error
+}
+
+// MemoryStore keeps flag definitions in memory, for tests and
+// single-instance deployments without persistence
+type MemoryStore struct {
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+// NewMemoryStore creates an empty in-memory flag store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{flags: make(map[string]Flag)}
+}
+
+// ListFlags returns every stored flag
+func (s *MemoryStore) ListFlags(ctx context.Context) ([]Flag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flags := make([]Flag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flags = append(flags, flag)
+	}
+	return flags, nil
+}
+
+// SaveFlag creates or replaces a flag
+func (s *MemoryStore) SaveFlag(ctx context.Context, flag *Flag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags[flag.Key] = *flag
+	return nil
+}
+
+// DeleteFlag removes a flag
+func (s *MemoryStore) DeleteFlag(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.flags[key]; !exists {
+		return ErrNotFound
+	}
+	delete(s.flags, key)
+	return nil
+}
+
+// SQLStore keeps flag definitions in a table of the shared database, so every
+// instance sees the same flags. Queries use ? placeholders.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a database flag store, creating its table if needed
+func NewSQLStore(ctx context.Context, db *sql.DB) (*SQLStore, error) {
+	_, err := db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS feature_flags (
+		flag_key TEXT PRIMARY KEY,
+		definition TEXT NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feature flags table: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// ListFlags returns every stored flag
+func (s *SQLStore) ListFlags(ctx context.Context) ([]Flag, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT definition FROM feature_flags ORDER BY flag_key`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []Flag
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		var flag Flag
+		if err := json.Unmarshal([]byte(definition), &flag); err != nil {
+			return nil, fmt.Errorf("invalid feature flag definition: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+	return flags, rows.Err()
+}
+
+// SaveFlag creates or replaces a flag
+func (s *SQLStore) SaveFlag(ctx context.Context, flag *Flag) error {
+	definition, err := json.Marshal(flag)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feature flag: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+	INSERT INTO feature_flags (flag_key, definition, updated_at) VALUES (?, ?, ?)
+	ON CONFLICT(flag_key) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		flag.Key, string(definition), flag.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save feature flag: %w", err)
+	}
+	return nil
+}
+
+// DeleteFlag removes a flag
+func (s *SQLStore) DeleteFlag(ctx context.Context, key string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM feature_flags WHERE flag_key = ?`, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
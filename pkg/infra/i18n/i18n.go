@@ -0,0 +1,137 @@
+// Package i18n renders user-facing messages from embedded catalogs in the
+// locale of the user, their tenant or the server default.
+//
+// Messages are looked up by key. API error messages use their English text as
+// the key, so a message missing from a catalog is shown in English; longer
+// texts such as emails use dotted keys defined in the English catalog.
+// Placeholders are written as {name} and filled from Args.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultLocale is used when no locale in the chain has a catalog
+const DefaultLocale = "en"
+
+//go:embed catalogs/*.json
+var catalogFS embed.FS
+
+// catalogs maps a locale, e.g. "zh-CN", to its messages
+var catalogs = mustLoad()
+
+// Args fills the {name} placeholders of a message
+type Args map[string]string
+
+func mustLoad() map[string]map[string]string {
+	entries, err := catalogFS.ReadDir("catalogs")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to read catalogs: %v", err))
+	}
+	loaded := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := catalogFS.ReadFile(path.Join("catalogs", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read catalog %s: %v", entry.Name(), err))
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog %s: %v", entry.Name(), err))
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	return loaded
+}
+
+// Locales returns the locales that have a catalog
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// Match returns the catalog locale serving a language tag, or "" when there
+// is none. Tags match case-insensitively, then by their base language, so
+// "zh-cn" and "zh-TW" are served by "zh-CN" and "en-GB" by "en".
+func Match(tag string) string {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	for locale := range catalogs {
+		if strings.EqualFold(locale, tag) {
+			return locale
+		}
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	best := ""
+	for locale := range catalogs {
+		localeBase, _, _ := strings.Cut(locale, "-")
+		if !strings.EqualFold(localeBase, base) {
+			continue
+		}
+		// Prefer the bare language, then the first region alphabetically
+		if best == "" || !strings.Contains(locale, "-") || (strings.Contains(best, "-") && locale < best) {
+			best = locale
+		}
+	}
+	return best
+}
+
+// Resolve returns the first locale of the chain that has a catalog, such as
+// user, tenant and server default, falling back to DefaultLocale
+func Resolve(chain ...string) string {
+	for _, tag := range chain {
+		if locale := Match(tag); locale != "" {
+			return locale
+		}
+	}
+	return DefaultLocale
+}
+
+// Translate renders the message key in locale, falling back to the default
+// catalog and then to the key itself
+func Translate(locale, key string, args Args) string {
+	message, ok := catalogs[Match(locale)][key]
+	if !ok {
+		message, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		message = key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	pairs := make([]string, 0, 2*len(args))
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+type localeKey struct{}
+
+// WithLocale stores the resolved locale of a request in ctx
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// FromContext returns the locale stored in ctx, or DefaultLocale
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	return DefaultLocale
+}
+
+// T renders the message key in the locale stored in ctx
+func T(ctx context.Context, key string, args Args) string {
+	return Translate(FromContext(ctx), key, args)
+}
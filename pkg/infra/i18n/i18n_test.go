@@ -0,0 +1,76 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMatchAndResolve(t *testing.T) {
+	cases := map[string]string{
+		"zh-CN": "zh-CN",
+		"zh_cn": "zh-CN",
+		"zh-TW": "zh-CN",
+		"en-GB": "en",
+		"EN":    "en",
+		"fr":    "",
+		"":      "",
+	}
+	for tag, want := range cases {
+		if got := Match(tag); got != want {
+			t.Errorf("Match(%q) = %q, want %q", tag, got, want)
+		}
+	}
+	// User, tenant, server default: the first supported locale wins
+	if got := Resolve("fr", "zh-CN", "en"); got != "zh-CN" {
+		t.Fatalf("expected tenant locale, got %q", got)
+	}
+	if got := Resolve("", "de", ""); got != DefaultLocale {
+		t.Fatalf("expected default locale, got %q", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	args := Args{"tenant": "Acme", "start": "2026-01-05", "end": "2026-01-11"}
+	if got := Translate("zh-CN", "report.email.subject", args); got != "Acme 周报 2026-01-05 至 2026-01-11" {
+		t.Fatalf("unexpected zh-CN subject %q", got)
+	}
+	if got := Translate("fr", "report.email.subject", args); got != "Acme weekly report 2026-01-05 to 2026-01-11" {
+		t.Fatalf("expected English fallback, got %q", got)
+	}
+	// Messages missing from every catalog are rendered as their key
+	if got := Translate("zh-CN", "Something went wrong", nil); got != "Something went wrong" {
+		t.Fatalf("expected key fallback, got %q", got)
+	}
+
+	ctx := WithLocale(context.Background(), "zh-CN")
+	if got := T(ctx, "Tenant not found", nil); got != "租户不存在" {
+		t.Fatalf("unexpected translation %q", got)
+	}
+	if got := FromContext(context.Background()); got != DefaultLocale {
+		t.Fatalf("expected default locale without one in context, got %q", got)
+	}
+}
+
+// Every dotted key in a catalog must exist in the English catalog, which
+// defines them
+func TestCatalogKeys(t *testing.T) {
+	for _, locale := range Locales() {
+		for key := range catalogs[locale] {
+			if !isMessageID(key) {
+				continue
+			}
+			if _, ok := catalogs[DefaultLocale][key]; !ok {
+				t.Errorf("%s defines %q, which the %s catalog lacks", locale, key, DefaultLocale)
+			}
+		}
+	}
+}
+
+func isMessageID(key string) bool {
+	for _, r := range key {
+		if r == ' ' {
+			return false
+		}
+	}
+	return true
+}
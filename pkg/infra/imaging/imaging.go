@@ -0,0 +1,137 @@
+// Package imaging validates uploaded images and renders them at standard sizes
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"  // registers the GIF decoder
+	_ "image/jpeg" // registers the JPEG decoder
+	"image/png"
+)
+
+// Errors returned by Decode
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooManyPixels     = errors.New("image dimensions are too large")
+)
+
+// Formats Decode accepts
+var Formats = []string{"png", "jpeg", "gif"}
+
+// Decode decodes a PNG, JPEG or GIF image after checking from its header
+// that it has at most maxPixels pixels, so oversized images are rejected
+// before they are decompressed
+func Decode(content []byte, maxPixels int) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	supported := false
+	for _, f := range Formats {
+		supported = supported || f == format
+	}
+	if !supported {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, "", fmt.Errorf("%w: %dx%d", ErrTooManyPixels, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	return img, format, nil
+}
+
+// Fit scales src to fit a size×size square, preserving its aspect ratio,
+// and centers it on a transparent background
+func Fit(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, size*bounds.Dy()/bounds.Dx())
+	} else {
+		width = max(1, size*bounds.Dx()/bounds.Dy())
+	}
+	scaled := Resize(src, width, height)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	offset := image.Pt((size-width)/2, (size-height)/2)
+	draw.Draw(dst, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Src)
+	return dst
+}
+
+// Resize scales src to width×height by averaging the source pixels each
+// destination pixel covers, which keeps downscaled logos free of aliasing
+func Resize(src image.Image, width, height int) *image.RGBA {
+	// Premultiplied RGBA averages correctly across transparent edges
+	rgba := image.NewRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, src.Bounds().Min, draw.Src)
+
+	horizontal := resample(rgba.Pix, rgba.Bounds().Dx(), rgba.Bounds().Dy(), width, true)
+	vertical := resample(horizontal, width, rgba.Bounds().Dy(), height, false)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	copy(dst.Pix, vertical)
+	return dst
+}
+
+// resample scales tightly packed RGBA pixels along one axis to size
+func resample(pix []uint8, width, height, size int, horizontal bool) []uint8 {
+	length, lines := height, width
+	outWidth, outHeight := width, size
+	if horizontal {
+		length, lines = width, height
+		outWidth, outHeight = size, height
+	}
+	out := make([]uint8, outWidth*outHeight*4)
+	scale := float64(length) / float64(size)
+	offset := func(line, pos int) int {
+		if horizontal {
+			return (line*width + pos) * 4
+		}
+		return (pos*width + line) * 4
+	}
+	outOffset := func(line, pos int) int {
+		if horizontal {
+			return (line*outWidth + pos) * 4
+		}
+		return (pos*outWidth + line) * 4
+	}
+
+	for line := 0; line < lines; line++ {
+		for pos := 0; pos < size; pos++ {
+			start, end := float64(pos)*scale, float64(pos+1)*scale
+			var sum [4]float64
+			var total float64
+			for i := int(start); i < length && float64(i) < end; i++ {
+				// Weight by how much of source pixel i the interval covers
+				weight := min(end, float64(i+1)) - max(start, float64(i))
+				if weight <= 0 {
+					continue
+				}
+				at := offset(line, i)
+				for c := 0; c < 4; c++ {
+					sum[c] += weight * float64(pix[at+c])
+				}
+				total += weight
+			}
+			at := outOffset(line, pos)
+			for c := 0; c < 4; c++ {
+				out[at+c] = uint8(sum[c]/total + 0.5)
+			}
+		}
+	}
+	return out
+}
+
+// EncodePNG encodes img as a PNG
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encode(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	content := encode(t, image.NewRGBA(image.Rect(0, 0, 40, 20)))
+	img, format, err := Decode(content, 1000)
+	if err != nil || format != "png" || img.Bounds().Dx() != 40 {
+		t.Fatalf("unexpected decode %v %s %v", img.Bounds(), format, err)
+	}
+	if _, _, err := Decode(content, 799); !errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("expected ErrTooManyPixels, got %v", err)
+	}
+	if _, _, err := Decode([]byte("<svg></svg>"), 1000); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestFit(t *testing.T) {
+	// A wide image with a red left half and a blue right half
+	src := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 100 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	dst := Fit(src, 32)
+	if dst.Bounds().Dx() != 32 || dst.Bounds().Dy() != 32 {
+		t.Fatalf("unexpected size %v", dst.Bounds())
+	}
+	// Scaled to 32x16 and centered, leaving transparent bands above and below
+	if _, _, _, a := dst.At(16, 2).RGBA(); a != 0 {
+		t.Fatal("expected transparent padding")
+	}
+	if r, _, b, a := dst.At(4, 16).RGBA(); r>>8 != 255 || b != 0 || a>>8 != 255 {
+		t.Fatalf("expected red on the left, got %v", dst.At(4, 16))
+	}
+	if r, _, b, _ := dst.At(28, 16).RGBA(); r != 0 || b>>8 != 255 {
+		t.Fatalf("expected blue on the right, got %v", dst.At(28, 16))
+	}
+
+	// Upscaling a single pixel fills the square
+	one := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	one.Set(0, 0, color.RGBA{G: 255, A: 255})
+	if _, g, _, _ := Fit(one, 8).At(7, 7).RGBA(); g>>8 != 255 {
+		t.Fatal("expected the upscaled pixel to fill the square")
+	}
+}
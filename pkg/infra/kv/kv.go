@@ -0,0 +1,71 @@
+// Package kv provides expiring key-value stores for state that must be shared
+// between API instances, such as sessions and hot caches. The in-memory store
+// suits single-instance deployments; the database and Redis stores let every
+// instance observe the same state.
+package kv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Store backends
+const (
+	BackendMemory   = "memory"
+	BackendDatabase = "database"
+	BackendRedis    = "redis"
+)
+
+// ErrNotFound is returned by Get when the key is missing or expired
+var ErrNotFound = errors.New("kv: key not found")
+
+// Store is an expiring key-value store
+type Store interface {
+	// Get returns the value of key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set stores value under key. A zero ttl keeps the value until deleted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete removes keys; missing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+
+	// Close releases the store's resources
+	Close() error
+}
+
+// Expirer is implemented by stores that drop expired entries on request
+// rather than automatically
+type Expirer interface {
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// Config selects and configures a store backend
+type Config struct {
+	Backend       string `json:"backend" yaml:"backend"`
+	RedisURL      string `json:"redis_url,omitempty" yaml:"redis_url,omitempty"`
+	RedisPassword string `json:"redis_password,omitempty" yaml:"redis_password,omitempty"`
+	RedisDB       int    `json:"redis_db,omitempty" yaml:"redis_db,omitempty"`
+	MaxEntries    int    `json:"max_entries,omitempty" yaml:"max_entries,omitempty"`
+}
+
+// New creates the store selected by config. db is used by the database
+// backend and may be nil otherwise.
+func New(ctx context.Context, config Config, db *sql.DB) (Store, error) {
+	switch config.Backend {
+	case "", BackendMemory:
+		return NewMemoryStore(config.MaxEntries), nil
+	case BackendDatabase:
+		if db == nil {
+			return nil, fmt.Errorf("database kv store requires a database")
+		}
+		return NewSQLStore(ctx, db)
+	case BackendRedis:
+		return NewRedisStore(config.RedisURL, config.RedisPassword, config.RedisDB)
+	default:
+		return nil, fmt.Errorf("unsupported kv backend: %s", config.Backend)
+	}
+}
@@ -0,0 +1,65 @@
+package kv
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "kv.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	sqlStore, err := NewSQLStore(ctx, db)
+	if err != nil {
+		t.Fatalf("sql store: %v", err)
+	}
+
+	for name, store := range map[string]Store{"memory": NewMemoryStore(0), "database": sqlStore} {
+		if err := store.Set(ctx, "a", []byte("1"), 0); err != nil {
+			t.Fatalf("%s: set: %v", name, err)
+		}
+		store.Set(ctx, "a", []byte("2"), 0)
+		store.Set(ctx, "short", []byte("x"), 20*time.Millisecond)
+
+		if value, err := store.Get(ctx, "a"); err != nil || string(value) != "2" {
+			t.Fatalf("%s: get = %q, %v", name, value, err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		if removed, _ := store.(Expirer).DeleteExpired(ctx); removed != 1 {
+			t.Fatalf("%s: expected 1 expired entry removed, got %d", name, removed)
+		}
+		if _, err := store.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: expired entry returned: %v", name, err)
+		}
+
+		store.Delete(ctx, "a", "missing")
+		if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("%s: deleted entry returned: %v", name, err)
+		}
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	store.Set(ctx, "a", []byte("1"), 0)
+	time.Sleep(time.Millisecond)
+	store.Set(ctx, "b", []byte("2"), 0)
+	store.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := store.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("oldest entry should be evicted")
+	}
+	if _, err := store.Get(ctx, "c"); err != nil {
+		t.Fatalf("newest entry missing: %v", err)
+	}
+}
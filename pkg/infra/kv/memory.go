@@ -0,0 +1,115 @@
+package kv
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+	storedAt  time.Time
+}
+
+// MemoryStore keeps entries in process memory. State is lost on restart and
+// not visible to other instances.
+type MemoryStore struct {
+	mu         sync.Mutex
+	entries    map[string]memoryEntry
+	maxEntries int
+}
+
+// NewMemoryStore creates an in-memory store. When maxEntries is positive the
+// oldest entry is evicted once the store is full.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		entries:    make(map[string]memoryEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+// Get returns the value of key
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(s.entries, key)
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), entry.value...), nil
+}
+
+// Set stores value under key
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, exists := s.entries[key]; !exists && s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		s.deleteExpired(now)
+		if len(s.entries) >= s.maxEntries {
+			s.evictOldest()
+		}
+	}
+
+	entry := memoryEntry{value: append([]byte(nil), value...), storedAt: now}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	s.entries[key] = entry
+	return nil
+}
+
+// Delete removes keys
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// DeleteExpired drops expired entries
+func (s *MemoryStore) DeleteExpired(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteExpired(time.Now()), nil
+}
+
+// Close is a no-op
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// deleteExpired drops expired entries. Caller holds s.mu.
+func (s *MemoryStore) deleteExpired(now time.Time) int64 {
+	var removed int64
+	for key, entry := range s.entries {
+		if !entry.expiresAt.IsZero() && now.After(entry.expiresAt) {
+			delete(s.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// evictOldest drops the least recently stored entry. Caller holds s.mu.
+func (s *MemoryStore) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range s.entries {
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+	if oldestKey != "" {
+		delete(s.entries, oldestKey)
+	}
+}
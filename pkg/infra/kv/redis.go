@@ -0,0 +1,73 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/common/redis"
+)
+
+const redisKeyPrefix = "metabase:kv:"
+
+// RedisStore keeps entries in Redis, which expires them itself
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects lazily to the Redis server at url
+func NewRedisStore(url, password string, db int) (*RedisStore, error) {
+	if url == "" {
+		return nil, fmt.Errorf("redis_url is required for the redis kv store")
+	}
+	client, err := redis.NewClient(url, password, db, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{client: client}, nil
+}
+
+// Get returns the value of key
+func (s *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := s.client.Do(ctx, "GET", redisKeyPrefix+key)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(value), nil
+}
+
+// Set stores value under key
+func (s *RedisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []interface{}{"SET", redisKeyPrefix + key, value}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+		args = append(args, "PX", ms)
+	}
+	_, err := s.client.Do(ctx, args...)
+	return err
+}
+
+// Delete removes keys
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := []interface{}{"DEL"}
+	for _, key := range keys {
+		args = append(args, redisKeyPrefix+key)
+	}
+	_, err := s.client.Do(ctx, args...)
+	return err
+}
+
+// Close closes the connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,84 @@
+package kv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLStore keeps entries in a table of a shared database, for deployments
+// without Redis. Queries use ? placeholders.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a database store, creating its table if needed
+func NewSQLStore(ctx context.Context, db *sql.DB) (*SQLStore, error) {
+	_, err := db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS kv_entries (
+		entry_key TEXT PRIMARY KEY,
+		value BLOB NOT NULL,
+		expires_at_ns INTEGER NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kv table: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// Get returns the value of key
+func (s *SQLStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT value FROM kv_entries WHERE entry_key = ? AND (expires_at_ns = 0 OR expires_at_ns > ?)`,
+		key, time.Now().UnixNano()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kv entry: %w", err)
+	}
+	return value, nil
+}
+
+// Set stores value under key
+func (s *SQLStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).UnixNano()
+	}
+	_, err := s.db.ExecContext(ctx, `
+	INSERT INTO kv_entries (entry_key, value, expires_at_ns) VALUES (?, ?, ?)
+	ON CONFLICT(entry_key) DO UPDATE SET value = excluded.value, expires_at_ns = excluded.expires_at_ns`,
+		key, value, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to set kv entry: %w", err)
+	}
+	return nil
+}
+
+// Delete removes keys
+func (s *SQLStore) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM kv_entries WHERE entry_key = ?`, key); err != nil {
+			return fmt.Errorf("failed to delete kv entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteExpired removes expired rows
+func (s *SQLStore) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM kv_entries WHERE expires_at_ns > 0 AND expires_at_ns <= ?`, time.Now().UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired kv entries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Close is a no-op; the database is owned by the caller
+func (s *SQLStore) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package logsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	// maxPending bounds the lines kept while the endpoint is unreachable, in
+	// batches; the oldest lines are dropped first
+	maxPending = 10
+)
+
+// pushWriter batches log lines and POSTs them to an HTTP endpoint, either as
+// newline-delimited JSON or in the Loki push format. Each Write is one line,
+// as zap writes one entry per call.
+type pushWriter struct {
+	config Config
+	client *http.Client
+
+	mu      sync.Mutex
+	lines   []pushLine
+	dropped int
+
+	flushNow chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+type pushLine struct {
+	at   time.Time
+	line []byte
+}
+
+func newPushWriter(c Config) *pushWriter {
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+	w := &pushWriter{
+		config:   c,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		flushNow: make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *pushWriter) Write(p []byte) (int, error) {
+	line := bytes.TrimRight(p, "\n")
+	w.mu.Lock()
+	w.lines = append(w.lines, pushLine{at: time.Now(), line: append([]byte(nil), line...)})
+	w.trim()
+	full := len(w.lines) >= w.config.BatchSize
+	w.mu.Unlock()
+	if full {
+		select {
+		case w.flushNow <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Close flushes pending lines and stops the background sender
+func (w *pushWriter) Close() error {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+	return nil
+}
+
+func (w *pushWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-w.flushNow:
+			w.flush()
+		case <-w.stop:
+			w.flush()
+			return
+		}
+	}
+}
+
+// flush sends pending lines in batches; a failed batch is put back and
+// retried on the next flush
+func (w *pushWriter) flush() {
+	for {
+		w.mu.Lock()
+		n := len(w.lines)
+		if n > w.config.BatchSize {
+			n = w.config.BatchSize
+		}
+		batch := w.lines[:n:n]
+		w.lines = w.lines[n:]
+		dropped := w.dropped
+		w.dropped = 0
+		w.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := w.send(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "logsink: failed to push %d log lines to %s: %v\n", len(batch), w.config.URL, err)
+			w.mu.Lock()
+			w.lines = append(batch, w.lines...)
+			w.dropped += dropped
+			w.trim()
+			w.mu.Unlock()
+			return
+		}
+		if dropped > 0 {
+			fmt.Fprintf(os.Stderr, "logsink: dropped %d log lines while %s was unreachable\n", dropped, w.config.URL)
+		}
+	}
+}
+
+// trim drops the oldest lines beyond the pending limit; callers hold mu
+func (w *pushWriter) trim() {
+	if limit := maxPending * w.config.BatchSize; len(w.lines) > limit {
+		w.dropped += len(w.lines) - limit
+		w.lines = w.lines[len(w.lines)-limit:]
+	}
+}
+
+func (w *pushWriter) send(batch []pushLine) error {
+	var body bytes.Buffer
+	contentType := "application/x-ndjson"
+	if w.config.Type == TypeLoki {
+		contentType = "application/json"
+		labels := w.config.Labels
+		if len(labels) == 0 {
+			labels = map[string]string{"service": "metabase"}
+		}
+		values := make([][2]string, len(batch))
+		for i, l := range batch {
+			values[i] = [2]string{strconv.FormatInt(l.at.UnixNano(), 10), string(l.line)}
+		}
+		payload := map[string]interface{}{
+			"streams": []map[string]interface{}{{"stream": labels, "values": values}},
+		}
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return err
+		}
+	} else {
+		for _, l := range batch {
+			body.Write(l.line)
+			body.WriteByte('\n')
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.config.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
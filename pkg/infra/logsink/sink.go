@@ -0,0 +1,167 @@
+// Package logsink builds zap loggers that write to configurable sinks:
+// stdout/stderr, rotating files, syslog and HTTP push endpoints such as Loki
+package logsink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Sink types
+const (
+	TypeStdout = "stdout"
+	TypeStderr = "stderr"
+	TypeFile   = "file"
+	TypeSyslog = "syslog"
+	TypeHTTP   = "http" // newline-delimited JSON POSTed in batches
+	TypeLoki   = "loki" // Loki push API
+)
+
+// Config describes one sink. Fields that do not apply to the sink type are
+// ignored.
+type Config struct {
+	Type   string `json:"type"`
+	Level  string `json:"level,omitempty"`  // debug, info, warn or error; default info
+	Format string `json:"format,omitempty"` // json or console; default json
+
+	// file
+	Path       string `json:"path,omitempty"`
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"`
+	MaxAgeDays int    `json:"max_age_days,omitempty"`
+	MaxBackups int    `json:"max_backups,omitempty"`
+	Compress   bool   `json:"compress,omitempty"`
+
+	// syslog; an empty address logs to the local syslog daemon
+	Network string `json:"network,omitempty"`
+	Address string `json:"address,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+
+	// http and loki
+	URL           string            `json:"url,omitempty"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"` // Loki stream labels
+	BatchSize     int               `json:"batch_size,omitempty"`
+	FlushInterval time.Duration     `json:"flush_interval,omitempty"`
+}
+
+// ConfigsFromEnv parses a JSON array of sink configs from the named
+// environment variable; unset or invalid values yield nil
+func ConfigsFromEnv(name string) []Config {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+	var configs []Config
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring invalid %s: %v\n", name, err)
+		return nil
+	}
+	return configs
+}
+
+// Validate checks that the sink has the settings its type needs
+func (c *Config) Validate() error {
+	switch c.Type {
+	case TypeStdout, TypeStderr, TypeSyslog:
+	case TypeFile:
+		if c.Path == "" {
+			return errors.New("file sink requires a path")
+		}
+	case TypeHTTP, TypeLoki:
+		if c.URL == "" {
+			return fmt.Errorf("%s sink requires a url", c.Type)
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q", c.Type)
+	}
+	switch c.Format {
+	case "", "json", "console":
+	default:
+		return fmt.Errorf("unknown log format %q", c.Format)
+	}
+	if c.Level != "" {
+		if _, err := zapcore.ParseLevel(c.Level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Open returns a writer for the sink. Writers that buffer must be closed to
+// flush them.
+func Open(c Config) (io.WriteCloser, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.Type {
+	case TypeStdout:
+		return nopCloser{os.Stdout}, nil
+	case TypeStderr:
+		return nopCloser{os.Stderr}, nil
+	case TypeFile:
+		return &lumberjack.Logger{
+			Filename:   c.Path,
+			MaxSize:    c.MaxSizeMB,
+			MaxAge:     c.MaxAgeDays,
+			MaxBackups: c.MaxBackups,
+			Compress:   c.Compress,
+		}, nil
+	case TypeSyslog:
+		return openSyslog(c)
+	default:
+		return newPushWriter(c), nil
+	}
+}
+
+// New builds a logger writing to all sinks. The returned close function
+// flushes and closes the sinks; call it on shutdown.
+func New(configs []Config, options ...zap.Option) (*zap.Logger, func() error, error) {
+	var cores []zapcore.Core
+	var writers []io.WriteCloser
+	closeAll := func() error {
+		var errs []error
+		for _, w := range writers {
+			errs = append(errs, w.Close())
+		}
+		return errors.Join(errs...)
+	}
+	for i, c := range configs {
+		w, err := Open(c)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("log sink %d: %w", i, err)
+		}
+		writers = append(writers, w)
+		level := zapcore.InfoLevel
+		if c.Level != "" {
+			level, _ = zapcore.ParseLevel(c.Level)
+		}
+		cores = append(cores, zapcore.NewCore(encoder(c.Format), zapcore.AddSync(w), level))
+	}
+	logger := zap.New(zapcore.NewTee(cores...), options...)
+	return logger, func() error {
+		logger.Sync()
+		return closeAll()
+	}, nil
+}
+
+func encoder(format string) zapcore.Encoder {
+	cfg := zap.NewProductionEncoderConfig()
+	cfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	if format == "console" {
+		return zapcore.NewConsoleEncoder(cfg)
+	}
+	return zapcore.NewJSONEncoder(cfg)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
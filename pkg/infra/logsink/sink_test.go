@@ -0,0 +1,83 @@
+package logsink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiAndFileSinks(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []lokiPush
+	loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body lokiPush
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid push body: %v", err)
+		}
+		mu.Lock()
+		pushes = append(pushes, body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer loki.Close()
+
+	path := filepath.Join(t.TempDir(), "api.log")
+	logger, closeSinks, err := New([]Config{
+		{Type: TypeLoki, URL: loki.URL, Labels: map[string]string{"app": "api"}, FlushInterval: time.Hour},
+		{Type: TypeFile, Path: path, Level: "warn"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("started", zap.String("port", "8080"))
+	logger.Warn("slow query")
+	if err := closeSinks(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pushes) != 1 || len(pushes[0].Streams) != 1 {
+		t.Fatalf("expected one push, got %+v", pushes)
+	}
+	stream := pushes[0].Streams[0]
+	if stream.Stream["app"] != "api" || len(stream.Values) != 2 || !strings.Contains(stream.Values[0][1], `"msg":"started"`) {
+		t.Fatalf("unexpected stream %+v", stream)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "started") || !strings.Contains(string(data), "slow query") {
+		t.Fatalf("expected only warnings in the file, got %s", data)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, c := range []Config{
+		{Type: "kafka"},
+		{Type: TypeFile},
+		{Type: TypeLoki},
+		{Type: TypeStdout, Level: "loud"},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", c)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows && !plan9
+
+package logsink
+
+import (
+	"io"
+	"log/syslog"
+)
+
+func openSyslog(c Config) (io.WriteCloser, error) {
+	tag := c.Tag
+	if tag == "" {
+		tag = "metabase"
+	}
+	return syslog.Dial(c.Network, c.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package logsink
+
+import (
+	"errors"
+	"io"
+)
+
+func openSyslog(c Config) (io.WriteCloser, error) {
+	return nil, errors.New("syslog sinks are not supported on this platform")
+}
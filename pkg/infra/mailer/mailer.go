@@ -0,0 +1,165 @@
+// Package mailer sends plain text and HTML email over SMTP
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned when no SMTP host is set
+var ErrNotConfigured = errors.New("mailer: smtp host not configured")
+
+// Config holds SMTP settings
+type Config struct {
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"-"`
+	From     string `json:"from,omitempty"`
+}
+
+// ConfigFromEnv reads METABASE_SMTP_HOST, _PORT, _USERNAME, _PASSWORD and _FROM
+func ConfigFromEnv() *Config {
+	cfg := &Config{
+		Host:     os.Getenv("METABASE_SMTP_HOST"),
+		Port:     587,
+		Username: os.Getenv("METABASE_SMTP_USERNAME"),
+		Password: os.Getenv("METABASE_SMTP_PASSWORD"),
+		From:     os.Getenv("METABASE_SMTP_FROM"),
+	}
+	if port, err := strconv.Atoi(os.Getenv("METABASE_SMTP_PORT")); err == nil && port > 0 {
+		cfg.Port = port
+	}
+	return cfg
+}
+
+// Message is an email with a plain text body and an optional HTML alternative
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer sends messages through an SMTP server
+type Mailer struct {
+	config *Config
+	send   func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+	now    func() time.Time
+}
+
+// New creates a mailer; a nil config or empty host disables sending
+func New(cfg *Config) *Mailer {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Mailer{
+		config: cfg,
+		send:   smtp.SendMail,
+		now:    time.Now,
+	}
+}
+
+// Enabled reports whether an SMTP host is configured
+func (m *Mailer) Enabled() bool {
+	return m.config.Host != ""
+}
+
+// Send delivers a message to its recipients
+func (m *Mailer) Send(msg *Message) error {
+	if !m.Enabled() {
+		return ErrNotConfigured
+	}
+	if len(msg.To) == 0 {
+		return fmt.Errorf("mailer: no recipients")
+	}
+	from := m.config.From
+	if from == "" {
+		from = "metabase@localhost"
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid from address: %w", err)
+	}
+
+	body, err := m.build(from, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	port := m.config.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := fmt.Sprintf("%s:%d", m.config.Host, port)
+	return m.send(addr, auth, sender.Address, msg.To, body)
+}
+
+// build renders the message with headers, as multipart/alternative when it has HTML
+func (m *Mailer) build(from string, msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", m.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", "text/plain; charset=UTF-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuoted(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuoted(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuoted(w interface{ Write([]byte) (int, error) }, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
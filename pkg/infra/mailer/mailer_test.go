@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestSendBuildsAlternativeMessage(t *testing.T) {
+	m := New(&Config{Host: "smtp.example.com", From: "Reports <reports@example.com>"})
+	var addr, from string
+	var raw []byte
+	m.send = func(a string, auth smtp.Auth, f string, to []string, msg []byte) error {
+		addr, from, raw = a, f, msg
+		return nil
+	}
+
+	err := m.Send(&Message{
+		To:      []string{"ops@example.com"},
+		Subject: "Weekly digest",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "smtp.example.com:587" || from != "reports@example.com" {
+		t.Fatalf("unexpected envelope %s %s", addr, from)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q, %v", mediaType, err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != "plain body" || bodies[1] != "<p>html body</p>" {
+		t.Fatalf("unexpected parts %q", bodies)
+	}
+
+	if err := New(nil).Send(&Message{To: []string{"ops@example.com"}}); err != ErrNotConfigured {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+}
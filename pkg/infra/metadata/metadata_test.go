@@ -0,0 +1,154 @@
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const projectSchema = `{
+	"type": "object",
+	"required": ["team"],
+	"additionalProperties": false,
+	"properties": {
+		"team": {"type": "string", "enum": ["search", "billing"]},
+		"tier": {"type": "integer", "minimum": 1, "maximum": 3},
+		"owner": {"type": "string", "format": "email"},
+		"labels": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}, "maxItems": 2},
+		"limits": {"type": "object", "properties": {"qps": {"type": "number"}}}
+	}
+}`
+
+func TestParseRejectsUnsupportedSchemas(t *testing.T) {
+	for name, data := range map[string]string{
+		"not an object":      `{"type": "string"}`,
+		"unknown keyword":    `{"type": "object", "oneOf": []}`,
+		"unknown type":       `{"type": "object", "properties": {"a": {"type": "date"}}}`,
+		"unknown format":     `{"type": "object", "properties": {"a": {"type": "string", "format": "ipv6"}}}`,
+		"bad pattern":        `{"type": "object", "properties": {"a": {"type": "string", "pattern": "("}}}`,
+		"undeclared require": `{"type": "object", "required": ["a"]}`,
+	} {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("%s: expected ErrInvalidSchema, got %v", name, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(projectSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schema.Fields(); !reflect.DeepEqual(got, []Field{
+		{Name: "labels", Type: TypeArray}, {Name: "owner", Type: TypeString},
+		{Name: "team", Type: TypeString}, {Name: "tier", Type: TypeInteger},
+	}) {
+		t.Fatalf("unexpected fields %v", got)
+	}
+
+	valid := map[string]interface{}{
+		"team": "search", "tier": 2.0, "owner": "ops@example.com",
+		"labels": []interface{}{"beta"}, "limits": map[string]interface{}{"qps": 1.5},
+	}
+	if err := schema.Validate(valid); err != nil {
+		t.Fatalf("expected valid metadata, got %v", err)
+	}
+
+	err = schema.Validate(map[string]interface{}{
+		"tier":   2.5,
+		"owner":  "nobody",
+		"labels": []interface{}{"Beta", "b", "c"},
+		"limits": map[string]interface{}{"qps": "fast"},
+		"extra":  true,
+	})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	want := []Violation{
+		{Field: "extra", Message: "is not declared in the schema"},
+		{Field: "labels", Message: "must have at most 2 items"},
+		{Field: "labels[0]", Message: "must match pattern ^[a-z]+$"},
+		{Field: "limits.qps", Message: "must be of type number"},
+		{Field: "owner", Message: "must be a valid email"},
+		{Field: "team", Message: "is required"},
+		{Field: "tier", Message: "must be of type integer"},
+	}
+	if !reflect.DeepEqual(invalid.Violations, want) {
+		t.Fatalf("violations = %+v, want %+v", invalid.Violations, want)
+	}
+}
+
+func TestStoreIndexAndMatch(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "metadata.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store, err := NewStore(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if schema, err := store.Schema(ctx, "t1", TargetProject); schema != nil || err != nil {
+		t.Fatalf("expected no schema, got %v, %v", schema, err)
+	}
+	schema, _ := Parse([]byte(projectSchema))
+	if err := store.SaveSchema(ctx, "t1", TargetProject, schema, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := store.Schema(ctx, "t1", TargetProject); err != nil || !saved.Declares("team") {
+		t.Fatalf("expected saved schema, got %v, %v", saved, err)
+	}
+
+	objects := map[string]map[string]interface{}{
+		"p1": {"team": "search", "tier": 1.0, "labels": []interface{}{"beta", "eu"}},
+		"p2": {"team": "search", "tier": 2.0, "labels": []interface{}{"eu"}},
+		"p3": {"team": "billing", "tier": 1.0, "limits": map[string]interface{}{"qps": 5.0}},
+	}
+	for id, values := range objects {
+		if err := store.Index(ctx, "t1", TargetProject, id, schema, values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		filters map[string]string
+		want    []string
+	}{
+		{map[string]string{"team": "search"}, []string{"p1", "p2"}},
+		{map[string]string{"team": "search", "tier": "1"}, []string{"p1"}},
+		{map[string]string{"labels": "eu"}, []string{"p1", "p2"}},
+		{map[string]string{"team": "billing", "labels": "eu"}, []string{}},
+	} {
+		if got, err := store.Match(ctx, "t1", TargetProject, c.filters); err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Match(%v) = %v, %v; want %v", c.filters, got, err, c.want)
+		}
+	}
+	if got, _ := store.Match(ctx, "t2", TargetProject, map[string]string{"team": "search"}); len(got) != 0 {
+		t.Fatalf("expected other tenants to match nothing, got %v", got)
+	}
+
+	// Reindexing replaces earlier values
+	if err := store.Index(ctx, "t1", TargetProject, "p1", schema, map[string]interface{}{"team": "billing"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Match(ctx, "t1", TargetProject, map[string]string{"team": "search"}); !reflect.DeepEqual(got, []string{"p2"}) {
+		t.Fatalf("expected reindexed project to stop matching, got %v", got)
+	}
+
+	if err := store.DeleteSchema(ctx, "t1", TargetProject); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.DeleteSchema(ctx, "t1", TargetProject); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if got, _ := store.Match(ctx, "t1", TargetProject, map[string]string{"team": "billing"}); len(got) != 0 {
+		t.Fatalf("expected index to be dropped with the schema, got %v", got)
+	}
+}
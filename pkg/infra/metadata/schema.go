@@ -0,0 +1,313 @@
+// Package metadata validates custom tenant, project and document metadata
+// against JSON Schemas defined by each tenant, and indexes the declared
+// fields so listings can be filtered by them.
+package metadata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSchema is returned for schemas using unsupported keywords or types
+var ErrInvalidSchema = errors.New("invalid metadata schema")
+
+// JSON types a schema may declare
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+var schemaTypes = []string{TypeObject, TypeArray, TypeString, TypeNumber, TypeInteger, TypeBoolean}
+
+// Formats a string schema may declare
+var schemaFormats = map[string]func(string) bool{
+	"date": func(s string) bool {
+		_, err := time.Parse(time.DateOnly, s)
+		return err == nil
+	},
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	},
+	"email": func(s string) bool {
+		_, err := mail.ParseAddress(s)
+		return err == nil
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+}
+
+// Schema is the subset of JSON Schema supported for custom metadata. The
+// root schema describes an object; its declared scalar properties are
+// indexed for filtering.
+type Schema struct {
+	SchemaURI   string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+
+	// Objects
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+
+	// Arrays
+	Items    *Schema `json:"items,omitempty"`
+	MaxItems *int    `json:"maxItems,omitempty"`
+
+	// Strings
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	Format    string `json:"format,omitempty"`
+
+	// Numbers
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+
+	Enum []interface{} `json:"enum,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// Field is a declared top-level property that is indexed for filtering
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Violation describes one way metadata fails its schema
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every violation found in a metadata map
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Field + ": " + v.Message
+	}
+	return "metadata does not match schema: " + strings.Join(messages, "; ")
+}
+
+// Parse decodes and checks a schema. Unknown keywords are rejected rather
+// than ignored, so a schema never looks stricter than it is.
+func Parse(data []byte) (*Schema, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	if schema.Type != TypeObject {
+		return nil, fmt.Errorf("%w: the root schema must have type object", ErrInvalidSchema)
+	}
+	if err := schema.compile("$"); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// compile checks a schema node and compiles its pattern
+func (s *Schema) compile(path string) error {
+	if s.Type != "" && !slices.Contains(schemaTypes, s.Type) {
+		return fmt.Errorf("%w: %s has unsupported type %q", ErrInvalidSchema, path, s.Type)
+	}
+	if s.Format != "" && schemaFormats[s.Format] == nil {
+		return fmt.Errorf("%w: %s has unsupported format %q", ErrInvalidSchema, path, s.Format)
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%w: %s has an invalid pattern: %v", ErrInvalidSchema, path, err)
+		}
+		s.pattern = pattern
+	}
+	for _, name := range s.Required {
+		if s.Properties[name] == nil {
+			return fmt.Errorf("%w: %s requires undeclared property %q", ErrInvalidSchema, path, name)
+		}
+	}
+	for name, property := range s.Properties {
+		if property == nil {
+			return fmt.Errorf("%w: %s.%s is empty", ErrInvalidSchema, path, name)
+		}
+		if err := property.compile(path + "." + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// Validate checks metadata against the schema, returning a *ValidationError
+// listing every violation
+func (s *Schema) Validate(metadata map[string]interface{}) error {
+	var violations []Violation
+	s.validateObject("", metadata, &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+	return &ValidationError{Violations: violations}
+}
+
+// Fields returns the declared top-level properties that are indexed: strings,
+// numbers, integers, booleans and arrays of those
+func (s *Schema) Fields() []Field {
+	var fields []Field
+	for name, property := range s.Properties {
+		typ := property.Type
+		if typ == TypeArray && property.Items != nil {
+			typ = property.Items.Type
+		}
+		switch typ {
+		case TypeString, TypeNumber, TypeInteger, TypeBoolean:
+			fields = append(fields, Field{Name: name, Type: property.Type})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	return fields
+}
+
+// Declares reports whether name is an indexed field of the schema
+func (s *Schema) Declares(name string) bool {
+	for _, field := range s.Fields() {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) validateObject(path string, object map[string]interface{}, violations *[]Violation) {
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			*violations = append(*violations, Violation{Field: join(path, name), Message: "is required"})
+		}
+	}
+	for name, value := range object {
+		property := s.Properties[name]
+		if property == nil {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*violations = append(*violations, Violation{Field: join(path, name), Message: "is not declared in the schema"})
+			}
+			continue
+		}
+		property.validate(join(path, name), value, violations)
+	}
+}
+
+func (s *Schema) validate(path string, value interface{}, violations *[]Violation) {
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.Type != "" && !hasType(value, s.Type) {
+		fail("must be of type %s", s.Type)
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed interface{}) bool { return equal(allowed, value) }) {
+		fail("must be one of %s", enumList(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match pattern %s", s.Pattern)
+		}
+		if check := schemaFormats[s.Format]; check != nil && !check(v) {
+			fail("must be a valid %s", s.Format)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case []interface{}:
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, violations)
+			}
+		}
+	case map[string]interface{}:
+		s.validateObject(path, v, violations)
+	}
+}
+
+// hasType reports whether a decoded JSON value has a schema type
+func hasType(value interface{}, typ string) bool {
+	switch v := value.(type) {
+	case string:
+		return typ == TypeString
+	case bool:
+		return typ == TypeBoolean
+	case float64:
+		return typ == TypeNumber || (typ == TypeInteger && v == math.Trunc(v))
+	case []interface{}:
+		return typ == TypeArray
+	case map[string]interface{}:
+		return typ == TypeObject
+	}
+	return false
+}
+
+// equal compares decoded JSON scalars
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case string, bool, float64, nil:
+		return a == b
+	}
+	return false
+}
+
+func enumList(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		encoded, _ := json.Marshal(value)
+		parts[i] = string(encoded)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,203 @@
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a tenant has no schema for a target
+var ErrNotFound = errors.New("metadata schema not found")
+
+// Targets whose custom metadata a tenant can constrain
+const (
+	TargetProject  = "project"
+	TargetDocument = "document"
+)
+
+// Targets lists every target a schema can be defined for
+var Targets = []string{TargetProject, TargetDocument}
+
+// ValidTarget reports whether a schema can be defined for target
+func ValidTarget(target string) bool {
+	return target == TargetProject || target == TargetDocument
+}
+
+// Store keeps tenant schemas and the index of declared metadata fields in
+// tables of the shared database. Queries use ? placeholders.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a schema store, creating its tables if needed
+func NewStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	_, err := db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS metadata_schemas (
+		tenant_id TEXT NOT NULL,
+		target TEXT NOT NULL,
+		schema TEXT NOT NULL,
+		updated_by TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (tenant_id, target)
+	);
+
+	CREATE TABLE IF NOT EXISTS metadata_index (
+		tenant_id TEXT NOT NULL,
+		target TEXT NOT NULL,
+		object_id TEXT NOT NULL,
+		field TEXT NOT NULL,
+		value TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_metadata_index_lookup ON metadata_index(tenant_id, target, field, value);
+	CREATE INDEX IF NOT EXISTS idx_metadata_index_object ON metadata_index(tenant_id, target, object_id);
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata schema tables: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Schema returns a tenant's schema for target, or nil when it has none
+func (s *Store) Schema(ctx context.Context, tenantID, target string) (*Schema, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT schema FROM metadata_schemas WHERE tenant_id = ? AND target = ?`,
+		tenantID, target).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata schema: %w", err)
+	}
+	return Parse([]byte(data))
+}
+
+// SaveSchema creates or replaces a tenant's schema for target. Metadata
+// written earlier is not revalidated; it is checked again when next written.
+func (s *Store) SaveSchema(ctx context.Context, tenantID, target string, schema *Schema, updatedBy string) error {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata schema: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO metadata_schemas (tenant_id, target, schema, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(tenant_id, target) DO UPDATE SET
+			schema = excluded.schema, updated_by = excluded.updated_by, updated_at = excluded.updated_at`,
+		tenantID, target, string(data), updatedBy, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save metadata schema: %w", err)
+	}
+	return nil
+}
+
+// DeleteSchema removes a tenant's schema for target and its index, or
+// returns ErrNotFound
+func (s *Store) DeleteSchema(ctx context.Context, tenantID, target string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM metadata_schemas WHERE tenant_id = ? AND target = ?`, tenantID, target)
+	if err != nil {
+		return fmt.Errorf("failed to delete metadata schema: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM metadata_index WHERE tenant_id = ? AND target = ?`, tenantID, target); err != nil {
+		return fmt.Errorf("failed to delete metadata index: %w", err)
+	}
+	return nil
+}
+
+// Index replaces the indexed values of an object with the values of the
+// fields the schema declares. Arrays index each of their scalar items.
+func (s *Store) Index(ctx context.Context, tenantID, target, objectID string, schema *Schema, metadata map[string]interface{}) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM metadata_index WHERE tenant_id = ? AND target = ? AND object_id = ?`,
+		tenantID, target, objectID); err != nil {
+		return fmt.Errorf("failed to clear metadata index: %w", err)
+	}
+	if schema != nil {
+		for _, field := range schema.Fields() {
+			for _, value := range Values(metadata[field.Name]) {
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO metadata_index (tenant_id, target, object_id, field, value) VALUES (?, ?, ?, ?, ?)`,
+					tenantID, target, objectID, field.Name, value); err != nil {
+					return fmt.Errorf("failed to index metadata: %w", err)
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Match returns the IDs of objects whose indexed fields have every value in
+// filters, in ID order
+func (s *Store) Match(ctx context.Context, tenantID, target string, filters map[string]string) ([]string, error) {
+	if len(filters) == 0 {
+		return nil, errors.New("no metadata filters given")
+	}
+	fields := make([]string, 0, len(filters))
+	for field := range filters {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	conditions := make([]string, len(fields))
+	args := []interface{}{tenantID, target}
+	for i, field := range fields {
+		conditions[i] = "(field = ? AND value = ?)"
+		args = append(args, field, filters[field])
+	}
+	args = append(args, len(fields))
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT object_id FROM metadata_index
+		WHERE tenant_id = ? AND target = ? AND (`+strings.Join(conditions, " OR ")+`)
+		GROUP BY object_id HAVING COUNT(DISTINCT field) = ?
+		ORDER BY object_id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match metadata: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan metadata match: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Values returns the indexed text of a scalar or array of scalars; other
+// values have none
+func Values(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if _, nested := item.([]interface{}); !nested {
+				values = append(values, Values(item)...)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package signedurl issues and verifies time-limited HMAC-signed URLs, so a
+// resource can be fetched without credentials until the link expires
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned by Verify
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("signed url has expired")
+	ErrIPMismatch       = errors.New("signed url is bound to another address")
+)
+
+// Query parameters added to signed URLs
+const (
+	paramExpires   = "expires"
+	paramBound     = "ip"
+	paramNonce     = "nonce"
+	paramSignature = "sig"
+)
+
+// Options controls how a URL is signed
+type Options struct {
+	TTL time.Duration
+
+	// IP binds the URL to a client address; the address itself is only part
+	// of the signature and never appears in the URL
+	IP string
+
+	// SingleUse adds a random nonce the caller records on first use
+	SingleUse bool
+}
+
+// Token describes a signed URL
+type Token struct {
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IP        string    `json:"ip,omitempty"`
+	Nonce     string    `json:"nonce,omitempty"`
+}
+
+// Signer signs and verifies URLs with a shared key
+type Signer struct {
+	key []byte
+	now func() time.Time
+}
+
+// New creates a signer; every instance serving the URLs must use the same key
+func New(key []byte) *Signer {
+	return &Signer{key: key, now: time.Now}
+}
+
+// Sign returns path with the signature in its query and the signed token
+func (s *Signer) Sign(path string, opts Options) (string, *Token) {
+	token := &Token{
+		Path:      path,
+		ExpiresAt: s.now().Add(opts.TTL).Truncate(time.Second),
+		IP:        opts.IP,
+	}
+	if opts.SingleUse {
+		nonce := make([]byte, 16)
+		rand.Read(nonce)
+		token.Nonce = hex.EncodeToString(nonce)
+	}
+
+	query := url.Values{}
+	query.Set(paramExpires, strconv.FormatInt(token.ExpiresAt.Unix(), 10))
+	if token.IP != "" {
+		query.Set(paramBound, "1")
+	}
+	if token.Nonce != "" {
+		query.Set(paramNonce, token.Nonce)
+	}
+	query.Set(paramSignature, s.signature(token))
+	return path + "?" + query.Encode(), token
+}
+
+// Verify checks the signature in query against path and, for bound URLs,
+// the client address. Single-use tokens are returned with their nonce for
+// the caller to consume.
+func (s *Signer) Verify(path string, query url.Values, clientIP string) (*Token, error) {
+	expires, err := strconv.ParseInt(query.Get(paramExpires), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	token := &Token{
+		Path:      path,
+		ExpiresAt: time.Unix(expires, 0),
+		Nonce:     query.Get(paramNonce),
+	}
+	bound := query.Get(paramBound) == "1"
+	if bound {
+		token.IP = clientIP
+	}
+	if !hmac.Equal([]byte(s.signature(token)), []byte(query.Get(paramSignature))) {
+		// A bound URL used from another address fails the signature
+		if bound {
+			return nil, ErrIPMismatch
+		}
+		return nil, ErrInvalidSignature
+	}
+	if !s.now().Before(token.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return token, nil
+}
+
+func (s *Signer) signature(token *Token) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strings.Join([]string{
+		token.Path,
+		strconv.FormatInt(token.ExpiresAt.Unix(), 10),
+		token.IP,
+		token.Nonce,
+	}, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
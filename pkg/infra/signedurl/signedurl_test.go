@@ -0,0 +1,73 @@
+package signedurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parse(t *testing.T, signed string) (string, url.Values) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Path, u.Query()
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := New([]byte("secret"))
+	now := time.Now()
+	signer.now = func() time.Time { return now }
+
+	signed, token := signer.Sign("/files/a.pdf", Options{TTL: time.Minute, IP: "203.0.113.7", SingleUse: true})
+	if strings.Contains(signed, "203.0.113.7") || token.Nonce == "" {
+		t.Fatalf("unexpected signed url %s", signed)
+	}
+	path, query := parse(t, signed)
+
+	verified, err := signer.Verify(path, query, "203.0.113.7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.Nonce != token.Nonce || !verified.ExpiresAt.Equal(token.ExpiresAt) {
+		t.Fatalf("expected %+v, got %+v", token, verified)
+	}
+
+	if _, err := signer.Verify(path, query, "198.51.100.1"); err != ErrIPMismatch {
+		t.Fatalf("expected an address mismatch, got %v", err)
+	}
+	if _, err := signer.Verify("/files/b.pdf", query, "203.0.113.7"); err == nil {
+		t.Fatal("expected another path to be rejected")
+	}
+	if _, err := New([]byte("other")).Verify(path, query, "203.0.113.7"); err == nil {
+		t.Fatal("expected another key to be rejected")
+	}
+
+	tampered := url.Values{}
+	for k, v := range query {
+		tampered[k] = v
+	}
+	tampered.Set("expires", "9999999999")
+	if _, err := signer.Verify(path, tampered, "203.0.113.7"); err == nil {
+		t.Fatal("expected an extended expiry to be rejected")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := signer.Verify(path, query, "203.0.113.7"); err != ErrExpired {
+		t.Fatalf("expected the url to expire, got %v", err)
+	}
+}
+
+func TestUnboundURL(t *testing.T) {
+	signer := New([]byte("secret"))
+	signed, token := signer.Sign("/exports/1.json.gz", Options{TTL: time.Hour})
+	path, query := parse(t, signed)
+	if token.Nonce != "" || query.Has("ip") {
+		t.Fatalf("unexpected signed url %s", signed)
+	}
+	if _, err := signer.Verify(path, query, "198.51.100.1"); err != nil {
+		t.Fatalf("expected any address to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// cacheQueryResult stores a query result along with the documents it was
+// built from, so updating any of them invalidates it
+func (p *Pipeline) cacheQueryResult(ctx context.Context, key string, result *QueryResult, ttl time.Duration) {
+	if dependent, ok := p.cache.(DependentCache); ok {
+		documents := resultDocuments(result.RetrievalResults)
+		if err := dependent.SetWithDocuments(ctx, key, result, documents, ttl); err != nil {
+			p.emitError(ctx, "cache_query", err)
+		}
+		return
+	}
+	if err := p.cache.Set(ctx, key, result, ttl); err != nil {
+		p.emitError(ctx, "cache_query", err)
+	}
+}
+
+// invalidateCachedAnswers drops cached query results that used any of the
+// documents. Caches that do not track dependencies are cleared entirely.
+func (p *Pipeline) invalidateCachedAnswers(ctx context.Context, documentIDs ...string) {
+	if p.cache == nil || len(documentIDs) == 0 {
+		return
+	}
+
+	dependent, ok := p.cache.(DependentCache)
+	if !ok {
+		if err := p.cache.Clear(ctx); err != nil {
+			p.emitError(ctx, "clear_cache", err)
+		}
+		return
+	}
+	removed, err := dependent.InvalidateDocuments(ctx, documentIDs)
+	if err != nil {
+		p.emitError(ctx, "invalidate_cache", err)
+	}
+	if removed > 0 {
+		p.emitEvent(ctx, "cache_invalidated", map[string]interface{}{
+			"documents": documentIDs,
+			"entries":   removed,
+		})
+	}
+}
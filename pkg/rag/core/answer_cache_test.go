@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// memoryCache is a Cache without dependency tracking
+type memoryCache struct {
+	entries map[string]*QueryResult
+	deps    map[string][]string
+	cleared int
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: make(map[string]*QueryResult), deps: make(map[string][]string)}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) (*QueryResult, error) {
+	return c.entries[key], nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, result *QueryResult, ttl time.Duration) error {
+	c.entries[key] = result
+	return nil
+}
+
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *memoryCache) Clear(ctx context.Context) error {
+	c.entries = make(map[string]*QueryResult)
+	c.cleared++
+	return nil
+}
+
+func (c *memoryCache) GetStats() (*CacheStats, error) { return &CacheStats{}, nil }
+func (c *memoryCache) Close() error                   { return nil }
+
+// dependentMemoryCache records the documents of each entry like RedisCache
+type dependentMemoryCache struct {
+	*memoryCache
+}
+
+func (c dependentMemoryCache) SetWithDocuments(ctx context.Context, key string, result *QueryResult, documentIDs []string, ttl time.Duration) error {
+	c.entries[key] = result
+	for _, id := range documentIDs {
+		c.deps[id] = append(c.deps[id], key)
+	}
+	return nil
+}
+
+func (c dependentMemoryCache) InvalidateDocuments(ctx context.Context, documentIDs []string) (int, error) {
+	removed := 0
+	for _, id := range documentIDs {
+		for _, key := range c.deps[id] {
+			if _, ok := c.entries[key]; ok {
+				delete(c.entries, key)
+				removed++
+			}
+		}
+		delete(c.deps, id)
+	}
+	return removed, nil
+}
+
+func TestInvalidateCachedAnswers(t *testing.T) {
+	ctx := context.Background()
+	cache := dependentMemoryCache{newMemoryCache()}
+	p := &Pipeline{config: DefaultConfig(), cache: cache}
+
+	refund := &QueryResult{RetrievalResults: []RetrievalResult{
+		{DocumentID: "policy"},
+		{Chunk: &DocumentChunk{DocumentID: "faq"}},
+	}}
+	sso := &QueryResult{RetrievalResults: []RetrievalResult{{DocumentID: "sso"}}}
+	p.cacheQueryResult(ctx, "refund", refund, time.Minute)
+	p.cacheQueryResult(ctx, "sso", sso, time.Minute)
+	if len(cache.deps["faq"]) != 1 || len(cache.deps["sso"]) != 1 {
+		t.Fatalf("unexpected dependencies %v", cache.deps)
+	}
+
+	// Updating the FAQ only drops the answer that cited it
+	p.invalidateCachedAnswers(ctx, "faq")
+	if _, ok := cache.entries["refund"]; ok {
+		t.Fatalf("expected refund answer to be invalidated")
+	}
+	if _, ok := cache.entries["sso"]; !ok || cache.cleared != 0 {
+		t.Fatalf("expected sso answer to stay cached")
+	}
+
+	// Caches without dependency tracking are cleared
+	plain := newMemoryCache()
+	p.cache = plain
+	p.cacheQueryResult(ctx, "sso", sso, time.Minute)
+	p.invalidateCachedAnswers(ctx, "policy")
+	if plain.cleared != 1 || len(plain.entries) != 0 {
+		t.Fatalf("expected plain cache to be cleared, got %+v", plain)
+	}
+}
@@ -0,0 +1,370 @@
+package core
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchStatus represents the lifecycle state of a batch query job
+type BatchStatus string
+
+const (
+	BatchStatusRunning   BatchStatus = "running"
+	BatchStatusCompleted BatchStatus = "completed"
+	BatchStatusCancelled BatchStatus = "cancelled"
+)
+
+// Batch result file formats
+const (
+	BatchFormatJSONL = "jsonl"
+	BatchFormatCSV   = "csv"
+)
+
+// BatchQueryItem is a single question in a batch
+type BatchQueryItem struct {
+	ID    string `json:"id,omitempty"` // Caller-supplied identifier, e.g. questionnaire item number
+	Query string `json:"query"`
+}
+
+// BatchQueryOptions defines a batch of questions answered with shared options
+type BatchQueryOptions struct {
+	Queries     []BatchQueryItem `json:"queries"`
+	Options     QueryOptions     `json:"options"`
+	Concurrency int              `json:"concurrency"` // Maximum queries in flight
+}
+
+// BatchQueryResult is the outcome of one question in a batch
+type BatchQueryResult struct {
+	Index    int           `json:"index"`
+	ID       string        `json:"id,omitempty"`
+	Query    string        `json:"query"`
+	Answer   string        `json:"answer"`
+	Sources  []Source      `json:"sources"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// BatchQueryJob tracks the progress of a background batch query job
+type BatchQueryJob struct {
+	ID          string      `json:"id"`
+	ProjectID   string      `json:"project_id,omitempty"`
+	Status      BatchStatus `json:"status"`
+	Concurrency int         `json:"concurrency"`
+
+	// Progress counters
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+// batchState holds the runtime state of a batch job
+type batchState struct {
+	job     *BatchQueryJob
+	results []BatchQueryResult
+	cancel  context.CancelFunc
+}
+
+// StartBatchQuery starts a background job that answers every query in the
+// batch with at most Concurrency queries in flight. Progress is available
+// from GetBatchQuery and results from BatchQueryResults once it finishes.
+func (p *Pipeline) StartBatchQuery(ctx context.Context, options BatchQueryOptions) (*BatchQueryJob, error) {
+	if !p.started {
+		return nil, fmt.Errorf("pipeline not started")
+	}
+	if len(options.Queries) == 0 {
+		return nil, fmt.Errorf("at least one query is required")
+	}
+	if max := p.config.System.MaxBatchQueries; max > 0 && len(options.Queries) > max {
+		return nil, fmt.Errorf("batch exceeds maximum of %d queries", max)
+	}
+	for i, item := range options.Queries {
+		if strings.TrimSpace(item.Query) == "" {
+			return nil, fmt.Errorf("query %d is empty", i)
+		}
+	}
+
+	limit := p.config.System.MaxConcurrency
+	if limit <= 0 {
+		limit = 1
+	}
+	if options.Concurrency <= 0 || options.Concurrency > limit {
+		options.Concurrency = limit
+	}
+
+	job := &BatchQueryJob{
+		ID:          p.generateID(),
+		ProjectID:   options.Options.ProjectID,
+		Status:      BatchStatusRunning,
+		Concurrency: options.Concurrency,
+		Total:       len(options.Queries),
+		StartedAt:   p.now(),
+	}
+
+	// The job outlives the request that started it
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	state := &batchState{
+		job:     job,
+		results: make([]BatchQueryResult, len(options.Queries)),
+		cancel:  cancel,
+	}
+
+	p.mu.Lock()
+	p.pruneBatchJobs()
+	p.batchJobs[job.ID] = state
+	p.mu.Unlock()
+
+	p.emitEvent(ctx, "batch_query_started", map[string]interface{}{
+		"job_id": job.ID,
+		"total":  job.Total,
+	})
+
+	go p.runBatchQuery(jobCtx, state, options)
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// GetBatchQuery returns a snapshot of a batch job
+func (p *Pipeline) GetBatchQuery(jobID string) (*BatchQueryJob, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	state, exists := p.batchJobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("batch job %s not found", jobID)
+	}
+	snapshot := *state.job
+	return &snapshot, nil
+}
+
+// ListBatchQueries returns snapshots of the batch jobs still held by the
+// pipeline, running ones included
+func (p *Pipeline) ListBatchQueries() []BatchQueryJob {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	jobs := make([]BatchQueryJob, 0, len(p.batchJobs))
+	for _, state := range p.batchJobs {
+		jobs = append(jobs, *state.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	return jobs
+}
+
+// BatchQueryResults returns the results of a finished batch job in query order
+func (p *Pipeline) BatchQueryResults(jobID string) ([]BatchQueryResult, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	state, exists := p.batchJobs[jobID]
+	if !exists {
+		return nil, fmt.Errorf("batch job %s not found", jobID)
+	}
+	if state.job.Status == BatchStatusRunning {
+		return nil, fmt.Errorf("batch job %s is still running", jobID)
+	}
+	return append([]BatchQueryResult(nil), state.results...), nil
+}
+
+// CancelBatchQuery stops a running batch job. Queries already answered are kept.
+func (p *Pipeline) CancelBatchQuery(jobID string) error {
+	p.mu.RLock()
+	state, exists := p.batchJobs[jobID]
+	p.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("batch job %s not found", jobID)
+	}
+	state.cancel()
+	return nil
+}
+
+// runBatchQuery answers the batch using a bounded worker pool
+func (p *Pipeline) runBatchQuery(ctx context.Context, state *batchState, options BatchQueryOptions) {
+	defer state.cancel()
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := p.runBatchItem(ctx, i, options.Queries[i], options.Options)
+
+				p.mu.Lock()
+				state.results[i] = result
+				state.job.Completed++
+				if result.Error != "" {
+					state.job.Failed++
+				}
+				p.mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range options.Queries {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	p.mu.Lock()
+	job := state.job
+	job.Status = BatchStatusCompleted
+	if ctx.Err() != nil && job.Completed < job.Total {
+		job.Status = BatchStatusCancelled
+		// Record unanswered queries so the results file covers the whole batch
+		for i := range state.results {
+			if state.results[i].Query == "" {
+				state.results[i] = BatchQueryResult{
+					Index: i,
+					ID:    options.Queries[i].ID,
+					Query: options.Queries[i].Query,
+					Error: "cancelled",
+				}
+			}
+		}
+	}
+	job.CompletedAt = p.now()
+	snapshot := *job
+	p.mu.Unlock()
+
+	p.emitEvent(context.Background(), "batch_query_finished", map[string]interface{}{
+		"job": snapshot,
+	})
+}
+
+// runBatchItem answers a single batch query
+func (p *Pipeline) runBatchItem(ctx context.Context, index int, item BatchQueryItem, options QueryOptions) BatchQueryResult {
+	result := BatchQueryResult{
+		Index: index,
+		ID:    item.ID,
+		Query: item.Query,
+	}
+
+	start := p.now()
+	queryResult, err := p.Query(ctx, item.Query, options)
+	result.Duration = p.since(start)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Answer = queryResult.GeneratedAnswer
+	if result.Answer == "" {
+		result.Answer = queryResult.GeneratedResponse
+	}
+	result.Sources = queryResult.Sources
+	if len(result.Sources) == 0 {
+		result.Sources = sourcesFromResults(queryResult.RetrievalResults)
+		p.linkSources(ctx, result.Sources, queryResult.RetrievalResults)
+	}
+	return result
+}
+
+// pruneBatchJobs drops finished jobs past the retention period. Caller must hold p.mu.
+func (p *Pipeline) pruneBatchJobs() {
+	retention := p.config.System.BatchJobRetention
+	if retention <= 0 {
+		return
+	}
+	for id, state := range p.batchJobs {
+		if state.job.Status != BatchStatusRunning && p.since(state.job.CompletedAt) > retention {
+			delete(p.batchJobs, id)
+		}
+	}
+}
+
+// sourcesFromResults builds citations from retrieval results
+func sourcesFromResults(results []RetrievalResult) []Source {
+	sources := make([]Source, 0, len(results))
+	for _, result := range results {
+		source := Source{
+			DocumentID: result.DocumentID,
+			Relevance:  result.Score,
+			ProjectID:  result.ProjectID,
+		}
+		if result.Document != nil {
+			source.DocumentTitle = result.Document.Title
+			source.DocumentURI = result.Document.URI
+		}
+		source.Language = resultLanguage(result)
+		if result.Chunk != nil {
+			source.ChunkID = result.Chunk.ID
+			source.Excerpt = result.Chunk.Content
+			_, source.Translated = result.Chunk.Metadata["original_content"]
+			if result.Chunk.Image != nil {
+				source.ImageURI = result.Chunk.Image.URI
+			}
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// WriteBatchResults writes batch results as a downloadable file in JSON Lines
+// or CSV format. CSV rows list sources as "title (uri)" separated by newlines.
+func WriteBatchResults(w io.Writer, format string, results []BatchQueryResult) error {
+	switch format {
+	case BatchFormatJSONL, "":
+		encoder := json.NewEncoder(w)
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return fmt.Errorf("failed to write result %d: %w", result.Index, err)
+			}
+		}
+		return nil
+
+	case BatchFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"index", "id", "query", "answer", "sources", "error", "duration_ms"}); err != nil {
+			return err
+		}
+		for _, result := range results {
+			sources := make([]string, 0, len(result.Sources))
+			for _, source := range result.Sources {
+				label := source.DocumentTitle
+				if label == "" {
+					label = source.DocumentID
+				}
+				if source.DocumentURI != "" {
+					label = fmt.Sprintf("%s (%s)", label, source.DocumentURI)
+				}
+				sources = append(sources, label)
+			}
+			record := []string{
+				strconv.Itoa(result.Index),
+				result.ID,
+				result.Query,
+				result.Answer,
+				strings.Join(sources, "\n"),
+				result.Error,
+				strconv.FormatInt(result.Duration.Milliseconds(), 10),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write result %d: %w", result.Index, err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	return fmt.Errorf("unsupported results format: %s", format)
+}
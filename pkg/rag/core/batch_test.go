@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteBatchResults(t *testing.T) {
+	results := []BatchQueryResult{
+		{
+			Index:    0,
+			ID:       "q1",
+			Query:    "What is the refund window?",
+			Answer:   "30 days, \"no questions asked\"",
+			Sources:  []Source{{DocumentID: "a", DocumentTitle: "Refunds", DocumentURI: "https://example.com/refunds"}, {DocumentID: "b"}},
+			Duration: 1500 * time.Millisecond,
+		},
+		{Index: 1, Query: "Who signs contracts?", Error: "cancelled"},
+	}
+
+	var jsonl bytes.Buffer
+	if err := WriteBatchResults(&jsonl, BatchFormatJSONL, results); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one JSON line per result, got %d", len(lines))
+	}
+	var decoded BatchQueryResult
+	if err := json.Unmarshal([]byte(lines[1]), &decoded); err != nil || decoded.Error != "cancelled" || decoded.Index != 1 {
+		t.Fatalf("unexpected second line %s: %v", lines[1], err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBatchResults(&buf, BatchFormatCSV, results); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != "index,id,query,answer,sources,error,duration_ms" {
+		t.Fatalf("unexpected CSV %q", records)
+	}
+	if records[1][3] != results[0].Answer || records[1][4] != "Refunds (https://example.com/refunds)\nb" || records[1][6] != "1500" {
+		t.Fatalf("unexpected first row %q", records[1])
+	}
+
+	if err := WriteBatchResults(&buf, "xml", results); err == nil {
+		t.Fatal("expected unsupported formats to be rejected")
+	}
+}
+
+func TestSourcesFromResults(t *testing.T) {
+	sources := sourcesFromResults([]RetrievalResult{
+		{
+			DocumentID: "a",
+			Score:      0.9,
+			Document:   &Document{Title: "Guide", URI: "docs/guide.md"},
+			Chunk:      &DocumentChunk{ID: "a_0", Content: "excerpt", Image: &ImageData{URI: "img.png"}},
+		},
+		{DocumentID: "b", Score: 0.5},
+	})
+	if len(sources) != 2 {
+		t.Fatalf("expected 2 sources, got %d", len(sources))
+	}
+	first := sources[0]
+	if first.DocumentTitle != "Guide" || first.DocumentURI != "docs/guide.md" || first.ChunkID != "a_0" || first.Excerpt != "excerpt" || first.ImageURI != "img.png" || first.Relevance != 0.9 {
+		t.Fatalf("unexpected source %+v", first)
+	}
+	if sources[1].DocumentID != "b" || sources[1].ChunkID != "" {
+		t.Fatalf("unexpected source %+v", sources[1])
+	}
+}
+
+func TestStartBatchQueryValidation(t *testing.T) {
+	config := DefaultConfig()
+	config.System.MaxBatchQueries = 2
+	p := &Pipeline{config: config, batchJobs: make(map[string]*batchState)}
+
+	if _, err := p.StartBatchQuery(context.Background(), BatchQueryOptions{Queries: []BatchQueryItem{{Query: "q"}}}); err == nil || !strings.Contains(err.Error(), "not started") {
+		t.Fatalf("expected a stopped pipeline to reject batches, got %v", err)
+	}
+	p.started = true
+
+	tests := []struct {
+		name    string
+		queries []BatchQueryItem
+		err     string
+	}{
+		{name: "no queries", err: "at least one query"},
+		{name: "too many queries", queries: []BatchQueryItem{{Query: "a"}, {Query: "b"}, {Query: "c"}}, err: "maximum of 2 queries"},
+		{name: "blank query", queries: []BatchQueryItem{{Query: "a"}, {Query: "  "}}, err: "query 1 is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.StartBatchQuery(context.Background(), BatchQueryOptions{Queries: tt.queries})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	if _, err := p.GetBatchQuery("missing"); err == nil {
+		t.Fatal("expected an unknown job to be reported")
+	}
+	if err := p.CancelBatchQuery("missing"); err == nil {
+		t.Fatal("expected cancelling an unknown job to fail")
+	}
+}
+
+func TestBatchQueryResultsAndPruning(t *testing.T) {
+	config := DefaultConfig()
+	config.System.BatchJobRetention = time.Hour
+	p := &Pipeline{config: config, batchJobs: map[string]*batchState{
+		"running": {job: &BatchQueryJob{ID: "running", Status: BatchStatusRunning}},
+		"recent": {
+			job:     &BatchQueryJob{ID: "recent", Status: BatchStatusCompleted, CompletedAt: time.Now()},
+			results: []BatchQueryResult{{Index: 0, Answer: "yes"}},
+		},
+		"expired": {job: &BatchQueryJob{ID: "expired", Status: BatchStatusCancelled, CompletedAt: time.Now().Add(-2 * time.Hour)}},
+	}}
+
+	if _, err := p.BatchQueryResults("running"); err == nil || !strings.Contains(err.Error(), "still running") {
+		t.Fatalf("expected running jobs to have no results yet, got %v", err)
+	}
+	results, err := p.BatchQueryResults("recent")
+	if err != nil || len(results) != 1 || results[0].Answer != "yes" {
+		t.Fatalf("unexpected results %+v %v", results, err)
+	}
+
+	p.pruneBatchJobs()
+	if _, ok := p.batchJobs["expired"]; ok {
+		t.Fatal("expected jobs past the retention period to be pruned")
+	}
+	if len(p.batchJobs) != 2 {
+		t.Fatalf("expected running and recent jobs to be kept, got %d", len(p.batchJobs))
+	}
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// boostResults multiplies scores by a recency factor and the weight of the
+// document's source type, then re-sorts the results. With a half-life h and
+// weight w a document modified t ago keeps (1-w) + w*0.5^(t/h) of its score,
+// so w bounds how far staleness alone can push a result down. Documents
+// without a date, and results without a document, are not boosted.
+func boostResults(results []RetrievalResult, options RetrieveOptions, now time.Time) []RetrievalResult {
+	recency := options.RecencyHalfLife > 0 && options.RecencyWeight > 0
+	if !recency && len(options.SourceTypeWeights) == 0 {
+		return results
+	}
+
+	for i := range results {
+		doc := results[i].Document
+		if doc == nil {
+			continue
+		}
+		factor := 1.0
+		if recency {
+			factor *= recencyFactor(doc, options.RecencyHalfLife, options.RecencyWeight, now)
+		}
+		if weight, ok := options.SourceTypeWeights[doc.SourceType]; ok {
+			factor *= weight
+		}
+		results[i].Score *= factor
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	for i := range results {
+		results[i].Position = i
+	}
+	return results
+}
+
+// recencyFactor is the share of the score a document keeps for its age
+func recencyFactor(doc *Document, halfLife time.Duration, weight float64, now time.Time) float64 {
+	updated := doc.Metadata.ModifiedAt
+	if updated.IsZero() {
+		updated = doc.Metadata.CreatedAt
+	}
+	if updated.IsZero() {
+		return 1
+	}
+	age := now.Sub(updated)
+	if age < 0 {
+		age = 0
+	}
+	decay := math.Pow(0.5, float64(age)/float64(halfLife))
+	return (1 - weight) + weight*decay
+}
+
+// validateBoosts checks recency and source type boosting settings
+func validateBoosts(halfLife time.Duration, weight float64, sourceTypeWeights map[string]float64) error {
+	if halfLife < 0 {
+		return fmt.Errorf("recency_half_life cannot be negative")
+	}
+	if weight < 0 || weight > 1 {
+		return fmt.Errorf("recency_weight must be between 0 and 1")
+	}
+	for sourceType, w := range sourceTypeWeights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("source_type_weights[%s] must be a non-negative number", sourceType)
+		}
+	}
+	return nil
+}
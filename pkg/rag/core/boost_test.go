@@ -0,0 +1,84 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBoostResults(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	results := []RetrievalResult{
+		{DocumentID: "stale", Score: 0.9, Document: &Document{SourceType: "wiki", Metadata: DocumentMetadata{ModifiedAt: now.Add(-60 * day)}}},
+		{DocumentID: "fresh", Score: 0.8, Document: &Document{SourceType: "wiki", Metadata: DocumentMetadata{ModifiedAt: now.Add(-1 * day)}}},
+		{DocumentID: "forum", Score: 0.85, Document: &Document{SourceType: "forum", Metadata: DocumentMetadata{CreatedAt: now}}},
+		{DocumentID: "undated", Score: 0.5, Document: &Document{SourceType: "wiki"}},
+	}
+
+	options := RetrieveOptions{
+		RecencyHalfLife:   30 * day,
+		RecencyWeight:     0.5,
+		SourceTypeWeights: map[string]float64{"forum": 0.5},
+	}
+	results = boostResults(results, options, now)
+
+	order := []string{}
+	for _, result := range results {
+		order = append(order, result.DocumentID)
+	}
+	if order[0] != "fresh" || order[len(order)-1] != "forum" {
+		t.Fatalf("unexpected order %v", order)
+	}
+	// 60 days at a 30 day half-life keeps half the score plus a quarter of the other half
+	for _, result := range results {
+		switch result.DocumentID {
+		case "stale":
+			if math.Abs(result.Score-0.9*0.625) > 1e-9 {
+				t.Fatalf("unexpected stale score %v", result.Score)
+			}
+		case "undated":
+			if result.Score != 0.5 {
+				t.Fatalf("expected undated document to keep its score, got %v", result.Score)
+			}
+		case "forum":
+			if math.Abs(result.Score-0.425) > 1e-9 {
+				t.Fatalf("unexpected forum score %v", result.Score)
+			}
+		}
+	}
+
+	// Disabled boosting leaves results untouched
+	unchanged := []RetrievalResult{{Score: 0.1, Document: &Document{}}, {Score: 0.9, Document: &Document{}}}
+	if got := boostResults(unchanged, RetrieveOptions{RecencyWeight: 1}, now); got[0].Score != 0.1 {
+		t.Fatalf("expected no reordering without a half-life, got %+v", got)
+	}
+}
+
+func TestValidateBoosts(t *testing.T) {
+	config := DefaultConfig()
+	config.Retrieval.RecencyWeight = 1.5
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected recency weight above 1 to be rejected")
+	}
+
+	weight := 0.3
+	halfLife := 90 * 24 * time.Hour
+	project := &ProjectConfig{Retrieval: RetrievalOverrides{
+		RecencyWeight:     &weight,
+		RecencyHalfLife:   &halfLife,
+		SourceTypeWeights: map[string]float64{"docs": 1.5},
+	}}
+	if err := project.Validate(DefaultConfig()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	merged := project.Apply(DefaultConfig())
+	if merged.Retrieval.RecencyWeight != 0.3 || merged.Retrieval.SourceTypeWeights["docs"] != 1.5 {
+		t.Fatalf("expected overrides to be applied, got %+v", merged.Retrieval)
+	}
+
+	project.Retrieval.SourceTypeWeights["spam"] = -1
+	if err := project.Validate(DefaultConfig()); err == nil {
+		t.Fatal("expected negative source weight to be rejected")
+	}
+}
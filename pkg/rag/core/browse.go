@@ -0,0 +1,425 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Document facets counted by BrowseDocuments, named after the filter DSL
+// fields they correspond to
+const (
+	FacetSource = "source"
+	FacetType   = "type"
+	FacetLang   = "lang"
+	FacetTag    = "tag"
+)
+
+// DefaultFacets are counted when BrowseOptions.Facets is empty
+var DefaultFacets = []string{FacetSource, FacetType, FacetLang, FacetTag}
+
+// Document browse sort fields
+const (
+	SortByRelevance = "relevance"
+	SortByCreated   = "created_at"
+	SortByModified  = "modified_at"
+	SortByUpdated   = "updated_at"
+	SortByTitle     = "title"
+	SortBySize      = "size"
+)
+
+const defaultBrowseLimit = 50
+
+// BrowseOptions configures BrowseDocuments
+type BrowseOptions struct {
+	ListOptions
+
+	// Query is a full-text search over title, path, URI and content; every
+	// term must match
+	Query string `json:"query,omitempty"`
+
+	// Cursor continues after the last document of a previous page and takes
+	// precedence over Offset
+	Cursor string `json:"cursor,omitempty"`
+
+	// Facets to count over the filtered documents; defaults to DefaultFacets
+	Facets []string `json:"facets,omitempty"`
+}
+
+// FacetCount is the number of documents sharing a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// DocumentPage is one page of browsed documents
+type DocumentPage struct {
+	Documents  []Document              `json:"documents"`
+	Total      int                     `json:"total"`
+	Offset     int                     `json:"offset"`
+	Limit      int                     `json:"limit"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+	Facets     map[string][]FacetCount `json:"facets"`
+}
+
+// documentCursor is the sort position of the last document on a page. Times
+// and titles sort by Text, sizes and relevance by Num.
+type documentCursor struct {
+	Num  float64 `json:"n,omitempty"`
+	Text string  `json:"t,omitempty"`
+	ID   string  `json:"id"`
+}
+
+// cursorTimeFormat is fixed width so formatted times sort lexically
+const cursorTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// BrowseDocuments lists indexed documents matching the filter criteria and
+// search query, with facet counts over all matches and one sorted page of
+// results
+func (p *Pipeline) BrowseDocuments(ctx context.Context, options BrowseOptions) (*DocumentPage, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	sortBy, desc, _ := browseSort(options)
+	criteria := options.Filter
+	if criteria.Expression != nil {
+		criteria.Expression.Pushdown(&criteria)
+	}
+	facets := options.Facets
+	if len(facets) == 0 {
+		facets = DefaultFacets
+	}
+	var after *documentCursor
+	if options.Cursor != "" {
+		after, _ = decodeDocumentCursor(options.Cursor)
+	}
+
+	// Storage may ignore filters, so every criterion is applied again below
+	documents, err := p.ListDocuments(ctx, ListOptions{Filter: criteria})
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(options.Query))
+	var matched []Document
+	var keys []documentCursor
+	for _, doc := range documents {
+		if !MatchDocument(doc, criteria) {
+			continue
+		}
+		relevance, ok := documentRelevance(doc, terms)
+		if !ok {
+			continue
+		}
+		matched = append(matched, doc)
+		keys = append(keys, documentSortKey(doc, sortBy, relevance))
+	}
+
+	page := &DocumentPage{
+		Total:  len(matched),
+		Limit:  options.Limit,
+		Facets: countFacets(matched, facets),
+	}
+	if page.Limit <= 0 {
+		page.Limit = defaultBrowseLimit
+	}
+
+	order := make([]int, len(matched))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return compareDocumentCursors(keys[order[a]], keys[order[b]], desc) < 0
+	})
+
+	start := options.Offset
+	if after != nil {
+		start = sort.Search(len(order), func(i int) bool {
+			return compareDocumentCursors(keys[order[i]], *after, desc) > 0
+		})
+	}
+	start = min(max(start, 0), len(order))
+	end := min(start+page.Limit, len(order))
+	page.Offset = start
+
+	page.Documents = make([]Document, 0, end-start)
+	for _, i := range order[start:end] {
+		page.Documents = append(page.Documents, matched[i])
+	}
+	if end < len(order) {
+		page.NextCursor = encodeDocumentCursor(keys[order[end-1]])
+	}
+	return page, nil
+}
+
+// Validate checks the sort, facets, filter expression and cursor
+func (o BrowseOptions) Validate() error {
+	if _, _, err := browseSort(o); err != nil {
+		return err
+	}
+	for _, facet := range o.Facets {
+		switch facet {
+		case FacetSource, FacetType, FacetLang, FacetTag:
+		default:
+			return fmt.Errorf("unsupported facet %q", facet)
+		}
+	}
+	if o.Filter.Expression != nil {
+		if err := o.Filter.Expression.Validate(); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	if o.Cursor != "" {
+		if _, err := decodeDocumentCursor(o.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// browseSort resolves the sort field and direction. Searches sort by
+// relevance and plain listings by modification time unless told otherwise.
+func browseSort(options BrowseOptions) (string, bool, error) {
+	sortBy := strings.ToLower(options.SortBy)
+	if sortBy == "" {
+		sortBy = SortByModified
+		if strings.TrimSpace(options.Query) != "" {
+			sortBy = SortByRelevance
+		}
+	}
+	switch sortBy {
+	case SortByRelevance, SortByCreated, SortByModified, SortByUpdated, SortByTitle, SortBySize:
+	default:
+		return "", false, fmt.Errorf("unsupported sort field %q", options.SortBy)
+	}
+
+	switch strings.ToLower(options.SortOrder) {
+	case "asc":
+		return sortBy, false, nil
+	case "desc":
+		return sortBy, true, nil
+	case "":
+		return sortBy, sortBy != SortByTitle, nil
+	}
+	return "", false, fmt.Errorf("unsupported sort order %q", options.SortOrder)
+}
+
+// MatchDocument reports whether a document satisfies the document-level
+// filter criteria. Chunk and score criteria do not apply to documents.
+func MatchDocument(doc Document, criteria FilterCriteria) bool {
+	if len(criteria.DocumentIDs) > 0 && !containsFold(criteria.DocumentIDs, doc.ID) {
+		return false
+	}
+	if len(criteria.DataSourceIDs) > 0 && !containsFold(criteria.DataSourceIDs, doc.DataSourceID) {
+		return false
+	}
+	if len(criteria.FileTypes) > 0 && !containsFold(criteria.FileTypes, doc.Metadata.FileType) {
+		return false
+	}
+	if len(criteria.Tags) > 0 && !containsAnyFold(criteria.Tags, doc.Tags) {
+		return false
+	}
+	if len(criteria.Categories) > 0 && !containsAnyFold(criteria.Categories, doc.Categories) {
+		return false
+	}
+	if len(criteria.Authors) > 0 && !containsFold(criteria.Authors, doc.Metadata.Author) {
+		return false
+	}
+	if criteria.Language != "" && !strings.EqualFold(languageBase(doc.Language), languageBase(criteria.Language)) {
+		return false
+	}
+
+	if !inTimeRange(doc.Metadata.CreatedAt, criteria.CreatedAfter, criteria.CreatedBefore) ||
+		!inTimeRange(doc.Metadata.ModifiedAt, criteria.ModifiedAfter, criteria.ModifiedBefore) {
+		return false
+	}
+
+	length := documentLength(doc)
+	if (criteria.MinLength > 0 && length < criteria.MinLength) || (criteria.MaxLength > 0 && length > criteria.MaxLength) {
+		return false
+	}
+	words := doc.Metadata.WordCount
+	if words == 0 {
+		words = len(strings.Fields(doc.Content))
+	}
+	if (criteria.MinWordCount > 0 && words < criteria.MinWordCount) || (criteria.MaxWordCount > 0 && words > criteria.MaxWordCount) {
+		return false
+	}
+
+	if criteria.Expression != nil && !criteria.Expression.Match(RetrievalResult{DocumentID: doc.ID, Document: &doc}) {
+		return false
+	}
+	return true
+}
+
+func inTimeRange(t time.Time, after, before *time.Time) bool {
+	if after != nil && (t.IsZero() || t.Before(*after)) {
+		return false
+	}
+	if before != nil && (t.IsZero() || t.After(*before)) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyFold(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsFold(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func documentLength(doc Document) int {
+	if doc.Metadata.Length > 0 {
+		return doc.Metadata.Length
+	}
+	return len(doc.Content)
+}
+
+// documentRelevance scores a document against lowercased search terms,
+// weighting title matches above body matches. It reports false when a term
+// is missing.
+func documentRelevance(doc Document, terms []string) (float64, bool) {
+	if len(terms) == 0 {
+		return 0, true
+	}
+	title := strings.ToLower(doc.Title)
+	location := strings.ToLower(doc.URI + " " + doc.Metadata.FilePath)
+	content := strings.ToLower(doc.Content)
+
+	var score float64
+	for _, term := range terms {
+		inTitle := strings.Count(title, term)
+		inLocation := strings.Count(location, term)
+		inContent := strings.Count(content, term)
+		if inTitle+inLocation+inContent == 0 {
+			return 0, false
+		}
+		score += 5*float64(inTitle) + 2*float64(inLocation) + float64(inContent)
+	}
+	return score, true
+}
+
+func documentSortKey(doc Document, sortBy string, relevance float64) documentCursor {
+	key := documentCursor{ID: doc.ID}
+	switch sortBy {
+	case SortByRelevance:
+		key.Num = relevance
+	case SortBySize:
+		key.Num = float64(documentLength(doc))
+	case SortByTitle:
+		key.Text = strings.ToLower(doc.Title)
+	case SortByCreated:
+		key.Text = doc.Metadata.CreatedAt.UTC().Format(cursorTimeFormat)
+	case SortByModified:
+		modified := doc.Metadata.ModifiedAt
+		if modified.IsZero() {
+			modified = doc.Metadata.CreatedAt
+		}
+		key.Text = modified.UTC().Format(cursorTimeFormat)
+	case SortByUpdated:
+		key.Text = doc.UpdatedAt.UTC().Format(cursorTimeFormat)
+	}
+	return key
+}
+
+// compareDocumentCursors orders sort keys in page order, breaking ties by
+// ascending document ID so pages never overlap or skip documents
+func compareDocumentCursors(a, b documentCursor, desc bool) int {
+	cmp := 0
+	switch {
+	case a.Num < b.Num:
+		cmp = -1
+	case a.Num > b.Num:
+		cmp = 1
+	default:
+		cmp = strings.Compare(a.Text, b.Text)
+	}
+	if desc {
+		cmp = -cmp
+	}
+	if cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func encodeDocumentCursor(key documentCursor) string {
+	data, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeDocumentCursor(cursor string) (*documentCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var key documentCursor
+	if err := json.Unmarshal(data, &key); err != nil || key.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &key, nil
+}
+
+// countFacets counts documents per facet value, most common first
+func countFacets(documents []Document, facets []string) map[string][]FacetCount {
+	result := make(map[string][]FacetCount, len(facets))
+	for _, facet := range facets {
+		counts := make(map[string]int)
+		for _, doc := range documents {
+			for _, value := range documentFacetValues(doc, facet) {
+				counts[value]++
+			}
+		}
+		list := make([]FacetCount, 0, len(counts))
+		for value, count := range counts {
+			list = append(list, FacetCount{Value: value, Count: count})
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Value < list[j].Value
+		})
+		result[facet] = list
+	}
+	return result
+}
+
+func documentFacetValues(doc Document, facet string) []string {
+	switch facet {
+	case FacetSource:
+		return []string{doc.DataSourceID}
+	case FacetType:
+		return []string{doc.Metadata.FileType}
+	case FacetLang:
+		return []string{doc.Language}
+	case FacetTag:
+		// Count each tag once per document
+		seen := make(map[string]bool, len(doc.Tags))
+		var tags []string
+		for _, tag := range doc.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		return tags
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listStorage serves a fixed document list and ignores list options
+type listStorage struct {
+	Storage
+	documents []Document
+}
+
+func (s *listStorage) ListDocuments(ctx context.Context, options ListOptions) ([]Document, error) {
+	return append([]Document(nil), s.documents...), nil
+}
+
+func browseIDs(page *DocumentPage) string {
+	ids := make([]string, len(page.Documents))
+	for i, doc := range page.Documents {
+		ids[i] = doc.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestBrowseDocuments(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) DocumentMetadata {
+		at := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return DocumentMetadata{CreatedAt: at, ModifiedAt: at, FileType: "markdown"}
+	}
+	documents := []Document{
+		{ID: "a", Title: "Billing guide", Content: "Invoices are sent monthly.", DataSourceID: "s1", Language: "en", Tags: []string{"billing"}, Metadata: day(1)},
+		{ID: "b", Title: "Refund invoices", Content: "Refunds are issued within 30 days.", DataSourceID: "s1", Language: "en", Tags: []string{"billing", "refunds"}, Metadata: day(3)},
+		{ID: "c", Title: "Facturas", Content: "Las facturas se envían cada mes.", DataSourceID: "s2", Language: "es", Tags: []string{"billing"}, Metadata: day(2)},
+		{ID: "d", Title: "Onboarding", Content: "Welcome aboard.", DataSourceID: "s2", Language: "en", Metadata: DocumentMetadata{FileType: "pdf"}},
+		{ID: "e", Title: "Other project", Content: "Invoices elsewhere.", DataSourceID: "s9", Language: "en"},
+	}
+	p := &Pipeline{config: DefaultConfig(), storage: &listStorage{documents: documents}}
+	scope := FilterCriteria{DataSourceIDs: []string{"s1", "s2"}}
+
+	// Newest first by default, with facets over every match
+	page, err := p.BrowseDocuments(ctx, BrowseOptions{ListOptions: ListOptions{Limit: 2, Filter: scope}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if browseIDs(page) != "b,c" || page.Total != 4 || page.NextCursor == "" {
+		t.Fatalf("unexpected first page %s total=%d cursor=%q", browseIDs(page), page.Total, page.NextCursor)
+	}
+	if tags := page.Facets[FacetTag]; len(tags) != 2 || tags[0] != (FacetCount{Value: "billing", Count: 3}) {
+		t.Fatalf("unexpected tag facets %+v", tags)
+	}
+	if langs := page.Facets[FacetLang]; len(langs) != 2 || langs[0] != (FacetCount{Value: "en", Count: 3}) {
+		t.Fatalf("unexpected language facets %+v", langs)
+	}
+
+	next, err := p.BrowseDocuments(ctx, BrowseOptions{ListOptions: ListOptions{Limit: 2, Filter: scope}, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if browseIDs(next) != "a,d" || next.Offset != 2 || next.NextCursor != "" {
+		t.Fatalf("unexpected second page %s offset=%d cursor=%q", browseIDs(next), next.Offset, next.NextCursor)
+	}
+
+	// Search ranks title matches first; filters narrow the facet counts too
+	page, err = p.BrowseDocuments(ctx, BrowseOptions{ListOptions: ListOptions{Filter: scope}, Query: "invoices"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if browseIDs(page) != "b,a" {
+		t.Fatalf("unexpected search results %s", browseIDs(page))
+	}
+	expr, _ := ParseFilterExpression("lang:es OR created<2024-01-02")
+	filtered := scope
+	filtered.Expression = expr
+	page, err = p.BrowseDocuments(ctx, BrowseOptions{ListOptions: ListOptions{Filter: filtered, SortBy: SortByTitle}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if browseIDs(page) != "a,c" || page.Facets[FacetSource][0] != (FacetCount{Value: "s1", Count: 1}) {
+		t.Fatalf("unexpected filtered results %s %+v", browseIDs(page), page.Facets)
+	}
+
+	for _, options := range []BrowseOptions{
+		{ListOptions: ListOptions{SortBy: "score"}},
+		{ListOptions: ListOptions{SortOrder: "up"}},
+		{Facets: []string{"owner"}},
+		{Cursor: "not-a-cursor"},
+	} {
+		if _, err := p.BrowseDocuments(ctx, options); err == nil {
+			t.Fatalf("expected %+v to be rejected", options)
+		}
+	}
+}
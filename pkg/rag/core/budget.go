@@ -0,0 +1,356 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Budget limit kinds
+const (
+	BudgetLimitTokens = "tokens"
+	BudgetLimitCost   = "cost"
+)
+
+// ErrBudgetExceeded matches errors returned when a tenant's hard budget is exhausted
+var ErrBudgetExceeded = errors.New("budget_exceeded")
+
+// TenantBudget defines the LLM spending limits of a tenant per billing cycle
+type TenantBudget struct {
+	TenantID   string  `json:"tenant_id"`
+	TokenLimit int64   `json:"token_limit,omitempty"` // Tokens per cycle, 0 for no limit
+	CostLimit  float64 `json:"cost_limit,omitempty"`  // Cost per cycle, 0 for no limit
+
+	// A hard limit rejects generation once reached; a soft limit only alerts
+	HardLimit bool `json:"hard_limit"`
+
+	// Alerting
+	AlertThresholds []float64 `json:"alert_thresholds,omitempty"` // Fractions of a limit that trigger warnings, e.g. 0.8
+	WebhookURLs     []string  `json:"webhook_urls,omitempty"`
+
+	BillingCycleDay int       `json:"billing_cycle_day"` // Day of month the cycle resets (1-28)
+	UpdatedAt       time.Time `json:"updated_at"`
+	UpdatedBy       string    `json:"updated_by,omitempty"`
+}
+
+// BudgetUsage is a tenant's consumption in one billing cycle
+type BudgetUsage struct {
+	TenantID   string    `json:"tenant_id"`
+	CycleStart time.Time `json:"cycle_start"`
+	Tokens     int64     `json:"tokens"`
+	Cost       float64   `json:"cost"`
+}
+
+// BudgetAlert is sent when usage crosses an alert threshold or a limit
+type BudgetAlert struct {
+	TenantID   string    `json:"tenant_id"`
+	Limit      string    `json:"limit"`     // tokens or cost
+	Threshold  float64   `json:"threshold"` // Fraction of the limit crossed
+	Used       float64   `json:"used"`
+	Max        float64   `json:"max"`
+	Exceeded   bool      `json:"exceeded"`   // The limit itself was reached
+	HardLimit  bool      `json:"hard_limit"` // Generation is now rejected
+	CycleStart time.Time `json:"cycle_start"`
+	ResetsAt   time.Time `json:"resets_at"`
+}
+
+// BudgetExceededError is returned when generation is rejected by a hard limit
+type BudgetExceededError struct {
+	TenantID string
+	Limit    string
+	Used     float64
+	Max      float64
+	ResetsAt time.Time
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("tenant %s exceeded its %s budget (%g of %g), resets at %s",
+		e.TenantID, e.Limit, e.Used, e.Max, e.ResetsAt.Format(time.RFC3339))
+}
+
+// Is makes errors.Is(err, ErrBudgetExceeded) match
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// BudgetStore persists tenant budgets and usage per billing cycle
+type BudgetStore interface {
+	// GetBudget returns the budget of a tenant, or nil if none is set
+	GetBudget(ctx context.Context, tenantID string) (*TenantBudget, error)
+
+	// SaveBudget stores the budget of a tenant
+	SaveBudget(ctx context.Context, budget *TenantBudget) error
+
+	// DeleteBudget removes the budget of a tenant
+	DeleteBudget(ctx context.Context, tenantID string) error
+
+	// GetUsage returns usage in the cycle, zero when nothing was recorded
+	GetUsage(ctx context.Context, tenantID string, cycleStart time.Time) (*BudgetUsage, error)
+
+	// AddUsage atomically adds to usage in the cycle and returns the new totals
+	AddUsage(ctx context.Context, tenantID string, cycleStart time.Time, tokens int64, cost float64) (*BudgetUsage, error)
+
+	// MarkAlerted records that an alert was sent in the cycle. It returns
+	// false when the alert had already been recorded.
+	MarkAlerted(ctx context.Context, tenantID string, cycleStart time.Time, alert string) (bool, error)
+}
+
+// BudgetNotifier delivers budget alerts
+type BudgetNotifier interface {
+	NotifyBudget(ctx context.Context, budget *TenantBudget, alert BudgetAlert) error
+}
+
+// Validate checks the budget settings
+func (b *TenantBudget) Validate() error {
+	if b.TenantID == "" {
+		return fmt.Errorf("tenant_id is required")
+	}
+	if b.TokenLimit < 0 || b.CostLimit < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	if b.BillingCycleDay < 0 || b.BillingCycleDay > 28 {
+		return fmt.Errorf("billing_cycle_day must be between 1 and 28, or 0 for the first of the month")
+	}
+	for _, threshold := range b.AlertThresholds {
+		if threshold <= 0 || threshold > 1 {
+			return fmt.Errorf("alert thresholds must be in (0, 1]")
+		}
+	}
+	for _, endpoint := range b.WebhookURLs {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url: %s", endpoint)
+		}
+	}
+	return nil
+}
+
+// CycleStart returns the start of the billing cycle containing now, in UTC
+func (b *TenantBudget) CycleStart(now time.Time) time.Time {
+	day := b.BillingCycleDay
+	if day <= 0 {
+		day = 1
+	}
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), day, 0, 0, 0, 0, time.UTC)
+	if now.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start
+}
+
+// CycleEnd returns when the billing cycle containing now resets
+func (b *TenantBudget) CycleEnd(now time.Time) time.Time {
+	return b.CycleStart(now).AddDate(0, 1, 0)
+}
+
+// SetBudgetStore enables tenant budget enforcement
+func (p *Pipeline) SetBudgetStore(store BudgetStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.budgets = store
+}
+
+// SetBudgetNotifier replaces the webhook notifier used for budget alerts
+func (p *Pipeline) SetBudgetNotifier(notifier BudgetNotifier) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.budgetNotifier = notifier
+}
+
+// TenantBudgetUsage returns a tenant's budget and its usage in the current cycle
+func (p *Pipeline) TenantBudgetUsage(ctx context.Context, tenantID string) (*TenantBudget, *BudgetUsage, error) {
+	p.mu.RLock()
+	store := p.budgets
+	p.mu.RUnlock()
+	if store == nil {
+		return nil, nil, fmt.Errorf("budgets not configured")
+	}
+
+	budget, err := store.GetBudget(ctx, tenantID)
+	if err != nil || budget == nil {
+		return nil, nil, err
+	}
+	usage, err := store.GetUsage(ctx, tenantID, budget.CycleStart(time.Now()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return budget, usage, nil
+}
+
+// checkBudget rejects generation when the tenant has reached a hard limit
+func (p *Pipeline) checkBudget(ctx context.Context, tenantID string) error {
+	if tenantID == "" {
+		return nil
+	}
+	p.mu.RLock()
+	store := p.budgets
+	p.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	budget, err := store.GetBudget(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load budget: %w", err)
+	}
+	if budget == nil || !budget.HardLimit {
+		return nil
+	}
+
+	now := time.Now()
+	usage, err := store.GetUsage(ctx, tenantID, budget.CycleStart(now))
+	if err != nil {
+		return fmt.Errorf("failed to load budget usage: %w", err)
+	}
+	for _, limit := range budgetLimits(budget, usage) {
+		if limit.max > 0 && limit.used >= limit.max {
+			return &BudgetExceededError{
+				TenantID: tenantID,
+				Limit:    limit.name,
+				Used:     limit.used,
+				Max:      limit.max,
+				ResetsAt: budget.CycleEnd(now),
+			}
+		}
+	}
+	return nil
+}
+
+// recordBudgetUsage adds generation usage to the tenant's cycle and sends an
+// alert for every threshold crossed for the first time in the cycle
+func (p *Pipeline) recordBudgetUsage(ctx context.Context, tenantID string, tokens int64, cost float64) {
+	if tenantID == "" || (tokens == 0 && cost == 0) {
+		return
+	}
+	p.mu.RLock()
+	store, notifier := p.budgets, p.budgetNotifier
+	p.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	budget, err := store.GetBudget(ctx, tenantID)
+	if err != nil || budget == nil {
+		if err != nil {
+			p.emitError(ctx, "budget_usage", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	cycleStart := budget.CycleStart(now)
+	usage, err := store.AddUsage(ctx, tenantID, cycleStart, tokens, cost)
+	if err != nil {
+		p.emitError(ctx, "budget_usage", err)
+		return
+	}
+
+	thresholds := append(append([]float64(nil), budget.AlertThresholds...), 1)
+	for _, limit := range budgetLimits(budget, usage) {
+		if limit.max <= 0 {
+			continue
+		}
+		for _, threshold := range thresholds {
+			if limit.used < limit.max*threshold {
+				continue
+			}
+			first, err := store.MarkAlerted(ctx, tenantID, cycleStart, fmt.Sprintf("%s:%g", limit.name, threshold))
+			if err != nil {
+				p.emitError(ctx, "budget_alert", err)
+				continue
+			}
+			if !first {
+				continue
+			}
+
+			alert := BudgetAlert{
+				TenantID:   tenantID,
+				Limit:      limit.name,
+				Threshold:  threshold,
+				Used:       limit.used,
+				Max:        limit.max,
+				Exceeded:   threshold >= 1,
+				HardLimit:  budget.HardLimit,
+				CycleStart: cycleStart,
+				ResetsAt:   budget.CycleEnd(now),
+			}
+			p.emitEvent(ctx, "budget_alert", map[string]interface{}{"alert": alert})
+			if notifier != nil {
+				// Deliver without holding up the query
+				go func() {
+					if err := notifier.NotifyBudget(context.WithoutCancel(ctx), budget, alert); err != nil {
+						p.emitError(ctx, "budget_alert", err)
+					}
+				}()
+			}
+		}
+	}
+}
+
+// estimateCost prices tokens at the configured rate when the generator reports no cost
+func (p *Pipeline) estimateCost(totalTokens int, reported float64) float64 {
+	if reported > 0 {
+		return reported
+	}
+	return float64(totalTokens) / 1000 * p.config.Generation.CostPer1KTokens
+}
+
+type budgetLimit struct {
+	name      string
+	used, max float64
+}
+
+func budgetLimits(budget *TenantBudget, usage *BudgetUsage) []budgetLimit {
+	return []budgetLimit{
+		{name: BudgetLimitTokens, used: float64(usage.Tokens), max: float64(budget.TokenLimit)},
+		{name: BudgetLimitCost, used: usage.Cost, max: budget.CostLimit},
+	}
+}
+
+// WebhookBudgetNotifier posts budget alerts as JSON to the budget's webhook URLs
+type WebhookBudgetNotifier struct {
+	client *http.Client
+}
+
+// NewWebhookBudgetNotifier creates a webhook notifier
+func NewWebhookBudgetNotifier(timeout time.Duration) *WebhookBudgetNotifier {
+	return &WebhookBudgetNotifier{client: &http.Client{Timeout: timeout}}
+}
+
+// NotifyBudget implements the BudgetNotifier interface
+func (n *WebhookBudgetNotifier) NotifyBudget(ctx context.Context, budget *TenantBudget, alert BudgetAlert) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"event": "budget_alert",
+		"alert": alert,
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, endpoint := range budget.WebhookURLs {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := n.client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", endpoint, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			errs = append(errs, fmt.Errorf("webhook %s: HTTP %d", endpoint, resp.StatusCode))
+		}
+	}
+	return errors.Join(errs...)
+}
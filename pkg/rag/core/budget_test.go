@@ -0,0 +1,231 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBudgetStore keeps budgets and usage in memory
+type memoryBudgetStore struct {
+	mu      sync.Mutex
+	budgets map[string]*TenantBudget
+	usage   map[string]*BudgetUsage
+	alerted map[string]bool
+}
+
+func newMemoryBudgetStore(budgets ...*TenantBudget) *memoryBudgetStore {
+	s := &memoryBudgetStore{
+		budgets: make(map[string]*TenantBudget),
+		usage:   make(map[string]*BudgetUsage),
+		alerted: make(map[string]bool),
+	}
+	for _, budget := range budgets {
+		s.budgets[budget.TenantID] = budget
+	}
+	return s
+}
+
+func (s *memoryBudgetStore) GetBudget(ctx context.Context, tenantID string) (*TenantBudget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.budgets[tenantID], nil
+}
+
+func (s *memoryBudgetStore) SaveBudget(ctx context.Context, budget *TenantBudget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budgets[budget.TenantID] = budget
+	return nil
+}
+
+func (s *memoryBudgetStore) DeleteBudget(ctx context.Context, tenantID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.budgets, tenantID)
+	return nil
+}
+
+func (s *memoryBudgetStore) GetUsage(ctx context.Context, tenantID string, cycleStart time.Time) (*BudgetUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if usage, ok := s.usage[tenantID+cycleStart.String()]; ok {
+		copied := *usage
+		return &copied, nil
+	}
+	return &BudgetUsage{TenantID: tenantID, CycleStart: cycleStart}, nil
+}
+
+func (s *memoryBudgetStore) AddUsage(ctx context.Context, tenantID string, cycleStart time.Time, tokens int64, cost float64) (*BudgetUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := tenantID + cycleStart.String()
+	usage, ok := s.usage[key]
+	if !ok {
+		usage = &BudgetUsage{TenantID: tenantID, CycleStart: cycleStart}
+		s.usage[key] = usage
+	}
+	usage.Tokens += tokens
+	usage.Cost += cost
+	copied := *usage
+	return &copied, nil
+}
+
+func (s *memoryBudgetStore) MarkAlerted(ctx context.Context, tenantID string, cycleStart time.Time, alert string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := tenantID + cycleStart.String() + alert
+	if s.alerted[key] {
+		return false, nil
+	}
+	s.alerted[key] = true
+	return true, nil
+}
+
+// channelNotifier forwards alerts to a channel
+type channelNotifier chan BudgetAlert
+
+func (n channelNotifier) NotifyBudget(ctx context.Context, budget *TenantBudget, alert BudgetAlert) error {
+	n <- alert
+	return nil
+}
+
+func TestTenantBudgetValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget TenantBudget
+		valid  bool
+	}{
+		{name: "valid", budget: TenantBudget{TenantID: "t", TokenLimit: 100, AlertThresholds: []float64{0.5, 1}, WebhookURLs: []string{"https://hooks.example.com/x"}}, valid: true},
+		{name: "missing tenant", budget: TenantBudget{TokenLimit: 100}},
+		{name: "negative limit", budget: TenantBudget{TenantID: "t", CostLimit: -1}},
+		{name: "cycle day", budget: TenantBudget{TenantID: "t", BillingCycleDay: 31}},
+		{name: "threshold", budget: TenantBudget{TenantID: "t", AlertThresholds: []float64{1.5}}},
+		{name: "webhook scheme", budget: TenantBudget{TenantID: "t", WebhookURLs: []string{"ftp://example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.budget.Validate(); (err == nil) != tt.valid {
+				t.Fatalf("got %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTenantBudgetCycle(t *testing.T) {
+	budget := &TenantBudget{BillingCycleDay: 15}
+	tests := []struct {
+		now   time.Time
+		start time.Time
+	}{
+		{now: time.Date(2026, 3, 20, 10, 0, 0, 0, time.UTC), start: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{now: time.Date(2026, 3, 14, 23, 0, 0, 0, time.UTC), start: time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)},
+		{now: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC), start: time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := budget.CycleStart(tt.now); !got.Equal(tt.start) {
+			t.Errorf("CycleStart(%s) = %s, want %s", tt.now, got, tt.start)
+		}
+		if got := budget.CycleEnd(tt.now); !got.Equal(tt.start.AddDate(0, 1, 0)) {
+			t.Errorf("CycleEnd(%s) = %s", tt.now, got)
+		}
+	}
+	if got := (&TenantBudget{}).CycleStart(time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)); got.Day() != 1 {
+		t.Fatalf("expected cycles to start on the first by default, got %s", got)
+	}
+}
+
+func TestBudgetEnforcementAndAlerts(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryBudgetStore(
+		&TenantBudget{TenantID: "hard", TokenLimit: 100, HardLimit: true, AlertThresholds: []float64{0.5}},
+		&TenantBudget{TenantID: "soft", CostLimit: 1},
+	)
+	alerts := make(channelNotifier, 10)
+	p := &Pipeline{config: DefaultConfig()}
+	p.SetBudgetStore(store)
+	p.SetBudgetNotifier(alerts)
+
+	// Crossing 50% alerts once, even when usage keeps growing
+	p.recordBudgetUsage(ctx, "hard", 60, 0)
+	p.recordBudgetUsage(ctx, "hard", 10, 0)
+	if alert := <-alerts; alert.Limit != BudgetLimitTokens || alert.Threshold != 0.5 || alert.Exceeded {
+		t.Fatalf("unexpected alert %+v", alert)
+	}
+	if err := p.checkBudget(ctx, "hard"); err != nil {
+		t.Fatalf("expected generation to be allowed under the limit, got %v", err)
+	}
+
+	p.recordBudgetUsage(ctx, "hard", 30, 0)
+	if alert := <-alerts; !alert.Exceeded || !alert.HardLimit || alert.Used != 100 {
+		t.Fatalf("unexpected alert %+v", alert)
+	}
+	err := p.checkBudget(ctx, "hard")
+	var exceeded *BudgetExceededError
+	if !errors.Is(err, ErrBudgetExceeded) || !errors.As(err, &exceeded) || exceeded.Limit != BudgetLimitTokens {
+		t.Fatalf("expected the hard limit to reject generation, got %v", err)
+	}
+
+	// Soft limits alert but never reject
+	p.recordBudgetUsage(ctx, "soft", 0, 1.5)
+	if alert := <-alerts; alert.Limit != BudgetLimitCost || !alert.Exceeded || alert.HardLimit {
+		t.Fatalf("unexpected alert %+v", alert)
+	}
+	if err := p.checkBudget(ctx, "soft"); err != nil {
+		t.Fatalf("expected soft limits not to reject generation, got %v", err)
+	}
+	if err := p.checkBudget(ctx, "unknown"); err != nil {
+		t.Fatalf("expected tenants without a budget to be unrestricted, got %v", err)
+	}
+
+	budget, usage, err := p.TenantBudgetUsage(ctx, "hard")
+	if err != nil || budget.TokenLimit != 100 || usage.Tokens != 100 {
+		t.Fatalf("unexpected usage %+v %v", usage, err)
+	}
+	select {
+	case alert := <-alerts:
+		t.Fatalf("unexpected extra alert %+v", alert)
+	default:
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	config := DefaultConfig()
+	config.Generation.CostPer1KTokens = 0.002
+	p := &Pipeline{config: config}
+	if got := p.estimateCost(1500, 0); got != 0.003 {
+		t.Fatalf("got %v, want 0.003", got)
+	}
+	if got := p.estimateCost(1500, 0.5); got != 0.5 {
+		t.Fatalf("expected the reported cost to win, got %v", got)
+	}
+}
+
+func TestWebhookBudgetNotifier(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	budget := &TenantBudget{TenantID: "t", WebhookURLs: []string{ok.URL, failing.URL}}
+	err := NewWebhookBudgetNotifier(time.Second).NotifyBudget(context.Background(), budget, BudgetAlert{TenantID: "t", Limit: BudgetLimitCost})
+	if err == nil {
+		t.Fatal("expected the failing webhook to be reported")
+	}
+	body := <-received
+	if body["event"] != "budget_alert" || body["alert"].(map[string]interface{})["limit"] != BudgetLimitCost {
+		t.Fatalf("unexpected webhook body %v", body)
+	}
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// FileColdStore keeps cold embeddings as gzip-compressed float32 vectors, one
+// file per chunk. Narrowing to float32 halves the size before compression,
+// at a precision loss that does not affect similarity ranking in practice.
+type FileColdStore struct {
+	dir string
+}
+
+// NewFileColdStore creates a cold store in dir, creating it if needed
+func NewFileColdStore(dir string) (*FileColdStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cold store directory: %w", err)
+	}
+	return &FileColdStore{dir: dir}, nil
+}
+
+// PutVectors writes each vector to its own file and returns the bytes written
+func (s *FileColdStore) PutVectors(ctx context.Context, vectors map[string][]float64) (int64, error) {
+	var written int64
+	for chunkID, vector := range vectors {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		values := make([]float32, len(vector))
+		for i, v := range vector {
+			values[i] = float32(v)
+		}
+		if err := binary.Write(zw, binary.LittleEndian, values); err != nil {
+			return written, err
+		}
+		if err := zw.Close(); err != nil {
+			return written, err
+		}
+
+		// Write then rename so a crash never leaves a truncated vector
+		path := s.path(chunkID)
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+			return written, fmt.Errorf("failed to write cold vector %s: %w", chunkID, err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return written, fmt.Errorf("failed to write cold vector %s: %w", chunkID, err)
+		}
+		written += int64(buf.Len())
+	}
+	return written, nil
+}
+
+// GetVectors reads the vectors of the given chunks, skipping missing ones
+func (s *FileColdStore) GetVectors(ctx context.Context, chunkIDs []string) (map[string][]float64, error) {
+	vectors := make(map[string][]float64, len(chunkIDs))
+	for _, chunkID := range chunkIDs {
+		file, err := os.Open(s.path(chunkID))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := readGzip(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cold vector %s: %w", chunkID, err)
+		}
+		if len(data)%4 != 0 {
+			return nil, fmt.Errorf("cold vector %s is corrupt", chunkID)
+		}
+		vector := make([]float64, len(data)/4)
+		for i := range vector {
+			vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+		}
+		vectors[chunkID] = vector
+	}
+	return vectors, nil
+}
+
+// DeleteVectors removes the files of the given chunks
+func (s *FileColdStore) DeleteVectors(ctx context.Context, chunkIDs []string) error {
+	for _, chunkID := range chunkIDs {
+		if err := os.Remove(s.path(chunkID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// path hashes the chunk ID so any ID is a safe file name
+func (s *FileColdStore) path(chunkID string) string {
+	sum := sha256.Sum256([]byte(chunkID))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".vec.gz")
+}
+
+func readGzip(r io.Reader) ([]byte, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,359 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// Comparison defaults
+const (
+	defaultCompareTopK      = 5
+	defaultCompareBatchSize = 32
+)
+
+// EvalCase is a question with the documents that answer it
+type EvalCase struct {
+	Query             string   `json:"query"`
+	RelevantDocuments []string `json:"relevant_documents"`
+}
+
+// ChunkerVariant is one side of a chunker comparison
+type ChunkerVariant struct {
+	Name     string           `json:"name"`
+	Config   ChunkingConfig   `json:"config"`
+	Strategy ChunkingStrategy `json:"-"` // Built from Config by the caller
+}
+
+// ChunkerComparisonOptions defines a chunker A/B comparison
+type ChunkerComparisonOptions struct {
+	A         ChunkerVariant            `json:"a"`
+	B         ChunkerVariant            `json:"b"`
+	Documents []Document                `json:"-"`
+	EvalSet   []EvalCase                `json:"eval_set"`
+	Generator embedding.VectorGenerator `json:"-"`
+
+	// NewStrategy builds the strategy of a variant that has none
+	NewStrategy func(ChunkingConfig, embedding.VectorGenerator) (ChunkingStrategy, error) `json:"-"`
+
+	TopK            int     `json:"top_k"`
+	BatchSize       int     `json:"batch_size"`
+	CostPer1KTokens float64 `json:"cost_per_1k_tokens"` // Embedding price used for cost estimates
+}
+
+// EvalCaseResult is how one variant answered one evaluation question
+type EvalCaseResult struct {
+	Query         string   `json:"query"`
+	Documents     []string `json:"documents"`      // Documents of the top chunks, in rank order
+	FirstRelevant int      `json:"first_relevant"` // 1-based rank of the first relevant document, 0 if none
+	Recall        float64  `json:"recall"`
+	TopScore      float64  `json:"top_score"`
+}
+
+// ChunkerReport holds the retrieval quality and cost of one variant
+type ChunkerReport struct {
+	Name     string         `json:"name"`
+	Strategy string         `json:"strategy"`
+	Config   ChunkingConfig `json:"config"`
+
+	// Cost
+	Chunks         int           `json:"chunks"`
+	AvgChunkTokens float64       `json:"avg_chunk_tokens"`
+	EmbeddedTokens int           `json:"embedded_tokens"`
+	EmbeddingCalls int           `json:"embedding_calls"`
+	EstimatedCost  float64       `json:"estimated_cost"`
+	ContextTokens  float64       `json:"context_tokens"` // Mean tokens of the top chunks sent to generation
+	IndexTime      time.Duration `json:"index_time"`
+
+	// Retrieval quality
+	HitRate     float64          `json:"hit_rate"` // Share of questions with a relevant document in the top K
+	Recall      float64          `json:"recall"`   // Mean share of relevant documents in the top K
+	MRR         float64          `json:"mrr"`
+	AvgTopScore float64          `json:"avg_top_score"`
+	Cases       []EvalCaseResult `json:"cases"`
+	Errors      []string         `json:"errors,omitempty"`
+}
+
+// ChunkerComparison reports two chunking configurations side by side
+type ChunkerComparison struct {
+	Documents int           `json:"documents"`
+	Questions int           `json:"questions"`
+	TopK      int           `json:"top_k"`
+	A         ChunkerReport `json:"a"`
+	B         ChunkerReport `json:"b"`
+
+	// B minus A
+	HitRateDelta        float64 `json:"hit_rate_delta"`
+	RecallDelta         float64 `json:"recall_delta"`
+	MRRDelta            float64 `json:"mrr_delta"`
+	EmbeddedTokensDelta int     `json:"embedded_tokens_delta"`
+	ContextTokensDelta  float64 `json:"context_tokens_delta"`
+
+	Winner string `json:"winner"` // Name of the variant with the higher MRR, "" on a tie
+}
+
+// namespaceEntry is a chunk in a temporary comparison namespace
+type namespaceEntry struct {
+	documentID string
+	tokens     int
+	vector     []float64
+}
+
+// CompareChunkers chunks the documents with both variants, embeds each into
+// its own temporary in-memory namespace and runs the evaluation set against
+// both. Nothing is written to storage or the serving index.
+func CompareChunkers(ctx context.Context, options ChunkerComparisonOptions) (*ChunkerComparison, error) {
+	if options.Generator == nil {
+		return nil, fmt.Errorf("embedding generator is required")
+	}
+	for _, variant := range []*ChunkerVariant{&options.A, &options.B} {
+		if variant.Strategy != nil {
+			continue
+		}
+		if options.NewStrategy == nil {
+			return nil, fmt.Errorf("both chunking strategies are required")
+		}
+		strategy, err := options.NewStrategy(variant.Config, options.Generator)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %w", variant.Name, err)
+		}
+		variant.Strategy = strategy
+	}
+	if len(options.Documents) == 0 {
+		return nil, fmt.Errorf("no documents to compare on")
+	}
+	if len(options.EvalSet) == 0 {
+		return nil, fmt.Errorf("evaluation set is empty")
+	}
+	if options.TopK <= 0 {
+		options.TopK = defaultCompareTopK
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultCompareBatchSize
+	}
+
+	// Questions are embedded once and shared by both namespaces
+	queries := make([]string, len(options.EvalSet))
+	for i, c := range options.EvalSet {
+		queries[i] = c.Query
+	}
+	queryVectors, err := embedBatches(ctx, options.Generator, queries, options.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed evaluation questions: %w", err)
+	}
+
+	comparison := &ChunkerComparison{
+		Documents: len(options.Documents),
+		Questions: len(options.EvalSet),
+		TopK:      options.TopK,
+	}
+	for _, side := range []struct {
+		variant ChunkerVariant
+		report  *ChunkerReport
+	}{{options.A, &comparison.A}, {options.B, &comparison.B}} {
+		report, err := evaluateChunker(ctx, side.variant, options, queryVectors)
+		if err != nil {
+			return nil, fmt.Errorf("variant %s: %w", side.variant.Name, err)
+		}
+		*side.report = *report
+	}
+
+	comparison.HitRateDelta = comparison.B.HitRate - comparison.A.HitRate
+	comparison.RecallDelta = comparison.B.Recall - comparison.A.Recall
+	comparison.MRRDelta = comparison.B.MRR - comparison.A.MRR
+	comparison.EmbeddedTokensDelta = comparison.B.EmbeddedTokens - comparison.A.EmbeddedTokens
+	comparison.ContextTokensDelta = comparison.B.ContextTokens - comparison.A.ContextTokens
+	switch {
+	case comparison.MRRDelta > 0:
+		comparison.Winner = comparison.B.Name
+	case comparison.MRRDelta < 0:
+		comparison.Winner = comparison.A.Name
+	}
+	return comparison, nil
+}
+
+// CompareProjectChunkers compares two chunkers on a sample of the documents of
+// the given data sources, embedding with the pipeline's generator. Documents
+// named in the evaluation set are sampled first so every question can be
+// answered.
+func (p *Pipeline) CompareProjectChunkers(ctx context.Context, sourceIDs []string, sampleSize int, options ChunkerComparisonOptions) (*ChunkerComparison, error) {
+	if options.Generator == nil && p.processor != nil {
+		options.Generator = p.processor.GetEmbeddingGenerator()
+	}
+	if len(options.Documents) == 0 {
+		documents, err := p.scopeDocuments(ctx, sourceIDs)
+		if err != nil {
+			return nil, err
+		}
+		options.Documents = SampleDocuments(documents, options.EvalSet, sampleSize)
+	}
+	return CompareChunkers(ctx, options)
+}
+
+// SampleDocuments keeps up to size documents, those relevant to the
+// evaluation set first; size <= 0 keeps all of them
+func SampleDocuments(documents []Document, evalSet []EvalCase, size int) []Document {
+	if size <= 0 || len(documents) <= size {
+		return documents
+	}
+	relevant := make(map[string]bool)
+	for _, c := range evalSet {
+		for _, id := range c.RelevantDocuments {
+			relevant[id] = true
+		}
+	}
+	sample := make([]Document, 0, size)
+	for _, doc := range documents {
+		if relevant[doc.ID] && len(sample) < size {
+			sample = append(sample, doc)
+		}
+	}
+	for _, doc := range documents {
+		if !relevant[doc.ID] && len(sample) < size {
+			sample = append(sample, doc)
+		}
+	}
+	return sample
+}
+
+// evaluateChunker indexes the documents with one variant and scores the evaluation set
+func evaluateChunker(ctx context.Context, variant ChunkerVariant, options ChunkerComparisonOptions, queryVectors [][]float64) (*ChunkerReport, error) {
+	report := &ChunkerReport{
+		Name:     variant.Name,
+		Strategy: variant.Strategy.GetName(),
+		Config:   variant.Config,
+	}
+
+	start := time.Now()
+	var namespace []namespaceEntry
+	var texts []string
+	for _, doc := range options.Documents {
+		chunks, err := variant.Strategy.Chunk(ctx, doc)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
+			continue
+		}
+		for _, chunk := range chunks {
+			tokens := EstimateTokens(chunk.Content)
+			namespace = append(namespace, namespaceEntry{documentID: doc.ID, tokens: tokens})
+			texts = append(texts, chunk.Content)
+			report.EmbeddedTokens += tokens
+		}
+	}
+	if len(namespace) == 0 {
+		return nil, fmt.Errorf("no chunks produced")
+	}
+
+	vectors, err := embedBatches(ctx, options.Generator, texts, options.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed chunks: %w", err)
+	}
+	for i := range namespace {
+		namespace[i].vector = vectors[i]
+	}
+	report.IndexTime = time.Since(start)
+	report.Chunks = len(namespace)
+	report.AvgChunkTokens = float64(report.EmbeddedTokens) / float64(report.Chunks)
+	report.EmbeddingCalls = (len(texts) + options.BatchSize - 1) / options.BatchSize
+	report.EstimatedCost = float64(report.EmbeddedTokens) / 1000 * options.CostPer1KTokens
+
+	type scored struct {
+		entry *namespaceEntry
+		score float64
+	}
+	for i, c := range options.EvalSet {
+		ranked := make([]scored, len(namespace))
+		for j := range namespace {
+			ranked[j] = scored{&namespace[j], cosine(queryVectors[i], namespace[j].vector)}
+		}
+		sort.SliceStable(ranked, func(a, b int) bool { return ranked[a].score > ranked[b].score })
+		if len(ranked) > options.TopK {
+			ranked = ranked[:options.TopK]
+		}
+
+		result := EvalCaseResult{Query: c.Query, TopScore: ranked[0].score}
+		relevant := make(map[string]bool, len(c.RelevantDocuments))
+		for _, id := range c.RelevantDocuments {
+			relevant[id] = true
+		}
+		listed := make(map[string]bool)
+		matched := 0
+		contextTokens := 0
+		for rank, r := range ranked {
+			contextTokens += r.entry.tokens
+			id := r.entry.documentID
+			if listed[id] {
+				continue
+			}
+			listed[id] = true
+			result.Documents = append(result.Documents, id)
+			if relevant[id] {
+				matched++
+				if result.FirstRelevant == 0 {
+					result.FirstRelevant = rank + 1
+				}
+			}
+		}
+		if len(relevant) > 0 {
+			result.Recall = float64(matched) / float64(len(relevant))
+		}
+
+		if result.FirstRelevant > 0 {
+			report.HitRate++
+			report.MRR += 1 / float64(result.FirstRelevant)
+		}
+		report.Recall += result.Recall
+		report.AvgTopScore += result.TopScore
+		report.ContextTokens += float64(contextTokens)
+		report.Cases = append(report.Cases, result)
+	}
+
+	n := float64(len(options.EvalSet))
+	report.HitRate /= n
+	report.MRR /= n
+	report.Recall /= n
+	report.AvgTopScore /= n
+	report.ContextTokens /= n
+	return report, nil
+}
+
+// embedBatches embeds texts in batches, keeping their order
+func embedBatches(ctx context.Context, generator embedding.VectorGenerator, texts []string, batchSize int) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for i := 0; i < len(texts); i += batchSize {
+		end := i + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		batch, err := generator.Embed(ctx, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-i {
+			return nil, fmt.Errorf("embedding count mismatch: got %d, want %d", len(batch), end-i)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// cosine returns the cosine similarity of two vectors
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
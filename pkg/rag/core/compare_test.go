@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// wordGenerator embeds texts as counts of a fixed vocabulary
+type wordGenerator struct {
+	vocabulary []string
+}
+
+func (g *wordGenerator) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = g.EmbedSingle(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (g *wordGenerator) EmbedSingle(ctx context.Context, text string) ([]float64, error) {
+	vector := make([]float64, len(g.vocabulary))
+	for i, word := range g.vocabulary {
+		vector[i] = float64(strings.Count(text, word))
+	}
+	return vector, nil
+}
+
+func (g *wordGenerator) GetDimension() int    { return len(g.vocabulary) }
+func (g *wordGenerator) GetModelName() string { return "words" }
+func (g *wordGenerator) GetCapabilities() embedding.ModelCapabilities {
+	return embedding.ModelCapabilities{}
+}
+func (g *wordGenerator) Close() error { return nil }
+
+// lineChunker splits documents into lines, or keeps them whole
+type lineChunker struct {
+	whole bool
+}
+
+func (c *lineChunker) Chunk(ctx context.Context, doc Document) ([]DocumentChunk, error) {
+	if c.whole {
+		return []DocumentChunk{{DocumentID: doc.ID, Content: doc.Content}}, nil
+	}
+	var chunks []DocumentChunk
+	for _, line := range strings.Split(doc.Content, "\n") {
+		chunks = append(chunks, DocumentChunk{DocumentID: doc.ID, Content: line})
+	}
+	return chunks, nil
+}
+
+func (c *lineChunker) GetName() string                                   { return "line" }
+func (c *lineChunker) GetDescription() string                            { return "" }
+func (c *lineChunker) SetParameters(params map[string]interface{}) error { return nil }
+func (c *lineChunker) GetParameters() map[string]interface{}             { return nil }
+
+func TestCompareChunkers(t *testing.T) {
+	documents := []Document{
+		{ID: "billing", Content: "refund refund\ninvoice"},
+		{ID: "auth", Content: "login login login login login\nsso"},
+		{ID: "faq", Content: "sso refund"},
+	}
+	comparison, err := CompareChunkers(context.Background(), ChunkerComparisonOptions{
+		A:         ChunkerVariant{Name: "whole", Strategy: &lineChunker{whole: true}},
+		B:         ChunkerVariant{Name: "lines", Strategy: &lineChunker{}},
+		Documents: documents,
+		EvalSet: []EvalCase{
+			{Query: "sso", RelevantDocuments: []string{"auth"}},
+			{Query: "refund", RelevantDocuments: []string{"billing"}},
+		},
+		Generator:       &wordGenerator{vocabulary: []string{"refund", "invoice", "sso", "login"}},
+		TopK:            1,
+		CostPer1KTokens: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// As a whole, the auth document is about logins and loses the sso
+	// question to the faq; split into lines its sso line wins
+	if comparison.A.Chunks != 3 || comparison.B.Chunks != 5 {
+		t.Fatalf("unexpected chunk counts %d, %d", comparison.A.Chunks, comparison.B.Chunks)
+	}
+	if comparison.A.HitRate != 0.5 || comparison.A.Cases[0].Documents[0] != "faq" || comparison.A.Cases[1].FirstRelevant != 1 {
+		t.Fatalf("unexpected whole-document results %+v", comparison.A.Cases)
+	}
+	if comparison.B.HitRate != 1 || comparison.B.MRR != 1 || comparison.Winner != "lines" || comparison.MRRDelta != 0.5 {
+		t.Fatalf("expected line chunks to answer every question, got %+v", comparison)
+	}
+	if comparison.A.EstimatedCost <= 0 || comparison.EmbeddedTokensDelta != comparison.B.EmbeddedTokens-comparison.A.EmbeddedTokens {
+		t.Fatalf("unexpected cost report %+v", comparison)
+	}
+
+	if _, err := CompareChunkers(context.Background(), ChunkerComparisonOptions{Documents: documents}); err == nil {
+		t.Fatal("expected comparison without generator to fail")
+	}
+}
+
+func TestSampleDocuments(t *testing.T) {
+	documents := []Document{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	sample := SampleDocuments(documents, []EvalCase{{RelevantDocuments: []string{"c"}}}, 2)
+	if len(sample) != 2 || sample[0].ID != "c" || sample[1].ID != "a" {
+		t.Fatalf("unexpected sample %+v", sample)
+	}
+	if len(SampleDocuments(documents, nil, 0)) != 3 {
+		t.Fatal("expected all documents without a sample size")
+	}
+}
@@ -25,6 +25,9 @@ type Config struct {
 	// Generation configuration
 	Generation GenerationConfig `json:"generation"`
 
+	// Provider routing and failover
+	Routing RoutingConfig `json:"routing"`
+
 	// Storage configuration
 	Storage StorageConfig `json:"storage"`
 
@@ -56,12 +59,33 @@ type SystemConfig struct {
 	MaxMemoryMB   int64 `json:"max_memory_mb"`    // Maximum memory usage in MB
 	MaxFileSizeMB int64 `json:"max_file_size_mb"` // Maximum file size to process in MB
 
+	// Batch queries
+	MaxBatchQueries   int           `json:"max_batch_queries"`   // Maximum queries per batch job
+	BatchJobRetention time.Duration `json:"batch_job_retention"` // How long finished batch results are kept
+
+	// Coordination between instances
+	Locking LockingConfig `json:"locking"`
+
+	// Reproducible IDs, timestamps and sampling for tests and evaluations
+	Deterministic DeterministicConfig `json:"deterministic"`
+
 	// Logging
 	LogLevel  string `json:"log_level"`  // debug, info, warn, error
 	LogFormat string `json:"log_format"` // json, text
 	LogFile   string `json:"log_file,omitempty"`
 }
 
+// LockingConfig selects how instances coordinate background jobs
+type LockingConfig struct {
+	// Backend is local (in-process) or redis. Host applications may replace
+	// local locks with locks on a shared database through SetLocker.
+	Backend       string        `json:"backend"`
+	RedisURL      string        `json:"redis_url,omitempty"`
+	RedisPassword string        `json:"redis_password,omitempty"`
+	RedisDB       int           `json:"redis_db"`
+	TTL           time.Duration `json:"ttl"` // Lease duration; leases are refreshed at a third of it
+}
+
 // ProcessingConfig represents document processing configuration
 type ProcessingConfig struct {
 	// Chunking configuration
@@ -76,11 +100,20 @@ type ProcessingConfig struct {
 	// Indexing configuration
 	Indexing IndexingConfig `json:"indexing"`
 
+	// Summarization-augmented indexing
+	Summarization SummarizationConfig `json:"summarization"`
+
+	// Image ingestion
+	Images ImageConfig `json:"images"`
+
 	// Batch processing
 	BatchSize    int           `json:"batch_size"`    // Documents per batch
 	BatchTimeout time.Duration `json:"batch_timeout"` // Timeout per batch
 	MaxRetries   int           `json:"max_retries"`   // Maximum retry attempts
 	RetryDelay   time.Duration `json:"retry_delay"`   // Delay between retries
+
+	// Failed syncs of a document before it is quarantined (default 3)
+	MaxDocumentAttempts int `json:"max_document_attempts"`
 }
 
 // ChunkingConfig represents chunking strategy configuration
@@ -101,6 +134,13 @@ type ChunkingConfig struct {
 	SimilarityThreshold float64 `json:"similarity_threshold"` // Minimum similarity for semantic chunking
 	MinSimilaritySize   int     `json:"min_similarity_size"`  // Minimum size for semantic chunks
 
+	// Deduplication of identical chunks across documents of the same project
+	Deduplicate  bool `json:"deduplicate"`    // Share embeddings between identical chunks
+	DedupMinSize int  `json:"dedup_min_size"` // Minimum chunk size to deduplicate
+
+	// Skip reprocessing documents whose content is already indexed
+	DeduplicateDocuments bool `json:"deduplicate_documents"`
+
 	// Language-specific settings
 	Languages map[string]interface{} `json:"languages,omitempty"`
 
@@ -123,6 +163,10 @@ type EmbeddingConfig struct {
 	Normalize bool `json:"normalize"`           // Normalize embeddings
 	Dimension int  `json:"dimension,omitempty"` // Target dimension
 
+	// Cross-lingual model (e.g. BAAI/bge-m3) replacing Model when set, so
+	// queries match chunks written in other languages
+	MultilingualModel string `json:"multilingual_model,omitempty"`
+
 	// Cache settings
 	EnableCache bool          `json:"enable_cache"` // Enable embedding cache
 	CacheSize   int           `json:"cache_size"`   // Maximum cache entries
@@ -139,6 +183,42 @@ type EmbeddingConfig struct {
 	RetryDelay time.Duration `json:"retry_delay"`
 }
 
+// RoutingConfig represents retry, fallback and circuit breaking across providers
+type RoutingConfig struct {
+	Policy string `json:"policy"` // priority, latency or cost
+
+	// Retry on the same provider before falling back
+	MaxRetries    int           `json:"max_retries"`
+	RetryDelay    time.Duration `json:"retry_delay"`     // Initial backoff, doubled per retry
+	MaxRetryDelay time.Duration `json:"max_retry_delay"` // Backoff cap
+
+	// Circuit breaker per provider
+	FailureThreshold int           `json:"failure_threshold"` // Consecutive failures before opening
+	OpenDuration     time.Duration `json:"open_duration"`     // Time before a probe is allowed
+
+	// Hedging: a request unanswered after HedgeAfter is also sent to the next
+	// provider, at most for HedgeBudget of requests (0-1) so slow providers
+	// cannot double the cost. Either being zero disables hedging.
+	HedgeAfter  time.Duration `json:"hedge_after,omitempty"`
+	HedgeBudget float64       `json:"hedge_budget,omitempty"`
+
+	// Additional providers, tried after the generation and embedding settings
+	Providers []ProviderConfig `json:"providers,omitempty"`
+
+	// Record or replay provider calls through a cassette file
+	Replay ReplayConfig `json:"replay,omitempty"`
+}
+
+// ProviderConfig represents an OpenAI-compatible provider
+type ProviderConfig struct {
+	Name            string  `json:"name"`
+	Kind            string  `json:"kind"` // chat, embedding or rerank
+	BaseURL         string  `json:"base_url"`
+	APIKey          string  `json:"api_key,omitempty"`
+	Model           string  `json:"model"`
+	CostPer1KTokens float64 `json:"cost_per_1k_tokens,omitempty"`
+}
+
 // PreprocessingConfig represents text preprocessing configuration
 type PreprocessingConfig struct {
 	// Text cleaning
@@ -169,8 +249,12 @@ type PreprocessingConfig struct {
 	PreserveCodeFormatting bool `json:"preserve_code_formatting"` // Preserve code formatting
 
 	// Custom preprocessing
-	CustomFilters []string               `json:"custom_filters"` // Custom filter names
+	CustomFilters []string               `json:"custom_filters"` // Custom filter names, run in order before chunking
 	CustomRules   map[string]interface{} `json:"custom_rules,omitempty"`
+
+	// WASM modules implementing custom filters, by filter name
+	WASMModules map[string]string `json:"wasm_modules,omitempty"` // Filter name to module path
+	WASMLimits  WASMLimits        `json:"wasm_limits"`            // Per invocation limits
 }
 
 // IndexingConfig represents document indexing configuration
@@ -206,6 +290,38 @@ type IndexingConfig struct {
 	BackupRetention int           `json:"backup_retention"` // Number of backups to keep
 }
 
+// SummarizationConfig represents summarization-augmented indexing configuration
+type SummarizationConfig struct {
+	// Enable generating summary and keyword chunks per document/section
+	Enabled bool   `json:"enabled"`
+	Model   string `json:"model,omitempty"` // LLM model used for summaries
+
+	// Budget settings
+	MaxTokensPerDocument int `json:"max_tokens_per_document"` // Token budget per document
+	MaxInputTokens       int `json:"max_input_tokens"`        // Maximum input tokens per request
+	MaxOutputTokens      int `json:"max_output_tokens"`       // Maximum output tokens per request
+
+	// Section settings
+	MaxSections int `json:"max_sections"` // Maximum sections summarized per document
+	SectionSize int `json:"section_size"` // Approximate section size in characters
+
+	// Output settings
+	MaxKeywords int `json:"max_keywords"` // Maximum keywords per summary
+
+	// Cache settings
+	CacheSize int `json:"cache_size"` // Maximum cached summaries (by content hash)
+}
+
+// ImageConfig represents multi-modal image indexing configuration
+type ImageConfig struct {
+	Enabled        bool     `json:"enabled"`           // Enable image ingestion
+	Model          string   `json:"model"`             // CLIP-style embedding model
+	CaptionModel   string   `json:"caption_model"`     // Vision/OCR model for captions
+	MaxImageSizeMB int64    `json:"max_image_size_mb"` // Maximum image size
+	SupportedTypes []string `json:"supported_types"`   // Allowed MIME types
+	ScoreWeight    float64  `json:"score_weight"`      // Weight applied to image scores when merging
+}
+
 // RetrievalConfig represents retrieval configuration
 type RetrievalConfig struct {
 	// Search configuration
@@ -229,15 +345,24 @@ type RetrievalConfig struct {
 	RerankTopK      int     `json:"rerank_top_k"`     // Number of results to rerank
 	RerankThreshold float64 `json:"rerank_threshold"` // Minimum score for reranking
 
+	// Expand queries with definitions from the project glossary
+	ExpandGlossaryTerms bool `json:"expand_glossary_terms"`
+
+	// Recency and source boosting, applied after reranking
+	RecencyHalfLife   time.Duration      `json:"recency_half_life"`             // Age since modification at which the recency factor halves, 0 disables
+	RecencyWeight     float64            `json:"recency_weight"`                // Share of the score subject to recency decay (0-1)
+	SourceTypeWeights map[string]float64 `json:"source_type_weights,omitempty"` // Score multipliers by document source type
+
 	// Filtering configuration
 	EnableFilters  bool     `json:"enable_filters"`  // Enable result filtering
 	DefaultFilters []string `json:"default_filters"` // Default filters to apply
 
 	// Performance settings
-	MaxQueryTime time.Duration `json:"max_query_time"` // Maximum query time
-	EnableCache  bool          `json:"enable_cache"`   // Enable query cache
-	CacheSize    int           `json:"cache_size"`     // Maximum cache entries
-	CacheTTL     time.Duration `json:"cache_ttl"`      // Cache TTL
+	MaxQueryTime  time.Duration `json:"max_query_time"` // Maximum query time
+	StageTimeouts StageTimeouts `json:"stage_timeouts"` // Per-stage budgets within max_query_time
+	EnableCache   bool          `json:"enable_cache"`   // Enable query cache
+	CacheSize     int           `json:"cache_size"`     // Maximum cache entries
+	CacheTTL      time.Duration `json:"cache_ttl"`      // Cache TTL
 
 	// Advanced settings
 	Diversity           bool    `json:"diversity"`             // Enable result diversity
@@ -263,10 +388,39 @@ type GenerationConfig struct {
 	UserPromptTemplate string `json:"user_prompt_template"` // User prompt template
 	MaxContextLength   int    `json:"max_context_length"`   // Maximum context length
 
+	// Cost accounting
+	CostPer1KTokens float64 `json:"cost_per_1k_tokens"` // Price used when the generator reports no cost
+
+	// Context packing
+	ContextSafetyMargin  float64 `json:"context_safety_margin"`   // Fraction of the window kept free for estimation error
+	ContextChunkOverhead int     `json:"context_chunk_overhead"`  // Tokens per chunk for labels and separators
+	MinPackedChunkTokens int     `json:"min_packed_chunk_tokens"` // Smallest truncated chunk worth including
+
 	// Response formatting
 	Format          string `json:"format"`           // Response format (markdown, json, etc.)
 	EnableCitations bool   `json:"enable_citations"` // Include source citations
 	CitationFormat  string `json:"citation_format"`  // Citation format style
+	RenderTables    bool   `json:"render_tables"`    // Render matched tables into the prompt
+
+	// Footnotes defining glossary terms used in answers
+	GlossaryFootnotes bool `json:"glossary_footnotes"`
+
+	// Answer language
+	AnswerLanguage    string `json:"answer_language,omitempty"` // Language code, "auto" to answer in the query's language
+	TranslateExcerpts bool   `json:"translate_excerpts"`        // Translate cited excerpts written in another language
+	TranslationModel  string `json:"translation_model,omitempty"`
+
+	// Citation links back to source systems
+	DeepLinks DeepLinkConfig `json:"deep_links"`
+
+	// Structured output validation
+	StructuredOutput StructuredOutputConfig `json:"structured_output"`
+
+	// Tool calling during generation
+	Tools ToolsConfig `json:"tools"`
+
+	// Output moderation
+	Moderation ModerationConfig `json:"moderation"`
 
 	// Quality settings
 	MinConfidence    float64 `json:"min_confidence"`    // Minimum confidence threshold
@@ -284,6 +438,56 @@ type GenerationConfig struct {
 	BaseURL string `json:"base_url,omitempty"`
 }
 
+// StructuredOutputConfig represents validation of schema-constrained answers
+type StructuredOutputConfig struct {
+	MaxRepairAttempts int    `json:"max_repair_attempts"`    // Model round trips to fix invalid output
+	RepairModel       string `json:"repair_model,omitempty"` // Model used for repair, defaults to the generation model
+	RejectInvalid     bool   `json:"reject_invalid"`         // Fail the query when output is still invalid
+}
+
+// DeepLinkConfig represents how citations link to their source systems
+type DeepLinkConfig struct {
+	Disabled bool         `json:"disabled"`
+	S3       S3LinkConfig `json:"s3"` // Credentials for presigning S3 object links
+}
+
+// S3LinkConfig represents presigning of S3 object links
+type S3LinkConfig struct {
+	Region          string        `json:"region"`
+	Endpoint        string        `json:"endpoint,omitempty"` // S3-compatible endpoint, addressed path-style
+	AccessKeyID     string        `json:"access_key_id,omitempty"`
+	SecretAccessKey string        `json:"secret_access_key,omitempty"`
+	SessionToken    string        `json:"session_token,omitempty"`
+	Expiry          time.Duration `json:"expiry"` // Lifetime of presigned links
+}
+
+// ToolsConfig represents tool calling and its sandbox limits
+type ToolsConfig struct {
+	Enabled          bool          `json:"enabled"`
+	MaxIterations    int           `json:"max_iterations"`      // Model rounds that may call tools
+	MaxCallsPerQuery int           `json:"max_calls_per_query"` // Total tool calls per query
+	Timeout          time.Duration `json:"timeout"`             // Per-call execution timeout
+	MaxOutputBytes   int           `json:"max_output_bytes"`    // Tool output returned to the model
+}
+
+// ModerationConfig represents moderation of generated responses
+type ModerationConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Providers []string `json:"providers"` // openai, keyword
+
+	// Actions
+	Action          string            `json:"action"`           // Default action: block, redact or warn
+	CategoryActions map[string]string `json:"category_actions"` // Per-category action overrides
+	FailClosed      bool              `json:"fail_closed"`      // Block when a moderator errors
+	BlockMessage    string            `json:"block_message"`    // Replacement for blocked responses
+	RedactionText   string            `json:"redaction_text"`   // Replacement for redacted spans
+
+	// Provider settings
+	Model     string              `json:"model,omitempty"` // Moderation model for the openai provider
+	Threshold float64             `json:"threshold"`       // Category score that flags text (0 uses the provider flag)
+	Keywords  map[string][]string `json:"keywords"`        // Terms by category for the keyword provider
+}
+
 // StorageConfig represents storage configuration
 type StorageConfig struct {
 	// Backend selection
@@ -322,8 +526,19 @@ type StorageConfig struct {
 	EncryptionKey    string `json:"encryption_key,omitempty"`
 
 	// Maintenance
-	EnableVacuum   bool          `json:"enable_vacuum"`   // Enable vacuum/cleanup
-	VacuumInterval time.Duration `json:"vacuum_interval"` // Vacuum interval
+	EnableVacuum     bool          `json:"enable_vacuum"`     // Enable vacuum/cleanup
+	VacuumInterval   time.Duration `json:"vacuum_interval"`   // Vacuum interval
+	DeletedRetention time.Duration `json:"deleted_retention"` // How long soft-deleted documents are kept before purge
+
+	// Cold tier
+	ColdAfter     time.Duration `json:"cold_after"`     // Embeddings of documents not retrieved for this long leave the index, 0 disables
+	ColdDirectory string        `json:"cold_directory"` // Directory of the compressed cold embedding store
+
+	// Data residency: storage locations per region, for tenants whose data
+	// must stay in a region. Tenants without a region use DefaultRegion, or
+	// the settings above when it is empty.
+	DefaultRegion string                         `json:"default_region,omitempty"`
+	Regions       map[string]RegionStorageConfig `json:"regions,omitempty"`
 }
 
 // CacheConfig represents cache configuration
@@ -436,30 +651,39 @@ func DefaultConfig() *Config {
 
 	return &Config{
 		System: SystemConfig{
-			Name:            "metabase-rag",
-			Version:         "1.0.0",
-			Environment:     "development",
-			Debug:           true,
-			MaxWorkers:      4,
-			MaxConcurrency:  10,
-			RequestTimeout:  30 * time.Second,
-			ShutdownTimeout: 10 * time.Second,
-			MaxMemoryMB:     1024,
-			MaxFileSizeMB:   100,
-			LogLevel:        "info",
-			LogFormat:       "json",
+			Name:              "metabase-rag",
+			Version:           "1.0.0",
+			Environment:       "development",
+			Debug:             true,
+			MaxWorkers:        4,
+			MaxConcurrency:    10,
+			RequestTimeout:    30 * time.Second,
+			ShutdownTimeout:   10 * time.Second,
+			MaxMemoryMB:       1024,
+			MaxFileSizeMB:     100,
+			MaxBatchQueries:   1000,
+			BatchJobRetention: 24 * time.Hour,
+			Locking: LockingConfig{
+				Backend: "local",
+				TTL:     30 * time.Second,
+			},
+			LogLevel:  "info",
+			LogFormat: "json",
 		},
 		DataSources: make(map[string]interface{}),
 		Processing: ProcessingConfig{
 			Chunking: ChunkingConfig{
-				Strategy:            "semantic",
-				MaxChunkSize:        1000,
-				MinChunkSize:        100,
-				OverlapSize:         200,
-				MaxTokens:           300,
-				OverlapTokens:       50,
-				SimilarityThreshold: 0.7,
-				MinSimilaritySize:   200,
+				Strategy:             "semantic",
+				MaxChunkSize:         1000,
+				MinChunkSize:         100,
+				OverlapSize:          200,
+				MaxTokens:            300,
+				OverlapTokens:        50,
+				SimilarityThreshold:  0.7,
+				MinSimilaritySize:    200,
+				Deduplicate:          true,
+				DedupMinSize:         50,
+				DeduplicateDocuments: true,
 			},
 			Embedding: EmbeddingConfig{
 				Model:          "text-embedding-3-small",
@@ -511,6 +735,23 @@ func DefaultConfig() *Config {
 				BackupInterval:   12 * time.Hour,
 				BackupRetention:  7,
 			},
+			Summarization: SummarizationConfig{
+				Enabled:              false,
+				MaxTokensPerDocument: 4000,
+				MaxInputTokens:       3000,
+				MaxOutputTokens:      256,
+				MaxSections:          8,
+				SectionSize:          4000,
+				MaxKeywords:          10,
+				CacheSize:            10000,
+			},
+			Images: ImageConfig{
+				Enabled:        false,
+				Model:          "clip-vit-base-patch32",
+				MaxImageSizeMB: 20,
+				SupportedTypes: []string{"image/png", "image/jpeg", "image/gif", "image/webp"},
+				ScoreWeight:    0.8,
+			},
 			BatchSize:    10,
 			BatchTimeout: 5 * time.Minute,
 			MaxRetries:   3,
@@ -527,11 +768,13 @@ func DefaultConfig() *Config {
 			KeywordWeight:       0.3,
 			FusionMethod:        "weighted",
 			EnableRerank:        true,
+			ExpandGlossaryTerms: true,
 			RerankModel:         "BAAI/bge-reranker-v2-m3",
 			RerankTopK:          20,
 			RerankThreshold:     0.6,
 			EnableFilters:       true,
 			MaxQueryTime:        30 * time.Second,
+			StageTimeouts:       StageTimeouts{Retrieval: 5 * time.Second, Rerank: 3 * time.Second},
 			EnableCache:         true,
 			CacheSize:           1000,
 			CacheTTL:            time.Hour,
@@ -540,26 +783,65 @@ func DefaultConfig() *Config {
 			MaxDiversityResults: 20,
 		},
 		Generation: GenerationConfig{
-			Model:              "gpt-3.5-turbo",
-			Provider:           "openai",
-			Temperature:        0.7,
-			MaxTokens:          1000,
-			TopP:               0.9,
-			FrequencyPenalty:   0.0,
-			PresencePenalty:    0.0,
-			SystemPrompt:       "You are a helpful AI assistant that answers questions based on the provided context.",
-			UserPromptTemplate: "Context: {{.Context}}\n\nQuestion: {{.Query}}\n\nAnswer:",
-			MaxContextLength:   8000,
-			Format:             "markdown",
-			EnableCitations:    true,
-			CitationFormat:     "numeric",
-			MinConfidence:      0.5,
-			EnableFactCheck:    false,
-			QualityThreshold:   0.6,
-			Streaming:          false,
-			Timeout:            60 * time.Second,
-			MaxRetries:         3,
-			RetryDelay:         time.Second,
+			Model:                "gpt-3.5-turbo",
+			Provider:             "openai",
+			Temperature:          0.7,
+			MaxTokens:            1000,
+			TopP:                 0.9,
+			FrequencyPenalty:     0.0,
+			PresencePenalty:      0.0,
+			SystemPrompt:         "You are a helpful AI assistant that answers questions based on the provided context.",
+			UserPromptTemplate:   "Context: {{.Context}}\n\nQuestion: {{.Query}}\n\nAnswer:",
+			MaxContextLength:     8000,
+			ContextSafetyMargin:  0.1,
+			ContextChunkOverhead: 8,
+			MinPackedChunkTokens: 50,
+			Format:               "markdown",
+			EnableCitations:      true,
+			CitationFormat:       "numeric",
+			RenderTables:         true,
+			TranslateExcerpts:    true,
+			GlossaryFootnotes:    true,
+			DeepLinks: DeepLinkConfig{
+				S3: S3LinkConfig{
+					Region: "us-east-1",
+					Expiry: 15 * time.Minute,
+				},
+			},
+			StructuredOutput: StructuredOutputConfig{
+				MaxRepairAttempts: 2,
+				RejectInvalid:     false,
+			},
+			Tools: ToolsConfig{
+				Enabled:          false,
+				MaxIterations:    5,
+				MaxCallsPerQuery: 10,
+				Timeout:          10 * time.Second,
+				MaxOutputBytes:   16 * 1024,
+			},
+			Moderation: ModerationConfig{
+				Enabled:       false,
+				Providers:     []string{"keyword"},
+				Action:        string(ModerationActionWarn),
+				BlockMessage:  "This response was withheld by content moderation.",
+				RedactionText: "[redacted]",
+				Threshold:     0,
+			},
+			MinConfidence:    0.5,
+			EnableFactCheck:  false,
+			QualityThreshold: 0.6,
+			Streaming:        false,
+			Timeout:          60 * time.Second,
+			MaxRetries:       3,
+			RetryDelay:       time.Second,
+		},
+		Routing: RoutingConfig{
+			Policy:           RoutingPriority,
+			MaxRetries:       2,
+			RetryDelay:       500 * time.Millisecond,
+			MaxRetryDelay:    10 * time.Second,
+			FailureThreshold: 5,
+			OpenDuration:     30 * time.Second,
 		},
 		Storage: StorageConfig{
 			Backend:          "sqlite",
@@ -578,6 +860,8 @@ func DefaultConfig() *Config {
 			EnableEncryption: false,
 			EnableVacuum:     true,
 			VacuumInterval:   24 * time.Hour,
+			DeletedRetention: 7 * 24 * time.Hour,
+			ColdDirectory:    filepath.Join(dataDir, "cold"),
 		},
 		Cache: CacheConfig{
 			Type:              "memory",
@@ -675,6 +959,9 @@ func (config *Config) Validate() error {
 	if config.System.MaxConcurrency <= 0 {
 		return fmt.Errorf("max_concurrency must be positive")
 	}
+	if config.System.Deterministic.ClockStep < 0 {
+		return fmt.Errorf("deterministic clock_step cannot be negative")
+	}
 
 	// Validate processing config
 	if config.Processing.Chunking.MaxChunkSize <= 0 {
@@ -686,6 +973,9 @@ func (config *Config) Validate() error {
 	if config.Processing.Chunking.MinChunkSize > config.Processing.Chunking.MaxChunkSize {
 		return fmt.Errorf("min_chunk_size cannot be greater than max_chunk_size")
 	}
+	if err := validateWASMFilters(config.Processing.Preprocessing); err != nil {
+		return err
+	}
 
 	// Validate retrieval config
 	if config.Retrieval.DefaultTopK <= 0 {
@@ -697,6 +987,15 @@ func (config *Config) Validate() error {
 	if config.Retrieval.DefaultTopK > config.Retrieval.MaxTopK {
 		return fmt.Errorf("default_top_k cannot be greater than max_top_k")
 	}
+	if err := validateBoosts(config.Retrieval.RecencyHalfLife, config.Retrieval.RecencyWeight, config.Retrieval.SourceTypeWeights); err != nil {
+		return err
+	}
+	if config.Retrieval.MaxQueryTime < 0 {
+		return fmt.Errorf("max_query_time cannot be negative")
+	}
+	if err := config.Retrieval.StageTimeouts.Validate(); err != nil {
+		return err
+	}
 
 	// Validate generation config
 	if config.Generation.Model == "" {
@@ -706,10 +1005,24 @@ func (config *Config) Validate() error {
 		return fmt.Errorf("max_tokens must be positive")
 	}
 
+	// Validate routing config
+	if config.Routing.HedgeAfter < 0 {
+		return fmt.Errorf("hedge_after cannot be negative")
+	}
+	if config.Routing.HedgeBudget < 0 || config.Routing.HedgeBudget > 1 {
+		return fmt.Errorf("hedge_budget must be between 0 and 1")
+	}
+	if err := config.Routing.Replay.Validate(); err != nil {
+		return err
+	}
+
 	// Validate storage config
 	if config.Storage.Backend == "" {
 		return fmt.Errorf("storage backend is required")
 	}
+	if err := config.Storage.validateRegions(); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -733,6 +1046,9 @@ func (config *Config) Merge(other *Config) {
 	if other.System.Debug {
 		config.System.Debug = true
 	}
+	if other.System.Deterministic.Enabled {
+		config.System.Deterministic = other.System.Deterministic
+	}
 	if other.System.MaxWorkers > 0 {
 		config.System.MaxWorkers = other.System.MaxWorkers
 	}
@@ -784,6 +1100,9 @@ func mergeProcessingConfig(dest *ProcessingConfig, src *ProcessingConfig) {
 	if src.Embedding.Provider != "" {
 		dest.Embedding.Provider = src.Embedding.Provider
 	}
+	if src.Embedding.MultilingualModel != "" {
+		dest.Embedding.MultilingualModel = src.Embedding.MultilingualModel
+	}
 }
 
 func mergeRetrievalConfig(dest *RetrievalConfig, src *RetrievalConfig) {
@@ -796,6 +1115,15 @@ func mergeRetrievalConfig(dest *RetrievalConfig, src *RetrievalConfig) {
 	if src.MinScore > 0 {
 		dest.MinScore = src.MinScore
 	}
+	if src.RecencyHalfLife > 0 {
+		dest.RecencyHalfLife = src.RecencyHalfLife
+	}
+	if src.RecencyWeight > 0 {
+		dest.RecencyWeight = src.RecencyWeight
+	}
+	if len(src.SourceTypeWeights) > 0 {
+		dest.SourceTypeWeights = src.SourceTypeWeights
+	}
 }
 
 func mergeGenerationConfig(dest *GenerationConfig, src *GenerationConfig) {
@@ -811,6 +1139,12 @@ func mergeGenerationConfig(dest *GenerationConfig, src *GenerationConfig) {
 	if src.MaxTokens > 0 {
 		dest.MaxTokens = src.MaxTokens
 	}
+	if src.AnswerLanguage != "" {
+		dest.AnswerLanguage = src.AnswerLanguage
+	}
+	if src.TranslationModel != "" {
+		dest.TranslationModel = src.TranslationModel
+	}
 }
 
 func mergeStorageConfig(dest *StorageConfig, src *StorageConfig) {
@@ -823,6 +1157,15 @@ func mergeStorageConfig(dest *StorageConfig, src *StorageConfig) {
 	if src.DataDirectory != "" {
 		dest.DataDirectory = src.DataDirectory
 	}
+	if src.DefaultRegion != "" {
+		dest.DefaultRegion = src.DefaultRegion
+	}
+	for region, storage := range src.Regions {
+		if dest.Regions == nil {
+			dest.Regions = make(map[string]RegionStorageConfig)
+		}
+		dest.Regions[region] = storage
+	}
 }
 
 func mergeCacheConfig(dest *CacheConfig, src *CacheConfig) {
@@ -0,0 +1,759 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// ChunkReference identifies one occurrence of a chunk's content in a document
+type ChunkReference struct {
+	DocumentID string `json:"document_id"`
+	ChunkID    string `json:"chunk_id"`
+}
+
+// DedupStats represents chunk deduplication statistics
+type DedupStats struct {
+	UniqueChunks    int `json:"unique_chunks"`
+	TotalReferences int `json:"total_references"`
+	SavedEmbeddings int `json:"saved_embeddings"`
+}
+
+// dedupEntry tracks every chunk sharing the same content hash. The first
+// reference is canonical: it owns the embedding and is the one indexed by
+// the retriever.
+type dedupEntry struct {
+	embedding    []float64
+	indexVersion string
+	references   []ChunkReference
+}
+
+// ChunkDeduplicator shares embeddings between chunks with identical content
+// in the same scope using content hashes with reference counting
+type ChunkDeduplicator struct {
+	mu      sync.RWMutex
+	entries map[string]*dedupEntry
+	byChunk map[string]string // chunk ID -> scoped content hash
+	minSize int
+	saved   int
+}
+
+// NewChunkDeduplicator creates a deduplicator that ignores chunks shorter than minSize
+func NewChunkDeduplicator(minSize int) *ChunkDeduplicator {
+	return &ChunkDeduplicator{
+		entries: make(map[string]*dedupEntry),
+		byChunk: make(map[string]string),
+		minSize: minSize,
+	}
+}
+
+// ContentHash returns a stable hash of chunk content with whitespace normalized
+func ContentHash(content string) string {
+	normalized := strings.Join(strings.Fields(content), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupKey scopes a content hash so identical content is only shared within
+// one scope
+func dedupKey(scope, hash string) string {
+	return scope + ":" + hash
+}
+
+// dedupScope returns the scope content of a project is deduplicated in: the
+// project within its tenant. Content is never linked across projects, where
+// the canonical copy would be invisible to project-scoped retrieval.
+func (p *Pipeline) dedupScope(ctx context.Context, projectID string) string {
+	tenantID := tenantFromContext(ctx)
+	if tenantID == "" {
+		if scoped, err := p.tenantContext(ctx, projectID); err == nil {
+			tenantID = tenantFromContext(scoped)
+		}
+	}
+	return tenantID + "/" + projectID
+}
+
+// projectScopes returns dedupScope memoized by project, for registering
+// every stored document without resolving the tenant of each
+func (p *Pipeline) projectScopes(ctx context.Context) func(projectID string) string {
+	scopes := make(map[string]string)
+	return func(projectID string) string {
+		scope, exists := scopes[projectID]
+		if !exists {
+			scope = p.dedupScope(ctx, projectID)
+			scopes[projectID] = scope
+		}
+		return scope
+	}
+}
+
+// Eligible reports whether the chunk is large enough to be deduplicated
+func (d *ChunkDeduplicator) Eligible(chunk DocumentChunk) bool {
+	return len(strings.TrimSpace(chunk.Content)) >= d.minSize
+}
+
+// Lookup returns the canonical chunk and shared embedding for a content hash
+// in a scope if one exists for the given index version
+func (d *ChunkDeduplicator) Lookup(scope, hash, indexVersion string) (canonical ChunkReference, embedding []float64, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, exists := d.entries[dedupKey(scope, hash)]
+	if !exists || len(entry.references) == 0 || entry.indexVersion != indexVersion || len(entry.embedding) == 0 {
+		return ChunkReference{}, nil, false
+	}
+	return entry.references[0], entry.embedding, true
+}
+
+// Register records a chunk occurrence in a scope. It returns true if the
+// chunk is the canonical owner of its content there and must be indexed.
+func (d *ChunkDeduplicator) Register(scope string, chunk DocumentChunk) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hash := dedupKey(scope, chunk.ContentHash)
+	if previous, exists := d.byChunk[chunk.ID]; exists {
+		if previous == hash {
+			return d.entries[hash].references[0].ChunkID == chunk.ID
+		}
+		d.removeReferenceLocked(chunk.ID)
+	}
+
+	ref := ChunkReference{DocumentID: chunk.DocumentID, ChunkID: chunk.ID}
+	d.byChunk[chunk.ID] = hash
+
+	entry, exists := d.entries[hash]
+	if !exists || len(entry.references) == 0 {
+		d.entries[hash] = &dedupEntry{
+			embedding:    chunk.Embedding,
+			indexVersion: chunk.IndexVersion,
+			references:   []ChunkReference{ref},
+		}
+		return true
+	}
+
+	entry.references = append(entry.references, ref)
+	d.saved++
+	return false
+}
+
+// References returns all occurrences of the content owned by a canonical chunk
+func (d *ChunkDeduplicator) References(chunkID string) []ChunkReference {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	hash, exists := d.byChunk[chunkID]
+	if !exists {
+		return nil
+	}
+	refs := d.entries[hash].references
+	return append([]ChunkReference(nil), refs...)
+}
+
+// ReleaseDocument drops all references held by a document. For each content
+// whose canonical chunk belonged to the document and which is still referenced
+// elsewhere, the returned map holds old canonical chunk ID -> new canonical.
+func (d *ChunkDeduplicator) ReleaseDocument(documentID string) (promoted map[string]ChunkReference) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	promoted = make(map[string]ChunkReference)
+	for chunkID, hash := range d.byChunk {
+		entry := d.entries[hash]
+		var owned bool
+		for _, ref := range entry.references {
+			if ref.ChunkID == chunkID && ref.DocumentID == documentID {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			continue
+		}
+
+		wasCanonical := entry.references[0].ChunkID == chunkID
+		d.removeReferenceLocked(chunkID)
+		if wasCanonical && len(entry.references) > 0 {
+			promoted[chunkID] = entry.references[0]
+		}
+	}
+
+	// A promoted chunk may itself have been released by a later iteration
+	for oldID, ref := range promoted {
+		if _, exists := d.byChunk[ref.ChunkID]; !exists {
+			delete(promoted, oldID)
+		}
+	}
+
+	return promoted
+}
+
+// removeReferenceLocked removes a chunk from its entry; caller holds the lock
+func (d *ChunkDeduplicator) removeReferenceLocked(chunkID string) {
+	hash, exists := d.byChunk[chunkID]
+	if !exists {
+		return
+	}
+	delete(d.byChunk, chunkID)
+
+	entry := d.entries[hash]
+	for i, ref := range entry.references {
+		if ref.ChunkID == chunkID {
+			entry.references = append(entry.references[:i], entry.references[i+1:]...)
+			break
+		}
+	}
+	if len(entry.references) == 0 {
+		delete(d.entries, hash)
+	} else if d.saved > 0 {
+		d.saved--
+	}
+}
+
+// Stats returns deduplication statistics
+func (d *ChunkDeduplicator) Stats() DedupStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return DedupStats{
+		UniqueChunks:    len(d.entries),
+		TotalReferences: len(d.byChunk),
+		SavedEmbeddings: d.saved,
+	}
+}
+
+// deduplicateChunks assigns content hashes and reuses embeddings for chunks
+// whose content has already been indexed in the scope. Chunks that still lack
+// an embedding are embedded in one batch. It returns the chunks that must be
+// added to the retriever and the number of embeddings generated, counting
+// those the processor already generated.
+func (p *Pipeline) deduplicateChunks(ctx context.Context, scope string, chunks []DocumentChunk, indexVersion string) ([]DocumentChunk, int, error) {
+	var missing []int
+	var embedded int
+	for i := range chunks {
+		if len(chunks[i].Embedding) > 0 {
+			embedded++
+		}
+		if chunks[i].ContentHash == "" {
+			chunks[i].ContentHash = ContentHash(chunks[i].Content)
+		}
+		if p.dedup == nil || !p.dedup.Eligible(chunks[i]) {
+			if len(chunks[i].Embedding) == 0 {
+				missing = append(missing, i)
+			}
+			continue
+		}
+
+		if canonical, vector, ok := p.dedup.Lookup(scope, chunks[i].ContentHash, indexVersion); ok && canonical.ChunkID != chunks[i].ID {
+			chunks[i].Embedding = vector
+			chunks[i].EmbeddingDim = len(vector)
+			chunks[i].IndexVersion = indexVersion
+			chunks[i].DuplicateOf = canonical.ChunkID
+			continue
+		}
+		if len(chunks[i].Embedding) == 0 {
+			missing = append(missing, i)
+		}
+	}
+
+	// Embed what remains, once per distinct content
+	generated, err := p.embedMissing(ctx, chunks, missing, indexVersion)
+	if err != nil {
+		return nil, 0, err
+	}
+	generated += embedded
+
+	// Register occurrences and collect the chunks the retriever must index
+	var indexable []DocumentChunk
+	for i := range chunks {
+		if p.dedup == nil || !p.dedup.Eligible(chunks[i]) {
+			indexable = append(indexable, chunks[i])
+			continue
+		}
+		if chunks[i].IndexVersion == "" {
+			chunks[i].IndexVersion = indexVersion
+		}
+		if p.dedup.Register(scope, chunks[i]) {
+			chunks[i].DuplicateOf = ""
+			indexable = append(indexable, chunks[i])
+		} else if refs := p.dedup.References(chunks[i].ID); len(refs) > 0 {
+			chunks[i].DuplicateOf = refs[0].ChunkID
+		}
+	}
+
+	return indexable, generated, nil
+}
+
+// embedMissing embeds the chunks at the given indexes, once per distinct
+// content, reusing vectors from the embedding cache. It returns the number of
+// vectors generated.
+func (p *Pipeline) embedMissing(ctx context.Context, chunks []DocumentChunk, missing []int, indexVersion string) (int, error) {
+	if p.processor == nil {
+		return 0, nil
+	}
+	return p.embedWith(ctx, p.processor.GetEmbeddingGenerator(), chunks, missing, indexVersion)
+}
+
+// embedWith is embedMissing with an explicit generator
+func (p *Pipeline) embedWith(ctx context.Context, generator embedding.VectorGenerator, chunks []DocumentChunk, missing []int, indexVersion string) (int, error) {
+	if len(missing) == 0 || generator == nil {
+		return 0, nil
+	}
+
+	byHash := make(map[string][]int)
+	var texts []string
+	for _, i := range missing {
+		hash := chunks[i].ContentHash
+		if _, exists := byHash[hash]; !exists {
+			texts = append(texts, chunks[i].Content)
+		}
+		byHash[hash] = append(byHash[hash], i)
+	}
+
+	// Reuse vectors from the embedding cache; lookup failures fall back to embedding
+	embeddings, _ := p.cache.(EmbeddingCache)
+	if embeddings != nil {
+		texts = texts[:0]
+		looked := make(map[string]bool)
+		for _, i := range missing {
+			hash := chunks[i].ContentHash
+			indexes, pending := byHash[hash]
+			if !pending || looked[hash] {
+				continue
+			}
+			looked[hash] = true
+			vector, err := embeddings.GetEmbedding(ctx, indexVersion+":"+hash)
+			if err != nil || len(vector) == 0 {
+				texts = append(texts, chunks[i].Content)
+				continue
+			}
+			for _, j := range indexes {
+				chunks[j].Embedding = vector
+				chunks[j].EmbeddingModel = generator.GetModelName()
+				chunks[j].EmbeddingDim = len(vector)
+				chunks[j].IndexVersion = indexVersion
+			}
+			delete(byHash, hash)
+		}
+	}
+
+	var vectors [][]float64
+	if len(texts) > 0 {
+		var err error
+		vectors, err = generator.Embed(ctx, texts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return 0, fmt.Errorf("embedding count mismatch: got %d, want %d", len(vectors), len(texts))
+		}
+	}
+
+	next := 0
+	for _, i := range missing {
+		hash := chunks[i].ContentHash
+		indexes, pending := byHash[hash]
+		if !pending {
+			continue
+		}
+		for _, j := range indexes {
+			chunks[j].Embedding = vectors[next]
+			chunks[j].EmbeddingModel = generator.GetModelName()
+			chunks[j].EmbeddingDim = len(vectors[next])
+			chunks[j].IndexVersion = indexVersion
+		}
+		if embeddings != nil {
+			embeddings.SetEmbedding(ctx, indexVersion+":"+hash, vectors[next], 0)
+		}
+		delete(byHash, hash)
+		next++
+	}
+	return len(vectors), nil
+}
+
+// rebuildChunkDeduplication registers the stored chunks with the chunk
+// deduplicator, canonical chunks first, so content indexed before a restart
+// keeps sharing embeddings. Duplicates whose canonical chunk is gone are
+// promoted in its place.
+func (p *Pipeline) rebuildChunkDeduplication(ctx context.Context) error {
+	if p.dedup == nil || p.storage == nil {
+		return nil
+	}
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	scopes := p.projectScopes(ctx)
+	var duplicates []DocumentChunk
+	duplicateScopes := make(map[string]string) // chunk ID -> scope
+	for _, doc := range documents {
+		chunks, err := p.storage.ListChunks(ctx, doc.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list chunks of %s: %w", doc.ID, err)
+		}
+		scope := scopes(documentProjectID(doc))
+		for _, chunk := range chunks {
+			// Chunks without a content hash were indexed without deduplication
+			if chunk.ContentHash == "" || !p.dedup.Eligible(chunk) {
+				continue
+			}
+			if chunk.DuplicateOf != "" {
+				duplicates = append(duplicates, chunk)
+				duplicateScopes[chunk.ID] = scope
+				continue
+			}
+			if len(chunk.Embedding) == 0 {
+				if vector, err := p.storage.GetEmbedding(ctx, chunk.ID); err == nil {
+					chunk.Embedding = vector
+				}
+			}
+			p.dedup.Register(scope, chunk)
+		}
+	}
+
+	promoted := make(map[string]ChunkReference)
+	for _, chunk := range duplicates {
+		if p.dedup.Register(duplicateScopes[chunk.ID], chunk) {
+			promoted[chunk.DuplicateOf] = ChunkReference{DocumentID: chunk.DocumentID, ChunkID: chunk.ID}
+		}
+	}
+	p.promoteChunks(ctx, promoted)
+	return nil
+}
+
+// expandDuplicateReferences attaches the other documents sharing a retrieved
+// chunk's content so citations resolve to every source document
+func (p *Pipeline) expandDuplicateReferences(results []RetrievalResult) {
+	if p.dedup == nil {
+		return
+	}
+	for i := range results {
+		if results[i].Chunk == nil {
+			continue
+		}
+		refs := p.dedup.References(results[i].Chunk.ID)
+		if len(refs) <= 1 {
+			continue
+		}
+		results[i].DuplicateSources = refs[1:]
+	}
+}
+
+// DeleteDocument removes a document and releases its deduplicated chunks.
+// Content still referenced by other documents is re-indexed under a
+// surviving chunk, or a surviving duplicate document.
+func (p *Pipeline) DeleteDocument(ctx context.Context, documentID string) error {
+	chunks, err := p.storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	var promoted map[string]ChunkReference
+	if p.dedup != nil {
+		promoted = p.dedup.ReleaseDocument(documentID)
+	}
+	if p.docDedup != nil {
+		if duplicate := p.docDedup.Release(documentID); duplicate != nil {
+			defer p.promoteDuplicate(ctx, *duplicate)
+		}
+	}
+
+	for _, chunk := range chunks {
+		if chunk.DuplicateOf != "" {
+			continue
+		}
+		if err := p.retriever.RemoveDocument(ctx, chunk.ID); err != nil {
+			p.emitError(ctx, "remove_chunk", err)
+		}
+	}
+
+	if err := p.storage.DeleteDocument(ctx, documentID); err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	p.dropCold(ctx, documentID)
+	if documents, ok := p.cache.(DocumentCache); ok {
+		documents.DeleteDocument(ctx, documentID)
+	}
+	p.invalidateCachedAnswers(ctx, documentID)
+
+	p.promoteChunks(ctx, promoted)
+
+	return nil
+}
+
+// promoteChunks makes the surviving duplicates of released content canonical
+// and indexes them
+func (p *Pipeline) promoteChunks(ctx context.Context, promoted map[string]ChunkReference) {
+	for _, ref := range promoted {
+		chunk, err := p.storage.GetChunk(ctx, ref.ChunkID)
+		if err != nil {
+			p.emitError(ctx, "promote_chunk", err)
+			continue
+		}
+		chunk.DuplicateOf = ""
+		if err := p.storage.StoreChunk(ctx, *chunk); err != nil {
+			p.emitError(ctx, "promote_chunk", err)
+			continue
+		}
+		if err := p.retriever.AddDocument(ctx, *chunk); err != nil {
+			p.emitError(ctx, "promote_chunk", err)
+		}
+
+		// Point the remaining duplicates at the new canonical chunk
+		for _, sibling := range p.dedup.References(ref.ChunkID)[1:] {
+			duplicate, err := p.storage.GetChunk(ctx, sibling.ChunkID)
+			if err != nil {
+				continue
+			}
+			duplicate.DuplicateOf = ref.ChunkID
+			if err := p.storage.StoreChunk(ctx, *duplicate); err != nil {
+				p.emitError(ctx, "promote_chunk", err)
+			}
+		}
+	}
+}
+
+// DocumentReference identifies one document holding a content
+type DocumentReference struct {
+	DocumentID   string `json:"document_id"`
+	DataSourceID string `json:"data_source_id,omitempty"`
+	URI          string `json:"uri,omitempty"`
+}
+
+// DuplicateDocument records a document linked to an indexed document of the
+// same project with the same content instead of being processed again
+type DuplicateDocument struct {
+	DocumentID  string `json:"document_id"`
+	URI         string `json:"uri,omitempty"`
+	DuplicateOf string `json:"duplicate_of"`
+}
+
+// duplicateOfKey is the custom metadata key linking a duplicate document to
+// the document whose chunks it shares
+const duplicateOfKey = "duplicate_of"
+
+// DocumentDeduplicator links documents with identical content, across uploads
+// and data sources, to the first one indexed in the same scope. The first
+// reference of each scoped content hash is canonical: only its content is
+// chunked and embedded.
+type DocumentDeduplicator struct {
+	mu         sync.RWMutex
+	entries    map[string][]DocumentReference
+	byDocument map[string]string // document ID -> scoped content hash
+}
+
+// NewDocumentDeduplicator creates an empty document deduplicator
+func NewDocumentDeduplicator() *DocumentDeduplicator {
+	return &DocumentDeduplicator{
+		entries:    make(map[string][]DocumentReference),
+		byDocument: make(map[string]string),
+	}
+}
+
+// Register records a document occurrence in a scope and returns the
+// canonical document for its content there; the document is a duplicate if
+// that is another document. If the document was canonical for different
+// content that is still referenced, the new canonical document of that
+// content is also returned.
+func (d *DocumentDeduplicator) Register(scope string, doc Document) (canonical DocumentReference, promoted *DocumentReference) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	hash := dedupKey(scope, ContentHash(doc.Content))
+	if previous, exists := d.byDocument[doc.ID]; exists {
+		if previous == hash {
+			return d.entries[hash][0], nil
+		}
+		promoted = d.removeLocked(doc.ID)
+	}
+
+	d.byDocument[doc.ID] = hash
+	d.entries[hash] = append(d.entries[hash], DocumentReference{
+		DocumentID:   doc.ID,
+		DataSourceID: doc.DataSourceID,
+		URI:          doc.URI,
+	})
+	return d.entries[hash][0], promoted
+}
+
+// References returns every document holding the same content as a document,
+// canonical first
+func (d *DocumentDeduplicator) References(documentID string) []DocumentReference {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	hash, exists := d.byDocument[documentID]
+	if !exists {
+		return nil
+	}
+	return append([]DocumentReference(nil), d.entries[hash]...)
+}
+
+// Release drops a document's reference. If the document was canonical and its
+// content is still referenced, the new canonical document is returned.
+func (d *DocumentDeduplicator) Release(documentID string) (promoted *DocumentReference) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.removeLocked(documentID)
+}
+
+// removeLocked removes a document from its entry; caller holds the lock
+func (d *DocumentDeduplicator) removeLocked(documentID string) (promoted *DocumentReference) {
+	hash, exists := d.byDocument[documentID]
+	if !exists {
+		return nil
+	}
+	delete(d.byDocument, documentID)
+
+	refs := d.entries[hash]
+	for i, ref := range refs {
+		if ref.DocumentID != documentID {
+			continue
+		}
+		refs = append(refs[:i], refs[i+1:]...)
+		if i == 0 && len(refs) > 0 {
+			next := refs[0]
+			promoted = &next
+		}
+		break
+	}
+	if len(refs) == 0 {
+		delete(d.entries, hash)
+	} else {
+		d.entries[hash] = refs
+	}
+	return promoted
+}
+
+// DocumentReferences returns the documents sharing a document's content,
+// canonical first, or nil if document deduplication is disabled
+func (p *Pipeline) DocumentReferences(documentID string) []DocumentReference {
+	if p.docDedup == nil {
+		return nil
+	}
+	return p.docDedup.References(documentID)
+}
+
+// registerDocument registers a document with the document deduplicator in
+// the scope of its project
+func (p *Pipeline) registerDocument(ctx context.Context, doc Document) (canonical DocumentReference, promoted *DocumentReference) {
+	return p.docDedup.Register(p.dedupScope(ctx, documentProjectID(doc)), doc)
+}
+
+// linkDuplicate stores a document whose content is already indexed under
+// another document without chunking or embedding it again
+func (p *Pipeline) linkDuplicate(ctx context.Context, doc Document, canonical DocumentReference, result *IndexResult, report func(IngestStage)) error {
+	// Drop chunks left from when the document had content of its own
+	if chunks, err := p.storage.ListChunks(ctx, doc.ID); err == nil && len(chunks) > 0 {
+		if err := p.DeleteDocument(ctx, doc.ID); err != nil {
+			p.emitError(ctx, "link_duplicate", err)
+		}
+		p.registerDocument(ctx, doc)
+	}
+
+	if doc.Metadata.Custom == nil {
+		doc.Metadata.Custom = make(map[string]interface{})
+	}
+	doc.Metadata.Custom[duplicateOfKey] = canonical.DocumentID
+	if err := p.storage.StoreDocument(ctx, doc); err != nil {
+		p.docDedup.Release(doc.ID)
+		result.DocumentsErrored++
+		result.Errors = append(result.Errors, fmt.Sprintf("Store document %s: %v", doc.ID, err))
+		return &IngestError{Stage: IngestChunked, Err: err}
+	}
+	if documents, ok := p.cache.(DocumentCache); ok {
+		documents.SetDocument(ctx, &doc, 0)
+	}
+	p.invalidateCachedAnswers(ctx, doc.ID)
+
+	result.DocumentsProcessed++
+	result.DocumentsDuplicate++
+	result.Duplicates = append(result.Duplicates, DuplicateDocument{
+		DocumentID:  doc.ID,
+		URI:         doc.URI,
+		DuplicateOf: canonical.DocumentID,
+	})
+	for _, stage := range IngestStages[2:] {
+		report(stage)
+	}
+	return nil
+}
+
+// rebuildDocumentDeduplication registers the stored documents with the
+// document deduplicator, canonical documents first, so content indexed before
+// a restart is still linked instead of indexed again. Duplicates whose
+// canonical document is gone are promoted in its place.
+func (p *Pipeline) rebuildDocumentDeduplication(ctx context.Context) error {
+	if p.docDedup == nil || p.storage == nil {
+		return nil
+	}
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	scopes := p.projectScopes(ctx)
+	var duplicates []Document
+	for _, doc := range documents {
+		if strings.TrimSpace(doc.Content) == "" {
+			continue
+		}
+		if canonical, _ := doc.Metadata.Custom[duplicateOfKey].(string); canonical != "" {
+			duplicates = append(duplicates, doc)
+			continue
+		}
+		p.docDedup.Register(scopes(documentProjectID(doc)), doc)
+	}
+
+	var promoted []DocumentReference
+	for _, doc := range duplicates {
+		if canonical, _ := p.docDedup.Register(scopes(documentProjectID(doc)), doc); canonical.DocumentID == doc.ID {
+			promoted = append(promoted, canonical)
+		}
+	}
+	for _, ref := range promoted {
+		p.promoteDuplicate(ctx, ref)
+	}
+	return nil
+}
+
+// promoteDuplicate indexes a linked duplicate whose canonical document was
+// deleted or changed, and points the remaining duplicates at it
+func (p *Pipeline) promoteDuplicate(ctx context.Context, ref DocumentReference) {
+	doc, err := p.storage.GetDocument(ctx, ref.DocumentID)
+	if err != nil {
+		p.emitError(ctx, "promote_document", err)
+		return
+	}
+	delete(doc.Metadata.Custom, duplicateOfKey)
+
+	indexVersion := IndexVersionFromConfig(p.config.Processing.Embedding).String()
+	if err := p.indexDocument(ctx, *doc, indexVersion, &IndexResult{}, nil); err != nil {
+		p.emitError(ctx, "promote_document", err)
+		return
+	}
+
+	refs := p.docDedup.References(ref.DocumentID)
+	if len(refs) == 0 || refs[0].DocumentID != ref.DocumentID {
+		return
+	}
+	for _, sibling := range refs[1:] {
+		duplicate, err := p.storage.GetDocument(ctx, sibling.DocumentID)
+		if err != nil {
+			continue
+		}
+		if duplicate.Metadata.Custom == nil {
+			duplicate.Metadata.Custom = make(map[string]interface{})
+		}
+		duplicate.Metadata.Custom[duplicateOfKey] = ref.DocumentID
+		if err := p.storage.StoreDocument(ctx, *duplicate); err != nil {
+			p.emitError(ctx, "promote_document", err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+func TestContentHashNormalizesWhitespace(t *testing.T) {
+	if ContentHash("Licensed under  MIT\n") != ContentHash("Licensed under MIT") {
+		t.Fatalf("whitespace differences should not change the hash")
+	}
+	if ContentHash("a") == ContentHash("b") {
+		t.Fatalf("different content should hash differently")
+	}
+}
+
+func TestChunkDeduplicatorReferenceCounting(t *testing.T) {
+	d := NewChunkDeduplicator(0)
+	hash := ContentHash("shared header")
+
+	first := DocumentChunk{ID: "a_0", DocumentID: "a", ContentHash: hash, IndexVersion: "v1", Embedding: []float64{1}}
+	second := DocumentChunk{ID: "b_0", DocumentID: "b", ContentHash: hash, IndexVersion: "v1"}
+
+	if !d.Register("t1/p1", first) {
+		t.Fatalf("first occurrence should be canonical")
+	}
+	if d.Register("t1/p1", second) {
+		t.Fatalf("second occurrence should be a duplicate")
+	}
+	if canonical, vector, ok := d.Lookup("t1/p1", hash, "v1"); !ok || canonical.ChunkID != "a_0" || len(vector) != 1 {
+		t.Fatalf("lookup should return canonical chunk and embedding")
+	}
+	if _, _, ok := d.Lookup("t1/p1", hash, "v2"); ok {
+		t.Fatalf("lookup should not match another index version")
+	}
+	if refs := d.References("a_0"); len(refs) != 2 {
+		t.Fatalf("expected 2 references, got %d", len(refs))
+	}
+
+	// Another project owns its own copy and never references this one
+	other := DocumentChunk{ID: "c_0", DocumentID: "c", ContentHash: hash, IndexVersion: "v1", Embedding: []float64{1}}
+	if _, _, ok := d.Lookup("t1/p2", hash, "v1"); ok {
+		t.Fatalf("lookup should not match another scope")
+	}
+	if !d.Register("t1/p2", other) {
+		t.Fatalf("the first occurrence in another scope should be canonical")
+	}
+	if refs := d.References("c_0"); len(refs) != 1 {
+		t.Fatalf("expected references to stay within the scope, got %+v", refs)
+	}
+	d.ReleaseDocument("c")
+
+	promoted := d.ReleaseDocument("a")
+	if promoted["a_0"].ChunkID != "b_0" {
+		t.Fatalf("expected b_0 to be promoted, got %+v", promoted)
+	}
+
+	d.ReleaseDocument("b")
+	if stats := d.Stats(); stats.UniqueChunks != 0 || stats.TotalReferences != 0 {
+		t.Fatalf("expected empty deduplicator, got %+v", stats)
+	}
+}
+
+func TestDocumentDeduplicatorLinksIdenticalContent(t *testing.T) {
+	d := NewDocumentDeduplicator()
+	first := Document{ID: "a", DataSourceID: "docs", Content: "Install with make"}
+	upload := Document{ID: "b", DataSourceID: "uploads:p1", Content: "Install  with make\n"}
+
+	if canonical, _ := d.Register("t1/p1", first); canonical.DocumentID != "a" {
+		t.Fatalf("first occurrence should be canonical")
+	}
+	if canonical, _ := d.Register("t1/p1", upload); canonical.DocumentID != "a" {
+		t.Fatalf("identical content should link to the first document, got %q", canonical.DocumentID)
+	}
+	// Other projects index their own copy
+	for _, scope := range []string{"t1/p2", "t2/p1"} {
+		other := Document{ID: "x:" + scope, Content: "Install with make"}
+		if canonical, _ := d.Register(scope, other); canonical.DocumentID != other.ID {
+			t.Fatalf("content in %s should not link to another scope, got %q", scope, canonical.DocumentID)
+		}
+	}
+	// Re-indexing unchanged content keeps the document canonical
+	if canonical, promoted := d.Register("t1/p1", first); canonical.DocumentID != "a" || promoted != nil {
+		t.Fatalf("re-registering should not change the canonical document")
+	}
+	if refs := d.References("b"); len(refs) != 2 || refs[1].DataSourceID != "uploads:p1" {
+		t.Fatalf("unexpected references %+v", refs)
+	}
+
+	// Changing the canonical document's content promotes the duplicate
+	first.Content = "Install with go build"
+	canonical, promoted := d.Register("t1/p1", first)
+	if canonical.DocumentID != "a" || promoted == nil || promoted.DocumentID != "b" {
+		t.Fatalf("expected b to be promoted, got %+v %+v", canonical, promoted)
+	}
+	if promoted := d.Release("b"); promoted != nil {
+		t.Fatalf("releasing the last reference should promote nothing")
+	}
+	if refs := d.References("b"); refs != nil {
+		t.Fatalf("released document should have no references")
+	}
+}
+
+func TestRebuildChunkDeduplication(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryStorage()
+	retriever := &keywordRetriever{}
+	p := &Pipeline{config: DefaultConfig(), storage: backend, retriever: retriever, dedup: NewChunkDeduplicator(0)}
+
+	shared, orphaned := ContentHash("shared header"), ContentHash("orphaned footer")
+	for _, doc := range []Document{{ID: "a"}, {ID: "b"}, {ID: "c"}} {
+		backend.StoreDocument(ctx, doc)
+	}
+	backend.StoreChunk(ctx, DocumentChunk{ID: "b_0", DocumentID: "b", Content: "shared header", ContentHash: shared, IndexVersion: "v1", DuplicateOf: "a_0"})
+	backend.StoreChunk(ctx, DocumentChunk{ID: "a_0", DocumentID: "a", Content: "shared header", ContentHash: shared, IndexVersion: "v1"})
+	backend.StoreEmbedding(ctx, "a_0", []float64{1, 2})
+	// The canonical chunk of this content was lost
+	backend.StoreChunk(ctx, DocumentChunk{ID: "c_0", DocumentID: "c", Content: "orphaned footer", ContentHash: orphaned, IndexVersion: "v1", DuplicateOf: "gone_0"})
+
+	if err := p.rebuildChunkDeduplication(ctx); err != nil {
+		t.Fatal(err)
+	}
+	scope := p.dedupScope(ctx, "")
+	if canonical, vector, ok := p.dedup.Lookup(scope, shared, "v1"); !ok || canonical.ChunkID != "a_0" || len(vector) != 2 {
+		t.Fatalf("expected the stored canonical chunk to be restored, got %+v %v %v", canonical, vector, ok)
+	}
+	if refs := p.dedup.References("a_0"); len(refs) != 2 || refs[1].ChunkID != "b_0" {
+		t.Fatalf("expected the stored duplicate to be restored, got %+v", refs)
+	}
+	if stats := p.dedup.Stats(); stats.SavedEmbeddings != 1 || stats.TotalReferences != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// The orphaned duplicate now owns its content and is indexed
+	if backend.chunks["c_0"].DuplicateOf != "" || len(retriever.chunks) != 1 || retriever.chunks[0].ID != "c_0" {
+		t.Fatalf("expected the orphaned duplicate to be promoted, got %+v %+v", backend.chunks["c_0"], retriever.chunks)
+	}
+
+	// A new occurrence after the restart reuses the stored embedding
+	chunks := []DocumentChunk{
+		{ID: "d_0", DocumentID: "d", Content: "shared  header"},
+		{ID: "d_1", DocumentID: "d", Content: "embedded by the processor", Embedding: []float64{3}},
+	}
+	indexable, generated, err := p.deduplicateChunks(ctx, scope, chunks, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks[0].DuplicateOf != "a_0" || len(indexable) != 1 || indexable[0].ID != "d_1" {
+		t.Fatalf("expected d_0 to share a_0's embedding, got %+v", chunks)
+	}
+	if generated != 1 {
+		t.Fatalf("expected the processor's embedding to be counted, got %d", generated)
+	}
+
+	// The same content in another project is indexed on its own
+	p.processor = wholeDocumentProcessor{}
+	chunks = []DocumentChunk{{ID: "e_0", DocumentID: "e", Content: "shared header"}}
+	indexable, _, err = p.deduplicateChunks(ctx, p.dedupScope(ctx, "p2"), chunks, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunks[0].DuplicateOf != "" || len(indexable) != 1 {
+		t.Fatalf("expected e_0 not to reference another project's chunk, got %+v", chunks)
+	}
+	if refs := p.dedup.References("a_0"); len(refs) != 3 {
+		t.Fatalf("expected a_0's references to stay within its project, got %+v", refs)
+	}
+}
+
+// wholeDocumentProcessor indexes each document as a single chunk
+type wholeDocumentProcessor struct {
+	DocumentProcessor
+}
+
+func (wholeDocumentProcessor) ProcessDocument(ctx context.Context, doc Document) ([]DocumentChunk, error) {
+	return []DocumentChunk{{ID: doc.ID + "_0", DocumentID: doc.ID, Content: doc.Content}}, nil
+}
+
+func (wholeDocumentProcessor) GetEmbeddingGenerator() embedding.VectorGenerator {
+	return &wordGenerator{vocabulary: []string{"install", "run", "tests"}}
+}
+
+func TestRebuildDocumentDeduplication(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryStorage()
+	retriever := &keywordRetriever{}
+	p := &Pipeline{config: DefaultConfig(), storage: backend, retriever: retriever, processor: wholeDocumentProcessor{}, docDedup: NewDocumentDeduplicator()}
+	p.tenants = projectTenants{"p1": "t1", "p2": "t1"}
+
+	linked := func(id, content, canonical string) Document {
+		return Document{ID: id, Content: content, Metadata: DocumentMetadata{Custom: map[string]interface{}{duplicateOfKey: canonical}}}
+	}
+	inProject := func(id, content, projectID string) Document {
+		return Document{ID: id, Content: content, Metadata: DocumentMetadata{Custom: map[string]interface{}{"project_id": projectID}}}
+	}
+	backend.StoreDocument(ctx, linked("b", "Install with make", "a"))
+	backend.StoreDocument(ctx, Document{ID: "a", Content: "Install with make"})
+	backend.StoreDocument(ctx, inProject("p", "Install with make", "p1"))
+	// The canonical document of this content was lost
+	backend.StoreDocument(ctx, linked("c", "Run the tests", "gone"))
+	backend.StoreDocument(ctx, linked("d", "Run the tests", "gone"))
+
+	if err := p.rebuildDocumentDeduplication(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if refs := p.DocumentReferences("b"); len(refs) != 2 || refs[0].DocumentID != "a" {
+		t.Fatalf("expected the stored duplicate to be linked, got %+v", refs)
+	}
+	if canonical, _ := p.registerDocument(ctx, Document{ID: "e", Content: "Install  with make"}); canonical.DocumentID != "a" {
+		t.Fatalf("expected new uploads to link to the stored document, got %+v", canonical)
+	}
+	// Uploads only link to documents of their own project
+	if canonical, _ := p.registerDocument(ctx, inProject("q", "Install with make", "p1")); canonical.DocumentID != "p" {
+		t.Fatalf("expected the upload to link to its project's document, got %+v", canonical)
+	}
+	if canonical, _ := p.registerDocument(ctx, inProject("r", "Install with make", "p2")); canonical.DocumentID != "r" {
+		t.Fatalf("expected the upload to be indexed in its own project, got %+v", canonical)
+	}
+
+	// The first orphaned duplicate is indexed and the other points at it
+	if refs := p.DocumentReferences("d"); len(refs) != 2 || refs[0].DocumentID != "c" {
+		t.Fatalf("expected c to be promoted, got %+v", refs)
+	}
+	if _, ok := backend.docs["c"].Metadata.Custom[duplicateOfKey]; ok || backend.docs["d"].Metadata.Custom[duplicateOfKey] != "c" {
+		t.Fatalf("unexpected links %+v %+v", backend.docs["c"].Metadata.Custom, backend.docs["d"].Metadata.Custom)
+	}
+	if len(retriever.chunks) != 1 || retriever.chunks[0].DocumentID != "c" {
+		t.Fatalf("expected the promoted document to be indexed, got %+v", retriever.chunks)
+	}
+}
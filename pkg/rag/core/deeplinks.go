@@ -0,0 +1,382 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Deep link defaults
+const (
+	defaultPresignExpiry = 15 * time.Minute
+	textFragmentWords    = 4  // Words at each end of a web text fragment
+	textFragmentRunes    = 10 // Characters at each end when the text has no spaces
+)
+
+// DeepLinker builds a link that opens a cited chunk in the system its
+// document came from
+type DeepLinker interface {
+	DeepLink(ctx context.Context, doc *Document, chunk *DocumentChunk) (string, error)
+}
+
+// DeepLinkFunc adapts a function to DeepLinker
+type DeepLinkFunc func(ctx context.Context, doc *Document, chunk *DocumentChunk) (string, error)
+
+// DeepLink implements DeepLinker
+func (f DeepLinkFunc) DeepLink(ctx context.Context, doc *Document, chunk *DocumentChunk) (string, error) {
+	return f(ctx, doc, chunk)
+}
+
+// SetDeepLinker replaces the linker used for documents of a source type
+func (p *Pipeline) SetDeepLinker(sourceType string, linker DeepLinker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.deepLinkers == nil {
+		p.deepLinkers = make(map[string]DeepLinker)
+	}
+	p.deepLinkers[sourceType] = linker
+}
+
+// deepLinker returns the linker for a document: a registered one, the
+// built-in one for its source type, or the web linker for http(s) URIs
+func (p *Pipeline) deepLinker(doc *Document) DeepLinker {
+	p.mu.RLock()
+	linker := p.deepLinkers[doc.SourceType]
+	p.mu.RUnlock()
+	if linker != nil {
+		return linker
+	}
+
+	switch doc.SourceType {
+	case "git", "github", "gitlab", "bitbucket":
+		return DeepLinkFunc(gitDeepLink)
+	case "confluence":
+		return DeepLinkFunc(confluenceDeepLink)
+	case "s3":
+		config := p.config.Generation.DeepLinks.S3
+		return DeepLinkFunc(func(ctx context.Context, doc *Document, chunk *DocumentChunk) (string, error) {
+			return s3DeepLink(config, doc, time.Now())
+		})
+	}
+	if isWebURI(doc.URI) {
+		return DeepLinkFunc(webDeepLink)
+	}
+	return nil
+}
+
+// linkSources fills the project and deep link of citations from the results
+// they cite
+func (p *Pipeline) linkSources(ctx context.Context, sources []Source, results []RetrievalResult) {
+	if len(sources) == 0 {
+		return
+	}
+	byChunk := make(map[string]*RetrievalResult, len(results))
+	for i := range results {
+		if results[i].Chunk != nil {
+			byChunk[results[i].Chunk.ID] = &results[i]
+		}
+	}
+
+	for i := range sources {
+		result := byChunk[sources[i].ChunkID]
+		if result == nil {
+			continue
+		}
+		if sources[i].ProjectID == "" {
+			sources[i].ProjectID = result.ProjectID
+		}
+		if p.config.Generation.DeepLinks.Disabled || sources[i].DeepLink != "" {
+			continue
+		}
+		doc := result.Document
+		if doc == nil && p.storage != nil {
+			var err error
+			if doc, err = p.GetDocument(ctx, result.DocumentID); err != nil {
+				p.emitError(ctx, "deep_link", err)
+			}
+		}
+		if doc == nil {
+			continue
+		}
+		sources[i].SourceType = doc.SourceType
+		linker := p.deepLinker(doc)
+		if linker == nil {
+			continue
+		}
+		link, err := linker.DeepLink(ctx, doc, result.Chunk)
+		if err != nil {
+			p.emitError(ctx, "deep_link", fmt.Errorf("document %s: %w", doc.ID, err))
+			continue
+		}
+		sources[i].DeepLink = link
+	}
+}
+
+// gitDeepLink links to the chunk's line range in the repository's web view.
+// Connectors set repo_url and commit (or ref) in the document metadata.
+func gitDeepLink(ctx context.Context, doc *Document, chunk *DocumentChunk) (string, error) {
+	repo := strings.TrimSuffix(strings.TrimSuffix(metadataString(doc, "repo_url"), "/"), ".git")
+	if repo == "" {
+		return "", fmt.Errorf("repo_url is not set")
+	}
+	ref := metadataString(doc, "commit")
+	if ref == "" {
+		ref = metadataString(doc, "ref")
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	filePath := metadataString(doc, "path")
+	if filePath == "" {
+		filePath = doc.Metadata.FilePath
+	}
+	if filePath == "" {
+		return "", fmt.Errorf("file path is not set")
+	}
+	filePath = escapePath(strings.TrimPrefix(filePath, "/"))
+
+	start, end := chunkLines(doc, chunk)
+	switch {
+	case strings.Contains(repo, "gitlab"):
+		link := repo + "/-/blob/" + url.PathEscape(ref) + "/" + filePath
+		if start > 0 {
+			link += fmt.Sprintf("#L%d-%d", start, end)
+		}
+		return link, nil
+	case strings.Contains(repo, "bitbucket"):
+		link := repo + "/src/" + url.PathEscape(ref) + "/" + filePath
+		if start > 0 {
+			link += fmt.Sprintf("#lines-%d:%d", start, end)
+		}
+		return link, nil
+	default:
+		link := repo + "/blob/" + url.PathEscape(ref) + "/" + filePath
+		if start > 0 {
+			link += fmt.Sprintf("#L%d-L%d", start, end)
+		}
+		return link, nil
+	}
+}
+
+// confluenceDeepLink links to the page, anchored at the chunk's heading when
+// the chunker recorded one. Confluence anchors are "PageTitle-Heading" with
+// spaces removed.
+func confluenceDeepLink(ctx context.Context, doc *Document, chunk *DocumentChunk) (string, error) {
+	page := doc.URI
+	if !isWebURI(page) {
+		base, pageID := metadataString(doc, "base_url"), metadataString(doc, "page_id")
+		if base == "" || pageID == "" {
+			return "", fmt.Errorf("page URL is not set")
+		}
+		page = strings.TrimSuffix(base, "/") + "/pages/viewpage.action?pageId=" + url.QueryEscape(pageID)
+	}
+
+	var heading string
+	if chunk != nil {
+		heading, _ = chunk.Metadata["heading"].(string)
+	}
+	if heading == "" {
+		return page, nil
+	}
+	anchor := strings.ReplaceAll(doc.Title, " ", "") + "-" + strings.ReplaceAll(heading, " ", "")
+	return withFragment(page, url.PathEscape(anchor)), nil
+}
+
+// webDeepLink links to the page with a text fragment that scrolls to and
+// highlights the chunk in browsers supporting it; others open the page
+func webDeepLink(ctx context.Context, doc *Document, chunk *DocumentChunk) (string, error) {
+	if chunk == nil {
+		return doc.URI, nil
+	}
+	fragment := textFragment(chunk.Content)
+	if fragment == "" {
+		return doc.URI, nil
+	}
+	return withFragment(doc.URI, ":~:text="+fragment), nil
+}
+
+// textFragment builds a "start,end" text directive matching the content
+func textFragment(content string) string {
+	words := strings.Fields(content)
+	if len(words) == 0 {
+		return ""
+	}
+	if len(words) == 1 {
+		// Text without spaces, such as Chinese
+		runes := []rune(words[0])
+		if len(runes) <= 2*textFragmentRunes {
+			return escapeFragment(words[0])
+		}
+		return escapeFragment(string(runes[:textFragmentRunes])) + "," + escapeFragment(string(runes[len(runes)-textFragmentRunes:]))
+	}
+	if len(words) <= 2*textFragmentWords {
+		return escapeFragment(strings.Join(words, " "))
+	}
+	return escapeFragment(strings.Join(words[:textFragmentWords], " ")) + "," +
+		escapeFragment(strings.Join(words[len(words)-textFragmentWords:], " "))
+}
+
+// escapeFragment percent-encodes text for a text directive, where "-", ","
+// and "&" are syntax
+func escapeFragment(text string) string {
+	escaped := url.PathEscape(text)
+	return strings.NewReplacer("-", "%2D", ",", "%2C", "&", "%26").Replace(escaped)
+}
+
+// s3DeepLink presigns a GET of the document's object. The location is read
+// from bucket and key metadata or an s3://bucket/key URI.
+func s3DeepLink(config S3LinkConfig, doc *Document, now time.Time) (string, error) {
+	bucket, key := metadataString(doc, "bucket"), metadataString(doc, "key")
+	if bucket == "" || key == "" {
+		u, err := url.Parse(doc.URI)
+		if err != nil || u.Scheme != "s3" {
+			return "", fmt.Errorf("object location is not set")
+		}
+		bucket, key = u.Host, strings.TrimPrefix(u.Path, "/")
+	}
+	return presignS3(config, bucket, key, now)
+}
+
+// presignS3 returns an AWS Signature Version 4 presigned GET URL
+func presignS3(config S3LinkConfig, bucket, key string, now time.Time) (string, error) {
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return "", fmt.Errorf("s3 credentials are not configured")
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	expiry := config.Expiry
+	if expiry <= 0 {
+		expiry = defaultPresignExpiry
+	}
+
+	scheme, host, path := "https", bucket+".s3.amazonaws.com", "/"+awsEscape(key, false)
+	if region != "us-east-1" {
+		host = bucket + ".s3." + region + ".amazonaws.com"
+	}
+	if config.Endpoint != "" {
+		// Custom endpoints (MinIO and other compatible stores) use path-style addressing
+		endpoint, err := url.Parse(config.Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return "", fmt.Errorf("invalid s3 endpoint %q", config.Endpoint)
+		}
+		scheme, host, path = endpoint.Scheme, endpoint.Host, "/"+awsEscape(bucket, true)+path
+	}
+
+	now = now.UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	scope := date + "/" + region + "/s3/aws4_request"
+	params := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    config.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(expiry / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if config.SessionToken != "" {
+		params["X-Amz-Security-Token"] = config.SessionToken
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = awsEscape(name, true) + "=" + awsEscape(params[name], true)
+	}
+	query := strings.Join(pairs, "&")
+
+	canonical := strings.Join([]string{"GET", path, query, "host:" + host, "", "host", "UNSIGNED-PAYLOAD"}, "\n")
+	hashed := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hashed[:])}, "\n")
+
+	signingKey := []byte("AWS4" + config.SecretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	return scheme + "://" + host + path + "?" + query + "&X-Amz-Signature=" + signature, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash is set, as SigV4 canonical requests require
+func awsEscape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// chunkLines returns the 1-based line range of a chunk in its document, or
+// zeros when it cannot be located
+func chunkLines(doc *Document, chunk *DocumentChunk) (int, int) {
+	if chunk == nil {
+		return 0, 0
+	}
+	if chunk.StartLine > 0 {
+		end := chunk.EndLine
+		if end < chunk.StartLine {
+			end = chunk.StartLine
+		}
+		return chunk.StartLine, end
+	}
+	offset := strings.Index(doc.Content, chunk.Content)
+	if offset < 0 || chunk.Content == "" {
+		return 0, 0
+	}
+	start := strings.Count(doc.Content[:offset], "\n") + 1
+	end := start + strings.Count(strings.TrimRight(chunk.Content, "\n"), "\n")
+	return start, end
+}
+
+// metadataString returns a string value of the document's custom metadata
+func metadataString(doc *Document, key string) string {
+	value, _ := doc.Metadata.Custom[key].(string)
+	return value
+}
+
+// escapePath escapes each segment of a slash-separated path
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// withFragment replaces the fragment of a link
+func withFragment(link, fragment string) string {
+	if i := strings.IndexByte(link, '#'); i >= 0 {
+		link = link[:i]
+	}
+	return link + "#" + fragment
+}
+
+// isWebURI reports whether uri is an http(s) URL
+func isWebURI(uri string) bool {
+	return utf8.ValidString(uri) && (strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://"))
+}
@@ -0,0 +1,335 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// tombstoneRefreshInterval is how often tombstones are reloaded from the store
+const tombstoneRefreshInterval = time.Minute
+
+// Tombstone marks a soft-deleted document awaiting purge
+type Tombstone struct {
+	DocumentID     string    `json:"document_id"`
+	DataSourceID   string    `json:"data_source_id,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	DeletedBy      string    `json:"deleted_by,omitempty"`
+	DeletedAt      time.Time `json:"deleted_at"`
+	PurgeAfter     time.Time `json:"purge_after"`
+	ChunkCount     int       `json:"chunk_count"`
+	EmbeddingCount int       `json:"embedding_count"`
+}
+
+// TombstoneStore persists tombstones so soft deletes survive restarts
+type TombstoneStore interface {
+	// SaveTombstone stores a tombstone, replacing an existing one for the document
+	SaveTombstone(ctx context.Context, tombstone *Tombstone) error
+
+	// DeleteTombstone removes a document's tombstone
+	DeleteTombstone(ctx context.Context, documentID string) error
+
+	// ListTombstones returns all tombstones
+	ListTombstones(ctx context.Context) ([]Tombstone, error)
+}
+
+// PurgeResult reports what a purge run removed
+type PurgeResult struct {
+	DocumentsPurged int      `json:"documents_purged"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// SetTombstoneStore persists tombstones in store and loads the existing ones
+func (p *Pipeline) SetTombstoneStore(ctx context.Context, store TombstoneStore) error {
+	p.mu.Lock()
+	p.tombstoneStore = store
+	p.mu.Unlock()
+	return p.RefreshTombstones(ctx)
+}
+
+// RefreshTombstones reloads tombstones from the store so soft deletes and
+// restores made by other instances take effect here
+func (p *Pipeline) RefreshTombstones(ctx context.Context) error {
+	p.mu.RLock()
+	store := p.tombstoneStore
+	p.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	listedAt := time.Now()
+	tombstones, err := store.ListTombstones(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load tombstones: %w", err)
+	}
+	loaded := make(map[string]Tombstone, len(tombstones))
+	for _, tombstone := range tombstones {
+		loaded[tombstone.DocumentID] = tombstone
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Keep local deletes that may not have been saved when the list was read
+	for id, tombstone := range p.tombstones {
+		if tombstone.DeletedAt.After(listedAt) {
+			loaded[id] = tombstone
+		}
+	}
+	p.tombstones = loaded
+	return nil
+}
+
+// refreshTombstonesLoop periodically reloads tombstones and tier state until ctx is done
+func (p *Pipeline) refreshTombstonesLoop(ctx context.Context) {
+	ticker := time.NewTicker(tombstoneRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.RefreshTombstones(ctx); err != nil {
+				p.emitError(ctx, "refresh_tombstones", err)
+			}
+			if err := p.RefreshTiers(ctx); err != nil {
+				p.emitError(ctx, "refresh_tiers", err)
+			}
+		}
+	}
+}
+
+// SoftDeleteDocument tombstones a document: its chunks are removed from the
+// retrievers at once and filtered from results, while stored data is kept
+// until the retention period passes and PurgeDeletedDocuments removes it
+func (p *Pipeline) SoftDeleteDocument(ctx context.Context, documentID, deletedBy, reason string) (*Tombstone, error) {
+	doc, err := p.storage.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("document %s not found", documentID)
+	}
+	chunks, err := p.storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	now := time.Now()
+	tombstone := Tombstone{
+		DocumentID:   documentID,
+		DataSourceID: doc.DataSourceID,
+		Reason:       reason,
+		DeletedBy:    deletedBy,
+		DeletedAt:    now,
+		PurgeAfter:   now.Add(p.config.Storage.DeletedRetention),
+		ChunkCount:   len(chunks),
+	}
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) > 0 && chunk.DuplicateOf == "" {
+			tombstone.EmbeddingCount++
+		}
+	}
+
+	// Record the tombstone first so results are filtered even if removal fails
+	p.mu.Lock()
+	p.tombstones[documentID] = tombstone
+	store := p.tombstoneStore
+	p.mu.Unlock()
+	if store != nil {
+		if err := store.SaveTombstone(ctx, &tombstone); err != nil {
+			return nil, fmt.Errorf("failed to save tombstone: %w", err)
+		}
+	}
+
+	for _, chunk := range chunks {
+		if chunk.DuplicateOf != "" {
+			continue
+		}
+		if err := p.retriever.RemoveDocument(ctx, chunk.ID); err != nil {
+			p.emitError(ctx, "tombstone_chunk", err)
+		}
+	}
+	if documents, ok := p.cache.(DocumentCache); ok {
+		documents.DeleteDocument(ctx, documentID)
+	}
+	p.invalidateCachedAnswers(ctx, documentID)
+
+	p.emitEvent(ctx, "document_deleted", map[string]interface{}{
+		"document_id": documentID,
+		"chunks":      tombstone.ChunkCount,
+		"purge_after": tombstone.PurgeAfter,
+	})
+	return &tombstone, nil
+}
+
+// RestoreDocument reverses a soft delete before the document is purged
+func (p *Pipeline) RestoreDocument(ctx context.Context, documentID string) error {
+	p.mu.RLock()
+	_, deleted := p.tombstones[documentID]
+	p.mu.RUnlock()
+	if !deleted {
+		return fmt.Errorf("document %s is not deleted", documentID)
+	}
+
+	// Cold embeddings are indexed again from the cold store
+	if p.isCold(documentID) {
+		if err := p.RehydrateDocument(ctx, documentID); err != nil {
+			return err
+		}
+		return p.clearTombstone(ctx, documentID)
+	}
+
+	chunks, err := p.storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to list chunks: %w", err)
+	}
+	for _, chunk := range chunks {
+		if chunk.DuplicateOf != "" {
+			continue
+		}
+		if err := p.retriever.AddDocument(ctx, chunk); err != nil {
+			return fmt.Errorf("failed to restore chunk %s: %w", chunk.ID, err)
+		}
+	}
+
+	return p.clearTombstone(ctx, documentID)
+}
+
+// ListDeletedDocuments returns the tombstones of documents awaiting purge
+func (p *Pipeline) ListDeletedDocuments() []Tombstone {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	tombstones := make([]Tombstone, 0, len(p.tombstones))
+	for _, tombstone := range p.tombstones {
+		tombstones = append(tombstones, tombstone)
+	}
+	return tombstones
+}
+
+// PurgeDeletedDocuments permanently removes soft-deleted documents whose
+// retention has passed, or all of them when force is set
+func (p *Pipeline) PurgeDeletedDocuments(ctx context.Context, force bool) *PurgeResult {
+	now := time.Now()
+	p.mu.RLock()
+	var due []string
+	for id, tombstone := range p.tombstones {
+		if force || !now.Before(tombstone.PurgeAfter) {
+			due = append(due, id)
+		}
+	}
+	p.mu.RUnlock()
+
+	result := &PurgeResult{}
+	for _, documentID := range due {
+		if err := p.DeleteDocument(ctx, documentID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Purge document %s: %v", documentID, err))
+			continue
+		}
+		if err := p.clearTombstone(ctx, documentID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Purge document %s: %v", documentID, err))
+			continue
+		}
+		result.DocumentsPurged++
+	}
+
+	if len(due) > 0 {
+		p.emitEvent(ctx, "documents_purged", map[string]interface{}{
+			"purged": result.DocumentsPurged,
+			"errors": len(result.Errors),
+		})
+	}
+	return result
+}
+
+// DeleteDataSource removes a data source and soft-deletes every document it
+// contributed. The returned SyncResult reports what was deleted.
+func (p *Pipeline) DeleteDataSource(ctx context.Context, sourceID, deletedBy string) (*SyncResult, error) {
+	result := &SyncResult{
+		StartTime:    time.Now(),
+		SyncType:     "delete",
+		DataSourceID: sourceID,
+	}
+
+	// A disabled source is not attached, but its documents are still removed
+	p.mu.RLock()
+	_, attached := p.dataSources[sourceID]
+	p.mu.RUnlock()
+	if attached {
+		if err := p.RemoveDataSource(sourceID); err != nil {
+			return nil, err
+		}
+	}
+
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{
+		Filter: FilterCriteria{DataSourceIDs: []string{sourceID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data source documents: %w", err)
+	}
+
+	reason := fmt.Sprintf("data source %s removed", sourceID)
+	for _, doc := range documents {
+		if doc.DataSourceID != sourceID || p.isTombstoned(doc.ID) {
+			continue
+		}
+		tombstone, err := p.SoftDeleteDocument(ctx, doc.ID, deletedBy, reason)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
+			continue
+		}
+		result.DocumentsDeleted++
+		result.ChunksDeleted += tombstone.ChunkCount
+		result.EmbeddingsDeleted += tombstone.EmbeddingCount
+	}
+
+	result.ErrorCount = len(result.Errors)
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.LastSyncTime = result.EndTime
+	return result, nil
+}
+
+// clearTombstone removes a document's tombstone from memory and the store
+func (p *Pipeline) clearTombstone(ctx context.Context, documentID string) error {
+	p.mu.Lock()
+	delete(p.tombstones, documentID)
+	store := p.tombstoneStore
+	p.mu.Unlock()
+	if store != nil {
+		if err := store.DeleteTombstone(ctx, documentID); err != nil {
+			return fmt.Errorf("failed to delete tombstone: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *Pipeline) isTombstoned(documentID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, deleted := p.tombstones[documentID]
+	return deleted
+}
+
+// dropTombstoned removes results of soft-deleted documents
+func (p *Pipeline) dropTombstoned(results []RetrievalResult) []RetrievalResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if len(p.tombstones) == 0 {
+		return results
+	}
+
+	kept := results[:0]
+	for _, result := range results {
+		documentID := result.DocumentID
+		if documentID == "" && result.Chunk != nil {
+			documentID = result.Chunk.DocumentID
+		}
+		if _, deleted := p.tombstones[documentID]; deleted {
+			continue
+		}
+		kept = append(kept, result)
+	}
+	return kept
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// tombstoneStorage keeps documents and chunks in memory
+type tombstoneStorage struct {
+	Storage
+	documents map[string]Document
+	chunks    map[string][]DocumentChunk
+}
+
+func (s *tombstoneStorage) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	doc, ok := s.documents[documentID]
+	if !ok {
+		return nil, nil
+	}
+	return &doc, nil
+}
+
+func (s *tombstoneStorage) ListDocuments(ctx context.Context, options ListOptions) ([]Document, error) {
+	var documents []Document
+	for _, doc := range s.documents {
+		documents = append(documents, doc)
+	}
+	return documents, nil
+}
+
+func (s *tombstoneStorage) ListChunks(ctx context.Context, documentID string) ([]DocumentChunk, error) {
+	return s.chunks[documentID], nil
+}
+
+func (s *tombstoneStorage) DeleteDocument(ctx context.Context, documentID string) error {
+	delete(s.documents, documentID)
+	delete(s.chunks, documentID)
+	return nil
+}
+
+// indexSet records the chunk IDs held by a retriever
+type indexSet struct {
+	Retriever
+	chunks map[string]bool
+}
+
+func (r *indexSet) AddDocument(ctx context.Context, chunk DocumentChunk) error {
+	r.chunks[chunk.ID] = true
+	return nil
+}
+
+func (r *indexSet) RemoveDocument(ctx context.Context, chunkID string) error {
+	delete(r.chunks, chunkID)
+	return nil
+}
+
+// memoryTombstoneStore persists tombstones in memory
+type memoryTombstoneStore struct {
+	tombstones map[string]Tombstone
+}
+
+func (s *memoryTombstoneStore) SaveTombstone(ctx context.Context, tombstone *Tombstone) error {
+	s.tombstones[tombstone.DocumentID] = *tombstone
+	return nil
+}
+
+func (s *memoryTombstoneStore) DeleteTombstone(ctx context.Context, documentID string) error {
+	delete(s.tombstones, documentID)
+	return nil
+}
+
+func (s *memoryTombstoneStore) ListTombstones(ctx context.Context) ([]Tombstone, error) {
+	var tombstones []Tombstone
+	for _, tombstone := range s.tombstones {
+		tombstones = append(tombstones, tombstone)
+	}
+	return tombstones, nil
+}
+
+// closingSource is a data source that only needs closing
+type closingSource struct {
+	DataSource
+	closed bool
+}
+
+func (s *closingSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+func newTombstonePipeline() (*Pipeline, *tombstoneStorage, *indexSet) {
+	storage := &tombstoneStorage{
+		documents: map[string]Document{
+			"a": {ID: "a", DataSourceID: "wiki"},
+			"b": {ID: "b", DataSourceID: "wiki"},
+			"c": {ID: "c", DataSourceID: "files"},
+		},
+		chunks: map[string][]DocumentChunk{
+			"a": {
+				{ID: "a_0", DocumentID: "a", Embedding: []float64{1}},
+				{ID: "a_1", DocumentID: "a", Embedding: []float64{1}, DuplicateOf: "c_0"},
+			},
+			"b": {{ID: "b_0", DocumentID: "b"}},
+			"c": {{ID: "c_0", DocumentID: "c", Embedding: []float64{1}}},
+		},
+	}
+	retriever := &indexSet{chunks: map[string]bool{"a_0": true, "b_0": true, "c_0": true}}
+	p := &Pipeline{
+		config:      DefaultConfig(),
+		storage:     storage,
+		retriever:   retriever,
+		dataSources: make(map[string]DataSource),
+		tombstones:  make(map[string]Tombstone),
+	}
+	return p, storage, retriever
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	ctx := context.Background()
+	p, _, retriever := newTombstonePipeline()
+	store := &memoryTombstoneStore{tombstones: make(map[string]Tombstone)}
+	if err := p.SetTombstoneStore(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.SoftDeleteDocument(ctx, "missing", "alice", ""); err == nil {
+		t.Fatal("expected deleting a missing document to fail")
+	}
+	tombstone, err := p.SoftDeleteDocument(ctx, "a", "alice", "outdated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tombstone.ChunkCount != 2 || tombstone.EmbeddingCount != 1 || tombstone.DataSourceID != "wiki" {
+		t.Fatalf("unexpected tombstone %+v", tombstone)
+	}
+	if want := tombstone.DeletedAt.Add(p.config.Storage.DeletedRetention); !tombstone.PurgeAfter.Equal(want) {
+		t.Fatalf("got purge time %v, want %v", tombstone.PurgeAfter, want)
+	}
+	if retriever.chunks["a_0"] || !retriever.chunks["c_0"] {
+		t.Fatalf("expected only the document's own chunks to be unindexed, got %v", retriever.chunks)
+	}
+	if _, saved := store.tombstones["a"]; !saved {
+		t.Fatal("expected the tombstone to be persisted")
+	}
+
+	results := p.dropTombstoned([]RetrievalResult{
+		{DocumentID: "a"},
+		{Chunk: &DocumentChunk{ID: "a_0", DocumentID: "a"}},
+		{DocumentID: "b"},
+	})
+	if len(results) != 1 || results[0].DocumentID != "b" {
+		t.Fatalf("expected results of deleted documents to be dropped, got %+v", results)
+	}
+
+	// A second pipeline picks the tombstone up from the store
+	reloaded, _, _ := newTombstonePipeline()
+	if err := reloaded.SetTombstoneStore(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.isTombstoned("a") {
+		t.Fatal("expected tombstones to be loaded from the store")
+	}
+
+	if err := p.RestoreDocument(ctx, "b"); err == nil {
+		t.Fatal("expected restoring a live document to fail")
+	}
+	if err := p.RestoreDocument(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if !retriever.chunks["a_0"] || retriever.chunks["a_1"] {
+		t.Fatalf("expected the document's own chunks to be reindexed, got %v", retriever.chunks)
+	}
+	if p.isTombstoned("a") || len(store.tombstones) != 0 {
+		t.Fatal("expected the tombstone to be cleared")
+	}
+}
+
+func TestPurgeDeletedDocuments(t *testing.T) {
+	ctx := context.Background()
+	p, storage, _ := newTombstonePipeline()
+	for _, id := range []string{"a", "b"} {
+		if _, err := p.SoftDeleteDocument(ctx, id, "alice", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only b is past its retention
+	p.mu.Lock()
+	tombstone := p.tombstones["b"]
+	tombstone.PurgeAfter = time.Now().Add(-time.Minute)
+	p.tombstones["b"] = tombstone
+	p.mu.Unlock()
+
+	result := p.PurgeDeletedDocuments(ctx, false)
+	if result.DocumentsPurged != 1 || len(result.Errors) != 0 {
+		t.Fatalf("unexpected purge result %+v", result)
+	}
+	if _, exists := storage.documents["b"]; exists {
+		t.Fatal("expected b to be purged from storage")
+	}
+	if _, exists := storage.documents["a"]; !exists || !p.isTombstoned("a") {
+		t.Fatal("expected a to be kept until its retention passes")
+	}
+
+	if result := p.PurgeDeletedDocuments(ctx, true); result.DocumentsPurged != 1 {
+		t.Fatalf("expected a forced purge to remove a, got %+v", result)
+	}
+	if len(p.ListDeletedDocuments()) != 0 {
+		t.Fatalf("expected no tombstones left, got %+v", p.ListDeletedDocuments())
+	}
+}
+
+func TestDeleteDataSource(t *testing.T) {
+	ctx := context.Background()
+	p, _, retriever := newTombstonePipeline()
+	source := &closingSource{}
+	p.dataSources["wiki"] = source
+
+	result, err := p.DeleteDataSource(ctx, "wiki", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !source.closed {
+		t.Fatal("expected the data source to be closed")
+	}
+	if result.DocumentsDeleted != 2 || result.ChunksDeleted != 3 || result.EmbeddingsDeleted != 1 || result.ErrorCount != 0 {
+		t.Fatalf("unexpected sync result %+v", result)
+	}
+
+	var deleted []string
+	for _, tombstone := range p.ListDeletedDocuments() {
+		deleted = append(deleted, tombstone.DocumentID)
+		if tombstone.DeletedBy != "alice" || tombstone.Reason != "data source wiki removed" {
+			t.Fatalf("unexpected tombstone %+v", tombstone)
+		}
+	}
+	sort.Strings(deleted)
+	if !reflect.DeepEqual(deleted, []string{"a", "b"}) {
+		t.Fatalf("got deleted documents %v, want a and b", deleted)
+	}
+	if !retriever.chunks["c_0"] {
+		t.Fatal("expected documents of other data sources to stay indexed")
+	}
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// FakeProvider selects FakeLLMClient as the generation or embedding
+// provider, for tests and evaluations that must not call a real model
+const FakeProvider = "fake"
+
+// Defaults for deterministic mode
+const (
+	defaultDeterministicStep      = time.Millisecond
+	defaultFakeEmbeddingDimension = 64
+)
+
+// defaultDeterministicEpoch is the first reading of the deterministic clock
+var defaultDeterministicEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// deterministicNamespace namespaces the UUIDs generated in deterministic mode
+var deterministicNamespace = uuid.MustParse("6f2c1d9e-5b7a-4c3e-9f1d-2a8b7c6d5e4f")
+
+// DeterministicConfig makes pipeline runs reproducible for golden-file tests
+// and evaluations. IDs come from a seeded sequence, the clock starts at Epoch
+// and advances ClockStep per reading, and generation uses temperature 0 with
+// the seed. Runs are only reproducible when operations are issued in the same
+// order, so golden tests should not run queries concurrently.
+type DeterministicConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Seed      int64         `json:"seed"`
+	Epoch     time.Time     `json:"epoch,omitempty"`      // Default 2024-01-01T00:00:00Z
+	ClockStep time.Duration `json:"clock_step,omitempty"` // Default 1ms
+}
+
+// deterministicClock returns Epoch, then advances by step on every reading
+type deterministicClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+func (c *deterministicClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(c.step)
+	return now
+}
+
+// idSequence derives UUIDs from the seed and a counter
+type idSequence struct {
+	mu   sync.Mutex
+	seed int64
+	next int64
+}
+
+func (s *idSequence) NewID() string {
+	s.mu.Lock()
+	s.next++
+	n := s.next
+	s.mu.Unlock()
+	return uuid.NewSHA1(deterministicNamespace, []byte(fmt.Sprintf("%d:%d", s.seed, n))).String()
+}
+
+// configureDeterminism installs the deterministic clock and ID sequence
+// when enabled
+func (p *Pipeline) configureDeterminism() {
+	config := p.config.System.Deterministic
+	if !config.Enabled {
+		return
+	}
+	epoch, step := config.Epoch, config.ClockStep
+	if epoch.IsZero() {
+		epoch = defaultDeterministicEpoch
+	}
+	if step == 0 {
+		step = defaultDeterministicStep
+	}
+	clock := &deterministicClock{now: epoch, step: step}
+	ids := &idSequence{seed: config.Seed}
+	p.clock = clock.Now
+	p.newID = ids.NewID
+}
+
+// now reads the pipeline clock
+func (p *Pipeline) now() time.Time {
+	if p.clock == nil {
+		return time.Now()
+	}
+	return p.clock()
+}
+
+// since is time.Since on the pipeline clock
+func (p *Pipeline) since(start time.Time) time.Duration {
+	return p.now().Sub(start)
+}
+
+// generateID returns a new random, or in deterministic mode sequential, ID
+func (p *Pipeline) generateID() string {
+	if p.newID == nil {
+		return uuid.New().String()
+	}
+	return p.newID()
+}
+
+// samplingSeed returns the seed for model sampling, nil unless deterministic
+func (p *Pipeline) samplingSeed() *int64 {
+	if p.config == nil || !p.config.System.Deterministic.Enabled {
+		return nil
+	}
+	seed := p.config.System.Deterministic.Seed
+	return &seed
+}
+
+// FakeLLMClient answers from content hashes instead of calling a model.
+// Embeddings hash words into buckets, so texts sharing words are similar and
+// retrieval over them behaves sensibly; completions name a hash of the
+// prompt, so any change to the prompt shows up in golden files.
+type FakeLLMClient struct {
+	dimension int
+}
+
+// NewFakeLLMClient creates a fake client producing embeddings of the given
+// dimension, or 64 when it is not positive
+func NewFakeLLMClient(dimension int) *FakeLLMClient {
+	if dimension <= 0 {
+		dimension = defaultFakeEmbeddingDimension
+	}
+	return &FakeLLMClient{dimension: dimension}
+}
+
+// GenerateCompletion implements the LLMClient interface
+func (c *FakeLLMClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	question := ""
+	promptTokens := 0
+	for _, message := range messages {
+		hash.Write([]byte(message.Role + "\x00" + message.Content + "\x00"))
+		promptTokens += len(strings.Fields(message.Content))
+		if message.Role == "user" {
+			question = message.Content
+		}
+	}
+	digest := hex.EncodeToString(hash.Sum(nil))[:12]
+	if len(question) > 80 {
+		question = question[:80]
+	}
+
+	content := fmt.Sprintf("Fake answer %s to: %s", digest, strings.Join(strings.Fields(question), " "))
+	if options.ResponseFormat != nil {
+		encoded, _ := json.Marshal(map[string]string{"answer": content})
+		content = string(encoded)
+	}
+	completionTokens := len(strings.Fields(content))
+	return &CompletionResponse{
+		ID:      "fake-" + digest,
+		Object:  "chat.completion",
+		Model:   FakeProvider,
+		Choices: []CompletionChoice{{Message: llm.ChatMessage{Role: "assistant", Content: content}, FinishReason: "stop"}},
+		Usage: CompletionUsage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		},
+	}, nil
+}
+
+// GenerateEmbedding implements the LLMClient interface
+func (c *FakeLLMClient) GenerateEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	embeddings := make([][]float64, len(texts))
+	for i, text := range texts {
+		embeddings[i] = c.embed(text)
+	}
+	return embeddings, nil
+}
+
+// Rerank implements the LLMClient interface, scoring documents by the
+// cosine similarity of their fake embeddings to the query's
+func (c *FakeLLMClient) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	queryVector := c.embed(query)
+	scores := make([]float64, len(documents))
+	for i, document := range documents {
+		vector := c.embed(document)
+		for j := range vector {
+			scores[i] += vector[j] * queryVector[j]
+		}
+	}
+	return scores, nil
+}
+
+// GetModelInfo implements the LLMClient interface
+func (c *FakeLLMClient) GetModelInfo() (*ModelInfo, error) {
+	return &ModelInfo{
+		Name:         FakeProvider,
+		Type:         "chat",
+		Provider:     FakeProvider,
+		Capabilities: []string{"chat", "embedding", "rerank"},
+		Metadata:     map[string]interface{}{"dimension": c.dimension},
+	}, nil
+}
+
+// Validate implements the LLMClient interface
+func (c *FakeLLMClient) Validate() error {
+	return nil
+}
+
+// Close implements the LLMClient interface
+func (c *FakeLLMClient) Close() error {
+	return nil
+}
+
+// embed hashes each word to a bucket and sign, then normalizes the vector
+func (c *FakeLLMClient) embed(text string) []float64 {
+	vector := make([]float64, c.dimension)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		sum := sha256.Sum256([]byte(word))
+		bucket := binary.BigEndian.Uint64(sum[:8]) % uint64(c.dimension)
+		if sum[8]&1 == 0 {
+			vector[bucket]++
+		} else {
+			vector[bucket]--
+		}
+	}
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	if norm == 0 {
+		vector[0] = 1
+		return vector
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// fakeGenerator answers through an LLM client, recording its options
+type fakeGenerator struct {
+	Generator
+	client  LLMClient
+	options GenerateOptions
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context, query string, context []RetrievalResult, options GenerateOptions) (*GenerationResult, error) {
+	g.options = options
+	response, err := g.client.GenerateCompletion(ctx, []llm.ChatMessage{{Role: "user", Content: query}}, CompletionOptions{
+		Temperature: options.Temperature,
+		Seed:        options.Seed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &GenerationResult{Response: response.Choices[0].Message.Content}, nil
+}
+
+func TestDeterministicQuery(t *testing.T) {
+	run := func() ([]byte, *fakeGenerator) {
+		config := DefaultConfig()
+		config.System.Deterministic = DeterministicConfig{Enabled: true, Seed: 42}
+		generator := &fakeGenerator{client: NewFakeLLMClient(0)}
+		p := &Pipeline{
+			config:        config,
+			retriever:     &keywordRetriever{chunks: []DocumentChunk{{ID: "a_0", DocumentID: "a", Content: "golden files"}}},
+			generator:     generator,
+			started:       true,
+			activeQueries: make(map[string]*QueryContext),
+		}
+		p.configureDeterminism()
+		result, err := p.Query(context.Background(), "golden", QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded, generator
+	}
+
+	first, generator := run()
+	second, _ := run()
+	if string(first) != string(second) {
+		t.Fatalf("expected identical results, got\n%s\n%s", first, second)
+	}
+	if generator.options.Temperature != 0 || generator.options.Seed == nil || *generator.options.Seed != 42 {
+		t.Fatalf("expected greedy seeded sampling, got %+v", generator.options)
+	}
+
+	var result QueryResult
+	json.Unmarshal(first, &result)
+	if !result.CreatedAt.Equal(defaultDeterministicEpoch.Add(2*time.Millisecond)) || result.TotalTime <= 0 {
+		t.Fatalf("expected timestamps from the deterministic clock, got %v %v", result.CreatedAt, result.TotalTime)
+	}
+}
+
+func TestFakeLLMClient(t *testing.T) {
+	ctx := context.Background()
+	client := NewFakeLLMClient(32)
+
+	vectors, err := client.GenerateEmbedding(ctx, []string{"vector search", "vector search", "cooking recipes"})
+	if err != nil || len(vectors) != 3 || len(vectors[0]) != 32 {
+		t.Fatalf("unexpected embeddings %v %v", vectors, err)
+	}
+	for i := range vectors[0] {
+		if vectors[0][i] != vectors[1][i] {
+			t.Fatal("expected identical texts to embed identically")
+		}
+	}
+
+	scores, err := client.Rerank(ctx, "vector search", []string{"cooking recipes", "fast vector search"})
+	if err != nil || scores[1] <= scores[0] {
+		t.Fatalf("expected the overlapping document to rank higher, got %v %v", scores, err)
+	}
+
+	messages := []llm.ChatMessage{{Role: "user", Content: "what is rag"}}
+	a, _ := client.GenerateCompletion(ctx, messages, CompletionOptions{})
+	b, _ := client.GenerateCompletion(ctx, messages, CompletionOptions{})
+	messages[0].Content = "what is retrieval"
+	c, _ := client.GenerateCompletion(ctx, messages, CompletionOptions{})
+	if a.Choices[0].Message.Content != b.Choices[0].Message.Content || a.Choices[0].Message.Content == c.Choices[0].Message.Content {
+		t.Fatalf("expected answers to depend only on the prompt, got %q %q %q",
+			a.Choices[0].Message.Content, b.Choices[0].Message.Content, c.Choices[0].Message.Content)
+	}
+}
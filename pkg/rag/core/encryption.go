@@ -0,0 +1,699 @@
+package core
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalKMSKeyID names the key of the built-in KMS, derived from the storage
+// encryption key
+const LocalKMSKeyID = "local"
+
+// encryptedPrefix marks values encrypted with a tenant data key
+const encryptedPrefix = "enc:v1:"
+
+// dataKeySize is the size of tenant data keys, for AES-256
+const dataKeySize = 32
+
+// ErrNoDataKey is returned when a value was encrypted with a tenant data key
+// version that no longer exists
+var ErrNoDataKey = errors.New("tenant data key not found")
+
+// KMS wraps and unwraps tenant data keys with a key-encryption key that never
+// leaves the KMS. Implement it to bring your own key from an external KMS.
+type KMS interface {
+	// WrapKey encrypts a data key with the KMS key keyID
+	WrapKey(ctx context.Context, keyID string, key []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a data key wrapped with the KMS key keyID
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// LocalKMS wraps data keys with a master key derived from a secret, for
+// installs without an external KMS
+type LocalKMS struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKMS creates a local KMS from the storage encryption key
+func NewLocalKMS(secret string) (*LocalKMS, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("encryption key is required")
+	}
+	master := sha256.Sum256([]byte(secret))
+	aead, err := newAEAD(master[:])
+	if err != nil {
+		return nil, err
+	}
+	return &LocalKMS{aead: aead}, nil
+}
+
+// WrapKey implements the KMS interface
+func (k *LocalKMS) WrapKey(ctx context.Context, keyID string, key []byte) ([]byte, error) {
+	return seal(k.aead, key, []byte(keyID))
+}
+
+// UnwrapKey implements the KMS interface
+func (k *LocalKMS) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	return open(k.aead, wrapped, []byte(keyID))
+}
+
+// TenantKey is one version of a tenant's data key. Data is encrypted with the
+// active version; older versions are kept to decrypt data written with them.
+type TenantKey struct {
+	TenantID    string     `json:"tenant_id"`
+	Version     int        `json:"version"`
+	KMSKeyID    string     `json:"kms_key_id"`
+	WrappedKey  []byte     `json:"-"`
+	Active      bool       `json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RewrappedAt *time.Time `json:"rewrapped_at,omitempty"`
+}
+
+// TenantKeyStore persists wrapped tenant data keys
+type TenantKeyStore interface {
+	// ListTenantKeys returns a tenant's data key versions, oldest first
+	ListTenantKeys(ctx context.Context, tenantID string) ([]TenantKey, error)
+
+	// SaveTenantKeys creates or replaces data key versions atomically
+	SaveTenantKeys(ctx context.Context, keys []TenantKey) error
+}
+
+// KeyOperation is an audited use of a tenant data key
+type KeyOperation string
+
+// Audited key operations. Encrypting and decrypting with a cached data key is
+// not audited; unwrapping one through the KMS is.
+const (
+	KeyCreated   KeyOperation = "create"
+	KeyRotated   KeyOperation = "rotate"
+	KeyRewrapped KeyOperation = "rewrap"
+	KeyUnwrapped KeyOperation = "unwrap"
+)
+
+// KeyUsageEvent records one use of a tenant data key
+type KeyUsageEvent struct {
+	TenantID   string       `json:"tenant_id"`
+	KeyVersion int          `json:"key_version"`
+	KMSKeyID   string       `json:"kms_key_id"`
+	Operation  KeyOperation `json:"operation"`
+	At         time.Time    `json:"at"`
+}
+
+// KeyAuditor records key usage events
+type KeyAuditor interface {
+	RecordKeyUsage(ctx context.Context, event KeyUsageEvent) error
+}
+
+// activeKeyTTL is how long a cached active key version is used before the
+// store is checked again for a rotation made by another instance
+const activeKeyTTL = time.Minute
+
+// KeyRing encrypts tenant data with per-tenant data keys, each wrapped by a
+// KMS key: KMS key -> tenant data key versions -> data. Unwrapped data keys
+// are cached in memory.
+type KeyRing struct {
+	kms      KMS
+	store    TenantKeyStore
+	auditor  KeyAuditor
+	kmsKeyID string
+	now      func() time.Time
+
+	writeMu sync.Mutex // Serializes key creation and rotation
+
+	mu     sync.Mutex                      // Guards the caches; never held during store or KMS calls
+	keys   map[string]map[int]unwrappedKey // by tenant and version
+	active map[string]activeVersion
+}
+
+// unwrappedKey is a cached data key version
+type unwrappedKey struct {
+	key     TenantKey
+	dataKey []byte
+}
+
+// activeVersion is a cached active key version
+type activeVersion struct {
+	version  int
+	loadedAt time.Time
+}
+
+// NewKeyRing creates a key ring wrapping new data keys with kmsKeyID, or
+// LocalKMSKeyID when empty. The auditor is optional.
+func NewKeyRing(kms KMS, store TenantKeyStore, auditor KeyAuditor, kmsKeyID string) *KeyRing {
+	if kmsKeyID == "" {
+		kmsKeyID = LocalKMSKeyID
+	}
+	return &KeyRing{
+		kms:      kms,
+		store:    store,
+		auditor:  auditor,
+		kmsKeyID: kmsKeyID,
+		now:      time.Now,
+		keys:     make(map[string]map[int]unwrappedKey),
+		active:   make(map[string]activeVersion),
+	}
+}
+
+// Keys returns a tenant's data key versions, oldest first
+func (r *KeyRing) Keys(ctx context.Context, tenantID string) ([]TenantKey, error) {
+	return r.store.ListTenantKeys(ctx, tenantID)
+}
+
+// Encrypt encrypts plaintext with the tenant's active data key, creating the
+// tenant's first key if needed
+func (r *KeyRing) Encrypt(ctx context.Context, tenantID string, plaintext []byte) (string, error) {
+	key, dataKey, err := r.activeKey(ctx, tenantID)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, plaintext, dataAAD(tenantID, key.Version))
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + strconv.Itoa(key.Version) + ":" + tenantID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value returned by Encrypt with the data key version it
+// was encrypted with
+func (r *KeyRing) Decrypt(ctx context.Context, ciphertext string) ([]byte, error) {
+	tenantID, version, sealed, err := parseEncrypted(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	_, dataKey, err := r.dataKey(ctx, tenantID, version)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, sealed, dataAAD(tenantID, version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt tenant %s data: %w", tenantID, err)
+	}
+
+	// Data written with a newer version than the cached active one means
+	// another instance rotated the key
+	r.mu.Lock()
+	if active, ok := r.active[tenantID]; ok && active.version < version {
+		delete(r.active, tenantID)
+	}
+	r.mu.Unlock()
+	return plaintext, nil
+}
+
+// Rotate creates a new active data key version for the tenant and re-wraps
+// the previous versions with kmsKeyID, or their current KMS key when empty.
+// Existing data is not re-encrypted: it stays readable with the re-wrapped
+// versions, while new writes use the new version. Other key rings sharing the
+// store switch to the new version within activeKeyTTL.
+func (r *KeyRing) Rotate(ctx context.Context, tenantID, kmsKeyID string) (*TenantKey, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	keys, err := r.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant keys: %w", err)
+	}
+	if kmsKeyID == "" {
+		kmsKeyID = r.kmsKeyID
+		for _, key := range keys {
+			if key.Active {
+				kmsKeyID = key.KMSKeyID
+			}
+		}
+	}
+
+	now := r.now()
+	unwrapped := make(map[int]unwrappedKey, len(keys)+1)
+	latest := 0
+	for i := range keys {
+		key := &keys[i]
+		dataKey, err := r.kms.UnwrapKey(ctx, key.KMSKeyID, key.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap tenant key version %d: %w", key.Version, err)
+		}
+		wrapped, err := r.kms.WrapKey(ctx, kmsKeyID, dataKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to re-wrap tenant key version %d: %w", key.Version, err)
+		}
+		key.KMSKeyID = kmsKeyID
+		key.WrappedKey = wrapped
+		key.Active = false
+		key.RewrappedAt = &now
+		unwrapped[key.Version] = unwrappedKey{key: *key, dataKey: dataKey}
+		if key.Version > latest {
+			latest = key.Version
+		}
+	}
+
+	key, dataKey, err := r.newKey(ctx, tenantID, latest+1, kmsKeyID, now)
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, *key)
+	if err := r.store.SaveTenantKeys(ctx, keys); err != nil {
+		return nil, fmt.Errorf("failed to save tenant keys: %w", err)
+	}
+
+	unwrapped[key.Version] = unwrappedKey{key: *key, dataKey: dataKey}
+	r.mu.Lock()
+	r.keys[tenantID] = unwrapped
+	r.active[tenantID] = activeVersion{version: key.Version, loadedAt: now}
+	r.mu.Unlock()
+
+	for _, previous := range keys[:len(keys)-1] {
+		r.audit(ctx, previous, KeyRewrapped)
+	}
+	r.audit(ctx, *key, KeyRotated)
+	return key, nil
+}
+
+// activeKey returns the tenant's active data key, creating the first one. The
+// active version is reloaded from the store once it is older than
+// activeKeyTTL.
+func (r *KeyRing) activeKey(ctx context.Context, tenantID string) (TenantKey, []byte, error) {
+	r.mu.Lock()
+	active, ok := r.active[tenantID]
+	cached, unwrapped := r.keys[tenantID][active.version]
+	r.mu.Unlock()
+	if ok && unwrapped && r.now().Sub(active.loadedAt) < activeKeyTTL {
+		return cached.key, cached.dataKey, nil
+	}
+
+	keys, err := r.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return TenantKey{}, nil, fmt.Errorf("failed to load tenant keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return r.createKey(ctx, tenantID)
+	}
+	return r.loadActive(ctx, keys)
+}
+
+// loadActive unwraps and caches the active version among a tenant's keys
+func (r *KeyRing) loadActive(ctx context.Context, keys []TenantKey) (TenantKey, []byte, error) {
+	for _, key := range keys {
+		if !key.Active {
+			continue
+		}
+		key, dataKey, err := r.cachedOrUnwrap(ctx, key)
+		if err != nil {
+			return TenantKey{}, nil, err
+		}
+		r.mu.Lock()
+		r.active[key.TenantID] = activeVersion{version: key.Version, loadedAt: r.now()}
+		r.mu.Unlock()
+		return key, dataKey, nil
+	}
+	return TenantKey{}, nil, fmt.Errorf("tenant %s has no active data key", keys[0].TenantID)
+}
+
+// createKey creates the tenant's first data key, unless a concurrent caller
+// already did
+func (r *KeyRing) createKey(ctx context.Context, tenantID string) (TenantKey, []byte, error) {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	keys, err := r.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return TenantKey{}, nil, fmt.Errorf("failed to load tenant keys: %w", err)
+	}
+	if len(keys) > 0 {
+		return r.loadActive(ctx, keys)
+	}
+
+	now := r.now()
+	key, dataKey, err := r.newKey(ctx, tenantID, 1, r.kmsKeyID, now)
+	if err != nil {
+		return TenantKey{}, nil, err
+	}
+	if err := r.store.SaveTenantKeys(ctx, []TenantKey{*key}); err != nil {
+		return TenantKey{}, nil, fmt.Errorf("failed to save tenant key: %w", err)
+	}
+	r.mu.Lock()
+	r.cache(*key, dataKey)
+	r.active[tenantID] = activeVersion{version: key.Version, loadedAt: now}
+	r.mu.Unlock()
+	r.audit(ctx, *key, KeyCreated)
+	return *key, dataKey, nil
+}
+
+// dataKey returns a version of the tenant's data key
+func (r *KeyRing) dataKey(ctx context.Context, tenantID string, version int) (TenantKey, []byte, error) {
+	r.mu.Lock()
+	cached, ok := r.keys[tenantID][version]
+	r.mu.Unlock()
+	if ok {
+		return cached.key, cached.dataKey, nil
+	}
+
+	keys, err := r.store.ListTenantKeys(ctx, tenantID)
+	if err != nil {
+		return TenantKey{}, nil, fmt.Errorf("failed to load tenant keys: %w", err)
+	}
+	for _, key := range keys {
+		if key.Version == version {
+			return r.cachedOrUnwrap(ctx, key)
+		}
+	}
+	return TenantKey{}, nil, fmt.Errorf("%w: tenant %s version %d", ErrNoDataKey, tenantID, version)
+}
+
+// cachedOrUnwrap returns a data key version from the cache, or unwraps it
+// with the KMS and caches it
+func (r *KeyRing) cachedOrUnwrap(ctx context.Context, key TenantKey) (TenantKey, []byte, error) {
+	r.mu.Lock()
+	cached, ok := r.keys[key.TenantID][key.Version]
+	r.mu.Unlock()
+	if ok {
+		return cached.key, cached.dataKey, nil
+	}
+
+	dataKey, err := r.kms.UnwrapKey(ctx, key.KMSKeyID, key.WrappedKey)
+	if err != nil {
+		return TenantKey{}, nil, fmt.Errorf("failed to unwrap tenant key version %d: %w", key.Version, err)
+	}
+	r.mu.Lock()
+	r.cache(key, dataKey)
+	r.mu.Unlock()
+	r.audit(ctx, key, KeyUnwrapped)
+	return key, dataKey, nil
+}
+
+// newKey generates and wraps a data key version
+func (r *KeyRing) newKey(ctx context.Context, tenantID string, version int, kmsKeyID string, now time.Time) (*TenantKey, []byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := r.kms.WrapKey(ctx, kmsKeyID, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return &TenantKey{
+		TenantID:   tenantID,
+		Version:    version,
+		KMSKeyID:   kmsKeyID,
+		WrappedKey: wrapped,
+		Active:     true,
+		CreatedAt:  now,
+	}, dataKey, nil
+}
+
+// cache stores an unwrapped data key version. Callers hold r.mu.
+func (r *KeyRing) cache(key TenantKey, dataKey []byte) {
+	if r.keys[key.TenantID] == nil {
+		r.keys[key.TenantID] = make(map[int]unwrappedKey)
+	}
+	r.keys[key.TenantID][key.Version] = unwrappedKey{key: key, dataKey: dataKey}
+}
+
+// audit records a key usage event; audit failures do not fail the operation
+func (r *KeyRing) audit(ctx context.Context, key TenantKey, operation KeyOperation) {
+	if r.auditor == nil {
+		return
+	}
+	r.auditor.RecordKeyUsage(ctx, KeyUsageEvent{
+		TenantID:   key.TenantID,
+		KeyVersion: key.Version,
+		KMSKeyID:   key.KMSKeyID,
+		Operation:  operation,
+		At:         r.now(),
+	})
+}
+
+// IsEncrypted reports whether a value was produced by KeyRing.Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// parseEncrypted splits an encrypted value into tenant, key version and
+// sealed bytes
+func parseEncrypted(value string) (string, int, []byte, error) {
+	if !IsEncrypted(value) {
+		return "", 0, nil, fmt.Errorf("value is not encrypted")
+	}
+	rest := strings.TrimPrefix(value, encryptedPrefix)
+	versionText, rest, _ := strings.Cut(rest, ":")
+	sep := strings.LastIndex(rest, ":")
+	version, err := strconv.Atoi(versionText)
+	if err != nil || sep <= 0 {
+		return "", 0, nil, fmt.Errorf("malformed encrypted value")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(rest[sep+1:])
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	return rest[:sep], version, sealed, nil
+}
+
+// dataAAD binds ciphertext to its tenant and key version
+func dataAAD(tenantID string, version int) []byte {
+	return []byte(tenantID + ":" + strconv.Itoa(version))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce prepended to the ciphertext
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
+
+// TenantResolver resolves the tenant that owns a project
+type TenantResolver interface {
+	ProjectTenant(ctx context.Context, projectID string) (string, error)
+}
+
+type tenantKey struct{}
+
+// withTenant returns a context whose storage writes are encrypted with the
+// tenant's data key
+func withTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+func tenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey{}).(string)
+	return tenantID
+}
+
+// SetKeyRing enables encryption at rest with per-tenant data keys. When the
+// storage config enables encryption, document and chunk content is encrypted
+// on write with the key of the project's tenant and decrypted on read.
+// Storage installed later by SetRegionalStorage is wrapped as well.
+func (p *Pipeline) SetKeyRing(ring *KeyRing, tenants TenantResolver) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keyRing = ring
+	p.tenants = tenants
+	p.storage = p.encryptedStorage(p.storage)
+}
+
+// encryptedStorage wraps storage with tenant encryption when encryption at
+// rest is enabled. Callers hold p.mu.
+func (p *Pipeline) encryptedStorage(storage Storage) Storage {
+	if !p.config.Storage.EnableEncryption || p.keyRing == nil || storage == nil {
+		return storage
+	}
+	if _, wrapped := storage.(*EncryptedStorage); wrapped {
+		return storage
+	}
+	return NewEncryptedStorage(storage, p.keyRing, p.tenants)
+}
+
+// KeyRing returns the key ring set by SetKeyRing, or nil
+func (p *Pipeline) KeyRing() *KeyRing {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.keyRing
+}
+
+// tenantContext scopes ctx to the tenant owning the project, so writes are
+// encrypted with its data key
+func (p *Pipeline) tenantContext(ctx context.Context, projectID string) (context.Context, error) {
+	p.mu.RLock()
+	tenants := p.tenants
+	p.mu.RUnlock()
+	if tenants == nil || projectID == "" {
+		return ctx, nil
+	}
+	tenantID, err := tenants.ProjectTenant(ctx, projectID)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to resolve tenant of project %s: %w", projectID, err)
+	}
+	if tenantID == "" {
+		return ctx, nil
+	}
+	return withTenant(ctx, tenantID), nil
+}
+
+// ErrNoTenant is returned when content is written to encrypted storage
+// without a tenant to encrypt it for
+var ErrNoTenant = errors.New("encryption at rest requires a tenant")
+
+// EncryptedStorage encrypts document and chunk content with the data key of
+// its tenant: the context's tenant, or else the tenant owning the project of
+// the document. Writes whose tenant cannot be resolved fail rather than store
+// plaintext. Encrypted content is decrypted on read whatever the context.
+type EncryptedStorage struct {
+	Storage
+	ring    *KeyRing
+	tenants TenantResolver
+}
+
+// NewEncryptedStorage wraps storage with tenant encryption. tenants may be
+// nil when every write carries its tenant in the context.
+func NewEncryptedStorage(storage Storage, ring *KeyRing, tenants TenantResolver) *EncryptedStorage {
+	return &EncryptedStorage{Storage: storage, ring: ring, tenants: tenants}
+}
+
+// encrypt encrypts content for the tenant of the context or project.
+// Already encrypted content, e.g. copied from storage, is kept as is.
+func (s *EncryptedStorage) encrypt(ctx context.Context, projectID func() string, content string) (string, error) {
+	if content == "" || IsEncrypted(content) {
+		return content, nil
+	}
+	tenantID := tenantFromContext(ctx)
+	if tenantID == "" && s.tenants != nil {
+		if project := projectID(); project != "" {
+			var err error
+			tenantID, err = s.tenants.ProjectTenant(ctx, project)
+			if err != nil {
+				return "", fmt.Errorf("failed to resolve tenant of project %s: %w", project, err)
+			}
+		}
+	}
+	if tenantID == "" {
+		return "", ErrNoTenant
+	}
+	return s.ring.Encrypt(ctx, tenantID, []byte(content))
+}
+
+// chunkProjectID returns the project of a chunk from its metadata, or else
+// from its stored document
+func (s *EncryptedStorage) chunkProjectID(ctx context.Context, chunk DocumentChunk) string {
+	if projectID, _ := chunk.Metadata["project_id"].(string); projectID != "" {
+		return projectID
+	}
+	doc, err := s.Storage.GetDocument(ctx, chunk.DocumentID)
+	if err != nil || doc == nil {
+		return ""
+	}
+	return documentProjectID(*doc)
+}
+
+// decrypt decrypts encrypted content, leaving plain content unchanged
+func (s *EncryptedStorage) decrypt(ctx context.Context, content string) (string, error) {
+	if !IsEncrypted(content) {
+		return content, nil
+	}
+	plaintext, err := s.ring.Decrypt(ctx, content)
+	return string(plaintext), err
+}
+
+// StoreDocument implements the Storage interface
+func (s *EncryptedStorage) StoreDocument(ctx context.Context, doc Document) error {
+	content, err := s.encrypt(ctx, func() string { return documentProjectID(doc) }, doc.Content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt document %s: %w", doc.ID, err)
+	}
+	doc.Content = content
+	return s.Storage.StoreDocument(ctx, doc)
+}
+
+// GetDocument implements the Storage interface
+func (s *EncryptedStorage) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	doc, err := s.Storage.GetDocument(ctx, documentID)
+	if err != nil || doc == nil {
+		return doc, err
+	}
+	if doc.Content, err = s.decrypt(ctx, doc.Content); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ListDocuments implements the Storage interface
+func (s *EncryptedStorage) ListDocuments(ctx context.Context, options ListOptions) ([]Document, error) {
+	docs, err := s.Storage.ListDocuments(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	for i := range docs {
+		if docs[i].Content, err = s.decrypt(ctx, docs[i].Content); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// StoreChunk implements the Storage interface
+func (s *EncryptedStorage) StoreChunk(ctx context.Context, chunk DocumentChunk) error {
+	content, err := s.encrypt(ctx, func() string { return s.chunkProjectID(ctx, chunk) }, chunk.Content)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt chunk %s: %w", chunk.ID, err)
+	}
+	chunk.Content = content
+	return s.Storage.StoreChunk(ctx, chunk)
+}
+
+// GetChunk implements the Storage interface
+func (s *EncryptedStorage) GetChunk(ctx context.Context, chunkID string) (*DocumentChunk, error) {
+	chunk, err := s.Storage.GetChunk(ctx, chunkID)
+	if err != nil || chunk == nil {
+		return chunk, err
+	}
+	if chunk.Content, err = s.decrypt(ctx, chunk.Content); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// ListChunks implements the Storage interface
+func (s *EncryptedStorage) ListChunks(ctx context.Context, documentID string) ([]DocumentChunk, error) {
+	chunks, err := s.Storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chunks {
+		if chunks[i].Content, err = s.decrypt(ctx, chunks[i].Content); err != nil {
+			return nil, err
+		}
+	}
+	return chunks, nil
+}
@@ -0,0 +1,433 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryKeyStore keeps tenant keys in memory and records key usage
+type memoryKeyStore struct {
+	keys   map[string][]TenantKey
+	events []KeyUsageEvent
+}
+
+func (s *memoryKeyStore) ListTenantKeys(ctx context.Context, tenantID string) ([]TenantKey, error) {
+	return append([]TenantKey(nil), s.keys[tenantID]...), nil
+}
+
+func (s *memoryKeyStore) SaveTenantKeys(ctx context.Context, keys []TenantKey) error {
+	for _, key := range keys {
+		saved := s.keys[key.TenantID]
+		replaced := false
+		for i := range saved {
+			if saved[i].Version == key.Version {
+				saved[i], replaced = key, true
+			}
+		}
+		if !replaced {
+			saved = append(saved, key)
+		}
+		s.keys[key.TenantID] = saved
+	}
+	return nil
+}
+
+func (s *memoryKeyStore) RecordKeyUsage(ctx context.Context, event KeyUsageEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryKeyStore) operations() string {
+	ops := make([]string, len(s.events))
+	for i, event := range s.events {
+		ops[i] = string(event.Operation)
+	}
+	return strings.Join(ops, ",")
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	ctx := context.Background()
+	kms, err := NewLocalKMS("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &memoryKeyStore{keys: map[string][]TenantKey{}}
+	ring := NewKeyRing(kms, store, store, "")
+
+	old, err := ring.Encrypt(ctx, "t1", []byte("before rotation"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(old) || strings.Contains(old, "before rotation") {
+		t.Fatalf("expected ciphertext, got %q", old)
+	}
+
+	key, err := ring.Rotate(ctx, "t1", "tenant-kms-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Version != 2 || !key.Active || key.KMSKeyID != "tenant-kms-key" {
+		t.Fatalf("unexpected rotated key %+v", key)
+	}
+	keys, _ := ring.Keys(ctx, "t1")
+	if len(keys) != 2 || keys[0].Active || keys[0].KMSKeyID != "tenant-kms-key" || keys[0].RewrappedAt == nil {
+		t.Fatalf("expected the old version to be re-wrapped and inactive, got %+v", keys)
+	}
+
+	// A fresh key ring reads data written before the rotation from the store
+	ring = NewKeyRing(kms, store, store, "")
+	if plaintext, err := ring.Decrypt(ctx, old); err != nil || string(plaintext) != "before rotation" {
+		t.Fatalf("expected old data to stay readable, got %q %v", plaintext, err)
+	}
+	current, err := ring.Encrypt(ctx, "t1", []byte("after rotation"))
+	if err != nil || !strings.HasPrefix(current, encryptedPrefix+"2:t1:") {
+		t.Fatalf("expected new writes to use version 2, got %q %v", current, err)
+	}
+	if got := store.operations(); got != "create,rewrap,rotate,unwrap,unwrap" {
+		t.Fatalf("unexpected key usage events %s", got)
+	}
+
+	// Ciphertext is bound to its tenant
+	forged := strings.Replace(current, ":t1:", ":t2:", 1)
+	store.keys["t2"] = []TenantKey{store.keys["t1"][1]}
+	store.keys["t2"][0].TenantID = "t2"
+	if _, err := ring.Decrypt(ctx, forged); err == nil {
+		t.Fatal("expected ciphertext moved to another tenant to fail")
+	}
+	if _, err := ring.Decrypt(ctx, encryptedPrefix+"9:t1:AAAA"); !errors.Is(err, ErrNoDataKey) {
+		t.Fatalf("expected a missing key version error, got %v", err)
+	}
+}
+
+func TestKeyRingPicksUpRotationsFromOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	kms, _ := NewLocalKMS("secret")
+	store := &memoryKeyStore{keys: map[string][]TenantKey{}}
+	now := time.Now()
+	first, second := NewKeyRing(kms, store, store, ""), NewKeyRing(kms, store, store, "")
+	second.now = func() time.Time { return now }
+
+	if _, err := first.Encrypt(ctx, "t1", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Encrypt(ctx, "t1", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Rotate(ctx, "t1", ""); err != nil {
+		t.Fatal(err)
+	}
+	encryptedVersion := func() string {
+		t.Helper()
+		ciphertext, err := second.Encrypt(ctx, "t1", []byte("c"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimPrefix(ciphertext, encryptedPrefix), ":")[0]
+	}
+	if version := encryptedVersion(); version != "1" {
+		t.Fatalf("expected the cached version until it expires, got %s", version)
+	}
+	now = now.Add(activeKeyTTL)
+	if version := encryptedVersion(); version != "2" {
+		t.Fatalf("expected the rotated version after the cache expired, got %s", version)
+	}
+
+	// Reading data written with a newer version switches right away
+	if _, err := second.Rotate(ctx, "t1", ""); err != nil {
+		t.Fatal(err)
+	}
+	newer, err := second.Encrypt(ctx, "t1", []byte("d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Decrypt(ctx, newer); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := first.Encrypt(ctx, "t1", []byte("e")); err != nil || !strings.HasPrefix(current, encryptedPrefix+"3:t1:") {
+		t.Fatalf("expected version 3 after reading it, got %q %v", current, err)
+	}
+}
+
+// blockingKeyStore blocks listing the keys of one tenant until released
+type blockingKeyStore struct {
+	*memoryKeyStore
+	tenantID string
+	entered  chan struct{}
+	release  chan struct{}
+}
+
+func (s *blockingKeyStore) ListTenantKeys(ctx context.Context, tenantID string) ([]TenantKey, error) {
+	if tenantID == s.tenantID {
+		s.entered <- struct{}{}
+		<-s.release
+	}
+	return s.memoryKeyStore.ListTenantKeys(ctx, tenantID)
+}
+
+func TestKeyRingDoesNotHoldLockDuringStoreCalls(t *testing.T) {
+	ctx := context.Background()
+	kms, _ := NewLocalKMS("secret")
+	store := &blockingKeyStore{
+		memoryKeyStore: &memoryKeyStore{keys: map[string][]TenantKey{}},
+		tenantID:       "slow",
+		entered:        make(chan struct{}),
+		release:        make(chan struct{}),
+	}
+	ring := NewKeyRing(kms, store, nil, "")
+	if _, err := ring.Encrypt(ctx, "t1", []byte("warm")); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ring.Encrypt(ctx, "slow", []byte("a"))
+		done <- err
+	}()
+	<-store.entered
+
+	encrypted := make(chan error, 1)
+	go func() {
+		_, err := ring.Encrypt(ctx, "t1", []byte("b"))
+		encrypted <- err
+	}()
+	select {
+	case err := <-encrypted:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a cached tenant to encrypt while another tenant's keys load")
+	}
+
+	// The second store call creates the key
+	close(store.release)
+	<-store.entered
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// projectTenants resolves the tenant of a project from a map
+type projectTenants map[string]string
+
+func (t projectTenants) ProjectTenant(ctx context.Context, projectID string) (string, error) {
+	return t[projectID], nil
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	ctx := context.Background()
+	kms, _ := NewLocalKMS("secret")
+	store := &memoryKeyStore{keys: map[string][]TenantKey{}}
+	backend := newMemoryStorage()
+	storage := NewEncryptedStorage(backend, NewKeyRing(kms, store, nil, ""), projectTenants{"p1": "t1"})
+
+	if err := storage.StoreDocument(withTenant(ctx, "t1"), Document{ID: "a", Content: "secret plans"}); err != nil {
+		t.Fatal(err)
+	}
+	// Writes without a tenant in the context use the tenant of the document's project
+	if err := storage.StoreDocument(ctx, withProjectID(Document{ID: "b", Content: "roadmap"}, "p1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreChunk(ctx, DocumentChunk{ID: "b_0", DocumentID: "b", Content: "roadmap"}); err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(backend.docs["a"].Content) || !IsEncrypted(backend.docs["b"].Content) || !IsEncrypted(backend.chunks["b_0"].Content) {
+		t.Fatalf("expected tenant content to be encrypted, got %+v %+v", backend.docs, backend.chunks)
+	}
+
+	// Content without a resolvable tenant is never stored as plaintext
+	if err := storage.StoreDocument(ctx, Document{ID: "c", Content: "public notes"}); !errors.Is(err, ErrNoTenant) {
+		t.Fatalf("expected ErrNoTenant, got %v", err)
+	}
+	if err := storage.StoreChunk(ctx, DocumentChunk{ID: "c_0", DocumentID: "c", Content: "public notes"}); !errors.Is(err, ErrNoTenant) {
+		t.Fatalf("expected ErrNoTenant for a chunk, got %v", err)
+	}
+	if _, ok := backend.docs["c"]; ok {
+		t.Fatal("expected the document without a tenant not to be stored")
+	}
+
+	doc, err := storage.GetDocument(ctx, "a")
+	if err != nil || doc.Content != "secret plans" {
+		t.Fatalf("expected decrypted content, got %+v %v", doc, err)
+	}
+}
+
+func TestReembedKeepsContentEncrypted(t *testing.T) {
+	ctx := context.Background()
+	kms, _ := NewLocalKMS("secret")
+	store := &memoryKeyStore{keys: map[string][]TenantKey{}}
+	backend := newMemoryStorage()
+	config := DefaultConfig()
+	config.Storage.EnableEncryption = true
+	p := &Pipeline{config: config, storage: backend, retriever: &keywordRetriever{}, locker: NewLocalLocker()}
+	p.SetKeyRing(NewKeyRing(kms, store, nil, ""), projectTenants{"p1": "t1"})
+
+	// Ingest stores the document and its chunks for the project's tenant
+	tenantCtx, err := p.tenantContext(ctx, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := withProjectID(Document{ID: "a", Content: "quarterly revenue forecast"}, "p1")
+	if err := p.storage.StoreDocument(tenantCtx, doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.storage.StoreChunk(tenantCtx, DocumentChunk{ID: "a_0", DocumentID: "a", Content: "quarterly revenue forecast"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The migration reads chunks back decrypted and stores them without a tenant context
+	target := &keywordRetriever{}
+	if _, err := p.StartReembed(ctx, ReembedOptions{
+		Target:    EmbeddingConfig{Model: "words"},
+		Generator: &wordGenerator{vocabulary: []string{"revenue"}},
+		Retriever: target,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); p.GetReembedJob().Status == ReembedStatusRunning; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("re-embedding did not finish")
+		}
+	}
+	if job := p.GetReembedJob(); job.Status != ReembedStatusCompleted || job.ChunksMigrated != 1 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	stored := backend.chunks["a_0"]
+	if !IsEncrypted(stored.Content) || strings.Contains(stored.Content, "revenue") || len(stored.Embedding) == 0 {
+		t.Fatalf("expected the re-embedded chunk to stay encrypted, got %+v", stored)
+	}
+	if chunk, err := p.storage.GetChunk(ctx, "a_0"); err != nil || chunk.Content != "quarterly revenue forecast" {
+		t.Fatalf("expected the chunk to decrypt, got %+v %v", chunk, err)
+	}
+}
+
+// memoryStorage keeps documents, chunks and embeddings in memory
+type memoryStorage struct {
+	Storage
+	mu         sync.Mutex
+	docs       map[string]Document
+	chunks     map[string]DocumentChunk
+	embeddings map[string][]float64
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		docs:       map[string]Document{},
+		chunks:     map[string]DocumentChunk{},
+		embeddings: map[string][]float64{},
+	}
+}
+
+func (s *memoryStorage) StoreDocument(ctx context.Context, doc Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[doc.ID] = doc
+	return nil
+}
+
+func (s *memoryStorage) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[documentID]
+	if !ok {
+		return nil, fmt.Errorf("document %s not found", documentID)
+	}
+	return &doc, nil
+}
+
+func (s *memoryStorage) ListDocuments(ctx context.Context, options ListOptions) ([]Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	docs := make([]Document, 0, len(s.docs))
+	for _, doc := range s.docs {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+	return docs, nil
+}
+
+func (s *memoryStorage) DeleteDocument(ctx context.Context, documentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.docs, documentID)
+	for id, chunk := range s.chunks {
+		if chunk.DocumentID == documentID {
+			delete(s.chunks, id)
+			delete(s.embeddings, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStorage) StoreChunk(ctx context.Context, chunk DocumentChunk) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chunks[chunk.ID] = chunk
+	return nil
+}
+
+func (s *memoryStorage) GetChunk(ctx context.Context, chunkID string) (*DocumentChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunk, ok := s.chunks[chunkID]
+	if !ok {
+		return nil, fmt.Errorf("chunk %s not found", chunkID)
+	}
+	return &chunk, nil
+}
+
+func (s *memoryStorage) ListChunks(ctx context.Context, documentID string) ([]DocumentChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var chunks []DocumentChunk
+	for _, chunk := range s.chunks {
+		if chunk.DocumentID == documentID {
+			chunks = append(chunks, chunk)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+	return chunks, nil
+}
+
+func (s *memoryStorage) ListAllChunks(ctx context.Context) ([]DocumentChunk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunks := make([]DocumentChunk, 0, len(s.chunks))
+	for _, chunk := range s.chunks {
+		chunks = append(chunks, chunk)
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ID < chunks[j].ID })
+	return chunks, nil
+}
+
+func (s *memoryStorage) DeleteChunk(ctx context.Context, chunkID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chunks, chunkID)
+	delete(s.embeddings, chunkID)
+	return nil
+}
+
+func (s *memoryStorage) StoreEmbedding(ctx context.Context, chunkID string, embedding []float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.embeddings[chunkID] = embedding
+	if chunk, ok := s.chunks[chunkID]; ok {
+		chunk.Embedding = embedding
+		s.chunks[chunkID] = chunk
+	}
+	return nil
+}
+
+func (s *memoryStorage) GetEmbedding(ctx context.Context, chunkID string) ([]float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.embeddings[chunkID], nil
+}
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// RetrievalMethod is one search method of a fan-out retriever, e.g. vector,
+// keyword or graph search
+type RetrievalMethod struct {
+	Name      string
+	Retriever Retriever
+	Weight    float64 // Multiplies the method's scores, defaults to 1
+}
+
+// FanOutOptions tune a fan-out retriever
+type FanOutOptions struct {
+	// Stop waiting for slower methods once TopK merged results score at
+	// least this much; 0 always waits for every method
+	EarlyStopScore float64 `json:"early_stop_score"`
+}
+
+// FanOutRetriever runs its search methods in parallel and merges their
+// results as they arrive. A chunk found by several methods scores the
+// weighted sum of their scores. A failing method is skipped; retrieval fails
+// only if all methods do.
+type FanOutRetriever struct {
+	methods []RetrievalMethod
+	options FanOutOptions
+}
+
+// methodResults are the results of one search method
+type methodResults struct {
+	method  RetrievalMethod
+	results []RetrievalResult
+	err     error
+}
+
+// NewFanOutRetriever creates a retriever fanning out to the given methods
+func NewFanOutRetriever(options FanOutOptions, methods ...RetrievalMethod) *FanOutRetriever {
+	for i := range methods {
+		if methods[i].Weight == 0 {
+			methods[i].Weight = 1
+		}
+	}
+	return &FanOutRetriever{methods: methods, options: options}
+}
+
+// Retrieve searches with every method concurrently
+func (r *FanOutRetriever) Retrieve(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	if len(r.methods) == 0 {
+		return nil, fmt.Errorf("fan-out retriever has no search methods")
+	}
+
+	// Cancelled on return so methods still running after an early stop give up
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so methods finishing after an early stop never block
+	arrivals := make(chan methodResults, len(r.methods))
+	var group errgroup.Group
+	for _, method := range r.methods {
+		method := method
+		group.Go(func() error {
+			results, err := method.Retriever.Retrieve(ctx, query, options)
+			arrivals <- methodResults{method: method, results: results, err: err}
+			return nil
+		})
+	}
+	go func() {
+		group.Wait()
+		close(arrivals)
+	}()
+
+	merged := make(map[string]*RetrievalResult)
+	var order []string
+	var failures []string
+	for arrival := range arrivals {
+		if arrival.err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", arrival.method.Name, arrival.err))
+			continue
+		}
+		for _, result := range arrival.results {
+			key := result.DocumentID
+			if result.Chunk != nil {
+				key = result.Chunk.ID
+			}
+			existing, ok := merged[key]
+			if !ok {
+				result.Score *= arrival.method.Weight
+				result.Method = arrival.method.Name
+				merged[key] = &result
+				order = append(order, key)
+				continue
+			}
+			existing.Score += result.Score * arrival.method.Weight
+			existing.Method += "+" + arrival.method.Name
+			if result.Similarity > existing.Similarity {
+				existing.Similarity = result.Similarity
+			}
+			if result.KeywordScore > existing.KeywordScore {
+				existing.KeywordScore = result.KeywordScore
+			}
+			existing.Matches = append(existing.Matches, result.Matches...)
+		}
+		if r.enough(merged, options.TopK) {
+			break
+		}
+	}
+
+	if len(order) == 0 && len(failures) > 0 && len(failures) == len(r.methods) {
+		return nil, fmt.Errorf("all search methods failed: %s", strings.Join(failures, "; "))
+	}
+
+	results := make([]RetrievalResult, 0, len(order))
+	for _, key := range order {
+		results = append(results, *merged[key])
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if options.TopK > 0 && len(results) > options.TopK {
+		results = results[:options.TopK]
+	}
+	for i := range results {
+		results[i].Position = i
+	}
+	return results, nil
+}
+
+// enough reports whether topK merged results already reach the early stop
+// score
+func (r *FanOutRetriever) enough(merged map[string]*RetrievalResult, topK int) bool {
+	if r.options.EarlyStopScore <= 0 || topK <= 0 {
+		return false
+	}
+	strong := 0
+	for _, result := range merged {
+		if result.Score >= r.options.EarlyStopScore {
+			strong++
+		}
+	}
+	return strong >= topK
+}
+
+// AddDocument indexes the chunk with every method
+func (r *FanOutRetriever) AddDocument(ctx context.Context, chunk DocumentChunk) error {
+	return r.each(func(method RetrievalMethod) error {
+		return method.Retriever.AddDocument(ctx, chunk)
+	})
+}
+
+// RemoveDocument removes the chunk from every method
+func (r *FanOutRetriever) RemoveDocument(ctx context.Context, chunkID string) error {
+	return r.each(func(method RetrievalMethod) error {
+		return method.Retriever.RemoveDocument(ctx, chunkID)
+	})
+}
+
+// UpdateDocument updates the chunk in every method
+func (r *FanOutRetriever) UpdateDocument(ctx context.Context, chunk DocumentChunk) error {
+	return r.each(func(method RetrievalMethod) error {
+		return method.Retriever.UpdateDocument(ctx, chunk)
+	})
+}
+
+// Clear clears every method
+func (r *FanOutRetriever) Clear(ctx context.Context) error {
+	return r.each(func(method RetrievalMethod) error {
+		return method.Retriever.Clear(ctx)
+	})
+}
+
+// GetStats combines the statistics of the methods
+func (r *FanOutRetriever) GetStats() (*RetrieverStats, error) {
+	combined := &RetrieverStats{IndexType: "fanout"}
+	for _, method := range r.methods {
+		stats, err := method.Retriever.GetStats()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", method.Name, err)
+		}
+		combined.TotalDocuments = max(combined.TotalDocuments, stats.TotalDocuments)
+		combined.TotalChunks = max(combined.TotalChunks, stats.TotalChunks)
+		combined.IndexedChunks = max(combined.IndexedChunks, stats.IndexedChunks)
+		combined.EmbeddingDim = max(combined.EmbeddingDim, stats.EmbeddingDim)
+		combined.VectorIndexSize += stats.VectorIndexSize
+		combined.QueriesProcessed = max(combined.QueriesProcessed, stats.QueriesProcessed)
+		combined.AvgRetrievalTime = max(combined.AvgRetrievalTime, stats.AvgRetrievalTime)
+		if stats.LastIndexed.After(combined.LastIndexed) {
+			combined.LastIndexed = stats.LastIndexed
+		}
+	}
+	return combined, nil
+}
+
+// each applies fn to every method concurrently, failing with the first error
+func (r *FanOutRetriever) each(fn func(method RetrievalMethod) error) error {
+	var group errgroup.Group
+	for _, method := range r.methods {
+		method := method
+		group.Go(func() error {
+			if err := fn(method); err != nil {
+				return fmt.Errorf("%s: %w", method.Name, err)
+			}
+			return nil
+		})
+	}
+	return group.Wait()
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// delayedRetriever returns its results after a delay, or fails
+type delayedRetriever struct {
+	keywordRetriever
+	results []RetrievalResult
+	delay   time.Duration
+	err     error
+}
+
+func (r *delayedRetriever) Retrieve(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return r.results, r.err
+}
+
+func TestFanOutRetriever(t *testing.T) {
+	ctx := context.Background()
+	result := func(chunkID string, score float64) RetrievalResult {
+		return RetrievalResult{DocumentID: chunkID, Chunk: &DocumentChunk{ID: chunkID}, Score: score}
+	}
+	vector := &delayedRetriever{results: []RetrievalResult{result("a", 0.9), result("b", 0.5)}}
+	keyword := &delayedRetriever{results: []RetrievalResult{result("b", 1), result("c", 0.2)}}
+	retriever := NewFanOutRetriever(FanOutOptions{},
+		RetrievalMethod{Name: "vector", Retriever: vector, Weight: 0.7},
+		RetrievalMethod{Name: "keyword", Retriever: keyword, Weight: 0.3},
+	)
+
+	results, err := retriever.Retrieve(ctx, "q", RetrieveOptions{TopK: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].Chunk.ID != "b" || results[1].Chunk.ID != "a" || !strings.Contains(results[0].Method, "+") {
+		t.Fatalf("unexpected merged results %+v", results)
+	}
+	if score := results[0].Score; score < 0.649 || score > 0.651 {
+		t.Fatalf("expected weighted sum 0.65 for b, got %v", score)
+	}
+
+	// A failing method is skipped
+	keyword.err = errors.New("index unavailable")
+	if results, err := retriever.Retrieve(ctx, "q", RetrieveOptions{TopK: 10}); err != nil || len(results) != 2 {
+		t.Fatalf("expected vector results only, got %+v %v", results, err)
+	}
+	vector.err = errors.New("index unavailable")
+	if _, err := retriever.Retrieve(ctx, "q", RetrieveOptions{TopK: 10}); err == nil {
+		t.Fatal("expected retrieval to fail when every method does")
+	}
+
+	// Enough strong candidates stop waiting for a slow method
+	slow := &delayedRetriever{results: []RetrievalResult{result("z", 1)}, delay: time.Minute}
+	fast := &delayedRetriever{results: []RetrievalResult{result("a", 0.9), result("b", 0.8)}}
+	retriever = NewFanOutRetriever(FanOutOptions{EarlyStopScore: 0.75},
+		RetrievalMethod{Name: "graph", Retriever: slow},
+		RetrievalMethod{Name: "vector", Retriever: fast},
+	)
+	start := time.Now()
+	results, err = retriever.Retrieve(ctx, "q", RetrieveOptions{TopK: 2})
+	if err != nil || len(results) != 2 || time.Since(start) > 10*time.Second {
+		t.Fatalf("expected an early stop, got %+v %v", results, err)
+	}
+}
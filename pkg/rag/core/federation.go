@@ -0,0 +1,239 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FederationOptions fans a query out to several projects and merges their
+// results. Each project is searched with its own overrides, within its own
+// data sources.
+type FederationOptions struct {
+	Projects []FederatedProject `json:"projects"`
+
+	// Most results a project contributes before the others are considered,
+	// defaults to an even share of MaxResults. Unused slots are backfilled
+	// with the best remaining results of any project.
+	PerProjectQuota int `json:"per_project_quota,omitempty"`
+}
+
+// FederatedProject is a project searched by a federated query
+type FederatedProject struct {
+	ProjectID     string   `json:"project_id"`
+	DataSourceIDs []string `json:"data_source_ids"` // Sources holding the project's documents
+	Quota         int      `json:"quota,omitempty"` // Overrides PerProjectQuota
+}
+
+// FederatedProjectResult reports one project's part in a federated query
+type FederatedProjectResult struct {
+	ProjectID string `json:"project_id"`
+	Retrieved int    `json:"retrieved"` // Results found in the project
+	Returned  int    `json:"returned"`  // Results kept after merging
+	Error     string `json:"error,omitempty"`
+}
+
+// retrieveFederated searches every project of the federation and merges the
+// results under the per-project quotas. A failing project is reported and
+// skipped; the query fails only if all projects do.
+func (p *Pipeline) retrieveFederated(ctx context.Context, query string, options QueryOptions) ([]RetrievalResult, []FederatedProjectResult, error) {
+	federation := options.Federation
+	if len(federation.Projects) == 0 {
+		return nil, nil, fmt.Errorf("federated query has no projects")
+	}
+
+	perProject := make([][]RetrievalResult, len(federation.Projects))
+	reports := make([]FederatedProjectResult, len(federation.Projects))
+	var wg sync.WaitGroup
+	for i, project := range federation.Projects {
+		wg.Add(1)
+		go func(i int, project FederatedProject) {
+			defer wg.Done()
+			reports[i].ProjectID = project.ProjectID
+			results, err := p.searchProject(ctx, query, project, options)
+			if err != nil {
+				reports[i].Error = err.Error()
+				p.emitError(ctx, "federated_search", fmt.Errorf("project %s: %w", project.ProjectID, err))
+				return
+			}
+			perProject[i] = results
+			reports[i].Retrieved = len(results)
+		}(i, project)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, report := range reports {
+		if report.Error != "" {
+			failed++
+		}
+	}
+	if failed == len(reports) {
+		return nil, reports, fmt.Errorf("all %d federated projects failed: %s", failed, reports[0].Error)
+	}
+
+	quotas := make([]int, len(federation.Projects))
+	share := federation.PerProjectQuota
+	if share <= 0 {
+		share = (options.MaxResults + len(quotas) - 1) / len(quotas)
+	}
+	for i, project := range federation.Projects {
+		quotas[i] = share
+		if project.Quota > 0 {
+			quotas[i] = project.Quota
+		}
+	}
+
+	merged := mergeFederated(perProject, quotas, options.MaxResults)
+	for _, result := range merged {
+		for i := range reports {
+			if reports[i].ProjectID == result.ProjectID {
+				reports[i].Returned++
+			}
+		}
+	}
+	return merged, reports, nil
+}
+
+// searchProject retrieves and filters a project's results with its overrides,
+// restricted to its data sources, and tags them with the project
+func (p *Pipeline) searchProject(ctx context.Context, query string, project FederatedProject, options QueryOptions) ([]RetrievalResult, error) {
+	if len(project.DataSourceIDs) == 0 {
+		return nil, nil
+	}
+	projectConfig, err := p.loadProjectConfig(ctx, project.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	options.ProjectID = project.ProjectID
+	options.Federation = nil
+	projectConfig.ApplyToQuery(&options)
+
+	// Scope to the project's sources on top of the caller's filter. Ranking
+	// waits for the merged results, so scores stay comparable across projects.
+	options.EnableRerank = false
+	sources := intersectStrings(options.RetrievalOptions.FilterOptions.DataSourceIDs, project.DataSourceIDs)
+	if len(sources) == 0 {
+		return nil, nil
+	}
+	options.RetrievalOptions.FilterOptions.DataSourceIDs = sources
+
+	results, err := p.retrieveDocuments(ctx, query, options.RetrievalOptions)
+	if err != nil {
+		return nil, err
+	}
+	results = p.scopeResults(ctx, results, sources)
+	if options.AsOf != nil {
+		if results, err = p.resolveVersionsAsOf(ctx, results, *options.AsOf); err != nil {
+			return nil, err
+		}
+	}
+	if results, err = p.filterAndRankResults(ctx, query, results, options); err != nil {
+		p.emitError(ctx, "filter_rank_results", err)
+	}
+	for i := range results {
+		results[i].ProjectID = project.ProjectID
+	}
+	return results, nil
+}
+
+// scopeResults keeps the results whose document belongs to one of the data
+// sources. Retrievers may ignore the pushed-down sources, so documents are
+// loaded when a result lacks one, and results that cannot be placed are dropped.
+func (p *Pipeline) scopeResults(ctx context.Context, results []RetrievalResult, sources []string) []RetrievalResult {
+	allowed := make(map[string]bool, len(sources))
+	for _, id := range sources {
+		allowed[id] = true
+	}
+
+	scoped := results[:0]
+	for _, result := range results {
+		if result.Document == nil && p.storage != nil {
+			id := result.DocumentID
+			if id == "" && result.Chunk != nil {
+				id = result.Chunk.DocumentID
+			}
+			doc, err := p.GetDocument(ctx, id)
+			if err != nil {
+				p.emitError(ctx, "federated_scope", err)
+			}
+			result.Document = doc
+		}
+		if result.Document != nil && allowed[result.Document.DataSourceID] {
+			scoped = append(scoped, result)
+		}
+	}
+	return scoped
+}
+
+// mergeFederated merges per-project results by score. Each project first
+// contributes up to its quota; remaining slots go to the best leftovers.
+func mergeFederated(perProject [][]RetrievalResult, quotas []int, maxResults int) []RetrievalResult {
+	byScore := func(results []RetrievalResult) {
+		sort.SliceStable(results, func(a, b int) bool { return results[a].Score > results[b].Score })
+	}
+
+	var admitted, leftover []RetrievalResult
+	for i, results := range perProject {
+		ranked := append([]RetrievalResult(nil), results...)
+		byScore(ranked)
+		quota := quotas[i]
+		if quota > len(ranked) {
+			quota = len(ranked)
+		}
+		admitted = append(admitted, ranked[:quota]...)
+		leftover = append(leftover, ranked[quota:]...)
+	}
+
+	byScore(admitted)
+	if len(admitted) > maxResults {
+		admitted = admitted[:maxResults]
+	}
+	if len(admitted) < maxResults {
+		byScore(leftover)
+		for _, result := range leftover {
+			if len(admitted) >= maxResults {
+				break
+			}
+			admitted = append(admitted, result)
+		}
+		byScore(admitted)
+	}
+	return admitted
+}
+
+// intersectStrings returns the items of scope also in constraint, or scope
+// when there is no constraint
+func intersectStrings(constraint, scope []string) []string {
+	if len(constraint) == 0 {
+		return scope
+	}
+	allowed := make(map[string]bool, len(constraint))
+	for _, item := range constraint {
+		allowed[item] = true
+	}
+	var items []string
+	for _, item := range scope {
+		if allowed[item] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// federationCacheKey identifies the projects and quotas of a federated query
+func federationCacheKey(federation *FederationOptions) string {
+	if federation == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(federation.Projects))
+	for _, project := range federation.Projects {
+		sources := append([]string(nil), project.DataSourceIDs...)
+		sort.Strings(sources)
+		parts = append(parts, fmt.Sprintf("%s=%s/%d", project.ProjectID, strings.Join(sources, ","), project.Quota))
+	}
+	sort.Strings(parts)
+	return fmt.Sprintf("%s/%d", strings.Join(parts, ";"), federation.PerProjectQuota)
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+// fixedRetriever returns the same results for every query
+type fixedRetriever struct {
+	keywordRetriever
+	results []RetrievalResult
+}
+
+func (r *fixedRetriever) Retrieve(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	return append([]RetrievalResult(nil), r.results...), nil
+}
+
+func federatedResult(id, source string, score float64) RetrievalResult {
+	return RetrievalResult{
+		DocumentID: id,
+		Document:   &Document{ID: id, DataSourceID: source},
+		Chunk:      &DocumentChunk{ID: id + "_0", DocumentID: id},
+		Score:      score,
+	}
+}
+
+func TestMergeFederated(t *testing.T) {
+	perProject := [][]RetrievalResult{
+		{{DocumentID: "a1", Score: 0.9}, {DocumentID: "a2", Score: 0.8}, {DocumentID: "a3", Score: 0.7}},
+		{{DocumentID: "b1", Score: 0.5}},
+		{{DocumentID: "c1", Score: 0.6}, {DocumentID: "c2", Score: 0.2}},
+	}
+
+	// Quotas of one let each project in before project a's runners-up
+	merged := mergeFederated(perProject, []int{1, 1, 1}, 4)
+	var ids []string
+	for _, result := range merged {
+		ids = append(ids, result.DocumentID)
+	}
+	if len(ids) != 4 || ids[0] != "a1" || ids[1] != "a2" || ids[2] != "c1" || ids[3] != "b1" {
+		t.Fatalf("unexpected merge %v", ids)
+	}
+
+	merged = mergeFederated(perProject, []int{3, 3, 3}, 2)
+	if len(merged) != 2 || merged[0].DocumentID != "a1" || merged[1].DocumentID != "a2" {
+		t.Fatalf("unexpected top results %+v", merged)
+	}
+}
+
+func TestRetrieveFederated(t *testing.T) {
+	retriever := &fixedRetriever{results: []RetrievalResult{
+		federatedResult("eng-1", "ds-eng", 0.9),
+		federatedResult("eng-2", "ds-eng", 0.8),
+		federatedResult("ops-1", "ds-ops", 0.4),
+		federatedResult("hr-1", "ds-hr", 0.95),
+	}}
+	p := &Pipeline{config: DefaultConfig(), retriever: retriever}
+
+	options := QueryOptions{MaxResults: 2, Federation: &FederationOptions{Projects: []FederatedProject{
+		{ProjectID: "eng", DataSourceIDs: []string{"ds-eng"}},
+		{ProjectID: "ops", DataSourceIDs: []string{"ds-ops"}},
+		{ProjectID: "empty"},
+	}}}
+	results, reports, err := p.retrieveFederated(context.Background(), "deploy", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The HR document is outside every project; ops gets its quota slot
+	if len(results) != 2 || results[0].DocumentID != "eng-1" || results[1].DocumentID != "ops-1" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if results[1].ProjectID != "ops" {
+		t.Fatalf("expected result to be tagged with its project, got %q", results[1].ProjectID)
+	}
+	if reports[0].Retrieved != 2 || reports[0].Returned != 1 || reports[1].Returned != 1 || reports[2].Retrieved != 0 {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+
+	sources := sourcesFromResults(results)
+	if sources[1].ProjectID != "ops" {
+		t.Fatalf("expected source to carry its project, got %+v", sources[1])
+	}
+}
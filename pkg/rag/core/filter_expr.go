@@ -0,0 +1,815 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter expression operators
+const (
+	FilterOpEq  = "eq"
+	FilterOpNe  = "ne"
+	FilterOpGt  = "gt"
+	FilterOpGte = "gte"
+	FilterOpLt  = "lt"
+	FilterOpLte = "lte"
+	FilterOpIn  = "in"
+)
+
+// maxFilterDepth limits nesting so untrusted filters stay cheap to evaluate
+const maxFilterDepth = 16
+
+// filterFields lists the fields filter expressions may reference. Custom
+// metadata is addressed as "meta.<key>".
+var filterFields = map[string]bool{
+	"tag":        true,
+	"category":   true,
+	"author":     true,
+	"owner":      true,
+	"type":       true,
+	"ext":        true,
+	"source":     true,
+	"doc":        true,
+	"lang":       true,
+	"title":      true,
+	"path":       true,
+	"chunk_type": true,
+	"created":    true,
+	"modified":   true,
+}
+
+// FilterExpr is a boolean filter over document and chunk metadata. A node is
+// either a combinator (And, Or, Not) or a predicate (Field, Op, Value).
+//
+// The same tree is produced by ParseFilterExpression and accepted as
+// structured JSON, e.g.
+//
+//	{"and": [{"field": "tag", "op": "eq", "value": "go"},
+//	         {"field": "created", "op": "gte", "value": "2024-01-01"}]}
+type FilterExpr struct {
+	And []*FilterExpr `json:"and,omitempty"`
+	Or  []*FilterExpr `json:"or,omitempty"`
+	Not *FilterExpr   `json:"not,omitempty"`
+
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Validate checks fields, operators and values
+func (e *FilterExpr) Validate() error {
+	return e.validate(0)
+}
+
+func (e *FilterExpr) validate(depth int) error {
+	if depth > maxFilterDepth {
+		return fmt.Errorf("filter nested too deeply (max %d)", maxFilterDepth)
+	}
+
+	combinators := 0
+	if len(e.And) > 0 {
+		combinators++
+	}
+	if len(e.Or) > 0 {
+		combinators++
+	}
+	if e.Not != nil {
+		combinators++
+	}
+
+	if combinators > 0 {
+		if combinators > 1 || e.Field != "" {
+			return fmt.Errorf("filter node must be exactly one of and, or, not or a predicate")
+		}
+		for _, child := range append(append([]*FilterExpr{}, e.And...), e.Or...) {
+			if child == nil {
+				return fmt.Errorf("filter contains an empty node")
+			}
+			if err := child.validate(depth + 1); err != nil {
+				return err
+			}
+		}
+		if e.Not != nil {
+			return e.Not.validate(depth + 1)
+		}
+		return nil
+	}
+
+	if e.Field == "" {
+		return fmt.Errorf("filter predicate requires a field")
+	}
+	if !filterFields[e.Field] && !strings.HasPrefix(e.Field, "meta.") {
+		return fmt.Errorf("unknown filter field: %s", e.Field)
+	}
+
+	switch e.Op {
+	case FilterOpEq, FilterOpNe, FilterOpIn:
+	case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+		if e.isTimeField() {
+			if _, err := parseFilterTime(fmt.Sprint(e.Value)); err != nil {
+				return fmt.Errorf("invalid date for %s: %v", e.Field, e.Value)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown filter operator: %s", e.Op)
+	}
+
+	if e.Value == nil {
+		return fmt.Errorf("filter predicate on %s requires a value", e.Field)
+	}
+	if e.Op == FilterOpIn {
+		if _, ok := e.Value.([]interface{}); !ok {
+			return fmt.Errorf("operator in requires a list value")
+		}
+	}
+
+	return nil
+}
+
+// Match reports whether a retrieval result satisfies the expression
+func (e *FilterExpr) Match(result RetrievalResult) bool {
+	switch {
+	case len(e.And) > 0:
+		for _, child := range e.And {
+			if !child.Match(result) {
+				return false
+			}
+		}
+		return true
+	case len(e.Or) > 0:
+		for _, child := range e.Or {
+			if child.Match(result) {
+				return true
+			}
+		}
+		return false
+	case e.Not != nil:
+		return !e.Not.Match(result)
+	}
+
+	values := filterFieldValues(e.Field, result)
+	if e.Op == FilterOpNe {
+		for _, value := range values {
+			if compareFilterValue(value, e.Value) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	targets := []interface{}{e.Value}
+	if e.Op == FilterOpIn {
+		targets, _ = e.Value.([]interface{})
+	}
+
+	for _, value := range values {
+		for _, target := range targets {
+			if matchFilterOp(e.Op, compareFilterValue(value, target)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Pushdown copies the coarse predicates of a top-level conjunction into the
+// flat criteria fields understood by vector and keyword retrievers, so they
+// can prune candidates before scoring. The full expression is still applied
+// after retrieval, so pushed-down criteria only need to be a superset.
+func (e *FilterExpr) Pushdown(criteria *FilterCriteria) {
+	predicates := e.And
+	if e.Field != "" {
+		predicates = []*FilterExpr{e}
+	}
+
+	for _, pred := range predicates {
+		if pred.Field == "" {
+			continue
+		}
+
+		switch pred.Op {
+		case FilterOpEq, FilterOpIn:
+			values := pred.stringValues()
+			switch pred.Field {
+			case "tag":
+				criteria.Tags = pushdownList(criteria.Tags, values)
+			case "category":
+				criteria.Categories = pushdownList(criteria.Categories, values)
+			case "author":
+				criteria.Authors = pushdownList(criteria.Authors, values)
+			case "type":
+				criteria.FileTypes = pushdownList(criteria.FileTypes, values)
+			case "source":
+				criteria.DataSourceIDs = pushdownList(criteria.DataSourceIDs, values)
+			case "doc":
+				criteria.DocumentIDs = pushdownList(criteria.DocumentIDs, values)
+			case "chunk_type":
+				criteria.ChunkTypes = pushdownList(criteria.ChunkTypes, values)
+			case "lang":
+				if criteria.Language == "" && len(values) == 1 {
+					criteria.Language = values[0]
+				}
+			}
+		case FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte:
+			if !pred.isTimeField() {
+				continue
+			}
+			t, err := parseFilterTime(fmt.Sprint(pred.Value))
+			if err != nil {
+				continue
+			}
+			after := pred.Op == FilterOpGt || pred.Op == FilterOpGte
+			switch {
+			case pred.Field == "created" && after:
+				criteria.CreatedAfter = laterTime(criteria.CreatedAfter, t)
+			case pred.Field == "created":
+				criteria.CreatedBefore = earlierTime(criteria.CreatedBefore, t)
+			case after:
+				criteria.ModifiedAfter = laterTime(criteria.ModifiedAfter, t)
+			default:
+				criteria.ModifiedBefore = earlierTime(criteria.ModifiedBefore, t)
+			}
+		}
+	}
+}
+
+// String renders the expression in the filter DSL
+func (e *FilterExpr) String() string {
+	switch {
+	case len(e.And) > 0:
+		return joinFilterExprs(e.And, " AND ")
+	case len(e.Or) > 0:
+		return joinFilterExprs(e.Or, " OR ")
+	case e.Not != nil:
+		return "NOT " + e.Not.wrapped()
+	}
+
+	ops := map[string]string{
+		FilterOpEq: ":", FilterOpNe: "!=", FilterOpGt: ">",
+		FilterOpGte: ">=", FilterOpLt: "<", FilterOpLte: "<=", FilterOpIn: ":",
+	}
+	values := e.stringValues()
+	for i, value := range values {
+		values[i] = quoteFilterValue(value)
+	}
+	return e.Field + ops[e.Op] + strings.Join(values, ",")
+}
+
+func (e *FilterExpr) wrapped() string {
+	if len(e.And) > 0 || len(e.Or) > 0 {
+		return "(" + e.String() + ")"
+	}
+	return e.String()
+}
+
+func (e *FilterExpr) isTimeField() bool {
+	return e.Field == "created" || e.Field == "modified"
+}
+
+func (e *FilterExpr) stringValues() []string {
+	if list, ok := e.Value.([]interface{}); ok {
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	}
+	return []string{fmt.Sprint(e.Value)}
+}
+
+// AndFilterExprs combines expressions with AND, skipping nil entries
+func AndFilterExprs(exprs ...*FilterExpr) *FilterExpr {
+	var nonNil []*FilterExpr
+	for _, expr := range exprs {
+		if expr != nil {
+			nonNil = append(nonNil, expr)
+		}
+	}
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	}
+	return &FilterExpr{And: nonNil}
+}
+
+// ParseFilterExpression parses the filter DSL, for example
+//
+//	tag:go AND author:"Jane Doe" AND created>=2024-01-01 AND (type:pdf OR type:md)
+//	NOT chunk_type:summary meta.team=search,infra
+//
+// Adjacent terms are joined with AND; a comma-separated value matches any of
+// the listed values.
+func ParseFilterExpression(input string) (*FilterExpr, error) {
+	tokens, err := tokenizeFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	parser := &filterParser{tokens: tokens}
+	expr, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(parser.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", parser.tokens[parser.pos].text, parser.tokens[parser.pos].pos)
+	}
+	if err := expr.Validate(); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// ExpressionFilter applies FilterCriteria.Expression to retrieval results
+type ExpressionFilter struct{}
+
+// NewExpressionFilter creates a new expression filter
+func NewExpressionFilter() *ExpressionFilter {
+	return &ExpressionFilter{}
+}
+
+// Filter implements the Filter interface
+func (f *ExpressionFilter) Filter(ctx context.Context, results []RetrievalResult, criteria FilterCriteria) ([]RetrievalResult, error) {
+	if criteria.Expression == nil {
+		return results, nil
+	}
+
+	filtered := make([]RetrievalResult, 0, len(results))
+	for _, result := range results {
+		if criteria.Expression.Match(result) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered, nil
+}
+
+// GetName returns the filter name
+func (f *ExpressionFilter) GetName() string {
+	return "expression"
+}
+
+// GetDescription returns the filter description
+func (f *ExpressionFilter) GetDescription() string {
+	return "Filters results by a metadata filter expression"
+}
+
+// Validate checks if the filter is valid
+func (f *ExpressionFilter) Validate() error {
+	return nil
+}
+
+// applyFilterExpression parses the query's filter string, merges it into the
+// retrieval criteria and pushes its predicates down to the retrievers
+func applyFilterExpression(options *QueryOptions) error {
+	criteria := &options.RetrievalOptions.FilterOptions
+
+	if options.Filter != "" {
+		parsed, err := ParseFilterExpression(options.Filter)
+		if err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+		criteria.Expression = AndFilterExprs(criteria.Expression, parsed)
+	}
+
+	if criteria.Expression == nil {
+		return nil
+	}
+	if err := criteria.Expression.Validate(); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+	criteria.Expression.Pushdown(criteria)
+	return nil
+}
+
+// filterFieldValues extracts the values of a field from a result
+func filterFieldValues(field string, result RetrievalResult) []interface{} {
+	doc := result.Document
+	chunk := result.Chunk
+
+	if key, ok := strings.CutPrefix(field, "meta."); ok {
+		var values []interface{}
+		if chunk != nil {
+			if value, ok := chunk.Metadata[key]; ok {
+				values = append(values, value)
+			}
+		}
+		if doc != nil {
+			if value, ok := doc.Metadata.Custom[key]; ok {
+				values = append(values, value)
+			}
+		}
+		return flattenFilterValues(values)
+	}
+
+	switch field {
+	case "doc":
+		return []interface{}{result.DocumentID}
+	case "chunk_type":
+		if chunk != nil {
+			return []interface{}{chunk.ChunkType}
+		}
+		return nil
+	}
+
+	if doc == nil {
+		return nil
+	}
+
+	switch field {
+	case "tag":
+		return stringsToValues(doc.Tags)
+	case "category":
+		return stringsToValues(doc.Categories)
+	case "author":
+		return nonEmptyValues(doc.Metadata.Author)
+	case "owner":
+		return nonEmptyValues(doc.Metadata.Owner)
+	case "type":
+		return nonEmptyValues(doc.Metadata.FileType)
+	case "ext":
+		return nonEmptyValues(strings.TrimPrefix(doc.Metadata.Extension, "."))
+	case "source":
+		return nonEmptyValues(doc.DataSourceID)
+	case "lang":
+		return nonEmptyValues(doc.Language)
+	case "title":
+		return nonEmptyValues(doc.Title)
+	case "path":
+		return nonEmptyValues(doc.Metadata.FilePath)
+	case "created":
+		if !doc.Metadata.CreatedAt.IsZero() {
+			return []interface{}{doc.Metadata.CreatedAt}
+		}
+	case "modified":
+		if !doc.Metadata.ModifiedAt.IsZero() {
+			return []interface{}{doc.Metadata.ModifiedAt}
+		}
+	}
+	return nil
+}
+
+// compareFilterValue compares a field value with a filter value, returning
+// -1, 0 or 1, or -2 when the values cannot be compared
+func compareFilterValue(value, target interface{}) int {
+	targetText := fmt.Sprint(target)
+
+	switch v := value.(type) {
+	case time.Time:
+		t, err := parseFilterTime(targetText)
+		if err != nil {
+			return -2
+		}
+		// Date-only filters compare at day granularity
+		if len(targetText) == len("2006-01-02") {
+			v = time.Date(v.Year(), v.Month(), v.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		return v.Compare(t)
+	case float64, float32, int, int64, int32:
+		n, err := strconv.ParseFloat(targetText, 64)
+		if err != nil {
+			return -2
+		}
+		f, _ := strconv.ParseFloat(fmt.Sprint(v), 64)
+		switch {
+		case f < n:
+			return -1
+		case f > n:
+			return 1
+		}
+		return 0
+	case bool:
+		b, err := strconv.ParseBool(targetText)
+		if err != nil || b != v {
+			return -2
+		}
+		return 0
+	}
+
+	text := strings.ToLower(fmt.Sprint(value))
+	return strings.Compare(text, strings.ToLower(targetText))
+}
+
+func matchFilterOp(op string, cmp int) bool {
+	if cmp == -2 {
+		return false
+	}
+	switch op {
+	case FilterOpEq, FilterOpIn:
+		return cmp == 0
+	case FilterOpGt:
+		return cmp > 0
+	case FilterOpGte:
+		return cmp >= 0
+	case FilterOpLt:
+		return cmp < 0
+	case FilterOpLte:
+		return cmp <= 0
+	}
+	return false
+}
+
+// parseFilterTime parses RFC 3339 timestamps and plain dates
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func flattenFilterValues(values []interface{}) []interface{} {
+	var flat []interface{}
+	for _, value := range values {
+		switch v := value.(type) {
+		case []interface{}:
+			flat = append(flat, v...)
+		case []string:
+			flat = append(flat, stringsToValues(v)...)
+		default:
+			flat = append(flat, v)
+		}
+	}
+	return flat
+}
+
+func stringsToValues(items []string) []interface{} {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item
+	}
+	return values
+}
+
+func nonEmptyValues(value string) []interface{} {
+	if value == "" {
+		return nil
+	}
+	return []interface{}{value}
+}
+
+func pushdownList(existing, values []string) []string {
+	if len(existing) > 0 {
+		// Already constrained; the expression filter enforces the rest
+		return existing
+	}
+	return values
+}
+
+func laterTime(current *time.Time, t time.Time) *time.Time {
+	if current == nil || t.After(*current) {
+		return &t
+	}
+	return current
+}
+
+func earlierTime(current *time.Time, t time.Time) *time.Time {
+	if current == nil || t.Before(*current) {
+		return &t
+	}
+	return current
+}
+
+func joinFilterExprs(exprs []*FilterExpr, sep string) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = expr.wrapped()
+	}
+	return strings.Join(parts, sep)
+}
+
+func quoteFilterValue(value string) string {
+	if value == "" || strings.IndexFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`()",:!=<>`, r)
+	}) >= 0 {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// filterToken is a lexical token of the filter DSL
+type filterToken struct {
+	kind string // word, string, op, lparen, rparen, comma
+	text string
+	pos  int
+}
+
+func tokenizeFilter(input string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, filterToken{kind: "lparen", text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, filterToken{kind: "rparen", text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, filterToken{kind: "comma", text: ",", pos: i})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(input[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d", i)
+			}
+			tokens = append(tokens, filterToken{kind: "string", text: text, pos: i})
+			i = end + 1
+		case strings.ContainsRune(":=!<>", rune(c)):
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' && c != ':' && c != '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("expected != at position %d", i)
+			}
+			tokens = append(tokens, filterToken{kind: "op", text: op, pos: i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n\r(),\":=!<>", rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, filterToken{kind: "word", text: input[start:i], pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser is a recursive descent parser over filter tokens
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() *filterToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *filterParser) keyword(word string) bool {
+	tok := p.peek()
+	return tok != nil && tok.kind == "word" && strings.EqualFold(tok.text, word)
+}
+
+func (p *filterParser) parseOr(depth int) (*FilterExpr, error) {
+	if depth > maxFilterDepth {
+		return nil, fmt.Errorf("filter nested too deeply (max %d)", maxFilterDepth)
+	}
+
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	terms := []*FilterExpr{left}
+	for p.keyword("OR") {
+		p.pos++
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+	if len(terms) == 1 {
+		return left, nil
+	}
+	return &FilterExpr{Or: terms}, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (*FilterExpr, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	terms := []*FilterExpr{left}
+	for {
+		tok := p.peek()
+		if tok == nil || tok.kind == "rparen" || p.keyword("OR") {
+			break
+		}
+		if p.keyword("AND") {
+			p.pos++
+		}
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, right)
+	}
+	if len(terms) == 1 {
+		return left, nil
+	}
+	return &FilterExpr{And: terms}, nil
+}
+
+func (p *filterParser) parseUnary(depth int) (*FilterExpr, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("unexpected end of filter")
+	}
+
+	if p.keyword("NOT") {
+		p.pos++
+		inner, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &FilterExpr{Not: inner}, nil
+	}
+
+	if tok.kind == "lparen" {
+		p.pos++
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != "rparen" {
+			return nil, fmt.Errorf("missing closing parenthesis for position %d", tok.pos)
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	return p.parsePredicate()
+}
+
+func (p *filterParser) parsePredicate() (*FilterExpr, error) {
+	field := p.peek()
+	if field.kind != "word" {
+		return nil, fmt.Errorf("expected field name at position %d", field.pos)
+	}
+	p.pos++
+
+	opTok := p.peek()
+	if opTok == nil || opTok.kind != "op" {
+		return nil, fmt.Errorf("expected operator after %q", field.text)
+	}
+	p.pos++
+
+	ops := map[string]string{
+		":": FilterOpEq, "=": FilterOpEq, "!=": FilterOpNe,
+		">": FilterOpGt, ">=": FilterOpGte, "<": FilterOpLt, "<=": FilterOpLte,
+	}
+	op, ok := ops[opTok.text]
+	if !ok {
+		return nil, fmt.Errorf("invalid operator %q at position %d", opTok.text, opTok.pos)
+	}
+
+	var values []interface{}
+	for {
+		tok := p.peek()
+		if tok == nil || (tok.kind != "word" && tok.kind != "string") {
+			return nil, fmt.Errorf("expected value for %q", field.text)
+		}
+		values = append(values, tok.text)
+		p.pos++
+
+		if next := p.peek(); next == nil || next.kind != "comma" {
+			break
+		}
+		p.pos++
+	}
+
+	name := field.text
+	if !strings.HasPrefix(name, "meta.") {
+		name = strings.ToLower(name)
+	}
+	expr := &FilterExpr{Field: name, Op: op, Value: values[0]}
+	if len(values) > 1 {
+		if op != FilterOpEq && op != FilterOpNe {
+			return nil, fmt.Errorf("value lists are only supported with : and !=")
+		}
+		if op == FilterOpEq {
+			expr.Op = FilterOpIn
+			expr.Value = values
+		} else {
+			// field!=a,b means none of the values
+			var terms []*FilterExpr
+			for _, value := range values {
+				terms = append(terms, &FilterExpr{Field: expr.Field, Op: FilterOpNe, Value: value})
+			}
+			return &FilterExpr{And: terms}, nil
+		}
+	}
+	return expr, nil
+}
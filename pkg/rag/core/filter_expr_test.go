@@ -0,0 +1,56 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFilterExpressionMatch(t *testing.T) {
+	expr, err := ParseFilterExpression(`tag:go,rust AND author:"Jane Doe" created>=2024-01-01 (type:pdf OR type:md) NOT chunk_type:summary meta.team=search`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	doc := &Document{
+		Tags: []string{"Go"},
+		Metadata: DocumentMetadata{
+			Author:    "jane doe",
+			FileType:  "md",
+			CreatedAt: time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC),
+			Custom:    map[string]interface{}{"team": "search"},
+		},
+	}
+	result := RetrievalResult{Document: doc, Chunk: &DocumentChunk{ChunkType: "paragraph"}}
+	if !expr.Match(result) {
+		t.Fatalf("expected %s to match", expr)
+	}
+
+	result.Chunk.ChunkType = ChunkTypeSummary
+	if expr.Match(result) {
+		t.Fatalf("NOT chunk_type:summary should exclude summary chunks")
+	}
+
+	var criteria FilterCriteria
+	expr.Pushdown(&criteria)
+	if len(criteria.Tags) != 2 || len(criteria.Authors) != 1 || criteria.CreatedAfter == nil {
+		t.Fatalf("unexpected pushdown: %+v", criteria)
+	}
+	if len(criteria.FileTypes) != 0 {
+		t.Fatalf("disjunctions must not be pushed down: %v", criteria.FileTypes)
+	}
+}
+
+func TestParseFilterExpressionErrors(t *testing.T) {
+	for _, input := range []string{
+		`tag:`,
+		`(tag:go`,
+		`unknown:x`,
+		`created>yesterday`,
+		`tag:"open`,
+		`author>=a,b`,
+	} {
+		if _, err := ParseFilterExpression(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Consistency issues found by CheckConsistency
+const (
+	FsckOrphanChunk       = "orphan_chunk"       // chunk whose document no longer exists
+	FsckOrphanEmbedding   = "orphan_embedding"   // embedding whose chunk no longer exists
+	FsckDanglingIndex     = "dangling_index"     // retriever index entry whose chunk no longer exists
+	FsckDimensionMismatch = "dimension_mismatch" // embedding size differs from its index version
+)
+
+// StorageScanner is implemented by storage backends whose raw contents can be
+// enumerated for consistency checks, including records the Storage interface
+// cannot reach through documents
+type StorageScanner interface {
+	// ListAllChunks returns every stored chunk, with or without a document
+	ListAllChunks(ctx context.Context) ([]DocumentChunk, error)
+
+	// EmbeddingDimensions returns the vector length of every stored embedding by chunk ID
+	EmbeddingDimensions(ctx context.Context) (map[string]int, error)
+
+	// DeleteChunk deletes a single chunk
+	DeleteChunk(ctx context.Context, chunkID string) error
+
+	// DeleteEmbedding deletes the embedding of a chunk
+	DeleteEmbedding(ctx context.Context, chunkID string) error
+}
+
+// IndexScanner is implemented by retrievers whose index entries can be enumerated
+type IndexScanner interface {
+	// IndexedChunkIDs returns the chunk IDs present in the retriever index
+	IndexedChunkIDs(ctx context.Context) ([]string, error)
+}
+
+// FsckOptions configures a consistency check
+type FsckOptions struct {
+	// Repair fixes the issues found: orphans and dangling index entries are
+	// removed, documents with mismatched embeddings are reprocessed
+	Repair bool `json:"repair"`
+}
+
+// FsckIssue is one inconsistency found in the store
+type FsckIssue struct {
+	Kind       string `json:"kind"`
+	ChunkID    string `json:"chunk_id"`
+	DocumentID string `json:"document_id,omitempty"`
+	Detail     string `json:"detail,omitempty"`
+	Repaired   bool   `json:"repaired"`
+	Error      string `json:"error,omitempty"` // Why the repair failed
+}
+
+// FsckReport summarizes a consistency check and what it repaired
+type FsckReport struct {
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration"`
+	Repair     bool          `json:"repair"`
+
+	Documents    int  `json:"documents"`
+	Chunks       int  `json:"chunks"`
+	Embeddings   int  `json:"embeddings"`
+	IndexEntries int  `json:"index_entries"`
+	IndexChecked bool `json:"index_checked"` // False when the retriever cannot list its index
+
+	Issues   []FsckIssue    `json:"issues"`
+	Counts   map[string]int `json:"counts"`
+	Repaired int            `json:"repaired"`
+	Failed   int            `json:"failed"`
+}
+
+// CheckConsistency looks for chunks without documents, embeddings without
+// chunks, index entries referencing deleted chunks and embeddings whose size
+// does not match their index version, and optionally repairs them. It shares
+// the storage maintenance lock so it never races an orphan purge.
+func (p *Pipeline) CheckConsistency(ctx context.Context, options FsckOptions) (*FsckReport, error) {
+	scanner, ok := p.storage.(StorageScanner)
+	if !ok {
+		return nil, fmt.Errorf("storage backend does not support consistency checks")
+	}
+
+	var report *FsckReport
+	err := WithLock(ctx, p.Locker(), LockKeyMaintenance, p.LockTTL(), func(ctx context.Context) error {
+		var err error
+		report, err = p.checkConsistency(ctx, scanner, options)
+		return err
+	})
+	if errors.Is(err, ErrLockHeld) {
+		return nil, fmt.Errorf("storage maintenance already running on another instance")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.emitEvent(ctx, "fsck", map[string]interface{}{
+		"repair":   report.Repair,
+		"issues":   len(report.Issues),
+		"repaired": report.Repaired,
+		"failed":   report.Failed,
+		"duration": report.Duration,
+	})
+	return report, nil
+}
+
+func (p *Pipeline) checkConsistency(ctx context.Context, scanner StorageScanner, options FsckOptions) (*FsckReport, error) {
+	report := &FsckReport{
+		StartedAt: time.Now(),
+		Repair:    options.Repair,
+		Counts:    make(map[string]int),
+	}
+
+	// Soft-deleted documents still own their chunks, so list storage directly
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	chunks, err := scanner.ListAllChunks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+	dimensions, err := scanner.EmbeddingDimensions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list embeddings: %w", err)
+	}
+	report.Documents = len(documents)
+	report.Chunks = len(chunks)
+	report.Embeddings = len(dimensions)
+
+	documentIDs := make(map[string]bool, len(documents))
+	for _, doc := range documents {
+		documentIDs[doc.ID] = true
+	}
+	chunkIDs := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		chunkIDs[chunk.ID] = true
+	}
+
+	mismatched := make(map[string][]int) // document ID -> issue indexes
+	for _, chunk := range chunks {
+		if !documentIDs[chunk.DocumentID] {
+			report.add(FsckIssue{Kind: FsckOrphanChunk, ChunkID: chunk.ID, DocumentID: chunk.DocumentID})
+			continue
+		}
+
+		dimension, ok := dimensions[chunk.ID]
+		if len(chunk.Embedding) > 0 {
+			dimension, ok = len(chunk.Embedding), true
+		}
+		if want := expectedDimension(chunk); ok && want > 0 && dimension != want {
+			mismatched[chunk.DocumentID] = append(mismatched[chunk.DocumentID], len(report.Issues))
+			report.add(FsckIssue{
+				Kind:       FsckDimensionMismatch,
+				ChunkID:    chunk.ID,
+				DocumentID: chunk.DocumentID,
+				Detail:     fmt.Sprintf("embedding has %d dimensions, %s expects %d", dimension, chunk.IndexVersion, want),
+			})
+		}
+	}
+
+	for _, chunkID := range sortedKeys(dimensions) {
+		if !chunkIDs[chunkID] {
+			report.add(FsckIssue{Kind: FsckOrphanEmbedding, ChunkID: chunkID})
+		}
+	}
+
+	if indexer, ok := p.retriever.(IndexScanner); ok {
+		indexed, err := indexer.IndexedChunkIDs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list index entries: %w", err)
+		}
+		report.IndexChecked = true
+		report.IndexEntries = len(indexed)
+		for _, chunkID := range indexed {
+			if !chunkIDs[chunkID] {
+				report.add(FsckIssue{Kind: FsckDanglingIndex, ChunkID: chunkID})
+			}
+		}
+	}
+
+	if options.Repair {
+		p.repairConsistency(ctx, scanner, report, mismatched)
+	}
+
+	report.FinishedAt = time.Now()
+	report.Duration = report.FinishedAt.Sub(report.StartedAt)
+	return report, nil
+}
+
+// repairConsistency fixes the issues in the report, recording the outcome on each
+func (p *Pipeline) repairConsistency(ctx context.Context, scanner StorageScanner, report *FsckReport, mismatched map[string][]int) {
+	for i := range report.Issues {
+		issue := &report.Issues[i]
+		var err error
+		switch issue.Kind {
+		case FsckOrphanChunk:
+			if p.retriever != nil {
+				err = p.retriever.RemoveDocument(ctx, issue.ChunkID)
+			}
+			if err == nil {
+				err = scanner.DeleteEmbedding(ctx, issue.ChunkID)
+			}
+			if err == nil {
+				err = scanner.DeleteChunk(ctx, issue.ChunkID)
+			}
+		case FsckOrphanEmbedding:
+			err = scanner.DeleteEmbedding(ctx, issue.ChunkID)
+		case FsckDanglingIndex:
+			err = p.retriever.RemoveDocument(ctx, issue.ChunkID)
+		default:
+			continue
+		}
+		report.resolve(issue, err)
+	}
+
+	// Reprocessing re-chunks and re-embeds the whole document with the current model
+	for _, documentID := range sortedKeys(mismatched) {
+		var err error
+		if p.isTombstoned(documentID) {
+			err = fmt.Errorf("document is soft-deleted; restore or purge it")
+		} else {
+			_, err = p.ReprocessDocument(ctx, documentID, ReprocessOptions{})
+		}
+		for _, i := range mismatched[documentID] {
+			report.resolve(&report.Issues[i], err)
+		}
+	}
+}
+
+func (report *FsckReport) add(issue FsckIssue) {
+	report.Issues = append(report.Issues, issue)
+	report.Counts[issue.Kind]++
+}
+
+func (report *FsckReport) resolve(issue *FsckIssue, err error) {
+	if err != nil {
+		issue.Error = err.Error()
+		report.Failed++
+		return
+	}
+	issue.Repaired = true
+	report.Repaired++
+}
+
+// expectedDimension returns the vector size a chunk's index version implies,
+// falling back to the dimension recorded on the chunk, or 0 when unknown
+func expectedDimension(chunk DocumentChunk) int {
+	if i := strings.LastIndex(chunk.IndexVersion, "@"); i >= 0 {
+		if n, err := strconv.Atoi(chunk.IndexVersion[i+1:]); err == nil {
+			return n
+		}
+	}
+	return chunk.EmbeddingDim
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,646 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Glossary entry sources
+const (
+	GlossarySourceMined  = "mined"  // Found in the corpus by MineGlossary
+	GlossarySourceManual = "manual" // Added or edited by a reviewer; mining leaves it alone
+)
+
+// Glossary entry statuses
+const (
+	GlossaryStatusActive   = "active"
+	GlossaryStatusRejected = "rejected" // Kept so mining does not suggest the term again
+)
+
+const (
+	// maxGlossaryEntries bounds a mined glossary
+	maxGlossaryEntries = 500
+
+	// maxGlossaryDocuments bounds the documents recorded per entry
+	maxGlossaryDocuments = 5
+
+	// minUndefinedAcronymMentions is how often an acronym without a definition
+	// must appear, in at least two documents, to be suggested for review
+	minUndefinedAcronymMentions = 3
+
+	// maxGlossaryFootnotes bounds the footnotes added to an answer
+	maxGlossaryFootnotes = 10
+)
+
+// GlossaryEntry is an acronym or domain term with its definition
+type GlossaryEntry struct {
+	Term        string    `json:"term"`
+	Definition  string    `json:"definition,omitempty"` // Expansion of an acronym or short description; empty until reviewed for undefined acronyms
+	Source      string    `json:"source"`
+	Status      string    `json:"status"`
+	Occurrences int       `json:"occurrences,omitempty"`  // Mentions across the corpus when last mined
+	DocumentIDs []string  `json:"document_ids,omitempty"` // Documents defining or mentioning the term
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks an entry submitted for review
+func (e *GlossaryEntry) Validate() error {
+	if strings.TrimSpace(e.Term) == "" {
+		return fmt.Errorf("glossary term is required")
+	}
+	if len(e.Term) > 100 {
+		return fmt.Errorf("glossary term %q is too long", e.Term)
+	}
+	if len(e.Definition) > 500 {
+		return fmt.Errorf("definition of %q is too long", e.Term)
+	}
+	switch e.Status {
+	case "", GlossaryStatusActive, GlossaryStatusRejected:
+	default:
+		return fmt.Errorf("unknown glossary status %q", e.Status)
+	}
+	return nil
+}
+
+// usable reports whether the entry expands queries and explains answers
+func (e *GlossaryEntry) usable() bool {
+	return e.Status != GlossaryStatusRejected && e.Definition != ""
+}
+
+// Glossary holds a project's acronyms and domain terms
+type Glossary struct {
+	ProjectID string          `json:"project_id"`
+	Entries   []GlossaryEntry `json:"entries"`
+	MinedAt   time.Time       `json:"mined_at,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// GlossaryStore persists project glossaries
+type GlossaryStore interface {
+	// GetGlossary returns a project's glossary, or nil if none
+	GetGlossary(ctx context.Context, projectID string) (*Glossary, error)
+
+	// SaveGlossary stores a project's glossary
+	SaveGlossary(ctx context.Context, glossary *Glossary) error
+}
+
+func glossaryKey(term string) string {
+	return strings.ToLower(strings.TrimSpace(term))
+}
+
+// Lookup returns the entry for a term, ignoring case
+func (g *Glossary) Lookup(term string) *GlossaryEntry {
+	if g == nil {
+		return nil
+	}
+	key := glossaryKey(term)
+	for i := range g.Entries {
+		if glossaryKey(g.Entries[i].Term) == key {
+			return &g.Entries[i]
+		}
+	}
+	return nil
+}
+
+// Upsert adds or replaces an entry on behalf of a reviewer. Edited entries
+// become manual, so later mining runs keep them as they are.
+func (g *Glossary) Upsert(entry GlossaryEntry, now time.Time) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	entry.Term = strings.TrimSpace(entry.Term)
+	entry.Definition = strings.TrimSpace(entry.Definition)
+	entry.Source = GlossarySourceManual
+	if entry.Status == "" {
+		entry.Status = GlossaryStatusActive
+	}
+	entry.UpdatedAt = now
+	if existing := g.Lookup(entry.Term); existing != nil {
+		entry.Occurrences = existing.Occurrences
+		if entry.DocumentIDs == nil {
+			entry.DocumentIDs = existing.DocumentIDs
+		}
+		*existing = entry
+	} else {
+		g.Entries = append(g.Entries, entry)
+	}
+	g.UpdatedAt = now
+	return nil
+}
+
+// Remove deletes a term. Mined terms are marked rejected instead, so the
+// next mining run does not add them back. It reports whether the term existed.
+func (g *Glossary) Remove(term, updatedBy string, now time.Time) bool {
+	key := glossaryKey(term)
+	for i := range g.Entries {
+		if glossaryKey(g.Entries[i].Term) != key {
+			continue
+		}
+		if g.Entries[i].Source == GlossarySourceMined {
+			g.Entries[i].Status = GlossaryStatusRejected
+			g.Entries[i].UpdatedBy = updatedBy
+			g.Entries[i].UpdatedAt = now
+		} else {
+			g.Entries = append(g.Entries[:i], g.Entries[i+1:]...)
+		}
+		g.UpdatedAt = now
+		return true
+	}
+	return false
+}
+
+// mergeMined replaces the mined entries with the result of a new mining run.
+// Manual and rejected entries are kept and only their statistics refreshed.
+func (g *Glossary) mergeMined(mined []GlossaryEntry, now time.Time) {
+	found := make(map[string]GlossaryEntry, len(mined))
+	for _, entry := range mined {
+		found[glossaryKey(entry.Term)] = entry
+	}
+
+	var entries []GlossaryEntry
+	for _, entry := range g.Entries {
+		key := glossaryKey(entry.Term)
+		fresh, ok := found[key]
+		if entry.Source == GlossarySourceMined && entry.Status != GlossaryStatusRejected {
+			continue
+		}
+		if ok {
+			entry.Occurrences = fresh.Occurrences
+			entry.DocumentIDs = fresh.DocumentIDs
+			delete(found, key)
+		}
+		entries = append(entries, entry)
+	}
+	for _, entry := range mined {
+		if _, ok := found[glossaryKey(entry.Term)]; ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return glossaryKey(entries[i].Term) < glossaryKey(entries[j].Term)
+	})
+	g.Entries = entries
+	g.MinedAt = now
+	g.UpdatedAt = now
+}
+
+// Expand returns the expansions of acronyms mentioned in a query, and the
+// acronyms whose expansions it spells out, for query expansion. Definitions
+// of other terms are descriptions and would dilute the query.
+func (g *Glossary) Expand(query string) []string {
+	if g == nil {
+		return nil
+	}
+	var expansions []string
+	seen := make(map[string]bool)
+	add := func(text string) {
+		if key := strings.ToLower(text); !seen[key] && !containsTerm(query, text) {
+			seen[key] = true
+			expansions = append(expansions, text)
+		}
+	}
+	for i := range g.Entries {
+		entry := &g.Entries[i]
+		if !entry.usable() || !isAcronym(entry.Term) {
+			continue
+		}
+		if containsTerm(query, entry.Term) {
+			add(entry.Definition)
+		} else if containsTerm(query, entry.Definition) {
+			add(entry.Term)
+		}
+	}
+	return expansions
+}
+
+// UsedIn returns the entries whose terms appear in text, in order of first
+// appearance, for answer footnotes
+func (g *Glossary) UsedIn(text string) []GlossaryEntry {
+	if g == nil {
+		return nil
+	}
+	type use struct {
+		entry GlossaryEntry
+		at    int
+	}
+	var uses []use
+	for _, entry := range g.Entries {
+		if !entry.usable() {
+			continue
+		}
+		if at := termIndex(text, entry.Term); at >= 0 {
+			uses = append(uses, use{entry: entry, at: at})
+		}
+	}
+	sort.SliceStable(uses, func(i, j int) bool {
+		return uses[i].at < uses[j].at
+	})
+	if len(uses) > maxGlossaryFootnotes {
+		uses = uses[:maxGlossaryFootnotes]
+	}
+	entries := make([]GlossaryEntry, len(uses))
+	for i, u := range uses {
+		entries[i] = u.entry
+	}
+	return entries
+}
+
+// GlossaryFootnotes renders entries as "SLA = Service Level Agreement" lines
+func GlossaryFootnotes(entries []GlossaryEntry) string {
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = fmt.Sprintf("- %s = %s", entry.Term, entry.Definition)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isAcronym reports whether a term is written mostly in capitals, like SLA or GDPRs
+func isAcronym(term string) bool {
+	upper, letters := 0, 0
+	for _, r := range term {
+		if unicode.IsSpace(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return upper >= 2 && upper*2 > letters
+}
+
+// termIndex returns the byte offset of the first whole-word occurrence of a
+// term, or -1. Acronyms match case-sensitively so "IT" does not match "it".
+func termIndex(text, term string) int {
+	if term == "" {
+		return -1
+	}
+	haystack, needle := text, term
+	if !isAcronym(term) {
+		haystack, needle = strings.ToLower(text), strings.ToLower(term)
+		if len(haystack) != len(text) {
+			// Lowercasing changed byte offsets, fall back to exact matching
+			haystack, needle = text, term
+		}
+	}
+	for offset := 0; offset <= len(haystack); {
+		i := strings.Index(haystack[offset:], needle)
+		if i < 0 {
+			return -1
+		}
+		start, end := offset+i, offset+i+len(needle)
+		if !wordRuneBefore(haystack, start) && !wordRuneAfter(haystack, end) {
+			return start
+		}
+		offset = start + 1
+	}
+	return -1
+}
+
+func containsTerm(text, term string) bool {
+	return termIndex(text, term) >= 0
+}
+
+func wordRuneBefore(text string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:i])
+	return isWordRune(r)
+}
+
+func wordRuneAfter(text string, i int) bool {
+	if i >= len(text) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(text[i:])
+	return isWordRune(r)
+}
+
+var (
+	// "Service Level Agreement (SLA)"
+	longFormFirstRegex = regexp.MustCompile(`([\p{L}][\p{L}\p{N}'&-]*(?:[ \t]+[\p{L}\p{N}'&-]+){0,9})[ \t]*\([ \t]*([A-Z][A-Za-z0-9&]{1,9})[ \t]*\)`)
+
+	// "SLA (Service Level Agreement)"
+	acronymFirstRegex = regexp.MustCompile(`\b([A-Z][A-Za-z0-9&]{1,9})[ \t]*\([ \t]*([\p{L}][^()\n]{2,80}?)[ \t]*\)`)
+
+	// "**Tenant**: an organization that ..." or "**Tenant** - ..."
+	definitionRegex = regexp.MustCompile(`(?m)^[ \t]*(?:[-*][ \t]+)?(?:\*\*|__)([^*_\n]{2,60})(?:\*\*|__)[ \t]*(?::|：|—|–|-)[ \t]*(.{5,300})$`)
+
+	// Candidate acronyms used without a definition
+	acronymRegex = regexp.MustCompile(`\b[A-Z][A-Z0-9&]{1,7}s?\b`)
+)
+
+// glossaryConnectors may be skipped when matching acronym letters to words
+var glossaryConnectors = map[string]bool{
+	"of": true, "and": true, "for": true, "the": true, "to": true, "in": true, "on": true, "a": true, "&": true,
+}
+
+// acronymLetters returns the capital letters and digits an acronym is built
+// from, ignoring a plural s
+func acronymLetters(acronym string) string {
+	acronym = strings.TrimSuffix(acronym, "s")
+	var letters []rune
+	for _, r := range acronym {
+		if unicode.IsUpper(r) || unicode.IsDigit(r) {
+			letters = append(letters, unicode.ToLower(r))
+		}
+	}
+	return string(letters)
+}
+
+// initialWords splits a phrase into words, treating hyphenated parts as words
+func initialWords(phrase string) []string {
+	return strings.FieldsFunc(phrase, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-'
+	})
+}
+
+// longFormFor returns the shortest tail of words whose initials spell the
+// acronym, skipping connectors like "of", or "" when none does
+func longFormFor(words []string, acronym string) string {
+	letters := []rune(acronymLetters(acronym))
+	if len(letters) < 2 {
+		return ""
+	}
+	j := len(letters) - 1
+	for i := len(words) - 1; i >= 0; i-- {
+		word := strings.ToLower(strings.Trim(words[i], "'"))
+		if word == "" {
+			continue
+		}
+		initial := []rune(word)[0]
+		switch {
+		case initial == letters[j]:
+			j--
+		case glossaryConnectors[word]:
+			continue
+		default:
+			return ""
+		}
+		if j < 0 {
+			return strings.Join(words[i:], " ")
+		}
+	}
+	return ""
+}
+
+// matchesAcronym reports whether all the words of a phrase spell the acronym
+func matchesAcronym(phrase, acronym string) bool {
+	words := initialWords(phrase)
+	form := longFormFor(words, acronym)
+	if form == "" {
+		return false
+	}
+	// The whole phrase must be used, apart from leading connectors
+	for _, word := range words[:len(words)-len(initialWords(form))] {
+		if !glossaryConnectors[strings.ToLower(word)] {
+			return false
+		}
+	}
+	return true
+}
+
+// glossaryCandidate collects the definitions seen for a term
+type glossaryCandidate struct {
+	term        string
+	definitions map[string]int
+	order       []string
+	documents   []string
+}
+
+func (c *glossaryCandidate) define(definition, documentID string) {
+	if definition != "" {
+		if c.definitions[definition] == 0 {
+			c.order = append(c.order, definition)
+		}
+		c.definitions[definition]++
+	}
+	c.addDocument(documentID)
+}
+
+func (c *glossaryCandidate) addDocument(documentID string) {
+	if documentID == "" || len(c.documents) >= maxGlossaryDocuments {
+		return
+	}
+	for _, id := range c.documents {
+		if id == documentID {
+			return
+		}
+	}
+	c.documents = append(c.documents, documentID)
+}
+
+// definition returns the most frequent definition, the earliest on ties
+func (c *glossaryCandidate) definition() string {
+	best, count := "", 0
+	for _, definition := range c.order {
+		if n := c.definitions[definition]; n > count {
+			best, count = definition, n
+		}
+	}
+	return best
+}
+
+// MineGlossary finds acronyms and domain terms in documents: acronyms defined
+// inline as "Service Level Agreement (SLA)" or "SLA (Service Level
+// Agreement)", bold definition lines like "**Tenant**: ...", and acronyms used
+// often without a definition, which are returned without one for review
+func MineGlossary(documents []Document, now time.Time) []GlossaryEntry {
+	candidates := make(map[string]*glossaryCandidate)
+	candidate := func(term string) *glossaryCandidate {
+		key := glossaryKey(term)
+		c, ok := candidates[key]
+		if !ok {
+			c = &glossaryCandidate{term: term, definitions: make(map[string]int)}
+			candidates[key] = c
+		}
+		return c
+	}
+
+	for _, doc := range documents {
+		content := doc.Content
+		for _, match := range longFormFirstRegex.FindAllStringSubmatch(content, -1) {
+			if form := longFormFor(initialWords(match[1]), match[2]); form != "" && isAcronym(match[2]) {
+				candidate(match[2]).define(normalizeDefinition(form), doc.ID)
+			}
+		}
+		for _, match := range acronymFirstRegex.FindAllStringSubmatch(content, -1) {
+			if isAcronym(match[1]) && matchesAcronym(match[2], match[1]) {
+				candidate(match[1]).define(normalizeDefinition(match[2]), doc.ID)
+			}
+		}
+		for _, match := range definitionRegex.FindAllStringSubmatch(content, -1) {
+			term := strings.TrimSpace(match[1])
+			if len(strings.Fields(term)) > 4 {
+				continue
+			}
+			candidate(term).define(normalizeDefinition(match[2]), doc.ID)
+		}
+	}
+
+	// Count mentions of every candidate and of acronyms used without a definition
+	occurrences := make(map[string]int)
+	mentionedIn := make(map[string]map[string]bool)
+	for _, doc := range documents {
+		for _, acronym := range acronymRegex.FindAllString(doc.Content, -1) {
+			if !isAcronym(acronym) {
+				continue
+			}
+			key := glossaryKey(acronym)
+			occurrences[key]++
+			if mentionedIn[key] == nil {
+				mentionedIn[key] = make(map[string]bool)
+			}
+			mentionedIn[key][doc.ID] = true
+			if _, ok := candidates[key]; !ok && occurrences[key] >= minUndefinedAcronymMentions && len(mentionedIn[key]) >= 2 {
+				candidate(acronym)
+			}
+		}
+	}
+
+	lowered := make([]string, len(documents))
+	for i, doc := range documents {
+		lowered[i] = strings.ToLower(doc.Content)
+	}
+	entries := make([]GlossaryEntry, 0, len(candidates))
+	for key, c := range candidates {
+		count := occurrences[key]
+		if !isAcronym(c.term) {
+			for _, content := range lowered {
+				count += strings.Count(content, key)
+			}
+		}
+		if len(c.documents) == 0 {
+			for _, doc := range documents {
+				if mentionedIn[key][doc.ID] {
+					c.addDocument(doc.ID)
+				}
+			}
+		}
+		entries = append(entries, GlossaryEntry{
+			Term:        c.term,
+			Definition:  c.definition(),
+			Source:      GlossarySourceMined,
+			Status:      GlossaryStatusActive,
+			Occurrences: count,
+			DocumentIDs: c.documents,
+			UpdatedAt:   now,
+		})
+	}
+
+	// Keep the most used terms, defined ones first
+	sort.Slice(entries, func(i, j int) bool {
+		if (entries[i].Definition != "") != (entries[j].Definition != "") {
+			return entries[i].Definition != ""
+		}
+		if entries[i].Occurrences != entries[j].Occurrences {
+			return entries[i].Occurrences > entries[j].Occurrences
+		}
+		return entries[i].Term < entries[j].Term
+	})
+	if len(entries) > maxGlossaryEntries {
+		entries = entries[:maxGlossaryEntries]
+	}
+	return entries
+}
+
+// normalizeDefinition collapses whitespace and trailing punctuation
+func normalizeDefinition(definition string) string {
+	definition = strings.Join(strings.Fields(definition), " ")
+	return strings.TrimRight(definition, ".;,")
+}
+
+// SetGlossaryStore sets the store holding project glossaries, used for
+// query expansion and answer footnotes
+func (p *Pipeline) SetGlossaryStore(store GlossaryStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.glossaries = store
+}
+
+// loadGlossary loads a project's glossary; it returns nil when none applies
+func (p *Pipeline) loadGlossary(ctx context.Context, projectID string) (*Glossary, error) {
+	p.mu.RLock()
+	store := p.glossaries
+	p.mu.RUnlock()
+
+	if store == nil || projectID == "" {
+		return nil, nil
+	}
+	glossary, err := store.GetGlossary(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load glossary: %w", err)
+	}
+	return glossary, nil
+}
+
+// MineGlossary mines a project's documents for acronyms and domain terms and
+// merges them into its stored glossary, keeping reviewed entries
+func (p *Pipeline) MineGlossary(ctx context.Context, projectID string) (*Glossary, error) {
+	p.mu.RLock()
+	store := p.glossaries
+	p.mu.RUnlock()
+	if store == nil {
+		return nil, fmt.Errorf("glossaries not enabled")
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("project ID is required")
+	}
+
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	var corpus []Document
+	for _, doc := range documents {
+		if documentProjectID(doc) == projectID && !p.isTombstoned(doc.ID) {
+			corpus = append(corpus, doc)
+		}
+	}
+
+	glossary, err := store.GetGlossary(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load glossary: %w", err)
+	}
+	if glossary == nil {
+		glossary = &Glossary{ProjectID: projectID}
+	}
+	now := time.Now()
+	glossary.mergeMined(MineGlossary(corpus, now), now)
+	if err := store.SaveGlossary(ctx, glossary); err != nil {
+		return nil, fmt.Errorf("failed to save glossary: %w", err)
+	}
+
+	p.emitEvent(ctx, "glossary_mined", map[string]interface{}{
+		"project_id": projectID,
+		"documents":  len(corpus),
+		"entries":    len(glossary.Entries),
+	})
+	return glossary, nil
+}
+
+// addGlossaryFootnotes explains glossary terms used in the answer
+func (p *Pipeline) addGlossaryFootnotes(ctx context.Context, projectID string, result *QueryResult) {
+	glossary, err := p.loadGlossary(ctx, projectID)
+	if err != nil {
+		p.emitError(ctx, "glossary_footnotes", err)
+		return
+	}
+	entries := glossary.UsedIn(result.GeneratedResponse)
+	if len(entries) == 0 {
+		return
+	}
+	result.Glossary = entries
+	result.GeneratedResponse += "\n\n---\n" + GlossaryFootnotes(entries)
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMineGlossary(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	documents := []Document{
+		{ID: "d1", Content: "Our Service Level Agreement (SLA) covers uptime. The SLA is reviewed yearly under the General Data Protection Regulation (GDPR). Ask the TAM."},
+		{ID: "d2", Content: "Each RPO (Recovery Point Objective) is listed per region. See the SLA. Escalate to your TAM.\n\n**Tenant**: an organization sharing the deployment"},
+		{ID: "d3", Content: "The TAM (great people) owns renewals. Revenue growth (RG) matters. Contact the TAM today."},
+	}
+	entries := MineGlossary(documents, now)
+
+	glossary := &Glossary{Entries: entries}
+	for term, want := range map[string]string{
+		"SLA":    "Service Level Agreement",
+		"GDPR":   "General Data Protection Regulation",
+		"RPO":    "Recovery Point Objective",
+		"Tenant": "an organization sharing the deployment",
+		"RG":     "Revenue growth",
+		"TAM":    "",
+	} {
+		entry := glossary.Lookup(term)
+		if entry == nil {
+			t.Fatalf("expected %s to be mined, got %+v", term, entries)
+		}
+		if entry.Definition != want || entry.Source != GlossarySourceMined {
+			t.Fatalf("%s: expected definition %q, got %+v", term, want, entry)
+		}
+	}
+	if sla := glossary.Lookup("sla"); sla.Occurrences != 3 || strings.Join(sla.DocumentIDs, ",") != "d1" {
+		t.Fatalf("unexpected SLA statistics %+v", sla)
+	}
+	if tam := glossary.Lookup("TAM"); tam.Occurrences != 4 || len(tam.DocumentIDs) != 3 {
+		t.Fatalf("expected undefined acronym to be suggested for review, got %+v", tam)
+	}
+}
+
+func TestGlossaryReviewAndExpansion(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	glossary := &Glossary{ProjectID: "p1"}
+	glossary.mergeMined([]GlossaryEntry{
+		{Term: "SLA", Definition: "Service Level Agreement", Source: GlossarySourceMined, Status: GlossaryStatusActive},
+		{Term: "IT", Definition: "Information Technology", Source: GlossarySourceMined, Status: GlossaryStatusActive},
+		{Term: "PR", Definition: "Public Relations", Source: GlossarySourceMined, Status: GlossaryStatusActive},
+	}, now)
+
+	// Reviewers correct, add and reject entries
+	if err := glossary.Upsert(GlossaryEntry{Term: "PR", Definition: "Pull Request"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := glossary.Upsert(GlossaryEntry{Term: "Tenant", Definition: "An organization sharing the deployment"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if !glossary.Remove("it", "alice", now) || glossary.Lookup("IT").Status != GlossaryStatusRejected {
+		t.Fatal("expected mined entry to be rejected")
+	}
+	if err := glossary.Upsert(GlossaryEntry{Term: "X", Status: "maybe"}, now); err == nil {
+		t.Fatal("expected unknown status to be rejected")
+	}
+
+	// Mining again keeps reviewed entries and drops mined terms no longer found
+	glossary.mergeMined([]GlossaryEntry{
+		{Term: "PR", Definition: "Public Relations", Source: GlossarySourceMined, Status: GlossaryStatusActive, Occurrences: 7},
+		{Term: "IT", Definition: "Information Technology", Source: GlossarySourceMined, Status: GlossaryStatusActive},
+	}, now)
+	if glossary.Lookup("SLA") != nil || len(glossary.Entries) != 3 {
+		t.Fatalf("unexpected entries %+v", glossary.Entries)
+	}
+	if pr := glossary.Lookup("PR"); pr.Definition != "Pull Request" || pr.Occurrences != 7 {
+		t.Fatalf("expected manual entry to survive mining, got %+v", pr)
+	}
+	if glossary.Lookup("IT").Status != GlossaryStatusRejected {
+		t.Fatal("expected rejected entry to stay rejected")
+	}
+
+	expansions := glossary.Expand("Who reviews a PR? Is it ready?")
+	if strings.Join(expansions, ",") != "Pull Request" {
+		t.Fatalf("unexpected expansions %v", expansions)
+	}
+	if expansions := glossary.Expand("how to open a pull request"); strings.Join(expansions, ",") != "PR" {
+		t.Fatalf("expected reverse expansion, got %v", expansions)
+	}
+
+	used := glossary.UsedIn("Each tenant opens a PR. PRs need IT approval.")
+	if len(used) != 2 || used[0].Term != "Tenant" || used[1].Term != "PR" {
+		t.Fatalf("unexpected footnote entries %+v", used)
+	}
+	if footnotes := GlossaryFootnotes(used[1:]); footnotes != "- PR = Pull Request" {
+		t.Fatalf("unexpected footnotes %q", footnotes)
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hedgeBurst caps the hedges saved up during quiet periods, so a burst of
+// slow requests after them cannot all be hedged
+const hedgeBurst = 10
+
+// HedgeStats reports how often the router hedged requests
+type HedgeStats struct {
+	Requests int64 `json:"requests"` // Requests eligible for hedging
+	Hedged   int64 `json:"hedged"`   // Requests also sent to a second provider
+	Wins     int64 `json:"wins"`     // Hedges that answered first
+	Denied   int64 `json:"denied"`   // Hedges skipped because the budget was spent
+}
+
+// hedgeState is the router's hedging budget: every eligible request earns
+// HedgeBudget of a hedge, every hedge spends one
+type hedgeState struct {
+	mu     sync.Mutex
+	credit float64
+	stats  HedgeStats
+}
+
+// HedgeStats returns the hedging counters
+func (r *Router) HedgeStats() HedgeStats {
+	r.hedging.mu.Lock()
+	defer r.hedging.mu.Unlock()
+	return r.hedging.stats
+}
+
+// earnHedge counts an eligible request and adds its share of the budget
+func (r *Router) earnHedge() {
+	r.hedging.mu.Lock()
+	defer r.hedging.mu.Unlock()
+	r.hedging.stats.Requests++
+	r.hedging.credit += r.config.HedgeBudget
+	if r.hedging.credit > hedgeBurst {
+		r.hedging.credit = hedgeBurst
+	}
+}
+
+// spendHedge reports whether the budget allows a hedge, spending it if so
+func (r *Router) spendHedge() bool {
+	r.hedging.mu.Lock()
+	defer r.hedging.mu.Unlock()
+	if r.hedging.credit < 1 {
+		r.hedging.stats.Denied++
+		return false
+	}
+	r.hedging.credit--
+	r.hedging.stats.Hedged++
+	return true
+}
+
+// hedge runs call with failover across the candidates, and if no answer has
+// come within HedgeAfter also across the candidates after the first. The
+// first success wins and the other request is cancelled; an error is
+// returned only once both have failed.
+func hedge[T any](ctx context.Context, r *Router, kind ProviderKind, candidates []*routedProvider, call func(context.Context, *routedProvider) (T, error)) (T, error) {
+	var zero T
+	r.earnHedge()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		value  T
+		err    error
+		hedged bool
+	}
+	// Buffered so the losing request never blocks
+	outcomes := make(chan outcome, 2)
+	run := func(candidates []*routedProvider, hedged bool) {
+		value, err := failover(ctx, r, kind, candidates, call)
+		outcomes <- outcome{value: value, err: err, hedged: hedged}
+	}
+
+	go run(candidates, false)
+	timer := time.NewTimer(r.config.HedgeAfter)
+	defer timer.Stop()
+
+	pending := 1
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			if r.spendHedge() {
+				pending++
+				go run(candidates[1:], true)
+			}
+		case result := <-outcomes:
+			pending--
+			if result.err == nil {
+				if result.hedged {
+					r.hedging.mu.Lock()
+					r.hedging.stats.Wins++
+					r.hedging.mu.Unlock()
+				}
+				return result.value, nil
+			}
+			lastErr = result.err
+			if pending == 0 {
+				return zero, lastErr
+			}
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Highlight defaults
+const (
+	defaultExcerptLength     = 200
+	defaultMaxExcerpts       = 2
+	defaultSemanticThreshold = 0.5
+	maxSemanticSentences     = 20 // Per chunk
+	minSemanticSentence      = 12 // Shorter sentences are not embedded
+)
+
+// Text match types
+const (
+	MatchExact    = "exact"    // The whole query
+	MatchTerm     = "term"     // One or more adjacent query terms
+	MatchSemantic = "semantic" // The sentence closest to the query in meaning
+)
+
+// highlightStopwords are query words that are not highlighted on their own
+var highlightStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"can": true, "do": true, "does": true, "for": true, "from": true, "how": true, "in": true,
+	"is": true, "it": true, "of": true, "on": true, "or": true, "the": true, "this": true, "to": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true, "why": true, "with": true,
+}
+
+// HighlightOptions controls the matches and excerpts added to retrieval results
+type HighlightOptions struct {
+	Disabled      bool `json:"disabled,omitempty"`
+	ExcerptLength int  `json:"excerpt_length,omitempty"` // Characters per excerpt
+	MaxExcerpts   int  `json:"max_excerpts,omitempty"`   // Excerpts per result
+
+	// Semantic highlighting embeds the sentences of each returned chunk to
+	// mark the one closest to the query, at the cost of an embedding call
+	Semantic          bool    `json:"semantic,omitempty"`
+	SemanticThreshold float64 `json:"semantic_threshold,omitempty"`
+}
+
+// Excerpt is a passage of a chunk around its best matches. Positions are
+// character offsets within the chunk content.
+type Excerpt struct {
+	Text     string `json:"text"`
+	StartPos int    `json:"start_pos"`
+	EndPos   int    `json:"end_pos"`
+}
+
+// textSpan is a range of characters in a chunk
+type textSpan struct {
+	start, end int
+}
+
+// highlightResults fills the matches, excerpts and highlights of results
+func (p *Pipeline) highlightResults(ctx context.Context, query, processedQuery string, results []RetrievalResult, options HighlightOptions) {
+	if options.Disabled || len(results) == 0 {
+		return
+	}
+	if options.ExcerptLength <= 0 {
+		options.ExcerptLength = defaultExcerptLength
+	}
+	if options.MaxExcerpts <= 0 {
+		options.MaxExcerpts = defaultMaxExcerpts
+	}
+
+	terms := queryTerms(query)
+	if processedQuery != query {
+		for _, term := range queryTerms(processedQuery) {
+			terms = appendUnique(terms, term)
+		}
+	}
+	for i := range results {
+		if results[i].Chunk == nil {
+			continue
+		}
+		results[i].Matches = matchText(results[i].Chunk.Content, query, terms)
+	}
+	if options.Semantic {
+		p.addSemanticMatches(ctx, query, results, options)
+	}
+	for i := range results {
+		if results[i].Chunk == nil {
+			continue
+		}
+		results[i].Excerpts = buildExcerpts(results[i].Chunk.Content, results[i].Matches, options)
+		results[i].Highlights = make([]string, len(results[i].Excerpts))
+		length := len([]rune(results[i].Chunk.Content))
+		for j, excerpt := range results[i].Excerpts {
+			text := excerpt.Text
+			if excerpt.StartPos > 0 {
+				text = "…" + text
+			}
+			if excerpt.EndPos < length {
+				text += "…"
+			}
+			results[i].Highlights[j] = text
+		}
+	}
+}
+
+// queryTerms splits a query into lower-case terms: words for alphabetic
+// scripts and character bigrams for Han text, which has no word breaks
+func queryTerms(query string) []string {
+	var terms []string
+	var word []rune
+	var han []rune
+	flushWord := func() {
+		if len(word) > 0 && !highlightStopwords[string(word)] && (len(word) > 1 || unicode.IsDigit(word[0])) {
+			terms = appendUnique(terms, string(word))
+		}
+		word = word[:0]
+	}
+	flushHan := func() {
+		if len(han) == 2 {
+			terms = appendUnique(terms, string(han))
+		}
+		for i := 0; len(han) > 2 && i+2 <= len(han); i++ {
+			terms = appendUnique(terms, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+	for _, r := range query {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return terms
+}
+
+// matchText finds the query and its terms in content. Adjacent term matches
+// are merged into one span; positions are character offsets.
+func matchText(content, query string, terms []string) []TextMatch {
+	runes := []rune(content)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var matches []TextMatch
+	var exact []textSpan
+	if phrase := []rune(strings.ToLower(strings.TrimSpace(query))); len(terms) > 1 && len(phrase) > 0 {
+		for _, start := range indexAll(lower, phrase) {
+			exact = append(exact, textSpan{start, start + len(phrase)})
+			matches = append(matches, TextMatch{
+				Text:     string(runes[start : start+len(phrase)]),
+				StartPos: start,
+				EndPos:   start + len(phrase),
+				Score:    1,
+				Type:     MatchExact,
+			})
+		}
+	}
+
+	// Term spans, extended to the end of the word so "refund" marks "refunds"
+	type termSpan struct {
+		textSpan
+		term string
+	}
+	var spans []termSpan
+	for _, term := range terms {
+		pattern := []rune(term)
+		han := unicode.Is(unicode.Han, pattern[0])
+		for _, start := range indexAll(lower, pattern) {
+			if !han && start > 0 && isWordRune(lower[start-1]) {
+				continue
+			}
+			end := start + len(pattern)
+			for !han && end < len(lower) && isWordRune(lower[end]) && !unicode.Is(unicode.Han, lower[end]) {
+				end++
+			}
+			spans = append(spans, termSpan{textSpan{start, end}, term})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+
+	for i := 0; i < len(spans); {
+		merged := spans[i].textSpan
+		matched := map[string]bool{spans[i].term: true}
+		j := i + 1
+		for ; j < len(spans) && spans[j].start <= merged.end; j++ {
+			if spans[j].end > merged.end {
+				merged.end = spans[j].end
+			}
+			matched[spans[j].term] = true
+		}
+		i = j
+		if overlaps(exact, merged) {
+			continue
+		}
+		matches = append(matches, TextMatch{
+			Text:     string(runes[merged.start:merged.end]),
+			StartPos: merged.start,
+			EndPos:   merged.end,
+			Score:    float64(len(matched)) / float64(len(terms)),
+			Type:     MatchTerm,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].StartPos < matches[j].StartPos })
+	return matches
+}
+
+// addSemanticMatches marks the sentence of each chunk closest to the query,
+// embedding the query and all candidate sentences in one call
+func (p *Pipeline) addSemanticMatches(ctx context.Context, query string, results []RetrievalResult, options HighlightOptions) {
+	if p.processor == nil || p.processor.GetEmbeddingGenerator() == nil {
+		return
+	}
+	threshold := options.SemanticThreshold
+	if threshold <= 0 {
+		threshold = defaultSemanticThreshold
+	}
+
+	type candidate struct {
+		result int
+		span   textSpan
+	}
+	texts := []string{query}
+	var candidates []candidate
+	for i, result := range results {
+		if result.Chunk == nil {
+			continue
+		}
+		runes := []rune(result.Chunk.Content)
+		for _, span := range splitSentences(runes) {
+			texts = append(texts, string(runes[span.start:span.end]))
+			candidates = append(candidates, candidate{i, span})
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	vectors, err := p.processor.GetEmbeddingGenerator().Embed(ctx, texts)
+	if err != nil || len(vectors) != len(texts) {
+		if err != nil {
+			p.emitError(ctx, "semantic_highlight", err)
+		}
+		return
+	}
+
+	best := make(map[int]int) // result index -> candidate index
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		scores[i] = cosine(vectors[0], vectors[i+1])
+		if scores[i] < threshold {
+			continue
+		}
+		if current, ok := best[c.result]; !ok || scores[i] > scores[current] {
+			best[c.result] = i
+		}
+	}
+	for resultIndex, i := range best {
+		span := candidates[i].span
+		runes := []rune(results[resultIndex].Chunk.Content)
+		results[resultIndex].Matches = append(results[resultIndex].Matches, TextMatch{
+			Text:     string(runes[span.start:span.end]),
+			StartPos: span.start,
+			EndPos:   span.end,
+			Score:    scores[i],
+			Type:     MatchSemantic,
+		})
+		sort.SliceStable(results[resultIndex].Matches, func(a, b int) bool {
+			return results[resultIndex].Matches[a].StartPos < results[resultIndex].Matches[b].StartPos
+		})
+	}
+}
+
+// splitSentences returns the trimmed sentence spans of text worth embedding
+func splitSentences(runes []rune) []textSpan {
+	var spans []textSpan
+	start := 0
+	for i := 0; i <= len(runes) && len(spans) < maxSemanticSentences; i++ {
+		if i < len(runes) && !strings.ContainsRune(".!?。！？\n", runes[i]) {
+			continue
+		}
+		end := i
+		if i < len(runes) && runes[i] != '\n' {
+			end = i + 1
+		}
+		s, e := start, end
+		for s < e && unicode.IsSpace(runes[s]) {
+			s++
+		}
+		for e > s && unicode.IsSpace(runes[e-1]) {
+			e--
+		}
+		if e-s >= minSemanticSentence {
+			spans = append(spans, textSpan{s, e})
+		}
+		start = i + 1
+	}
+	return spans
+}
+
+// buildExcerpts picks up to MaxExcerpts passages around the best matches, in
+// the order they appear. Content without matches is excerpted from its start.
+func buildExcerpts(content string, matches []TextMatch, options HighlightOptions) []Excerpt {
+	runes := []rune(content)
+	if len(runes) == 0 {
+		return nil
+	}
+	if len(matches) == 0 {
+		end := snapEnd(runes, min(options.ExcerptLength, len(runes)))
+		return []Excerpt{{Text: string(runes[:end]), StartPos: 0, EndPos: end}}
+	}
+
+	ranked := make([]TextMatch, len(matches))
+	copy(ranked, matches)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+
+	var windows []textSpan
+	for _, match := range ranked {
+		if len(windows) == options.MaxExcerpts {
+			break
+		}
+		if overlaps(windows, textSpan{match.StartPos, match.EndPos}) {
+			continue
+		}
+		center := (match.StartPos + match.EndPos) / 2
+		start := max(center-options.ExcerptLength/2, 0)
+		end := min(start+options.ExcerptLength, len(runes))
+		start = max(end-options.ExcerptLength, 0)
+		// Long matches are kept whole
+		start = min(start, match.StartPos)
+		end = max(end, match.EndPos)
+		windows = append(windows, textSpan{snapStart(runes, start), snapEnd(runes, end)})
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].start < windows[j].start })
+
+	excerpts := make([]Excerpt, len(windows))
+	for i, w := range windows {
+		excerpts[i] = Excerpt{Text: string(runes[w.start:w.end]), StartPos: w.start, EndPos: w.end}
+	}
+	return excerpts
+}
+
+// snapStart moves an excerpt start forward to a word boundary nearby
+func snapStart(runes []rune, start int) int {
+	if start == 0 || !isWordRune(runes[start-1]) || !isWordRune(runes[start]) {
+		return start
+	}
+	for i := start; i < len(runes) && i < start+15; i++ {
+		if !isWordRune(runes[i]) {
+			return i + 1
+		}
+	}
+	return start
+}
+
+// snapEnd moves an excerpt end back to a word boundary nearby
+func snapEnd(runes []rune, end int) int {
+	if end == len(runes) || !isWordRune(runes[end]) || !isWordRune(runes[end-1]) {
+		return end
+	}
+	for i := end; i > 0 && i > end-15; i-- {
+		if !isWordRune(runes[i-1]) {
+			return i - 1
+		}
+	}
+	return end
+}
+
+// isWordRune reports whether r belongs to an alphabetic word; Han characters
+// are their own words
+func isWordRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !unicode.Is(unicode.Han, r)
+}
+
+// indexAll returns the start of every non-overlapping occurrence of pattern
+func indexAll(text, pattern []rune) []int {
+	var starts []int
+	for i := 0; i+len(pattern) <= len(text); i++ {
+		j := 0
+		for j < len(pattern) && text[i+j] == pattern[j] {
+			j++
+		}
+		if j == len(pattern) {
+			starts = append(starts, i)
+			i += len(pattern) - 1
+		}
+	}
+	return starts
+}
+
+// overlaps reports whether span overlaps any of spans
+func overlaps(spans []textSpan, span textSpan) bool {
+	for _, s := range spans {
+		if span.start < s.end && s.start < span.end {
+			return true
+		}
+	}
+	return false
+}
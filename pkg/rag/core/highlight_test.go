@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMatchText(t *testing.T) {
+	content := "Refunds are issued within 5 days. See the refund policy for details."
+	matches := matchText(content, "refund policy", queryTerms("refund policy"))
+	if len(matches) != 2 {
+		t.Fatalf("expected a term and an exact match, got %+v", matches)
+	}
+	if m := matches[0]; m.Type != MatchTerm || m.Text != "Refunds" || m.StartPos != 0 || m.EndPos != 7 {
+		t.Fatalf("unexpected term match %+v", m)
+	}
+	if m := matches[1]; m.Type != MatchExact || []rune(content)[m.StartPos] != 'r' || m.Text != "refund policy" {
+		t.Fatalf("unexpected exact match %+v", m)
+	}
+
+	// Offsets are in characters, and Han bigrams merge into one span
+	content = "退款说明：申请退款后五个工作日内到账。"
+	matches = matchText(content, "如何申请退款", queryTerms("如何申请退款"))
+	if len(matches) != 2 {
+		t.Fatalf("unexpected Han matches %+v", matches)
+	}
+	runes := []rune(content)
+	for _, m := range matches {
+		if string(runes[m.StartPos:m.EndPos]) != m.Text {
+			t.Fatalf("offsets do not match text: %+v", m)
+		}
+	}
+	if matches[1].Text != "申请退款" || matches[1].StartPos != 5 {
+		t.Fatalf("expected merged span, got %+v", matches[1])
+	}
+
+	// Words only match at their start
+	if matches := matchText("prefund", "refund", queryTerms("refund")); len(matches) != 0 {
+		t.Fatalf("expected no match inside a word, got %+v", matches)
+	}
+}
+
+func TestBuildExcerpts(t *testing.T) {
+	content := strings.Repeat("filler words here ", 20) + "the sso setup guide " + strings.Repeat("more filler text ", 20)
+	matches := matchText(content, "sso", queryTerms("sso"))
+	excerpts := buildExcerpts(content, matches, HighlightOptions{ExcerptLength: 60, MaxExcerpts: 2})
+	if len(excerpts) != 1 {
+		t.Fatalf("expected one excerpt, got %+v", excerpts)
+	}
+	e := excerpts[0]
+	if !strings.Contains(e.Text, "sso setup") || len([]rune(e.Text)) > 60 || string([]rune(content)[e.StartPos:e.EndPos]) != e.Text {
+		t.Fatalf("unexpected excerpt %+v", e)
+	}
+	if strings.HasPrefix(e.Text, " ") || e.Text[0] == 'i' {
+		t.Fatalf("expected excerpt to start at a word, got %q", e.Text)
+	}
+
+	if excerpts := buildExcerpts("short text", nil, HighlightOptions{ExcerptLength: 60}); len(excerpts) != 1 || excerpts[0].Text != "short text" {
+		t.Fatalf("expected leading excerpt without matches, got %+v", excerpts)
+	}
+}
+
+func TestHighlightResults(t *testing.T) {
+	p := &Pipeline{config: DefaultConfig()}
+	results := []RetrievalResult{
+		{Chunk: &DocumentChunk{Content: "Single sign-on is configured per tenant. Logins use SAML."}},
+		{Chunk: nil},
+	}
+	p.highlightResults(context.Background(), "sign-on tenant", "sign-on tenant", results, HighlightOptions{ExcerptLength: 20})
+	if len(results[0].Matches) != 2 || len(results[0].Highlights) != 2 || !strings.HasSuffix(results[0].Highlights[0], "…") {
+		t.Fatalf("unexpected highlights %+v", results[0])
+	}
+
+	results[0].Matches = nil
+	p.highlightResults(context.Background(), "sso", "sso", results, HighlightOptions{Disabled: true})
+	if results[0].Matches != nil {
+		t.Fatal("expected highlighting to be disabled")
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	runes := []rune("First sentence here. Short. 第二个句子比较长一些吧。\nlast line without stop")
+	spans := splitSentences(runes)
+	var got []string
+	for _, s := range spans {
+		got = append(got, string(runes[s.start:s.end]))
+	}
+	if len(got) != 3 || got[0] != "First sentence here." || got[2] != "last line without stop" {
+		t.Fatalf("unexpected sentences %q", got)
+	}
+}
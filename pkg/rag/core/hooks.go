@@ -0,0 +1,346 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// HookStage is an extension point of the pipeline
+type HookStage string
+
+const (
+	HookPreChunk     HookStage = "pre_chunk"     // Transform a document before it is chunked
+	HookPostChunk    HookStage = "post_chunk"    // Filter or rewrite chunks before they are embedded
+	HookPreGenerate  HookStage = "pre_generate"  // Mutate the query, context and system prompt
+	HookPostGenerate HookStage = "post_generate" // Post-process the generated answer
+)
+
+// HookFailurePolicy decides what a hook failure does to the operation
+type HookFailurePolicy string
+
+const (
+	HookFailAbort HookFailurePolicy = "abort" // Fail the ingest or query
+	HookFailSkip  HookFailurePolicy = "skip"  // Discard the hook's changes and continue
+)
+
+// defaultHookTimeout bounds a hook invocation when the binding sets no timeout
+const defaultHookTimeout = 10 * time.Second
+
+// HookPayload is the data a hook may change. Only the fields of its stage are set:
+// Document for pre_chunk, Document and Chunks for post_chunk, Query, Context and
+// SystemPrompt for pre_generate, and Query and Result for post_generate.
+type HookPayload struct {
+	Stage        HookStage         `json:"stage"`
+	ProjectID    string            `json:"project_id,omitempty"`
+	Document     *Document         `json:"document,omitempty"`
+	Chunks       []DocumentChunk   `json:"chunks,omitempty"`
+	Query        string            `json:"query,omitempty"`
+	Context      []RetrievalResult `json:"context,omitempty"`
+	SystemPrompt string            `json:"system_prompt,omitempty"`
+	Result       *QueryResult      `json:"result,omitempty"`
+}
+
+// clone copies the payload so a failed hook's changes can be discarded. Chunks
+// and context are copied element-wise; nested maps and pointers are shared.
+func (hp *HookPayload) clone() *HookPayload {
+	cloned := *hp
+	if hp.Document != nil {
+		document := *hp.Document
+		cloned.Document = &document
+	}
+	if hp.Chunks != nil {
+		cloned.Chunks = append([]DocumentChunk(nil), hp.Chunks...)
+	}
+	if hp.Context != nil {
+		cloned.Context = append([]RetrievalResult(nil), hp.Context...)
+	}
+	if hp.Result != nil {
+		result := *hp.Result
+		result.Sources = append([]Source(nil), hp.Result.Sources...)
+		cloned.Result = &result
+	}
+	return &cloned
+}
+
+// Hook transforms the payload of a pipeline stage in place
+type Hook interface {
+	Run(ctx context.Context, payload *HookPayload) error
+}
+
+// HookFunc adapts a function to the Hook interface
+type HookFunc func(ctx context.Context, payload *HookPayload) error
+
+// Run implements the Hook interface
+func (f HookFunc) Run(ctx context.Context, payload *HookPayload) error {
+	return f(ctx, payload)
+}
+
+// HookBinding attaches a registered hook or an external plugin to a stage of a
+// project's pipeline
+type HookBinding struct {
+	Stage     HookStage         `json:"stage"`
+	Hook      string            `json:"hook,omitempty"` // Name of a hook registered with RegisterHook
+	URL       string            `json:"url,omitempty"`  // External plugin endpoint, used when Hook is empty
+	Order     int               `json:"order"`          // Lower runs first; ties keep configuration order
+	OnFailure HookFailurePolicy `json:"on_failure,omitempty"`
+	Timeout   time.Duration     `json:"timeout,omitempty"`
+	Disabled  bool              `json:"disabled,omitempty"`
+}
+
+// name identifies the binding in errors
+func (b HookBinding) name() string {
+	if b.Hook != "" {
+		return b.Hook
+	}
+	return b.URL
+}
+
+// Validate checks a hook binding
+func (b HookBinding) Validate() error {
+	switch b.Stage {
+	case HookPreChunk, HookPostChunk, HookPreGenerate, HookPostGenerate:
+	default:
+		return fmt.Errorf("unknown hook stage %q", b.Stage)
+	}
+	switch b.OnFailure {
+	case "", HookFailAbort, HookFailSkip:
+	default:
+		return fmt.Errorf("unknown hook failure policy %q", b.OnFailure)
+	}
+	if (b.Hook == "") == (b.URL == "") {
+		return fmt.Errorf("hook binding needs exactly one of hook or url")
+	}
+	if b.Timeout < 0 {
+		return fmt.Errorf("hook timeout cannot be negative")
+	}
+	return nil
+}
+
+// HookError reports a hook failure that aborted an operation
+type HookError struct {
+	Stage HookStage
+	Hook  string
+	Err   error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook %s: %v", e.Stage, e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookHook runs an external plugin over HTTP. The payload is POSTed as JSON
+// and the plugin answers with the payload to continue with; an empty body
+// leaves the payload unchanged.
+type WebhookHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookHook creates a hook that calls an external plugin
+func NewWebhookHook(url string) *WebhookHook {
+	return &WebhookHook{url: url, client: &http.Client{}}
+}
+
+// Run implements the Hook interface
+func (h *WebhookHook) Run(ctx context.Context, payload *HookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	// Fields the plugin leaves out keep their value; stage and project are fixed
+	var present map[string]json.RawMessage
+	var updated HookPayload
+	if err := json.Unmarshal(data, &present); err != nil {
+		return fmt.Errorf("invalid plugin response: %w", err)
+	}
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return fmt.Errorf("invalid plugin response: %w", err)
+	}
+	if _, ok := present["document"]; ok {
+		payload.Document = updated.Document
+	}
+	if _, ok := present["chunks"]; ok {
+		payload.Chunks = updated.Chunks
+	}
+	if _, ok := present["query"]; ok {
+		payload.Query = updated.Query
+	}
+	if _, ok := present["context"]; ok {
+		payload.Context = updated.Context
+	}
+	if _, ok := present["system_prompt"]; ok {
+		payload.SystemPrompt = updated.SystemPrompt
+	}
+	if _, ok := present["result"]; ok {
+		payload.Result = updated.Result
+	}
+	return nil
+}
+
+// RegisterHook registers a Go hook that projects can bind by name
+func (p *Pipeline) RegisterHook(name string, hook Hook) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.hooks == nil {
+		p.hooks = make(map[string]Hook)
+	}
+	p.hooks[name] = hook
+}
+
+// runHooks runs a project's hooks for a stage in order. Each hook works on a
+// copy of the payload that replaces it on success; failures abort or are
+// skipped according to the binding's policy.
+func (p *Pipeline) runHooks(ctx context.Context, stage HookStage, payload *HookPayload) error {
+	projectConfig, err := p.loadProjectConfig(ctx, payload.ProjectID)
+	if err != nil || projectConfig == nil {
+		return err
+	}
+
+	var bindings []HookBinding
+	for _, binding := range projectConfig.Hooks {
+		if binding.Stage == stage && !binding.Disabled {
+			bindings = append(bindings, binding)
+		}
+	}
+	sort.SliceStable(bindings, func(i, j int) bool {
+		return bindings[i].Order < bindings[j].Order
+	})
+
+	payload.Stage = stage
+	for _, binding := range bindings {
+		working := payload.clone()
+		if err := p.runHook(ctx, binding, working); err != nil {
+			if binding.OnFailure == HookFailSkip {
+				p.emitError(ctx, "hook_"+string(stage), &HookError{Stage: stage, Hook: binding.name(), Err: err})
+				continue
+			}
+			return &HookError{Stage: stage, Hook: binding.name(), Err: err}
+		}
+		*payload = *working
+	}
+	return nil
+}
+
+// runHook resolves and runs one binding within its timeout
+func (p *Pipeline) runHook(ctx context.Context, binding HookBinding, payload *HookPayload) error {
+	var hook Hook
+	if binding.Hook != "" {
+		p.mu.RLock()
+		hook = p.hooks[binding.Hook]
+		p.mu.RUnlock()
+		if hook == nil {
+			return fmt.Errorf("hook not registered")
+		}
+	} else {
+		hook = NewWebhookHook(binding.URL)
+	}
+
+	timeout := binding.Timeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return hook.Run(ctx, payload)
+}
+
+// chunkDocument runs the custom filters and pre_chunk hooks, chunks the
+// document with strategy, or the processor when nil, and runs the post_chunk
+// hooks. Chunks whose content a hook rewrote lose their embedding so the new
+// content is embedded. Documents dropped by a filter have no chunks.
+func (p *Pipeline) chunkDocument(ctx context.Context, doc *Document, strategy ChunkingStrategy) ([]DocumentChunk, error) {
+	if keep, err := p.applyCustomFilters(ctx, doc); err != nil || !keep {
+		return nil, err
+	}
+
+	payload := &HookPayload{ProjectID: documentProjectID(*doc), Document: doc}
+	if err := p.runHooks(ctx, HookPreChunk, payload); err != nil {
+		return nil, err
+	}
+	if payload.Document == nil {
+		return nil, &HookError{Stage: HookPreChunk, Err: fmt.Errorf("hooks removed the document")}
+	}
+	*doc = *payload.Document
+
+	var chunks []DocumentChunk
+	var err error
+	if strategy != nil {
+		chunks, err = strategy.Chunk(ctx, *doc)
+	} else {
+		chunks, err = p.processor.ProcessDocument(ctx, *doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.detectLanguages(doc, chunks)
+
+	original := make(map[string]string, len(chunks))
+	for _, chunk := range chunks {
+		original[chunk.ID] = chunk.Content
+	}
+	payload.Chunks = chunks
+	if err := p.runHooks(ctx, HookPostChunk, payload); err != nil {
+		return nil, err
+	}
+	chunks = payload.Chunks
+	for i := range chunks {
+		if content, ok := original[chunks[i].ID]; !ok || content != chunks[i].Content {
+			chunks[i].Embedding = nil
+			chunks[i].ContentHash = ""
+		}
+	}
+	return chunks, nil
+}
+
+// documentProjectID returns the project a document was ingested for
+func documentProjectID(doc Document) string {
+	projectID, _ := doc.Metadata.Custom["project_id"].(string)
+	return projectID
+}
+
+// withProjectID records the project a document is indexed for, without
+// modifying the metadata map shared with the data source
+func withProjectID(doc Document, projectID string) Document {
+	if projectID == "" || documentProjectID(doc) != "" {
+		return doc
+	}
+	custom := make(map[string]interface{}, len(doc.Metadata.Custom)+1)
+	for key, value := range doc.Metadata.Custom {
+		custom[key] = value
+	}
+	custom["project_id"] = projectID
+	doc.Metadata.Custom = custom
+	return doc
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// staticProjectConfigs serves fixed project configurations
+type staticProjectConfigs map[string]*ProjectConfig
+
+func (s staticProjectConfigs) GetProjectConfig(ctx context.Context, projectID string) (*ProjectConfig, error) {
+	return s[projectID], nil
+}
+
+func (s staticProjectConfigs) SaveProjectConfig(ctx context.Context, config *ProjectConfig) error {
+	s[config.ProjectID] = config
+	return nil
+}
+
+func (s staticProjectConfigs) DeleteProjectConfig(ctx context.Context, projectID string) error {
+	delete(s, projectID)
+	return nil
+}
+
+func TestRunHooksOrderAndFailurePolicy(t *testing.T) {
+	ctx := context.Background()
+	p := &Pipeline{config: DefaultConfig()}
+	p.RegisterHook("suffix", HookFunc(func(ctx context.Context, payload *HookPayload) error {
+		payload.Query += " second"
+		return nil
+	}))
+	p.RegisterHook("prefix", HookFunc(func(ctx context.Context, payload *HookPayload) error {
+		payload.Query = "first " + payload.Query
+		return nil
+	}))
+	p.RegisterHook("broken", HookFunc(func(ctx context.Context, payload *HookPayload) error {
+		payload.Query = "corrupted"
+		return errors.New("boom")
+	}))
+	p.SetProjectConfigStore(staticProjectConfigs{
+		"p1": {ProjectID: "p1", Hooks: []HookBinding{
+			{Stage: HookPreGenerate, Hook: "suffix", Order: 2},
+			{Stage: HookPreGenerate, Hook: "broken", Order: 1, OnFailure: HookFailSkip},
+			{Stage: HookPreGenerate, Hook: "prefix", Order: 1},
+			{Stage: HookPostGenerate, Hook: "broken"},
+		}},
+	})
+
+	payload := &HookPayload{ProjectID: "p1", Query: "query"}
+	if err := p.runHooks(ctx, HookPreGenerate, payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.Query != "first query second" {
+		t.Fatalf("expected skipped hook changes to be discarded, got %q", payload.Query)
+	}
+
+	payload = &HookPayload{ProjectID: "p1", Result: &QueryResult{GeneratedResponse: "answer"}}
+	err := p.runHooks(ctx, HookPostGenerate, payload)
+	var hookErr *HookError
+	if !errors.As(err, &hookErr) || hookErr.Hook != "broken" || hookErr.Stage != HookPostGenerate {
+		t.Fatalf("expected abort, got %v", err)
+	}
+
+	// Projects without hooks leave the payload alone
+	payload = &HookPayload{ProjectID: "other", Query: "query"}
+	if err := p.runHooks(ctx, HookPreGenerate, payload); err != nil || payload.Query != "query" {
+		t.Fatalf("unexpected result %q, %v", payload.Query, err)
+	}
+}
+
+func TestWebhookHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload HookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Stage != HookPostChunk {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		var kept []DocumentChunk
+		for _, chunk := range payload.Chunks {
+			if !strings.Contains(chunk.Content, "secret") {
+				kept = append(kept, chunk)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"chunks": kept, "project_id": "elsewhere"})
+	}))
+	defer server.Close()
+
+	payload := &HookPayload{
+		Stage:     HookPostChunk,
+		ProjectID: "p1",
+		Document:  &Document{ID: "doc"},
+		Chunks:    []DocumentChunk{{ID: "a", Content: "public"}, {ID: "b", Content: "secret"}},
+	}
+	if err := NewWebhookHook(server.URL).Run(context.Background(), payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(payload.Chunks) != 1 || payload.Chunks[0].ID != "a" {
+		t.Fatalf("expected filtered chunks, got %+v", payload.Chunks)
+	}
+	if payload.Document == nil || payload.ProjectID != "p1" {
+		t.Fatalf("expected omitted and fixed fields to be kept, got %+v", payload)
+	}
+}
+
+func TestHookBindingValidate(t *testing.T) {
+	valid := HookBinding{Stage: HookPreChunk, Hook: "scrub"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, binding := range []HookBinding{
+		{Stage: "mid_chunk", Hook: "scrub"},
+		{Stage: HookPreChunk},
+		{Stage: HookPreChunk, Hook: "scrub", URL: "http://plugin"},
+		{Stage: HookPreChunk, Hook: "scrub", OnFailure: "retry"},
+	} {
+		if err := binding.Validate(); err == nil {
+			t.Fatalf("expected %+v to be rejected", binding)
+		}
+	}
+}
+
+func TestWithProjectID(t *testing.T) {
+	custom := map[string]interface{}{"lang": "en"}
+	doc := withProjectID(Document{Metadata: DocumentMetadata{Custom: custom}}, "p1")
+	if documentProjectID(doc) != "p1" || len(custom) != 1 {
+		t.Fatalf("expected project on a copy of the metadata, got %v and %v", doc.Metadata.Custom, custom)
+	}
+	if documentProjectID(withProjectID(doc, "p2")) != "p1" {
+		t.Fatal("expected existing project to be kept")
+	}
+}
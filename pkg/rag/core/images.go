@@ -0,0 +1,224 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ChunkTypeImage marks chunks that represent an image
+const ChunkTypeImage = "image"
+
+// ImageNamespace is the vector namespace holding image embeddings. Image
+// vectors live in a different embedding space than text chunks and are
+// never compared with them directly.
+const ImageNamespace = "image"
+
+// ImageData describes an indexed image
+type ImageData struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mime_type"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	Size     int64  `json:"size"`
+	Caption  string `json:"caption,omitempty"`  // Generated by a vision model
+	OCRText  string `json:"ocr_text,omitempty"` // Text extracted from the image
+}
+
+// ImageInput is an image submitted for indexing
+type ImageInput struct {
+	DocumentID string                 `json:"document_id"` // Document the image belongs to
+	URI        string                 `json:"uri"`
+	Title      string                 `json:"title,omitempty"`
+	Data       []byte                 `json:"-"`
+	MimeType   string                 `json:"mime_type,omitempty"`
+	Caption    string                 `json:"caption,omitempty"` // Optional caption supplied by the caller
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ImageEmbedder generates CLIP-style embeddings where images and text share
+// one vector space, so text queries can be matched against images
+type ImageEmbedder interface {
+	// EmbedImage generates an embedding for image bytes
+	EmbedImage(ctx context.Context, data []byte, mimeType string) ([]float64, error)
+
+	// EmbedText generates an embedding for text in the image vector space
+	EmbedText(ctx context.Context, text string) ([]float64, error)
+
+	// GetModelName returns the model name
+	GetModelName() string
+
+	// GetDimension returns the embedding dimension
+	GetDimension() int
+}
+
+// ImageCaptioner extracts descriptive text from images using OCR or a vision model
+type ImageCaptioner interface {
+	// Caption returns a caption and any text found in the image
+	Caption(ctx context.Context, data []byte, mimeType string) (caption string, ocrText string, err error)
+}
+
+// SetImageIndex configures the image ingestion path. The retriever is the
+// image vector namespace and must embed queries with the same model as embedder.
+func (p *Pipeline) SetImageIndex(embedder ImageEmbedder, captioner ImageCaptioner, retriever Retriever) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.imageEmbedder = embedder
+	p.imageCaptioner = captioner
+	p.imageRetriever = retriever
+}
+
+// IndexImage captions and embeds an image and stores it as an image chunk
+// in the image vector namespace
+func (p *Pipeline) IndexImage(ctx context.Context, input ImageInput) (*DocumentChunk, error) {
+	p.mu.RLock()
+	embedder, captioner, retriever := p.imageEmbedder, p.imageCaptioner, p.imageRetriever
+	p.mu.RUnlock()
+
+	if embedder == nil || retriever == nil {
+		return nil, fmt.Errorf("image indexing is not configured")
+	}
+	if len(input.Data) == 0 {
+		return nil, fmt.Errorf("image data is empty")
+	}
+
+	config := p.config.Processing.Images
+	if config.MaxImageSizeMB > 0 && int64(len(input.Data)) > config.MaxImageSizeMB*1024*1024 {
+		return nil, fmt.Errorf("image exceeds maximum size of %d MB", config.MaxImageSizeMB)
+	}
+
+	mimeType := input.MimeType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(input.Data)
+	}
+	if !isSupportedImageType(mimeType, config.SupportedTypes) {
+		return nil, fmt.Errorf("unsupported image type: %s", mimeType)
+	}
+
+	image := &ImageData{
+		URI:      input.URI,
+		MimeType: mimeType,
+		Size:     int64(len(input.Data)),
+		Caption:  input.Caption,
+	}
+
+	if captioner != nil && image.Caption == "" {
+		caption, ocrText, err := captioner.Caption(ctx, input.Data, mimeType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to caption image: %w", err)
+		}
+		image.Caption = caption
+		image.OCRText = ocrText
+	}
+
+	vector, err := embedder.EmbedImage(ctx, input.Data, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed image: %w", err)
+	}
+
+	documentID := input.DocumentID
+	if documentID == "" {
+		documentID = input.URI
+	}
+
+	content := imageChunkContent(input.Title, image)
+	now := time.Now()
+	chunk := DocumentChunk{
+		ID:             fmt.Sprintf("%s_image_%s", documentID, ContentHash(input.URI)[:12]),
+		DocumentID:     documentID,
+		Content:        content,
+		ChunkIndex:     -1,
+		ChunkType:      ChunkTypeImage,
+		ChunkSize:      len(content),
+		Embedding:      vector,
+		EmbeddingModel: embedder.GetModelName(),
+		EmbeddingDim:   len(vector),
+		IndexVersion:   ImageNamespace + ":" + embedder.GetModelName(),
+		Image:          image,
+		Metadata:       input.Metadata,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := p.storage.StoreChunk(ctx, chunk); err != nil {
+		return nil, fmt.Errorf("failed to store image chunk: %w", err)
+	}
+	if err := p.storage.StoreEmbedding(ctx, chunk.ID, vector); err != nil {
+		return nil, fmt.Errorf("failed to store image embedding: %w", err)
+	}
+	if err := retriever.AddDocument(ctx, chunk); err != nil {
+		return nil, fmt.Errorf("failed to index image: %w", err)
+	}
+
+	return &chunk, nil
+}
+
+// retrieveImages queries the image namespace and labels results by method.
+// Image scores come from a different embedding space, so they are scaled by
+// the configured image weight before being merged with text results.
+func (p *Pipeline) retrieveImages(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	p.mu.RLock()
+	retriever := p.imageRetriever
+	p.mu.RUnlock()
+
+	if retriever == nil {
+		return nil, nil
+	}
+
+	imageOptions := options
+	if options.ImageTopK > 0 {
+		imageOptions.TopK = options.ImageTopK
+	}
+
+	results, err := retriever.Retrieve(ctx, query, imageOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	weight := p.config.Processing.Images.ScoreWeight
+	if weight <= 0 {
+		weight = 1
+	}
+	for i := range results {
+		results[i].Score *= weight
+		results[i].Method = ImageNamespace
+	}
+	return results, nil
+}
+
+// imageChunkContent builds the searchable text for an image chunk
+func imageChunkContent(title string, image *ImageData) string {
+	var parts []string
+	if title != "" {
+		parts = append(parts, title)
+	} else if image.URI != "" {
+		parts = append(parts, filepath.Base(image.URI))
+	}
+	if image.Caption != "" {
+		parts = append(parts, image.Caption)
+	}
+	if image.OCRText != "" {
+		parts = append(parts, image.OCRText)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// isSupportedImageType checks a MIME type against the allowed list
+func isSupportedImageType(mimeType string, supported []string) bool {
+	if !strings.HasPrefix(mimeType, "image/") {
+		return false
+	}
+	if len(supported) == 0 {
+		return true
+	}
+	for _, allowed := range supported {
+		if strings.EqualFold(allowed, mimeType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough of a PNG file for content type detection
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000")
+
+// fixedImageEmbedder embeds every image and text as the same vector
+type fixedImageEmbedder struct{}
+
+func (fixedImageEmbedder) EmbedImage(ctx context.Context, data []byte, mimeType string) ([]float64, error) {
+	return []float64{1, 0}, nil
+}
+
+func (fixedImageEmbedder) EmbedText(ctx context.Context, text string) ([]float64, error) {
+	return []float64{1, 0}, nil
+}
+
+func (fixedImageEmbedder) GetModelName() string { return "clip-test" }
+func (fixedImageEmbedder) GetDimension() int    { return 2 }
+
+// ocrCaptioner captions every image with the same text
+type ocrCaptioner struct {
+	calls int
+}
+
+func (c *ocrCaptioner) Caption(ctx context.Context, data []byte, mimeType string) (string, string, error) {
+	c.calls++
+	return "a bar chart", "Q3 revenue", nil
+}
+
+// imageStorage records stored image chunks and embeddings
+type imageStorage struct {
+	Storage
+	chunks     []DocumentChunk
+	embeddings map[string][]float64
+}
+
+func (s *imageStorage) StoreChunk(ctx context.Context, chunk DocumentChunk) error {
+	s.chunks = append(s.chunks, chunk)
+	return nil
+}
+
+func (s *imageStorage) StoreEmbedding(ctx context.Context, chunkID string, embedding []float64) error {
+	if s.embeddings == nil {
+		s.embeddings = make(map[string][]float64)
+	}
+	s.embeddings[chunkID] = embedding
+	return nil
+}
+
+// scoredRetriever returns every indexed chunk with a fixed score
+type scoredRetriever struct {
+	chunks []DocumentChunk
+	score  float64
+}
+
+func (r *scoredRetriever) Retrieve(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	var results []RetrievalResult
+	for i := range r.chunks {
+		results = append(results, RetrievalResult{DocumentID: r.chunks[i].DocumentID, Chunk: &r.chunks[i], Score: r.score})
+	}
+	if options.TopK > 0 && len(results) > options.TopK {
+		results = results[:options.TopK]
+	}
+	return results, nil
+}
+
+func (r *scoredRetriever) AddDocument(ctx context.Context, chunk DocumentChunk) error {
+	r.chunks = append(r.chunks, chunk)
+	return nil
+}
+
+func (r *scoredRetriever) RemoveDocument(ctx context.Context, chunkID string) error      { return nil }
+func (r *scoredRetriever) UpdateDocument(ctx context.Context, chunk DocumentChunk) error { return nil }
+func (r *scoredRetriever) Clear(ctx context.Context) error                               { r.chunks = nil; return nil }
+func (r *scoredRetriever) GetStats() (*RetrieverStats, error)                            { return &RetrieverStats{}, nil }
+
+func TestIndexImage(t *testing.T) {
+	ctx := context.Background()
+	backend := &imageStorage{}
+	images := &scoredRetriever{score: 0.5}
+	captioner := &ocrCaptioner{}
+	p := &Pipeline{config: DefaultConfig(), storage: backend}
+
+	if _, err := p.IndexImage(ctx, ImageInput{URI: "charts/q3.png", Data: pngHeader}); err == nil {
+		t.Fatal("expected indexing to fail before the image index is configured")
+	}
+	p.SetImageIndex(fixedImageEmbedder{}, captioner, images)
+
+	chunk, err := p.IndexImage(ctx, ImageInput{DocumentID: "report", URI: "charts/q3.png", Data: pngHeader})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk.ChunkType != ChunkTypeImage || chunk.Image.MimeType != "image/png" || chunk.IndexVersion != "image:clip-test" {
+		t.Fatalf("unexpected image chunk %+v", chunk)
+	}
+	if chunk.Content != "q3.png\na bar chart\nQ3 revenue" {
+		t.Fatalf("got content %q", chunk.Content)
+	}
+	if !strings.HasPrefix(chunk.ID, "report_image_") || len(backend.chunks) != 1 || len(backend.embeddings[chunk.ID]) != 2 || len(images.chunks) != 1 {
+		t.Fatalf("expected the chunk to be stored and indexed, got %+v", backend)
+	}
+
+	// A caption supplied by the caller skips the captioner
+	if _, err := p.IndexImage(ctx, ImageInput{URI: "logo.png", Data: pngHeader, Caption: "logo", Title: "Logo"}); err != nil {
+		t.Fatal(err)
+	}
+	if captioner.calls != 1 || images.chunks[1].DocumentID != "logo.png" || images.chunks[1].Content != "Logo\nlogo" {
+		t.Fatalf("unexpected second image %+v", images.chunks[1])
+	}
+}
+
+func TestIndexImageRejectsInvalidInput(t *testing.T) {
+	config := DefaultConfig()
+	config.Processing.Images.MaxImageSizeMB = 1
+	p := &Pipeline{config: config, storage: &imageStorage{}}
+	p.SetImageIndex(fixedImageEmbedder{}, nil, &scoredRetriever{})
+
+	tests := []struct {
+		name  string
+		input ImageInput
+		err   string
+	}{
+		{name: "empty", input: ImageInput{URI: "a.png"}, err: "empty"},
+		{name: "too large", input: ImageInput{URI: "a.png", Data: make([]byte, 1024*1024+1)}, err: "maximum size"},
+		{name: "not an image", input: ImageInput{URI: "a.txt", Data: []byte("plain text")}, err: "unsupported image type"},
+		{name: "type not allowed", input: ImageInput{URI: "a.bmp", Data: []byte("BM0000"), MimeType: "image/bmp"}, err: "unsupported image type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.IndexImage(context.Background(), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRetrieveImagesWeightsScores(t *testing.T) {
+	p := &Pipeline{config: DefaultConfig()}
+	if results, err := p.retrieveImages(context.Background(), "chart", RetrieveOptions{TopK: 5}); err != nil || results != nil {
+		t.Fatalf("expected no image results without an image index, got %v %v", results, err)
+	}
+
+	images := &scoredRetriever{score: 0.5, chunks: []DocumentChunk{{ID: "a"}, {ID: "b"}, {ID: "c"}}}
+	p.SetImageIndex(fixedImageEmbedder{}, nil, images)
+	results, err := p.retrieveImages(context.Background(), "chart", RetrieveOptions{TopK: 5, ImageTopK: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected ImageTopK to limit results, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Method != ImageNamespace || math.Abs(result.Score-0.4) > 1e-9 {
+			t.Fatalf("expected image results scaled by the image weight, got %+v", result)
+		}
+	}
+}
+
+func TestIsSupportedImageType(t *testing.T) {
+	if !isSupportedImageType("image/png", nil) || isSupportedImageType("text/plain", nil) {
+		t.Fatal("unexpected result without an allow list")
+	}
+	if !isSupportedImageType("image/png", []string{"IMAGE/PNG"}) {
+		t.Fatal("expected MIME types to match case-insensitively")
+	}
+	if isSupportedImageType("image/gif", []string{"image/png"}) {
+		t.Fatal("expected types outside the allow list to be rejected")
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// IngestStage is a step of document ingestion
+type IngestStage string
+
+// Ingestion stages, in order
+const (
+	IngestReceived  IngestStage = "received"
+	IngestExtracted IngestStage = "extracted"
+	IngestChunked   IngestStage = "chunked"
+	IngestEmbedded  IngestStage = "embedded"
+	IngestIndexed   IngestStage = "indexed"
+)
+
+// IngestStages lists the ingestion stages in order
+var IngestStages = []IngestStage{IngestReceived, IngestExtracted, IngestChunked, IngestEmbedded, IngestIndexed}
+
+// IngestError reports the stage at which ingestion of a document failed
+type IngestError struct {
+	Stage IngestStage
+	Err   error
+}
+
+func (e *IngestError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *IngestError) Unwrap() error {
+	return e.Err
+}
+
+// IndexDocument chunks, embeds and indexes a single document, calling progress
+// after each completed stage. Failures are returned as *IngestError.
+func (p *Pipeline) IndexDocument(ctx context.Context, doc Document, progress func(IngestStage)) (*IndexResult, error) {
+	if p.processor == nil || p.storage == nil || p.retriever == nil {
+		return nil, &IngestError{Stage: IngestChunked, Err: fmt.Errorf("pipeline not initialized")}
+	}
+
+	startTime := p.now()
+	result := &IndexResult{StartedAt: startTime}
+	indexVersion := IndexVersionFromConfig(p.config.Processing.Embedding).String()
+	err := p.indexDocument(ctx, doc, indexVersion, result, progress)
+
+	result.EmbeddingTime = p.since(startTime)
+	result.CompletedAt = p.now()
+	result.TotalTime = result.CompletedAt.Sub(startTime)
+	return result, err
+}
+
+// indexDocument indexes one document into result. Documents whose content is
+// already indexed under another document are linked to it instead.
+func (p *Pipeline) indexDocument(ctx context.Context, doc Document, indexVersion string, result *IndexResult, progress func(IngestStage)) error {
+	report := func(stage IngestStage) {
+		if progress != nil {
+			progress(stage)
+		}
+	}
+	// Keep the document in its tenant's region
+	ctx, err := p.documentRegionContext(ctx, doc)
+	if err == nil {
+		// Encrypt the document with its tenant's data key
+		ctx, err = p.tenantContext(ctx, documentProjectID(doc))
+	}
+	if err != nil {
+		result.DocumentsErrored++
+		result.Errors = append(result.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
+		return &IngestError{Stage: IngestChunked, Err: err}
+	}
+	if p.docDedup == nil || strings.TrimSpace(doc.Content) == "" {
+		return p.indexContent(ctx, doc, indexVersion, result, report)
+	}
+
+	canonical, promoted := p.registerDocument(ctx, doc)
+	if promoted != nil {
+		// The document's previous content is still referenced elsewhere
+		defer p.promoteDuplicate(ctx, *promoted)
+	}
+	if canonical.DocumentID != doc.ID {
+		return p.linkDuplicate(ctx, doc, canonical, result, report)
+	}
+
+	err = p.indexContent(ctx, doc, indexVersion, result, report)
+	if err != nil {
+		// Let the next occurrence of the content be indexed in full
+		if promoted := p.docDedup.Release(doc.ID); promoted != nil {
+			p.promoteDuplicate(ctx, *promoted)
+		}
+	}
+	return err
+}
+
+// indexContent chunks, embeds and indexes a document. Stage failures skip the
+// rest of the document; chunk store failures are recorded and the remaining
+// chunks are still indexed.
+func (p *Pipeline) indexContent(ctx context.Context, doc Document, indexVersion string, result *IndexResult, report func(IngestStage)) error {
+	fail := func(stage IngestStage, message string, err error) error {
+		result.DocumentsErrored++
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", message, err))
+		return &IngestError{Stage: stage, Err: err}
+	}
+
+	// Process document (chunking and embedding) through the project's hooks
+	chunks, err := p.chunkDocument(ctx, &doc, nil)
+	if err != nil {
+		return fail(IngestChunked, "Document "+doc.ID, err)
+	}
+
+	if len(chunks) == 0 {
+		result.DocumentsSkipped++
+		return &IngestError{Stage: IngestChunked, Err: fmt.Errorf("document has no content to index")}
+	}
+	report(IngestChunked)
+
+	// Assign the version before storing so the record carries it
+	newVersion, err := p.assignDocumentVersion(ctx, &doc)
+	if err != nil {
+		p.emitError(ctx, "document_version", err)
+	}
+
+	// Store document and chunks
+	if err := p.storage.StoreDocument(ctx, doc); err != nil {
+		return fail(IngestEmbedded, "Store document "+doc.ID, err)
+	}
+	if documents, ok := p.cache.(DocumentCache); ok {
+		documents.SetDocument(ctx, &doc, 0)
+	}
+	p.invalidateCachedAnswers(ctx, doc.ID)
+
+	// Re-indexing a soft-deleted document brings it back
+	if p.isTombstoned(doc.ID) {
+		if err := p.clearTombstone(ctx, doc.ID); err != nil {
+			p.emitError(ctx, "clear_tombstone", err)
+		}
+	}
+
+	// Add summary and keyword representations
+	if p.summarizer != nil {
+		derived, err := p.summarizer.SummarizeDocument(ctx, doc, chunks)
+		if err != nil {
+			p.emitError(ctx, "summarize_document", err)
+		}
+		chunks = append(chunks, derived...)
+	}
+
+	// Share embeddings between chunks with identical content
+	indexable, generated, err := p.deduplicateChunks(ctx, p.dedupScope(ctx, documentProjectID(doc)), chunks, indexVersion)
+	if err != nil {
+		return fail(IngestEmbedded, "Embed document "+doc.ID, err)
+	}
+	result.EmbeddingsGenerated += generated
+	report(IngestEmbedded)
+
+	var firstErr error
+	chunkFailed := func(message string, err error) {
+		result.DocumentsErrored++
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", message, err))
+		if firstErr == nil {
+			firstErr = &IngestError{Stage: IngestIndexed, Err: err}
+		}
+	}
+
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) > 0 && chunk.IndexVersion == "" {
+			chunk.IndexVersion = indexVersion
+		}
+
+		if err := p.storage.StoreChunk(ctx, chunk); err != nil {
+			chunkFailed("Store chunk "+chunk.ID, err)
+			continue
+		}
+
+		if len(chunk.Embedding) > 0 && chunk.DuplicateOf == "" {
+			if err := p.storage.StoreEmbedding(ctx, chunk.ID, chunk.Embedding); err != nil {
+				chunkFailed("Store embedding "+chunk.ID, err)
+				continue
+			}
+		}
+	}
+
+	if newVersion {
+		if err := p.recordDocumentVersion(ctx, doc, chunks); err != nil {
+			p.emitError(ctx, "document_version", err)
+		}
+	}
+
+	// Add canonical chunks to retriever
+	for _, chunk := range indexable {
+		if err := p.retriever.AddDocument(ctx, chunk); err != nil {
+			chunkFailed("Add to retriever "+chunk.ID, err)
+			continue
+		}
+	}
+	p.dualWriteChunks(ctx, indexable, indexVersion)
+
+	result.DocumentsProcessed++
+	result.DocumentsAdded++
+	result.ChunksCreated += len(chunks)
+	if firstErr == nil {
+		report(IngestIndexed)
+	}
+	return firstErr
+}
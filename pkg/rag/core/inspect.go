@@ -0,0 +1,127 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Chunk embedding states reported by InspectChunks
+const (
+	ChunkEmbeddingCurrent = "current" // embedded under the active index version
+	ChunkEmbeddingStale   = "stale"   // embedded under another index version
+	ChunkEmbeddingMissing = "missing" // no vector, so the chunk is only found by keyword search
+)
+
+// chunkPreviewRunes limits the chunk text returned for inspection
+const chunkPreviewRunes = 200
+
+// ChunkInspection describes how one chunk of a document was indexed
+type ChunkInspection struct {
+	ID         string `json:"id"`
+	ChunkIndex int    `json:"chunk_index"`
+	ChunkType  string `json:"chunk_type"`
+	StartPos   int    `json:"start_pos"`
+	EndPos     int    `json:"end_pos"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	ChunkSize  int    `json:"chunk_size"`
+	TokenCount int    `json:"token_count"`
+
+	EmbeddingStatus string `json:"embedding_status"`
+	EmbeddingModel  string `json:"embedding_model,omitempty"`
+	EmbeddingDim    int    `json:"embedding_dim,omitempty"`
+	IndexVersion    string `json:"index_version,omitempty"`
+	DuplicateOf     string `json:"duplicate_of,omitempty"`
+
+	Preview string `json:"preview"`
+}
+
+// DocumentInspection is the chunk layout of a document with embedding coverage
+type DocumentInspection struct {
+	DocumentID   string            `json:"document_id"`
+	Title        string            `json:"title"`
+	DataSourceID string            `json:"data_source_id"`
+	IndexVersion string            `json:"index_version"` // Active index version chunks are compared against
+	Chunks       []ChunkInspection `json:"chunks"`
+
+	TotalTokens int `json:"total_tokens"`
+	Embedded    int `json:"embedded"`
+	Stale       int `json:"stale"`
+	Missing     int `json:"missing"`
+}
+
+// InspectChunks reports the chunk boundaries, token counts and embedding
+// status of a document, to diagnose why it is or isn't retrieved. It
+// returns nil when the document does not exist.
+func (p *Pipeline) InspectChunks(ctx context.Context, documentID string) (*DocumentInspection, error) {
+	doc, err := p.GetDocument(ctx, documentID)
+	if err != nil || doc == nil {
+		return nil, err
+	}
+	chunks, err := p.storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	inspection := &DocumentInspection{
+		DocumentID:   doc.ID,
+		Title:        doc.Title,
+		DataSourceID: doc.DataSourceID,
+		IndexVersion: IndexVersionFromConfig(p.config.Processing.Embedding).String(),
+		Chunks:       make([]ChunkInspection, 0, len(chunks)),
+	}
+	for _, chunk := range chunks {
+		info := ChunkInspection{
+			ID:             chunk.ID,
+			ChunkIndex:     chunk.ChunkIndex,
+			ChunkType:      chunk.ChunkType,
+			StartPos:       chunk.StartPos,
+			EndPos:         chunk.EndPos,
+			StartLine:      chunk.StartLine,
+			EndLine:        chunk.EndLine,
+			ChunkSize:      chunk.ChunkSize,
+			TokenCount:     chunk.TokenCount,
+			EmbeddingModel: chunk.EmbeddingModel,
+			EmbeddingDim:   chunk.EmbeddingDim,
+			IndexVersion:   chunk.IndexVersion,
+			DuplicateOf:    chunk.DuplicateOf,
+			Preview:        truncateRunes(chunk.Content, chunkPreviewRunes),
+		}
+		if info.ChunkSize == 0 {
+			info.ChunkSize = len(chunk.Content)
+		}
+		if info.TokenCount == 0 {
+			info.TokenCount = EstimateTokens(chunk.Content)
+		}
+
+		// Some storages keep vectors apart from chunk records
+		vector := chunk.Embedding
+		if len(vector) == 0 {
+			if stored, err := p.storage.GetEmbedding(ctx, chunk.ID); err == nil {
+				vector = stored
+			}
+		}
+		switch {
+		case len(vector) == 0:
+			info.EmbeddingStatus = ChunkEmbeddingMissing
+			inspection.Missing++
+		case chunk.IndexVersion != "" && chunk.IndexVersion != inspection.IndexVersion:
+			info.EmbeddingStatus = ChunkEmbeddingStale
+			inspection.Stale++
+		default:
+			info.EmbeddingStatus = ChunkEmbeddingCurrent
+			inspection.Embedded++
+		}
+		if info.EmbeddingDim == 0 {
+			info.EmbeddingDim = len(vector)
+		}
+
+		inspection.TotalTokens += info.TokenCount
+		inspection.Chunks = append(inspection.Chunks, info)
+	}
+	sort.SliceStable(inspection.Chunks, func(i, j int) bool {
+		return inspection.Chunks[i].ChunkIndex < inspection.Chunks[j].ChunkIndex
+	})
+	return inspection, nil
+}
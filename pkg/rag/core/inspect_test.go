@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// chunkStorage serves one document with its chunks and separately stored vectors
+type chunkStorage struct {
+	Storage
+	doc     Document
+	chunks  []DocumentChunk
+	vectors map[string][]float64
+}
+
+func (s *chunkStorage) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	if documentID != s.doc.ID {
+		return nil, nil
+	}
+	doc := s.doc
+	return &doc, nil
+}
+
+func (s *chunkStorage) ListChunks(ctx context.Context, documentID string) ([]DocumentChunk, error) {
+	return s.chunks, nil
+}
+
+func (s *chunkStorage) GetEmbedding(ctx context.Context, chunkID string) ([]float64, error) {
+	if vector, ok := s.vectors[chunkID]; ok {
+		return vector, nil
+	}
+	return nil, errors.New("embedding not found")
+}
+
+func TestInspectChunks(t *testing.T) {
+	config := DefaultConfig()
+	current := IndexVersionFromConfig(config.Processing.Embedding).String()
+	storage := &chunkStorage{
+		doc: Document{ID: "d1", Title: "Guide", DataSourceID: "s1"},
+		chunks: []DocumentChunk{
+			{ID: "c2", ChunkIndex: 1, Content: "second chunk", Embedding: []float64{1, 2}, IndexVersion: "openai/old-model@2"},
+			{ID: "c1", ChunkIndex: 0, Content: "first chunk of text", TokenCount: 4, IndexVersion: current},
+			{ID: "c3", ChunkIndex: 2, Content: "third"},
+		},
+		vectors: map[string][]float64{"c1": {1, 2, 3}},
+	}
+	p := &Pipeline{config: config, storage: storage}
+
+	inspection, err := p.InspectChunks(context.Background(), "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspection.Embedded != 1 || inspection.Stale != 1 || inspection.Missing != 1 || inspection.DataSourceID != "s1" {
+		t.Fatalf("unexpected summary %+v", inspection)
+	}
+	c1, c2, c3 := inspection.Chunks[0], inspection.Chunks[1], inspection.Chunks[2]
+	if c1.ID != "c1" || c1.EmbeddingStatus != ChunkEmbeddingCurrent || c1.EmbeddingDim != 3 || c1.TokenCount != 4 {
+		t.Fatalf("unexpected first chunk %+v", c1)
+	}
+	if c2.EmbeddingStatus != ChunkEmbeddingStale || c3.EmbeddingStatus != ChunkEmbeddingMissing || c3.TokenCount == 0 {
+		t.Fatalf("unexpected chunks %+v %+v", c2, c3)
+	}
+
+	if missing, err := p.InspectChunks(context.Background(), "nope"); err != nil || missing != nil {
+		t.Fatalf("expected unknown document to return nil, got %+v %v", missing, err)
+	}
+}
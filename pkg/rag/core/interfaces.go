@@ -275,6 +275,38 @@ type Cache interface {
 	Close() error
 }
 
+// EmbeddingCache is implemented by caches that also store embedding vectors
+type EmbeddingCache interface {
+	// GetEmbedding returns a cached vector, or nil on a miss
+	GetEmbedding(ctx context.Context, key string) ([]float64, error)
+
+	// SetEmbedding stores a vector
+	SetEmbedding(ctx context.Context, key string, vector []float64, ttl time.Duration) error
+}
+
+// DocumentCache is implemented by caches that also store documents
+type DocumentCache interface {
+	// GetDocument returns a cached document, or nil on a miss
+	GetDocument(ctx context.Context, id string) (*Document, error)
+
+	// SetDocument stores a document
+	SetDocument(ctx context.Context, doc *Document, ttl time.Duration) error
+
+	// DeleteDocument removes a cached document
+	DeleteDocument(ctx context.Context, id string) error
+}
+
+// DependentCache is implemented by caches that track the documents each cached
+// query result was built from, so results can be invalidated per document
+type DependentCache interface {
+	// SetWithDocuments stores a query result and records the documents it depends on
+	SetWithDocuments(ctx context.Context, key string, result *QueryResult, documentIDs []string, ttl time.Duration) error
+
+	// InvalidateDocuments removes the results depending on any of the
+	// documents and returns how many were removed
+	InvalidateDocuments(ctx context.Context, documentIDs []string) (int, error)
+}
+
 // Filter defines the interface for filtering retrieval results
 type Filter interface {
 	// Filter filters retrieval results based on criteria
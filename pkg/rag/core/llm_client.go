@@ -0,0 +1,148 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// APIClient is an LLMClient backed by an OpenAI-compatible HTTP API
+type APIClient struct {
+	config *llm.Config
+}
+
+// NewAPIClient creates an LLM client for an OpenAI-compatible API
+func NewAPIClient(config *llm.Config) *APIClient {
+	return &APIClient{config: config}
+}
+
+// GenerateCompletion implements the LLMClient interface. Structured output
+// and tools are requested natively when the model supports them; otherwise
+// they are dropped and callers rely on prompting and validation.
+func (c *APIClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	model := options.Model
+	if model == "" {
+		model = c.config.Model
+	}
+
+	request := llm.ChatCompletionRequest{
+		Model:       model,
+		Messages:    messages,
+		MaxTokens:   options.MaxTokens,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		Stop:        options.Stop,
+		Seed:        options.Seed,
+	}
+	if format := options.ResponseFormat; format != nil && c.SupportsResponseFormat(model, format.Type) {
+		request.ResponseFormat = format
+	}
+	if len(options.Tools) > 0 && llm.SupportsCapability(model, "tools") {
+		request.Tools = options.Tools
+		request.ToolChoice = options.ToolChoice
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response, err := llm.CreateChatCompletion(request, c.config)
+	if err != nil {
+		return nil, err
+	}
+	return completionResponse(response), nil
+}
+
+// StreamCompletion implements the StreamingLLMClient interface. Cancelling
+// ctx aborts the HTTP request to the model.
+func (c *APIClient) StreamCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions, onToken func(token string) error) (*CompletionResponse, error) {
+	model := options.Model
+	if model == "" {
+		model = c.config.Model
+	}
+
+	response, err := llm.StreamChatCompletion(ctx, llm.ChatCompletionRequest{
+		Model:       model,
+		Messages:    messages,
+		MaxTokens:   options.MaxTokens,
+		Temperature: options.Temperature,
+		TopP:        options.TopP,
+		Stop:        options.Stop,
+		Seed:        options.Seed,
+	}, c.config, onToken)
+	if err != nil {
+		return nil, err
+	}
+	return completionResponse(response), nil
+}
+
+// SupportsResponseFormat reports whether the model accepts a native response format
+func (c *APIClient) SupportsResponseFormat(model, formatType string) bool {
+	return llm.SupportsCapability(model, formatType)
+}
+
+// GenerateEmbedding implements the LLMClient interface
+func (c *APIClient) GenerateEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
+	return llm.EnhancedEmbeddings(texts, c.config)
+}
+
+// Rerank implements the LLMClient interface
+func (c *APIClient) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	return llm.EnhancedRerank(query, documents, c.config)
+}
+
+// GetModelInfo implements the LLMClient interface
+func (c *APIClient) GetModelInfo() (*ModelInfo, error) {
+	for _, info := range llm.GetSupportedModels() {
+		if info.Name == c.config.Model {
+			return &ModelInfo{
+				Name:         info.Name,
+				Type:         info.Type,
+				Provider:     info.Provider,
+				MaxTokens:    info.MaxTokens,
+				Capabilities: info.Capabilities,
+			}, nil
+		}
+	}
+	return &ModelInfo{Name: c.config.Model, Type: "chat"}, nil
+}
+
+// Validate implements the LLMClient interface
+func (c *APIClient) Validate() error {
+	if c.config == nil || c.config.BaseURL == "" || c.config.APIKey == "" {
+		return fmt.Errorf("llm client not configured: missing BaseURL or APIKey")
+	}
+	return nil
+}
+
+// Close implements the LLMClient interface
+func (c *APIClient) Close() error {
+	return nil
+}
+
+// completionResponse converts an API response to a CompletionResponse
+func completionResponse(response *llm.ChatCompletionResponse) *CompletionResponse {
+	result := &CompletionResponse{
+		ID:      response.ID,
+		Object:  response.Object,
+		Created: response.Created,
+		Model:   response.Model,
+		Usage: CompletionUsage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.Usage.TotalTokens,
+		},
+	}
+	for _, choice := range response.Choices {
+		result.Choices = append(result.Choices, CompletionChoice{
+			Index: choice.Index,
+			Message: llm.ChatMessage{
+				Role:      choice.Message.Role,
+				Content:   choice.Message.Content,
+				ToolCalls: choice.Message.ToolCalls,
+			},
+			FinishReason: choice.FinishReason,
+		})
+	}
+	return result
+}
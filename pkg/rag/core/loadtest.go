@@ -0,0 +1,417 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of load test queries
+const (
+	LoadQueryKeyword = "keyword" // A rare term found in few documents
+	LoadQueryPhrase  = "phrase"  // Several words of a topic, answered by many documents
+	LoadQueryRepeat  = "repeat"  // One of a few hot queries, exercising the cache
+	LoadQueryMiss    = "miss"    // Terms no document contains
+)
+
+// LoadTarget is the system under a RAG load test, e.g. an in-process
+// pipeline or an API server
+type LoadTarget interface {
+	// Index indexes the corpus and returns once it is searchable
+	Index(ctx context.Context, docs []Document) error
+
+	// Query runs one query and reports whether it was served from cache
+	Query(ctx context.Context, query string) (cacheHit bool, err error)
+
+	// SystemMetrics samples the target's memory, nil when unavailable
+	SystemMetrics(ctx context.Context) (*SystemMetrics, error)
+}
+
+// CorpusOptions shape a synthetic corpus
+type CorpusOptions struct {
+	Documents        int   `json:"documents"`
+	WordsPerDocument int   `json:"words_per_document"`
+	Topics           int   `json:"topics"` // Documents of a topic share vocabulary
+	Seed             int64 `json:"seed"`
+}
+
+// QueryMix weighs the kinds of queries a load test sends
+type QueryMix struct {
+	Keyword float64 `json:"keyword"`
+	Phrase  float64 `json:"phrase"`
+	Repeat  float64 `json:"repeat"`
+	Miss    float64 `json:"miss"`
+}
+
+// LoadTestOptions configure a load test. Queries are sent at a fixed rate
+// regardless of latency (open loop); a query due while all workers are busy
+// is counted as dropped rather than delayed, so an overloaded target shows
+// up as lost throughput instead of hiding behind the sender.
+type LoadTestOptions struct {
+	Corpus      CorpusOptions `json:"corpus"`
+	QPS         float64       `json:"qps"`
+	Duration    time.Duration `json:"duration"`
+	Concurrency int           `json:"concurrency"`
+	Mix         QueryMix      `json:"mix"`
+	SkipIndex   bool          `json:"skip_index"` // Query a corpus indexed by an earlier run
+}
+
+// LatencyStats summarizes query latencies
+type LatencyStats struct {
+	Count int           `json:"count"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+// LoadTestReport is the outcome of a load test
+type LoadTestReport struct {
+	Documents       int           `json:"documents"`
+	IndexTime       time.Duration `json:"index_time"`
+	IndexThroughput float64       `json:"index_throughput"` // Documents per second
+
+	Sent         int     `json:"sent"`
+	Completed    int     `json:"completed"`
+	Errors       int     `json:"errors"`
+	Dropped      int     `json:"dropped"`    // Due while every worker was busy
+	Throughput   float64 `json:"throughput"` // Completed queries per second
+	CacheHits    int     `json:"cache_hits"`
+	CacheHitRate float64 `json:"cache_hit_rate"`
+
+	Latency LatencyStats            `json:"latency"`
+	ByKind  map[string]LatencyStats `json:"by_kind"`
+
+	MemoryBefore *SystemMetrics `json:"memory_before,omitempty"`
+	MemoryAfter  *SystemMetrics `json:"memory_after,omitempty"`
+
+	FirstError string        `json:"first_error,omitempty"`
+	Elapsed    time.Duration `json:"elapsed"`
+}
+
+// DefaultLoadTestOptions returns a short, moderate load test
+func DefaultLoadTestOptions() LoadTestOptions {
+	return LoadTestOptions{
+		Corpus:      CorpusOptions{Documents: 200, WordsPerDocument: 300, Topics: 10, Seed: 1},
+		QPS:         10,
+		Duration:    30 * time.Second,
+		Concurrency: 8,
+		Mix:         QueryMix{Keyword: 0.3, Phrase: 0.4, Repeat: 0.2, Miss: 0.1},
+	}
+}
+
+// Validate checks the options describe a runnable test
+func (o LoadTestOptions) Validate() error {
+	if o.Corpus.Documents <= 0 || o.Corpus.WordsPerDocument <= 0 || o.Corpus.Topics <= 0 {
+		return fmt.Errorf("corpus needs positive documents, words per document and topics")
+	}
+	if o.QPS <= 0 || o.Duration <= 0 || o.Concurrency <= 0 {
+		return fmt.Errorf("qps, duration and concurrency must be positive")
+	}
+	m := o.Mix
+	if m.Keyword < 0 || m.Phrase < 0 || m.Repeat < 0 || m.Miss < 0 || m.Keyword+m.Phrase+m.Repeat+m.Miss == 0 {
+		return fmt.Errorf("query mix weights must be non-negative and not all zero")
+	}
+	return nil
+}
+
+// SyntheticCorpus is a generated corpus with the vocabulary to query it
+type SyntheticCorpus struct {
+	Documents []Document
+
+	topics [][]string // Common words of each topic
+	rare   []string   // One term per document, found only there
+	hot    []string   // Queries repeated to exercise the cache
+}
+
+// GenerateCorpus builds a deterministic synthetic corpus. Each document
+// draws most words from its topic, some from the shared vocabulary and
+// carries one rare term for keyword lookups.
+func GenerateCorpus(options CorpusOptions) *SyntheticCorpus {
+	rng := rand.New(rand.NewSource(options.Seed))
+	word := func() string {
+		const letters = "abcdefghijklmnopqrstuvwxyz"
+		b := make([]byte, 4+rng.Intn(6))
+		for i := range b {
+			b[i] = letters[rng.Intn(len(letters))]
+		}
+		return string(b)
+	}
+
+	corpus := &SyntheticCorpus{}
+	shared := make([]string, 200)
+	for i := range shared {
+		shared[i] = word()
+	}
+	corpus.topics = make([][]string, options.Topics)
+	for t := range corpus.topics {
+		corpus.topics[t] = make([]string, 50)
+		for i := range corpus.topics[t] {
+			corpus.topics[t][i] = word()
+		}
+	}
+
+	now := time.Now()
+	corpus.Documents = make([]Document, options.Documents)
+	corpus.rare = make([]string, options.Documents)
+	for d := range corpus.Documents {
+		topic := corpus.topics[d%options.Topics]
+		corpus.rare[d] = fmt.Sprintf("zq%dx%s", d, word())
+		words := make([]string, options.WordsPerDocument)
+		for i := range words {
+			if rng.Float64() < 0.7 {
+				words[i] = topic[rng.Intn(len(topic))]
+			} else {
+				words[i] = shared[rng.Intn(len(shared))]
+			}
+		}
+		words[rng.Intn(len(words))] = corpus.rare[d]
+		id := fmt.Sprintf("bench_%06d", d)
+		corpus.Documents[d] = Document{
+			ID:         id,
+			Title:      fmt.Sprintf("Synthetic document %d", d),
+			Content:    strings.Join(words, " "),
+			URI:        id + ".txt",
+			SourceType: "synthetic",
+			Metadata:   DocumentMetadata{FileName: id + ".txt", CreatedAt: now, ModifiedAt: now},
+			UpdatedAt:  now,
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		corpus.hot = append(corpus.hot, corpus.phrase(rng))
+	}
+	return corpus
+}
+
+// phrase returns a few words of a random topic
+func (c *SyntheticCorpus) phrase(rng *rand.Rand) string {
+	topic := c.topics[rng.Intn(len(c.topics))]
+	words := make([]string, 3)
+	for i := range words {
+		words[i] = topic[rng.Intn(len(topic))]
+	}
+	return strings.Join(words, " ")
+}
+
+// Query draws a query of the given kind
+func (c *SyntheticCorpus) Query(rng *rand.Rand, kind string) string {
+	switch kind {
+	case LoadQueryKeyword:
+		return c.rare[rng.Intn(len(c.rare))]
+	case LoadQueryRepeat:
+		return c.hot[rng.Intn(len(c.hot))]
+	case LoadQueryMiss:
+		return fmt.Sprintf("nomatch%d unknownterm%d", rng.Intn(1e6), rng.Intn(1e6))
+	}
+	return c.phrase(rng)
+}
+
+// pickKind draws a query kind by the mix weights
+func (m QueryMix) pickKind(rng *rand.Rand) string {
+	x := rng.Float64() * (m.Keyword + m.Phrase + m.Repeat + m.Miss)
+	switch {
+	case x < m.Keyword:
+		return LoadQueryKeyword
+	case x < m.Keyword+m.Phrase:
+		return LoadQueryPhrase
+	case x < m.Keyword+m.Phrase+m.Repeat:
+		return LoadQueryRepeat
+	}
+	return LoadQueryMiss
+}
+
+// loadSample is the outcome of one query
+type loadSample struct {
+	kind     string
+	latency  time.Duration
+	cacheHit bool
+	err      error
+}
+
+// RunLoadTest indexes a synthetic corpus into target, then queries it at
+// the configured rate for the configured duration
+func RunLoadTest(ctx context.Context, target LoadTarget, options LoadTestOptions) (*LoadTestReport, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	corpus := GenerateCorpus(options.Corpus)
+	report := &LoadTestReport{Documents: len(corpus.Documents), ByKind: make(map[string]LatencyStats)}
+	report.MemoryBefore, _ = target.SystemMetrics(ctx)
+
+	if !options.SkipIndex {
+		start := time.Now()
+		if err := target.Index(ctx, corpus.Documents); err != nil {
+			return nil, fmt.Errorf("failed to index corpus: %w", err)
+		}
+		report.IndexTime = time.Since(start)
+		if seconds := report.IndexTime.Seconds(); seconds > 0 {
+			report.IndexThroughput = float64(len(corpus.Documents)) / seconds
+		}
+	}
+
+	// Workers take queries from an unbuffered channel, so a send that would
+	// block means every worker is busy
+	type job struct{ kind, query string }
+	jobs := make(chan job)
+	samples := make(chan loadSample, options.Concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < options.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				start := time.Now()
+				hit, err := target.Query(ctx, j.query)
+				samples <- loadSample{kind: j.kind, latency: time.Since(start), cacheHit: hit, err: err}
+			}
+		}()
+	}
+
+	var collected []loadSample
+	done := make(chan struct{})
+	go func() {
+		for sample := range samples {
+			collected = append(collected, sample)
+		}
+		close(done)
+	}()
+
+	rng := rand.New(rand.NewSource(options.Corpus.Seed + 1))
+	interval := time.Duration(float64(time.Second) / options.QPS)
+	ticker := time.NewTicker(interval)
+	deadline := time.NewTimer(options.Duration)
+	start := time.Now()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-deadline.C:
+			break loop
+		case <-ticker.C:
+			kind := options.Mix.pickKind(rng)
+			report.Sent++
+			select {
+			case jobs <- job{kind: kind, query: corpus.Query(rng, kind)}:
+			default:
+				report.Dropped++
+			}
+		}
+	}
+	ticker.Stop()
+	deadline.Stop()
+	close(jobs)
+	wg.Wait()
+	close(samples)
+	<-done
+	report.Elapsed = time.Since(start)
+
+	report.MemoryAfter, _ = target.SystemMetrics(ctx)
+	summarizeLoadTest(report, collected)
+	return report, nil
+}
+
+// summarizeLoadTest fills the report's counters and latency statistics
+func summarizeLoadTest(report *LoadTestReport, samples []loadSample) {
+	var all []time.Duration
+	byKind := make(map[string][]time.Duration)
+	for _, sample := range samples {
+		if sample.err != nil {
+			report.Errors++
+			if report.FirstError == "" {
+				report.FirstError = sample.err.Error()
+			}
+			continue
+		}
+		report.Completed++
+		if sample.cacheHit {
+			report.CacheHits++
+		}
+		all = append(all, sample.latency)
+		byKind[sample.kind] = append(byKind[sample.kind], sample.latency)
+	}
+	if report.Completed > 0 {
+		report.CacheHitRate = float64(report.CacheHits) / float64(report.Completed)
+	}
+	if seconds := report.Elapsed.Seconds(); seconds > 0 {
+		report.Throughput = float64(report.Completed) / seconds
+	}
+	report.Latency = latencyStats(all)
+	for kind, latencies := range byKind {
+		report.ByKind[kind] = latencyStats(latencies)
+	}
+}
+
+// latencyStats computes percentiles by the nearest-rank method
+func latencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return sorted[rank]
+	}
+	return LatencyStats{
+		Count: len(sorted),
+		Mean:  total / time.Duration(len(sorted)),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P95:   percentile(95),
+		P99:   percentile(99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// PipelineLoadTarget load tests an in-process pipeline
+type PipelineLoadTarget struct {
+	pipeline *Pipeline
+	options  QueryOptions
+}
+
+// NewPipelineLoadTarget creates a load target querying the pipeline with
+// the given options
+func NewPipelineLoadTarget(pipeline *Pipeline, options QueryOptions) *PipelineLoadTarget {
+	return &PipelineLoadTarget{pipeline: pipeline, options: options}
+}
+
+// Index indexes the documents one by one
+func (t *PipelineLoadTarget) Index(ctx context.Context, docs []Document) error {
+	for _, doc := range docs {
+		if _, err := t.pipeline.IndexDocument(ctx, doc, nil); err != nil {
+			return fmt.Errorf("document %s: %w", doc.ID, err)
+		}
+	}
+	return nil
+}
+
+// Query runs a full RAG query
+func (t *PipelineLoadTarget) Query(ctx context.Context, query string) (bool, error) {
+	result, err := t.pipeline.Query(ctx, query, t.options)
+	if err != nil {
+		return false, err
+	}
+	return result.CacheHit, nil
+}
+
+// SystemMetrics samples the current process
+func (t *PipelineLoadTarget) SystemMetrics(ctx context.Context) (*SystemMetrics, error) {
+	metrics := CollectSystemMetrics()
+	return &metrics, nil
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryTarget answers from an in-memory corpus, caching repeated queries
+type memoryTarget struct {
+	mu     sync.Mutex
+	docs   []Document
+	seen   map[string]bool
+	failOn string
+}
+
+func (t *memoryTarget) Index(ctx context.Context, docs []Document) error {
+	t.docs = docs
+	t.seen = make(map[string]bool)
+	return nil
+}
+
+func (t *memoryTarget) Query(ctx context.Context, query string) (bool, error) {
+	if t.failOn != "" && strings.HasPrefix(query, t.failOn) {
+		return false, errors.New("query failed")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hit := t.seen[query]
+	t.seen[query] = true
+	return hit, nil
+}
+
+func (t *memoryTarget) SystemMetrics(ctx context.Context) (*SystemMetrics, error) {
+	metrics := CollectSystemMetrics()
+	return &metrics, nil
+}
+
+func TestGenerateCorpus(t *testing.T) {
+	options := CorpusOptions{Documents: 20, WordsPerDocument: 50, Topics: 4, Seed: 7}
+	a, b := GenerateCorpus(options), GenerateCorpus(options)
+	if len(a.Documents) != 20 || a.Documents[3].Content != b.Documents[3].Content {
+		t.Fatal("expected a deterministic corpus of 20 documents")
+	}
+	for d, doc := range a.Documents {
+		if !strings.Contains(doc.Content, a.rare[d]) {
+			t.Fatalf("document %d lacks its rare term", d)
+		}
+	}
+}
+
+func TestRunLoadTest(t *testing.T) {
+	options := DefaultLoadTestOptions()
+	options.Corpus.Documents = 20
+	options.QPS = 200
+	options.Duration = 300 * time.Millisecond
+	options.Mix = QueryMix{Repeat: 1}
+	target := &memoryTarget{}
+
+	report, err := RunLoadTest(context.Background(), target, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Completed == 0 || report.Completed+report.Dropped+report.Errors != report.Sent {
+		t.Fatalf("unexpected counts %+v", report)
+	}
+	// Only five hot queries exist, so nearly every repeat is a cache hit
+	if report.CacheHitRate < 0.5 || report.ByKind[LoadQueryRepeat].Count != report.Completed {
+		t.Fatalf("expected repeated queries to hit the cache, got %+v", report)
+	}
+	if report.Latency.P50 > report.Latency.P99 || report.Latency.P99 > report.Latency.Max || report.MemoryAfter == nil {
+		t.Fatalf("unexpected latency stats %+v", report.Latency)
+	}
+
+	target.failOn = "nomatch"
+	options.Mix = QueryMix{Miss: 1}
+	if report, err = RunLoadTest(context.Background(), target, options); err != nil || report.Errors == 0 || report.FirstError == "" {
+		t.Fatalf("expected failed queries to be counted, got %+v %v", report, err)
+	}
+
+	options.QPS = 0
+	if _, err := RunLoadTest(context.Background(), target, options); err == nil {
+		t.Fatal("expected invalid options to be rejected")
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	stats := latencyStats(latencies)
+	if stats.P50 != 50*time.Millisecond || stats.P99 != 99*time.Millisecond || stats.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected percentiles %+v", stats)
+	}
+}
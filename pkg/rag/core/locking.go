@@ -0,0 +1,508 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/common/redis"
+)
+
+// Lock keys of the pipeline's background jobs
+const (
+	LockKeyScheduler   = "rag:scheduler"
+	LockKeyMaintenance = "rag:storage-maintenance"
+	LockKeyReembed     = "rag:reembed"
+)
+
+// defaultLockTTL is used when the locking config leaves the TTL unset
+const defaultLockTTL = 30 * time.Second
+
+// ErrLockHeld is returned when a lock is held by another owner
+var ErrLockHeld = errors.New("lock held by another instance")
+
+// ErrLockLost is returned by Refresh when the lock expired or was taken over
+var ErrLockLost = errors.New("lock lost")
+
+// Locker provides mutual exclusion between pipeline instances, so background
+// jobs run on a single replica at a time
+type Locker interface {
+	// TryLock acquires key for ttl without waiting, or returns ErrLockHeld
+	TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a lock held by this instance. It expires after its TTL unless refreshed.
+type Lock interface {
+	// Refresh extends the lock by ttl, or returns ErrLockLost
+	Refresh(ctx context.Context, ttl time.Duration) error
+
+	// Unlock releases the lock
+	Unlock(ctx context.Context) error
+}
+
+// newLockToken returns a random owner token
+func newLockToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// LocalLocker is an in-process Locker for single-instance deployments
+type LocalLocker struct {
+	mu    sync.Mutex
+	locks map[string]localLease
+}
+
+type localLease struct {
+	token   string
+	expires time.Time
+}
+
+// NewLocalLocker creates an in-process locker
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{locks: make(map[string]localLease)}
+}
+
+// TryLock implements the Locker interface
+func (l *LocalLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if lease, held := l.locks[key]; held && now.Before(lease.expires) {
+		return nil, ErrLockHeld
+	}
+	token := newLockToken()
+	l.locks[key] = localLease{token: token, expires: now.Add(ttl)}
+	return &localLock{locker: l, key: key, token: token}, nil
+}
+
+type localLock struct {
+	locker *LocalLocker
+	key    string
+	token  string
+}
+
+func (l *localLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	lease, held := l.locker.locks[l.key]
+	if !held || lease.token != l.token || time.Now().After(lease.expires) {
+		return ErrLockLost
+	}
+	lease.expires = time.Now().Add(ttl)
+	l.locker.locks[l.key] = lease
+	return nil
+}
+
+func (l *localLock) Unlock(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	if lease, held := l.locker.locks[l.key]; held && lease.token == l.token {
+		delete(l.locker.locks, l.key)
+	}
+	return nil
+}
+
+// Redis scripts that only touch the lock while the caller still owns it
+const (
+	redisRefreshScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+	redisUnlockScript  = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+)
+
+// RedisLocker is a Locker backed by Redis keys set with NX and a TTL
+type RedisLocker struct {
+	client *redis.Client
+}
+
+// NewRedisLocker creates a locker on the Redis server at url
+func NewRedisLocker(url, password string, db int) (*RedisLocker, error) {
+	if url == "" {
+		return nil, fmt.Errorf("redis_url is required for redis locking")
+	}
+	client, err := redis.NewClient(url, password, db, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisLocker{client: client}, nil
+}
+
+// TryLock implements the Locker interface
+func (l *RedisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	key = "metabase:lock:" + key
+	token := newLockToken()
+	reply, err := l.client.Do(ctx, "SET", key, token, "NX", "PX", ttl.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if reply == nil {
+		return nil, ErrLockHeld
+	}
+	return &redisLock{client: l.client, key: key, token: token}, nil
+}
+
+// Close closes the Redis connection
+func (l *RedisLocker) Close() error {
+	return l.client.Close()
+}
+
+type redisLock struct {
+	client *redis.Client
+	key    string
+	token  string
+}
+
+func (l *redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	reply, err := l.client.Do(ctx, "EVAL", redisRefreshScript, 1, l.key, l.token, ttl.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock: %w", err)
+	}
+	if n, _ := reply.(int64); n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (l *redisLock) Unlock(ctx context.Context) error {
+	if _, err := l.client.Do(ctx, "EVAL", redisUnlockScript, 1, l.key, l.token); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// PostgresLocker is a Locker backed by Postgres session advisory locks. Each
+// lock holds a dedicated connection, so it is released if the instance dies;
+// the TTL is not used.
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+// NewPostgresLocker creates a locker on a Postgres database
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+// TryLock implements the Locker interface
+func (l *PostgresLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	id := advisoryLockID(key)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, id).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrLockHeld
+	}
+	return &postgresLock{conn: conn, id: id}, nil
+}
+
+// advisoryLockID maps a lock key to a Postgres advisory lock ID
+func advisoryLockID(key string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	return int64(hash.Sum64())
+}
+
+type postgresLock struct {
+	conn *sql.Conn
+	id   int64
+}
+
+// Refresh checks that the session holding the lock is still alive
+func (l *postgresLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if err := l.conn.PingContext(ctx); err != nil {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (l *postgresLock) Unlock(ctx context.Context) error {
+	defer l.conn.Close()
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.id); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// SQLLeaseLocker is a Locker backed by lease rows in a SQL table, for
+// databases without advisory locks such as SQLite
+type SQLLeaseLocker struct {
+	db *sql.DB
+}
+
+// NewSQLLeaseLocker creates a lease locker, creating its table if needed
+func NewSQLLeaseLocker(ctx context.Context, db *sql.DB) (*SQLLeaseLocker, error) {
+	_, err := db.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS rag_locks (
+		lock_key TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		expires_at_ns INTEGER NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock table: %w", err)
+	}
+	return &SQLLeaseLocker{db: db}, nil
+}
+
+// TryLock implements the Locker interface
+func (l *SQLLeaseLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	token := newLockToken()
+	now := time.Now()
+	result, err := l.db.ExecContext(ctx, `
+		INSERT INTO rag_locks (lock_key, owner, expires_at_ns)
+		VALUES (?, ?, ?)
+		ON CONFLICT(lock_key) DO UPDATE SET
+			owner = excluded.owner,
+			expires_at_ns = excluded.expires_at_ns
+		WHERE rag_locks.expires_at_ns < ?`,
+		key, token, now.Add(ttl).UnixNano(), now.UnixNano(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if affected == 0 {
+		return nil, ErrLockHeld
+	}
+	return &sqlLeaseLock{db: l.db, key: key, token: token}, nil
+}
+
+type sqlLeaseLock struct {
+	db    *sql.DB
+	key   string
+	token string
+}
+
+func (l *sqlLeaseLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	now := time.Now()
+	result, err := l.db.ExecContext(ctx, `
+		UPDATE rag_locks SET expires_at_ns = ?
+		WHERE lock_key = ? AND owner = ? AND expires_at_ns >= ?`,
+		now.Add(ttl).UnixNano(), l.key, l.token, now.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func (l *sqlLeaseLock) Unlock(ctx context.Context) error {
+	_, err := l.db.ExecContext(ctx, `DELETE FROM rag_locks WHERE lock_key = ? AND owner = ?`, l.key, l.token)
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// NewSQLLocker returns advisory locks for Postgres and lease rows for other databases
+func NewSQLLocker(ctx context.Context, db *sql.DB, driver string) (Locker, error) {
+	switch driver {
+	case "postgres", "pgx":
+		return NewPostgresLocker(db), nil
+	default:
+		return NewSQLLeaseLocker(ctx, db)
+	}
+}
+
+// WithLock runs fn while holding key. The lock is refreshed until fn returns,
+// and fn's context is cancelled if the lock is lost. It returns ErrLockHeld
+// without running fn when another instance holds the lock.
+func WithLock(ctx context.Context, locker Locker, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lock, err := locker.TryLock(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	return WithHeldLock(ctx, lock, ttl, fn)
+}
+
+// WithHeldLock runs fn while refreshing an acquired lock, and releases the
+// lock when fn returns
+func WithHeldLock(ctx context.Context, lock Lock, ttl time.Duration, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer lock.Unlock(context.WithoutCancel(ctx))
+
+	go keepLock(ctx, lock, ttl, cancel)
+	return fn(ctx)
+}
+
+// keepLock refreshes lock until ctx is done, calling lost if the lock is lost
+func keepLock(ctx context.Context, lock Lock, ttl time.Duration, lost func()) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := lock.Refresh(ctx, ttl); errors.Is(err, ErrLockLost) {
+				lost()
+				return
+			}
+		}
+	}
+}
+
+// SetLocker sets the locker that coordinates background jobs between
+// instances. It must be called before Start.
+func (p *Pipeline) SetLocker(locker Locker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.locker = locker
+}
+
+// Locker returns the locker that coordinates background jobs
+func (p *Pipeline) Locker() Locker {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.locker
+}
+
+// LockTTL returns the configured lease duration of background job locks
+func (p *Pipeline) LockTTL() time.Duration {
+	if ttl := p.config.System.Locking.TTL; ttl > 0 {
+		return ttl
+	}
+	return defaultLockTTL
+}
+
+// IsLeader reports whether this instance runs the pipeline's scheduled jobs
+func (p *Pipeline) IsLeader() bool {
+	p.mu.RLock()
+	leader := p.leader
+	p.mu.RUnlock()
+	return leader != nil && leader.IsLeader()
+}
+
+// createLocker creates the locker selected by the locking config
+func (p *Pipeline) createLocker() (Locker, error) {
+	config := p.config.System.Locking
+	switch config.Backend {
+	case "", "local":
+		return NewLocalLocker(), nil
+	case "redis":
+		return NewRedisLocker(config.RedisURL, config.RedisPassword, config.RedisDB)
+	default:
+		return nil, fmt.Errorf("unsupported locking backend: %s", config.Backend)
+	}
+}
+
+// LeaderElector elects one instance to run singleton schedulers. The leader
+// holds a lock and refreshes it; when it stops or dies the lock expires and
+// another instance takes over.
+type LeaderElector struct {
+	locker Locker
+	key    string
+	ttl    time.Duration
+
+	mu     sync.RWMutex
+	lock   Lock
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLeaderElector creates an elector for the leadership identified by key
+func NewLeaderElector(locker Locker, key string, ttl time.Duration) *LeaderElector {
+	if ttl <= 0 {
+		ttl = defaultLockTTL
+	}
+	return &LeaderElector{locker: locker, key: key, ttl: ttl}
+}
+
+// Start campaigns for leadership until Stop
+func (e *LeaderElector) Start(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.cancel != nil {
+		return
+	}
+
+	ctx, e.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	e.done = make(chan struct{})
+	go e.run(ctx, e.done)
+}
+
+// Stop resigns leadership and stops campaigning
+func (e *LeaderElector) Stop() {
+	e.mu.Lock()
+	cancel, done := e.cancel, e.done
+	e.cancel, e.done = nil, nil
+	e.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// IsLeader reports whether this instance currently leads
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.lock != nil
+}
+
+func (e *LeaderElector) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		e.campaign(ctx)
+
+		select {
+		case <-ctx.Done():
+			e.mu.Lock()
+			lock := e.lock
+			e.lock = nil
+			e.mu.Unlock()
+			if lock != nil {
+				lock.Unlock(context.WithoutCancel(ctx))
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign acquires leadership, or refreshes it when already leading
+func (e *LeaderElector) campaign(ctx context.Context) {
+	e.mu.RLock()
+	lock := e.lock
+	e.mu.RUnlock()
+
+	if lock != nil {
+		if err := lock.Refresh(ctx, e.ttl); err == nil {
+			return
+		}
+		// Step down on any refresh failure; another instance may take over
+		lock = nil
+	} else if acquired, err := e.locker.TryLock(ctx, e.key, e.ttl); err == nil {
+		lock = acquired
+	}
+
+	e.mu.Lock()
+	e.lock = lock
+	e.mu.Unlock()
+}
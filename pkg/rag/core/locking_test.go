@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLocalLockerExpiry(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocalLocker()
+
+	lock, err := locker.TryLock(ctx, "job", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+	if _, err := locker.TryLock(ctx, "job", time.Second); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("expected ErrLockHeld, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	other, err := locker.TryLock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatalf("expired lock should be taken over: %v", err)
+	}
+	if err := lock.Refresh(ctx, time.Second); !errors.Is(err, ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+
+	// Unlocking a lost lock must not release the new owner's lock
+	lock.Unlock(ctx)
+	if _, err := locker.TryLock(ctx, "job", time.Second); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("expected ErrLockHeld, got %v", err)
+	}
+	other.Unlock(ctx)
+}
+
+func TestLeaderElectorFailover(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocalLocker()
+	first := NewLeaderElector(locker, LockKeyScheduler, 30*time.Millisecond)
+	second := NewLeaderElector(locker, LockKeyScheduler, 30*time.Millisecond)
+
+	first.Start(ctx)
+	waitFor(t, first.IsLeader)
+	second.Start(ctx)
+	defer second.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("expected exactly the first elector to lead")
+	}
+
+	first.Stop()
+	waitFor(t, second.IsLeader)
+	if first.IsLeader() {
+		t.Fatalf("stopped elector must not lead")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Maintenance tasks
+const (
+	MaintenanceTaskVacuum       = "vacuum"
+	MaintenanceTaskCompact      = "compact_indexes"
+	MaintenanceTaskPurgeOrphans = "purge_orphans"
+)
+
+// maxMaintenanceLog is the number of runs kept in the maintenance log
+const maxMaintenanceLog = 100
+
+// StorageMaintainer is implemented by storage backends that support background maintenance
+type StorageMaintainer interface {
+	// Vacuum reclaims free database pages and returns the bytes reclaimed
+	Vacuum(ctx context.Context) (int64, error)
+
+	// CompactIndexes rewrites vector index files and returns the bytes reclaimed
+	CompactIndexes(ctx context.Context) (int64, error)
+
+	// PurgeOrphans drops chunks and embeddings whose document no longer exists
+	PurgeOrphans(ctx context.Context) (*OrphanPurgeResult, error)
+}
+
+// MaintenanceRecorder is implemented by metrics collectors that record maintenance runs
+type MaintenanceRecorder interface {
+	RecordMaintenance(ctx context.Context, run MaintenanceRun)
+}
+
+// OrphanPurgeResult reports what an orphan purge removed
+type OrphanPurgeResult struct {
+	Chunks         int   `json:"chunks"`
+	Embeddings     int   `json:"embeddings"`
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+}
+
+// MaintenanceTaskResult is the outcome of one task in a maintenance run
+type MaintenanceTaskResult struct {
+	Task           string        `json:"task"`
+	ReclaimedBytes int64         `json:"reclaimed_bytes"`
+	Duration       time.Duration `json:"duration"`
+	Error          string        `json:"error,omitempty"`
+}
+
+// MaintenanceRun is an entry in the maintenance log
+type MaintenanceRun struct {
+	ID               string                  `json:"id"`
+	Trigger          string                  `json:"trigger"` // scheduled or manual
+	StartedAt        time.Time               `json:"started_at"`
+	FinishedAt       time.Time               `json:"finished_at"`
+	Duration         time.Duration           `json:"duration"`
+	Tasks            []MaintenanceTaskResult `json:"tasks"`
+	ReclaimedBytes   int64                   `json:"reclaimed_bytes"`
+	OrphanChunks     int                     `json:"orphan_chunks"`
+	OrphanEmbeddings int                     `json:"orphan_embeddings"`
+	Success          bool                    `json:"success"`
+}
+
+// maintenanceState tracks the scheduler and the maintenance log
+type maintenanceState struct {
+	mu             sync.Mutex
+	running        bool
+	cancel         context.CancelFunc
+	log            []MaintenanceRun
+	reclaimedTotal int64
+	runs           int64
+}
+
+// RunStorageMaintenance vacuums the database, compacts vector indexes and
+// purges orphaned chunks and embeddings. Only one run executes at a time
+// across all instances sharing the pipeline's locker.
+func (p *Pipeline) RunStorageMaintenance(ctx context.Context) (*MaintenanceRun, error) {
+	return p.runStorageMaintenance(ctx, "manual")
+}
+
+// MaintenanceLog returns the most recent maintenance runs, newest first
+func (p *Pipeline) MaintenanceLog(limit int) []MaintenanceRun {
+	p.maintenance.mu.Lock()
+	defer p.maintenance.mu.Unlock()
+
+	log := p.maintenance.log
+	if limit <= 0 || limit > len(log) {
+		limit = len(log)
+	}
+	runs := make([]MaintenanceRun, 0, limit)
+	for i := len(log) - 1; i >= len(log)-limit; i-- {
+		runs = append(runs, log[i])
+	}
+	return runs
+}
+
+// startMaintenanceScheduler runs storage maintenance every VacuumInterval until Stop
+func (p *Pipeline) startMaintenanceScheduler(ctx context.Context) {
+	interval := p.config.Storage.VacuumInterval
+	if !p.config.Storage.EnableVacuum || interval <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	p.maintenance.mu.Lock()
+	p.maintenance.cancel = cancel
+	p.maintenance.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !p.IsLeader() {
+					continue
+				}
+				if _, err := p.runStorageMaintenance(ctx, "scheduled"); err != nil {
+					p.emitError(ctx, "storage_maintenance", err)
+				}
+			}
+		}
+	}()
+}
+
+// stopMaintenanceScheduler stops the scheduler; a run in progress is cancelled
+func (p *Pipeline) stopMaintenanceScheduler() {
+	p.maintenance.mu.Lock()
+	defer p.maintenance.mu.Unlock()
+	if p.maintenance.cancel != nil {
+		p.maintenance.cancel()
+		p.maintenance.cancel = nil
+	}
+}
+
+func (p *Pipeline) runStorageMaintenance(ctx context.Context, trigger string) (*MaintenanceRun, error) {
+	maintainer, ok := p.storage.(StorageMaintainer)
+	if !ok {
+		return nil, fmt.Errorf("storage backend does not support maintenance")
+	}
+
+	p.maintenance.mu.Lock()
+	if p.maintenance.running {
+		p.maintenance.mu.Unlock()
+		return nil, fmt.Errorf("storage maintenance already running")
+	}
+	p.maintenance.running = true
+	p.maintenance.mu.Unlock()
+
+	var run MaintenanceRun
+	err := WithLock(ctx, p.Locker(), LockKeyMaintenance, p.LockTTL(), func(ctx context.Context) error {
+		run = maintainStorage(ctx, maintainer, trigger)
+		return nil
+	})
+
+	p.maintenance.mu.Lock()
+	p.maintenance.running = false
+	if err != nil {
+		p.maintenance.mu.Unlock()
+		if errors.Is(err, ErrLockHeld) {
+			return nil, fmt.Errorf("storage maintenance already running on another instance")
+		}
+		return nil, err
+	}
+	p.maintenance.runs++
+	p.maintenance.reclaimedTotal += run.ReclaimedBytes
+	p.maintenance.log = append(p.maintenance.log, run)
+	if len(p.maintenance.log) > maxMaintenanceLog {
+		p.maintenance.log = p.maintenance.log[len(p.maintenance.log)-maxMaintenanceLog:]
+	}
+	p.maintenance.mu.Unlock()
+
+	if recorder, ok := p.metrics.(MaintenanceRecorder); ok {
+		recorder.RecordMaintenance(ctx, run)
+	}
+	p.emitEvent(ctx, "storage_maintenance", map[string]interface{}{
+		"run_id":            run.ID,
+		"trigger":           run.Trigger,
+		"reclaimed_bytes":   run.ReclaimedBytes,
+		"orphan_chunks":     run.OrphanChunks,
+		"orphan_embeddings": run.OrphanEmbeddings,
+		"duration":          run.Duration,
+		"success":           run.Success,
+	})
+
+	return &run, nil
+}
+
+// maintainStorage runs the maintenance tasks in order
+func maintainStorage(ctx context.Context, maintainer StorageMaintainer, trigger string) MaintenanceRun {
+	run := MaintenanceRun{
+		ID:        fmt.Sprintf("maintenance_%d", time.Now().UnixNano()),
+		Trigger:   trigger,
+		StartedAt: time.Now(),
+		Success:   true,
+	}
+
+	// Purge orphans first so vacuum reclaims the space they held
+	run.addTask(MaintenanceTaskPurgeOrphans, func() (int64, error) {
+		purged, err := maintainer.PurgeOrphans(ctx)
+		if err != nil || purged == nil {
+			return 0, err
+		}
+		run.OrphanChunks = purged.Chunks
+		run.OrphanEmbeddings = purged.Embeddings
+		return purged.ReclaimedBytes, nil
+	})
+	run.addTask(MaintenanceTaskVacuum, func() (int64, error) {
+		return maintainer.Vacuum(ctx)
+	})
+	run.addTask(MaintenanceTaskCompact, func() (int64, error) {
+		return maintainer.CompactIndexes(ctx)
+	})
+
+	run.FinishedAt = time.Now()
+	run.Duration = run.FinishedAt.Sub(run.StartedAt)
+	return run
+}
+
+// addTask runs one maintenance task and records its outcome
+func (run *MaintenanceRun) addTask(task string, fn func() (int64, error)) {
+	start := time.Now()
+	reclaimed, err := fn()
+	result := MaintenanceTaskResult{
+		Task:           task,
+		ReclaimedBytes: reclaimed,
+		Duration:       time.Since(start),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		run.Success = false
+	}
+	run.ReclaimedBytes += reclaimed
+	run.Tasks = append(run.Tasks, result)
+}
+
+// VacuumSQLDatabase vacuums a SQLite or Postgres database and returns the
+// bytes reclaimed, measured from the database size before and after
+func VacuumSQLDatabase(ctx context.Context, db *sql.DB, driver string) (int64, error) {
+	var sizeQuery, vacuum string
+	switch driver {
+	case "sqlite", "sqlite3":
+		sizeQuery = `SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()`
+		vacuum = `VACUUM`
+	case "postgres", "pgx":
+		sizeQuery = `SELECT pg_database_size(current_database())`
+		vacuum = `VACUUM (ANALYZE)`
+	default:
+		return 0, fmt.Errorf("vacuum not supported for driver %s", driver)
+	}
+
+	var before, after int64
+	if err := db.QueryRowContext(ctx, sizeQuery).Scan(&before); err != nil {
+		return 0, fmt.Errorf("failed to measure database size: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, vacuum); err != nil {
+		return 0, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, sizeQuery).Scan(&after); err != nil {
+		return 0, fmt.Errorf("failed to measure database size: %w", err)
+	}
+
+	if after > before {
+		return 0, nil
+	}
+	return before - after, nil
+}
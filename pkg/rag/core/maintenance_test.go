@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// maintainedStorage records maintenance calls and fails vacuum when vacuumErr is set
+type maintainedStorage struct {
+	Storage
+	calls     []string
+	vacuumErr error
+}
+
+func (s *maintainedStorage) Vacuum(ctx context.Context) (int64, error) {
+	s.calls = append(s.calls, MaintenanceTaskVacuum)
+	if s.vacuumErr != nil {
+		return 0, s.vacuumErr
+	}
+	return 100, nil
+}
+
+func (s *maintainedStorage) CompactIndexes(ctx context.Context) (int64, error) {
+	s.calls = append(s.calls, MaintenanceTaskCompact)
+	return 20, nil
+}
+
+func (s *maintainedStorage) PurgeOrphans(ctx context.Context) (*OrphanPurgeResult, error) {
+	s.calls = append(s.calls, MaintenanceTaskPurgeOrphans)
+	return &OrphanPurgeResult{Chunks: 3, Embeddings: 2, ReclaimedBytes: 5}, nil
+}
+
+func TestRunStorageMaintenance(t *testing.T) {
+	ctx := context.Background()
+	backend := &maintainedStorage{}
+	p := &Pipeline{config: DefaultConfig(), storage: backend, locker: NewLocalLocker()}
+
+	run, err := p.RunStorageMaintenance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(backend.calls, ",") != "purge_orphans,vacuum,compact_indexes" {
+		t.Fatalf("expected orphans to be purged before vacuum, got %v", backend.calls)
+	}
+	if !run.Success || run.Trigger != "manual" || run.ReclaimedBytes != 125 || run.OrphanChunks != 3 || run.OrphanEmbeddings != 2 || len(run.Tasks) != 3 {
+		t.Fatalf("unexpected run %+v", run)
+	}
+
+	// A failing task is recorded and the remaining tasks still run
+	backend.calls = nil
+	backend.vacuumErr = errors.New("database is locked")
+	run, err = p.RunStorageMaintenance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Success || run.Tasks[1].Error != "database is locked" || len(backend.calls) != 3 || run.ReclaimedBytes != 25 {
+		t.Fatalf("unexpected run %+v", run)
+	}
+
+	runs := p.MaintenanceLog(0)
+	if len(runs) != 2 || runs[0].ID != run.ID {
+		t.Fatalf("expected the log newest first, got %d runs", len(runs))
+	}
+	if runs := p.MaintenanceLog(1); len(runs) != 1 || runs[0].Success {
+		t.Fatalf("expected the limit to keep the newest run, got %+v", runs)
+	}
+	if p.maintenance.runs != 2 || p.maintenance.reclaimedTotal != 150 {
+		t.Fatalf("unexpected totals: %d runs, %d bytes", p.maintenance.runs, p.maintenance.reclaimedTotal)
+	}
+
+	// A run holding the lock on another instance blocks this one
+	lock, err := p.locker.TryLock(ctx, LockKeyMaintenance, p.LockTTL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.RunStorageMaintenance(ctx); err == nil || !strings.Contains(err.Error(), "another instance") {
+		t.Fatalf("expected runs elsewhere to be respected, got %v", err)
+	}
+	lock.Unlock(ctx)
+
+	// A run already in progress blocks another one
+	p.maintenance.running = true
+	if _, err := p.RunStorageMaintenance(ctx); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("expected concurrent runs to be rejected, got %v", err)
+	}
+}
+
+func TestRunStorageMaintenanceUnsupported(t *testing.T) {
+	p := &Pipeline{config: DefaultConfig(), storage: &imageStorage{}}
+	if _, err := p.RunStorageMaintenance(context.Background()); err == nil || !strings.Contains(err.Error(), "does not support maintenance") {
+		t.Fatalf("expected storage without maintenance support to be rejected, got %v", err)
+	}
+}
+
+func TestVacuumSQLDatabase(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "rag.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, `CREATE TABLE chunks (content TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		if _, err := db.ExecContext(ctx, `INSERT INTO chunks VALUES (?)`, strings.Repeat("x", 4096)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM chunks`); err != nil {
+		t.Fatal(err)
+	}
+
+	reclaimed, err := VacuumSQLDatabase(ctx, db, "sqlite3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reclaimed < 200*4096 {
+		t.Fatalf("expected the deleted rows to be reclaimed, got %d bytes", reclaimed)
+	}
+	if _, err := VacuumSQLDatabase(ctx, db, "mysql"); err == nil {
+		t.Fatal("expected unsupported drivers to be rejected")
+	}
+}
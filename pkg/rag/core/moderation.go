@@ -0,0 +1,323 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// ModerationAction is the action taken when a moderator flags generated output
+type ModerationAction string
+
+const (
+	ModerationActionWarn   ModerationAction = "warn"   // Return the text unchanged and record the flag
+	ModerationActionRedact ModerationAction = "redact" // Replace flagged spans
+	ModerationActionBlock  ModerationAction = "block"  // Replace the whole response
+)
+
+// severity orders actions so the strictest one wins
+func (a ModerationAction) severity() int {
+	switch a {
+	case ModerationActionBlock:
+		return 3
+	case ModerationActionRedact:
+		return 2
+	case ModerationActionWarn:
+		return 1
+	}
+	return 0
+}
+
+// ModerationSpan is a flagged region of text
+type ModerationSpan struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Category string `json:"category"`
+}
+
+// ModerationResult is the verdict of one moderator on one piece of text
+type ModerationResult struct {
+	Moderator  string             `json:"moderator"`
+	Field      string             `json:"field"` // response, answer or summary
+	Flagged    bool               `json:"flagged"`
+	Categories []string           `json:"categories,omitempty"`
+	Scores     map[string]float64 `json:"scores,omitempty"`
+	Spans      []ModerationSpan   `json:"spans,omitempty"` // Empty when the whole text is flagged
+}
+
+// ModerationReport records moderation of a query result for audit
+type ModerationReport struct {
+	Flagged    bool               `json:"flagged"`
+	Action     ModerationAction   `json:"action,omitempty"` // Action applied, empty when nothing was flagged
+	Redactions int                `json:"redactions,omitempty"`
+	Results    []ModerationResult `json:"results"`
+	Errors     []string           `json:"errors,omitempty"`
+	CheckedAt  time.Time          `json:"checked_at"`
+}
+
+// Moderator classifies generated text before it is returned to the caller
+type Moderator interface {
+	// Moderate classifies text and reports flagged categories
+	Moderate(ctx context.Context, text string) (*ModerationResult, error)
+
+	// GetName returns the moderator name
+	GetName() string
+}
+
+// AddModerator registers a moderator applied to generated responses
+func (p *Pipeline) AddModerator(moderator Moderator) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.moderators = append(p.moderators, moderator)
+}
+
+// moderateResult runs all moderators over the generated text of a result and
+// applies the configured action in place. It returns nil when moderation is off.
+func (p *Pipeline) moderateResult(ctx context.Context, result *QueryResult) *ModerationReport {
+	p.mu.RLock()
+	moderators := p.moderators
+	p.mu.RUnlock()
+
+	config := p.config.Generation.Moderation
+	if !config.Enabled || len(moderators) == 0 {
+		return nil
+	}
+
+	report := &ModerationReport{CheckedAt: time.Now()}
+	fields := []struct {
+		name string
+		text *string
+	}{
+		{"response", &result.GeneratedResponse},
+		{"answer", &result.GeneratedAnswer},
+		{"summary", &result.GeneratedSummary},
+	}
+
+	action := ModerationAction("")
+	flaggedFields := make(map[string][]ModerationResult)
+	for _, field := range fields {
+		if strings.TrimSpace(*field.text) == "" {
+			continue
+		}
+		for _, moderator := range moderators {
+			verdict, err := moderator.Moderate(ctx, *field.text)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", moderator.GetName(), err))
+				if config.FailClosed {
+					action = ModerationActionBlock
+				}
+				continue
+			}
+			verdict.Moderator = moderator.GetName()
+			verdict.Field = field.name
+			report.Results = append(report.Results, *verdict)
+
+			if verdict.Flagged {
+				report.Flagged = true
+				flaggedFields[field.name] = append(flaggedFields[field.name], *verdict)
+				if a := config.actionFor(verdict.Categories); a.severity() > action.severity() {
+					action = a
+				}
+			}
+		}
+	}
+
+	report.Action = action
+	switch action {
+	case ModerationActionBlock:
+		for _, field := range fields {
+			if *field.text != "" {
+				*field.text = config.BlockMessage
+			}
+		}
+		result.Sources = nil
+	case ModerationActionRedact:
+		for _, field := range fields {
+			if verdicts := flaggedFields[field.name]; len(verdicts) > 0 {
+				var n int
+				*field.text, n = redactSpans(*field.text, verdicts, config.RedactionText)
+				report.Redactions += n
+			}
+		}
+	}
+
+	if report.Flagged || len(report.Errors) > 0 {
+		p.emitEvent(ctx, "response_moderated", map[string]interface{}{
+			"query_id": result.QueryID,
+			"action":   report.Action,
+			"results":  report.Results,
+			"errors":   report.Errors,
+		})
+	}
+
+	return report
+}
+
+// actionFor returns the strictest action configured for the flagged categories
+func (c ModerationConfig) actionFor(categories []string) ModerationAction {
+	action := ModerationAction(c.Action)
+	for _, category := range categories {
+		if override, ok := c.CategoryActions[category]; ok && ModerationAction(override).severity() > action.severity() {
+			action = ModerationAction(override)
+		}
+	}
+	if action.severity() == 0 {
+		action = ModerationActionWarn
+	}
+	return action
+}
+
+// redactSpans replaces flagged spans in text. A verdict without spans flags
+// the whole text, which is then replaced entirely.
+func redactSpans(text string, verdicts []ModerationResult, replacement string) (string, int) {
+	var spans []ModerationSpan
+	for _, verdict := range verdicts {
+		if len(verdict.Spans) == 0 {
+			return replacement, 1
+		}
+		spans = append(spans, verdict.Spans...)
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	var b strings.Builder
+	pos, count := 0, 0
+	for _, span := range spans {
+		if span.Start < pos {
+			// Overlaps a span already redacted
+			if span.End > pos {
+				pos = span.End
+			}
+			continue
+		}
+		if span.End > len(text) {
+			span.End = len(text)
+		}
+		b.WriteString(text[pos:span.Start])
+		b.WriteString(replacement)
+		pos = span.End
+		count++
+	}
+	b.WriteString(text[pos:])
+	return b.String(), count
+}
+
+// OpenAIModerator classifies text with an OpenAI-compatible moderation API
+type OpenAIModerator struct {
+	config    *llm.Config
+	model     string
+	threshold float64
+}
+
+// NewOpenAIModerator creates a moderator backed by the moderation endpoint.
+// Besides the API's own flag, any category scoring at or above threshold is
+// flagged; a zero threshold relies on the API flag alone.
+func NewOpenAIModerator(config *llm.Config, model string, threshold float64) *OpenAIModerator {
+	return &OpenAIModerator{
+		config:    config,
+		model:     model,
+		threshold: threshold,
+	}
+}
+
+// Moderate implements the Moderator interface
+func (m *OpenAIModerator) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	response, err := llm.Moderate(text, m.model, m.config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ModerationResult{Scores: make(map[string]float64)}
+	for _, r := range response.Results {
+		if r.Flagged {
+			result.Flagged = true
+		}
+		for category, score := range r.CategoryScores {
+			if score > result.Scores[category] {
+				result.Scores[category] = score
+			}
+			if m.threshold > 0 && score >= m.threshold {
+				result.Flagged = true
+				result.Categories = appendUnique(result.Categories, category)
+			}
+		}
+		for category, flagged := range r.Categories {
+			if flagged {
+				result.Flagged = true
+				result.Categories = appendUnique(result.Categories, category)
+			}
+		}
+	}
+	sort.Strings(result.Categories)
+
+	return result, nil
+}
+
+// GetName returns the moderator name
+func (m *OpenAIModerator) GetName() string {
+	return "openai"
+}
+
+// KeywordModerator is a local classifier that flags configured terms by
+// category. It needs no network access and reports exact spans, so it can
+// be used with the redact action.
+type KeywordModerator struct {
+	patterns map[string]*regexp.Regexp
+}
+
+// NewKeywordModerator creates a keyword moderator from terms grouped by category
+func NewKeywordModerator(terms map[string][]string) (*KeywordModerator, error) {
+	patterns := make(map[string]*regexp.Regexp, len(terms))
+	for category, words := range terms {
+		if len(words) == 0 {
+			continue
+		}
+		quoted := make([]string, len(words))
+		for i, word := range words {
+			quoted[i] = regexp.QuoteMeta(word)
+		}
+		pattern, err := regexp.Compile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid terms for category %s: %w", category, err)
+		}
+		patterns[category] = pattern
+	}
+	return &KeywordModerator{patterns: patterns}, nil
+}
+
+// Moderate implements the Moderator interface
+func (m *KeywordModerator) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	result := &ModerationResult{}
+	for category, pattern := range m.patterns {
+		matches := pattern.FindAllStringIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		result.Flagged = true
+		result.Categories = append(result.Categories, category)
+		for _, match := range matches {
+			result.Spans = append(result.Spans, ModerationSpan{Start: match[0], End: match[1], Category: category})
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
+
+// GetName returns the moderator name
+func (m *KeywordModerator) GetName() string {
+	return "keyword"
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
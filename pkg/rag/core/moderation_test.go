@@ -0,0 +1,222 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// failingModerator fails every check
+type failingModerator struct{}
+
+func (failingModerator) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	return nil, errors.New("moderation unavailable")
+}
+
+func (failingModerator) GetName() string { return "failing" }
+
+func TestKeywordModerator(t *testing.T) {
+	moderator, err := NewKeywordModerator(map[string][]string{
+		"pii":       {"SSN", "passport number"},
+		"profanity": {"darn"},
+		"empty":     nil,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := moderator.Moderate(context.Background(), "Send your ssn and passport number, darn it. SSNs are fine.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Flagged || !reflect.DeepEqual(result.Categories, []string{"pii", "profanity"}) {
+		t.Fatalf("unexpected verdict %+v", result)
+	}
+	if len(result.Spans) != 3 {
+		t.Fatalf("expected whole-word matches only, got %+v", result.Spans)
+	}
+
+	clean, err := moderator.Moderate(context.Background(), "Nothing to see here")
+	if err != nil || clean.Flagged {
+		t.Fatalf("expected clean text to pass, got %+v %v", clean, err)
+	}
+}
+
+func TestRedactSpans(t *testing.T) {
+	text := "call 555-1234 or 555-9876 today"
+	tests := []struct {
+		name     string
+		verdicts []ModerationResult
+		want     string
+		count    int
+	}{
+		{
+			name:     "separate spans",
+			verdicts: []ModerationResult{{Spans: []ModerationSpan{{Start: 17, End: 25}, {Start: 5, End: 13}}}},
+			want:     "call [x] or [x] today",
+			count:    2,
+		},
+		{
+			name: "overlapping spans from two moderators",
+			verdicts: []ModerationResult{
+				{Spans: []ModerationSpan{{Start: 5, End: 13}}},
+				{Spans: []ModerationSpan{{Start: 10, End: 16}, {Start: 26, End: 100}}},
+			},
+			want:  "call [x] 555-9876 [x]",
+			count: 2,
+		},
+		{
+			name:     "verdict without spans",
+			verdicts: []ModerationResult{{Spans: []ModerationSpan{{Start: 0, End: 4}}}, {}},
+			want:     "[x]",
+			count:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count := redactSpans(text, tt.verdicts, "[x]")
+			if got != tt.want || count != tt.count {
+				t.Fatalf("got %q (%d), want %q (%d)", got, count, tt.want, tt.count)
+			}
+		})
+	}
+}
+
+func TestModerationConfigActionFor(t *testing.T) {
+	config := ModerationConfig{Action: "warn", CategoryActions: map[string]string{"pii": "redact", "violence": "block"}}
+	tests := []struct {
+		categories []string
+		want       ModerationAction
+	}{
+		{categories: nil, want: ModerationActionWarn},
+		{categories: []string{"pii"}, want: ModerationActionRedact},
+		{categories: []string{"pii", "violence"}, want: ModerationActionBlock},
+	}
+	for _, tt := range tests {
+		if got := config.actionFor(tt.categories); got != tt.want {
+			t.Errorf("actionFor(%v) = %s, want %s", tt.categories, got, tt.want)
+		}
+	}
+	if got := (ModerationConfig{Action: "unknown"}).actionFor(nil); got != ModerationActionWarn {
+		t.Fatalf("expected unknown actions to fall back to warn, got %s", got)
+	}
+}
+
+func TestModerateResult(t *testing.T) {
+	keywords, err := NewKeywordModerator(map[string][]string{"pii": {"secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newResult := func() *QueryResult {
+		return &QueryResult{
+			GeneratedResponse: "the secret is out",
+			GeneratedAnswer:   "no secret here? secret!",
+			Sources:           []Source{{DocumentID: "a"}},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		config     ModerationConfig
+		moderators []Moderator
+		response   string
+		answer     string
+		action     ModerationAction
+		sources    int
+	}{
+		{
+			name:       "redact",
+			config:     ModerationConfig{Enabled: true, Action: "redact", RedactionText: "***"},
+			moderators: []Moderator{keywords},
+			response:   "the *** is out",
+			answer:     "no *** here? ***!",
+			action:     ModerationActionRedact,
+			sources:    1,
+		},
+		{
+			name:       "block",
+			config:     ModerationConfig{Enabled: true, Action: "block", BlockMessage: "blocked"},
+			moderators: []Moderator{keywords},
+			response:   "blocked",
+			answer:     "blocked",
+			action:     ModerationActionBlock,
+		},
+		{
+			name:       "warn leaves text unchanged",
+			config:     ModerationConfig{Enabled: true, Action: "warn"},
+			moderators: []Moderator{keywords},
+			response:   "the secret is out",
+			answer:     "no secret here? secret!",
+			action:     ModerationActionWarn,
+			sources:    1,
+		},
+		{
+			name:       "fail closed",
+			config:     ModerationConfig{Enabled: true, Action: "warn", FailClosed: true, BlockMessage: "unavailable"},
+			moderators: []Moderator{failingModerator{}},
+			response:   "unavailable",
+			answer:     "unavailable",
+			action:     ModerationActionBlock,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Generation.Moderation = tt.config
+			p := &Pipeline{config: config}
+			for _, moderator := range tt.moderators {
+				p.AddModerator(moderator)
+			}
+
+			result := newResult()
+			report := p.moderateResult(context.Background(), result)
+			if report == nil || report.Action != tt.action {
+				t.Fatalf("got report %+v, want action %s", report, tt.action)
+			}
+			if result.GeneratedResponse != tt.response || result.GeneratedAnswer != tt.answer || result.GeneratedSummary != "" {
+				t.Fatalf("got response %q answer %q", result.GeneratedResponse, result.GeneratedAnswer)
+			}
+			if len(result.Sources) != tt.sources {
+				t.Fatalf("got %d sources, want %d", len(result.Sources), tt.sources)
+			}
+		})
+	}
+
+	p := &Pipeline{config: DefaultConfig()}
+	p.AddModerator(keywords)
+	p.config.Generation.Moderation.Enabled = false
+	if report := p.moderateResult(context.Background(), newResult()); report != nil {
+		t.Fatalf("expected no report when moderation is disabled, got %+v", report)
+	}
+}
+
+func TestOpenAIModerator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/moderations" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"results":[{"flagged":false,"categories":{"hate":false,"violence":false},"category_scores":{"hate":0.2,"violence":0.7}}]}`))
+	}))
+	defer server.Close()
+
+	config := &llm.Config{BaseURL: server.URL, APIKey: "key"}
+	result, err := NewOpenAIModerator(config, "omni-moderation-latest", 0.5).Moderate(context.Background(), "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Flagged || !reflect.DeepEqual(result.Categories, []string{"violence"}) || result.Scores["hate"] != 0.2 {
+		t.Fatalf("expected scores above the threshold to be flagged, got %+v", result)
+	}
+
+	result, err = NewOpenAIModerator(config, "", 0).Moderate(context.Background(), "text")
+	if err != nil || result.Flagged {
+		t.Fatalf("expected the API flag alone to decide without a threshold, got %+v %v", result, err)
+	}
+}
@@ -0,0 +1,331 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// languageSampleRunes bounds how much text language detection reads
+const languageSampleRunes = 4000
+
+// languageNames maps language codes to the names used in prompts
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"hi": "Hindi",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"th": "Thai",
+	"zh": "Chinese",
+}
+
+// latinLanguages lists languages written in Latin script in tie-break order,
+// with their most frequent function words
+var latinLanguages = []struct {
+	code      string
+	stopwords map[string]bool
+}{
+	{"en", wordSet("the and is are was of to in that it with for this be on not you have")},
+	{"es", wordSet("el la los las de que y en por con una es para del se no como más")},
+	{"fr", wordSet("le la les de et est des une que dans pour pas sur du qui au ce sont")},
+	{"de", wordSet("der die das und ist nicht mit ein eine den von zu auf für sich dem wird")},
+	{"pt", wordSet("o a os de que e do da em um para não uma com são no na")},
+	{"it", wordSet("il di che e la per un non sono del della una con gli è le")},
+	{"nl", wordSet("de het een en van is dat niet op te met voor zijn ook er")},
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// DetectLanguage returns the ISO 639-1 code of the language text is written
+// in, or "" when it cannot tell. Scripts identify most languages directly;
+// Latin-script languages are told apart by their function words.
+func DetectLanguage(text string) string {
+	var han, kana, hangul, latin, letters int
+	scripts := map[string]int{}
+	count := 0
+	for _, r := range text {
+		if count++; count > languageSampleRunes {
+			break
+		}
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			scripts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese mixes kana into Han text; any kana at all rules out Chinese
+	if kana > 0 {
+		scripts["ja"] = han + kana
+	} else {
+		scripts["zh"] = han
+	}
+	scripts["ko"] = hangul
+
+	best, bestCount := "", 0
+	for code, n := range scripts {
+		if n > bestCount || (n == bestCount && n > 0 && code < best) {
+			best, bestCount = code, n
+		}
+	}
+	if bestCount > latin {
+		return best
+	}
+	return detectLatinLanguage(text)
+}
+
+// detectLatinLanguage picks the language whose function words occur most
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(truncateRunes(text, languageSampleRunes)), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	best, bestHits := "", 0
+	for _, language := range latinLanguages {
+		hits := 0
+		for _, word := range words {
+			if language.stopwords[word] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = language.code, hits
+		}
+	}
+	return best
+}
+
+func truncateRunes(text string, n int) string {
+	count := 0
+	for i := range text {
+		if count == n {
+			return text[:i]
+		}
+		count++
+	}
+	return text
+}
+
+// languageName returns the English name of a language code for prompts
+func languageName(code string) string {
+	if name, ok := languageNames[languageBase(code)]; ok {
+		return name
+	}
+	return code
+}
+
+// languageBase returns the primary subtag of a language tag, e.g. "pt" for "pt-BR"
+func languageBase(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// EffectiveModel returns the model embeddings are generated with
+func (c EmbeddingConfig) EffectiveModel() string {
+	if c.MultilingualModel != "" {
+		return c.MultilingualModel
+	}
+	return c.Model
+}
+
+// detectLanguages records the language of a document and each of its chunks,
+// keeping languages set by the data source or by filters
+func (p *Pipeline) detectLanguages(doc *Document, chunks []DocumentChunk) {
+	preprocessing := p.config.Processing.Preprocessing
+	if !preprocessing.DetectLanguage {
+		return
+	}
+	detect := func(text string) string {
+		language := DetectLanguage(text)
+		if language == "" || !supportedLanguage(preprocessing.SupportedLanguages, language) {
+			return ""
+		}
+		return language
+	}
+
+	if doc.Language == "" {
+		doc.Language = detect(doc.Content)
+		if doc.Language == "" {
+			doc.Language = preprocessing.DefaultLanguage
+		}
+	}
+	for i := range chunks {
+		if language, _ := chunks[i].Metadata["language"].(string); language != "" {
+			continue
+		}
+		language := detect(chunks[i].Content)
+		if language == "" {
+			language = doc.Language
+		}
+		if language == "" {
+			continue
+		}
+		metadata := make(map[string]interface{}, len(chunks[i].Metadata)+1)
+		for key, value := range chunks[i].Metadata {
+			metadata[key] = value
+		}
+		metadata["language"] = language
+		chunks[i].Metadata = metadata
+	}
+}
+
+func supportedLanguage(supported []string, language string) bool {
+	if len(supported) == 0 {
+		return true
+	}
+	for _, candidate := range supported {
+		if languageBase(candidate) == languageBase(language) {
+			return true
+		}
+	}
+	return false
+}
+
+// resultLanguage returns the language of a result's chunk, falling back to
+// the language of its document
+func resultLanguage(result RetrievalResult) string {
+	if result.Chunk != nil {
+		if language, _ := result.Chunk.Metadata["language"].(string); language != "" {
+			return language
+		}
+	}
+	if result.Document != nil {
+		return result.Document.Language
+	}
+	return ""
+}
+
+// answerLanguage resolves the language the answer is written in, "" when the
+// model may choose. "auto" answers in the language of the query.
+func (p *Pipeline) answerLanguage(query string, options GenerateOptions) string {
+	language := options.AnswerLanguage
+	if language == "" {
+		language = p.config.Generation.AnswerLanguage
+	}
+	if strings.EqualFold(language, "auto") {
+		language = DetectLanguage(query)
+	}
+	return strings.ToLower(strings.TrimSpace(language))
+}
+
+// answerLanguageInstruction is appended to the system prompt
+func answerLanguageInstruction(language string) string {
+	return fmt.Sprintf("Write the answer in %s, whatever the language of the question or the context.", languageName(language))
+}
+
+// translateExcerpts translates context chunks written in another language
+// into the answer language, so the model quotes and cites text the reader
+// can follow. Results are copied rather than modifying chunks shared with the
+// retriever, and excerpts that fail to translate keep their original text.
+func (p *Pipeline) translateExcerpts(ctx context.Context, results []RetrievalResult, language string, options GenerateOptions) []RetrievalResult {
+	if p.llmClient == nil || language == "" {
+		return results
+	}
+
+	translated := make([]RetrievalResult, len(results))
+	copy(translated, results)
+	cache := make(map[string]string)
+	for i, result := range translated {
+		source := resultLanguage(result)
+		if result.Chunk == nil || result.Chunk.Image != nil || source == "" || languageBase(source) == languageBase(language) {
+			continue
+		}
+		if strings.TrimSpace(result.Chunk.Content) == "" {
+			continue
+		}
+
+		content, ok := cache[result.Chunk.Content]
+		if !ok {
+			var err error
+			if content, err = p.translate(ctx, result.Chunk.Content, source, language, options); err != nil {
+				p.emitError(ctx, "translate_excerpt", fmt.Errorf("chunk %s: %w", result.Chunk.ID, err))
+				continue
+			}
+			cache[result.Chunk.Content] = content
+		}
+
+		chunk := *result.Chunk
+		metadata := make(map[string]interface{}, len(chunk.Metadata)+3)
+		for key, value := range chunk.Metadata {
+			metadata[key] = value
+		}
+		metadata["language"] = language
+		metadata["original_language"] = source
+		metadata["original_content"] = chunk.Content
+		chunk.Metadata = metadata
+		chunk.Content = content
+		translated[i].Chunk = &chunk
+	}
+	return translated
+}
+
+// translate asks the model to translate an excerpt
+func (p *Pipeline) translate(ctx context.Context, text, from, to string, options GenerateOptions) (string, error) {
+	messages := []llm.ChatMessage{
+		{Role: "system", Content: fmt.Sprintf("You translate excerpts from %s into %s so they can be quoted in an answer. Preserve meaning, formatting, code, numbers and proper names. Respond only with the translation.", languageName(from), languageName(to))},
+		{Role: "user", Content: text},
+	}
+
+	model := p.config.Generation.TranslationModel
+	if model == "" {
+		model = options.Model
+	}
+	response, err := p.llmClient.GenerateCompletion(ctx, messages, CompletionOptions{
+		Model:       model,
+		Temperature: 0,
+		Seed:        p.samplingSeed(),
+		MaxTokens:   2*EstimateTokens(text) + 64,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 || strings.TrimSpace(response.Choices[0].Message.Content) == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// translatingClient answers completions by upper-casing the user message
+type translatingClient struct {
+	stubClient
+	systems []string
+}
+
+func (c *translatingClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	c.calls++
+	c.systems = append(c.systems, messages[0].Content)
+	content := strings.ToUpper(messages[len(messages)-1].Content)
+	return &CompletionResponse{Choices: []CompletionChoice{{Message: llm.ChatMessage{Role: "assistant", Content: content}}}}, nil
+}
+
+func TestDetectLanguage(t *testing.T) {
+	for text, want := range map[string]string{
+		"The cache is invalidated when the index is rebuilt":        "en",
+		"Der Index wird neu aufgebaut, wenn sich das Modell ändert": "de",
+		"La caché se invalida cuando el índice de los documentos":   "es",
+		"Le cache est invalidé lorsque les documents sont modifiés": "fr",
+		"当索引重建时缓存会失效":                                               "zh",
+		"インデックスを再構築するとキャッシュは無効になります":                                "ja",
+		"인덱스를 다시 빌드하면 캐시가 무효화됩니다":                                   "ko",
+		"Кэш сбрасывается при перестроении индекса":                 "ru",
+		"12345 !!!": "",
+	} {
+		if got := DetectLanguage(text); got != want {
+			t.Fatalf("%q: expected %q, got %q", text, want, got)
+		}
+	}
+}
+
+func TestDetectLanguagesOnChunks(t *testing.T) {
+	p := &Pipeline{config: DefaultConfig()}
+	doc := &Document{Content: "The setup guide. Die Installation ist nicht schwer und wird mit dem Installer durchgeführt."}
+	shared := map[string]interface{}{"section": "intro"}
+	chunks := []DocumentChunk{
+		{Content: "The setup guide is short and covers the basics", Metadata: shared},
+		{Content: "Die Installation ist nicht schwer und wird mit dem Installer durchgeführt"},
+		{Content: "1.2.3"},
+		{Content: "Source text", Metadata: map[string]interface{}{"language": "fr"}},
+	}
+	p.detectLanguages(doc, chunks)
+
+	if doc.Language != "de" {
+		t.Fatalf("expected document language de, got %q", doc.Language)
+	}
+	for i, want := range []string{"en", "de", "de", "fr"} {
+		if got := chunks[i].Metadata["language"]; got != want {
+			t.Fatalf("chunk %d: expected %q, got %v", i, want, got)
+		}
+	}
+	if _, ok := shared["language"]; ok || chunks[0].Metadata["section"] != "intro" {
+		t.Fatalf("expected chunk metadata to be copied, got %v", chunks[0].Metadata)
+	}
+}
+
+func TestTranslateExcerpts(t *testing.T) {
+	client := &translatingClient{}
+	p := &Pipeline{config: DefaultConfig(), llmClient: client}
+	german := &DocumentChunk{ID: "c1", Content: "der index", Metadata: map[string]interface{}{"language": "de"}}
+	results := []RetrievalResult{
+		{DocumentID: "d1", Chunk: german},
+		{DocumentID: "d2", Chunk: &DocumentChunk{ID: "c2", Content: "the index"}, Document: &Document{Language: "en-US"}},
+		{DocumentID: "d3", Chunk: &DocumentChunk{ID: "c3", Content: "der index"}, Document: &Document{Language: "de"}},
+	}
+
+	language := p.answerLanguage("How is the index rebuilt?", GenerateOptions{AnswerLanguage: "auto"})
+	if language != "en" {
+		t.Fatalf("expected auto to resolve to en, got %q", language)
+	}
+	translated := p.translateExcerpts(context.Background(), results, language, GenerateOptions{})
+
+	if translated[0].Chunk.Content != "DER INDEX" || translated[2].Chunk.Content != "DER INDEX" || translated[1].Chunk.Content != "the index" {
+		t.Fatalf("unexpected translations %q %q %q", translated[0].Chunk.Content, translated[1].Chunk.Content, translated[2].Chunk.Content)
+	}
+	if client.calls != 1 || !strings.Contains(client.systems[0], "from German into English") {
+		t.Fatalf("expected one cached translation request, got %d: %v", client.calls, client.systems)
+	}
+	if german.Content != "der index" || results[0].Chunk != german {
+		t.Fatal("expected the retrieved chunk to be left untouched")
+	}
+
+	sources := sourcesFromResults(translated)
+	if !sources[0].Translated || sources[0].Language != "en" || sources[1].Translated || sources[1].Language != "en-US" {
+		t.Fatalf("unexpected sources %+v", sources)
+	}
+	if translated[0].Chunk.Metadata["original_language"] != "de" || translated[0].Chunk.Metadata["original_content"] != "der index" {
+		t.Fatalf("expected original excerpt to be kept, got %v", translated[0].Chunk.Metadata)
+	}
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PackingStats records how retrieved candidates were fitted into the context window
+type PackingStats struct {
+	WindowTokens int `json:"window_tokens"` // Model context window
+	BudgetTokens int `json:"budget_tokens"` // Tokens available for retrieved context
+	UsedTokens   int `json:"used_tokens"`   // Tokens used by packed context
+
+	Candidates   int `json:"candidates"`   // Results offered to the packer
+	Packed       int `json:"packed"`       // Results included in the context
+	Deduplicated int `json:"deduplicated"` // Results dropped or trimmed as overlapping
+	Truncated    int `json:"truncated"`    // Results cut short to fit the budget
+	Dropped      int `json:"dropped"`      // Results left out for lack of room
+}
+
+// ContextPacker selects and trims retrieval results to fit a token budget.
+// Results are taken in score order; text already covered by a higher
+// scoring chunk is removed before tokens are counted.
+type ContextPacker struct {
+	chunkOverhead int // Tokens per chunk for source labels and separators
+	minChunk      int // Smallest useful truncated chunk
+}
+
+// NewContextPacker creates a context packer
+func NewContextPacker(chunkOverhead, minChunkTokens int) *ContextPacker {
+	return &ContextPacker{
+		chunkOverhead: chunkOverhead,
+		minChunk:      minChunkTokens,
+	}
+}
+
+// Pack returns the results that fit in budget tokens, in score order
+func (cp *ContextPacker) Pack(results []RetrievalResult, budget int) ([]RetrievalResult, PackingStats) {
+	stats := PackingStats{BudgetTokens: budget, Candidates: len(results)}
+
+	ordered := append([]RetrievalResult(nil), results...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Score > ordered[j].Score })
+
+	var packed []RetrievalResult
+	for i, result := range ordered {
+		if result.Chunk == nil {
+			stats.Dropped++
+			continue
+		}
+
+		trimmed, changed := trimOverlap(result, packed)
+		if changed {
+			stats.Deduplicated++
+			if trimmed == nil {
+				continue
+			}
+			result = *trimmed
+		}
+
+		remaining := budget - stats.UsedTokens
+		tokens := EstimateTokens(result.Chunk.Content) + cp.chunkOverhead
+		if tokens <= remaining {
+			packed = append(packed, result)
+			stats.UsedTokens += tokens
+			continue
+		}
+
+		// Fill the remaining room with the head of this chunk, then stop
+		if room := remaining - cp.chunkOverhead; room >= cp.minChunk && room > 0 {
+			chunk := *result.Chunk
+			chunk.Content = truncateToTokens(chunk.Content, room)
+			result.Chunk = &chunk
+			packed = append(packed, result)
+			stats.UsedTokens += EstimateTokens(chunk.Content) + cp.chunkOverhead
+			stats.Truncated++
+			stats.Dropped += len(ordered) - i - 1
+			break
+		}
+
+		// Too little room to be useful; a smaller lower-ranked chunk may still fit
+		stats.Dropped++
+	}
+
+	for i := range packed {
+		packed[i].Position = i + 1
+	}
+	stats.Packed = len(packed)
+	return packed, stats
+}
+
+// trimOverlap removes text of result already covered by packed results. It
+// returns nil when nothing new remains, and whether anything was removed.
+func trimOverlap(result RetrievalResult, packed []RetrievalResult) (*RetrievalResult, bool) {
+	chunk := result.Chunk
+	content := strings.TrimSpace(chunk.Content)
+	start, end := chunk.StartPos, chunk.EndPos
+	positional := end > start && end-start == len(chunk.Content)
+
+	for _, other := range packed {
+		oc := other.Chunk
+		if chunk.ContentHash != "" && chunk.ContentHash == oc.ContentHash {
+			return nil, true
+		}
+		if strings.Contains(oc.Content, content) {
+			return nil, true
+		}
+
+		// Positional overlap is only meaningful within the same document
+		if !positional || other.DocumentID != result.DocumentID || oc.EndPos <= oc.StartPos {
+			continue
+		}
+		if oc.StartPos <= start && oc.EndPos >= end {
+			return nil, true
+		}
+		switch {
+		case oc.StartPos <= start && oc.EndPos > start:
+			start = oc.EndPos
+		case oc.StartPos < end && oc.EndPos >= end:
+			end = oc.StartPos
+		}
+	}
+
+	if start == chunk.StartPos && end == chunk.EndPos {
+		return &result, false
+	}
+
+	offset := start - chunk.StartPos
+	length := end - start
+	if length <= 0 {
+		return nil, true
+	}
+	trimmed := *chunk
+	trimmed.Content = chunk.Content[offset : offset+length]
+	trimmed.StartPos, trimmed.EndPos = start, end
+	if !utf8.ValidString(trimmed.Content) || strings.TrimSpace(trimmed.Content) == "" {
+		return nil, true
+	}
+	result.Chunk = &trimmed
+	return &result, true
+}
+
+// truncateToTokens cuts text to about maxTokens, preferring a sentence or word boundary
+func truncateToTokens(text string, maxTokens int) string {
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+
+	// Binary search the longest prefix within budget
+	lo, hi := 0, len(text)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if EstimateTokens(text[:mid]) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	for lo > 0 && !utf8.RuneStart(text[lo]) {
+		lo--
+	}
+	cut := text[:lo]
+
+	if i := strings.LastIndexAny(cut, ".!?。！？\n"); i > len(cut)/2 {
+		_, size := utf8.DecodeRuneInString(cut[i:])
+		return cut[:i+size]
+	}
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > len(cut)/2 {
+		return cut[:i]
+	}
+	return cut
+}
+
+// EstimateTokens estimates the token count of text for BPE tokenizers such as
+// cl100k. It is considerably closer than a fixed characters-per-token ratio
+// for code, numbers and CJK text:
+//   - Latin words cost one token per four letters, at least one
+//   - digit runs cost one token per three digits
+//   - each punctuation or symbol character costs one token
+//   - each CJK character costs one token, other scripts one per two runes
+//   - whitespace is folded into the following token, except line breaks
+func EstimateTokens(text string) int {
+	tokens := 0
+	letters, digits, other := 0, 0, 0
+
+	flush := func() {
+		tokens += (letters + 3) / 4
+		tokens += (digits + 2) / 3
+		tokens += (other + 1) / 2
+		letters, digits, other = 0, 0, 0
+	}
+
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || r == '_'):
+			if digits > 0 || other > 0 {
+				flush()
+			}
+			letters++
+		case unicode.IsDigit(r):
+			if letters > 0 || other > 0 {
+				flush()
+			}
+			digits++
+		case isCJK(r):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			if letters > 0 || digits > 0 {
+				flush()
+			}
+			other++
+		case r == '\n':
+			flush()
+			tokens++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// packContext fits retrieval results into the generation model's context
+// window, leaving room for the prompt, the answer and a safety margin
+func (p *Pipeline) packContext(query string, results []RetrievalResult, options GenerateOptions) ([]RetrievalResult, PackingStats) {
+	config := p.config.Generation
+
+	window := options.MaxContextLength
+	if window == 0 {
+		window = config.MaxContextLength
+	}
+	if window <= 0 {
+		return results, PackingStats{Candidates: len(results), Packed: len(results)}
+	}
+
+	systemPrompt := options.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = config.SystemPrompt
+	}
+	template := options.PromptTemplate
+	if template == "" {
+		template = config.UserPromptTemplate
+	}
+	reserved := options.MaxTokens +
+		EstimateTokens(systemPrompt) +
+		EstimateTokens(template) +
+		EstimateTokens(query) +
+		EstimateTokens(options.StructuredContext)
+
+	budget := int(float64(window-reserved) * (1 - config.ContextSafetyMargin))
+	if budget < 0 {
+		budget = 0
+	}
+
+	packer := NewContextPacker(config.ContextChunkOverhead, config.MinPackedChunkTokens)
+	packed, stats := packer.Pack(results, budget)
+	stats.WindowTokens = window
+	return packed, stats
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "a", want: 1},
+		{text: "hello world", want: 4},
+		{text: "1234567", want: 3},
+		{text: "x := 42;", want: 5},
+		{text: "line\nbreak", want: 4},
+		{text: "检索增强", want: 4},
+		{text: "привет", want: 3},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	text := "First sentence here. Second sentence follows and keeps going for a while"
+	if got := truncateToTokens(text, 100); got != text {
+		t.Fatalf("expected text within budget to be unchanged, got %q", got)
+	}
+	if got := truncateToTokens(text, 10); got != "First sentence here." {
+		t.Fatalf("expected a cut at the sentence boundary, got %q", got)
+	}
+	if got := truncateToTokens("Short one. Then a much longer tail", 6); got != "Short one. Then a" {
+		t.Fatalf("got %q", got)
+	}
+	if got := truncateToTokens("检索增强生成", 3); got != "检索增" {
+		t.Fatalf("expected a cut on a rune boundary, got %q", got)
+	}
+}
+
+func TestContextPackerPack(t *testing.T) {
+	doc := "alpha beta gamma delta epsilon zeta eta theta"
+	chunk := func(id string, start, end int, score float64) RetrievalResult {
+		return RetrievalResult{
+			DocumentID: "doc",
+			Score:      score,
+			Chunk:      &DocumentChunk{ID: id, DocumentID: "doc", Content: doc[start:end], StartPos: start, EndPos: end},
+		}
+	}
+
+	results := []RetrievalResult{
+		chunk("low", 31, 45, 0.2),
+		chunk("top", 0, 22, 0.9),
+		chunk("overlap", 11, 35, 0.8),
+		chunk("inside", 6, 16, 0.7),
+		{DocumentID: "other", Score: 0.6},
+	}
+	packed, stats := NewContextPacker(0, 1).Pack(results, 100)
+
+	var ids []string
+	for i, result := range packed {
+		ids = append(ids, result.Chunk.ID)
+		if result.Position != i+1 {
+			t.Fatalf("expected positions to follow packing order, got %d at %d", result.Position, i)
+		}
+	}
+	if strings.Join(ids, ",") != "top,overlap,low" {
+		t.Fatalf("got packed %v", ids)
+	}
+	if packed[1].Chunk.Content != " epsilon zeta" || packed[1].Chunk.StartPos != 22 {
+		t.Fatalf("expected the overlap with the top chunk to be trimmed, got %+v", packed[1].Chunk)
+	}
+	if packed[2].Chunk.Content != " eta theta" {
+		t.Fatalf("expected the overlap with the trimmed chunk to be trimmed, got %q", packed[2].Chunk.Content)
+	}
+	if stats.Candidates != 5 || stats.Packed != 3 || stats.Deduplicated != 3 || stats.Dropped != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if results[2].Chunk.Content != doc[11:35] {
+		t.Fatal("expected the input results to be left untouched")
+	}
+}
+
+func TestContextPackerBudget(t *testing.T) {
+	results := []RetrievalResult{
+		{DocumentID: "a", Score: 0.9, Chunk: &DocumentChunk{ID: "a", Content: strings.Repeat("word ", 20)}},
+		{DocumentID: "b", Score: 0.8, Chunk: &DocumentChunk{ID: "b", Content: strings.Repeat("long ", 20)}},
+		{DocumentID: "c", Score: 0.7, Chunk: &DocumentChunk{ID: "c", Content: "tiny"}},
+		{DocumentID: "d", Score: 0.6, Chunk: &DocumentChunk{ID: "d", Content: "small"}},
+	}
+
+	// The second chunk is truncated to fill the room left and packing stops
+	packed, stats := NewContextPacker(2, 5).Pack(results, 40)
+	if len(packed) != 2 || stats.Truncated != 1 || stats.Dropped != 2 || stats.UsedTokens > 40 {
+		t.Fatalf("unexpected packing %+v", stats)
+	}
+	if !strings.HasPrefix(results[1].Chunk.Content, packed[1].Chunk.Content) || len(packed[1].Chunk.Content) >= len(results[1].Chunk.Content) {
+		t.Fatalf("expected a prefix of the second chunk, got %q", packed[1].Chunk.Content)
+	}
+
+	// Without room for a useful prefix, smaller lower-ranked chunks still fit
+	packed, stats = NewContextPacker(2, 50).Pack(results, 30)
+	if len(packed) != 3 || packed[1].Chunk.ID != "c" || packed[2].Chunk.ID != "d" || stats.Dropped != 1 || stats.Truncated != 0 {
+		t.Fatalf("unexpected packing %+v", stats)
+	}
+}
+
+func TestPipelinePackContext(t *testing.T) {
+	config := DefaultConfig()
+	config.Generation.MaxContextLength = 0
+	p := &Pipeline{config: config}
+	results := []RetrievalResult{{DocumentID: "a", Chunk: &DocumentChunk{Content: strings.Repeat("word ", 100)}}}
+
+	packed, stats := p.packContext("query", results, GenerateOptions{})
+	if len(packed) != 1 || stats.Packed != 1 {
+		t.Fatalf("expected packing to be skipped without a context window, got %+v", stats)
+	}
+
+	packed, stats = p.packContext("query", results, GenerateOptions{MaxContextLength: 60, MaxTokens: 40, SystemPrompt: "s", PromptTemplate: "t"})
+	if stats.WindowTokens != 60 || stats.BudgetTokens >= 20 || stats.UsedTokens > stats.BudgetTokens {
+		t.Fatalf("expected the prompt and answer to be reserved, got %+v", stats)
+	}
+	if len(packed) == 1 && len(packed[0].Chunk.Content) >= len(results[0].Chunk.Content) {
+		t.Fatal("expected the chunk to be cut to the budget")
+	}
+}
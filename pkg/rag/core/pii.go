@@ -0,0 +1,137 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PII categories recognized by MaskPII
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "credit_card"
+	PIINationalID = "national_id"
+	PIIIPAddress  = "ip_address"
+)
+
+// piiPatterns are checked in order; earlier patterns win where matches overlap
+var piiPatterns = []struct {
+	category string
+	pattern  *regexp.Regexp
+	valid    func(text string, start, end int) bool
+}{
+	{PIIEmail, regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), nil},
+	{PIICreditCard, regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), luhnValid},
+	// US social security numbers and 18-digit PRC resident identity numbers
+	{PIINationalID, regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b|\b\d{17}[\dXx]\b`), nil},
+	{PIIIPAddress, regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`), nil},
+	{PIIPhone, regexp.MustCompile(`(?:\+\d{1,3}[ \-.]?)?(?:\(\d{2,4}\)[ \-.]?)?\d{3,4}[ \-.]?\d{3,4}[ \-.]?\d{0,4}`), phoneValid},
+}
+
+// PIIMaskResult counts masked spans by category
+type PIIMaskResult struct {
+	Masked     int            `json:"masked"`
+	Categories map[string]int `json:"categories,omitempty"`
+}
+
+// Add merges the counts of another result
+func (r *PIIMaskResult) Add(other PIIMaskResult) {
+	if other.Masked == 0 {
+		return
+	}
+	if r.Categories == nil {
+		r.Categories = make(map[string]int)
+	}
+	r.Masked += other.Masked
+	for category, n := range other.Categories {
+		r.Categories[category] += n
+	}
+}
+
+// MaskPII replaces email addresses, phone numbers, payment card numbers,
+// national ID numbers and IP addresses in text with [category] placeholders.
+// Detection is pattern based and errs towards masking.
+func MaskPII(text string) (string, PIIMaskResult) {
+	type span struct {
+		start, end int
+		category   string
+	}
+	var spans []span
+	taken := func(start, end int) bool {
+		for _, s := range spans {
+			if start < s.end && s.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range piiPatterns {
+		for _, loc := range p.pattern.FindAllStringIndex(text, -1) {
+			if taken(loc[0], loc[1]) || (p.valid != nil && !p.valid(text, loc[0], loc[1])) {
+				continue
+			}
+			spans = append(spans, span{loc[0], loc[1], p.category})
+		}
+	}
+
+	result := PIIMaskResult{}
+	if len(spans) == 0 {
+		return text, result
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	result.Categories = make(map[string]int)
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(text[last:s.start])
+		b.WriteString("[" + s.category + "]")
+		last = s.end
+		result.Masked++
+		result.Categories[s.category]++
+	}
+	b.WriteString(text[last:])
+	return b.String(), result
+}
+
+// luhnValid reports whether the digits of a card-like match pass the Luhn check
+func luhnValid(text string, start, end int) bool {
+	match := text[start:end]
+	sum, n := 0, 0
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if !isDigit(c) {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// phoneValid requires enough digits that dates and short numbers are left
+// alone, and rejects matches cut out of a longer run of digits
+func phoneValid(text string, start, end int) bool {
+	if (start > 0 && isDigit(text[start-1])) || (end < len(text) && isDigit(text[end])) {
+		return false
+	}
+	digits := 0
+	for _, c := range text[start:end] {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	return digits >= 10 && digits <= 15
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,34 @@
+package core
+
+import "testing"
+
+func TestMaskPII(t *testing.T) {
+	cases := []struct {
+		name, text, want string
+		masked           int
+	}{
+		{"email", "Write to jane.doe@example.com today", "Write to [email] today", 1},
+		{"phone", "Call +1 415-555-0132 or 13800138000", "Call [phone] or [phone]", 2},
+		{"card", "Card 4111 1111 1111 1111 was charged", "Card [credit_card] was charged", 1},
+		{"invalid card", "Order 1234567812345678 shipped", "Order 1234567812345678 shipped", 0},
+		{"ssn", "SSN 123-45-6789", "SSN [national_id]", 1},
+		{"resident id", "身份证 11010519491231002X 已登记", "身份证 [national_id] 已登记", 1},
+		{"ip", "Login from 192.168.1.20", "Login from [ip_address]", 1},
+		{"plain", "Released on 2024-01-15 as version 3.2.1", "Released on 2024-01-15 as version 3.2.1", 0},
+	}
+	for _, c := range cases {
+		got, result := MaskPII(c.text)
+		if got != c.want || result.Masked != c.masked {
+			t.Errorf("%s: got %q (%d masked), want %q (%d)", c.name, got, result.Masked, c.want, c.masked)
+		}
+	}
+
+	total := PIIMaskResult{}
+	_, first := MaskPII("a@example.com b@example.com")
+	_, second := MaskPII("10.0.0.1")
+	total.Add(first)
+	total.Add(second)
+	if total.Masked != 3 || total.Categories[PIIEmail] != 2 || total.Categories[PIIIPAddress] != 1 {
+		t.Fatalf("unexpected totals %+v", total)
+	}
+}
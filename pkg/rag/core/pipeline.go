@@ -2,11 +2,14 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/guileen/metabase/pkg/rag/llm"
+	"golang.org/x/sync/errgroup"
 )
 
 // Pipeline represents the main RAG system implementation
@@ -36,6 +39,87 @@ type Pipeline struct {
 	// Runtime state
 	activeQueries map[string]*QueryContext
 	queryCounter  int64
+	stageTimeouts map[string]int64 // Query stage timeouts by stage
+
+	// Clock and ID source, replaced in deterministic mode
+	clock func() time.Time
+	newID func() string
+
+	// Background migration state
+	reembed *reembedState
+
+	// Chunk and document deduplication
+	dedup    *ChunkDeduplicator
+	docDedup *DocumentDeduplicator
+
+	// Summarization-augmented indexing
+	summarizer *Summarizer
+
+	// Image namespace
+	imageEmbedder  ImageEmbedder
+	imageCaptioner ImageCaptioner
+	imageRetriever Retriever
+
+	// Output moderation
+	moderators []Moderator
+
+	// Go hooks projects can bind to pipeline stages, by name
+	hooks map[string]Hook
+
+	// WASM runtime and modules of custom preprocessing filters
+	wasm wasmState
+
+	// Per-project configuration overrides
+	projectConfigs ProjectConfigStore
+
+	// Per-project glossaries of acronyms and domain terms
+	glossaries GlossaryStore
+
+	// Background batch query jobs
+	batchJobs map[string]*batchState
+
+	// Tool registries by project ID, "" holds global tools
+	toolRegistries map[string]*ToolRegistry
+
+	// Tenant LLM budgets
+	budgets        BudgetStore
+	budgetNotifier BudgetNotifier
+
+	// Data residency region of each project's tenant
+	residency ResidencyStore
+
+	// Per-tenant encryption at rest
+	keyRing *KeyRing
+	tenants TenantResolver
+
+	// Storage maintenance scheduler and log
+	maintenance maintenanceState
+
+	// Coordination of background jobs between instances
+	locker Locker
+	leader *LeaderElector
+
+	// Document version history
+	versions DocumentVersionStore
+
+	// Soft-deleted documents awaiting purge, by document ID
+	tombstones     map[string]Tombstone
+	tombstoneStore TombstoneStore
+
+	// Cold embedding tier and pinned hot documents
+	tiering tieringState
+
+	// Index snapshots for rollback
+	snapshots IndexSnapshotStore
+
+	// Failed and quarantined data source documents
+	documentFailures DocumentFailureStore
+
+	// Serializes blue/green rebuilds, which replace the retriever
+	swapMu sync.Mutex
+
+	// Citation deep link builders by document source type
+	deepLinkers map[string]DeepLinker
 }
 
 // QueryContext tracks the context of an active query
@@ -61,10 +145,23 @@ func NewPipeline(config *Config) (*Pipeline, error) {
 	}
 
 	pipeline := &Pipeline{
-		config:        config,
-		dataSources:   make(map[string]DataSource),
-		activeQueries: make(map[string]*QueryContext),
-		queryCounter:  0,
+		config:         config,
+		dataSources:    make(map[string]DataSource),
+		activeQueries:  make(map[string]*QueryContext),
+		batchJobs:      make(map[string]*batchState),
+		toolRegistries: make(map[string]*ToolRegistry),
+		tombstones:     make(map[string]Tombstone),
+		budgetNotifier: NewWebhookBudgetNotifier(10 * time.Second),
+		queryCounter:   0,
+	}
+
+	pipeline.configureDeterminism()
+
+	if config.Processing.Chunking.Deduplicate {
+		pipeline.dedup = NewChunkDeduplicator(config.Processing.Chunking.DedupMinSize)
+	}
+	if config.Processing.Chunking.DeduplicateDocuments {
+		pipeline.docDedup = NewDocumentDeduplicator()
 	}
 
 	// Initialize core components
@@ -114,16 +211,37 @@ func (p *Pipeline) initializeComponents() error {
 
 // initializeOptionalComponents initializes optional RAG components
 func (p *Pipeline) initializeOptionalComponents() error {
+	// Initialize locking for background jobs
+	locker, err := p.createLocker()
+	if err != nil {
+		return fmt.Errorf("failed to create locker: %w", err)
+	}
+	p.locker = locker
+
 	// Initialize cache if enabled
 	if p.config.Cache.Enabled {
 		p.cache, _ = p.createCache()
 	}
 
+	// Initialize summarizer if enabled
+	if p.config.Processing.Summarization.Enabled && p.llmClient != nil {
+		p.summarizer = NewSummarizer(p.llmClient, p.config.Processing.Summarization)
+	}
+
 	// Initialize metrics if enabled
 	if p.config.Metrics.Enabled {
 		p.metrics, _ = p.createMetricsCollector()
 	}
 
+	// Initialize output moderation
+	if p.config.Generation.Moderation.Enabled {
+		moderators, err := p.createModerators()
+		if err != nil {
+			return fmt.Errorf("failed to create moderators: %w", err)
+		}
+		p.moderators = moderators
+	}
+
 	// Initialize default filters and rankers
 	if p.config.Retrieval.EnableFilters {
 		p.filters = p.createDefaultFilters()
@@ -138,6 +256,14 @@ func (p *Pipeline) initializeOptionalComponents() error {
 
 // Start starts the RAG pipeline
 func (p *Pipeline) Start(ctx context.Context) error {
+	// Deduplication state is kept in memory, so rebuild it from storage
+	if err := p.rebuildChunkDeduplication(ctx); err != nil {
+		p.emitError(ctx, "rebuild_chunk_dedup", err)
+	}
+	if err := p.rebuildDocumentDeduplication(ctx); err != nil {
+		p.emitError(ctx, "rebuild_document_dedup", err)
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -146,11 +272,17 @@ func (p *Pipeline) Start(ctx context.Context) error {
 	}
 
 	p.started = true
-	p.startTime = time.Now()
+	p.startTime = p.now()
 	p.lastActivity = p.startTime
 
+	// Scheduled jobs run only on the elected instance
+	p.leader = NewLeaderElector(p.locker, LockKeyScheduler, p.LockTTL())
+	p.leader.Start(ctx)
+
 	// Start background tasks
 	go p.backgroundMaintenance(ctx)
+	go p.refreshTombstonesLoop(ctx)
+	p.startMaintenanceScheduler(ctx)
 
 	// Emit startup event
 	p.emitEvent(ctx, "pipeline_started", map[string]interface{}{
@@ -172,6 +304,19 @@ func (p *Pipeline) Stop(ctx context.Context) error {
 
 	p.started = false
 
+	// Stop background migrations
+	if p.reembed != nil {
+		p.reembed.cancel()
+	}
+	for _, batch := range p.batchJobs {
+		batch.cancel()
+	}
+	p.stopMaintenanceScheduler()
+	if p.leader != nil {
+		p.leader.Stop()
+		p.leader = nil
+	}
+
 	// Close all data sources
 	for _, source := range p.dataSources {
 		if err := source.Close(); err != nil {
@@ -189,11 +334,17 @@ func (p *Pipeline) Stop(ctx context.Context) error {
 	if p.cache != nil {
 		p.cache.Close()
 	}
+	p.wasm.mu.Lock()
+	if p.wasm.runtime != nil {
+		p.wasm.runtime.Close(ctx)
+		p.wasm.runtime, p.wasm.modules = nil, nil
+	}
+	p.wasm.mu.Unlock()
 
 	// Emit shutdown event
 	p.emitEvent(ctx, "pipeline_stopped", map[string]interface{}{
-		"stop_time": time.Now(),
-		"uptime":    time.Since(p.startTime),
+		"stop_time": p.now(),
+		"uptime":    p.since(p.startTime),
 	})
 
 	return nil
@@ -219,7 +370,7 @@ func (p *Pipeline) AddDataSource(source DataSource) error {
 	}
 
 	p.dataSources[source.GetID()] = source
-	p.lastActivity = time.Now()
+	p.lastActivity = p.now()
 
 	return nil
 }
@@ -240,7 +391,7 @@ func (p *Pipeline) RemoveDataSource(sourceID string) error {
 	}
 
 	delete(p.dataSources, sourceID)
-	p.lastActivity = time.Now()
+	p.lastActivity = p.now()
 
 	return nil
 }
@@ -264,7 +415,7 @@ func (p *Pipeline) Index(ctx context.Context, options IndexOptions) (*IndexResul
 		return nil, fmt.Errorf("pipeline not started")
 	}
 
-	startTime := time.Now()
+	startTime := p.now()
 	result := &IndexResult{
 		DataSourceID: "multiple",
 		IndexType:    "full",
@@ -289,6 +440,16 @@ func (p *Pipeline) Index(ctx context.Context, options IndexOptions) (*IndexResul
 		return nil, fmt.Errorf("no data sources found")
 	}
 
+	// Keep a copy of the index a forced reindex replaces
+	if err := p.snapshotBeforeReindex(ctx, options, sources); err != nil {
+		return nil, fmt.Errorf("failed to snapshot index before reindex: %w", err)
+	}
+
+	// A forced reindex is built beside the serving index and swapped in
+	if options.ForceReindex && !options.DryRun {
+		return p.reindexBlueGreen(ctx, options, sources)
+	}
+
 	// Process each data source
 	for _, source := range sources {
 		sourceResult, err := p.indexDataSource(ctx, source, options)
@@ -304,13 +465,15 @@ func (p *Pipeline) Index(ctx context.Context, options IndexOptions) (*IndexResul
 		result.DocumentsAdded += sourceResult.DocumentsAdded
 		result.DocumentsSkipped += sourceResult.DocumentsSkipped
 		result.DocumentsErrored += sourceResult.DocumentsErrored
+		result.DocumentsDuplicate += sourceResult.DocumentsDuplicate
+		result.Duplicates = append(result.Duplicates, sourceResult.Duplicates...)
 		result.ChunksCreated += sourceResult.ChunksCreated
 		result.ChunksUpdated += sourceResult.ChunksUpdated
 		result.ChunksDeleted += sourceResult.ChunksDeleted
 		result.EmbeddingsGenerated += sourceResult.EmbeddingsGenerated
 	}
 
-	result.CompletedAt = time.Now()
+	result.CompletedAt = p.now()
 	result.TotalTime = result.CompletedAt.Sub(startTime)
 
 	// Calculate processing rate
@@ -328,7 +491,7 @@ func (p *Pipeline) Index(ctx context.Context, options IndexOptions) (*IndexResul
 
 // indexDataSource indexes documents from a single data source
 func (p *Pipeline) indexDataSource(ctx context.Context, source DataSource, options IndexOptions) (*IndexResult, error) {
-	startTime := time.Now()
+	startTime := p.now()
 	result := &IndexResult{
 		DataSourceID: source.GetID(),
 		IndexType:    "full",
@@ -368,6 +531,8 @@ func (p *Pipeline) indexDataSource(ctx context.Context, source DataSource, optio
 		result.DocumentsAdded += batchResult.DocumentsAdded
 		result.DocumentsSkipped += batchResult.DocumentsSkipped
 		result.DocumentsErrored += batchResult.DocumentsErrored
+		result.DocumentsDuplicate += batchResult.DocumentsDuplicate
+		result.Duplicates = append(result.Duplicates, batchResult.Duplicates...)
 		result.ChunksCreated += batchResult.ChunksCreated
 		result.ChunksUpdated += batchResult.ChunksUpdated
 		result.ChunksDeleted += batchResult.ChunksDeleted
@@ -381,7 +546,7 @@ func (p *Pipeline) indexDataSource(ctx context.Context, source DataSource, optio
 		}
 	}
 
-	result.CompletedAt = time.Now()
+	result.CompletedAt = p.now()
 	result.TotalTime = result.CompletedAt.Sub(startTime)
 
 	return result, nil
@@ -394,11 +559,11 @@ func (p *Pipeline) Query(ctx context.Context, query string, options QueryOptions
 	}
 
 	// Create query context
-	queryID := uuid.New().String()
+	queryID := p.generateID()
 	queryCtx := &QueryContext{
 		ID:        queryID,
 		Query:     query,
-		StartTime: time.Now(),
+		StartTime: p.now(),
 		Options:   options,
 		Status:    "started",
 	}
@@ -420,11 +585,35 @@ func (p *Pipeline) Query(ctx context.Context, query string, options QueryOptions
 		"options":  options,
 	})
 
-	startTime := time.Now()
+	startTime := p.now()
 	result := &QueryResult{
 		QueryID:   queryID,
 		Query:     query,
-		CreatedAt: time.Now(),
+		CreatedAt: p.now(),
+	}
+
+	// Merge per-project overrides under explicit request options
+	projectConfig, err := p.loadProjectConfig(ctx, options.ProjectID)
+	if err != nil {
+		queryCtx.Status = "error"
+		queryCtx.Error = err
+		return nil, err
+	}
+	projectConfig.ApplyToQuery(&options)
+
+	// Read from the storage of the project's region
+	ctx, err = p.projectRegionContext(ctx, options.ProjectID)
+	if err != nil {
+		queryCtx.Status = "error"
+		queryCtx.Error = err
+		return nil, err
+	}
+
+	// Parse the filter expression and push it down to the retrievers
+	if err := applyFilterExpression(&options); err != nil {
+		queryCtx.Status = "error"
+		queryCtx.Error = err
+		return nil, err
 	}
 
 	// Check cache first
@@ -432,7 +621,7 @@ func (p *Pipeline) Query(ctx context.Context, query string, options QueryOptions
 		if cached, err := p.cache.Get(ctx, p.getCacheKey(query, options)); err == nil && cached != nil {
 			result = cached
 			result.CacheHit = true
-			result.TotalTime = time.Since(startTime)
+			result.TotalTime = p.since(startTime)
 			queryCtx.Result = result
 			queryCtx.Status = "completed"
 			return result, nil
@@ -442,6 +631,19 @@ func (p *Pipeline) Query(ctx context.Context, query string, options QueryOptions
 	// Set default options if needed
 	p.setDefaultsForOptions(&options)
 
+	// Bound the stages by the query's overall deadline; work between stages
+	// keeps the caller's context so hooks still run on a degraded result
+	timeouts := p.config.Retrieval.StageTimeouts
+	if options.Timeouts != nil {
+		timeouts = options.Timeouts.withDefaults(timeouts)
+	}
+	deadlineCtx := ctx
+	if options.RetrievalOptions.MaxQueryTime > 0 {
+		var cancel context.CancelFunc
+		deadlineCtx, cancel = context.WithTimeout(ctx, options.RetrievalOptions.MaxQueryTime)
+		defer cancel()
+	}
+
 	// Step 1: Process query
 	processedQuery, expandedTerms, err := p.processQuery(ctx, query, options)
 	if err != nil {
@@ -455,67 +657,199 @@ func (p *Pipeline) Query(ctx context.Context, query string, options QueryOptions
 
 	// Step 2: Retrieve documents
 	queryCtx.Status = "retrieving"
-	retrievalStart := time.Now()
-	retrievalResults, err := p.retrieveDocuments(ctx, processedQuery, options.RetrievalOptions)
+	retrievalStart := p.now()
+	var retrievalResults []RetrievalResult
+	retrievalCtx, cancelRetrieval := stageContext(deadlineCtx, timeouts, StageRetrieval)
+	if options.Federation != nil {
+		retrievalResults, result.FederatedProjects, err = p.retrieveFederated(retrievalCtx, processedQuery, options)
+	} else {
+		retrievalResults, err = p.retrieveDocuments(retrievalCtx, processedQuery, options.RetrievalOptions)
+	}
+	timeout, timedOut := stageTimedOut(ctx, retrievalCtx, StageRetrieval, timeouts, err)
+	cancelRetrieval()
+	if timedOut {
+		// Nothing to generate from; return the empty result marked degraded
+		p.recordTimeout(ctx, queryID, result, timeout)
+		result.RetrievalTime = p.since(retrievalStart)
+		result.TotalTime = p.since(startTime)
+		result.Options = options
+		result.Options.GenerateOptions.Stream = nil
+		queryCtx.Result = result
+		queryCtx.Status = "completed"
+		return result, nil
+	}
 	if err != nil {
 		queryCtx.Status = "error"
 		queryCtx.Error = err
 		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
 	}
-	result.RetrievalTime = time.Since(retrievalStart)
+	if options.AsOf != nil && options.Federation == nil {
+		// Serve the document versions current at the requested time
+		if retrievalResults, err = p.resolveVersionsAsOf(ctx, retrievalResults, *options.AsOf); err != nil {
+			queryCtx.Status = "error"
+			queryCtx.Error = err
+			return nil, fmt.Errorf("failed to resolve document versions: %w", err)
+		}
+	}
+	result.RetrievalTime = p.since(retrievalStart)
 	result.RetrievalResults = retrievalResults
 	result.TotalRetrieved = len(retrievalResults)
 
-	// Step 3: Filter and rank results
+	// Step 3: Filter and rank results; a reranker that runs out of time
+	// leaves the results in retrieval order
 	if len(retrievalResults) > 0 {
-		retrievalResults, err = p.filterAndRankResults(ctx, processedQuery, retrievalResults, options)
-		if err != nil {
+		rerankCtx, cancelRerank := stageContext(deadlineCtx, timeouts, StageRerank)
+		retrievalResults, err = p.filterAndRankResults(rerankCtx, processedQuery, retrievalResults, options)
+		if timeout, ok := stageTimedOut(ctx, rerankCtx, StageRerank, timeouts, err); ok {
+			p.recordTimeout(ctx, queryID, result, timeout)
+		} else if err != nil {
 			p.emitError(ctx, "filter_rank_results", err)
 		}
+		cancelRerank()
 	}
 
 	// Apply top-k limit
 	if len(retrievalResults) > options.MaxResults {
 		retrievalResults = retrievalResults[:options.MaxResults]
 	}
+	p.highlightResults(ctx, query, processedQuery, retrievalResults, options.Highlight)
 
 	result.RetrievalResults = retrievalResults
 	result.TotalReturned = len(retrievalResults)
 
-	// Step 4: Generate response
+	// Step 4: Generate response, unless the tenant's budget is exhausted
+	if err := p.checkBudget(ctx, options.TenantID); err != nil {
+		queryCtx.Status = "error"
+		queryCtx.Error = err
+		return nil, err
+	}
 	queryCtx.Status = "generating"
-	generationStart := time.Now()
-	generationResult, err := p.generateResponse(ctx, processedQuery, retrievalResults, options.GenerateOptions)
+	generationStart := p.now()
+	contextResults, packing := p.packContext(processedQuery, retrievalResults, options.GenerateOptions)
+	result.ContextPacking = &packing
+	// Answer in the requested language, translating excerpts written in others
+	options.GenerateOptions.AnswerLanguage = p.answerLanguage(query, options.GenerateOptions)
+	if options.GenerateOptions.TranslateExcerpts || p.config.Generation.TranslateExcerpts {
+		contextResults = p.translateExcerpts(ctx, contextResults, options.GenerateOptions.AnswerLanguage, options.GenerateOptions)
+	}
+	generationQuery := processedQuery
+	hookPayload := &HookPayload{
+		ProjectID:    options.ProjectID,
+		Query:        generationQuery,
+		Context:      contextResults,
+		SystemPrompt: options.GenerateOptions.SystemPrompt,
+	}
+	if err := p.runHooks(ctx, HookPreGenerate, hookPayload); err != nil {
+		queryCtx.Status = "error"
+		queryCtx.Error = err
+		return nil, err
+	}
+	generationQuery, contextResults = hookPayload.Query, hookPayload.Context
+	options.GenerateOptions.SystemPrompt = hookPayload.SystemPrompt
+	sandbox := p.newToolSandbox(options)
+	options.GenerateOptions.ToolSandbox = sandbox
+	if sink := options.GenerateOptions.Stream; sink != nil {
+		citations := sourcesFromResults(contextResults)
+		p.linkSources(ctx, citations, contextResults)
+		sink.OnCitations(citations)
+	}
+	generationCtx, cancelGeneration := stageContext(deadlineCtx, timeouts, StageGeneration)
+	generationResult, err := p.generateResponse(generationCtx, generationQuery, contextResults, options.GenerateOptions)
+	if timeout, ok := stageTimedOut(ctx, generationCtx, StageGeneration, timeouts, err); ok {
+		// Return the retrieved results without an answer
+		p.recordTimeout(ctx, queryID, result, timeout)
+		generationResult, err = &GenerationResult{}, nil
+	}
+	cancelGeneration()
 	if err != nil {
 		queryCtx.Status = "error"
 		queryCtx.Error = err
 		return nil, fmt.Errorf("failed to generate response: %w", err)
 	}
-	result.GenerationTime = time.Since(generationStart)
+	result.GenerationTime = p.since(generationStart)
 
 	// Populate generation results
 	result.GeneratedResponse = generationResult.Response
 	result.GeneratedAnswer = generationResult.Answer
 	result.GeneratedSummary = generationResult.Summary
 	result.Sources = generationResult.Sources
+	p.linkSources(ctx, result.Sources, contextResults)
 	result.InputTokens = generationResult.PromptTokens
 	result.OutputTokens = generationResult.OutputTokens
 	result.TotalTokens = generationResult.PromptTokens + generationResult.OutputTokens
-	result.Cost = generationResult.Cost
+	result.Cost = p.estimateCost(result.TotalTokens, generationResult.Cost)
+	p.recordBudgetUsage(ctx, options.TenantID, int64(result.TotalTokens), result.Cost)
+	result.ToolCalls = generationResult.ToolCalls
+	if result.ToolCalls == nil && sandbox != nil {
+		result.ToolCalls = sandbox.Invocations()
+	}
+	if len(result.ToolCalls) > 0 {
+		p.emitEvent(ctx, "tools_invoked", map[string]interface{}{
+			"query_id":   queryID,
+			"project_id": options.ProjectID,
+			"calls":      result.ToolCalls,
+		})
+	}
+
+	// Post-process the answer before validation and moderation see it
+	hookPayload = &HookPayload{ProjectID: options.ProjectID, Query: query, Result: result}
+	if err := p.runHooks(ctx, HookPostGenerate, hookPayload); err != nil {
+		queryCtx.Status = "error"
+		queryCtx.Error = err
+		return nil, err
+	}
+	if hookPayload.Result != nil {
+		*result = *hookPayload.Result
+	}
+
+	// Validate and repair schema-constrained output
+	if options.GenerateOptions.OutputSchema != nil && !result.Degraded {
+		structured := p.enforceStructuredOutput(ctx, result.GeneratedResponse, options.GenerateOptions)
+		if !structured.Valid && p.config.Generation.StructuredOutput.RejectInvalid {
+			queryCtx.Status = "error"
+			queryCtx.Error = fmt.Errorf("structured output invalid: %s", strings.Join(structured.Errors, "; "))
+			return nil, queryCtx.Error
+		}
+		if structured.Valid {
+			result.GeneratedResponse = string(structured.Data)
+		}
+		result.StructuredOutput = structured
+	}
+
+	// Define glossary terms the answer uses
+	if p.config.Generation.GlossaryFootnotes && options.GenerateOptions.OutputSchema == nil {
+		p.addGlossaryFootnotes(ctx, options.ProjectID, result)
+	}
+
+	// Step 5: Moderate generated output before it leaves the pipeline
+	result.Moderation = p.moderateResult(ctx, result)
+	if structured := result.StructuredOutput; structured != nil && result.Moderation != nil &&
+		result.Moderation.Action.severity() >= ModerationActionRedact.severity() {
+		// Moderated text replaces the structured data so it cannot bypass the action
+		structured.Data = nil
+		if json.Valid([]byte(result.GeneratedResponse)) {
+			structured.Data = json.RawMessage(result.GeneratedResponse)
+		} else {
+			structured.Valid = false
+			structured.Errors = append(structured.Errors, "withheld by moderation")
+		}
+	}
 
 	// Calculate total time
-	result.TotalTime = time.Since(startTime)
+	result.TotalTime = p.since(startTime)
 	result.Options = options
+	result.Options.GenerateOptions.Stream = nil
 	result.FilterApplied = len(p.filters) > 0
 	result.RerankingApplied = p.config.Retrieval.EnableRerank
 
-	// Cache result
-	if p.cache != nil && options.EnableCache {
+	// Cache result; answers that used tools depend on live data and degraded
+	// results would outlive the slowdown that caused them
+	if p.cache != nil && options.EnableCache && len(result.ToolCalls) == 0 && !result.Degraded {
 		cacheTTL := options.CacheTTL
 		if cacheTTL == 0 {
 			cacheTTL = p.config.Cache.TTL
 		}
-		p.cache.Set(ctx, p.getCacheKey(query, options), result, cacheTTL)
+		p.cacheQueryResult(ctx, p.getCacheKey(query, options), result, cacheTTL)
 	}
 
 	// Record metrics
@@ -535,11 +869,90 @@ func (p *Pipeline) Query(ctx context.Context, query string, options QueryOptions
 
 	queryCtx.Result = result
 	queryCtx.Status = "completed"
-	p.lastActivity = time.Now()
+	p.lastActivity = p.now()
 
 	return result, nil
 }
 
+// Search retrieves and ranks the chunks relevant to a query without
+// generating a response, for callers that do their own generation
+func (p *Pipeline) Search(ctx context.Context, query string, options QueryOptions) ([]RetrievalResult, error) {
+	if !p.started {
+		return nil, fmt.Errorf("pipeline not started")
+	}
+
+	projectConfig, err := p.loadProjectConfig(ctx, options.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	projectConfig.ApplyToQuery(&options)
+	if ctx, err = p.projectRegionContext(ctx, options.ProjectID); err != nil {
+		return nil, err
+	}
+	if err := applyFilterExpression(&options); err != nil {
+		return nil, err
+	}
+	p.setDefaultsForOptions(&options)
+
+	processedQuery, _, err := p.processQuery(ctx, query, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process query: %w", err)
+	}
+	results, err := p.retrieveDocuments(ctx, processedQuery, options.RetrievalOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve documents: %w", err)
+	}
+	if options.AsOf != nil {
+		if results, err = p.resolveVersionsAsOf(ctx, results, *options.AsOf); err != nil {
+			return nil, fmt.Errorf("failed to resolve document versions: %w", err)
+		}
+	}
+
+	if len(results) > 0 {
+		if results, err = p.filterAndRankResults(ctx, processedQuery, results, options); err != nil {
+			p.emitError(ctx, "filter_rank_results", err)
+		}
+	}
+	if len(results) > options.MaxResults {
+		results = results[:options.MaxResults]
+	}
+	p.highlightResults(ctx, query, processedQuery, results, options.Highlight)
+	p.lastActivity = p.now()
+	return results, nil
+}
+
+// GetDocument returns a stored document; soft-deleted documents are not found
+func (p *Pipeline) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	if p.isTombstoned(documentID) {
+		return nil, nil
+	}
+	doc, err := p.storage.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	return doc, nil
+}
+
+// ListDocuments lists stored documents, leaving out soft-deleted ones
+func (p *Pipeline) ListDocuments(ctx context.Context, options ListOptions) ([]Document, error) {
+	documents, err := p.storage.ListDocuments(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	listed := documents[:0]
+	for _, doc := range documents {
+		if !p.isTombstoned(doc.ID) {
+			listed = append(listed, doc)
+		}
+	}
+	return listed, nil
+}
+
+// Config returns the pipeline configuration
+func (p *Pipeline) Config() *Config {
+	return p.config
+}
+
 // GetStats returns system statistics
 func (p *Pipeline) GetStats() (*SystemStats, error) {
 	p.mu.RLock()
@@ -547,9 +960,10 @@ func (p *Pipeline) GetStats() (*SystemStats, error) {
 
 	stats := &SystemStats{
 		TotalQueries:  p.queryCounter,
-		Uptime:        time.Since(p.startTime),
+		Uptime:        p.since(p.startTime),
 		LastUpdated:   p.lastActivity,
 		ActiveSources: make([]string, 0, len(p.dataSources)),
+		MemoryUsage:   CollectSystemMetrics().MemoryUsage,
 	}
 
 	for id := range p.dataSources {
@@ -582,6 +996,19 @@ func (p *Pipeline) GetStats() (*SystemStats, error) {
 		}
 	}
 
+	// Get maintenance stats
+	p.maintenance.mu.Lock()
+	stats.MaintenanceRuns = p.maintenance.runs
+	stats.ReclaimedSpace = p.maintenance.reclaimedTotal
+	p.maintenance.mu.Unlock()
+
+	if len(p.stageTimeouts) > 0 {
+		stats.QueryTimeouts = make(map[string]int64, len(p.stageTimeouts))
+		for stage, count := range p.stageTimeouts {
+			stats.QueryTimeouts[stage] = count
+		}
+	}
+
 	return stats, nil
 }
 
@@ -647,65 +1074,21 @@ func (p *Pipeline) filterDocuments(documents []Document, options IndexOptions) [
 
 // processDocumentBatch processes a batch of documents
 func (p *Pipeline) processDocumentBatch(ctx context.Context, documents []Document, options IndexOptions) (*IndexResult, error) {
-	startTime := time.Now()
+	startTime := p.now()
 	result := &IndexResult{
 		StartedAt: startTime,
 	}
 
-	embeddingStart := time.Now()
-
-	for _, doc := range documents {
-		// Process document (chunking and embedding)
-		chunks, err := p.processor.ProcessDocument(ctx, doc)
-		if err != nil {
-			result.DocumentsErrored++
-			result.Errors = append(result.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
-			continue
-		}
-
-		if len(chunks) == 0 {
-			result.DocumentsSkipped++
-			continue
-		}
-
-		// Store document and chunks
-		if err := p.storage.StoreDocument(ctx, doc); err != nil {
-			result.DocumentsErrored++
-			result.Errors = append(result.Errors, fmt.Sprintf("Store document %s: %v", doc.ID, err))
-			continue
-		}
-
-		for _, chunk := range chunks {
-			if err := p.storage.StoreChunk(ctx, chunk); err != nil {
-				result.DocumentsErrored++
-				result.Errors = append(result.Errors, fmt.Sprintf("Store chunk %s: %v", chunk.ID, err))
-				continue
-			}
-
-			if len(chunk.Embedding) > 0 {
-				if err := p.storage.StoreEmbedding(ctx, chunk.ID, chunk.Embedding); err != nil {
-					result.DocumentsErrored++
-					result.Errors = append(result.Errors, fmt.Sprintf("Store embedding %s: %v", chunk.ID, err))
-					continue
-				}
-				result.EmbeddingsGenerated++
-			}
+	embeddingStart := p.now()
+	indexVersion := IndexVersionFromConfig(p.config.Processing.Embedding).String()
 
-			// Add to retriever
-			if err := p.retriever.AddDocument(ctx, chunk); err != nil {
-				result.DocumentsErrored++
-				result.Errors = append(result.Errors, fmt.Sprintf("Add to retriever %s: %v", chunk.ID, err))
-				continue
-			}
-		}
-
-		result.DocumentsProcessed++
-		result.DocumentsAdded++
-		result.ChunksCreated += len(chunks)
+	for i := range documents {
+		documents[i] = withProjectID(documents[i], options.ProjectID)
 	}
+	p.indexSourceDocuments(ctx, documents, indexVersion, result)
 
-	result.EmbeddingTime = time.Since(embeddingStart)
-	result.CompletedAt = time.Now()
+	result.EmbeddingTime = p.since(embeddingStart)
+	result.CompletedAt = p.now()
 	result.TotalTime = result.CompletedAt.Sub(startTime)
 
 	return result, nil
@@ -716,6 +1099,17 @@ func (p *Pipeline) processQuery(ctx context.Context, query string, options Query
 	processedQuery := query
 	var expandedTerms []string
 
+	// Expand glossary terms, e.g. "SLA" with "Service Level Agreement"
+	if p.config.Retrieval.ExpandGlossaryTerms {
+		glossary, err := p.loadGlossary(ctx, options.ProjectID)
+		if err != nil {
+			p.emitError(ctx, "expand_glossary_terms", err)
+		} else if expansions := glossary.Expand(query); len(expansions) > 0 {
+			expandedTerms = append(expandedTerms, expansions...)
+			processedQuery = fmt.Sprintf("%s (%s)", query, strings.Join(expansions, "; "))
+		}
+	}
+
 	// Use vocabulary system for expansion if available
 	if vocabManager := p.getVocabularyManager(); vocabManager != nil {
 		// TODO: Implement vocabulary expansion when vocabulary manager is available
@@ -734,13 +1128,65 @@ func (p *Pipeline) processQuery(ctx context.Context, query string, options Query
 
 // retrieveDocuments retrieves relevant documents for the query
 func (p *Pipeline) retrieveDocuments(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
-	return p.retriever.Retrieve(ctx, query, options)
+	// Search the index, the migration target of a re-embedding in progress
+	// and the image namespace concurrently; only the index is required
+	var results, migrated, images []RetrievalResult
+	var migratedErr, imagesErr error
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		var err error
+		results, err = p.retriever.Retrieve(groupCtx, query, options)
+		return err
+	})
+	target := p.dualReadRetriever()
+	if target != nil {
+		group.Go(func() error {
+			migrated, migratedErr = target.Retrieve(groupCtx, query, options)
+			return nil
+		})
+	}
+	if options.IncludeImages {
+		group.Go(func() error {
+			images, imagesErr = p.retrieveImages(groupCtx, query, options)
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Dual-read while a re-embedding migration is in progress
+	if target != nil {
+		if migratedErr != nil {
+			p.emitError(ctx, "dual_read_retrieve", migratedErr)
+		} else {
+			results = fuseRetrievalResults(results, migrated, options.TopK)
+		}
+	}
+
+	// Merge the image namespace
+	if imagesErr != nil {
+		p.emitError(ctx, "retrieve_images", imagesErr)
+	} else if len(images) > 0 {
+		results = mergeRetrievalResults(results, images, 0)
+	}
+
+	results = p.dropTombstoned(results)
+	p.expandDuplicateReferences(results)
+	p.rehydrateRetrieved(results)
+
+	return results, nil
 }
 
 // filterAndRankResults applies filters and ranking to retrieval results
 func (p *Pipeline) filterAndRankResults(ctx context.Context, query string, results []RetrievalResult, options QueryOptions) ([]RetrievalResult, error) {
 	var err error
 
+	// Enforce the filter expression exactly; retrievers may only apply the pushed-down subset
+	if options.RetrievalOptions.FilterOptions.Expression != nil {
+		results, _ = NewExpressionFilter().Filter(ctx, results, options.RetrievalOptions.FilterOptions)
+	}
+
 	// Apply filters
 	if len(p.filters) > 0 {
 		for _, filter := range p.filters {
@@ -751,21 +1197,73 @@ func (p *Pipeline) filterAndRankResults(ctx context.Context, query string, resul
 		}
 	}
 
-	// Apply rankers
+	// Apply rankers, keeping the previous order when one fails; once the
+	// context is done the remaining rankers are skipped
+	var rankErr error
 	if len(p.rankers) > 0 && options.EnableRerank {
 		for _, ranker := range p.rankers {
-			results, err = ranker.Rank(ctx, query, results)
+			ranked, err := ranker.Rank(ctx, query, results)
 			if err != nil {
+				if ctx.Err() != nil {
+					rankErr = err
+					break
+				}
 				p.emitError(ctx, "rank_results", err)
+				continue
 			}
+			results = ranked
 		}
 	}
 
-	return results, nil
+	// Favor fresh documents and preferred sources
+	results = boostResults(results, options.RetrievalOptions, p.now())
+
+	// Rescore with the project's expression on top of the base scores
+	if expression := options.RetrievalOptions.ScoringExpression; expression != "" {
+		results = p.rescoreResults(ctx, expression, results)
+	}
+
+	return results, rankErr
 }
 
 // generateResponse generates a response using the query and retrieved context
 func (p *Pipeline) generateResponse(ctx context.Context, query string, context []RetrievalResult, options GenerateOptions) (*GenerationResult, error) {
+	// Render matched tables in a stable format so aggregate questions can be answered
+	if options.RenderTables || p.config.Generation.RenderTables {
+		if tables := RenderTablesForPrompt(context); tables != "" {
+			if options.StructuredContext != "" {
+				options.StructuredContext += "\n\n"
+			}
+			options.StructuredContext += tables
+		}
+	}
+
+	// Ask for the answer language and for schema-conforming JSON; generators
+	// map the latter onto native JSON modes
+	var instructions []string
+	if options.AnswerLanguage != "" {
+		instructions = append(instructions, answerLanguageInstruction(options.AnswerLanguage))
+	}
+	if options.OutputSchema != nil {
+		instructions = append(instructions, structuredOutputInstruction(options.OutputSchema))
+		options.Format = "json"
+	}
+	if len(instructions) > 0 {
+		systemPrompt := options.SystemPrompt
+		if systemPrompt == "" {
+			systemPrompt = p.config.Generation.SystemPrompt
+		}
+		if systemPrompt != "" {
+			systemPrompt += "\n\n"
+		}
+		options.SystemPrompt = systemPrompt + strings.Join(instructions, "\n\n")
+	}
+
+	if p.canStream(options) {
+		if streaming, ok := p.generator.(StreamingGenerator); ok {
+			return streaming.GenerateStream(ctx, query, context, options, options.Stream.OnToken)
+		}
+	}
 	return p.generator.Generate(ctx, query, context, options)
 }
 
@@ -780,18 +1278,49 @@ func (p *Pipeline) setDefaultsForOptions(options *QueryOptions) {
 	if options.RetrievalOptions.SimilarityThreshold == 0 {
 		options.RetrievalOptions.SimilarityThreshold = p.config.Retrieval.MinScore
 	}
+	if options.RetrievalOptions.RecencyHalfLife == 0 {
+		options.RetrievalOptions.RecencyHalfLife = p.config.Retrieval.RecencyHalfLife
+	}
+	if options.RetrievalOptions.RecencyWeight == 0 {
+		options.RetrievalOptions.RecencyWeight = p.config.Retrieval.RecencyWeight
+	}
+	if options.RetrievalOptions.SourceTypeWeights == nil {
+		options.RetrievalOptions.SourceTypeWeights = p.config.Retrieval.SourceTypeWeights
+	}
 	if options.GenerateOptions.MaxTokens == 0 {
 		options.GenerateOptions.MaxTokens = p.config.Generation.MaxTokens
 	}
 	if options.GenerateOptions.Temperature == 0 {
 		options.GenerateOptions.Temperature = p.config.Generation.Temperature
 	}
+	if options.RetrievalOptions.MaxQueryTime == 0 {
+		options.RetrievalOptions.MaxQueryTime = p.config.Retrieval.MaxQueryTime
+	}
+	// Deterministic mode samples greedily with a fixed seed
+	if seed := p.samplingSeed(); seed != nil {
+		options.GenerateOptions.Temperature = 0
+		options.GenerateOptions.Seed = seed
+	}
 }
 
 // getCacheKey generates a cache key for the query
 func (p *Pipeline) getCacheKey(query string, options QueryOptions) string {
 	// Simple implementation - in production, use proper serialization
-	return fmt.Sprintf("query:%s:%d:%t", query, options.MaxResults, options.EnableRerank)
+	filter := ""
+	if expr := options.RetrievalOptions.FilterOptions.Expression; expr != nil {
+		filter = expr.String()
+	}
+	schema := ""
+	if options.GenerateOptions.OutputSchema != nil {
+		encoded, _ := json.Marshal(options.GenerateOptions.OutputSchema)
+		schema = string(encoded)
+	}
+	asOf := ""
+	if options.AsOf != nil {
+		asOf = options.AsOf.UTC().Format(time.RFC3339Nano)
+	}
+	language := p.answerLanguage(query, options.GenerateOptions)
+	return fmt.Sprintf("query:%s:%s:%s:%s:%s:%s:%s:%d:%t", options.ProjectID, federationCacheKey(options.Federation), query, filter, schema, asOf, language, options.MaxResults, options.EnableRerank)
 }
 
 // backgroundMaintenance performs background maintenance tasks
@@ -804,7 +1333,9 @@ func (p *Pipeline) backgroundMaintenance(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			p.performMaintenance(ctx)
+			if p.IsLeader() {
+				p.performMaintenance(ctx)
+			}
 		}
 	}
 }
@@ -816,6 +1347,13 @@ func (p *Pipeline) performMaintenance(ctx context.Context) {
 		// Cache cleanup logic
 	}
 
+	// Purge soft-deleted documents past their retention
+	if p.storage != nil {
+		if result := p.PurgeDeletedDocuments(ctx, false); len(result.Errors) > 0 {
+			p.emitError(ctx, "purge_documents", fmt.Errorf("%d documents failed to purge", len(result.Errors)))
+		}
+	}
+
 	// Sync data sources if needed
 	if p.config.Processing.Indexing.SyncInterval > 0 {
 		// Sync logic
@@ -868,9 +1406,114 @@ func (p *Pipeline) createStorage() (Storage, error) {
 	return nil, fmt.Errorf("storage creation not implemented")
 }
 
+// createLLMClient builds a router over the configured providers: the
+// generation and embedding settings first, then embedding fallback models
+// and any additional routing providers. With replay enabled the router is
+// wrapped to record or replay its calls.
 func (p *Pipeline) createLLMClient() (LLMClient, error) {
-	// Implementation would create LLM client based on config
-	return nil, fmt.Errorf("LLM client creation not implemented")
+	providers, err := p.routedProviders()
+	if err != nil {
+		return nil, err
+	}
+	router := NewRouter(p.config.Routing)
+	for _, provider := range providers {
+		if err := router.AddProvider(provider); err != nil {
+			return nil, err
+		}
+	}
+
+	if replay := p.config.Routing.Replay; replay.Mode != "" && replay.Mode != ReplayOff {
+		cassette, err := LoadCassette(replay.Cassette)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayClient(router, cassette, replay.Mode), nil
+	}
+	return router, nil
+}
+
+// routedProviders builds a client for every configured provider, in
+// routing order
+func (p *Pipeline) routedProviders() ([]RoutedProvider, error) {
+	generation := p.config.Generation
+	embedding := p.config.Processing.Embedding
+	rerankModel := p.config.Retrieval.RerankModel
+
+	// Provider clients retry through the router, not internally
+	newConfig := func(baseURL, apiKey string, timeout time.Duration) *llm.Config {
+		return &llm.Config{
+			BaseURL:       baseURL,
+			APIKey:        apiKey,
+			Model:         generation.Model,
+			RerankModel:   rerankModel,
+			Timeout:       timeout,
+			RetryAttempts: 1,
+		}
+	}
+
+	// The fake provider answers from content hashes, for tests and evaluations
+	newClient := func(provider string, config *llm.Config) LLMClient {
+		if provider == FakeProvider {
+			return NewFakeLLMClient(embedding.Dimension)
+		}
+		return NewAPIClient(config)
+	}
+
+	embeddingURL, embeddingKey := embedding.BaseURL, embedding.APIKey
+	if embeddingURL == "" {
+		embeddingURL, embeddingKey = generation.BaseURL, generation.APIKey
+	}
+
+	providers := []RoutedProvider{{
+		Name:   "primary",
+		Kind:   ProviderKindChat,
+		Client: newClient(generation.Provider, newConfig(generation.BaseURL, generation.APIKey, generation.Timeout)),
+	}}
+	embeddingModels := []string{embedding.EffectiveModel()}
+	if embedding.EnableFallback {
+		embeddingModels = append(embeddingModels, embedding.FallbackModels...)
+	}
+	for i, model := range embeddingModels {
+		config := newConfig(embeddingURL, embeddingKey, embedding.Timeout)
+		config.EmbeddingModel = model
+		name := "primary"
+		if i > 0 {
+			name = "fallback-" + model
+		}
+		providers = append(providers, RoutedProvider{Name: name, Kind: ProviderKindEmbedding, Client: newClient(embedding.Provider, config)})
+	}
+	if rerankModel != "" {
+		providers = append(providers, RoutedProvider{
+			Name:   "primary",
+			Kind:   ProviderKindRerank,
+			Client: newClient(generation.Provider, newConfig(generation.BaseURL, generation.APIKey, generation.Timeout)),
+		})
+	}
+
+	for _, provider := range p.config.Routing.Providers {
+		config := newConfig(provider.BaseURL, provider.APIKey, generation.Timeout)
+		model := ""
+		switch ProviderKind(provider.Kind) {
+		case ProviderKindChat:
+			config.Model = provider.Model
+			model = provider.Model
+		case ProviderKindEmbedding:
+			config.EmbeddingModel = provider.Model
+		case ProviderKindRerank:
+			config.RerankModel = provider.Model
+		default:
+			return nil, fmt.Errorf("provider %s: unknown kind %q", provider.Name, provider.Kind)
+		}
+		providers = append(providers, RoutedProvider{
+			Name:            provider.Name,
+			Kind:            ProviderKind(provider.Kind),
+			Client:          NewAPIClient(config),
+			Model:           model,
+			CostPer1KTokens: provider.CostPer1KTokens,
+		})
+	}
+
+	return providers, nil
 }
 
 func (p *Pipeline) createDocumentProcessor() (DocumentProcessor, error) {
@@ -889,7 +1532,15 @@ func (p *Pipeline) createGenerator() (Generator, error) {
 }
 
 func (p *Pipeline) createCache() (Cache, error) {
-	// Implementation would create cache based on config
+	switch p.config.Cache.Type {
+	case "redis":
+		cache, err := NewRedisCache(p.config.Cache)
+		if err != nil {
+			return nil, err
+		}
+		return cache, nil
+	}
+	// Implementation would create other caches based on config
 	return nil, fmt.Errorf("cache creation not implemented")
 }
 
@@ -898,9 +1549,34 @@ func (p *Pipeline) createMetricsCollector() (MetricsCollector, error) {
 	return nil, fmt.Errorf("metrics collector creation not implemented")
 }
 
+func (p *Pipeline) createModerators() ([]Moderator, error) {
+	config := p.config.Generation.Moderation
+	var moderators []Moderator
+	for _, provider := range config.Providers {
+		switch provider {
+		case "openai":
+			moderators = append(moderators, NewOpenAIModerator(&llm.Config{
+				BaseURL:       p.config.Generation.BaseURL,
+				APIKey:        p.config.Generation.APIKey,
+				Timeout:       p.config.Generation.Timeout,
+				RetryAttempts: p.config.Generation.MaxRetries,
+				RetryDelay:    p.config.Generation.RetryDelay,
+			}, config.Model, config.Threshold))
+		case "keyword":
+			moderator, err := NewKeywordModerator(config.Keywords)
+			if err != nil {
+				return nil, err
+			}
+			moderators = append(moderators, moderator)
+		default:
+			return nil, fmt.Errorf("unknown moderation provider: %s", provider)
+		}
+	}
+	return moderators, nil
+}
+
 func (p *Pipeline) createDefaultFilters() []Filter {
-	// Implementation would create default filters
-	return nil
+	return []Filter{NewChunkTypeFilter()}
 }
 
 func (p *Pipeline) createDefaultRankers() []Ranker {
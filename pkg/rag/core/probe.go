@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// defaultProbeTimeout bounds each provider probe
+const defaultProbeTimeout = 15 * time.Second
+
+// ProviderCheck is the result of probing one configured provider
+type ProviderCheck struct {
+	Name    string        `json:"name"`
+	Kind    ProviderKind  `json:"kind"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// OK reports whether the provider answered the probe
+func (c ProviderCheck) OK() bool {
+	return c.Error == ""
+}
+
+// CheckProviders sends a minimal request to every provider the config
+// routes to, bypassing retries, circuit breakers and replay, so installs can
+// verify their credentials and endpoints before serving traffic
+func CheckProviders(ctx context.Context, config *Config, timeout time.Duration) ([]ProviderCheck, error) {
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	p := &Pipeline{config: config}
+	providers, err := p.routedProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]ProviderCheck, 0, len(providers))
+	for _, provider := range providers {
+		check := ProviderCheck{Name: provider.Name, Kind: provider.Kind}
+		err := provider.Client.Validate()
+		if err == nil {
+			probeCtx, cancel := context.WithTimeout(ctx, timeout)
+			start := time.Now()
+			err = probe(probeCtx, provider)
+			check.Latency = time.Since(start)
+			cancel()
+		}
+		if err != nil {
+			check.Error = err.Error()
+		}
+		checks = append(checks, check)
+		provider.Client.Close()
+	}
+	return checks, nil
+}
+
+// probe sends the smallest request of the provider's kind
+func probe(ctx context.Context, provider RoutedProvider) error {
+	switch provider.Kind {
+	case ProviderKindEmbedding:
+		_, err := provider.Client.GenerateEmbedding(ctx, []string{"ping"})
+		return err
+	case ProviderKindRerank:
+		_, err := provider.Client.Rerank(ctx, "ping", []string{"pong"})
+		return err
+	}
+	_, err := provider.Client.GenerateCompletion(ctx, []llm.ChatMessage{{Role: "user", Content: "ping"}},
+		CompletionOptions{Model: provider.Model, MaxTokens: 1})
+	return err
+}
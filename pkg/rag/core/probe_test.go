@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckProviders(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	config := DefaultConfig()
+	config.Generation.Provider = FakeProvider
+	config.Processing.Embedding.Provider = FakeProvider
+	config.Processing.Embedding.EnableFallback = false
+	config.Routing.Providers = []ProviderConfig{
+		{Name: "down", Kind: string(ProviderKindEmbedding), BaseURL: down.URL, APIKey: "key", Model: "embed"},
+		{Name: "unconfigured", Kind: string(ProviderKindChat), Model: "chat"},
+	}
+
+	checks, err := CheckProviders(context.Background(), config, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := map[string]ProviderCheck{}
+	for _, check := range checks {
+		results[string(check.Kind)+"/"+check.Name] = check
+	}
+	if check := results["chat/primary"]; !check.OK() {
+		t.Fatalf("expected the fake chat provider to pass, got %q", check.Error)
+	}
+	if check := results["embedding/primary"]; !check.OK() {
+		t.Fatalf("expected the fake embedding provider to pass, got %q", check.Error)
+	}
+	if check := results["embedding/down"]; check.OK() {
+		t.Fatal("expected the failing provider to be reported")
+	}
+	if check := results["chat/unconfigured"]; check.OK() || check.Latency != 0 {
+		t.Fatalf("expected the unconfigured provider to fail validation without a request, got %+v", check)
+	}
+
+	config.Routing.Providers = []ProviderConfig{{Name: "bad", Kind: "speech"}}
+	if _, err := CheckProviders(context.Background(), config, time.Second); err == nil {
+		t.Fatal("expected an unknown provider kind to be rejected")
+	}
+}
@@ -0,0 +1,279 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProjectConfig holds per-project overrides applied on top of the global
+// RAG configuration. Nil fields inherit the global value.
+type ProjectConfig struct {
+	ProjectID string             `json:"project_id"`
+	Retrieval RetrievalOverrides `json:"retrieval"`
+	Chunking  ChunkingOverrides  `json:"chunking"`
+	Hooks     []HookBinding      `json:"hooks,omitempty"`     // Pipeline hooks, run in order within each stage
+	Retention []RetentionPolicy  `json:"retention,omitempty"` // Archival and deletion of old documents, in priority order
+	UpdatedAt time.Time          `json:"updated_at"`
+	UpdatedBy string             `json:"updated_by,omitempty"`
+}
+
+// RetrievalOverrides overrides retrieval settings for a project
+type RetrievalOverrides struct {
+	TopK          *int     `json:"top_k,omitempty"`
+	MinScore      *float64 `json:"min_score,omitempty"`
+	HybridWeight  *float64 `json:"hybrid_weight,omitempty"`  // Weight for vector search (0-1)
+	KeywordWeight *float64 `json:"keyword_weight,omitempty"` // Weight for keyword search (0-1)
+	FusionMethod  *string  `json:"fusion_method,omitempty"`
+
+	EnableVectorSearch  *bool `json:"enable_vector_search,omitempty"`
+	EnableKeywordSearch *bool `json:"enable_keyword_search,omitempty"`
+	EnableHybridSearch  *bool `json:"enable_hybrid_search,omitempty"`
+
+	EnableRerank    *bool    `json:"enable_rerank,omitempty"`
+	RerankModel     *string  `json:"rerank_model,omitempty"`
+	RerankTopK      *int     `json:"rerank_top_k,omitempty"`
+	RerankThreshold *float64 `json:"rerank_threshold,omitempty"`
+
+	RecencyHalfLife   *time.Duration     `json:"recency_half_life,omitempty"`
+	RecencyWeight     *float64           `json:"recency_weight,omitempty"`
+	SourceTypeWeights map[string]float64 `json:"source_type_weights,omitempty"`
+
+	// Expression rescoring candidates from their metadata, see CompileScoringExpression
+	ScoringExpression *string `json:"scoring_expression,omitempty"`
+}
+
+// ChunkingOverrides overrides chunking settings for a project
+type ChunkingOverrides struct {
+	Strategy     *string `json:"strategy,omitempty"`
+	MaxChunkSize *int    `json:"max_chunk_size,omitempty"`
+	MinChunkSize *int    `json:"min_chunk_size,omitempty"`
+	OverlapSize  *int    `json:"overlap_size,omitempty"`
+}
+
+// ProjectConfigStore persists per-project configuration documents
+type ProjectConfigStore interface {
+	// GetProjectConfig returns the overrides for a project, or nil if none are stored
+	GetProjectConfig(ctx context.Context, projectID string) (*ProjectConfig, error)
+
+	// SaveProjectConfig stores the overrides for a project
+	SaveProjectConfig(ctx context.Context, config *ProjectConfig) error
+
+	// DeleteProjectConfig removes the overrides for a project
+	DeleteProjectConfig(ctx context.Context, projectID string) error
+}
+
+// Validate checks the overrides against the global configuration limits
+func (pc *ProjectConfig) Validate(global *Config) error {
+	r := pc.Retrieval
+	if r.TopK != nil && (*r.TopK <= 0 || *r.TopK > global.Retrieval.MaxTopK) {
+		return fmt.Errorf("top_k must be between 1 and %d", global.Retrieval.MaxTopK)
+	}
+	if r.MinScore != nil && (*r.MinScore < 0 || *r.MinScore > 1) {
+		return fmt.Errorf("min_score must be between 0 and 1")
+	}
+	if r.HybridWeight != nil && (*r.HybridWeight < 0 || *r.HybridWeight > 1) {
+		return fmt.Errorf("hybrid_weight must be between 0 and 1")
+	}
+	if r.KeywordWeight != nil && (*r.KeywordWeight < 0 || *r.KeywordWeight > 1) {
+		return fmt.Errorf("keyword_weight must be between 0 and 1")
+	}
+	if r.RerankTopK != nil && *r.RerankTopK <= 0 {
+		return fmt.Errorf("rerank_top_k must be positive")
+	}
+	if r.RecencyHalfLife != nil || r.RecencyWeight != nil || r.SourceTypeWeights != nil {
+		var halfLife time.Duration
+		var weight float64
+		setDuration(&halfLife, r.RecencyHalfLife)
+		setFloat(&weight, r.RecencyWeight)
+		if err := validateBoosts(halfLife, weight, r.SourceTypeWeights); err != nil {
+			return err
+		}
+	}
+	if r.ScoringExpression != nil && *r.ScoringExpression != "" {
+		if _, err := CompileScoringExpression(*r.ScoringExpression); err != nil {
+			return fmt.Errorf("invalid scoring_expression: %w", err)
+		}
+	}
+
+	c := pc.Chunking
+	if c.MaxChunkSize != nil && *c.MaxChunkSize <= 0 {
+		return fmt.Errorf("max_chunk_size must be positive")
+	}
+	if c.MinChunkSize != nil && *c.MinChunkSize <= 0 {
+		return fmt.Errorf("min_chunk_size must be positive")
+	}
+
+	for i, binding := range pc.Hooks {
+		if err := binding.Validate(); err != nil {
+			return fmt.Errorf("hooks[%d]: %w", i, err)
+		}
+	}
+	for i := range pc.Retention {
+		if err := pc.Retention[i].Validate(); err != nil {
+			return fmt.Errorf("retention[%d]: %w", i, err)
+		}
+	}
+
+	// Check the merged result stays consistent
+	merged := pc.Apply(global)
+	if merged.Processing.Chunking.MinChunkSize > merged.Processing.Chunking.MaxChunkSize {
+		return fmt.Errorf("min_chunk_size cannot be greater than max_chunk_size")
+	}
+
+	return nil
+}
+
+// Apply returns a copy of the global configuration with the project overrides merged in
+func (pc *ProjectConfig) Apply(global *Config) *Config {
+	merged := *global
+	if pc == nil {
+		return &merged
+	}
+
+	r := pc.Retrieval
+	setInt(&merged.Retrieval.DefaultTopK, r.TopK)
+	setFloat(&merged.Retrieval.MinScore, r.MinScore)
+	setFloat(&merged.Retrieval.HybridWeight, r.HybridWeight)
+	setFloat(&merged.Retrieval.KeywordWeight, r.KeywordWeight)
+	setString(&merged.Retrieval.FusionMethod, r.FusionMethod)
+	setBool(&merged.Retrieval.EnableVectorSearch, r.EnableVectorSearch)
+	setBool(&merged.Retrieval.EnableKeywordSearch, r.EnableKeywordSearch)
+	setBool(&merged.Retrieval.EnableHybridSearch, r.EnableHybridSearch)
+	setBool(&merged.Retrieval.EnableRerank, r.EnableRerank)
+	setString(&merged.Retrieval.RerankModel, r.RerankModel)
+	setInt(&merged.Retrieval.RerankTopK, r.RerankTopK)
+	setFloat(&merged.Retrieval.RerankThreshold, r.RerankThreshold)
+	setDuration(&merged.Retrieval.RecencyHalfLife, r.RecencyHalfLife)
+	setFloat(&merged.Retrieval.RecencyWeight, r.RecencyWeight)
+	if r.SourceTypeWeights != nil {
+		merged.Retrieval.SourceTypeWeights = r.SourceTypeWeights
+	}
+
+	c := pc.Chunking
+	setString(&merged.Processing.Chunking.Strategy, c.Strategy)
+	setInt(&merged.Processing.Chunking.MaxChunkSize, c.MaxChunkSize)
+	setInt(&merged.Processing.Chunking.MinChunkSize, c.MinChunkSize)
+	setInt(&merged.Processing.Chunking.OverlapSize, c.OverlapSize)
+
+	return &merged
+}
+
+// ApplyToQuery fills query options the caller left unset from the project
+// overrides. Values set explicitly on the request always win.
+func (pc *ProjectConfig) ApplyToQuery(options *QueryOptions) {
+	if pc == nil {
+		return
+	}
+
+	r := pc.Retrieval
+	if options.MaxResults == 0 && r.TopK != nil {
+		options.MaxResults = *r.TopK
+	}
+	retrieval := &options.RetrievalOptions
+	if retrieval.TopK == 0 && r.TopK != nil {
+		retrieval.TopK = *r.TopK
+	}
+	if retrieval.SimilarityThreshold == 0 && r.MinScore != nil {
+		retrieval.SimilarityThreshold = *r.MinScore
+	}
+	if retrieval.VectorWeight == 0 && r.HybridWeight != nil {
+		retrieval.VectorWeight = *r.HybridWeight
+	}
+	if retrieval.KeywordWeight == 0 && r.KeywordWeight != nil {
+		retrieval.KeywordWeight = *r.KeywordWeight
+	}
+	if retrieval.RerankModel == "" && r.RerankModel != nil {
+		retrieval.RerankModel = *r.RerankModel
+	}
+	if retrieval.RerankTopK == 0 && r.RerankTopK != nil {
+		retrieval.RerankTopK = *r.RerankTopK
+	}
+	if retrieval.RecencyHalfLife == 0 && r.RecencyHalfLife != nil {
+		retrieval.RecencyHalfLife = *r.RecencyHalfLife
+	}
+	if retrieval.RecencyWeight == 0 && r.RecencyWeight != nil {
+		retrieval.RecencyWeight = *r.RecencyWeight
+	}
+	if retrieval.SourceTypeWeights == nil && r.SourceTypeWeights != nil {
+		retrieval.SourceTypeWeights = r.SourceTypeWeights
+	}
+	if retrieval.ScoringExpression == "" && r.ScoringExpression != nil {
+		retrieval.ScoringExpression = *r.ScoringExpression
+	}
+	if r.EnableRerank != nil && !options.EnableRerank && !retrieval.EnableRerank {
+		options.EnableRerank = *r.EnableRerank
+		retrieval.EnableRerank = *r.EnableRerank
+	}
+	if r.EnableVectorSearch != nil && !retrieval.EnableVectorSearch {
+		retrieval.EnableVectorSearch = *r.EnableVectorSearch
+	}
+	if r.EnableKeywordSearch != nil && !retrieval.EnableKeywordSearch {
+		retrieval.EnableKeywordSearch = *r.EnableKeywordSearch
+	}
+	if r.EnableHybridSearch != nil && !retrieval.EnableHybridSearch {
+		retrieval.EnableHybridSearch = *r.EnableHybridSearch
+	}
+}
+
+// SetProjectConfigStore sets the store used to load per-project overrides at query time
+func (p *Pipeline) SetProjectConfigStore(store ProjectConfigStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.projectConfigs = store
+}
+
+// EffectiveConfig returns the global configuration merged with a project's overrides
+func (p *Pipeline) EffectiveConfig(ctx context.Context, projectID string) (*Config, error) {
+	projectConfig, err := p.loadProjectConfig(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return projectConfig.Apply(p.config), nil
+}
+
+// loadProjectConfig loads overrides for a project; it returns nil when none apply
+func (p *Pipeline) loadProjectConfig(ctx context.Context, projectID string) (*ProjectConfig, error) {
+	p.mu.RLock()
+	store := p.projectConfigs
+	p.mu.RUnlock()
+
+	if store == nil || projectID == "" {
+		return nil, nil
+	}
+	projectConfig, err := store.GetProjectConfig(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project config: %w", err)
+	}
+	return projectConfig, nil
+}
+
+func setInt(dest *int, src *int) {
+	if src != nil {
+		*dest = *src
+	}
+}
+
+func setFloat(dest *float64, src *float64) {
+	if src != nil {
+		*dest = *src
+	}
+}
+
+func setDuration(dest *time.Duration, src *time.Duration) {
+	if src != nil {
+		*dest = *src
+	}
+}
+
+func setString(dest *string, src *string) {
+	if src != nil {
+		*dest = *src
+	}
+}
+
+func setBool(dest *bool, src *bool) {
+	if src != nil {
+		*dest = *src
+	}
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// projectConfigMap serves project overrides from memory
+type projectConfigMap map[string]*ProjectConfig
+
+func (m projectConfigMap) GetProjectConfig(ctx context.Context, projectID string) (*ProjectConfig, error) {
+	if projectID == "broken" {
+		return nil, errors.New("store unavailable")
+	}
+	return m[projectID], nil
+}
+
+func (m projectConfigMap) SaveProjectConfig(ctx context.Context, config *ProjectConfig) error {
+	m[config.ProjectID] = config
+	return nil
+}
+
+func (m projectConfigMap) DeleteProjectConfig(ctx context.Context, projectID string) error {
+	delete(m, projectID)
+	return nil
+}
+
+func TestProjectConfigValidate(t *testing.T) {
+	global := DefaultConfig()
+	global.Retrieval.MaxTopK = 50
+	intValue := func(v int) *int { return &v }
+	floatValue := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		config ProjectConfig
+		err    string
+	}{
+		{name: "empty", config: ProjectConfig{}},
+		{name: "valid", config: ProjectConfig{Retrieval: RetrievalOverrides{TopK: intValue(20), MinScore: floatValue(0.3)}}},
+		{name: "top_k above limit", config: ProjectConfig{Retrieval: RetrievalOverrides{TopK: intValue(51)}}, err: "top_k must be between 1 and 50"},
+		{name: "min_score out of range", config: ProjectConfig{Retrieval: RetrievalOverrides{MinScore: floatValue(1.5)}}, err: "min_score"},
+		{name: "negative weight", config: ProjectConfig{Retrieval: RetrievalOverrides{HybridWeight: floatValue(-0.1)}}, err: "hybrid_weight"},
+		{name: "zero rerank_top_k", config: ProjectConfig{Retrieval: RetrievalOverrides{RerankTopK: intValue(0)}}, err: "rerank_top_k"},
+		{name: "zero chunk size", config: ProjectConfig{Chunking: ChunkingOverrides{MaxChunkSize: intValue(0)}}, err: "max_chunk_size"},
+		{
+			name:   "min above merged max",
+			config: ProjectConfig{Chunking: ChunkingOverrides{MinChunkSize: intValue(global.Processing.Chunking.MaxChunkSize + 1)}},
+			err:    "cannot be greater than max_chunk_size",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate(global)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestProjectConfigApply(t *testing.T) {
+	global := DefaultConfig()
+	topK, strategy := 7, "sentence"
+	config := &ProjectConfig{
+		Retrieval: RetrievalOverrides{TopK: &topK},
+		Chunking:  ChunkingOverrides{Strategy: &strategy},
+	}
+
+	merged := config.Apply(global)
+	if merged.Retrieval.DefaultTopK != 7 || merged.Processing.Chunking.Strategy != "sentence" {
+		t.Fatalf("expected overrides to be applied, got top_k %d strategy %q", merged.Retrieval.DefaultTopK, merged.Processing.Chunking.Strategy)
+	}
+	if merged.Retrieval.MinScore != global.Retrieval.MinScore {
+		t.Fatalf("expected unset fields to inherit the global value")
+	}
+	if global.Retrieval.DefaultTopK == 7 {
+		t.Fatalf("expected the global configuration to be left untouched")
+	}
+
+	var none *ProjectConfig
+	if none.Apply(global).Retrieval.DefaultTopK != global.Retrieval.DefaultTopK {
+		t.Fatalf("expected a nil project config to return the global values")
+	}
+}
+
+func TestProjectConfigApplyToQuery(t *testing.T) {
+	topK, minScore, rerank := 8, 0.4, true
+	config := &ProjectConfig{Retrieval: RetrievalOverrides{TopK: &topK, MinScore: &minScore, EnableRerank: &rerank}}
+
+	options := QueryOptions{}
+	config.ApplyToQuery(&options)
+	if options.MaxResults != 8 || options.RetrievalOptions.TopK != 8 || options.RetrievalOptions.SimilarityThreshold != 0.4 {
+		t.Fatalf("expected project defaults to fill unset options, got %+v", options)
+	}
+	if !options.EnableRerank || !options.RetrievalOptions.EnableRerank {
+		t.Fatalf("expected rerank to be enabled from the project config")
+	}
+
+	explicit := QueryOptions{MaxResults: 3, RetrievalOptions: RetrieveOptions{TopK: 3, SimilarityThreshold: 0.9}}
+	config.ApplyToQuery(&explicit)
+	if explicit.MaxResults != 3 || explicit.RetrievalOptions.TopK != 3 || explicit.RetrievalOptions.SimilarityThreshold != 0.9 {
+		t.Fatalf("expected explicit request options to win, got %+v", explicit)
+	}
+}
+
+func TestPipelineEffectiveConfig(t *testing.T) {
+	ctx := context.Background()
+	p := &Pipeline{config: DefaultConfig()}
+	topK := 9
+
+	config, err := p.EffectiveConfig(ctx, "proj")
+	if err != nil || config.Retrieval.DefaultTopK != p.config.Retrieval.DefaultTopK {
+		t.Fatalf("expected the global config without a store, got %v", err)
+	}
+
+	store := projectConfigMap{"proj": {ProjectID: "proj", Retrieval: RetrievalOverrides{TopK: &topK}}}
+	p.SetProjectConfigStore(store)
+	if config, err = p.EffectiveConfig(ctx, "proj"); err != nil || config.Retrieval.DefaultTopK != 9 {
+		t.Fatalf("expected the project override, got %v", err)
+	}
+	if config, err = p.EffectiveConfig(ctx, "other"); err != nil || config.Retrieval.DefaultTopK != p.config.Retrieval.DefaultTopK {
+		t.Fatalf("expected the global config for a project without overrides, got %v", err)
+	}
+	if _, err := p.EffectiveConfig(ctx, "broken"); err == nil || !strings.Contains(err.Error(), "failed to load project config") {
+		t.Fatalf("expected store errors to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// DefaultMaxDocumentAttempts is the number of failed indexing attempts after
+// which a document is quarantined when MaxDocumentAttempts is not set
+const DefaultMaxDocumentAttempts = 3
+
+// ErrNoDocumentFailure is returned when requeueing a document without failures
+var ErrNoDocumentFailure = errors.New("document has no recorded failures")
+
+// DocumentFailure records the failed indexing attempts of a data source
+// document. Once Attempts reaches the limit the document is quarantined:
+// syncs skip it until its content changes or it is requeued.
+type DocumentFailure struct {
+	DocumentID   string `json:"document_id"`
+	ProjectID    string `json:"project_id,omitempty"`
+	DataSourceID string `json:"data_source_id"`
+	Title        string `json:"title,omitempty"`
+	URI          string `json:"uri,omitempty"`
+	ContentHash  string `json:"content_hash"` // Content of the last failed attempt
+
+	Error string `json:"error"`
+	Trace string `json:"trace,omitempty"` // Panic stack when processing crashed
+
+	Attempts      int        `json:"attempts"`
+	FirstFailedAt time.Time  `json:"first_failed_at"`
+	LastFailedAt  time.Time  `json:"last_failed_at"`
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+}
+
+// Quarantined reports whether syncs skip the document
+func (f *DocumentFailure) Quarantined() bool {
+	return f.QuarantinedAt != nil
+}
+
+// DocumentFailureStore persists document failures across syncs
+type DocumentFailureStore interface {
+	// GetDocumentFailure returns the failure of a document, or nil if none
+	GetDocumentFailure(ctx context.Context, documentID string) (*DocumentFailure, error)
+
+	// SaveDocumentFailure creates or replaces the failure of a document
+	SaveDocumentFailure(ctx context.Context, failure *DocumentFailure) error
+
+	// DeleteDocumentFailure removes the failure of a document
+	DeleteDocumentFailure(ctx context.Context, documentID string) error
+}
+
+// SetDocumentFailureStore enables per-document retry limits and quarantine
+// for syncs
+func (p *Pipeline) SetDocumentFailureStore(store DocumentFailureStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.documentFailures = store
+}
+
+func (p *Pipeline) documentFailureStore() DocumentFailureStore {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.documentFailures
+}
+
+func (p *Pipeline) maxDocumentAttempts() int {
+	if p.config.Processing.MaxDocumentAttempts > 0 {
+		return p.config.Processing.MaxDocumentAttempts
+	}
+	return DefaultMaxDocumentAttempts
+}
+
+// guardDocument indexes a data source document with index, which records its
+// own errors in result. A panic is recovered and recorded as a failure of the
+// document. Failures are counted per document; quarantined documents whose
+// content has not changed are skipped. It returns false when the document was
+// skipped or failed.
+func (p *Pipeline) guardDocument(ctx context.Context, doc Document, result *IndexResult, index func() error) bool {
+	store := p.documentFailureStore()
+	hash := ContentHash(doc.Content)
+
+	var failure *DocumentFailure
+	if store != nil {
+		var err error
+		failure, err = store.GetDocumentFailure(ctx, doc.ID)
+		if err != nil {
+			p.emitError(ctx, "get_document_failure", err)
+		}
+		if failure != nil && failure.Quarantined() && failure.ContentHash == hash {
+			result.DocumentsQuarantined++
+			return false
+		}
+	}
+
+	var trace string
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+				trace = string(debug.Stack())
+				result.DocumentsErrored++
+				result.Errors = append(result.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
+			}
+		}()
+		return index()
+	}()
+
+	if store == nil {
+		return err == nil
+	}
+	if err == nil {
+		if failure != nil {
+			if err := store.DeleteDocumentFailure(ctx, doc.ID); err != nil {
+				p.emitError(ctx, "delete_document_failure", err)
+			}
+		}
+		return true
+	}
+
+	now := p.now()
+	if failure == nil || failure.ContentHash != hash {
+		// Changed content gets a fresh set of attempts
+		failure = &DocumentFailure{DocumentID: doc.ID, FirstFailedAt: now}
+	}
+	failure.ProjectID = documentProjectID(doc)
+	failure.DataSourceID = doc.DataSourceID
+	failure.Title = doc.Title
+	failure.URI = doc.URI
+	failure.ContentHash = hash
+	failure.Error = err.Error()
+	failure.Trace = trace
+	failure.Attempts++
+	failure.LastFailedAt = now
+	if failure.Attempts >= p.maxDocumentAttempts() {
+		failure.QuarantinedAt = &now
+		p.emitEvent(ctx, "document_quarantined", map[string]interface{}{
+			"document_id":    doc.ID,
+			"project_id":     failure.ProjectID,
+			"data_source_id": failure.DataSourceID,
+			"attempts":       failure.Attempts,
+			"error":          failure.Error,
+		})
+	}
+	if err := store.SaveDocumentFailure(ctx, failure); err != nil {
+		p.emitError(ctx, "save_document_failure", err)
+	}
+	return false
+}
+
+// indexSourceDocuments indexes data source documents one at a time, so a
+// failing document does not stop the rest
+func (p *Pipeline) indexSourceDocuments(ctx context.Context, documents []Document, indexVersion string, result *IndexResult) {
+	for _, doc := range documents {
+		p.guardDocument(ctx, doc, result, func() error {
+			errored := result.DocumentsErrored
+			err := p.indexDocument(ctx, doc, indexVersion, result, nil)
+			if result.DocumentsErrored == errored {
+				// Documents without content are skipped, not failed
+				return nil
+			}
+			return err
+		})
+	}
+}
+
+// RequeueDocument releases a document from quarantine with a fresh set of
+// attempts and indexes it again from its data source. When the data source is
+// not attached the document is indexed by its next sync.
+func (p *Pipeline) RequeueDocument(ctx context.Context, documentID string) (*IndexResult, error) {
+	store := p.documentFailureStore()
+	if store == nil {
+		return nil, fmt.Errorf("document quarantine not configured")
+	}
+	failure, err := store.GetDocumentFailure(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if failure == nil {
+		return nil, ErrNoDocumentFailure
+	}
+	if err := store.DeleteDocumentFailure(ctx, documentID); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	source := p.dataSources[failure.DataSourceID]
+	p.mu.RUnlock()
+	if source == nil {
+		return nil, nil
+	}
+	doc, err := source.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	result := &IndexResult{DataSourceID: failure.DataSourceID, IndexType: "requeue", StartedAt: p.now()}
+	indexVersion := IndexVersionFromConfig(p.config.Processing.Embedding).String()
+	p.indexSourceDocuments(ctx, []Document{withProjectID(*doc, failure.ProjectID)}, indexVersion, result)
+	result.CompletedAt = p.now()
+	result.TotalTime = result.CompletedAt.Sub(result.StartedAt)
+	return result, nil
+}
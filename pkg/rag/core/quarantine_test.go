@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type memoryFailureStore map[string]DocumentFailure
+
+func (s memoryFailureStore) GetDocumentFailure(ctx context.Context, documentID string) (*DocumentFailure, error) {
+	failure, ok := s[documentID]
+	if !ok {
+		return nil, nil
+	}
+	return &failure, nil
+}
+
+func (s memoryFailureStore) SaveDocumentFailure(ctx context.Context, failure *DocumentFailure) error {
+	s[failure.DocumentID] = *failure
+	return nil
+}
+
+func (s memoryFailureStore) DeleteDocumentFailure(ctx context.Context, documentID string) error {
+	delete(s, documentID)
+	return nil
+}
+
+func TestDocumentQuarantine(t *testing.T) {
+	ctx := context.Background()
+	store := memoryFailureStore{}
+	p := &Pipeline{config: DefaultConfig()}
+	p.SetDocumentFailureStore(store)
+
+	doc := withProjectID(Document{ID: "d1", Title: "Broken", Content: "\xff\xfe garbage", DataSourceID: "s1"}, "p1")
+	calls := 0
+	crash := func() error {
+		calls++
+		panic("parser crashed")
+	}
+
+	// Every sync retries the document until it reaches the attempt limit
+	for attempt := 1; attempt <= DefaultMaxDocumentAttempts; attempt++ {
+		result := &IndexResult{}
+		if p.guardDocument(ctx, doc, result, crash) {
+			t.Fatal("expected a crashing document to fail")
+		}
+		if result.DocumentsErrored != 1 || len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "parser crashed") {
+			t.Fatalf("expected the panic to be recorded as a document error, got %+v", result)
+		}
+		failure := store["d1"]
+		if failure.Attempts != attempt || failure.ProjectID != "p1" || failure.DataSourceID != "s1" || !strings.Contains(failure.Trace, "quarantine_test.go") {
+			t.Fatalf("unexpected failure after attempt %d: %+v", attempt, failure)
+		}
+		if quarantined := failure.Quarantined(); quarantined != (attempt == DefaultMaxDocumentAttempts) {
+			t.Fatalf("attempt %d: quarantined = %v", attempt, quarantined)
+		}
+	}
+
+	// Quarantined documents are skipped without processing
+	result := &IndexResult{}
+	if p.guardDocument(ctx, doc, result, crash) || calls != DefaultMaxDocumentAttempts || result.DocumentsQuarantined != 1 || result.DocumentsErrored != 0 {
+		t.Fatalf("expected the quarantined document to be skipped, calls %d, result %+v", calls, result)
+	}
+
+	// Fixed content gets a fresh set of attempts and clears the failure on success
+	fixed := doc
+	fixed.Content = "Readable content"
+	if !p.guardDocument(ctx, fixed, &IndexResult{}, func() error { return nil }) {
+		t.Fatal("expected the fixed document to be indexed")
+	}
+	if _, ok := store["d1"]; ok {
+		t.Fatal("expected the failure to be cleared after indexing succeeded")
+	}
+
+	// Requeueing releases a quarantined document for the next sync
+	for i := 0; i < DefaultMaxDocumentAttempts; i++ {
+		p.guardDocument(ctx, doc, &IndexResult{}, func() error { return errors.New("bad encoding") })
+	}
+	if failure := store["d1"]; !failure.Quarantined() || failure.Error != "bad encoding" || failure.Trace != "" {
+		t.Fatalf("expected the document to be quarantined again, got %+v", failure)
+	}
+	if _, err := p.RequeueDocument(ctx, "d1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store["d1"]; ok {
+		t.Fatal("expected requeueing to release the document")
+	}
+	if _, err := p.RequeueDocument(ctx, "d1"); !errors.Is(err, ErrNoDocumentFailure) {
+		t.Fatalf("expected ErrNoDocumentFailure, got %v", err)
+	}
+}
@@ -0,0 +1,475 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/common/redis"
+)
+
+// Cache namespaces
+const (
+	cacheNamespaceQuery     = "query"
+	cacheNamespaceEmbedding = "embedding"
+	cacheNamespaceDocument  = "document"
+)
+
+const redisCachePrefix = "metabase:rag:"
+
+// Value encodings, stored as the first byte of every cached value
+const (
+	cacheEncodingRaw  byte = 0
+	cacheEncodingGzip byte = 1
+)
+
+// RedisCache caches query results, embeddings and documents in Redis. Each
+// namespace keeps a sorted set index used to apply the eviction policy once
+// MaxEntries is exceeded. When Redis is unreachable operations fail fast and
+// callers fall back to uncached behaviour.
+type RedisCache struct {
+	client *redis.Client
+	config CacheConfig
+
+	mu        sync.Mutex
+	hits      int64
+	misses    int64
+	evictions int64
+	hitTime   time.Duration
+	missTime  time.Duration
+}
+
+// NewRedisCache creates a Redis cache. The connection is established lazily,
+// so an unavailable server does not prevent the pipeline from starting.
+func NewRedisCache(config CacheConfig) (*RedisCache, error) {
+	if config.RedisURL == "" {
+		return nil, fmt.Errorf("redis_url is required for redis cache")
+	}
+	switch config.EvictionPolicy {
+	case "", "lru", "lfu", "fifo":
+	default:
+		return nil, fmt.Errorf("unsupported eviction policy: %s", config.EvictionPolicy)
+	}
+
+	client, err := redis.NewClient(config.RedisURL, config.RedisPassword, config.RedisDB, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisCache{client: client, config: config}, nil
+}
+
+// Get implements the Cache interface
+func (c *RedisCache) Get(ctx context.Context, key string) (*QueryResult, error) {
+	if !c.config.QueryCache {
+		return nil, nil
+	}
+	data, err := c.get(ctx, cacheNamespaceQuery, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var result QueryResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode cached query result: %w", err)
+	}
+	return &result, nil
+}
+
+// Set implements the Cache interface
+func (c *RedisCache) Set(ctx context.Context, key string, result *QueryResult, ttl time.Duration) error {
+	if !c.config.QueryCache || result == nil {
+		return nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode query result: %w", err)
+	}
+	return c.set(ctx, cacheNamespaceQuery, key, data, ttl)
+}
+
+// SetWithDocuments implements the DependentCache interface. Each document
+// keeps a set of the query entries built from it, which lives as long as the
+// longest of those entries.
+func (c *RedisCache) SetWithDocuments(ctx context.Context, key string, result *QueryResult, documentIDs []string, ttl time.Duration) error {
+	if !c.config.QueryCache || result == nil {
+		return nil
+	}
+	if err := c.Set(ctx, key, result, ttl); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = c.config.TTL
+	}
+
+	entryKey := c.entryKey(cacheNamespaceQuery, key)
+	for _, id := range documentIDs {
+		depsKey := c.dependencyKey(id)
+		if _, err := c.client.Do(ctx, "SADD", depsKey, entryKey); err != nil {
+			return fmt.Errorf("failed to record cache dependency: %w", err)
+		}
+		if ttl <= 0 {
+			continue
+		}
+		// Only extend, so earlier entries stay reachable until they expire
+		reply, err := c.client.Do(ctx, "PTTL", depsKey)
+		if remaining, ok := reply.(int64); err == nil && ok && remaining >= ttl.Milliseconds() {
+			continue
+		}
+		c.client.Do(ctx, "PEXPIRE", depsKey, ttl.Milliseconds())
+	}
+	return nil
+}
+
+// InvalidateDocuments implements the DependentCache interface
+func (c *RedisCache) InvalidateDocuments(ctx context.Context, documentIDs []string) (int, error) {
+	removed := 0
+	for _, id := range documentIDs {
+		depsKey := c.dependencyKey(id)
+		reply, err := c.client.Do(ctx, "SMEMBERS", depsKey)
+		if err != nil {
+			return removed, fmt.Errorf("failed to read cache dependencies: %w", err)
+		}
+		entries := redisStrings(reply)
+		args := []interface{}{"DEL", depsKey}
+		for _, entry := range entries {
+			args = append(args, entry)
+		}
+		reply, err = c.client.Do(ctx, args...)
+		if err != nil {
+			return removed, fmt.Errorf("failed to invalidate cached queries: %w", err)
+		}
+		if n, ok := reply.(int64); ok && n > 0 {
+			// The dependency set itself is one of the deleted keys
+			removed += int(n) - 1
+		}
+		if len(entries) > 0 {
+			zrem := []interface{}{"ZREM", c.indexKey(cacheNamespaceQuery)}
+			for _, entry := range entries {
+				zrem = append(zrem, entry)
+			}
+			c.client.Do(ctx, zrem...)
+		}
+	}
+	return removed, nil
+}
+
+// Delete implements the Cache interface
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	return c.delete(ctx, cacheNamespaceQuery, key)
+}
+
+// GetEmbedding implements the EmbeddingCache interface
+func (c *RedisCache) GetEmbedding(ctx context.Context, key string) ([]float64, error) {
+	if !c.config.EmbeddingCache {
+		return nil, nil
+	}
+	data, err := c.get(ctx, cacheNamespaceEmbedding, key)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	if len(data)%8 != 0 {
+		return nil, fmt.Errorf("invalid cached embedding length %d", len(data))
+	}
+
+	vector := make([]float64, len(data)/8)
+	for i := range vector {
+		vector[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:]))
+	}
+	return vector, nil
+}
+
+// SetEmbedding implements the EmbeddingCache interface
+func (c *RedisCache) SetEmbedding(ctx context.Context, key string, vector []float64, ttl time.Duration) error {
+	if !c.config.EmbeddingCache || len(vector) == 0 {
+		return nil
+	}
+	data := make([]byte, len(vector)*8)
+	for i, v := range vector {
+		binary.LittleEndian.PutUint64(data[i*8:], math.Float64bits(v))
+	}
+	return c.set(ctx, cacheNamespaceEmbedding, key, data, ttl)
+}
+
+// GetDocument implements the DocumentCache interface
+func (c *RedisCache) GetDocument(ctx context.Context, id string) (*Document, error) {
+	if !c.config.DocumentCache {
+		return nil, nil
+	}
+	data, err := c.get(ctx, cacheNamespaceDocument, id)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode cached document: %w", err)
+	}
+	return &doc, nil
+}
+
+// SetDocument implements the DocumentCache interface
+func (c *RedisCache) SetDocument(ctx context.Context, doc *Document, ttl time.Duration) error {
+	if !c.config.DocumentCache || doc == nil {
+		return nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+	return c.set(ctx, cacheNamespaceDocument, doc.ID, data, ttl)
+}
+
+// DeleteDocument implements the DocumentCache interface
+func (c *RedisCache) DeleteDocument(ctx context.Context, id string) error {
+	return c.delete(ctx, cacheNamespaceDocument, id)
+}
+
+// Clear implements the Cache interface. It removes every entry in all namespaces.
+func (c *RedisCache) Clear(ctx context.Context) error {
+	for _, namespace := range []string{cacheNamespaceQuery, cacheNamespaceEmbedding, cacheNamespaceDocument} {
+		index := c.indexKey(namespace)
+		reply, err := c.client.Do(ctx, "ZRANGE", index, 0, -1)
+		if err != nil {
+			return fmt.Errorf("failed to clear %s cache: %w", namespace, err)
+		}
+		args := []interface{}{"DEL", index}
+		for _, member := range redisStrings(reply) {
+			args = append(args, member)
+		}
+		if _, err := c.client.Do(ctx, args...); err != nil {
+			return fmt.Errorf("failed to clear %s cache: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// GetStats implements the Cache interface. Entry counts are read from Redis
+// when it is reachable; hit and miss rates cover this process only.
+func (c *RedisCache) GetStats() (*CacheStats, error) {
+	c.mu.Lock()
+	stats := &CacheStats{
+		Evictions: c.evictions,
+		AvgTTL:    c.config.TTL,
+		MinTTL:    c.config.TTL,
+		MaxTTL:    c.config.TTL,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+		stats.MissRate = float64(c.misses) / float64(total)
+	}
+	if c.hits > 0 {
+		stats.AvgHitTime = c.hitTime / time.Duration(c.hits)
+	}
+	if c.misses > 0 {
+		stats.AvgMissTime = c.missTime / time.Duration(c.misses)
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, namespace := range []string{cacheNamespaceQuery, cacheNamespaceEmbedding, cacheNamespaceDocument} {
+		reply, err := c.client.Do(ctx, "ZCARD", c.indexKey(namespace))
+		if err != nil {
+			break
+		}
+		if n, ok := reply.(int64); ok {
+			stats.TotalEntries += int(n)
+		}
+	}
+	return stats, nil
+}
+
+// Close implements the Cache interface
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+func (c *RedisCache) get(ctx context.Context, namespace, key string) ([]byte, error) {
+	start := time.Now()
+	entryKey := c.entryKey(namespace, key)
+	reply, err := c.client.Do(ctx, "GET", entryKey)
+	if err != nil {
+		c.recordLookup(false, time.Since(start))
+		return nil, err
+	}
+
+	value, ok := reply.(string)
+	if !ok {
+		// Expired or evicted by Redis itself; drop it from the index
+		c.client.Do(ctx, "ZREM", c.indexKey(namespace), entryKey)
+		c.recordLookup(false, time.Since(start))
+		return nil, nil
+	}
+
+	switch c.config.EvictionPolicy {
+	case "lfu":
+		c.client.Do(ctx, "ZINCRBY", c.indexKey(namespace), 1, entryKey)
+	case "fifo":
+	default:
+		c.client.Do(ctx, "ZADD", c.indexKey(namespace), time.Now().UnixNano(), entryKey)
+	}
+
+	data, err := decodeCacheValue([]byte(value))
+	c.recordLookup(err == nil, time.Since(start))
+	return data, err
+}
+
+func (c *RedisCache) set(ctx context.Context, namespace, key string, data []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.config.TTL
+	}
+	value, err := encodeCacheValue(data, c.config.EnableCompression)
+	if err != nil {
+		return err
+	}
+
+	entryKey := c.entryKey(namespace, key)
+	args := []interface{}{"SET", entryKey, value}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+	if _, err := c.client.Do(ctx, args...); err != nil {
+		return fmt.Errorf("failed to cache %s: %w", namespace, err)
+	}
+
+	index := c.indexKey(namespace)
+	switch c.config.EvictionPolicy {
+	case "lfu":
+		_, err = c.client.Do(ctx, "ZADD", index, "NX", 1, entryKey)
+	case "fifo":
+		_, err = c.client.Do(ctx, "ZADD", index, "NX", time.Now().UnixNano(), entryKey)
+	default:
+		_, err = c.client.Do(ctx, "ZADD", index, time.Now().UnixNano(), entryKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to index cached %s: %w", namespace, err)
+	}
+	return c.evict(ctx, namespace)
+}
+
+func (c *RedisCache) delete(ctx context.Context, namespace, key string) error {
+	entryKey := c.entryKey(namespace, key)
+	if _, err := c.client.Do(ctx, "DEL", entryKey); err != nil {
+		return fmt.Errorf("failed to delete cached %s: %w", namespace, err)
+	}
+	c.client.Do(ctx, "ZREM", c.indexKey(namespace), entryKey)
+	return nil
+}
+
+// evict removes the lowest ranked entries once the namespace exceeds MaxEntries
+func (c *RedisCache) evict(ctx context.Context, namespace string) error {
+	if c.config.MaxEntries <= 0 {
+		return nil
+	}
+	index := c.indexKey(namespace)
+	reply, err := c.client.Do(ctx, "ZCARD", index)
+	if err != nil {
+		return err
+	}
+	count, _ := reply.(int64)
+	overflow := count - int64(c.config.MaxEntries)
+	if overflow <= 0 {
+		return nil
+	}
+
+	reply, err = c.client.Do(ctx, "ZPOPMIN", index, overflow)
+	if err != nil {
+		return err
+	}
+	// ZPOPMIN replies with member, score pairs
+	popped := redisStrings(reply)
+	args := []interface{}{"DEL"}
+	for i := 0; i < len(popped); i += 2 {
+		args = append(args, popped[i])
+	}
+	if len(args) == 1 {
+		return nil
+	}
+	if _, err := c.client.Do(ctx, args...); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.evictions += int64(len(args) - 1)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *RedisCache) recordLookup(hit bool, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.hits++
+		c.hitTime += elapsed
+	} else {
+		c.misses++
+		c.missTime += elapsed
+	}
+}
+
+func (c *RedisCache) entryKey(namespace, key string) string {
+	return redisCachePrefix + namespace + ":" + key
+}
+
+func (c *RedisCache) indexKey(namespace string) string {
+	return redisCachePrefix + namespace + ":__index"
+}
+
+func (c *RedisCache) dependencyKey(documentID string) string {
+	return redisCachePrefix + cacheNamespaceQuery + ":__deps:" + documentID
+}
+
+// encodeCacheValue prefixes data with its encoding, compressing it when that saves space
+func encodeCacheValue(data []byte, compress bool) ([]byte, error) {
+	if compress {
+		var buf bytes.Buffer
+		buf.WriteByte(cacheEncodingGzip)
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		if buf.Len() < len(data)+1 {
+			return buf.Bytes(), nil
+		}
+	}
+	return append([]byte{cacheEncodingRaw}, data...), nil
+}
+
+func decodeCacheValue(value []byte) ([]byte, error) {
+	if len(value) == 0 {
+		return nil, fmt.Errorf("empty cache value")
+	}
+	switch value[0] {
+	case cacheEncodingRaw:
+		return value[1:], nil
+	case cacheEncodingGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(value[1:]))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("unknown cache value encoding %d", value[0])
+}
+
+func redisStrings(reply interface{}) []string {
+	items, _ := reply.([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
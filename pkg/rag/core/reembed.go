@@ -0,0 +1,502 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// ReembedStatus represents the lifecycle state of a re-embedding job
+type ReembedStatus string
+
+const (
+	ReembedStatusRunning   ReembedStatus = "running"
+	ReembedStatusCompleted ReembedStatus = "completed"
+	ReembedStatusFailed    ReembedStatus = "failed"
+	ReembedStatusCancelled ReembedStatus = "cancelled"
+)
+
+// IndexVersion identifies the embedding space a chunk vector belongs to.
+// Vectors produced under different index versions are not comparable.
+type IndexVersion struct {
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	Dimension int    `json:"dimension,omitempty"`
+}
+
+// String returns the canonical form stored on chunks, e.g. "openai/text-embedding-3-small@1536"
+func (v IndexVersion) String() string {
+	s := v.Model
+	if v.Provider != "" {
+		s = v.Provider + "/" + s
+	}
+	if v.Dimension > 0 {
+		s = fmt.Sprintf("%s@%d", s, v.Dimension)
+	}
+	return s
+}
+
+// IndexVersionFromConfig derives the index version from an embedding configuration
+func IndexVersionFromConfig(config EmbeddingConfig) IndexVersion {
+	return IndexVersion{
+		Provider:  config.Provider,
+		Model:     config.EffectiveModel(),
+		Dimension: config.Dimension,
+	}
+}
+
+// ReembedOptions defines options for migrating chunks to a new embedding model
+type ReembedOptions struct {
+	// Target embedding configuration (model, provider, dimension)
+	Target EmbeddingConfig `json:"target"`
+
+	// Generator produces vectors for the target model
+	Generator embedding.VectorGenerator `json:"-"`
+
+	// Retriever receives migrated chunks. It serves reads alongside the
+	// current retriever until the migration completes. Defaults to a
+	// VectorRetriever over the Generator and the pipeline storage.
+	Retriever Retriever `json:"-"`
+
+	// Scope and batching
+	DocumentIDs []string `json:"document_ids,omitempty"`
+	BatchSize   int      `json:"batch_size"`
+	DryRun      bool     `json:"dry_run"`
+}
+
+// ReembedJob tracks the progress of a background re-embedding migration
+type ReembedJob struct {
+	ID          string        `json:"id"`
+	FromVersion string        `json:"from_version"`
+	ToVersion   string        `json:"to_version"`
+	Status      ReembedStatus `json:"status"`
+	DryRun      bool          `json:"dry_run"`
+
+	// Progress counters
+	DocumentsTotal  int `json:"documents_total"`
+	DocumentsDone   int `json:"documents_done"`
+	ChunksMigrated  int `json:"chunks_migrated"`
+	ChunksSkipped   int `json:"chunks_skipped"`
+	ChunksFailed    int `json:"chunks_failed"`
+	EmbeddingsCalls int `json:"embeddings_calls"`
+
+	Errors      []string  `json:"errors,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+}
+
+// reembedState holds the runtime state of the active migration
+type reembedState struct {
+	job       *ReembedJob
+	options   ReembedOptions
+	cancel    context.CancelFunc
+	generator embedding.VectorGenerator
+	lock      Lock
+	promoted  bool // The target index replaced the current one
+}
+
+// StartReembed starts a background job that migrates all chunks to the target
+// embedding model. While the job runs, queries are served from both the
+// current index and the target index (dual-read), and documents ingested in
+// the meantime are written to both (dual-write); once it completes, the
+// target index replaces the current one.
+func (p *Pipeline) StartReembed(ctx context.Context, options ReembedOptions) (*ReembedJob, error) {
+	if options.Target.EffectiveModel() == "" {
+		return nil, fmt.Errorf("target embedding model is required")
+	}
+	if options.Generator == nil {
+		return nil, fmt.Errorf("embedding generator is required")
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = p.config.Processing.Embedding.BatchSize
+	}
+	if options.Target.Dimension == 0 {
+		options.Target.Dimension = options.Generator.GetDimension()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.reembed != nil && p.reembed.job.Status == ReembedStatusRunning {
+		return nil, fmt.Errorf("re-embedding job %s is already running", p.reembed.job.ID)
+	}
+
+	from := IndexVersionFromConfig(p.config.Processing.Embedding)
+	to := IndexVersionFromConfig(options.Target)
+	if from.String() == to.String() && !options.DryRun {
+		return nil, fmt.Errorf("index is already at version %s", to)
+	}
+
+	if options.Retriever == nil && !options.DryRun {
+		options.Retriever = NewVectorRetriever(options.Generator, p.storage)
+	}
+
+	// Re-embedding rewrites shared storage, so only one instance migrates at a time
+	lock, err := p.locker.TryLock(ctx, LockKeyReembed, p.LockTTL())
+	if errors.Is(err, ErrLockHeld) {
+		return nil, fmt.Errorf("re-embedding job is already running on another instance")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := &ReembedJob{
+		ID:          p.generateID(),
+		FromVersion: from.String(),
+		ToVersion:   to.String(),
+		Status:      ReembedStatusRunning,
+		DryRun:      options.DryRun,
+		StartedAt:   p.now(),
+	}
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	p.reembed = &reembedState{
+		job:       job,
+		options:   options,
+		cancel:    cancel,
+		generator: options.Generator,
+		lock:      lock,
+	}
+
+	go p.runReembed(jobCtx, p.reembed)
+
+	copied := *job
+	return &copied, nil
+}
+
+// GetReembedJob returns a snapshot of the current or last re-embedding job
+func (p *Pipeline) GetReembedJob() *ReembedJob {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.reembed == nil {
+		return nil
+	}
+	copied := *p.reembed.job
+	copied.Errors = append([]string(nil), p.reembed.job.Errors...)
+	return &copied
+}
+
+// CancelReembed stops the running re-embedding job. Chunks that were already
+// migrated keep their new vectors; the current index keeps serving reads.
+func (p *Pipeline) CancelReembed() error {
+	p.mu.RLock()
+	state := p.reembed
+	p.mu.RUnlock()
+
+	if state == nil || state.job.Status != ReembedStatusRunning {
+		return fmt.Errorf("no re-embedding job is running")
+	}
+	state.cancel()
+	return nil
+}
+
+// runReembed migrates chunks document by document
+func (p *Pipeline) runReembed(ctx context.Context, state *reembedState) {
+	defer state.cancel()
+	defer state.lock.Unlock(context.WithoutCancel(ctx))
+	go keepLock(ctx, state.lock, p.LockTTL(), state.cancel)
+
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{
+		Filter: FilterCriteria{DocumentIDs: state.options.DocumentIDs},
+	})
+	if err != nil {
+		p.finishReembed(state, ReembedStatusFailed, fmt.Errorf("failed to list documents: %w", err))
+		return
+	}
+
+	p.mu.Lock()
+	state.job.DocumentsTotal = len(documents)
+	p.mu.Unlock()
+
+	for _, doc := range documents {
+		select {
+		case <-ctx.Done():
+			p.finishReembed(state, ReembedStatusCancelled, nil)
+			return
+		default:
+		}
+
+		if err := p.reembedDocument(ctx, state, doc.ID); err != nil {
+			p.recordReembedError(state, fmt.Sprintf("Document %s: %v", doc.ID, err))
+		}
+
+		p.mu.Lock()
+		state.job.DocumentsDone++
+		p.mu.Unlock()
+	}
+
+	p.finishReembed(state, ReembedStatusCompleted, nil)
+}
+
+// reembedDocument re-embeds the chunks of one document that are not yet at the target version
+func (p *Pipeline) reembedDocument(ctx context.Context, state *reembedState, documentID string) error {
+	chunks, err := p.storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to list chunks: %w", err)
+	}
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].ChunkIndex < chunks[j].ChunkIndex })
+
+	toVersion := state.job.ToVersion
+	var pending []DocumentChunk
+	for _, chunk := range chunks {
+		if chunk.IndexVersion == toVersion || chunk.DuplicateOf != "" {
+			p.mu.Lock()
+			state.job.ChunksSkipped++
+			p.mu.Unlock()
+			continue
+		}
+		pending = append(pending, chunk)
+	}
+
+	if state.options.DryRun {
+		p.mu.Lock()
+		state.job.ChunksMigrated += len(pending)
+		p.mu.Unlock()
+		return nil
+	}
+
+	for i := 0; i < len(pending); i += state.options.BatchSize {
+		end := i + state.options.BatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		if err := p.reembedBatch(ctx, state, pending[i:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reembedBatch generates target vectors for a batch and writes them to storage and the target index
+func (p *Pipeline) reembedBatch(ctx context.Context, state *reembedState, batch []DocumentChunk) error {
+	texts := make([]string, len(batch))
+	for i, chunk := range batch {
+		texts[i] = chunk.Content
+	}
+
+	vectors, err := state.generator.Embed(ctx, texts)
+	p.mu.Lock()
+	state.job.EmbeddingsCalls++
+	p.mu.Unlock()
+	if err != nil {
+		p.mu.Lock()
+		state.job.ChunksFailed += len(batch)
+		p.mu.Unlock()
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	if len(vectors) != len(batch) {
+		return fmt.Errorf("embedding count mismatch: got %d, want %d", len(vectors), len(batch))
+	}
+
+	now := p.now()
+	for i, chunk := range batch {
+		chunk.Embedding = vectors[i]
+		chunk.EmbeddingModel = state.options.Target.EffectiveModel()
+		chunk.EmbeddingDim = len(vectors[i])
+		chunk.IndexVersion = state.job.ToVersion
+		chunk.UpdatedAt = now
+
+		if err := p.storeReembeddedChunk(ctx, state, chunk); err != nil {
+			p.mu.Lock()
+			state.job.ChunksFailed++
+			p.mu.Unlock()
+			p.recordReembedError(state, fmt.Sprintf("Chunk %s: %v", chunk.ID, err))
+			continue
+		}
+
+		p.mu.Lock()
+		state.job.ChunksMigrated++
+		p.mu.Unlock()
+	}
+
+	return nil
+}
+
+// storeReembeddedChunk persists a migrated chunk
+func (p *Pipeline) storeReembeddedChunk(ctx context.Context, state *reembedState, chunk DocumentChunk) error {
+	if err := p.storage.StoreChunk(ctx, chunk); err != nil {
+		return fmt.Errorf("store chunk: %w", err)
+	}
+	if err := p.storage.StoreEmbedding(ctx, chunk.ID, chunk.Embedding); err != nil {
+		return fmt.Errorf("store embedding: %w", err)
+	}
+	if err := state.options.Retriever.AddDocument(ctx, chunk); err != nil {
+		return fmt.Errorf("add to target index: %w", err)
+	}
+	return nil
+}
+
+// recordReembedError appends an error to the job
+func (p *Pipeline) recordReembedError(state *reembedState, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state.job.Errors = append(state.job.Errors, message)
+}
+
+// finishReembed finalizes the job and, on success, switches reads to the target index
+func (p *Pipeline) finishReembed(state *reembedState, status ReembedStatus, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state.job.Status = status
+	state.job.CompletedAt = p.now()
+	if err != nil {
+		state.job.Errors = append(state.job.Errors, err.Error())
+	}
+
+	if status != ReembedStatusCompleted || state.options.DryRun || state.job.ChunksFailed > 0 {
+		return
+	}
+
+	// Promote the target index and record the new model as current
+	p.retriever = state.options.Retriever
+	state.promoted = true
+	p.config.Processing.Embedding.Model = state.options.Target.EffectiveModel()
+	p.config.Processing.Embedding.MultilingualModel = ""
+	if state.options.Target.Provider != "" {
+		p.config.Processing.Embedding.Provider = state.options.Target.Provider
+	}
+	p.config.Processing.Embedding.Dimension = state.options.Target.Dimension
+}
+
+// dualReadRetriever returns the target index while a migration is running
+func (p *Pipeline) dualReadRetriever() Retriever {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.reembed == nil || p.reembed.job.Status != ReembedStatusRunning || p.reembed.options.DryRun {
+		return nil
+	}
+	return p.reembed.options.Retriever
+}
+
+// migrationTarget returns the migration whose target index must also receive
+// chunks embedded at another version: a running one, or a promoted one for
+// ingests that embedded their chunks before it was promoted
+func (p *Pipeline) migrationTarget() *reembedState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	state := p.reembed
+	if state == nil || state.options.DryRun {
+		return nil
+	}
+	if state.job.Status == ReembedStatusRunning || state.promoted {
+		return state
+	}
+	return nil
+}
+
+// dualWriteChunks re-embeds chunks freshly indexed at indexVersion for the
+// target index of a migration, so documents ingested while it runs are not
+// lost when it is promoted. Failures count against the job, which then keeps
+// the current index.
+func (p *Pipeline) dualWriteChunks(ctx context.Context, chunks []DocumentChunk, indexVersion string) {
+	state := p.migrationTarget()
+	if state == nil {
+		return
+	}
+
+	var pending []DocumentChunk
+	for _, chunk := range chunks {
+		if chunk.IndexVersion == "" {
+			chunk.IndexVersion = indexVersion
+		}
+		if len(chunk.Embedding) == 0 || chunk.DuplicateOf != "" || chunk.IndexVersion == state.job.ToVersion {
+			continue
+		}
+		if state.promoted {
+			// The ingest added the old vector to the index that now serves reads
+			if err := state.options.Retriever.RemoveDocument(ctx, chunk.ID); err != nil {
+				p.emitError(ctx, "dual_write", err)
+			}
+		}
+		pending = append(pending, chunk)
+	}
+
+	for i := 0; i < len(pending); i += state.options.BatchSize {
+		end := i + state.options.BatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		if err := p.reembedBatch(ctx, state, pending[i:end]); err != nil {
+			p.recordReembedError(state, fmt.Sprintf("Dual write: %v", err))
+			p.emitError(ctx, "dual_write", err)
+		}
+	}
+}
+
+// rrfK dampens the weight of the top ranks in reciprocal rank fusion
+const rrfK = 60
+
+// fuseRetrievalResults merges results from the current and target index of
+// a migration by reciprocal rank fusion, since scores of different embedding
+// models are not comparable but ranks are. Each result scores 1/(rrfK+rank)
+// per index it was found in; chunks found in both keep the result from
+// target (the newer index).
+func fuseRetrievalResults(current, target []RetrievalResult, topK int) []RetrievalResult {
+	merged := make([]RetrievalResult, 0, len(current)+len(target))
+	positions := make(map[string]int, len(target))
+
+	for _, list := range [][]RetrievalResult{target, current} {
+		ranked := append([]RetrievalResult(nil), list...)
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+		for rank, result := range ranked {
+			score := 1 / float64(rrfK+rank+1)
+			if result.Chunk != nil {
+				if i, ok := positions[result.Chunk.ID]; ok {
+					merged[i].Score += score
+					continue
+				}
+				positions[result.Chunk.ID] = len(merged)
+			}
+			result.Score = score
+			merged = append(merged, result)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	if topK > 0 && len(merged) > topK {
+		merged = merged[:topK]
+	}
+	for i := range merged {
+		merged[i].Position = i
+	}
+	return merged
+}
+
+// mergeRetrievalResults merges results from two indexes whose scores are
+// comparable, such as the weighted image namespace, ordered by score.
+// Chunks found in both keep the result from target.
+func mergeRetrievalResults(current, target []RetrievalResult, topK int) []RetrievalResult {
+	merged := make([]RetrievalResult, 0, len(current)+len(target))
+	seen := make(map[string]bool, len(target))
+
+	for _, result := range target {
+		if result.Chunk != nil {
+			seen[result.Chunk.ID] = true
+		}
+		merged = append(merged, result)
+	}
+	for _, result := range current {
+		if result.Chunk != nil && seen[result.Chunk.ID] {
+			continue
+		}
+		merged = append(merged, result)
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	if topK > 0 && len(merged) > topK {
+		merged = merged[:topK]
+	}
+	for i := range merged {
+		merged[i].Position = i
+	}
+	return merged
+}
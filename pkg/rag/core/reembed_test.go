@@ -0,0 +1,188 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// dualWriteStorage keeps the chunks written by a migration
+type dualWriteStorage struct {
+	Storage
+	chunks map[string]DocumentChunk
+}
+
+func (s *dualWriteStorage) StoreChunk(ctx context.Context, chunk DocumentChunk) error {
+	s.chunks[chunk.ID] = chunk
+	return nil
+}
+
+func (s *dualWriteStorage) StoreEmbedding(ctx context.Context, chunkID string, vector []float64) error {
+	return nil
+}
+
+// dualWriteIndex records the chunks added to an index, in order
+type dualWriteIndex struct {
+	Retriever
+	chunks []DocumentChunk
+}
+
+func (r *dualWriteIndex) AddDocument(ctx context.Context, chunk DocumentChunk) error {
+	r.chunks = append(r.chunks, chunk)
+	return nil
+}
+
+func (r *dualWriteIndex) RemoveDocument(ctx context.Context, chunkID string) error {
+	for i, chunk := range r.chunks {
+		if chunk.ID == chunkID {
+			r.chunks = append(r.chunks[:i], r.chunks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// termCountGenerator embeds texts as the number of times a term occurs
+type termCountGenerator struct {
+	term string
+}
+
+func (g *termCountGenerator) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = g.EmbedSingle(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (g *termCountGenerator) EmbedSingle(ctx context.Context, text string) ([]float64, error) {
+	return []float64{float64(strings.Count(text, g.term))}, nil
+}
+
+func (g *termCountGenerator) GetDimension() int    { return 1 }
+func (g *termCountGenerator) GetModelName() string { return "terms" }
+func (g *termCountGenerator) GetCapabilities() embedding.ModelCapabilities {
+	return embedding.ModelCapabilities{}
+}
+func (g *termCountGenerator) Close() error { return nil }
+
+func TestFuseRetrievalResults(t *testing.T) {
+	result := func(id string, score float64) RetrievalResult {
+		return RetrievalResult{DocumentID: id, Chunk: &DocumentChunk{ID: id}, Score: score}
+	}
+	// The current model scores around 0.8, the target model around 0.2
+	current := []RetrievalResult{result("a", 0.91), result("b", 0.88), result("c", 0.85)}
+	target := []RetrievalResult{result("d", 0.31), result("c", 0.22), result("e", 0.12)}
+
+	fused := fuseRetrievalResults(current, target, 4)
+	var ids []string
+	for _, r := range fused {
+		ids = append(ids, r.Chunk.ID)
+	}
+	// c is found by both indexes; the top results of each index come next,
+	// instead of all current results ranking above all target results
+	if len(ids) != 4 || ids[0] != "c" || ids[1] != "d" || ids[2] != "a" || ids[3] != "b" {
+		t.Fatalf("unexpected fused order %v", ids)
+	}
+	if fused[0].Score != 1.0/(rrfK+2)+1.0/(rrfK+3) || fused[3].Position != 3 {
+		t.Fatalf("unexpected fused scores %+v", fused)
+	}
+}
+
+func TestReembedDualWritesIngestedChunks(t *testing.T) {
+	ctx := context.Background()
+	backend := &dualWriteStorage{chunks: make(map[string]DocumentChunk)}
+	current, target := &dualWriteIndex{}, &dualWriteIndex{}
+	p := &Pipeline{config: DefaultConfig(), storage: backend, retriever: current}
+	state := &reembedState{
+		job:       &ReembedJob{Status: ReembedStatusRunning, FromVersion: "old", ToVersion: "terms@1"},
+		options:   ReembedOptions{Retriever: target, BatchSize: 8},
+		generator: &termCountGenerator{term: "refund"},
+	}
+	p.reembed = state
+
+	// A document ingested while the migration runs reaches the target index
+	ingested := []DocumentChunk{
+		{ID: "a_0", DocumentID: "a", Content: "refund policy", Embedding: []float64{1, 0}},
+		{ID: "a_1", DocumentID: "a", Content: "already migrated", Embedding: []float64{1}, IndexVersion: "terms@1"},
+		{ID: "a_2", DocumentID: "a", Content: "duplicate", Embedding: []float64{1, 0}, DuplicateOf: "b_0"},
+	}
+	p.dualWriteChunks(ctx, ingested, "old")
+	if len(target.chunks) != 1 || target.chunks[0].ID != "a_0" || target.chunks[0].IndexVersion != "terms@1" || len(target.chunks[0].Embedding) != 1 {
+		t.Fatalf("expected only the chunk at the old version to be dual-written, got %+v", target.chunks)
+	}
+	if stored := backend.chunks["a_0"]; stored.IndexVersion != "terms@1" {
+		t.Fatalf("expected the stored chunk to move to the target version, got %+v", stored)
+	}
+	if job := p.GetReembedJob(); job.ChunksMigrated != 1 || job.ChunksFailed != 0 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	// An ingest that embedded its chunks before the promotion is re-embedded
+	// for the index that now serves reads
+	p.finishReembed(state, ReembedStatusCompleted, nil)
+	if p.retriever != target {
+		t.Fatal("expected the target index to be promoted")
+	}
+	target.chunks = append(target.chunks, DocumentChunk{ID: "b_0", DocumentID: "b", Embedding: []float64{1, 0}})
+	p.dualWriteChunks(ctx, []DocumentChunk{{ID: "b_0", DocumentID: "b", Content: "refund refund", Embedding: []float64{1, 0}}}, "old")
+	if len(target.chunks) != 2 || target.chunks[1].ID != "b_0" || len(target.chunks[1].Embedding) != 1 || target.chunks[1].Embedding[0] != 2 {
+		t.Fatalf("expected the late chunk to replace its old vector in the promoted index, got %+v", target.chunks)
+	}
+
+	// Ingests at the new version are left alone
+	p.dualWriteChunks(ctx, []DocumentChunk{{ID: "c_0", DocumentID: "c", Content: "refund", Embedding: []float64{1}}}, "terms@1")
+	if len(target.chunks) != 2 {
+		t.Fatalf("expected chunks at the target version not to be re-embedded, got %+v", target.chunks)
+	}
+}
+
+func TestReembedBuildsTargetIndex(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryStorage()
+	p := &Pipeline{config: DefaultConfig(), storage: backend, retriever: &keywordRetriever{}, locker: NewLocalLocker()}
+	for _, chunk := range []DocumentChunk{
+		{ID: "a_0", DocumentID: "a", Content: "refund within 30 days", Embedding: []float64{1, 0, 0}},
+		{ID: "b_0", DocumentID: "b", Content: "shipping takes a week", Embedding: []float64{0, 1, 0}},
+	} {
+		backend.StoreDocument(ctx, Document{ID: chunk.DocumentID})
+		backend.StoreChunk(ctx, chunk)
+	}
+
+	// Without a retriever the migration indexes into a vector retriever
+	if _, err := p.StartReembed(ctx, ReembedOptions{
+		Target:    EmbeddingConfig{Model: "words"},
+		Generator: &wordGenerator{vocabulary: []string{"refund", "shipping"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); p.GetReembedJob().Status == ReembedStatusRunning; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("re-embedding did not finish")
+		}
+	}
+	if job := p.GetReembedJob(); job.Status != ReembedStatusCompleted || job.ChunksMigrated != 2 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	target, ok := p.retriever.(*VectorRetriever)
+	if !ok {
+		t.Fatalf("expected the vector retriever to be promoted, got %T", p.retriever)
+	}
+	if stats, _ := target.GetStats(); stats.IndexedChunks != 2 || stats.EmbeddingDim != 2 {
+		t.Fatalf("unexpected target index stats %+v", stats)
+	}
+	results, err := target.Retrieve(ctx, "how do refunds work", RetrieveOptions{TopK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Chunk.ID != "a_0" || results[0].Chunk.Content != "refund within 30 days" || results[0].Score != 1 {
+		t.Fatalf("expected the refund chunk from storage, got %+v", results)
+	}
+	if err := target.AddDocument(ctx, DocumentChunk{ID: "c_0", Embedding: []float64{1, 0, 0}}); err == nil {
+		t.Fatal("expected vectors of the old model to be rejected")
+	}
+}
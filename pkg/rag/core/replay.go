@@ -0,0 +1,263 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// Replay modes
+const (
+	ReplayOff    = "off"    // Call providers directly
+	ReplayRecord = "record" // Call providers and record every interaction
+	ReplayReplay = "replay" // Answer only from the cassette, never call providers
+	ReplayAuto   = "auto"   // Answer from the cassette, recording what is missing
+)
+
+// ErrCassetteMiss is returned in replay mode for a request the cassette has
+// not recorded
+var ErrCassetteMiss = errors.New("request not recorded in cassette")
+
+// ReplayConfig records provider interactions to a cassette file and replays
+// them, so integration tests and evaluation runs do not reach paid APIs
+type ReplayConfig struct {
+	Mode     string `json:"mode,omitempty"`     // off, record, replay or auto
+	Cassette string `json:"cassette,omitempty"` // Path of the cassette file
+}
+
+// Validate checks the mode and that a cassette is given when enabled
+func (c ReplayConfig) Validate() error {
+	switch c.Mode {
+	case "", ReplayOff:
+		return nil
+	case ReplayRecord, ReplayReplay, ReplayAuto:
+		if c.Cassette == "" {
+			return fmt.Errorf("replay mode %s requires a cassette", c.Mode)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown replay mode %q", c.Mode)
+}
+
+// Interaction is one recorded provider call
+type Interaction struct {
+	Key      string          `json:"key"` // Hash of kind and request
+	Kind     ProviderKind    `json:"kind"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Cassette stores recorded interactions by request hash. A cassette with a
+// path is saved after every recorded interaction, so a run that fails part
+// way keeps what it recorded.
+type Cassette struct {
+	mu           sync.Mutex
+	path         string
+	interactions map[string]Interaction
+}
+
+// cassetteFile is the on-disk cassette format
+type cassetteFile struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// NewCassette creates an empty in-memory cassette
+func NewCassette() *Cassette {
+	return &Cassette{interactions: make(map[string]Interaction)}
+}
+
+// LoadCassette opens the cassette at path; a missing file is an empty
+// cassette that will be created when the first interaction is recorded
+func LoadCassette(path string) (*Cassette, error) {
+	cassette := NewCassette()
+	cassette.path = path
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cassette, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	for _, interaction := range file.Interactions {
+		cassette.interactions[interaction.Key] = interaction
+	}
+	return cassette, nil
+}
+
+// cassetteNameUnsafe matches characters kept out of cassette file names
+var cassetteNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// CassettePath returns the cassette path for a test in dir, e.g.
+// CassettePath("testdata/cassettes", t.Name())
+func CassettePath(dir, name string) string {
+	return filepath.Join(dir, cassetteNameUnsafe.ReplaceAllString(name, "_")+".json")
+}
+
+// Len returns the number of recorded interactions
+func (c *Cassette) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.interactions)
+}
+
+func (c *Cassette) get(key string) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interaction, ok := c.interactions[key]
+	return interaction, ok
+}
+
+func (c *Cassette) record(interaction Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions[interaction.Key] = interaction
+	if c.path == "" {
+		return nil
+	}
+	return c.save()
+}
+
+// save writes the cassette sorted by key, so re-recording the same calls
+// leaves the file unchanged
+func (c *Cassette) save() error {
+	file := cassetteFile{Version: 1, Interactions: make([]Interaction, 0, len(c.interactions))}
+	for _, interaction := range c.interactions {
+		file.Interactions = append(file.Interactions, interaction)
+	}
+	sort.Slice(file.Interactions, func(i, j int) bool { return file.Interactions[i].Key < file.Interactions[j].Key })
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to save cassette: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save cassette: %w", err)
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// ReplayClient records and replays the calls of an LLM client. Requests are
+// keyed by a hash of their content, so a test replays correctly as long as
+// it sends the same requests, in any order.
+type ReplayClient struct {
+	client   LLMClient
+	cassette *Cassette
+	mode     string
+}
+
+// NewReplayClient wraps client; client may be nil in replay mode
+func NewReplayClient(client LLMClient, cassette *Cassette, mode string) *ReplayClient {
+	return &ReplayClient{client: client, cassette: cassette, mode: mode}
+}
+
+// completionRequest is the recorded form of a completion request. The
+// timeout does not change the answer, so it is left out of the key.
+type completionRequest struct {
+	Messages []llm.ChatMessage `json:"messages"`
+	Options  CompletionOptions `json:"options"`
+}
+
+// GenerateCompletion implements the LLMClient interface
+func (c *ReplayClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	keyed := options
+	keyed.Timeout = 0
+	var response *CompletionResponse
+	err := c.do(ProviderKindChat, completionRequest{Messages: messages, Options: keyed}, &response, func() (interface{}, error) {
+		return c.client.GenerateCompletion(ctx, messages, options)
+	})
+	return response, err
+}
+
+// GenerateEmbedding implements the LLMClient interface
+func (c *ReplayClient) GenerateEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
+	var embeddings [][]float64
+	err := c.do(ProviderKindEmbedding, map[string]interface{}{"texts": texts}, &embeddings, func() (interface{}, error) {
+		return c.client.GenerateEmbedding(ctx, texts)
+	})
+	return embeddings, err
+}
+
+// Rerank implements the LLMClient interface
+func (c *ReplayClient) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	var scores []float64
+	err := c.do(ProviderKindRerank, map[string]interface{}{"query": query, "documents": documents}, &scores, func() (interface{}, error) {
+		return c.client.Rerank(ctx, query, documents)
+	})
+	return scores, err
+}
+
+// do answers request from the cassette into out, or calls the client and
+// records its response. Failed calls are not recorded.
+func (c *ReplayClient) do(kind ProviderKind, request interface{}, out interface{}, call func() (interface{}, error)) error {
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(string(kind)+"\x00"), encoded...))
+	key := hex.EncodeToString(sum[:])
+
+	if c.mode != ReplayRecord {
+		if interaction, ok := c.cassette.get(key); ok {
+			return json.Unmarshal(interaction.Response, out)
+		}
+		if c.mode == ReplayReplay || c.client == nil {
+			return fmt.Errorf("%w: %s request %s", ErrCassetteMiss, kind, key[:12])
+		}
+	}
+
+	response, err := call()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+	if err := c.cassette.record(Interaction{Key: key, Kind: kind, Request: encoded, Response: data}); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// GetModelInfo implements the LLMClient interface
+func (c *ReplayClient) GetModelInfo() (*ModelInfo, error) {
+	if c.client == nil {
+		return &ModelInfo{Name: "replay", Type: "chat", Provider: "replay"}, nil
+	}
+	return c.client.GetModelInfo()
+}
+
+// Validate implements the LLMClient interface. Replaying needs no provider
+// configuration.
+func (c *ReplayClient) Validate() error {
+	if c.mode == ReplayReplay || c.client == nil {
+		return nil
+	}
+	return c.client.Validate()
+}
+
+// Close implements the LLMClient interface
+func (c *ReplayClient) Close() error {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Close()
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// countingClient counts the calls reaching a fake provider
+type countingClient struct {
+	*FakeLLMClient
+	calls int
+}
+
+func (c *countingClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	c.calls++
+	return c.FakeLLMClient.GenerateCompletion(ctx, messages, options)
+}
+
+func (c *countingClient) GenerateEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
+	c.calls++
+	return c.FakeLLMClient.GenerateEmbedding(ctx, texts)
+}
+
+func TestReplayClient(t *testing.T) {
+	ctx := context.Background()
+	path := CassettePath(t.TempDir(), t.Name()+"/sub test")
+	if filepath.Base(path) != "TestReplayClient_sub_test.json" {
+		t.Fatalf("unexpected cassette path %s", path)
+	}
+	messages := []llm.ChatMessage{{Role: "user", Content: "what is a cassette"}}
+
+	// Recording calls the provider and saves each interaction
+	provider := &countingClient{FakeLLMClient: NewFakeLLMClient(8)}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewReplayClient(provider, cassette, ReplayRecord)
+	recorded, err := recorder.GenerateCompletion(ctx, messages, CompletionOptions{Timeout: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := recorder.GenerateEmbedding(ctx, []string{"tape"})
+	if err != nil || provider.calls != 2 {
+		t.Fatalf("expected two provider calls, got %d %v", provider.calls, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the cassette to be saved: %v", err)
+	}
+
+	// Replaying answers from the file without a provider, ignoring timeouts
+	cassette, err = LoadCassette(path)
+	if err != nil || cassette.Len() != 2 {
+		t.Fatalf("expected two recorded interactions, got %d %v", cassette.Len(), err)
+	}
+	player := NewReplayClient(nil, cassette, ReplayReplay)
+	replayed, err := player.GenerateCompletion(ctx, messages, CompletionOptions{Timeout: 2})
+	if err != nil || replayed.Choices[0].Message.Content != recorded.Choices[0].Message.Content {
+		t.Fatalf("unexpected replayed completion %+v %v", replayed, err)
+	}
+	replayedVectors, err := player.GenerateEmbedding(ctx, []string{"tape"})
+	if err != nil || replayedVectors[0][3] != vectors[0][3] {
+		t.Fatalf("unexpected replayed embedding %v %v", replayedVectors, err)
+	}
+	if _, err := player.GenerateEmbedding(ctx, []string{"unrecorded"}); !errors.Is(err, ErrCassetteMiss) {
+		t.Fatalf("expected a cassette miss, got %v", err)
+	}
+
+	// Auto mode records only what is missing
+	auto := NewReplayClient(provider, cassette, ReplayAuto)
+	auto.GenerateEmbedding(ctx, []string{"tape"})
+	auto.GenerateEmbedding(ctx, []string{"new"})
+	if provider.calls != 3 || cassette.Len() != 3 {
+		t.Fatalf("expected one new recording, got %d calls and %d interactions", provider.calls, cassette.Len())
+	}
+
+	if err := (ReplayConfig{Mode: ReplayReplay}).Validate(); err == nil {
+		t.Fatal("expected replay without a cassette to be rejected")
+	}
+}
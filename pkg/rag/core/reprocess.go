@@ -0,0 +1,165 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// Reprocess errors
+var (
+	// ErrDocumentNotFound is returned when the document is missing or soft-deleted
+	ErrDocumentNotFound = errors.New("document not found")
+
+	// ErrReprocessBusy is returned while a blue/green rebuild owns the index
+	ErrReprocessBusy = errors.New("index rebuild in progress")
+
+	// ErrEmbeddingMismatch is returned when an embedding override would put
+	// vectors into an index built with another model
+	ErrEmbeddingMismatch = errors.New("embedding model differs from the index")
+)
+
+// ReprocessOptions overrides how a single document is re-chunked and
+// re-embedded. Unset fields use the pipeline's configuration.
+type ReprocessOptions struct {
+	// Chunking is built into Strategy with NewStrategy when Strategy is nil
+	Chunking    *ChunkingConfig                                                           `json:"chunking,omitempty"`
+	Strategy    ChunkingStrategy                                                          `json:"-"`
+	NewStrategy func(ChunkingConfig, embedding.VectorGenerator) (ChunkingStrategy, error) `json:"-"`
+
+	// Embedding model of Generator. It must match the index, or the target of
+	// a running re-embedding migration.
+	Embedding *EmbeddingConfig          `json:"embedding,omitempty"`
+	Generator embedding.VectorGenerator `json:"-"`
+}
+
+// ReprocessResult reports the chunks replaced by reprocessing a document
+type ReprocessResult struct {
+	DocumentID          string          `json:"document_id"`
+	Strategy            string          `json:"strategy,omitempty"`
+	Chunking            *ChunkingConfig `json:"chunking,omitempty"`
+	IndexVersion        string          `json:"index_version"`
+	ChunksRemoved       int             `json:"chunks_removed"`
+	ChunksCreated       int             `json:"chunks_created"`
+	EmbeddingsGenerated int             `json:"embeddings_generated"`
+	Errors              []string        `json:"errors,omitempty"`
+	TotalTime           time.Duration   `json:"total_time"`
+}
+
+// ReprocessDocument rebuilds the chunks and embeddings of one stored document,
+// optionally with another chunking strategy or embedding generator. The new
+// chunks are built before the old ones are replaced, so a failed rebuild
+// leaves the document as it was.
+func (p *Pipeline) ReprocessDocument(ctx context.Context, documentID string, options ReprocessOptions) (*ReprocessResult, error) {
+	if p.processor == nil || p.storage == nil || p.retriever == nil {
+		return nil, fmt.Errorf("pipeline not initialized")
+	}
+	if !p.swapMu.TryLock() {
+		return nil, ErrReprocessBusy
+	}
+	defer p.swapMu.Unlock()
+
+	startTime := time.Now()
+	doc, err := p.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrDocumentNotFound
+	}
+
+	// Vectors of another model only make sense in the index migrating to it
+	target := p.retriever
+	indexVersion := IndexVersionFromConfig(p.config.Processing.Embedding).String()
+	if options.Embedding != nil {
+		override := *options.Embedding
+		if override.Dimension == 0 && options.Generator != nil {
+			override.Dimension = options.Generator.GetDimension()
+		}
+		version := IndexVersionFromConfig(override).String()
+		if version != indexVersion {
+			migrating := p.dualReadRetriever()
+			if migrating == nil || p.GetReembedJob().ToVersion != version {
+				return nil, fmt.Errorf("%w: %s, index %s", ErrEmbeddingMismatch, version, indexVersion)
+			}
+			target = migrating
+			indexVersion = version
+		}
+	}
+
+	if options.Strategy == nil && options.Chunking != nil && options.NewStrategy != nil {
+		generator := options.Generator
+		if generator == nil {
+			generator = p.processor.GetEmbeddingGenerator()
+		}
+		strategy, err := options.NewStrategy(*options.Chunking, generator)
+		if err != nil {
+			return nil, err
+		}
+		options.Strategy = strategy
+	}
+
+	result := &ReprocessResult{
+		DocumentID:   documentID,
+		Chunking:     options.Chunking,
+		IndexVersion: indexVersion,
+	}
+	if options.Strategy != nil {
+		result.Strategy = options.Strategy.GetName()
+	} else if strategy := p.processor.GetChunkingStrategy(); strategy != nil {
+		result.Strategy = strategy.GetName()
+	}
+
+	chunks, generated, err := p.buildDocumentChunks(ctx, *doc, indexVersion, options.Strategy, options.Generator)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild document: %w", err)
+	}
+	result.EmbeddingsGenerated = generated
+
+	previous, err := p.storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+	for _, chunk := range previous {
+		if chunk.DuplicateOf != "" {
+			continue
+		}
+		if err := p.retriever.RemoveDocument(ctx, chunk.ID); err != nil {
+			p.emitError(ctx, "remove_chunk", err)
+		}
+		if target != p.retriever {
+			if err := target.RemoveDocument(ctx, chunk.ID); err != nil {
+				p.emitError(ctx, "remove_chunk", err)
+			}
+		}
+	}
+	result.ChunksRemoved = len(previous)
+
+	if _, err := p.persistShadowDocument(ctx, stagedDocument{doc: *doc, chunks: chunks}, nil); err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	for _, chunk := range chunks {
+		if len(chunk.Embedding) == 0 || chunk.DuplicateOf != "" {
+			continue
+		}
+		if err := target.AddDocument(ctx, chunk); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Add to retriever %s: %v", chunk.ID, err))
+		}
+	}
+	result.ChunksCreated = len(chunks)
+
+	p.invalidateCachedAnswers(ctx, documentID)
+	result.TotalTime = time.Since(startTime)
+
+	p.emitEvent(ctx, "document_reprocessed", map[string]interface{}{
+		"document_id":    documentID,
+		"strategy":       result.Strategy,
+		"index_version":  indexVersion,
+		"chunks_removed": result.ChunksRemoved,
+		"chunks_created": result.ChunksCreated,
+	})
+	return result, nil
+}
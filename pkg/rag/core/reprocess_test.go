@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// reprocessStorage also stores what reprocessing writes back
+type reprocessStorage struct {
+	*tombstoneStorage
+	embeddings map[string][]float64
+}
+
+func (s *reprocessStorage) StoreDocument(ctx context.Context, doc Document) error {
+	s.documents[doc.ID] = doc
+	return nil
+}
+
+func (s *reprocessStorage) StoreChunk(ctx context.Context, chunk DocumentChunk) error {
+	s.chunks[chunk.DocumentID] = append(s.chunks[chunk.DocumentID], chunk)
+	return nil
+}
+
+func (s *reprocessStorage) StoreEmbedding(ctx context.Context, chunkID string, vector []float64) error {
+	s.embeddings[chunkID] = vector
+	return nil
+}
+
+// numberedChunker splits documents into lines, or keeps them whole, with
+// positional chunk IDs
+type numberedChunker struct {
+	name  string
+	lines bool
+}
+
+func (c *numberedChunker) Chunk(ctx context.Context, doc Document) ([]DocumentChunk, error) {
+	parts := []string{doc.Content}
+	if c.lines {
+		parts = strings.Split(doc.Content, "\n")
+	}
+	var chunks []DocumentChunk
+	for i, line := range parts {
+		chunks = append(chunks, DocumentChunk{ID: fmt.Sprintf("%s_%s_%d", doc.ID, c.name, i), DocumentID: doc.ID, Content: line})
+	}
+	return chunks, nil
+}
+
+func (c *numberedChunker) GetName() string                                   { return c.name }
+func (c *numberedChunker) GetDescription() string                            { return "" }
+func (c *numberedChunker) SetParameters(params map[string]interface{}) error { return nil }
+func (c *numberedChunker) GetParameters() map[string]interface{}             { return nil }
+
+// strategyProcessor chunks with a strategy and leaves embedding to the pipeline
+type strategyProcessor struct {
+	DocumentProcessor
+	strategy  ChunkingStrategy
+	generator embedding.VectorGenerator
+}
+
+func (p *strategyProcessor) ProcessDocument(ctx context.Context, doc Document) ([]DocumentChunk, error) {
+	return p.strategy.Chunk(ctx, doc)
+}
+
+func (p *strategyProcessor) GetChunkingStrategy() ChunkingStrategy { return p.strategy }
+
+func (p *strategyProcessor) GetEmbeddingGenerator() embedding.VectorGenerator { return p.generator }
+
+func newReprocessPipeline() (*Pipeline, *reprocessStorage, *indexSet) {
+	storage := &reprocessStorage{
+		tombstoneStorage: &tombstoneStorage{
+			documents: map[string]Document{"guide": {ID: "guide", Content: "refund policy\nsso setup"}},
+			chunks: map[string][]DocumentChunk{"guide": {
+				{ID: "guide_old_0", DocumentID: "guide", Content: "refund policy\nsso setup", Embedding: []float64{1, 0}},
+			}},
+		},
+		embeddings: make(map[string][]float64),
+	}
+	retriever := &indexSet{chunks: map[string]bool{"guide_old_0": true}}
+	p := &Pipeline{
+		config: DefaultConfig(),
+		processor: &strategyProcessor{
+			strategy:  &numberedChunker{name: "whole"},
+			generator: &wordGenerator{vocabulary: []string{"refund", "sso"}},
+		},
+		storage:    storage,
+		retriever:  retriever,
+		tombstones: make(map[string]Tombstone),
+	}
+	return p, storage, retriever
+}
+
+func TestReprocessDocument(t *testing.T) {
+	ctx := context.Background()
+	p, storage, retriever := newReprocessPipeline()
+
+	result, err := p.ReprocessDocument(ctx, "guide", ReprocessOptions{Strategy: &numberedChunker{name: "lines", lines: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Strategy != "lines" || result.ChunksRemoved != 1 || result.ChunksCreated != 2 || result.EmbeddingsGenerated != 2 || len(result.Errors) != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if result.IndexVersion != IndexVersionFromConfig(p.config.Processing.Embedding).String() {
+		t.Fatalf("expected the serving index version, got %s", result.IndexVersion)
+	}
+	if retriever.chunks["guide_old_0"] || !retriever.chunks["guide_lines_0"] || !retriever.chunks["guide_lines_1"] {
+		t.Fatalf("expected the old chunks to be replaced in the index, got %v", retriever.chunks)
+	}
+	chunks := storage.chunks["guide"]
+	if len(chunks) != 2 || chunks[0].ID != "guide_lines_0" || chunks[1].IndexVersion != result.IndexVersion {
+		t.Fatalf("unexpected stored chunks %+v", chunks)
+	}
+	if vector := storage.embeddings["guide_lines_1"]; len(vector) != 2 || vector[1] != 1 {
+		t.Fatalf("unexpected stored embedding %v", vector)
+	}
+
+	// Without overrides the processor's strategy is used
+	result, err = p.ReprocessDocument(ctx, "guide", ReprocessOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Strategy != "whole" || result.ChunksRemoved != 2 || result.ChunksCreated != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	// Chunking overrides are built with NewStrategy
+	var built ChunkingConfig
+	result, err = p.ReprocessDocument(ctx, "guide", ReprocessOptions{
+		Chunking: &ChunkingConfig{MaxChunkSize: 42},
+		NewStrategy: func(config ChunkingConfig, generator embedding.VectorGenerator) (ChunkingStrategy, error) {
+			built = config
+			return &numberedChunker{name: "built"}, nil
+		},
+	})
+	if err != nil || built.MaxChunkSize != 42 || result.Strategy != "built" || result.Chunking.MaxChunkSize != 42 {
+		t.Fatalf("unexpected result %+v %v", result, err)
+	}
+}
+
+func TestReprocessDocumentErrors(t *testing.T) {
+	ctx := context.Background()
+	p, _, _ := newReprocessPipeline()
+
+	if _, err := p.ReprocessDocument(ctx, "missing", ReprocessOptions{}); !errors.Is(err, ErrDocumentNotFound) {
+		t.Fatalf("expected ErrDocumentNotFound, got %v", err)
+	}
+	p.tombstones["guide"] = Tombstone{DocumentID: "guide"}
+	if _, err := p.ReprocessDocument(ctx, "guide", ReprocessOptions{}); !errors.Is(err, ErrDocumentNotFound) {
+		t.Fatalf("expected soft-deleted documents to be missing, got %v", err)
+	}
+	delete(p.tombstones, "guide")
+
+	p.swapMu.Lock()
+	_, err := p.ReprocessDocument(ctx, "guide", ReprocessOptions{})
+	p.swapMu.Unlock()
+	if !errors.Is(err, ErrReprocessBusy) {
+		t.Fatalf("expected ErrReprocessBusy during a rebuild, got %v", err)
+	}
+
+	override := ReprocessOptions{
+		Embedding: &EmbeddingConfig{Model: "words"},
+		Generator: &wordGenerator{vocabulary: []string{"refund", "sso", "setup"}},
+	}
+	if _, err := p.ReprocessDocument(ctx, "guide", override); !errors.Is(err, ErrEmbeddingMismatch) {
+		t.Fatalf("expected ErrEmbeddingMismatch, got %v", err)
+	}
+	if _, err := p.ReprocessDocument(ctx, "guide", ReprocessOptions{
+		Chunking: &ChunkingConfig{},
+		NewStrategy: func(ChunkingConfig, embedding.VectorGenerator) (ChunkingStrategy, error) {
+			return nil, errors.New("unknown strategy")
+		},
+	}); err == nil || !strings.Contains(err.Error(), "unknown strategy") {
+		t.Fatalf("expected the strategy error, got %v", err)
+	}
+}
+
+func TestReprocessDocumentIntoMigration(t *testing.T) {
+	ctx := context.Background()
+	p, _, serving := newReprocessPipeline()
+	migrating := &indexSet{chunks: map[string]bool{"guide_old_0": true}}
+	p.reembed = &reembedState{
+		job:     &ReembedJob{Status: ReembedStatusRunning, ToVersion: "words@3"},
+		options: ReembedOptions{Retriever: migrating},
+	}
+
+	result, err := p.ReprocessDocument(ctx, "guide", ReprocessOptions{
+		Embedding: &EmbeddingConfig{Model: "words"},
+		Generator: &wordGenerator{vocabulary: []string{"refund", "sso", "setup"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IndexVersion != "words@3" || result.EmbeddingsGenerated != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	// Vectors of the target model go to the target index only
+	if serving.chunks["guide_old_0"] || len(serving.chunks) != 0 {
+		t.Fatalf("expected the serving index to drop the document, got %v", serving.chunks)
+	}
+	if migrating.chunks["guide_old_0"] || !migrating.chunks["guide_whole_0"] {
+		t.Fatalf("expected the target index to hold the new chunk, got %v", migrating.chunks)
+	}
+}
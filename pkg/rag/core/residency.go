@@ -0,0 +1,368 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var (
+	// ErrCrossRegionWrite is returned for a write of a tenant's data to
+	// storage outside the tenant's region
+	ErrCrossRegionWrite = errors.New("cross-region write rejected")
+	// ErrUnknownRegion is returned for a region without configured storage
+	ErrUnknownRegion = errors.New("no storage configured for region")
+)
+
+// regionPattern matches region names such as eu-west-1 or us
+var regionPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{1,31}$`)
+
+// ValidRegion reports whether name is a well-formed region name
+func ValidRegion(name string) bool {
+	return regionPattern.MatchString(name)
+}
+
+// RegionStorageConfig overrides the storage settings for one region. Empty
+// fields keep the global storage settings.
+type RegionStorageConfig struct {
+	Backend          string `json:"backend,omitempty"`
+	ConnectionString string `json:"connection_string,omitempty"`
+	DataDirectory    string `json:"data_directory,omitempty"`
+	IndexDirectory   string `json:"index_directory,omitempty"`
+	BackupPath       string `json:"backup_path,omitempty"`
+	ColdDirectory    string `json:"cold_directory,omitempty"`
+}
+
+// validateRegions checks region names and that the default region exists
+func (c StorageConfig) validateRegions() error {
+	for name := range c.Regions {
+		if !ValidRegion(name) {
+			return fmt.Errorf("invalid storage region %q", name)
+		}
+	}
+	if c.DefaultRegion != "" {
+		if _, ok := c.Regions[c.DefaultRegion]; !ok {
+			return fmt.Errorf("default_region %s is not a configured storage region", c.DefaultRegion)
+		}
+	}
+	return nil
+}
+
+// ForRegion returns the storage settings of a region, with the region's
+// overrides applied to the global settings. An empty region means the
+// default region, or the global settings when regions are not configured.
+func (c StorageConfig) ForRegion(region string) (StorageConfig, error) {
+	if region == "" {
+		region = c.DefaultRegion
+	}
+	if region == "" {
+		return c, nil
+	}
+	override, ok := c.Regions[region]
+	if !ok {
+		return StorageConfig{}, fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+	}
+	if override.Backend != "" {
+		c.Backend = override.Backend
+	}
+	if override.ConnectionString != "" {
+		c.ConnectionString = override.ConnectionString
+	}
+	if override.DataDirectory != "" {
+		c.DataDirectory = override.DataDirectory
+	}
+	if override.IndexDirectory != "" {
+		c.IndexDirectory = override.IndexDirectory
+	}
+	if override.BackupPath != "" {
+		c.BackupPath = override.BackupPath
+	}
+	if override.ColdDirectory != "" {
+		c.ColdDirectory = override.ColdDirectory
+	}
+	c.DefaultRegion = region
+	c.Regions = nil
+	return c, nil
+}
+
+// RegionNames returns the configured regions in order
+func (c StorageConfig) RegionNames() []string {
+	names := make([]string, 0, len(c.Regions))
+	for name := range c.Regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResidencyStore resolves the region a project's data must stay in, ""
+// for projects whose tenant has no residency requirement
+type ResidencyStore interface {
+	ProjectRegion(ctx context.Context, projectID string) (string, error)
+}
+
+// SetResidencyStore enables data residency, routing each project's reads
+// and writes to the storage of its tenant's region
+func (p *Pipeline) SetResidencyStore(store ResidencyStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.residency = store
+}
+
+type regionKey struct{}
+
+// WithRegion returns a context whose storage operations go to region
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// RegionFromContext returns the region set by WithRegion
+func RegionFromContext(ctx context.Context) string {
+	region, _ := ctx.Value(regionKey{}).(string)
+	return region
+}
+
+// documentRegion returns the region a document is tagged with, if any
+func documentRegion(doc Document) string {
+	region, _ := doc.Metadata.Custom["region"].(string)
+	return region
+}
+
+// projectRegionContext scopes ctx to the region of the project's tenant
+func (p *Pipeline) projectRegionContext(ctx context.Context, projectID string) (context.Context, error) {
+	p.mu.RLock()
+	store := p.residency
+	p.mu.RUnlock()
+	if store == nil || projectID == "" {
+		return ctx, nil
+	}
+	region, err := store.ProjectRegion(ctx, projectID)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to resolve region of project %s: %w", projectID, err)
+	}
+	if region == "" {
+		return ctx, nil
+	}
+	return WithRegion(ctx, region), nil
+}
+
+// documentRegionContext scopes ctx to the region of the document's project,
+// rejecting documents tagged with another region
+func (p *Pipeline) documentRegionContext(ctx context.Context, doc Document) (context.Context, error) {
+	ctx, err := p.projectRegionContext(ctx, documentProjectID(doc))
+	if err != nil {
+		return ctx, err
+	}
+	if tagged, region := documentRegion(doc), RegionFromContext(ctx); tagged != "" && region != "" && tagged != region {
+		return ctx, fmt.Errorf("%w: document %s is tagged %s but its tenant is in %s", ErrCrossRegionWrite, doc.ID, tagged, region)
+	}
+	return ctx, nil
+}
+
+// SetRegionalStorage replaces the pipeline storage with one backend per
+// configured region. Every region in the storage config needs a backend.
+// When encryption at rest is enabled the regional storage is encrypted too.
+func (p *Pipeline) SetRegionalStorage(backends map[string]Storage) error {
+	config := p.config.Storage
+	for _, region := range config.RegionNames() {
+		if backends[region] == nil {
+			return fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+		}
+	}
+	storage, err := NewRegionalStorage(config.DefaultRegion, backends)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.storage = p.encryptedStorage(storage)
+	return nil
+}
+
+// RegionalStorage routes storage operations to the backend of the context's
+// region. Operations without a region go to the default region; operations
+// for a region without a backend fail rather than fall back to another
+// region, so a tenant's data never leaves its region.
+type RegionalStorage struct {
+	backends      map[string]Storage
+	defaultRegion string
+}
+
+// NewRegionalStorage creates regional storage; the default region must
+// have a backend
+func NewRegionalStorage(defaultRegion string, backends map[string]Storage) (*RegionalStorage, error) {
+	if backends[defaultRegion] == nil {
+		return nil, fmt.Errorf("%w: default region %q", ErrUnknownRegion, defaultRegion)
+	}
+	return &RegionalStorage{backends: backends, defaultRegion: defaultRegion}, nil
+}
+
+// backend returns the storage of the context's region
+func (s *RegionalStorage) backend(ctx context.Context) (Storage, string, error) {
+	region := RegionFromContext(ctx)
+	if region == "" {
+		region = s.defaultRegion
+	}
+	backend, ok := s.backends[region]
+	if !ok {
+		return nil, region, fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+	}
+	return backend, region, nil
+}
+
+// StoreDocument implements the Storage interface
+func (s *RegionalStorage) StoreDocument(ctx context.Context, doc Document) error {
+	backend, region, err := s.backend(ctx)
+	if err != nil {
+		return err
+	}
+	if tagged := documentRegion(doc); tagged != "" && tagged != region {
+		return fmt.Errorf("%w: document %s is tagged %s, not %s", ErrCrossRegionWrite, doc.ID, tagged, region)
+	}
+	return backend.StoreDocument(ctx, doc)
+}
+
+// GetDocument implements the Storage interface
+func (s *RegionalStorage) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetDocument(ctx, documentID)
+}
+
+// StoreChunk implements the Storage interface
+func (s *RegionalStorage) StoreChunk(ctx context.Context, chunk DocumentChunk) error {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return backend.StoreChunk(ctx, chunk)
+}
+
+// GetChunk implements the Storage interface
+func (s *RegionalStorage) GetChunk(ctx context.Context, chunkID string) (*DocumentChunk, error) {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetChunk(ctx, chunkID)
+}
+
+// StoreEmbedding implements the Storage interface
+func (s *RegionalStorage) StoreEmbedding(ctx context.Context, chunkID string, embedding []float64) error {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return backend.StoreEmbedding(ctx, chunkID, embedding)
+}
+
+// GetEmbedding implements the Storage interface
+func (s *RegionalStorage) GetEmbedding(ctx context.Context, chunkID string) ([]float64, error) {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetEmbedding(ctx, chunkID)
+}
+
+// SearchEmbeddings implements the Storage interface
+func (s *RegionalStorage) SearchEmbeddings(ctx context.Context, queryEmbedding []float64, limit int) ([]EmbeddingMatch, error) {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.SearchEmbeddings(ctx, queryEmbedding, limit)
+}
+
+// StoreQuery implements the Storage interface
+func (s *RegionalStorage) StoreQuery(ctx context.Context, query QueryRecord) error {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return backend.StoreQuery(ctx, query)
+}
+
+// GetQuery implements the Storage interface
+func (s *RegionalStorage) GetQuery(ctx context.Context, queryID string) (*QueryRecord, error) {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.GetQuery(ctx, queryID)
+}
+
+// ListDocuments implements the Storage interface
+func (s *RegionalStorage) ListDocuments(ctx context.Context, options ListOptions) ([]Document, error) {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.ListDocuments(ctx, options)
+}
+
+// ListChunks implements the Storage interface
+func (s *RegionalStorage) ListChunks(ctx context.Context, documentID string) ([]DocumentChunk, error) {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return backend.ListChunks(ctx, documentID)
+}
+
+// DeleteDocument implements the Storage interface
+func (s *RegionalStorage) DeleteDocument(ctx context.Context, documentID string) error {
+	backend, _, err := s.backend(ctx)
+	if err != nil {
+		return err
+	}
+	return backend.DeleteDocument(ctx, documentID)
+}
+
+// Clear implements the Storage interface, clearing every region
+func (s *RegionalStorage) Clear(ctx context.Context) error {
+	for region, backend := range s.backends {
+		if err := backend.Clear(ctx); err != nil {
+			return fmt.Errorf("failed to clear region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// GetStorageStats implements the Storage interface, summing sizes and
+// counts over all regions
+func (s *RegionalStorage) GetStorageStats() (*StorageStats, error) {
+	total := &StorageStats{}
+	for region, backend := range s.backends {
+		stats, err := backend.GetStorageStats()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stats of region %s: %w", region, err)
+		}
+		total.TotalSize += stats.TotalSize
+		total.DocumentSize += stats.DocumentSize
+		total.ChunkSize += stats.ChunkSize
+		total.EmbeddingSize += stats.EmbeddingSize
+		total.IndexSize += stats.IndexSize
+		total.DocumentCount += stats.DocumentCount
+		total.ChunkCount += stats.ChunkCount
+		total.EmbeddingCount += stats.EmbeddingCount
+		total.IndexCount += stats.IndexCount
+		total.CacheSize += stats.CacheSize
+	}
+	return total, nil
+}
+
+// Close implements the Storage interface
+func (s *RegionalStorage) Close() error {
+	var first error
+	for _, backend := range s.backends {
+		if err := backend.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// recordingStorage records the IDs of stored documents
+type recordingStorage struct {
+	Storage
+	stored []string
+}
+
+func (s *recordingStorage) StoreDocument(ctx context.Context, doc Document) error {
+	s.stored = append(s.stored, doc.ID)
+	return nil
+}
+
+// projectRegions maps project IDs to regions
+type projectRegions map[string]string
+
+func (r projectRegions) ProjectRegion(ctx context.Context, projectID string) (string, error) {
+	return r[projectID], nil
+}
+
+func TestStorageConfigForRegion(t *testing.T) {
+	config := DefaultConfig().Storage
+	config.DefaultRegion = "us"
+	config.Regions = map[string]RegionStorageConfig{
+		"us": {},
+		"eu": {DataDirectory: "/data/eu", BackupPath: "/backups/eu"},
+	}
+	if err := config.validateRegions(); err != nil {
+		t.Fatal(err)
+	}
+
+	eu, err := config.ForRegion("eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if eu.DataDirectory != "/data/eu" || eu.BackupPath != "/backups/eu" || eu.IndexDirectory != config.IndexDirectory {
+		t.Fatalf("unexpected eu storage config %+v", eu)
+	}
+	if us, err := config.ForRegion(""); err != nil || us.DefaultRegion != "us" || us.DataDirectory != config.DataDirectory {
+		t.Fatalf("expected the default region, got %+v (%v)", us, err)
+	}
+	if _, err := config.ForRegion("ap"); !errors.Is(err, ErrUnknownRegion) {
+		t.Fatalf("expected an unknown region error, got %v", err)
+	}
+
+	config.DefaultRegion = "ap"
+	if err := config.validateRegions(); err == nil {
+		t.Fatal("expected an unconfigured default region to be rejected")
+	}
+}
+
+func TestRegionalStorageRouting(t *testing.T) {
+	ctx := context.Background()
+	us, eu := &recordingStorage{}, &recordingStorage{}
+	storage, err := NewRegionalStorage("us", map[string]Storage{"us": us, "eu": eu})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	euDoc := Document{ID: "eu-doc", Metadata: DocumentMetadata{Custom: map[string]interface{}{"region": "eu"}}}
+	if err := storage.StoreDocument(WithRegion(ctx, "eu"), euDoc); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.StoreDocument(ctx, Document{ID: "untagged"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(eu.stored) != 1 || len(us.stored) != 1 || us.stored[0] != "untagged" {
+		t.Fatalf("unexpected routing us=%v eu=%v", us.stored, eu.stored)
+	}
+
+	if err := storage.StoreDocument(WithRegion(ctx, "us"), euDoc); !errors.Is(err, ErrCrossRegionWrite) {
+		t.Fatalf("expected a cross-region write to be rejected, got %v", err)
+	}
+	if err := storage.StoreDocument(WithRegion(ctx, "ap"), Document{ID: "ap-doc"}); !errors.Is(err, ErrUnknownRegion) {
+		t.Fatalf("expected a region without storage to be rejected, got %v", err)
+	}
+	if len(us.stored) != 1 {
+		t.Fatalf("rejected writes must not fall back to the default region, got %v", us.stored)
+	}
+}
+
+func TestDocumentRegionContext(t *testing.T) {
+	p := &Pipeline{config: DefaultConfig()}
+	p.SetResidencyStore(projectRegions{"p-eu": "eu"})
+	inProject := func(projectID, region string) Document {
+		custom := map[string]interface{}{"project_id": projectID}
+		if region != "" {
+			custom["region"] = region
+		}
+		return Document{ID: projectID + "-doc", Metadata: DocumentMetadata{Custom: custom}}
+	}
+
+	ctx, err := p.documentRegionContext(context.Background(), inProject("p-eu", ""))
+	if err != nil || RegionFromContext(ctx) != "eu" {
+		t.Fatalf("expected the eu region, got %q (%v)", RegionFromContext(ctx), err)
+	}
+	ctx, err = p.documentRegionContext(context.Background(), inProject("p-global", ""))
+	if err != nil || RegionFromContext(ctx) != "" {
+		t.Fatalf("expected no region for a tenant without residency, got %q (%v)", RegionFromContext(ctx), err)
+	}
+	if _, err := p.documentRegionContext(context.Background(), inProject("p-eu", "us")); !errors.Is(err, ErrCrossRegionWrite) {
+		t.Fatalf("expected a document tagged with another region to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Retention actions
+const (
+	RetentionArchive = "archive" // soft-delete, restorable until the deleted retention passes
+	RetentionDelete  = "delete"  // delete permanently
+)
+
+// RetentionPolicy removes old or unused documents. A document is removed
+// when every age condition set on the policy holds; combine conditions with
+// OR by adding more policies.
+type RetentionPolicy struct {
+	Name string `json:"name,omitempty"`
+
+	// Data sources the policy applies to, all of the project's when empty
+	DataSourceIDs []string `json:"data_source_ids,omitempty"`
+
+	// Documents last modified more than MaxAgeDays ago
+	MaxAgeDays int `json:"max_age_days,omitempty"`
+
+	// Documents not retrieved in the last UnretrievedDays; never retrieved
+	// documents count from when they were indexed
+	UnretrievedDays int `json:"unretrieved_days,omitempty"`
+
+	Action   string `json:"action"`             // RetentionArchive or RetentionDelete
+	Disabled bool   `json:"disabled,omitempty"` // Kept for reference, not applied
+
+	// Documents never removed by the policy
+	ExcludeDocuments []string `json:"exclude_documents,omitempty"`
+	ExcludeTags      []string `json:"exclude_tags,omitempty"`
+}
+
+// Validate checks the policy has an action and at least one age condition
+func (rp *RetentionPolicy) Validate() error {
+	if rp.Action != RetentionArchive && rp.Action != RetentionDelete {
+		return fmt.Errorf("action must be %q or %q", RetentionArchive, RetentionDelete)
+	}
+	if rp.MaxAgeDays < 0 || rp.UnretrievedDays < 0 {
+		return fmt.Errorf("max_age_days and unretrieved_days cannot be negative")
+	}
+	if rp.MaxAgeDays == 0 && rp.UnretrievedDays == 0 {
+		return fmt.Errorf("max_age_days or unretrieved_days is required")
+	}
+	return nil
+}
+
+// RetentionOptions selects the documents retention policies are applied to
+type RetentionOptions struct {
+	// Documents ingested for the project, or belonging to one of its data sources
+	ProjectID     string   `json:"project_id"`
+	DataSourceIDs []string `json:"data_source_ids,omitempty"`
+
+	// Policies in priority order; the first matching policy decides the action
+	Policies []RetentionPolicy `json:"policies"`
+
+	// Last time each document was retrieved, by document ID
+	LastRetrieved map[string]time.Time `json:"-"`
+
+	// DryRun lists the documents that would be removed without removing them
+	DryRun bool `json:"dry_run"`
+
+	// Now is the reference time for ages, the current time when zero
+	Now time.Time `json:"-"`
+}
+
+// RetentionCandidate is a document a retention policy removes
+type RetentionCandidate struct {
+	DocumentID      string     `json:"document_id"`
+	Title           string     `json:"title"`
+	DataSourceID    string     `json:"data_source_id"`
+	Policy          string     `json:"policy"`
+	Action          string     `json:"action"`
+	Reason          string     `json:"reason"`
+	ModifiedAt      time.Time  `json:"modified_at"`
+	LastRetrievedAt *time.Time `json:"last_retrieved_at,omitempty"`
+	Error           string     `json:"error,omitempty"` // Why the removal failed
+}
+
+// RetentionReport lists the documents retention policies removed, or would
+// remove in a dry run
+type RetentionReport struct {
+	ProjectID  string               `json:"project_id"`
+	DryRun     bool                 `json:"dry_run"`
+	Documents  int                  `json:"documents"` // Documents checked
+	Candidates []RetentionCandidate `json:"candidates"`
+	Archived   int                  `json:"archived"`
+	Deleted    int                  `json:"deleted"`
+	Failed     int                  `json:"failed"`
+	RanAt      time.Time            `json:"ran_at"`
+}
+
+// ApplyRetention archives or deletes a project's documents matched by its
+// retention policies. Soft-deleted documents are left to the regular purge.
+// Removal shares the storage maintenance lock so it never races a purge or
+// a consistency repair; dry runs take no lock.
+func (p *Pipeline) ApplyRetention(ctx context.Context, options RetentionOptions) (*RetentionReport, error) {
+	for i := range options.Policies {
+		if err := options.Policies[i].Validate(); err != nil {
+			return nil, fmt.Errorf("policies[%d]: %w", i, err)
+		}
+	}
+	if options.Now.IsZero() {
+		options.Now = time.Now()
+	}
+
+	if options.DryRun {
+		return p.applyRetention(ctx, options)
+	}
+	var report *RetentionReport
+	err := WithLock(ctx, p.Locker(), LockKeyMaintenance, p.LockTTL(), func(ctx context.Context) error {
+		var err error
+		report, err = p.applyRetention(ctx, options)
+		return err
+	})
+	if errors.Is(err, ErrLockHeld) {
+		return nil, fmt.Errorf("storage maintenance already running on another instance")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(report.Candidates) > 0 {
+		p.emitEvent(ctx, "retention_applied", map[string]interface{}{
+			"project_id": report.ProjectID,
+			"archived":   report.Archived,
+			"deleted":    report.Deleted,
+			"failed":     report.Failed,
+		})
+	}
+	return report, nil
+}
+
+func (p *Pipeline) applyRetention(ctx context.Context, options RetentionOptions) (*RetentionReport, error) {
+	report := &RetentionReport{
+		ProjectID:  options.ProjectID,
+		DryRun:     options.DryRun,
+		Candidates: make([]RetentionCandidate, 0),
+		RanAt:      options.Now,
+	}
+	active := make([]RetentionPolicy, 0, len(options.Policies))
+	for _, policy := range options.Policies {
+		if !policy.Disabled {
+			active = append(active, policy)
+		}
+	}
+	if len(active) == 0 {
+		return report, nil
+	}
+
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	sort.Slice(documents, func(i, j int) bool { return documents[i].ID < documents[j].ID })
+
+	for _, doc := range documents {
+		if documentProjectID(doc) != options.ProjectID && !slices.Contains(options.DataSourceIDs, doc.DataSourceID) {
+			continue
+		}
+		if p.isTombstoned(doc.ID) {
+			continue
+		}
+		report.Documents++
+
+		for i := range active {
+			candidate, ok := matchRetention(&doc, &active[i], options)
+			if !ok {
+				continue
+			}
+			if !options.DryRun {
+				var err error
+				if candidate.Action == RetentionDelete {
+					err = p.DeleteDocument(ctx, doc.ID)
+				} else {
+					_, err = p.SoftDeleteDocument(ctx, doc.ID, "retention", candidate.Reason)
+				}
+				switch {
+				case err != nil:
+					candidate.Error = err.Error()
+					report.Failed++
+				case candidate.Action == RetentionDelete:
+					report.Deleted++
+				default:
+					report.Archived++
+				}
+			}
+			report.Candidates = append(report.Candidates, candidate)
+			break
+		}
+	}
+	return report, nil
+}
+
+// matchRetention reports whether a policy removes the document, and why
+func matchRetention(doc *Document, policy *RetentionPolicy, options RetentionOptions) (RetentionCandidate, bool) {
+	candidate := RetentionCandidate{
+		DocumentID:   doc.ID,
+		Title:        doc.Title,
+		DataSourceID: doc.DataSourceID,
+		Policy:       policy.Name,
+		Action:       policy.Action,
+		ModifiedAt:   documentModifiedAt(doc),
+	}
+	if len(policy.DataSourceIDs) > 0 && !slices.Contains(policy.DataSourceIDs, doc.DataSourceID) {
+		return candidate, false
+	}
+	if slices.Contains(policy.ExcludeDocuments, doc.ID) {
+		return candidate, false
+	}
+	for _, tag := range doc.Tags {
+		if slices.Contains(policy.ExcludeTags, tag) {
+			return candidate, false
+		}
+	}
+
+	var reasons []string
+	if policy.MaxAgeDays > 0 {
+		if candidate.ModifiedAt.IsZero() || options.Now.Sub(candidate.ModifiedAt) < days(policy.MaxAgeDays) {
+			return candidate, false
+		}
+		reasons = append(reasons, fmt.Sprintf("not modified in %d days", policy.MaxAgeDays))
+	}
+	if policy.UnretrievedDays > 0 {
+		lastUsed := doc.ProcessedAt
+		if retrieved, ok := options.LastRetrieved[doc.ID]; ok {
+			candidate.LastRetrievedAt = &retrieved
+			lastUsed = retrieved
+		}
+		if lastUsed.IsZero() {
+			lastUsed = candidate.ModifiedAt
+		}
+		if lastUsed.IsZero() || options.Now.Sub(lastUsed) < days(policy.UnretrievedDays) {
+			return candidate, false
+		}
+		reasons = append(reasons, fmt.Sprintf("not retrieved in %d days", policy.UnretrievedDays))
+	}
+
+	candidate.Reason = reasons[0]
+	if len(reasons) > 1 {
+		candidate.Reason += " and " + reasons[1]
+	}
+	return candidate, true
+}
+
+// documentModifiedAt is when the document last changed, falling back to its
+// creation and then processing time
+func documentModifiedAt(doc *Document) time.Time {
+	if !doc.Metadata.ModifiedAt.IsZero() {
+		return doc.Metadata.ModifiedAt
+	}
+	if !doc.Metadata.CreatedAt.IsZero() {
+		return doc.Metadata.CreatedAt
+	}
+	return doc.ProcessedAt
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// deletingStorage records deleted documents
+type deletingStorage struct {
+	usageStorage
+	deleted []string
+}
+
+func (s *deletingStorage) GetDocument(ctx context.Context, documentID string) (*Document, error) {
+	for _, doc := range s.documents {
+		if doc.ID == documentID {
+			return &doc, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *deletingStorage) DeleteDocument(ctx context.Context, documentID string) error {
+	s.deleted = append(s.deleted, documentID)
+	return nil
+}
+
+func TestApplyRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := func(daysAgo int, tags ...string) Document {
+		return Document{
+			Metadata:    DocumentMetadata{ModifiedAt: now.AddDate(0, 0, -daysAgo), Custom: map[string]interface{}{"project_id": "p1"}},
+			ProcessedAt: now.AddDate(0, 0, -daysAgo),
+			Tags:        tags,
+		}
+	}
+	documents := []Document{old(400), old(400, "legal"), old(10), old(200), old(400)}
+	for i, id := range []string{"stale", "pinned", "fresh", "unused", "other"} {
+		documents[i].ID = id
+	}
+	documents[4].Metadata.Custom["project_id"] = "p2"
+	storage := &deletingStorage{usageStorage: usageStorage{listStorage: listStorage{documents: documents}}}
+	p := &Pipeline{
+		config:     DefaultConfig(),
+		storage:    storage,
+		retriever:  &keywordRetriever{},
+		locker:     NewLocalLocker(),
+		tombstones: make(map[string]Tombstone),
+	}
+
+	options := RetentionOptions{
+		ProjectID: "p1",
+		Policies: []RetentionPolicy{
+			{Name: "yearly", MaxAgeDays: 365, Action: RetentionDelete, ExcludeTags: []string{"legal"}},
+			{Name: "unused", UnretrievedDays: 90, Action: RetentionArchive, ExcludeDocuments: []string{"pinned"}},
+		},
+		LastRetrieved: map[string]time.Time{"stale": now.AddDate(0, 0, -1)},
+		DryRun:        true,
+		Now:           now,
+	}
+	report, err := p.ApplyRetention(ctx, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Documents != 4 || len(report.Candidates) != 2 || len(storage.deleted) != 0 {
+		t.Fatalf("unexpected dry run %+v", report)
+	}
+	if c := report.Candidates[0]; c.DocumentID != "stale" || c.Action != RetentionDelete || c.Policy != "yearly" {
+		t.Fatalf("expected the old document to be deleted, got %+v", c)
+	}
+	if c := report.Candidates[1]; c.DocumentID != "unused" || c.Action != RetentionArchive || c.LastRetrievedAt != nil {
+		t.Fatalf("expected the unretrieved document to be archived, got %+v", c)
+	}
+
+	options.DryRun = false
+	report, err = p.ApplyRetention(ctx, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 1 || report.Archived != 1 || report.Failed != 0 {
+		t.Fatalf("unexpected report %+v", report.Candidates)
+	}
+	if len(storage.deleted) != 1 || storage.deleted[0] != "stale" || !p.isTombstoned("unused") {
+		t.Fatalf("expected stale deleted and unused archived, got %v", storage.deleted)
+	}
+
+	// Archived documents are left to the purge
+	report, _ = p.ApplyRetention(ctx, options)
+	for _, c := range report.Candidates {
+		if c.DocumentID == "unused" {
+			t.Fatalf("archived document was matched again: %+v", report.Candidates)
+		}
+	}
+
+	if _, err := p.ApplyRetention(ctx, RetentionOptions{Policies: []RetentionPolicy{{Action: RetentionDelete}}}); err == nil {
+		t.Fatal("expected a policy without age conditions to be rejected")
+	}
+}
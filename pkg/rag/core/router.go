@@ -0,0 +1,427 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// ProviderKind is the kind of request a provider serves
+type ProviderKind string
+
+const (
+	ProviderKindChat      ProviderKind = "chat"
+	ProviderKindEmbedding ProviderKind = "embedding"
+	ProviderKindRerank    ProviderKind = "rerank"
+)
+
+// Routing policies for ordering providers of the same kind
+const (
+	RoutingPriority = "priority" // Registration order
+	RoutingLatency  = "latency"  // Lowest observed latency first
+	RoutingCost     = "cost"     // Cheapest first
+)
+
+// CircuitState is the state of a provider circuit breaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Requests flow normally
+	CircuitOpen     CircuitState = "open"      // Requests are skipped until the cooldown passes
+	CircuitHalfOpen CircuitState = "half_open" // A single probe request is allowed
+)
+
+// CircuitBreaker stops sending requests to a failing provider. It opens after
+// threshold consecutive failures and lets one probe through after cooldown;
+// the probe's outcome closes or re-opens it.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a circuit breaker. A threshold of zero never opens.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     CircuitClosed,
+	}
+}
+
+// Allow reports whether a request may be sent
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.probing = true
+		return true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Success records a successful request and closes the breaker
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = CircuitClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a failed request, opening the breaker at the threshold
+// or when a half-open probe fails
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == CircuitHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = CircuitOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// State returns the current state
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// RoutedProvider is an LLM client registered with the router
+type RoutedProvider struct {
+	Name            string
+	Kind            ProviderKind
+	Client          LLMClient
+	Model           string  // Overrides the requested chat model when set
+	CostPer1KTokens float64 // Used by the cost policy
+}
+
+// ProviderStatus reports the health of a routed provider
+type ProviderStatus struct {
+	Name      string       `json:"name"`
+	Kind      ProviderKind `json:"kind"`
+	State     CircuitState `json:"state"`
+	Requests  int64        `json:"requests"`
+	Failures  int64        `json:"failures"`
+	LatencyMs float64      `json:"latency_ms"` // Moving average of successful requests
+}
+
+// routedProvider holds a provider with its runtime state
+type routedProvider struct {
+	RoutedProvider
+	breaker *CircuitBreaker
+
+	mu        sync.Mutex
+	requests  int64
+	failures  int64
+	latencyMs float64
+}
+
+// record updates request counters and the latency average
+func (rp *routedProvider) record(latency time.Duration, err error) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.requests++
+	if err != nil {
+		rp.failures++
+		return
+	}
+	ms := float64(latency) / float64(time.Millisecond)
+	if rp.latencyMs == 0 {
+		rp.latencyMs = ms
+	} else {
+		rp.latencyMs = 0.8*rp.latencyMs + 0.2*ms
+	}
+}
+
+func (rp *routedProvider) latency() float64 {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.latencyMs
+}
+
+// Router is an LLMClient that spreads requests over several providers. Each
+// request is retried on the chosen provider for transient failures (429,
+// 5xx, timeouts, connection errors), then falls back to the next provider in
+// policy order. Providers whose circuit breaker is open are skipped. With
+// hedging configured, a request still unanswered after HedgeAfter is also
+// sent to the next provider and the first answer wins.
+type Router struct {
+	config RoutingConfig
+
+	mu        sync.RWMutex
+	providers []*routedProvider
+
+	// Hedging budget and counters
+	hedging hedgeState
+}
+
+// NewRouter creates a router with no providers
+func NewRouter(config RoutingConfig) *Router {
+	return &Router{config: config}
+}
+
+// AddProvider registers a provider. Providers are tried in registration order
+// under the priority policy.
+func (r *Router) AddProvider(provider RoutedProvider) error {
+	if provider.Client == nil {
+		return fmt.Errorf("provider %s has no client", provider.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.providers {
+		if existing.Name == provider.Name && existing.Kind == provider.Kind {
+			return fmt.Errorf("%s provider %s already registered", provider.Kind, provider.Name)
+		}
+	}
+	r.providers = append(r.providers, &routedProvider{
+		RoutedProvider: provider,
+		breaker:        NewCircuitBreaker(r.config.FailureThreshold, r.config.OpenDuration),
+	})
+	return nil
+}
+
+// Status returns the health of every provider
+func (r *Router) Status() []ProviderStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statuses := make([]ProviderStatus, 0, len(r.providers))
+	for _, provider := range r.providers {
+		provider.mu.Lock()
+		statuses = append(statuses, ProviderStatus{
+			Name:      provider.Name,
+			Kind:      provider.Kind,
+			State:     provider.breaker.State(),
+			Requests:  provider.requests,
+			Failures:  provider.failures,
+			LatencyMs: provider.latencyMs,
+		})
+		provider.mu.Unlock()
+	}
+	return statuses
+}
+
+// candidates returns the providers of a kind in policy order
+func (r *Router) candidates(kind ProviderKind) []*routedProvider {
+	r.mu.RLock()
+	var candidates []*routedProvider
+	for _, provider := range r.providers {
+		if provider.Kind == kind {
+			candidates = append(candidates, provider)
+		}
+	}
+	r.mu.RUnlock()
+
+	switch r.config.Policy {
+	case RoutingLatency:
+		// Providers without measurements go first so they get measured
+		sort.SliceStable(candidates, func(i, j int) bool {
+			li, lj := candidates[i].latency(), candidates[j].latency()
+			if li == 0 || lj == 0 {
+				return li == 0 && lj != 0
+			}
+			return li < lj
+		})
+	case RoutingCost:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].CostPer1KTokens < candidates[j].CostPer1KTokens
+		})
+	}
+	return candidates
+}
+
+// execute runs call against providers of kind until one succeeds, hedging
+// slow requests when configured
+func execute[T any](ctx context.Context, r *Router, kind ProviderKind, call func(context.Context, *routedProvider) (T, error)) (T, error) {
+	var zero T
+	candidates := r.candidates(kind)
+	if len(candidates) == 0 {
+		return zero, fmt.Errorf("no %s provider configured", kind)
+	}
+	if r.config.HedgeAfter > 0 && r.config.HedgeBudget > 0 && len(candidates) > 1 {
+		return hedge(ctx, r, kind, candidates, call)
+	}
+	return failover(ctx, r, kind, candidates, call)
+}
+
+// failover runs call against the candidates in order until one succeeds
+func failover[T any](ctx context.Context, r *Router, kind ProviderKind, candidates []*routedProvider, call func(context.Context, *routedProvider) (T, error)) (T, error) {
+	var zero T
+	var lastErr error
+	for _, provider := range candidates {
+		for attempt := 0; attempt <= r.config.MaxRetries; attempt++ {
+			if !provider.breaker.Allow() {
+				if lastErr == nil {
+					lastErr = fmt.Errorf("%s: circuit open", provider.Name)
+				}
+				break
+			}
+			if attempt > 0 {
+				if err := sleepContext(ctx, r.backoff(attempt)); err != nil {
+					return zero, err
+				}
+			}
+
+			start := time.Now()
+			value, err := call(ctx, provider)
+			if err != nil && ctx.Err() != nil {
+				// Abandoned by the caller or a faster hedge, not the provider's fault
+				return zero, ctx.Err()
+			}
+			provider.record(time.Since(start), err)
+			if err == nil {
+				provider.breaker.Success()
+				return value, nil
+			}
+
+			lastErr = fmt.Errorf("%s: %w", provider.Name, err)
+			if !IsRetryableError(err) {
+				// The provider answered; the request itself would fail everywhere
+				provider.breaker.Success()
+				return zero, lastErr
+			}
+			provider.breaker.Failure()
+		}
+	}
+	return zero, fmt.Errorf("all %s providers failed: %w", kind, lastErr)
+}
+
+// backoff returns the delay before a retry, doubling per attempt
+func (r *Router) backoff(attempt int) time.Duration {
+	delay := r.config.RetryDelay << (attempt - 1)
+	if max := r.config.MaxRetryDelay; max > 0 && (delay > max || delay <= 0) {
+		delay = max
+	}
+	return delay
+}
+
+// GenerateCompletion implements the LLMClient interface
+func (r *Router) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	return execute(ctx, r, ProviderKindChat, func(ctx context.Context, provider *routedProvider) (*CompletionResponse, error) {
+		opts := options
+		if provider.Model != "" {
+			opts.Model = provider.Model
+		}
+		return provider.Client.GenerateCompletion(ctx, messages, opts)
+	})
+}
+
+// GenerateEmbedding implements the LLMClient interface. Fallback and hedge
+// providers must produce vectors in the same space, e.g. the same model on
+// another host.
+func (r *Router) GenerateEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
+	return execute(ctx, r, ProviderKindEmbedding, func(ctx context.Context, provider *routedProvider) ([][]float64, error) {
+		return provider.Client.GenerateEmbedding(ctx, texts)
+	})
+}
+
+// Rerank implements the LLMClient interface
+func (r *Router) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	return execute(ctx, r, ProviderKindRerank, func(ctx context.Context, provider *routedProvider) ([]float64, error) {
+		return provider.Client.Rerank(ctx, query, documents)
+	})
+}
+
+// GetModelInfo implements the LLMClient interface, describing the first chat provider
+func (r *Router) GetModelInfo() (*ModelInfo, error) {
+	candidates := r.candidates(ProviderKindChat)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no chat provider configured")
+	}
+	return candidates[0].Client.GetModelInfo()
+}
+
+// Validate implements the LLMClient interface. The router is valid when at
+// least one chat provider is.
+func (r *Router) Validate() error {
+	var lastErr error
+	for _, provider := range r.candidates(ProviderKindChat) {
+		if lastErr = provider.Client.Validate(); lastErr == nil {
+			return nil
+		}
+	}
+	if lastErr == nil {
+		return fmt.Errorf("no chat provider configured")
+	}
+	return lastErr
+}
+
+// Close implements the LLMClient interface
+func (r *Router) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var firstErr error
+	for _, provider := range r.providers {
+		if err := provider.Client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// IsRetryableError reports whether a provider error is transient: rate
+// limiting, server errors, timeouts and connection failures
+func IsRetryableError(err error) bool {
+	var httpErr *llm.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Retryable()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
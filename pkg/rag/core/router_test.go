@@ -0,0 +1,122 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// stubClient is an LLMClient that fails with err when set
+type stubClient struct {
+	err   error
+	calls int
+}
+
+func (c *stubClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &CompletionResponse{Model: options.Model}, nil
+}
+
+func (c *stubClient) GenerateEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
+	return nil, c.err
+}
+
+func (c *stubClient) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	return nil, c.err
+}
+
+func (c *stubClient) GetModelInfo() (*ModelInfo, error) { return &ModelInfo{}, nil }
+func (c *stubClient) Validate() error                   { return nil }
+func (c *stubClient) Close() error                      { return nil }
+
+func TestRouterFailoverAndCircuitBreaker(t *testing.T) {
+	router := NewRouter(RoutingConfig{
+		MaxRetries:       2,
+		RetryDelay:       time.Millisecond,
+		FailureThreshold: 3,
+		OpenDuration:     time.Hour,
+	})
+	down := &stubClient{err: &llm.HTTPError{Op: "chat completion", StatusCode: 503}}
+	backup := &stubClient{}
+	router.AddProvider(RoutedProvider{Name: "down", Kind: ProviderKindChat, Client: down})
+	router.AddProvider(RoutedProvider{Name: "backup", Kind: ProviderKindChat, Client: backup, Model: "backup-model"})
+
+	response, err := router.GenerateCompletion(context.Background(), nil, CompletionOptions{})
+	if err != nil {
+		t.Fatalf("expected fallback to succeed: %v", err)
+	}
+	if response.Model != "backup-model" {
+		t.Fatalf("expected backup model, got %q", response.Model)
+	}
+	if down.calls != 3 {
+		t.Fatalf("expected 3 attempts on failing provider, got %d", down.calls)
+	}
+
+	// The breaker is open now, so the failing provider is skipped
+	if _, err := router.GenerateCompletion(context.Background(), nil, CompletionOptions{}); err != nil {
+		t.Fatalf("second request failed: %v", err)
+	}
+	if down.calls != 3 || backup.calls != 2 {
+		t.Fatalf("expected open circuit to skip provider, calls down=%d backup=%d", down.calls, backup.calls)
+	}
+	if state := router.Status()[0].State; state != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", state)
+	}
+
+	// Request errors are not retried or failed over
+	invalid := &stubClient{err: errors.New("invalid request")}
+	router = NewRouter(RoutingConfig{MaxRetries: 2})
+	router.AddProvider(RoutedProvider{Name: "invalid", Kind: ProviderKindChat, Client: invalid})
+	router.AddProvider(RoutedProvider{Name: "backup", Kind: ProviderKindChat, Client: &stubClient{}})
+	if _, err := router.GenerateCompletion(context.Background(), nil, CompletionOptions{}); err == nil || invalid.calls != 1 {
+		t.Fatalf("expected single failed attempt, got err=%v calls=%d", err, invalid.calls)
+	}
+}
+
+// slowClient answers chat completions after delay unless cancelled first
+type slowClient struct {
+	stubClient
+	delay time.Duration
+}
+
+func (c *slowClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	select {
+	case <-time.After(c.delay):
+		return &CompletionResponse{Model: options.Model}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestRouterHedging(t *testing.T) {
+	router := NewRouter(RoutingConfig{HedgeAfter: 5 * time.Millisecond, HedgeBudget: 0.5})
+	router.AddProvider(RoutedProvider{Name: "slow", Kind: ProviderKindChat, Client: &slowClient{delay: 200 * time.Millisecond}, Model: "slow-model"})
+	router.AddProvider(RoutedProvider{Name: "fast", Kind: ProviderKindChat, Client: &slowClient{}, Model: "fast-model"})
+
+	// The first request has only earned half a hedge, so it waits for the slow provider
+	response, err := router.GenerateCompletion(context.Background(), nil, CompletionOptions{})
+	if err != nil || response.Model != "slow-model" {
+		t.Fatalf("expected the slow provider's answer, got %+v %v", response, err)
+	}
+
+	// The second completes the budget for a hedge, which answers first
+	response, err = router.GenerateCompletion(context.Background(), nil, CompletionOptions{})
+	if err != nil || response.Model != "fast-model" {
+		t.Fatalf("expected the hedge's answer, got %+v %v", response, err)
+	}
+	stats := router.HedgeStats()
+	if stats.Requests != 2 || stats.Hedged != 1 || stats.Wins != 1 || stats.Denied != 1 {
+		t.Fatalf("unexpected hedge stats %+v", stats)
+	}
+
+	// The cancelled slow request is not held against its provider
+	if failures := router.Status()[0].Failures; failures != 0 {
+		t.Fatalf("expected no failures on the slow provider, got %d", failures)
+	}
+}
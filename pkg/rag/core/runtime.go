@@ -0,0 +1,16 @@
+package core
+
+import "runtime"
+
+// CollectSystemMetrics samples the Go runtime of the current process. CPU,
+// disk and network usage are left to the host's monitoring.
+func CollectSystemMetrics() SystemMetrics {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return SystemMetrics{
+		MemoryUsage: int64(mem.Sys),
+		GoRoutines:  runtime.NumGoroutine(),
+		HeapSize:    int64(mem.HeapAlloc),
+		GCCount:     mem.NumGC,
+	}
+}
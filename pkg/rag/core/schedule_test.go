@@ -0,0 +1,50 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleNext(t *testing.T) {
+	from := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC) // Wednesday
+
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * 0", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}, // day of month OR Sunday
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2024, 1, 31, 11, 47, 30, 0, time.UTC)},
+		{"2h", time.Date(2024, 1, 31, 12, 17, 30, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		schedule, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Fatalf("%q: parse failed: %v", tc.spec, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tc.want) {
+			t.Errorf("%q: next = %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+
+	never, err := ParseSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if next := never.Next(from); !next.IsZero() {
+		t.Fatalf("February 30th should never run, got %s", next)
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@every 10s", "@sometimes", "abc"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/env"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/overloads"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// Scoring expressions rescore retrieval candidates after base scoring and
+// reranking. They are CEL expressions whose result is the new score, for
+// example
+//
+//	score * (doc.source_type == "wiki" ? 1.2 : 1.0) * exp(-doc.age_days / 180)
+//	"deprecated" in doc.tags ? score * 0.5 : score + (has(doc.meta.pinned) ? 0.1 : 0)
+//
+// Variables are score, similarity, keyword_score, rerank_score, doc and chunk;
+// see scoringDocFields and scoringChunkFields. Numbers are doubles: integer
+// literals are read as doubles and size returns a double. The standard library
+// is cut down to comparison, arithmetic except %, logic, indexing, in, the has
+// macro and the string methods contains, startsWith and endsWith. Added
+// functions are exp, log, pow, min, max, abs, size and lowerAscii.
+
+// Limits keeping untrusted expressions cheap
+const (
+	maxScoringExprLength = 2048
+	maxScoringExprNodes  = 256
+	maxScoringExprDepth  = 32
+
+	// maxScoringCost bounds the CEL runtime cost of one evaluation; string
+	// and list operations cost in proportion to their size
+	maxScoringCost = 10000
+
+	// scoringTimeBudget bounds rescoring of one result set; candidates left
+	// when it runs out keep their base score
+	scoringTimeBudget = 50 * time.Millisecond
+)
+
+// scoringVariables are the top-level names an expression may reference
+var scoringVariables = map[string]bool{
+	"score":         true,
+	"similarity":    true,
+	"keyword_score": true,
+	"rerank_score":  true,
+	"doc":           true,
+	"chunk":         true,
+}
+
+// scoringDocFields are the fields of doc; custom metadata is doc.meta
+var scoringDocFields = map[string]bool{
+	"id": true, "title": true, "source": true, "source_type": true, "author": true,
+	"owner": true, "type": true, "ext": true, "lang": true, "path": true,
+	"tags": true, "categories": true, "created": true, "modified": true,
+	"age_days": true, "meta": true,
+}
+
+// scoringChunkFields are the fields of chunk; chunk metadata is chunk.meta
+var scoringChunkFields = map[string]bool{
+	"id": true, "type": true, "index": true, "tokens": true, "meta": true,
+}
+
+// scoringStdLib is the part of the CEL standard library expressions may use
+var scoringStdLib = env.NewLibrarySubset().
+	AddIncludedMacros(operators.Has).
+	AddIncludedFunctions(
+		env.NewFunction(operators.Conditional),
+		env.NewFunction(operators.LogicalAnd),
+		env.NewFunction(operators.LogicalOr),
+		env.NewFunction(operators.LogicalNot),
+		env.NewFunction(operators.Equals),
+		env.NewFunction(operators.NotEquals),
+		env.NewFunction(operators.Less),
+		env.NewFunction(operators.LessEquals),
+		env.NewFunction(operators.Greater),
+		env.NewFunction(operators.GreaterEquals),
+		env.NewFunction(operators.Add),
+		env.NewFunction(operators.Subtract),
+		env.NewFunction(operators.Multiply),
+		env.NewFunction(operators.Divide),
+		env.NewFunction(operators.Negate),
+		env.NewFunction(operators.Index),
+		env.NewFunction(operators.In),
+		env.NewFunction(overloads.Contains),
+		env.NewFunction(overloads.StartsWith),
+		env.NewFunction(overloads.EndsWith),
+	)
+
+var (
+	scoringEnvOnce sync.Once
+	scoringEnv     *cel.Env
+	scoringEnvErr  error
+)
+
+// scoringEnvironment returns the CEL environment of scoring expressions
+func scoringEnvironment() (*cel.Env, error) {
+	scoringEnvOnce.Do(func() {
+		scoringEnv, scoringEnvErr = cel.NewCustomEnv(
+			cel.StdLib(cel.StdLibSubset(scoringStdLib)),
+			cel.ParserExpressionSizeLimit(maxScoringExprLength),
+			cel.ParserRecursionLimit(maxScoringExprDepth),
+			cel.Variable("score", cel.DoubleType),
+			cel.Variable("similarity", cel.DoubleType),
+			cel.Variable("keyword_score", cel.DoubleType),
+			cel.Variable("rerank_score", cel.DoubleType),
+			cel.Variable("doc", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Variable("chunk", cel.MapType(cel.StringType, cel.DynType)),
+			scoringUnaryFunction("exp", math.Exp),
+			scoringUnaryFunction("log", math.Log),
+			scoringUnaryFunction("abs", math.Abs),
+			scoringBinaryFunction("pow", math.Pow),
+			scoringBinaryFunction("min", math.Min),
+			scoringBinaryFunction("max", math.Max),
+			cel.Function(overloads.Size,
+				cel.Overload("size_string_double", []*cel.Type{cel.StringType}, cel.DoubleType, cel.UnaryBinding(scoringSize)),
+				cel.Overload("size_list_double", []*cel.Type{cel.ListType(cel.DynType)}, cel.DoubleType, cel.UnaryBinding(scoringSize)),
+				cel.Overload("size_map_double", []*cel.Type{cel.MapType(cel.DynType, cel.DynType)}, cel.DoubleType, cel.UnaryBinding(scoringSize)),
+			),
+			cel.Function("lowerAscii",
+				cel.MemberOverload("string_lower_ascii", []*cel.Type{cel.StringType}, cel.StringType,
+					cel.UnaryBinding(func(value ref.Val) ref.Val {
+						return types.String(strings.ToLower(string(value.(types.String))))
+					})),
+			),
+		)
+	})
+	return scoringEnv, scoringEnvErr
+}
+
+// scoringUnaryFunction declares a function of one double
+func scoringUnaryFunction(name string, fn func(float64) float64) cel.EnvOption {
+	return cel.Function(name, cel.Overload(name+"_double", []*cel.Type{cel.DoubleType}, cel.DoubleType,
+		cel.UnaryBinding(func(value ref.Val) ref.Val {
+			return types.Double(fn(float64(value.(types.Double))))
+		})))
+}
+
+// scoringBinaryFunction declares a function of two doubles
+func scoringBinaryFunction(name string, fn func(float64, float64) float64) cel.EnvOption {
+	return cel.Function(name, cel.Overload(name+"_double_double", []*cel.Type{cel.DoubleType, cel.DoubleType}, cel.DoubleType,
+		cel.BinaryBinding(func(lhs, rhs ref.Val) ref.Val {
+			return types.Double(fn(float64(lhs.(types.Double)), float64(rhs.(types.Double))))
+		})))
+}
+
+// scoringSize returns the size of a string, list or map as a double
+func scoringSize(value ref.Val) ref.Val {
+	return types.Double(value.(traits.Sizer).Size().(types.Int))
+}
+
+// ScoringExpression is a compiled scoring expression
+type ScoringExpression struct {
+	source  string
+	program cel.Program
+}
+
+// CompileScoringExpression parses and checks a scoring expression
+func CompileScoringExpression(source string) (*ScoringExpression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("scoring expression is empty")
+	}
+	celEnv, err := scoringEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	parsed, issues := celEnv.Parse(source)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if err := prepareScoringAST(parsed.NativeRep().Expr()); err != nil {
+		return nil, err
+	}
+	checked, issues := celEnv.Check(parsed)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	// Values whose type depends on the data are checked at evaluation
+	if output := checked.OutputType(); !output.IsExactType(cel.DoubleType) && !output.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("scoring expression must evaluate to a number, not %s", output)
+	}
+	program, err := celEnv.Program(checked, cel.CostLimit(maxScoringCost))
+	if err != nil {
+		return nil, err
+	}
+	return &ScoringExpression{source: source, program: program}, nil
+}
+
+// prepareScoringAST rejects oversized expressions and unknown variables and
+// fields, and turns integer literals into doubles
+func prepareScoringAST(root ast.Expr) error {
+	factory := ast.NewExprFactory()
+	nodes := 0
+	var err error
+	ast.PreOrderVisit(root, ast.NewExprVisitor(func(e ast.Expr) {
+		nodes++
+		if err != nil {
+			return
+		}
+		switch e.Kind() {
+		case ast.IdentKind:
+			if !scoringVariables[e.AsIdent()] {
+				err = fmt.Errorf("unknown variable %s", e.AsIdent())
+			}
+		case ast.SelectKind:
+			sel := e.AsSelect()
+			if sel.Operand().Kind() != ast.IdentKind {
+				return
+			}
+			switch name := sel.Operand().AsIdent(); {
+			case name == "doc" && !scoringDocFields[sel.FieldName()]:
+				err = fmt.Errorf("unknown field doc.%s", sel.FieldName())
+			case name == "chunk" && !scoringChunkFields[sel.FieldName()]:
+				err = fmt.Errorf("unknown field chunk.%s", sel.FieldName())
+			}
+		case ast.LiteralKind:
+			switch value := e.AsLiteral().(type) {
+			case types.Int:
+				e.SetKindCase(factory.NewLiteral(e.ID(), types.Double(value)))
+			case types.Uint:
+				e.SetKindCase(factory.NewLiteral(e.ID(), types.Double(value)))
+			}
+		}
+	}))
+	if nodes > maxScoringExprNodes {
+		return fmt.Errorf("scoring expression has more than %d terms", maxScoringExprNodes)
+	}
+	return err
+}
+
+// String returns the expression source
+func (e *ScoringExpression) String() string {
+	return e.source
+}
+
+// Eval computes the new score of a candidate
+func (e *ScoringExpression) Eval(result RetrievalResult, now time.Time) (float64, error) {
+	value, _, err := e.program.Eval(scoringActivation(result, now))
+	if err != nil {
+		return 0, err
+	}
+	score, ok := value.(types.Double)
+	if !ok {
+		return 0, fmt.Errorf("scoring expression returned %s, not a number", value.Type().TypeName())
+	}
+	if math.IsNaN(float64(score)) || math.IsInf(float64(score), 0) {
+		return 0, fmt.Errorf("scoring expression returned %v", score)
+	}
+	return float64(score), nil
+}
+
+// rescoreResults applies a scoring expression to retrieval results and
+// re-sorts them. Candidates whose evaluation fails keep their base score.
+func (p *Pipeline) rescoreResults(ctx context.Context, expression string, results []RetrievalResult) []RetrievalResult {
+	compiled, err := CompileScoringExpression(expression)
+	if err != nil {
+		p.emitError(ctx, "scoring_expression", err)
+		return results
+	}
+
+	now := time.Now()
+	deadline := now.Add(scoringTimeBudget)
+	failures := 0
+	for i := range results {
+		if time.Now().After(deadline) {
+			p.emitError(ctx, "scoring_expression", fmt.Errorf("time budget exceeded after %d of %d results", i, len(results)))
+			break
+		}
+		score, err := compiled.Eval(results[i], now)
+		if err != nil {
+			if failures == 0 {
+				p.emitError(ctx, "scoring_expression", err)
+			}
+			failures++
+			continue
+		}
+		results[i].Score = score
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	for i := range results {
+		results[i].Position = i
+	}
+	return results
+}
+
+// scoringActivation binds the variables of a candidate
+func scoringActivation(result RetrievalResult, now time.Time) map[string]interface{} {
+	doc := map[string]interface{}{"id": result.DocumentID}
+	if d := result.Document; d != nil {
+		doc["title"] = d.Title
+		doc["source"] = d.DataSourceID
+		doc["source_type"] = d.SourceType
+		doc["author"] = d.Metadata.Author
+		doc["owner"] = d.Metadata.Owner
+		doc["type"] = d.Metadata.FileType
+		doc["ext"] = strings.TrimPrefix(d.Metadata.Extension, ".")
+		doc["lang"] = d.Language
+		doc["path"] = d.Metadata.FilePath
+		doc["tags"] = stringsToValues(d.Tags)
+		doc["categories"] = stringsToValues(d.Categories)
+		doc["meta"] = scoringMap(d.Metadata.Custom)
+
+		// Times are Unix seconds; age is measured from the last modification
+		updated := d.Metadata.ModifiedAt
+		if updated.IsZero() {
+			updated = d.Metadata.CreatedAt
+		}
+		if !d.Metadata.CreatedAt.IsZero() {
+			doc["created"] = float64(d.Metadata.CreatedAt.Unix())
+		}
+		if !d.Metadata.ModifiedAt.IsZero() {
+			doc["modified"] = float64(d.Metadata.ModifiedAt.Unix())
+		}
+		if !updated.IsZero() {
+			doc["age_days"] = math.Max(0, now.Sub(updated).Hours()/24)
+		}
+	}
+
+	chunk := map[string]interface{}{}
+	if c := result.Chunk; c != nil {
+		chunk["id"] = c.ID
+		chunk["type"] = c.ChunkType
+		chunk["index"] = float64(c.ChunkIndex)
+		chunk["tokens"] = float64(c.TokenCount)
+		chunk["meta"] = scoringMap(c.Metadata)
+	}
+
+	return map[string]interface{}{
+		"score":         result.Score,
+		"similarity":    result.Similarity,
+		"keyword_score": result.KeywordScore,
+		"rerank_score":  result.RerankScore,
+		"doc":           doc,
+		"chunk":         chunk,
+	}
+}
+
+// scoringMap converts metadata to expression values
+func scoringMap(metadata map[string]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		converted[key] = scoringValue(value)
+	}
+	return converted
+}
+
+// scoringValue converts a metadata value to an expression value
+func scoringValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, string, float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return float64(v.Unix())
+	case []string:
+		return stringsToValues(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = scoringValue(item)
+		}
+		return list
+	case map[string]interface{}:
+		return scoringMap(v)
+	}
+	return fmt.Sprint(value)
+}
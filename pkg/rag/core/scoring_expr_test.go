@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScoringExpressionEval(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	result := RetrievalResult{
+		DocumentID: "a",
+		Score:      0.5,
+		Document: &Document{
+			SourceType: "wiki",
+			Tags:       []string{"go", "deprecated"},
+			Metadata: DocumentMetadata{
+				Author:     "jane",
+				ModifiedAt: now.AddDate(0, 0, -30),
+				Custom:     map[string]interface{}{"pinned": true, "rank": 3},
+			},
+		},
+		Chunk: &DocumentChunk{ChunkType: "paragraph"},
+	}
+
+	cases := map[string]float64{
+		`score * (doc.source_type == "wiki" ? 2 : 1)`:    1.0,
+		`"deprecated" in doc.tags ? score * 0.5 : score`: 0.25,
+		`score + (has(doc.meta.pinned) ? 0.1 : 0)`:       0.6,
+		`score + (has(doc.meta.missing) ? 0.1 : 0)`:      0.5,
+		`doc.age_days`:                       30,
+		`doc.meta.rank * 2 - size(doc.tags)`: 4,
+		`doc.author.startsWith('ja') && chunk.type != "summary" ? 1 : 0`: 1,
+		`max(min(score, 0.2), -1) + abs(-1)`:                             1.2,
+		`doc["source_type"] in ["wiki", "docs"] ? 1 : 0`:                 1,
+	}
+	for source, want := range cases {
+		expr, err := CompileScoringExpression(source)
+		if err != nil {
+			t.Fatalf("%s: unexpected compile error: %v", source, err)
+		}
+		got, err := expr.Eval(result, now)
+		if err != nil {
+			t.Fatalf("%s: unexpected eval error: %v", source, err)
+		}
+		if math.Abs(got-want) > 1e-9 {
+			t.Fatalf("%s: expected %v, got %v", source, want, got)
+		}
+	}
+
+	// Evaluation cost grows with the data
+	expr, err := CompileScoringExpression(`doc.title.contains("needle") ? score : 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Document.Title = strings.Repeat("hay", 100000)
+	if _, err := expr.Eval(result, now); err == nil || !strings.Contains(err.Error(), "cost limit exceeded") {
+		t.Fatalf("expected the cost limit to stop evaluation, got %v", err)
+	}
+
+	// Missing keys and type errors are evaluation errors
+	for _, source := range []string{`doc.meta.missing * score`, `score + doc.author`, `score / 0`, `exp(doc.author)`, `size(doc.meta.rank)`} {
+		expr, err := CompileScoringExpression(source)
+		if err != nil {
+			t.Fatalf("%s: unexpected compile error: %v", source, err)
+		}
+		if _, err := expr.Eval(result, now); err == nil {
+			t.Fatalf("%s: expected eval error", source)
+		}
+	}
+}
+
+func TestCompileScoringExpressionRejects(t *testing.T) {
+	for source, message := range map[string]string{
+		``:                                  "empty",
+		`score *`:                           "Syntax error",
+		`boost * 2`:                         "unknown variable",
+		`doc.colour == "red" ? 1 : 0`:       "unknown field",
+		`eval("score")`:                     "undeclared reference to 'eval'",
+		`score > 0.5`:                       "must evaluate to a number",
+		`"text"`:                            "must evaluate to a number",
+		`pow(score)`:                        "no matching overload for 'pow'",
+		`score % 2`:                         "undeclared reference to '_%_'",
+		`doc.title.matches("a+") ? 1 : 0`:   "undeclared reference to 'matches'",
+		`[1].all(score, score > 0) ? 1 : 0`: "undeclared reference to 'all'",
+		strings.Repeat("(", 40) + "score" + strings.Repeat(")", 40): "recursion limit exceeded",
+		"score + size([" + strings.Repeat("1,", 300) + "1])":        "more than",
+	} {
+		if _, err := CompileScoringExpression(source); err == nil || !strings.Contains(err.Error(), message) {
+			t.Fatalf("%q: expected error containing %q, got %v", source, message, err)
+		}
+	}
+}
+
+func TestRescoreResults(t *testing.T) {
+	p := &Pipeline{config: DefaultConfig()}
+	results := []RetrievalResult{
+		{DocumentID: "old", Score: 0.9, Document: &Document{SourceType: "forum"}},
+		{DocumentID: "wiki", Score: 0.6, Document: &Document{SourceType: "wiki"}},
+		{DocumentID: "bad", Score: 0.7},
+	}
+	results = p.rescoreResults(context.Background(), `doc.source_type == "wiki" ? score * 2 : score / 2`, results)
+
+	order := []string{results[0].DocumentID, results[1].DocumentID, results[2].DocumentID}
+	// "bad" has no source type and keeps its base score
+	if strings.Join(order, ",") != "wiki,bad,old" || results[0].Score != 1.2 || results[1].Score != 0.7 {
+		t.Fatalf("unexpected order %v: %+v", order, results)
+	}
+
+	// Project overrides are validated when saved
+	invalid := "score >"
+	config := &ProjectConfig{Retrieval: RetrievalOverrides{ScoringExpression: &invalid}}
+	if err := config.Validate(DefaultConfig()); err == nil {
+		t.Fatal("expected invalid scoring expression to be rejected")
+	}
+}
+
+func FuzzCompileScoringExpression(f *testing.F) {
+	for _, seed := range []string{
+		`score * (doc.source_type == "wiki" ? 2 : 1)`,
+		`"deprecated" in doc.tags ? score * 0.5 : score`,
+		`score + (has(doc.meta.pinned) ? 0.1 : 0)`,
+		`doc.meta.rank * 2 - size(doc.tags)`,
+		`doc.author.lowerAscii().startsWith('ja') ? exp(-doc.age_days / 180) : 0`,
+		`max(min(score, 0.2), -1) + pow(abs(-1), 2) + log(chunk.tokens)`,
+		`doc["source_type"] in ["wiki", "docs"] ? 1u : 0`,
+		`score *`,
+		`((score)`,
+		`"unterminated`,
+	} {
+		f.Add(seed)
+	}
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	result := RetrievalResult{
+		Score: 0.5,
+		Document: &Document{
+			Title:    "Guide",
+			Tags:     []string{"go"},
+			Metadata: DocumentMetadata{Author: "jane", ModifiedAt: now, Custom: map[string]interface{}{"rank": 3, "pinned": true}},
+		},
+		Chunk: &DocumentChunk{TokenCount: 100},
+	}
+	f.Fuzz(func(t *testing.T, source string) {
+		expr, err := CompileScoringExpression(source)
+		if err != nil {
+			return
+		}
+		// Any expression that compiles evaluates to a finite number or an error
+		score, err := expr.Eval(result, now)
+		if err == nil && (math.IsNaN(score) || math.IsInf(score, 0)) {
+			t.Fatalf("%q returned %v", source, score)
+		}
+	})
+}
@@ -0,0 +1,378 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Snapshot reasons
+const (
+	SnapshotManual     = "manual"
+	SnapshotPreReindex = "pre_reindex" // Taken automatically before a forced reindex
+	SnapshotPreRestore = "pre_restore" // Taken automatically before a restore replaces the index
+)
+
+// ErrSnapshotIncompatible is returned when a snapshot's vectors were produced
+// by a different embedding model than the one currently configured
+var ErrSnapshotIncompatible = errors.New("snapshot index version differs from the current embedding configuration")
+
+// IndexSnapshot is a point-in-time copy of the documents, chunks and
+// embeddings a project indexed from a set of data sources
+type IndexSnapshot struct {
+	ID                string    `json:"id"`
+	ProjectID         string    `json:"project_id"`
+	Name              string    `json:"name"`
+	Reason            string    `json:"reason"` // manual, pre_reindex or pre_restore
+	DataSourceIDs     []string  `json:"data_source_ids"`
+	ConfigFingerprint string    `json:"config_fingerprint"` // Hash of the chunking and embedding settings
+	IndexVersion      string    `json:"index_version"`
+	DocumentCount     int       `json:"document_count"`
+	ChunkCount        int       `json:"chunk_count"`
+	EmbeddingCount    int       `json:"embedding_count"`
+	CreatedBy         string    `json:"created_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+
+	// Content, omitted when snapshots are listed
+	Documents []Document      `json:"documents,omitempty"`
+	Chunks    []DocumentChunk `json:"chunks,omitempty"` // Canonical chunks carry their embeddings
+}
+
+// IndexSnapshotStore keeps index snapshots
+type IndexSnapshotStore interface {
+	// SaveIndexSnapshot stores a snapshot with its content
+	SaveIndexSnapshot(ctx context.Context, snapshot *IndexSnapshot) error
+
+	// ListIndexSnapshots returns a project's snapshots, newest first, without content
+	ListIndexSnapshots(ctx context.Context, projectID string) ([]IndexSnapshot, error)
+
+	// GetIndexSnapshot returns a snapshot with its content, or nil if none
+	GetIndexSnapshot(ctx context.Context, projectID, snapshotID string) (*IndexSnapshot, error)
+
+	// DeleteIndexSnapshot removes a snapshot
+	DeleteIndexSnapshot(ctx context.Context, projectID, snapshotID string) error
+}
+
+// SnapshotOptions defines what a snapshot covers
+type SnapshotOptions struct {
+	ProjectID     string   `json:"project_id"`
+	Name          string   `json:"name"`
+	Reason        string   `json:"reason"`
+	DataSourceIDs []string `json:"data_source_ids"` // Documents of these sources are captured
+	CreatedBy     string   `json:"created_by,omitempty"`
+}
+
+// SnapshotRestoreOptions controls a restore
+type SnapshotRestoreOptions struct {
+	// Force restores vectors from a different index version, which then
+	// cannot be compared with queries until the project is re-embedded
+	Force      bool   `json:"force"`
+	RestoredBy string `json:"restored_by,omitempty"`
+}
+
+// SnapshotRestoreResult reports what a restore replaced
+type SnapshotRestoreResult struct {
+	SnapshotID        string   `json:"snapshot_id"`
+	BackupSnapshotID  string   `json:"backup_snapshot_id,omitempty"` // Snapshot of the index replaced by the restore
+	DocumentsRemoved  int      `json:"documents_removed"`
+	DocumentsRestored int      `json:"documents_restored"`
+	ChunksRestored    int      `json:"chunks_restored"`
+	ConfigChanged     bool     `json:"config_changed"` // The chunking or embedding settings differ from the snapshot's
+	Errors            []string `json:"errors,omitempty"`
+}
+
+// SetSnapshotStore enables index snapshots, taken automatically before
+// forced reindexes
+func (p *Pipeline) SetSnapshotStore(store IndexSnapshotStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.snapshots = store
+}
+
+func (p *Pipeline) snapshotStore() (IndexSnapshotStore, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.snapshots == nil {
+		return nil, fmt.Errorf("index snapshots not enabled")
+	}
+	return p.snapshots, nil
+}
+
+// ConfigFingerprint hashes the settings that decide how a project's documents
+// are chunked and embedded; a reindex is needed when it changes
+func (p *Pipeline) ConfigFingerprint(ctx context.Context, projectID string) (fingerprint, indexVersion string, err error) {
+	config, err := p.EffectiveConfig(ctx, projectID)
+	if err != nil {
+		return "", "", err
+	}
+	version := IndexVersionFromConfig(config.Processing.Embedding)
+	data, err := json.Marshal(struct {
+		Chunking  ChunkingConfig `json:"chunking"`
+		Embedding IndexVersion   `json:"embedding"`
+	}{config.Processing.Chunking, version})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), version.String(), nil
+}
+
+// CreateSnapshot copies the documents, chunks and embeddings of the given
+// data sources into a new snapshot. Soft-deleted documents are left out.
+func (p *Pipeline) CreateSnapshot(ctx context.Context, options SnapshotOptions) (*IndexSnapshot, error) {
+	store, err := p.snapshotStore()
+	if err != nil {
+		return nil, err
+	}
+	if len(options.DataSourceIDs) == 0 {
+		return nil, fmt.Errorf("snapshot has no data sources")
+	}
+
+	fingerprint, indexVersion, err := p.ConfigFingerprint(ctx, options.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if options.Reason == "" {
+		options.Reason = SnapshotManual
+	}
+	snapshot := &IndexSnapshot{
+		ID:                p.generateID(),
+		ProjectID:         options.ProjectID,
+		Name:              options.Name,
+		Reason:            options.Reason,
+		DataSourceIDs:     options.DataSourceIDs,
+		ConfigFingerprint: fingerprint,
+		IndexVersion:      indexVersion,
+		CreatedBy:         options.CreatedBy,
+		CreatedAt:         p.now(),
+	}
+	if snapshot.Name == "" {
+		snapshot.Name = fmt.Sprintf("%s %s", snapshot.Reason, snapshot.CreatedAt.UTC().Format(time.RFC3339))
+	}
+
+	documents, err := p.scopeDocuments(ctx, options.DataSourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range documents {
+		if p.isTombstoned(doc.ID) {
+			continue
+		}
+		chunks, err := p.storage.ListChunks(ctx, doc.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chunks of %s: %w", doc.ID, err)
+		}
+		for i := range chunks {
+			chunk := &chunks[i]
+			if chunk.DuplicateOf != "" {
+				continue
+			}
+			if len(chunk.Embedding) == 0 {
+				embedding, err := p.storage.GetEmbedding(ctx, chunk.ID)
+				if err == nil {
+					chunk.Embedding = embedding
+				}
+			}
+			if len(chunk.Embedding) > 0 {
+				snapshot.EmbeddingCount++
+			}
+		}
+		snapshot.Documents = append(snapshot.Documents, doc)
+		snapshot.Chunks = append(snapshot.Chunks, chunks...)
+	}
+	snapshot.DocumentCount = len(snapshot.Documents)
+	snapshot.ChunkCount = len(snapshot.Chunks)
+
+	if err := store.SaveIndexSnapshot(ctx, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	p.emitEvent(ctx, "index_snapshot_created", map[string]interface{}{
+		"snapshot_id": snapshot.ID,
+		"project_id":  snapshot.ProjectID,
+		"reason":      snapshot.Reason,
+		"documents":   snapshot.DocumentCount,
+		"chunks":      snapshot.ChunkCount,
+	})
+	return snapshot, nil
+}
+
+// ListSnapshots returns a project's snapshots, newest first
+func (p *Pipeline) ListSnapshots(ctx context.Context, projectID string) ([]IndexSnapshot, error) {
+	store, err := p.snapshotStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.ListIndexSnapshots(ctx, projectID)
+}
+
+// DeleteSnapshot removes a snapshot
+func (p *Pipeline) DeleteSnapshot(ctx context.Context, projectID, snapshotID string) error {
+	store, err := p.snapshotStore()
+	if err != nil {
+		return err
+	}
+	return store.DeleteIndexSnapshot(ctx, projectID, snapshotID)
+}
+
+// RestoreSnapshot replaces the indexed documents of a snapshot's data sources
+// with its content. The replaced index is snapshotted first so the restore
+// can itself be rolled back. Callers must keep the data sources from syncing
+// while the restore runs.
+func (p *Pipeline) RestoreSnapshot(ctx context.Context, projectID, snapshotID string, options SnapshotRestoreOptions) (*SnapshotRestoreResult, error) {
+	store, err := p.snapshotStore()
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := store.GetIndexSnapshot(ctx, projectID, snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, fmt.Errorf("snapshot %s not found", snapshotID)
+	}
+
+	fingerprint, indexVersion, err := p.ConfigFingerprint(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if indexVersion != snapshot.IndexVersion && !options.Force {
+		return nil, fmt.Errorf("%w: snapshot %s, current %s", ErrSnapshotIncompatible, snapshot.IndexVersion, indexVersion)
+	}
+
+	backup, err := p.CreateSnapshot(ctx, SnapshotOptions{
+		ProjectID:     projectID,
+		Name:          fmt.Sprintf("before restoring %s", snapshot.Name),
+		Reason:        SnapshotPreRestore,
+		DataSourceIDs: snapshot.DataSourceIDs,
+		CreatedBy:     options.RestoredBy,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot current index: %w", err)
+	}
+
+	result := &SnapshotRestoreResult{
+		SnapshotID:       snapshot.ID,
+		BackupSnapshotID: backup.ID,
+		ConfigChanged:    fingerprint != snapshot.ConfigFingerprint,
+	}
+
+	// Remove the current documents, including soft-deleted ones
+	current, err := p.scopeDocuments(ctx, snapshot.DataSourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range current {
+		if err := p.DeleteDocument(ctx, doc.ID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Remove document %s: %v", doc.ID, err))
+			continue
+		}
+		if p.isTombstoned(doc.ID) {
+			if err := p.clearTombstone(ctx, doc.ID); err != nil {
+				p.emitError(ctx, "clear_tombstone", err)
+			}
+		}
+		result.DocumentsRemoved++
+	}
+
+	documents, _ := p.cache.(DocumentCache)
+	projects := make(map[string]string, len(snapshot.Documents)) // document ID -> project ID
+	for _, doc := range snapshot.Documents {
+		projects[doc.ID] = documentProjectID(doc)
+		if err := p.storage.StoreDocument(ctx, doc); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Restore document %s: %v", doc.ID, err))
+			continue
+		}
+		if documents != nil {
+			documents.SetDocument(ctx, &doc, 0)
+		}
+		if p.docDedup != nil && doc.Content != "" {
+			p.registerDocument(ctx, doc)
+		}
+		result.DocumentsRestored++
+	}
+
+	scopes := p.projectScopes(ctx)
+	for _, chunk := range snapshot.Chunks {
+		if err := p.storage.StoreChunk(ctx, chunk); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Restore chunk %s: %v", chunk.ID, err))
+			continue
+		}
+		if chunk.DuplicateOf == "" && len(chunk.Embedding) > 0 {
+			if err := p.storage.StoreEmbedding(ctx, chunk.ID, chunk.Embedding); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Restore embedding %s: %v", chunk.ID, err))
+				continue
+			}
+		}
+		if p.dedup != nil {
+			p.dedup.Register(scopes(projects[chunk.DocumentID]), chunk)
+		}
+		if chunk.DuplicateOf == "" {
+			if err := p.retriever.AddDocument(ctx, chunk); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Restore chunk %s: %v", chunk.ID, err))
+				continue
+			}
+		}
+		result.ChunksRestored++
+	}
+
+	p.emitEvent(ctx, "index_snapshot_restored", map[string]interface{}{
+		"snapshot_id": snapshot.ID,
+		"project_id":  projectID,
+		"documents":   result.DocumentsRestored,
+		"chunks":      result.ChunksRestored,
+		"errors":      len(result.Errors),
+	})
+	return result, nil
+}
+
+// snapshotBeforeReindex snapshots the data sources a forced reindex is about
+// to rebuild when snapshots are enabled
+func (p *Pipeline) snapshotBeforeReindex(ctx context.Context, options IndexOptions, sources []DataSource) error {
+	p.mu.RLock()
+	enabled := p.snapshots != nil
+	p.mu.RUnlock()
+	if !enabled || !options.ForceReindex || options.DryRun {
+		return nil
+	}
+
+	ids := make([]string, 0, len(sources))
+	for _, source := range sources {
+		ids = append(ids, source.GetID())
+	}
+	sort.Strings(ids)
+	_, err := p.CreateSnapshot(ctx, SnapshotOptions{
+		ProjectID:     options.ProjectID,
+		Name:          "before reindex",
+		Reason:        SnapshotPreReindex,
+		DataSourceIDs: ids,
+	})
+	return err
+}
+
+// scopeDocuments lists the stored documents of the given data sources
+func (p *Pipeline) scopeDocuments(ctx context.Context, sourceIDs []string) ([]Document, error) {
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{
+		Filter: FilterCriteria{DataSourceIDs: sourceIDs},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	inScope := make(map[string]bool, len(sourceIDs))
+	for _, id := range sourceIDs {
+		inScope[id] = true
+	}
+	kept := documents[:0]
+	for _, doc := range documents {
+		if inScope[doc.DataSourceID] {
+			kept = append(kept, doc)
+		}
+	}
+	return kept, nil
+}
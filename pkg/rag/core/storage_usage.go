@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// bytesPerDimension is the stored size of one float64 vector component
+const bytesPerDimension = 8
+
+// DocumentUsage is the storage one document takes up, including its chunks,
+// embeddings and index entries
+type DocumentUsage struct {
+	DocumentID   string `json:"document_id"`
+	Title        string `json:"title"`
+	DataSourceID string `json:"data_source_id"`
+	Chunks       int    `json:"chunks"`
+	Bytes        int64  `json:"bytes"`
+	Deleted      bool   `json:"deleted,omitempty"` // Soft-deleted, still stored until purged
+}
+
+// StorageUsageOptions selects the documents MeasureStorage counts
+type StorageUsageOptions struct {
+	// Documents ingested for the project, or belonging to one of its data
+	// sources; both empty measures the whole store
+	ProjectID     string   `json:"project_id,omitempty"`
+	DataSourceIDs []string `json:"data_source_ids,omitempty"`
+
+	// Number of largest documents to list, all of them when negative
+	Top int `json:"top"`
+}
+
+// StorageUsage is the storage a project takes up, broken down by documents,
+// chunks, embeddings and indexes
+type StorageUsage struct {
+	ProjectID string `json:"project_id,omitempty"`
+	StorageStats
+
+	// Bytes held by soft-deleted documents, included in the totals
+	DeletedBytes int64 `json:"deleted_bytes"`
+
+	TopDocuments []DocumentUsage `json:"top_documents"`
+	MeasuredAt   time.Time       `json:"measured_at"`
+}
+
+// MeasureStorage computes the bytes used by a project's documents, chunks,
+// embeddings and index entries, and lists its largest documents. Documents
+// and chunks are measured by their serialized size and embeddings by vector
+// length; index sizes are the project's share of the retriever index when the
+// retriever reports one.
+func (p *Pipeline) MeasureStorage(ctx context.Context, options StorageUsageOptions) (*StorageUsage, error) {
+	// Soft-deleted documents take up space until purged, so list storage directly
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	var indexBytesPerEntry float64
+	if p.retriever != nil {
+		if stats, err := p.retriever.GetStats(); err == nil && stats.VectorIndexSize > 0 && stats.IndexedChunks > 0 {
+			indexBytesPerEntry = float64(stats.VectorIndexSize) / float64(stats.IndexedChunks)
+		}
+	}
+
+	scoped := options.ProjectID != "" || len(options.DataSourceIDs) > 0
+	usage := &StorageUsage{ProjectID: options.ProjectID, MeasuredAt: time.Now()}
+	perDocument := make([]DocumentUsage, 0)
+	for _, doc := range documents {
+		if scoped && (options.ProjectID == "" || documentProjectID(doc) != options.ProjectID) &&
+			!slices.Contains(options.DataSourceIDs, doc.DataSourceID) {
+			continue
+		}
+		chunks, err := p.storage.ListChunks(ctx, doc.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chunks of %s: %w", doc.ID, err)
+		}
+
+		doc.DataSource = nil
+		docUsage := DocumentUsage{
+			DocumentID:   doc.ID,
+			Title:        doc.Title,
+			DataSourceID: doc.DataSourceID,
+			Chunks:       len(chunks),
+			Bytes:        serializedSize(doc),
+			Deleted:      p.isTombstoned(doc.ID),
+		}
+		usage.DocumentCount++
+		usage.DocumentSize += docUsage.Bytes
+
+		for _, chunk := range chunks {
+			dimension := len(chunk.Embedding)
+			if dimension == 0 {
+				dimension = chunk.EmbeddingDim
+			}
+			chunk.Embedding = nil
+			chunkBytes := serializedSize(chunk)
+			usage.ChunkCount++
+			usage.ChunkSize += chunkBytes
+			docUsage.Bytes += chunkBytes
+
+			if dimension == 0 {
+				continue
+			}
+			embeddingBytes := int64(dimension * bytesPerDimension)
+			usage.EmbeddingCount++
+			usage.EmbeddingSize += embeddingBytes
+			docUsage.Bytes += embeddingBytes
+
+			// Duplicates share the canonical chunk's index entry
+			if chunk.DuplicateOf != "" {
+				continue
+			}
+			indexBytes := embeddingBytes
+			if indexBytesPerEntry > 0 {
+				indexBytes = int64(indexBytesPerEntry)
+			}
+			usage.IndexCount++
+			usage.IndexSize += indexBytes
+			docUsage.Bytes += indexBytes
+		}
+
+		if docUsage.Deleted {
+			usage.DeletedBytes += docUsage.Bytes
+		}
+		perDocument = append(perDocument, docUsage)
+	}
+	usage.TotalSize = usage.DocumentSize + usage.ChunkSize + usage.EmbeddingSize + usage.IndexSize
+
+	sort.SliceStable(perDocument, func(i, j int) bool {
+		return perDocument[i].Bytes > perDocument[j].Bytes
+	})
+	if options.Top >= 0 && len(perDocument) > options.Top {
+		perDocument = perDocument[:options.Top]
+	}
+	usage.TopDocuments = perDocument
+	return usage, nil
+}
+
+// serializedSize is the JSON-encoded size of a stored record
+func serializedSize(v interface{}) int64 {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"testing"
+)
+
+// usageStorage serves documents with their chunks by document ID
+type usageStorage struct {
+	listStorage
+	chunks map[string][]DocumentChunk
+}
+
+func (s *usageStorage) ListChunks(ctx context.Context, documentID string) ([]DocumentChunk, error) {
+	return s.chunks[documentID], nil
+}
+
+// sizedRetriever reports a vector index size
+type sizedRetriever struct {
+	keywordRetriever
+	stats RetrieverStats
+}
+
+func (r *sizedRetriever) GetStats() (*RetrieverStats, error) {
+	stats := r.stats
+	return &stats, nil
+}
+
+func TestMeasureStorage(t *testing.T) {
+	project := func(id string) DocumentMetadata {
+		return DocumentMetadata{Custom: map[string]interface{}{"project_id": id}}
+	}
+	storage := &usageStorage{
+		listStorage: listStorage{documents: []Document{
+			{ID: "small", DataSourceID: "upload:p1", Content: "hi", Metadata: project("p1")},
+			{ID: "large", DataSourceID: "wiki", Content: "a much longer document body"},
+			{ID: "gone", DataSourceID: "upload:p1", Content: "deleted", Metadata: project("p1")},
+			{ID: "other", DataSourceID: "upload:p2", Content: "not ours", Metadata: project("p2")},
+		}},
+		chunks: map[string][]DocumentChunk{
+			"small": {{ID: "small_0", DocumentID: "small", Content: "hi", EmbeddingDim: 4}},
+			"large": {
+				{ID: "large_0", DocumentID: "large", Content: "a much longer", Embedding: []float64{1, 2, 3, 4}},
+				{ID: "large_1", DocumentID: "large", Content: "document body", EmbeddingDim: 4, DuplicateOf: "small_0"},
+				{ID: "large_2", DocumentID: "large", Content: "not embedded"},
+			},
+			"gone":  {{ID: "gone_0", DocumentID: "gone", Content: "deleted", EmbeddingDim: 4}},
+			"other": {{ID: "other_0", DocumentID: "other", Content: "not ours", EmbeddingDim: 4}},
+		},
+	}
+	retriever := &sizedRetriever{stats: RetrieverStats{IndexedChunks: 10, VectorIndexSize: 1000}}
+	p := &Pipeline{
+		config:     DefaultConfig(),
+		storage:    storage,
+		retriever:  retriever,
+		tombstones: map[string]Tombstone{"gone": {}},
+	}
+
+	usage, err := p.MeasureStorage(context.Background(), StorageUsageOptions{
+		ProjectID:     "p1",
+		DataSourceIDs: []string{"wiki"},
+		Top:           2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.DocumentCount != 3 || usage.ChunkCount != 5 || usage.EmbeddingCount != 4 || usage.IndexCount != 3 {
+		t.Fatalf("unexpected counts %+v", usage.StorageStats)
+	}
+	if usage.EmbeddingSize != 4*4*bytesPerDimension || usage.IndexSize != 3*100 {
+		t.Fatalf("unexpected embedding or index size %+v", usage.StorageStats)
+	}
+	if usage.TotalSize != usage.DocumentSize+usage.ChunkSize+usage.EmbeddingSize+usage.IndexSize {
+		t.Fatalf("total does not add up %+v", usage.StorageStats)
+	}
+	if len(usage.TopDocuments) != 2 || usage.TopDocuments[0].DocumentID != "large" || usage.TopDocuments[0].Chunks != 3 {
+		t.Fatalf("unexpected top documents %+v", usage.TopDocuments)
+	}
+
+	var deleted int64
+	all, _ := p.MeasureStorage(context.Background(), StorageUsageOptions{ProjectID: "p1", Top: -1})
+	for _, doc := range all.TopDocuments {
+		if doc.Deleted {
+			deleted += doc.Bytes
+		}
+	}
+	if len(all.TopDocuments) != 2 || deleted == 0 || all.DeletedBytes != deleted {
+		t.Fatalf("expected the soft-deleted document to be counted, got %+v", all)
+	}
+
+	whole, _ := p.MeasureStorage(context.Background(), StorageUsageOptions{Top: 0})
+	if whole.DocumentCount != 4 || len(whole.TopDocuments) != 0 {
+		t.Fatalf("expected the whole store without documents, got %+v", whole)
+	}
+}
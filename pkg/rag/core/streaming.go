@@ -0,0 +1,39 @@
+package core
+
+import (
+	"context"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// StreamSink receives the incremental output of a streamed query. Set it as
+// GenerateOptions.Stream; the final result is still returned by Query.
+type StreamSink interface {
+	// OnCitations receives the sources packed into the prompt, before generation starts
+	OnCitations(sources []Source)
+
+	// OnToken receives the next piece of generated text; an error aborts generation
+	OnToken(token string) error
+}
+
+// StreamingGenerator is implemented by generators that can emit text as it is generated
+type StreamingGenerator interface {
+	GenerateStream(ctx context.Context, query string, context []RetrievalResult, options GenerateOptions, onToken func(token string) error) (*GenerationResult, error)
+}
+
+// StreamingLLMClient is implemented by LLM clients that can stream completions
+type StreamingLLMClient interface {
+	StreamCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions, onToken func(token string) error) (*CompletionResponse, error)
+}
+
+// canStream reports whether generated text may reach the client before the
+// result is complete. Moderated and schema-constrained output is only
+// released once checked, and tool rounds are not user-facing.
+func (p *Pipeline) canStream(options GenerateOptions) bool {
+	if options.Stream == nil || options.OutputSchema != nil || options.EnableTools {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.moderators) == 0
+}
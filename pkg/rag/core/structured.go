@@ -0,0 +1,336 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// StructuredOutput is the validated JSON answer for a structured-output query
+type StructuredOutput struct {
+	Data           json.RawMessage `json:"data,omitempty"`
+	Valid          bool            `json:"valid"`
+	Repaired       bool            `json:"repaired"`        // Output needed local or model repair
+	RepairAttempts int             `json:"repair_attempts"` // Model repair round trips
+	Errors         []string        `json:"errors,omitempty"`
+}
+
+// trailingCommaRegex matches commas directly before a closing brace or bracket
+var trailingCommaRegex = regexp.MustCompile(`,\s*([}\]])`)
+
+// responseFormatFor builds the native response format for a schema
+func responseFormatFor(name string, schema map[string]interface{}) *llm.ResponseFormat {
+	if name == "" {
+		name = "answer"
+	}
+	return &llm.ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &llm.JSONSchemaFormat{
+			Name:   name,
+			Schema: schema,
+			Strict: true,
+		},
+	}
+}
+
+// structuredOutputInstruction tells the model to answer with schema-conforming JSON.
+// It is always added so models without a native JSON mode still comply.
+func structuredOutputInstruction(schema map[string]interface{}) string {
+	encoded, _ := json.MarshalIndent(schema, "", "  ")
+	return "Respond only with a JSON value that conforms to this JSON schema. Do not add explanations or code fences.\n" + string(encoded)
+}
+
+// enforceStructuredOutput parses the generated text against the schema,
+// repairing it locally and then with the model when validation fails
+func (p *Pipeline) enforceStructuredOutput(ctx context.Context, text string, options GenerateOptions) *StructuredOutput {
+	output := &StructuredOutput{}
+	value, errs := parseStructured(text, options.OutputSchema)
+	if errs == nil {
+		output.Valid = true
+		output.Data, _ = json.Marshal(value)
+		output.Repaired = !json.Valid([]byte(strings.TrimSpace(text)))
+		return output
+	}
+
+	config := p.config.Generation.StructuredOutput
+	current := text
+	for output.RepairAttempts < config.MaxRepairAttempts && p.llmClient != nil {
+		output.RepairAttempts++
+		repaired, err := p.repairStructuredOutput(ctx, current, errs, options)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("repair failed: %v", err))
+			break
+		}
+		current = repaired
+		if value, errs = parseStructured(current, options.OutputSchema); errs == nil {
+			output.Valid = true
+			output.Repaired = true
+			output.Data, _ = json.Marshal(value)
+			return output
+		}
+	}
+
+	output.Errors = errs
+	return output
+}
+
+// repairStructuredOutput asks the model to correct invalid output
+func (p *Pipeline) repairStructuredOutput(ctx context.Context, text string, errs []string, options GenerateOptions) (string, error) {
+	schema, _ := json.MarshalIndent(options.OutputSchema, "", "  ")
+	messages := []llm.ChatMessage{
+		{Role: "system", Content: "You correct JSON so it conforms to a JSON schema. Keep the original content where possible. Respond only with the corrected JSON."},
+		{Role: "user", Content: fmt.Sprintf("Schema:\n%s\n\nInvalid output:\n%s\n\nValidation errors:\n- %s", schema, text, strings.Join(errs, "\n- "))},
+	}
+
+	model := p.config.Generation.StructuredOutput.RepairModel
+	if model == "" {
+		model = options.Model
+	}
+	response, err := p.llmClient.GenerateCompletion(ctx, messages, CompletionOptions{
+		Model:          model,
+		Temperature:    0,
+		Seed:           p.samplingSeed(),
+		MaxTokens:      options.MaxTokens,
+		ResponseFormat: responseFormatFor(options.OutputSchemaName, options.OutputSchema),
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("empty response")
+	}
+	return response.Choices[0].Message.Content, nil
+}
+
+// parseStructured extracts JSON from text, applies local repairs and validates it
+func parseStructured(text string, schema map[string]interface{}) (interface{}, []string) {
+	candidate := extractJSON(text)
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(candidate), &value); err != nil {
+		fixed := trailingCommaRegex.ReplaceAllString(candidate, "$1")
+		fixed = strings.NewReplacer("“", `"`, "”", `"`).Replace(fixed)
+		if err2 := json.Unmarshal([]byte(fixed), &value); err2 != nil {
+			return nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+		}
+	}
+
+	if errs := ValidateJSONSchema(value, schema); len(errs) > 0 {
+		return nil, errs
+	}
+	return value, nil
+}
+
+// extractJSON strips code fences and surrounding prose from model output
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		if i := strings.LastIndex(text, "```"); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.TrimSpace(text)
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return text
+	}
+	closing := "}"
+	if text[start] == '[' {
+		closing = "]"
+	}
+	end := strings.LastIndex(text, closing)
+	if end < start {
+		return text[start:]
+	}
+	return text[start : end+1]
+}
+
+// ValidateJSONSchema validates a decoded JSON value against a JSON schema.
+// It supports the subset used for answer schemas: type, enum, const,
+// properties, required, additionalProperties, items, anyOf, minItems,
+// maxItems, minLength, maxLength, minimum, maximum and pattern.
+func ValidateJSONSchema(value interface{}, schema map[string]interface{}) []string {
+	var errs []string
+	validateSchema(value, schema, "$", &errs)
+	return errs
+}
+
+func validateSchema(value interface{}, schema map[string]interface{}, path string, errs *[]string) {
+	if schema == nil {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if types, ok := schema["type"]; ok && !matchesSchemaType(value, types) {
+		fail("expected type %v, got %s", types, jsonTypeName(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			if jsonValuesEqual(value, option) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("value not in enum %v", enum)
+		}
+	}
+	if constant, ok := schema["const"]; ok && !jsonValuesEqual(value, constant) {
+		fail("value must be %v", constant)
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, option := range anyOf {
+			sub, _ := option.(map[string]interface{})
+			var subErrs []string
+			validateSchema(value, sub, path, &subErrs)
+			if len(subErrs) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("value does not match any allowed schema")
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, exists := v[fmt.Sprint(name)]; !exists {
+					fail("missing required property %q", name)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if sub, ok := properties[key].(map[string]interface{}); ok {
+				validateSchema(v[key], sub, path+"."+key, errs)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					fail("unexpected property %q", key)
+				}
+			case map[string]interface{}:
+				validateSchema(v[key], extra, path+"."+key, errs)
+			}
+		}
+
+	case []interface{}:
+		if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < min {
+			fail("expected at least %v items", min)
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > max {
+			fail("expected at most %v items", max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case string:
+		length := float64(len([]rune(v)))
+		if min, ok := schemaNumber(schema, "minLength"); ok && length < min {
+			fail("shorter than %v characters", min)
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && length > max {
+			fail("longer than %v characters", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				fail("does not match pattern %s", pattern)
+			}
+		}
+
+	case float64:
+		if min, ok := schemaNumber(schema, "minimum"); ok && v < min {
+			fail("less than minimum %v", min)
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && v > max {
+			fail("greater than maximum %v", max)
+		}
+	}
+}
+
+func matchesSchemaType(value interface{}, types interface{}) bool {
+	var names []string
+	switch t := types.(type) {
+	case string:
+		names = []string{t}
+	case []interface{}:
+		for _, name := range t {
+			names = append(names, fmt.Sprint(name))
+		}
+	default:
+		return true
+	}
+
+	actual := jsonTypeName(value)
+	for _, name := range names {
+		if name == actual || (name == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonValuesEqual(a, b interface{}) bool {
+	ea, errA := json.Marshal(a)
+	eb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ea) == string(eb)
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	switch n := schema[key].(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+var answerSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"answer", "confidence"},
+	"properties": map[string]interface{}{
+		"answer":     map[string]interface{}{"type": "string", "minLength": float64(1)},
+		"confidence": map[string]interface{}{"type": "number", "minimum": float64(0), "maximum": float64(1)},
+		"tags": map[string]interface{}{
+			"type":     "array",
+			"maxItems": float64(2),
+			"items":    map[string]interface{}{"enum": []interface{}{"billing", "legal"}},
+		},
+	},
+	"additionalProperties": false,
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		errs []string
+	}{
+		{name: "valid", json: `{"answer":"yes","confidence":1,"tags":["legal"]}`},
+		{name: "missing required", json: `{"answer":"yes"}`, errs: []string{`$: missing required property "confidence"`}},
+		{name: "wrong type", json: `{"answer":3,"confidence":0.5}`, errs: []string{"$.answer: expected type string, got integer"}},
+		{name: "out of range", json: `{"answer":"yes","confidence":1.5}`, errs: []string{"$.confidence: greater than maximum 1"}},
+		{name: "unexpected property", json: `{"answer":"yes","confidence":0.5,"extra":true}`, errs: []string{`$: unexpected property "extra"`}},
+		{
+			name: "array constraints",
+			json: `{"answer":"yes","confidence":0.5,"tags":["billing","legal","hr"]}`,
+			errs: []string{"$.tags: expected at most 2 items", "$.tags[2]: value not in enum [billing legal]"},
+		},
+		{name: "not an object", json: `[1]`, errs: []string{"$: expected type object, got array"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(tt.json), &value); err != nil {
+				t.Fatal(err)
+			}
+			errs := ValidateJSONSchema(value, answerSchema)
+			if strings.Join(errs, "|") != strings.Join(tt.errs, "|") {
+				t.Fatalf("got %q, want %q", errs, tt.errs)
+			}
+		})
+	}
+
+	union := map[string]interface{}{"anyOf": []interface{}{
+		map[string]interface{}{"type": "string", "pattern": "^[a-z]+$"},
+		map[string]interface{}{"type": []interface{}{"integer", "null"}},
+	}}
+	for _, value := range []interface{}{"abc", float64(3), nil} {
+		if errs := ValidateJSONSchema(value, union); len(errs) != 0 {
+			t.Errorf("expected %v to match, got %q", value, errs)
+		}
+	}
+	for _, value := range []interface{}{"ABC", 1.5} {
+		if errs := ValidateJSONSchema(value, union); len(errs) != 1 {
+			t.Errorf("expected %v not to match, got %q", value, errs)
+		}
+	}
+}
+
+func TestParseStructuredRepairsLocally(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "plain", text: `{"answer":"yes","confidence":0.5}`},
+		{name: "code fence", text: "```json\n{\"answer\":\"yes\",\"confidence\":0.5}\n```"},
+		{name: "surrounding prose", text: `Here you go: {"answer":"yes","confidence":0.5} Hope that helps.`},
+		{name: "trailing comma and smart quotes", text: `{“answer”: "yes", "confidence": 0.5,}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, errs := parseStructured(tt.text, answerSchema)
+			if errs != nil {
+				t.Fatalf("unexpected errors %q", errs)
+			}
+			if value.(map[string]interface{})["answer"] != "yes" {
+				t.Fatalf("got %v", value)
+			}
+		})
+	}
+
+	if _, errs := parseStructured("no json at all", answerSchema); len(errs) != 1 || !strings.HasPrefix(errs[0], "invalid JSON") {
+		t.Fatalf("expected invalid JSON to be reported, got %q", errs)
+	}
+}
+
+func TestEnforceStructuredOutput(t *testing.T) {
+	config := DefaultConfig()
+	config.Generation.StructuredOutput.MaxRepairAttempts = 2
+	options := GenerateOptions{OutputSchema: answerSchema}
+
+	// Valid output needs no repair
+	p := &Pipeline{config: config}
+	output := p.enforceStructuredOutput(context.Background(), `{"answer":"yes","confidence":0.5}`, options)
+	if !output.Valid || output.Repaired || string(output.Data) != `{"answer":"yes","confidence":0.5}` {
+		t.Fatalf("unexpected output %+v", output)
+	}
+
+	// Locally repaired output is valid but marked repaired
+	output = p.enforceStructuredOutput(context.Background(), "```json\n{\"answer\":\"yes\",\"confidence\":0.5}\n```", options)
+	if !output.Valid || !output.Repaired || output.RepairAttempts != 0 {
+		t.Fatalf("unexpected output %+v", output)
+	}
+
+	// Invalid output is sent back to the model
+	client := &summaryClient{reply: `{"answer":"fixed","confidence":0.9}`}
+	p = &Pipeline{config: config, llmClient: client}
+	output = p.enforceStructuredOutput(context.Background(), `{"answer":"yes"}`, options)
+	if !output.Valid || !output.Repaired || output.RepairAttempts != 1 || client.calls != 1 {
+		t.Fatalf("unexpected output %+v after %d calls", output, client.calls)
+	}
+
+	// Repair gives up after the configured attempts
+	client = &summaryClient{reply: `{"answer":""}`}
+	p = &Pipeline{config: config, llmClient: client}
+	output = p.enforceStructuredOutput(context.Background(), `{"answer":"yes"}`, options)
+	if output.Valid || output.RepairAttempts != 2 || client.calls != 2 || len(output.Errors) == 0 {
+		t.Fatalf("unexpected output %+v after %d calls", output, client.calls)
+	}
+}
+
+func TestAPIClientResponseFormat(t *testing.T) {
+	var formats []*llm.ResponseFormat
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request llm.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		formats = append(formats, request.ResponseFormat)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"c1","model":"` + request.Model + `","choices":[{"index":0,"message":{"role":"assistant","content":"{}"},"finish_reason":"stop"}],"usage":{"total_tokens":7}}`))
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(&llm.Config{BaseURL: server.URL, APIKey: "key", Model: "gpt-4o"})
+	format := responseFormatFor("", answerSchema)
+	if format.JSONSchema.Name != "answer" || !format.JSONSchema.Strict {
+		t.Fatalf("unexpected response format %+v", format.JSONSchema)
+	}
+
+	response, err := client.GenerateCompletion(context.Background(), []llm.ChatMessage{{Role: "user", Content: "hi"}}, CompletionOptions{ResponseFormat: format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Usage.TotalTokens != 7 || len(response.Choices) != 1 || response.Choices[0].Message.Content != "{}" {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	if _, err := client.GenerateCompletion(context.Background(), nil, CompletionOptions{Model: "gpt-4", ResponseFormat: format}); err != nil {
+		t.Fatal(err)
+	}
+	if len(formats) != 2 || formats[0] == nil || formats[0].Type != "json_schema" || formats[1] != nil {
+		t.Fatalf("expected the format to be sent only to models that support it, got %+v", formats)
+	}
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// Chunk types produced by summarization-augmented indexing
+const (
+	ChunkTypeSummary  = "summary"
+	ChunkTypeKeywords = "keywords"
+)
+
+// SummaryResult holds the summary and keywords generated for a piece of content
+type SummaryResult struct {
+	Summary   string    `json:"summary"`
+	Keywords  []string  `json:"keywords"`
+	Tokens    int       `json:"tokens"` // Tokens spent generating this result
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Summarizer generates summaries and keyword lists for documents and sections.
+// Results are cached by content hash so unchanged content is never
+// re-summarized, and spending is capped per document.
+type Summarizer struct {
+	client LLMClient
+	config SummarizationConfig
+
+	mu    sync.Mutex
+	cache map[string]*SummaryResult
+	order []string // insertion order for cache eviction
+}
+
+// NewSummarizer creates a summarizer backed by the given LLM client
+func NewSummarizer(client LLMClient, config SummarizationConfig) *Summarizer {
+	return &Summarizer{
+		client: client,
+		config: config,
+		cache:  make(map[string]*SummaryResult),
+	}
+}
+
+// SummarizeDocument produces summary and keyword chunks for a document and
+// its sections. The document summary is generated first; section summaries
+// follow in order until the per-document token budget is exhausted.
+func (s *Summarizer) SummarizeDocument(ctx context.Context, doc Document, chunks []DocumentChunk) ([]DocumentChunk, error) {
+	budget := s.config.MaxTokensPerDocument
+	var derived []DocumentChunk
+
+	docResult, err := s.summarize(ctx, doc.Title, doc.Content, &budget)
+	if err != nil {
+		return nil, err
+	}
+	if docResult != nil {
+		derived = append(derived, s.buildChunks(doc, "doc", docResult, 0, len(doc.Content))...)
+	}
+
+	if s.config.MaxSections <= 0 {
+		return derived, nil
+	}
+
+	for i, section := range groupSections(chunks, s.config.SectionSize) {
+		if i >= s.config.MaxSections || (s.config.MaxTokensPerDocument > 0 && budget <= 0) {
+			break
+		}
+
+		result, err := s.summarize(ctx, doc.Title, section.content, &budget)
+		if err != nil {
+			return derived, err
+		}
+		if result == nil {
+			break
+		}
+		derived = append(derived, s.buildChunks(doc, fmt.Sprintf("section_%d", i), result, section.start, section.end)...)
+	}
+
+	return derived, nil
+}
+
+// summarize returns a cached result or generates a new one within the budget.
+// It returns nil when the remaining budget cannot cover the request.
+func (s *Summarizer) summarize(ctx context.Context, title, content string, budget *int) (*SummaryResult, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, nil
+	}
+
+	hash := ContentHash(content)
+	if cached := s.getCached(hash); cached != nil {
+		return cached, nil
+	}
+
+	// Truncate input so a single request never exceeds the budget
+	maxInput := s.config.MaxInputTokens
+	if maxInput > 0 && estimateTokenCount(content) > maxInput {
+		content = content[:maxInput*4]
+	}
+	cost := estimateTokenCount(content) + s.config.MaxOutputTokens
+	if s.config.MaxTokensPerDocument > 0 && cost > *budget {
+		return nil, nil
+	}
+
+	messages := []llm.ChatMessage{
+		{Role: "system", Content: summarizationPrompt(s.config.MaxKeywords)},
+		{Role: "user", Content: fmt.Sprintf("Title: %s\n\n%s", title, content)},
+	}
+
+	response, err := s.client.GenerateCompletion(ctx, messages, CompletionOptions{
+		Model:       s.config.Model,
+		Temperature: 0,
+		MaxTokens:   s.config.MaxOutputTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("failed to summarize: empty response")
+	}
+
+	result := parseSummaryResponse(response.Choices[0].Message.Content, s.config.MaxKeywords)
+	result.Tokens = response.Usage.TotalTokens
+	if result.Tokens == 0 {
+		result.Tokens = cost
+	}
+	*budget -= result.Tokens
+
+	s.putCached(hash, result)
+	return result, nil
+}
+
+// buildChunks converts a summary result into indexable chunks
+func (s *Summarizer) buildChunks(doc Document, scope string, result *SummaryResult, startPos, endPos int) []DocumentChunk {
+	now := time.Now()
+	var chunks []DocumentChunk
+
+	if result.Summary != "" {
+		chunks = append(chunks, DocumentChunk{
+			ID:         fmt.Sprintf("%s_%s_summary", doc.ID, scope),
+			DocumentID: doc.ID,
+			Content:    result.Summary,
+			ChunkIndex: -1,
+			StartPos:   startPos,
+			EndPos:     endPos,
+			ChunkType:  ChunkTypeSummary,
+			ChunkSize:  len(result.Summary),
+			TokenCount: estimateTokenCount(result.Summary),
+			Metadata:   map[string]interface{}{"scope": scope},
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+
+	if len(result.Keywords) > 0 {
+		content := strings.Join(result.Keywords, ", ")
+		chunks = append(chunks, DocumentChunk{
+			ID:         fmt.Sprintf("%s_%s_keywords", doc.ID, scope),
+			DocumentID: doc.ID,
+			Content:    content,
+			ChunkIndex: -1,
+			StartPos:   startPos,
+			EndPos:     endPos,
+			ChunkType:  ChunkTypeKeywords,
+			ChunkSize:  len(content),
+			TokenCount: estimateTokenCount(content),
+			Metadata:   map[string]interface{}{"scope": scope, "keywords": result.Keywords},
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
+
+	return chunks
+}
+
+func (s *Summarizer) getCached(hash string) *SummaryResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cache[hash]
+}
+
+func (s *Summarizer) putCached(hash string, result *SummaryResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.cache[hash]; !exists {
+		s.order = append(s.order, hash)
+	}
+	s.cache[hash] = result
+
+	for s.config.CacheSize > 0 && len(s.order) > s.config.CacheSize {
+		delete(s.cache, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// section is a contiguous run of chunks summarized together
+type section struct {
+	content    string
+	start, end int
+}
+
+// groupSections groups consecutive raw chunks into sections of roughly size characters
+func groupSections(chunks []DocumentChunk, size int) []section {
+	var sections []section
+	var current section
+	var builder strings.Builder
+
+	for _, chunk := range chunks {
+		if chunk.ChunkType == ChunkTypeSummary || chunk.ChunkType == ChunkTypeKeywords {
+			continue
+		}
+		if builder.Len() == 0 {
+			current.start = chunk.StartPos
+		}
+		builder.WriteString(chunk.Content)
+		builder.WriteString("\n")
+		current.end = chunk.EndPos
+
+		if size > 0 && builder.Len() >= size {
+			current.content = builder.String()
+			sections = append(sections, current)
+			builder.Reset()
+		}
+	}
+	if builder.Len() > 0 {
+		current.content = builder.String()
+		sections = append(sections, current)
+	}
+
+	return sections
+}
+
+// summarizationPrompt returns the system prompt for summary generation
+func summarizationPrompt(maxKeywords int) string {
+	return fmt.Sprintf(`Summarize the following content in 2-4 sentences so it can be found by broad questions.
+Then list up to %d keywords or key phrases.
+Respond exactly in this format:
+SUMMARY: <summary>
+KEYWORDS: <keyword>, <keyword>, ...`, maxKeywords)
+}
+
+// parseSummaryResponse extracts the summary and keywords from the model output
+func parseSummaryResponse(text string, maxKeywords int) *SummaryResult {
+	result := &SummaryResult{CreatedAt: time.Now()}
+
+	var summaryLines []string
+	inSummary := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		upper := strings.ToUpper(trimmed)
+		switch {
+		case strings.HasPrefix(upper, "SUMMARY:"):
+			inSummary = true
+			summaryLines = append(summaryLines, strings.TrimSpace(trimmed[len("SUMMARY:"):]))
+		case strings.HasPrefix(upper, "KEYWORDS:"):
+			inSummary = false
+			for _, keyword := range strings.Split(trimmed[len("KEYWORDS:"):], ",") {
+				if keyword = strings.TrimSpace(keyword); keyword != "" {
+					result.Keywords = append(result.Keywords, keyword)
+				}
+			}
+		case inSummary && trimmed != "":
+			summaryLines = append(summaryLines, trimmed)
+		}
+	}
+
+	result.Summary = strings.Join(summaryLines, " ")
+	if result.Summary == "" && len(result.Keywords) == 0 {
+		result.Summary = strings.TrimSpace(text)
+	}
+	if maxKeywords > 0 && len(result.Keywords) > maxKeywords {
+		result.Keywords = result.Keywords[:maxKeywords]
+	}
+
+	return result
+}
+
+// estimateTokenCount roughly estimates tokens as four characters per token
+func estimateTokenCount(text string) int {
+	return (len(text) + 3) / 4
+}
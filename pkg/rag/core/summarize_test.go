@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// summaryClient is an LLMClient that answers every completion with reply
+type summaryClient struct {
+	reply  string
+	tokens int
+	calls  int
+}
+
+func (c *summaryClient) GenerateCompletion(ctx context.Context, messages []llm.ChatMessage, options CompletionOptions) (*CompletionResponse, error) {
+	c.calls++
+	return &CompletionResponse{
+		Choices: []CompletionChoice{{Message: llm.ChatMessage{Role: "assistant", Content: c.reply}}},
+		Usage:   CompletionUsage{TotalTokens: c.tokens},
+	}, nil
+}
+
+func (c *summaryClient) GenerateEmbedding(ctx context.Context, texts []string) ([][]float64, error) {
+	return nil, nil
+}
+
+func (c *summaryClient) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	return nil, nil
+}
+
+func (c *summaryClient) GetModelInfo() (*ModelInfo, error) { return &ModelInfo{}, nil }
+func (c *summaryClient) Validate() error                   { return nil }
+func (c *summaryClient) Close() error                      { return nil }
+
+func TestParseSummaryResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		summary  string
+		keywords []string
+	}{
+		{
+			name:     "summary and keywords",
+			text:     "SUMMARY: Explains billing.\nIt covers refunds.\nKEYWORDS: billing, refunds , , invoices",
+			summary:  "Explains billing. It covers refunds.",
+			keywords: []string{"billing", "refunds"},
+		},
+		{
+			name:    "lower case labels",
+			text:    "summary: Short one.",
+			summary: "Short one.",
+		},
+		{
+			name:    "free text falls back to the whole reply",
+			text:    "  Just a plain answer  ",
+			summary: "Just a plain answer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseSummaryResponse(tt.text, 2)
+			if result.Summary != tt.summary {
+				t.Fatalf("got summary %q, want %q", result.Summary, tt.summary)
+			}
+			if strings.Join(result.Keywords, "|") != strings.Join(tt.keywords, "|") {
+				t.Fatalf("got keywords %q, want %q", result.Keywords, tt.keywords)
+			}
+		})
+	}
+}
+
+func TestGroupSectionsSkipsDerivedChunks(t *testing.T) {
+	chunks := []DocumentChunk{
+		{Content: "aaaa", StartPos: 0, EndPos: 4},
+		{Content: "summary", ChunkType: ChunkTypeSummary},
+		{Content: "bbbb", StartPos: 4, EndPos: 8},
+		{Content: "cccc", StartPos: 8, EndPos: 12},
+	}
+	sections := groupSections(chunks, 10)
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 sections, got %+v", sections)
+	}
+	if sections[0].content != "aaaa\nbbbb\n" || sections[0].start != 0 || sections[0].end != 8 {
+		t.Fatalf("unexpected first section %+v", sections[0])
+	}
+	if sections[1].content != "cccc\n" || sections[1].start != 8 || sections[1].end != 12 {
+		t.Fatalf("unexpected second section %+v", sections[1])
+	}
+}
+
+func TestSummarizeDocumentBudgetAndCache(t *testing.T) {
+	client := &summaryClient{reply: "SUMMARY: A summary.\nKEYWORDS: alpha, beta", tokens: 50}
+	summarizer := NewSummarizer(client, SummarizationConfig{
+		MaxTokensPerDocument: 100,
+		MaxOutputTokens:      10,
+		MaxSections:          5,
+		SectionSize:          1,
+		MaxKeywords:          5,
+	})
+
+	doc := Document{ID: "doc1", Title: "Guide", Content: "whole document"}
+	chunks := []DocumentChunk{
+		{Content: "first section", StartPos: 0, EndPos: 13},
+		{Content: "second section", StartPos: 13, EndPos: 27},
+		{Content: "third section", StartPos: 27, EndPos: 40},
+	}
+
+	derived, err := summarizer.SummarizeDocument(context.Background(), doc, chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The document and the first section fit the budget, the rest do not
+	if client.calls != 2 {
+		t.Fatalf("expected 2 completions within the budget, got %d", client.calls)
+	}
+	var ids []string
+	for _, chunk := range derived {
+		ids = append(ids, chunk.ID)
+		if chunk.DocumentID != "doc1" || chunk.ChunkIndex != -1 {
+			t.Fatalf("unexpected derived chunk %+v", chunk)
+		}
+	}
+	want := "doc1_doc_summary,doc1_doc_keywords,doc1_section_0_summary,doc1_section_0_keywords"
+	if strings.Join(ids, ",") != want {
+		t.Fatalf("got chunks %s, want %s", strings.Join(ids, ","), want)
+	}
+	if derived[1].ChunkType != ChunkTypeKeywords || derived[1].Content != "alpha, beta" {
+		t.Fatalf("unexpected keywords chunk %+v", derived[1])
+	}
+
+	// Unchanged content is served from the cache without spending tokens
+	if _, err := summarizer.SummarizeDocument(context.Background(), doc, chunks[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if client.calls != 2 {
+		t.Fatalf("expected cached summaries to be reused, got %d calls", client.calls)
+	}
+}
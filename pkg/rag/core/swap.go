@@ -0,0 +1,516 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// Index swap outcomes
+const (
+	IndexSwapSwapped  = "swapped"
+	IndexSwapRejected = "rejected" // Validation failed; the current index keeps serving reads
+	IndexSwapFailed   = "failed"
+)
+
+// Validation defaults for a rebuilt index
+const (
+	defaultSwapMinDocumentRatio = 0.9
+	defaultSwapMinOverlap       = 0.5
+	defaultSwapTopK             = 5
+)
+
+// SwapValidation sets the checks a rebuilt index must pass before reads
+// switch to it; zero values use the defaults
+type SwapValidation struct {
+	// Rebuilt documents relative to the documents currently indexed
+	MinDocumentRatio float64 `json:"min_document_ratio,omitempty"`
+
+	// Queries run against both indexes; the mean share of the current top
+	// documents the rebuilt index also returns must reach MinOverlap
+	SampleQueries []string `json:"sample_queries,omitempty"`
+	MinOverlap    float64  `json:"min_overlap,omitempty"`
+	TopK          int      `json:"top_k,omitempty"`
+}
+
+// SampleComparison compares the documents both indexes return for a query
+type SampleComparison struct {
+	Query            string   `json:"query"`
+	CurrentDocuments []string `json:"current_documents"`
+	RebuiltDocuments []string `json:"rebuilt_documents"`
+	Overlap          float64  `json:"overlap"`
+}
+
+// IndexSwapReport describes a blue/green rebuild: the new index is built
+// beside the serving one, validated, and only then takes over reads
+type IndexSwapReport struct {
+	Status           string             `json:"status"`
+	CurrentDocuments int                `json:"current_documents"`
+	RebuiltDocuments int                `json:"rebuilt_documents"`
+	RebuiltChunks    int                `json:"rebuilt_chunks"`
+	CopiedChunks     int                `json:"copied_chunks"` // Chunks of other data sources carried over
+	Comparisons      []SampleComparison `json:"comparisons,omitempty"`
+	MeanOverlap      float64            `json:"mean_overlap"`
+	Reason           string             `json:"reason,omitempty"`  // Why the rebuilt index was rejected
+	DocumentsRemoved int                `json:"documents_removed"` // No longer in the data sources
+	ChunksCollected  int                `json:"chunks_collected"`  // Old chunks removed after the switch
+	SwappedAt        *time.Time         `json:"swapped_at,omitempty"`
+}
+
+// stagedDocument is a rebuilt document whose chunks are only in the shadow index
+type stagedDocument struct {
+	doc    Document
+	chunks []DocumentChunk
+}
+
+// reindexBlueGreen rebuilds the data sources into a shadow index while the
+// current index keeps serving, switches reads once the rebuild validates and
+// then replaces the stored chunks and garbage-collects the old index
+func (p *Pipeline) reindexBlueGreen(ctx context.Context, options IndexOptions, sources []DataSource) (*IndexResult, error) {
+	p.swapMu.Lock()
+	defer p.swapMu.Unlock()
+
+	if p.dualReadRetriever() != nil {
+		return nil, fmt.Errorf("cannot rebuild the index while a re-embedding migration is running")
+	}
+
+	shadow := options.Shadow
+	if shadow == nil {
+		var err error
+		if shadow, err = p.createRetriever(); err != nil {
+			return nil, fmt.Errorf("failed to create shadow index: %w", err)
+		}
+	}
+
+	startTime := time.Now()
+	report := &IndexSwapReport{}
+	result := &IndexResult{
+		DataSourceID: "multiple",
+		IndexType:    "full",
+		StartedAt:    startTime,
+		Swap:         report,
+	}
+	finish := func(status string, err error) (*IndexResult, error) {
+		report.Status = status
+		if status != IndexSwapSwapped {
+			if clearErr := shadow.Clear(context.WithoutCancel(ctx)); clearErr != nil {
+				p.emitError(ctx, "clear_shadow_index", clearErr)
+			}
+		}
+		result.CompletedAt = time.Now()
+		result.TotalTime = result.CompletedAt.Sub(startTime)
+		if result.TotalTime > 0 {
+			result.ProcessingRate = float64(result.DocumentsProcessed) / result.TotalTime.Seconds()
+		}
+		return result, err
+	}
+
+	sourceIDs := make([]string, 0, len(sources))
+	for _, source := range sources {
+		sourceIDs = append(sourceIDs, source.GetID())
+	}
+	current, err := p.scopeDocuments(ctx, sourceIDs)
+	if err != nil {
+		return finish(IndexSwapFailed, err)
+	}
+	for _, doc := range current {
+		if !p.isTombstoned(doc.ID) {
+			report.CurrentDocuments++
+		}
+	}
+
+	// Build the new index beside the serving one
+	indexVersion := IndexVersionFromConfig(p.config.Processing.Embedding).String()
+	var staged []stagedDocument
+	for _, source := range sources {
+		documents, err := source.ListDocuments(ctx)
+		if err != nil {
+			return finish(IndexSwapFailed, fmt.Errorf("failed to list documents of %s: %w", source.GetID(), err))
+		}
+		for _, doc := range p.filterDocuments(documents, options) {
+			select {
+			case <-ctx.Done():
+				return finish(IndexSwapFailed, ctx.Err())
+			default:
+			}
+
+			var chunks []DocumentChunk
+			var generated int
+			projectDoc := withProjectID(doc, options.ProjectID)
+			built := p.guardDocument(ctx, projectDoc, result, func() error {
+				var err error
+				chunks, generated, err = p.buildShadowDocument(ctx, projectDoc, indexVersion)
+				if err != nil {
+					result.DocumentsErrored++
+					result.Errors = append(result.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
+				}
+				return err
+			})
+			if !built {
+				continue
+			}
+			for _, chunk := range chunks {
+				if err := shadow.AddDocument(ctx, chunk); err != nil {
+					return finish(IndexSwapFailed, fmt.Errorf("failed to add chunk %s to shadow index: %w", chunk.ID, err))
+				}
+			}
+			staged = append(staged, stagedDocument{doc: doc, chunks: chunks})
+			result.DocumentsProcessed++
+			result.DocumentsUpdated++
+			result.ChunksCreated += len(chunks)
+			result.EmbeddingsGenerated += generated
+		}
+	}
+	report.RebuiltDocuments = len(staged)
+	report.RebuiltChunks = result.ChunksCreated
+	result.EmbeddingTime = time.Since(startTime)
+
+	// Carry over the rest of the index so both serve the same corpus
+	copied, err := p.copyToShadow(ctx, shadow, sourceIDs)
+	report.CopiedChunks = copied
+	if err != nil {
+		return finish(IndexSwapFailed, err)
+	}
+
+	if reason := p.validateShadow(ctx, shadow, options.Validation, report); reason != "" {
+		report.Reason = reason
+		p.emitEvent(ctx, "index_swap_rejected", map[string]interface{}{
+			"project_id": options.ProjectID,
+			"reason":     reason,
+		})
+		return finish(IndexSwapRejected, fmt.Errorf("rebuilt index rejected: %s", reason))
+	}
+
+	// Switch reads to the new index
+	p.mu.Lock()
+	old := p.retriever
+	p.retriever = shadow
+	p.mu.Unlock()
+	swappedAt := time.Now()
+	report.SwappedAt = &swappedAt
+
+	// Replace the stored chunks and collect the old ones
+	rebuilt := make(map[string]bool, len(staged))
+	for _, document := range staged {
+		rebuilt[document.doc.ID] = true
+	}
+	for _, document := range staged {
+		kept := make(map[string]bool, len(document.chunks))
+		for _, chunk := range document.chunks {
+			kept[chunk.ID] = true
+		}
+		previous, err := p.persistShadowDocument(ctx, document, rebuilt)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Store document %s: %v", document.doc.ID, err))
+		}
+		for _, id := range previous {
+			if !kept[id] {
+				report.ChunksCollected++
+			}
+		}
+	}
+	result.ChunksDeleted = report.ChunksCollected
+
+	for _, doc := range current {
+		if rebuilt[doc.ID] {
+			continue
+		}
+		if err := p.DeleteDocument(ctx, doc.ID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Remove document %s: %v", doc.ID, err))
+			continue
+		}
+		if p.isTombstoned(doc.ID) {
+			if err := p.clearTombstone(ctx, doc.ID); err != nil {
+				p.emitError(ctx, "clear_tombstone", err)
+			}
+		}
+		report.DocumentsRemoved++
+	}
+
+	if err := old.Clear(ctx); err != nil {
+		p.emitError(ctx, "clear_old_index", err)
+	}
+	// Cached answers cite chunks that no longer exist
+	if p.cache != nil {
+		if err := p.cache.Clear(ctx); err != nil {
+			p.emitError(ctx, "clear_cache", err)
+		}
+	}
+
+	p.emitEvent(ctx, "index_swapped", map[string]interface{}{
+		"project_id":   options.ProjectID,
+		"documents":    report.RebuiltDocuments,
+		"chunks":       report.RebuiltChunks,
+		"mean_overlap": report.MeanOverlap,
+	})
+	if p.metrics != nil {
+		p.metrics.RecordDocumentProcessing(ctx, "blue_green_index", time.Since(startTime), result.ChunksCreated)
+	}
+	return finish(IndexSwapSwapped, nil)
+}
+
+// buildShadowDocument chunks and embeds a document without touching storage,
+// the serving index or deduplication state. It returns the chunks and the
+// number of vectors generated.
+func (p *Pipeline) buildShadowDocument(ctx context.Context, doc Document, indexVersion string) ([]DocumentChunk, int, error) {
+	return p.buildDocumentChunks(ctx, doc, indexVersion, nil, nil)
+}
+
+// buildDocumentChunks is buildShadowDocument with an optional chunking
+// strategy and generator in place of the processor's
+func (p *Pipeline) buildDocumentChunks(ctx context.Context, doc Document, indexVersion string, strategy ChunkingStrategy, generator embedding.VectorGenerator) ([]DocumentChunk, int, error) {
+	chunks, err := p.chunkDocument(ctx, &doc, strategy)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(chunks) == 0 {
+		return nil, 0, fmt.Errorf("document has no content to index")
+	}
+	if p.summarizer != nil {
+		derived, err := p.summarizer.SummarizeDocument(ctx, doc, chunks)
+		if err != nil {
+			p.emitError(ctx, "summarize_document", err)
+		}
+		chunks = append(chunks, derived...)
+	}
+
+	var missing []int
+	for i := range chunks {
+		if chunks[i].ContentHash == "" {
+			chunks[i].ContentHash = ContentHash(chunks[i].Content)
+		}
+		chunks[i].DuplicateOf = ""
+		if generator != nil {
+			chunks[i].Embedding = nil
+		}
+		if len(chunks[i].Embedding) == 0 {
+			missing = append(missing, i)
+		}
+	}
+	if generator == nil {
+		generator = p.processor.GetEmbeddingGenerator()
+	}
+	generated, err := p.embedWith(ctx, generator, chunks, missing, indexVersion)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range chunks {
+		if len(chunks[i].Embedding) > 0 && chunks[i].IndexVersion == "" {
+			chunks[i].IndexVersion = indexVersion
+		}
+	}
+	return chunks, generated, nil
+}
+
+// copyToShadow adds the canonical chunks of every stored document outside the
+// rebuilt data sources to the shadow index
+func (p *Pipeline) copyToShadow(ctx context.Context, shadow Retriever, rebuiltSources []string) (int, error) {
+	rebuilt := make(map[string]bool, len(rebuiltSources))
+	for _, id := range rebuiltSources {
+		rebuilt[id] = true
+	}
+
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	copied := 0
+	for _, doc := range documents {
+		if rebuilt[doc.DataSourceID] || p.isTombstoned(doc.ID) {
+			continue
+		}
+		chunks, err := p.storage.ListChunks(ctx, doc.ID)
+		if err != nil {
+			return copied, fmt.Errorf("failed to list chunks of %s: %w", doc.ID, err)
+		}
+		for _, chunk := range chunks {
+			if chunk.DuplicateOf != "" {
+				continue
+			}
+			if len(chunk.Embedding) == 0 {
+				if embedding, err := p.storage.GetEmbedding(ctx, chunk.ID); err == nil {
+					chunk.Embedding = embedding
+				}
+			}
+			if err := shadow.AddDocument(ctx, chunk); err != nil {
+				return copied, fmt.Errorf("failed to copy chunk %s: %w", chunk.ID, err)
+			}
+			copied++
+		}
+	}
+	return copied, nil
+}
+
+// validateShadow checks the rebuilt index against the serving one and
+// returns why it must not replace it, or "" if it may
+func (p *Pipeline) validateShadow(ctx context.Context, shadow Retriever, validation SwapValidation, report *IndexSwapReport) string {
+	if validation.MinDocumentRatio <= 0 {
+		validation.MinDocumentRatio = defaultSwapMinDocumentRatio
+	}
+	if validation.MinOverlap <= 0 {
+		validation.MinOverlap = defaultSwapMinOverlap
+	}
+	if validation.TopK <= 0 {
+		validation.TopK = defaultSwapTopK
+	}
+
+	if report.CurrentDocuments > 0 {
+		ratio := float64(report.RebuiltDocuments) / float64(report.CurrentDocuments)
+		if ratio < validation.MinDocumentRatio {
+			return fmt.Sprintf("rebuilt index has %d documents, %d currently indexed (minimum ratio %.2f)",
+				report.RebuiltDocuments, report.CurrentDocuments, validation.MinDocumentRatio)
+		}
+	}
+
+	options := RetrieveOptions{
+		TopK:                validation.TopK,
+		SimilarityThreshold: p.config.Retrieval.MinScore,
+	}
+	p.mu.RLock()
+	serving := p.retriever
+	p.mu.RUnlock()
+
+	total := 0.0
+	for _, query := range validation.SampleQueries {
+		currentResults, err := serving.Retrieve(ctx, query, options)
+		if err != nil {
+			return fmt.Sprintf("sample query %q failed on the current index: %v", query, err)
+		}
+		rebuiltResults, err := shadow.Retrieve(ctx, query, options)
+		if err != nil {
+			return fmt.Sprintf("sample query %q failed on the rebuilt index: %v", query, err)
+		}
+
+		// Chunk IDs change with the chunking, so documents are compared
+		comparison := SampleComparison{
+			Query:            query,
+			CurrentDocuments: resultDocuments(p.dropTombstoned(currentResults)),
+			RebuiltDocuments: resultDocuments(p.dropTombstoned(rebuiltResults)),
+			Overlap:          1,
+		}
+		if len(comparison.CurrentDocuments) > 0 {
+			found := make(map[string]bool, len(comparison.RebuiltDocuments))
+			for _, id := range comparison.RebuiltDocuments {
+				found[id] = true
+			}
+			shared := 0
+			for _, id := range comparison.CurrentDocuments {
+				if found[id] {
+					shared++
+				}
+			}
+			comparison.Overlap = float64(shared) / float64(len(comparison.CurrentDocuments))
+		}
+		report.Comparisons = append(report.Comparisons, comparison)
+		total += comparison.Overlap
+	}
+	if len(report.Comparisons) == 0 {
+		return ""
+	}
+
+	report.MeanOverlap = total / float64(len(report.Comparisons))
+	if report.MeanOverlap < validation.MinOverlap {
+		return fmt.Sprintf("sample queries share %.0f%% of their top documents with the current index (minimum %.0f%%)",
+			report.MeanOverlap*100, validation.MinOverlap*100)
+	}
+	return ""
+}
+
+// resultDocuments lists the distinct documents of results in rank order
+func resultDocuments(results []RetrievalResult) []string {
+	seen := make(map[string]bool, len(results))
+	documents := make([]string, 0, len(results))
+	for _, result := range results {
+		id := result.DocumentID
+		if id == "" && result.Chunk != nil {
+			id = result.Chunk.DocumentID
+		}
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		documents = append(documents, id)
+	}
+	return documents
+}
+
+// persistShadowDocument replaces a document's stored chunks with its rebuilt
+// ones once the new index serves reads. It returns the IDs of the chunks the
+// document had before.
+func (p *Pipeline) persistShadowDocument(ctx context.Context, document stagedDocument, rebuilt map[string]bool) ([]string, error) {
+	doc := document.doc
+	previous, err := p.storage.ListChunks(ctx, doc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+	ids := make([]string, len(previous))
+	for i, chunk := range previous {
+		ids[i] = chunk.ID
+	}
+
+	// Duplicates in other rebuilt documents are replaced rather than promoted
+	promoted := make(map[string]ChunkReference)
+	if p.dedup != nil {
+		for id, ref := range p.dedup.ReleaseDocument(doc.ID) {
+			if !rebuilt[ref.DocumentID] {
+				promoted[id] = ref
+			}
+		}
+	}
+	newVersion, err := p.assignDocumentVersion(ctx, &doc)
+	if err != nil {
+		p.emitError(ctx, "document_version", err)
+	}
+
+	// Storage drops chunks with their document, so the document is rewritten
+	if err := p.storage.DeleteDocument(ctx, doc.ID); err != nil {
+		return ids, fmt.Errorf("failed to delete document: %w", err)
+	}
+	if err := p.storage.StoreDocument(ctx, doc); err != nil {
+		return ids, fmt.Errorf("failed to store document: %w", err)
+	}
+	if documents, ok := p.cache.(DocumentCache); ok {
+		documents.SetDocument(ctx, &doc, 0)
+	}
+	if p.isTombstoned(doc.ID) {
+		if err := p.clearTombstone(ctx, doc.ID); err != nil {
+			p.emitError(ctx, "clear_tombstone", err)
+		}
+	}
+
+	var firstErr error
+	scope := p.dedupScope(ctx, documentProjectID(doc))
+	for i := range document.chunks {
+		chunk := &document.chunks[i]
+		// Content already indexed elsewhere becomes a duplicate again
+		if p.dedup != nil && p.dedup.Eligible(*chunk) && !p.dedup.Register(scope, *chunk) {
+			if refs := p.dedup.References(chunk.ID); len(refs) > 0 {
+				chunk.DuplicateOf = refs[0].ChunkID
+				if err := p.retriever.RemoveDocument(ctx, chunk.ID); err != nil {
+					p.emitError(ctx, "remove_chunk", err)
+				}
+			}
+		}
+		if err := p.storage.StoreChunk(ctx, *chunk); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to store chunk %s: %w", chunk.ID, err)
+			}
+			continue
+		}
+		if len(chunk.Embedding) > 0 && chunk.DuplicateOf == "" {
+			if err := p.storage.StoreEmbedding(ctx, chunk.ID, chunk.Embedding); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to store embedding %s: %w", chunk.ID, err)
+			}
+		}
+	}
+
+	if newVersion {
+		if err := p.recordDocumentVersion(ctx, doc, document.chunks); err != nil {
+			p.emitError(ctx, "document_version", err)
+		}
+	}
+	p.promoteChunks(ctx, promoted)
+	return ids, firstErr
+}
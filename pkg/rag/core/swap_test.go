@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// keywordRetriever returns the chunks whose content contains the query
+type keywordRetriever struct {
+	chunks []DocumentChunk
+}
+
+func (r *keywordRetriever) Retrieve(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	var results []RetrievalResult
+	for i := range r.chunks {
+		if strings.Contains(r.chunks[i].Content, query) {
+			results = append(results, RetrievalResult{DocumentID: r.chunks[i].DocumentID, Chunk: &r.chunks[i]})
+		}
+	}
+	if len(results) > options.TopK {
+		results = results[:options.TopK]
+	}
+	return results, nil
+}
+
+func (r *keywordRetriever) AddDocument(ctx context.Context, chunk DocumentChunk) error {
+	r.chunks = append(r.chunks, chunk)
+	return nil
+}
+
+func (r *keywordRetriever) RemoveDocument(ctx context.Context, chunkID string) error      { return nil }
+func (r *keywordRetriever) UpdateDocument(ctx context.Context, chunk DocumentChunk) error { return nil }
+func (r *keywordRetriever) Clear(ctx context.Context) error                               { r.chunks = nil; return nil }
+func (r *keywordRetriever) GetStats() (*RetrieverStats, error)                            { return &RetrieverStats{}, nil }
+
+func TestValidateShadow(t *testing.T) {
+	ctx := context.Background()
+	serving := &keywordRetriever{chunks: []DocumentChunk{
+		{ID: "a_0", DocumentID: "a", Content: "refund policy"},
+		{ID: "b_0", DocumentID: "b", Content: "refund timeline"},
+		{ID: "c_0", DocumentID: "c", Content: "sso setup"},
+	}}
+	p := &Pipeline{config: DefaultConfig(), retriever: serving, tombstones: make(map[string]Tombstone)}
+
+	// Rechunked into different chunk IDs, but "b" lost its refund content
+	shadow := &keywordRetriever{chunks: []DocumentChunk{
+		{ID: "a_0_0", DocumentID: "a", Content: "refund policy"},
+		{ID: "b_0_0", DocumentID: "b", Content: "timeline"},
+		{ID: "c_0_0", DocumentID: "c", Content: "sso setup"},
+	}}
+
+	report := &IndexSwapReport{CurrentDocuments: 3, RebuiltDocuments: 3}
+	validation := SwapValidation{SampleQueries: []string{"refund", "sso"}}
+	if reason := p.validateShadow(ctx, shadow, validation, report); reason != "" {
+		t.Fatalf("expected rebuilt index to pass, got %q", reason)
+	}
+	if report.MeanOverlap != 0.75 || len(report.Comparisons) != 2 || report.Comparisons[0].Overlap != 0.5 {
+		t.Fatalf("unexpected comparisons %+v", report)
+	}
+
+	report = &IndexSwapReport{CurrentDocuments: 3, RebuiltDocuments: 3}
+	validation.MinOverlap = 0.8
+	if reason := p.validateShadow(ctx, shadow, validation, report); !strings.Contains(reason, "75%") {
+		t.Fatalf("expected overlap rejection, got %q", reason)
+	}
+
+	// Too few documents rebuilt
+	report = &IndexSwapReport{CurrentDocuments: 10, RebuiltDocuments: 8}
+	if reason := p.validateShadow(ctx, shadow, SwapValidation{}, report); !strings.Contains(reason, "8 documents") {
+		t.Fatalf("expected document count rejection, got %q", reason)
+	}
+}
+
+func TestResultDocuments(t *testing.T) {
+	results := []RetrievalResult{
+		{DocumentID: "a"},
+		{Chunk: &DocumentChunk{DocumentID: "b"}},
+		{DocumentID: "a"},
+	}
+	if got := resultDocuments(results); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("unexpected documents %v", got)
+	}
+}
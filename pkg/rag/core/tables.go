@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ChunkTypeTable marks chunks that hold a single structured table
+const ChunkTypeTable = "table"
+
+// TableData represents a table preserved in structured form
+type TableData struct {
+	Caption string     `json:"caption,omitempty"`
+	Headers []string   `json:"headers"`
+	Rows    [][]string `json:"rows"`
+	Format  string     `json:"format,omitempty"` // Source format: markdown, html, pdf, etc.
+}
+
+// ColumnCount returns the number of columns in the table
+func (t *TableData) ColumnCount() int {
+	count := len(t.Headers)
+	for _, row := range t.Rows {
+		if len(row) > count {
+			count = len(row)
+		}
+	}
+	return count
+}
+
+// Render renders the table as a Markdown pipe table. The output is stable:
+// cells are trimmed, pipes are escaped and every row has the same column count.
+func (t *TableData) Render() string {
+	columns := t.ColumnCount()
+	if columns == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if t.Caption != "" {
+		b.WriteString(t.Caption)
+		b.WriteString("\n\n")
+	}
+
+	headers := t.Headers
+	if len(headers) == 0 {
+		headers = make([]string, columns)
+		for i := range headers {
+			headers[i] = fmt.Sprintf("Column %d", i+1)
+		}
+	}
+
+	writeTableRow(&b, headers, columns)
+	separators := make([]string, columns)
+	for i := range separators {
+		separators[i] = "---"
+	}
+	writeTableRow(&b, separators, columns)
+	for _, row := range t.Rows {
+		writeTableRow(&b, row, columns)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeTableRow(b *strings.Builder, cells []string, columns int) {
+	b.WriteString("|")
+	for i := 0; i < columns; i++ {
+		cell := ""
+		if i < len(cells) {
+			cell = strings.ReplaceAll(strings.TrimSpace(cells[i]), "|", "\\|")
+			cell = strings.ReplaceAll(cell, "\n", " ")
+		}
+		b.WriteString(" ")
+		b.WriteString(cell)
+		b.WriteString(" |")
+	}
+	b.WriteString("\n")
+}
+
+// RenderTablesForPrompt renders the tables among retrieval results in result
+// order, each labelled with its source so the model can cite it. It returns
+// an empty string when no table chunks were retrieved.
+func RenderTablesForPrompt(results []RetrievalResult) string {
+	var b strings.Builder
+	n := 0
+	for _, result := range results {
+		if result.Chunk == nil || result.Chunk.Table == nil {
+			continue
+		}
+		n++
+		source := result.DocumentID
+		if result.Document != nil && result.Document.Title != "" {
+			source = result.Document.Title
+		}
+		fmt.Fprintf(&b, "Table %d (source: %s, chunk: %s)\n", n, source, result.Chunk.ID)
+		b.WriteString(result.Chunk.Table.Render())
+		b.WriteString("\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ChunkTypeFilter keeps only results whose chunk type is listed in
+// FilterCriteria.ChunkTypes. It is a no-op when no chunk types are given.
+type ChunkTypeFilter struct{}
+
+// NewChunkTypeFilter creates a new chunk type filter
+func NewChunkTypeFilter() *ChunkTypeFilter {
+	return &ChunkTypeFilter{}
+}
+
+// Filter implements the Filter interface
+func (f *ChunkTypeFilter) Filter(ctx context.Context, results []RetrievalResult, criteria FilterCriteria) ([]RetrievalResult, error) {
+	if len(criteria.ChunkTypes) == 0 {
+		return results, nil
+	}
+
+	allowed := make(map[string]bool, len(criteria.ChunkTypes))
+	for _, chunkType := range criteria.ChunkTypes {
+		allowed[chunkType] = true
+	}
+
+	filtered := make([]RetrievalResult, 0, len(results))
+	for _, result := range results {
+		if result.Chunk != nil && allowed[result.Chunk.ChunkType] {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered, nil
+}
+
+// GetName returns the filter name
+func (f *ChunkTypeFilter) GetName() string {
+	return "chunk_type"
+}
+
+// GetDescription returns the filter description
+func (f *ChunkTypeFilter) GetDescription() string {
+	return "Filters results by chunk type (e.g. table, summary)"
+}
+
+// Validate checks if the filter is valid
+func (f *ChunkTypeFilter) Validate() error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTableDataRender(t *testing.T) {
+	table := &TableData{
+		Caption: "Prices",
+		Headers: []string{"Plan", "Price"},
+		Rows:    [][]string{{" Free ", "0"}, {"Pro | Team", "10", "monthly"}},
+	}
+	want := "Prices\n\n" +
+		"| Plan | Price |  |\n" +
+		"| --- | --- | --- |\n" +
+		"| Free | 0 |  |\n" +
+		"| Pro \\| Team | 10 | monthly |"
+	if got := table.Render(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+
+	headless := &TableData{Rows: [][]string{{"a", "b"}}}
+	if got := headless.Render(); !strings.HasPrefix(got, "| Column 1 | Column 2 |") {
+		t.Fatalf("expected generated headers, got %q", got)
+	}
+	if got := (&TableData{}).Render(); got != "" {
+		t.Fatalf("expected an empty table to render nothing, got %q", got)
+	}
+}
+
+func TestRenderTablesForPrompt(t *testing.T) {
+	table := &TableData{Headers: []string{"k"}, Rows: [][]string{{"v"}}}
+	results := []RetrievalResult{
+		{DocumentID: "doc1", Chunk: &DocumentChunk{ID: "c1", Content: "prose"}},
+		{DocumentID: "doc1", Document: &Document{Title: "Pricing"}, Chunk: &DocumentChunk{ID: "c2", Table: table}},
+		{DocumentID: "doc2", Chunk: &DocumentChunk{ID: "c3", Table: table}},
+	}
+	got := RenderTablesForPrompt(results)
+	want := "Table 1 (source: Pricing, chunk: c2)\n| k |\n| --- |\n| v |\n\n" +
+		"Table 2 (source: doc2, chunk: c3)\n| k |\n| --- |\n| v |"
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if RenderTablesForPrompt(results[:1]) != "" {
+		t.Fatalf("expected no output without table chunks")
+	}
+}
+
+func TestChunkTypeFilter(t *testing.T) {
+	results := []RetrievalResult{
+		{Chunk: &DocumentChunk{ID: "text", ChunkType: "text"}},
+		{Chunk: &DocumentChunk{ID: "table", ChunkType: ChunkTypeTable}},
+		{DocumentID: "no chunk"},
+	}
+	filter := NewChunkTypeFilter()
+
+	all, err := filter.Filter(context.Background(), results, FilterCriteria{})
+	if err != nil || len(all) != 3 {
+		t.Fatalf("expected no filtering without chunk types, got %d %v", len(all), err)
+	}
+	tables, err := filter.Filter(context.Background(), results, FilterCriteria{ChunkTypes: []string{ChunkTypeTable}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].Chunk.ID != "table" {
+		t.Fatalf("expected only the table chunk, got %+v", tables)
+	}
+}
@@ -0,0 +1,541 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ColdStore keeps embeddings moved out of the retriever index, typically
+// compressed on disk or in object storage
+type ColdStore interface {
+	// PutVectors stores vectors by chunk ID and returns the bytes written
+	PutVectors(ctx context.Context, vectors map[string][]float64) (int64, error)
+
+	// GetVectors returns the stored vectors of the given chunks
+	GetVectors(ctx context.Context, chunkIDs []string) (map[string][]float64, error)
+
+	// DeleteVectors removes the vectors of the given chunks
+	DeleteVectors(ctx context.Context, chunkIDs []string) error
+}
+
+// TierStore persists which documents are cold and which are pinned hot
+type TierStore interface {
+	// SaveColdDocument records a document whose embeddings are in cold storage
+	SaveColdDocument(ctx context.Context, doc *ColdDocument) error
+
+	// DeleteColdDocument removes a document's cold record after rehydration
+	DeleteColdDocument(ctx context.Context, documentID string) error
+
+	// ListColdDocuments returns all cold records
+	ListColdDocuments(ctx context.Context) ([]ColdDocument, error)
+
+	// SavePin records a document that is never moved to cold storage
+	SavePin(ctx context.Context, pin *DocumentPin) error
+
+	// DeletePin removes a document's pin
+	DeletePin(ctx context.Context, documentID string) error
+
+	// ListPins returns all pins
+	ListPins(ctx context.Context) ([]DocumentPin, error)
+}
+
+// ColdDocument is a document whose embeddings were moved to cold storage
+type ColdDocument struct {
+	DocumentID      string     `json:"document_id"`
+	ProjectID       string     `json:"project_id,omitempty"`
+	DataSourceID    string     `json:"data_source_id,omitempty"`
+	ChunkIDs        []string   `json:"chunk_ids"`
+	Bytes           int64      `json:"bytes"`            // Uncompressed vector size
+	StoredBytes     int64      `json:"stored_bytes"`     // Size in the cold store
+	StorageReleased bool       `json:"storage_released"` // Vectors were removed from primary storage
+	LastRetrievedAt *time.Time `json:"last_retrieved_at,omitempty"`
+	MovedAt         time.Time  `json:"moved_at"`
+}
+
+// DocumentPin keeps a document's embeddings in the index regardless of use
+type DocumentPin struct {
+	DocumentID string    `json:"document_id"`
+	ProjectID  string    `json:"project_id,omitempty"`
+	PinnedBy   string    `json:"pinned_by,omitempty"`
+	PinnedAt   time.Time `json:"pinned_at"`
+}
+
+// TieringOptions selects the documents TierColdEmbeddings moves to cold storage
+type TieringOptions struct {
+	// Documents ingested for the project, or belonging to one of its data sources
+	ProjectID     string   `json:"project_id"`
+	DataSourceIDs []string `json:"data_source_ids,omitempty"`
+
+	// Documents not retrieved for this long go cold, the configured
+	// storage cold_after when zero
+	ColdAfter time.Duration `json:"cold_after"`
+
+	// Last time each document was retrieved, by document ID; never retrieved
+	// documents count from when they were indexed
+	LastRetrieved map[string]time.Time `json:"-"`
+
+	// Now is the reference time for ages, the current time when zero
+	Now time.Time `json:"-"`
+}
+
+// TieringResult reports what a tiering run moved to cold storage
+type TieringResult struct {
+	DocumentsMoved int           `json:"documents_moved"`
+	ChunksMoved    int           `json:"chunks_moved"`
+	BytesMoved     int64         `json:"bytes_moved"`
+	StoredBytes    int64         `json:"stored_bytes"`
+	Errors         []string      `json:"errors,omitempty"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// TieringStats describes the hot and cold tiers of a project, or of all
+// documents when no project is given
+type TieringStats struct {
+	ColdDocuments   int        `json:"cold_documents"`
+	ColdChunks      int        `json:"cold_chunks"`
+	ColdBytes       int64      `json:"cold_bytes"`        // Uncompressed vector size
+	ColdStoredBytes int64      `json:"cold_stored_bytes"` // Size in the cold store
+	PinnedDocuments int        `json:"pinned_documents"`
+	Rehydrations    int64      `json:"rehydrations"` // Since the pipeline started, all projects
+	LastRun         *time.Time `json:"last_run,omitempty"`
+}
+
+// tieringState tracks cold documents, pins and rehydration
+type tieringState struct {
+	mu           sync.Mutex
+	coldStore    ColdStore
+	store        TierStore
+	cold         map[string]ColdDocument
+	pins         map[string]DocumentPin
+	rehydrating  map[string]bool
+	rehydrations int64
+	lastRun      time.Time
+}
+
+// SetColdStore enables the cold tier: cold embeddings are kept in cold and
+// tier state is persisted in store and loaded now
+func (p *Pipeline) SetColdStore(ctx context.Context, cold ColdStore, store TierStore) error {
+	p.tiering.mu.Lock()
+	p.tiering.coldStore = cold
+	p.tiering.store = store
+	p.tiering.mu.Unlock()
+	return p.RefreshTiers(ctx)
+}
+
+// RefreshTiers reloads cold records and pins from the tier store so moves
+// made by other instances are rehydrated here too
+func (p *Pipeline) RefreshTiers(ctx context.Context) error {
+	p.tiering.mu.Lock()
+	store := p.tiering.store
+	p.tiering.mu.Unlock()
+	if store == nil {
+		return nil
+	}
+
+	coldDocs, err := store.ListColdDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load cold documents: %w", err)
+	}
+	pins, err := store.ListPins(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load pins: %w", err)
+	}
+
+	cold := make(map[string]ColdDocument, len(coldDocs))
+	for _, doc := range coldDocs {
+		cold[doc.DocumentID] = doc
+	}
+	pinned := make(map[string]DocumentPin, len(pins))
+	for _, pin := range pins {
+		pinned[pin.DocumentID] = pin
+	}
+
+	p.tiering.mu.Lock()
+	p.tiering.cold = cold
+	p.tiering.pins = pinned
+	p.tiering.mu.Unlock()
+	return nil
+}
+
+// TierColdEmbeddings moves the embeddings of documents not retrieved for a
+// while out of the retriever index into the cold store. Pinned and
+// soft-deleted documents are skipped. Cold documents remain findable by
+// keyword search, and are rehydrated into the index when retrieved.
+func (p *Pipeline) TierColdEmbeddings(ctx context.Context, options TieringOptions) (*TieringResult, error) {
+	p.tiering.mu.Lock()
+	cold := p.tiering.coldStore
+	p.tiering.mu.Unlock()
+	if cold == nil {
+		return nil, fmt.Errorf("cold storage is not configured")
+	}
+	if options.ColdAfter <= 0 {
+		options.ColdAfter = p.config.Storage.ColdAfter
+	}
+	if options.ColdAfter <= 0 {
+		return nil, fmt.Errorf("cold_after must be positive")
+	}
+	if options.Now.IsZero() {
+		options.Now = time.Now()
+	}
+
+	var result *TieringResult
+	err := WithLock(ctx, p.Locker(), LockKeyMaintenance, p.LockTTL(), func(ctx context.Context) error {
+		var err error
+		result, err = p.tierColdEmbeddings(ctx, cold, options)
+		return err
+	})
+	if errors.Is(err, ErrLockHeld) {
+		return nil, fmt.Errorf("storage maintenance already running on another instance")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.tiering.mu.Lock()
+	p.tiering.lastRun = options.Now
+	p.tiering.mu.Unlock()
+	if result.DocumentsMoved > 0 {
+		p.emitEvent(ctx, "embeddings_tiered", map[string]interface{}{
+			"project_id": options.ProjectID,
+			"documents":  result.DocumentsMoved,
+			"chunks":     result.ChunksMoved,
+			"bytes":      result.BytesMoved,
+		})
+	}
+	return result, nil
+}
+
+func (p *Pipeline) tierColdEmbeddings(ctx context.Context, cold ColdStore, options TieringOptions) (*TieringResult, error) {
+	start := time.Now()
+	documents, err := p.storage.ListDocuments(ctx, ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	sort.Slice(documents, func(i, j int) bool { return documents[i].ID < documents[j].ID })
+
+	result := &TieringResult{}
+	for _, doc := range documents {
+		if documentProjectID(doc) != options.ProjectID && !slices.Contains(options.DataSourceIDs, doc.DataSourceID) {
+			continue
+		}
+		if p.isTombstoned(doc.ID) || p.isPinned(doc.ID) || p.isCold(doc.ID) {
+			continue
+		}
+		lastUsed := doc.ProcessedAt
+		retrieved, wasRetrieved := options.LastRetrieved[doc.ID]
+		if wasRetrieved {
+			lastUsed = retrieved
+		}
+		if lastUsed.IsZero() || options.Now.Sub(lastUsed) < options.ColdAfter {
+			continue
+		}
+
+		record, err := p.moveToCold(ctx, cold, doc, options.ProjectID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
+			continue
+		}
+		if record == nil {
+			continue
+		}
+		if wasRetrieved {
+			record.LastRetrievedAt = &retrieved
+		}
+		if err := p.saveColdDocument(ctx, record); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Document %s: %v", doc.ID, err))
+			continue
+		}
+		result.DocumentsMoved++
+		result.ChunksMoved += len(record.ChunkIDs)
+		result.BytesMoved += record.Bytes
+		result.StoredBytes += record.StoredBytes
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// moveToCold copies a document's vectors to the cold store, then removes them
+// from the index and, when the backend allows it, from primary storage. It
+// returns nil when the document has no embeddings.
+func (p *Pipeline) moveToCold(ctx context.Context, cold ColdStore, doc Document, projectID string) (*ColdDocument, error) {
+	chunks, err := p.storage.ListChunks(ctx, doc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+	vectors := make(map[string][]float64, len(chunks))
+	for _, chunk := range chunks {
+		// Duplicates share the canonical chunk's index entry
+		if chunk.DuplicateOf != "" {
+			continue
+		}
+		vector := chunk.Embedding
+		if len(vector) == 0 {
+			if stored, err := p.storage.GetEmbedding(ctx, chunk.ID); err == nil {
+				vector = stored
+			}
+		}
+		if len(vector) > 0 {
+			vectors[chunk.ID] = vector
+		}
+	}
+	if len(vectors) == 0 {
+		return nil, nil
+	}
+
+	record := &ColdDocument{
+		DocumentID:   doc.ID,
+		ProjectID:    projectID,
+		DataSourceID: doc.DataSourceID,
+		ChunkIDs:     sortedKeys(vectors),
+		MovedAt:      time.Now(),
+	}
+	for _, vector := range vectors {
+		record.Bytes += int64(len(vector) * bytesPerDimension)
+	}
+	record.StoredBytes, err = cold.PutVectors(ctx, vectors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store cold vectors: %w", err)
+	}
+
+	for _, chunkID := range record.ChunkIDs {
+		if err := p.retriever.RemoveDocument(ctx, chunkID); err != nil {
+			p.emitError(ctx, "tier_chunk", err)
+		}
+	}
+	if scanner, ok := p.storage.(StorageScanner); ok {
+		record.StorageReleased = true
+		for _, chunkID := range record.ChunkIDs {
+			if err := scanner.DeleteEmbedding(ctx, chunkID); err != nil {
+				record.StorageReleased = false
+				p.emitError(ctx, "tier_release_embedding", err)
+			}
+		}
+	}
+	return record, nil
+}
+
+// RehydrateDocument moves a cold document's embeddings back into the index
+func (p *Pipeline) RehydrateDocument(ctx context.Context, documentID string) error {
+	p.tiering.mu.Lock()
+	record, ok := p.tiering.cold[documentID]
+	cold := p.tiering.coldStore
+	if !ok || cold == nil || p.tiering.rehydrating[documentID] {
+		p.tiering.mu.Unlock()
+		return nil
+	}
+	if p.tiering.rehydrating == nil {
+		p.tiering.rehydrating = make(map[string]bool)
+	}
+	p.tiering.rehydrating[documentID] = true
+	p.tiering.mu.Unlock()
+	defer func() {
+		p.tiering.mu.Lock()
+		delete(p.tiering.rehydrating, documentID)
+		p.tiering.mu.Unlock()
+	}()
+
+	vectors, err := cold.GetVectors(ctx, record.ChunkIDs)
+	if err != nil {
+		return fmt.Errorf("failed to read cold vectors: %w", err)
+	}
+	chunks, err := p.storage.ListChunks(ctx, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to list chunks: %w", err)
+	}
+	for _, chunk := range chunks {
+		vector, ok := vectors[chunk.ID]
+		if !ok {
+			continue
+		}
+		if record.StorageReleased {
+			if err := p.storage.StoreEmbedding(ctx, chunk.ID, vector); err != nil {
+				return fmt.Errorf("failed to restore embedding of %s: %w", chunk.ID, err)
+			}
+		}
+		chunk.Embedding = vector
+		if err := p.retriever.AddDocument(ctx, chunk); err != nil {
+			return fmt.Errorf("failed to index chunk %s: %w", chunk.ID, err)
+		}
+	}
+
+	p.tiering.mu.Lock()
+	delete(p.tiering.cold, documentID)
+	p.tiering.rehydrations++
+	store := p.tiering.store
+	p.tiering.mu.Unlock()
+	if store != nil {
+		if err := store.DeleteColdDocument(ctx, documentID); err != nil {
+			return fmt.Errorf("failed to delete cold record: %w", err)
+		}
+	}
+	if err := cold.DeleteVectors(ctx, record.ChunkIDs); err != nil {
+		p.emitError(ctx, "delete_cold_vectors", err)
+	}
+
+	p.emitEvent(ctx, "document_rehydrated", map[string]interface{}{
+		"document_id": documentID,
+		"chunks":      len(record.ChunkIDs),
+	})
+	return nil
+}
+
+// rehydrateRetrieved rehydrates cold documents found by a query in the
+// background, so later vector searches find them again
+func (p *Pipeline) rehydrateRetrieved(results []RetrievalResult) {
+	var documentIDs []string
+	for _, result := range results {
+		if p.isCold(result.DocumentID) && !slices.Contains(documentIDs, result.DocumentID) {
+			documentIDs = append(documentIDs, result.DocumentID)
+		}
+	}
+	if len(documentIDs) == 0 {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		for _, documentID := range documentIDs {
+			if err := p.RehydrateDocument(ctx, documentID); err != nil {
+				p.emitError(ctx, "rehydrate_document", err)
+			}
+		}
+	}()
+}
+
+// PinDocument keeps a document hot: it is rehydrated if cold and never moved
+// to cold storage until unpinned
+func (p *Pipeline) PinDocument(ctx context.Context, pin DocumentPin) error {
+	if pin.PinnedAt.IsZero() {
+		pin.PinnedAt = time.Now()
+	}
+	p.tiering.mu.Lock()
+	if p.tiering.pins == nil {
+		p.tiering.pins = make(map[string]DocumentPin)
+	}
+	p.tiering.pins[pin.DocumentID] = pin
+	store := p.tiering.store
+	p.tiering.mu.Unlock()
+	if store != nil {
+		if err := store.SavePin(ctx, &pin); err != nil {
+			return fmt.Errorf("failed to save pin: %w", err)
+		}
+	}
+	return p.RehydrateDocument(ctx, pin.DocumentID)
+}
+
+// UnpinDocument lets a pinned document go cold again when unused
+func (p *Pipeline) UnpinDocument(ctx context.Context, documentID string) error {
+	p.tiering.mu.Lock()
+	delete(p.tiering.pins, documentID)
+	store := p.tiering.store
+	p.tiering.mu.Unlock()
+	if store != nil {
+		if err := store.DeletePin(ctx, documentID); err != nil {
+			return fmt.Errorf("failed to delete pin: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListPinnedDocuments returns the pins of a project, or all pins when projectID is empty
+func (p *Pipeline) ListPinnedDocuments(projectID string) []DocumentPin {
+	p.tiering.mu.Lock()
+	defer p.tiering.mu.Unlock()
+
+	pins := make([]DocumentPin, 0, len(p.tiering.pins))
+	for _, pin := range p.tiering.pins {
+		if projectID == "" || pin.ProjectID == projectID {
+			pins = append(pins, pin)
+		}
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].DocumentID < pins[j].DocumentID })
+	return pins
+}
+
+// TieringStats returns the size of the cold tier and the pinned documents of
+// a project, or of all documents when projectID is empty
+func (p *Pipeline) TieringStats(projectID string) *TieringStats {
+	p.tiering.mu.Lock()
+	defer p.tiering.mu.Unlock()
+
+	stats := &TieringStats{Rehydrations: p.tiering.rehydrations}
+	for _, doc := range p.tiering.cold {
+		if projectID != "" && doc.ProjectID != projectID {
+			continue
+		}
+		stats.ColdDocuments++
+		stats.ColdChunks += len(doc.ChunkIDs)
+		stats.ColdBytes += doc.Bytes
+		stats.ColdStoredBytes += doc.StoredBytes
+	}
+	for _, pin := range p.tiering.pins {
+		if projectID == "" || pin.ProjectID == projectID {
+			stats.PinnedDocuments++
+		}
+	}
+	if !p.tiering.lastRun.IsZero() {
+		lastRun := p.tiering.lastRun
+		stats.LastRun = &lastRun
+	}
+	return stats
+}
+
+func (p *Pipeline) saveColdDocument(ctx context.Context, record *ColdDocument) error {
+	p.tiering.mu.Lock()
+	if p.tiering.cold == nil {
+		p.tiering.cold = make(map[string]ColdDocument)
+	}
+	p.tiering.cold[record.DocumentID] = *record
+	store := p.tiering.store
+	p.tiering.mu.Unlock()
+	if store != nil {
+		if err := store.SaveColdDocument(ctx, record); err != nil {
+			return fmt.Errorf("failed to save cold record: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropCold discards the cold vectors and record of a deleted document
+func (p *Pipeline) dropCold(ctx context.Context, documentID string) {
+	p.tiering.mu.Lock()
+	record, ok := p.tiering.cold[documentID]
+	delete(p.tiering.cold, documentID)
+	delete(p.tiering.pins, documentID)
+	cold, store := p.tiering.coldStore, p.tiering.store
+	p.tiering.mu.Unlock()
+	if store != nil {
+		if err := store.DeletePin(ctx, documentID); err != nil {
+			p.emitError(ctx, "delete_pin", err)
+		}
+	}
+	if !ok || cold == nil {
+		return
+	}
+	if err := cold.DeleteVectors(ctx, record.ChunkIDs); err != nil {
+		p.emitError(ctx, "delete_cold_vectors", err)
+	}
+	if store != nil {
+		if err := store.DeleteColdDocument(ctx, documentID); err != nil {
+			p.emitError(ctx, "delete_cold_record", err)
+		}
+	}
+}
+
+func (p *Pipeline) isCold(documentID string) bool {
+	p.tiering.mu.Lock()
+	defer p.tiering.mu.Unlock()
+	_, cold := p.tiering.cold[documentID]
+	return cold
+}
+
+func (p *Pipeline) isPinned(documentID string) bool {
+	p.tiering.mu.Lock()
+	defer p.tiering.mu.Unlock()
+	_, pinned := p.tiering.pins[documentID]
+	return pinned
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFileColdStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileColdStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := store.PutVectors(ctx, map[string][]float64{"a/0": {0.25, -1, 3.5}, "b": make([]float64, 256)})
+	if err != nil || written == 0 || written >= 256*bytesPerDimension {
+		t.Fatalf("expected compressed vectors, wrote %d bytes: %v", written, err)
+	}
+	vectors, err := store.GetVectors(ctx, []string{"a/0", "missing"})
+	if err != nil || len(vectors) != 1 {
+		t.Fatalf("unexpected vectors %v %v", vectors, err)
+	}
+	for i, want := range []float64{0.25, -1, 3.5} {
+		if math.Abs(vectors["a/0"][i]-want) > 1e-6 {
+			t.Fatalf("vector not restored: %v", vectors["a/0"])
+		}
+	}
+	if err := store.DeleteVectors(ctx, []string{"a/0", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	if vectors, _ := store.GetVectors(ctx, []string{"a/0"}); len(vectors) != 0 {
+		t.Fatal("expected the vector to be deleted")
+	}
+}
+
+func TestTierColdEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	doc := func(id string, processed time.Time) Document {
+		return Document{ID: id, ProcessedAt: processed, Metadata: DocumentMetadata{Custom: map[string]interface{}{"project_id": "p1"}}}
+	}
+	storage := &usageStorage{
+		listStorage: listStorage{documents: []Document{
+			doc("old", now.AddDate(0, 0, -60)),
+			doc("used", now.AddDate(0, 0, -60)),
+			doc("pinned", now.AddDate(0, 0, -60)),
+			doc("new", now.AddDate(0, 0, -1)),
+		}},
+		chunks: map[string][]DocumentChunk{
+			"old": {
+				{ID: "old_0", DocumentID: "old", Content: "archived report", Embedding: []float64{1, 2}},
+				{ID: "old_1", DocumentID: "old", Content: "copy", Embedding: []float64{3, 4}, DuplicateOf: "x"},
+			},
+			"used":   {{ID: "used_0", DocumentID: "used", Embedding: []float64{1, 2}}},
+			"pinned": {{ID: "pinned_0", DocumentID: "pinned", Embedding: []float64{1, 2}}},
+			"new":    {{ID: "new_0", DocumentID: "new", Embedding: []float64{1, 2}}},
+		},
+	}
+	retriever := &indexedRetriever{ids: map[string]bool{"old_0": true, "used_0": true, "pinned_0": true, "new_0": true}}
+	p := &Pipeline{config: DefaultConfig(), storage: storage, retriever: retriever, locker: NewLocalLocker()}
+
+	if _, err := p.TierColdEmbeddings(ctx, TieringOptions{ProjectID: "p1", ColdAfter: time.Hour}); err == nil {
+		t.Fatal("expected tiering to require a cold store")
+	}
+	cold, _ := NewFileColdStore(t.TempDir())
+	if err := p.SetColdStore(ctx, cold, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PinDocument(ctx, DocumentPin{DocumentID: "pinned", ProjectID: "p1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.TierColdEmbeddings(ctx, TieringOptions{
+		ProjectID:     "p1",
+		ColdAfter:     30 * 24 * time.Hour,
+		LastRetrieved: map[string]time.Time{"used": now.AddDate(0, 0, -2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DocumentsMoved != 1 || result.ChunksMoved != 1 || len(result.Errors) != 0 {
+		t.Fatalf("expected only the unused document to go cold, got %+v", result)
+	}
+	if retriever.ids["old_0"] || !retriever.ids["used_0"] || !retriever.ids["pinned_0"] {
+		t.Fatalf("unexpected index after tiering %v", retriever.ids)
+	}
+	stats := p.TieringStats("p1")
+	if stats.ColdDocuments != 1 || stats.ColdBytes != 2*bytesPerDimension || stats.PinnedDocuments != 1 || stats.LastRun == nil {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// Retrieving a cold document brings its embeddings back into the index
+	if err := p.RehydrateDocument(ctx, "old"); err != nil {
+		t.Fatal(err)
+	}
+	if p.isCold("old") || len(retriever.chunks) != 1 || len(retriever.chunks[0].Embedding) != 2 {
+		t.Fatalf("expected old to be rehydrated, got %+v", retriever.chunks)
+	}
+	if stats := p.TieringStats(""); stats.ColdDocuments != 0 || stats.Rehydrations != 1 {
+		t.Fatalf("unexpected stats after rehydration %+v", stats)
+	}
+}
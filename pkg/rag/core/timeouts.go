@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Query stages with their own time budget
+const (
+	StageRetrieval  = "retrieval"
+	StageRerank     = "rerank"
+	StageGeneration = "generation"
+)
+
+// StageTimeouts are the time budgets of query stages. A zero budget bounds
+// the stage only by the query's overall max_query_time.
+type StageTimeouts struct {
+	Retrieval  time.Duration `json:"retrieval,omitempty"`
+	Rerank     time.Duration `json:"rerank,omitempty"`
+	Generation time.Duration `json:"generation,omitempty"`
+}
+
+// budget returns the time budget of a stage
+func (st StageTimeouts) budget(stage string) time.Duration {
+	switch stage {
+	case StageRetrieval:
+		return st.Retrieval
+	case StageRerank:
+		return st.Rerank
+	case StageGeneration:
+		return st.Generation
+	}
+	return 0
+}
+
+// withDefaults fills unset budgets from defaults
+func (st StageTimeouts) withDefaults(defaults StageTimeouts) StageTimeouts {
+	if st.Retrieval == 0 {
+		st.Retrieval = defaults.Retrieval
+	}
+	if st.Rerank == 0 {
+		st.Rerank = defaults.Rerank
+	}
+	if st.Generation == 0 {
+		st.Generation = defaults.Generation
+	}
+	return st
+}
+
+// Validate checks no budget is negative
+func (st StageTimeouts) Validate() error {
+	if st.Retrieval < 0 || st.Rerank < 0 || st.Generation < 0 {
+		return fmt.Errorf("stage timeouts cannot be negative")
+	}
+	return nil
+}
+
+// StageTimeoutError reports a query stage that ran out of time
+type StageTimeoutError struct {
+	Stage  string
+	Budget time.Duration
+}
+
+func (e *StageTimeoutError) Error() string {
+	return fmt.Sprintf("%s stage exceeded its %s budget", e.Stage, e.Budget)
+}
+
+// TimeoutRecorder is implemented by metrics collectors that count query
+// stage timeouts
+type TimeoutRecorder interface {
+	RecordTimeout(ctx context.Context, queryID, stage string)
+}
+
+// stageContext derives the context of a query stage from the query context,
+// bounded by the stage's budget
+func stageContext(ctx context.Context, timeouts StageTimeouts, stage string) (context.Context, context.CancelFunc) {
+	if budget := timeouts.budget(stage); budget > 0 {
+		return context.WithTimeout(ctx, budget)
+	}
+	return context.WithCancel(ctx)
+}
+
+// stageTimedOut reports whether err is the stage running out of time, as
+// opposed to the caller cancelling the query, and wraps it as a
+// StageTimeoutError
+func stageTimedOut(caller, stageCtx context.Context, stage string, timeouts StageTimeouts, err error) (*StageTimeoutError, bool) {
+	if err == nil || caller.Err() != nil || !errors.Is(stageCtx.Err(), context.DeadlineExceeded) {
+		return nil, false
+	}
+	return &StageTimeoutError{Stage: stage, Budget: timeouts.budget(stage)}, true
+}
+
+// recordTimeout marks the result degraded and counts the stage timeout
+func (p *Pipeline) recordTimeout(ctx context.Context, queryID string, result *QueryResult, timeout *StageTimeoutError) {
+	result.Degraded = true
+	result.TimedOutStages = append(result.TimedOutStages, timeout.Stage)
+
+	p.mu.Lock()
+	if p.stageTimeouts == nil {
+		p.stageTimeouts = make(map[string]int64)
+	}
+	p.stageTimeouts[timeout.Stage]++
+	p.mu.Unlock()
+
+	if recorder, ok := p.metrics.(TimeoutRecorder); ok {
+		recorder.RecordTimeout(ctx, queryID, timeout.Stage)
+	}
+	p.emitEvent(ctx, "query_stage_timeout", map[string]interface{}{
+		"query_id": queryID,
+		"stage":    timeout.Stage,
+		"budget":   timeout.Budget,
+	})
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// slowRetriever blocks until its context is done
+type slowRetriever struct{ keywordRetriever }
+
+func (r *slowRetriever) Retrieve(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// slowRanker blocks until its context is done
+type slowRanker struct{ Ranker }
+
+func (r *slowRanker) Rank(ctx context.Context, query string, results []RetrievalResult) ([]RetrievalResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// stageGenerator answers immediately unless slow, then blocks until its
+// context is done
+type stageGenerator struct {
+	Generator
+	slow bool
+}
+
+func (g *stageGenerator) Generate(ctx context.Context, query string, context []RetrievalResult, options GenerateOptions) (*GenerationResult, error) {
+	if g.slow {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &GenerationResult{Response: "answer"}, nil
+}
+
+func TestQueryStageTimeouts(t *testing.T) {
+	ctx := context.Background()
+	newPipeline := func(retriever Retriever, rankers []Ranker, generator Generator) *Pipeline {
+		config := DefaultConfig()
+		config.Retrieval.StageTimeouts = StageTimeouts{Retrieval: 20 * time.Millisecond, Rerank: 20 * time.Millisecond, Generation: 20 * time.Millisecond}
+		return &Pipeline{
+			config:        config,
+			retriever:     retriever,
+			rankers:       rankers,
+			generator:     generator,
+			started:       true,
+			activeQueries: make(map[string]*QueryContext),
+		}
+	}
+	chunks := []DocumentChunk{{ID: "a_0", DocumentID: "a", Content: "timeout handling"}}
+	options := QueryOptions{EnableRerank: true}
+
+	// A slow reranker keeps the retrieval order and the answer
+	p := newPipeline(&keywordRetriever{chunks: chunks}, []Ranker{&slowRanker{}}, &stageGenerator{})
+	result, err := p.Query(ctx, "timeout", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Degraded || len(result.TimedOutStages) != 1 || result.TimedOutStages[0] != StageRerank ||
+		len(result.RetrievalResults) != 1 || result.GeneratedResponse != "answer" {
+		t.Fatalf("unexpected rerank timeout result %+v", result)
+	}
+
+	// A slow generator returns the retrieved results without an answer
+	p = newPipeline(&keywordRetriever{chunks: chunks}, nil, &stageGenerator{slow: true})
+	result, err = p.Query(ctx, "timeout", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Degraded || result.TimedOutStages[0] != StageGeneration || len(result.RetrievalResults) != 1 || result.GeneratedResponse != "" {
+		t.Fatalf("unexpected generation timeout result %+v", result)
+	}
+
+	// A slow retriever returns an empty degraded result, counted in the stats
+	p = newPipeline(&slowRetriever{}, nil, &stageGenerator{})
+	result, err = p.Query(ctx, "timeout", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Degraded || result.TimedOutStages[0] != StageRetrieval || len(result.RetrievalResults) != 0 {
+		t.Fatalf("unexpected retrieval timeout result %+v", result)
+	}
+	stats, _ := p.GetStats()
+	if stats.QueryTimeouts[StageRetrieval] != 1 {
+		t.Fatalf("expected the timeout to be counted, got %v", stats.QueryTimeouts)
+	}
+
+	// Cancellation by the caller is an error, not a degraded result
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := p.Query(cancelled, "timeout", options); err == nil {
+		t.Fatal("expected a cancelled query to fail")
+	}
+
+	// The per-request override replaces the configured budget
+	p = newPipeline(&keywordRetriever{chunks: chunks}, nil, &stageGenerator{slow: true})
+	p.config.Retrieval.StageTimeouts.Generation = time.Minute
+	options.Timeouts = &StageTimeouts{Generation: time.Millisecond}
+	start := time.Now()
+	if result, err := p.Query(ctx, "timeout", options); err != nil || !result.Degraded {
+		t.Fatalf("expected a degraded result, got %+v %v", result, err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("expected the request budget to apply")
+	}
+}
@@ -0,0 +1,363 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/guileen/metabase/pkg/rag/llm"
+)
+
+// Tool is a function the model may call while generating an answer
+type Tool interface {
+	// Definition describes the tool name, purpose and argument schema
+	Definition() llm.ToolFunction
+
+	// Execute runs the tool with JSON-encoded arguments and returns text for the model
+	Execute(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// ToolFunc adapts a function to the Tool interface
+type ToolFunc struct {
+	Spec llm.ToolFunction
+	Fn   func(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// Definition implements the Tool interface
+func (t ToolFunc) Definition() llm.ToolFunction {
+	return t.Spec
+}
+
+// Execute implements the Tool interface
+func (t ToolFunc) Execute(ctx context.Context, arguments json.RawMessage) (string, error) {
+	return t.Fn(ctx, arguments)
+}
+
+// ToolInvocation records one tool call made during generation
+type ToolInvocation struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Output    string          `json:"output,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Truncated bool            `json:"truncated,omitempty"` // Output cut to the sandbox limit
+	Duration  time.Duration   `json:"duration"`
+}
+
+// toolNameRegex matches names accepted by OpenAI-compatible APIs
+var toolNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ToolRegistry holds the tools available to a project
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]Tool
+}
+
+// NewToolRegistry creates an empty tool registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]Tool)}
+}
+
+// Register adds a tool. Names must be unique within the registry.
+func (r *ToolRegistry) Register(tool Tool) error {
+	name := tool.Definition().Name
+	if !toolNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid tool name %q", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; exists {
+		return fmt.Errorf("tool %s already registered", name)
+	}
+	r.tools[name] = tool
+	return nil
+}
+
+// Unregister removes a tool
+func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, name)
+}
+
+// Get returns a tool by name
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, exists := r.tools[name]
+	return tool, exists
+}
+
+// Definitions returns the tool definitions sorted by name
+func (r *ToolRegistry) Definitions() []llm.ToolFunction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	definitions := make([]llm.ToolFunction, 0, len(r.tools))
+	for _, tool := range r.tools {
+		definitions = append(definitions, tool.Definition())
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	return definitions
+}
+
+// Len returns the number of registered tools
+func (r *ToolRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.tools)
+}
+
+// RegisterTool registers a tool for a project. An empty project ID registers
+// a global tool available to every project unless a project tool shadows it.
+func (p *Pipeline) RegisterTool(projectID string, tool Tool) error {
+	p.mu.Lock()
+	registry, exists := p.toolRegistries[projectID]
+	if !exists {
+		registry = NewToolRegistry()
+		p.toolRegistries[projectID] = registry
+	}
+	p.mu.Unlock()
+
+	return registry.Register(tool)
+}
+
+// UnregisterTool removes a tool from a project, or a global tool when projectID is empty
+func (p *Pipeline) UnregisterTool(projectID, name string) {
+	p.mu.RLock()
+	registry, exists := p.toolRegistries[projectID]
+	p.mu.RUnlock()
+
+	if exists {
+		registry.Unregister(name)
+	}
+}
+
+// ProjectTools returns the tools available to a project: its own tools plus
+// global tools it does not shadow
+func (p *Pipeline) ProjectTools(projectID string) *ToolRegistry {
+	p.mu.RLock()
+	global := p.toolRegistries[""]
+	project := p.toolRegistries[projectID]
+	p.mu.RUnlock()
+
+	merged := NewToolRegistry()
+	for _, registry := range []*ToolRegistry{project, global} {
+		if registry == nil {
+			continue
+		}
+		registry.mu.RLock()
+		for name, tool := range registry.tools {
+			if _, exists := merged.tools[name]; !exists {
+				merged.tools[name] = tool
+			}
+		}
+		registry.mu.RUnlock()
+	}
+	return merged
+}
+
+// ToolSandbox executes tool calls for one query under the configured limits:
+// each call gets its own timeout, arguments are validated against the tool
+// schema, output is capped, panics are contained and the number of calls is
+// bounded. Tools never see anything but their own arguments.
+type ToolSandbox struct {
+	registry *ToolRegistry
+	config   ToolsConfig
+
+	mu          sync.Mutex
+	invocations []ToolInvocation
+}
+
+// NewToolSandbox creates a sandbox over a project's tools
+func NewToolSandbox(registry *ToolRegistry, config ToolsConfig) *ToolSandbox {
+	return &ToolSandbox{
+		registry: registry,
+		config:   config,
+	}
+}
+
+// Definitions returns the tools offered to the model
+func (s *ToolSandbox) Definitions() []llm.Tool {
+	functions := s.registry.Definitions()
+	tools := make([]llm.Tool, len(functions))
+	for i, function := range functions {
+		tools[i] = llm.Tool{Type: "function", Function: function}
+	}
+	return tools
+}
+
+// Invocations returns the calls executed so far
+func (s *ToolSandbox) Invocations() []ToolInvocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ToolInvocation(nil), s.invocations...)
+}
+
+// exhausted reports whether the call limit has been reached
+func (s *ToolSandbox) exhausted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config.MaxCallsPerQuery > 0 && len(s.invocations) >= s.config.MaxCallsPerQuery
+}
+
+// Execute runs a tool call requested by the model. Failures are reported in
+// the invocation so they can be returned to the model rather than aborting.
+func (s *ToolSandbox) Execute(ctx context.Context, call llm.ToolCall) ToolInvocation {
+	invocation := ToolInvocation{
+		ID:   call.ID,
+		Name: call.Function.Name,
+	}
+	if json.Valid([]byte(call.Function.Arguments)) {
+		invocation.Arguments = json.RawMessage(call.Function.Arguments)
+	}
+
+	start := time.Now()
+	output, err := s.run(ctx, call, s.exhausted())
+	invocation.Duration = time.Since(start)
+	if err != nil {
+		invocation.Error = err.Error()
+	} else {
+		if max := s.config.MaxOutputBytes; max > 0 && len(output) > max {
+			for max > 0 && !utf8.RuneStart(output[max]) {
+				max--
+			}
+			output = output[:max]
+			invocation.Truncated = true
+		}
+		invocation.Output = output
+	}
+
+	s.mu.Lock()
+	s.invocations = append(s.invocations, invocation)
+	s.mu.Unlock()
+	return invocation
+}
+
+// run validates and executes a single call
+func (s *ToolSandbox) run(ctx context.Context, call llm.ToolCall, exceeded bool) (string, error) {
+	if exceeded {
+		return "", fmt.Errorf("tool call limit of %d reached", s.config.MaxCallsPerQuery)
+	}
+	tool, exists := s.registry.Get(call.Function.Name)
+	if !exists {
+		return "", fmt.Errorf("unknown tool %s", call.Function.Name)
+	}
+
+	arguments := call.Function.Arguments
+	if arguments == "" {
+		arguments = "{}"
+	}
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(arguments), &decoded); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if schema := tool.Definition().Parameters; schema != nil {
+		if errs := ValidateJSONSchema(decoded, schema); len(errs) > 0 {
+			return "", fmt.Errorf("invalid arguments: %s", errs[0])
+		}
+	}
+
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		output string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("tool panicked: %v", r)}
+			}
+		}()
+		out, err := tool.Execute(ctx, json.RawMessage(arguments))
+		done <- outcome{output: out, err: err}
+	}()
+
+	// A tool that ignores its context is abandoned once the timeout passes
+	select {
+	case result := <-done:
+		return result.output, result.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("tool %s: %w", call.Function.Name, ctx.Err())
+	}
+}
+
+// RunToolLoop runs a completion that may call tools. Each round the model's
+// tool calls are executed in the sandbox and their results appended to the
+// conversation, until the model answers without calling tools. After
+// maxIterations rounds, or once the sandbox call limit is reached, tools are
+// withheld so the model must answer.
+// Token usage is summed over all rounds.
+func RunToolLoop(ctx context.Context, client LLMClient, messages []llm.ChatMessage, options CompletionOptions, sandbox *ToolSandbox, maxIterations int) (*CompletionResponse, error) {
+	conversation := append([]llm.ChatMessage(nil), messages...)
+	var usage CompletionUsage
+
+	for iteration := 0; ; iteration++ {
+		roundOptions := options
+		if iteration < maxIterations && !sandbox.exhausted() {
+			roundOptions.Tools = sandbox.Definitions()
+		} else {
+			roundOptions.Tools = nil
+			roundOptions.ToolChoice = nil
+		}
+
+		response, err := client.GenerateCompletion(ctx, conversation, roundOptions)
+		if err != nil {
+			return nil, err
+		}
+		usage.PromptTokens += response.Usage.PromptTokens
+		usage.CompletionTokens += response.Usage.CompletionTokens
+		usage.TotalTokens += response.Usage.TotalTokens
+
+		if len(response.Choices) == 0 || len(response.Choices[0].Message.ToolCalls) == 0 || len(roundOptions.Tools) == 0 {
+			response.Usage = usage
+			return response, nil
+		}
+
+		message := response.Choices[0].Message
+		message.Role = "assistant"
+		conversation = append(conversation, message)
+		for _, call := range message.ToolCalls {
+			invocation := sandbox.Execute(ctx, call)
+			content := invocation.Output
+			if invocation.Error != "" {
+				content = "error: " + invocation.Error
+			}
+			conversation = append(conversation, llm.ChatMessage{
+				Role:       "tool",
+				Content:    content,
+				ToolCallID: call.ID,
+			})
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// newToolSandbox returns a sandbox for the query's project, or nil when
+// tools are disabled or the project has none
+func (p *Pipeline) newToolSandbox(options QueryOptions) *ToolSandbox {
+	config := p.config.Generation.Tools
+	if !config.Enabled || !options.GenerateOptions.EnableTools {
+		return nil
+	}
+	registry := p.ProjectTools(options.ProjectID)
+	if registry.Len() == 0 {
+		return nil
+	}
+	return NewToolSandbox(registry, config)
+}
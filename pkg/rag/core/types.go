@@ -81,6 +81,17 @@ type DocumentChunk struct {
 	Embedding      []float64 `json:"embedding,omitempty"`
 	EmbeddingModel string    `json:"embedding_model,omitempty"`
 	EmbeddingDim   int       `json:"embedding_dim,omitempty"`
+	IndexVersion   string    `json:"index_version,omitempty"` // Embedding space the vector belongs to
+
+	// Structured table content (for table chunks)
+	Table *TableData `json:"table,omitempty"`
+
+	// Image information (for image chunks)
+	Image *ImageData `json:"image,omitempty"`
+
+	// Deduplication information
+	ContentHash string `json:"content_hash,omitempty"` // Hash of normalized content
+	DuplicateOf string `json:"duplicate_of,omitempty"` // Canonical chunk sharing this content
 
 	// Metadata
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
@@ -106,8 +117,15 @@ type RetrievalResult struct {
 	RerankScore  float64 `json:"rerank_score"`  // Reranking score
 
 	// Match information
-	Matches    []TextMatch `json:"matches"`    // Text matches within the chunk
-	Highlights []string    `json:"highlights"` // Highlighted passages
+	Matches    []TextMatch `json:"matches"`            // Text matches within the chunk
+	Highlights []string    `json:"highlights"`         // Highlighted passages
+	Excerpts   []Excerpt   `json:"excerpts,omitempty"` // Highlighted passages with their positions
+
+	// Other documents of the same project containing identical content
+	DuplicateSources []ChunkReference `json:"duplicate_sources,omitempty"`
+
+	// Project the result came from in a federated query
+	ProjectID string `json:"project_id,omitempty"`
 
 	// Metadata
 	Explanation string `json:"explanation,omitempty"` // Why this was retrieved
@@ -118,10 +136,10 @@ type RetrievalResult struct {
 // TextMatch represents a text match within a chunk
 type TextMatch struct {
 	Text     string  `json:"text"`      // Matched text
-	StartPos int     `json:"start_pos"` // Start position
-	EndPos   int     `json:"end_pos"`   // End position
+	StartPos int     `json:"start_pos"` // Start character offset within the chunk content
+	EndPos   int     `json:"end_pos"`   // End character offset, exclusive
 	Score    float64 `json:"score"`     // Match score
-	Type     string  `json:"type"`      // exact, fuzzy, semantic, etc.
+	Type     string  `json:"type"`      // exact, term or semantic
 }
 
 // QueryResult represents the result of a RAG query
@@ -143,6 +161,9 @@ type QueryResult struct {
 	GeneratedSummary  string   `json:"generated_summary"` // Generated summary
 	Sources           []Source `json:"sources"`           // Source citations
 
+	// Glossary terms used in the answer, appended to it as footnotes
+	Glossary []GlossaryEntry `json:"glossary,omitempty"`
+
 	// Performance metrics
 	QueryTime      time.Duration `json:"query_time"`
 	RetrievalTime  time.Duration `json:"retrieval_time"`
@@ -160,7 +181,26 @@ type QueryResult struct {
 	FilterApplied    bool         `json:"filter_applied"`
 	RerankingApplied bool         `json:"reranking_applied"`
 	CacheHit         bool         `json:"cache_hit"`
-	CreatedAt        time.Time    `json:"created_at"`
+
+	// Set when a stage ran out of time and the result is partial
+	Degraded       bool     `json:"degraded,omitempty"`
+	TimedOutStages []string `json:"timed_out_stages,omitempty"`
+
+	// How retrieved results were fitted into the context window
+	ContextPacking *PackingStats `json:"context_packing,omitempty"`
+
+	// Tools invoked while generating the answer
+	ToolCalls []ToolInvocation `json:"tool_calls,omitempty"`
+
+	// Per-project outcome of a federated query
+	FederatedProjects []FederatedProjectResult `json:"federated_projects,omitempty"`
+
+	// Validated JSON answer when an output schema was requested
+	StructuredOutput *StructuredOutput `json:"structured_output,omitempty"`
+
+	// Moderation outcome, recorded for audit
+	Moderation *ModerationReport `json:"moderation,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
 }
 
 // Source represents a source citation in the generated response
@@ -172,6 +212,12 @@ type Source struct {
 	Relevance     float64 `json:"relevance"`
 	Excerpt       string  `json:"excerpt"`
 	PageNumber    int     `json:"page_number,omitempty"`
+	ImageURI      string  `json:"image_uri,omitempty"` // Set when the source is an image
+	SourceType    string  `json:"source_type,omitempty"`
+	Language      string  `json:"language,omitempty"`   // Language the excerpt was written in
+	Translated    bool    `json:"translated,omitempty"` // Excerpt was translated into the answer language
+	DeepLink      string  `json:"deep_link,omitempty"`  // Opens the cited passage in its source system
+	ProjectID     string  `json:"project_id,omitempty"` // Set in federated queries
 }
 
 // GenerationResult represents the result of text generation
@@ -203,17 +249,22 @@ type GenerationResult struct {
 	// Sources used
 	Sources []Source `json:"sources"`
 
+	// Tools invoked during generation
+	ToolCalls []ToolInvocation `json:"tool_calls,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 }
 
 // IndexResult represents the result of an indexing operation
 type IndexResult struct {
 	// Processing statistics
-	DocumentsProcessed int `json:"documents_processed"`
-	DocumentsUpdated   int `json:"documents_updated"`
-	DocumentsAdded     int `json:"documents_added"`
-	DocumentsSkipped   int `json:"documents_skipped"`
-	DocumentsErrored   int `json:"documents_errored"`
+	DocumentsProcessed   int `json:"documents_processed"`
+	DocumentsUpdated     int `json:"documents_updated"`
+	DocumentsAdded       int `json:"documents_added"`
+	DocumentsSkipped     int `json:"documents_skipped"`
+	DocumentsErrored     int `json:"documents_errored"`
+	DocumentsDuplicate   int `json:"documents_duplicate"`             // Skipped as duplicates of indexed documents
+	DocumentsQuarantined int `json:"documents_quarantined,omitempty"` // Skipped after repeatedly failing
 
 	// Chunk statistics
 	ChunksCreated int `json:"chunks_created"`
@@ -229,6 +280,12 @@ type IndexResult struct {
 	ProcessingRate float64       `json:"processing_rate"` // docs per second
 	Errors         []string      `json:"errors,omitempty"`
 
+	// Documents linked to an already indexed document with the same content
+	Duplicates []DuplicateDocument `json:"duplicates,omitempty"`
+
+	// Blue/green rebuild of a forced reindex
+	Swap *IndexSwapReport `json:"swap,omitempty"`
+
 	// Data source information
 	DataSourceID string `json:"data_source_id"`
 	IndexType    string `json:"index_type"` // full, incremental
@@ -244,6 +301,8 @@ type SyncResult struct {
 	DocumentsUpdated   int `json:"documents_updated"`
 	DocumentsDeleted   int `json:"documents_deleted"`
 	DocumentsUnchanged int `json:"documents_unchanged"`
+	ChunksDeleted      int `json:"chunks_deleted"`
+	EmbeddingsDeleted  int `json:"embeddings_deleted"`
 
 	// Error information
 	Errors     []string `json:"errors,omitempty"`
@@ -297,6 +356,9 @@ type QueryFeedback struct {
 
 // IndexOptions defines options for document indexing
 type IndexOptions struct {
+	// Project the indexed data sources belong to
+	ProjectID string `json:"project_id,omitempty"`
+
 	// Data source filtering
 	DataSourceIDs []string `json:"data_source_ids,omitempty"`
 	DocumentIDs   []string `json:"document_ids,omitempty"`
@@ -321,6 +383,11 @@ type IndexOptions struct {
 	// Timeout options
 	Timeout time.Duration `json:"timeout,omitempty"`
 	DryRun  bool          `json:"dry_run"` // Simulate without actually indexing
+
+	// Forced reindex: checks the rebuilt index must pass before it replaces
+	// the serving one, and the index it is built in (created when nil)
+	Validation SwapValidation `json:"validation,omitempty"`
+	Shadow     Retriever      `json:"-"`
 }
 
 // QueryOptions defines options for query execution
@@ -338,17 +405,31 @@ type QueryOptions struct {
 	EnableStreaming bool          `json:"enable_streaming"` // Enable streaming responses
 
 	// Filtering options
+	Filter        string     `json:"filter,omitempty"` // Filter expression, e.g. tag:go AND created>=2024-01-01
 	DataSourceIDs []string   `json:"data_source_ids,omitempty"`
 	DocumentIDs   []string   `json:"document_ids,omitempty"`
 	FileTypes     []string   `json:"file_types,omitempty"`
 	Tags          []string   `json:"tags,omitempty"`
 	DateRange     *TimeRange `json:"date_range,omitempty"`
 
+	// Point-in-time retrieval: answer from the document versions current at
+	// this time, for reproducible evaluation runs
+	AsOf *time.Time `json:"as_of,omitempty"`
+
 	// Result options
-	MaxResults int     `json:"max_results"` // Maximum results to return
-	MinScore   float64 `json:"min_score"`   // Minimum relevance score
+	MaxResults int              `json:"max_results"` // Maximum results to return
+	MinScore   float64          `json:"min_score"`   // Minimum relevance score
+	Highlight  HighlightOptions `json:"highlight"`   // Matches and excerpts of returned results
+
+	// Per-stage time budgets; unset budgets use the retrieval configuration
+	Timeouts *StageTimeouts `json:"timeouts,omitempty"`
+
+	// Fan out to several projects instead of the single ProjectID
+	Federation *FederationOptions `json:"federation,omitempty"`
 
 	// User context
+	ProjectID string                 `json:"project_id,omitempty"` // Applies the project's stored overrides
+	TenantID  string                 `json:"tenant_id,omitempty"`  // Charges generation to the tenant's budget
 	UserID    string                 `json:"user_id,omitempty"`
 	SessionID string                 `json:"session_id,omitempty"`
 	Context   map[string]interface{} `json:"context,omitempty"`
@@ -365,6 +446,10 @@ type RetrieveOptions struct {
 	EnableKeywordSearch bool `json:"enable_keyword_search"` // Enable keyword search
 	EnableHybridSearch  bool `json:"enable_hybrid_search"`  // Enable hybrid search
 
+	// Image search
+	IncludeImages bool `json:"include_images"`        // Also search the image namespace
+	ImageTopK     int  `json:"image_top_k,omitempty"` // Number of images to retrieve
+
 	// Hybrid search configuration
 	VectorWeight  float64 `json:"vector_weight"`  // Weight for vector search in hybrid
 	KeywordWeight float64 `json:"keyword_weight"` // Weight for keyword search in hybrid
@@ -377,6 +462,14 @@ type RetrieveOptions struct {
 	RerankTopK   int    `json:"rerank_top_k"`           // Number of results to rerank
 	RerankModel  string `json:"rerank_model,omitempty"` // Reranking model to use
 
+	// Recency and source boosting; unset values use the retrieval configuration
+	RecencyHalfLife   time.Duration      `json:"recency_half_life,omitempty"`
+	RecencyWeight     float64            `json:"recency_weight,omitempty"`
+	SourceTypeWeights map[string]float64 `json:"source_type_weights,omitempty"`
+
+	// Scoring expression applied after base scoring and reranking
+	ScoringExpression string `json:"scoring_expression,omitempty"`
+
 	// Performance options
 	MaxQueryTime time.Duration `json:"max_query_time,omitempty"`
 	EnableCache  bool          `json:"enable_cache"` // Enable query caching
@@ -391,6 +484,7 @@ type GenerateOptions struct {
 	TopP             float64 `json:"top_p"`
 	FrequencyPenalty float64 `json:"frequency_penalty"`
 	PresencePenalty  float64 `json:"presence_penalty"`
+	Seed             *int64  `json:"seed,omitempty"` // Sampling seed, set in deterministic mode
 
 	// Prompt configuration
 	PromptTemplate   string `json:"prompt_template,omitempty"`
@@ -403,6 +497,24 @@ type GenerateOptions struct {
 	IncludeSummary  bool   `json:"include_summary"`  // Include summary
 	Format          string `json:"format"`           // Response format (markdown, json, etc.)
 
+	// Answer language, a code such as "de" or "auto" for the query's language.
+	// Cited excerpts in other languages are translated when TranslateExcerpts
+	// or the generation configuration enables it.
+	AnswerLanguage    string `json:"answer_language,omitempty"`
+	TranslateExcerpts bool   `json:"translate_excerpts"`
+
+	// Structured context rendered into the prompt (e.g. matched tables)
+	RenderTables      bool   `json:"render_tables"`
+	StructuredContext string `json:"structured_context,omitempty"`
+
+	// Structured output: the answer must be JSON conforming to OutputSchema
+	OutputSchema     map[string]interface{} `json:"output_schema,omitempty"`
+	OutputSchemaName string                 `json:"output_schema_name,omitempty"`
+
+	// Tool calling: generators run the tool loop through ToolSandbox when set
+	EnableTools bool         `json:"enable_tools"`
+	ToolSandbox *ToolSandbox `json:"-"`
+
 	// Quality options
 	MinConfidence   float64 `json:"min_confidence"`    // Minimum confidence threshold
 	EnableFactCheck bool    `json:"enable_fact_check"` // Enable fact checking
@@ -410,6 +522,9 @@ type GenerateOptions struct {
 	// Performance options
 	EnableStreaming   bool          `json:"enable_streaming"` // Enable streaming responses
 	MaxGenerationTime time.Duration `json:"max_generation_time,omitempty"`
+
+	// Stream receives citations and generated text while the query runs
+	Stream StreamSink `json:"-"`
 }
 
 // CompletionOptions defines options for LLM completion
@@ -423,6 +538,14 @@ type CompletionOptions struct {
 	Stream           bool          `json:"stream"`
 	Stop             []string      `json:"stop,omitempty"`
 	Timeout          time.Duration `json:"timeout,omitempty"`
+	Seed             *int64        `json:"seed,omitempty"` // Sampling seed, set in deterministic mode
+
+	// Native structured output, used when the model supports it
+	ResponseFormat *llm.ResponseFormat `json:"response_format,omitempty"`
+
+	// Tool calling, used when the model supports it
+	Tools      []llm.Tool  `json:"tools,omitempty"`
+	ToolChoice interface{} `json:"tool_choice,omitempty"`
 }
 
 // FilterCriteria defines criteria for filtering results
@@ -434,6 +557,7 @@ type FilterCriteria struct {
 	Tags          []string `json:"tags,omitempty"`
 	Categories    []string `json:"categories,omitempty"`
 	Authors       []string `json:"authors,omitempty"`
+	ChunkTypes    []string `json:"chunk_types,omitempty"` // e.g. table, summary
 
 	// Time-based filtering
 	CreatedAfter   *time.Time `json:"created_after,omitempty"`
@@ -454,6 +578,9 @@ type FilterCriteria struct {
 
 	// Custom filtering
 	Custom map[string]interface{} `json:"custom,omitempty"`
+
+	// Boolean metadata filter; see ParseFilterExpression
+	Expression *FilterExpr `json:"expression,omitempty"`
 }
 
 // ListOptions defines options for listing documents
@@ -521,9 +648,10 @@ type SystemStats struct {
 	ActiveSources    []string `json:"active_sources"`
 
 	// Query statistics
-	TotalQueries int64         `json:"total_queries"`
-	CacheHitRate float64       `json:"cache_hit_rate"`
-	AvgQueryTime time.Duration `json:"avg_query_time"`
+	TotalQueries  int64            `json:"total_queries"`
+	CacheHitRate  float64          `json:"cache_hit_rate"`
+	AvgQueryTime  time.Duration    `json:"avg_query_time"`
+	QueryTimeouts map[string]int64 `json:"query_timeouts,omitempty"` // Stage timeouts by stage
 
 	// Performance metrics
 	Uptime      time.Duration `json:"uptime"`
@@ -533,6 +661,10 @@ type SystemStats struct {
 	MemoryUsage int64   `json:"memory_usage"` // in bytes
 	CPUUsage    float64 `json:"cpu_usage"`    // percentage
 
+	// Storage maintenance
+	MaintenanceRuns int64 `json:"maintenance_runs"`
+	ReclaimedSpace  int64 `json:"reclaimed_space"` // Bytes reclaimed by maintenance
+
 	// Error statistics
 	ErrorRate float64 `json:"error_rate"`
 	LastError *string `json:"last_error,omitempty"`
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/guileen/metabase/pkg/rag/embedding"
+)
+
+// VectorRetriever is an exact nearest-neighbour index over chunk embeddings.
+// Queries are embedded with its generator and matching chunks are read back
+// from storage, so the index only holds vectors. It is the target index of a
+// re-embedding migration when none is given.
+type VectorRetriever struct {
+	generator embedding.VectorGenerator
+	storage   Storage
+
+	mu      sync.RWMutex
+	vectors map[string]indexedVector // by chunk ID
+}
+
+// indexedVector is one chunk's embedding with what filtering needs
+type indexedVector struct {
+	documentID string
+	chunkType  string
+	vector     []float64
+}
+
+// NewVectorRetriever creates an empty index embedding queries with generator
+// and reading chunks from storage
+func NewVectorRetriever(generator embedding.VectorGenerator, storage Storage) *VectorRetriever {
+	return &VectorRetriever{
+		generator: generator,
+		storage:   storage,
+		vectors:   make(map[string]indexedVector),
+	}
+}
+
+// Retrieve returns the chunks most similar to the query, best first
+func (r *VectorRetriever) Retrieve(ctx context.Context, query string, options RetrieveOptions) ([]RetrievalResult, error) {
+	vectors, err := r.generator.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedding count mismatch: got %d, want 1", len(vectors))
+	}
+
+	documents := stringSet(options.FilterOptions.DocumentIDs)
+	chunkTypes := stringSet(options.FilterOptions.ChunkTypes)
+	type scored struct {
+		chunkID    string
+		documentID string
+		score      float64
+	}
+	var matches []scored
+	r.mu.RLock()
+	for id, indexed := range r.vectors {
+		if documents != nil && !documents[indexed.documentID] {
+			continue
+		}
+		if chunkTypes != nil && !chunkTypes[indexed.chunkType] {
+			continue
+		}
+		score := cosine(vectors[0], indexed.vector)
+		if score < options.SimilarityThreshold {
+			continue
+		}
+		matches = append(matches, scored{chunkID: id, documentID: indexed.documentID, score: score})
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].chunkID < matches[j].chunkID
+	})
+	if options.TopK > 0 && len(matches) > options.TopK {
+		matches = matches[:options.TopK]
+	}
+
+	results := make([]RetrievalResult, 0, len(matches))
+	for _, match := range matches {
+		chunk, err := r.storage.GetChunk(ctx, match.chunkID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chunk %s: %w", match.chunkID, err)
+		}
+		results = append(results, RetrievalResult{
+			DocumentID: match.documentID,
+			Chunk:      chunk,
+			Score:      match.score,
+			Similarity: match.score,
+		})
+	}
+	return results, nil
+}
+
+// AddDocument indexes a chunk's embedding
+func (r *VectorRetriever) AddDocument(ctx context.Context, chunk DocumentChunk) error {
+	if len(chunk.Embedding) == 0 {
+		return fmt.Errorf("chunk %s has no embedding", chunk.ID)
+	}
+	if dimension := r.generator.GetDimension(); dimension > 0 && len(chunk.Embedding) != dimension {
+		return fmt.Errorf("chunk %s has %d dimensions, want %d", chunk.ID, len(chunk.Embedding), dimension)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.vectors[chunk.ID] = indexedVector{
+		documentID: chunk.DocumentID,
+		chunkType:  chunk.ChunkType,
+		vector:     chunk.Embedding,
+	}
+	return nil
+}
+
+// RemoveDocument removes a chunk from the index
+func (r *VectorRetriever) RemoveDocument(ctx context.Context, chunkID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.vectors, chunkID)
+	return nil
+}
+
+// UpdateDocument replaces a chunk's embedding
+func (r *VectorRetriever) UpdateDocument(ctx context.Context, chunk DocumentChunk) error {
+	return r.AddDocument(ctx, chunk)
+}
+
+// Clear empties the index
+func (r *VectorRetriever) Clear(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.vectors = make(map[string]indexedVector)
+	return nil
+}
+
+// GetStats returns the size of the index
+func (r *VectorRetriever) GetStats() (*RetrieverStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	documents := make(map[string]bool)
+	var size int64
+	for _, indexed := range r.vectors {
+		documents[indexed.documentID] = true
+		size += int64(len(indexed.vector) * 8)
+	}
+	return &RetrieverStats{
+		TotalDocuments:  len(documents),
+		TotalChunks:     len(r.vectors),
+		IndexedChunks:   len(r.vectors),
+		EmbeddingDim:    r.generator.GetDimension(),
+		VectorIndexSize: size,
+	}, nil
+}
+
+// stringSet returns the values as a set, or nil when there are none
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
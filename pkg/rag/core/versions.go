@@ -0,0 +1,354 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Diff operations
+const (
+	DiffEqual  = "equal"
+	DiffAdd    = "add"
+	DiffRemove = "remove"
+)
+
+// maxDiffCells bounds the line diff table; larger changes are reported as a replacement
+const maxDiffCells = 4_000_000
+
+// DocumentVersion is an immutable snapshot of a document and its chunks
+type DocumentVersion struct {
+	DocumentID  string           `json:"document_id"`
+	Version     int              `json:"version"`
+	Title       string           `json:"title"`
+	Content     string           `json:"content,omitempty"`
+	ContentHash string           `json:"content_hash"`
+	Metadata    DocumentMetadata `json:"metadata"`
+	Chunks      []DocumentChunk  `json:"chunks,omitempty"` // Stored without embeddings
+	CreatedAt   time.Time        `json:"created_at"`       // The version is current from this time
+}
+
+// DocumentVersionStore keeps every version of indexed documents
+type DocumentVersionStore interface {
+	// SaveDocumentVersion stores a new version
+	SaveDocumentVersion(ctx context.Context, version *DocumentVersion) error
+
+	// ListDocumentVersions returns all versions of a document, oldest first,
+	// without content and chunks
+	ListDocumentVersions(ctx context.Context, documentID string) ([]DocumentVersion, error)
+
+	// GetDocumentVersion returns a version, the latest when version <= 0, or nil if none
+	GetDocumentVersion(ctx context.Context, documentID string, version int) (*DocumentVersion, error)
+
+	// GetDocumentVersionAt returns the version current at a time, or nil if
+	// the document did not exist yet
+	GetDocumentVersionAt(ctx context.Context, documentID string, at time.Time) (*DocumentVersion, error)
+}
+
+// DiffLine is one line of a version diff
+type DiffLine struct {
+	Op   string `json:"op"` // equal, add or remove
+	Text string `json:"text"`
+}
+
+// VersionDiff is the line diff between two versions of a document
+type VersionDiff struct {
+	DocumentID    string     `json:"document_id"`
+	FromVersion   int        `json:"from_version"`
+	ToVersion     int        `json:"to_version"`
+	LinesAdded    int        `json:"lines_added"`
+	LinesRemoved  int        `json:"lines_removed"`
+	ChunksAdded   int        `json:"chunks_added"`
+	ChunksRemoved int        `json:"chunks_removed"`
+	Lines         []DiffLine `json:"lines"`
+}
+
+// SetVersionStore enables document versioning
+func (p *Pipeline) SetVersionStore(store DocumentVersionStore) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.versions = store
+}
+
+// ListDocumentVersions returns the versions of a document, oldest first
+func (p *Pipeline) ListDocumentVersions(ctx context.Context, documentID string) ([]DocumentVersion, error) {
+	store, err := p.versionStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.ListDocumentVersions(ctx, documentID)
+}
+
+// GetDocumentVersion returns a version of a document, the latest when version <= 0
+func (p *Pipeline) GetDocumentVersion(ctx context.Context, documentID string, version int) (*DocumentVersion, error) {
+	store, err := p.versionStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.GetDocumentVersion(ctx, documentID, version)
+}
+
+// DiffDocumentVersions returns the line diff from one version of a document to another
+func (p *Pipeline) DiffDocumentVersions(ctx context.Context, documentID string, from, to int) (*VersionDiff, error) {
+	store, err := p.versionStore()
+	if err != nil {
+		return nil, err
+	}
+
+	fromVersion, err := store.GetDocumentVersion(ctx, documentID, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := store.GetDocumentVersion(ctx, documentID, to)
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion == nil || toVersion == nil {
+		return nil, fmt.Errorf("document %s version not found", documentID)
+	}
+
+	return DiffVersions(fromVersion, toVersion), nil
+}
+
+// DiffVersions returns the line and chunk diff from one version to another
+func DiffVersions(fromVersion, toVersion *DocumentVersion) *VersionDiff {
+	diff := &VersionDiff{
+		DocumentID:  toVersion.DocumentID,
+		FromVersion: fromVersion.Version,
+		ToVersion:   toVersion.Version,
+		Lines:       diffLines(fromVersion.Content, toVersion.Content),
+	}
+	for _, line := range diff.Lines {
+		switch line.Op {
+		case DiffAdd:
+			diff.LinesAdded++
+		case DiffRemove:
+			diff.LinesRemoved++
+		}
+	}
+
+	// Chunks are compared by content, so re-chunked but unchanged text does not count
+	fromChunks := make(map[string]int)
+	for _, chunk := range fromVersion.Chunks {
+		fromChunks[chunkContentHash(chunk)]++
+	}
+	for _, chunk := range toVersion.Chunks {
+		hash := chunkContentHash(chunk)
+		if fromChunks[hash] > 0 {
+			fromChunks[hash]--
+		} else {
+			diff.ChunksAdded++
+		}
+	}
+	for _, remaining := range fromChunks {
+		diff.ChunksRemoved += remaining
+	}
+
+	return diff
+}
+
+func (p *Pipeline) versionStore() (DocumentVersionStore, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.versions == nil {
+		return nil, fmt.Errorf("document versioning not configured")
+	}
+	return p.versions, nil
+}
+
+// assignDocumentVersion sets the document's version number. It returns true
+// when the content differs from the latest stored version, so a new version
+// must be recorded once the document's chunks are known.
+func (p *Pipeline) assignDocumentVersion(ctx context.Context, doc *Document) (bool, error) {
+	p.mu.RLock()
+	store := p.versions
+	p.mu.RUnlock()
+	if store == nil {
+		return false, nil
+	}
+
+	latest, err := store.GetDocumentVersion(ctx, doc.ID, 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to load latest document version: %w", err)
+	}
+	if latest == nil {
+		doc.Version = 1
+		return true, nil
+	}
+	if latest.ContentHash == ContentHash(doc.Title+"\n"+doc.Content) {
+		doc.Version = latest.Version
+		return false, nil
+	}
+	doc.Version = latest.Version + 1
+	return true, nil
+}
+
+// recordDocumentVersion stores a snapshot of the document and its chunks
+func (p *Pipeline) recordDocumentVersion(ctx context.Context, doc Document, chunks []DocumentChunk) error {
+	p.mu.RLock()
+	store := p.versions
+	p.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	snapshot := make([]DocumentChunk, len(chunks))
+	for i, chunk := range chunks {
+		chunk.Embedding = nil
+		snapshot[i] = chunk
+	}
+
+	return store.SaveDocumentVersion(ctx, &DocumentVersion{
+		DocumentID:  doc.ID,
+		Version:     doc.Version,
+		Title:       doc.Title,
+		Content:     doc.Content,
+		ContentHash: ContentHash(doc.Title + "\n" + doc.Content),
+		Metadata:    doc.Metadata,
+		Chunks:      snapshot,
+		CreatedAt:   p.now(),
+	})
+}
+
+// resolveVersionsAsOf rewrites retrieval results to the document versions
+// current at asOf. Chunks are matched by ID, so results keep the scores of
+// the live index; results from documents that did not exist at asOf, or whose
+// chunk did not exist in that version, are dropped.
+func (p *Pipeline) resolveVersionsAsOf(ctx context.Context, results []RetrievalResult, asOf time.Time) ([]RetrievalResult, error) {
+	store, err := p.versionStore()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string]*DocumentVersion)
+	resolved := results[:0]
+	for _, result := range results {
+		documentID := result.DocumentID
+		if documentID == "" && result.Chunk != nil {
+			documentID = result.Chunk.DocumentID
+		}
+
+		version, seen := versions[documentID]
+		if !seen {
+			if version, err = store.GetDocumentVersionAt(ctx, documentID, asOf); err != nil {
+				return nil, fmt.Errorf("failed to load version of document %s: %w", documentID, err)
+			}
+			versions[documentID] = version
+		}
+		if version == nil {
+			continue
+		}
+
+		if result.Chunk != nil {
+			var match *DocumentChunk
+			for i := range version.Chunks {
+				if version.Chunks[i].ID == result.Chunk.ID {
+					match = &version.Chunks[i]
+					break
+				}
+			}
+			if match == nil {
+				continue
+			}
+			chunk := *match
+			result.Chunk = &chunk
+		}
+		if result.Document != nil {
+			doc := *result.Document
+			doc.Title = version.Title
+			doc.Content = version.Content
+			doc.Metadata = version.Metadata
+			doc.Version = version.Version
+			result.Document = &doc
+		}
+		resolved = append(resolved, result)
+	}
+	return resolved, nil
+}
+
+func chunkContentHash(chunk DocumentChunk) string {
+	if chunk.ContentHash != "" {
+		return chunk.ContentHash
+	}
+	return ContentHash(chunk.Content)
+}
+
+// diffLines returns a line diff based on the longest common subsequence
+func diffLines(from, to string) []DiffLine {
+	a, b := splitLines(from), splitLines(to)
+
+	// Common prefix and suffix need no table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []DiffLine
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, line := range midA {
+			lines = append(lines, DiffLine{Op: DiffRemove, Text: line})
+		}
+		for _, line := range midB {
+			lines = append(lines, DiffLine{Op: DiffAdd, Text: line})
+		}
+	} else {
+		// lcs[i][j] is the LCS length of midA[i:] and midB[j:]
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(midA) && j < len(midB) {
+			switch {
+			case midA[i] == midB[j]:
+				lines = append(lines, DiffLine{Op: DiffEqual, Text: midA[i]})
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lines = append(lines, DiffLine{Op: DiffRemove, Text: midA[i]})
+				i++
+			default:
+				lines = append(lines, DiffLine{Op: DiffAdd, Text: midB[j]})
+				j++
+			}
+		}
+		for ; i < len(midA); i++ {
+			lines = append(lines, DiffLine{Op: DiffRemove, Text: midA[i]})
+		}
+		for ; j < len(midB); j++ {
+			lines = append(lines, DiffLine{Op: DiffAdd, Text: midB[j]})
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// memoryVersionStore keeps document versions in memory
+type memoryVersionStore struct {
+	versions map[string][]DocumentVersion
+}
+
+func newMemoryVersionStore() *memoryVersionStore {
+	return &memoryVersionStore{versions: make(map[string][]DocumentVersion)}
+}
+
+func (s *memoryVersionStore) SaveDocumentVersion(ctx context.Context, version *DocumentVersion) error {
+	s.versions[version.DocumentID] = append(s.versions[version.DocumentID], *version)
+	return nil
+}
+
+func (s *memoryVersionStore) ListDocumentVersions(ctx context.Context, documentID string) ([]DocumentVersion, error) {
+	var versions []DocumentVersion
+	for _, version := range s.versions[documentID] {
+		version.Content = ""
+		version.Chunks = nil
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (s *memoryVersionStore) GetDocumentVersion(ctx context.Context, documentID string, version int) (*DocumentVersion, error) {
+	versions := s.versions[documentID]
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	if version <= 0 {
+		latest := versions[len(versions)-1]
+		return &latest, nil
+	}
+	for _, v := range versions {
+		if v.Version == version {
+			return &v, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryVersionStore) GetDocumentVersionAt(ctx context.Context, documentID string, at time.Time) (*DocumentVersion, error) {
+	var current *DocumentVersion
+	for _, v := range s.versions[documentID] {
+		if !v.CreatedAt.After(at) {
+			current = &v
+		}
+	}
+	return current, nil
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []DiffLine
+	}{
+		{name: "empty"},
+		{
+			name: "unchanged",
+			from: "a\nb\n",
+			to:   "a\nb",
+			want: []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}},
+		},
+		{
+			name: "added document",
+			to:   "a\nb",
+			want: []DiffLine{{DiffAdd, "a"}, {DiffAdd, "b"}},
+		},
+		{
+			name: "changed middle line",
+			from: "a\nb\nc",
+			to:   "a\nx\nc",
+			want: []DiffLine{{DiffEqual, "a"}, {DiffRemove, "b"}, {DiffAdd, "x"}, {DiffEqual, "c"}},
+		},
+		{
+			name: "insert and delete",
+			from: "a\nb\nc\nd",
+			to:   "b\nc\ne\nd",
+			want: []DiffLine{{DiffRemove, "a"}, {DiffEqual, "b"}, {DiffEqual, "c"}, {DiffAdd, "e"}, {DiffEqual, "d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	from := &DocumentVersion{
+		DocumentID: "doc",
+		Version:    1,
+		Content:    "intro\nbody",
+		Chunks:     []DocumentChunk{{ID: "c1", Content: "intro"}, {ID: "c2", Content: "body"}},
+	}
+	to := &DocumentVersion{
+		DocumentID: "doc",
+		Version:    2,
+		Content:    "intro\nnew body\nfooter",
+		// Re-chunked but unchanged text does not count as added
+		Chunks: []DocumentChunk{{ID: "c3", Content: "intro"}, {ID: "c4", Content: "new body\nfooter"}},
+	}
+
+	diff := DiffVersions(from, to)
+	if diff.FromVersion != 1 || diff.ToVersion != 2 || diff.DocumentID != "doc" {
+		t.Fatalf("unexpected diff header %+v", diff)
+	}
+	if diff.LinesAdded != 2 || diff.LinesRemoved != 1 {
+		t.Fatalf("got %d lines added and %d removed, want 2 and 1", diff.LinesAdded, diff.LinesRemoved)
+	}
+	if diff.ChunksAdded != 1 || diff.ChunksRemoved != 1 {
+		t.Fatalf("got %d chunks added and %d removed, want 1 and 1", diff.ChunksAdded, diff.ChunksRemoved)
+	}
+}
+
+func TestDocumentVersioning(t *testing.T) {
+	ctx := context.Background()
+	p := &Pipeline{config: DefaultConfig()}
+
+	if _, err := p.ListDocumentVersions(ctx, "doc"); err == nil {
+		t.Fatal("expected an error without a version store")
+	}
+	doc := Document{ID: "doc", Title: "Guide", Content: "v1"}
+	if changed, err := p.assignDocumentVersion(ctx, &doc); err != nil || changed {
+		t.Fatalf("expected versioning to be off, got %v %v", changed, err)
+	}
+
+	store := newMemoryVersionStore()
+	p.SetVersionStore(store)
+
+	record := func(content string) (int, bool) {
+		t.Helper()
+		doc := Document{ID: "doc", Title: "Guide", Content: content}
+		changed, err := p.assignDocumentVersion(ctx, &doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed {
+			chunks := []DocumentChunk{{ID: "doc_" + content, DocumentID: "doc", Content: content, Embedding: []float64{1}}}
+			if err := p.recordDocumentVersion(ctx, doc, chunks); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return doc.Version, changed
+	}
+
+	if version, changed := record("v1"); version != 1 || !changed {
+		t.Fatalf("got version %d (changed %v), want a new version 1", version, changed)
+	}
+	if version, changed := record("v1"); version != 1 || changed {
+		t.Fatalf("got version %d (changed %v), want unchanged version 1", version, changed)
+	}
+	if version, changed := record("v2"); version != 2 || !changed {
+		t.Fatalf("got version %d (changed %v), want a new version 2", version, changed)
+	}
+
+	versions, err := p.ListDocumentVersions(ctx, "doc")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %+v %v", versions, err)
+	}
+	latest, err := p.GetDocumentVersion(ctx, "doc", 0)
+	if err != nil || latest.Version != 2 || latest.Content != "v2" {
+		t.Fatalf("unexpected latest version %+v %v", latest, err)
+	}
+	if latest.Chunks[0].Embedding != nil {
+		t.Fatal("expected version snapshots to be stored without embeddings")
+	}
+
+	diff, err := p.DiffDocumentVersions(ctx, "doc", 1, 2)
+	if err != nil || diff.LinesAdded != 1 || diff.LinesRemoved != 1 {
+		t.Fatalf("unexpected diff %+v %v", diff, err)
+	}
+	if _, err := p.DiffDocumentVersions(ctx, "doc", 1, 5); err == nil {
+		t.Fatal("expected a missing version to be an error")
+	}
+}
+
+func TestResolveVersionsAsOf(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemoryVersionStore()
+	store.versions["a"] = []DocumentVersion{
+		{DocumentID: "a", Version: 1, Title: "A1", Content: "old", CreatedAt: start,
+			Chunks: []DocumentChunk{{ID: "a_0", Content: "old"}}},
+		{DocumentID: "a", Version: 2, Title: "A2", Content: "new", CreatedAt: start.Add(2 * time.Hour),
+			Chunks: []DocumentChunk{{ID: "a_0", Content: "new"}, {ID: "a_1", Content: "more"}}},
+	}
+	store.versions["b"] = []DocumentVersion{
+		{DocumentID: "b", Version: 1, Title: "B1", Content: "b", CreatedAt: start.Add(3 * time.Hour),
+			Chunks: []DocumentChunk{{ID: "b_0", Content: "b"}}},
+	}
+	p := &Pipeline{config: DefaultConfig()}
+	p.SetVersionStore(store)
+
+	live := func() []RetrievalResult {
+		return []RetrievalResult{
+			{DocumentID: "a", Score: 0.9, Chunk: &DocumentChunk{ID: "a_0", Content: "new"}, Document: &Document{ID: "a", Title: "A2", Content: "new"}},
+			{DocumentID: "a", Score: 0.8, Chunk: &DocumentChunk{ID: "a_1", Content: "more"}},
+			{Score: 0.7, Chunk: &DocumentChunk{ID: "b_0", DocumentID: "b", Content: "b"}},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		asOf  time.Time
+		want  []string // chunk ID:content
+		title string
+	}{
+		{name: "before any version", asOf: start.Add(-time.Hour)},
+		{name: "first version", asOf: start.Add(time.Hour), want: []string{"a_0:old"}, title: "A1"},
+		{name: "latest versions", asOf: start.Add(4 * time.Hour), want: []string{"a_0:new", "a_1:more", "b_0:b"}, title: "A2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := p.resolveVersionsAsOf(ctx, live(), tt.asOf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Chunk.ID+":"+result.Chunk.Content)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			if tt.title != "" && (results[0].Document.Title != tt.title || results[0].Score != 0.9) {
+				t.Fatalf("expected the live score and the %s title, got %+v", tt.title, results[0])
+			}
+		})
+	}
+}
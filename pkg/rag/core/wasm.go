@@ -0,0 +1,217 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Custom preprocessing filters listed in PreprocessingConfig.CustomFilters are
+// user-supplied WASM modules, so SaaS tenants can run their own code without
+// loading it into the process. A module exports its linear memory, an
+// allocator and the filter entry point:
+//
+//	alloc(size i32) -> ptr i32
+//	filter(ptr i32, len i32) -> i64  // (out_ptr << 32) | out_len
+//
+// The input is a JSON WASMFilterInput and the output a JSON WASMFilterOutput.
+// Every call runs in a fresh instance under the configured limits.
+
+// Default limits for a WASM filter invocation
+const (
+	defaultWASMTimeout       = 2 * time.Second
+	defaultWASMMemoryLimitMB = 64
+	defaultWASMMaxOutputMB   = 16
+)
+
+// WASMLimits bounds a single filter invocation
+type WASMLimits struct {
+	Timeout       time.Duration `json:"timeout"`         // CPU time, the call is aborted when exceeded
+	MemoryLimitMB int           `json:"memory_limit_mb"` // Linear memory of the instance
+	MaxOutputMB   int           `json:"max_output_mb"`   // Largest accepted output
+}
+
+// withDefaults fills unset limits
+func (l WASMLimits) withDefaults() WASMLimits {
+	if l.Timeout <= 0 {
+		l.Timeout = defaultWASMTimeout
+	}
+	if l.MemoryLimitMB <= 0 {
+		l.MemoryLimitMB = defaultWASMMemoryLimitMB
+	}
+	if l.MaxOutputMB <= 0 {
+		l.MaxOutputMB = defaultWASMMaxOutputMB
+	}
+	return l
+}
+
+// WASMFilterInput is passed to a filter module
+type WASMFilterInput struct {
+	Filter   string    `json:"filter"`
+	Document *Document `json:"document"`
+}
+
+// WASMFilterOutput is returned by a filter module. Unset fields leave the
+// document unchanged.
+type WASMFilterOutput struct {
+	Drop     bool                   `json:"drop,omitempty"`     // Skip the document
+	Content  *string                `json:"content,omitempty"`  // Replacement content
+	Title    *string                `json:"title,omitempty"`    // Replacement title
+	Language *string                `json:"language,omitempty"` // Detected language
+	Tags     []string               `json:"tags,omitempty"`     // Tags to add
+	Metadata map[string]interface{} `json:"metadata,omitempty"` // Extracted metadata, merged into custom metadata
+	Error    string                 `json:"error,omitempty"`    // Set by the module to reject the document
+}
+
+// WASMRuntime compiles WASM modules
+type WASMRuntime interface {
+	// Compile validates and compiles a module; limits apply to every call
+	Compile(ctx context.Context, name string, code []byte, limits WASMLimits) (WASMModule, error)
+
+	// Close releases all compiled modules
+	Close(ctx context.Context) error
+}
+
+// WASMModule is a compiled filter module
+type WASMModule interface {
+	// Call runs the filter entry point on input in a fresh instance
+	Call(ctx context.Context, input []byte) ([]byte, error)
+}
+
+// wasmState holds the runtime and modules compiled on first use
+type wasmState struct {
+	mu      sync.Mutex
+	runtime WASMRuntime
+	modules map[string]WASMModule
+}
+
+// SetWASMRuntime sets the runtime used for custom WASM filters
+func (p *Pipeline) SetWASMRuntime(runtime WASMRuntime) {
+	p.wasm.mu.Lock()
+	defer p.wasm.mu.Unlock()
+	p.wasm.runtime = runtime
+	p.wasm.modules = nil
+}
+
+// wasmModule returns the compiled module of a custom filter
+func (p *Pipeline) wasmModule(ctx context.Context, name string) (WASMModule, error) {
+	p.wasm.mu.Lock()
+	defer p.wasm.mu.Unlock()
+
+	if module, ok := p.wasm.modules[name]; ok {
+		return module, nil
+	}
+	path, ok := p.config.Processing.Preprocessing.WASMModules[name]
+	if !ok {
+		return nil, fmt.Errorf("custom filter %s has no WASM module", name)
+	}
+	if p.wasm.runtime == nil {
+		p.wasm.runtime = newWazeroRuntime(context.Background())
+	}
+
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("custom filter %s: %w", name, err)
+	}
+	limits := p.config.Processing.Preprocessing.WASMLimits.withDefaults()
+	module, err := p.wasm.runtime.Compile(ctx, name, code, limits)
+	if err != nil {
+		return nil, fmt.Errorf("custom filter %s: %w", name, err)
+	}
+	if p.wasm.modules == nil {
+		p.wasm.modules = make(map[string]WASMModule)
+	}
+	p.wasm.modules[name] = module
+	return module, nil
+}
+
+// applyCustomFilters runs the configured custom filters over a document in
+// order. It reports false when a filter dropped the document.
+func (p *Pipeline) applyCustomFilters(ctx context.Context, doc *Document) (bool, error) {
+	preprocessing := p.config.Processing.Preprocessing
+	if len(preprocessing.CustomFilters) == 0 {
+		return true, nil
+	}
+	limits := preprocessing.WASMLimits.withDefaults()
+
+	for _, name := range preprocessing.CustomFilters {
+		module, err := p.wasmModule(ctx, name)
+		if err != nil {
+			return false, err
+		}
+		input, err := json.Marshal(WASMFilterInput{Filter: name, Document: doc})
+		if err != nil {
+			return false, err
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, limits.Timeout)
+		data, err := module.Call(callCtx, input)
+		cancel()
+		if err != nil {
+			return false, fmt.Errorf("custom filter %s: %w", name, err)
+		}
+		if len(data) > limits.MaxOutputMB<<20 {
+			return false, fmt.Errorf("custom filter %s: output exceeds %d MB", name, limits.MaxOutputMB)
+		}
+
+		var output WASMFilterOutput
+		if err := json.Unmarshal(data, &output); err != nil {
+			return false, fmt.Errorf("custom filter %s: invalid output: %w", name, err)
+		}
+		if output.Error != "" {
+			return false, fmt.Errorf("custom filter %s: %s", name, output.Error)
+		}
+		if output.Drop {
+			return false, nil
+		}
+		output.apply(doc)
+	}
+	return true, nil
+}
+
+// apply merges filter output into a document without modifying the custom
+// metadata map shared with the data source
+func (o *WASMFilterOutput) apply(doc *Document) {
+	if o.Content != nil {
+		doc.Content = *o.Content
+	}
+	if o.Title != nil {
+		doc.Title = *o.Title
+	}
+	if o.Language != nil {
+		doc.Language = *o.Language
+	}
+	if len(o.Tags) > 0 {
+		doc.Tags = append(append([]string(nil), doc.Tags...), o.Tags...)
+	}
+	if len(o.Metadata) > 0 {
+		custom := make(map[string]interface{}, len(doc.Metadata.Custom)+len(o.Metadata))
+		for key, value := range doc.Metadata.Custom {
+			custom[key] = value
+		}
+		for key, value := range o.Metadata {
+			// The project selects hooks and must not be moved by tenant code
+			if key != "project_id" {
+				custom[key] = value
+			}
+		}
+		doc.Metadata.Custom = custom
+	}
+}
+
+// validateWASMFilters checks that every custom filter has a module
+func validateWASMFilters(preprocessing PreprocessingConfig) error {
+	for _, name := range preprocessing.CustomFilters {
+		if _, ok := preprocessing.WASMModules[name]; !ok {
+			return fmt.Errorf("custom filter %s has no WASM module", name)
+		}
+	}
+	limits := preprocessing.WASMLimits
+	if limits.Timeout < 0 || limits.MemoryLimitMB < 0 || limits.MaxOutputMB < 0 {
+		return fmt.Errorf("wasm_limits cannot be negative")
+	}
+	return nil
+}
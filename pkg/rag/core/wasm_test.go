@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeWASMRuntime runs Go functions in place of compiled modules, keyed by
+// module file content
+type fakeWASMRuntime struct {
+	filters map[string]func(input WASMFilterInput) WASMFilterOutput
+	limits  WASMLimits
+}
+
+func (r *fakeWASMRuntime) Compile(ctx context.Context, name string, code []byte, limits WASMLimits) (WASMModule, error) {
+	r.limits = limits
+	return fakeWASMModule(r.filters[string(code)]), nil
+}
+
+func (r *fakeWASMRuntime) Close(ctx context.Context) error { return nil }
+
+type fakeWASMModule func(input WASMFilterInput) WASMFilterOutput
+
+func (m fakeWASMModule) Call(ctx context.Context, input []byte) ([]byte, error) {
+	var decoded WASMFilterInput
+	if err := json.Unmarshal(input, &decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(m(decoded))
+}
+
+func TestApplyCustomFilters(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"redact", "classify"} {
+		if err := os.WriteFile(filepath.Join(dir, name+".wasm"), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := DefaultConfig()
+	config.Processing.Preprocessing.CustomFilters = []string{"redact", "classify"}
+	config.Processing.Preprocessing.WASMModules = map[string]string{
+		"redact":   filepath.Join(dir, "redact.wasm"),
+		"classify": filepath.Join(dir, "classify.wasm"),
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	runtime := &fakeWASMRuntime{filters: map[string]func(WASMFilterInput) WASMFilterOutput{
+		"redact": func(input WASMFilterInput) WASMFilterOutput {
+			if strings.Contains(input.Document.Content, "DRAFT") {
+				return WASMFilterOutput{Drop: true}
+			}
+			content := strings.ReplaceAll(input.Document.Content, "hunter2", "[redacted]")
+			return WASMFilterOutput{Content: &content}
+		},
+		"classify": func(input WASMFilterInput) WASMFilterOutput {
+			return WASMFilterOutput{
+				Tags:     []string{"security"},
+				Metadata: map[string]interface{}{"category": "credentials", "project_id": "stolen"},
+			}
+		},
+	}}
+	p := &Pipeline{config: config}
+	p.SetWASMRuntime(runtime)
+
+	custom := map[string]interface{}{"project_id": "p1"}
+	doc := &Document{Content: "password is hunter2", Metadata: DocumentMetadata{Custom: custom}}
+	keep, err := p.applyCustomFilters(context.Background(), doc)
+	if err != nil || !keep {
+		t.Fatalf("expected document to be kept, got %v, %v", keep, err)
+	}
+	if doc.Content != "password is [redacted]" || len(doc.Tags) != 1 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	if doc.Metadata.Custom["category"] != "credentials" || documentProjectID(*doc) != "p1" || len(custom) != 1 {
+		t.Fatalf("unexpected metadata %v (source %v)", doc.Metadata.Custom, custom)
+	}
+	if runtime.limits.Timeout != defaultWASMTimeout || runtime.limits.MemoryLimitMB != defaultWASMMemoryLimitMB {
+		t.Fatalf("expected default limits, got %+v", runtime.limits)
+	}
+
+	keep, err = p.applyCustomFilters(context.Background(), &Document{Content: "DRAFT notes"})
+	if err != nil || keep {
+		t.Fatalf("expected document to be dropped, got %v, %v", keep, err)
+	}
+
+	config.Processing.Preprocessing.CustomFilters = append(config.Processing.Preprocessing.CustomFilters, "missing")
+	if err := config.Validate(); err == nil {
+		t.Fatal("expected filter without module to be rejected")
+	}
+}
+
+// dropModule is a minimal reactor module whose filter always returns
+// {"drop":true} from the start of its memory
+var dropModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// Types: (i32) -> i32, (i32, i32) -> i64
+	0x01, 0x0c, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e,
+	// Functions: alloc, filter
+	0x03, 0x03, 0x02, 0x00, 0x01,
+	// Memory: one page
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	// Exports: memory, alloc, filter
+	0x07, 0x1b, 0x03,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	0x05, 'a', 'l', 'l', 'o', 'c', 0x00, 0x00,
+	0x06, 'f', 'i', 'l', 't', 'e', 'r', 0x00, 0x01,
+	// Code: alloc returns 1024, filter returns pointer 0 and length 13
+	0x0a, 0x0c, 0x02,
+	0x05, 0x00, 0x41, 0x80, 0x08, 0x0b,
+	0x04, 0x00, 0x42, 0x0d, 0x0b,
+	// Data: the filter output at address 0
+	0x0b, 0x13, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x0d,
+	'{', '"', 'd', 'r', 'o', 'p', '"', ':', 't', 'r', 'u', 'e', '}',
+}
+
+func TestDefaultWASMRuntime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drop.wasm")
+	if err := os.WriteFile(path, dropModule, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.Processing.Preprocessing.CustomFilters = []string{"drop"}
+	config.Processing.Preprocessing.WASMModules = map[string]string{"drop": path}
+	p := &Pipeline{config: config}
+
+	keep, err := p.applyCustomFilters(context.Background(), &Document{Content: "anything"})
+	if err != nil || keep {
+		t.Fatalf("expected the built-in runtime to run the module, got %v, %v", keep, err)
+	}
+}
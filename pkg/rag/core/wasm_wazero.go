@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmPageSize is the size of a WASM linear memory page
+const wasmPageSize = 64 * 1024
+
+// wazeroRuntime runs filter modules with wazero. Modules get WASI without
+// filesystem or network access.
+type wazeroRuntime struct {
+	runtimes []wazero.Runtime
+}
+
+// newWazeroRuntime creates a wazero backed runtime
+func newWazeroRuntime(ctx context.Context) WASMRuntime {
+	return &wazeroRuntime{}
+}
+
+// Compile implements the WASMRuntime interface. Each module gets its own
+// wazero runtime because the memory limit is a runtime setting.
+func (r *wazeroRuntime) Compile(ctx context.Context, name string, code []byte, limits WASMLimits) (WASMModule, error) {
+	pages := uint32(limits.MemoryLimitMB * 1024 * 1024 / wasmPageSize)
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(pages).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("invalid module: %w", err)
+	}
+	exports := compiled.ExportedFunctions()
+	for _, export := range []string{"alloc", "filter"} {
+		if _, ok := exports[export]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("module does not export %s", export)
+		}
+	}
+
+	r.runtimes = append(r.runtimes, runtime)
+	return &wazeroModule{name: name, runtime: runtime, compiled: compiled}, nil
+}
+
+// Close implements the WASMRuntime interface
+func (r *wazeroRuntime) Close(ctx context.Context) error {
+	for _, runtime := range r.runtimes {
+		runtime.Close(ctx)
+	}
+	r.runtimes = nil
+	return nil
+}
+
+// wazeroModule is a compiled filter module
+type wazeroModule struct {
+	name     string
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+}
+
+// Call implements the WASMModule interface. The instance is discarded after
+// the call so no state leaks between documents.
+func (m *wazeroModule) Call(ctx context.Context, input []byte) ([]byte, error) {
+	// Reactor modules initialize in _initialize; command modules are not supported
+	config := wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize")
+	instance, err := m.runtime.InstantiateModule(ctx, m.compiled, config)
+	if err != nil {
+		return nil, err
+	}
+	defer instance.Close(context.Background())
+
+	memory := instance.Memory()
+	if memory == nil {
+		return nil, fmt.Errorf("module does not export memory")
+	}
+	results, err := instance.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, wasmCallError(ctx, err)
+	}
+	inputPtr := uint32(results[0])
+	if !memory.Write(inputPtr, input) {
+		return nil, fmt.Errorf("alloc returned an out of range pointer")
+	}
+
+	results, err = instance.ExportedFunction("filter").Call(ctx, uint64(inputPtr), uint64(len(input)))
+	if err != nil {
+		return nil, wasmCallError(ctx, err)
+	}
+	outputPtr, outputLen := uint32(results[0]>>32), uint32(results[0])
+	output, ok := memory.Read(outputPtr, outputLen)
+	if !ok {
+		return nil, fmt.Errorf("filter returned an out of range result")
+	}
+	// The view is invalid once the instance is closed
+	return append([]byte(nil), output...), nil
+}
+
+// wasmCallError reports a timeout instead of the exit wazero raises when the
+// context ends
+func wasmCallError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("exceeded time limit: %w", ctx.Err())
+	}
+	return err
+}
@@ -912,31 +912,44 @@ func NewFileSystemDataSourceFactory() *FileSystemDataSourceFactory {
 
 // CreateDataSource implements DataSourceFactory interface
 func (f *FileSystemDataSourceFactory) CreateDataSource(config map[string]interface{}) (core.DataSource, error) {
+	fileConfig := parseFileSystemConfig(config)
+
+	// Generate ID if not provided
+	id, _ := config["id"].(string)
+	if id == "" {
+		id = fmt.Sprintf("fs_%d", time.Now().Unix())
+	}
+
+	return NewFileSystemDataSource(id, fileConfig)
+}
+
+// parseFileSystemConfig reads a filesystem config from a map, accepting both
+// typed values and values decoded from JSON
+func parseFileSystemConfig(config map[string]interface{}) *FileSystemConfig {
 	fileConfig := &FileSystemConfig{}
 
-	// Parse configuration
 	if rootPath, ok := config["root_path"].(string); ok {
 		fileConfig.RootPath = rootPath
 	}
 	if recursive, ok := config["recursive"].(bool); ok {
 		fileConfig.Recursive = recursive
 	}
-	if includePatterns, ok := config["include_patterns"].([]string); ok {
+	if includePatterns, ok := toStringSlice(config["include_patterns"]); ok {
 		fileConfig.IncludePatterns = includePatterns
 	}
-	if excludePatterns, ok := config["exclude_patterns"].([]string); ok {
+	if excludePatterns, ok := toStringSlice(config["exclude_patterns"]); ok {
 		fileConfig.ExcludePatterns = excludePatterns
 	}
-	if maxFileSize, ok := config["max_file_size"].(int64); ok {
+	if maxFileSize, ok := toInt64(config["max_file_size"]); ok {
 		fileConfig.MaxFileSize = maxFileSize
 	}
-	if minFileSize, ok := config["min_file_size"].(int64); ok {
+	if minFileSize, ok := toInt64(config["min_file_size"]); ok {
 		fileConfig.MinFileSize = minFileSize
 	}
-	if includeTypes, ok := config["include_types"].([]string); ok {
+	if includeTypes, ok := toStringSlice(config["include_types"]); ok {
 		fileConfig.IncludeTypes = includeTypes
 	}
-	if excludeTypes, ok := config["exclude_types"].([]string); ok {
+	if excludeTypes, ok := toStringSlice(config["exclude_types"]); ok {
 		fileConfig.ExcludeTypes = excludeTypes
 	}
 	if followSymlinks, ok := config["follow_symlinks"].(bool); ok {
@@ -951,11 +964,11 @@ func (f *FileSystemDataSourceFactory) CreateDataSource(config map[string]interfa
 	if detectLanguage, ok := config["detect_language"].(bool); ok {
 		fileConfig.DetectLanguage = detectLanguage
 	}
-	if maxWorkers, ok := config["max_workers"].(int); ok {
-		fileConfig.MaxWorkers = maxWorkers
+	if maxWorkers, ok := toInt64(config["max_workers"]); ok {
+		fileConfig.MaxWorkers = int(maxWorkers)
 	}
-	if batchSize, ok := config["batch_size"].(int); ok {
-		fileConfig.BatchSize = batchSize
+	if batchSize, ok := toInt64(config["batch_size"]); ok {
+		fileConfig.BatchSize = int(batchSize)
 	}
 	if enableCache, ok := config["enable_cache"].(bool); ok {
 		fileConfig.EnableCache = enableCache
@@ -967,13 +980,7 @@ func (f *FileSystemDataSourceFactory) CreateDataSource(config map[string]interfa
 		fileConfig.CacheTTL = cacheTTL
 	}
 
-	// Generate ID if not provided
-	id, _ := config["id"].(string)
-	if id == "" {
-		id = fmt.Sprintf("fs_%d", time.Now().Unix())
-	}
-
-	return NewFileSystemDataSource(id, fileConfig)
+	return fileConfig
 }
 
 // GetSupportedTypes implements DataSourceFactory interface
@@ -983,10 +990,14 @@ func (f *FileSystemDataSourceFactory) GetSupportedTypes() []string {
 
 // ValidateConfig implements DataSourceFactory interface
 func (f *FileSystemDataSourceFactory) ValidateConfig(config map[string]interface{}) error {
-	fileConfig := &FileSystemConfig{}
+	fileConfig := parseFileSystemConfig(config)
 
-	if rootPath, ok := config["root_path"].(string); ok {
-		fileConfig.RootPath = rootPath
+	// Unset limits take the defaults applied by NewFileSystemDataSource
+	if fileConfig.MaxWorkers == 0 {
+		fileConfig.MaxWorkers = 4
+	}
+	if fileConfig.BatchSize == 0 {
+		fileConfig.BatchSize = 100
 	}
 
 	return validateFileSystemConfig(fileConfig)
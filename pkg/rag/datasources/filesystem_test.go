@@ -0,0 +1,112 @@
+package datasources
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFileSystemConfigFromJSON(t *testing.T) {
+	var config map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"root_path": "/docs",
+		"recursive": true,
+		"include_patterns": ["*.md", "*.txt"],
+		"exclude_types": [".bin"],
+		"max_file_size": 2048,
+		"max_workers": 8,
+		"batch_size": 10
+	}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileConfig := parseFileSystemConfig(config)
+	if fileConfig.RootPath != "/docs" || !fileConfig.Recursive {
+		t.Fatalf("unexpected config %+v", fileConfig)
+	}
+	if !reflect.DeepEqual(fileConfig.IncludePatterns, []string{"*.md", "*.txt"}) || !reflect.DeepEqual(fileConfig.ExcludeTypes, []string{".bin"}) {
+		t.Fatalf("expected JSON arrays to be read, got %q %q", fileConfig.IncludePatterns, fileConfig.ExcludeTypes)
+	}
+	if fileConfig.MaxFileSize != 2048 || fileConfig.MaxWorkers != 8 || fileConfig.BatchSize != 10 {
+		t.Fatalf("expected JSON numbers to be read, got %+v", fileConfig)
+	}
+
+	// Typed values set in code are read as well
+	fileConfig = parseFileSystemConfig(map[string]interface{}{
+		"include_patterns": []string{"*.go"},
+		"max_file_size":    int64(512),
+		"max_workers":      2,
+	})
+	if !reflect.DeepEqual(fileConfig.IncludePatterns, []string{"*.go"}) || fileConfig.MaxFileSize != 512 || fileConfig.MaxWorkers != 2 {
+		t.Fatalf("unexpected config %+v", fileConfig)
+	}
+}
+
+func TestConfigConversions(t *testing.T) {
+	if values, ok := toStringSlice([]interface{}{"a", 1}); ok {
+		t.Fatalf("expected mixed arrays to be rejected, got %q", values)
+	}
+	if _, ok := toStringSlice("a"); ok {
+		t.Fatal("expected a string not to convert to a slice")
+	}
+	if _, ok := toInt64("10"); ok {
+		t.Fatal("expected a string not to convert to a number")
+	}
+	if value, ok := toInt64(float64(3)); !ok || value != 3 {
+		t.Fatalf("got %d %v, want 3", value, ok)
+	}
+}
+
+func TestFileSystemFactoryValidateConfig(t *testing.T) {
+	factory := NewFileSystemDataSourceFactory()
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		err    string
+	}{
+		{name: "defaults for unset limits", config: map[string]interface{}{"root_path": "/docs"}},
+		{name: "missing root path", config: map[string]interface{}{}, err: "root_path is required"},
+		{name: "negative size", config: map[string]interface{}{"root_path": "/docs", "max_file_size": float64(-1)}, err: "max_file_size"},
+		{name: "negative workers", config: map[string]interface{}{"root_path": "/docs", "max_workers": float64(-2)}, err: "max_workers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := factory.ValidateConfig(tt.config)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestFileSystemFactoryCreateDataSource(t *testing.T) {
+	root := t.TempDir()
+	source, err := NewFileSystemDataSourceFactory().CreateDataSource(map[string]interface{}{
+		"id":               "docs",
+		"root_path":        root,
+		"include_patterns": []interface{}{"*.md"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	if source.GetID() != "docs" {
+		t.Fatalf("got ID %s, want docs", source.GetID())
+	}
+	if err := source.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	fileSource := source.(*FileSystemDataSource)
+	if !reflect.DeepEqual(fileSource.config.IncludePatterns, []string{"*.md"}) {
+		t.Fatalf("got include patterns %q", fileSource.config.IncludePatterns)
+	}
+}
@@ -429,3 +429,35 @@ func CreateDataSourceFromConfig(config map[string]interface{}) (core.DataSource,
 
 	return defaultRegistry.CreateDataSource(sourceType, config)
 }
+
+// toStringSlice converts []string or a JSON-decoded []interface{} of strings
+func toStringSlice(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, s)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// toInt64 converts integer values and JSON-decoded numbers
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
@@ -59,29 +59,94 @@ type Config struct {
 	RetryDelay     time.Duration
 }
 
+// HTTPError is returned when a provider responds with a non-200 status
+type HTTPError struct {
+	Op         string // embedding, rerank, chat completion or moderation
+	StatusCode int
+	Body       string // Head of the response body
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s HTTP error: %d %s", e.Op, e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if retried: rate limits and server errors
+func (e *HTTPError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
 // ModelInfo contains information about supported models
 type ModelInfo struct {
-	Name      string
-	Type      string // "chat", "embedding", "rerank"
-	MaxTokens int
-	Provider  string
-	Endpoint  string
+	Name         string
+	Type         string // "chat", "embedding", "rerank"
+	MaxTokens    int
+	Provider     string
+	Endpoint     string
+	Capabilities []string // Native features such as "json_object", "json_schema" and "tools"
 }
 
 // ChatMessage represents a chat message
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Calls requested by an assistant message
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Call answered by a tool message
+}
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string       `json:"type"` // Always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the signature of a callable function.
+// Parameters is a JSON schema describing the arguments object.
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the called function name and its JSON-encoded arguments
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // ChatCompletionRequest represents a chat completion request
 type ChatCompletionRequest struct {
-	Model       string        `json:"model"`
-	Messages    []ChatMessage `json:"messages"`
-	MaxTokens   int           `json:"max_tokens,omitempty"`
-	Temperature float64       `json:"temperature,omitempty"`
-	Stream      bool          `json:"stream,omitempty"`
-	TopP        float64       `json:"top_p,omitempty"`
+	Model          string          `json:"model"`
+	Messages       []ChatMessage   `json:"messages"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Temperature    float64         `json:"temperature"` // Sent even when 0, which callers use for reproducible output
+	Stream         bool            `json:"stream,omitempty"`
+	TopP           float64         `json:"top_p,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Seed           *int64          `json:"seed,omitempty"` // Best-effort reproducible sampling, where supported
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+	ToolChoice     interface{}     `json:"tool_choice,omitempty"` // "auto", "none", "required" or a specific function
+}
+
+// ResponseFormat requests structured output from the model.
+// Type is "json_object" for JSON mode or "json_schema" for schema-constrained output.
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat names the schema a structured response must follow
+type JSONSchemaFormat struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict,omitempty"`
 }
 
 // ChatCompletionResponse represents a chat completion response
@@ -93,8 +158,9 @@ type ChatCompletionResponse struct {
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -131,16 +197,18 @@ func GetSupportedModels() []ModelInfo {
 			MaxTokens: 8192,
 		},
 		{
-			Name:      "Qwen/Qwen2.5-7B-Instruct",
-			Type:      "chat",
-			Provider:  "SiliconFlow",
-			MaxTokens: 32768,
+			Name:         "Qwen/Qwen2.5-7B-Instruct",
+			Type:         "chat",
+			Provider:     "SiliconFlow",
+			MaxTokens:    32768,
+			Capabilities: []string{"json_object", "tools"},
 		},
 		{
-			Name:      "Qwen/Qwen2.5-14B-Instruct",
-			Type:      "chat",
-			Provider:  "SiliconFlow",
-			MaxTokens: 32768,
+			Name:         "Qwen/Qwen2.5-14B-Instruct",
+			Type:         "chat",
+			Provider:     "SiliconFlow",
+			MaxTokens:    32768,
+			Capabilities: []string{"json_object", "tools"},
 		},
 
 		// BGE Embedding Models
@@ -169,14 +237,30 @@ func GetSupportedModels() []ModelInfo {
 			Provider: "OpenAI",
 		},
 		{
-			Name:     "gpt-3.5-turbo",
-			Type:     "chat",
-			Provider: "OpenAI",
+			Name:         "gpt-3.5-turbo",
+			Type:         "chat",
+			Provider:     "OpenAI",
+			Capabilities: []string{"json_object", "tools"},
 		},
 		{
-			Name:     "gpt-4",
-			Type:     "chat",
-			Provider: "OpenAI",
+			Name:         "gpt-4",
+			Type:         "chat",
+			Provider:     "OpenAI",
+			Capabilities: []string{"tools"},
+		},
+		{
+			Name:         "gpt-4o",
+			Type:         "chat",
+			Provider:     "OpenAI",
+			MaxTokens:    128000,
+			Capabilities: []string{"json_object", "json_schema", "tools"},
+		},
+		{
+			Name:         "gpt-4o-mini",
+			Type:         "chat",
+			Provider:     "OpenAI",
+			MaxTokens:    128000,
+			Capabilities: []string{"json_object", "json_schema", "tools"},
 		},
 	}
 }
@@ -192,6 +276,20 @@ func getModelInfo(modelName string) *ModelInfo {
 	return nil
 }
 
+// SupportsCapability reports whether a known model natively supports a feature
+func SupportsCapability(model, capability string) bool {
+	info := getModelInfo(model)
+	if info == nil {
+		return false
+	}
+	for _, c := range info.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
 // makeHTTPRequest makes an HTTP request with retry logic
 func makeHTTPRequest(method, url string, headers map[string]string, body []byte) (*http.Response, error) {
 	config := getDefaultConfig()
@@ -433,7 +531,7 @@ func processEmbeddingChunk(chunk []string, model string, config *Config) ([][]fl
 			return nil, fmt.Errorf("token limit exceeded: %s (estimated tokens: %d, chunk size: %d inputs, %d chars)",
 				head(b), estimatedTokens, len(chunk), totalChars)
 		}
-		return nil, fmt.Errorf("embedding HTTP error: %d %s", resp.StatusCode, head(b))
+		return nil, &HTTPError{Op: "embedding", StatusCode: resp.StatusCode, Body: head(b)}
 	}
 
 	var response struct {
@@ -518,7 +616,7 @@ func EnhancedRerank(query string, docs []string, config *Config) ([]float64, err
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("rerank HTTP error: %d %s", resp.StatusCode, head(b))
+		return nil, &HTTPError{Op: "rerank", StatusCode: resp.StatusCode, Body: head(b)}
 	}
 
 	var response struct {
@@ -555,26 +653,37 @@ func ChatCompletion(messages []ChatMessage, config *Config) (*ChatCompletionResp
 		config = getDefaultConfig()
 	}
 
-	if config.BaseURL == "" || config.APIKey == "" || config.Model == "" {
+	return CreateChatCompletion(ChatCompletionRequest{
+		Model:       config.Model,
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   1000,
+		TopP:        0.9,
+	}, config)
+}
+
+// CreateChatCompletion sends a fully specified chat completion request.
+// The request model defaults to the configured model.
+func CreateChatCompletion(request ChatCompletionRequest, config *Config) (*ChatCompletionResponse, error) {
+	if config == nil {
+		config = getDefaultConfig()
+	}
+	if request.Model == "" {
+		request.Model = config.Model
+	}
+
+	if config.BaseURL == "" || config.APIKey == "" || request.Model == "" {
 		return nil, fmt.Errorf("chat completion not configured: missing BaseURL, APIKey, or Model")
 	}
 
 	// Validate model is supported
-	if modelInfo := getModelInfo(config.Model); modelInfo != nil && modelInfo.Type != "chat" {
-		return nil, fmt.Errorf("model %s is not a chat model", config.Model)
+	if modelInfo := getModelInfo(request.Model); modelInfo != nil && modelInfo.Type != "chat" {
+		return nil, fmt.Errorf("model %s is not a chat model", request.Model)
 	}
 
 	path := resolvePath(config.BaseURL, os.Getenv("LLM_COMPLETIONS_PATH"), "/chat/completions")
 	url := strings.TrimRight(config.BaseURL, "/") + path
 
-	request := ChatCompletionRequest{
-		Model:       config.Model,
-		Messages:    messages,
-		Temperature: 0.7,
-		MaxTokens:   1000,
-		TopP:        0.9,
-	}
-
 	buf, err := json.Marshal(request)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
@@ -597,7 +706,7 @@ func ChatCompletion(messages []ChatMessage, config *Config) (*ChatCompletionResp
 	}
 
 	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("chat completion HTTP error: %d %s", resp.StatusCode, head(b))
+		return nil, &HTTPError{Op: "chat completion", StatusCode: resp.StatusCode, Body: head(b)}
 	}
 
 	var response ChatCompletionResponse
@@ -649,3 +758,65 @@ func ExpandKeywords(query string) []string {
 	}
 	return keywords
 }
+
+// ModerationResponse represents a moderation API response
+type ModerationResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// Moderate classifies text with an OpenAI-compatible moderation endpoint
+func Moderate(input string, model string, config *Config) (*ModerationResponse, error) {
+	if config == nil {
+		config = getDefaultConfig()
+	}
+
+	if config.BaseURL == "" || config.APIKey == "" {
+		return nil, fmt.Errorf("moderation not configured: missing BaseURL or APIKey")
+	}
+
+	path := resolvePath(config.BaseURL, os.Getenv("LLM_MODERATIONS_PATH"), "/moderations")
+	url := strings.TrimRight(config.BaseURL, "/") + path
+
+	request := map[string]interface{}{"input": input}
+	if model != "" {
+		request["model"] = model
+	}
+
+	buf, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	headers := map[string]string{
+		"Authorization": "Bearer " + config.APIKey,
+		"Content-Type":  "application/json",
+	}
+
+	resp, err := makeHTTPRequest("POST", url, headers, buf)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := readAll(resp)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, &HTTPError{Op: "moderation", StatusCode: resp.StatusCode, Body: head(b)}
+	}
+
+	var response ModerationResponse
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w, body: %s", err, head(b))
+	}
+
+	return &response, nil
+}
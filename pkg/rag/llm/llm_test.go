@@ -319,16 +319,18 @@ func TestChatCompletionResponseStructure(t *testing.T) {
 		Choices: []struct {
 			Index   int `json:"index"`
 			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
+				Role      string     `json:"role"`
+				Content   string     `json:"content"`
+				ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		}{
 			{
 				Index: 0,
 				Message: struct {
-					Role    string `json:"role"`
-					Content string `json:"content"`
+					Role      string     `json:"role"`
+					Content   string     `json:"content"`
+					ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 				}{
 					Role:    "assistant",
 					Content: "Hello! How can I help you?",
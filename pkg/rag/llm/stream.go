@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// chatCompletionChunk is one server-sent event of a streamed chat completion
+type chatCompletionChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// StreamChatCompletion sends a streaming chat completion request and calls
+// onDelta with each piece of generated content as it arrives. Cancelling ctx
+// or returning an error from onDelta aborts the request. The returned
+// response holds the accumulated content.
+func StreamChatCompletion(ctx context.Context, request ChatCompletionRequest, config *Config, onDelta func(delta string) error) (*ChatCompletionResponse, error) {
+	if config == nil {
+		config = getDefaultConfig()
+	}
+	if request.Model == "" {
+		request.Model = config.Model
+	}
+	if config.BaseURL == "" || config.APIKey == "" || request.Model == "" {
+		return nil, fmt.Errorf("chat completion not configured: missing BaseURL, APIKey, or Model")
+	}
+	request.Stream = true
+
+	path := resolvePath(config.BaseURL, os.Getenv("LLM_COMPLETIONS_PATH"), "/chat/completions")
+	url := strings.TrimRight(config.BaseURL, "/") + path
+
+	buf, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	// No client timeout: a stream legitimately outlives it; ctx bounds the call
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		b, _ := readAll(resp)
+		return nil, &HTTPError{Op: "chat completion", StatusCode: resp.StatusCode, Body: head(b)}
+	}
+
+	var content strings.Builder
+	var response ChatCompletionResponse
+	finishReason := ""
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("unmarshal stream chunk: %w, body: %s", err, head([]byte(data)))
+		}
+		if response.ID == "" {
+			response.ID, response.Model = chunk.ID, chunk.Model
+		}
+		if chunk.Usage != nil {
+			response.Usage.PromptTokens = chunk.Usage.PromptTokens
+			response.Usage.CompletionTokens = chunk.Usage.CompletionTokens
+			response.Usage.TotalTokens = chunk.Usage.TotalTokens
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != nil {
+				finishReason = *choice.FinishReason
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("read stream: %w", err)
+	}
+
+	response.Object = "chat.completion"
+	response.Choices = make([]struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	}, 1)
+	response.Choices[0].Message.Role = "assistant"
+	response.Choices[0].Message.Content = content.String()
+	response.Choices[0].FinishReason = finishReason
+	return &response, nil
+}
@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestStreamChatCompletion tests that deltas are delivered and accumulated
+func TestStreamChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, part := range []string{"Hel", "lo"} {
+			fmt.Fprintf(w, "data: {\"id\":\"c1\",\"model\":\"m\",\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", part)
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"total_tokens\":7}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	config := &Config{BaseURL: server.URL + "/v1", APIKey: "key", Model: "m"}
+	var deltas []string
+	response, err := StreamChatCompletion(context.Background(), ChatCompletionRequest{}, config, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(deltas) != 2 {
+		t.Errorf("Expected 2 deltas, got %v", deltas)
+	}
+	if got := response.Choices[0].Message.Content; got != "Hello" {
+		t.Errorf("Expected accumulated content 'Hello', got %q", got)
+	}
+	if response.Choices[0].FinishReason != "stop" || response.Usage.TotalTokens != 7 {
+		t.Errorf("Unexpected finish reason or usage: %+v", response)
+	}
+
+	// An error from the callback aborts the stream
+	stop := fmt.Errorf("stop")
+	if _, err := StreamChatCompletion(context.Background(), ChatCompletionRequest{}, config, func(string) error { return stop }); err != stop {
+		t.Errorf("Expected callback error, got %v", err)
+	}
+}
@@ -998,6 +998,7 @@ func init() {
 	defaultChunkingRegistry.RegisterStrategy("paragraph", NewParagraphChunkingStrategy(2000, 10, 100, 200))
 	defaultChunkingRegistry.RegisterStrategy("semantic", NewSemanticChunkingStrategy(1500, 100, 0.7, nil))
 	defaultChunkingRegistry.RegisterStrategy("code", NewCodeChunkingStrategy(1500, 50, 100))
+	defaultChunkingRegistry.RegisterStrategy("table_aware", NewTableAwareChunkingStrategy(NewParagraphChunkingStrategy(2000, 10, 100, 200), 50))
 }
 
 // GetChunkingStrategy returns a strategy from the default registry
@@ -1014,3 +1015,35 @@ func ListChunkingStrategies() []string {
 func RegisterChunkingStrategy(name string, strategy core.ChunkingStrategy) {
 	defaultChunkingRegistry.RegisterStrategy(name, strategy)
 }
+
+// NewChunkingStrategy creates a strategy configured from config; sizes left
+// at zero use the defaults of the registered strategy. The generator is only
+// used by semantic chunking.
+func NewChunkingStrategy(config core.ChunkingConfig, generator embedding.VectorGenerator) (core.ChunkingStrategy, error) {
+	size := func(value, fallback int) int {
+		if value > 0 {
+			return value
+		}
+		return fallback
+	}
+
+	switch config.Strategy {
+	case "fixed", "":
+		return NewFixedSizeChunkingStrategy(size(config.MaxChunkSize, 1000), size(config.MinChunkSize, 100), size(config.OverlapSize, 200)), nil
+	case "paragraph":
+		return NewParagraphChunkingStrategy(size(config.MaxChunkSize, 2000), 10, size(config.MinChunkSize, 100), size(config.OverlapSize, 200)), nil
+	case "semantic":
+		threshold := config.SimilarityThreshold
+		if threshold <= 0 {
+			threshold = 0.7
+		}
+		return NewSemanticChunkingStrategy(size(config.MaxChunkSize, 1500), size(config.MinChunkSize, 100), threshold, generator), nil
+	case "code":
+		return NewCodeChunkingStrategy(size(config.MaxChunkSize, 1500), size(config.MinChunkSize, 50), size(config.OverlapSize, 100)), nil
+	case "table_aware":
+		base := NewParagraphChunkingStrategy(size(config.MaxChunkSize, 2000), 10, size(config.MinChunkSize, 100), size(config.OverlapSize, 200))
+		return NewTableAwareChunkingStrategy(base, 50), nil
+	default:
+		return nil, fmt.Errorf("unknown chunking strategy: %s", config.Strategy)
+	}
+}
@@ -0,0 +1,236 @@
+package processors
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+// tableSeparatorRegex matches a Markdown table separator row such as "| --- | :-: |"
+var tableSeparatorRegex = regexp.MustCompile(`^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?$`)
+
+// TableSpan is a table found in document content
+type TableSpan struct {
+	Table     core.TableData
+	StartPos  int
+	EndPos    int
+	StartLine int
+	EndLine   int
+}
+
+// ExtractMarkdownTables finds Markdown pipe tables in content. A line directly
+// above the header that is not blank is used as the table caption.
+func ExtractMarkdownTables(content string) []TableSpan {
+	lines := strings.Split(content, "\n")
+	offsets := make([]int, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + len(line) + 1
+	}
+
+	var spans []TableSpan
+	for i := 0; i+1 < len(lines); i++ {
+		header := strings.TrimSpace(lines[i])
+		if !strings.Contains(header, "|") || !tableSeparatorRegex.MatchString(strings.TrimSpace(lines[i+1])) {
+			continue
+		}
+
+		table := core.TableData{
+			Headers: splitTableRow(header),
+			Format:  "markdown",
+		}
+		if i > 0 {
+			if caption := strings.TrimSpace(lines[i-1]); caption != "" && !strings.Contains(caption, "|") {
+				table.Caption = strings.TrimLeft(caption, "# ")
+			}
+		}
+
+		end := i + 2
+		for end < len(lines) {
+			row := strings.TrimSpace(lines[end])
+			if row == "" || !strings.Contains(row, "|") {
+				break
+			}
+			table.Rows = append(table.Rows, splitTableRow(row))
+			end++
+		}
+
+		endPos := offsets[end] - 1
+		if endPos > len(content) {
+			endPos = len(content)
+		}
+		spans = append(spans, TableSpan{
+			Table:     table,
+			StartPos:  offsets[i],
+			EndPos:    endPos,
+			StartLine: i + 1,
+			EndLine:   end,
+		})
+		i = end - 1
+	}
+
+	return spans
+}
+
+// splitTableRow splits a Markdown table row into trimmed cells, honouring escaped pipes
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, "\\|") {
+		row = row[:len(row)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		if row[i] == '\\' && i+1 < len(row) && row[i+1] == '|' {
+			cell.WriteByte('|')
+			i++
+			continue
+		}
+		if row[i] == '|' {
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(row[i])
+	}
+	cells = append(cells, strings.TrimSpace(cell.String()))
+	return cells
+}
+
+// TableAwareChunkingStrategy keeps tables intact as structured table chunks
+// and delegates the surrounding prose to a base strategy
+type TableAwareChunkingStrategy struct {
+	base         core.ChunkingStrategy
+	maxTableRows int
+}
+
+// NewTableAwareChunkingStrategy wraps a base chunking strategy. Tables with
+// more than maxTableRows rows are split into several table chunks that each
+// repeat the header.
+func NewTableAwareChunkingStrategy(base core.ChunkingStrategy, maxTableRows int) *TableAwareChunkingStrategy {
+	return &TableAwareChunkingStrategy{
+		base:         base,
+		maxTableRows: maxTableRows,
+	}
+}
+
+// Chunk implements ChunkingStrategy interface
+func (s *TableAwareChunkingStrategy) Chunk(ctx context.Context, doc core.Document) ([]core.DocumentChunk, error) {
+	spans := ExtractMarkdownTables(doc.Content)
+	if len(spans) == 0 {
+		return s.base.Chunk(ctx, doc)
+	}
+
+	// Blank out tables so the base strategy only sees prose, keeping offsets stable
+	prose := []byte(doc.Content)
+	for _, span := range spans {
+		for i := span.StartPos; i < span.EndPos; i++ {
+			if prose[i] != '\n' {
+				prose[i] = ' '
+			}
+		}
+	}
+
+	proseDoc := doc
+	proseDoc.Content = string(prose)
+	var chunks []core.DocumentChunk
+	if strings.TrimSpace(proseDoc.Content) != "" {
+		proseChunks, err := s.base.Chunk(ctx, proseDoc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to chunk prose: %w", err)
+		}
+		chunks = append(chunks, proseChunks...)
+	}
+
+	for i, span := range spans {
+		chunks = append(chunks, s.createTableChunks(doc, span, i)...)
+	}
+
+	// Restore document order and renumber
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].StartPos < chunks[j].StartPos })
+	for i := range chunks {
+		chunks[i].ChunkIndex = i
+	}
+
+	return chunks, nil
+}
+
+// createTableChunks converts a table span into one or more table chunks
+func (s *TableAwareChunkingStrategy) createTableChunks(doc core.Document, span TableSpan, tableIndex int) []core.DocumentChunk {
+	rows := span.Table.Rows
+	size := s.maxTableRows
+	if size <= 0 || size > len(rows) {
+		size = len(rows)
+	}
+	if size == 0 {
+		size = 1
+	}
+
+	var chunks []core.DocumentChunk
+	for part, start := 0, 0; start < len(rows) || (start == 0 && len(rows) == 0); part, start = part+1, start+size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		table := span.Table
+		table.Rows = rows[start:end]
+		content := table.Render()
+
+		chunks = append(chunks, core.DocumentChunk{
+			ID:         fmt.Sprintf("%s_table_%d_%d", doc.ID, tableIndex, part),
+			DocumentID: doc.ID,
+			Content:    content,
+			StartPos:   span.StartPos,
+			EndPos:     span.EndPos,
+			StartLine:  span.StartLine,
+			EndLine:    span.EndLine,
+			ChunkType:  core.ChunkTypeTable,
+			ChunkSize:  len(content),
+			Table:      &table,
+			Metadata: map[string]interface{}{
+				"table_index": tableIndex,
+				"row_offset":  start,
+				"total_rows":  len(rows),
+			},
+			CreatedAt: time.Now(),
+		})
+
+		if len(rows) == 0 {
+			break
+		}
+	}
+
+	return chunks
+}
+
+// GetName implements ChunkingStrategy interface
+func (s *TableAwareChunkingStrategy) GetName() string {
+	return "table_aware"
+}
+
+// GetDescription implements ChunkingStrategy interface
+func (s *TableAwareChunkingStrategy) GetDescription() string {
+	return fmt.Sprintf("Preserves tables as structured chunks; prose chunked by %s", s.base.GetName())
+}
+
+// SetParameters implements ChunkingStrategy interface
+func (s *TableAwareChunkingStrategy) SetParameters(params map[string]interface{}) error {
+	if maxTableRows, ok := params["max_table_rows"].(int); ok {
+		s.maxTableRows = maxTableRows
+	}
+	return s.base.SetParameters(params)
+}
+
+// GetParameters implements ChunkingStrategy interface
+func (s *TableAwareChunkingStrategy) GetParameters() map[string]interface{} {
+	params := s.base.GetParameters()
+	params["max_table_rows"] = s.maxTableRows
+	return params
+}
@@ -0,0 +1,79 @@
+package processors
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guileen/metabase/pkg/rag/core"
+)
+
+const tableDocument = `Intro paragraph.
+
+## Plans
+| Plan | Price |
+| --- | ---: |
+| Free | 0 |
+| Pro \| Team | 10 |
+| Enterprise | call |
+
+Closing paragraph.`
+
+func TestExtractMarkdownTables(t *testing.T) {
+	spans := ExtractMarkdownTables(tableDocument)
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Table.Caption != "Plans" {
+		t.Fatalf("got caption %q", span.Table.Caption)
+	}
+	if !reflect.DeepEqual(span.Table.Headers, []string{"Plan", "Price"}) {
+		t.Fatalf("got headers %q", span.Table.Headers)
+	}
+	if len(span.Table.Rows) != 3 || span.Table.Rows[1][0] != "Pro | Team" {
+		t.Fatalf("got rows %q", span.Table.Rows)
+	}
+	if span.StartLine != 4 || span.EndLine != 8 {
+		t.Fatalf("got lines %d-%d, want 4-8", span.StartLine, span.EndLine)
+	}
+	if got := tableDocument[span.StartPos:span.EndPos]; !strings.HasPrefix(got, "| Plan") || !strings.HasSuffix(got, "| Enterprise | call |") {
+		t.Fatalf("unexpected span content %q", got)
+	}
+
+	if spans := ExtractMarkdownTables("a | b\nnot a separator"); len(spans) != 0 {
+		t.Fatalf("expected no tables, got %+v", spans)
+	}
+}
+
+func TestTableAwareChunkingStrategy(t *testing.T) {
+	strategy := NewTableAwareChunkingStrategy(NewParagraphChunkingStrategy(2000, 10, 100, 200), 2)
+	chunks, err := strategy.Chunk(context.Background(), core.Document{ID: "doc", Content: tableDocument})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tables []core.DocumentChunk
+	for i, chunk := range chunks {
+		if chunk.ChunkIndex != i {
+			t.Fatalf("expected chunks to be renumbered in order, got %d at %d", chunk.ChunkIndex, i)
+		}
+		if chunk.ChunkType == core.ChunkTypeTable {
+			tables = append(tables, chunk)
+			continue
+		}
+		if strings.Contains(chunk.Content, "Enterprise") {
+			t.Fatalf("table content leaked into prose chunk %q", chunk.Content)
+		}
+	}
+	if len(tables) != 2 {
+		t.Fatalf("expected the table to be split in 2 chunks, got %d", len(tables))
+	}
+	if tables[0].ID != "doc_table_0_0" || len(tables[0].Table.Rows) != 2 || tables[1].Metadata["row_offset"] != 2 {
+		t.Fatalf("unexpected table chunks %+v", tables)
+	}
+	if !strings.HasPrefix(tables[1].Content, "Plans\n\n| Plan | Price |") {
+		t.Fatalf("expected every part to repeat the caption and header, got %q", tables[1].Content)
+	}
+}